@@ -9,16 +9,30 @@ import (
 	"syscall"
 	"time"
 
+	"connect/internal/alertrule"
 	"connect/internal/api"
+	"connect/internal/apikey"
 	"connect/internal/auth"
+	"connect/internal/bootstrap"
+	"connect/internal/cipurge"
 	"connect/internal/config"
 	"connect/internal/database"
+	"connect/internal/graph"
 	"connect/internal/logger"
+	"connect/internal/negotiation"
+	"connect/internal/offboarding"
+	"connect/internal/organization"
 	"connect/internal/repositories"
+	"connect/internal/schemacheck"
+	"connect/internal/scim"
+	"connect/internal/serviceaccount"
+	"connect/internal/visibility"
+	"connect/internal/webhooks"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/rs/zerolog/log"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
@@ -46,24 +60,136 @@ func main() {
 		log.Fatal().Err(err).Msg("Database health check failed")
 	}
 
-	// Initialize authentication services
-	jwtService := auth.NewJWTService(
-		cfg.Auth.SecretKey,
-		cfg.Auth.AccessTokenTTL,
-		cfg.Auth.RefreshTokenTTL,
+	// Check the schema against what this version expects; drift is logged,
+	// and refuses startup in strict mode
+	schemaChecker := schemacheck.NewChecker(
+		schemacheck.DefaultExpectations(),
+		schemacheck.NewSQLInspector(dbManager.Postgres),
+		schemacheck.NewGraphInspector(dbManager.Neo4j, cfg.Database.Neo4j.Database),
 	)
+	if cfg.SchemaCheck.Enabled {
+		if _, err := schemaChecker.RunStartupCheck(ctx, cfg.SchemaCheck.Strict); err != nil {
+			log.Fatal().Err(err).Msg("Schema self-check failed")
+		}
+	}
+
+	// Initialize authentication services
+	jwtService, err := newJWTService(cfg.Auth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load JWT signing keys")
+	}
 
 	passwordService := auth.NewPasswordService(auth.DefaultPasswordConfig())
 
 	// Initialize repositories
 	userRepository := repositories.NewUserRepository(dbManager.Postgres, passwordService)
+	roleRepository := repositories.NewRoleRepository(dbManager.Postgres)
+	// Role permissions are cached in Redis so authorizing a request does not
+	// query the database; without Redis they are loaded on every request
+	var permissionCache auth.PermissionCache
+	redisClient, err := database.NewRedisClient(&cfg.Database.Redis, logrus.StandardLogger())
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("Failed to connect to Redis, role permissions will not be cached")
+	} else {
+		permissionCache = redisClient
+	}
+	permissions := auth.NewCachedPermissionResolver(roleRepository, permissionCache, cfg.Auth.PermissionCacheTTL)
+	serviceAccounts := serviceaccount.NewService(
+		serviceaccount.NewPostgresStore(dbManager.Postgres),
+		jwtService,
+		repositories.NewRefreshTokenRepository(dbManager.Postgres),
+	)
+	apiKeys := apikey.NewService(apikey.NewPostgresStore(dbManager.Postgres), permissions)
 
 	// Initialize API handlers
 	authHandler := api.NewAuthHandler(cfg, appLogger, jwtService, userRepository, passwordService)
+	if cfg.Auth.RefreshTokenRotation {
+		refreshTokenRepository := repositories.NewRefreshTokenRepository(dbManager.Postgres)
+		authHandler.SetRefreshTokenRotation(auth.NewRefreshTokenRotator(jwtService, refreshTokenRepository, refreshTokenRepository))
+	}
+	failedLogins := alertrule.NewWindowCounter(alertrule.MetricFailedLogins, cfg.AlertRules.FailedLoginWindow)
+	authHandler.SetFailedLogins(failedLogins)
+	organizations := organization.NewService(organization.NewPostgresStore(dbManager.Postgres))
+	authHandler.SetOrganizations(organizations)
 	ciHandler := api.NewCIHandler(cfg, appLogger, dbManager)
 	relationshipHandler := api.NewRelationshipHandler(cfg, appLogger, dbManager)
-	graphHandler := api.NewGraphHandler(cfg, appLogger, dbManager)
+	ciRepository := repositories.NewCIRepository(dbManager.Postgres)
+	graphHandler := api.NewGraphHandler(cfg, appLogger, graph.NewService(
+		graph.NewNeo4jTraverser(dbManager.Neo4j),
+		ciRepository,
+	), ciRepository)
+	// Graph results hide the CIs the caller's permissions do not grant, like
+	// the CI responses of the API server
+	visibilityResolver := visibility.NewResolver(permissions)
+	graphHandler.SetVisibility(visibilityResolver)
 	healthHandler := api.NewHealthHandler(cfg, appLogger, dbManager)
+	userHandler := api.NewUserHandler(cfg, appLogger, userRepository, roleRepository)
+	roleHandler := api.NewRoleHandler(cfg, appLogger, roleRepository)
+	roleHandler.SetPermissionCache(permissions)
+	permissionHandler := api.NewPermissionHandler(cfg, appLogger, roleRepository)
+	offboardingHandler := api.NewOffboardingHandler(cfg, appLogger, offboarding.NewService(offboarding.NewPostgresStore(dbManager.Postgres)))
+	purgeHandler := api.NewCIPurgeHandler(cfg, appLogger, cipurge.NewService(cipurge.NewPostgresStore(dbManager.Postgres), cipurge.Options{
+		RequiredApprovals: cfg.Purge.RequiredApprovals,
+		RequestTTL:        cfg.Purge.RequestTTL,
+	}))
+	// Alerts are queued as webhook deliveries to the endpoints subscribing to
+	// alert events, e.g. incident management connectors
+	alertRules := alertrule.NewService(
+		alertrule.NewPostgresStore(dbManager.Postgres),
+		alertrule.WebhookNotifier{Publisher: webhooks.NewService(webhooks.NewPostgresStore(dbManager.Postgres), webhooks.Options{
+			Timeout:     cfg.Webhooks.Timeout,
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+		})},
+		alertrule.NewPostgresMetrics(dbManager.Postgres),
+		failedLogins,
+	)
+	alertRuleHandler := api.NewAlertRuleHandler(cfg, appLogger, alertRules)
+	organizationHandler := api.NewOrganizationHandler(cfg, appLogger, organizations)
+	apiKeyHandler := api.NewAPIKeyHandler(cfg, appLogger, apiKeys)
+	scimHandler := api.NewSCIMHandler(cfg, appLogger, scim.NewService(repositories.NewSCIMRepository(userRepository, roleRepository), cfg.SCIM.GroupRoles))
+	bootstrapHandler := api.NewBootstrapHandler(cfg, appLogger, bootstrap.NewService(bootstrap.NewPostgresStore(dbManager.Postgres), passwordService))
+
+	// Authentication middleware, shared by the routes below and the API server
+	authMiddleware := auth.NewAuthMiddleware(auth.AuthConfig{
+		JWTService:  jwtService,
+		APIKeys:     auth.APIKeyAuthenticators{serviceAccounts, apiKeys},
+		Permissions: permissions,
+		Logger:      appLogger,
+		ExcludePaths: []string{
+			"/api/v1/health",
+			"/api/v1/auth/login",
+			"/api/v1/auth/register",
+			"/api/v1/auth/refresh",
+			"/api/v1/auth/password-reset-request",
+			"/api/v1/auth/password-reset",
+			"/api/v1/oauth/token",
+			"/api/versions",
+			"/health/shards",
+			"/health/schema",
+			cfg.Metrics.Path,
+		},
+		// The public catalog serves anonymous callers
+		ExcludePrefixes: []string{"/api/v1/public/"},
+		OptionalPaths:   []string{},
+	})
+
+	// The API server serves the feature routes; the routes below are served
+	// for the requests it does not match
+	apiServer, err := newAPIServer(serverDeps{
+		cfg:             cfg,
+		dbManager:       dbManager,
+		redisClient:     redisClient,
+		ciRepository:    ciRepository,
+		authMiddleware:  authMiddleware,
+		permissions:     permissions,
+		serviceAccounts: serviceAccounts,
+		alertRules:      alertRules,
+		schemaChecker:   schemaChecker,
+		visibility:      visibilityResolver,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize API server")
+	}
 
 	// Create router
 	router := chi.NewRouter()
@@ -74,7 +200,14 @@ func main() {
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Timeout(60 * time.Second))
-	router.Use(middleware.AllowContentType("application/json"))
+	// Bodies and responses are JSON but for SCIM, which identity providers
+	// speak as application/scim+json, and the graph export
+	scimMediaTypes := []string{scim.ContentType, negotiation.JSON}
+	router.Use(negotiation.NewPolicy(negotiation.JSONRule, map[string]negotiation.Rule{
+		"GET /api/v1/graph/export": {Produces: []string{negotiation.CSV}},
+		"/scim/v2/*":               {Consumes: scimMediaTypes, Produces: scimMediaTypes},
+	}).Middleware)
+	router.Use(api.NewPaginationPolicy(cfg.Pagination).Middleware)
 
 	// CORS
 	cors := cors.New(cors.Options{
@@ -87,6 +220,14 @@ func main() {
 	})
 	router.Use(cors.Handler)
 
+	// Public signing keys for services validating our tokens
+	router.Get("/.well-known/jwks.json", authHandler.JWKS)
+
+	// SCIM provisioning by identity providers, authenticated by the SCIM token
+	if cfg.SCIM.Enabled {
+		router.Mount("/scim/v2", scimHandler.Routes())
+	}
+
 	// API version
 	router.Route("/api/v1", func(r chi.Router) {
 		// Health check
@@ -95,80 +236,67 @@ func main() {
 		// Authentication routes
 		r.Mount("/auth", authHandler.Routes())
 
+		// First-run setup, refused once any user exists
+		if cfg.Bootstrap.Enabled {
+			r.Mount("/bootstrap", bootstrapHandler.Routes())
+		}
+
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			// Authentication middleware
-			r.Use(auth.NewAuthMiddleware(auth.AuthConfig{
-				JWTService: jwtService,
-				Logger:     appLogger,
-				ExcludePaths: []string{
-					"/api/v1/health",
-					"/api/v1/auth/login",
-					"/api/v1/auth/register",
-					"/api/v1/auth/refresh",
-					"/api/v1/auth/password-reset-request",
-					"/api/v1/auth/password-reset",
-				},
-				OptionalPaths: []string{},
-			}).Middleware)
+			r.Use(authMiddleware.Middleware)
 
 			// CI Management routes
-			r.Mount("/cis", ciHandler.Routes())
+			r.With(authMiddleware.RequireMethodPermission(auth.MethodPermissions{
+				http.MethodPost:   "ci:create",
+				http.MethodPut:    "ci:update",
+				http.MethodPatch:  "ci:update",
+				http.MethodDelete: "ci:delete",
+			}, "ci:read")).Mount("/cis", ciHandler.Routes())
 
 			// Relationship Management routes
-			r.Mount("/relationships", relationshipHandler.Routes())
+			r.With(authMiddleware.RequireMethodPermission(auth.MethodPermissions{
+				http.MethodPost:   "relationship:manage",
+				http.MethodPut:    "relationship:manage",
+				http.MethodPatch:  "relationship:manage",
+				http.MethodDelete: "relationship:manage",
+			}, "ci:read")).Mount("/relationships", relationshipHandler.Routes())
 
-			// Graph Service routes
-			r.Mount("/graph", graphHandler.Routes())
+			// Graph Service routes, which only read
+			r.With(authMiddleware.RequirePermission("ci:read")).Mount("/graph", graphHandler.Routes())
 
 			// User Management routes (admin only)
-			r.Group(func(r chi.Router) {
-				r.Use(auth.RequireRole("admin"))
-
-				// TODO: Add user management routes
-				// r.Get("/users", userHandler.ListUsers)
-				// r.Post("/users", userHandler.CreateUser)
-				// r.Get("/users/{id}", userHandler.GetUser)
-				// r.Put("/users/{id}", userHandler.UpdateUser)
-				// r.Delete("/users/{id}", userHandler.DeleteUser)
-				// r.Get("/users/{id}/roles", userHandler.GetUserRoles)
-				// r.Post("/users/{id}/roles", userHandler.AssignRole)
-				// r.Delete("/users/{id}/roles/{roleId}", userHandler.RevokeRole)
-			})
+			r.Mount("/users", userHandler.Routes())
 
 			// Role Management routes (admin only)
-			r.Group(func(r chi.Router) {
-				r.Use(auth.RequireRole("admin"))
-
-				// TODO: Add role management routes
-				// r.Get("/roles", roleHandler.ListRoles)
-				// r.Post("/roles", roleHandler.CreateRole)
-				// r.Get("/roles/{id}", roleHandler.GetRole)
-				// r.Put("/roles/{id}", roleHandler.UpdateRole)
-				// r.Delete("/roles/{id}", roleHandler.DeleteRole)
-				// r.Get("/roles/{id}/permissions", roleHandler.GetRolePermissions)
-				// r.Post("/roles/{id}/permissions", roleHandler.GrantPermission)
-				// r.Delete("/roles/{id}/permissions/{permissionId}", roleHandler.RevokePermission)
-			})
+			r.Mount("/roles", roleHandler.Routes())
 
 			// Permission Management routes (admin only)
-			r.Group(func(r chi.Router) {
-				r.Use(auth.RequireRole("admin"))
-
-				// TODO: Add permission management routes
-				// r.Get("/permissions", permissionHandler.ListPermissions)
-				// r.Post("/permissions", permissionHandler.CreatePermission)
-				// r.Get("/permissions/{id}", permissionHandler.GetPermission)
-				// r.Put("/permissions/{id}", permissionHandler.UpdatePermission)
-				// r.Delete("/permissions/{id}", permissionHandler.DeletePermission)
-			})
+			r.Mount("/permissions", permissionHandler.Routes())
+
+			// User offboarding routes (admin only)
+			r.Mount("/offboarding", offboardingHandler.Routes())
+
+			// CI purge routes for legal erasure (admin only)
+			r.Mount("/purges", purgeHandler.Routes())
+
+			// Alert rule routes over internal metrics (admin only)
+			r.Mount("/alert-rules", alertRuleHandler.Routes())
+
+			// Organization routes for multi-tenancy (admin only)
+			r.Mount("/organizations", organizationHandler.Routes())
+
+			// API key routes for machine-to-machine access
+			r.Mount("/apikeys", apiKeyHandler.Routes())
 		})
 	})
 
+	apiServer.SetFallback(router)
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      router,
+		Handler:      apiServer.Handler(),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
@@ -204,3 +332,37 @@ func main() {
 
 	appLogger.Info("Server stopped")
 }
+
+// newJWTService builds the JWT service from the configured signing keys, falling
+// back to HS256 with the single secret key when none are configured
+func newJWTService(cfg config.AuthConfig) (*auth.JWTService, error) {
+	if len(cfg.SigningKeys) == 0 {
+		return auth.NewJWTService(cfg.SecretKey, cfg.AccessTokenTTL, cfg.RefreshTokenTTL), nil
+	}
+
+	overlap := cfg.KeyOverlap
+	if overlap == 0 {
+		overlap = cfg.RefreshTokenTTL
+	}
+
+	var current *auth.SigningKey
+	var retired []*auth.SigningKey
+	for _, keyCfg := range cfg.SigningKeys {
+		key, err := auth.LoadSigningKey(keyCfg.ID, keyCfg.Algorithm, keyCfg.Secret, keyCfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if keyCfg.RetiredAt == nil {
+			current = key
+			continue
+		}
+		key.RetiredAt = *keyCfg.RetiredAt
+		retired = append(retired, key)
+	}
+
+	keys := auth.NewKeySet(current, overlap)
+	for _, key := range retired {
+		keys.AddRetired(key)
+	}
+	return auth.NewJWTServiceWithKeys(keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL), nil
+}
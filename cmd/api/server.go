@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+
+	"connect/internal/accessreview"
+	"connect/internal/alertrule"
+	"connect/internal/api"
+	"connect/internal/attrtrigger"
+	"connect/internal/auditlog"
+	"connect/internal/auth"
+	"connect/internal/autotag"
+	"connect/internal/billing"
+	"connect/internal/changestream"
+	"connect/internal/cialias"
+	"connect/internal/cisummary"
+	"connect/internal/cloudimport"
+	"connect/internal/config"
+	"connect/internal/conflictescalation"
+	"connect/internal/dashboard"
+	"connect/internal/database"
+	"connect/internal/featureflags"
+	"connect/internal/federation"
+	"connect/internal/forcesync"
+	"connect/internal/heartbeat"
+	"connect/internal/importjournal"
+	"connect/internal/incidentlearn"
+	"connect/internal/logtuning"
+	"connect/internal/maintenance"
+	"connect/internal/models"
+	"connect/internal/netflow"
+	"connect/internal/ownership"
+	"connect/internal/pathpolicy"
+	"connect/internal/payloadlog"
+	"connect/internal/quarantine"
+	"connect/internal/quota"
+	"connect/internal/repositories"
+	"connect/internal/residency"
+	"connect/internal/resync"
+	"connect/internal/schemacheck"
+	"connect/internal/scripthooks"
+	"connect/internal/serviceaccount"
+	"connect/internal/servicetree"
+	"connect/internal/sessionlimits"
+	"connect/internal/suggest"
+	"connect/internal/suggestion"
+	"connect/internal/sync"
+	"connect/internal/syncexclusion"
+	"connect/internal/syncoverview"
+	"connect/internal/typemigration"
+	"connect/internal/velocity"
+	"connect/internal/visibility"
+	"connect/internal/webhooks"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// serverDeps are the services shared by the API server and the routes served
+// outside it
+type serverDeps struct {
+	cfg             *config.Config
+	dbManager       *database.Manager
+	redisClient     *database.RedisClient // nil when Redis is unavailable
+	ciRepository    *repositories.CIRepository
+	authMiddleware  *auth.AuthMiddleware
+	permissions     *auth.CachedPermissionResolver
+	serviceAccounts *serviceaccount.Service
+	alertRules      *alertrule.Service
+	schemaChecker   *schemacheck.Checker
+	visibility      *visibility.Resolver
+}
+
+// newAPIServer builds the API server with every feature enabled. Features
+// backed by sync are only enabled when Redis is available, since sync needs
+// it to queue events.
+func newAPIServer(deps serverDeps) (*api.Server, error) {
+	cfg := deps.cfg
+	db := deps.dbManager.Postgres
+
+	server := api.NewServer(cfg, deps.ciRepository)
+	// Authentication first, so that the middleware of the features below
+	// sees the caller
+	server.EnableAuthentication(deps.authMiddleware)
+
+	// Caches are only set when Redis is available; the services load from
+	// their stores without them
+	var flagCache featureflags.Cache
+	var suggestCache suggest.Cache
+	if deps.redisClient != nil {
+		flagCache = deps.redisClient
+		suggestCache = deps.redisClient
+	}
+
+	server.EnableFeatureFlags(featureflags.NewService(featureflags.NewPostgresStore(db), flagCache, cfg.Environment, cfg.FeatureFlags.CacheTTL))
+	server.EnableMaintenanceMode(maintenance.NewService(maintenance.NewPostgresStore(db), cfg.Maintenance.RefreshInterval, cfg.Maintenance.ExemptPaths))
+	server.EnableSchemaHealth(deps.schemaChecker)
+	server.EnablePayloadLogging(payloadlog.NewService(deps.ciRepository, log.Logger, cfg.PayloadLog.MaxBodyBytes, cfg.PayloadLog.RedactKeys))
+	server.EnableAccessReview(accessreview.NewService(accessreview.NewPostgresDirectory(db), accessreview.NewPostgresReportStore(db)))
+	server.EnableSessionLimits(sessionlimits.NewService(sessionlimits.NewPostgresStore(db), sessionlimits.Policy{
+		Default: cfg.Auth.SessionLimits.Default,
+		Roles:   cfg.Auth.SessionLimits.Roles,
+		Tenants: cfg.Auth.SessionLimits.Tenants,
+	}, sessionlimits.DefaultRefreshInterval))
+	server.EnableLogTuning(logtuning.NewService(logtuning.NewPostgresStore(db), log.Logger, logtuning.Settings{
+		SlowQueryThresholdMS: cfg.Logging.SlowQueryThreshold.Milliseconds(),
+		DebugSampleRate:      cfg.Logging.DebugSampleRate,
+		SampleRates:          cfg.Logging.SampleRates,
+	}))
+	server.EnableOwnershipTransfers(ownership.NewService(ownership.NewPostgresStore(db), ownership.LogNotifier{}, cfg.Ownership.DefaultDeadline, cfg.Ownership.MaxDeadline))
+	server.EnableAutoTagging(autotag.NewService(autotag.NewPostgresStore(db), autotag.DefaultCacheTTL))
+	server.EnableTypeMigrations(typemigration.NewService(typemigration.NewPostgresStore(db)))
+	server.EnableScriptHooks(scripthooks.NewService(scripthooks.NewPostgresStore(db), scripthooks.DefaultCacheTTL))
+	server.EnableAttributeTriggers(attrtrigger.NewService(attrtrigger.NewPostgresStore(db), attrtrigger.LogNotifier{}, attrtrigger.DefaultCacheTTL))
+	server.EnableRelationshipPathRules(pathpolicy.NewService(pathpolicy.NewPostgresStore(db), pathpolicy.DefaultCacheTTL))
+	server.EnableBilling(billing.NewService(billing.NewPostgresStore(db), cfg.Billing.ResourceAttribute, billingConnectors(cfg.Billing.Connectors)))
+	server.EnableFederation(federation.NewService(federationSources(cfg.Federation.Sources)))
+
+	var archiver auditlog.Archiver
+	if cfg.Audit.ArchivePath != "" {
+		archiver = auditlog.NewDirArchiver(cfg.Audit.ArchivePath)
+	}
+	server.EnableAuditLog(auditlog.NewService(auditlog.NewPostgresStore(db), archiver))
+
+	server.EnableAnomalyDetection(velocity.NewPostgresStore(db), velocity.LogNotifier{})
+	server.EnableSummaryCache(cisummary.NewPostgresEventSource(db))
+	server.EnableImportRollback(importjournal.NewPostgresStore(db))
+	server.EnableEdgeCleanup()
+	server.EnableQuotas(quota.NewPostgresStore(db), quota.LogNotifier{})
+	server.EnableQuarantine(quarantine.NewPostgresStore(db))
+	server.EnableDashboards(dashboard.NewPostgresStore(db))
+	server.EnableFlowIngestion(netflow.NewPostgresStore(db))
+	server.EnableServiceAccounts(deps.serviceAccounts)
+	server.EnableArchive()
+	server.EnableHeartbeats(heartbeat.LogNotifier{})
+	server.EnableMetrics()
+	server.EnableCloudImport(cloudimport.NewPostgresStore(db))
+	server.EnableCorrectionSuggestions(suggestion.NewService(suggestion.NewPostgresStore(db), deps.permissions, suggestion.LogNotifier{}))
+	server.EnableCIAliases(cialias.NewPostgresStore(db))
+	server.EnableIncidentLearning(incidentlearn.NewPostgresStore(db))
+	server.EnableWebhooks(webhooks.NewPostgresStore(db))
+	server.EnableSearchSuggest(suggest.NewService(deps.ciRepository, suggestCache, suggest.DefaultCacheTTL))
+	server.EnablePublicCatalog()
+
+	server.EnableVisibilityFiltering(deps.visibility)
+	server.EnablePermissionChecks(deps.visibility)
+
+	shards, err := residency.Open(cfg.GetShardConnectionStrings(), cfg.Database.PostgreSQL.MaxOpenConns, cfg.Database.PostgreSQL.MaxIdleConns)
+	if err != nil {
+		return nil, err
+	}
+	router, err := residency.NewRouter(db, shards, cfg.Residency.Tenants, residency.NewPostgresStore(db))
+	if err != nil {
+		return nil, err
+	}
+	server.EnableResidency(router)
+
+	var excludedTypes, excludedTenants []string
+	if cfg.Sync != nil {
+		excludedTypes, excludedTenants = cfg.Sync.ExcludedEntityTypes, cfg.Sync.ExcludedTenants
+	}
+	exclusions := syncexclusion.NewService(syncexclusion.NewPostgresStore(db), excludedTypes, excludedTenants, syncexclusion.DefaultCacheTTL)
+	server.EnableSyncExclusions(exclusions)
+
+	serviceTree := servicetree.NewService(servicetree.NewPostgresStore(db), models.MaxImpactDepth)
+	server.EnableServiceTree(serviceTree)
+	hub := changestream.NewHub(changestream.Options{
+		BufferSize: cfg.EventStream.BufferSize,
+		ReplaySize: cfg.EventStream.ReplaySize,
+	})
+	server.EnableEventStream(hub)
+
+	if deps.redisClient != nil {
+		if err := enableSync(server, deps, exclusions, serviceTree, hub); err != nil {
+			return nil, err
+		}
+	} else {
+		log.Warn().Msg("Redis is unavailable, sync and the features backed by it are disabled")
+		server.EnableSyncOverview(syncoverview.NewPostgresStore(db), nil)
+		server.EnableConflictEscalation(conflictescalation.NewPostgresStore(db), nil, conflictescalation.LogNotifier{})
+	}
+
+	// Last, so that every response, including those of the middleware
+	// above, is formatted
+	server.EnableResponseFormats()
+	return server, nil
+}
+
+// enableSync starts the sync service, holding back the excluded events and
+// applying the processed ones to the projections, and enables the features
+// backed by it
+func enableSync(server *api.Server, deps serverDeps, exclusions sync.EventGate, projections ...sync.Projection) error {
+	cfg := deps.cfg
+	db := deps.dbManager.Postgres
+
+	syncService, err := sync.NewSyncService(cfg, deps.dbManager, deps.redisClient, &log.Logger)
+	if err != nil {
+		return err
+	}
+	syncService.SetExclusions(exclusions)
+	syncService.SetProjections(projections...)
+
+	conflicts := sync.NewConflictResolver(deps.dbManager, sync.ResolutionTimestamp, &log.Logger)
+	monitor := sync.NewMonitor(deps.dbManager, syncService, conflicts, &log.Logger)
+	monitor.SetAlertRules(deps.alertRules)
+	go monitor.StartMonitoring(context.Background())
+
+	server.EnableBackpressure(syncService)
+	server.EnableResync(resync.NewService(resync.NewPostgresStore(db), syncService, cfg.Resync.BatchSize, cfg.Resync.RateLimit))
+	server.EnableForceSync(forcesync.NewService(forcesync.NewPostgresStore(db), syncService, cfg.ForceSync.BatchSize, cfg.ForceSync.RateLimit, cfg.ForceSync.MaxJobs))
+	server.EnableSyncOverview(syncoverview.NewPostgresStore(db), monitor)
+	server.EnableConflictEscalation(conflictescalation.NewPostgresStore(db), func(ctx context.Context, conflictID uuid.UUID, resolvedBy string) error {
+		return conflicts.ResolveConflict(ctx, conflictID.String(), sync.ResolutionTimestamp, resolvedBy)
+	}, conflictescalation.LogNotifier{})
+	return nil
+}
+
+// billingConnectors converts the configured billing connectors, skipping the
+// invalid ones
+func billingConnectors(configs []config.BillingConnectorConfig) []billing.Connector {
+	connectors := make([]billing.Connector, 0, len(configs))
+	for _, cfg := range configs {
+		connector := billing.Connector{
+			Name:     cfg.Name,
+			Provider: cfg.Provider,
+			Path:     cfg.Path,
+			Pattern:  cfg.Pattern,
+		}
+		if err := connector.Validate(); err != nil {
+			log.Warn().Err(err).Msg("Billing connector skipped")
+			continue
+		}
+		connectors = append(connectors, connector)
+	}
+	return connectors
+}
+
+// federationSources converts the configured federation sources, skipping the
+// invalid ones
+func federationSources(configs []config.FederationSourceConfig) []federation.Source {
+	sources := make([]federation.Source, 0, len(configs))
+	for _, cfg := range configs {
+		source := federation.Source{
+			Name:        cfg.Name,
+			Type:        cfg.Type,
+			URL:         cfg.URL,
+			ItemsPath:   cfg.ItemsPath,
+			IDField:     cfg.IDField,
+			NameField:   cfg.NameField,
+			StatusField: cfg.StatusField,
+			Attributes:  cfg.Attributes,
+			Headers:     cfg.Headers,
+			Timeout:     cfg.Timeout,
+			CacheTTL:    cfg.CacheTTL,
+		}
+		if err := source.Validate(); err != nil {
+			log.Warn().Err(err).Msg("Federation source skipped")
+			continue
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"connect/internal/manifest"
+)
+
+// runApply implements `conxctl apply`: the manifest is planned by the server,
+// the plan printed, and then applied unless it is a dry run
+func runApply(args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := flags.String("f", "", "manifest file to apply ('-' for stdin)")
+	server := flags.String("server", firstEnv("CONX_SERVER", "http://localhost:8080"), "conx API address")
+	token := flags.String("token", os.Getenv("CONX_TOKEN"), "API bearer token")
+	dryRun := flags.Bool("dry-run", false, "print the plan without applying it")
+	prune := flags.Bool("prune", false, "delete the CIs and relationships the manifest created before but no longer declares")
+	noCreate := flags.Bool("no-create", false, "do not create missing entries")
+	noUpdate := flags.Bool("no-update", false, "do not update existing entries")
+	flags.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("a manifest file is required (-f)")
+	}
+	data, err := readManifestFile(*file)
+	if err != nil {
+		return err
+	}
+	// Invalid manifests are reported before anything is sent
+	if _, err := manifest.Parse(data); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("create", strconv.FormatBool(!*noCreate))
+	query.Set("update", strconv.FormatBool(!*noUpdate))
+	query.Set("prune", strconv.FormatBool(*prune))
+	client := &manifestClient{server: *server, token: *token, http: &http.Client{Timeout: 5 * time.Minute}}
+
+	var plan manifest.Plan
+	if err := client.post("plan", query, data, &plan); err != nil {
+		return err
+	}
+	if err := plan.Write(os.Stdout); err != nil {
+		return err
+	}
+	if plan.HasConflicts() {
+		return fmt.Errorf("the plan has %d conflicts; nothing was applied", plan.Summary.Conflict)
+	}
+	if *dryRun || plan.Summary.Create+plan.Summary.Update+plan.Summary.Delete == 0 {
+		return nil
+	}
+
+	var result manifest.Result
+	if err := client.post("apply", query, data, &result); err != nil {
+		return err
+	}
+	fmt.Printf("Applied: %d created, %d updated, %d deleted.\n", result.Applied.Create, result.Applied.Update, result.Applied.Delete)
+	for _, failure := range result.Failed {
+		fmt.Fprintf(os.Stderr, "  failed %s %s: %s\n", failure.Kind, failure.Key, failure.Error)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d changes failed", len(result.Failed))
+	}
+	return nil
+}
+
+// readManifestFile reads a manifest from a file or stdin
+func readManifestFile(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(io.LimitReader(os.Stdin, manifest.MaxBytes+1))
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return data, nil
+}
+
+// manifestClient calls the manifest endpoints of the API
+type manifestClient struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// post sends the manifest to an endpoint and decodes the response into out.
+// An apply refused for conflicts is decoded as well as reported.
+func (c *manifestClient) post(endpoint string, query url.Values, data []byte, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, c.server+"/api/v1/manifests/"+endpoint+"?"+query.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/yaml")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", c.server, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		var apiErr struct {
+			Error   string `json:"error"`
+			Details string `json:"details"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s: %s", apiErr.Error, apiErr.Details)
+		}
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", endpoint, err)
+	}
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("the plan changed and now has conflicts; nothing was applied")
+	}
+	return nil
+}
+
+// firstEnv returns the value of an environment variable, or def if it is unset
+func firstEnv(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
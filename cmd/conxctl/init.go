@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"connect/internal/auth"
+	"connect/internal/bootstrap"
+	"connect/internal/config"
+	"github.com/jmoiron/sqlx"
+)
+
+// runInit implements `conxctl init`: an empty database is given its admin
+// user, default roles and permissions and starter schemas. Running it again
+// on an initialized database changes nothing.
+func runInit(args []string) error {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	username := flags.String("username", "admin", "username of the initial admin user")
+	email := flags.String("email", "", "email of the initial admin user")
+	password := flags.String("password", os.Getenv("CONX_ADMIN_PASSWORD"), "password of the initial admin user (defaults to $CONX_ADMIN_PASSWORD)")
+	flags.Parse(args)
+
+	if *email == "" {
+		return fmt.Errorf("an admin email is required (-email)")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := sqlx.Connect("postgres", cfg.GetPostgreSQLConnectionString())
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer db.Close()
+
+	service := bootstrap.NewService(bootstrap.NewPostgresStore(db), auth.NewPasswordService(auth.DefaultPasswordConfig()))
+	result, err := service.Initialize(context.Background(), bootstrap.Admin{Username: *username, Email: *email, Password: *password})
+	if errors.Is(err, bootstrap.ErrAlreadyInitialized) {
+		fmt.Fprintln(os.Stderr, "The database is already initialized; nothing was changed.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Initialized: admin user %s created, %d roles, %d permissions and %d schemas created\n",
+		result.Admin, result.RolesCreated, result.PermissionsCreated, result.SchemasCreated)
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"connect/internal/config"
+	"connect/internal/repositories"
+	"connect/internal/seeder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+const usage = `conxctl is the conx CMDB administration tool.
+
+Usage:
+  conxctl <command> [flags]
+
+Commands:
+  init    Create the initial admin user, default roles and starter schemas in an
+          empty database (conxctl init -email admin@example.com)
+  seed    Generate a deterministic demo dataset and load it into PostgreSQL
+  apply   Plan and apply a declarative manifest of CIs, relationships and schemas
+          (conxctl apply -f manifest.yaml [--dry-run] [--prune])
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "init":
+		err = runInit(os.Args[2:])
+	case "seed":
+		err = runSeed(os.Args[2:])
+	case "apply":
+		err = runApply(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runSeed implements `conxctl seed`
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	profileName := flags.String("profile", "demo", "dataset profile: "+strings.Join(seeder.ProfileNames(), "|"))
+	seed := flags.Int64("seed", 1, "random seed; the same profile and seed always produce the same dataset")
+	output := flags.String("output", "", "write the dataset as JSON to this file instead of loading it ('-' for stdout)")
+	flags.Parse(args)
+
+	profile, err := seeder.GetProfile(*profileName)
+	if err != nil {
+		return err
+	}
+
+	dataset := seeder.Generate(profile, *seed)
+	fmt.Fprintf(os.Stderr, "Generated %s dataset (seed %d): %d CIs, %d relationships, %d schemas\n",
+		profile.Name, *seed, len(dataset.CIs), len(dataset.Relationships), len(dataset.CISchemas)+len(dataset.RelationshipSchemas))
+
+	if *output != "" {
+		return writeDataset(dataset, *output)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := sqlx.Connect("postgres", cfg.GetPostgreSQLConnectionString())
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer db.Close()
+
+	stats, err := seeder.Load(context.Background(), repositories.NewCIRepository(db), dataset, func(s seeder.LoadStats) {
+		fmt.Fprintf(os.Stderr, "  schemas: %d, CIs: %d, relationships: %d, skipped: %d\n",
+			s.SchemasCreated, s.CIsCreated, s.RelationshipsCreated, s.Skipped)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Seeding completed: %d CIs and %d relationships created, %d existing records skipped\n",
+		stats.CIsCreated, stats.RelationshipsCreated, stats.Skipped)
+	return nil
+}
+
+// writeDataset writes the dataset as indented JSON
+func writeDataset(dataset *seeder.Dataset, path string) error {
+	out := os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dataset); err != nil {
+		return fmt.Errorf("failed to write dataset: %w", err)
+	}
+	return nil
+}
@@ -245,6 +245,10 @@ export default {
     return api.delete(`/cis/${id}`)
   },
   
+  async getCIDeletionPreview(id) {
+    return api.get(`/cis/${id}/delete-preview`)
+  },
+  
   // Relationship endpoints
   async getRelationships(params = {}) {
     return api.get('/relationships', { params })
@@ -0,0 +1,187 @@
+// Package accessreview produces the users × effective permissions matrix that
+// auditors review each quarter. Permissions are resolved through the roles
+// assigned to each user and the roles those roles inherit from. Reports are
+// generated in the background and kept so past reviews can be retrieved.
+package accessreview
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// InheritanceSeparator joins the roles of an inherited grant, e.g. "ci_manager > viewer"
+const InheritanceSeparator = " > "
+
+// UserRoles is a user together with the names of the roles assigned to them
+type UserRoles struct {
+	ID       uuid.UUID `db:"id"`
+	Username string    `db:"username"`
+	Email    string    `db:"email"`
+	IsActive bool      `db:"is_active"`
+	Roles    []string  `db:"-"`
+}
+
+// RoleGrant describes what a role grants directly and which roles it inherits from
+type RoleGrant struct {
+	Name        string
+	IsActive    bool
+	Permissions []string
+	Inherits    []string
+}
+
+// Grant is an effective permission and the role paths granting it
+type Grant struct {
+	Permission string   `json:"permission"`
+	Via        []string `json:"via"`
+}
+
+// Entry is a row of the matrix
+type Entry struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	IsActive    bool      `json:"is_active"`
+	Roles       []string  `json:"roles"`
+	Permissions []Grant   `json:"permissions"`
+}
+
+// Matrix is the result of an access review
+type Matrix struct {
+	Permissions []string `json:"permissions"`
+	Entries     []Entry  `json:"entries"`
+}
+
+// Report is an access review run
+type Report struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Status      string     `json:"status" db:"status"`
+	RequestedBy string     `json:"requested_by" db:"requested_by"`
+	RequestedAt time.Time  `json:"requested_at" db:"requested_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	Error       string     `json:"error,omitempty" db:"error"`
+	UserCount   int        `json:"user_count" db:"user_count"`
+	Matrix      *Matrix    `json:"matrix,omitempty" db:"-"`
+}
+
+// Resolver computes effective permissions from role grants
+type Resolver struct {
+	roles map[string]RoleGrant
+	cache map[string]map[string][]string
+}
+
+// NewResolver creates a resolver over the given roles
+func NewResolver(roles []RoleGrant) *Resolver {
+	byName := make(map[string]RoleGrant, len(roles))
+	for _, role := range roles {
+		byName[role.Name] = role
+	}
+	return &Resolver{roles: byName, cache: make(map[string]map[string][]string)}
+}
+
+// Permissions returns the sorted names of all permissions any role grants
+func (r *Resolver) Permissions() []string {
+	seen := make(map[string]bool)
+	for name := range r.roles {
+		for permission := range r.rolePermissions(name) {
+			seen[permission] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// Entry resolves the effective permissions of a user
+func (r *Resolver) Entry(user UserRoles) Entry {
+	via := make(map[string][]string)
+	for _, role := range user.Roles {
+		for permission, paths := range r.rolePermissions(role) {
+			via[permission] = append(via[permission], paths...)
+		}
+	}
+
+	grants := make([]Grant, 0, len(via))
+	for _, permission := range sortedKeys(via) {
+		paths := via[permission]
+		sort.Strings(paths)
+		grants = append(grants, Grant{Permission: permission, Via: paths})
+	}
+
+	roles := append([]string{}, user.Roles...)
+	sort.Strings(roles)
+
+	return Entry{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		IsActive:    user.IsActive,
+		Roles:       roles,
+		Permissions: grants,
+	}
+}
+
+// rolePermissions maps each permission a role grants, directly or inherited, to the role paths granting it
+func (r *Resolver) rolePermissions(name string) map[string][]string {
+	if cached, ok := r.cache[name]; ok {
+		return cached
+	}
+	result := make(map[string][]string)
+	r.collect(name, []string{name}, map[string]bool{}, result)
+	r.cache[name] = result
+	return result
+}
+
+// collect walks the inheritance graph depth first; visited guards against cycles
+func (r *Resolver) collect(name string, path []string, visited map[string]bool, result map[string][]string) {
+	role, ok := r.roles[name]
+	if !ok || !role.IsActive || visited[name] {
+		return
+	}
+	visited[name] = true
+	defer delete(visited, name)
+
+	via := strings.Join(path, InheritanceSeparator)
+	for _, permission := range role.Permissions {
+		result[permission] = appendUnique(result[permission], via)
+	}
+	for _, parent := range role.Inherits {
+		r.collect(parent, append(append([]string{}, path...), parent), visited, result)
+	}
+}
+
+// Build resolves the matrix for a set of users
+func Build(users []UserRoles, roles []RoleGrant) *Matrix {
+	resolver := NewResolver(roles)
+	matrix := &Matrix{Permissions: resolver.Permissions(), Entries: make([]Entry, 0, len(users))}
+	for _, user := range users {
+		matrix.Entries = append(matrix.Entries, resolver.Entry(user))
+	}
+	return matrix
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package accessreview
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryDirectory struct {
+	users []UserRoles
+	roles []RoleGrant
+}
+
+func (d *memoryDirectory) ListUsers(ctx context.Context, after uuid.UUID, limit int) ([]UserRoles, error) {
+	var page []UserRoles
+	for _, user := range d.users {
+		if user.ID.String() > after.String() && len(page) < limit {
+			page = append(page, user)
+		}
+	}
+	return page, nil
+}
+
+func (d *memoryDirectory) ListRoleGrants(ctx context.Context) ([]RoleGrant, error) {
+	return d.roles, nil
+}
+
+type memoryReports struct {
+	mu      sync.Mutex
+	reports map[uuid.UUID]Report
+}
+
+func (s *memoryReports) CreateReport(ctx context.Context, report *Report) error {
+	return s.UpdateReport(ctx, report)
+}
+
+func (s *memoryReports) UpdateReport(ctx context.Context, report *Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports[report.ID] = *report
+	return nil
+}
+
+func (s *memoryReports) GetReport(ctx context.Context, id uuid.UUID) (*Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report, ok := s.reports[id]
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+	return &report, nil
+}
+
+func (s *memoryReports) ListReports(ctx context.Context, limit int) ([]*Report, error) {
+	return nil, nil
+}
+
+func testRoles() []RoleGrant {
+	return []RoleGrant{
+		{Name: "viewer", IsActive: true, Permissions: []string{"ci:read"}},
+		{Name: "ci_manager", IsActive: true, Permissions: []string{"ci:update"}, Inherits: []string{"viewer"}},
+		{Name: "admin", IsActive: true, Permissions: []string{"user:manage"}, Inherits: []string{"ci_manager", "admin"}},
+		{Name: "retired", IsActive: false, Permissions: []string{"import:csv"}},
+	}
+}
+
+func sortedUUIDs(n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.MustParse("00000000-0000-0000-0000-00000000000" + string(rune('1'+i)))
+	}
+	return ids
+}
+
+func TestResolver_Inheritance(t *testing.T) {
+	resolver := NewResolver(testRoles())
+	// retired is inactive, so import:csv is granted by no role
+	assert.Equal(t, []string{"ci:read", "ci:update", "user:manage"}, resolver.Permissions())
+
+	entry := resolver.Entry(UserRoles{Username: "alice", Roles: []string{"admin", "viewer", "retired"}})
+	assert.Equal(t, []string{"admin", "retired", "viewer"}, entry.Roles)
+	assert.Equal(t, []Grant{
+		{Permission: "ci:read", Via: []string{"admin > ci_manager > viewer", "viewer"}},
+		{Permission: "ci:update", Via: []string{"admin > ci_manager"}},
+		{Permission: "user:manage", Via: []string{"admin"}},
+	}, entry.Permissions)
+}
+
+func TestService_GenerateAndCSV(t *testing.T) {
+	ids := sortedUUIDs(3)
+	directory := &memoryDirectory{
+		users: []UserRoles{
+			{ID: ids[0], Username: "alice", Email: "alice@example.com", IsActive: true, Roles: []string{"ci_manager"}},
+			{ID: ids[1], Username: "bob", Email: "bob@example.com", IsActive: false, Roles: []string{"viewer"}},
+			{ID: ids[2], Username: "carol", Email: "carol@example.com", IsActive: true},
+		},
+		roles: testRoles(),
+	}
+	reports := &memoryReports{reports: map[uuid.UUID]Report{}}
+	service := NewService(directory, reports)
+	service.pageSize = 2
+
+	report, err := service.Request(context.Background(), "auditor")
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, report.Status)
+
+	var stored *Report
+	require.Eventually(t, func() bool {
+		stored, err = service.Get(context.Background(), report.ID)
+		return err == nil && stored.Status == StatusCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.NotNil(t, stored.Matrix)
+	assert.Equal(t, 3, stored.UserCount)
+	assert.Equal(t, []string{"ci:read", "ci:update", "user:manage"}, stored.Matrix.Permissions)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, stored.Matrix))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{"user_id", "username", "email", "active", "roles", "ci:read", "ci:update", "user:manage"}, rows[0])
+	assert.Equal(t, []string{ids[0].String(), "alice", "alice@example.com", "true", "ci_manager", "ci_manager > viewer", "ci_manager", ""}, rows[1])
+	assert.Equal(t, []string{ids[1].String(), "bob", "bob@example.com", "false", "viewer", "viewer", "", ""}, rows[2])
+	assert.Equal(t, []string{ids[2].String(), "carol", "carol@example.com", "true", "", "", "", ""}, rows[3])
+}
@@ -0,0 +1,161 @@
+package accessreview
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Generation defaults
+const (
+	DefaultPageSize = 500
+	DefaultTimeout  = 30 * time.Minute
+)
+
+// Service generates access review reports in the background
+type Service struct {
+	directory Directory
+	reports   ReportStore
+	pageSize  int
+	timeout   time.Duration
+	now       func() time.Time
+}
+
+// NewService creates a new access review service
+func NewService(directory Directory, reports ReportStore) *Service {
+	return &Service{
+		directory: directory,
+		reports:   reports,
+		pageSize:  DefaultPageSize,
+		timeout:   DefaultTimeout,
+		now:       time.Now,
+	}
+}
+
+// Request records a pending report and starts generating it. The returned report
+// is pending; poll Get until it is completed or failed.
+func (s *Service) Request(ctx context.Context, requestedBy string) (*Report, error) {
+	report := &Report{
+		ID:          uuid.New(),
+		Status:      StatusPending,
+		RequestedBy: requestedBy,
+		RequestedAt: s.now(),
+	}
+	if err := s.reports.CreateReport(ctx, report); err != nil {
+		return nil, err
+	}
+
+	generated := *report
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		s.Generate(ctx, &generated)
+	}()
+
+	return report, nil
+}
+
+// Generate builds the matrix of a report and stores the outcome
+func (s *Service) Generate(ctx context.Context, report *Report) {
+	report.Status = StatusRunning
+	if err := s.reports.UpdateReport(ctx, report); err != nil {
+		log.Printf("Failed to mark access review %s as running: %v", report.ID, err)
+	}
+
+	matrix, err := s.build(ctx)
+	completedAt := s.now()
+	report.CompletedAt = &completedAt
+	if err != nil {
+		report.Status = StatusFailed
+		report.Error = err.Error()
+	} else {
+		report.Status = StatusCompleted
+		report.Matrix = matrix
+		report.UserCount = len(matrix.Entries)
+	}
+
+	if err := s.reports.UpdateReport(ctx, report); err != nil {
+		log.Printf("Failed to store access review %s: %v", report.ID, err)
+	}
+}
+
+// build reads users page by page and resolves their permissions
+func (s *Service) build(ctx context.Context) (*Matrix, error) {
+	roles, err := s.directory.ListRoleGrants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolver := NewResolver(roles)
+
+	matrix := &Matrix{Permissions: resolver.Permissions(), Entries: []Entry{}}
+	after := uuid.Nil
+	for {
+		users, err := s.directory.ListUsers(ctx, after, s.pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			matrix.Entries = append(matrix.Entries, resolver.Entry(user))
+		}
+		if len(users) < s.pageSize {
+			return matrix, nil
+		}
+		after = users[len(users)-1].ID
+	}
+}
+
+// Get retrieves a report
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Report, error) {
+	return s.reports.GetReport(ctx, id)
+}
+
+// List retrieves recent reports without their matrices
+func (s *Service) List(ctx context.Context, limit int) ([]*Report, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.reports.ListReports(ctx, limit)
+}
+
+// WriteCSV writes a matrix with one row per user and one column per permission.
+// A granted cell lists the role paths granting the permission; an empty cell means not granted.
+func WriteCSV(w io.Writer, matrix *Matrix) error {
+	writer := csv.NewWriter(w)
+
+	header := append([]string{"user_id", "username", "email", "active", "roles"}, matrix.Permissions...)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	column := make(map[string]int, len(matrix.Permissions))
+	for i, permission := range matrix.Permissions {
+		column[permission] = 5 + i
+	}
+
+	for _, entry := range matrix.Entries {
+		row := make([]string, len(header))
+		row[0] = entry.UserID.String()
+		row[1] = entry.Username
+		row[2] = entry.Email
+		row[3] = strconv.FormatBool(entry.IsActive)
+		row[4] = strings.Join(entry.Roles, ";")
+		for _, grant := range entry.Permissions {
+			if i, ok := column[grant.Permission]; ok {
+				row[i] = strings.Join(grant.Via, ";")
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,207 @@
+package accessreview
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrReportNotFound is returned when a report does not exist
+var ErrReportNotFound = errors.New("access review report not found")
+
+// Directory reads users, their role assignments and the role graph
+type Directory interface {
+	// ListUsers returns up to limit users with IDs greater than after, ordered by ID
+	ListUsers(ctx context.Context, after uuid.UUID, limit int) ([]UserRoles, error)
+	ListRoleGrants(ctx context.Context) ([]RoleGrant, error)
+}
+
+// ReportStore persists reports
+type ReportStore interface {
+	CreateReport(ctx context.Context, report *Report) error
+	UpdateReport(ctx context.Context, report *Report) error
+	GetReport(ctx context.Context, id uuid.UUID) (*Report, error)
+	ListReports(ctx context.Context, limit int) ([]*Report, error)
+}
+
+// PostgresDirectory reads the users, user_roles, roles, role_permissions and role_inheritance tables
+type PostgresDirectory struct {
+	db *sqlx.DB
+}
+
+// NewPostgresDirectory creates a new Postgres backed directory
+func NewPostgresDirectory(db *sqlx.DB) *PostgresDirectory {
+	return &PostgresDirectory{db: db}
+}
+
+// ListUsers retrieves a page of users with their role names
+func (d *PostgresDirectory) ListUsers(ctx context.Context, after uuid.UUID, limit int) ([]UserRoles, error) {
+	query := `
+		SELECT u.id, u.username, u.email, COALESCE(u.is_active, true) AS is_active,
+		       COALESCE(array_agg(r.name ORDER BY r.name) FILTER (WHERE r.name IS NOT NULL), '{}') AS roles
+		FROM users u
+		LEFT JOIN user_roles ur ON ur.user_id = u.id
+		LEFT JOIN roles r ON r.id = ur.role_id
+		WHERE u.id > $1
+		GROUP BY u.id, u.username, u.email, u.is_active
+		ORDER BY u.id
+		LIMIT $2`
+
+	rows, err := d.db.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []UserRoles
+	for rows.Next() {
+		var user UserRoles
+		var roles pq.StringArray
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.IsActive, &roles); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.Roles = roles
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, nil
+}
+
+// ListRoleGrants retrieves every role with its direct permissions and inherited roles
+func (d *PostgresDirectory) ListRoleGrants(ctx context.Context) ([]RoleGrant, error) {
+	query := `
+		SELECT r.name,
+		       COALESCE((SELECT array_agg(p.name ORDER BY p.name)
+		                 FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id
+		                 WHERE rp.role_id = r.id), '{}') AS permissions,
+		       COALESCE((SELECT array_agg(parent.name ORDER BY parent.name)
+		                 FROM role_inheritance ri JOIN roles parent ON parent.id = ri.inherits_role_id
+		                 WHERE ri.role_id = r.id), '{}') AS inherits
+		FROM roles r
+		ORDER BY r.name`
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role grants: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []RoleGrant
+	for rows.Next() {
+		var permissions, inherits pq.StringArray
+		// Roles carry no activity flag in the base schema, so every stored role grants its permissions
+		role := RoleGrant{IsActive: true}
+		if err := rows.Scan(&role.Name, &permissions, &inherits); err != nil {
+			return nil, fmt.Errorf("failed to scan role grant: %w", err)
+		}
+		role.Permissions = permissions
+		role.Inherits = inherits
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list role grants: %w", err)
+	}
+
+	return roles, nil
+}
+
+// PostgresReportStore stores reports in the access_review_reports table
+type PostgresReportStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresReportStore creates a new Postgres backed report store
+func NewPostgresReportStore(db *sqlx.DB) *PostgresReportStore {
+	return &PostgresReportStore{db: db}
+}
+
+// CreateReport inserts a new report
+func (s *PostgresReportStore) CreateReport(ctx context.Context, report *Report) error {
+	query := `
+		INSERT INTO access_review_reports (id, status, requested_by, requested_at, user_count)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := s.db.ExecContext(ctx, query, report.ID, report.Status, report.RequestedBy, report.RequestedAt, report.UserCount); err != nil {
+		return fmt.Errorf("failed to create access review report: %w", err)
+	}
+	return nil
+}
+
+// UpdateReport stores the status and, once completed, the matrix of a report
+func (s *PostgresReportStore) UpdateReport(ctx context.Context, report *Report) error {
+	var matrix []byte
+	if report.Matrix != nil {
+		var err error
+		if matrix, err = json.Marshal(report.Matrix); err != nil {
+			return fmt.Errorf("failed to marshal access review matrix: %w", err)
+		}
+	}
+
+	query := `
+		UPDATE access_review_reports
+		SET status = $2, completed_at = $3, error = $4, user_count = $5, matrix = $6
+		WHERE id = $1`
+
+	result, err := s.db.ExecContext(ctx, query, report.ID, report.Status, report.CompletedAt, report.Error, report.UserCount, matrix)
+	if err != nil {
+		return fmt.Errorf("failed to update access review report: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrReportNotFound
+	}
+	return nil
+}
+
+// GetReport retrieves a report including its matrix
+func (s *PostgresReportStore) GetReport(ctx context.Context, id uuid.UUID) (*Report, error) {
+	query := `
+		SELECT id, status, requested_by, requested_at, completed_at, COALESCE(error, '') AS error, user_count, matrix
+		FROM access_review_reports
+		WHERE id = $1`
+
+	var report Report
+	var matrix []byte
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&report.ID, &report.Status, &report.RequestedBy, &report.RequestedAt,
+		&report.CompletedAt, &report.Error, &report.UserCount, &matrix,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to get access review report: %w", err)
+	}
+
+	if len(matrix) > 0 {
+		report.Matrix = &Matrix{}
+		if err := json.Unmarshal(matrix, report.Matrix); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal access review matrix: %w", err)
+		}
+	}
+
+	return &report, nil
+}
+
+// ListReports retrieves the most recent reports without their matrices
+func (s *PostgresReportStore) ListReports(ctx context.Context, limit int) ([]*Report, error) {
+	query := `
+		SELECT id, status, requested_by, requested_at, completed_at, COALESCE(error, '') AS error, user_count
+		FROM access_review_reports
+		ORDER BY requested_at DESC
+		LIMIT $1`
+
+	var reports []*Report
+	if err := s.db.SelectContext(ctx, &reports, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to list access review reports: %w", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,216 @@
+package alertrule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps rules in memory
+type memoryStore struct {
+	rules map[uuid.UUID]*Rule
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{rules: map[uuid.UUID]*Rule{}}
+}
+
+func (m *memoryStore) CreateRule(ctx context.Context, rule *Rule) error {
+	for _, r := range m.rules {
+		if r.Name == rule.Name {
+			return ErrDuplicateName
+		}
+	}
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) UpdateRule(ctx context.Context, rule *Rule) error {
+	if _, ok := m.rules[rule.ID]; !ok {
+		return ErrRuleNotFound
+	}
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.rules[id]; !ok {
+		return ErrRuleNotFound
+	}
+	delete(m.rules, id)
+	return nil
+}
+
+func (m *memoryStore) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, ErrRuleNotFound
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+func (m *memoryStore) ListRules(ctx context.Context, enabledOnly bool) ([]*Rule, error) {
+	rules := []*Rule{}
+	for _, rule := range m.rules {
+		if rule.Enabled || !enabledOnly {
+			copied := *rule
+			rules = append(rules, &copied)
+		}
+	}
+	return rules, nil
+}
+
+func (m *memoryStore) SaveState(ctx context.Context, rule *Rule) error {
+	return m.UpdateRule(ctx, rule)
+}
+
+// recordingNotifier records the alerts it is sent
+type recordingNotifier struct {
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// staticMetrics reports fixed metric values
+type staticMetrics map[string]float64
+
+func (s staticMetrics) Collect(ctx context.Context) (map[string]float64, error) {
+	return s, nil
+}
+
+func TestRuleRequestValidate(t *testing.T) {
+	req := RuleRequest{Name: "  backlog ", Metric: MetricPendingSyncEvents, Operator: OperatorGreater, Threshold: 100}
+	require.NoError(t, req.validate())
+	assert.Equal(t, "backlog", req.Name)
+	assert.Equal(t, SeverityWarning, req.Severity)
+
+	cases := map[string]RuleRequest{
+		"no name":          {Metric: MetricPendingSyncEvents, Operator: OperatorGreater},
+		"unknown metric":   {Name: "x", Metric: "cpu", Operator: OperatorGreater},
+		"unknown operator": {Name: "x", Metric: MetricPendingSyncEvents, Operator: "eq"},
+		"negative for":     {Name: "x", Metric: MetricPendingSyncEvents, Operator: OperatorGreater, ForSeconds: -1},
+		"unknown severity": {Name: "x", Metric: MetricPendingSyncEvents, Operator: OperatorGreater, Severity: "page"},
+	}
+	for name, req := range cases {
+		assert.ErrorIs(t, req.validate(), ErrInvalidRule, name)
+	}
+}
+
+func TestEvaluateFiresAfterDuration(t *testing.T) {
+	metrics := staticMetrics{MetricPendingSyncEvents: 1500}
+	notifier := &recordingNotifier{}
+	service := NewService(newMemoryStore(), notifier, metrics)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	rule, err := service.Create(ctx, RuleRequest{
+		Name: "sync backlog", Metric: MetricPendingSyncEvents, Operator: OperatorGreater, Threshold: 1000, ForSeconds: 600,
+	}, "admin")
+	require.NoError(t, err)
+	_, err = service.Create(ctx, RuleRequest{Name: "sync backlog", Metric: MetricFailedSyncEvents, Operator: OperatorGreater}, "admin")
+	assert.ErrorIs(t, err, ErrDuplicateName)
+	_, err = service.Create(ctx, RuleRequest{Name: "logins", Metric: MetricFailedLogins, Operator: OperatorGreater}, "admin")
+	require.NoError(t, err)
+
+	// The condition starts to hold
+	alerts, err := service.Evaluate(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+	stored, _ := service.Get(ctx, rule.ID)
+	assert.Equal(t, StatePending, stored.State)
+
+	// It has not held for long enough yet
+	now = now.Add(5 * time.Minute)
+	alerts, _ = service.Evaluate(ctx)
+	assert.Empty(t, alerts)
+
+	now = now.Add(5 * time.Minute)
+	alerts, err = service.Evaluate(ctx)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, AlertFiring, alerts[0].Status)
+	assert.Equal(t, rule.ID, alerts[0].RuleID)
+	assert.Equal(t, "sync.pending_events > 1000 for 10m0s", alerts[0].Condition)
+	stored, _ = service.Get(ctx, rule.ID)
+	assert.Equal(t, StateFiring, stored.State)
+
+	// A firing rule does not fire again
+	now = now.Add(time.Minute)
+	alerts, _ = service.Evaluate(ctx)
+	assert.Empty(t, alerts)
+
+	metrics[MetricPendingSyncEvents] = 10
+	firedAt := now.Add(-time.Minute)
+	now = now.Add(time.Minute)
+	alerts, err = service.Evaluate(ctx)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, AlertResolved, alerts[0].Status)
+	assert.Equal(t, firedAt, alerts[0].Since)
+	stored, _ = service.Get(ctx, rule.ID)
+	assert.Equal(t, StateOK, stored.State)
+	assert.Nil(t, stored.FiringSince)
+	assert.Equal(t, 10.0, *stored.LastValue)
+
+	assert.Len(t, notifier.alerts, 2)
+}
+
+func TestEvaluateResetsPendingRule(t *testing.T) {
+	metrics := staticMetrics{MetricUnresolvedConflicts: 60}
+	notifier := &recordingNotifier{}
+	service := NewService(newMemoryStore(), notifier, metrics)
+	ctx := context.Background()
+
+	rule, err := service.Create(ctx, RuleRequest{
+		Name: "conflicts", Metric: MetricUnresolvedConflicts, Operator: OperatorGreaterEqual, Threshold: 50, ForSeconds: 3600,
+	}, "admin")
+	require.NoError(t, err)
+	_, err = service.Evaluate(ctx)
+	require.NoError(t, err)
+
+	metrics[MetricUnresolvedConflicts] = 20
+	_, err = service.Evaluate(ctx)
+	require.NoError(t, err)
+	stored, _ := service.Get(ctx, rule.ID)
+	assert.Equal(t, StateOK, stored.State)
+	assert.Nil(t, stored.PendingSince)
+	assert.Empty(t, notifier.alerts)
+
+	disabled := false
+	_, err = service.Update(ctx, rule.ID, RuleRequest{
+		Name: "conflicts", Metric: MetricUnresolvedConflicts, Operator: OperatorGreater, Threshold: 0, Enabled: &disabled,
+	})
+	require.NoError(t, err)
+	alerts, err := service.Evaluate(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
+
+func TestWindowCounter(t *testing.T) {
+	counter := NewWindowCounter(MetricFailedLogins, 5*time.Minute)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	counter.now = func() time.Time { return now }
+
+	counter.Add()
+	now = now.Add(3 * time.Minute)
+	counter.Add()
+	counter.Add()
+	metrics, err := counter.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, metrics[MetricFailedLogins])
+
+	now = now.Add(3 * time.Minute)
+	metrics, _ = counter.Collect(context.Background())
+	assert.Equal(t, 2.0, metrics[MetricFailedLogins])
+}
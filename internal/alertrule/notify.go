@@ -0,0 +1,93 @@
+package alertrule
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"connect/internal/webhooks"
+)
+
+// Notifier delivers alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alerts to the log. It is used until a delivery channel
+// is configured.
+type LogNotifier struct{}
+
+// Notify logs the alert
+func (LogNotifier) Notify(ctx context.Context, alert Alert) error {
+	log.Printf("Alert %s (%s): %s", alert.Status, alert.Severity, alert.Message)
+	return nil
+}
+
+// Publisher publishes events, as the webhook service does
+type Publisher interface {
+	Publish(ctx context.Context, eventType string, data interface{})
+}
+
+// WebhookNotifier publishes alerts as alert.firing and alert.resolved events
+// to the webhook endpoints subscribing to them, e.g. an incident management
+// or chat connector
+type WebhookNotifier struct {
+	Publisher Publisher
+}
+
+// Notify publishes the alert
+func (n WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	eventType := webhooks.EventAlertFiring
+	if alert.Status == AlertResolved {
+		eventType = webhooks.EventAlertResolved
+	}
+	n.Publisher.Publish(ctx, eventType, alert)
+	return nil
+}
+
+// MetricSource collects current metric values
+type MetricSource interface {
+	Collect(ctx context.Context) (map[string]float64, error)
+}
+
+// WindowCounter counts events in a sliding window, e.g. failed logins, and
+// reports the count as a metric
+type WindowCounter struct {
+	metric string
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	events []time.Time
+}
+
+// NewWindowCounter creates a counter reporting the events of the last window as metric
+func NewWindowCounter(metric string, window time.Duration) *WindowCounter {
+	return &WindowCounter{metric: metric, window: window, now: time.Now}
+}
+
+// Add counts an event
+func (c *WindowCounter) Add() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.prune(), c.now())
+}
+
+// Collect reports the events of the last window
+func (c *WindowCounter) Collect(ctx context.Context) (map[string]float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = c.prune()
+	return map[string]float64{c.metric: float64(len(c.events))}, nil
+}
+
+// prune drops the events older than the window. Callers hold the mutex.
+func (c *WindowCounter) prune() []time.Time {
+	cutoff := c.now().Add(-c.window)
+	i := 0
+	for i < len(c.events) && !c.events[i].After(cutoff) {
+		i++
+	}
+	return c.events[i:]
+}
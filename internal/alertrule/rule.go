@@ -0,0 +1,220 @@
+// Package alertrule lets operators define threshold rules over internal
+// metrics, e.g. more than 1000 pending sync events for 10 minutes, a conflict
+// rate above 50 an hour or a spike of failed logins. Rules are evaluated
+// whenever the sync monitor checks the system's health: a rule whose
+// condition holds becomes pending, fires once it has held for its duration
+// and resolves when the condition no longer holds. Firing and resolution are
+// delivered through a notifier, e.g. to the webhook endpoints that subscribe
+// to alert events.
+package alertrule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metrics rules can be defined over
+const (
+	MetricPendingSyncEvents     = "sync.pending_events"
+	MetricFailedSyncEvents      = "sync.failed_events"
+	MetricOldestPendingSeconds  = "sync.oldest_pending_seconds"
+	MetricSyncErrorRate         = "sync.error_rate"
+	MetricConflictRate          = "sync.conflict_rate"
+	MetricUnresolvedConflicts   = "sync.unresolved_conflicts"
+	MetricFailedLogins          = "auth.failed_logins"
+	MetricOrphanedRelationships = "data_quality.orphaned_relationships"
+	MetricCIsWithoutOwner       = "data_quality.cis_without_owner"
+)
+
+// Metric describes a metric rules can be defined over
+type Metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Metrics are the metrics rules can be defined over
+var Metrics = []Metric{
+	{MetricPendingSyncEvents, "Sync events waiting to be processed"},
+	{MetricFailedSyncEvents, "Sync events that failed and were not retried successfully"},
+	{MetricOldestPendingSeconds, "Age in seconds of the oldest pending sync event"},
+	{MetricSyncErrorRate, "Percentage of sync attempts that failed in the last hour"},
+	{MetricConflictRate, "Sync conflicts detected in the last hour"},
+	{MetricUnresolvedConflicts, "Sync conflicts awaiting resolution"},
+	{MetricFailedLogins, "Failed logins in the configured window"},
+	{MetricOrphanedRelationships, "Active relationships whose source or target CI is deleted or inactive"},
+	{MetricCIsWithoutOwner, "Live CIs without an owner"},
+}
+
+// Operators comparing a metric to a rule's threshold
+const (
+	OperatorGreater      = "gt"
+	OperatorGreaterEqual = "gte"
+	OperatorLess         = "lt"
+	OperatorLessEqual    = "lte"
+)
+
+// Operators are the accepted operators
+var Operators = []string{OperatorGreater, OperatorGreaterEqual, OperatorLess, OperatorLessEqual}
+
+// Severities of the alerts a rule fires
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Severities are the accepted severities
+var Severities = []string{SeverityInfo, SeverityWarning, SeverityCritical}
+
+// States of a rule
+const (
+	// StateOK is a rule whose condition does not hold
+	StateOK = "ok"
+	// StatePending is a rule whose condition holds, but not for long enough
+	StatePending = "pending"
+	// StateFiring is a rule whose condition held for its duration
+	StateFiring = "firing"
+)
+
+// Alert statuses
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// MaxNameLength bounds rule names
+const MaxNameLength = 255
+
+var (
+	ErrRuleNotFound      = errors.New("alert rule not found")
+	ErrInvalidRule       = errors.New("invalid alert rule")
+	ErrDuplicateName     = errors.New("an alert rule with this name already exists")
+	ErrEvaluationRunning = errors.New("alert rules are already being evaluated")
+)
+
+// Rule is a threshold rule over a metric and its evaluation state
+type Rule struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Metric      string    `json:"metric"`
+	Operator    string    `json:"operator"`
+	Threshold   float64   `json:"threshold"`
+	// ForSeconds is how long the condition must hold before the rule fires;
+	// 0 fires on the first evaluation it holds
+	ForSeconds int      `json:"for_seconds"`
+	Severity   string   `json:"severity"`
+	Enabled    bool     `json:"enabled"`
+	State      string   `json:"state"`
+	LastValue  *float64 `json:"last_value,omitempty"`
+	// PendingSince is when the condition started to hold
+	PendingSince    *time.Time `json:"pending_since,omitempty"`
+	FiringSince     *time.Time `json:"firing_since,omitempty"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Breached reports whether a metric value meets the rule's condition
+func (r *Rule) Breached(value float64) bool {
+	switch r.Operator {
+	case OperatorGreater:
+		return value > r.Threshold
+	case OperatorGreaterEqual:
+		return value >= r.Threshold
+	case OperatorLess:
+		return value < r.Threshold
+	case OperatorLessEqual:
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// Condition describes the rule's condition, e.g. "sync.pending_events > 1000 for 10m0s"
+func (r *Rule) Condition() string {
+	symbols := map[string]string{OperatorGreater: ">", OperatorGreaterEqual: ">=", OperatorLess: "<", OperatorLessEqual: "<="}
+	condition := fmt.Sprintf("%s %s %g", r.Metric, symbols[r.Operator], r.Threshold)
+	if r.ForSeconds > 0 {
+		condition += fmt.Sprintf(" for %s", time.Duration(r.ForSeconds)*time.Second)
+	}
+	return condition
+}
+
+// RuleRequest defines or redefines a rule. Enabled defaults to true.
+type RuleRequest struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Metric      string  `json:"metric"`
+	Operator    string  `json:"operator"`
+	Threshold   float64 `json:"threshold"`
+	ForSeconds  int     `json:"for_seconds"`
+	Severity    string  `json:"severity,omitempty"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+}
+
+// validate checks the request, trimming the name and defaulting the severity
+func (r *RuleRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if len(r.Name) > MaxNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidRule, MaxNameLength)
+	}
+	if !knownMetric(r.Metric) {
+		return fmt.Errorf("%w: unknown metric %q", ErrInvalidRule, r.Metric)
+	}
+	if !contains(Operators, r.Operator) {
+		return fmt.Errorf("%w: operator must be one of %s", ErrInvalidRule, strings.Join(Operators, ", "))
+	}
+	if r.ForSeconds < 0 {
+		return fmt.Errorf("%w: for_seconds must not be negative", ErrInvalidRule)
+	}
+	if r.Severity == "" {
+		r.Severity = SeverityWarning
+	}
+	if !contains(Severities, r.Severity) {
+		return fmt.Errorf("%w: severity must be one of %s", ErrInvalidRule, strings.Join(Severities, ", "))
+	}
+	return nil
+}
+
+// Alert is a rule firing or resolving
+type Alert struct {
+	Status   string    `json:"status"`
+	RuleID   uuid.UUID `json:"rule_id"`
+	RuleName string    `json:"rule_name"`
+	Metric   string    `json:"metric"`
+	Value    float64   `json:"value"`
+	// Condition describes the rule's condition, e.g. "sync.pending_events > 1000 for 10m0s"
+	Condition string    `json:"condition"`
+	Severity  string    `json:"severity"`
+	Message   string    `json:"message"`
+	At        time.Time `json:"at"`
+	// Since is when the rule started firing
+	Since time.Time `json:"since"`
+}
+
+// knownMetric reports whether a metric is one rules can be defined over
+func knownMetric(name string) bool {
+	for _, metric := range Metrics {
+		if metric.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
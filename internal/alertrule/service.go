@@ -0,0 +1,218 @@
+package alertrule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Service manages rules and evaluates them against the metric sources
+type Service struct {
+	store    Store
+	notifier Notifier
+	sources  []MetricSource
+	now      func() time.Time
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewService creates a new alert rule service collecting metrics from sources
+func NewService(store Store, notifier Notifier, sources ...MetricSource) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	return &Service{store: store, notifier: notifier, sources: sources, now: time.Now}
+}
+
+// Create defines a new rule
+func (s *Service) Create(ctx context.Context, req RuleRequest, by string) (*Rule, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	rule := &Rule{
+		ID:        uuid.New(),
+		Enabled:   true,
+		State:     StateOK,
+		CreatedBy: by,
+		CreatedAt: now,
+	}
+	apply(rule, req, now)
+	if err := s.store.CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Update redefines a rule. Its evaluation starts over, without resolving an
+// alert it fired.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, req RuleRequest) (*Rule, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	rule, err := s.store.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	apply(rule, req, s.now())
+	rule.State = StateOK
+	rule.PendingSince = nil
+	rule.FiringSince = nil
+	if err := s.store.UpdateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// apply copies a request's definition to a rule
+func apply(rule *Rule, req RuleRequest, now time.Time) {
+	rule.Name = req.Name
+	rule.Description = req.Description
+	rule.Metric = req.Metric
+	rule.Operator = req.Operator
+	rule.Threshold = req.Threshold
+	rule.ForSeconds = req.ForSeconds
+	rule.Severity = req.Severity
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	rule.UpdatedAt = now
+}
+
+// Delete deletes a rule
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.store.DeleteRule(ctx, id)
+}
+
+// Get retrieves a rule
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	return s.store.GetRule(ctx, id)
+}
+
+// List retrieves the rules by name
+func (s *Service) List(ctx context.Context) ([]*Rule, error) {
+	return s.store.ListRules(ctx, false)
+}
+
+// Collect returns the current value of every metric the sources report
+func (s *Service) Collect(ctx context.Context) (map[string]float64, error) {
+	metrics := map[string]float64{}
+	for _, source := range s.sources {
+		values, err := source.Collect(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect metrics: %w", err)
+		}
+		for name, value := range values {
+			metrics[name] = value
+		}
+	}
+	return metrics, nil
+}
+
+// Evaluate evaluates the enabled rules against the current metrics, records
+// their state and notifies about the rules that fired or resolved. Rules
+// over a metric no source reports are left as they are.
+func (s *Service) Evaluate(ctx context.Context) ([]Alert, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrEvaluationRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	metrics, err := s.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.store.ListRules(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	alerts := []Alert{}
+	for _, rule := range rules {
+		value, ok := metrics[rule.Metric]
+		if !ok {
+			continue
+		}
+		alert := evaluate(rule, value, now)
+		if err := s.store.SaveState(ctx, rule); err != nil {
+			return nil, err
+		}
+		if alert == nil {
+			continue
+		}
+		if err := s.notifier.Notify(ctx, *alert); err != nil {
+			log.Printf("Failed to notify about alert rule %s: %v", rule.ID, err)
+		}
+		alerts = append(alerts, *alert)
+	}
+	return alerts, nil
+}
+
+// evaluate moves a rule to its next state given the current metric value,
+// returning the alert to send when it fires or resolves
+func evaluate(rule *Rule, value float64, now time.Time) *Alert {
+	rule.LastValue = &value
+	rule.LastEvaluatedAt = &now
+
+	if !rule.Breached(value) {
+		wasFiring := rule.State == StateFiring
+		since := rule.FiringSince
+		rule.State = StateOK
+		rule.PendingSince = nil
+		rule.FiringSince = nil
+		if !wasFiring {
+			return nil
+		}
+		alert := newAlert(rule, AlertResolved, value, now, now)
+		if since != nil {
+			alert.Since = *since
+		}
+		alert.Message = fmt.Sprintf("%s resolved: %s is %g", rule.Name, rule.Metric, value)
+		return alert
+	}
+
+	switch rule.State {
+	case StateFiring:
+		return nil
+	case StatePending:
+	default:
+		rule.State = StatePending
+		rule.PendingSince = &now
+	}
+	if now.Sub(*rule.PendingSince) < time.Duration(rule.ForSeconds)*time.Second {
+		return nil
+	}
+	rule.State = StateFiring
+	rule.FiringSince = &now
+	alert := newAlert(rule, AlertFiring, value, now, now)
+	alert.Message = fmt.Sprintf("%s: %s is %g (%s)", rule.Name, rule.Metric, value, rule.Condition())
+	return alert
+}
+
+func newAlert(rule *Rule, status string, value float64, at, since time.Time) *Alert {
+	return &Alert{
+		Status:    status,
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		Metric:    rule.Metric,
+		Value:     value,
+		Condition: rule.Condition(),
+		Severity:  rule.Severity,
+		At:        at,
+		Since:     since,
+	}
+}
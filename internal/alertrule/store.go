@@ -0,0 +1,227 @@
+package alertrule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store persists rules and their evaluation state
+type Store interface {
+	CreateRule(ctx context.Context, rule *Rule) error
+	UpdateRule(ctx context.Context, rule *Rule) error
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	GetRule(ctx context.Context, id uuid.UUID) (*Rule, error)
+	ListRules(ctx context.Context, enabledOnly bool) ([]*Rule, error)
+	// SaveState records the outcome of a rule's evaluation
+	SaveState(ctx context.Context, rule *Rule) error
+}
+
+// PostgresStore keeps rules in alert_rules
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed alert rule store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const ruleColumns = `id, name, description, metric, operator, threshold, for_seconds, severity, enabled,
+	state, last_value, pending_since, firing_since, last_evaluated_at, created_by, created_at, updated_at`
+
+// CreateRule inserts a rule
+func (s *PostgresStore) CreateRule(ctx context.Context, r *Rule) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO alert_rules (`+ruleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+		r.ID, r.Name, r.Description, r.Metric, r.Operator, r.Threshold, r.ForSeconds, r.Severity, r.Enabled,
+		r.State, r.LastValue, r.PendingSince, r.FiringSince, r.LastEvaluatedAt, r.CreatedBy, r.CreatedAt, r.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateName
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	return nil
+}
+
+// UpdateRule updates a rule's definition and state
+func (s *PostgresStore) UpdateRule(ctx context.Context, r *Rule) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE alert_rules
+		SET name = $2, description = $3, metric = $4, operator = $5, threshold = $6, for_seconds = $7,
+			severity = $8, enabled = $9, state = $10, pending_since = $11, firing_since = $12, updated_at = $13
+		WHERE id = $1`,
+		r.ID, r.Name, r.Description, r.Metric, r.Operator, r.Threshold, r.ForSeconds,
+		r.Severity, r.Enabled, r.State, r.PendingSince, r.FiringSince, r.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateName
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	return requireRow(res, ErrRuleNotFound)
+}
+
+// DeleteRule deletes a rule
+func (s *PostgresStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	return requireRow(res, ErrRuleNotFound)
+}
+
+// GetRule retrieves a rule
+func (s *PostgresStore) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	rules, err := s.queryRules(ctx, `SELECT `+ruleColumns+` FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, ErrRuleNotFound
+	}
+	return rules[0], nil
+}
+
+// ListRules retrieves rules by name
+func (s *PostgresStore) ListRules(ctx context.Context, enabledOnly bool) ([]*Rule, error) {
+	return s.queryRules(ctx, `
+		SELECT `+ruleColumns+` FROM alert_rules
+		WHERE enabled OR NOT $1
+		ORDER BY name`, enabledOnly)
+}
+
+// SaveState records a rule's evaluation state. A rule deleted meanwhile is
+// ignored.
+func (s *PostgresStore) SaveState(ctx context.Context, r *Rule) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE alert_rules
+		SET state = $2, last_value = $3, pending_since = $4, firing_since = $5, last_evaluated_at = $6
+		WHERE id = $1`,
+		r.ID, r.State, r.LastValue, r.PendingSince, r.FiringSince, r.LastEvaluatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save alert rule state: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) queryRules(ctx context.Context, query string, args ...interface{}) ([]*Rule, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []*Rule{}
+	for rows.Next() {
+		r := &Rule{}
+		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.Metric, &r.Operator, &r.Threshold, &r.ForSeconds,
+			&r.Severity, &r.Enabled, &r.State, &r.LastValue, &r.PendingSince, &r.FiringSince, &r.LastEvaluatedAt,
+			&r.CreatedBy, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// PostgresMetrics collects the sync and data quality metrics from Postgres
+type PostgresMetrics struct {
+	db *sqlx.DB
+}
+
+// NewPostgresMetrics creates a new Postgres metric source
+func NewPostgresMetrics(db *sqlx.DB) *PostgresMetrics {
+	return &PostgresMetrics{db: db}
+}
+
+// Collect reports the sync and data quality metrics
+func (p *PostgresMetrics) Collect(ctx context.Context) (map[string]float64, error) {
+	var pending, failed, oldestPending float64
+	err := p.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'PENDING'),
+			COUNT(*) FILTER (WHERE status = 'FAILED'),
+			COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at) FILTER (WHERE status = 'PENDING')), 0)
+		FROM sync_events`).Scan(&pending, &failed, &oldestPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect sync event metrics: %w", err)
+	}
+
+	var errorRate float64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT COALESCE(100.0 * COUNT(*) FILTER (WHERE status = 'FAILED') / NULLIF(COUNT(*), 0), 0)
+		FROM sync_log
+		WHERE created_at > NOW() - INTERVAL '1 hour'`).Scan(&errorRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect sync error rate: %w", err)
+	}
+
+	var conflictRate, unresolved float64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '1 hour'),
+			COUNT(*) FILTER (WHERE NOT resolved)
+		FROM sync_conflicts`).Scan(&conflictRate, &unresolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect sync conflict metrics: %w", err)
+	}
+
+	var orphaned float64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM ci_relationships r
+		JOIN configuration_items s ON s.id = r.source_ci_id
+		JOIN configuration_items t ON t.id = r.target_ci_id
+		WHERE r.is_active AND (s.is_deleted OR t.is_deleted OR NOT s.is_active OR NOT t.is_active)`).Scan(&orphaned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect orphaned relationships: %w", err)
+	}
+
+	var withoutOwner float64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM configuration_items
+		WHERE is_deleted = false AND COALESCE(TRIM(owner), '') = ''`).Scan(&withoutOwner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect CIs without owner: %w", err)
+	}
+
+	return map[string]float64{
+		MetricPendingSyncEvents:     pending,
+		MetricFailedSyncEvents:      failed,
+		MetricOldestPendingSeconds:  oldestPending,
+		MetricSyncErrorRate:         errorRate,
+		MetricConflictRate:          conflictRate,
+		MetricUnresolvedConflicts:   unresolved,
+		MetricOrphanedRelationships: orphaned,
+		MetricCIsWithoutOwner:       withoutOwner,
+	}, nil
+}
+
+// requireRow returns notFound if a statement affected no row
+func requireRow(res sql.Result, notFound error) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rows == 0 {
+		return notFound
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
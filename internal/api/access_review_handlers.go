@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"connect/internal/accessreview"
+	"connect/internal/auth"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// AccessReviewHandler handles access review exports for auditors
+type AccessReviewHandler struct {
+	service *accessreview.Service
+}
+
+// NewAccessReviewHandler creates a new AccessReviewHandler
+func NewAccessReviewHandler(service *accessreview.Service) *AccessReviewHandler {
+	return &AccessReviewHandler{service: service}
+}
+
+// RegisterRoutes registers access review routes
+func (h *AccessReviewHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/security/access-review", h.authMiddleware(h.handleRequestReview)).Methods("POST")
+	router.HandleFunc("/api/v1/security/access-review", h.authMiddleware(h.handleListReviews)).Methods("GET")
+	router.HandleFunc("/api/v1/security/access-review/{id}", h.authMiddleware(h.handleGetReview)).Methods("GET")
+}
+
+// handleRequestReview starts generating a new access review. Generation runs in
+// the background; the response points at the report to poll.
+func (h *AccessReviewHandler) handleRequestReview(w http.ResponseWriter, r *http.Request) {
+	requestedBy, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || requestedBy == "" {
+		requestedBy = "unknown"
+	}
+
+	report, err := h.service.Request(r.Context(), requestedBy)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to request access review", err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/security/access-review/"+report.ID.String())
+	h.respondWithJSON(w, http.StatusAccepted, report)
+}
+
+// handleListReviews handles listing recent access reviews without their matrices
+func (h *AccessReviewHandler) handleListReviews(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = parsed
+	}
+
+	reports, err := h.service.List(r.Context(), limit)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list access reviews", err)
+		return
+	}
+	if reports == nil {
+		reports = []*accessreview.Report{}
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"reports": reports,
+	})
+}
+
+// handleGetReview handles retrieving an access review as JSON, or as CSV with
+// ?format=csv or Accept: text/csv. Reports still being generated return 202
+// with their status and no matrix.
+func (h *AccessReviewHandler) handleGetReview(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid access review ID", err)
+		return
+	}
+
+	report, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, accessreview.ErrReportNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "Access review not found", nil)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get access review", err)
+		return
+	}
+
+	switch report.Status {
+	case accessreview.StatusPending, accessreview.StatusRunning:
+		h.respondWithJSON(w, http.StatusAccepted, report)
+		return
+	case accessreview.StatusFailed:
+		h.respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	if !wantsCSV(r) {
+		h.respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=access-review-%s.csv", report.RequestedAt.Format("2006-01-02")))
+	w.WriteHeader(http.StatusOK)
+	if err := accessreview.WriteCSV(w, report.Matrix); err != nil {
+		// Headers are already sent, so the client sees a truncated file
+		log.Printf("Failed to write access review %s as CSV: %v", report.ID, err)
+	}
+}
+
+// wantsCSV reports whether the caller asked for a CSV export
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+// authMiddleware requires the admin role, as reviews list every user's permissions
+func (h *AccessReviewHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// respondWithError sends an error response
+func (h *AccessReviewHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *AccessReviewHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/alertrule"
+	"connect/internal/config"
+	"connect/internal/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// AlertRuleHandler handles the threshold rules operators define over
+// internal metrics, admin only
+type AlertRuleHandler struct {
+	config  *config.Config
+	logger  *logger.Logger
+	service *alertrule.Service
+}
+
+func NewAlertRuleHandler(config *config.Config, appLogger *logger.Logger, service *alertrule.Service) *AlertRuleHandler {
+	return &AlertRuleHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+	}
+}
+
+// Metrics handles listing the metrics rules can be defined over with their
+// current values
+func (h *AlertRuleHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	values, err := h.service.Collect(r.Context())
+	if err != nil {
+		h.respondWithAlertRuleError(w, r, "Failed to collect metrics", err)
+		return
+	}
+
+	metrics := make([]map[string]interface{}, 0, len(alertrule.Metrics))
+	for _, metric := range alertrule.Metrics {
+		entry := map[string]interface{}{"name": metric.Name, "description": metric.Description}
+		if value, ok := values[metric.Name]; ok {
+			entry["value"] = value
+		}
+		metrics = append(metrics, entry)
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"metrics": metrics})
+}
+
+// Create handles defining a rule
+func (h *AlertRuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req alertrule.RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode alert rule request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	rule, err := h.service.Create(r.Context(), req, actorID(r).String())
+	if err != nil {
+		h.respondWithAlertRuleError(w, r, "Failed to create alert rule", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Alert rule created", map[string]interface{}{
+		"rule_id":   rule.ID,
+		"condition": rule.Condition(),
+	})
+	w.Header().Set("Location", "/api/v1/alert-rules/"+rule.ID.String())
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, rule)
+}
+
+// List handles listing the rules with their state
+func (h *AlertRuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.List(r.Context())
+	if err != nil {
+		h.respondWithAlertRuleError(w, r, "Failed to list alert rules", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"rules": rules, "count": len(rules)})
+}
+
+// Get handles getting a rule with its state
+func (h *AlertRuleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ruleID, ok := uuidParam(w, r, "id", "alert rule")
+	if !ok {
+		return
+	}
+
+	rule, err := h.service.Get(r.Context(), ruleID)
+	if err != nil {
+		h.respondWithAlertRuleError(w, r, "Failed to get alert rule", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, rule)
+}
+
+// Update handles redefining a rule, which starts its evaluation over
+func (h *AlertRuleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ruleID, ok := uuidParam(w, r, "id", "alert rule")
+	if !ok {
+		return
+	}
+
+	var req alertrule.RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode alert rule request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	rule, err := h.service.Update(r.Context(), ruleID, req)
+	if err != nil {
+		h.respondWithAlertRuleError(w, r, "Failed to update alert rule", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Alert rule updated", map[string]interface{}{
+		"rule_id":   rule.ID,
+		"condition": rule.Condition(),
+		"enabled":   rule.Enabled,
+	})
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, rule)
+}
+
+// Delete handles deleting a rule
+func (h *AlertRuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ruleID, ok := uuidParam(w, r, "id", "alert rule")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), ruleID); err != nil {
+		h.respondWithAlertRuleError(w, r, "Failed to delete alert rule", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Alert rule deleted", map[string]interface{}{"rule_id": ruleID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routes returns the alert rule routes, all of which require the admin role
+func (h *AlertRuleHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.Get("/metrics", h.Metrics)
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+
+	return r
+}
+
+// respondWithAlertRuleError maps alert rule errors to status codes
+func (h *AlertRuleHandler) respondWithAlertRuleError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, alertrule.ErrInvalidRule):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, alertrule.ErrRuleNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, alertrule.ErrDuplicateName):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	default:
+		h.logger.ErrorRequest(r, err, message)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": message})
+	}
+}
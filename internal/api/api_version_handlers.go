@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/apiversion"
+	"github.com/gorilla/mux"
+)
+
+// APIVersionHandler exposes the served API versions and their deprecation status
+type APIVersionHandler struct {
+	versions *apiversion.Registry
+}
+
+// NewAPIVersionHandler creates a new APIVersionHandler
+func NewAPIVersionHandler(versions *apiversion.Registry) *APIVersionHandler {
+	return &APIVersionHandler{versions: versions}
+}
+
+// RegisterRoutes registers API version discovery routes
+func (h *APIVersionHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/versions", h.handleListVersions).Methods("GET")
+}
+
+// handleListVersions handles listing API versions
+func (h *APIVersionHandler) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	response, err := json.Marshal(map[string]interface{}{
+		"versions": h.versions.Versions(),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"connect/internal/apikey"
+	"connect/internal/auth"
+	"connect/internal/config"
+	"connect/internal/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler handles the API keys users create for their pipelines and
+// agents. Users manage their own keys; admins may manage everyone's.
+type APIKeyHandler struct {
+	config  *config.Config
+	logger  *logger.Logger
+	service *apikey.Service
+}
+
+func NewAPIKeyHandler(config *config.Config, appLogger *logger.Logger, service *apikey.Service) *APIKeyHandler {
+	return &APIKeyHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+	}
+}
+
+// Create handles creating an API key. The key is only ever returned in this
+// response.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req apikey.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode API key request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	issued, err := h.service.Create(r.Context(), req, apiKeyCaller(r))
+	if err != nil {
+		h.respondWithAPIKeyError(w, r, "Failed to create API key", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "API key created", map[string]interface{}{
+		"api_key_id":  issued.ID,
+		"prefix":      issued.Prefix,
+		"permissions": issued.Permissions,
+	})
+	w.Header().Set("Location", "/api/v1/apikeys/"+issued.ID.String())
+	w.Header().Set("Cache-Control", "no-store")
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, issued)
+}
+
+// List handles listing the caller's API keys, or every user's for admins
+// passing all=true
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	all := false
+	if value := r.URL.Query().Get("all"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid all parameter"})
+			return
+		}
+		all = parsed
+	}
+
+	keys, err := h.service.List(r.Context(), apiKeyCaller(r), all)
+	if err != nil {
+		h.respondWithAPIKeyError(w, r, "Failed to list API keys", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"api_keys": keys, "count": len(keys)})
+}
+
+// Get handles getting an API key
+func (h *APIKeyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := uuidParam(w, r, "id", "API key")
+	if !ok {
+		return
+	}
+
+	key, err := h.service.Get(r.Context(), keyID, apiKeyCaller(r))
+	if err != nil {
+		h.respondWithAPIKeyError(w, r, "Failed to get API key", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, key)
+}
+
+// Rotate handles replacing the secret of an API key. The new key is only
+// ever returned in this response.
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := uuidParam(w, r, "id", "API key")
+	if !ok {
+		return
+	}
+
+	issued, err := h.service.Rotate(r.Context(), keyID, apiKeyCaller(r))
+	if err != nil {
+		h.respondWithAPIKeyError(w, r, "Failed to rotate API key", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "API key rotated", map[string]interface{}{
+		"api_key_id": issued.ID,
+		"prefix":     issued.Prefix,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, issued)
+}
+
+// Revoke handles revoking an API key
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := uuidParam(w, r, "id", "API key")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), keyID, apiKeyCaller(r)); err != nil {
+		h.respondWithAPIKeyError(w, r, "Failed to revoke API key", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "API key revoked", map[string]interface{}{"api_key_id": keyID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routes returns the API key routes, which require a logged in user
+func (h *APIKeyHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireUserSession)
+
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Post("/{id}/rotate", h.Rotate)
+	r.Delete("/{id}", h.Revoke)
+
+	return r
+}
+
+// requireUserSession refuses requests not made by a logged in user, so
+// neither service accounts nor API keys can create more keys
+func requireUserSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, scoped := auth.GetScopesFromContext(r.Context()); scoped || actorID(r) == uuid.Nil {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, map[string]string{"error": "API keys can only be managed by users"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiKeyCaller returns the user managing API keys
+func apiKeyCaller(r *http.Request) apikey.Caller {
+	roles, _ := auth.GetUserRolesFromContext(r.Context())
+	org, _ := auth.GetOrgFromContext(r.Context())
+	return apikey.Caller{ID: actorID(r), Roles: roles, Org: org}
+}
+
+// respondWithAPIKeyError maps API key errors to status codes
+func (h *APIKeyHandler) respondWithAPIKeyError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrInvalidKey), errors.Is(err, apikey.ErrUnknownPermission):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, apikey.ErrPermissionNotHeld):
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, apikey.ErrKeyNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "API key not found"})
+	default:
+		h.logger.ErrorRequest(r, err, message)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": message})
+	}
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/attrtrigger"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// AttributeTriggerHandler handles the attribute change trigger endpoints
+type AttributeTriggerHandler struct {
+	service *attrtrigger.Service
+}
+
+// NewAttributeTriggerHandler creates a new AttributeTriggerHandler
+func NewAttributeTriggerHandler(service *attrtrigger.Service) *AttributeTriggerHandler {
+	return &AttributeTriggerHandler{service: service}
+}
+
+// RegisterRoutes registers attribute trigger routes
+func (h *AttributeTriggerHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/attribute-triggers", h.authMiddleware(h.handleListTriggers)).Methods("GET")
+	router.HandleFunc("/api/v1/attribute-triggers", h.authMiddleware(h.handleCreateTrigger)).Methods("POST")
+	router.HandleFunc("/api/v1/attribute-triggers/{id}", h.authMiddleware(h.handleGetTrigger)).Methods("GET")
+	router.HandleFunc("/api/v1/attribute-triggers/{id}", h.authMiddleware(h.handleUpdateTrigger)).Methods("PUT")
+	router.HandleFunc("/api/v1/attribute-triggers/{id}", h.authMiddleware(h.handleDeleteTrigger)).Methods("DELETE")
+	router.HandleFunc("/api/v1/attribute-triggers/{id}/executions", h.authMiddleware(h.handleListExecutions)).Methods("GET")
+}
+
+// AttributeTriggerRequest represents a request to create or update an
+// attribute trigger, e.g. {"name": "Decommissioned servers", "ci_types":
+// ["server"], "attribute": "status", "to": "decommissioned", "actions":
+// [{"type": "deactivate_relationships"}, {"type": "tag", "add_tags": ["retired"]}]}
+type AttributeTriggerRequest struct {
+	Name      string               `json:"name"`
+	CITypes   []string             `json:"ci_types"`
+	Attribute string               `json:"attribute"`
+	From      *string              `json:"from"`
+	To        *string              `json:"to"`
+	Actions   []attrtrigger.Action `json:"actions"`
+	Enabled   *bool                `json:"enabled"` // defaults to true
+}
+
+// trigger converts the request to a trigger
+func (req *AttributeTriggerRequest) trigger() *attrtrigger.Trigger {
+	trigger := &attrtrigger.Trigger{
+		Name:      req.Name,
+		CITypes:   req.CITypes,
+		Attribute: req.Attribute,
+		From:      req.From,
+		To:        req.To,
+		Actions:   req.Actions,
+		Enabled:   true,
+	}
+	if req.Enabled != nil {
+		trigger.Enabled = *req.Enabled
+	}
+	return trigger
+}
+
+var triggerOutcomes = []string{attrtrigger.OutcomeOK, attrtrigger.OutcomeError}
+
+// handleListTriggers handles listing attribute triggers
+func (h *AttributeTriggerHandler) handleListTriggers(w http.ResponseWriter, r *http.Request) {
+	triggers, err := h.service.ListTriggers(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list attribute triggers", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"triggers": triggers})
+}
+
+// handleCreateTrigger handles creating an attribute trigger
+func (h *AttributeTriggerHandler) handleCreateTrigger(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req AttributeTriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	trigger, err := h.service.CreateTrigger(ctx, req.trigger(), userID.String())
+	if err != nil {
+		h.respondWithTriggerError(w, "Failed to create attribute trigger", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, trigger)
+}
+
+// handleGetTrigger handles retrieving an attribute trigger
+func (h *AttributeTriggerHandler) handleGetTrigger(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	triggerID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	trigger, err := h.service.GetTrigger(r.Context(), triggerID)
+	if err != nil {
+		h.respondWithTriggerError(w, "Failed to get attribute trigger", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, trigger)
+}
+
+// handleUpdateTrigger handles replacing an attribute trigger
+func (h *AttributeTriggerHandler) handleUpdateTrigger(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	params := bindRequest(r)
+	triggerID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	var req AttributeTriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	trigger := req.trigger()
+	trigger.ID = triggerID
+	trigger, err := h.service.UpdateTrigger(ctx, trigger, userID.String())
+	if err != nil {
+		h.respondWithTriggerError(w, "Failed to update attribute trigger", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, trigger)
+}
+
+// handleDeleteTrigger handles deleting an attribute trigger and its execution log
+func (h *AttributeTriggerHandler) handleDeleteTrigger(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	params := bindRequest(r)
+	triggerID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteTrigger(ctx, triggerID, userID.String()); err != nil {
+		h.respondWithTriggerError(w, "Failed to delete attribute trigger", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Attribute trigger deleted successfully",
+	})
+}
+
+// handleListExecutions handles listing the latest action runs of an attribute trigger
+func (h *AttributeTriggerHandler) handleListExecutions(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	triggerID := params.PathUUID("id")
+	outcome := params.Enum("outcome", "", triggerOutcomes)
+	limit := params.Int("limit", 0, 1, 500)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	executions, err := h.service.ListExecutions(r.Context(), triggerID, outcome, limit)
+	if err != nil {
+		h.respondWithTriggerError(w, "Failed to list attribute trigger executions", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"executions": executions})
+}
+
+// respondWithTriggerError maps trigger errors to status codes
+func (h *AttributeTriggerHandler) respondWithTriggerError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, attrtrigger.ErrInvalidTrigger):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	case errors.Is(err, attrtrigger.ErrTriggerNotFound):
+		h.respondWithError(w, http.StatusNotFound, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *AttributeTriggerHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens and require the admin role
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *AttributeTriggerHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *AttributeTriggerHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *AttributeTriggerHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
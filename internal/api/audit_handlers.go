@@ -0,0 +1,438 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"connect/internal/auditlog"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// auditFlushInterval is how many exported entries are written between flushes
+const auditFlushInterval = 500
+
+// AuditHandler handles the audit log export and retention endpoints
+type AuditHandler struct {
+	service *auditlog.Service
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(service *auditlog.Service) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// RegisterRoutes registers audit log routes
+func (h *AuditHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/audit/export", h.authMiddleware(h.handleExport)).Methods("GET")
+	router.HandleFunc("/api/v1/audit/archives", h.authMiddleware(h.handleListArchives)).Methods("GET")
+
+	// Retention policy routes
+	router.HandleFunc("/api/v1/audit/retention-policies", h.authMiddleware(h.handleListPolicies)).Methods("GET")
+	router.HandleFunc("/api/v1/audit/retention-policies", h.authMiddleware(h.handleCreatePolicy)).Methods("POST")
+	router.HandleFunc("/api/v1/audit/retention-policies/{id}", h.authMiddleware(h.handleGetPolicy)).Methods("GET")
+	router.HandleFunc("/api/v1/audit/retention-policies/{id}", h.authMiddleware(h.handleUpdatePolicy)).Methods("PUT")
+	router.HandleFunc("/api/v1/audit/retention-policies/{id}", h.authMiddleware(h.handleDeletePolicy)).Methods("DELETE")
+	router.HandleFunc("/api/v1/audit/retention/enforcement", h.authMiddleware(h.handleGetEnforcement)).Methods("GET")
+	router.HandleFunc("/api/v1/audit/retention/enforcement", h.authMiddleware(h.handleStartEnforcement)).Methods("POST")
+
+	// Legal hold routes
+	router.HandleFunc("/api/v1/audit/legal-holds", h.authMiddleware(h.handleListHolds)).Methods("GET")
+	router.HandleFunc("/api/v1/audit/legal-holds", h.authMiddleware(h.handleCreateHold)).Methods("POST")
+	router.HandleFunc("/api/v1/audit/legal-holds/{id}/release", h.authMiddleware(h.handleReleaseHold)).Methods("POST")
+}
+
+// RetentionPolicyRequest represents a request to create or update a retention policy
+type RetentionPolicyRequest struct {
+	Name       string `json:"name"`
+	EntityType string `json:"entity_type"` // empty covers every type without a policy of its own
+	RetainDays int    `json:"retain_days"`
+	Archive    bool   `json:"archive"`
+	Enabled    *bool  `json:"enabled"` // defaults to true
+}
+
+// policy converts the request to a retention policy
+func (req *RetentionPolicyRequest) policy() *auditlog.RetentionPolicy {
+	policy := &auditlog.RetentionPolicy{
+		Name:       req.Name,
+		EntityType: req.EntityType,
+		RetainDays: req.RetainDays,
+		Archive:    req.Archive,
+		Enabled:    true,
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	return policy
+}
+
+// LegalHoldRequest represents a request to place a legal hold
+type LegalHoldRequest struct {
+	Name       string     `json:"name"`
+	Reason     string     `json:"reason"`
+	EntityType string     `json:"entity_type"`
+	EntityID   *uuid.UUID `json:"entity_id"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+}
+
+// handleExport handles streaming the audit log entries of a time range as
+// NDJSON, or as CSV (?format=csv or Accept: text/csv)
+func (h *AuditHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid audit log filter", err)
+		return
+	}
+	if err := filter.Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid audit log filter", err)
+		return
+	}
+
+	format, contentType := auditlog.FormatNDJSON, "application/x-ndjson"
+	if wantsCSV(r) {
+		format, contentType = auditlog.FormatCSV, "text/csv"
+	} else if value := r.URL.Query().Get("format"); value != "" && value != auditlog.FormatNDJSON {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid format", fmt.Errorf("unknown format %q, expected ndjson or csv", value))
+		return
+	}
+
+	encoder, err := auditlog.NewEncoder(format, w)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid format", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%s-%s.%s",
+		filter.From.UTC().Format("20060102"), filter.To.UTC().Format("20060102"), format))
+	w.WriteHeader(http.StatusOK)
+
+	count, err := h.service.Export(r.Context(), filter, &flushingEncoder{Encoder: encoder, w: w})
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated export
+		log.Printf("Failed to export audit log after %d entries: %v", count, err)
+	}
+}
+
+// flushingEncoder flushes the response every auditFlushInterval entries so
+// long exports stream to the client
+type flushingEncoder struct {
+	auditlog.Encoder
+	w     http.ResponseWriter
+	count int
+}
+
+func (e *flushingEncoder) Encode(entry *auditlog.Entry) error {
+	if err := e.Encoder.Encode(entry); err != nil {
+		return err
+	}
+	e.count++
+	if e.count%auditFlushInterval == 0 {
+		if err := e.Encoder.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := e.w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// parseAuditFilter reads an audit log filter from the query string
+func parseAuditFilter(r *http.Request) (auditlog.Filter, error) {
+	query := r.URL.Query()
+	filter := auditlog.Filter{
+		EntityType: query.Get("entity_type"),
+		Action:     query.Get("action"),
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(query.Get("from")); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseAuditTime(query.Get("to")); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+	if value := query.Get("entity_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid entity_id: %w", err)
+		}
+		filter.EntityID = &id
+	}
+	if value := query.Get("changed_by"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid changed_by: %w", err)
+		}
+		filter.ChangedBy = &id
+	}
+	if value := query.Get("include_archived"); value != "" {
+		if filter.IncludeArchived, err = strconv.ParseBool(value); err != nil {
+			return filter, fmt.Errorf("invalid include_archived: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// parseAuditTime parses an RFC 3339 time or a YYYY-MM-DD date; empty is the zero time
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// handleListArchives handles listing the audit archives, optionally overlapping ?from= and ?to=
+func (h *AuditHandler) handleListArchives(w http.ResponseWriter, r *http.Request) {
+	from, err := parseAuditTime(r.URL.Query().Get("from"))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid from", err)
+		return
+	}
+	to, err := parseAuditTime(r.URL.Query().Get("to"))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid to", err)
+		return
+	}
+
+	archives, err := h.service.ListArchives(r.Context(), from, to)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list audit archives", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"archives": archives})
+}
+
+// handleListPolicies handles listing retention policies
+func (h *AuditHandler) handleListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.service.ListPolicies(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list retention policies", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"policies": policies})
+}
+
+// handleCreatePolicy handles creating a retention policy
+func (h *AuditHandler) handleCreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var req RetentionPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	policy, err := h.service.CreatePolicy(r.Context(), req.policy(), h.getUserIDFromContext(r.Context()).String())
+	if err != nil {
+		h.respondWithAuditError(w, "Failed to create retention policy", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, policy)
+}
+
+// handleGetPolicy handles retrieving a retention policy
+func (h *AuditHandler) handleGetPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid policy ID", err)
+		return
+	}
+
+	policy, err := h.service.GetPolicy(r.Context(), id)
+	if err != nil {
+		h.respondWithAuditError(w, "Failed to get retention policy", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, policy)
+}
+
+// handleUpdatePolicy handles updating a retention policy
+func (h *AuditHandler) handleUpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid policy ID", err)
+		return
+	}
+
+	var req RetentionPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	policy, err := h.service.UpdatePolicy(r.Context(), id, req.policy(), h.getUserIDFromContext(r.Context()).String())
+	if err != nil {
+		h.respondWithAuditError(w, "Failed to update retention policy", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, policy)
+}
+
+// handleDeletePolicy handles deleting a retention policy
+func (h *AuditHandler) handleDeletePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid policy ID", err)
+		return
+	}
+
+	if err := h.service.DeletePolicy(r.Context(), id); err != nil {
+		h.respondWithAuditError(w, "Failed to delete retention policy", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Retention policy deleted successfully",
+	})
+}
+
+// handleGetEnforcement handles reporting the running or latest retention enforcement
+func (h *AuditHandler) handleGetEnforcement(w http.ResponseWriter, r *http.Request) {
+	report := h.service.LastEnforcement()
+	if report == nil {
+		h.respondWithError(w, http.StatusNotFound, "Retention has not been enforced since startup", nil)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, report)
+}
+
+// handleStartEnforcement handles applying the retention policies now
+func (h *AuditHandler) handleStartEnforcement(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.StartEnforcement(); err != nil {
+		h.respondWithAuditError(w, "Failed to start retention enforcement", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"message": "Retention enforcement started",
+	})
+}
+
+// handleListHolds handles listing legal holds, only the active ones with ?active=true
+func (h *AuditHandler) handleListHolds(w http.ResponseWriter, r *http.Request) {
+	activeOnly, _ := strconv.ParseBool(r.URL.Query().Get("active"))
+
+	holds, err := h.service.ListHolds(r.Context(), activeOnly)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list legal holds", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"holds": holds})
+}
+
+// handleCreateHold handles placing a legal hold
+func (h *AuditHandler) handleCreateHold(w http.ResponseWriter, r *http.Request) {
+	var req LegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	hold := &auditlog.LegalHold{
+		Name:       req.Name,
+		Reason:     req.Reason,
+		EntityType: req.EntityType,
+		EntityID:   req.EntityID,
+		From:       req.From,
+		To:         req.To,
+	}
+	hold, err := h.service.CreateHold(r.Context(), hold, h.getUserIDFromContext(r.Context()).String())
+	if err != nil {
+		h.respondWithAuditError(w, "Failed to place legal hold", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, hold)
+}
+
+// handleReleaseHold handles releasing a legal hold
+func (h *AuditHandler) handleReleaseHold(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid legal hold ID", err)
+		return
+	}
+
+	hold, err := h.service.ReleaseHold(r.Context(), id, h.getUserIDFromContext(r.Context()).String())
+	if err != nil {
+		h.respondWithAuditError(w, "Failed to release legal hold", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, hold)
+}
+
+// respondWithAuditError maps audit log errors to status codes
+func (h *AuditHandler) respondWithAuditError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, auditlog.ErrInvalidPolicy), errors.Is(err, auditlog.ErrInvalidHold), errors.Is(err, auditlog.ErrInvalidFilter):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	case errors.Is(err, auditlog.ErrPolicyNotFound), errors.Is(err, auditlog.ErrHoldNotFound):
+		h.respondWithError(w, http.StatusNotFound, message, err)
+	case errors.Is(err, auditlog.ErrEnforcementRunning):
+		h.respondWithError(w, http.StatusConflict, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role for the audit export, retention
+// policies and legal holds
+func (h *AuditHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *AuditHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *AuditHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *AuditHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -3,13 +3,16 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	"connect/internal/alertrule"
 	"connect/internal/auth"
 	"connect/internal/config"
 	"connect/internal/logger"
 	"connect/internal/models"
+	"connect/internal/organization"
 	"connect/internal/repositories"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -23,6 +26,9 @@ type AuthHandler struct {
 	jwtService     *auth.JWTService
 	userRepository *repositories.UserRepository
 	passwordService *auth.PasswordService
+	refreshTokens  *auth.RefreshTokenRotator
+	failedLogins   *alertrule.WindowCounter
+	organizations  *organization.Service
 }
 
 func NewAuthHandler(
@@ -41,6 +47,56 @@ func NewAuthHandler(
 	}
 }
 
+// SetRefreshTokenRotation makes refresh tokens single use: each refresh returns
+// a new refresh token and replaying a rotated one revokes its whole family
+func (h *AuthHandler) SetRefreshTokenRotation(rotator *auth.RefreshTokenRotator) {
+	h.refreshTokens = rotator
+}
+
+// SetFailedLogins counts failed logins for the auth.failed_logins alert metric
+func (h *AuthHandler) SetFailedLogins(counter *alertrule.WindowCounter) {
+	h.failedLogins = counter
+}
+
+// SetOrganizations scopes users to the organizations they are members of:
+// they log in acting for their default organization and may switch to
+// another of theirs
+func (h *AuthHandler) SetOrganizations(service *organization.Service) {
+	h.organizations = service
+}
+
+// defaultOrganization returns the organization a user logs in acting for
+func (h *AuthHandler) defaultOrganization(ctx context.Context, userID uuid.UUID) (string, error) {
+	if h.organizations == nil {
+		return "", nil
+	}
+	return h.organizations.DefaultTenant(ctx, userID)
+}
+
+// issueTokens generates the access and refresh tokens for a new login, acting
+// for org when it is set
+func (h *AuthHandler) issueTokens(ctx context.Context, userID, username string, roles []string, org string) (string, string, error) {
+	if h.refreshTokens != nil {
+		pair, err := h.refreshTokens.IssueForOrg(ctx, userID, username, roles, org)
+		if err != nil {
+			return "", "", err
+		}
+		return pair.AccessToken, pair.RefreshToken, nil
+	}
+
+	accessToken, err := h.jwtService.GenerateOrgAccessToken(userID, username, roles, org)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := h.jwtService.GenerateOrgRefreshToken(userID, username, roles, org, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
 // Register handles user registration
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateUserRequest
@@ -75,17 +131,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate tokens
-	accessToken, err := h.jwtService.GenerateAccessToken(user.ID.String(), user.Username, []string{"viewer"})
-	if err != nil {
-		h.logger.ErrorRequest(r, err, "Failed to generate access token")
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to generate tokens"})
-		return
-	}
-
-	refreshToken, err := h.jwtService.GenerateRefreshToken(user.ID.String(), user.Username, []string{"viewer"})
+	accessToken, refreshToken, err := h.issueTokens(r.Context(), user.ID.String(), user.Username, []string{"viewer"}, "")
 	if err != nil {
-		h.logger.ErrorRequest(r, err, "Failed to generate refresh token")
+		h.logger.ErrorRequest(r, err, "Failed to generate tokens")
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, map[string]string{"error": "Failed to generate tokens"})
 		return
@@ -127,6 +175,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	user, err := h.userRepository.Authenticate(r.Context(), req.Username, req.Password)
 	if err != nil {
 		if err == repositories.ErrUserNotFound || err == repositories.ErrInvalidPassword {
+			if h.failedLogins != nil {
+				h.failedLogins.Add()
+			}
 			h.logger.ErrorRequest(r, err, "Authentication failed")
 			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, map[string]string{"error": "Invalid credentials"})
@@ -147,18 +198,18 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// TODO: Get user roles from role repository
 	userRoles := []string{"viewer"} // Default role for now
 
-	// Generate tokens
-	accessToken, err := h.jwtService.GenerateAccessToken(user.ID.String(), user.Username, userRoles)
+	org, err := h.defaultOrganization(r.Context(), user.ID)
 	if err != nil {
-		h.logger.ErrorRequest(r, err, "Failed to generate access token")
+		h.logger.ErrorRequest(r, err, "Failed to get default organization")
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to generate tokens"})
+		render.JSON(w, r, map[string]string{"error": "Authentication failed"})
 		return
 	}
 
-	refreshToken, err := h.jwtService.GenerateRefreshToken(user.ID.String(), user.Username, userRoles)
+	// Generate tokens
+	accessToken, refreshToken, err := h.issueTokens(r.Context(), user.ID.String(), user.Username, userRoles, org)
 	if err != nil {
-		h.logger.ErrorRequest(r, err, "Failed to generate refresh token")
+		h.logger.ErrorRequest(r, err, "Failed to generate tokens")
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, map[string]string{"error": "Failed to generate tokens"})
 		return
@@ -171,6 +222,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		TokenType:    "Bearer",
 		ExpiresIn:    int64(h.config.Auth.AccessTokenTTL.Seconds()),
 		User:         user.ToResponse(userRoles),
+		Organization: org,
 	}
 
 	h.logger.InfoRequest(r, "User logged in successfully", "user_id", user.ID)
@@ -196,6 +248,11 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.refreshTokens != nil {
+		h.rotateRefreshToken(w, r, req.RefreshToken)
+		return
+	}
+
 	// Refresh access token
 	accessToken, err := h.jwtService.RefreshAccessToken(req.RefreshToken)
 	if err != nil {
@@ -236,6 +293,155 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, response)
 }
 
+// rotateRefreshToken exchanges a single-use refresh token for a new token pair
+func (h *AuthHandler) rotateRefreshToken(w http.ResponseWriter, r *http.Request, refreshToken string) {
+	pair, err := h.refreshTokens.Rotate(r.Context(), refreshToken, auth.ClientInfo{
+		IPAddress: r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			log.Warn().Str("remote_addr", r.RemoteAddr).Msg("Refresh token reuse detected, token family revoked")
+		}
+		h.logger.ErrorRequest(r, err, "Failed to rotate refresh token")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]string{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	claims, err := h.jwtService.ValidateToken(pair.AccessToken)
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to validate rotated access token")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to refresh token"})
+		return
+	}
+
+	// Get user info
+	user, err := h.userRepository.GetByID(r.Context(), uuid.MustParse(claims.UserID))
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to get user info")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]string{"error": "User not found"})
+		return
+	}
+
+	response := models.LoginResponse{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(h.config.Auth.AccessTokenTTL.Seconds()),
+		User:         user.ToResponse(claims.Roles),
+		Organization: claims.Org,
+	}
+
+	h.logger.InfoRequest(r, "Token refreshed successfully", map[string]interface{}{"user_id": user.ID})
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, response)
+}
+
+// ListOrganizations handles listing the organizations the caller is a member
+// of and the one they act for
+func (h *AuthHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		h.logger.ErrorRequest(r, nil, "User ID not found in context")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	memberships := []*organization.Membership{}
+	if h.organizations != nil {
+		var err error
+		memberships, err = h.organizations.Memberships(r.Context(), uuid.MustParse(userID))
+		if err != nil {
+			h.logger.ErrorRequest(r, err, "Failed to list organizations")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]string{"error": "Failed to list organizations"})
+			return
+		}
+	}
+
+	current, _ := auth.GetOrgFromContext(r.Context())
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"organizations": memberships, "current": current})
+}
+
+// SwitchOrganization handles switching the organization the caller acts for.
+// New tokens acting for it are issued; the caller must be a member of it.
+func (h *AuthHandler) SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		h.logger.ErrorRequest(r, nil, "User ID not found in context")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]string{"error": "Unauthorized"})
+		return
+	}
+	if actorType, _ := auth.GetActorTypeFromContext(r.Context()); actorType != auth.ActorTypeUser {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]string{"error": "Only users can switch organizations"})
+		return
+	}
+
+	var req models.SwitchOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Organization == "" {
+		h.logger.ErrorRequest(r, err, "Invalid switch organization request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+	if h.organizations == nil {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]string{"error": organization.ErrNotMember.Error()})
+		return
+	}
+
+	user, err := h.userRepository.GetByID(r.Context(), uuid.MustParse(userID))
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to get user info")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]string{"error": "User not found"})
+		return
+	}
+
+	org, err := h.organizations.Switch(r.Context(), user.ID, req.Organization)
+	if err != nil {
+		if errors.Is(err, organization.ErrNotMember) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, map[string]string{"error": err.Error()})
+			return
+		}
+		h.logger.ErrorRequest(r, err, "Failed to switch organization")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to switch organization"})
+		return
+	}
+
+	roles, _ := auth.GetUserRolesFromContext(r.Context())
+	accessToken, refreshToken, err := h.issueTokens(r.Context(), user.ID.String(), user.Username, roles, org.Slug)
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to generate tokens")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to generate tokens"})
+		return
+	}
+
+	h.logger.InfoRequest(r, "User switched organization", map[string]interface{}{
+		"user_id":      user.ID,
+		"organization": org.Slug,
+	})
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(h.config.Auth.AccessTokenTTL.Seconds()),
+		User:         user.ToResponse(roles),
+		Organization: org.Slug,
+	})
+}
+
 // ChangePassword handles password change
 func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	userID, ok := auth.GetUserIDFromContext(r.Context())
@@ -442,6 +648,13 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, map[string]string{"message": "Logged out successfully"})
 }
 
+// JWKS serves the public signing keys so other services can validate conx-issued tokens
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, h.jwtService.JWKS())
+}
+
 // Routes returns the auth routes
 func (h *AuthHandler) Routes() chi.Router {
 	r := chi.NewRouter()
@@ -466,6 +679,8 @@ func (h *AuthHandler) Routes() chi.Router {
 		r.Put("/profile", h.UpdateProfile)
 		r.Post("/change-password", h.ChangePassword)
 		r.Post("/logout", h.Logout)
+		r.Get("/organizations", h.ListOrganizations)
+		r.Post("/switch-organization", h.SwitchOrganization)
 	})
 
 	return r
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/autotag"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// AutoTagHandler handles the auto-tagging rule endpoints
+type AutoTagHandler struct {
+	service *autotag.Service
+}
+
+// NewAutoTagHandler creates a new AutoTagHandler
+func NewAutoTagHandler(service *autotag.Service) *AutoTagHandler {
+	return &AutoTagHandler{service: service}
+}
+
+// RegisterRoutes registers auto-tagging routes
+func (h *AutoTagHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/auto-tag-rules", h.authMiddleware(h.handleListRules)).Methods("GET")
+	router.HandleFunc("/api/v1/auto-tag-rules", h.authMiddleware(h.handleCreateRule)).Methods("POST")
+	router.HandleFunc("/api/v1/auto-tag-rules/dry-run", h.authMiddleware(h.handleDryRun)).Methods("POST")
+	router.HandleFunc("/api/v1/auto-tag-rules/backfill", h.authMiddleware(h.handleStartBackfill)).Methods("POST")
+	router.HandleFunc("/api/v1/auto-tag-rules/backfill", h.authMiddleware(h.handleGetBackfill)).Methods("GET")
+	router.HandleFunc("/api/v1/auto-tag-rules/{id}", h.authMiddleware(h.handleGetRule)).Methods("GET")
+	router.HandleFunc("/api/v1/auto-tag-rules/{id}", h.authMiddleware(h.handleUpdateRule)).Methods("PUT")
+	router.HandleFunc("/api/v1/auto-tag-rules/{id}", h.authMiddleware(h.handleDeleteRule)).Methods("DELETE")
+}
+
+// AutoTagRuleRequest represents a request to create or update an auto-tagging rule
+type AutoTagRuleRequest struct {
+	Name            string `json:"name"`
+	Attribute       string `json:"attribute"`
+	Operator        string `json:"operator"` // equals, contains, matches or exists
+	Value           string `json:"value"`
+	Tag             string `json:"tag"`
+	RemoveUnmatched bool   `json:"remove_unmatched"`
+	Enabled         *bool  `json:"enabled"` // defaults to true
+}
+
+// rule converts the request to a rule
+func (req *AutoTagRuleRequest) rule() *autotag.Rule {
+	rule := &autotag.Rule{
+		Name:            req.Name,
+		Attribute:       req.Attribute,
+		Operator:        req.Operator,
+		Value:           req.Value,
+		Tag:             req.Tag,
+		RemoveUnmatched: req.RemoveUnmatched,
+		Enabled:         true,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return rule
+}
+
+// AutoTagDryRunRequest represents a request to evaluate rules across the
+// existing CIs. Without rules, the stored enabled rules are evaluated.
+type AutoTagDryRunRequest struct {
+	Rules []AutoTagRuleRequest `json:"rules"`
+}
+
+// handleListRules handles listing auto-tagging rules
+func (h *AutoTagHandler) handleListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListRules(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list auto-tagging rules", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"rules": rules})
+}
+
+// handleCreateRule handles creating an auto-tagging rule. It applies to CIs
+// written from now on; run a backfill to apply it to the existing ones.
+func (h *AutoTagHandler) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req AutoTagRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	rule, err := h.service.CreateRule(ctx, req.rule(), userID.String())
+	if err != nil {
+		h.respondWithRuleError(w, "Failed to create auto-tagging rule", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, rule)
+}
+
+// handleGetRule handles retrieving an auto-tagging rule
+func (h *AutoTagHandler) handleGetRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid rule ID", err)
+		return
+	}
+
+	rule, err := h.service.GetRule(r.Context(), ruleID)
+	if err != nil {
+		h.respondWithRuleError(w, "Failed to get auto-tagging rule", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, rule)
+}
+
+// handleUpdateRule handles replacing an auto-tagging rule
+func (h *AutoTagHandler) handleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	ruleID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid rule ID", err)
+		return
+	}
+
+	var req AutoTagRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	rule := req.rule()
+	rule.ID = ruleID
+	rule, err = h.service.UpdateRule(ctx, rule, userID.String())
+	if err != nil {
+		h.respondWithRuleError(w, "Failed to update auto-tagging rule", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, rule)
+}
+
+// handleDeleteRule handles deleting an auto-tagging rule. Tags it applied stay on the CIs.
+func (h *AutoTagHandler) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	ruleID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid rule ID", err)
+		return
+	}
+
+	if err := h.service.DeleteRule(ctx, ruleID, userID.String()); err != nil {
+		h.respondWithRuleError(w, "Failed to delete auto-tagging rule", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Auto-tagging rule deleted successfully",
+	})
+}
+
+// handleDryRun handles evaluating rules across the existing CIs without changing them
+func (h *AutoTagHandler) handleDryRun(w http.ResponseWriter, r *http.Request) {
+	var req AutoTagDryRunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+
+	rules := make([]*autotag.Rule, 0, len(req.Rules))
+	for i := range req.Rules {
+		rules = append(rules, req.Rules[i].rule())
+	}
+
+	result, err := h.service.DryRun(r.Context(), rules)
+	if err != nil {
+		h.respondWithRuleError(w, "Failed to evaluate auto-tagging rules", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// handleStartBackfill handles applying the enabled rules to every existing CI
+// in the background
+func (h *AutoTagHandler) handleStartBackfill(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserIDFromContext(r.Context())
+
+	backfill, err := h.service.StartBackfill(userID.String())
+	if err != nil {
+		if errors.Is(err, autotag.ErrBackfillRunning) {
+			h.respondWithError(w, http.StatusConflict, "A backfill is already running", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to start backfill", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusAccepted, backfill)
+}
+
+// handleGetBackfill handles reporting the progress of the latest backfill
+func (h *AutoTagHandler) handleGetBackfill(w http.ResponseWriter, r *http.Request) {
+	backfill := h.service.Backfill()
+	if backfill == nil {
+		h.respondWithError(w, http.StatusNotFound, "No backfill has run", nil)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, backfill)
+}
+
+// respondWithRuleError maps rule errors to status codes
+func (h *AutoTagHandler) respondWithRuleError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, autotag.ErrInvalidRule):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	case errors.Is(err, autotag.ErrRuleNotFound):
+		h.respondWithError(w, http.StatusNotFound, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *AutoTagHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens and require the admin role
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *AutoTagHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *AutoTagHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *AutoTagHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/backpressure"
+	"github.com/gorilla/mux"
+)
+
+// BackpressureHandler handles the write throttling status endpoint
+type BackpressureHandler struct {
+	throttle *backpressure.Throttle
+}
+
+// NewBackpressureHandler creates a new BackpressureHandler
+func NewBackpressureHandler(throttle *backpressure.Throttle) *BackpressureHandler {
+	return &BackpressureHandler{throttle: throttle}
+}
+
+// RegisterRoutes registers backpressure routes
+func (h *BackpressureHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc(backpressure.AdminPath, h.authMiddleware(h.handleGetStats)).Methods("GET")
+}
+
+// handleGetStats handles retrieving the sync backlog and throttling counters
+func (h *BackpressureHandler) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, h.throttle.Stats(r.Context()))
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role to read the throttling statistics
+func (h *BackpressureHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// respondWithJSON sends a JSON response
+func (h *BackpressureHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
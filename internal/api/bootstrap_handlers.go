@@ -0,0 +1,110 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/bootstrap"
+	"connect/internal/config"
+	"connect/internal/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// BootstrapTokenHeader carries the configured bootstrap token
+const BootstrapTokenHeader = "X-Bootstrap-Token"
+
+// BootstrapHandler handles first-run setup of an empty database. Its routes
+// are unauthenticated, as no user exists to authenticate as yet.
+type BootstrapHandler struct {
+	config  *config.Config
+	logger  *logger.Logger
+	service *bootstrap.Service
+}
+
+func NewBootstrapHandler(config *config.Config, appLogger *logger.Logger, service *bootstrap.Service) *BootstrapHandler {
+	return &BootstrapHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+	}
+}
+
+// Status handles reporting whether the database was bootstrapped, so setup
+// tools know whether to ask for the initial admin user
+func (h *BootstrapHandler) Status(w http.ResponseWriter, r *http.Request) {
+	initialized, err := h.service.Initialized(r.Context())
+	if err != nil {
+		h.respondWithBootstrapError(w, r, "Failed to check bootstrap status", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{
+		"initialized":    initialized,
+		"token_required": h.config.Bootstrap.Token != "",
+	})
+}
+
+// Initialize handles creating the initial admin user, the default roles and
+// permissions and the starter schemas. It is refused with 409 once any user
+// exists.
+func (h *BootstrapHandler) Initialize(w http.ResponseWriter, r *http.Request) {
+	if token := h.config.Bootstrap.Token; token != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get(BootstrapTokenHeader)), []byte(token)) != 1 {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]string{"error": "Invalid bootstrap token"})
+		return
+	}
+
+	var admin bootstrap.Admin
+	if err := json.NewDecoder(r.Body).Decode(&admin); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode bootstrap request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := h.service.Initialize(r.Context(), admin)
+	if err != nil {
+		h.respondWithBootstrapError(w, r, "Failed to bootstrap", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Database bootstrapped", map[string]interface{}{
+		"admin_id":            result.AdminID,
+		"roles_created":       result.RolesCreated,
+		"permissions_created": result.PermissionsCreated,
+		"schemas_created":     result.SchemasCreated,
+	})
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+// Routes returns the bootstrap routes
+func (h *BootstrapHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.Status)
+	r.Post("/", h.Initialize)
+
+	return r
+}
+
+// respondWithBootstrapError maps bootstrap errors to status codes
+func (h *BootstrapHandler) respondWithBootstrapError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, bootstrap.ErrInvalidAdmin):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, bootstrap.ErrAlreadyInitialized):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": "The database is already initialized"})
+	default:
+		h.logger.ErrorRequest(r, err, message)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": message})
+	}
+}
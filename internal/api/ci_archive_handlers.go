@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/ciarchive"
+	"connect/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// ArchiveHandler handles the archived CI endpoints
+type ArchiveHandler struct {
+	service *ciarchive.Service
+}
+
+// NewArchiveHandler creates a new ArchiveHandler
+func NewArchiveHandler(service *ciarchive.Service) *ArchiveHandler {
+	return &ArchiveHandler{service: service}
+}
+
+// RegisterRoutes registers archive routes
+func (h *ArchiveHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/archive/cis", h.authMiddleware(h.handleListArchivedCIs)).Methods("GET")
+	router.HandleFunc("/api/v1/archive/cis/{id}", h.authMiddleware(h.handleGetArchivedCI)).Methods("GET")
+	router.HandleFunc("/api/v1/archive/cis/{id}/restore", h.authMiddleware(h.handleRestoreArchivedCI)).Methods("POST")
+
+	router.HandleFunc("/api/v1/admin/ci-archive", h.authMiddleware(h.handleGetLastRun)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/ci-archive", h.authMiddleware(h.handleRun)).Methods("POST")
+}
+
+// handleListArchivedCIs handles listing archived CIs, e.g. ?type=server&search=web
+func (h *ArchiveHandler) handleListArchivedCIs(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	filter := models.ArchivedCIFilter{
+		Type:     params.String("type"),
+		Search:   params.String("search"),
+		Page:     params.Int("page", 1, 1, 0),
+		PageSize: params.PageSize("page_size"),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	list, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list archived CIs", err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, list)
+}
+
+// handleGetArchivedCI handles retrieving an archived CI as it was archived
+func (h *ArchiveHandler) handleGetArchivedCI(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	ci, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrArchivedCINotFound) {
+			h.respondWithError(w, http.StatusNotFound, "Archived CI not found", nil)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get archived CI", err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, ci)
+}
+
+// handleRestoreArchivedCI handles moving an archived CI back to the live tables
+func (h *ArchiveHandler) handleRestoreArchivedCI(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	ci, err := h.service.Restore(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrArchivedCINotFound):
+			h.respondWithError(w, http.StatusNotFound, "Archived CI not found", nil)
+		case errors.Is(err, models.ErrArchiveRestoreConflict):
+			h.respondWithError(w, http.StatusConflict, "Failed to restore archived CI", err)
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to restore archived CI", err)
+		}
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, ci)
+}
+
+// handleGetLastRun handles returning the outcome of the last archive run
+func (h *ArchiveHandler) handleGetLastRun(w http.ResponseWriter, r *http.Request) {
+	lastRun := h.service.LastRun()
+	if lastRun == nil {
+		h.respondWithError(w, http.StatusNotFound, "CI archiving has not run yet", nil)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, lastRun)
+}
+
+// handleRun handles archiving inactive CIs now
+func (h *ArchiveHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	result := h.service.Archive(r.Context())
+	if result.Error != "" {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to archive inactive CIs", errors.New(result.Error))
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *ArchiveHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithError sends an error response
+func (h *ArchiveHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *ArchiveHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
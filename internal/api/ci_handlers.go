@@ -1,25 +1,350 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"strconv"
+	"path"
+	"sort"
 	"strings"
 
+	"connect/internal/auth"
+	"connect/internal/attrtrigger"
+	"connect/internal/autotag"
+	"connect/internal/cialias"
+	"connect/internal/cisummary"
+	"connect/internal/federation"
 	"connect/internal/models"
+	"connect/internal/pagination"
+	"connect/internal/pathpolicy"
+	"connect/internal/quarantine"
+	"connect/internal/quota"
+	"connect/internal/relationshipbulk"
+	"connect/internal/reparent"
 	"connect/internal/repositories"
+	"connect/internal/scripthooks"
+	"connect/internal/visibility"
+	"connect/internal/webhooks"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 // CIHandler handles CI-related endpoints
 type CIHandler struct {
-	ciRepo *repositories.CIRepository
+	ciRepo     *repositories.CIRepository
+	visibility *visibility.Resolver
+	autoTags   *autotag.Service
+	hooks      *scripthooks.Service
+	pathRules  *pathpolicy.Service
+	federation *federation.Service
+	summaries  *cisummary.Cache
+	quotas     *quota.Service
+	quarantine *quarantine.Service
+	reparenting *reparent.Service
+	bulk       *relationshipbulk.Service
+	aliases    *cialias.Service
+	triggers   *attrtrigger.Service
+	webhooks   *webhooks.Service
 }
 
 // NewCIHandler creates a new CIHandler
 func NewCIHandler(ciRepo *repositories.CIRepository) *CIHandler {
-	return &CIHandler{ciRepo: ciRepo}
+	h := &CIHandler{ciRepo: ciRepo}
+	h.reparenting = reparent.NewService(ciRepo, h.checkPathRules)
+	h.bulk = relationshipbulk.NewService(ciRepo, h.checkPathRules, relationshipbulk.Options{})
+	return h
+}
+
+// SetVisibility restricts list, search and relationship responses to the CIs the caller may see
+func (h *CIHandler) SetVisibility(resolver *visibility.Resolver) {
+	h.visibility = resolver
+}
+
+// SetAutoTagging applies the auto-tagging rules to CIs on create, update and clone
+func (h *CIHandler) SetAutoTagging(service *autotag.Service) {
+	h.autoTags = service
+}
+
+// applyAutoTags updates the tags of a CI about to be written from the
+// auto-tagging rules. Rules failing to load never block the write.
+func (h *CIHandler) applyAutoTags(ctx context.Context, ci *models.CI) {
+	if h.autoTags == nil {
+		return
+	}
+	tags, change, err := h.autoTags.Apply(ctx, ci.Attributes, ci.Tags)
+	if err != nil {
+		log.Printf("Failed to apply auto-tagging rules to CI %s: %v", ci.ID, err)
+		return
+	}
+	if !change.IsEmpty() {
+		ci.Tags = tags
+	}
+}
+
+// SetScriptHooks runs the pre-save and post-save script hooks on create, update and clone
+func (h *CIHandler) SetScriptHooks(service *scripthooks.Service) {
+	h.hooks = service
+}
+
+// runPreSaveHooks runs the pre-save script hooks on a CI about to be written,
+// letting them update its attributes. When a hook vetoes the save it responds
+// and returns false.
+func (h *CIHandler) runPreSaveHooks(ctx context.Context, w http.ResponseWriter, ci, previous *models.CI) bool {
+	if h.hooks == nil {
+		return true
+	}
+	err := h.hooks.PreSave(ctx, ci, previous)
+	var rejected *scripthooks.RejectedError
+	if errors.As(err, &rejected) {
+		h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   rejected.Message,
+			"success": false,
+			"details": map[string]interface{}{"hook_id": rejected.HookID, "hook": rejected.Hook},
+		})
+		return false
+	}
+	return true
+}
+
+// runPostSaveHooks runs the post-save script hooks on a written CI, fires
+// the attribute triggers its changes match and publishes its webhook event
+func (h *CIHandler) runPostSaveHooks(ctx context.Context, ci, previous *models.CI) {
+	if h.hooks != nil {
+		h.hooks.PostSave(ctx, ci, previous)
+	}
+	if h.triggers != nil {
+		h.triggers.Evaluate(ctx, ci, previous)
+	}
+	if previous == nil {
+		h.publishEvent(ctx, webhooks.EventCICreated, ci)
+	} else {
+		h.publishEvent(ctx, webhooks.EventCIUpdated, ci)
+	}
+}
+
+// SetWebhooks publishes CI and relationship lifecycle events to the webhook endpoints
+func (h *CIHandler) SetWebhooks(service *webhooks.Service) {
+	h.webhooks = service
+}
+
+// publishEvent publishes a webhook event, if webhooks are enabled
+func (h *CIHandler) publishEvent(ctx context.Context, eventType string, data interface{}) {
+	if h.webhooks != nil {
+		h.webhooks.Publish(ctx, eventType, data)
+	}
+}
+
+// SetAttributeTriggers fires the attribute change triggers on create, update and clone
+func (h *CIHandler) SetAttributeTriggers(service *attrtrigger.Service) {
+	h.triggers = service
+}
+
+// SetPathRules checks relationships against the relationship path rules when
+// they are created or made active
+func (h *CIHandler) SetPathRules(service *pathpolicy.Service) {
+	h.pathRules = service
+}
+
+// checkPathRules checks a relationship against the path rules, if any are set
+func (h *CIHandler) checkPathRules(ctx context.Context, relationshipType string, sourceID, targetID uuid.UUID) error {
+	return checkRelationshipPath(ctx, h.pathRules, h.ciRepo, relationshipType, sourceID, targetID)
+}
+
+// enforcePathRules checks a relationship about to be written against the path
+// rules. When it breaks one it responds and returns false.
+func (h *CIHandler) enforcePathRules(ctx context.Context, w http.ResponseWriter, relationshipType string, sourceID, targetID uuid.UUID) bool {
+	err := checkRelationshipPath(ctx, h.pathRules, h.ciRepo, relationshipType, sourceID, targetID)
+	if err == nil {
+		return true
+	}
+
+	var violation *pathpolicy.ViolationError
+	if errors.As(err, &violation) {
+		h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Relationship breaks path rules",
+			"success": false,
+			"details": violation.Violations,
+		})
+		return false
+	}
+	h.respondWithError(w, http.StatusInternalServerError, "Failed to check relationship path rules", err)
+	return false
+}
+
+// enforceRelationshipEndpoints checks that both CIs of a relationship about to
+// be created or activated exist and are live and active. When one is not it
+// responds and returns false.
+func (h *CIHandler) enforceRelationshipEndpoints(ctx context.Context, w http.ResponseWriter, sourceID, targetID uuid.UUID) bool {
+	err := h.ciRepo.CheckRelationshipEndpoints(ctx, sourceID, targetID)
+	if err == nil {
+		return true
+	}
+	if !h.respondWithEndpointError(w, err) {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to check relationship endpoints", err)
+	}
+	return false
+}
+
+// respondWithEndpointError responds to a relationship rejected because of one
+// of its CIs, with a code telling which end and why, and reports whether err was one
+func (h *CIHandler) respondWithEndpointError(w http.ResponseWriter, err error) bool {
+	var endpointErr *models.RelationshipEndpointError
+	if !errors.As(err, &endpointErr) {
+		return false
+	}
+	h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"error":   "Relationship endpoint is not a live, active CI",
+		"code":    endpointErr.Code(),
+		"ci_id":   endpointErr.CIID,
+		"success": false,
+		"details": endpointErr.Error(),
+	})
+	return true
+}
+
+// respondWithPrimaryError responds with 409 when err rejects a primary
+// relationship, naming the relationship already primary, and reports whether
+// it did
+func (h *CIHandler) respondWithPrimaryError(w http.ResponseWriter, err error) bool {
+	var primaryErr *models.PrimaryRelationshipError
+	switch {
+	case errors.As(err, &primaryErr):
+		h.respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":                   "Source CI already has a primary relationship of this type",
+			"primary_relationship_id": primaryErr.ExistingID,
+			"success":                 false,
+			"details":                 primaryErr.Error(),
+		})
+		return true
+	case errors.Is(err, models.ErrPrimaryRelationshipDeprecated):
+		h.respondWithError(w, http.StatusConflict, "Deprecated relationships cannot be primary", err)
+		return true
+	}
+	return false
+}
+
+// SetFederation merges the records of external systems of record into the
+// CI lists of the types they serve, and serves those records by ID
+func (h *CIHandler) SetFederation(service *federation.Service) {
+	h.federation = service
+}
+
+// SetSummaryCache serves the CI summaries of relationship endpoints from a cache
+// instead of loading them for every request
+func (h *CIHandler) SetSummaryCache(cache *cisummary.Cache) {
+	h.summaries = cache
+}
+
+// SetQuotas checks created CIs against the per-tenant and per-type CI quotas
+func (h *CIHandler) SetQuotas(service *quota.Service) {
+	h.quotas = service
+}
+
+// checkQuota checks creating a CI against the quotas and responds with an
+// error when a hard limit rejects it
+func (h *CIHandler) checkQuota(w http.ResponseWriter, r *http.Request, ci *models.CI) bool {
+	if h.quotas == nil {
+		return true
+	}
+	if err := h.quotas.Check(r.Context(), ci.Type, quota.CITenant(ci.Attributes)); err != nil {
+		h.respondWithError(w, http.StatusForbidden, "CI quota exceeded", err)
+		return false
+	}
+	return true
+}
+
+// SetQuarantine holds the CIs reported by discovery sources that cannot be
+// matched or validated against a schema in quarantine instead of rejecting them
+func (h *CIHandler) SetQuarantine(service *quarantine.Service) {
+	h.quarantine = service
+}
+
+// SetAliases redirects requests for the former IDs of merged CIs to the CI
+// they were merged into
+func (h *CIHandler) SetAliases(service *cialias.Service) {
+	h.aliases = service
+}
+
+// withEndpoints expands relationships with summaries of the CIs at both ends,
+// loading the summaries in one batch
+func (h *CIHandler) withEndpoints(ctx context.Context, relationships []*models.CIRelationship) ([]models.RelationshipWithEndpoints, error) {
+	ids := make([]uuid.UUID, 0, 2*len(relationships))
+	for _, rel := range relationships {
+		ids = append(ids, rel.SourceCIID, rel.TargetCIID)
+	}
+
+	summaries := make(map[uuid.UUID]models.CISummary, len(ids))
+	if h.summaries != nil {
+		cached, err := h.summaries.Get(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		summaries = cached
+	} else {
+		loaded, err := h.ciRepo.GetCISummaries(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range loaded {
+			summaries[summary.ID] = summary
+		}
+	}
+
+	expanded := make([]models.RelationshipWithEndpoints, len(relationships))
+	for i, rel := range relationships {
+		expanded[i].CIRelationship = rel
+		if summary, ok := summaries[rel.SourceCIID]; ok {
+			expanded[i].Source = &summary
+		}
+		if summary, ok := summaries[rel.TargetCIID]; ok {
+			expanded[i].Target = &summary
+		}
+	}
+	return expanded, nil
+}
+
+// listFederatedCIs responds with the local CIs of a federated type merged with
+// the records of its sources. The merged list is paginated in memory.
+func (h *CIHandler) listFederatedCIs(w http.ResponseWriter, r *http.Request, req *models.ListCIsRequest) {
+	ctx := r.Context()
+	page, pageSize := req.Page, req.PageSize
+
+	var local []models.CI
+	req.PageSize = pagination.FromContext(ctx).Max
+	for req.Page = 1; len(local) < federation.MaxLocalRecords; req.Page++ {
+		response, err := h.ciRepo.ListCIs(ctx, req)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to list CIs", err)
+			return
+		}
+		local = append(local, response.CIs...)
+		if req.Page >= response.TotalPages {
+			break
+		}
+	}
+
+	records, sources := h.federation.Merge(ctx, req.Type, local, req)
+
+	start := (page - 1) * pageSize
+	if start > len(records) {
+		start = len(records)
+	}
+	end := start + pageSize
+	if end > len(records) {
+		end = len(records)
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"cis":         records[start:end],
+		"total_count": len(records),
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (len(records) + pageSize - 1) / pageSize,
+		"sources":     sources,
+	})
 }
 
 // RegisterRoutes registers CI-related routes
@@ -27,13 +352,25 @@ func (h *CIHandler) RegisterRoutes(router *mux.Router) {
 	// CI CRUD routes
 	router.HandleFunc("/api/v1/cis", h.authMiddleware(h.handleListCIs)).Methods("GET")
 	router.HandleFunc("/api/v1/cis", h.authMiddleware(h.handleCreateCI)).Methods("POST")
-	router.HandleFunc("/api/v1/cis/{id}", h.authMiddleware(h.handleGetCI)).Methods("GET")
-	router.HandleFunc("/api/v1/cis/{id}", h.authMiddleware(h.handleUpdateCI)).Methods("PUT")
-	router.HandleFunc("/api/v1/cis/{id}", h.authMiddleware(h.handleDeleteCI)).Methods("DELETE")
+	router.HandleFunc("/api/v1/cis/batch-get", h.authMiddleware(h.handleBatchGetCIs)).Methods("POST")
+	router.HandleFunc("/api/v1/cis/{id}", h.authMiddleware(h.handleGetCI)).Methods("GET").MatcherFunc(notReservedCIPath)
+	router.HandleFunc("/api/v1/cis/{id}", h.authMiddleware(h.handleUpdateCI)).Methods("PUT").MatcherFunc(notReservedCIPath)
+	router.HandleFunc("/api/v1/cis/{id}", h.authMiddleware(h.handleDeleteCI)).Methods("DELETE").MatcherFunc(notReservedCIPath)
+	router.HandleFunc("/api/v1/cis/{id}/delete-preview", h.authMiddleware(h.handleGetDeletionPreview)).Methods("GET")
+	router.HandleFunc("/api/v1/cis/{id}/clone", h.authMiddleware(h.handleCloneCI)).Methods("POST")
+	router.HandleFunc("/api/v1/cis/{id}/history", h.authMiddleware(h.handleGetCIHistory)).Methods("GET")
+	router.HandleFunc("/api/v1/cis/{id}/restore/{revision}", h.authMiddleware(h.handleRestoreCIRevision)).Methods("POST")
 
 	// CI relationship routes
 	router.HandleFunc("/api/v1/cis/{id}/relationships", h.authMiddleware(h.handleGetRelationships)).Methods("GET")
+	router.HandleFunc("/api/v1/relationships", h.authMiddleware(h.handleListRelationships)).Methods("GET")
 	router.HandleFunc("/api/v1/relationships", h.authMiddleware(h.handleCreateRelationship)).Methods("POST")
+	router.HandleFunc("/api/v1/relationships/batch-get", h.authMiddleware(h.handleBatchGetRelationships)).Methods("POST")
+	router.HandleFunc("/api/v1/relationships/reparent", h.authMiddleware(h.handleReparentRelationships)).Methods("POST")
+	router.HandleFunc("/api/v1/relationships/bulk", h.authMiddleware(h.handleBulkRelationships)).Methods("POST")
+	router.HandleFunc("/api/v1/relationships/{id}/state", h.authMiddleware(h.handleTransitionRelationshipState)).Methods("PUT")
+	router.HandleFunc("/api/v1/relationships/{id}/primary", h.authMiddleware(h.handleSetPrimaryRelationship)).Methods("PUT")
+	router.HandleFunc("/api/v1/relationships/{id}/primary", h.authMiddleware(h.handleClearPrimaryRelationship)).Methods("DELETE")
 	router.HandleFunc("/api/v1/relationships/{id}", h.authMiddleware(h.handleDeleteRelationship)).Methods("DELETE")
 }
 
@@ -43,36 +380,32 @@ func (h *CIHandler) RegisterRoutes(router *mux.Router) {
 func (h *CIHandler) handleListCIs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Parse query parameters
-	req := &models.ListCIsRequest{
-		Page:     1,
-		PageSize: 20,
+	profile, err := responseProfile(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid profile", err)
+		return
 	}
 
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			req.Page = page
-		}
+	// Parse query parameters
+	params := bindRequest(r)
+	req := bindCIFilter(params)
+	req.Page = params.Int("page", 1, 1, 0)
+	req.PageSize = params.ProfilePageSize("page_size", profile)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
 	}
 
-	if pageSizeStr := r.URL.Query().Get("page_size"); pageSizeStr != "" {
-		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil && pageSize > 0 && pageSize <= 100 {
-			req.PageSize = pageSize
-		}
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return
 	}
+	req.Scope = scope
 
-	req.Search = r.URL.Query().Get("search")
-	req.Type = r.URL.Query().Get("type")
-	req.Status = r.URL.Query().Get("status")
-	req.Criticality = r.URL.Query().Get("criticality")
-	req.Owner = r.URL.Query().Get("owner")
-	req.Location = r.URL.Query().Get("location")
-	req.SortBy = r.URL.Query().Get("sort_by")
-	req.SortOrder = r.URL.Query().Get("sort_order")
-
-	// Parse tags
-	if tagsStr := r.URL.Query().Get("tags"); tagsStr != "" {
-		req.Tags = strings.Split(tagsStr, ",")
+	if h.federation != nil && h.federation.Federated(req.Type) {
+		h.listFederatedCIs(w, r, req)
+		return
 	}
 
 	// Get CIs
@@ -82,6 +415,22 @@ func (h *CIHandler) handleListCIs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if profile == profileCompact {
+		cis, err := projectCIs(ctx, h.ciRepo, profile, response.CIs)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve display names", err)
+			return
+		}
+		h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"cis":         cis,
+			"total_count": response.TotalCount,
+			"page":        response.Page,
+			"page_size":   response.PageSize,
+			"total_pages": response.TotalPages,
+		})
+		return
+	}
+
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
@@ -96,6 +445,12 @@ func (h *CIHandler) handleCreateCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	source, err := models.ParseProvenanceSource(r.Header.Get(models.ProvenanceHeader))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid change source", err)
+		return
+	}
+
 	// Create CI object
 	ci := &models.CI{
 		ID:           uuid.New(),
@@ -106,6 +461,8 @@ func (h *CIHandler) handleCreateCI(w http.ResponseWriter, r *http.Request) {
 		Criticality:  req.Criticality,
 		Owner:        req.Owner,
 		Location:     req.Location,
+		OrgUnit:      req.OrgUnit,
+		CostCenter:   req.CostCenter,
 		Attributes:   req.Attributes,
 		Tags:         req.Tags,
 		InstallDate:  req.InstallDate,
@@ -114,6 +471,33 @@ func (h *CIHandler) handleCreateCI(w http.ResponseWriter, r *http.Request) {
 		UpdatedBy:    userID,
 	}
 
+	if !h.validateOrgAssignment(w, r, ci) {
+		return
+	}
+	if !h.assignTenant(w, r, ci) {
+		return
+	}
+	if !h.runPreSaveHooks(ctx, w, ci, nil) {
+		return
+	}
+	h.applyAutoTags(ctx, ci)
+	if !h.checkQuota(w, r, ci) {
+		return
+	}
+
+	// Discovered assets that do not match a schema are held for review
+	if h.quarantine != nil && source.Type == models.ProvenanceSourceDiscovery {
+		item, err := h.quarantine.Screen(ctx, ci, source.Name)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to screen discovered CI", err)
+			return
+		}
+		if item != nil {
+			h.respondWithJSON(w, http.StatusAccepted, item)
+			return
+		}
+	}
+
 	// Try to get schema for CI type validation
 	schema, err := h.ciRepo.GetCISchemaByType(ctx, req.Type)
 	if err == nil {
@@ -123,6 +507,8 @@ func (h *CIHandler) handleCreateCI(w http.ResponseWriter, r *http.Request) {
 			h.respondWithError(w, http.StatusInternalServerError, "Failed to create CI with validation", err)
 			return
 		}
+		recordProvenance(ctx, h.ciRepo, createdCI.ID, nil, createdCI.Attributes, source, userID)
+		h.runPostSaveHooks(ctx, createdCI, nil)
 		h.respondWithJSON(w, http.StatusCreated, createdCI)
 		return
 	}
@@ -134,37 +520,163 @@ func (h *CIHandler) handleCreateCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordProvenance(ctx, h.ciRepo, createdCI.ID, nil, createdCI.Attributes, source, userID)
+	h.runPostSaveHooks(ctx, createdCI, nil)
 	h.respondWithJSON(w, http.StatusCreated, createdCI)
 }
 
 // handleGetCI handles retrieving a CI by ID
 func (h *CIHandler) handleGetCI(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	vars := mux.Vars(r)
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
+		return
+	}
 
-	ciID, err := uuid.Parse(vars["id"])
+	profile, err := responseProfile(r)
 	if err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
+		h.respondWithError(w, http.StatusBadRequest, "Invalid profile", err)
 		return
 	}
 
 	ci, err := h.ciRepo.GetCI(ctx, ciID)
 	if err != nil {
+		// Federated records are not stored, so look them up in their sources
+		if h.federation != nil {
+			if record, err := h.federation.Get(ctx, ciID); err == nil {
+				h.respondWithJSON(w, http.StatusOK, record)
+				return
+			}
+		}
+		if h.respondWithMovedCI(w, r, ciID) {
+			return
+		}
 		h.respondWithError(w, http.StatusNotFound, "CI not found", err)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, ci)
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return
+	}
+	if scope != nil && !scope.Allows(ci) {
+		h.respondWithError(w, http.StatusNotFound, "CI not found", nil)
+		return
+	}
+
+	if hasInclude(r, "provenance") {
+		provenance, err := h.ciRepo.GetAttributeProvenance(ctx, ciID)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to get attribute provenance", err)
+			return
+		}
+		h.respondWithJSON(w, http.StatusOK, models.CIWithProvenance{CI: ci, Provenance: provenance})
+		return
+	}
+
+	payload, err := projectCI(ctx, h.ciRepo, profile, ci)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve display names", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, payload)
+}
+
+// respondWithMovedCI responds with 301 and the CI a merged CI was merged into
+// when ciID is the ID of one the caller may see, and reports whether it did
+func (h *CIHandler) respondWithMovedCI(w http.ResponseWriter, r *http.Request, ciID uuid.UUID) bool {
+	if h.aliases == nil {
+		return false
+	}
+	resolution, err := h.aliases.ResolveID(r.Context(), ciID)
+	if err != nil || !resolution.Moved {
+		return false
+	}
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil || (scope != nil && !scope.Allows(resolution.CI)) {
+		return false
+	}
+
+	w.Header().Set("Location", "/api/v1/cis/"+resolution.CI.ID.String())
+	h.respondWithJSON(w, http.StatusMovedPermanently, map[string]interface{}{
+		"error":    "CI was merged into another CI",
+		"success":  false,
+		"moved_to": resolution.CI.ID,
+		"alias":    resolution.Alias,
+	})
+	return true
+}
+
+// handleBatchGetCIs handles fetching several CIs by ID in one round trip. CIs
+// that do not exist or that the caller may not see are listed as missing.
+// Federated records are not looked up.
+func (h *CIHandler) handleBatchGetCIs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	profile, err := responseProfile(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid profile", err)
+		return
+	}
+
+	var req models.BatchGetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid batch get request", err)
+		return
+	}
+
+	loaded, err := h.ciRepo.GetCIsByIDs(ctx, req.IDs)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get CIs", err)
+		return
+	}
+
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return
+	}
+
+	byID := make(map[uuid.UUID]models.CI, len(loaded))
+	for _, ci := range loaded {
+		if scope == nil || scope.Allows(&ci) {
+			byID[ci.ID] = ci
+		}
+	}
+	cis := make([]models.CI, 0, len(byID))
+	missing := []uuid.UUID{}
+	for _, id := range req.IDs {
+		if ci, ok := byID[id]; ok {
+			cis = append(cis, ci)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	found, err := projectCIs(ctx, h.ciRepo, profile, cis)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve display names", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, models.BatchGetResult{Found: found, Missing: missing})
 }
 
 // handleUpdateCI handles updating an existing CI
 func (h *CIHandler) handleUpdateCI(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	userID := h.getUserIDFromContext(ctx)
-	vars := mux.Vars(r)
-
-	ciID, err := uuid.Parse(vars["id"])
-	if err != nil {
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
 		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
 		return
 	}
@@ -175,6 +687,9 @@ func (h *CIHandler) handleUpdateCI(w http.ResponseWriter, r *http.Request) {
 		h.respondWithError(w, http.StatusNotFound, "CI not found", err)
 		return
 	}
+	if !h.authorizeCI(w, r, existingCI) {
+		return
+	}
 
 	var req models.UpdateCIRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -182,6 +697,14 @@ func (h *CIHandler) handleUpdateCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	source, err := models.ParseProvenanceSource(r.Header.Get(models.ProvenanceHeader))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid change source", err)
+		return
+	}
+	previousAttributes := existingCI.Attributes
+	previousCI := *existingCI
+
 	// Update CI fields
 	if req.Name != "" {
 		existingCI.Name = req.Name
@@ -204,6 +727,13 @@ func (h *CIHandler) handleUpdateCI(w http.ResponseWriter, r *http.Request) {
 	if req.Location != "" {
 		existingCI.Location = req.Location
 	}
+	orgChanged := req.OrgUnit != "" || req.CostCenter != ""
+	if req.OrgUnit != "" {
+		existingCI.OrgUnit = req.OrgUnit
+	}
+	if req.CostCenter != "" {
+		existingCI.CostCenter = req.CostCenter
+	}
 	if len(req.Attributes) > 0 {
 		existingCI.Attributes = req.Attributes
 	}
@@ -227,6 +757,20 @@ func (h *CIHandler) handleUpdateCI(w http.ResponseWriter, r *http.Request) {
 	}
 	existingCI.UpdatedBy = userID
 
+	if err := visibility.CheckTenantUnchanged(&previousCI, existingCI); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid CI attributes", err)
+		return
+	}
+
+	// Existing assignments stay valid when their list entry is deactivated later
+	if orgChanged && !h.validateOrgAssignment(w, r, existingCI) {
+		return
+	}
+	if !h.runPreSaveHooks(ctx, w, existingCI, &previousCI) {
+		return
+	}
+	h.applyAutoTags(ctx, existingCI)
+
 	// Try to get schema for CI type validation
 	schema, err := h.ciRepo.GetCISchemaByType(ctx, existingCI.Type)
 	if err == nil {
@@ -236,6 +780,8 @@ func (h *CIHandler) handleUpdateCI(w http.ResponseWriter, r *http.Request) {
 			h.respondWithError(w, http.StatusInternalServerError, "Failed to update CI with validation", err)
 			return
 		}
+		recordProvenance(ctx, h.ciRepo, updatedCI.ID, previousAttributes, updatedCI.Attributes, source, userID)
+		h.runPostSaveHooks(ctx, updatedCI, &previousCI)
 		h.respondWithJSON(w, http.StatusOK, updatedCI)
 		return
 	}
@@ -247,77 +793,444 @@ func (h *CIHandler) handleUpdateCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordProvenance(ctx, h.ciRepo, updatedCI.ID, previousAttributes, updatedCI.Attributes, source, userID)
+	h.runPostSaveHooks(ctx, updatedCI, &previousCI)
 	h.respondWithJSON(w, http.StatusOK, updatedCI)
 }
 
-// handleDeleteCI handles deleting a CI
-func (h *CIHandler) handleDeleteCI(w http.ResponseWriter, r *http.Request) {
+// handleGetDeletionPreview handles reporting what deleting a CI would affect, so
+// the UI can show it in the confirmation dialog
+func (h *CIHandler) handleGetDeletionPreview(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	vars := mux.Vars(r)
-
-	ciID, err := uuid.Parse(vars["id"])
-	if err != nil {
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
 		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
 		return
 	}
 
-	if err := h.ciRepo.DeleteCI(ctx, ciID); err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Failed to delete CI", err)
+	ci, err := h.ciRepo.GetCI(ctx, ciID)
+	if err != nil {
+		h.respondWithError(w, http.StatusNotFound, "CI not found", err)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, map[string]string{"message": "CI deleted successfully"})
-}
-
-// Relationship Handlers
-
-// handleGetRelationships handles retrieving relationships for a CI
-func (h *CIHandler) handleGetRelationships(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	vars := mux.Vars(r)
-
-	ciID, err := uuid.Parse(vars["id"])
+	scope, err := resolveVisibility(r, h.visibility)
 	if err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
 		return
 	}
-
-	// Check if CI exists
-	_, err = h.ciRepo.GetCI(ctx, ciID)
-	if err != nil {
-		h.respondWithError(w, http.StatusNotFound, "CI not found", err)
+	if scope != nil && !scope.Allows(ci) {
+		h.respondWithError(w, http.StatusNotFound, "CI not found", nil)
 		return
 	}
 
-	relationships, err := h.ciRepo.GetRelationshipsByCI(ctx, ciID)
+	preview, err := h.ciRepo.GetDeletionPreview(ctx, ci, scope)
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Failed to get relationships", err)
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to preview deletion", err)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, relationships)
+	h.respondWithJSON(w, http.StatusOK, preview)
 }
 
-// handleCreateRelationship handles creating a new relationship
-func (h *CIHandler) handleCreateRelationship(w http.ResponseWriter, r *http.Request) {
+// handleDeleteCI handles deleting a CI
+func (h *CIHandler) handleDeleteCI(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := h.getUserIDFromContext(ctx)
-
-	var req models.CreateRelationshipRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
 		return
 	}
 
-	// Check for circular dependency
-	hasCircular, err := h.ciRepo.CheckCircularDependency(ctx, req.SourceCIID, req.TargetCIID, req.Type)
+	ci, err := h.ciRepo.GetCI(ctx, ciID)
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Failed to check circular dependency", err)
+		h.respondWithError(w, http.StatusNotFound, "CI not found", err)
+		return
+	}
+	if !h.authorizeCI(w, r, ci) {
 		return
 	}
 
-	if hasCircular {
-		h.respondWithError(w, http.StatusBadRequest, "Circular dependency detected", nil)
+	if err := h.ciRepo.DeleteCI(ctx, ciID); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to delete CI", err)
+		return
+	}
+	h.publishEvent(ctx, webhooks.EventCIDeleted, map[string]interface{}{"id": ciID})
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"message": "CI deleted successfully"})
+}
+
+// handleGetCIHistory handles listing the revisions of a CI, newest first. Each
+// revision holds the CI as it was before an update, delete or restore; pages
+// continue with ?before=<next_before>.
+func (h *CIHandler) handleGetCIHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	limit := params.Int("limit", 50, 1, 500)
+	var before *int
+	if params.String("before") != "" {
+		n := params.Int("before", 0, 1, 0)
+		before = &n
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	history, err := h.ciRepo.ListCIRevisions(ctx, ciID, before, limit)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get CI history", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, history)
+}
+
+// handleRestoreCIRevision handles putting a CI back as it was at a revision,
+// undeleting it when needed. The replaced state becomes a new revision.
+func (h *CIHandler) handleRestoreCIRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	revision := params.PathInt("revision", 1)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	var previousCI *models.CI
+	if existing, err := h.ciRepo.GetCI(ctx, ciID); err == nil {
+		previousCI = existing
+	}
+
+	restoredCI, err := h.ciRepo.RestoreCIRevision(ctx, ciID, revision, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrCIRevisionNotFound):
+			h.respondWithError(w, http.StatusNotFound, "CI revision not found", err)
+		case errors.Is(err, models.ErrCIRevisionRestoreConflict):
+			h.respondWithError(w, http.StatusConflict, "Failed to restore CI revision", err)
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to restore CI revision", err)
+		}
+		return
+	}
+
+	h.runPostSaveHooks(ctx, restoredCI, previousCI)
+	h.respondWithJSON(w, http.StatusOK, restoredCI)
+}
+
+// handleCloneCI handles duplicating a CI, optionally together with its relationships
+func (h *CIHandler) handleCloneCI(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+	params := bindRequest(r)
+	sourceID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
+		return
+	}
+
+	var req models.CloneCIRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := req.Normalize(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid clone request", err)
+		return
+	}
+
+	source, err := models.ParseProvenanceSource(r.Header.Get(models.ProvenanceHeader))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid change source", err)
+		return
+	}
+
+	sourceCI, err := h.ciRepo.GetCI(ctx, sourceID)
+	if err != nil {
+		h.respondWithError(w, http.StatusNotFound, "CI not found", err)
+		return
+	}
+
+	// CIs of types without a schema are cloned without validation
+	schema, err := h.ciRepo.GetCISchemaByType(ctx, sourceCI.Type)
+	if errors.Is(err, sql.ErrNoRows) {
+		schema = nil
+	} else if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get CI type schema", err)
+		return
+	}
+
+	tags := sourceCI.Tags
+	if req.Tags != nil {
+		tags = req.Tags
+	}
+
+	// Build and validate every clone before creating any of them
+	clones := make([]*models.CI, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		index := req.StartIndex + i
+		name, err := req.CloneName(sourceCI, index)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Failed to build clone name", err)
+			return
+		}
+
+		attributes, err := req.CloneAttributes(sourceCI, schema, index, name)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Failed to build clone attributes", err)
+			return
+		}
+
+		clone := &models.CI{
+			ID:             uuid.New(),
+			Name:           name,
+			Type:           sourceCI.Type,
+			Description:    sourceCI.Description,
+			Status:         sourceCI.Status,
+			Criticality:    sourceCI.Criticality,
+			Owner:          sourceCI.Owner,
+			Location:       sourceCI.Location,
+			OrgUnit:        sourceCI.OrgUnit,
+			CostCenter:     sourceCI.CostCenter,
+			Attributes:     attributes,
+			Tags:           tags,
+			InstallDate:    sourceCI.InstallDate,
+			WarrantyExpiry: sourceCI.WarrantyExpiry,
+			CreatedBy:      userID,
+			UpdatedBy:      userID,
+		}
+		if !h.runPreSaveHooks(ctx, w, clone, nil) {
+			return
+		}
+		h.applyAutoTags(ctx, clone)
+
+		if schema != nil {
+			result, err := h.ciRepo.ValidateCIAgainstSchema(ctx, clone, schema)
+			if err != nil {
+				h.respondWithError(w, http.StatusInternalServerError, "Failed to validate clone", err)
+				return
+			}
+			if !result.IsValid {
+				h.respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+					"error":   "Clone " + name + " failed schema validation",
+					"success": false,
+					"details": result.Errors,
+				})
+				return
+			}
+		}
+
+		clones = append(clones, clone)
+	}
+
+	// Collect the relationships to copy onto each clone
+	var relationships []*models.CIRelationship
+	if req.CloneRelationships {
+		sourceRelationships, err := h.ciRepo.GetRelationshipsByCI(ctx, sourceID)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to get relationships", err)
+			return
+		}
+		selected := req.SelectRelationships(sourceRelationships)
+		for _, clone := range clones {
+			relationships = append(relationships, models.CloneRelationships(selected, sourceID, clone.ID, userID)...)
+		}
+	}
+
+	// Every clone and relationship is created or none is
+	createdCIs, createdRels, err := h.ciRepo.CreateCIClones(ctx, clones, schema, relationships)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to create clones", err)
+		return
+	}
+	for _, createdCI := range createdCIs {
+		recordProvenance(ctx, h.ciRepo, createdCI.ID, nil, createdCI.Attributes, source, userID)
+		h.runPostSaveHooks(ctx, createdCI, nil)
+	}
+
+	response := models.CloneCIResponse{
+		SourceID:      sourceID.String(),
+		CIs:           createdCIs,
+		Relationships: createdRels,
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, response)
+}
+
+// Relationship Handlers
+
+// handleGetRelationships handles retrieving relationships for a CI
+func (h *CIHandler) handleGetRelationships(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
+		return
+	}
+
+	// Check if CI exists
+	ci, err := h.ciRepo.GetCI(ctx, ciID)
+	if err != nil {
+		h.respondWithError(w, http.StatusNotFound, "CI not found", err)
+		return
+	}
+
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return
+	}
+	var filter *visibility.Filter
+	if scope != nil {
+		filter = visibility.NewFilter(*scope, h.ciRepo)
+		if !filter.Remember(ci) {
+			h.respondWithError(w, http.StatusNotFound, "CI not found", nil)
+			return
+		}
+	}
+
+	// Only active relationships are returned unless other states are requested
+	states := []string{models.RelationshipStateActive}
+	if stateStr := r.URL.Query().Get("state"); stateStr != "" {
+		states = strings.Split(stateStr, ",")
+		for _, state := range states {
+			if !models.IsValidRelationshipState(state) {
+				h.respondWithError(w, http.StatusBadRequest, "Invalid relationship state", nil)
+				return
+			}
+		}
+	}
+
+	relationships, err := h.ciRepo.GetRelationshipsByCIAndState(ctx, ciID, states)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get relationships", err)
+		return
+	}
+
+	if filter != nil {
+		relationships = filter.Relationships(ctx, relationships)
+	}
+
+	if hasInclude(r, "endpoints") {
+		expanded, err := h.withEndpoints(ctx, relationships)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve relationship endpoints", err)
+			return
+		}
+		h.respondWithJSON(w, http.StatusOK, expanded)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, relationships)
+}
+
+// handleBatchGetRelationships handles fetching several relationships by ID in
+// one round trip. Relationships that do not exist or that touch a CI the
+// caller may not see are listed as missing.
+func (h *CIHandler) handleBatchGetRelationships(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req models.BatchGetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid batch get request", err)
+		return
+	}
+
+	loaded, err := h.ciRepo.GetRelationshipsByIDs(ctx, req.IDs)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get relationships", err)
+		return
+	}
+
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return
+	}
+	if scope != nil {
+		loaded = visibility.NewFilter(*scope, h.ciRepo).Relationships(ctx, loaded)
+	}
+
+	byID := make(map[uuid.UUID]*models.CIRelationship, len(loaded))
+	for _, rel := range loaded {
+		byID[rel.ID] = rel
+	}
+	relationships := make([]*models.CIRelationship, 0, len(byID))
+	missing := []uuid.UUID{}
+	for _, id := range req.IDs {
+		if rel, ok := byID[id]; ok {
+			relationships = append(relationships, rel)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	var found interface{} = relationships
+	if hasInclude(r, "endpoints") {
+		expanded, err := h.withEndpoints(ctx, relationships)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve relationship endpoints", err)
+			return
+		}
+		found = expanded
+	}
+
+	h.respondWithJSON(w, http.StatusOK, models.BatchGetResult{Found: found, Missing: missing})
+}
+
+// handleCreateRelationship handles creating a new relationship
+func (h *CIHandler) handleCreateRelationship(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req models.CreateRelationshipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.State == "" {
+		state, err := defaultRelationshipState(r)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid change source", err)
+			return
+		}
+		req.State = state
+	}
+	if req.State != models.RelationshipStateProposed && req.State != models.RelationshipStateActive {
+		h.respondWithError(w, http.StatusBadRequest, "New relationships must be proposed or active", nil)
+		return
+	}
+
+	if !h.enforceRelationshipEndpoints(ctx, w, req.SourceCIID, req.TargetCIID) {
+		return
+	}
+
+	// Check for circular dependency
+	hasCircular, err := h.ciRepo.CheckCircularDependency(ctx, req.SourceCIID, req.TargetCIID, req.Type)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to check circular dependency", err)
+		return
+	}
+
+	if hasCircular {
+		h.respondWithError(w, http.StatusBadRequest, "Circular dependency detected", nil)
+		return
+	}
+
+	if !h.enforcePathRules(ctx, w, req.Type, req.SourceCIID, req.TargetCIID) {
 		return
 	}
 
@@ -329,6 +1242,8 @@ func (h *CIHandler) handleCreateRelationship(w http.ResponseWriter, r *http.Requ
 		Type:         req.Type,
 		Attributes:   req.Attributes,
 		Description:  req.Description,
+		State:        req.State,
+		IsPrimary:    req.IsPrimary,
 		CreatedBy:    userID,
 		UpdatedBy:    userID,
 	}
@@ -339,9 +1254,13 @@ func (h *CIHandler) handleCreateRelationship(w http.ResponseWriter, r *http.Requ
 		// Schema found, create with validation
 		createdRelationship, err := h.ciRepo.CreateRelationshipWithValidation(ctx, relationship, schema)
 		if err != nil {
+			if h.respondWithEndpointError(w, err) || h.respondWithPrimaryError(w, err) {
+				return
+			}
 			h.respondWithError(w, http.StatusInternalServerError, "Failed to create relationship with validation", err)
 			return
 		}
+		h.publishEvent(ctx, webhooks.EventRelationshipCreated, createdRelationship)
 		h.respondWithJSON(w, http.StatusCreated, createdRelationship)
 		return
 	}
@@ -349,20 +1268,314 @@ func (h *CIHandler) handleCreateRelationship(w http.ResponseWriter, r *http.Requ
 	// No schema found, create without validation
 	createdRelationship, err := h.ciRepo.CreateRelationship(ctx, relationship)
 	if err != nil {
+		if h.respondWithEndpointError(w, err) || h.respondWithPrimaryError(w, err) {
+			return
+		}
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to create relationship", err)
 		return
 	}
 
+	h.publishEvent(ctx, webhooks.EventRelationshipCreated, createdRelationship)
 	h.respondWithJSON(w, http.StatusCreated, createdRelationship)
 }
 
-// handleDeleteRelationship handles deleting a relationship
-func (h *CIHandler) handleDeleteRelationship(w http.ResponseWriter, r *http.Request) {
+// handleListRelationships handles listing relationships by lifecycle state, e.g. proposed edges awaiting confirmation.
+// Relationships can be filtered by attribute like CIs, e.g. ?attr.port=5432&attr.protocol=tcp, and sorted by
+// created_at or strength.
+func (h *CIHandler) handleListRelationships(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	vars := mux.Vars(r)
 
-	relationshipID, err := uuid.Parse(vars["id"])
+	params := bindRequest(r)
+	req := &models.ListRelationshipsRequest{
+		State:      params.Enum("state", models.RelationshipStateActive, models.RelationshipStates),
+		Attributes: bindAttributeFilters(params),
+		SortBy:     params.Enum("sort_by", models.RelationshipSortCreatedAt, models.RelationshipSortFields),
+		SortOrder:  params.Enum("sort_order", models.SortOrderDesc, models.SortOrders),
+		Page:       params.Int("page", 1, 1, 0),
+		PageSize:   params.PageSize("page_size"),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+	page, pageSize := req.Page, req.PageSize
+
+	// Edges touching CIs the caller may not see are left out of both the page
+	// and total_count
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return
+	}
+	req.Scope = scope
+
+	relationships, totalCount, err := h.ciRepo.SearchRelationships(ctx, req)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list relationships", err)
+		return
+	}
+
+	var payload interface{} = relationships
+	if hasInclude(r, "endpoints") {
+		expanded, err := h.withEndpoints(ctx, relationships)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve relationship endpoints", err)
+			return
+		}
+		payload = expanded
+	}
+
+	response := map[string]interface{}{
+		"relationships": payload,
+		"total_count":   totalCount,
+		"page":          page,
+		"page_size":     pageSize,
+	}
+
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// handleTransitionRelationshipState handles confirming, deprecating or reinstating a relationship
+func (h *CIHandler) handleTransitionRelationshipState(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+	params := bindRequest(r)
+	relationshipID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid relationship ID", err)
+		return
+	}
+
+	var req models.TransitionRelationshipStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	existing, err := h.ciRepo.GetRelationship(ctx, relationshipID)
+	if err != nil {
+		h.respondWithError(w, http.StatusNotFound, "Relationship not found", err)
+		return
+	}
+
+	if err := models.ValidateRelationshipStateTransition(existing.State, req.State); err != nil {
+		h.respondWithError(w, http.StatusConflict, "Invalid state transition", err)
+		return
+	}
+
+	// Rules added since the relationship was proposed apply when it is confirmed
+	if req.State == models.RelationshipStateActive && !h.enforcePathRules(ctx, w, existing.Type, existing.SourceCIID, existing.TargetCIID) {
+		return
+	}
+
+	relationship, err := h.ciRepo.TransitionRelationshipState(ctx, relationshipID, req.State, userID)
 	if err != nil {
+		if h.respondWithEndpointError(w, err) {
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to transition relationship state", err)
+		return
+	}
+
+	h.publishEvent(ctx, webhooks.EventRelationshipUpdated, relationship)
+	h.respondWithJSON(w, http.StatusOK, relationship)
+}
+
+// handleSetPrimaryRelationship handles making a relationship the primary one
+// of its type for its source CI; the one that was primary no longer is
+func (h *CIHandler) handleSetPrimaryRelationship(w http.ResponseWriter, r *http.Request) {
+	h.setPrimaryRelationship(w, r, true)
+}
+
+// handleClearPrimaryRelationship handles clearing the primary flag of a relationship
+func (h *CIHandler) handleClearPrimaryRelationship(w http.ResponseWriter, r *http.Request) {
+	h.setPrimaryRelationship(w, r, false)
+}
+
+func (h *CIHandler) setPrimaryRelationship(w http.ResponseWriter, r *http.Request, primary bool) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+	params := bindRequest(r)
+	relationshipID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid relationship ID", err)
+		return
+	}
+
+	relationship, err := h.ciRepo.SetPrimaryRelationship(ctx, relationshipID, primary, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.respondWithError(w, http.StatusNotFound, "Relationship not found", err)
+			return
+		}
+		if h.respondWithPrimaryError(w, err) {
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to set primary relationship", err)
+		return
+	}
+
+	h.publishEvent(ctx, webhooks.EventRelationshipUpdated, relationship)
+	h.respondWithJSON(w, http.StatusOK, relationship)
+}
+
+// handleReparentRelationships handles moving the relationships of one CI to
+// another, e.g. {"from_ci_id": "...", "to_ci_id": "...", "types": ["RUNS_ON"],
+// "direction": "incoming", "dry_run": true}. Relationships the new CI already
+// has, or that link the two CIs, stay on the old one and are listed as skipped.
+func (h *CIHandler) handleReparentRelationships(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req reparent.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.reparenting.Reparent(ctx, req, userID)
+	if err != nil {
+		var violation *pathpolicy.ViolationError
+		var cycle *reparent.CycleError
+		switch {
+		case errors.As(err, &violation):
+			h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"error":   "Moved relationship breaks path rules",
+				"success": false,
+				"details": violation.Violations,
+			})
+		case errors.As(err, &cycle):
+			h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"error":            "Moving the relationships would create a cycle",
+				"relationship_ids": cycle.RelationshipIDs,
+				"success":          false,
+				"details":          cycle.Error(),
+			})
+		case errors.Is(err, reparent.ErrConcurrentChange):
+			h.respondWithError(w, http.StatusConflict, "Relationships changed while they were being moved", err)
+		case h.respondWithEndpointError(w, err):
+		case errors.Is(err, reparent.ErrMissingCI), errors.Is(err, reparent.ErrSameCI), errors.Is(err, reparent.ErrInvalidDirection):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid re-parenting request", err)
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to move relationships", err)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// handleBulkRelationships handles creating and deleting many relationships in
+// one transaction, e.g. {"operations": [{"op": "delete", "id": "..."},
+// {"op": "create", "source_ci_id": "...", "target_ci_id": "...", "type": "RUNS_ON"}],
+// "dry_run": true}. Either every operation is applied or none is; a rejected
+// operation is named by its index.
+func (h *CIHandler) handleBulkRelationships(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req relationshipbulk.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	state, err := defaultRelationshipState(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid change source", err)
+		return
+	}
+
+	result, err := h.bulk.Apply(ctx, req, state, userID)
+	if err != nil {
+		h.respondWithBulkError(w, err)
+		return
+	}
+
+	if !result.DryRun {
+		for _, change := range result.Deleted {
+			h.publishEvent(ctx, webhooks.EventRelationshipDeleted, map[string]interface{}{"id": change.Relationship.ID})
+		}
+		for _, change := range result.Created {
+			h.publishEvent(ctx, webhooks.EventRelationshipCreated, change.Relationship)
+		}
+	}
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// respondWithBulkError responds to a rejected bulk request, naming the
+// operations that caused it
+func (h *CIHandler) respondWithBulkError(w http.ResponseWriter, err error) {
+	var opErr *relationshipbulk.OperationError
+	var cycle *relationshipbulk.CycleError
+	var violation *pathpolicy.ViolationError
+	var endpointErr *models.RelationshipEndpointError
+	switch {
+	case errors.As(err, &cycle):
+		h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "The created relationships would create a cycle",
+			"indexes": cycle.Indexes,
+			"success": false,
+			"details": cycle.Error(),
+		})
+	case errors.Is(err, relationshipbulk.ErrNoOperations), errors.Is(err, relationshipbulk.ErrTooManyOperations):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid bulk request", err)
+	case !errors.As(err, &opErr):
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to apply relationship operations", err)
+	case errors.As(err, &violation):
+		h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Relationship breaks path rules",
+			"index":   opErr.Index,
+			"success": false,
+			"details": violation.Violations,
+		})
+	case errors.As(err, &endpointErr):
+		h.respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Relationship endpoint is not a live, active CI",
+			"index":   opErr.Index,
+			"code":    endpointErr.Code(),
+			"ci_id":   endpointErr.CIID,
+			"success": false,
+			"details": endpointErr.Error(),
+		})
+	case errors.Is(err, relationshipbulk.ErrNotFound):
+		h.respondWithJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error":   "Relationship not found",
+			"index":   opErr.Index,
+			"success": false,
+			"details": opErr.Error(),
+		})
+	case errors.Is(err, relationshipbulk.ErrInvalidOperation), errors.Is(err, relationshipbulk.ErrDuplicateOperation):
+		h.respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "Invalid operation",
+			"index":   opErr.Index,
+			"success": false,
+			"details": opErr.Error(),
+		})
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to apply relationship operations", err)
+	}
+}
+
+// defaultRelationshipState is the state of relationships created without one:
+// relationships reported by discovery need confirmation before they become
+// active
+func defaultRelationshipState(r *http.Request) (string, error) {
+	source, err := models.ParseProvenanceSource(r.Header.Get(models.ProvenanceHeader))
+	if err != nil {
+		return "", err
+	}
+	if source.Type == models.ProvenanceSourceDiscovery {
+		return models.RelationshipStateProposed, nil
+	}
+	return models.RelationshipStateActive, nil
+}
+
+// handleDeleteRelationship handles deleting a relationship
+func (h *CIHandler) handleDeleteRelationship(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := bindRequest(r)
+	relationshipID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
 		h.respondWithError(w, http.StatusBadRequest, "Invalid relationship ID", err)
 		return
 	}
@@ -371,6 +1584,7 @@ func (h *CIHandler) handleDeleteRelationship(w http.ResponseWriter, r *http.Requ
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to delete relationship", err)
 		return
 	}
+	h.publishEvent(ctx, webhooks.EventRelationshipDeleted, map[string]interface{}{"id": relationshipID})
 
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"message": "Relationship deleted successfully"})
 }
@@ -393,6 +1607,185 @@ func (h *CIHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
 	return uuid.New()
 }
 
+// recordProvenance records source as the last writer of the attributes that differ between before and after.
+// Failures are logged rather than returned so they never fail the write itself.
+func recordProvenance(ctx context.Context, ciRepo *repositories.CIRepository, ciID uuid.UUID, before, after json.RawMessage, source models.ProvenanceSource, userID uuid.UUID) {
+	changed, err := models.ChangedAttributes(before, after)
+	if err != nil {
+		log.Printf("Failed to diff attributes of CI %s: %v", ciID, err)
+		return
+	}
+
+	if err := ciRepo.RecordAttributeProvenance(ctx, ciID, changed, source, userID); err != nil {
+		log.Printf("Failed to record attribute provenance for CI %s: %v", ciID, err)
+	}
+}
+
+// resolveVisibility returns the visibility scope of the caller, or nil when no resolver
+// is configured and responses are not filtered
+func resolveVisibility(r *http.Request, resolver *visibility.Resolver) (*models.VisibilityScope, error) {
+	if resolver == nil {
+		return nil, nil
+	}
+
+	// API keys see what their permissions grant rather than what roles do
+	if scopes, ok := auth.GetScopesFromContext(r.Context()); ok {
+		scope := visibility.ScopeFromPermissions(scopes, visibility.TenantFromContext(r.Context()))
+		return &scope, nil
+	}
+
+	roles, _ := auth.GetUserRolesFromContext(r.Context())
+	scope, err := resolver.Scope(r.Context(), roles)
+	if err != nil {
+		return nil, err
+	}
+	return &scope, nil
+}
+
+// authorizeCI responds with 404 when the caller may not see a CI they are
+// about to change, so CIs of other tenants can neither be changed nor probed
+// for by ID. Without a resolver the caller's tenant still applies.
+func (h *CIHandler) authorizeCI(w http.ResponseWriter, r *http.Request, ci *models.CI) bool {
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return false
+	}
+	if scope == nil {
+		tenantScope := visibility.TenantScope(r.Context())
+		scope = &tenantScope
+	}
+	if !scope.Allows(ci) {
+		h.respondWithError(w, http.StatusNotFound, "CI not found", nil)
+		return false
+	}
+	return true
+}
+
+// validateOrgAssignment checks the CI's org unit and cost center against the
+// managed lists and responds with an error when they are not valid
+func (h *CIHandler) validateOrgAssignment(w http.ResponseWriter, r *http.Request, ci *models.CI) bool {
+	err := h.ciRepo.ValidateOrgAssignment(r.Context(), ci.OrgUnit, ci.CostCenter)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, models.ErrInvalidOrgAssignment) {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid org unit or cost center", err)
+	} else {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to validate org unit and cost center", err)
+	}
+	return false
+}
+
+// assignTenant stamps a CI created by a caller acting for an organization with
+// its tenant and responds with an error when the CI names another tenant
+func (h *CIHandler) assignTenant(w http.ResponseWriter, r *http.Request, ci *models.CI) bool {
+	tenant := visibility.TenantFromContext(r.Context())
+	if tenant == "" {
+		return true
+	}
+
+	attributes := map[string]interface{}{}
+	if len(ci.Attributes) > 0 {
+		if err := json.Unmarshal(ci.Attributes, &attributes); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid CI attributes", err)
+			return false
+		}
+		if attributes == nil {
+			attributes = map[string]interface{}{}
+		}
+	}
+	if current, ok := attributes[models.TenantAttribute]; ok {
+		if current != tenant {
+			h.respondWithError(w, http.StatusForbidden, "CI belongs to another organization", nil)
+			return false
+		}
+		return true
+	}
+
+	attributes[models.TenantAttribute] = tenant
+	stamped, err := json.Marshal(attributes)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to assign CI tenant", err)
+		return false
+	}
+	ci.Attributes = stamped
+	return true
+}
+
+// bindCIFilter binds the filter and sort query parameters of a CI listing
+func bindCIFilter(params *requestParams) *models.ListCIsRequest {
+	return &models.ListCIsRequest{
+		Search:      params.String("search"),
+		Type:        params.String("type"),
+		Status:      params.Enum("status", "", models.CIStatuses),
+		Criticality: params.Enum("criticality", "", models.CICriticalities),
+		Owner:       params.String("owner"),
+		Location:    params.String("location"),
+		OrgUnit:     params.String("org_unit"),
+		CostCenter:  params.String("cost_center"),
+		Tags:        params.Strings("tags"),
+		SortBy:      params.Enum("sort_by", "", models.CISortFields),
+		SortOrder:   params.Enum("sort_order", "", models.SortOrders),
+		Attributes:  bindAttributeFilters(params),
+	}
+}
+
+// bindAttributeFilters returns the attr.<key>[.<operator>] query parameters as
+// attribute filters, ordered by parameter name
+func bindAttributeFilters(params *requestParams) []models.AttributeFilter {
+	names := make([]string, 0)
+	for name := range params.query {
+		if strings.HasPrefix(name, models.AttributeFilterPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var filters []models.AttributeFilter
+	for _, name := range names {
+		for _, value := range params.query[name] {
+			filter, err := models.ParseAttributeFilter(name, strings.TrimSpace(value))
+			if err != nil {
+				params.invalid(name, "%s", err.Error())
+				continue
+			}
+			filters = append(filters, filter)
+		}
+	}
+	if len(filters) > models.MaxAttributeFilters {
+		params.invalid("attr", "at most %d attribute filters may be given", models.MaxAttributeFilters)
+		return nil
+	}
+	return filters
+}
+
+// reservedCIPaths are the fixed paths under /api/v1/cis served by other
+// handlers, which the CI routes must not take for a CI ID
+var reservedCIPaths = map[string]bool{
+	"quarantine": true,
+	"batch-get":  true,
+	"import":     true,
+	"export":     true,
+	"resolve":    true,
+}
+
+// notReservedCIPath matches the requests to /api/v1/cis/{id} whose ID is not a reserved path
+func notReservedCIPath(r *http.Request, rm *mux.RouteMatch) bool {
+	return !reservedCIPaths[path.Base(r.URL.Path)]
+}
+
+// hasInclude reports whether the comma-separated include query parameter requests the given expansion
+func hasInclude(r *http.Request, name string) bool {
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(include) == name {
+			return true
+		}
+	}
+	return false
+}
+
 // respondWithError sends an error response
 func (h *CIHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
 	response := map[string]interface{}{
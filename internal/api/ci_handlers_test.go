@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIHandler_RevisionParams(t *testing.T) {
+	// The handler has no repository, so these requests must be rejected
+	// before reaching it
+	h := &CIHandler{}
+	ciID := uuid.New().String()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		query   string
+		vars    map[string]string
+		field   string
+	}{
+		{"invalid CI ID", h.handleGetCIHistory, "GET", "", map[string]string{"id": "web-01"}, "id"},
+		{"zero before", h.handleGetCIHistory, "GET", "?before=0", map[string]string{"id": ciID}, "before"},
+		{"oversized limit", h.handleGetCIHistory, "GET", "?limit=501", map[string]string{"id": ciID}, "limit"},
+		{"zero revision", h.handleRestoreCIRevision, "POST", "", map[string]string{"id": ciID, "revision": "0"}, "revision"},
+		{"non-numeric revision", h.handleRestoreCIRevision, "POST", "", map[string]string{"id": ciID, "revision": "latest"}, "revision"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mux.SetURLVars(httptest.NewRequest(tt.method, "/api/v1/cis/x/history"+tt.query, nil), tt.vars)
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"`+tt.field+`":`)
+		})
+	}
+}
+
+func TestBindAttributeFilters(t *testing.T) {
+	params := bindQuery(url.Values{
+		"attr.os":             {" linux "},
+		"attr.cpu.gte":        {"4"},
+		"attr.ports.contains": {"443", "8443"},
+		"search":              {"web"},
+	}, nil)
+	filters := bindAttributeFilters(params)
+	require.NoError(t, params.Err())
+	assert.Equal(t, []models.AttributeFilter{
+		{Key: "cpu", Operator: models.AttributeOpGte, Value: "4"},
+		{Key: "os", Operator: models.AttributeOpEq, Value: "linux"},
+		{Key: "ports", Operator: models.AttributeOpContains, Value: "443"},
+		{Key: "ports", Operator: models.AttributeOpContains, Value: "8443"},
+	}, filters, "filters are ordered by parameter name")
+
+	params = bindQuery(url.Values{
+		"attr.os.like": {"linux"},
+		"attr.cpu.gt":  {"many"},
+		"attr.rack":    {" "},
+	}, nil)
+	assert.Empty(t, bindAttributeFilters(params))
+	paramErr, ok := params.Err().(*paramError)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"attr.os.like": "operator must be one of eq, neq, gt, gte, lt, lte, contains",
+		"attr.cpu.gt":  "gt requires a number",
+		"attr.rack":    "value is required",
+	}, paramErr.Fields)
+
+	query := url.Values{}
+	for i := 0; i <= models.MaxAttributeFilters; i++ {
+		query.Set(fmt.Sprintf("attr.key%d", i), "value")
+	}
+	params = bindQuery(query, nil)
+	assert.Nil(t, bindAttributeFilters(params))
+	paramErr, ok = params.Err().(*paramError)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"attr": "at most 10 attribute filters may be given"}, paramErr.Fields)
+}
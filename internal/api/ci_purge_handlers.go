@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/cipurge"
+	"connect/internal/config"
+	"connect/internal/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CIPurgeHandler handles permanently purging CIs for legal erasure, admin only
+type CIPurgeHandler struct {
+	config  *config.Config
+	logger  *logger.Logger
+	service *cipurge.Service
+}
+
+func NewCIPurgeHandler(config *config.Config, appLogger *logger.Logger, service *cipurge.Service) *CIPurgeHandler {
+	return &CIPurgeHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+	}
+}
+
+// Preview handles the dry run of purging a CI, e.g.
+// ?ci_id=...&audit_policy=delete, without requesting the purge
+func (h *CIPurgeHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	ciID := params.UUID("ci_id")
+	auditPolicy := params.Enum("audit_policy", cipurge.AuditRedact, cipurge.AuditPolicies)
+	if params.String("ci_id") == "" {
+		params.invalid("ci_id", "is required")
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	report, err := h.service.Preview(r.Context(), *ciID, auditPolicy)
+	if err != nil {
+		h.respondWithPurgeError(w, r, "Failed to preview purge", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, report)
+}
+
+// Request handles requesting a purge. The response holds the dry-run report
+// the approvers will see.
+func (h *CIPurgeHandler) Request(w http.ResponseWriter, r *http.Request) {
+	var req cipurge.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode purge request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	purge, err := h.service.Request(r.Context(), req, actorID(r))
+	if err != nil {
+		h.respondWithPurgeError(w, r, "Failed to request purge", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "CI purge requested", map[string]interface{}{
+		"purge_id": purge.ID,
+		"ci_id":    purge.CIID,
+	})
+	w.Header().Set("Location", "/api/v1/purges/"+purge.ID.String())
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, purge)
+}
+
+// List handles listing recent purge requests, optionally in one status
+func (h *CIPurgeHandler) List(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	status := params.Enum("status", "", cipurge.Statuses)
+	limit := params.Int("limit", cipurge.DefaultListLimit, 1, 500)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	purges, err := h.service.List(r.Context(), status, limit)
+	if err != nil {
+		h.respondWithPurgeError(w, r, "Failed to list purge requests", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"purges": purges, "count": len(purges)})
+}
+
+// Get handles getting a purge request with its report
+func (h *CIPurgeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	purgeID, ok := uuidParam(w, r, "id", "purge")
+	if !ok {
+		return
+	}
+
+	purge, err := h.service.Get(r.Context(), purgeID)
+	if err != nil {
+		h.respondWithPurgeError(w, r, "Failed to get purge request", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, purge)
+}
+
+// Approve handles an admin other than the requester approving a purge
+func (h *CIPurgeHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	purgeID, ok := uuidParam(w, r, "id", "purge")
+	if !ok {
+		return
+	}
+
+	purge, err := h.service.Approve(r.Context(), purgeID, actorID(r))
+	if err != nil {
+		h.respondWithPurgeError(w, r, "Failed to approve purge", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "CI purge approved", map[string]interface{}{
+		"purge_id": purge.ID,
+		"status":   purge.Status,
+	})
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, purge)
+}
+
+// Execute handles purging the CI of an approved request
+func (h *CIPurgeHandler) Execute(w http.ResponseWriter, r *http.Request) {
+	purgeID, ok := uuidParam(w, r, "id", "purge")
+	if !ok {
+		return
+	}
+
+	purge, err := h.service.Execute(r.Context(), purgeID, actorID(r))
+	if err != nil {
+		h.respondWithPurgeError(w, r, "Failed to execute purge", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "CI purged", map[string]interface{}{
+		"purge_id": purge.ID,
+		"ci_id":    purge.CIID,
+	})
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, purge)
+}
+
+// Cancel handles cancelling an open purge request
+func (h *CIPurgeHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	purgeID, ok := uuidParam(w, r, "id", "purge")
+	if !ok {
+		return
+	}
+
+	purge, err := h.service.Cancel(r.Context(), purgeID, actorID(r))
+	if err != nil {
+		h.respondWithPurgeError(w, r, "Failed to cancel purge", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, purge)
+}
+
+// Routes returns the purge routes, all of which require the admin role
+func (h *CIPurgeHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.Get("/preview", h.Preview)
+	r.Post("/", h.Request)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Post("/{id}/approve", h.Approve)
+	r.Post("/{id}/execute", h.Execute)
+	r.Post("/{id}/cancel", h.Cancel)
+
+	return r
+}
+
+// respondWithPurgeError maps purge errors to status codes
+func (h *CIPurgeHandler) respondWithPurgeError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, cipurge.ErrInvalidRequest):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, cipurge.ErrSelfApproval):
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, cipurge.ErrCINotFound), errors.Is(err, cipurge.ErrPurgeNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, cipurge.ErrBlocked), errors.Is(err, cipurge.ErrAlreadyApproved),
+		errors.Is(err, cipurge.ErrNotApproved), errors.Is(err, cipurge.ErrNotPending),
+		errors.Is(err, cipurge.ErrExpired), errors.Is(err, cipurge.ErrPlanChanged):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	default:
+		h.logger.ErrorRequest(r, err, message)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": message})
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/conflictescalation"
+	"github.com/gorilla/mux"
+)
+
+// ConflictEscalationHandler handles the sync conflict escalation endpoints
+type ConflictEscalationHandler struct {
+	service *conflictescalation.Service
+}
+
+// NewConflictEscalationHandler creates a new ConflictEscalationHandler
+func NewConflictEscalationHandler(service *conflictescalation.Service) *ConflictEscalationHandler {
+	return &ConflictEscalationHandler{service: service}
+}
+
+// RegisterRoutes registers conflict escalation routes
+func (h *ConflictEscalationHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/sync/conflicts/{id}/escalations", h.authMiddleware(h.handleListSteps)).Methods("GET")
+
+	router.HandleFunc("/api/v1/admin/conflict-escalation", h.authMiddleware(h.handleGetLastRun)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/conflict-escalation", h.authMiddleware(h.handleRun)).Methods("POST")
+}
+
+// handleListSteps handles listing the recorded escalation steps of a conflict
+func (h *ConflictEscalationHandler) handleListSteps(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	steps, err := h.service.Steps(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, conflictescalation.ErrConflictNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "Sync conflict not found", nil)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list escalation steps", err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"steps": steps,
+		"count": len(steps),
+	})
+}
+
+// handleGetLastRun handles returning the outcome of the last escalation run
+func (h *ConflictEscalationHandler) handleGetLastRun(w http.ResponseWriter, r *http.Request) {
+	lastRun := h.service.LastRun()
+	if lastRun == nil {
+		h.respondWithError(w, http.StatusNotFound, "Conflict escalation has not run yet", nil)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, lastRun)
+}
+
+// handleRun handles escalating aging conflicts now
+func (h *ConflictEscalationHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	result := h.service.Check(r.Context())
+	if result.Error != "" {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to escalate sync conflicts", errors.New(result.Error))
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *ConflictEscalationHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithError sends an error response
+func (h *ConflictEscalationHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *ConflictEscalationHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/auth"
+	"connect/internal/dashboard"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// DashboardHandler handles the endpoints of the caller's own home dashboard
+type DashboardHandler struct {
+	service *dashboard.Service
+}
+
+// NewDashboardHandler creates a new DashboardHandler
+func NewDashboardHandler(service *dashboard.Service) *DashboardHandler {
+	return &DashboardHandler{service: service}
+}
+
+// RegisterRoutes registers dashboard routes
+func (h *DashboardHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/me/dashboard", h.authMiddleware(h.handleGetDashboard)).Methods("GET")
+	router.HandleFunc("/api/v1/me/dashboard", h.authMiddleware(h.handleReplaceDashboard)).Methods("PUT")
+	router.HandleFunc("/api/v1/me/dashboard", h.authMiddleware(h.handleResetDashboard)).Methods("DELETE")
+	router.HandleFunc("/api/v1/me/dashboard/saved-searches", h.authMiddleware(h.handleAddSavedSearch)).Methods("POST")
+	router.HandleFunc("/api/v1/me/dashboard/saved-searches/{id}", h.authMiddleware(h.handleUpdateSavedSearch)).Methods("PUT")
+	router.HandleFunc("/api/v1/me/dashboard/saved-searches/{id}", h.authMiddleware(h.handleDeleteSavedSearch)).Methods("DELETE")
+	router.HandleFunc("/api/v1/me/dashboard/watched-cis/{ci_id}", h.authMiddleware(h.handleWatchCI)).Methods("PUT")
+	router.HandleFunc("/api/v1/me/dashboard/watched-cis/{ci_id}", h.authMiddleware(h.handleUnwatchCI)).Methods("DELETE")
+	router.HandleFunc("/api/v1/me/dashboard/favorite-reports/{report}", h.authMiddleware(h.handleFavoriteReport)).Methods("PUT")
+	router.HandleFunc("/api/v1/me/dashboard/favorite-reports/{report}", h.authMiddleware(h.handleUnfavoriteReport)).Methods("DELETE")
+}
+
+// handleGetDashboard handles retrieving the caller's dashboard
+func (h *DashboardHandler) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
+	prefs, err := h.service.Get(r.Context(), h.userID(r))
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to get dashboard", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// handleReplaceDashboard handles saving the whole dashboard. The body must
+// carry the version it was read at; a newer stored version is a conflict.
+func (h *DashboardHandler) handleReplaceDashboard(w http.ResponseWriter, r *http.Request) {
+	var prefs dashboard.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	saved, err := h.service.Replace(r.Context(), h.userID(r), &prefs)
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to save dashboard", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, saved)
+}
+
+// handleResetDashboard handles returning the caller's dashboard to the empty one
+func (h *DashboardHandler) handleResetDashboard(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Reset(r.Context(), h.userID(r)); err != nil {
+		h.respondWithDashboardError(w, "Failed to reset dashboard", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAddSavedSearch handles saving a search on the dashboard
+func (h *DashboardHandler) handleAddSavedSearch(w http.ResponseWriter, r *http.Request) {
+	var search dashboard.SavedSearch
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	saved, err := h.service.AddSavedSearch(r.Context(), h.userID(r), search)
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to save search", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, saved)
+}
+
+// handleUpdateSavedSearch handles renaming, re-querying or (un)pinning a saved search
+func (h *DashboardHandler) handleUpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid saved search ID", err)
+		return
+	}
+
+	var search dashboard.SavedSearch
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	updated, err := h.service.UpdateSavedSearch(r.Context(), h.userID(r), id, search)
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to update saved search", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, updated)
+}
+
+// handleDeleteSavedSearch handles removing a saved search and the widgets showing it
+func (h *DashboardHandler) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid saved search ID", err)
+		return
+	}
+
+	if err := h.service.DeleteSavedSearch(r.Context(), h.userID(r), id); err != nil {
+		h.respondWithDashboardError(w, "Failed to delete saved search", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWatchCI handles adding a CI to the watched CIs
+func (h *DashboardHandler) handleWatchCI(w http.ResponseWriter, r *http.Request) {
+	ciID, err := uuid.Parse(mux.Vars(r)["ci_id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
+		return
+	}
+
+	prefs, err := h.service.WatchCI(r.Context(), h.userID(r), ciID)
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to watch CI", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// handleUnwatchCI handles removing a CI from the watched CIs
+func (h *DashboardHandler) handleUnwatchCI(w http.ResponseWriter, r *http.Request) {
+	ciID, err := uuid.Parse(mux.Vars(r)["ci_id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid CI ID", err)
+		return
+	}
+
+	prefs, err := h.service.UnwatchCI(r.Context(), h.userID(r), ciID)
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to unwatch CI", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// handleFavoriteReport handles adding a report to the favorites; the body
+// optionally carries the parameters to run it with
+func (h *DashboardHandler) handleFavoriteReport(w http.ResponseWriter, r *http.Request) {
+	report := dashboard.FavoriteReport{Report: mux.Vars(r)["report"]}
+	if r.ContentLength != 0 {
+		var req struct {
+			Params map[string]string `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+		report.Params = req.Params
+	}
+
+	prefs, err := h.service.FavoriteReport(r.Context(), h.userID(r), report)
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to favorite report", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// handleUnfavoriteReport handles removing a report from the favorites
+func (h *DashboardHandler) handleUnfavoriteReport(w http.ResponseWriter, r *http.Request) {
+	prefs, err := h.service.UnfavoriteReport(r.Context(), h.userID(r), mux.Vars(r)["report"])
+	if err != nil {
+		h.respondWithDashboardError(w, "Failed to unfavorite report", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// respondWithDashboardError maps dashboard errors to status codes
+func (h *DashboardHandler) respondWithDashboardError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, dashboard.ErrInvalidPreferences):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	case errors.Is(err, dashboard.ErrNotFound):
+		h.respondWithError(w, http.StatusNotFound, message, err)
+	case errors.Is(err, dashboard.ErrVersionConflict):
+		h.respondWithError(w, http.StatusConflict, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Helper methods
+
+// authMiddleware requires an authenticated caller, since a dashboard belongs to its user
+func (h *DashboardHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.userID(r) == "" {
+			h.respondWithError(w, http.StatusUnauthorized, "Authentication required", nil)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// userID returns the authenticated caller
+func (h *DashboardHandler) userID(r *http.Request) string {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	return userID
+}
+
+// respondWithError sends an error response
+func (h *DashboardHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *DashboardHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
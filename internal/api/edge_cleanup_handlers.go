@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/edgecleanup"
+	"github.com/gorilla/mux"
+)
+
+// EdgeCleanupHandler handles the orphaned relationship cleanup admin endpoints
+type EdgeCleanupHandler struct {
+	service *edgecleanup.Service
+}
+
+// NewEdgeCleanupHandler creates a new EdgeCleanupHandler
+func NewEdgeCleanupHandler(service *edgecleanup.Service) *EdgeCleanupHandler {
+	return &EdgeCleanupHandler{service: service}
+}
+
+// RegisterRoutes registers relationship cleanup routes
+func (h *EdgeCleanupHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/admin/relationship-cleanup", h.authMiddleware(h.handleGetLastRun)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/relationship-cleanup", h.authMiddleware(h.handleRun)).Methods("POST")
+}
+
+// handleGetLastRun handles returning the outcome of the last cleanup
+func (h *EdgeCleanupHandler) handleGetLastRun(w http.ResponseWriter, r *http.Request) {
+	lastRun := h.service.LastRun()
+	if lastRun == nil {
+		h.respondWithError(w, http.StatusNotFound, "Relationship cleanup has not run yet", nil)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, lastRun)
+}
+
+// handleRun handles deactivating orphaned relationships now, e.g. after a bulk delete
+func (h *EdgeCleanupHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	result := h.service.Cleanup(r.Context())
+	if result.Error != "" {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to deactivate orphaned relationships", errors.New(result.Error))
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role to inspect or run the edge cleanup
+func (h *EdgeCleanupHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// respondWithError sends an error response
+func (h *EdgeCleanupHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *EdgeCleanupHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/eventfilter"
+	"github.com/gorilla/mux"
+)
+
+// EventFilterHandler lets subscribers check filter expressions before saving them
+type EventFilterHandler struct{}
+
+// NewEventFilterHandler creates a new EventFilterHandler
+func NewEventFilterHandler() *EventFilterHandler {
+	return &EventFilterHandler{}
+}
+
+// RegisterRoutes registers event filter routes
+func (h *EventFilterHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/event-filters/validate", h.handleValidateFilter).Methods("POST")
+}
+
+// ValidateEventFilterRequest represents a filter to validate, optionally against a sample event
+type ValidateEventFilterRequest struct {
+	Filter string                 `json:"filter"`
+	Sample map[string]interface{} `json:"sample,omitempty"`
+}
+
+// ValidateEventFilterResponse reports whether a filter compiles and matches the sample
+type ValidateEventFilterResponse struct {
+	Valid   bool   `json:"valid"`
+	Error   string `json:"error,omitempty"`
+	Matched *bool  `json:"matched,omitempty"`
+}
+
+// handleValidateFilter handles compiling a filter and evaluating it against a sample event
+func (h *EventFilterHandler) handleValidateFilter(w http.ResponseWriter, r *http.Request) {
+	var req ValidateEventFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	filter, err := eventfilter.Compile(req.Filter)
+	if err != nil {
+		h.respondWithJSON(w, http.StatusOK, ValidateEventFilterResponse{Valid: false, Error: err.Error()})
+		return
+	}
+
+	response := ValidateEventFilterResponse{Valid: true}
+	if req.Sample != nil {
+		matched, err := filter.Match(req.Sample)
+		if err != nil {
+			response.Error = err.Error()
+		} else {
+			response.Matched = &matched
+		}
+	}
+
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// respondWithError sends an error response
+func (h *EventFilterHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *EventFilterHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/featureflags"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// FeatureFlagHandler handles feature flag evaluation and administration endpoints
+type FeatureFlagHandler struct {
+	flags *featureflags.Service
+}
+
+// NewFeatureFlagHandler creates a new FeatureFlagHandler
+func NewFeatureFlagHandler(flags *featureflags.Service) *FeatureFlagHandler {
+	return &FeatureFlagHandler{flags: flags}
+}
+
+// RegisterRoutes registers feature flag routes
+func (h *FeatureFlagHandler) RegisterRoutes(router *mux.Router) {
+	// Evaluation routes, open to any authenticated caller
+	router.HandleFunc("/api/v1/feature-flags", h.handleEvaluateFlags).Methods("GET")
+
+	// Admin routes
+	router.HandleFunc("/api/v1/admin/feature-flags", h.authMiddleware(h.handleListFlags)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/feature-flags/{key}", h.authMiddleware(h.handleGetFlag)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/feature-flags/{key}", h.authMiddleware(h.handleSaveFlag)).Methods("PUT")
+	router.HandleFunc("/api/v1/admin/feature-flags/{key}", h.authMiddleware(h.handleDeleteFlag)).Methods("DELETE")
+	router.HandleFunc("/api/v1/admin/feature-flags/{key}/overrides", h.authMiddleware(h.handleSetOverride)).Methods("PUT")
+	router.HandleFunc("/api/v1/admin/feature-flags/{key}/overrides/{scope}/{value}", h.authMiddleware(h.handleDeleteOverride)).Methods("DELETE")
+}
+
+// SaveFeatureFlagRequest represents a request to create or update a feature flag
+type SaveFeatureFlagRequest struct {
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// SetFeatureFlagOverrideRequest represents a request to override a flag for an environment or tenant
+type SetFeatureFlagOverrideRequest struct {
+	Scope   string `json:"scope"`
+	Value   string `json:"value"`
+	Enabled bool   `json:"enabled"`
+}
+
+// handleEvaluateFlags handles resolving all flags for the requesting tenant
+func (h *FeatureFlagHandler) handleEvaluateFlags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := r.Header.Get(featureflags.TenantHeader)
+
+	evaluations, err := h.flags.EvaluateAll(ctx, tenant)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to evaluate feature flags", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"environment": h.flags.Environment(),
+		"tenant":      tenant,
+		"flags":       evaluations,
+	})
+}
+
+// handleListFlags handles listing all flags with their overrides
+func (h *FeatureFlagHandler) handleListFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.flags.ListFlags(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list feature flags", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, flags)
+}
+
+// handleGetFlag handles retrieving a flag
+func (h *FeatureFlagHandler) handleGetFlag(w http.ResponseWriter, r *http.Request) {
+	flag, err := h.flags.GetFlag(r.Context(), mux.Vars(r)["key"])
+	if err != nil {
+		h.respondWithFlagError(w, "Failed to get feature flag", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, flag)
+}
+
+// handleSaveFlag handles creating or updating a flag
+func (h *FeatureFlagHandler) handleSaveFlag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req SaveFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	if err := featureflags.ValidateKey(key); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid feature flag key", err)
+		return
+	}
+
+	flag, err := h.flags.SaveFlag(ctx, &featureflags.Flag{
+		Key:         key,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+		UpdatedBy:   userID.String(),
+	})
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to save feature flag", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, flag)
+}
+
+// handleDeleteFlag handles deleting a flag
+func (h *FeatureFlagHandler) handleDeleteFlag(w http.ResponseWriter, r *http.Request) {
+	if err := h.flags.DeleteFlag(r.Context(), mux.Vars(r)["key"]); err != nil {
+		h.respondWithFlagError(w, "Failed to delete feature flag", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"message": "Feature flag deleted successfully"})
+}
+
+// handleSetOverride handles enabling or disabling a flag for an environment or tenant
+func (h *FeatureFlagHandler) handleSetOverride(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+	key := mux.Vars(r)["key"]
+
+	var req SetFeatureFlagOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	override := &featureflags.Override{
+		FlagKey:   key,
+		Scope:     req.Scope,
+		Value:     req.Value,
+		Enabled:   req.Enabled,
+		UpdatedBy: userID.String(),
+	}
+	if err := featureflags.ValidateOverride(*override); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid feature flag override", err)
+		return
+	}
+
+	if err := h.flags.SetOverride(ctx, override); err != nil {
+		h.respondWithFlagError(w, "Failed to set feature flag override", err)
+		return
+	}
+
+	flag, err := h.flags.GetFlag(ctx, key)
+	if err != nil {
+		h.respondWithFlagError(w, "Failed to get feature flag", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, flag)
+}
+
+// handleDeleteOverride handles removing an override
+func (h *FeatureFlagHandler) handleDeleteOverride(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.flags.DeleteOverride(r.Context(), vars["key"], vars["scope"], vars["value"]); err != nil {
+		h.respondWithFlagError(w, "Failed to delete feature flag override", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"message": "Feature flag override deleted successfully"})
+}
+
+// Helper methods
+
+// respondWithFlagError maps a missing flag to 404 and anything else to 500
+func (h *FeatureFlagHandler) respondWithFlagError(w http.ResponseWriter, message string, err error) {
+	if errors.Is(err, featureflags.ErrFlagNotFound) {
+		h.respondWithError(w, http.StatusNotFound, "Feature flag not found", err)
+		return
+	}
+	h.respondWithError(w, http.StatusInternalServerError, message, err)
+}
+
+// authMiddleware requires the admin role for the flag administration routes
+func (h *FeatureFlagHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *FeatureFlagHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *FeatureFlagHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *FeatureFlagHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to marshal response", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
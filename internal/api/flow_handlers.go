@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/netflow"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxFlowBatchSize is the largest flow batch body accepted
+const maxFlowBatchSize = 16 << 20
+
+// FlowHandler handles the ingestion of aggregated network flow records
+type FlowHandler struct {
+	service *netflow.Service
+}
+
+// NewFlowHandler creates a new FlowHandler
+func NewFlowHandler(service *netflow.Service) *FlowHandler {
+	return &FlowHandler{service: service}
+}
+
+// RegisterRoutes registers flow ingestion routes
+func (h *FlowHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/relationships/flows", h.authMiddleware(h.handleIngestFlows)).Methods("POST")
+}
+
+// handleIngestFlows handles a batch of flow records from a collector. Records
+// that are invalid or cannot be resolved to CIs are reported in the response
+// rather than failing the batch.
+func (h *FlowHandler) handleIngestFlows(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFlowBatchSize)
+	var batch netflow.Batch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.Ingest(ctx, batch, userID)
+	if err != nil {
+		if errors.Is(err, netflow.ErrInvalidBatch) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid flow batch", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to ingest flows", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *FlowHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *FlowHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *FlowHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *FlowHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
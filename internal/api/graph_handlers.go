@@ -0,0 +1,157 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"connect/internal/config"
+	"connect/internal/graph"
+	"connect/internal/importexport"
+	"connect/internal/logger"
+	"connect/internal/models"
+	"connect/internal/repositories"
+	"connect/internal/visibility"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// GraphHandler handles queries answered by traversing the Neo4j graph
+type GraphHandler struct {
+	config     *config.Config
+	logger     *logger.Logger
+	service    *graph.Service
+	ciRepo     *repositories.CIRepository
+	visibility *visibility.Resolver
+}
+
+func NewGraphHandler(config *config.Config, appLogger *logger.Logger, service *graph.Service, ciRepo *repositories.CIRepository) *GraphHandler {
+	return &GraphHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+		ciRepo:  ciRepo,
+	}
+}
+
+// SetVisibility hides CIs the caller may not see from graph results
+func (h *GraphHandler) SetVisibility(resolver *visibility.Resolver) {
+	h.visibility = resolver
+}
+
+// Impact handles listing the CIs affected by a CI, grouped by criticality.
+// ?direction is upstream (the default), downstream or both and ?depth bounds
+// the relationship hops followed, by default the configured impact depth.
+func (h *GraphHandler) Impact(w http.ResponseWriter, r *http.Request) {
+	ciID, ok := uuidParam(w, r, "ciId", "CI")
+	if !ok {
+		return
+	}
+	params := bindRequest(r)
+	direction := params.Enum("direction", models.GraphDirectionUpstream, models.GraphDirections)
+	depth := params.Int("depth", h.config.Impact.DefaultDepth, 1, graph.MaxDepth)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to resolve permissions")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to resolve permissions"})
+		return
+	}
+	var visible func(ci *models.CI) bool
+	if scope != nil {
+		visible = scope.Allows
+	}
+
+	result, err := h.service.Impact(r.Context(), ciID, direction, depth, visible)
+	if err != nil {
+		switch {
+		case errors.Is(err, graph.ErrCINotFound):
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, map[string]string{"error": "CI not found"})
+		case errors.Is(err, graph.ErrInvalidQuery):
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": err.Error()})
+		default:
+			h.logger.ErrorRequest(r, err, "Failed to analyze impact")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]string{"error": "Failed to analyze impact"})
+		}
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{
+		"ci_id":       ciID,
+		"ci_name":     result.CI.Name,
+		"direction":   result.Direction,
+		"depth":       result.Depth,
+		"total_count": result.TotalCount,
+		"truncated":   result.Truncated,
+		"groups":      result.Groups,
+	})
+}
+
+// graphExportFormats are the formats the dependency graph is exported in
+var graphExportFormats = []string{importexport.FormatEdgeList}
+
+// Export handles exporting the dependency graph as a CSV edge list
+// (?format=edgelist) for BI tools, one row per relationship between
+// non-deleted CIs. ?type and ?ci_type narrow it to the relationships of a type
+// or touching CIs of a type, and ?state, active by default, to a lifecycle
+// state. Rows are streamed from the database as they are written.
+func (h *GraphHandler) Export(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	params.Enum("format", importexport.FormatEdgeList, graphExportFormats)
+	filter := repositories.RelationshipExportFilter{
+		Type:   params.String("type"),
+		State:  params.Enum("state", models.RelationshipStateActive, models.RelationshipStates),
+		CIType: params.String("ci_type"),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to resolve permissions")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to resolve permissions"})
+		return
+	}
+	filter.Scope = scope
+
+	filename := fmt.Sprintf("cmdb-graph-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", importexport.ExportContentType(importexport.FormatCSV))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	exporter, err := importexport.NewEdgeListExporter(w)
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to start graph export")
+		return
+	}
+	if err := h.ciRepo.StreamRelationships(r.Context(), filter, exporter.WriteRelationship); err != nil {
+		// The status is sent already, so the download is cut short instead
+		h.logger.ErrorRequest(r, err, "Failed to export graph")
+		return
+	}
+	if err := exporter.Close(); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to finish graph export")
+	}
+}
+
+func (h *GraphHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/impact/{ciId}", h.Impact)
+	r.Get("/export", h.Export)
+
+	return r
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"connect/internal/heartbeat"
+	"github.com/gorilla/mux"
+)
+
+// HeartbeatHandler handles the CI heartbeat endpoints
+type HeartbeatHandler struct {
+	service *heartbeat.Service
+}
+
+// NewHeartbeatHandler creates a new HeartbeatHandler
+func NewHeartbeatHandler(service *heartbeat.Service) *HeartbeatHandler {
+	return &HeartbeatHandler{service: service}
+}
+
+// RegisterRoutes registers heartbeat routes
+func (h *HeartbeatHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/cis/{id}/heartbeat", h.authMiddleware(h.handleRecordHeartbeat)).Methods("POST")
+	router.HandleFunc("/api/v1/cis/{id}/heartbeat", h.authMiddleware(h.handleGetHeartbeat)).Methods("GET")
+	router.HandleFunc("/api/v1/heartbeats", h.authMiddleware(h.handleListHeartbeats)).Methods("GET")
+
+	router.HandleFunc("/api/v1/admin/heartbeat-check", h.authMiddleware(h.handleGetLastRun)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/heartbeat-check", h.authMiddleware(h.handleRun)).Methods("POST")
+}
+
+// recordHeartbeatRequest is the optional body of a heartbeat
+type recordHeartbeatRequest struct {
+	Source string `json:"source"`
+}
+
+// handleRecordHeartbeat handles a heartbeat sent for a CI by an agent or
+// monitoring integration, e.g. {"source": "prometheus"}. The body may be empty.
+func (h *HeartbeatHandler) handleRecordHeartbeat(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	var req recordHeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	hb, err := h.service.Record(r.Context(), id, req.Source)
+	if err != nil {
+		h.respondWithHeartbeatError(w, "Failed to record heartbeat", err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, hb)
+}
+
+// handleGetHeartbeat handles retrieving the heartbeat state of a CI
+func (h *HeartbeatHandler) handleGetHeartbeat(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	hb, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		h.respondWithHeartbeatError(w, "Failed to get heartbeat", err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, hb)
+}
+
+// handleListHeartbeats handles listing the monitored CIs, least recent
+// heartbeat first. ?unreachable=true lists the unreachable ones only.
+func (h *HeartbeatHandler) handleListHeartbeats(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	unreachable := params.Bool("unreachable")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	heartbeats, err := h.service.List(r.Context(), unreachable != nil && *unreachable)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list heartbeats", err)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"heartbeats": heartbeats,
+		"count":      len(heartbeats),
+	})
+}
+
+// handleGetLastRun handles returning the outcome of the last missed heartbeat check
+func (h *HeartbeatHandler) handleGetLastRun(w http.ResponseWriter, r *http.Request) {
+	lastRun := h.service.LastRun()
+	if lastRun == nil {
+		h.respondWithError(w, http.StatusNotFound, "Heartbeat check has not run yet", nil)
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, lastRun)
+}
+
+// handleRun handles checking for missed heartbeats now
+func (h *HeartbeatHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	result := h.service.Check(r.Context())
+	if result.Error != "" {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to check for missed heartbeats", errors.New(result.Error))
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// Helper methods
+
+// respondWithHeartbeatError maps heartbeat errors to status codes
+func (h *HeartbeatHandler) respondWithHeartbeatError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, heartbeat.ErrCINotFound):
+		h.respondWithError(w, http.StatusNotFound, "CI not found", nil)
+	case errors.Is(err, heartbeat.ErrHeartbeatNotFound):
+		h.respondWithError(w, http.StatusNotFound, "No heartbeat received for CI", nil)
+	case errors.Is(err, heartbeat.ErrInvalidSource):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *HeartbeatHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithError sends an error response
+func (h *HeartbeatHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *HeartbeatHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpactHandler_ParseAsOf(t *testing.T) {
+	h := &ImpactHandler{}
+	parse := func(query string) (*time.Time, bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		asOf, ok := h.parseAsOf(w, httptest.NewRequest("GET", "/api/v1/cis/x/topology"+query, nil))
+		return asOf, ok, w
+	}
+
+	asOf, ok, _ := parse("")
+	assert.True(t, ok)
+	assert.Nil(t, asOf, "the current graph is analyzed by default")
+
+	asOf, ok, _ = parse("?as_of=2024-03-01T12:00:00%2B01:00")
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), *asOf)
+
+	for query, reason := range map[string]string{
+		"?as_of=yesterday": "must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		"?as_of=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339): "must not be in the future",
+	} {
+		asOf, ok, w := parse(query)
+		assert.False(t, ok, query)
+		assert.Nil(t, asOf, query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+
+		var body struct {
+			Fields map[string]string `json:"fields"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), query)
+		assert.Equal(t, map[string]string{"as_of": reason}, body.Fields, query)
+	}
+}
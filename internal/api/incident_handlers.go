@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/incidentlearn"
+	"github.com/gorilla/mux"
+)
+
+// IncidentHandler handles ingesting incidents from incident tools and the
+// relationship scores learned from them
+type IncidentHandler struct {
+	service *incidentlearn.Service
+}
+
+// NewIncidentHandler creates a new IncidentHandler
+func NewIncidentHandler(service *incidentlearn.Service) *IncidentHandler {
+	return &IncidentHandler{service: service}
+}
+
+// RegisterRoutes registers incident routes
+func (h *IncidentHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/incidents", h.authMiddleware(h.handleIngestIncidents)).Methods("POST")
+	router.HandleFunc("/api/v1/incidents", h.authMiddleware(h.handleListIncidents)).Methods("GET")
+	router.HandleFunc("/api/v1/incidents/learn", h.authMiddleware(h.handleLearn)).Methods("POST")
+	router.HandleFunc("/api/v1/incidents/relationship-scores", h.authMiddleware(h.handleListScores)).Methods("GET")
+}
+
+// IngestIncidentsRequest represents a batch of incidents to ingest
+type IngestIncidentsRequest struct {
+	Incidents []incidentlearn.Incident `json:"incidents"`
+}
+
+// handleIngestIncidents handles ingesting a batch of incidents. Incidents
+// already ingested with the same source and external ID are replaced.
+func (h *IncidentHandler) handleIngestIncidents(w http.ResponseWriter, r *http.Request) {
+	var req IngestIncidentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.Ingest(r.Context(), req.Incidents)
+	if err != nil {
+		if errors.Is(err, incidentlearn.ErrInvalidIncident) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid incident", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to ingest incidents", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusAccepted, result)
+}
+
+// handleListIncidents handles listing the latest incidents, those that hit
+// ?ci_id if given
+func (h *IncidentHandler) handleListIncidents(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	ciID := params.UUID("ci_id")
+	limit := params.Int("limit", 50, 1, 500)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	incidents, err := h.service.ListIncidents(r.Context(), ciID, limit)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list incidents", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"incidents": incidents,
+		"count":     len(incidents),
+	})
+}
+
+// handleLearn handles recomputing the relationship scores now rather than on
+// the next scheduled run
+func (h *IncidentHandler) handleLearn(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.Learn(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to learn relationship scores", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// handleListScores handles listing the learned relationship scores, highest
+// confidence first; ?critical=true lists the empirically critical ones only
+func (h *IncidentHandler) handleListScores(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	critical := params.Bool("critical")
+	limit := params.Int("limit", 100, 1, 1000)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	scores, err := h.service.ListScores(r.Context(), critical != nil && *critical, limit)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list relationship scores", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"scores": scores,
+		"count":  len(scores),
+	})
+}
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *IncidentHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithError sends an error response
+func (h *IncidentHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *IncidentHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
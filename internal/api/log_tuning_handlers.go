@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/logtuning"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// LogTuningHandler handles the admin endpoints tuning slow query logging and
+// debug log sampling at runtime
+type LogTuningHandler struct {
+	service *logtuning.Service
+}
+
+// NewLogTuningHandler creates a new LogTuningHandler
+func NewLogTuningHandler(service *logtuning.Service) *LogTuningHandler {
+	return &LogTuningHandler{service: service}
+}
+
+// RegisterRoutes registers log tuning routes
+func (h *LogTuningHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/admin/config/logging", h.authMiddleware(h.handleGetSettings)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/config/logging", h.authMiddleware(h.handleUpdateSettings)).Methods("PUT")
+	router.HandleFunc("/api/v1/admin/config/logging", h.authMiddleware(h.handleResetSettings)).Methods("DELETE")
+}
+
+// handleGetSettings handles retrieving the settings in effect and those configured
+func (h *LogTuningHandler) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"effective":  h.service.Settings(),
+		"configured": h.service.Configured(),
+	})
+}
+
+// handleUpdateSettings handles changing settings at runtime
+func (h *LogTuningHandler) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req logtuning.UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	settings, err := h.service.Update(ctx, req, userID.String())
+	if err != nil {
+		if errors.Is(err, logtuning.ErrInvalidSettings) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid log settings", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to update log settings", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, settings)
+}
+
+// handleResetSettings handles restoring the configured settings
+func (h *LogTuningHandler) handleResetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.Reset(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to reset log settings", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, settings)
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role to read or tune the logging settings
+func (h *LogTuningHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *LogTuningHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *LogTuningHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *LogTuningHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
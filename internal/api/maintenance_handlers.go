@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"connect/internal/maintenance"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// MaintenanceHandler handles the maintenance mode admin endpoints
+type MaintenanceHandler struct {
+	maintenance *maintenance.Service
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler
+func NewMaintenanceHandler(maintenance *maintenance.Service) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenance: maintenance}
+}
+
+// RegisterRoutes registers maintenance routes
+func (h *MaintenanceHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc(maintenance.AdminPath, h.authMiddleware(h.handleGetMaintenanceMode)).Methods("GET")
+	router.HandleFunc(maintenance.AdminPath, h.authMiddleware(h.handleSetMaintenanceMode)).Methods("PUT")
+}
+
+// SetMaintenanceModeRequest represents a request to switch read-only maintenance mode
+type SetMaintenanceModeRequest struct {
+	ReadOnly          bool       `json:"read_only"`
+	Reason            string     `json:"reason"`
+	RetryAfterSeconds int        `json:"retry_after_seconds"`
+	ExpectedEnd       *time.Time `json:"expected_end,omitempty"`
+}
+
+// handleGetMaintenanceMode handles retrieving the current maintenance mode
+func (h *MaintenanceHandler) handleGetMaintenanceMode(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, h.maintenance.Current(r.Context()))
+}
+
+// handleSetMaintenanceMode handles enabling or disabling read-only maintenance mode
+func (h *MaintenanceHandler) handleSetMaintenanceMode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req SetMaintenanceModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.RetryAfterSeconds < 0 {
+		h.respondWithError(w, http.StatusBadRequest, "Retry after cannot be negative", nil)
+		return
+	}
+
+	mode, err := h.maintenance.Set(ctx, maintenance.Mode{
+		ReadOnly:          req.ReadOnly,
+		Reason:            req.Reason,
+		RetryAfterSeconds: req.RetryAfterSeconds,
+		ExpectedEnd:       req.ExpectedEnd,
+		UpdatedBy:         userID.String(),
+	})
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to set maintenance mode", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, mode)
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role to read or switch maintenance mode
+func (h *MaintenanceHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *MaintenanceHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *MaintenanceHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *MaintenanceHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to marshal response", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"connect/internal/manifest"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ManifestHandler plans and applies declarative manifests, sent as YAML or
+// JSON in the request body
+type ManifestHandler struct {
+	service *manifest.Service
+}
+
+// NewManifestHandler creates a new ManifestHandler
+func NewManifestHandler(service *manifest.Service) *ManifestHandler {
+	return &ManifestHandler{service: service}
+}
+
+// RegisterRoutes registers manifest routes
+func (h *ManifestHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/manifests/plan", h.authMiddleware(h.handlePlanManifest)).Methods("POST")
+	router.HandleFunc("/api/v1/manifests/apply", h.authMiddleware(h.handleApplyManifest)).Methods("POST")
+}
+
+// handlePlanManifest handles computing the changes a manifest would make
+func (h *ManifestHandler) handlePlanManifest(w http.ResponseWriter, r *http.Request) {
+	m, opts, ok := h.readManifest(w, r)
+	if !ok {
+		return
+	}
+
+	plan, err := h.service.Plan(r.Context(), m, opts)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to plan manifest", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, plan)
+}
+
+// handleApplyManifest handles applying a manifest. A plan with conflicts is
+// returned with 409 and nothing is changed.
+func (h *ManifestHandler) handleApplyManifest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	m, opts, ok := h.readManifest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Apply(ctx, m, opts, userID)
+	if err != nil {
+		if errors.Is(err, manifest.ErrPlanConflicts) {
+			h.respondWithJSON(w, http.StatusConflict, result)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to apply manifest", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// readManifest parses the manifest in the body and the create, update and
+// prune options, responding with an error if either is invalid
+func (h *ManifestHandler) readManifest(w http.ResponseWriter, r *http.Request) (*manifest.Manifest, manifest.Options, bool) {
+	params := bindRequest(r)
+	opts := manifest.DefaultOptions()
+	if create := params.Bool("create"); create != nil {
+		opts.Create = *create
+	}
+	if update := params.Bool("update"); update != nil {
+		opts.Update = *update
+	}
+	if prune := params.Bool("prune"); prune != nil {
+		opts.Prune = *prune
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return nil, opts, false
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, manifest.MaxBytes))
+	if err != nil {
+		h.respondWithError(w, http.StatusRequestEntityTooLarge, "Manifest too large", err)
+		return nil, opts, false
+	}
+	m, err := manifest.Parse(data)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid manifest", err)
+		return nil, opts, false
+	}
+	return m, opts, true
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *ManifestHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *ManifestHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *ManifestHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *ManifestHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"connect/internal/inventorymetrics"
+	"github.com/gorilla/mux"
+)
+
+// MetricsHandler exposes the inventory counts to metrics scrapers
+type MetricsHandler struct {
+	service *inventorymetrics.Service
+	path    string
+}
+
+// NewMetricsHandler creates a new MetricsHandler serving metrics on path, e.g. /metrics
+func NewMetricsHandler(service *inventorymetrics.Service, path string) *MetricsHandler {
+	return &MetricsHandler{service: service, path: path}
+}
+
+// RegisterRoutes registers metrics routes
+func (h *MetricsHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc(h.path, h.handleGetMetrics).Methods("GET")
+}
+
+// handleGetMetrics handles a scrape. Scrapers asking for
+// application/openmetrics-text get OpenMetrics, the others the Prometheus
+// text format.
+func (h *MetricsHandler) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.service.Counts(r.Context())
+	if err != nil {
+		log.Printf("Failed to read inventory metrics: %v", err)
+		http.Error(w, "Failed to read inventory metrics", http.StatusServiceUnavailable)
+		return
+	}
+
+	openMetrics := inventorymetrics.AcceptsOpenMetrics(r.Header.Get("Accept"))
+	if openMetrics {
+		w.Header().Set("Content-Type", inventorymetrics.ContentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", inventorymetrics.ContentTypePrometheus)
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := inventorymetrics.Write(w, counts, openMetrics); err != nil {
+		log.Printf("Failed to write inventory metrics: %v", err)
+	}
+}
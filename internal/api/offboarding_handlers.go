@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/config"
+	"connect/internal/logger"
+	"connect/internal/offboarding"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// OffboardingHandler handles batch user offboarding, admin only
+type OffboardingHandler struct {
+	config  *config.Config
+	logger  *logger.Logger
+	service *offboarding.Service
+}
+
+func NewOffboardingHandler(config *config.Config, appLogger *logger.Logger, service *offboarding.Service) *OffboardingHandler {
+	return &OffboardingHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+	}
+}
+
+// Offboard handles offboarding a list of users. Users that cannot be found or
+// offboarded are reported in the response instead of failing the request.
+func (h *OffboardingHandler) Offboard(w http.ResponseWriter, r *http.Request) {
+	var req offboarding.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode offboarding request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	report, err := h.service.Offboard(r.Context(), req, actorID(r))
+	if err != nil {
+		h.respondWithOffboardingError(w, r, "Failed to offboard users", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Users offboarded", map[string]interface{}{
+		"report_id":  report.ID,
+		"offboarded": report.Summary.Offboarded,
+		"failed":     report.Summary.Failed,
+	})
+	w.Header().Set("Location", "/api/v1/offboarding/"+report.ID.String())
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, report)
+}
+
+// ListReports handles listing recent offboarding reports without their user results
+func (h *OffboardingHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	limit := params.Int("limit", offboarding.DefaultListLimit, 1, 500)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	reports, err := h.service.List(r.Context(), limit)
+	if err != nil {
+		h.respondWithOffboardingError(w, r, "Failed to list offboarding reports", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"reports": reports, "count": len(reports)})
+}
+
+// GetReport handles getting an offboarding report with its user results
+func (h *OffboardingHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	reportID, ok := uuidParam(w, r, "id", "report")
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Get(r.Context(), reportID)
+	if err != nil {
+		h.respondWithOffboardingError(w, r, "Failed to get offboarding report", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, report)
+}
+
+// Routes returns the offboarding routes, all of which require the admin role
+func (h *OffboardingHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.Post("/", h.Offboard)
+	r.Get("/", h.ListReports)
+	r.Get("/{id}", h.GetReport)
+
+	return r
+}
+
+// respondWithOffboardingError maps offboarding errors to status codes
+func (h *OffboardingHandler) respondWithOffboardingError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, offboarding.ErrInvalidRequest):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, offboarding.ErrReportNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "Offboarding report not found"})
+	default:
+		h.logger.ErrorRequest(r, err, message)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": message})
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/config"
+	"connect/internal/logger"
+	"connect/internal/organization"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// OrganizationHandler handles the organizations users and CIs are scoped to,
+// admin only
+type OrganizationHandler struct {
+	config  *config.Config
+	logger  *logger.Logger
+	service *organization.Service
+}
+
+func NewOrganizationHandler(config *config.Config, appLogger *logger.Logger, service *organization.Service) *OrganizationHandler {
+	return &OrganizationHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+	}
+}
+
+// Create handles creating an organization
+func (h *OrganizationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req organization.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode organization request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	org, err := h.service.Create(r.Context(), req, actorID(r))
+	if err != nil {
+		h.respondWithOrganizationError(w, r, "Failed to create organization", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Organization created", map[string]interface{}{
+		"organization_id": org.ID,
+		"slug":            org.Slug,
+	})
+	w.Header().Set("Location", "/api/v1/organizations/"+org.ID.String())
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, org)
+}
+
+// List handles listing the organizations
+func (h *OrganizationHandler) List(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.service.List(r.Context())
+	if err != nil {
+		h.respondWithOrganizationError(w, r, "Failed to list organizations", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"organizations": orgs, "count": len(orgs)})
+}
+
+// Get handles getting an organization
+func (h *OrganizationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := uuidParam(w, r, "id", "organization")
+	if !ok {
+		return
+	}
+
+	org, err := h.service.Get(r.Context(), orgID)
+	if err != nil {
+		h.respondWithOrganizationError(w, r, "Failed to get organization", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, org)
+}
+
+// Delete handles deleting an organization and its memberships
+func (h *OrganizationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := uuidParam(w, r, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), orgID); err != nil {
+		h.respondWithOrganizationError(w, r, "Failed to delete organization", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Organization deleted", map[string]interface{}{"organization_id": orgID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Members handles listing the members of an organization
+func (h *OrganizationHandler) Members(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := uuidParam(w, r, "id", "organization")
+	if !ok {
+		return
+	}
+
+	members, err := h.service.Members(r.Context(), orgID)
+	if err != nil {
+		h.respondWithOrganizationError(w, r, "Failed to list organization members", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"members": members, "count": len(members)})
+}
+
+// AddMember handles adding a user to an organization or changing their
+// default organization
+func (h *OrganizationHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := uuidParam(w, r, "id", "organization")
+	if !ok {
+		return
+	}
+
+	var req organization.AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode organization member request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	member, err := h.service.AddMember(r.Context(), orgID, req)
+	if err != nil {
+		h.respondWithOrganizationError(w, r, "Failed to add organization member", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Organization member added", map[string]interface{}{
+		"organization_id": orgID,
+		"user_id":         member.UserID,
+		"default":         member.Default,
+	})
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, member)
+}
+
+// RemoveMember handles removing a user from an organization
+func (h *OrganizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := uuidParam(w, r, "id", "organization")
+	if !ok {
+		return
+	}
+	userID, ok := uuidParam(w, r, "userId", "user")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveMember(r.Context(), orgID, userID); err != nil {
+		h.respondWithOrganizationError(w, r, "Failed to remove organization member", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Organization member removed", map[string]interface{}{
+		"organization_id": orgID,
+		"user_id":         userID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routes returns the organization routes, all of which require the admin role
+func (h *OrganizationHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Delete("/{id}", h.Delete)
+	r.Get("/{id}/members", h.Members)
+	r.Post("/{id}/members", h.AddMember)
+	r.Delete("/{id}/members/{userId}", h.RemoveMember)
+
+	return r
+}
+
+// respondWithOrganizationError maps organization errors to status codes
+func (h *OrganizationHandler) respondWithOrganizationError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, organization.ErrInvalidOrganization):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, organization.ErrOrganizationNotFound),
+		errors.Is(err, organization.ErrUserNotFound),
+		errors.Is(err, organization.ErrNotMember):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	case errors.Is(err, organization.ErrDuplicateSlug):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	default:
+		h.logger.ErrorRequest(r, err, message)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": message})
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"connect/internal/payloadlog"
+	"github.com/gorilla/mux"
+)
+
+// PayloadLoggingHandler handles switching payload logging on and off per route
+type PayloadLoggingHandler struct {
+	payloadLog      *payloadlog.Service
+	defaultDuration time.Duration
+}
+
+// NewPayloadLoggingHandler creates a new PayloadLoggingHandler
+func NewPayloadLoggingHandler(payloadLog *payloadlog.Service, defaultDuration time.Duration) *PayloadLoggingHandler {
+	if defaultDuration <= 0 {
+		defaultDuration = payloadlog.DefaultDuration
+	}
+	return &PayloadLoggingHandler{payloadLog: payloadLog, defaultDuration: defaultDuration}
+}
+
+// RegisterRoutes registers payload logging admin routes
+func (h *PayloadLoggingHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/admin/payload-logging", h.authMiddleware(h.handleListRoutes)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/payload-logging", h.authMiddleware(h.handleSetRoute)).Methods("PUT")
+}
+
+// SetPayloadLoggingRequest represents a request to toggle payload logging for a route
+type SetPayloadLoggingRequest struct {
+	Route    string `json:"route"` // "[METHOD ]/path/template", e.g. "PUT /api/v1/cis/{id}"
+	Enabled  bool   `json:"enabled"`
+	Duration string `json:"duration,omitempty"` // e.g. "30m"; defaults to the configured duration
+}
+
+// handleListRoutes handles listing the routes whose payloads are being logged
+func (h *PayloadLoggingHandler) handleListRoutes(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"routes": h.payloadLog.Routes(),
+	})
+}
+
+// handleSetRoute handles enabling or disabling payload logging for a route
+func (h *PayloadLoggingHandler) handleSetRoute(w http.ResponseWriter, r *http.Request) {
+	var req SetPayloadLoggingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	req.Route = strings.TrimSpace(req.Route)
+	if req.Route == "" || !strings.Contains(req.Route, "/") {
+		h.respondWithError(w, http.StatusBadRequest, "Route must be a path template, optionally prefixed by a method", nil)
+		return
+	}
+
+	if !req.Enabled {
+		h.payloadLog.Disable(req.Route)
+		h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"route":   req.Route,
+			"enabled": false,
+		})
+		return
+	}
+
+	duration := h.defaultDuration
+	if req.Duration != "" {
+		parsed, err := time.ParseDuration(req.Duration)
+		if err != nil || parsed <= 0 {
+			h.respondWithError(w, http.StatusBadRequest, "Duration must be a positive duration such as 30m", err)
+			return
+		}
+		duration = parsed
+	}
+
+	h.respondWithJSON(w, http.StatusOK, h.payloadLog.Enable(r.Context(), req.Route, duration))
+}
+
+// authMiddleware requires the admin role, as logged payloads can hold customer data
+func (h *PayloadLoggingHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// respondWithError sends an error response
+func (h *PayloadLoggingHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *PayloadLoggingHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/auth"
+	"connect/internal/permcheck"
+	"github.com/gorilla/mux"
+)
+
+// PermissionCheckHandler handles bulk permission checks, which let clients
+// decide which actions to offer in one call
+type PermissionCheckHandler struct {
+	checker *permcheck.Checker
+}
+
+// NewPermissionCheckHandler creates a new PermissionCheckHandler
+func NewPermissionCheckHandler(checker *permcheck.Checker) *PermissionCheckHandler {
+	return &PermissionCheckHandler{checker: checker}
+}
+
+// RegisterRoutes registers permission check routes
+func (h *PermissionCheckHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/auth/can", h.authMiddleware(h.handleCan)).Methods("POST")
+}
+
+// PermissionCheckRequest represents a batch of permission checks
+type PermissionCheckRequest struct {
+	Checks []permcheck.Check `json:"checks"`
+}
+
+// handleCan handles answering a batch of permission checks for the caller
+func (h *PermissionCheckHandler) handleCan(w http.ResponseWriter, r *http.Request) {
+	var req PermissionCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	roles, _ := auth.GetUserRolesFromContext(r.Context())
+	results, err := h.checker.Evaluate(r.Context(), roles, req.Checks)
+	if err != nil {
+		if errors.Is(err, permcheck.ErrInvalidChecks) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid permission checks", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+		return
+	}
+
+	// Answers depend on the caller, so they must not be shared
+	w.Header().Set("Cache-Control", "private, no-store")
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *PermissionCheckHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithError sends an error response
+func (h *PermissionCheckHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *PermissionCheckHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to marshal response", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/config"
+	"connect/internal/logger"
+	"connect/internal/models"
+	"connect/internal/repositories"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// PermissionHandler handles permission management, admin only
+type PermissionHandler struct {
+	config         *config.Config
+	logger         *logger.Logger
+	roleRepository *repositories.RoleRepository
+}
+
+func NewPermissionHandler(config *config.Config, appLogger *logger.Logger, roleRepository *repositories.RoleRepository) *PermissionHandler {
+	return &PermissionHandler{
+		config:         config,
+		logger:         appLogger,
+		roleRepository: roleRepository,
+	}
+}
+
+// ListPermissions handles listing permissions a page at a time, filtered by the
+// name, resource, action, is_active and is_system query parameters
+func (h *PermissionHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	page := params.Int("page", 1, 1, 0)
+	size := params.PageSize("size")
+	filter := &models.PermissionFilterOptions{
+		Name:      params.String("name"),
+		Resource:  params.String("resource"),
+		Action:    params.String("action"),
+		IsActive:  params.Bool("is_active"),
+		IsSystem:  params.Bool("is_system"),
+		SortBy:    params.Enum("sort_by", "", []string{"name", "display_name", "resource", "created_at", "is_active"}),
+		SortOrder: params.Enum("sort_order", "desc", []string{"asc", "desc"}),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	permissions, err := h.roleRepository.ListPermissions(r.Context(), filter, page, size)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to list permissions", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, permissions)
+}
+
+// CreatePermission handles creating a permission
+func (h *PermissionHandler) CreatePermission(w http.ResponseWriter, r *http.Request) {
+	var req models.CreatePermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode create permission request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		h.logger.ErrorRequest(r, err, "Invalid create permission request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	permission, err := h.roleRepository.CreatePermission(r.Context(), &req)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to create permission", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Permission created successfully", map[string]interface{}{"permission_id": permission.ID, "name": permission.Name})
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, permission.ToResponse(0))
+}
+
+// GetPermission handles getting a permission with the number of roles granted it
+func (h *PermissionHandler) GetPermission(w http.ResponseWriter, r *http.Request) {
+	permissionID, ok := uuidParam(w, r, "id", "permission")
+	if !ok {
+		return
+	}
+
+	permission, err := h.roleRepository.GetPermissionByID(r.Context(), permissionID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to get permission", err)
+		return
+	}
+
+	h.respondWithPermission(w, r, permission)
+}
+
+// UpdatePermission handles updating a permission. System permissions cannot be changed.
+func (h *PermissionHandler) UpdatePermission(w http.ResponseWriter, r *http.Request) {
+	permissionID, ok := uuidParam(w, r, "id", "permission")
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode update permission request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		h.logger.ErrorRequest(r, err, "Invalid update permission request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	permission, err := h.roleRepository.UpdatePermission(r.Context(), permissionID, &req)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to update permission", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Permission updated successfully", map[string]interface{}{"permission_id": permissionID})
+	h.respondWithPermission(w, r, permission)
+}
+
+// DeletePermission handles deleting a permission that is neither a system
+// permission nor granted to any role
+func (h *PermissionHandler) DeletePermission(w http.ResponseWriter, r *http.Request) {
+	permissionID, ok := uuidParam(w, r, "id", "permission")
+	if !ok {
+		return
+	}
+
+	if err := h.roleRepository.DeletePermission(r.Context(), permissionID); err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to delete permission", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Permission deleted successfully", map[string]interface{}{"permission_id": permissionID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routes returns the permission management routes, all of which require the admin role
+func (h *PermissionHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.Get("/", h.ListPermissions)
+	r.Post("/", h.CreatePermission)
+	r.Get("/{id}", h.GetPermission)
+	r.Put("/{id}", h.UpdatePermission)
+	r.Delete("/{id}", h.DeletePermission)
+
+	return r
+}
+
+// respondWithPermission sends a permission with the number of roles granted it
+func (h *PermissionHandler) respondWithPermission(w http.ResponseWriter, r *http.Request, permission *models.Permission) {
+	roleCount, err := h.roleRepository.CountRolesByPermission(r.Context(), permission.ID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to count permission roles", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, permission.ToResponse(roleCount))
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connect/internal/auth"
+	"connect/internal/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionHandler_RejectsBeforeRepository(t *testing.T) {
+	// The handler has no repository, so these requests must be answered
+	// without reaching it
+	routes := NewPermissionHandler(nil, logger.NewLogger("test"), nil).Routes()
+	permissionID := uuid.New().String()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{"invalid filter", "GET", "/?is_system=sometimes", "", http.StatusBadRequest, "Invalid request parameters"},
+		{"invalid page size", "GET", "/?size=1000", "", http.StatusBadRequest, "Invalid request parameters"},
+		{"malformed permission", "POST", "/", "{", http.StatusBadRequest, "Invalid request body"},
+		{"invalid permission ID", "GET", "/ci:read", "", http.StatusBadRequest, "Invalid permission ID"},
+		{"malformed update", "PUT", "/" + permissionID, "[]", http.StatusBadRequest, "Invalid request body"},
+		{"invalid permission ID on delete", "DELETE", "/ci:read", "", http.StatusBadRequest, "Invalid permission ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := asActor(httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)), uuid.New(), auth.ActorTypeUser, "admin")
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.message, errorMessage(t, w))
+		})
+	}
+
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, httptest.NewRequest("DELETE", "/"+permissionID, nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "permission management needs an authenticated admin")
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"connect/internal/auth"
+	"connect/internal/config"
+	"connect/internal/models"
+	"connect/internal/repositories"
+	"github.com/gorilla/mux"
+)
+
+// PublicCatalogHandler serves the curated, read-only CI catalog to
+// unauthenticated callers, e.g. a public service catalog page. Only the CIs
+// with the configured tags or types are served, trimmed to the configured
+// fields and attributes.
+type PublicCatalogHandler struct {
+	ciRepo *repositories.CIRepository
+	cfg    config.PublicCatalogConfig
+	scope  *models.VisibilityScope
+}
+
+// NewPublicCatalogHandler creates a new PublicCatalogHandler
+func NewPublicCatalogHandler(ciRepo *repositories.CIRepository, cfg config.PublicCatalogConfig) *PublicCatalogHandler {
+	return &PublicCatalogHandler{
+		ciRepo: ciRepo,
+		cfg:    cfg,
+		scope:  &models.VisibilityScope{Types: cfg.Types, Tags: cfg.Tags},
+	}
+}
+
+// RegisterRoutes registers the public catalog routes under the anonymous
+// read-only scope
+func (h *PublicCatalogHandler) RegisterRoutes(router *mux.Router) {
+	public := router.PathPrefix("/api/v1/public/catalog").Subrouter()
+	public.Use(auth.AnonymousReadOnly)
+	public.HandleFunc("", h.handleListCatalog).Methods("GET")
+	public.HandleFunc("/{id}", h.handleGetCatalogEntry).Methods("GET")
+}
+
+// handleListCatalog handles listing the catalog, paged and optionally
+// narrowed with ?search= and ?type=
+func (h *PublicCatalogHandler) handleListCatalog(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	req := &models.ListCIsRequest{
+		Page:      params.Int("page", 1, 1, 0),
+		PageSize:  params.PageSize("page_size"),
+		Search:    params.String("search"),
+		Type:      params.String("type"),
+		SortBy:    "name",
+		SortOrder: "asc",
+		Scope:     h.scope,
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	result, err := h.ciRepo.ListCIs(r.Context(), req)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list catalog", err)
+		return
+	}
+
+	entries := make([]map[string]interface{}, 0, len(result.CIs))
+	for i := range result.CIs {
+		entries = append(entries, h.entry(&result.CIs[i]))
+	}
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"items":       entries,
+		"total_count": result.TotalCount,
+		"page":        result.Page,
+		"page_size":   result.PageSize,
+		"total_pages": result.TotalPages,
+	})
+}
+
+// handleGetCatalogEntry handles getting one catalog entry. CIs outside the
+// catalog are reported as not found, so they cannot be probed for.
+func (h *PublicCatalogHandler) handleGetCatalogEntry(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	ci, err := h.ciRepo.GetCI(r.Context(), id)
+	if err != nil || !h.scope.Allows(ci) {
+		h.respondWithError(w, http.StatusNotFound, "Catalog entry not found", nil)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, h.entry(ci))
+}
+
+// entry trims a CI to the configured fields and custom attributes. Custom
+// attributes are only served when listed in the attributes setting.
+func (h *PublicCatalogHandler) entry(ci *models.CI) map[string]interface{} {
+	entry := make(map[string]interface{}, len(h.cfg.Fields)+1)
+	if encoded, err := json.Marshal(ci); err == nil {
+		var fields map[string]interface{}
+		if json.Unmarshal(encoded, &fields) == nil {
+			for _, field := range h.cfg.Fields {
+				if value, ok := fields[field]; ok && field != "attributes" {
+					entry[field] = value
+				}
+			}
+		}
+	}
+
+	if len(h.cfg.Attributes) > 0 {
+		var stored map[string]interface{}
+		json.Unmarshal(ci.Attributes, &stored)
+		attributes := make(map[string]interface{}, len(h.cfg.Attributes))
+		for _, key := range h.cfg.Attributes {
+			if value, ok := stored[key]; ok {
+				attributes[key] = value
+			}
+		}
+		entry["attributes"] = attributes
+	}
+	return entry
+}
+
+// respondWithError sends an error response. Error details are logged rather
+// than returned, as the catalog is served to anyone.
+func (h *PublicCatalogHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	if err != nil {
+		log.Printf("Public catalog: %s: %v", message, err)
+	}
+	h.respondWithJSON(w, code, map[string]interface{}{
+		"error":   message,
+		"success": false,
+	})
+}
+
+// respondWithJSON sends a JSON response
+func (h *PublicCatalogHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"connect/internal/quarantine"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// QuarantineHandler handles the review of discovered CIs held in quarantine
+type QuarantineHandler struct {
+	service *quarantine.Service
+}
+
+// NewQuarantineHandler creates a new QuarantineHandler
+func NewQuarantineHandler(service *quarantine.Service) *QuarantineHandler {
+	return &QuarantineHandler{service: service}
+}
+
+// RegisterRoutes registers quarantine routes
+func (h *QuarantineHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/cis/quarantine", h.authMiddleware(h.handleListItems)).Methods("GET")
+	router.HandleFunc("/api/v1/cis/quarantine/classify", h.authMiddleware(h.handleClassify)).Methods("POST")
+	router.HandleFunc("/api/v1/cis/quarantine/approve", h.authMiddleware(h.handleApprove)).Methods("POST")
+	router.HandleFunc("/api/v1/cis/quarantine/reject", h.authMiddleware(h.handleReject)).Methods("POST")
+	router.HandleFunc("/api/v1/cis/quarantine/{id}", h.authMiddleware(h.handleGetItem)).Methods("GET")
+}
+
+// handleListItems handles listing quarantined items, filtered by status, type, source and reason
+func (h *QuarantineHandler) handleListItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := quarantine.Filter{
+		Status: query.Get("status"),
+		Type:   query.Get("type"),
+		Source: query.Get("source"),
+		Reason: query.Get("reason"),
+	}
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	items, total, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list quarantined CIs", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"items":       items,
+		"total_count": total,
+	})
+}
+
+// handleGetItem handles retrieving a quarantined item
+func (h *QuarantineHandler) handleGetItem(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid quarantined CI ID", err)
+		return
+	}
+
+	item, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		h.respondWithQuarantineError(w, "Failed to get quarantined CI", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, item)
+}
+
+// handleClassify handles correcting the type and attributes of quarantined items
+func (h *QuarantineHandler) handleClassify(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []uuid.UUID `json:"ids"`
+		quarantine.Classification
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.Classify(r.Context(), req.IDs, req.Classification)
+	if err != nil {
+		h.respondWithQuarantineError(w, "Failed to classify quarantined CIs", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// handleApprove handles creating the CIs of quarantined items
+func (h *QuarantineHandler) handleApprove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.Approve(ctx, req.IDs, userID)
+	if err != nil {
+		h.respondWithQuarantineError(w, "Failed to approve quarantined CIs", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// handleReject handles discarding quarantined items
+func (h *QuarantineHandler) handleReject(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req struct {
+		IDs    []uuid.UUID `json:"ids"`
+		Reason string      `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.Reject(ctx, req.IDs, req.Reason, userID)
+	if err != nil {
+		h.respondWithQuarantineError(w, "Failed to reject quarantined CIs", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// respondWithQuarantineError maps quarantine errors to status codes
+func (h *QuarantineHandler) respondWithQuarantineError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, quarantine.ErrItemNotFound):
+		h.respondWithError(w, http.StatusNotFound, message, err)
+	case errors.Is(err, quarantine.ErrInvalidBulk):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *QuarantineHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *QuarantineHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *QuarantineHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *QuarantineHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
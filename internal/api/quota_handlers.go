@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/quota"
+	"github.com/gorilla/mux"
+)
+
+// QuotaHandler handles the CI quota admin endpoints
+type QuotaHandler struct {
+	service *quota.Service
+}
+
+// NewQuotaHandler creates a new QuotaHandler
+func NewQuotaHandler(service *quota.Service) *QuotaHandler {
+	return &QuotaHandler{service: service}
+}
+
+// RegisterRoutes registers quota routes
+func (h *QuotaHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/admin/quotas", h.authMiddleware(h.handleGetUsage)).Methods("GET")
+}
+
+// handleGetUsage handles reporting every limited tenant and type against its limit
+func (h *QuotaHandler) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.service.Usage(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get CI quota usage", err)
+		return
+	}
+
+	limits := h.service.Limits()
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"mode":         limits.Mode,
+		"warn_percent": limits.WarnPercent,
+		"usage":        usage,
+	})
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *QuotaHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens and require the admin role
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithError sends an error response
+func (h *QuotaHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *QuotaHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
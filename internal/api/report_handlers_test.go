@@ -0,0 +1,46 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCIGroup(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/reports/relationship-matrix?rows_type=application&rows_tags=prod,eu&columns_owner=dba&columns_org_unit=PLAT", nil)
+
+	assert.Equal(t, models.CIGroup{Type: "application", Tags: []string{"prod", "eu"}}, parseCIGroup(r, "rows_"))
+	assert.Equal(t, models.CIGroup{Owner: "dba", OrgUnit: "PLAT"}, parseCIGroup(r, "columns_"))
+	assert.True(t, parseCIGroup(r, "other_").IsEmpty())
+}
+
+func TestWriteRelationshipMatrixCSV(t *testing.T) {
+	shop, crm := models.MatrixMember{ID: uuid.New(), Name: "shop"}, models.MatrixMember{ID: uuid.New(), Name: "crm"}
+	orders, backup := models.MatrixMember{ID: uuid.New(), Name: "orders-db"}, models.MatrixMember{ID: uuid.New(), Name: "backup-db"}
+	matrix := &models.RelationshipMatrix{
+		Rows:    []models.MatrixMember{shop, crm},
+		Columns: []models.MatrixMember{orders, backup},
+		Cells: []models.MatrixCell{{
+			RowID:    shop.ID,
+			ColumnID: orders.ID,
+			Relationships: []models.MatrixRelationship{
+				{Type: "depends_on", Direction: models.RelationshipDirectionOutgoing},
+				{Type: "replicates_to", Direction: models.RelationshipDirectionIncoming},
+			},
+		}},
+		UnlinkedRows: []uuid.UUID{crm.ID},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeRelationshipMatrixCSV(&buf, matrix))
+	assert.Equal(t,
+		"ci_id,ci_name,linked,orders-db,backup-db\n"+
+			shop.ID.String()+",shop,true,depends_on;<replicates_to,\n"+
+			crm.ID.String()+",crm,false,,\n",
+		buf.String())
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"connect/internal/pagination"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxParamLength bounds the length in characters of a string parameter
+const maxParamLength = 256
+
+// requestParams binds path variables and query parameters to typed values.
+// String values are trimmed and enum values lowercased before they are
+// checked. Every invalid parameter is collected, so Err reports them all at
+// once instead of the request silently falling back to a default.
+type requestParams struct {
+	vars   map[string]string
+	query  url.Values
+	limits pagination.Limits
+	errors map[string]string
+}
+
+// bindRequest starts binding the parameters of a request
+func bindRequest(r *http.Request) *requestParams {
+	return &requestParams{
+		vars:   mux.Vars(r),
+		query:  r.URL.Query(),
+		limits: pagination.FromContext(r.Context()),
+		errors: map[string]string{},
+	}
+}
+
+// invalid records why a parameter is invalid, keeping the first reason
+func (p *requestParams) invalid(name, format string, args ...interface{}) {
+	if _, ok := p.errors[name]; !ok {
+		p.errors[name] = fmt.Sprintf(format, args...)
+	}
+}
+
+// PathUUID returns a path variable that must be a UUID
+func (p *requestParams) PathUUID(name string) uuid.UUID {
+	id, err := uuid.Parse(strings.TrimSpace(p.vars[name]))
+	if err != nil {
+		p.invalid(name, "must be a UUID")
+		return uuid.Nil
+	}
+	return id
+}
+
+// PathInt returns a path variable that must be an integer of at least min
+func (p *requestParams) PathInt(name string, min int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(p.vars[name]))
+	if err != nil || n < min {
+		p.invalid(name, "must be an integer of at least %d", min)
+		return 0
+	}
+	return n
+}
+
+// UUID returns an optional query parameter that must be a UUID when set
+func (p *requestParams) UUID(name string) *uuid.UUID {
+	value := p.String(name)
+	if value == "" {
+		return nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		p.invalid(name, "must be a UUID")
+		return nil
+	}
+	return &id
+}
+
+// String returns a trimmed query parameter, "" when it is not set. Control
+// characters and values over maxParamLength characters are rejected.
+func (p *requestParams) String(name string) string {
+	value := strings.TrimSpace(p.query.Get(name))
+	if !utf8.ValidString(value) {
+		p.invalid(name, "must be valid UTF-8")
+		return ""
+	}
+	if utf8.RuneCountInString(value) > maxParamLength {
+		p.invalid(name, "must be at most %d characters", maxParamLength)
+		return ""
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			p.invalid(name, "must not contain control characters")
+			return ""
+		}
+	}
+	return value
+}
+
+// Strings returns a comma separated query parameter as its trimmed, non-empty
+// items, nil when it is not set
+func (p *requestParams) Strings(name string) []string {
+	value := p.String(name)
+	if value == "" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Enum returns a lowercased query parameter that must be one of allowed, or
+// def when it is not set
+func (p *requestParams) Enum(name, def string, allowed []string) string {
+	value := strings.ToLower(p.String(name))
+	if value == "" {
+		return def
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return value
+		}
+	}
+	p.invalid(name, "must be one of %s", strings.Join(allowed, ", "))
+	return def
+}
+
+// Int returns an integer query parameter of at least min and, when max is
+// positive, at most max, or def when it is not set
+func (p *requestParams) Int(name string, def, min, max int) int {
+	value := p.String(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	switch {
+	case err != nil:
+		p.invalid(name, "must be an integer")
+	case max > 0 && (n < min || n > max):
+		p.invalid(name, "must be between %d and %d", min, max)
+	case n < min:
+		p.invalid(name, "must be at least %d", min)
+	default:
+		return n
+	}
+	return def
+}
+
+// PageSize returns a page size query parameter within the page size limits of
+// the endpoint, or its default page size when it is not set
+func (p *requestParams) PageSize(name string) int {
+	return p.Int(name, p.limits.Default, 1, p.limits.Max)
+}
+
+// Bool returns a boolean query parameter, nil when it is not set
+func (p *requestParams) Bool(name string) *bool {
+	value := p.String(name)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.invalid(name, "must be true or false")
+		return nil
+	}
+	return &b
+}
+
+// Time returns an optional query parameter that must be an RFC 3339 timestamp
+// or a YYYY-MM-DD date, which is midnight UTC, when set
+func (p *requestParams) Time(name string) *time.Time {
+	value := p.String(name)
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	p.invalid(name, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	return nil
+}
+
+// Err returns the invalid parameters as a *paramError, or nil if all are valid
+func (p *requestParams) Err() error {
+	if len(p.errors) == 0 {
+		return nil
+	}
+	return &paramError{Fields: p.errors}
+}
+
+// paramError maps the invalid parameters of a request to why they are invalid
+type paramError struct {
+	Fields map[string]string
+}
+
+func (e *paramError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := make([]string, 0, len(names))
+	for _, name := range names {
+		problems = append(problems, fmt.Sprintf("%s %s", name, e.Fields[name]))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// respondWithParamError sends the 400 response for invalid request parameters,
+// naming each invalid parameter under "fields"
+func respondWithParamError(w http.ResponseWriter, err error) {
+	response := map[string]interface{}{
+		"error":   "Invalid request parameters",
+		"success": false,
+		"details": err.Error(),
+	}
+	if paramErr, ok := err.(*paramError); ok {
+		response["fields"] = paramErr.Fields
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(body)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"connect/internal/pagination"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bindQuery binds a request with the given query parameters and path variables
+func bindQuery(query url.Values, vars map[string]string) *requestParams {
+	r := httptest.NewRequest("GET", "/api/v1/cis?"+query.Encode(), nil)
+	r = r.WithContext(pagination.WithLimits(context.Background(), pagination.Limits{Default: 25, Max: 50}))
+	return bindRequest(mux.SetURLVars(r, vars))
+}
+
+func TestRequestParams_Valid(t *testing.T) {
+	id, owner := uuid.New(), uuid.New()
+	params := bindQuery(url.Values{
+		"owner_id":   {" " + owner.String() + " "},
+		"search":     {"  web  "},
+		"tags":       {"prod, ,eu,"},
+		"status":     {"Active"},
+		"page":       {"3"},
+		"page_size":  {"50"},
+		"is_active":  {"false"},
+		"since":      {"2024-05-01"},
+		"until":      {"2024-05-02T10:00:00+02:00"},
+		"ignored_by": {""},
+	}, map[string]string{"id": id.String(), "version": "2"})
+
+	assert.Equal(t, id, params.PathUUID("id"))
+	assert.Equal(t, 2, params.PathInt("version", 1))
+	assert.Equal(t, &owner, params.UUID("owner_id"))
+	assert.Nil(t, params.UUID("ignored_by"))
+	assert.Equal(t, "web", params.String("search"))
+	assert.Equal(t, []string{"prod", "eu"}, params.Strings("tags"))
+	assert.Nil(t, params.Strings("missing"))
+	assert.Equal(t, "active", params.Enum("status", "", []string{"active", "retired"}))
+	assert.Equal(t, "name", params.Enum("sort_by", "name", []string{"name"}))
+	assert.Equal(t, 3, params.Int("page", 1, 1, 0))
+	assert.Equal(t, 7, params.Int("depth", 7, 1, 10))
+	assert.Equal(t, 50, params.PageSize("page_size"))
+	assert.Equal(t, 25, params.PageSize("per_page"), "the endpoint's default page size")
+	require.NotNil(t, params.Bool("is_active"))
+	assert.False(t, *params.Bool("is_active"))
+	assert.Nil(t, params.Bool("missing"))
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), *params.Time("since"))
+	assert.Equal(t, time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC), *params.Time("until"))
+	assert.NoError(t, params.Err())
+}
+
+func TestRequestParams_Invalid(t *testing.T) {
+	params := bindQuery(url.Values{
+		"owner_id":  {"nobody"},
+		"search":    {strings.Repeat("x", maxParamLength+1)},
+		"name":      {"web\x00"},
+		"status":    {"gone"},
+		"page":      {"first"},
+		"page_size": {"500"},
+		"depth":     {"0"},
+		"is_active": {"yes please"},
+		"since":     {"last week"},
+	}, map[string]string{"id": "42", "version": "0"})
+
+	assert.Equal(t, uuid.Nil, params.PathUUID("id"))
+	assert.Zero(t, params.PathInt("version", 1))
+	assert.Nil(t, params.UUID("owner_id"))
+	assert.Empty(t, params.String("search"))
+	assert.Empty(t, params.String("name"))
+	assert.Equal(t, "active", params.Enum("status", "active", []string{"active", "retired"}), "invalid values fall back to the default")
+	assert.Equal(t, 1, params.Int("page", 1, 1, 0))
+	assert.Equal(t, 25, params.PageSize("page_size"))
+	assert.Equal(t, 5, params.Int("depth", 5, 1, 0))
+	assert.Nil(t, params.Bool("is_active"))
+	assert.Nil(t, params.Time("since"))
+
+	err := params.Err()
+	require.Error(t, err)
+	paramErr, ok := err.(*paramError)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"id":        "must be a UUID",
+		"version":   "must be an integer of at least 1",
+		"owner_id":  "must be a UUID",
+		"search":    "must be at most 256 characters",
+		"name":      "must not contain control characters",
+		"status":    "must be one of active, retired",
+		"page":      "must be an integer",
+		"page_size": "must be between 1 and 50",
+		"depth":     "must be at least 1",
+		"is_active": "must be true or false",
+		"since":     "must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+	}, paramErr.Fields)
+	assert.True(t, strings.HasPrefix(err.Error(), "depth must be at least 1; id must be a UUID; "), "problems are listed by parameter name")
+}
+
+func TestRequestParams_KeepsFirstReason(t *testing.T) {
+	params := bindQuery(url.Values{"status": {"gone"}}, nil)
+	params.Enum("status", "", []string{"active"})
+	params.Enum("status", "", []string{"retired"})
+	assert.Equal(t, "status must be one of active", params.Err().Error())
+}
+
+func TestRespondWithParamError(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondWithParamError(w, &paramError{Fields: map[string]string{"page": "must be an integer"}})
+
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Invalid request parameters", body["error"])
+	assert.Equal(t, "page must be an integer", body["details"])
+	assert.Equal(t, map[string]interface{}{"page": "must be an integer"}, body["fields"])
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/residency"
+	"github.com/gorilla/mux"
+)
+
+// ResidencyHandler exposes the tenant shard assignments and the health of
+// every shard
+type ResidencyHandler struct {
+	router *residency.Router
+}
+
+// NewResidencyHandler creates a new ResidencyHandler
+func NewResidencyHandler(router *residency.Router) *ResidencyHandler {
+	return &ResidencyHandler{router: router}
+}
+
+// RegisterRoutes registers residency routes
+func (h *ResidencyHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/health/shards", h.handleGetShardHealth).Methods("GET")
+	router.HandleFunc("/api/v1/admin/residency", h.authMiddleware(h.handleGetResidency)).Methods("GET")
+}
+
+// handleGetShardHealth handles pinging the main database and every shard; a
+// shard that is down responds with 503
+func (h *ResidencyHandler) handleGetShardHealth(w http.ResponseWriter, r *http.Request) {
+	health := h.router.Health(r.Context())
+
+	code := http.StatusOK
+	if !residency.Healthy(health) {
+		code = http.StatusServiceUnavailable
+	}
+
+	h.respondWithJSON(w, code, map[string]interface{}{
+		"healthy": code == http.StatusOK,
+		"shards":  health,
+	})
+}
+
+// handleGetResidency handles listing the tenants not on the main database
+// along with the health of each shard
+func (h *ResidencyHandler) handleGetResidency(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"assignments": h.router.Assignments(),
+		"shards":      h.router.Health(r.Context()),
+	})
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role; shard health stays public for probes
+func (h *ResidencyHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// respondWithJSON sends a JSON response
+func (h *ResidencyHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
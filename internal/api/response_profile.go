@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connect/internal/models"
+	"connect/internal/repositories"
+)
+
+// Response profiles selected with ?profile=. The compact profile trims CIs to
+// the fields mobile and field tooling show, for small payloads on slow links.
+const (
+	profileFull    = "full"
+	profileCompact = "compact"
+)
+
+// compactPageSize is the default page size of compact CI lists unless the
+// endpoint's default is smaller; an explicit page size still applies
+const compactPageSize = 10
+
+// responseProfile returns the response profile requested by the client
+func responseProfile(r *http.Request) (string, error) {
+	switch profile := strings.ToLower(r.URL.Query().Get("profile")); profile {
+	case "", profileFull:
+		return profileFull, nil
+	case profileCompact:
+		return profileCompact, nil
+	default:
+		return "", fmt.Errorf("unsupported profile %q, expected %s or %s", profile, profileFull, profileCompact)
+	}
+}
+
+// ProfilePageSize returns the page size query parameter of a CI list within
+// the page size limits of the endpoint, defaulting per profile
+func (p *requestParams) ProfilePageSize(name, profile string) int {
+	def := p.limits.Default
+	if profile == profileCompact && compactPageSize < def {
+		def = compactPageSize
+	}
+	return p.Int(name, def, 1, p.limits.Max)
+}
+
+// projectCIs returns the CIs as served in the profile: unchanged for the full
+// profile, projected with resolved user names for the compact one
+func projectCIs(ctx context.Context, ciRepo *repositories.CIRepository, profile string, cis []models.CI) (interface{}, error) {
+	if profile != profileCompact {
+		if cis == nil {
+			cis = []models.CI{}
+		}
+		return cis, nil
+	}
+
+	names, err := ciRepo.ResolveUserDisplayNames(ctx, models.UserReferences(cis))
+	if err != nil {
+		return nil, err
+	}
+
+	compact := make([]models.CompactCI, len(cis))
+	for i := range cis {
+		compact[i] = models.NewCompactCI(&cis[i], names)
+	}
+	return compact, nil
+}
+
+// projectCI returns a single CI as served in the profile
+func projectCI(ctx context.Context, ciRepo *repositories.CIRepository, profile string, ci *models.CI) (interface{}, error) {
+	if profile != profileCompact {
+		return ci, nil
+	}
+
+	projected, err := projectCIs(ctx, ciRepo, profile, []models.CI{*ci})
+	if err != nil {
+		return nil, err
+	}
+	return projected.([]models.CompactCI)[0], nil
+}
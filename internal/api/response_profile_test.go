@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseProfile(t *testing.T) {
+	for query, expected := range map[string]string{
+		"":                 profileFull,
+		"?profile=full":    profileFull,
+		"?profile=compact": profileCompact,
+		"?profile=COMPACT": profileCompact,
+	} {
+		profile, err := responseProfile(httptest.NewRequest("GET", "/api/v1/cis"+query, nil))
+		require.NoError(t, err, query)
+		assert.Equal(t, expected, profile, query)
+	}
+
+	_, err := responseProfile(httptest.NewRequest("GET", "/api/v1/cis?profile=tiny", nil))
+	assert.Error(t, err)
+}
+
+func TestRequestParams_ProfilePageSize(t *testing.T) {
+	pageSize := func(query, profile string) int {
+		return bindRequest(httptest.NewRequest("GET", "/api/v1/cis"+query, nil)).ProfilePageSize("page_size", profile)
+	}
+
+	assert.Equal(t, 20, pageSize("", profileFull))
+	assert.Equal(t, compactPageSize, pageSize("", profileCompact), "compact lists default to fewer CIs")
+	assert.Equal(t, 50, pageSize("?page_size=50", profileCompact), "an explicit page size still applies")
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/auth"
+	"connect/internal/config"
+	"connect/internal/logger"
+	"connect/internal/models"
+	"connect/internal/repositories"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// RoleHandler handles role management and permission grants, admin only
+type RoleHandler struct {
+	config         *config.Config
+	logger         *logger.Logger
+	roleRepository *repositories.RoleRepository
+	permissions    *auth.CachedPermissionResolver
+}
+
+func NewRoleHandler(config *config.Config, appLogger *logger.Logger, roleRepository *repositories.RoleRepository) *RoleHandler {
+	return &RoleHandler{
+		config:         config,
+		logger:         appLogger,
+		roleRepository: roleRepository,
+	}
+}
+
+// SetPermissionCache invalidates the cached permissions of roles as they are
+// changed, so that grants, revocations, deactivations and deletions apply
+// from the next request
+func (h *RoleHandler) SetPermissionCache(permissions *auth.CachedPermissionResolver) {
+	h.permissions = permissions
+}
+
+// invalidatePermissions drops the cached permissions of the named roles. A
+// failure is only logged, as the entries still expire.
+func (h *RoleHandler) invalidatePermissions(r *http.Request, names ...string) {
+	if h.permissions == nil {
+		return
+	}
+	for _, name := range names {
+		if err := h.permissions.Invalidate(r.Context(), name); err != nil {
+			h.logger.ErrorRequest(r, err, "Failed to invalidate cached role permissions", map[string]interface{}{"role": name})
+		}
+	}
+}
+
+// invalidateRolePermissions drops the cached permissions of the role with the ID
+func (h *RoleHandler) invalidateRolePermissions(r *http.Request, roleID uuid.UUID) {
+	if h.permissions == nil {
+		return
+	}
+	role, err := h.roleRepository.GetRoleByID(r.Context(), roleID)
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to get role to invalidate its cached permissions", map[string]interface{}{"role_id": roleID})
+		return
+	}
+	h.invalidatePermissions(r, role.Name)
+}
+
+// GrantRolePermissionRequest represents a request to grant a permission to a role
+type GrantRolePermissionRequest struct {
+	PermissionID uuid.UUID `json:"permission_id"`
+}
+
+// ListRoles handles listing roles a page at a time, filtered by the name,
+// is_active, is_default and is_system query parameters
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	page := params.Int("page", 1, 1, 0)
+	size := params.PageSize("size")
+	filter := &models.RoleFilterOptions{
+		Name:      params.String("name"),
+		IsActive:  params.Bool("is_active"),
+		IsDefault: params.Bool("is_default"),
+		IsSystem:  params.Bool("is_system"),
+		SortBy:    params.Enum("sort_by", "", []string{"name", "display_name", "created_at", "is_active"}),
+		SortOrder: params.Enum("sort_order", "desc", []string{"asc", "desc"}),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	roles, err := h.roleRepository.ListRoles(r.Context(), filter, page, size)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to list roles", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, roles)
+}
+
+// CreateRole handles creating a role
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode create role request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		h.logger.ErrorRequest(r, err, "Invalid create role request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	role, err := h.roleRepository.CreateRole(r.Context(), &req)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to create role", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Role created successfully", map[string]interface{}{"role_id": role.ID, "name": role.Name})
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, role.ToResponse([]models.PermissionResponse{}, 0))
+}
+
+// GetRole handles getting a role with its permissions and the number of users holding it
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := uuidParam(w, r, "id", "role")
+	if !ok {
+		return
+	}
+
+	role, err := h.roleRepository.GetRoleByID(r.Context(), roleID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to get role", err)
+		return
+	}
+
+	h.respondWithRole(w, r, http.StatusOK, role)
+}
+
+// UpdateRole handles updating a role. System roles cannot be changed.
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := uuidParam(w, r, "id", "role")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode update role request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		h.logger.ErrorRequest(r, err, "Invalid update role request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	previous, err := h.roleRepository.GetRoleByID(r.Context(), roleID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to get role", err)
+		return
+	}
+
+	role, err := h.roleRepository.UpdateRole(r.Context(), roleID, &req)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to update role", err)
+		return
+	}
+	// Deactivating a role revokes its permissions, and renaming it moves them
+	// to the new name
+	h.invalidatePermissions(r, previous.Name, role.Name)
+
+	h.logger.InfoRequest(r, "Role updated successfully", map[string]interface{}{"role_id": roleID})
+	h.respondWithRole(w, r, http.StatusOK, role)
+}
+
+// DeleteRole handles deleting a role that is neither a default or system role
+// nor assigned to any user
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := uuidParam(w, r, "id", "role")
+	if !ok {
+		return
+	}
+
+	role, err := h.roleRepository.GetRoleByID(r.Context(), roleID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to get role", err)
+		return
+	}
+
+	if err := h.roleRepository.DeleteRole(r.Context(), roleID); err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to delete role", err)
+		return
+	}
+	h.invalidatePermissions(r, role.Name)
+
+	h.logger.InfoRequest(r, "Role deleted successfully", map[string]interface{}{"role_id": roleID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRolePermissions handles listing the permissions granted to a role
+func (h *RoleHandler) GetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := uuidParam(w, r, "id", "role")
+	if !ok {
+		return
+	}
+
+	if _, err := h.roleRepository.GetRoleByID(r.Context(), roleID); err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to get role", err)
+		return
+	}
+
+	permissions, err := h.roleRepository.GetRolePermissions(r.Context(), roleID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to get role permissions", err)
+		return
+	}
+	if permissions == nil {
+		permissions = []models.Permission{}
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"role_id": roleID, "permissions": permissions})
+}
+
+// GrantPermission handles granting a permission to a role
+func (h *RoleHandler) GrantPermission(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := uuidParam(w, r, "id", "role")
+	if !ok {
+		return
+	}
+
+	var req GrantRolePermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PermissionID == uuid.Nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body, permission_id is required"})
+		return
+	}
+
+	if err := h.roleRepository.GrantPermissionToRole(r.Context(), roleID, req.PermissionID); err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to grant permission", err)
+		return
+	}
+	h.invalidateRolePermissions(r, roleID)
+
+	h.logger.InfoRequest(r, "Permission granted to role", map[string]interface{}{"role_id": roleID, "permission_id": req.PermissionID})
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{"role_id": roleID, "permission_id": req.PermissionID})
+}
+
+// RevokePermission handles revoking a permission from a role
+func (h *RoleHandler) RevokePermission(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := uuidParam(w, r, "id", "role")
+	if !ok {
+		return
+	}
+	permissionID, ok := uuidParam(w, r, "permissionId", "permission")
+	if !ok {
+		return
+	}
+
+	if err := h.roleRepository.RevokePermissionFromRole(r.Context(), roleID, permissionID); err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to revoke permission", err)
+		return
+	}
+	h.invalidateRolePermissions(r, roleID)
+
+	h.logger.InfoRequest(r, "Permission revoked from role", map[string]interface{}{"role_id": roleID, "permission_id": permissionID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routes returns the role management routes, all of which require the admin role
+func (h *RoleHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.Get("/", h.ListRoles)
+	r.Post("/", h.CreateRole)
+	r.Get("/{id}", h.GetRole)
+	r.Put("/{id}", h.UpdateRole)
+	r.Delete("/{id}", h.DeleteRole)
+	r.Get("/{id}/permissions", h.GetRolePermissions)
+	r.Post("/{id}/permissions", h.GrantPermission)
+	r.Delete("/{id}/permissions/{permissionId}", h.RevokePermission)
+
+	return r
+}
+
+// respondWithRole sends a role with its permissions and user count
+func (h *RoleHandler) respondWithRole(w http.ResponseWriter, r *http.Request, code int, role *models.Role) {
+	permissions, err := h.roleRepository.GetRolePermissions(r.Context(), role.ID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to get role permissions", err)
+		return
+	}
+	userCount, err := h.roleRepository.CountUsersByRole(r.Context(), role.ID)
+	if err != nil {
+		respondWithRBACError(w, r, h.logger, "Failed to count role users", err)
+		return
+	}
+
+	responses := make([]models.PermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		roleCount, err := h.roleRepository.CountRolesByPermission(r.Context(), permission.ID)
+		if err != nil {
+			respondWithRBACError(w, r, h.logger, "Failed to count permission roles", err)
+			return
+		}
+		responses = append(responses, permission.ToResponse(roleCount))
+	}
+
+	render.Status(r, code)
+	render.JSON(w, r, role.ToResponse(responses, userCount))
+}
+
+// respondWithRBACError maps role and permission repository errors to status codes
+func respondWithRBACError(w http.ResponseWriter, r *http.Request, appLogger *logger.Logger, message string, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrRoleNotFound), errors.Is(err, repositories.ErrPermissionNotFound),
+		errors.Is(err, repositories.ErrRolePermissionNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, repositories.ErrRoleAlreadyExists), errors.Is(err, repositories.ErrPermissionAlreadyExists),
+		errors.Is(err, repositories.ErrRolePermissionAlreadyExists),
+		errors.Is(err, repositories.ErrRoleInUse), errors.Is(err, repositories.ErrPermissionInUse):
+		code = http.StatusConflict
+	case errors.Is(err, repositories.ErrCannotDeleteDefaultRole), errors.Is(err, repositories.ErrCannotDeleteSystemPermission),
+		errors.Is(err, repositories.ErrSharedRole):
+		code = http.StatusForbidden
+	default:
+		appLogger.ErrorRequest(r, err, message)
+		render.Status(r, code)
+		render.JSON(w, r, map[string]string{"error": message})
+		return
+	}
+
+	render.Status(r, code)
+	render.JSON(w, r, map[string]string{"error": message, "details": err.Error()})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connect/internal/auth"
+	"connect/internal/logger"
+	"connect/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleHandler_RejectsBeforeRepository(t *testing.T) {
+	// The handler has no repository, so these requests must be answered
+	// without reaching it
+	routes := NewRoleHandler(nil, logger.NewLogger("test"), nil).Routes()
+	roleID := uuid.New().String()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{"invalid filter", "GET", "/?is_active=maybe", "", http.StatusBadRequest, "Invalid request parameters"},
+		{"invalid sort", "GET", "/?sort_by=password", "", http.StatusBadRequest, "Invalid request parameters"},
+		{"malformed role", "POST", "/", "{", http.StatusBadRequest, "Invalid request body"},
+		{"invalid role ID", "GET", "/admin", "", http.StatusBadRequest, "Invalid role ID"},
+		{"malformed update", "PUT", "/" + roleID, "[]", http.StatusBadRequest, "Invalid request body"},
+		{"missing permission", "POST", "/" + roleID + "/permissions", `{}`, http.StatusBadRequest, "Invalid request body, permission_id is required"},
+		{"invalid permission ID", "DELETE", "/" + roleID + "/permissions/ci:read", "", http.StatusBadRequest, "Invalid permission ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := asActor(httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)), uuid.New(), auth.ActorTypeUser, "admin")
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.message, errorMessage(t, w))
+		})
+	}
+
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, asActor(httptest.NewRequest("GET", "/", nil), uuid.New(), auth.ActorTypeUser, "viewer"))
+	assert.Equal(t, http.StatusForbidden, w.Code, "role management is admin only")
+}
+
+func TestRespondWithRBACError(t *testing.T) {
+	appLogger := logger.NewLogger("test")
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{repositories.ErrRoleNotFound, http.StatusNotFound},
+		{repositories.ErrPermissionNotFound, http.StatusNotFound},
+		{repositories.ErrRolePermissionNotFound, http.StatusNotFound},
+		{repositories.ErrRoleAlreadyExists, http.StatusConflict},
+		{repositories.ErrPermissionAlreadyExists, http.StatusConflict},
+		{repositories.ErrRolePermissionAlreadyExists, http.StatusConflict},
+		{fmt.Errorf("cannot delete role: %w: 3 users have this role", repositories.ErrRoleInUse), http.StatusConflict},
+		{fmt.Errorf("cannot delete permission: %w: 2 roles have this permission", repositories.ErrPermissionInUse), http.StatusConflict},
+		{repositories.ErrCannotDeleteDefaultRole, http.StatusForbidden},
+		{repositories.ErrCannotDeleteSystemPermission, http.StatusForbidden},
+		{repositories.ErrSharedRole, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		respondWithRBACError(w, httptest.NewRequest("DELETE", "/", nil), appLogger, "Failed to delete role", tt.err)
+		assert.Equal(t, tt.status, w.Code, tt.err.Error())
+		assert.JSONEq(t, fmt.Sprintf(`{"error": "Failed to delete role", "details": %q}`, tt.err.Error()), w.Body.String())
+	}
+
+	// Unexpected errors are logged rather than shown to the client
+	w := httptest.NewRecorder()
+	respondWithRBACError(w, httptest.NewRequest("DELETE", "/", nil), appLogger, "Failed to delete role", fmt.Errorf("connection reset"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error": "Failed to delete role"}`, w.Body.String())
+}
@@ -1,19 +1,23 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"connect/internal/models"
 	"connect/internal/repositories"
+	"connect/internal/schemaform"
+	"connect/internal/webhooks"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 // SchemaHandler handles schema management endpoints
 type SchemaHandler struct {
-	ciRepo *repositories.CIRepository
+	ciRepo   *repositories.CIRepository
+	webhooks *webhooks.Service
 }
 
 // NewSchemaHandler creates a new SchemaHandler
@@ -21,6 +25,25 @@ func NewSchemaHandler(ciRepo *repositories.CIRepository) *SchemaHandler {
 	return &SchemaHandler{ciRepo: ciRepo}
 }
 
+// SetWebhooks publishes schema lifecycle events to the webhook endpoints
+func (h *SchemaHandler) SetWebhooks(service *webhooks.Service) {
+	h.webhooks = service
+}
+
+// Schema kinds in schema webhook events
+const (
+	schemaKindCIType           = "ci_type"
+	schemaKindRelationshipType = "relationship_type"
+)
+
+// publishSchemaEvent publishes a schema webhook event, if webhooks are
+// enabled. The kind tells CI type schemas from relationship type schemas.
+func (h *SchemaHandler) publishSchemaEvent(ctx context.Context, eventType, kind string, schema interface{}) {
+	if h.webhooks != nil {
+		h.webhooks.Publish(ctx, eventType, map[string]interface{}{"kind": kind, "schema": schema})
+	}
+}
+
 // RegisterRoutes registers schema management routes
 func (h *SchemaHandler) RegisterRoutes(router *mux.Router) {
 	// CI Type Schema routes
@@ -29,10 +52,13 @@ func (h *SchemaHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/v1/schemas/ci-types/{id}", h.authMiddleware(h.handleGetCITypeSchema)).Methods("GET")
 	router.HandleFunc("/api/v1/schemas/ci-types/{id}", h.authMiddleware(h.handleUpdateCITypeSchema)).Methods("PUT")
 	router.HandleFunc("/api/v1/schemas/ci-types/{id}", h.authMiddleware(h.handleDeleteCITypeSchema)).Methods("DELETE")
+	router.HandleFunc("/api/v1/schemas/ci-types/{id}/usage", h.authMiddleware(h.handleGetCITypeSchemaUsage)).Methods("GET")
+	router.HandleFunc("/api/v1/schemas/ci-types/{name}/form", h.authMiddleware(h.handleGetCITypeForm)).Methods("GET")
 
 	// Relationship Type Schema routes
 	router.HandleFunc("/api/v1/schemas/relationship-types", h.authMiddleware(h.handleListRelationshipTypeSchemas)).Methods("GET")
 	router.HandleFunc("/api/v1/schemas/relationship-types", h.authMiddleware(h.handleCreateRelationshipTypeSchema)).Methods("POST")
+	router.HandleFunc("/api/v1/schemas/relationship-types/usage", h.authMiddleware(h.handleGetRelationshipTypeUsage)).Methods("GET")
 	router.HandleFunc("/api/v1/schemas/relationship-types/{id}", h.authMiddleware(h.handleGetRelationshipTypeSchema)).Methods("GET")
 	router.HandleFunc("/api/v1/schemas/relationship-types/{id}", h.authMiddleware(h.handleUpdateRelationshipTypeSchema)).Methods("PUT")
 	router.HandleFunc("/api/v1/schemas/relationship-types/{id}", h.authMiddleware(h.handleDeleteRelationshipTypeSchema)).Methods("DELETE")
@@ -53,11 +79,13 @@ func (h *SchemaHandler) RegisterRoutes(router *mux.Router) {
 func (h *SchemaHandler) handleListCITypeSchemas(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Parse pagination parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	req, err := h.parseListSchemasRequest(r)
+	if err != nil {
+		respondWithParamError(w, err)
+		return
+	}
 
-	schemas, totalCount, err := h.ciRepo.ListCITypeSchemas(ctx, page, pageSize)
+	schemas, totalCount, err := h.ciRepo.SearchCITypeSchemas(ctx, req)
 	if err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to list CI type schemas", err)
 		return
@@ -66,8 +94,9 @@ func (h *SchemaHandler) handleListCITypeSchemas(w http.ResponseWriter, r *http.R
 	response := map[string]interface{}{
 		"schemas":     schemas,
 		"total_count": totalCount,
-		"page":        page,
-		"page_size":   pageSize,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"total_pages": (totalCount + int64(req.PageSize) - 1) / int64(req.PageSize),
 	}
 
 	h.respondWithJSON(w, http.StatusOK, response)
@@ -115,6 +144,7 @@ func (h *SchemaHandler) handleCreateCITypeSchema(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.publishSchemaEvent(ctx, webhooks.EventSchemaCreated, schemaKindCIType, schema)
 	h.respondWithJSON(w, http.StatusCreated, schema)
 }
 
@@ -138,6 +168,48 @@ func (h *SchemaHandler) handleGetCITypeSchema(w http.ResponseWriter, r *http.Req
 	h.respondWithJSON(w, http.StatusOK, schema)
 }
 
+// handleGetCITypeSchemaUsage handles reporting how the CIs of a type use the
+// attributes of its schema
+func (h *SchemaHandler) handleGetCITypeSchemaUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	schemaID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid schema ID", err)
+		return
+	}
+
+	schema, err := h.ciRepo.GetCITypeSchema(ctx, schemaID)
+	if err != nil {
+		h.respondWithError(w, http.StatusNotFound, "CI type schema not found", err)
+		return
+	}
+
+	usage, err := h.ciRepo.GetCITypeSchemaUsage(ctx, schema)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get CI type schema usage", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, usage)
+}
+
+// handleGetCITypeForm handles retrieving the form metadata of a CI type, so the
+// frontend can generate its create and edit forms
+func (h *SchemaHandler) handleGetCITypeForm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	schema, err := h.ciRepo.GetCITypeSchemaByName(ctx, vars["name"])
+	if err != nil {
+		h.respondWithError(w, http.StatusNotFound, "CI type schema not found", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, schemaform.Build(schema))
+}
+
 // handleUpdateCITypeSchema handles updating a CI type schema
 func (h *SchemaHandler) handleUpdateCITypeSchema(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -194,6 +266,7 @@ func (h *SchemaHandler) handleUpdateCITypeSchema(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.publishSchemaEvent(ctx, webhooks.EventSchemaUpdated, schemaKindCIType, updatedSchema)
 	h.respondWithJSON(w, http.StatusOK, updatedSchema)
 }
 
@@ -209,7 +282,7 @@ func (h *SchemaHandler) handleDeleteCITypeSchema(w http.ResponseWriter, r *http.
 	}
 
 	// Check if schema exists
-	_, err = h.ciRepo.GetCITypeSchema(ctx, schemaID)
+	existingSchema, err := h.ciRepo.GetCITypeSchema(ctx, schemaID)
 	if err != nil {
 		h.respondWithError(w, http.StatusNotFound, "CI type schema not found", err)
 		return
@@ -221,6 +294,7 @@ func (h *SchemaHandler) handleDeleteCITypeSchema(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.publishSchemaEvent(ctx, webhooks.EventSchemaDeleted, schemaKindCIType, existingSchema)
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"message": "CI type schema deleted successfully"})
 }
 
@@ -230,11 +304,13 @@ func (h *SchemaHandler) handleDeleteCITypeSchema(w http.ResponseWriter, r *http.
 func (h *SchemaHandler) handleListRelationshipTypeSchemas(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Parse pagination parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	req, err := h.parseListSchemasRequest(r)
+	if err != nil {
+		respondWithParamError(w, err)
+		return
+	}
 
-	schemas, totalCount, err := h.ciRepo.ListRelationshipTypeSchemas(ctx, page, pageSize)
+	schemas, totalCount, err := h.ciRepo.SearchRelationshipTypeSchemas(ctx, req)
 	if err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to list relationship type schemas", err)
 		return
@@ -243,8 +319,9 @@ func (h *SchemaHandler) handleListRelationshipTypeSchemas(w http.ResponseWriter,
 	response := map[string]interface{}{
 		"schemas":     schemas,
 		"total_count": totalCount,
-		"page":        page,
-		"page_size":   pageSize,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"total_pages": (totalCount + int64(req.PageSize) - 1) / int64(req.PageSize),
 	}
 
 	h.respondWithJSON(w, http.StatusOK, response)
@@ -296,6 +373,7 @@ func (h *SchemaHandler) handleCreateRelationshipTypeSchema(w http.ResponseWriter
 		return
 	}
 
+	h.publishSchemaEvent(ctx, webhooks.EventSchemaCreated, schemaKindRelationshipType, schema)
 	h.respondWithJSON(w, http.StatusCreated, schema)
 }
 
@@ -378,6 +456,7 @@ func (h *SchemaHandler) handleUpdateRelationshipTypeSchema(w http.ResponseWriter
 		return
 	}
 
+	h.publishSchemaEvent(ctx, webhooks.EventSchemaUpdated, schemaKindRelationshipType, updatedSchema)
 	h.respondWithJSON(w, http.StatusOK, updatedSchema)
 }
 
@@ -393,21 +472,38 @@ func (h *SchemaHandler) handleDeleteRelationshipTypeSchema(w http.ResponseWriter
 	}
 
 	// Check if schema exists
-	_, err = h.ciRepo.GetRelationshipTypeSchema(ctx, schemaID)
+	existingSchema, err := h.ciRepo.GetRelationshipTypeSchema(ctx, schemaID)
 	if err != nil {
 		h.respondWithError(w, http.StatusNotFound, "Relationship type schema not found", err)
 		return
 	}
 
-	// Delete schema
+	// Delete schema, unless relationships still use it
 	if err := h.ciRepo.DeleteRelationshipTypeSchema(ctx, schemaID); err != nil {
+		if errors.Is(err, models.ErrRelationshipTypeInUse) {
+			h.respondWithError(w, http.StatusConflict, "Relationship type schema is in use", err)
+			return
+		}
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to delete relationship type schema", err)
 		return
 	}
 
+	h.publishSchemaEvent(ctx, webhooks.EventSchemaDeleted, schemaKindRelationshipType, existingSchema)
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"message": "Relationship type schema deleted successfully"})
 }
 
+// handleGetRelationshipTypeUsage handles reporting how many relationships use
+// each relationship type and which types are unused
+func (h *SchemaHandler) handleGetRelationshipTypeUsage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.ciRepo.GetRelationshipTypeUsage(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get relationship type usage", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, report)
+}
+
 // Schema Validation Handlers
 
 // handleValidateCIAgainstSchema handles validating CI data against a schema
@@ -507,11 +603,36 @@ func (h *SchemaHandler) handleCreateSchemaFromTemplate(w http.ResponseWriter, r
 		return
 	}
 
+	h.publishSchemaEvent(ctx, webhooks.EventSchemaCreated, schemaKindCIType, schema)
 	h.respondWithJSON(w, http.StatusCreated, schema)
 }
 
 // Helper methods
 
+// parseListSchemasRequest parses the pagination, filter and sort parameters of
+// a schema listing, e.g. ?search=server&is_active=true&attribute=ip_address&sort_by=updated_at&sort_order=desc
+func (h *SchemaHandler) parseListSchemasRequest(r *http.Request) (*models.ListSchemasRequest, error) {
+	params := bindRequest(r)
+	req := &models.ListSchemasRequest{
+		Page:      params.Int("page", 1, 1, 0),
+		PageSize:  params.PageSize("page_size"),
+		Search:    params.String("search"),
+		IsActive:  params.Bool("is_active"),
+		Attribute: params.String("attribute"),
+		CreatedBy: params.UUID("created_by"),
+		SortBy:    params.Enum("sort_by", "name", models.SchemaSortFields),
+		SortOrder: params.Enum("sort_order", models.SortOrderAsc, models.SortOrders),
+	}
+	if err := params.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // authMiddleware is a placeholder for authentication middleware
 func (h *SchemaHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
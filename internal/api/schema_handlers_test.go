@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaHandler_ParseListSchemasRequest(t *testing.T) {
+	h := &SchemaHandler{}
+	creator := uuid.New()
+
+	req, err := h.parseListSchemasRequest(httptest.NewRequest("GET",
+		"/api/v1/schemas/ci-types?search=server&is_active=false&attribute=ip_address&created_by="+creator.String()+"&sort_by=updated_at&sort_order=DESC&page=2&page_size=5", nil))
+	require.NoError(t, err)
+	inactive := false
+	assert.Equal(t, &models.ListSchemasRequest{
+		Page:      2,
+		PageSize:  5,
+		Search:    "server",
+		IsActive:  &inactive,
+		Attribute: "ip_address",
+		CreatedBy: &creator,
+		SortBy:    "updated_at",
+		SortOrder: models.SortOrderDesc,
+	}, req)
+
+	req, err = h.parseListSchemasRequest(httptest.NewRequest("GET", "/api/v1/schemas/ci-types", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "name", req.SortBy)
+	assert.Equal(t, models.SortOrderAsc, req.SortOrder)
+	assert.Equal(t, 1, req.Page)
+
+	for _, query := range []string{"?is_active=maybe", "?created_by=someone", "?sort_by=attributes", "?sort_order=up", "?page=0"} {
+		_, err := h.parseListSchemasRequest(httptest.NewRequest("GET", "/api/v1/schemas/ci-types"+query, nil))
+		assert.Error(t, err, query)
+	}
+}
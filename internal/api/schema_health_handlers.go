@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/schemacheck"
+	"github.com/gorilla/mux"
+)
+
+// SchemaHealthHandler exposes the result of the schema self-check
+type SchemaHealthHandler struct {
+	checker *schemacheck.Checker
+}
+
+// NewSchemaHealthHandler creates a new SchemaHealthHandler
+func NewSchemaHealthHandler(checker *schemacheck.Checker) *SchemaHealthHandler {
+	return &SchemaHealthHandler{checker: checker}
+}
+
+// RegisterRoutes registers schema health routes
+func (h *SchemaHealthHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/health/schema", h.handleGetSchemaHealth).Methods("GET")
+}
+
+// handleGetSchemaHealth handles reporting schema drift. The startup report is returned
+// unless ?refresh=true asks for a new check; drift responds with 503.
+func (h *SchemaHealthHandler) handleGetSchemaHealth(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.LastReport()
+	if report == nil || r.URL.Query().Get("refresh") == "true" {
+		report = h.checker.Check(r.Context())
+	}
+
+	code := http.StatusOK
+	if !report.OK() {
+		code = http.StatusServiceUnavailable
+	}
+
+	h.respondWithJSON(w, code, report)
+}
+
+// respondWithJSON sends a JSON response
+func (h *SchemaHealthHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"connect/internal/config"
+	"connect/internal/logger"
+	"connect/internal/scim"
+	"github.com/go-chi/chi/v5"
+)
+
+// SCIMHandler handles SCIM 2.0 provisioning by identity providers. Its routes
+// are authenticated by the configured SCIM bearer token rather than user
+// tokens, and speak SCIM messages instead of the API's own.
+type SCIMHandler struct {
+	config  *config.Config
+	logger  *logger.Logger
+	service *scim.Service
+}
+
+func NewSCIMHandler(config *config.Config, appLogger *logger.Logger, service *scim.Service) *SCIMHandler {
+	return &SCIMHandler{
+		config:  config,
+		logger:  appLogger,
+		service: service,
+	}
+}
+
+// Authenticate refuses requests without the SCIM bearer token
+func (h *SCIMHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if h.config.SCIM.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.config.SCIM.Token)) != 1 {
+			h.respond(w, http.StatusUnauthorized, &scim.Error{Status: http.StatusUnauthorized, Detail: "Invalid SCIM token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListUsers handles listing users, e.g. GET /Users?filter=userName eq "jdoe"
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	startIndex, count := scimPage(r)
+	list, err := h.service.ListUsers(r.Context(), r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to list SCIM users", err)
+		return
+	}
+	h.respond(w, http.StatusOK, list)
+}
+
+// CreateUser handles provisioning a user
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var user scim.User
+	if !h.decode(w, r, &user) {
+		return
+	}
+	created, err := h.service.CreateUser(r.Context(), user)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to provision SCIM user", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM user provisioned", map[string]interface{}{
+		"user_id":  created.ID,
+		"username": created.UserName,
+	})
+	h.respond(w, http.StatusCreated, created)
+}
+
+// GetUser handles retrieving a user
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to get SCIM user", err)
+		return
+	}
+	h.respond(w, http.StatusOK, user)
+}
+
+// ReplaceUser handles replacing a user
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	var user scim.User
+	if !h.decode(w, r, &user) {
+		return
+	}
+	replaced, err := h.service.ReplaceUser(r.Context(), chi.URLParam(r, "id"), user)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to replace SCIM user", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM user replaced", map[string]interface{}{
+		"user_id": replaced.ID,
+		"active":  *replaced.Active,
+	})
+	h.respond(w, http.StatusOK, replaced)
+}
+
+// PatchUser handles modifying a user, e.g. deactivating them on deprovisioning
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	var patch scim.PatchRequest
+	if !h.decode(w, r, &patch) {
+		return
+	}
+	patched, err := h.service.PatchUser(r.Context(), chi.URLParam(r, "id"), patch)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to patch SCIM user", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM user patched", map[string]interface{}{
+		"user_id": patched.ID,
+		"active":  *patched.Active,
+	})
+	h.respond(w, http.StatusOK, patched)
+}
+
+// DeleteUser handles deleting a user
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.service.DeleteUser(r.Context(), id); err != nil {
+		h.respondWithSCIMError(w, r, "Failed to delete SCIM user", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM user deleted", map[string]interface{}{
+		"user_id": id,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroups handles listing groups, e.g. GET /Groups?filter=displayName eq "Admins"
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	startIndex, count := scimPage(r)
+	list, err := h.service.ListGroups(r.Context(), r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to list SCIM groups", err)
+		return
+	}
+	h.respond(w, http.StatusOK, list)
+}
+
+// CreateGroup handles linking a group to its role
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var group scim.Group
+	if !h.decode(w, r, &group) {
+		return
+	}
+	created, err := h.service.CreateGroup(r.Context(), group)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to provision SCIM group", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM group provisioned", map[string]interface{}{
+		"role_id": created.ID,
+		"group":   created.DisplayName,
+		"members": len(created.Members),
+	})
+	h.respond(w, http.StatusCreated, created)
+}
+
+// GetGroup handles retrieving a group with its members
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.service.GetGroup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to get SCIM group", err)
+		return
+	}
+	h.respond(w, http.StatusOK, group)
+}
+
+// ReplaceGroup handles replacing the members of a group
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	var group scim.Group
+	if !h.decode(w, r, &group) {
+		return
+	}
+	replaced, err := h.service.ReplaceGroup(r.Context(), chi.URLParam(r, "id"), group)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to replace SCIM group", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM group replaced", map[string]interface{}{
+		"role_id": replaced.ID,
+		"members": len(replaced.Members),
+	})
+	h.respond(w, http.StatusOK, replaced)
+}
+
+// PatchGroup handles adding and removing the members of a group
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	var patch scim.PatchRequest
+	if !h.decode(w, r, &patch) {
+		return
+	}
+	patched, err := h.service.PatchGroup(r.Context(), chi.URLParam(r, "id"), patch)
+	if err != nil {
+		h.respondWithSCIMError(w, r, "Failed to patch SCIM group", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM group patched", map[string]interface{}{
+		"role_id": patched.ID,
+		"members": len(patched.Members),
+	})
+	h.respond(w, http.StatusOK, patched)
+}
+
+// DeleteGroup handles unlinking a group, which revokes its role from its
+// members and keeps the role
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.service.DeleteGroup(r.Context(), id); err != nil {
+		h.respondWithSCIMError(w, r, "Failed to delete SCIM group", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "SCIM group deleted", map[string]interface{}{
+		"role_id": id,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routes returns the SCIM routes
+func (h *SCIMHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.Authenticate)
+
+	r.Route("/Users", func(r chi.Router) {
+		r.Get("/", h.ListUsers)
+		r.Post("/", h.CreateUser)
+		r.Get("/{id}", h.GetUser)
+		r.Put("/{id}", h.ReplaceUser)
+		r.Patch("/{id}", h.PatchUser)
+		r.Delete("/{id}", h.DeleteUser)
+	})
+	r.Route("/Groups", func(r chi.Router) {
+		r.Get("/", h.ListGroups)
+		r.Post("/", h.CreateGroup)
+		r.Get("/{id}", h.GetGroup)
+		r.Put("/{id}", h.ReplaceGroup)
+		r.Patch("/{id}", h.PatchGroup)
+		r.Delete("/{id}", h.DeleteGroup)
+	})
+
+	return r
+}
+
+// scimPage returns the startIndex and count query parameters. Count defaults
+// to scim.MaxResults.
+func scimPage(r *http.Request) (int, int) {
+	startIndex, count := 1, scim.MaxResults
+	if value, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil {
+		startIndex = value
+	}
+	if value, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil {
+		count = value
+	}
+	return startIndex, count
+}
+
+// decode decodes a SCIM request body, responding with 400 if it is invalid
+func (h *SCIMHandler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode SCIM request")
+		h.respond(w, http.StatusBadRequest, &scim.Error{Status: http.StatusBadRequest, Type: scim.ErrorInvalidSyntax, Detail: "Invalid request body"})
+		return false
+	}
+	return true
+}
+
+// respond writes a SCIM message
+func (h *SCIMHandler) respond(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// respondWithSCIMError reports SCIM errors with their status and any other
+// error as a 500
+func (h *SCIMHandler) respondWithSCIMError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	var scimErr *scim.Error
+	if errors.As(err, &scimErr) {
+		h.respond(w, scimErr.Status, scimErr)
+		return
+	}
+	h.logger.ErrorRequest(r, err, message)
+	h.respond(w, http.StatusInternalServerError, &scim.Error{Status: http.StatusInternalServerError, Detail: message})
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"connect/internal/models"
+	"connect/internal/scripthooks"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ScriptHookHandler handles the pre-save and post-save script hook endpoints
+type ScriptHookHandler struct {
+	service *scripthooks.Service
+}
+
+// NewScriptHookHandler creates a new ScriptHookHandler
+func NewScriptHookHandler(service *scripthooks.Service) *ScriptHookHandler {
+	return &ScriptHookHandler{service: service}
+}
+
+// RegisterRoutes registers script hook routes
+func (h *ScriptHookHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/script-hooks", h.authMiddleware(h.handleListHooks)).Methods("GET")
+	router.HandleFunc("/api/v1/script-hooks", h.authMiddleware(h.handleCreateHook)).Methods("POST")
+	router.HandleFunc("/api/v1/script-hooks/test", h.authMiddleware(h.handleTestHook)).Methods("POST")
+	router.HandleFunc("/api/v1/script-hooks/{id}", h.authMiddleware(h.handleGetHook)).Methods("GET")
+	router.HandleFunc("/api/v1/script-hooks/{id}", h.authMiddleware(h.handleUpdateHook)).Methods("PUT")
+	router.HandleFunc("/api/v1/script-hooks/{id}", h.authMiddleware(h.handleDeleteHook)).Methods("DELETE")
+	router.HandleFunc("/api/v1/script-hooks/{id}/executions", h.authMiddleware(h.handleListExecutions)).Methods("GET")
+}
+
+// ScriptHookRequest represents a request to create or update a script hook
+type ScriptHookRequest struct {
+	Name      string   `json:"name"`
+	Phase     string   `json:"phase"` // pre_save or post_save
+	CITypes   []string `json:"ci_types"`
+	Script    string   `json:"script"`
+	Priority  int      `json:"priority"`
+	TimeoutMs int      `json:"timeout_ms"`
+	Enabled   *bool    `json:"enabled"` // defaults to true
+}
+
+// hook converts the request to a hook
+func (req *ScriptHookRequest) hook() *scripthooks.Hook {
+	hook := &scripthooks.Hook{
+		Name:      req.Name,
+		Phase:     req.Phase,
+		CITypes:   req.CITypes,
+		Script:    req.Script,
+		Priority:  req.Priority,
+		TimeoutMs: req.TimeoutMs,
+		Enabled:   true,
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	return hook
+}
+
+// TestScriptHookRequest represents a request to try a script against a sample
+// CI. Without previous, the run is treated as a create.
+type TestScriptHookRequest struct {
+	Hook     ScriptHookRequest `json:"hook"`
+	CI       models.CI         `json:"ci"`
+	Previous *models.CI        `json:"previous,omitempty"`
+}
+
+// handleListHooks handles listing script hooks
+func (h *ScriptHookHandler) handleListHooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.service.ListHooks(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list script hooks", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"hooks": hooks})
+}
+
+// handleCreateHook handles creating a script hook
+func (h *ScriptHookHandler) handleCreateHook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req ScriptHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	hook, err := h.service.CreateHook(ctx, req.hook(), userID.String())
+	if err != nil {
+		h.respondWithHookError(w, "Failed to create script hook", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, hook)
+}
+
+// handleTestHook handles running a script against a sample CI without saving anything
+func (h *ScriptHookHandler) handleTestHook(w http.ResponseWriter, r *http.Request) {
+	var req TestScriptHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.Test(req.Hook.hook(), &req.CI, req.Previous)
+	if err != nil {
+		h.respondWithHookError(w, "Failed to test script hook", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// handleGetHook handles retrieving a script hook
+func (h *ScriptHookHandler) handleGetHook(w http.ResponseWriter, r *http.Request) {
+	hookID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid hook ID", err)
+		return
+	}
+
+	hook, err := h.service.GetHook(r.Context(), hookID)
+	if err != nil {
+		h.respondWithHookError(w, "Failed to get script hook", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, hook)
+}
+
+// handleUpdateHook handles replacing a script hook
+func (h *ScriptHookHandler) handleUpdateHook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	hookID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid hook ID", err)
+		return
+	}
+
+	var req ScriptHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	hook := req.hook()
+	hook.ID = hookID
+	hook, err = h.service.UpdateHook(ctx, hook, userID.String())
+	if err != nil {
+		h.respondWithHookError(w, "Failed to update script hook", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, hook)
+}
+
+// handleDeleteHook handles deleting a script hook and its execution log
+func (h *ScriptHookHandler) handleDeleteHook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	hookID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid hook ID", err)
+		return
+	}
+
+	if err := h.service.DeleteHook(ctx, hookID, userID.String()); err != nil {
+		h.respondWithHookError(w, "Failed to delete script hook", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Script hook deleted successfully",
+	})
+}
+
+// handleListExecutions handles listing the latest runs of a script hook
+func (h *ScriptHookHandler) handleListExecutions(w http.ResponseWriter, r *http.Request) {
+	hookID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid hook ID", err)
+		return
+	}
+
+	query := r.URL.Query()
+	outcome := query.Get("outcome")
+	switch outcome {
+	case "", scripthooks.OutcomeOK, scripthooks.OutcomeRejected, scripthooks.OutcomeError, scripthooks.OutcomeTimeout:
+	default:
+		h.respondWithError(w, http.StatusBadRequest, "Invalid outcome", nil)
+		return
+	}
+
+	limit := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+	}
+
+	executions, err := h.service.ListExecutions(r.Context(), hookID, outcome, limit)
+	if err != nil {
+		h.respondWithHookError(w, "Failed to list script hook executions", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"executions": executions})
+}
+
+// respondWithHookError maps hook errors to status codes
+func (h *ScriptHookHandler) respondWithHookError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, scripthooks.ErrInvalidHook):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	case errors.Is(err, scripthooks.ErrHookNotFound):
+		h.respondWithError(w, http.StatusNotFound, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Helper methods
+
+// authMiddleware restricts script hooks to admins, since hooks run code on every save
+func (h *ScriptHookHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *ScriptHookHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *ScriptHookHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *ScriptHookHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
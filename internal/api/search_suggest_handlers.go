@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/models"
+	"connect/internal/suggest"
+	"connect/internal/visibility"
+	"github.com/gorilla/mux"
+)
+
+// SearchSuggestHandler handles search-as-you-type suggestions for the global
+// search box
+type SearchSuggestHandler struct {
+	service    *suggest.Service
+	visibility *visibility.Resolver
+}
+
+// NewSearchSuggestHandler creates a new SearchSuggestHandler
+func NewSearchSuggestHandler(service *suggest.Service) *SearchSuggestHandler {
+	return &SearchSuggestHandler{service: service}
+}
+
+// SetVisibility leaves the CIs the caller may not see out of suggestions
+func (h *SearchSuggestHandler) SetVisibility(resolver *visibility.Resolver) {
+	h.visibility = resolver
+}
+
+// RegisterRoutes registers search suggestion routes
+func (h *SearchSuggestHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/search/suggest", h.authMiddleware(h.handleSuggest)).Methods("GET")
+}
+
+// handleSuggest handles suggesting CIs by name for ?q=, optionally of one
+// ?type, at most ?limit of them
+func (h *SearchSuggestHandler) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	req := models.SuggestCIsRequest{
+		Query: params.String("q"),
+		Type:  params.String("type"),
+		Limit: params.Int("limit", models.DefaultSuggestLimit, 1, models.MaxSuggestLimit),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	scope, err := resolveVisibility(r, h.visibility)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions", err)
+		return
+	}
+	req.Scope = scope
+
+	suggestions, err := h.service.Suggest(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, suggest.ErrInvalidQuery) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid query", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to suggest CIs", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"query":       req.Query,
+		"suggestions": suggestions,
+	})
+}
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *SearchSuggestHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithError sends an error response
+func (h *SearchSuggestHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *SearchSuggestHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
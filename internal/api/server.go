@@ -6,11 +6,66 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"connect/internal/accessreview"
+	"connect/internal/apiversion"
+	"connect/internal/attrtrigger"
+	"connect/internal/auditlog"
+	"connect/internal/auth"
+	"connect/internal/autotag"
+	"connect/internal/backpressure"
+	"connect/internal/billing"
+	"connect/internal/changestream"
+	"connect/internal/cialias"
+	"connect/internal/ciarchive"
+	"connect/internal/cisummary"
 	"connect/internal/config"
+	"connect/internal/cloudimport"
+	"connect/internal/conflictescalation"
+	"connect/internal/dashboard"
+	"connect/internal/edgecleanup"
+	"connect/internal/featureflags"
+	"connect/internal/federation"
+	"connect/internal/forcesync"
+	"connect/internal/heartbeat"
+	"connect/internal/impact"
+	"connect/internal/incidentlearn"
+	"connect/internal/importjournal"
+	"connect/internal/inventorymetrics"
+	"connect/internal/jsoncompat"
+	"connect/internal/logtuning"
+	"connect/internal/maintenance"
+	"connect/internal/manifest"
+	"connect/internal/models"
+	"connect/internal/negotiation"
+	"connect/internal/netflow"
+	"connect/internal/ownership"
+	"connect/internal/pagination"
+	"connect/internal/pathpolicy"
+	"connect/internal/permcheck"
+	"connect/internal/payloadlog"
+	"connect/internal/qrcode"
+	"connect/internal/quarantine"
+	"connect/internal/quota"
 	"connect/internal/repositories"
+	"connect/internal/residency"
+	"connect/internal/resync"
+	"connect/internal/schemacheck"
+	"connect/internal/scripthooks"
+	"connect/internal/serviceaccount"
+	"connect/internal/servicetree"
+	"connect/internal/sessionlimits"
+	"connect/internal/suggest"
+	"connect/internal/suggestion"
+	"connect/internal/syncexclusion"
+	"connect/internal/syncoverview"
+	"connect/internal/typemigration"
+	"connect/internal/velocity"
+	"connect/internal/visibility"
+	"connect/internal/webhooks"
 	"github.com/gorilla/mux"
 )
 
@@ -20,7 +75,61 @@ type Server struct {
 	router      *mux.Router
 	ciRepo      *repositories.CIRepository
 	ciHandler   *CIHandler
+	ciHandlerV2 *CIHandlerV2
 	schemaHandler *SchemaHandler
+	importExportHandler *ImportExportHandler
+	reportHandler *ReportHandler
+	featureFlagHandler *FeatureFlagHandler
+	maintenanceHandler *MaintenanceHandler
+	schemaHealthHandler *SchemaHealthHandler
+	eventFilterHandler *EventFilterHandler
+	orgUnitHandler *OrgUnitHandler
+	assetLabelHandler *AssetLabelHandler
+	impactHandler *ImpactHandler
+	manifestHandler *ManifestHandler
+	apiVersions *apiversion.Registry
+	payloadLoggingHandler *PayloadLoggingHandler
+	accessReviewHandler *AccessReviewHandler
+	sessionLimitHandler *SessionLimitHandler
+	logTuningHandler *LogTuningHandler
+	ownershipTransferHandler *OwnershipTransferHandler
+	autoTagHandler *AutoTagHandler
+	typeMigrationHandler *TypeMigrationHandler
+	scriptHookHandler *ScriptHookHandler
+	backpressureHandler *BackpressureHandler
+	resyncHandler *ResyncHandler
+	syncExclusionHandler *SyncExclusionHandler
+	relationshipPathHandler *RelationshipPathHandler
+	costHandler *CostHandler
+	federationHandler *FederationHandler
+	auditHandler *AuditHandler
+	anomalyHandler *AnomalyHandler
+	summaryCacheHandler *SummaryCacheHandler
+	forceSyncHandler *ForceSyncHandler
+	syncOverviewHandler *SyncOverviewHandler
+	importRollbackHandler *ImportRollbackHandler
+	edgeCleanupHandler *EdgeCleanupHandler
+	quotaHandler *QuotaHandler
+	quarantineHandler *QuarantineHandler
+	dashboardHandler *DashboardHandler
+	flowHandler *FlowHandler
+	residencyHandler *ResidencyHandler
+	serviceAccountHandler *ServiceAccountHandler
+	permissionCheckHandler *PermissionCheckHandler
+	archiveHandler *ArchiveHandler
+	heartbeatHandler *HeartbeatHandler
+	metricsHandler *MetricsHandler
+	conflictEscalationHandler *ConflictEscalationHandler
+	cloudImportHandler *CloudImportHandler
+	serviceTreeHandler *ServiceTreeHandler
+	suggestionHandler *SuggestionHandler
+	ciAliasHandler *CIAliasHandler
+	attributeTriggerHandler *AttributeTriggerHandler
+	publicCatalogHandler *PublicCatalogHandler
+	searchSuggestHandler *SearchSuggestHandler
+	incidentHandler *IncidentHandler
+	webhookHandler *WebhookHandler
+	eventStreamHandler *EventStreamHandler
 	httpServer  *http.Server
 }
 
@@ -31,10 +140,44 @@ func NewServer(cfg *config.Config, ciRepo *repositories.CIRepository) *Server {
 	// Create handlers
 	ciHandler := NewCIHandler(ciRepo)
 	schemaHandler := NewSchemaHandler(ciRepo)
+	importExportHandler := NewImportExportHandler(ciRepo)
+	reportHandler := NewReportHandler(ciRepo, models.FreshnessPolicy{
+		DefaultMaxAge: cfg.Freshness.DefaultMaxAge,
+		TypeMaxAge:    cfg.Freshness.TypeMaxAge,
+	})
+	eventFilterHandler := NewEventFilterHandler()
+	orgUnitHandler := NewOrgUnitHandler(ciRepo)
+	// The level was validated when the configuration was loaded
+	labelLevel, _ := qrcode.ParseLevel(cfg.AssetLabels.ErrorCorrection)
+	assetLabelHandler := NewAssetLabelHandler(ciRepo, cfg.AssetLabels.BaseURL, labelLevel)
+	impactHandler := NewImpactHandler(ciRepo, impact.Weights{
+		Criticality: cfg.Impact.CriticalityWeight,
+		SLA:         cfg.Impact.SLAWeight,
+	}, cfg.Impact.DefaultDepth)
+	manifestHandler := NewManifestHandler(manifest.NewService(ciRepo))
 	
 	// Register routes
 	ciHandler.RegisterRoutes(router)
 	schemaHandler.RegisterRoutes(router)
+	importExportHandler.RegisterRoutes(router)
+	reportHandler.RegisterRoutes(router)
+	eventFilterHandler.RegisterRoutes(router)
+	orgUnitHandler.RegisterRoutes(router)
+	assetLabelHandler.RegisterRoutes(router)
+	impactHandler.RegisterRoutes(router)
+	manifestHandler.RegisterRoutes(router)
+	
+	// Versioned routes: v1 handlers register absolute paths above, later
+	// versions register relative to their prefix and are only routed when enabled
+	apiVersions := newAPIVersionRegistry(router, cfg.APIVersions)
+	ciHandlerV2 := NewCIHandlerV2(ciRepo)
+	if err := apiVersions.Register("v2", ciHandlerV2); err != nil {
+		log.Printf("Failed to register v2 routes: %v", err)
+	}
+	NewAPIVersionHandler(apiVersions).RegisterRoutes(router)
+	router.Use(apiVersions.Middleware)
+	router.Use(NewContentNegotiation(cfg).Middleware)
+	router.Use(NewPaginationPolicy(cfg.Pagination).Middleware)
 	
 	// Add CORS middleware
 	router.Use(func(next http.Handler) http.Handler {
@@ -57,7 +200,16 @@ func NewServer(cfg *config.Config, ciRepo *repositories.CIRepository) *Server {
 		router:       router,
 		ciRepo:       ciRepo,
 		ciHandler:    ciHandler,
+		ciHandlerV2:  ciHandlerV2,
 		schemaHandler: schemaHandler,
+		importExportHandler: importExportHandler,
+		reportHandler: reportHandler,
+		eventFilterHandler: eventFilterHandler,
+		orgUnitHandler: orgUnitHandler,
+		assetLabelHandler: assetLabelHandler,
+		impactHandler: impactHandler,
+		manifestHandler: manifestHandler,
+		apiVersions: apiVersions,
 		httpServer: &http.Server{
 			Addr:         ":" + cfg.Server.Port,
 			Handler:      router,
@@ -68,6 +220,599 @@ func NewServer(cfg *config.Config, ciRepo *repositories.CIRepository) *Server {
 	}
 }
 
+// newAPIVersionRegistry declares the API versions and applies the configured
+// deprecations. Keys containing a slash name an endpoint, other keys a version.
+func newAPIVersionRegistry(router *mux.Router, cfg config.APIVersionsConfig) *apiversion.Registry {
+	versions := apiversion.NewRegistry(router)
+	versions.AddVersion("v1", true)
+	versions.AddVersion("v2", cfg.V2Enabled)
+
+	for key, d := range cfg.Deprecations {
+		// Dates were validated when the configuration was loaded
+		since, _ := apiversion.ParseDate(d.Since)
+		sunset, _ := apiversion.ParseDate(d.Sunset)
+		deprecation := apiversion.Deprecation{Since: since, Sunset: sunset, Link: d.Link, Successor: d.Successor}
+
+		if strings.Contains(key, "/") {
+			versions.DeprecateEndpoint(key, deprecation)
+		} else if err := versions.Deprecate(key, deprecation); err != nil {
+			log.Printf("Ignoring deprecation of %s: %v", key, err)
+		}
+	}
+
+	return versions
+}
+
+// NewPaginationPolicy bounds list page sizes by the configured limits, applying
+// the endpoint overrides to the routes they name
+func NewPaginationPolicy(cfg config.PaginationConfig) *pagination.Policy {
+	endpoints := make(map[string]pagination.Limits, len(cfg.Endpoints))
+	for route, sizes := range cfg.Endpoints {
+		endpoints[route] = pagination.Limits{Default: sizes.DefaultPageSize, Max: sizes.MaxPageSize}
+	}
+	return pagination.NewPolicy(pagination.Config{
+		Limits:    pagination.Limits{Default: cfg.DefaultPageSize, Max: cfg.MaxPageSize},
+		Endpoints: endpoints,
+	})
+}
+
+// NewContentNegotiation validates request and response media types, JSON
+// unless a route takes uploads, YAML or forms or serves files or streams
+func NewContentNegotiation(cfg *config.Config) *negotiation.Policy {
+	exports := negotiation.Rule{Produces: []string{negotiation.JSON, negotiation.NDJSON, negotiation.CSV, negotiation.XLSX}}
+	csvReports := negotiation.Rule{Produces: []string{negotiation.JSON, negotiation.CSV}}
+	return negotiation.NewPolicy(negotiation.JSONRule, map[string]negotiation.Rule{
+		"POST /api/v1/import/xlsx":                {Consumes: []string{negotiation.Multipart}, Produces: []string{negotiation.JSON}},
+		"POST /api/v1/cis/import":                 {Consumes: []string{negotiation.Multipart}, Produces: []string{negotiation.JSON}},
+		"POST /api/v1/manifests/plan":             {Consumes: []string{negotiation.YAML, negotiation.JSON}, Produces: []string{negotiation.JSON}},
+		"POST /api/v1/manifests/apply":            {Consumes: []string{negotiation.YAML, negotiation.JSON}, Produces: []string{negotiation.JSON}},
+		"POST /api/v1/oauth/token":                {Consumes: []string{negotiation.Form}, Produces: []string{negotiation.JSON}},
+		"GET /api/v1/export/xlsx":                 {Produces: []string{negotiation.XLSX}},
+		"GET /api/v1/cis/export":                  exports,
+		"GET /api/v1/relationships/export":        exports,
+		"GET /api/v1/audit/export":                {Produces: []string{negotiation.NDJSON, negotiation.CSV}},
+		"GET /api/v1/security/access-review/{id}": csvReports,
+		"GET /api/v1/reports/chargeback":          csvReports,
+		"GET /api/v1/reports/relationship-matrix": csvReports,
+		"GET /api/v1/cis/{id}/qr":                 {Produces: []string{"image/png", "image/svg+xml"}},
+		"GET /api/v1/events/stream":               {Produces: []string{"text/event-stream"}},
+		"GET " + cfg.Metrics.Path:                 {Produces: []string{"application/openmetrics-text", "text/plain"}},
+	})
+}
+
+// EnableAuthentication authenticates requests by their token or API key
+// before they are routed, so that admin routes can check the caller's roles.
+// Call it before the hooks whose middleware reads the caller.
+func (s *Server) EnableAuthentication(middleware *auth.AuthMiddleware) {
+	s.router.Use(middleware.Middleware)
+}
+
+// EnableFeatureFlags registers the feature flag API and gates the routes
+// configured under feature_flags.routes behind their flags
+func (s *Server) EnableFeatureFlags(flags *featureflags.Service) {
+	s.featureFlagHandler = NewFeatureFlagHandler(flags)
+	s.featureFlagHandler.RegisterRoutes(s.router)
+	s.router.Use(flags.RouteMiddleware(s.cfg.FeatureFlags.Routes))
+}
+
+// EnableMaintenanceMode registers the maintenance API and rejects writes while read-only mode is on
+func (s *Server) EnableMaintenanceMode(service *maintenance.Service) {
+	s.maintenanceHandler = NewMaintenanceHandler(service)
+	s.maintenanceHandler.RegisterRoutes(s.router)
+	s.router.Use(service.Middleware)
+}
+
+// EnableSchemaHealth registers the /health/schema endpoint
+func (s *Server) EnableSchemaHealth(checker *schemacheck.Checker) {
+	s.schemaHealthHandler = NewSchemaHealthHandler(checker)
+	s.schemaHealthHandler.RegisterRoutes(s.router)
+}
+
+// EnableVisibilityFiltering hides CIs, and relationships touching them, that the
+// caller's permissions do not grant from CI, search and relationship responses
+func (s *Server) EnableVisibilityFiltering(resolver *visibility.Resolver) {
+	s.ciHandler.SetVisibility(resolver)
+	s.ciHandlerV2.SetVisibility(resolver)
+	s.assetLabelHandler.SetVisibility(resolver)
+	s.reportHandler.SetVisibility(resolver)
+	s.impactHandler.SetVisibility(resolver)
+	s.importExportHandler.SetVisibility(resolver)
+	if s.ciAliasHandler != nil {
+		s.ciAliasHandler.SetVisibility(resolver)
+	}
+	if s.searchSuggestHandler != nil {
+		s.searchSuggestHandler.SetVisibility(resolver)
+	}
+}
+
+// EnableSearchSuggest registers the search-as-you-type suggest API
+func (s *Server) EnableSearchSuggest(service *suggest.Service) {
+	s.searchSuggestHandler = NewSearchSuggestHandler(service)
+	s.searchSuggestHandler.RegisterRoutes(s.router)
+}
+
+// EnablePublicCatalog serves the curated catalog to unauthenticated callers
+// when the public catalog is enabled in the configuration
+func (s *Server) EnablePublicCatalog() {
+	if !s.cfg.PublicCatalog.Enabled {
+		return
+	}
+	s.publicCatalogHandler = NewPublicCatalogHandler(s.ciRepo, s.cfg.PublicCatalog)
+	s.publicCatalogHandler.RegisterRoutes(s.router)
+}
+
+// EnablePayloadLogging registers the payload logging admin API and logs the
+// redacted bodies of the routes it enables
+func (s *Server) EnablePayloadLogging(service *payloadlog.Service) {
+	for _, route := range s.cfg.PayloadLog.Routes {
+		service.Enable(context.Background(), route, 0)
+	}
+
+	s.payloadLoggingHandler = NewPayloadLoggingHandler(service, s.cfg.PayloadLog.DefaultDuration)
+	s.payloadLoggingHandler.RegisterRoutes(s.router)
+	s.router.Use(service.Middleware)
+}
+
+// EnableAccessReview registers the access review export API
+func (s *Server) EnableAccessReview(service *accessreview.Service) {
+	s.accessReviewHandler = NewAccessReviewHandler(service)
+	s.accessReviewHandler.RegisterRoutes(s.router)
+}
+
+// EnableSessionLimits registers the admin API for concurrent session limits
+func (s *Server) EnableSessionLimits(limits *sessionlimits.Service) {
+	s.sessionLimitHandler = NewSessionLimitHandler(limits)
+	s.sessionLimitHandler.RegisterRoutes(s.router)
+}
+
+// EnableLogTuning registers the admin API tuning slow query logging and debug
+// log sampling, and picks up changes made on other instances. The service's
+// query tracer and samplers must be given to the connection pool and loggers
+// they apply to.
+func (s *Server) EnableLogTuning(service *logtuning.Service) {
+	s.logTuningHandler = NewLogTuningHandler(service)
+	s.logTuningHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Logging.RefreshInterval)
+}
+
+// EnableOwnershipTransfers registers the CI ownership transfer API and starts
+// expiring transfers that pass their deadline unanswered
+func (s *Server) EnableOwnershipTransfers(service *ownership.Service) {
+	s.ownershipTransferHandler = NewOwnershipTransferHandler(service)
+	s.ownershipTransferHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Ownership.ExpiryInterval)
+}
+
+// EnableAutoTagging registers the auto-tagging rule API and applies the rules
+// whenever a CI is written
+func (s *Server) EnableAutoTagging(service *autotag.Service) {
+	s.autoTagHandler = NewAutoTagHandler(service)
+	s.autoTagHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetAutoTagging(service)
+}
+
+// EnableTypeMigrations registers the bulk CI type migration API
+func (s *Server) EnableTypeMigrations(service *typemigration.Service) {
+	s.typeMigrationHandler = NewTypeMigrationHandler(service)
+	s.typeMigrationHandler.RegisterRoutes(s.router)
+}
+
+// EnableScriptHooks registers the script hook API and runs the hooks around CI saves
+func (s *Server) EnableScriptHooks(service *scripthooks.Service) {
+	s.scriptHookHandler = NewScriptHookHandler(service)
+	s.scriptHookHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetScriptHooks(service)
+}
+
+// EnableAttributeTriggers registers the attribute trigger API, fires the
+// triggers on CI saves and starts the workers running their actions
+func (s *Server) EnableAttributeTriggers(service *attrtrigger.Service) {
+	s.attributeTriggerHandler = NewAttributeTriggerHandler(service)
+	s.attributeTriggerHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetAttributeTriggers(service)
+	go service.Run(context.Background(), attrtrigger.DefaultWorkers)
+}
+
+// EnableBackpressure registers the backpressure status API and throttles
+// low-priority writes while the sync backlog is over the configured thresholds
+func (s *Server) EnableBackpressure(backlog backpressure.Backlog) {
+	throttle := backpressure.NewThrottle(backlog, backpressure.Config{
+		SlowThreshold:   s.cfg.Backpressure.SlowThreshold,
+		RejectThreshold: s.cfg.Backpressure.RejectThreshold,
+		SlowDelay:       s.cfg.Backpressure.SlowDelay,
+		RetryAfter:      s.cfg.Backpressure.RetryAfter,
+		RefreshInterval: s.cfg.Backpressure.RefreshInterval,
+		Routes:          s.cfg.Backpressure.Routes,
+	})
+	s.backpressureHandler = NewBackpressureHandler(throttle)
+	s.backpressureHandler.RegisterRoutes(s.router)
+	s.router.Use(throttle.Middleware)
+}
+
+// EnableResync registers the resync orchestration API and resumes a resync
+// interrupted by a crash or restart from its checkpoint
+func (s *Server) EnableResync(service *resync.Service) {
+	s.resyncHandler = NewResyncHandler(service)
+	s.resyncHandler.RegisterRoutes(s.router)
+	if err := service.Recover(context.Background()); err != nil {
+		log.Printf("Failed to recover interrupted resync: %v", err)
+	}
+}
+
+// EnableSyncExclusions registers the sync exclusion admin API. The sync service
+// holds events according to the same service, given to it with SetExclusions.
+func (s *Server) EnableSyncExclusions(service *syncexclusion.Service) {
+	s.syncExclusionHandler = NewSyncExclusionHandler(service)
+	s.syncExclusionHandler.RegisterRoutes(s.router)
+}
+
+// EnableRelationshipPathRules registers the relationship path rule API and
+// checks relationships against the rules when they are created, confirmed or imported
+func (s *Server) EnableRelationshipPathRules(service *pathpolicy.Service) {
+	s.relationshipPathHandler = NewRelationshipPathHandler(service)
+	s.relationshipPathHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetPathRules(service)
+	s.importExportHandler.SetPathRules(service)
+}
+
+// EnableBilling registers the CI cost API and starts enriching CIs from the
+// configured billing connectors
+func (s *Server) EnableBilling(service *billing.Service) {
+	s.costHandler = NewCostHandler(service)
+	s.costHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Billing.Interval)
+}
+
+// EnableFederation registers the federation source API and merges the records
+// of the sources into the CI lists of the types they serve
+func (s *Server) EnableFederation(service *federation.Service) {
+	s.federationHandler = NewFederationHandler(service)
+	s.federationHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetFederation(service)
+}
+
+// EnableAuditLog registers the audit log export and retention API and starts
+// enforcing the retention policies
+func (s *Server) EnableAuditLog(service *auditlog.Service) {
+	s.auditHandler = NewAuditHandler(service)
+	s.auditHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Audit.EnforcementInterval)
+}
+
+// EnableAnomalyDetection registers the change anomaly API and starts running
+// the velocity detectors with the configured thresholds
+func (s *Server) EnableAnomalyDetection(store velocity.Store, notifier velocity.Notifier) {
+	thresholds := velocity.Thresholds{
+		Window:          s.cfg.Velocity.Window,
+		EntityChanges:   s.cfg.Velocity.EntityChanges,
+		Deletions:       s.cfg.Velocity.Deletions,
+		TypeChurnRatio:  s.cfg.Velocity.TypeChurnRatio,
+		TypeChurnMinCIs: s.cfg.Velocity.TypeChurnMinCIs,
+		SpikeFactor:     s.cfg.Velocity.SpikeFactor,
+		SpikeMinEvents:  s.cfg.Velocity.SpikeMinEvents,
+		Baseline:        s.cfg.Velocity.Baseline,
+		Cooldown:        s.cfg.Velocity.Cooldown,
+	}
+	if err := thresholds.Validate(); err != nil {
+		log.Printf("Anomaly detection disabled: %v", err)
+		return
+	}
+
+	service := velocity.NewService(store, notifier, thresholds)
+	s.anomalyHandler = NewAnomalyHandler(service)
+	s.anomalyHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Velocity.Interval)
+}
+
+// EnableSummaryCache caches the CI summaries shown at relationship endpoints,
+// invalidating them from the sync event stream
+func (s *Server) EnableSummaryCache(source cisummary.EventSource) {
+	cache := cisummary.NewCache(s.ciRepo, cisummary.Options{
+		TTL:        s.cfg.SummaryCache.TTL,
+		MaxEntries: s.cfg.SummaryCache.MaxEntries,
+	})
+	s.summaryCacheHandler = NewSummaryCacheHandler(cache)
+	s.summaryCacheHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetSummaryCache(cache)
+	go cisummary.NewWatcher(cache, source).Run(context.Background(), s.cfg.SummaryCache.PollInterval)
+}
+
+// EnableForceSync registers the filtered force sync API and resumes the force
+// syncs interrupted by a crash or restart from their checkpoints
+func (s *Server) EnableForceSync(service *forcesync.Service) {
+	s.forceSyncHandler = NewForceSyncHandler(service)
+	s.forceSyncHandler.RegisterRoutes(s.router)
+	if err := service.Recover(context.Background()); err != nil {
+		log.Printf("Failed to recover interrupted force syncs: %v", err)
+	}
+}
+
+// EnableSyncOverview registers the sync overview polled by the operations
+// dashboard. health is normally the sync monitor; nil reports components as unavailable.
+func (s *Server) EnableSyncOverview(store syncoverview.Store, health syncoverview.HealthChecker) {
+	service := syncoverview.NewService(store, health, s.cfg.SyncOverview.CacheTTL)
+	s.syncOverviewHandler = NewSyncOverviewHandler(service)
+	s.syncOverviewHandler.RegisterRoutes(s.router)
+}
+
+// EnableImportRollback journals the changes of every import so it can be
+// rolled back within the configured window, and registers the rollback API
+func (s *Server) EnableImportRollback(store importjournal.Store) {
+	service := importjournal.NewService(store, s.ciRepo, s.cfg.Imports.RollbackWindow)
+	s.importRollbackHandler = NewImportRollbackHandler(service)
+	s.importRollbackHandler.RegisterRoutes(s.router)
+	s.importExportHandler.SetImportJournal(service)
+	go service.Run(context.Background(), s.cfg.Imports.PurgeInterval)
+}
+
+// EnableEdgeCleanup periodically deactivates relationships whose endpoints
+// were deleted after they were created, and registers the cleanup admin API
+func (s *Server) EnableEdgeCleanup() {
+	service := edgecleanup.NewService(s.ciRepo, s.cfg.EdgeCleanup.BatchSize)
+	s.edgeCleanupHandler = NewEdgeCleanupHandler(service)
+	s.edgeCleanupHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.EdgeCleanup.Interval)
+}
+
+// EnableQuotas checks the CIs created through the API and imports against the
+// configured per-tenant and per-type quotas, and registers the quota usage API
+func (s *Server) EnableQuotas(store quota.Store, notifier quota.Notifier) {
+	limits := quota.Limits{
+		Mode:          s.cfg.Quotas.Mode,
+		WarnPercent:   s.cfg.Quotas.WarnPercent,
+		DefaultTenant: s.cfg.Quotas.DefaultTenant,
+		Tenants:       s.cfg.Quotas.Tenants,
+		Types:         s.cfg.Quotas.Types,
+	}
+	if err := limits.Validate(); err != nil {
+		log.Printf("CI quotas disabled: %v", err)
+		return
+	}
+
+	service := quota.NewService(store, notifier, limits, s.cfg.Quotas.CountTTL, s.cfg.Quotas.NotifyInterval)
+	s.ciHandler.SetQuotas(service)
+	s.importExportHandler.SetQuotas(service)
+	s.quotaHandler = NewQuotaHandler(service)
+	s.quotaHandler.RegisterRoutes(s.router)
+}
+
+// EnableQuarantine holds the CIs discovery sources report that cannot be
+// matched or validated against a schema for review, and registers the
+// quarantine review API
+func (s *Server) EnableQuarantine(store quarantine.Store) {
+	service := quarantine.NewService(store, s.ciRepo)
+	s.ciHandler.SetQuarantine(service)
+	s.quarantineHandler = NewQuarantineHandler(service)
+	s.quarantineHandler.RegisterRoutes(s.router)
+}
+
+// EnableDashboards registers the API storing each user's home dashboard server-side
+func (s *Server) EnableDashboards(store dashboard.Store) {
+	s.dashboardHandler = NewDashboardHandler(dashboard.NewService(store))
+	s.dashboardHandler.RegisterRoutes(s.router)
+}
+
+// EnableFlowIngestion registers the endpoint turning aggregated network flow
+// records into communicates_with relationships between the CIs they connect
+func (s *Server) EnableFlowIngestion(store netflow.Store) {
+	s.flowHandler = NewFlowHandler(netflow.NewService(store))
+	s.flowHandler.RegisterRoutes(s.router)
+}
+
+// EnableResidency routes the CI data of each tenant to the database of its
+// shard, registers the shard health and assignment endpoints, and reloads the
+// tenant_shards control table periodically
+func (s *Server) EnableResidency(router *residency.Router) {
+	s.ciRepo.SetRouter(router)
+	s.residencyHandler = NewResidencyHandler(router)
+	s.residencyHandler.RegisterRoutes(s.router)
+	go router.Run(context.Background(), s.cfg.Residency.RefreshInterval)
+}
+
+// EnableServiceAccounts registers the service account administration API and
+// the client credentials token endpoint
+func (s *Server) EnableServiceAccounts(service *serviceaccount.Service) {
+	s.serviceAccountHandler = NewServiceAccountHandler(service)
+	s.serviceAccountHandler.RegisterRoutes(s.router)
+}
+
+// EnablePermissionChecks registers the endpoint answering many permission
+// checks in one call, resolving the caller's roles through the resolver
+func (s *Server) EnablePermissionChecks(resolver *visibility.Resolver) {
+	s.permissionCheckHandler = NewPermissionCheckHandler(permcheck.NewChecker(resolver, s.ciRepo))
+	s.permissionCheckHandler.RegisterRoutes(s.router)
+}
+
+// EnableArchive periodically moves CIs inactive for the configured period to
+// the archive tier, and registers the archive listing, restore and admin API
+func (s *Server) EnableArchive() {
+	service := ciarchive.NewService(s.ciRepo, s.cfg.Archive.InactiveFor, s.cfg.Archive.BatchSize)
+	s.archiveHandler = NewArchiveHandler(service)
+	s.archiveHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Archive.Interval)
+}
+
+// EnableHeartbeats registers the heartbeat ingestion API and periodically
+// moves the CIs that missed their heartbeats to the unreachable status
+func (s *Server) EnableHeartbeats(notifier heartbeat.Notifier) {
+	policy := heartbeat.Policy{
+		DefaultThreshold: s.cfg.Heartbeats.DefaultThreshold,
+		TypeThreshold:    s.cfg.Heartbeats.TypeThreshold,
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Heartbeat monitoring disabled: %v", err)
+		return
+	}
+
+	service := heartbeat.NewService(s.ciRepo, notifier, policy)
+	s.heartbeatHandler = NewHeartbeatHandler(service)
+	s.heartbeatHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Heartbeats.CheckInterval)
+}
+
+// EnableMetrics exposes the inventory counts on the configured metrics path
+// for capacity dashboards, unless metrics are disabled
+func (s *Server) EnableMetrics() {
+	if !s.cfg.Metrics.Enabled {
+		return
+	}
+	service := inventorymetrics.NewService(s.ciRepo, s.cfg.Metrics.CacheTTL)
+	s.metricsHandler = NewMetricsHandler(service, s.cfg.Metrics.Path)
+	s.metricsHandler.RegisterRoutes(s.router)
+}
+
+// EnableConflictEscalation registers the conflict escalation API and
+// periodically escalates sync conflicts left unresolved, auto-resolving them
+// with resolve once they reach the configured age. resolve may be nil, leaving
+// all conflicts to be resolved by hand.
+func (s *Server) EnableConflictEscalation(store conflictescalation.Store, resolve conflictescalation.Resolver, notifier conflictescalation.Notifier) {
+	policy := conflictescalation.Policy{
+		EscalateAfter:    s.cfg.Escalation.EscalateAfter,
+		NotifyRole:       s.cfg.Escalation.NotifyRole,
+		AutoResolveAfter: s.cfg.Escalation.AutoResolveAfter,
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Conflict escalation disabled: %v", err)
+		return
+	}
+
+	service := conflictescalation.NewService(store, resolve, notifier, policy, s.cfg.Escalation.BatchSize)
+	s.conflictEscalationHandler = NewConflictEscalationHandler(service)
+	s.conflictEscalationHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.Escalation.Interval)
+}
+
+// EnableCloudImport registers the CI external ID API and starts importing the
+// dependencies of the resources read by the configured cloud connectors
+func (s *Server) EnableCloudImport(store cloudimport.Store) {
+	connectors := make([]cloudimport.Connector, 0, len(s.cfg.CloudImport.Connectors))
+	for _, cfg := range s.cfg.CloudImport.Connectors {
+		connector := cloudimport.Connector{
+			Name:            cfg.Name,
+			Provider:        cfg.Provider,
+			Regions:         cfg.Regions,
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			SessionToken:    cfg.SessionToken,
+			Subscriptions:   cfg.Subscriptions,
+			TenantID:        cfg.TenantID,
+			ClientID:        cfg.ClientID,
+			ClientSecret:    cfg.ClientSecret,
+			Endpoint:        cfg.Endpoint,
+			Timeout:         cfg.Timeout,
+		}
+		if err := connector.Validate(); err != nil {
+			log.Printf("Cloud import connector skipped: %v", err)
+			continue
+		}
+		connectors = append(connectors, connector)
+	}
+
+	service := cloudimport.NewService(store, s.cfg.CloudImport.ResourceAttribute, connectors)
+	s.cloudImportHandler = NewCloudImportHandler(service)
+	s.cloudImportHandler.RegisterRoutes(s.router)
+	go service.Run(context.Background(), s.cfg.CloudImport.Interval)
+}
+
+// EnableServiceTree registers the business service membership and health API
+// and rebuilds every service tree to fill the projection. The sync service
+// keeps the trees current by applying events to the same service, given to it
+// with SetProjections.
+func (s *Server) EnableServiceTree(service *servicetree.Service) {
+	s.serviceTreeHandler = NewServiceTreeHandler(service)
+	s.serviceTreeHandler.RegisterRoutes(s.router)
+	service.StartRebuild()
+}
+
+// EnableCorrectionSuggestions registers the CI correction suggestion API and
+// its approval queue
+func (s *Server) EnableCorrectionSuggestions(service *suggestion.Service) {
+	s.suggestionHandler = NewSuggestionHandler(service)
+	s.suggestionHandler.RegisterRoutes(s.router)
+}
+
+// EnableCIAliases registers the CI alias and merge API. Former names and IDs of
+// renamed and merged CIs resolve to the current CI, and getting a merged CI
+// redirects to the CI it was merged into.
+func (s *Server) EnableCIAliases(store cialias.Store) {
+	service := cialias.NewService(store, s.ciHandler.reparenting)
+	s.ciAliasHandler = NewCIAliasHandler(service)
+	s.ciAliasHandler.SetVisibility(s.ciHandler.visibility)
+	s.ciAliasHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetAliases(service)
+}
+
+// EnableIncidentLearning registers the incident ingestion API, relearns the
+// relationship scores periodically and has impact analysis follow the
+// empirically critical relationships
+func (s *Server) EnableIncidentLearning(store incidentlearn.Store) {
+	service := incidentlearn.NewService(store, incidentlearn.Settings{
+		Window:             s.cfg.IncidentLearning.Window,
+		MinIncidents:       s.cfg.IncidentLearning.MinIncidents,
+		CriticalConfidence: s.cfg.IncidentLearning.CriticalConfidence,
+	})
+	s.incidentHandler = NewIncidentHandler(service)
+	s.incidentHandler.RegisterRoutes(s.router)
+	s.impactHandler.SetIncidentLearning(service)
+	go service.Run(context.Background(), s.cfg.IncidentLearning.Interval)
+}
+
+// EnableWebhooks registers the webhook management API, publishes CI,
+// relationship and schema lifecycle events to the registered endpoints and
+// dispatches their deliveries in the background
+func (s *Server) EnableWebhooks(store webhooks.Store) {
+	service := webhooks.NewService(store, webhooks.Options{
+		Timeout:     s.cfg.Webhooks.Timeout,
+		MaxAttempts: s.cfg.Webhooks.MaxAttempts,
+	})
+	s.webhookHandler = NewWebhookHandler(service)
+	s.webhookHandler.RegisterRoutes(s.router)
+	s.ciHandler.SetWebhooks(service)
+	s.schemaHandler.SetWebhooks(service)
+	go service.Run(context.Background(), s.cfg.Webhooks.DispatchInterval)
+}
+
+// EnableEventStream registers the live change stream. The sync service feeds
+// it by applying the events it processes to the same hub, given to it with
+// SetProjections.
+func (s *Server) EnableEventStream(hub *changestream.Hub) {
+	s.eventStreamHandler = NewEventStreamHandler(hub, s.cfg.EventStream.Heartbeat)
+	s.eventStreamHandler.RegisterRoutes(s.router)
+}
+
+// EnableResponseFormats serializes JSON responses in the naming and envelope
+// configured per API version or asked for by the client. It wraps the whole
+// server, so every response, including those of middleware, is formatted.
+func (s *Server) EnableResponseFormats() {
+	// Namings were validated when the configuration was loaded
+	naming, _ := jsoncompat.ParseNaming(s.cfg.Responses.Naming)
+	versions := make(map[string]jsoncompat.Format, len(s.cfg.Responses.Versions))
+	for name, format := range s.cfg.Responses.Versions {
+		versionNaming, _ := jsoncompat.ParseNaming(format.Naming)
+		versions[name] = jsoncompat.Format{Naming: versionNaming, Envelope: format.Envelope}
+	}
+
+	formatter := jsoncompat.NewFormatter(jsoncompat.Config{
+		Default:      jsoncompat.Format{Naming: naming, Envelope: s.cfg.Responses.Envelope},
+		Versions:     versions,
+		Header:       s.cfg.Responses.Header,
+		PreserveKeys: s.cfg.Responses.PreserveKeys,
+	})
+	s.httpServer.Handler = formatter.Middleware(s.httpServer.Handler)
+}
+
+// SetFallback serves the requests no route of the server matches with handler,
+// e.g. the router of the routes served outside it
+func (s *Server) SetFallback(handler http.Handler) {
+	s.router.NotFoundHandler = handler
+}
+
+// Handler returns the handler serving the server's routes, with the response
+// formats applied, for callers running their own HTTP server
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	log.Printf("Starting server on port %s", s.cfg.Server.Port)
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/sessionlimits"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SessionLimitHandler handles the concurrent session limit admin endpoints
+type SessionLimitHandler struct {
+	limits *sessionlimits.Service
+}
+
+// NewSessionLimitHandler creates a new SessionLimitHandler
+func NewSessionLimitHandler(limits *sessionlimits.Service) *SessionLimitHandler {
+	return &SessionLimitHandler{limits: limits}
+}
+
+// RegisterRoutes registers session limit routes
+func (h *SessionLimitHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/admin/session-limits", h.authMiddleware(h.handleGetLimits)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/session-limits", h.authMiddleware(h.handleSetLimit)).Methods("PUT")
+	router.HandleFunc("/api/v1/admin/session-limits/{scope}", h.authMiddleware(h.handleResetLimit)).Methods("DELETE")
+	router.HandleFunc("/api/v1/admin/session-limits/{scope}/{name}", h.authMiddleware(h.handleResetLimit)).Methods("DELETE")
+}
+
+// SetSessionLimitRequest represents a request to adjust a concurrent session limit
+type SetSessionLimitRequest struct {
+	Scope       string `json:"scope"` // default, role or tenant
+	Name        string `json:"name,omitempty"`
+	MaxSessions int    `json:"max_sessions"` // 0 means unlimited
+}
+
+// handleGetLimits handles retrieving the effective limits and the runtime overrides
+func (h *SessionLimitHandler) handleGetLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"effective": h.limits.Policy(ctx),
+		"overrides": h.limits.Overrides(ctx),
+	})
+}
+
+// handleSetLimit handles adjusting a limit at runtime
+func (h *SessionLimitHandler) handleSetLimit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var req SetSessionLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	limit := sessionlimits.Limit{
+		Scope:       req.Scope,
+		Name:        req.Name,
+		MaxSessions: req.MaxSessions,
+		UpdatedBy:   userID.String(),
+	}
+	if err := limit.Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid session limit", err)
+		return
+	}
+
+	limit, err := h.limits.Set(ctx, limit)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to set session limit", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, limit)
+}
+
+// handleResetLimit handles removing a runtime override so the configured limit applies again
+func (h *SessionLimitHandler) handleResetLimit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	scope, name := vars["scope"], vars["name"]
+
+	if err := (sessionlimits.Limit{Scope: scope, Name: name}).Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid session limit", err)
+		return
+	}
+
+	if err := h.limits.Reset(r.Context(), scope, name); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to reset session limit", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Session limit reset to configured value",
+	})
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role to read or change session limits
+func (h *SessionLimitHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *SessionLimitHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *SessionLimitHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *SessionLimitHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
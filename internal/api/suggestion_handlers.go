@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/auth"
+	"connect/internal/suggestion"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SuggestionHandler handles CI correction suggestions and their approval queue
+type SuggestionHandler struct {
+	service *suggestion.Service
+}
+
+// NewSuggestionHandler creates a new SuggestionHandler
+func NewSuggestionHandler(service *suggestion.Service) *SuggestionHandler {
+	return &SuggestionHandler{service: service}
+}
+
+// RegisterRoutes registers correction suggestion routes
+func (h *SuggestionHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/cis/{id}/suggestions", h.authMiddleware(h.handleSubmitSuggestion)).Methods("POST")
+	router.HandleFunc("/api/v1/cis/{id}/suggestions", h.authMiddleware(h.handleListCISuggestions)).Methods("GET")
+	router.HandleFunc("/api/v1/suggestions", h.authMiddleware(h.handleListSuggestions)).Methods("GET")
+	router.HandleFunc("/api/v1/suggestions/{id}", h.authMiddleware(h.handleGetSuggestion)).Methods("GET")
+	router.HandleFunc("/api/v1/suggestions/{id}/accept", h.authMiddleware(h.handleAcceptSuggestion)).Methods("POST")
+	router.HandleFunc("/api/v1/suggestions/{id}/reject", h.authMiddleware(h.handleRejectSuggestion)).Methods("POST")
+	router.HandleFunc("/api/v1/suggestions/{id}/withdraw", h.authMiddleware(h.handleWithdrawSuggestion)).Methods("POST")
+}
+
+// ReviewSuggestionRequest represents the optional body of accept and reject requests
+type ReviewSuggestionRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+var suggestionStatuses = []string{
+	suggestion.StatusPending,
+	suggestion.StatusAccepted,
+	suggestion.StatusRejected,
+	suggestion.StatusWithdrawn,
+}
+
+// handleSubmitSuggestion handles suggesting a correction of a CI
+func (h *SuggestionHandler) handleSubmitSuggestion(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	var req suggestion.SubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	submitted, err := h.service.Submit(r.Context(), h.actorFromRequest(r), ciID, req)
+	if err != nil {
+		h.respondWithSuggestionError(w, "Failed to submit suggestion", err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/suggestions/"+submitted.ID.String())
+	h.respondWithJSON(w, http.StatusCreated, submitted)
+}
+
+// handleListCISuggestions handles listing the suggestions for a CI
+func (h *SuggestionHandler) handleListCISuggestions(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	ciID := params.PathUUID("id")
+	filter := suggestion.Filter{
+		CIID:   &ciID,
+		Status: params.Enum("status", "", suggestionStatuses),
+		Limit:  params.Int("limit", suggestion.DefaultListLimit, 1, 1000),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	h.list(w, r, filter)
+}
+
+// handleListSuggestions handles listing suggestions. Editors get the queue of
+// suggestions for the CIs they can see, pending ones with status=pending;
+// other users get their own suggestions.
+func (h *SuggestionHandler) handleListSuggestions(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	filter := suggestion.Filter{
+		CIID:        params.UUID("ci_id"),
+		Status:      params.Enum("status", "", suggestionStatuses),
+		SuggestedBy: params.String("suggested_by"),
+		Limit:       params.Int("limit", suggestion.DefaultListLimit, 1, 1000),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	h.list(w, r, filter)
+}
+
+// list responds with the suggestions matching the filter that the caller may see
+func (h *SuggestionHandler) list(w http.ResponseWriter, r *http.Request, filter suggestion.Filter) {
+	suggestions, err := h.service.List(r.Context(), h.actorFromRequest(r), filter)
+	if err != nil {
+		h.respondWithSuggestionError(w, "Failed to list suggestions", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"suggestions": suggestions,
+		"count":       len(suggestions),
+	})
+}
+
+// handleGetSuggestion handles retrieving a suggestion with its diff
+func (h *SuggestionHandler) handleGetSuggestion(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	found, err := h.service.Get(r.Context(), h.actorFromRequest(r), id)
+	if err != nil {
+		h.respondWithSuggestionError(w, "Failed to get suggestion", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, found)
+}
+
+// handleAcceptSuggestion handles an editor applying a suggestion
+func (h *SuggestionHandler) handleAcceptSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "Failed to accept suggestion", h.service.Accept)
+}
+
+// handleRejectSuggestion handles an editor rejecting a suggestion
+func (h *SuggestionHandler) handleRejectSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "Failed to reject suggestion", h.service.Reject)
+}
+
+// handleWithdrawSuggestion handles the suggester withdrawing a suggestion
+func (h *SuggestionHandler) handleWithdrawSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "Failed to withdraw suggestion",
+		func(ctx context.Context, actor suggestion.Actor, id uuid.UUID, _ string) (*suggestion.Suggestion, error) {
+			return h.service.Withdraw(ctx, actor, id)
+		})
+}
+
+// review runs a workflow step on the suggestion named in the path
+func (h *SuggestionHandler) review(w http.ResponseWriter, r *http.Request, message string,
+	step func(ctx context.Context, actor suggestion.Actor, id uuid.UUID, note string) (*suggestion.Suggestion, error)) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	// The body is optional
+	var req ReviewSuggestionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+
+	reviewed, err := step(r.Context(), h.actorFromRequest(r), id, req.Note)
+	if err != nil {
+		h.respondWithSuggestionError(w, message, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, reviewed)
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *SuggestionHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// actorFromRequest builds the workflow actor from the authenticated user
+func (h *SuggestionHandler) actorFromRequest(r *http.Request) suggestion.Actor {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	roles, _ := auth.GetUserRolesFromContext(r.Context())
+	return suggestion.Actor{ID: userID, Roles: roles}
+}
+
+// respondWithSuggestionError maps workflow errors to status codes
+func (h *SuggestionHandler) respondWithSuggestionError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, suggestion.ErrSuggestionNotFound), errors.Is(err, suggestion.ErrCINotFound):
+		h.respondWithError(w, http.StatusNotFound, message, err)
+	case errors.Is(err, suggestion.ErrNotAuthorized):
+		h.respondWithError(w, http.StatusForbidden, message, err)
+	case errors.Is(err, suggestion.ErrNotPending), errors.Is(err, suggestion.ErrStale):
+		h.respondWithError(w, http.StatusConflict, message, err)
+	case errors.Is(err, suggestion.ErrInvalidSuggestion), errors.Is(err, suggestion.ErrNoChange):
+		h.respondWithError(w, http.StatusBadRequest, message, err)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// respondWithError sends an error response
+func (h *SuggestionHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *SuggestionHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
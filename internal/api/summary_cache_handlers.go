@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"connect/internal/cisummary"
+	"github.com/gorilla/mux"
+)
+
+// SummaryCacheHandler handles the CI summary cache admin endpoints
+type SummaryCacheHandler struct {
+	cache *cisummary.Cache
+}
+
+// NewSummaryCacheHandler creates a new SummaryCacheHandler
+func NewSummaryCacheHandler(cache *cisummary.Cache) *SummaryCacheHandler {
+	return &SummaryCacheHandler{cache: cache}
+}
+
+// RegisterRoutes registers summary cache routes
+func (h *SummaryCacheHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/admin/cache/ci-summaries", h.authMiddleware(h.handleGetStats)).Methods("GET")
+	router.HandleFunc("/api/v1/admin/cache/ci-summaries", h.authMiddleware(h.handlePurge)).Methods("DELETE")
+}
+
+// handleGetStats handles returning the cache size and hit rate counters
+func (h *SummaryCacheHandler) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, h.cache.Stats())
+}
+
+// handlePurge handles emptying the cache, e.g. after CIs were changed with the sync triggers disabled
+func (h *SummaryCacheHandler) handlePurge(w http.ResponseWriter, r *http.Request) {
+	h.cache.Purge()
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "CI summary cache purged",
+	})
+}
+
+// Helper methods
+
+// authMiddleware is a placeholder for authentication middleware
+func (h *SummaryCacheHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// In a real implementation, this would validate JWT tokens and require the admin role
+		// For now, we'll just pass through
+		next(w, r)
+	}
+}
+
+// respondWithJSON sends a JSON response
+func (h *SummaryCacheHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"connect/internal/typemigration"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// TypeMigrationHandler handles the bulk CI type migration endpoints
+type TypeMigrationHandler struct {
+	service *typemigration.Service
+}
+
+// NewTypeMigrationHandler creates a new TypeMigrationHandler
+func NewTypeMigrationHandler(service *typemigration.Service) *TypeMigrationHandler {
+	return &TypeMigrationHandler{service: service}
+}
+
+// RegisterRoutes registers CI type migration routes
+func (h *TypeMigrationHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/ci-type-migrations", h.authMiddleware(h.handleStartMigration)).Methods("POST")
+	router.HandleFunc("/api/v1/ci-type-migrations", h.authMiddleware(h.handleListMigrations)).Methods("GET")
+	router.HandleFunc("/api/v1/ci-type-migrations/preview", h.authMiddleware(h.handlePreviewMigration)).Methods("POST")
+	router.HandleFunc("/api/v1/ci-type-migrations/{id}", h.authMiddleware(h.handleGetMigration)).Methods("GET")
+}
+
+// handleStartMigration handles starting a CI type migration job
+func (h *TypeMigrationHandler) handleStartMigration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := h.getUserIDFromContext(ctx)
+
+	var plan typemigration.Plan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	job, err := h.service.Start(ctx, plan, userID.String())
+	if err != nil {
+		switch {
+		case errors.Is(err, typemigration.ErrInvalidPlan):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid CI type migration plan", err)
+		case errors.Is(err, typemigration.ErrJobRunning):
+			h.respondWithError(w, http.StatusConflict, "A CI type migration is already running", err)
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to start CI type migration", err)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/ci-type-migrations/"+job.ID.String())
+	h.respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// handlePreviewMigration handles reporting what a plan would change without running it
+func (h *TypeMigrationHandler) handlePreviewMigration(w http.ResponseWriter, r *http.Request) {
+	var plan typemigration.Plan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	preview, err := h.service.Preview(r.Context(), plan)
+	if err != nil {
+		if errors.Is(err, typemigration.ErrInvalidPlan) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid CI type migration plan", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to preview CI type migration", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, preview)
+}
+
+// handleListMigrations handles listing the latest CI type migration jobs
+func (h *TypeMigrationHandler) handleListMigrations(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+	}
+
+	jobs, err := h.service.ListJobs(r.Context(), limit)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list CI type migrations", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{"migrations": jobs})
+}
+
+// handleGetMigration handles retrieving a CI type migration job and its progress
+func (h *TypeMigrationHandler) handleGetMigration(w http.ResponseWriter, r *http.Request) {
+	jobID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid migration ID", err)
+		return
+	}
+
+	job, err := h.service.GetJob(r.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, typemigration.ErrJobNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "CI type migration not found", err)
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get CI type migration", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, job)
+}
+
+// Helper methods
+
+// authMiddleware requires the admin role, as migrations rewrite CIs in bulk
+func (h *TypeMigrationHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// getUserIDFromContext extracts user ID from context
+func (h *TypeMigrationHandler) getUserIDFromContext(ctx context.Context) uuid.UUID {
+	// In a real implementation, this would extract user ID from JWT token
+	// For now, we'll return a placeholder
+	return uuid.New()
+}
+
+// respondWithError sends an error response
+func (h *TypeMigrationHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *TypeMigrationHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
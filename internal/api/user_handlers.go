@@ -0,0 +1,400 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/auth"
+	"connect/internal/config"
+	"connect/internal/logger"
+	"connect/internal/models"
+	"connect/internal/repositories"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// userSortFields are the fields the user list can be sorted by
+var userSortFields = []string{"username", "email", "first_name", "last_name", "created_at", "updated_at", "last_login_at"}
+
+// UserHandler handles user management and role assignment, admin only
+type UserHandler struct {
+	config         *config.Config
+	logger         *logger.Logger
+	userRepository *repositories.UserRepository
+	roleRepository *repositories.RoleRepository
+}
+
+func NewUserHandler(
+	config *config.Config,
+	appLogger *logger.Logger,
+	userRepository *repositories.UserRepository,
+	roleRepository *repositories.RoleRepository,
+) *UserHandler {
+	return &UserHandler{
+		config:         config,
+		logger:         appLogger,
+		userRepository: userRepository,
+		roleRepository: roleRepository,
+	}
+}
+
+// AssignUserRoleRequest represents a request to assign a role to a user
+type AssignUserRoleRequest struct {
+	RoleID uuid.UUID `json:"role_id"`
+}
+
+// ListUsers handles listing users a page at a time, filtered by the search,
+// status, roles, created_from and created_to query parameters
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	page := params.Int("page", 1, 1, 0)
+	size := params.PageSize("size")
+	filter := &models.UserFilterOptions{
+		Search:      params.String("search"),
+		Status:      params.Enum("status", "", []string{"active", "inactive"}),
+		Roles:       params.Strings("roles"),
+		SortBy:      params.Enum("sort_by", "", userSortFields),
+		SortOrder:   params.Enum("sort_order", "desc", []string{"asc", "desc"}),
+		CreatedFrom: params.Time("created_from"),
+		CreatedTo:   params.Time("created_to"),
+	}
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	users, err := h.userRepository.List(r.Context(), filter, page, size)
+	if err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to list users")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to list users"})
+		return
+	}
+
+	for i := range users.Users {
+		roles, err := h.roleRepository.GetUserRoleNames(r.Context(), users.Users[i].ID)
+		if err != nil {
+			h.logger.ErrorRequest(r, err, "Failed to get user roles")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]string{"error": "Failed to list users"})
+			return
+		}
+		users.Users[i].Roles = roleNames(roles)
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, users)
+}
+
+// CreateUser handles creating a user on behalf of an admin
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode create user request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		h.logger.ErrorRequest(r, err, "Invalid create user request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	user, err := h.userRepository.Create(r.Context(), &req, actorID(r))
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserAlreadyExists) {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, map[string]string{"error": "User already exists"})
+			return
+		}
+		h.logger.ErrorRequest(r, err, "Failed to create user")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to create user"})
+		return
+	}
+
+	h.logger.InfoRequest(r, "User created successfully", map[string]interface{}{"user_id": user.ID})
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, user.ToResponse([]string{}))
+}
+
+// GetUser handles getting a user with their roles
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uuidParam(w, r, "id", "user")
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepository.GetByID(r.Context(), userID)
+	if err != nil {
+		h.respondWithUserError(w, r, "Failed to get user", err)
+		return
+	}
+
+	roles, err := h.roleRepository.GetUserRoleNames(r.Context(), userID)
+	if err != nil {
+		h.respondWithUserError(w, r, "Failed to get user roles", err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, user.ToResponse(roleNames(roles)))
+}
+
+// UpdateUser handles updating a user's profile or active status
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uuidParam(w, r, "id", "user")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.ErrorRequest(r, err, "Failed to decode update user request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		h.logger.ErrorRequest(r, err, "Invalid update user request")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	// An admin deactivating their own account would lock themselves out
+	if req.IsActive != nil && !*req.IsActive && userID == actorID(r) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Cannot deactivate your own account"})
+		return
+	}
+
+	user, err := h.userRepository.Update(r.Context(), userID, &req, actorID(r))
+	if err != nil {
+		h.respondWithUserError(w, r, "Failed to update user", err)
+		return
+	}
+
+	roles, err := h.roleRepository.GetUserRoleNames(r.Context(), userID)
+	if err != nil {
+		h.respondWithUserError(w, r, "Failed to get user roles", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "User updated successfully", map[string]interface{}{"user_id": userID})
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, user.ToResponse(roleNames(roles)))
+}
+
+// DeleteUser handles deleting a user
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uuidParam(w, r, "id", "user")
+	if !ok {
+		return
+	}
+
+	if userID == actorID(r) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Cannot delete your own account"})
+		return
+	}
+
+	if err := h.userRepository.Delete(r.Context(), userID); err != nil {
+		h.respondWithUserError(w, r, "Failed to delete user", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "User deleted successfully", map[string]interface{}{"user_id": userID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserRoles handles listing the roles assigned to a user
+func (h *UserHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uuidParam(w, r, "id", "user")
+	if !ok {
+		return
+	}
+
+	if _, err := h.userRepository.GetByID(r.Context(), userID); err != nil {
+		h.respondWithUserError(w, r, "Failed to get user", err)
+		return
+	}
+
+	roles, err := h.roleRepository.GetUserRoles(r.Context(), userID)
+	if err != nil {
+		h.respondWithUserError(w, r, "Failed to get user roles", err)
+		return
+	}
+	if roles == nil {
+		roles = []models.Role{}
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]interface{}{"user_id": userID, "roles": roles})
+}
+
+// AssignRole handles assigning a role to a user
+func (h *UserHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uuidParam(w, r, "id", "user")
+	if !ok {
+		return
+	}
+
+	var req AssignUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoleID == uuid.Nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body, role_id is required"})
+		return
+	}
+
+	if err := h.roleRepository.AssignRoleToUser(r.Context(), userID, req.RoleID); err != nil {
+		h.respondWithUserError(w, r, "Failed to assign role", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Role assigned to user", map[string]interface{}{"user_id": userID, "role_id": req.RoleID})
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{"user_id": userID, "role_id": req.RoleID})
+}
+
+// RevokeRole handles revoking a role from a user
+func (h *UserHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uuidParam(w, r, "id", "user")
+	if !ok {
+		return
+	}
+
+	roleID, ok := uuidParam(w, r, "roleId", "role")
+	if !ok {
+		return
+	}
+
+	// Revoking their own admin role would lock the admin out
+	if userID == actorID(r) {
+		role, err := h.roleRepository.GetRoleByID(r.Context(), roleID)
+		if err != nil {
+			h.respondWithUserError(w, r, "Failed to revoke role", err)
+			return
+		}
+		if role.Name == "admin" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Cannot revoke your own admin role"})
+			return
+		}
+	}
+
+	if err := h.roleRepository.RevokeRoleFromUser(r.Context(), userID, roleID); err != nil {
+		h.respondWithUserError(w, r, "Failed to revoke role", err)
+		return
+	}
+
+	h.logger.InfoRequest(r, "Role revoked from user", map[string]interface{}{"user_id": userID, "role_id": roleID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Routes returns the user management routes, all of which require the admin role
+func (h *UserHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.Get("/", h.ListUsers)
+	r.Post("/", h.CreateUser)
+	r.Get("/{id}", h.GetUser)
+	r.Put("/{id}", h.UpdateUser)
+	r.Delete("/{id}", h.DeleteUser)
+	r.Get("/{id}/roles", h.GetUserRoles)
+	r.Post("/{id}/roles", h.AssignRole)
+	r.Delete("/{id}/roles/{roleId}", h.RevokeRole)
+
+	return r
+}
+
+// requireAdmin rejects requests whose authenticated user lacks the admin role.
+// It runs behind the authentication middleware, which puts the roles in the context.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roles, ok := auth.GetUserRolesFromContext(r.Context())
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, map[string]string{"error": "Unauthorized"})
+			return
+		}
+		for _, role := range roles {
+			if role == "admin" {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]string{"error": "Admin role required"})
+	})
+}
+
+// uuidParam parses a UUID path parameter, responding with 400 when it is not one
+func uuidParam(w http.ResponseWriter, r *http.Request, name, label string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, name))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid " + label + " ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// actorID returns the ID of the authenticated user, uuid.Nil when the request
+// was not made by a user
+func actorID(r *http.Request) uuid.UUID {
+	if actorType, ok := auth.GetActorTypeFromContext(r.Context()); ok && actorType != auth.ActorTypeUser {
+		return uuid.Nil
+	}
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+// respondWithUserError maps user and role repository errors to status codes
+func (h *UserHandler) respondWithUserError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "User not found"})
+	case errors.Is(err, repositories.ErrRoleNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "Role not found"})
+	case errors.Is(err, repositories.ErrUserRoleNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "User does not have the role"})
+	case errors.Is(err, repositories.ErrUserRoleAlreadyExists):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": "User already has the role"})
+	case errors.Is(err, repositories.ErrUserAlreadyExists):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": "Username or email already in use"})
+	default:
+		h.logger.ErrorRequest(r, err, message)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": message})
+	}
+}
+
+// roleNames returns the role names, never nil so they encode as an empty list
+func roleNames(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connect/internal/auth"
+	"connect/internal/logger"
+	"connect/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asActor returns the request as made by the user with the given roles
+func asActor(r *http.Request, userID uuid.UUID, actorType string, roles ...string) *http.Request {
+	ctx := context.WithValue(r.Context(), auth.UserContextKey, userID.String())
+	ctx = context.WithValue(ctx, auth.RolesContextKey, roles)
+	ctx = context.WithValue(ctx, auth.ActorTypeContextKey, actorType)
+	return r.WithContext(ctx)
+}
+
+// errorMessage decodes the error message of a JSON error response
+func errorMessage(t *testing.T, w *httptest.ResponseRecorder) string {
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body["error"]
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, asActor(httptest.NewRequest("GET", "/", nil), uuid.New(), auth.ActorTypeUser, "viewer"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "Admin role required", errorMessage(t, w))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, asActor(httptest.NewRequest("GET", "/", nil), uuid.New(), auth.ActorTypeUser, "viewer", "admin"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestActorID(t *testing.T) {
+	userID := uuid.New()
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Equal(t, uuid.Nil, actorID(r))
+	assert.Equal(t, userID, actorID(asActor(r, userID, auth.ActorTypeUser)))
+	assert.Equal(t, uuid.Nil, actorID(asActor(r, userID, auth.ActorTypeServiceAccount)), "service accounts are no users")
+
+	invalid := r.WithContext(context.WithValue(r.Context(), auth.UserContextKey, "admin"))
+	assert.Equal(t, uuid.Nil, actorID(invalid))
+}
+
+func TestUserHandler_RejectsBeforeRepository(t *testing.T) {
+	// The handler has no repositories, so these requests must be answered
+	// without reaching them
+	h := NewUserHandler(nil, logger.NewLogger("test"), nil, nil)
+	routes := h.Routes()
+	adminID, otherID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{"invalid user ID", "GET", "/not-a-uuid", "", http.StatusBadRequest, "Invalid user ID"},
+		{"invalid role ID", "DELETE", "/" + otherID.String() + "/roles/admin", "", http.StatusBadRequest, "Invalid role ID"},
+		{"malformed user", "POST", "/", "{", http.StatusBadRequest, "Invalid request body"},
+		{"malformed update", "PUT", "/" + otherID.String(), "[]", http.StatusBadRequest, "Invalid request body"},
+		{"deactivating oneself", "PUT", "/" + adminID.String(), `{"is_active": false}`, http.StatusBadRequest, "Cannot deactivate your own account"},
+		{"deleting oneself", "DELETE", "/" + adminID.String(), "", http.StatusBadRequest, "Cannot delete your own account"},
+		{"missing role", "POST", "/" + otherID.String() + "/roles", `{}`, http.StatusBadRequest, "Invalid request body, role_id is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := asActor(httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)), adminID, auth.ActorTypeUser, "admin")
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.message, errorMessage(t, w))
+		})
+	}
+}
+
+func TestUserHandler_RespondWithUserError(t *testing.T) {
+	h := NewUserHandler(nil, logger.NewLogger("test"), nil, nil)
+	tests := []struct {
+		err     error
+		status  int
+		message string
+	}{
+		{repositories.ErrUserNotFound, http.StatusNotFound, "User not found"},
+		{fmt.Errorf("lookup: %w", repositories.ErrRoleNotFound), http.StatusNotFound, "Role not found"},
+		{repositories.ErrUserRoleNotFound, http.StatusNotFound, "User does not have the role"},
+		{repositories.ErrUserRoleAlreadyExists, http.StatusConflict, "User already has the role"},
+		{repositories.ErrUserAlreadyExists, http.StatusConflict, "Username or email already in use"},
+		{fmt.Errorf("connection reset"), http.StatusInternalServerError, "Failed to update user"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.respondWithUserError(w, httptest.NewRequest("PUT", "/", nil), "Failed to update user", tt.err)
+		assert.Equal(t, tt.status, w.Code, tt.err.Error())
+		assert.Equal(t, tt.message, errorMessage(t, w), tt.err.Error())
+	}
+}
+
+func TestRoleNames(t *testing.T) {
+	assert.Equal(t, []string{}, roleNames(nil))
+	assert.Equal(t, []string{"admin"}, roleNames([]string{"admin"}))
+
+	body, err := json.Marshal(roleNames(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(body))
+}
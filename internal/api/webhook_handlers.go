@@ -0,0 +1,235 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connect/internal/auth"
+	"connect/internal/webhooks"
+	"github.com/gorilla/mux"
+)
+
+// WebhookHandler handles managing webhook endpoints and their delivery log
+type WebhookHandler struct {
+	service *webhooks.Service
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(service *webhooks.Service) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// RegisterRoutes registers webhook routes
+func (h *WebhookHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/webhooks", h.authMiddleware(h.handleCreateEndpoint)).Methods("POST")
+	router.HandleFunc("/api/v1/webhooks", h.authMiddleware(h.handleListEndpoints)).Methods("GET")
+	router.HandleFunc("/api/v1/webhooks/deliveries/{id}/redeliver", h.authMiddleware(h.handleRedeliver)).Methods("POST")
+	router.HandleFunc("/api/v1/webhooks/{id}", h.authMiddleware(h.handleGetEndpoint)).Methods("GET")
+	router.HandleFunc("/api/v1/webhooks/{id}", h.authMiddleware(h.handleUpdateEndpoint)).Methods("PUT")
+	router.HandleFunc("/api/v1/webhooks/{id}", h.authMiddleware(h.handleDeleteEndpoint)).Methods("DELETE")
+	router.HandleFunc("/api/v1/webhooks/{id}/test", h.authMiddleware(h.handleTestEndpoint)).Methods("POST")
+	router.HandleFunc("/api/v1/webhooks/{id}/deliveries", h.authMiddleware(h.handleListDeliveries)).Methods("GET")
+}
+
+// handleCreateEndpoint handles registering an endpoint. The response carries
+// the signing secret, which is not returned again.
+func (h *WebhookHandler) handleCreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req webhooks.EndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	endpoint, secret, err := h.service.CreateEndpoint(r.Context(), req, userID)
+	if err != nil {
+		h.respondWithServiceError(w, "Failed to create webhook endpoint", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"endpoint": endpoint,
+		"secret":   secret,
+	})
+}
+
+// handleListEndpoints handles listing every endpoint
+func (h *WebhookHandler) handleListEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.service.ListEndpoints(r.Context())
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list webhook endpoints", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"endpoints": endpoints,
+		"count":     len(endpoints),
+	})
+}
+
+// handleGetEndpoint handles getting an endpoint
+func (h *WebhookHandler) handleGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	endpoint, err := h.service.GetEndpoint(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, "Failed to get webhook endpoint", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, endpoint)
+}
+
+// handleUpdateEndpoint handles replacing an endpoint's settings. An omitted
+// secret keeps the current one.
+func (h *WebhookHandler) handleUpdateEndpoint(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	var req webhooks.EndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	endpoint, err := h.service.UpdateEndpoint(r.Context(), id, req)
+	if err != nil {
+		h.respondWithServiceError(w, "Failed to update webhook endpoint", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, endpoint)
+}
+
+// handleDeleteEndpoint handles deleting an endpoint along with its delivery log
+func (h *WebhookHandler) handleDeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteEndpoint(r.Context(), id); err != nil {
+		h.respondWithServiceError(w, "Failed to delete webhook endpoint", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTestEndpoint handles sending a ping event to an endpoint right away.
+// The delivery is returned whatever its outcome, so a failing endpoint still
+// gets a 200 with the failed delivery.
+func (h *WebhookHandler) handleTestEndpoint(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	delivery, err := h.service.Test(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, "Failed to test webhook endpoint", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, delivery)
+}
+
+// handleListDeliveries handles listing the latest deliveries to an endpoint,
+// optionally those with ?status
+func (h *WebhookHandler) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	status := params.Enum("status", "", []string{webhooks.DeliveryPending, webhooks.DeliverySucceeded, webhooks.DeliveryFailed})
+	limit := params.Int("limit", 50, 1, 500)
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), id, status, limit)
+	if err != nil {
+		h.respondWithServiceError(w, "Failed to list webhook deliveries", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// handleRedeliver handles queueing a new delivery of a logged delivery's event
+func (h *WebhookHandler) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	params := bindRequest(r)
+	id := params.PathUUID("id")
+	if err := params.Err(); err != nil {
+		respondWithParamError(w, err)
+		return
+	}
+
+	delivery, err := h.service.Redeliver(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, "Failed to redeliver webhook", err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusAccepted, delivery)
+}
+
+// respondWithServiceError maps webhook service errors to statuses
+func (h *WebhookHandler) respondWithServiceError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrInvalidEndpoint):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid webhook endpoint", err)
+	case errors.Is(err, webhooks.ErrEndpointNotFound), errors.Is(err, webhooks.ErrDeliveryNotFound):
+		h.respondWithError(w, http.StatusNotFound, err.Error(), nil)
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
+// authMiddleware requires the admin role to manage webhooks and their deliveries
+func (h *WebhookHandler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return requireAdmin(next).ServeHTTP
+}
+
+// respondWithError sends an error response
+func (h *WebhookHandler) respondWithError(w http.ResponseWriter, code int, message string, err error) {
+	response := map[string]interface{}{
+		"error":   message,
+		"success": false,
+	}
+
+	if err != nil {
+		response["details"] = err.Error()
+	}
+
+	h.respondWithJSON(w, code, response)
+}
+
+// respondWithJSON sends a JSON response
+func (h *WebhookHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,168 @@
+// Package apikey manages API keys for machine-to-machine access, e.g. by
+// discovery agents and CI/CD pipelines, which should not have to log in and
+// refresh tokens. A key belongs to the user who created it and is scoped to
+// permissions: requests sent with it, as "Authorization: ApiKey <key>" or in
+// the X-API-Key header, are granted those of its permissions its owner still
+// holds and nothing else. Keys carry no roles, so routes requiring a role
+// refuse them.
+//
+// Only a hash of a key is stored, so a key is shown once when it is created
+// or rotated. Rotating a key replaces its secret, keeping its permissions
+// and lifetime; revoking it stops it working for good.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Key lifetimes
+const (
+	DefaultLifetime = 90 * 24 * time.Hour
+	MaxLifetime     = 365 * 24 * time.Hour
+)
+
+// MaxNameLength bounds the length of a key name
+const MaxNameLength = 255
+
+// secretPrefix starts every key, so leaked ones are easy to scan for
+const secretPrefix = "conxak_"
+
+var (
+	ErrInvalidKey         = errors.New("invalid API key")
+	ErrKeyNotFound        = errors.New("API key not found")
+	ErrUnknownPermission  = errors.New("unknown permission")
+	ErrPermissionNotHeld  = errors.New("permission not held")
+	ErrInvalidCredentials = errors.New("invalid or expired API key")
+)
+
+// Key is an API key. Only a hash of its secret is kept; the prefix identifies it.
+type Key struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	// OwnerID is the user the key acts for
+	OwnerID uuid.UUID `json:"owner_id" db:"owner_id"`
+	// Org is the organization the key acts for, that of its owner when it
+	// was created
+	Org         string     `json:"org,omitempty" db:"org"`
+	Permissions []string   `json:"permissions" db:"-"`
+	Prefix      string     `json:"prefix" db:"prefix"`
+	SecretHash  string     `json:"-" db:"secret_hash"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RotatedAt   *time.Time `json:"rotated_at,omitempty" db:"rotated_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy   *uuid.UUID `json:"revoked_by,omitempty" db:"revoked_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Expired reports whether the key has expired at now
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// issuedAt is when the current secret of the key was issued
+func (k *Key) issuedAt() time.Time {
+	if k.RotatedAt != nil {
+		return *k.RotatedAt
+	}
+	return k.CreatedAt
+}
+
+// IssuedKey is a key with its secret, which is only returned when the key
+// is created or rotated
+type IssuedKey struct {
+	Key
+	Secret string `json:"key"`
+}
+
+// Owner is the user a key acts for
+type Owner struct {
+	ID       uuid.UUID
+	Username string
+	IsActive bool
+	Roles    []string
+}
+
+// Caller is the user managing keys
+type Caller struct {
+	ID    uuid.UUID
+	Roles []string
+	// Org is the organization the caller acts for, if any
+	Org string
+}
+
+// CreateRequest represents a request to create an API key
+type CreateRequest struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Permissions []string   `json:"permissions"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate checks the request fields that do not depend on stored state
+func (r *CreateRequest) Validate(now time.Time) error {
+	name := strings.TrimSpace(r.Name)
+	if name == "" || len(name) > MaxNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidKey, MaxNameLength)
+	}
+	if len(r.Permissions) == 0 {
+		return fmt.Errorf("%w: at least one permission is required", ErrInvalidKey)
+	}
+	if r.ExpiresAt != nil {
+		if !r.ExpiresAt.After(now) {
+			return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidKey)
+		}
+		if r.ExpiresAt.Sub(now) > MaxLifetime {
+			return fmt.Errorf("%w: keys expire within %s", ErrInvalidKey, MaxLifetime)
+		}
+	}
+	return nil
+}
+
+// newSecret generates a key, returning it with its prefix and hash
+func newSecret() (secret, prefix, hash string, err error) {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	raw := base64.RawURLEncoding.EncodeToString(secretBytes)
+	return secretPrefix + prefix + "." + raw, prefix, hashSecret(raw), nil
+}
+
+// parseSecret splits a key into its prefix and secret
+func parseSecret(secret string) (prefix, raw string, ok bool) {
+	rest, ok := strings.CutPrefix(secret, secretPrefix)
+	if !ok {
+		return "", "", false
+	}
+	prefix, raw, ok = strings.Cut(rest, ".")
+	return prefix, raw, ok && prefix != "" && raw != ""
+}
+
+// hashSecret hashes a secret. Secrets are random, so a plain hash is enough.
+func hashSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// secretMatches compares a secret with a stored hash in constant time
+func secretMatches(hash, raw string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(raw))) == 1
+}
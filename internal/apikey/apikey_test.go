@@ -0,0 +1,242 @@
+package apikey
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"connect/internal/auth"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// memoryStore holds keys and owners in memory and records audit actions
+type memoryStore struct {
+	keys   map[uuid.UUID]*Key
+	owners map[uuid.UUID]*Owner
+	audit  []string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{keys: map[uuid.UUID]*Key{}, owners: map[uuid.UUID]*Owner{}}
+}
+
+func (m *memoryStore) UnknownPermissions(ctx context.Context, permissions []string) ([]string, error) {
+	var unknown []string
+	for _, permission := range permissions {
+		if permission != "ci:read" && permission != "ci:write" {
+			unknown = append(unknown, permission)
+		}
+	}
+	return unknown, nil
+}
+
+func (m *memoryStore) CreateKey(ctx context.Context, key *Key) error {
+	copied := *key
+	m.keys[key.ID] = &copied
+	m.audit = append(m.audit, "created")
+	return nil
+}
+
+func (m *memoryStore) GetKey(ctx context.Context, id uuid.UUID) (*Key, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (m *memoryStore) ListKeys(ctx context.Context, ownerID uuid.UUID) ([]*Key, error) {
+	var keys []*Key
+	for _, key := range m.keys {
+		if ownerID == uuid.Nil || key.OwnerID == ownerID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *memoryStore) GetKeyByPrefix(ctx context.Context, prefix string) (*Key, error) {
+	for _, key := range m.keys {
+		if key.Prefix == prefix {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (m *memoryStore) RotateKey(ctx context.Context, key *Key, by uuid.UUID) error {
+	stored, ok := m.keys[key.ID]
+	if !ok || stored.RevokedAt != nil {
+		return ErrKeyNotFound
+	}
+	copied := *key
+	m.keys[key.ID] = &copied
+	m.audit = append(m.audit, "rotated")
+	return nil
+}
+
+func (m *memoryStore) RevokeKey(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error {
+	key, ok := m.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return ErrKeyNotFound
+	}
+	key.RevokedAt, key.RevokedBy = &at, &by
+	m.audit = append(m.audit, "revoked")
+	return nil
+}
+
+func (m *memoryStore) RecordUse(ctx context.Context, id uuid.UUID, at time.Time) error {
+	m.keys[id].LastUsedAt = &at
+	return nil
+}
+
+func (m *memoryStore) GetOwner(ctx context.Context, userID uuid.UUID) (*Owner, error) {
+	return m.owners[userID], nil
+}
+
+// rolePermissions grants "ci_manager" reading and writing CIs and "viewer" reading them
+var rolePermissions = map[string][]string{
+	"ci_manager": {"ci:read", "ci:write"},
+	"viewer":     {"ci:read"},
+}
+
+type permissionResolver struct{}
+
+func (permissionResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, rolePermissions[role]...)
+	}
+	return permissions, nil
+}
+
+func newTestService() (*Service, *memoryStore) {
+	store := newMemoryStore()
+	service := NewService(store, permissionResolver{})
+	service.now = func() time.Time { return now }
+	return service, store
+}
+
+// newOwner stores an active user with roles and returns them as a caller
+func newOwner(store *memoryStore, roles ...string) Caller {
+	id := uuid.New()
+	store.owners[id] = &Owner{ID: id, Username: "pipeline-owner", IsActive: true, Roles: roles}
+	return Caller{ID: id, Roles: roles, Org: "acme"}
+}
+
+func TestCreateValidatesRequest(t *testing.T) {
+	service, store := newTestService()
+	caller := newOwner(store, "viewer")
+	past := now.Add(-time.Hour)
+	tooLong := now.Add(MaxLifetime + time.Hour)
+
+	for name, req := range map[string]CreateRequest{
+		"no name":        {Permissions: []string{"ci:read"}},
+		"no permissions": {Name: "discovery", Permissions: []string{" "}},
+		"past expiry":    {Name: "discovery", Permissions: []string{"ci:read"}, ExpiresAt: &past},
+		"too long":       {Name: "discovery", Permissions: []string{"ci:read"}, ExpiresAt: &tooLong},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.Create(context.Background(), req, caller)
+			assert.ErrorIs(t, err, ErrInvalidKey)
+		})
+	}
+
+	_, err := service.Create(context.Background(), CreateRequest{Name: "discovery", Permissions: []string{"ci:delete"}}, caller)
+	assert.ErrorIs(t, err, ErrUnknownPermission)
+
+	// A key cannot grant more than its creator holds
+	_, err = service.Create(context.Background(), CreateRequest{Name: "discovery", Permissions: []string{"ci:write"}}, caller)
+	assert.ErrorIs(t, err, ErrPermissionNotHeld)
+	assert.Empty(t, store.audit)
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	service, store := newTestService()
+	ctx := context.Background()
+	caller := newOwner(store, "ci_manager")
+
+	issued, err := service.Create(ctx, CreateRequest{Name: "discovery", Permissions: []string{"ci:read", "ci:read"}}, caller)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Secret, secretPrefix+issued.Prefix+"."))
+	assert.Equal(t, now.Add(DefaultLifetime), *issued.ExpiresAt)
+	assert.Equal(t, []string{"ci:read"}, issued.Permissions)
+	assert.Equal(t, "acme", issued.Org)
+
+	claims, err := service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, caller.ID.String(), claims.UserID)
+	assert.Equal(t, "pipeline-owner", claims.Username)
+	assert.Equal(t, "acme", claims.Org)
+	assert.Equal(t, []string{"ci:read"}, claims.Scopes)
+	assert.Empty(t, claims.Roles)
+	assert.Equal(t, now, *store.keys[issued.ID].LastUsedAt)
+
+	for _, secret := range []string{"", "not-a-key", secretPrefix + "000000000000.secret", secretPrefix + issued.Prefix + ".guessed"} {
+		_, err := service.AuthenticateAPIKey(ctx, secret, auth.ClientInfo{})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// Keys lose the permissions their owner loses
+	store.owners[caller.ID].Roles = nil
+	claims, err = service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, claims.Scopes)
+
+	store.owners[caller.ID].IsActive = false
+	_, err = service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	store.owners[caller.ID].IsActive = true
+	service.now = func() time.Time { return *issued.ExpiresAt }
+	_, err = service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestRotateAndRevoke(t *testing.T) {
+	service, store := newTestService()
+	ctx := context.Background()
+	caller := newOwner(store, "viewer")
+	expiresAt := now.Add(30 * 24 * time.Hour)
+
+	issued, err := service.Create(ctx, CreateRequest{Name: "pipeline", Permissions: []string{"ci:read"}, ExpiresAt: &expiresAt}, caller)
+	require.NoError(t, err)
+
+	later := now.Add(24 * time.Hour)
+	service.now = func() time.Time { return later }
+	rotated, err := service.Rotate(ctx, issued.ID, caller)
+	require.NoError(t, err)
+	assert.NotEqual(t, issued.Secret, rotated.Secret)
+	assert.Equal(t, later.Add(30*24*time.Hour), *rotated.ExpiresAt)
+	assert.Equal(t, later, *rotated.RotatedAt)
+
+	_, err = service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.AuthenticateAPIKey(ctx, rotated.Secret, auth.ClientInfo{})
+	require.NoError(t, err)
+
+	// Other users cannot see the key, admins can
+	other := newOwner(store, "viewer")
+	assert.ErrorIs(t, service.Revoke(ctx, issued.ID, other), ErrKeyNotFound)
+	keys, err := service.List(ctx, other, true)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	admin := newOwner(store, "admin")
+	keys, err = service.List(ctx, admin, true)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	require.NoError(t, service.Revoke(ctx, issued.ID, caller))
+	_, err = service.AuthenticateAPIKey(ctx, rotated.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.Rotate(ctx, issued.ID, caller)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.Equal(t, []string{"created", "rotated", "revoked"}, store.audit)
+}
@@ -0,0 +1,242 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"connect/internal/auth"
+	"github.com/google/uuid"
+)
+
+// adminRole may manage the keys of every user
+const adminRole = "admin"
+
+// Service manages API keys and authenticates requests sent with them
+type Service struct {
+	store       Store
+	permissions auth.PermissionResolver
+	now         func() time.Time
+}
+
+// NewService creates a new API key service. permissions resolves the
+// permissions of key owners' roles.
+func NewService(store Store, permissions auth.PermissionResolver) *Service {
+	return &Service{store: store, permissions: permissions, now: time.Now}
+}
+
+// Create creates a key for the caller. Its permissions must be granted to the
+// caller, so a key never exceeds its owner. It expires after DefaultLifetime
+// unless asked otherwise.
+func (s *Service) Create(ctx context.Context, req CreateRequest, caller Caller) (*IssuedKey, error) {
+	now := s.now()
+	if err := req.Validate(now); err != nil {
+		return nil, err
+	}
+	permissions := dedupe(req.Permissions)
+	if len(permissions) == 0 {
+		return nil, fmt.Errorf("%w: at least one permission is required", ErrInvalidKey)
+	}
+
+	unknown, err := s.store.UnknownPermissions(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, strings.Join(unknown, ", "))
+	}
+	held, err := s.permissions.Permissions(ctx, caller.Roles)
+	if err != nil {
+		return nil, err
+	}
+	if missing := subtract(permissions, held); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionNotHeld, strings.Join(missing, ", "))
+	}
+
+	expiresAt := now.Add(DefaultLifetime)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	secret, prefix, hash, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	key := Key{
+		ID:          uuid.New(),
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		OwnerID:     caller.ID,
+		Org:         caller.Org,
+		Permissions: permissions,
+		Prefix:      prefix,
+		SecretHash:  hash,
+		ExpiresAt:   &expiresAt,
+		CreatedAt:   now,
+	}
+	if err := s.store.CreateKey(ctx, &key); err != nil {
+		return nil, err
+	}
+	return &IssuedKey{Key: key, Secret: secret}, nil
+}
+
+// List lists the caller's keys, or every user's when all is set and the
+// caller is an admin
+func (s *Service) List(ctx context.Context, caller Caller, all bool) ([]*Key, error) {
+	owner := caller.ID
+	if all && caller.admin() {
+		owner = uuid.Nil
+	}
+	return s.store.ListKeys(ctx, owner)
+}
+
+// Get retrieves a key of the caller; admins may retrieve any key
+func (s *Service) Get(ctx context.Context, id uuid.UUID, caller Caller) (*Key, error) {
+	key, err := s.store.GetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.OwnerID != caller.ID && !caller.admin() {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Rotate replaces the secret of a key, which stops the old secret working.
+// The new secret is issued for as long as the old one was.
+func (s *Service) Rotate(ctx context.Context, id uuid.UUID, caller Caller) (*IssuedKey, error) {
+	key, err := s.Get(ctx, id, caller)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: key %s is revoked", ErrInvalidKey, key.Name)
+	}
+
+	lifetime := DefaultLifetime
+	if key.ExpiresAt != nil {
+		lifetime = key.ExpiresAt.Sub(key.issuedAt())
+	}
+	expiresAt := now.Add(lifetime)
+
+	secret, prefix, hash, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	key.Prefix, key.SecretHash, key.ExpiresAt, key.RotatedAt = prefix, hash, &expiresAt, &now
+	if err := s.store.RotateKey(ctx, key, caller.ID); err != nil {
+		return nil, err
+	}
+	return &IssuedKey{Key: *key, Secret: secret}, nil
+}
+
+// Revoke revokes a key
+func (s *Service) Revoke(ctx context.Context, id uuid.UUID, caller Caller) error {
+	if _, err := s.Get(ctx, id, caller); err != nil {
+		return err
+	}
+	return s.store.RevokeKey(ctx, id, caller.ID, s.now())
+}
+
+// AuthenticateAPIKey resolves a key to the claims of its owner, scoped to the
+// key's permissions the owner still holds. Every failure returns
+// ErrInvalidCredentials so callers cannot tell a wrong key from a revoked one.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, secret string, client auth.ClientInfo) (*auth.Claims, error) {
+	prefix, raw, ok := parseSecret(secret)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	key, err := s.store.GetKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	now := s.now()
+	if !secretMatches(key.SecretHash, raw) || key.RevokedAt != nil || key.Expired(now) {
+		return nil, ErrInvalidCredentials
+	}
+	owner, err := s.store.GetOwner(ctx, key.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil || !owner.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+
+	held, err := s.permissions.Permissions(ctx, owner.Roles)
+	if err != nil {
+		return nil, err
+	}
+	scopes := intersect(key.Permissions, held)
+
+	if err := s.store.RecordUse(ctx, key.ID, now); err != nil {
+		log.Printf("Failed to record use of API key %s: %v", key.ID, err)
+	}
+	return &auth.Claims{
+		UserID:   owner.ID.String(),
+		Username: owner.Username,
+		Org:      key.Org,
+		Scopes:   scopes,
+	}, nil
+}
+
+func (c Caller) admin() bool {
+	for _, role := range c.Roles {
+		if role == adminRole {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupe returns the non-empty values once each, in order
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// subtract returns the values missing from others
+func subtract(values, others []string) []string {
+	var missing []string
+	for _, value := range values {
+		if !contains(others, value) {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// intersect returns the values also in others. It never returns nil, so an
+// empty result still scopes a request to nothing.
+func intersect(values, others []string) []string {
+	result := []string{}
+	for _, value := range values {
+		if contains(others, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
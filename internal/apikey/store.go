@@ -0,0 +1,253 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// auditEntityType is the entity type of API key audit entries
+const auditEntityType = "api_key"
+
+// Store persists API keys. Creating, rotating and revoking a key is written
+// to the audit log in the same transaction.
+type Store interface {
+	// UnknownPermissions returns the permissions that do not exist
+	UnknownPermissions(ctx context.Context, permissions []string) ([]string, error)
+	CreateKey(ctx context.Context, key *Key) error
+	GetKey(ctx context.Context, id uuid.UUID) (*Key, error)
+	// ListKeys lists the keys of an owner, or of every owner for uuid.Nil
+	ListKeys(ctx context.Context, ownerID uuid.UUID) ([]*Key, error)
+	GetKeyByPrefix(ctx context.Context, prefix string) (*Key, error)
+	// RotateKey saves the new secret and expiry of a key that is not revoked
+	RotateKey(ctx context.Context, key *Key, by uuid.UUID) error
+	RevokeKey(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error
+	// RecordUse sets when a key was last used
+	RecordUse(ctx context.Context, id uuid.UUID, at time.Time) error
+	// GetOwner retrieves a user with their roles, or nil if there is none
+	GetOwner(ctx context.Context, userID uuid.UUID) (*Owner, error)
+}
+
+// PostgresStore keeps API keys in the api_keys table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed API key store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// keyRow is a key as stored, with its permissions as a Postgres array
+type keyRow struct {
+	Key
+	Permissions pq.StringArray `db:"permissions"`
+}
+
+func (r *keyRow) key() *Key {
+	key := r.Key
+	key.Permissions = []string(r.Permissions)
+	return &key
+}
+
+const keyColumns = `id, name, description, owner_id, org, permissions, prefix, secret_hash,
+	expires_at, last_used_at, rotated_at, revoked_at, revoked_by, created_at`
+
+// UnknownPermissions retrieves the permissions missing from the permissions table
+func (s *PostgresStore) UnknownPermissions(ctx context.Context, permissions []string) ([]string, error) {
+	var unknown []string
+	err := s.db.SelectContext(ctx, &unknown, `
+		SELECT wanted.name FROM unnest($1::text[]) AS wanted(name)
+		WHERE NOT EXISTS (SELECT 1 FROM permissions p WHERE p.name = wanted.name)`, pq.Array(permissions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	return unknown, nil
+}
+
+// CreateKey inserts a key
+func (s *PostgresStore) CreateKey(ctx context.Context, key *Key) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO api_keys (`+keyColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+			key.ID, key.Name, key.Description, key.OwnerID, key.Org, pq.Array(key.Permissions), key.Prefix,
+			key.SecretHash, key.ExpiresAt, key.LastUsedAt, key.RotatedAt, key.RevokedAt, key.RevokedBy, key.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create API key: %w", err)
+		}
+		return insertAudit(ctx, tx, key.ID, "created", key.OwnerID, key.CreatedAt, map[string]interface{}{
+			"name":        key.Name,
+			"prefix":      key.Prefix,
+			"permissions": key.Permissions,
+			"expires_at":  key.ExpiresAt,
+		})
+	})
+}
+
+// GetKey retrieves a key
+func (s *PostgresStore) GetKey(ctx context.Context, id uuid.UUID) (*Key, error) {
+	return s.getKey(ctx, `id = $1`, id)
+}
+
+// GetKeyByPrefix retrieves the key with a prefix
+func (s *PostgresStore) GetKeyByPrefix(ctx context.Context, prefix string) (*Key, error) {
+	return s.getKey(ctx, `prefix = $1`, prefix)
+}
+
+func (s *PostgresStore) getKey(ctx context.Context, where string, arg interface{}) (*Key, error) {
+	var row keyRow
+	err := s.db.GetContext(ctx, &row, `SELECT `+keyColumns+` FROM api_keys WHERE `+where, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return row.key(), nil
+}
+
+// ListKeys retrieves the keys of an owner, newest first
+func (s *PostgresStore) ListKeys(ctx context.Context, ownerID uuid.UUID) ([]*Key, error) {
+	var rows []keyRow
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+keyColumns+` FROM api_keys
+		WHERE $1 = '00000000-0000-0000-0000-000000000000'::uuid OR owner_id = $1
+		ORDER BY created_at DESC`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	keys := make([]*Key, 0, len(rows))
+	for i := range rows {
+		keys = append(keys, rows[i].key())
+	}
+	return keys, nil
+}
+
+// RotateKey saves the secret of a key that is not revoked
+func (s *PostgresStore) RotateKey(ctx context.Context, key *Key, by uuid.UUID) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE api_keys SET prefix = $2, secret_hash = $3, expires_at = $4, rotated_at = $5
+			WHERE id = $1 AND revoked_at IS NULL`,
+			key.ID, key.Prefix, key.SecretHash, key.ExpiresAt, key.RotatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to rotate API key: %w", err)
+		}
+		if err := requireRow(result); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, key.ID, "rotated", by, *key.RotatedAt, map[string]interface{}{
+			"prefix":     key.Prefix,
+			"expires_at": key.ExpiresAt,
+		})
+	})
+}
+
+// RevokeKey revokes a key that is not revoked yet
+func (s *PostgresStore) RevokeKey(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error {
+	var revokedBy *uuid.UUID
+	if by != uuid.Nil {
+		revokedBy = &by
+	}
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE api_keys SET revoked_at = $2, revoked_by = $3
+			WHERE id = $1 AND revoked_at IS NULL`, id, at, revokedBy)
+		if err != nil {
+			return fmt.Errorf("failed to revoke API key: %w", err)
+		}
+		if err := requireRow(result); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, id, "revoked", by, at, map[string]interface{}{})
+	})
+}
+
+// RecordUse sets the last use of a key
+func (s *PostgresStore) RecordUse(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at); err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
+
+// GetOwner retrieves a user with the names of their active roles
+func (s *PostgresStore) GetOwner(ctx context.Context, userID uuid.UUID) (*Owner, error) {
+	var row struct {
+		ID       uuid.UUID      `db:"id"`
+		Username string         `db:"username"`
+		IsActive bool           `db:"is_active"`
+		Roles    pq.StringArray `db:"roles"`
+	}
+	err := s.db.GetContext(ctx, &row, `
+		SELECT u.id, u.username, u.is_active,
+		       COALESCE(ARRAY(
+		           SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		           WHERE ur.user_id = u.id AND r.is_active = true ORDER BY r.name
+		       ), '{}') AS roles
+		FROM users u WHERE u.id = $1`, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get API key owner: %w", err)
+	}
+	return &Owner{ID: row.ID, Username: row.Username, IsActive: row.IsActive, Roles: []string(row.Roles)}, nil
+}
+
+// requireRow returns ErrKeyNotFound if an update matched no row
+func requireRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (s *PostgresStore) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertAudit writes an audit log entry for a key
+func insertAudit(ctx context.Context, tx *sqlx.Tx, keyID uuid.UUID, action string, by uuid.UUID, at time.Time, details map[string]interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	var changedBy *uuid.UUID
+	if by != uuid.Nil {
+		changedBy = &by
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ($1, $2, $3, $4, $5, $6)`, auditEntityType, keyID, action, changedBy, at, data)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Package apiversion groups API routes by version and announces deprecations.
+//
+// Each version is served under /api/<name>. Versions are enabled individually,
+// so a new version can be rolled out behind configuration, and a version or a
+// single endpoint can be marked deprecated. Responses from deprecated routes
+// carry Deprecation, Sunset and Link headers (RFC 9745 and RFC 8594) so clients
+// notice before the route goes away.
+package apiversion
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Version statuses reported by the discovery endpoint
+const (
+	StatusActive     = "active"
+	StatusDeprecated = "deprecated"
+	StatusDisabled   = "disabled"
+)
+
+// Deprecation describes a deprecated version or endpoint
+type Deprecation struct {
+	Since     time.Time `json:"since,omitempty"`     // when the deprecation took effect; zero means "deprecated, date unspecified"
+	Sunset    time.Time `json:"sunset,omitempty"`    // when the route will stop being served
+	Link      string    `json:"link,omitempty"`      // documentation about the deprecation
+	Successor string    `json:"successor,omitempty"` // replacement version or endpoint
+}
+
+// SetHeaders adds the deprecation headers to a response
+func (d Deprecation) SetHeaders(h http.Header) {
+	if d.Since.IsZero() {
+		h.Set("Deprecation", "true")
+	} else {
+		h.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+	}
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, d.Link))
+	}
+	if d.Successor != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor))
+	}
+}
+
+// RouteRegistrar is implemented by handlers that register their routes on a router
+type RouteRegistrar interface {
+	RegisterRoutes(router *mux.Router)
+}
+
+// Version is an API version served under /api/<name>
+type Version struct {
+	Name        string       `json:"name"`
+	Prefix      string       `json:"prefix"`
+	Status      string       `json:"status"`
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+
+	enabled bool
+	router  *mux.Router
+}
+
+// Registry tracks API versions and endpoint deprecations
+type Registry struct {
+	router    *mux.Router
+	versions  map[string]*Version
+	endpoints map[string]Deprecation
+}
+
+// NewRegistry creates a registry for routes on router
+func NewRegistry(router *mux.Router) *Registry {
+	return &Registry{
+		router:    router,
+		versions:  make(map[string]*Version),
+		endpoints: make(map[string]Deprecation),
+	}
+}
+
+// Prefix returns the path prefix of a version
+func Prefix(name string) string {
+	return "/api/" + name
+}
+
+// AddVersion declares a version. Handlers registered for a disabled version are not routed.
+func (r *Registry) AddVersion(name string, enabled bool) *Version {
+	if v, ok := r.versions[name]; ok {
+		v.enabled = enabled
+		v.Status = status(v)
+		return v
+	}
+
+	v := &Version{Name: name, Prefix: Prefix(name), enabled: enabled}
+	v.Status = status(v)
+	r.versions[name] = v
+	return v
+}
+
+// Enabled reports whether a version is declared and enabled
+func (r *Registry) Enabled(name string) bool {
+	v, ok := r.versions[name]
+	return ok && v.enabled
+}
+
+// Register registers handlers on the version's subrouter. Handlers register paths
+// relative to the version prefix, e.g. "/cis" for /api/v2/cis.
+func (r *Registry) Register(name string, handlers ...RouteRegistrar) error {
+	v, ok := r.versions[name]
+	if !ok {
+		return fmt.Errorf("unknown API version: %s", name)
+	}
+	if !v.enabled {
+		return nil
+	}
+
+	if v.router == nil {
+		v.router = r.router.PathPrefix(v.Prefix).Subrouter()
+	}
+	for _, handler := range handlers {
+		handler.RegisterRoutes(v.router)
+	}
+	return nil
+}
+
+// Deprecate marks a whole version as deprecated
+func (r *Registry) Deprecate(name string, deprecation Deprecation) error {
+	v, ok := r.versions[name]
+	if !ok {
+		return fmt.Errorf("unknown API version: %s", name)
+	}
+	v.Deprecation = &deprecation
+	v.Status = status(v)
+	return nil
+}
+
+// DeprecateEndpoint marks a route as deprecated. The route is a mux path template
+// optionally prefixed by a method, e.g. "GET /api/v1/cis" or "/api/v1/cis/{id}".
+func (r *Registry) DeprecateEndpoint(route string, deprecation Deprecation) {
+	r.endpoints[route] = deprecation
+}
+
+// Versions returns the declared versions ordered by name
+func (r *Registry) Versions() []Version {
+	versions := make([]Version, 0, len(r.versions))
+	for _, v := range r.versions {
+		versions = append(versions, *v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Name < versions[j].Name })
+	return versions
+}
+
+// Middleware adds deprecation headers to responses from deprecated routes.
+// An endpoint deprecation takes precedence over the deprecation of its version.
+func (r *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if deprecation, ok := r.lookup(req); ok {
+			deprecation.SetHeaders(w.Header())
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// lookup finds the deprecation that applies to a request
+func (r *Registry) lookup(req *http.Request) (Deprecation, bool) {
+	if len(r.endpoints) > 0 {
+		if route := mux.CurrentRoute(req); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				if d, ok := r.endpoints[strings.ToUpper(req.Method)+" "+template]; ok {
+					return d, true
+				}
+				if d, ok := r.endpoints[template]; ok {
+					return d, true
+				}
+			}
+		}
+	}
+
+	if v := r.versionOf(req.URL.Path); v != nil && v.Deprecation != nil {
+		return *v.Deprecation, true
+	}
+	return Deprecation{}, false
+}
+
+// versionOf returns the version whose prefix the path falls under
+func (r *Registry) versionOf(path string) *Version {
+	name := Name(path)
+	if name == "" {
+		return nil
+	}
+	return r.versions[name]
+}
+
+// Name returns the name of the version a path is served under, or "" when the
+// path is not under /api/
+func Name(path string) string {
+	if !strings.HasPrefix(path, "/api/") {
+		return ""
+	}
+	name := strings.TrimPrefix(path, "/api/")
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// ParseDate parses a deprecation or sunset date written as YYYY-MM-DD or RFC 3339
+func ParseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+func status(v *Version) string {
+	switch {
+	case !v.enabled:
+		return StatusDisabled
+	case v.Deprecation != nil:
+		return StatusDeprecated
+	default:
+		return StatusActive
+	}
+}
@@ -0,0 +1,117 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pingHandler struct{ path string }
+
+func (h pingHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc(h.path, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+}
+
+func serve(router *mux.Router, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestRegistry_RegisterOnlyEnabledVersions(t *testing.T) {
+	router := mux.NewRouter()
+	registry := NewRegistry(router)
+	registry.AddVersion("v2", true)
+	registry.AddVersion("v3", false)
+
+	require.NoError(t, registry.Register("v2", pingHandler{path: "/cis"}))
+	require.NoError(t, registry.Register("v3", pingHandler{path: "/cis"}))
+	assert.Error(t, registry.Register("v9", pingHandler{path: "/cis"}))
+
+	assert.Equal(t, http.StatusOK, serve(router, "GET", "/api/v2/cis").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/api/v3/cis").Code)
+}
+
+func TestRegistry_VersionDeprecationHeaders(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/cis", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+
+	registry := NewRegistry(router)
+	registry.AddVersion("v1", true)
+	registry.AddVersion("v2", true)
+	require.NoError(t, registry.Register("v2", pingHandler{path: "/cis"}))
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, registry.Deprecate("v1", Deprecation{
+		Since:     since,
+		Sunset:    sunset,
+		Link:      "https://docs.example.com/api/v1-deprecation",
+		Successor: "/api/v2",
+	}))
+	router.Use(registry.Middleware)
+
+	rec := serve(router, "GET", "/api/v1/cis")
+	assert.Equal(t, "@1767225600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 31 Dec 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, []string{
+		`<https://docs.example.com/api/v1-deprecation>; rel="deprecation"`,
+		`</api/v2>; rel="successor-version"`,
+	}, rec.Header().Values("Link"))
+
+	rec = serve(router, "GET", "/api/v2/cis")
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+}
+
+func TestRegistry_EndpointDeprecation(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/cis/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET", "DELETE")
+
+	registry := NewRegistry(router)
+	registry.AddVersion("v1", true)
+	registry.DeprecateEndpoint("DELETE /api/v1/cis/{id}", Deprecation{})
+	router.Use(registry.Middleware)
+
+	assert.Equal(t, "true", serve(router, "DELETE", "/api/v1/cis/123").Header().Get("Deprecation"))
+	assert.Empty(t, serve(router, "GET", "/api/v1/cis/123").Header().Get("Deprecation"))
+}
+
+func TestRegistry_Versions(t *testing.T) {
+	registry := NewRegistry(mux.NewRouter())
+	registry.AddVersion("v2", false)
+	registry.AddVersion("v1", true)
+	require.NoError(t, registry.Deprecate("v1", Deprecation{}))
+
+	versions := registry.Versions()
+	require.Len(t, versions, 2)
+	assert.Equal(t, "v1", versions[0].Name)
+	assert.Equal(t, StatusDeprecated, versions[0].Status)
+	assert.Equal(t, "/api/v1", versions[0].Prefix)
+	assert.Equal(t, StatusDisabled, versions[1].Status)
+	assert.False(t, registry.Enabled("v2"))
+}
+
+func TestParseDate(t *testing.T) {
+	d, err := ParseDate("2026-06-30")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC), d)
+
+	d, err = ParseDate("2026-06-30T12:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, 12, d.Hour())
+
+	d, err = ParseDate("")
+	require.NoError(t, err)
+	assert.True(t, d.IsZero())
+
+	_, err = ParseDate("30/06/2026")
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package attrtrigger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// WebhookTimeout bounds each webhook call
+const WebhookTimeout = 10 * time.Second
+
+// Notification tells the members of a role that a trigger fired
+type Notification struct {
+	TriggerID   uuid.UUID  `json:"trigger_id"`
+	TriggerName string     `json:"trigger_name"`
+	Role        string     `json:"role"`
+	Message     string     `json:"message"`
+	CI          *models.CI `json:"ci"`
+	Change      Change     `json:"change"`
+}
+
+// Notifier delivers trigger notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the log. It is used until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the notification
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	log.Printf("Attribute trigger %s fired on CI %s (%s changed from %s to %s), notifying role %s: %s",
+		n.TriggerName, n.CI.ID, n.Change.Attribute, describeValue(n.Change.From), describeValue(n.Change.To), n.Role, n.Message)
+	return nil
+}
+
+func describeValue(value *string) string {
+	if value == nil {
+		return "no value"
+	}
+	return fmt.Sprintf("%q", *value)
+}
+
+// WebhookPayload is the body POSTed to webhook actions
+type WebhookPayload struct {
+	TriggerID   uuid.UUID  `json:"trigger_id"`
+	TriggerName string     `json:"trigger_name"`
+	CI          *models.CI `json:"ci"`
+	Change      Change     `json:"change"`
+	FiredAt     time.Time  `json:"fired_at"`
+}
+
+// callWebhook POSTs the payload and fails on any status but 2xx
+func callWebhook(ctx context.Context, client *http.Client, target string, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook call failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package attrtrigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store that records the actions applied
+type memoryStore struct {
+	mu         sync.Mutex
+	triggers   map[uuid.UUID]*Trigger
+	executions []Execution
+	tagChanges [][]string
+	deprecated []uuid.UUID
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{triggers: map[uuid.UUID]*Trigger{}}
+}
+
+func (m *memoryStore) ListTriggers(ctx context.Context) ([]*Trigger, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	triggers := []*Trigger{}
+	for _, trigger := range m.triggers {
+		copied := *trigger
+		triggers = append(triggers, &copied)
+	}
+	return triggers, nil
+}
+
+func (m *memoryStore) GetTrigger(ctx context.Context, id uuid.UUID) (*Trigger, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	trigger, ok := m.triggers[id]
+	if !ok {
+		return nil, ErrTriggerNotFound
+	}
+	copied := *trigger
+	return &copied, nil
+}
+
+func (m *memoryStore) CreateTrigger(ctx context.Context, trigger *Trigger) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *trigger
+	m.triggers[trigger.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) UpdateTrigger(ctx context.Context, trigger *Trigger) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.triggers[trigger.ID]; !ok {
+		return ErrTriggerNotFound
+	}
+	copied := *trigger
+	m.triggers[trigger.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteTrigger(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.triggers[id]; !ok {
+		return ErrTriggerNotFound
+	}
+	delete(m.triggers, id)
+	return nil
+}
+
+func (m *memoryStore) RecordExecutions(ctx context.Context, executions []Execution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.executions = append(m.executions, executions...)
+	return nil
+}
+
+func (m *memoryStore) ListExecutions(ctx context.Context, triggerID uuid.UUID, outcome string, limit int) ([]Execution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	executions := []Execution{}
+	for _, execution := range m.executions {
+		if execution.TriggerID == triggerID && (outcome == "" || execution.Outcome == outcome) {
+			executions = append(executions, execution)
+		}
+	}
+	return executions, nil
+}
+
+func (m *memoryStore) ChangeTags(ctx context.Context, ciID uuid.UUID, add, remove []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tagChanges = append(m.tagChanges, append(append([]string{}, add...), remove...))
+	return nil
+}
+
+func (m *memoryStore) DeprecateRelationships(ctx context.Context, ciID uuid.UUID, types []string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deprecated = append(m.deprecated, ciID)
+	return 2, nil
+}
+
+// recordingNotifier keeps notifications, failing when err is set
+type recordingNotifier struct {
+	notifications []Notification
+	err           error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return n.err
+}
+
+func strPtr(s string) *string { return &s }
+
+// drain runs the actions of every queued firing
+func drain(s *Service) {
+	for {
+		select {
+		case j := <-s.jobs:
+			s.execute(context.Background(), j)
+		default:
+			return
+		}
+	}
+}
+
+func TestTriggerValidate(t *testing.T) {
+	valid := Trigger{Name: "decommission", Attribute: "status", Actions: []Action{{Type: ActionDeactivateRelationships}}}
+	require.NoError(t, valid.Validate())
+	assert.Equal(t, []string{}, valid.CITypes)
+
+	for name, trigger := range map[string]Trigger{
+		"missing name":     {Attribute: "status", Actions: []Action{{Type: ActionDeactivateRelationships}}},
+		"unknown field":    {Name: "t", Attribute: "colour", Actions: []Action{{Type: ActionDeactivateRelationships}}},
+		"empty attribute":  {Name: "t", Attribute: "attributes.", Actions: []Action{{Type: ActionDeactivateRelationships}}},
+		"no actions":       {Name: "t", Attribute: "status"},
+		"bad webhook url":  {Name: "t", Attribute: "status", Actions: []Action{{Type: ActionWebhook, URL: "ftp://example.com"}}},
+		"empty tag action": {Name: "t", Attribute: "status", Actions: []Action{{Type: ActionTag}}},
+		"notify no role":   {Name: "t", Attribute: "status", Actions: []Action{{Type: ActionNotify}}},
+		"unknown action":   {Name: "t", Attribute: "status", Actions: []Action{{Type: "email"}}},
+	} {
+		trigger := trigger
+		assert.ErrorIs(t, trigger.Validate(), ErrInvalidTrigger, name)
+	}
+}
+
+func TestTriggerMatch(t *testing.T) {
+	trigger := Trigger{Attribute: "status", To: strPtr("decommissioned"), CITypes: []string{"server"}}
+	previous := &models.CI{Type: "server", Status: "active"}
+	ci := &models.CI{Type: "server", Status: "decommissioned"}
+
+	change := trigger.Match(ci, previous)
+	require.NotNil(t, change)
+	assert.Equal(t, "active", *change.From)
+	assert.Equal(t, "decommissioned", *change.To)
+
+	assert.Nil(t, trigger.Match(ci, ci), "unchanged value")
+	assert.Nil(t, trigger.Match(&models.CI{Type: "server", Status: "maintenance"}, previous), "other target value")
+	assert.Nil(t, trigger.Match(&models.CI{Type: "database", Status: "decommissioned"}, previous), "other CI type")
+	assert.NotNil(t, trigger.Match(ci, nil), "create with the value set")
+
+	trigger.From = strPtr("maintenance")
+	assert.Nil(t, trigger.Match(ci, previous), "other source value")
+	assert.Nil(t, trigger.Match(ci, nil), "create never matches a source value")
+}
+
+func TestTriggerMatchCustomAttribute(t *testing.T) {
+	trigger := Trigger{Attribute: "attributes.replicas"}
+	previous := &models.CI{Attributes: json.RawMessage(`{"replicas": 2}`)}
+
+	change := trigger.Match(&models.CI{Attributes: json.RawMessage(`{"replicas": 3}`)}, previous)
+	require.NotNil(t, change)
+	assert.Equal(t, "2", *change.From)
+	assert.Equal(t, "3", *change.To)
+
+	change = trigger.Match(&models.CI{Attributes: json.RawMessage(`{}`)}, previous)
+	require.NotNil(t, change)
+	assert.Nil(t, change.To, "removed attribute")
+	assert.Nil(t, trigger.Match(&models.CI{Attributes: json.RawMessage(`{"replicas": 2, "zone": "a"}`)}, previous))
+}
+
+func TestEvaluateRunsActionsAsync(t *testing.T) {
+	var received WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	store := newMemoryStore()
+	notifier := &recordingNotifier{err: errors.New("mail server down")}
+	service := NewService(store, notifier, time.Minute)
+	trigger, err := service.CreateTrigger(context.Background(), &Trigger{
+		Name:      "decommission",
+		Attribute: "status",
+		To:        strPtr("decommissioned"),
+		Enabled:   true,
+		Actions: []Action{
+			{Type: ActionNotify, Role: "cmdb-admins"},
+			{Type: ActionWebhook, URL: server.URL},
+			{Type: ActionTag, AddTags: []string{"retired"}},
+			{Type: ActionDeactivateRelationships},
+		},
+	}, "admin")
+	require.NoError(t, err)
+
+	ci := &models.CI{ID: uuid.New(), Type: "server", Status: "decommissioned"}
+	service.Evaluate(context.Background(), ci, &models.CI{ID: ci.ID, Type: "server", Status: "active"})
+	assert.Empty(t, store.executions, "actions wait for a worker")
+	drain(service)
+
+	require.Len(t, store.executions, 4)
+	assert.Equal(t, OutcomeError, store.executions[0].Outcome, "a failing action is recorded")
+	for _, execution := range store.executions[1:] {
+		assert.Equal(t, OutcomeOK, execution.Outcome, execution.Action)
+		assert.Equal(t, trigger.ID, execution.TriggerID)
+	}
+	assert.Equal(t, ci.ID, received.CI.ID)
+	assert.Equal(t, "decommissioned", *received.Change.To)
+	assert.Equal(t, [][]string{{"retired"}}, store.tagChanges)
+	assert.Equal(t, []uuid.UUID{ci.ID}, store.deprecated)
+}
+
+func TestEvaluateSkipsDisabledTriggersAndReloadsOnChange(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, &recordingNotifier{}, time.Hour)
+	trigger, err := service.CreateTrigger(context.Background(), &Trigger{
+		Name: "owner change", Attribute: "owner", Actions: []Action{{Type: ActionNotify, Role: "owners"}},
+	}, "admin")
+	require.NoError(t, err)
+
+	ci := &models.CI{ID: uuid.New(), Owner: "bob"}
+	service.Evaluate(context.Background(), ci, &models.CI{ID: ci.ID, Owner: "alice"})
+	assert.Empty(t, service.jobs)
+
+	trigger.Enabled = true
+	_, err = service.UpdateTrigger(context.Background(), trigger, "admin")
+	require.NoError(t, err)
+	service.Evaluate(context.Background(), ci, &models.CI{ID: ci.ID, Owner: "alice"})
+	assert.Len(t, service.jobs, 1)
+}
@@ -0,0 +1,317 @@
+package attrtrigger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Defaults
+const (
+	// DefaultCacheTTL bounds how long triggers are reused before being
+	// reloaded, so changes made through another instance apply within that delay
+	DefaultCacheTTL = time.Minute
+	// QueueSize is the number of fired triggers waiting for a worker; beyond
+	// it, firings are dropped and logged rather than slowing down saves
+	QueueSize = 1000
+	// DefaultWorkers is the number of workers running actions
+	DefaultWorkers = 4
+)
+
+// job is a fired trigger waiting for its actions to run
+type job struct {
+	trigger *Trigger
+	ci      *models.CI
+	change  Change
+	firedAt time.Time
+}
+
+// Service manages triggers, matches them on CI saves and runs their actions
+type Service struct {
+	store    Store
+	notifier Notifier
+	client   *http.Client
+	cacheTTL time.Duration
+	now      func() time.Time
+	jobs     chan job
+
+	mu       sync.Mutex
+	triggers []*Trigger
+	loadedAt time.Time
+}
+
+// NewService creates a new attribute trigger service. Actions only run once
+// Run is started.
+func NewService(store Store, notifier Notifier, cacheTTL time.Duration) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{
+		store:    store,
+		notifier: notifier,
+		client:   &http.Client{},
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+		jobs:     make(chan job, QueueSize),
+	}
+}
+
+// ListTriggers retrieves every trigger
+func (s *Service) ListTriggers(ctx context.Context) ([]*Trigger, error) {
+	return s.store.ListTriggers(ctx)
+}
+
+// GetTrigger retrieves a trigger
+func (s *Service) GetTrigger(ctx context.Context, id uuid.UUID) (*Trigger, error) {
+	return s.store.GetTrigger(ctx, id)
+}
+
+// CreateTrigger validates and stores a new trigger
+func (s *Service) CreateTrigger(ctx context.Context, trigger *Trigger, by string) (*Trigger, error) {
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	trigger.ID = uuid.New()
+	trigger.CreatedAt = now
+	trigger.UpdatedAt = now
+	trigger.UpdatedBy = by
+	if err := s.store.CreateTrigger(ctx, trigger); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Attribute trigger %s (%s) created by %s", trigger.ID, trigger.Name, by)
+	return trigger, nil
+}
+
+// UpdateTrigger validates and replaces a trigger
+func (s *Service) UpdateTrigger(ctx context.Context, trigger *Trigger, by string) (*Trigger, error) {
+	existing, err := s.store.GetTrigger(ctx, trigger.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+
+	trigger.CreatedAt = existing.CreatedAt
+	trigger.UpdatedAt = s.now()
+	trigger.UpdatedBy = by
+	if err := s.store.UpdateTrigger(ctx, trigger); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Attribute trigger %s (%s) updated by %s", trigger.ID, trigger.Name, by)
+	return trigger, nil
+}
+
+// DeleteTrigger removes a trigger
+func (s *Service) DeleteTrigger(ctx context.Context, id uuid.UUID, by string) error {
+	if err := s.store.DeleteTrigger(ctx, id); err != nil {
+		return err
+	}
+
+	s.invalidate()
+	log.Printf("Attribute trigger %s deleted by %s", id, by)
+	return nil
+}
+
+// ListExecutions retrieves the latest action runs of a trigger
+func (s *Service) ListExecutions(ctx context.Context, triggerID uuid.UUID, outcome string, limit int) ([]Execution, error) {
+	if _, err := s.store.GetTrigger(ctx, triggerID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	return s.store.ListExecutions(ctx, triggerID, outcome, limit)
+}
+
+// Evaluate matches the enabled triggers against a saved CI and queues the
+// actions of those that fire. previous is the stored CI on update and nil on
+// create. It never blocks: when the queue is full the firing is dropped.
+func (s *Service) Evaluate(ctx context.Context, ci *models.CI, previous *models.CI) {
+	triggers, err := s.enabledTriggers(ctx)
+	if err != nil {
+		log.Printf("Skipping attribute triggers for CI %s: %v", ci.ID, err)
+		return
+	}
+
+	var snapshot *models.CI
+	for _, trigger := range triggers {
+		change := trigger.Match(ci, previous)
+		if change == nil {
+			continue
+		}
+		// Actions run after the request returns, so they get their own copy
+		if snapshot == nil {
+			copied := *ci
+			copied.Tags = append([]string(nil), ci.Tags...)
+			copied.Attributes = append([]byte(nil), ci.Attributes...)
+			snapshot = &copied
+		}
+		select {
+		case s.jobs <- job{trigger: trigger, ci: snapshot, change: *change, firedAt: s.now()}:
+		default:
+			log.Printf("Attribute trigger queue full, dropping trigger %s (%s) for CI %s", trigger.ID, trigger.Name, ci.ID)
+		}
+	}
+}
+
+// Run starts workers running the actions of fired triggers and returns once
+// ctx is done. Queued firings left when it stops are not run.
+func (s *Service) Run(ctx context.Context, workers int) {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-s.jobs:
+					s.execute(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// execute runs the actions of a fired trigger in order and records each run.
+// A failing action does not stop the next ones.
+func (s *Service) execute(ctx context.Context, j job) {
+	executions := make([]Execution, 0, len(j.trigger.Actions))
+	for _, action := range j.trigger.Actions {
+		executions = append(executions, s.runAction(ctx, j, action))
+	}
+	if err := s.store.RecordExecutions(ctx, executions); err != nil {
+		log.Printf("Failed to record attribute trigger executions: %v", err)
+	}
+}
+
+// runAction runs one action, containing panics
+func (s *Service) runAction(ctx context.Context, j job, action Action) (execution Execution) {
+	started := s.now()
+	execution = Execution{
+		ID:          uuid.New(),
+		TriggerID:   j.trigger.ID,
+		TriggerName: j.trigger.Name,
+		CIID:        j.ci.ID,
+		Action:      action.Type,
+		Change:      j.change,
+		Outcome:     OutcomeOK,
+		ExecutedAt:  started,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			execution.Outcome = OutcomeError
+			execution.Message = fmt.Sprintf("action panicked: %v", r)
+		}
+		execution.DurationMs = float64(s.now().Sub(started).Microseconds()) / 1000
+	}()
+
+	message, err := s.perform(ctx, j, action)
+	if err != nil {
+		execution.Outcome = OutcomeError
+		execution.Message = err.Error()
+		log.Printf("Attribute trigger %s (%s) failed to run %s action on CI %s: %v", j.trigger.ID, j.trigger.Name, action.Type, j.ci.ID, err)
+		return execution
+	}
+	execution.Message = message
+	return execution
+}
+
+// perform runs an action and describes what it did
+func (s *Service) perform(ctx context.Context, j job, action Action) (string, error) {
+	switch action.Type {
+	case ActionWebhook:
+		payload := WebhookPayload{TriggerID: j.trigger.ID, TriggerName: j.trigger.Name, CI: j.ci, Change: j.change, FiredAt: j.firedAt}
+		if err := callWebhook(ctx, s.client, action.URL, payload); err != nil {
+			return "", err
+		}
+		return "webhook called", nil
+	case ActionTag:
+		if err := s.store.ChangeTags(ctx, j.ci.ID, action.AddTags, action.RemoveTags); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("added tags [%s], removed tags [%s]", strings.Join(action.AddTags, ", "), strings.Join(action.RemoveTags, ", ")), nil
+	case ActionDeactivateRelationships:
+		count, err := s.store.DeprecateRelationships(ctx, j.ci.ID, action.RelationshipTypes)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("deprecated %d relationships", count), nil
+	case ActionNotify:
+		err := s.notifier.Notify(ctx, Notification{
+			TriggerID:   j.trigger.ID,
+			TriggerName: j.trigger.Name,
+			Role:        action.Role,
+			Message:     action.Message,
+			CI:          j.ci,
+			Change:      j.change,
+		})
+		if err != nil {
+			return "", err
+		}
+		return "notified role " + action.Role, nil
+	}
+	return "", fmt.Errorf("unknown action type %q", action.Type)
+}
+
+// enabledTriggers returns the enabled triggers, reloading them once the cache expires
+func (s *Service) enabledTriggers(ctx context.Context) ([]*Trigger, error) {
+	s.mu.Lock()
+	triggers := s.triggers
+	fresh := triggers != nil && s.now().Sub(s.loadedAt) < s.cacheTTL
+	s.mu.Unlock()
+	if fresh {
+		return triggers, nil
+	}
+
+	stored, err := s.store.ListTriggers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	triggers = make([]*Trigger, 0, len(stored))
+	for _, trigger := range stored {
+		if !trigger.Enabled {
+			continue
+		}
+		if err := trigger.Validate(); err != nil {
+			log.Printf("Skipping invalid attribute trigger %s: %v", trigger.ID, err)
+			continue
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	s.mu.Lock()
+	s.triggers = triggers
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+	return triggers, nil
+}
+
+// invalidate drops the cached triggers so the next save reloads them
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.triggers = nil
+	s.mu.Unlock()
+}
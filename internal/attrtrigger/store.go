@@ -0,0 +1,249 @@
+package attrtrigger
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store persists triggers and their execution logs and applies the actions
+// that change stored data
+type Store interface {
+	ListTriggers(ctx context.Context) ([]*Trigger, error)
+	GetTrigger(ctx context.Context, id uuid.UUID) (*Trigger, error)
+	CreateTrigger(ctx context.Context, trigger *Trigger) error
+	UpdateTrigger(ctx context.Context, trigger *Trigger) error
+	DeleteTrigger(ctx context.Context, id uuid.UUID) error
+	RecordExecutions(ctx context.Context, executions []Execution) error
+	// ListExecutions returns the latest action runs of a trigger, newest first
+	ListExecutions(ctx context.Context, triggerID uuid.UUID, outcome string, limit int) ([]Execution, error)
+	// ChangeTags adds and removes tags of a live CI, keeping their order
+	ChangeTags(ctx context.Context, ciID uuid.UUID, add, remove []string) error
+	// DeprecateRelationships deprecates the active relationships of a CI, of
+	// the given types or all, and returns how many it deprecated
+	DeprecateRelationships(ctx context.Context, ciID uuid.UUID, types []string) (int64, error)
+}
+
+// PostgresStore keeps triggers in the attribute_triggers and attribute_trigger_executions tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed trigger store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const triggerColumns = `id, name, ci_types, attribute, from_value, to_value, actions, enabled, created_at, updated_at, COALESCE(updated_by, '')`
+
+// scanTrigger reads a trigger row selected with triggerColumns
+func scanTrigger(row interface{ Scan(...interface{}) error }) (*Trigger, error) {
+	var trigger Trigger
+	var ciTypes pq.StringArray
+	var from, to sql.NullString
+	var actions []byte
+	if err := row.Scan(&trigger.ID, &trigger.Name, &ciTypes, &trigger.Attribute, &from, &to, &actions,
+		&trigger.Enabled, &trigger.CreatedAt, &trigger.UpdatedAt, &trigger.UpdatedBy); err != nil {
+		return nil, err
+	}
+	trigger.CITypes = []string(ciTypes)
+	if from.Valid {
+		trigger.From = &from.String
+	}
+	if to.Valid {
+		trigger.To = &to.String
+	}
+	if err := json.Unmarshal(actions, &trigger.Actions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trigger actions: %w", err)
+	}
+	return &trigger, nil
+}
+
+// ListTriggers retrieves every trigger
+func (s *PostgresStore) ListTriggers(ctx context.Context) ([]*Trigger, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+triggerColumns+` FROM attribute_triggers ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attribute triggers: %w", err)
+	}
+	defer rows.Close()
+
+	triggers := []*Trigger{}
+	for rows.Next() {
+		trigger, err := scanTrigger(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attribute trigger: %w", err)
+		}
+		triggers = append(triggers, trigger)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list attribute triggers: %w", err)
+	}
+	return triggers, nil
+}
+
+// GetTrigger retrieves a trigger
+func (s *PostgresStore) GetTrigger(ctx context.Context, id uuid.UUID) (*Trigger, error) {
+	trigger, err := scanTrigger(s.db.QueryRowContext(ctx, `SELECT `+triggerColumns+` FROM attribute_triggers WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTriggerNotFound
+		}
+		return nil, fmt.Errorf("failed to get attribute trigger: %w", err)
+	}
+	return trigger, nil
+}
+
+// CreateTrigger inserts a trigger
+func (s *PostgresStore) CreateTrigger(ctx context.Context, trigger *Trigger) error {
+	actions, err := json.Marshal(trigger.Actions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trigger actions: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO attribute_triggers (id, name, ci_types, attribute, from_value, to_value, actions, enabled, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		trigger.ID, trigger.Name, pq.Array(trigger.CITypes), trigger.Attribute, trigger.From, trigger.To, actions,
+		trigger.Enabled, trigger.CreatedAt, trigger.UpdatedAt, trigger.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create attribute trigger: %w", err)
+	}
+	return nil
+}
+
+// UpdateTrigger replaces a trigger
+func (s *PostgresStore) UpdateTrigger(ctx context.Context, trigger *Trigger) error {
+	actions, err := json.Marshal(trigger.Actions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trigger actions: %w", err)
+	}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE attribute_triggers
+		SET name = $2, ci_types = $3, attribute = $4, from_value = $5, to_value = $6, actions = $7,
+		    enabled = $8, updated_at = $9, updated_by = $10
+		WHERE id = $1`,
+		trigger.ID, trigger.Name, pq.Array(trigger.CITypes), trigger.Attribute, trigger.From, trigger.To, actions,
+		trigger.Enabled, trigger.UpdatedAt, trigger.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update attribute trigger: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrTriggerNotFound
+	}
+	return nil
+}
+
+// DeleteTrigger removes a trigger along with its execution log
+func (s *PostgresStore) DeleteTrigger(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM attribute_triggers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attribute trigger: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrTriggerNotFound
+	}
+	return nil
+}
+
+// RecordExecutions appends action runs to the execution log
+func (s *PostgresStore) RecordExecutions(ctx context.Context, executions []Execution) error {
+	for _, execution := range executions {
+		_, err := s.db.ExecContext(ctx, `
+			INSERT INTO attribute_trigger_executions
+				(id, trigger_id, trigger_name, ci_id, action, attribute, from_value, to_value, outcome, message, duration_ms, executed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12)`,
+			execution.ID, execution.TriggerID, execution.TriggerName, execution.CIID, execution.Action,
+			execution.Change.Attribute, execution.Change.From, execution.Change.To,
+			execution.Outcome, execution.Message, execution.DurationMs, execution.ExecutedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record attribute trigger execution: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListExecutions retrieves the latest action runs of a trigger, optionally by outcome
+func (s *PostgresStore) ListExecutions(ctx context.Context, triggerID uuid.UUID, outcome string, limit int) ([]Execution, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, trigger_id, trigger_name, ci_id, action, attribute, from_value, to_value,
+		       outcome, COALESCE(message, ''), duration_ms, executed_at
+		FROM attribute_trigger_executions
+		WHERE trigger_id = $1 AND ($2 = '' OR outcome = $2)
+		ORDER BY executed_at DESC
+		LIMIT $3`, triggerID, outcome, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attribute trigger executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions := []Execution{}
+	for rows.Next() {
+		var execution Execution
+		var from, to sql.NullString
+		if err := rows.Scan(&execution.ID, &execution.TriggerID, &execution.TriggerName, &execution.CIID, &execution.Action,
+			&execution.Change.Attribute, &from, &to, &execution.Outcome, &execution.Message,
+			&execution.DurationMs, &execution.ExecutedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute trigger execution: %w", err)
+		}
+		if from.Valid {
+			execution.Change.From = &from.String
+		}
+		if to.Valid {
+			execution.Change.To = &to.String
+		}
+		executions = append(executions, execution)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list attribute trigger executions: %w", err)
+	}
+	return executions, nil
+}
+
+// ChangeTags adds and removes tags of a live CI in one statement, so
+// concurrent tag changes are not lost
+func (s *PostgresStore) ChangeTags(ctx context.Context, ciID uuid.UUID, add, remove []string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE configuration_items SET
+			tags = ARRAY(
+				SELECT tag FROM unnest(COALESCE(tags, '{}') || COALESCE($2::text[], '{}')) WITH ORDINALITY AS t(tag, position)
+				WHERE NOT tag = ANY(COALESCE($3::text[], '{}'))
+				GROUP BY tag
+				ORDER BY MIN(position)),
+			updated_at = $4
+		WHERE id = $1 AND is_deleted = false`,
+		ciID, pq.Array(add), pq.Array(remove), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to change CI tags: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("CI %s not found", ciID)
+	}
+	return nil
+}
+
+// DeprecateRelationships deprecates the active relationships of a CI,
+// clearing their primary flag as deprecating through the API does
+func (s *PostgresStore) DeprecateRelationships(ctx context.Context, ciID uuid.UUID, types []string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE ci_relationships SET
+			state = 'deprecated',
+			state_changed_at = $3,
+			is_primary = false,
+			updated_at = $3
+		WHERE (source_ci_id = $1 OR target_ci_id = $1) AND state = 'active'
+		  AND (COALESCE(cardinality($2::text[]), 0) = 0 OR type = ANY($2::text[]))`,
+		ciID, pq.Array(types), time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to deprecate relationships: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to deprecate relationships: %w", err)
+	}
+	return count, nil
+}
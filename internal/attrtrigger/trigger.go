@@ -0,0 +1,228 @@
+// Package attrtrigger fires admin-defined actions when an attribute of a CI
+// changes, e.g. when status moves to decommissioned. Triggers are matched in
+// the write path, which only compares the stored and saved CI; their actions
+// (calling a webhook, changing tags, deprecating relationships or notifying a
+// role) run on background workers, so a slow or failing action never delays
+// or fails a save. Every action run is recorded in the trigger's execution log.
+package attrtrigger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Action types
+const (
+	ActionWebhook                 = "webhook"
+	ActionTag                     = "tag"
+	ActionDeactivateRelationships = "deactivate_relationships"
+	ActionNotify                  = "notify"
+)
+
+// Execution outcomes
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// AttributesPrefix selects a key of the CI's custom attributes instead of one
+// of its fields, e.g. attributes.environment
+const AttributesPrefix = "attributes."
+
+var (
+	ErrInvalidTrigger  = errors.New("invalid attribute trigger")
+	ErrTriggerNotFound = errors.New("attribute trigger not found")
+)
+
+// ciFields are the CI fields a trigger can watch besides custom attributes
+var ciFields = map[string]func(ci *models.CI) string{
+	"name":        func(ci *models.CI) string { return ci.Name },
+	"type":        func(ci *models.CI) string { return ci.Type },
+	"description": func(ci *models.CI) string { return ci.Description },
+	"status":      func(ci *models.CI) string { return ci.Status },
+	"criticality": func(ci *models.CI) string { return ci.Criticality },
+	"owner":       func(ci *models.CI) string { return ci.Owner },
+	"location":    func(ci *models.CI) string { return ci.Location },
+	"org_unit":    func(ci *models.CI) string { return ci.OrgUnit },
+	"cost_center": func(ci *models.CI) string { return ci.CostCenter },
+	"is_active":   func(ci *models.CI) string { return fmt.Sprint(ci.IsActive) },
+}
+
+// Trigger fires its actions when the watched attribute of a matching CI
+// changes. From and To narrow it to changes from and to given values; a CI
+// created with the attribute set counts as a change from no value, which
+// only matches when From is unset.
+type Trigger struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// CITypes restricts the trigger to these CI types; empty means every type
+	CITypes []string `json:"ci_types"`
+	// Attribute is a CI field such as status, or attributes.<key>
+	Attribute string    `json:"attribute"`
+	From      *string   `json:"from,omitempty"`
+	To        *string   `json:"to,omitempty"`
+	Actions   []Action  `json:"actions"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by"`
+}
+
+// Action is one thing a trigger does when it fires. Only the fields of its
+// type are used.
+type Action struct {
+	Type string `json:"type"`
+	// URL receives a POST with the CI and the change, for webhook actions
+	URL string `json:"url,omitempty"`
+	// AddTags and RemoveTags change the CI's tags, for tag actions
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	// RelationshipTypes limits which active relationships of the CI are
+	// deprecated, for deactivate_relationships actions; empty means all
+	RelationshipTypes []string `json:"relationship_types,omitempty"`
+	// Role and Message are the recipients and text of notify actions
+	Role    string `json:"role,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Validate checks the trigger and its actions
+func (t *Trigger) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTrigger)
+	}
+	t.Attribute = strings.TrimSpace(t.Attribute)
+	if _, ok := ciFields[t.Attribute]; !ok {
+		if !strings.HasPrefix(t.Attribute, AttributesPrefix) || len(t.Attribute) == len(AttributesPrefix) {
+			return fmt.Errorf("%w: attribute must be a CI field or %s<key>", ErrInvalidTrigger, AttributesPrefix)
+		}
+	}
+	if len(t.Actions) == 0 {
+		return fmt.Errorf("%w: at least one action is required", ErrInvalidTrigger)
+	}
+	for i := range t.Actions {
+		if err := t.Actions[i].validate(); err != nil {
+			return fmt.Errorf("%w: action %d: %v", ErrInvalidTrigger, i+1, err)
+		}
+	}
+	if t.CITypes == nil {
+		t.CITypes = []string{}
+	}
+	return nil
+}
+
+func (a *Action) validate() error {
+	switch a.Type {
+	case ActionWebhook:
+		parsed, err := url.Parse(a.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("url must be an http or https URL")
+		}
+	case ActionTag:
+		if len(a.AddTags) == 0 && len(a.RemoveTags) == 0 {
+			return errors.New("add_tags or remove_tags is required")
+		}
+	case ActionDeactivateRelationships:
+	case ActionNotify:
+		if strings.TrimSpace(a.Role) == "" {
+			return errors.New("role is required")
+		}
+	default:
+		return fmt.Errorf("type must be one of %s, %s, %s or %s", ActionWebhook, ActionTag, ActionDeactivateRelationships, ActionNotify)
+	}
+	return nil
+}
+
+// AppliesTo reports whether the trigger watches a CI type
+func (t *Trigger) AppliesTo(ciType string) bool {
+	if len(t.CITypes) == 0 {
+		return true
+	}
+	for _, ciT := range t.CITypes {
+		if ciT == ciType {
+			return true
+		}
+	}
+	return false
+}
+
+// Change is a change of a watched attribute. From is nil when the attribute
+// had no value, To when it was removed.
+type Change struct {
+	Attribute string  `json:"attribute"`
+	From      *string `json:"from"`
+	To        *string `json:"to"`
+}
+
+// Match returns the change a save makes to the trigger's attribute, or nil
+// when it does not fire. previous is the stored CI on update and nil on create.
+func (t *Trigger) Match(ci, previous *models.CI) *Change {
+	if !t.AppliesTo(ci.Type) {
+		return nil
+	}
+	to := attributeValue(ci, t.Attribute)
+	var from *string
+	if previous != nil {
+		from = attributeValue(previous, t.Attribute)
+	}
+	if equalValues(from, to) || !matchesValue(t.From, from) || !matchesValue(t.To, to) {
+		return nil
+	}
+	return &Change{Attribute: t.Attribute, From: from, To: to}
+}
+
+// attributeValue returns the text of a CI field or custom attribute, or nil
+// when a custom attribute is not set. Attributes other than strings compare
+// by their JSON text.
+func attributeValue(ci *models.CI, attribute string) *string {
+	if field, ok := ciFields[attribute]; ok {
+		value := field(ci)
+		return &value
+	}
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(ci.Attributes, &attributes); err != nil {
+		return nil
+	}
+	raw, ok := attributes[strings.TrimPrefix(attribute, AttributesPrefix)]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		text = string(raw)
+	}
+	return &text
+}
+
+func equalValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// matchesValue reports whether a value satisfies an optional condition
+func matchesValue(want, value *string) bool {
+	return want == nil || (value != nil && *value == *want)
+}
+
+// Execution is the log entry of one action run
+type Execution struct {
+	ID          uuid.UUID `json:"id"`
+	TriggerID   uuid.UUID `json:"trigger_id"`
+	TriggerName string    `json:"trigger_name"`
+	CIID        uuid.UUID `json:"ci_id"`
+	Action      string    `json:"action"`
+	Change      Change    `json:"change"`
+	Outcome     string    `json:"outcome"`
+	Message     string    `json:"message,omitempty"`
+	DurationMs  float64   `json:"duration_ms"`
+	ExecutedAt  time.Time `json:"executed_at"`
+}
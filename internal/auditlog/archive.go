@@ -0,0 +1,123 @@
+package auditlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Archiver stores files of archived entries
+type Archiver interface {
+	// Write stores the entries under the key as gzip compressed NDJSON
+	Write(ctx context.Context, key string, entries []Entry) error
+	// Read calls fn with each entry stored under the key, in order
+	Read(ctx context.Context, key string, fn func(*Entry) error) error
+}
+
+// DirArchiver keeps archives in a directory, such as an object storage bucket
+// mounted on the host
+type DirArchiver struct {
+	root string
+}
+
+// NewDirArchiver creates an archiver writing below the root directory
+func NewDirArchiver(root string) *DirArchiver {
+	return &DirArchiver{root: root}
+}
+
+// path resolves a key below the root, refusing keys that escape it
+func (a *DirArchiver) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid archive key %q", key)
+	}
+	return filepath.Join(a.root, clean), nil
+}
+
+// Write writes the archive to a temporary file and renames it into place, so a
+// failed write never leaves a partial archive under the key
+func (a *DirArchiver) Write(ctx context.Context, key string, entries []Entry) error {
+	path, err := a.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	for i := range entries {
+		if err := encoder.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to store archive: %w", err)
+	}
+	return nil
+}
+
+// Read decodes the archive line by line
+func (a *DirArchiver) Read(ctx context.Context, key string, fn func(*Entry) error) error {
+	path, err := a.path(key)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	defer gz.Close()
+
+	reader := bufio.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var entry Entry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return fmt.Errorf("failed to decode archive %s: %w", key, err)
+			}
+			if err := fn(&entry); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", key, err)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	entries  []Entry
+	policies map[uuid.UUID]*RetentionPolicy
+	holds    map[uuid.UUID]*LegalHold
+	archives []Archive
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{policies: map[uuid.UUID]*RetentionPolicy{}, holds: map[uuid.UUID]*LegalHold{}}
+}
+
+func (m *memoryStore) add(entityType string, entityID uuid.UUID, action string, at time.Time) Entry {
+	entry := Entry{ID: uuid.New(), EntityType: entityType, EntityID: &entityID, Action: action, ChangedAt: at, Details: json.RawMessage(`{}`)}
+	m.entries = append(m.entries, entry)
+	sort.SliceStable(m.entries, func(i, j int) bool { return m.entries[i].ChangedAt.Before(m.entries[j].ChangedAt) })
+	return entry
+}
+
+func (m *memoryStore) Stream(ctx context.Context, filter Filter, fn func(*Entry) error) error {
+	for _, entry := range m.entries {
+		entry := entry
+		if filter.Matches(&entry) {
+			if err := fn(&entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) ExpiredEntries(ctx context.Context, entityType string, exclude []string, before time.Time, limit int) ([]Entry, error) {
+	expired := []Entry{}
+	for _, entry := range m.entries {
+		entry := entry
+		if !entry.ChangedAt.Before(before) || (entityType != "" && entry.EntityType != entityType) {
+			continue
+		}
+		excluded := false
+		for _, t := range exclude {
+			excluded = excluded || t == entry.EntityType
+		}
+		for _, hold := range m.holds {
+			excluded = excluded || hold.Covers(&entry)
+		}
+		if !excluded && len(expired) < limit {
+			expired = append(expired, entry)
+		}
+	}
+	return expired, nil
+}
+
+func (m *memoryStore) DeleteEntries(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	remove := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		remove[id] = true
+	}
+	kept := m.entries[:0]
+	for _, entry := range m.entries {
+		if !remove[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	deleted := int64(len(m.entries) - len(kept))
+	m.entries = kept
+	return deleted, nil
+}
+
+func (m *memoryStore) ListPolicies(ctx context.Context) ([]*RetentionPolicy, error) {
+	policies := []*RetentionPolicy{}
+	for _, policy := range m.policies {
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func (m *memoryStore) GetPolicy(ctx context.Context, id uuid.UUID) (*RetentionPolicy, error) {
+	policy, ok := m.policies[id]
+	if !ok {
+		return nil, ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (m *memoryStore) CreatePolicy(ctx context.Context, policy *RetentionPolicy) error {
+	m.policies[policy.ID] = policy
+	return nil
+}
+
+func (m *memoryStore) UpdatePolicy(ctx context.Context, policy *RetentionPolicy) error {
+	if _, ok := m.policies[policy.ID]; !ok {
+		return ErrPolicyNotFound
+	}
+	m.policies[policy.ID] = policy
+	return nil
+}
+
+func (m *memoryStore) DeletePolicy(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.policies[id]; !ok {
+		return ErrPolicyNotFound
+	}
+	delete(m.policies, id)
+	return nil
+}
+
+func (m *memoryStore) ListHolds(ctx context.Context, activeOnly bool) ([]*LegalHold, error) {
+	holds := []*LegalHold{}
+	for _, hold := range m.holds {
+		if !activeOnly || hold.Active() {
+			holds = append(holds, hold)
+		}
+	}
+	return holds, nil
+}
+
+func (m *memoryStore) GetHold(ctx context.Context, id uuid.UUID) (*LegalHold, error) {
+	hold, ok := m.holds[id]
+	if !ok {
+		return nil, ErrHoldNotFound
+	}
+	return hold, nil
+}
+
+func (m *memoryStore) CreateHold(ctx context.Context, hold *LegalHold) error {
+	m.holds[hold.ID] = hold
+	return nil
+}
+
+func (m *memoryStore) ReleaseHold(ctx context.Context, id uuid.UUID, at time.Time, by string) error {
+	hold, ok := m.holds[id]
+	if !ok || !hold.Active() {
+		return ErrHoldNotFound
+	}
+	hold.ReleasedAt = &at
+	hold.ReleasedBy = by
+	return nil
+}
+
+func (m *memoryStore) CreateArchive(ctx context.Context, archive *Archive) error {
+	m.archives = append(m.archives, *archive)
+	return nil
+}
+
+func (m *memoryStore) ListArchives(ctx context.Context, from, to time.Time) ([]Archive, error) {
+	archives := []Archive{}
+	for _, archive := range m.archives {
+		if (from.IsZero() || !archive.To.Before(from)) && (to.IsZero() || archive.From.Before(to)) {
+			archives = append(archives, archive)
+		}
+	}
+	return archives, nil
+}
+
+var now = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newService(t *testing.T) (*Service, *memoryStore) {
+	store := newMemoryStore()
+	service := NewService(store, NewDirArchiver(t.TempDir()))
+	service.now = func() time.Time { return now }
+	return service, store
+}
+
+func export(t *testing.T, service *Service, format string, filter Filter) (string, int) {
+	var buf bytes.Buffer
+	encoder, err := NewEncoder(format, &buf)
+	require.NoError(t, err)
+	count, err := service.Export(context.Background(), filter, encoder)
+	require.NoError(t, err)
+	return buf.String(), count
+}
+
+func TestExport(t *testing.T) {
+	service, store := newService(t)
+	ci := uuid.New()
+	store.add("ci", ci, "create", now.AddDate(0, -2, 0))
+	store.add("ci", ci, "update", now.AddDate(0, -1, 0))
+	store.add("user", uuid.New(), "login", now.AddDate(0, -1, 0))
+
+	out, count := export(t, service, FormatNDJSON, Filter{From: now.AddDate(-1, 0, 0), To: now, EntityType: "ci"})
+	assert.Equal(t, 2, count)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	var entry Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "create", entry.Action)
+
+	out, count = export(t, service, FormatCSV, Filter{From: now.AddDate(0, -1, 0), To: now})
+	assert.Equal(t, 2, count)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, csvHeader, records[0])
+
+	// Empty CSV exports still carry the header
+	out, count = export(t, service, FormatCSV, Filter{From: now, To: now.Add(time.Hour)})
+	assert.Equal(t, 0, count)
+	assert.Equal(t, strings.Join(csvHeader, ",")+"\n", out)
+
+	_, err = service.Export(context.Background(), Filter{From: now}, &ndjsonEncoder{})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	_, err = NewEncoder("xml", &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestPolicies(t *testing.T) {
+	service, _ := newService(t)
+	ctx := context.Background()
+
+	all, err := service.CreatePolicy(ctx, &RetentionPolicy{Name: "default", RetainDays: 365, Enabled: true}, "admin")
+	require.NoError(t, err)
+	_, err = service.CreatePolicy(ctx, &RetentionPolicy{Name: "sessions", EntityType: "session", RetainDays: 90, Enabled: true}, "admin")
+	require.NoError(t, err)
+
+	_, err = service.CreatePolicy(ctx, &RetentionPolicy{Name: "other", RetainDays: 30}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+	_, err = service.CreatePolicy(ctx, &RetentionPolicy{Name: "short", EntityType: "ci", RetainDays: 0}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+
+	updated, err := service.UpdatePolicy(ctx, all.ID, &RetentionPolicy{Name: "default", RetainDays: 730, Archive: true, Enabled: true}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, 730, updated.RetainDays)
+	assert.Equal(t, all.CreatedAt, updated.CreatedAt)
+
+	_, err = service.UpdatePolicy(ctx, uuid.New(), &RetentionPolicy{Name: "x", RetainDays: 1}, "admin")
+	assert.ErrorIs(t, err, ErrPolicyNotFound)
+
+	withoutArchive := NewService(newMemoryStore(), nil)
+	_, err = withoutArchive.CreatePolicy(ctx, &RetentionPolicy{Name: "archived", RetainDays: 30, Archive: true}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+}
+
+func TestEnforce(t *testing.T) {
+	service, store := newService(t)
+	ctx := context.Background()
+
+	held := uuid.New()
+	other := uuid.New()
+	oldHeld := store.add("ci", held, "update", now.AddDate(-3, 0, 0))
+	oldOther := store.add("ci", other, "update", now.AddDate(-3, 0, 0).Add(time.Hour))
+	recent := store.add("ci", other, "update", now.AddDate(0, -1, 0))
+	oldSession := store.add("session", uuid.New(), "login", now.AddDate(0, -6, 0))
+	newSession := store.add("session", uuid.New(), "login", now.AddDate(0, 0, -10))
+
+	_, err := service.CreatePolicy(ctx, &RetentionPolicy{Name: "default", RetainDays: 365, Archive: true, Enabled: true}, "admin")
+	require.NoError(t, err)
+	_, err = service.CreatePolicy(ctx, &RetentionPolicy{Name: "sessions", EntityType: "session", RetainDays: 90, Enabled: true}, "admin")
+	require.NoError(t, err)
+	hold, err := service.CreateHold(ctx, &LegalHold{Name: "case-42", Reason: "litigation", EntityType: "ci", EntityID: &held}, "legal")
+	require.NoError(t, err)
+
+	report, err := service.Enforce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementCompleted, report.Status)
+	require.Len(t, report.Policies, 2)
+
+	ids := map[uuid.UUID]bool{}
+	for _, entry := range store.entries {
+		ids[entry.ID] = true
+	}
+	assert.True(t, ids[oldHeld.ID], "held entries are kept")
+	assert.False(t, ids[oldOther.ID], "expired entries are removed")
+	assert.True(t, ids[recent.ID])
+	assert.False(t, ids[oldSession.ID], "the session policy overrides the default")
+	assert.True(t, ids[newSession.ID])
+	require.Len(t, store.archives, 1, "sessions are deleted without archiving")
+	assert.Equal(t, 1, store.archives[0].EntryCount)
+
+	// Archived entries can still be exported on demand
+	filter := Filter{From: now.AddDate(-4, 0, 0), To: now, EntityType: "ci"}
+	_, count := export(t, service, FormatNDJSON, filter)
+	assert.Equal(t, 2, count)
+	filter.IncludeArchived = true
+	out, count := export(t, service, FormatNDJSON, filter)
+	assert.Equal(t, 3, count)
+	var first Entry
+	require.NoError(t, json.Unmarshal([]byte(strings.SplitN(out, "\n", 2)[0]), &first))
+	assert.Equal(t, oldOther.ID, first.ID)
+	assert.True(t, first.Archived)
+
+	// Released holds no longer protect their entries
+	_, err = service.ReleaseHold(ctx, hold.ID, "legal")
+	require.NoError(t, err)
+	_, err = service.ReleaseHold(ctx, hold.ID, "legal")
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+	_, err = service.Enforce(ctx)
+	require.NoError(t, err)
+	assert.Len(t, store.entries, 2)
+	assert.Len(t, store.archives, 2)
+	assert.Equal(t, EnforcementCompleted, service.LastEnforcement().Status)
+}
+
+func TestLegalHold(t *testing.T) {
+	from := now.AddDate(-1, 0, 0)
+	to := now
+	hold := LegalHold{Name: "audit", Reason: "regulator", From: &from, To: &to}
+	require.NoError(t, hold.Validate())
+
+	inside := Entry{EntityType: "ci", ChangedAt: now.AddDate(0, -1, 0)}
+	outside := Entry{EntityType: "ci", ChangedAt: now.AddDate(-2, 0, 0)}
+	assert.True(t, hold.Covers(&inside))
+	assert.False(t, hold.Covers(&outside))
+
+	hold.ReleasedAt = &now
+	assert.False(t, hold.Covers(&inside))
+
+	assert.ErrorIs(t, (&LegalHold{Name: "x"}).Validate(), ErrInvalidHold)
+	assert.ErrorIs(t, (&LegalHold{Name: "x", Reason: "y", From: &to, To: &from}).Validate(), ErrInvalidHold)
+}
+
+func TestDirArchiver_RejectsEscapingKeys(t *testing.T) {
+	archiver := NewDirArchiver(t.TempDir())
+	assert.Error(t, archiver.Write(context.Background(), "../outside.ndjson.gz", nil))
+	assert.Error(t, archiver.Write(context.Background(), "/etc/outside.ndjson.gz", nil))
+}
@@ -0,0 +1,107 @@
+package auditlog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxRetainDays bounds retention periods to 100 years
+const MaxRetainDays = 36500
+
+// RetentionPolicy keeps the audit entries of an entity type for a number of
+// days. A policy without an entity type covers every type without a policy of
+// its own. Expired entries are archived first when Archive is set, otherwise
+// they are deleted.
+type RetentionPolicy struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	EntityType string    `json:"entity_type,omitempty" db:"entity_type"`
+	RetainDays int       `json:"retain_days" db:"retain_days"`
+	Archive    bool      `json:"archive" db:"archive"`
+	Enabled    bool      `json:"enabled" db:"enabled"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	UpdatedBy  string    `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// Validate checks the policy
+func (p *RetentionPolicy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPolicy)
+	}
+	if p.RetainDays < 1 || p.RetainDays > MaxRetainDays {
+		return fmt.Errorf("%w: retain_days must be between 1 and %d", ErrInvalidPolicy, MaxRetainDays)
+	}
+	return nil
+}
+
+// Cutoff returns the time before which entries have expired under the policy
+func (p *RetentionPolicy) Cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -p.RetainDays)
+}
+
+// LegalHold exempts the audit entries it covers from retention until it is
+// released. Unset fields match everything, so a hold on an entity type alone
+// covers its whole history.
+type LegalHold struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Reason     string     `json:"reason" db:"reason"`
+	EntityType string     `json:"entity_type,omitempty" db:"entity_type"`
+	EntityID   *uuid.UUID `json:"entity_id,omitempty" db:"entity_id"`
+	From       *time.Time `json:"from,omitempty" db:"from_time"`
+	To         *time.Time `json:"to,omitempty" db:"to_time"`
+	CreatedBy  string     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty" db:"released_at"`
+	ReleasedBy string     `json:"released_by,omitempty" db:"released_by"`
+}
+
+// Validate checks the hold
+func (h *LegalHold) Validate() error {
+	if h.Name == "" || h.Reason == "" {
+		return fmt.Errorf("%w: name and reason are required", ErrInvalidHold)
+	}
+	if h.From != nil && h.To != nil && !h.From.Before(*h.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidHold)
+	}
+	return nil
+}
+
+// Active reports whether the hold has not been released
+func (h *LegalHold) Active() bool {
+	return h.ReleasedAt == nil
+}
+
+// Covers reports whether the hold, while active, exempts an entry
+func (h *LegalHold) Covers(entry *Entry) bool {
+	if !h.Active() {
+		return false
+	}
+	if h.EntityType != "" && h.EntityType != entry.EntityType {
+		return false
+	}
+	if h.EntityID != nil && (entry.EntityID == nil || *entry.EntityID != *h.EntityID) {
+		return false
+	}
+	if h.From != nil && entry.ChangedAt.Before(*h.From) {
+		return false
+	}
+	if h.To != nil && !entry.ChangedAt.Before(*h.To) {
+		return false
+	}
+	return true
+}
+
+// Archive is a file of archived entries
+type Archive struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Key        string    `json:"key" db:"archive_key"`
+	PolicyID   uuid.UUID `json:"policy_id" db:"policy_id"`
+	From       time.Time `json:"from" db:"from_time"` // earliest entry
+	To         time.Time `json:"to" db:"to_time"`     // latest entry
+	EntryCount int       `json:"entry_count" db:"entry_count"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
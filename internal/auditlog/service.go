@@ -0,0 +1,419 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// enforceBatchSize bounds the entries archived and deleted at a time
+const enforceBatchSize = 5000
+
+// Enforcement statuses
+const (
+	EnforcementRunning   = "running"
+	EnforcementCompleted = "completed"
+	EnforcementFailed    = "failed"
+)
+
+// PolicyResult is what an enforcement run did under one policy
+type PolicyResult struct {
+	PolicyID   uuid.UUID `json:"policy_id"`
+	Name       string    `json:"name"`
+	EntityType string    `json:"entity_type,omitempty"`
+	Cutoff     time.Time `json:"cutoff"`
+	Archives   int       `json:"archives"`
+	Archived   int       `json:"archived"`
+	Deleted    int64     `json:"deleted"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// EnforcementReport is the outcome of a retention enforcement run
+type EnforcementReport struct {
+	Status      string         `json:"status"`
+	StartedAt   time.Time      `json:"started_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	Policies    []PolicyResult `json:"policies"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// Service exports the audit log and enforces its retention policies
+type Service struct {
+	store    Store
+	archiver Archiver
+	now      func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	last    *EnforcementReport
+}
+
+// NewService creates a new audit log service. Without an archiver, policies
+// cannot archive and archived entries cannot be exported.
+func NewService(store Store, archiver Archiver) *Service {
+	return &Service{store: store, archiver: archiver, now: time.Now}
+}
+
+// Export writes the entries passing the filter with the encoder and returns
+// how many were written. Archived entries, when included, come first, oldest
+// archive first; live entries follow, oldest first.
+func (s *Service) Export(ctx context.Context, filter Filter, encoder Encoder) (int, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	write := func(entry *Entry) error {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write audit log entry: %w", err)
+		}
+		count++
+		return nil
+	}
+
+	if filter.IncludeArchived && s.archiver != nil {
+		archives, err := s.store.ListArchives(ctx, filter.From, filter.To)
+		if err != nil {
+			return count, err
+		}
+		for _, archive := range archives {
+			err := s.archiver.Read(ctx, archive.Key, func(entry *Entry) error {
+				if !filter.Matches(entry) {
+					return nil
+				}
+				entry.Archived = true
+				return write(entry)
+			})
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+
+	if err := s.store.Stream(ctx, filter, write); err != nil {
+		return count, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return count, fmt.Errorf("failed to write audit log export: %w", err)
+	}
+	return count, nil
+}
+
+// ListPolicies retrieves every retention policy
+func (s *Service) ListPolicies(ctx context.Context) ([]*RetentionPolicy, error) {
+	return s.store.ListPolicies(ctx)
+}
+
+// GetPolicy retrieves a retention policy
+func (s *Service) GetPolicy(ctx context.Context, id uuid.UUID) (*RetentionPolicy, error) {
+	return s.store.GetPolicy(ctx, id)
+}
+
+// CreatePolicy validates and stores a new retention policy
+func (s *Service) CreatePolicy(ctx context.Context, policy *RetentionPolicy, by string) (*RetentionPolicy, error) {
+	if err := s.checkPolicy(ctx, policy, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	policy.ID = uuid.New()
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+	policy.UpdatedBy = by
+	if err := s.store.CreatePolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdatePolicy validates and replaces a retention policy
+func (s *Service) UpdatePolicy(ctx context.Context, id uuid.UUID, policy *RetentionPolicy, by string) (*RetentionPolicy, error) {
+	existing, err := s.store.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPolicy(ctx, policy, id); err != nil {
+		return nil, err
+	}
+
+	policy.ID = id
+	policy.CreatedAt = existing.CreatedAt
+	policy.UpdatedAt = s.now()
+	policy.UpdatedBy = by
+	if err := s.store.UpdatePolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeletePolicy removes a retention policy
+func (s *Service) DeletePolicy(ctx context.Context, id uuid.UUID) error {
+	return s.store.DeletePolicy(ctx, id)
+}
+
+// checkPolicy validates a policy and makes sure no other policy covers its entity type
+func (s *Service) checkPolicy(ctx context.Context, policy *RetentionPolicy, id uuid.UUID) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if policy.Archive && s.archiver == nil {
+		return fmt.Errorf("%w: no audit archive is configured", ErrInvalidPolicy)
+	}
+
+	policies, err := s.store.ListPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range policies {
+		if other.ID != id && other.EntityType == policy.EntityType {
+			if policy.EntityType == "" {
+				return fmt.Errorf("%w: policy %q already covers every entity type", ErrInvalidPolicy, other.Name)
+			}
+			return fmt.Errorf("%w: policy %q already covers %s", ErrInvalidPolicy, other.Name, policy.EntityType)
+		}
+	}
+	return nil
+}
+
+// ListHolds retrieves the legal holds, or only the active ones
+func (s *Service) ListHolds(ctx context.Context, activeOnly bool) ([]*LegalHold, error) {
+	return s.store.ListHolds(ctx, activeOnly)
+}
+
+// CreateHold validates and places a legal hold
+func (s *Service) CreateHold(ctx context.Context, hold *LegalHold, by string) (*LegalHold, error) {
+	if err := hold.Validate(); err != nil {
+		return nil, err
+	}
+
+	hold.ID = uuid.New()
+	hold.CreatedBy = by
+	hold.CreatedAt = s.now()
+	hold.ReleasedAt = nil
+	hold.ReleasedBy = ""
+	if err := s.store.CreateHold(ctx, hold); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Legal hold %q placed on audit log by %s", hold.Name, by)
+	return hold, nil
+}
+
+// ReleaseHold releases an active legal hold. Entries it covered expire again
+// at the next enforcement run.
+func (s *Service) ReleaseHold(ctx context.Context, id uuid.UUID, by string) (*LegalHold, error) {
+	if err := s.store.ReleaseHold(ctx, id, s.now(), by); err != nil {
+		return nil, err
+	}
+
+	hold, err := s.store.GetHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Legal hold %q on audit log released by %s", hold.Name, by)
+	return hold, nil
+}
+
+// ListArchives retrieves the archives holding entries in a time range
+func (s *Service) ListArchives(ctx context.Context, from, to time.Time) ([]Archive, error) {
+	return s.store.ListArchives(ctx, from, to)
+}
+
+// Enforce applies every enabled retention policy and returns the report
+func (s *Service) Enforce(ctx context.Context) (*EnforcementReport, error) {
+	if !s.begin() {
+		return nil, ErrEnforcementRunning
+	}
+	defer s.end()
+	return s.enforce(ctx), nil
+}
+
+// StartEnforcement applies the retention policies in the background. Poll
+// LastEnforcement for the report.
+func (s *Service) StartEnforcement() error {
+	if !s.begin() {
+		return ErrEnforcementRunning
+	}
+	go func() {
+		defer s.end()
+		s.enforce(context.Background())
+	}()
+	return nil
+}
+
+// LastEnforcement returns the report of the running or latest enforcement run
+func (s *Service) LastEnforcement() *EnforcementReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil {
+		return nil
+	}
+	report := *s.last
+	report.Policies = append([]PolicyResult(nil), s.last.Policies...)
+	return &report
+}
+
+// Run applies the retention policies at each interval
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.begin() {
+				s.enforce(ctx)
+				s.end()
+			}
+		}
+	}
+}
+
+// begin marks an enforcement run as running, reporting false when one already is
+func (s *Service) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return false
+	}
+	s.running = true
+	s.last = &EnforcementReport{Status: EnforcementRunning, StartedAt: s.now(), Policies: []PolicyResult{}}
+	return true
+}
+
+// end marks the enforcement run as finished
+func (s *Service) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+}
+
+// enforce applies every enabled policy. A policy for an entity type takes
+// precedence over the policy covering every type.
+func (s *Service) enforce(ctx context.Context) *EnforcementReport {
+	policies, err := s.store.ListPolicies(ctx)
+	if err != nil {
+		return s.finish(EnforcementFailed, err)
+	}
+
+	var specific []string
+	for _, policy := range policies {
+		if policy.Enabled && policy.EntityType != "" {
+			specific = append(specific, policy.EntityType)
+		}
+	}
+
+	status := EnforcementCompleted
+	for _, policy := range policies {
+		if !policy.Enabled {
+			continue
+		}
+		var exclude []string
+		if policy.EntityType == "" {
+			exclude = specific
+		}
+
+		result := s.enforcePolicy(ctx, policy, exclude)
+		if result.Error != "" {
+			status = EnforcementFailed
+			log.Printf("Failed to enforce audit retention policy %s: %s", policy.Name, result.Error)
+		} else if result.Deleted > 0 {
+			log.Printf("Audit retention policy %s removed %d entries, %d archived", policy.Name, result.Deleted, result.Archived)
+		}
+
+		s.mu.Lock()
+		s.last.Policies = append(s.last.Policies, result)
+		s.mu.Unlock()
+	}
+	return s.finish(status, nil)
+}
+
+// enforcePolicy archives and deletes the entries expired under one policy,
+// a batch at a time. Entries are only deleted once their archive is stored, so
+// a failed run leaves them in place to be archived again.
+func (s *Service) enforcePolicy(ctx context.Context, policy *RetentionPolicy, exclude []string) PolicyResult {
+	result := PolicyResult{PolicyID: policy.ID, Name: policy.Name, EntityType: policy.EntityType, Cutoff: policy.Cutoff(s.now())}
+	if policy.Archive && s.archiver == nil {
+		result.Error = "no audit archive is configured, expired entries are kept"
+		return result
+	}
+
+	for {
+		entries, err := s.store.ExpiredEntries(ctx, policy.EntityType, exclude, result.Cutoff, enforceBatchSize)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if len(entries) == 0 {
+			return result
+		}
+
+		if policy.Archive {
+			if err := s.archive(ctx, policy, entries); err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			result.Archives++
+			result.Archived += len(entries)
+		}
+
+		ids := make([]uuid.UUID, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		deleted, err := s.store.DeleteEntries(ctx, ids)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Deleted += deleted
+
+		if len(entries) < enforceBatchSize {
+			return result
+		}
+	}
+}
+
+// archive writes a batch of entries, ordered by time, to a new archive
+func (s *Service) archive(ctx context.Context, policy *RetentionPolicy, entries []Entry) error {
+	first, last := entries[0].ChangedAt.UTC(), entries[len(entries)-1].ChangedAt.UTC()
+	archive := &Archive{
+		ID:         uuid.New(),
+		PolicyID:   policy.ID,
+		From:       first,
+		To:         last,
+		EntryCount: len(entries),
+		CreatedAt:  s.now(),
+	}
+	archive.Key = fmt.Sprintf("audit/%s/%s-%s.ndjson.gz", first.Format("2006/01/02"), first.Format("20060102T150405Z"), archive.ID)
+
+	if err := s.archiver.Write(ctx, archive.Key, entries); err != nil {
+		return err
+	}
+	return s.store.CreateArchive(ctx, archive)
+}
+
+// finish completes the report of the current run
+func (s *Service) finish(status string, err error) *EnforcementReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	completedAt := s.now()
+	s.last.Status = status
+	s.last.CompletedAt = &completedAt
+	if err != nil {
+		s.last.Error = err.Error()
+		log.Printf("Failed to enforce audit retention: %v", err)
+	}
+	report := *s.last
+	report.Policies = append([]PolicyResult(nil), s.last.Policies...)
+	return &report
+}
@@ -0,0 +1,284 @@
+package auditlog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store reads the audit log and persists retention policies, legal holds and the archive catalogue
+type Store interface {
+	// Stream calls fn with each live entry passing the filter, oldest first
+	Stream(ctx context.Context, filter Filter, fn func(*Entry) error) error
+	// ExpiredEntries returns up to limit of the oldest entries changed before
+	// the cutoff that no active legal hold covers. An empty entity type
+	// selects every type but the excluded ones.
+	ExpiredEntries(ctx context.Context, entityType string, exclude []string, before time.Time, limit int) ([]Entry, error)
+	DeleteEntries(ctx context.Context, ids []uuid.UUID) (int64, error)
+
+	ListPolicies(ctx context.Context) ([]*RetentionPolicy, error)
+	GetPolicy(ctx context.Context, id uuid.UUID) (*RetentionPolicy, error)
+	CreatePolicy(ctx context.Context, policy *RetentionPolicy) error
+	UpdatePolicy(ctx context.Context, policy *RetentionPolicy) error
+	DeletePolicy(ctx context.Context, id uuid.UUID) error
+
+	ListHolds(ctx context.Context, activeOnly bool) ([]*LegalHold, error)
+	GetHold(ctx context.Context, id uuid.UUID) (*LegalHold, error)
+	CreateHold(ctx context.Context, hold *LegalHold) error
+	ReleaseHold(ctx context.Context, id uuid.UUID, at time.Time, by string) error
+
+	CreateArchive(ctx context.Context, archive *Archive) error
+	// ListArchives returns the archives holding entries in [from, to), oldest
+	// first. Zero times leave that side unbounded.
+	ListArchives(ctx context.Context, from, to time.Time) ([]Archive, error)
+}
+
+// PostgresStore reads the audit_logs table and keeps retention in the
+// audit_retention_policies, audit_legal_holds and audit_archives tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed audit log store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const entryColumns = `id, entity_type, entity_id, action, changed_by, COALESCE(changed_at, 'epoch') AS changed_at, details`
+
+// Stream streams the matching entries from a single query
+func (s *PostgresStore) Stream(ctx context.Context, filter Filter, fn func(*Entry) error) error {
+	conditions := []string{"changed_at >= $1", "changed_at < $2"}
+	args := []interface{}{filter.From, filter.To}
+	if filter.EntityType != "" {
+		args = append(args, filter.EntityType)
+		conditions = append(conditions, fmt.Sprintf("entity_type = $%d", len(args)))
+	}
+	if filter.EntityID != nil {
+		args = append(args, *filter.EntityID)
+		conditions = append(conditions, fmt.Sprintf("entity_id = $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if filter.ChangedBy != nil {
+		args = append(args, *filter.ChangedBy)
+		conditions = append(conditions, fmt.Sprintf("changed_by = $%d", len(args)))
+	}
+
+	rows, err := s.db.QueryxContext(ctx, `SELECT `+entryColumns+` FROM audit_logs WHERE `+
+		strings.Join(conditions, " AND ")+` ORDER BY changed_at, id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry Entry
+		if err := rows.StructScan(&entry); err != nil {
+			return fmt.Errorf("failed to scan audit log entry: %w", err)
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query audit log: %w", err)
+	}
+	return nil
+}
+
+// heldCondition excludes the entries covered by an active legal hold
+const heldCondition = `NOT EXISTS (
+		SELECT 1 FROM audit_legal_holds h
+		WHERE h.released_at IS NULL
+		  AND (h.entity_type = '' OR h.entity_type = a.entity_type)
+		  AND (h.entity_id IS NULL OR h.entity_id = a.entity_id)
+		  AND (h.from_time IS NULL OR a.changed_at >= h.from_time)
+		  AND (h.to_time IS NULL OR a.changed_at < h.to_time))`
+
+// ExpiredEntries retrieves a batch of expired entries outside legal holds
+func (s *PostgresStore) ExpiredEntries(ctx context.Context, entityType string, exclude []string, before time.Time, limit int) ([]Entry, error) {
+	entries := []Entry{}
+	err := s.db.SelectContext(ctx, &entries, `
+		SELECT a.id, a.entity_type, a.entity_id, a.action, a.changed_by, COALESCE(a.changed_at, 'epoch') AS changed_at, a.details
+		FROM audit_logs a
+		WHERE COALESCE(a.changed_at, 'epoch') < $1
+		  AND ($2 = '' OR a.entity_type = $2)
+		  AND NOT (a.entity_type = ANY($3))
+		  AND `+heldCondition+`
+		ORDER BY a.changed_at, a.id
+		LIMIT $4`, before, entityType, pq.Array(exclude), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired audit log entries: %w", err)
+	}
+	return entries, nil
+}
+
+// DeleteEntries removes entries from the audit log
+func (s *PostgresStore) DeleteEntries(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM audit_logs WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete audit log entries: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+const policyColumns = `id, name, entity_type, retain_days, archive, enabled, created_at, updated_at, COALESCE(updated_by, '') AS updated_by`
+
+// ListPolicies retrieves every retention policy
+func (s *PostgresStore) ListPolicies(ctx context.Context) ([]*RetentionPolicy, error) {
+	policies := []*RetentionPolicy{}
+	if err := s.db.SelectContext(ctx, &policies, `SELECT `+policyColumns+` FROM audit_retention_policies ORDER BY entity_type, name`); err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %w", err)
+	}
+	return policies, nil
+}
+
+// GetPolicy retrieves a retention policy
+func (s *PostgresStore) GetPolicy(ctx context.Context, id uuid.UUID) (*RetentionPolicy, error) {
+	var policy RetentionPolicy
+	if err := s.db.GetContext(ctx, &policy, `SELECT `+policyColumns+` FROM audit_retention_policies WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPolicyNotFound
+		}
+		return nil, fmt.Errorf("failed to get retention policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// CreatePolicy inserts a retention policy
+func (s *PostgresStore) CreatePolicy(ctx context.Context, policy *RetentionPolicy) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_retention_policies (id, name, entity_type, retain_days, archive, enabled, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		policy.ID, policy.Name, policy.EntityType, policy.RetainDays, policy.Archive, policy.Enabled,
+		policy.CreatedAt, policy.UpdatedAt, policy.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create retention policy: %w", err)
+	}
+	return nil
+}
+
+// UpdatePolicy replaces a retention policy
+func (s *PostgresStore) UpdatePolicy(ctx context.Context, policy *RetentionPolicy) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE audit_retention_policies
+		SET name = $2, entity_type = $3, retain_days = $4, archive = $5, enabled = $6, updated_at = $7, updated_by = $8
+		WHERE id = $1`,
+		policy.ID, policy.Name, policy.EntityType, policy.RetainDays, policy.Archive, policy.Enabled,
+		policy.UpdatedAt, policy.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update retention policy: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// DeletePolicy removes a retention policy. Its archives stay catalogued.
+func (s *PostgresStore) DeletePolicy(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM audit_retention_policies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention policy: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+const holdColumns = `id, name, reason, entity_type, entity_id, from_time, to_time, COALESCE(created_by, '') AS created_by,
+	created_at, released_at, COALESCE(released_by, '') AS released_by`
+
+// ListHolds retrieves the legal holds, newest first
+func (s *PostgresStore) ListHolds(ctx context.Context, activeOnly bool) ([]*LegalHold, error) {
+	holds := []*LegalHold{}
+	err := s.db.SelectContext(ctx, &holds, `SELECT `+holdColumns+` FROM audit_legal_holds
+		WHERE NOT $1 OR released_at IS NULL ORDER BY created_at DESC`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	return holds, nil
+}
+
+// GetHold retrieves a legal hold
+func (s *PostgresStore) GetHold(ctx context.Context, id uuid.UUID) (*LegalHold, error) {
+	var hold LegalHold
+	if err := s.db.GetContext(ctx, &hold, `SELECT `+holdColumns+` FROM audit_legal_holds WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+// CreateHold inserts a legal hold
+func (s *PostgresStore) CreateHold(ctx context.Context, hold *LegalHold) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_legal_holds (id, name, reason, entity_type, entity_id, from_time, to_time, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		hold.ID, hold.Name, hold.Reason, hold.EntityType, hold.EntityID, hold.From, hold.To, hold.CreatedBy, hold.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create legal hold: %w", err)
+	}
+	return nil
+}
+
+// ReleaseHold releases an active legal hold
+func (s *PostgresStore) ReleaseHold(ctx context.Context, id uuid.UUID, at time.Time, by string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE audit_legal_holds SET released_at = $2, released_by = $3
+		WHERE id = $1 AND released_at IS NULL`, id, at, by)
+	if err != nil {
+		return fmt.Errorf("failed to release legal hold: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrHoldNotFound
+	}
+	return nil
+}
+
+// CreateArchive catalogues an archive
+func (s *PostgresStore) CreateArchive(ctx context.Context, archive *Archive) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_archives (id, archive_key, policy_id, from_time, to_time, entry_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		archive.ID, archive.Key, archive.PolicyID, archive.From, archive.To, archive.EntryCount, archive.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to catalogue audit archive: %w", err)
+	}
+	return nil
+}
+
+// ListArchives retrieves the archives overlapping a time range
+func (s *PostgresStore) ListArchives(ctx context.Context, from, to time.Time) ([]Archive, error) {
+	var fromArg, toArg interface{}
+	if !from.IsZero() {
+		fromArg = from
+	}
+	if !to.IsZero() {
+		toArg = to
+	}
+
+	archives := []Archive{}
+	err := s.db.SelectContext(ctx, &archives, `
+		SELECT id, archive_key, policy_id, from_time, to_time, entry_count, created_at
+		FROM audit_archives
+		WHERE ($1::timestamptz IS NULL OR to_time >= $1) AND ($2::timestamptz IS NULL OR from_time < $2)
+		ORDER BY from_time, id`, fromArg, toArg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit archives: %w", err)
+	}
+	return archives, nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// ActorTypeAnonymous marks requests served without authentication by the
+// anonymous read-only scope
+const ActorTypeAnonymous = "anonymous"
+
+// AnonymousReadOnly serves routes to unauthenticated callers. Only reads are
+// allowed, and any credentials sent are dropped so the routes behave the
+// same for every caller and never act on someone's behalf.
+func AnonymousReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error":"Read-only access"}`))
+			return
+		}
+
+		r = r.Clone(context.WithValue(r.Context(), ActorTypeContextKey, ActorTypeAnonymous))
+		r.Header.Del("Authorization")
+		r.Header.Del(APIKeyHeader)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsAnonymous reports whether a request is served by the anonymous read-only scope
+func IsAnonymous(ctx context.Context) bool {
+	actorType, _ := GetActorTypeFromContext(ctx)
+	return actorType == ActorTypeAnonymous
+}
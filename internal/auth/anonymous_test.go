@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymousReadOnly(t *testing.T) {
+	var served *http.Request
+	handler := AnonymousReadOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("reads are served anonymously without credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/public/catalog", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set(APIKeyHeader, "key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, IsAnonymous(served.Context()))
+		assert.Empty(t, served.Header.Get("Authorization"))
+		assert.Empty(t, served.Header.Get(APIKeyHeader))
+		_, hasUser := GetUserIDFromContext(served.Context())
+		assert.False(t, hasUser)
+	})
+
+	t.Run("writes are rejected", func(t *testing.T) {
+		served = nil
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/public/catalog", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+		assert.Nil(t, served)
+	})
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DeviceIDHeader carries an explicit device identifier from clients that have one
+const DeviceIDHeader = "X-Device-ID"
+
+// Fingerprint binding modes, from least to most strict
+const (
+	// FingerprintOff ignores fingerprint changes
+	FingerprintOff = "off"
+	// FingerprintFlag records fingerprint changes but accepts the request
+	FingerprintFlag = "flag"
+	// FingerprintStrict rejects drastic changes and records minor ones
+	FingerprintStrict = "strict"
+	// FingerprintPinned rejects any change
+	FingerprintPinned = "pinned"
+)
+
+// Fingerprint change levels
+const (
+	ChangeNone    = "none"
+	ChangeMinor   = "minor"
+	ChangeDrastic = "drastic"
+)
+
+// Default prefix lengths used to group client addresses into subnets
+const (
+	DefaultIPv4PrefixLength = 24
+	DefaultIPv6PrefixLength = 64
+)
+
+// ErrFingerprintMismatch is returned when a request does not match the client its session is bound to
+var ErrFingerprintMismatch = errors.New("session fingerprint mismatch")
+
+// Fingerprint identifies the client a session was created from
+type Fingerprint struct {
+	DeviceID string `json:"device_id,omitempty"`
+	UAFamily string `json:"ua_family"` // browser and OS family, e.g. "chrome/windows"
+	IPPrefix string `json:"ip_prefix"` // subnet of the client address, e.g. "10.1.2.0/24"
+}
+
+// FingerprintChange describes how a presented fingerprint differs from the bound one
+type FingerprintChange struct {
+	Level   string   `json:"level"`
+	Changed []string `json:"changed,omitempty"` // "device_id", "ua_family", "ip_prefix"
+}
+
+// FingerprintPolicy decides how strictly sessions are bound to their client
+type FingerprintPolicy struct {
+	Mode             string
+	IPv4PrefixLength int
+	IPv6PrefixLength int
+}
+
+// DefaultFingerprintPolicy returns a policy that only flags changes
+func DefaultFingerprintPolicy() FingerprintPolicy {
+	return FingerprintPolicy{
+		Mode:             FingerprintFlag,
+		IPv4PrefixLength: DefaultIPv4PrefixLength,
+		IPv6PrefixLength: DefaultIPv6PrefixLength,
+	}
+}
+
+// Validate checks the policy settings
+func (p FingerprintPolicy) Validate() error {
+	switch p.Mode {
+	case FingerprintOff, FingerprintFlag, FingerprintStrict, FingerprintPinned:
+	default:
+		return fmt.Errorf("unknown fingerprint mode: %s", p.Mode)
+	}
+	if p.IPv4PrefixLength < 0 || p.IPv4PrefixLength > 32 {
+		return fmt.Errorf("IPv4 prefix length must be between 0 and 32")
+	}
+	if p.IPv6PrefixLength < 0 || p.IPv6PrefixLength > 128 {
+		return fmt.Errorf("IPv6 prefix length must be between 0 and 128")
+	}
+	return nil
+}
+
+// Compute derives the fingerprint of a client
+func (p FingerprintPolicy) Compute(client ClientInfo) Fingerprint {
+	return Fingerprint{
+		DeviceID: strings.TrimSpace(client.DeviceID),
+		UAFamily: UserAgentFamily(client.UserAgent),
+		IPPrefix: p.ipPrefix(client.IPAddress),
+	}
+}
+
+// Check compares a presented fingerprint against the bound one. It returns the
+// change and ErrFingerprintMismatch when the policy rejects it.
+func (p FingerprintPolicy) Check(bound, presented Fingerprint) (FingerprintChange, error) {
+	change := Compare(bound, presented)
+	if change.Level == ChangeNone {
+		return change, nil
+	}
+
+	switch p.Mode {
+	case FingerprintPinned:
+		return change, ErrFingerprintMismatch
+	case FingerprintStrict:
+		if change.Level == ChangeDrastic {
+			return change, ErrFingerprintMismatch
+		}
+	}
+	return change, nil
+}
+
+// Compare classifies the difference between two fingerprints. A different
+// explicit device ID is drastic on its own; otherwise a change of both the
+// user agent family and the subnet is drastic and a change of either is minor.
+// Attributes missing from the bound fingerprint are not compared.
+func Compare(bound, presented Fingerprint) FingerprintChange {
+	change := FingerprintChange{Level: ChangeNone}
+
+	if bound.DeviceID != "" && presented.DeviceID != bound.DeviceID {
+		change.Changed = append(change.Changed, "device_id")
+	}
+	if bound.UAFamily != "" && presented.UAFamily != bound.UAFamily {
+		change.Changed = append(change.Changed, "ua_family")
+	}
+	if bound.IPPrefix != "" && presented.IPPrefix != bound.IPPrefix {
+		change.Changed = append(change.Changed, "ip_prefix")
+	}
+
+	switch {
+	case len(change.Changed) == 0:
+	case change.Changed[0] == "device_id" || len(change.Changed) > 1:
+		change.Level = ChangeDrastic
+	default:
+		change.Level = ChangeMinor
+	}
+	return change
+}
+
+// ipPrefix returns the subnet of an address, or "" when it cannot be parsed
+func (p FingerprintPolicy) ipPrefix(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	ip := net.ParseIP(strings.TrimSpace(address))
+	if ip == nil {
+		return ""
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		mask := net.CIDRMask(p.IPv4PrefixLength, 32)
+		return fmt.Sprintf("%s/%d", ip4.Mask(mask), p.IPv4PrefixLength)
+	}
+	mask := net.CIDRMask(p.IPv6PrefixLength, 128)
+	return fmt.Sprintf("%s/%d", ip.Mask(mask), p.IPv6PrefixLength)
+}
+
+// browserFamilies are matched in order; Edge and Opera also announce Chrome,
+// and Chrome also announces Safari
+var browserFamilies = []struct{ token, family string }{
+	{"edg/", "edge"},
+	{"opr/", "opera"},
+	{"firefox/", "firefox"},
+	{"chrome/", "chrome"},
+	{"crios/", "chrome"},
+	{"safari/", "safari"},
+	{"curl/", "curl"},
+	{"python-requests/", "python"},
+	{"go-http-client/", "go"},
+}
+
+var osFamilies = []struct{ token, family string }{
+	{"android", "android"},
+	{"iphone", "ios"},
+	{"ipad", "ios"},
+	{"windows", "windows"},
+	{"mac os x", "macos"},
+	{"cros", "chromeos"},
+	{"linux", "linux"},
+}
+
+// UserAgentFamily reduces a User-Agent header to "browser/os" so that version
+// upgrades do not count as a different client
+func UserAgentFamily(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return ""
+	}
+
+	browser := "other"
+	for _, candidate := range browserFamilies {
+		if strings.Contains(ua, candidate.token) {
+			browser = candidate.family
+			break
+		}
+	}
+
+	os := "other"
+	for _, candidate := range osFamilies {
+		if strings.Contains(ua, candidate.token) {
+			os = candidate.family
+			break
+		}
+	}
+
+	return browser + "/" + os
+}
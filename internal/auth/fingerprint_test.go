@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	chromeWindows  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	chromeWindows2 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+	edgeWindows    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0"
+	safariIPhone   = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+)
+
+func TestUserAgentFamily(t *testing.T) {
+	assert.Equal(t, "chrome/windows", UserAgentFamily(chromeWindows))
+	assert.Equal(t, UserAgentFamily(chromeWindows), UserAgentFamily(chromeWindows2))
+	assert.Equal(t, "edge/windows", UserAgentFamily(edgeWindows))
+	assert.Equal(t, "safari/ios", UserAgentFamily(safariIPhone))
+	assert.Equal(t, "curl/other", UserAgentFamily("curl/8.4.0"))
+	assert.Equal(t, "", UserAgentFamily(""))
+}
+
+func TestFingerprintPolicy_Compute(t *testing.T) {
+	policy := DefaultFingerprintPolicy()
+	require.NoError(t, policy.Validate())
+
+	fp := policy.Compute(ClientInfo{IPAddress: "10.1.2.3:5123", UserAgent: chromeWindows, DeviceID: " dev-1 "})
+	assert.Equal(t, Fingerprint{DeviceID: "dev-1", UAFamily: "chrome/windows", IPPrefix: "10.1.2.0/24"}, fp)
+
+	fp = policy.Compute(ClientInfo{IPAddress: "2001:db8:1:2:3::4"})
+	assert.Equal(t, "2001:db8:1:2::/64", fp.IPPrefix)
+
+	assert.Error(t, FingerprintPolicy{Mode: "lenient"}.Validate())
+}
+
+func TestFingerprintPolicy_Check(t *testing.T) {
+	base := DefaultFingerprintPolicy()
+	bound := base.Compute(ClientInfo{IPAddress: "10.1.2.3", UserAgent: chromeWindows})
+
+	sameSubnet := base.Compute(ClientInfo{IPAddress: "10.1.2.99", UserAgent: chromeWindows2})
+	newSubnet := base.Compute(ClientInfo{IPAddress: "10.9.9.9", UserAgent: chromeWindows})
+	elsewhere := base.Compute(ClientInfo{IPAddress: "203.0.113.7", UserAgent: safariIPhone})
+
+	tests := []struct {
+		name      string
+		mode      string
+		presented Fingerprint
+		level     string
+		rejected  bool
+	}{
+		{"upgrade in same subnet", FingerprintPinned, sameSubnet, ChangeNone, false},
+		{"flag accepts drastic", FingerprintFlag, elsewhere, ChangeDrastic, false},
+		{"strict accepts minor", FingerprintStrict, newSubnet, ChangeMinor, false},
+		{"strict rejects drastic", FingerprintStrict, elsewhere, ChangeDrastic, true},
+		{"pinned rejects minor", FingerprintPinned, newSubnet, ChangeMinor, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := base
+			policy.Mode = tt.mode
+
+			change, err := policy.Check(bound, tt.presented)
+			assert.Equal(t, tt.level, change.Level)
+			if tt.rejected {
+				assert.ErrorIs(t, err, ErrFingerprintMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("device ID change is drastic", func(t *testing.T) {
+		boundDevice := Fingerprint{DeviceID: "dev-1", UAFamily: "chrome/windows", IPPrefix: "10.1.2.0/24"}
+		presented := boundDevice
+		presented.DeviceID = "dev-2"
+
+		change := Compare(boundDevice, presented)
+		assert.Equal(t, ChangeDrastic, change.Level)
+		assert.Equal(t, []string{"device_id"}, change.Changed)
+	})
+}
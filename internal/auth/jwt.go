@@ -13,7 +13,7 @@ var (
 )
 
 type JWTService struct {
-	secretKey     string
+	keys          *KeySet
 	accessTTL     time.Duration
 	refreshTTL    time.Duration
 	issuer        string
@@ -23,18 +23,40 @@ type Claims struct {
 	UserID   string   `json:"user_id"`
 	Username string   `json:"username"`
 	Roles    []string `json:"roles"`
+	// Org is the slug of the organization the user acts for, empty for
+	// users that are members of none
+	Org      string   `json:"org,omitempty"`
+	// Scopes limits the caller to these permissions instead of those of
+	// its roles; only API keys scoped to permissions set it
+	Scopes   []string `json:"scopes,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func NewJWTService(secretKey string, accessTTL, refreshTTL time.Duration) *JWTService {
+	return NewJWTServiceWithKeys(NewKeySet(NewHMACKey(DefaultKeyID, []byte(secretKey)), refreshTTL), accessTTL, refreshTTL)
+}
+
+// NewJWTServiceWithKeys creates a JWT service that signs with the current key of
+// keys and accepts tokens signed by any key the set still accepts
+func NewJWTServiceWithKeys(keys *KeySet, accessTTL, refreshTTL time.Duration) *JWTService {
 	return &JWTService{
-		secretKey:  secretKey,
+		keys:       keys,
 		accessTTL:  accessTTL,
 		refreshTTL: refreshTTL,
 		issuer:     "conx-cmdb",
 	}
 }
 
+// Keys returns the signing keys, e.g. to rotate them
+func (s *JWTService) Keys() *KeySet {
+	return s.keys
+}
+
+// JWKS returns the public keys other services validate tokens with
+func (s *JWTService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
+
 func (s *JWTService) GenerateAccessToken(userID, username string, roles []string) (string, error) {
 	return s.generateToken(userID, username, roles, s.accessTTL)
 }
@@ -43,12 +65,46 @@ func (s *JWTService) GenerateRefreshToken(userID, username string, roles []strin
 	return s.generateToken(userID, username, roles, s.refreshTTL)
 }
 
+// GenerateRefreshTokenWithID generates a refresh token carrying tokenID as its
+// jti so it can be tracked for rotation
+func (s *JWTService) GenerateRefreshTokenWithID(userID, username string, roles []string, tokenID string) (string, error) {
+	return s.generateTokenWithID(userID, username, roles, "", s.refreshTTL, tokenID)
+}
+
+// GenerateOrgAccessToken generates an access token for a user acting for an
+// organization
+func (s *JWTService) GenerateOrgAccessToken(userID, username string, roles []string, org string) (string, error) {
+	return s.generateTokenWithID(userID, username, roles, org, s.accessTTL, "")
+}
+
+// GenerateOrgRefreshToken generates a refresh token for a user acting for an
+// organization; tokenID, when set, is its jti for rotation
+func (s *JWTService) GenerateOrgRefreshToken(userID, username string, roles []string, org, tokenID string) (string, error) {
+	return s.generateTokenWithID(userID, username, roles, org, s.refreshTTL, tokenID)
+}
+
+// AccessTTL returns how long access tokens are valid
+func (s *JWTService) AccessTTL() time.Duration {
+	return s.accessTTL
+}
+
+// RefreshTTL returns how long refresh tokens are valid
+func (s *JWTService) RefreshTTL() time.Duration {
+	return s.refreshTTL
+}
+
 func (s *JWTService) generateToken(userID, username string, roles []string, ttl time.Duration) (string, error) {
+	return s.generateTokenWithID(userID, username, roles, "", ttl, "")
+}
+
+func (s *JWTService) generateTokenWithID(userID, username string, roles []string, org string, ttl time.Duration, tokenID string) (string, error) {
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
 		Roles:    roles,
+		Org:      org,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			Issuer:    s.issuer,
 			Subject:   userID,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -56,16 +112,24 @@ func (s *JWTService) generateToken(userID, username string, roles []string, ttl
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+	key := s.keys.Current()
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
 }
 
 func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := s.keys.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		// The algorithm must be the key's own, never whatever the token claims
+		if token.Method.Alg() != key.Algorithm {
 			return nil, ErrInvalidToken
 		}
-		return []byte(s.secretKey), nil
+		return key.verifyKey, nil
 	})
 
 	if err != nil {
@@ -88,8 +152,8 @@ func (s *JWTService) RefreshAccessToken(refreshTokenString string) (string, erro
 		return "", err
 	}
 
-	// Generate new access token
-	return s.GenerateAccessToken(claims.UserID, claims.Username, claims.Roles)
+	// Generate new access token, for the same organization
+	return s.GenerateOrgAccessToken(claims.UserID, claims.Username, claims.Roles, claims.Org)
 }
 
 func (s *JWTService) ExtractUserID(tokenString string) (string, error) {
@@ -109,7 +173,7 @@ func (s *JWTService) ExtractRoles(tokenString string) ([]string, error) {
 }
 
 func (s *JWTService) IsTokenExpired(tokenString string) bool {
-	claims, err := s.ValidateToken(tokenString)
+	_, err := s.ValidateToken(tokenString)
 	if err != nil {
 		return errors.Is(err, ErrTokenExpired)
 	}
@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+// DefaultKeyID is the kid of the key built from the legacy single secret
+const DefaultKeyID = "default"
+
+var (
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+)
+
+// SigningKey is a key tokens are signed and verified with, identified by the kid header
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	// RetiredAt is when the key stopped signing; zero while it is the current key
+	RetiredAt time.Time
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey creates an HS256 key from a shared secret
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{ID: id, Algorithm: AlgorithmHS256, signKey: secret, verifyKey: secret}
+}
+
+// NewRSAKey creates an RS256 key
+func NewRSAKey(id string, key *rsa.PrivateKey) *SigningKey {
+	return &SigningKey{ID: id, Algorithm: AlgorithmRS256, signKey: key, verifyKey: &key.PublicKey}
+}
+
+// NewECDSAKey creates an ES256 key; the key must be on the P-256 curve
+func NewECDSAKey(id string, key *ecdsa.PrivateKey) (*SigningKey, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: ES256 requires a P-256 key", ErrUnsupportedAlgorithm)
+	}
+	return &SigningKey{ID: id, Algorithm: AlgorithmES256, signKey: key, verifyKey: &key.PublicKey}, nil
+}
+
+// ParsePrivateKeyPEM creates a key from a PEM encoded RSA or P-256 ECDSA private key.
+// PKCS#1, SEC 1 and PKCS#8 encodings are accepted.
+func ParsePrivateKeyPEM(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM data found", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: failed to parse private key: %w", id, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return NewRSAKey(id, key), nil
+	case *ecdsa.PrivateKey:
+		return NewECDSAKey(id, key)
+	default:
+		return nil, fmt.Errorf("key %s: %w: %T", id, ErrUnsupportedAlgorithm, parsed)
+	}
+}
+
+// LoadSigningKey creates a key from configuration: HS256 keys use secret,
+// RS256 and ES256 keys read the PEM private key at privateKeyFile
+func LoadSigningKey(id, algorithm, secret, privateKeyFile string) (*SigningKey, error) {
+	switch algorithm {
+	case "", AlgorithmHS256:
+		if secret == "" {
+			return nil, fmt.Errorf("key %s: HS256 requires a secret", id)
+		}
+		return NewHMACKey(id, []byte(secret)), nil
+	case AlgorithmRS256, AlgorithmES256:
+		data, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: failed to read private key: %w", id, err)
+		}
+		key, err := ParsePrivateKeyPEM(id, data)
+		if err != nil {
+			return nil, err
+		}
+		if key.Algorithm != algorithm {
+			return nil, fmt.Errorf("key %s: private key is for %s, not %s", id, key.Algorithm, algorithm)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("key %s: %w: %s", id, ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// method returns the JWT signing method of the key
+func (k *SigningKey) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case AlgorithmES256:
+		return jwt.SigningMethodES256
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// KeySet holds the current signing key and the retired keys still accepted for
+// verification. A retired key is accepted for the overlap window after it was
+// retired, so tokens it signed stay valid until they would expire anyway.
+type KeySet struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	retired []*SigningKey
+	overlap time.Duration
+	now     func() time.Time
+}
+
+// NewKeySet creates a key set signing with current
+func NewKeySet(current *SigningKey, overlap time.Duration) *KeySet {
+	return &KeySet{current: current, overlap: overlap, now: time.Now}
+}
+
+// AddRetired adds a previously used key that is still accepted for verification
+func (ks *KeySet) AddRetired(key *SigningKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if key.RetiredAt.IsZero() {
+		key.RetiredAt = ks.now()
+	}
+	ks.retired = append(ks.retired, key)
+}
+
+// Rotate makes next the signing key and retires the current one
+func (ks *KeySet) Rotate(next *SigningKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	previous := ks.current
+	previous.RetiredAt = ks.now()
+	ks.retired = append(ks.retired, previous)
+	ks.current = next
+	ks.pruneLocked()
+}
+
+// Current returns the key new tokens are signed with
+func (ks *KeySet) Current() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.current
+}
+
+// Lookup returns the key with the given kid if it is still accepted.
+// Tokens without a kid predate key rotation and are checked against the current key.
+func (ks *KeySet) Lookup(kid string) (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if kid == "" || kid == ks.current.ID {
+		return ks.current, nil
+	}
+	for _, key := range ks.retired {
+		if key.ID == kid && ks.acceptedLocked(key) {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// acceptedLocked reports whether a retired key is still within the overlap window
+func (ks *KeySet) acceptedLocked(key *SigningKey) bool {
+	return ks.now().Before(key.RetiredAt.Add(ks.overlap))
+}
+
+// pruneLocked drops retired keys past the overlap window
+func (ks *KeySet) pruneLocked() {
+	kept := ks.retired[:0]
+	for _, key := range ks.retired {
+		if ks.acceptedLocked(key) {
+			kept = append(kept, key)
+		}
+	}
+	ks.retired = kept
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the current and accepted retired keys.
+// HS256 keys are shared secrets and are never published.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	keys := append([]*SigningKey{ks.current}, ks.retired...)
+	for _, key := range keys {
+		if key != ks.current && !ks.acceptedLocked(key) {
+			continue
+		}
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// jwk returns the public JWK of an asymmetric key
+func (k *SigningKey) jwk() (JWK, bool) {
+	switch public := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Algorithm,
+			N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}, true
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Algorithm,
+			Crv: public.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size))),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
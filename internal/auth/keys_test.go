@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySet_Rotation(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	keys := NewKeySet(NewHMACKey("k1", []byte("first-secret-that-is-at-least-32-chars")), time.Hour)
+	keys.now = func() time.Time { return now }
+	service := NewJWTServiceWithKeys(keys, 15*time.Minute, 2*time.Hour)
+
+	oldToken, err := service.GenerateAccessToken("user-1", "alice", []string{"viewer"})
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys.Rotate(NewRSAKey("k2", rsaKey))
+
+	newToken, err := service.GenerateAccessToken("user-1", "alice", []string{"viewer"})
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "k2", parsed.Header["kid"])
+	assert.Equal(t, AlgorithmRS256, parsed.Method.Alg())
+
+	t.Run("old key accepted during overlap", func(t *testing.T) {
+		_, err := service.ValidateToken(oldToken)
+		assert.NoError(t, err)
+		_, err = service.ValidateToken(newToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("old key rejected after overlap", func(t *testing.T) {
+		keys.now = func() time.Time { return now.Add(2 * time.Hour) }
+		defer func() { keys.now = func() time.Time { return now } }()
+
+		_, err := service.ValidateToken(oldToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("algorithm must match the key", func(t *testing.T) {
+		// An HS256 token carrying the RSA key's kid must not be verified with the public key
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "user-1"})
+		forged.Header["kid"] = "k2"
+		signed, err := forged.SignedString(x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+		require.NoError(t, err)
+
+		_, err = service.ValidateToken(signed)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("JWKS publishes only asymmetric keys", func(t *testing.T) {
+		set := service.JWKS()
+		require.Len(t, set.Keys, 1)
+		assert.Equal(t, "k2", set.Keys[0].Kid)
+		assert.Equal(t, "RSA", set.Keys[0].Kty)
+		assert.Equal(t, "AQAB", set.Keys[0].E)
+	})
+}
+
+func TestParsePrivateKeyPEM_ECDSA(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+
+	key, err := ParsePrivateKeyPEM("ec1", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmES256, key.Algorithm)
+
+	service := NewJWTServiceWithKeys(NewKeySet(key, time.Hour), 15*time.Minute, time.Hour)
+	token, err := service.GenerateAccessToken("user-1", "alice", nil)
+	require.NoError(t, err)
+	claims, err := service.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+
+	set := service.JWKS()
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "P-256", set.Keys[0].Crv)
+	assert.Len(t, set.Keys[0].X, 43)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, err = NewECDSAKey("ec2", p384)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
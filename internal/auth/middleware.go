@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"connect/internal/logger"
+	"connect/internal/visibility"
 )
 
 var (
@@ -21,20 +22,75 @@ const (
 	UserContextKey   contextKey = "user"
 	RolesContextKey  contextKey = "roles"
 	TokenContextKey  contextKey = "token"
+	ActorTypeContextKey contextKey = "actor_type"
+	OrgContextKey    contextKey = "org"
+	ScopesContextKey contextKey = "scopes"
 )
 
+// Actor types
+const (
+	ActorTypeUser           = "user"
+	ActorTypeServiceAccount = "service_account"
+)
+
+// ServiceAccountPrefix prefixes the username in the claims of a service
+// account, so its actions can be told apart from people's
+const ServiceAccountPrefix = "svc:"
+
+// APIKeyHeader carries an API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyScheme is the Authorization scheme of API keys, as in
+// "Authorization: ApiKey <key>"
+const APIKeyScheme = "ApiKey"
+
+// APIKeyAuthenticator resolves an API key to the claims of the identity it
+// belongs to
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string, client ClientInfo) (*Claims, error)
+}
+
+// APIKeyAuthenticators tries each authenticator in turn, so keys of several
+// kinds, e.g. service account and user API keys, are accepted alike
+type APIKeyAuthenticators []APIKeyAuthenticator
+
+// AuthenticateAPIKey returns the claims of the first authenticator accepting
+// the key, else the error of the last one
+func (a APIKeyAuthenticators) AuthenticateAPIKey(ctx context.Context, key string, client ClientInfo) (*Claims, error) {
+	err := ErrUnauthorized
+	for _, authenticator := range a {
+		var claims *Claims
+		if claims, err = authenticator.AuthenticateAPIKey(ctx, key, client); err == nil {
+			return claims, nil
+		}
+	}
+	return nil, err
+}
+
 type AuthMiddleware struct {
 	jwtService     *JWTService
 	logger         *logger.Logger
 	excludePaths   map[string]bool
+	excludePrefixes []string
 	optionalPaths  map[string]bool
+	apiKeys        APIKeyAuthenticator
+	permissions    PermissionResolver
 }
 
 type AuthConfig struct {
 	JWTService     *JWTService
 	Logger         *logger.Logger
 	ExcludePaths   []string
+	// ExcludePrefixes excludes every path below each prefix, e.g. routes
+	// served to anonymous callers
+	ExcludePrefixes []string
 	OptionalPaths  []string
+	// APIKeys authenticates requests sending an API key instead of a token;
+	// API keys are rejected when it is nil
+	APIKeys        APIKeyAuthenticator
+	// Permissions resolves the permissions of the caller's roles for
+	// RequirePermission; the seeded role permissions are used when it is nil
+	Permissions    PermissionResolver
 }
 
 func NewAuthMiddleware(config AuthConfig) *AuthMiddleware {
@@ -48,11 +104,19 @@ func NewAuthMiddleware(config AuthConfig) *AuthMiddleware {
 		optionalPaths[path] = true
 	}
 
+	permissions := config.Permissions
+	if permissions == nil {
+		permissions = defaultRolePermissions
+	}
+
 	return &AuthMiddleware{
 		jwtService:    config.JWTService,
 		logger:        config.Logger,
 		excludePaths:  excludePaths,
+		excludePrefixes: config.ExcludePrefixes,
 		optionalPaths: optionalPaths,
+		apiKeys:       config.APIKeys,
+		permissions:   permissions,
 	}
 }
 
@@ -64,6 +128,12 @@ func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Service accounts and automation may send an API key instead of a token
+		if key := m.extractAPIKey(r); key != "" {
+			m.authenticateAPIKey(w, r, next, key)
+			return
+		}
+
 		// Extract token from Authorization header
 		tokenString, err := m.extractToken(r)
 		if err != nil {
@@ -92,6 +162,24 @@ func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateAPIKey authenticates a request with an API key
+func (m *AuthMiddleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, key string) {
+	if m.apiKeys == nil {
+		m.respondWithError(w, http.StatusUnauthorized, "API keys are not accepted")
+		return
+	}
+
+	claims, err := m.apiKeys.AuthenticateAPIKey(r.Context(), key, ClientInfo{IPAddress: r.RemoteAddr, UserAgent: r.UserAgent()})
+	if err != nil {
+		m.logger.ErrorRequest(r, err, "API key authentication failed")
+		m.respondWithError(w, http.StatusUnauthorized, "Invalid or expired API key")
+		return
+	}
+
+	ctx := m.addUserContext(r.Context(), claims, "")
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 func (m *AuthMiddleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -113,26 +201,18 @@ func (m *AuthMiddleware) RequireRole(roles ...string) func(http.Handler) http.Ha
 	}
 }
 
+// RequirePermission requires the caller's roles to grant a permission
 func (m *AuthMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
-	// This is a simplified permission check
-	// In a real implementation, you would map permissions to roles
-	rolePermissions := map[string][]string{
-		"admin": {
-			"ci:create", "ci:read", "ci:update", "ci:delete",
-			"relationship:manage", "audit_log:read", "user:manage", "import:csv",
-		},
-		"ci_manager": {
-			"ci:create", "ci:read", "ci:update", "ci:delete",
-			"relationship:manage", "import:csv",
-		},
-		"viewer": {
-			"ci:read",
-		},
-		"auditor": {
-			"ci:read", "audit_log:read",
-		},
-	}
+	return m.RequireMethodPermission(nil, permission)
+}
 
+// MethodPermissions maps HTTP methods to the permission they require
+type MethodPermissions map[string]string
+
+// RequireMethodPermission requires the permission mapped to the request method,
+// or fallback for methods that are not mapped, so reads and writes of the same
+// routes can require different permissions
+func (m *AuthMiddleware) RequireMethodPermission(permissions MethodPermissions, fallback string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userRoles, ok := r.Context().Value(RolesContextKey).([]string)
@@ -142,8 +222,25 @@ func (m *AuthMiddleware) RequirePermission(permission string) func(http.Handler)
 				return
 			}
 
-			if !m.hasRequiredPermission(userRoles, permission, rolePermissions) {
-				m.logger.ErrorRequest(r, ErrUnauthorized, "Insufficient permissions")
+			permission, ok := permissions[r.Method]
+			if !ok {
+				permission = fallback
+			}
+
+			// API keys scoped to permissions are granted those alone
+			granted, scoped := GetScopesFromContext(r.Context())
+			if !scoped {
+				var err error
+				granted, err = m.permissions.Permissions(r.Context(), userRoles)
+				if err != nil {
+					m.logger.ErrorRequest(r, err, "Failed to resolve permissions")
+					m.respondWithError(w, http.StatusInternalServerError, "Failed to resolve permissions")
+					return
+				}
+			}
+
+			if !m.hasRequiredPermission(granted, permission) {
+				m.logger.ErrorRequest(r, ErrUnauthorized, "Insufficient permissions", map[string]interface{}{"permission": permission})
 				m.respondWithError(w, http.StatusForbidden, "Insufficient permissions")
 				return
 			}
@@ -168,8 +265,29 @@ func (m *AuthMiddleware) extractToken(r *http.Request) (string, error) {
 	return parts[1], nil
 }
 
+// extractAPIKey returns the API key sent in the X-API-Key header or with the
+// ApiKey Authorization scheme, if any
+func (m *AuthMiddleware) extractAPIKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, APIKeyScheme) {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
+
 func (m *AuthMiddleware) isPathExcluded(path string) bool {
-	return m.excludePaths[path]
+	if m.excludePaths[path] {
+		return true
+	}
+	for _, prefix := range m.excludePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *AuthMiddleware) isPathOptional(path string) bool {
@@ -186,6 +304,24 @@ func (m *AuthMiddleware) addUserContext(ctx context.Context, claims *Claims, tok
 	// Add token
 	ctx = context.WithValue(ctx, TokenContextKey, tokenString)
 
+	// Add actor type
+	actorType := ActorTypeUser
+	if strings.HasPrefix(claims.Username, ServiceAccountPrefix) {
+		actorType = ActorTypeServiceAccount
+	}
+	ctx = context.WithValue(ctx, ActorTypeContextKey, actorType)
+
+	// Add the organization, which is the tenant the request acts for
+	if claims.Org != "" {
+		ctx = context.WithValue(ctx, OrgContextKey, claims.Org)
+		ctx = visibility.WithTenant(ctx, claims.Org)
+	}
+
+	// Add the permissions an API key is scoped to
+	if claims.Scopes != nil {
+		ctx = context.WithValue(ctx, ScopesContextKey, claims.Scopes)
+	}
+
 	return ctx
 }
 
@@ -205,14 +341,10 @@ func (m *AuthMiddleware) hasRequiredRole(userRoles []string, requiredRoles []str
 	return false
 }
 
-func (m *AuthMiddleware) hasRequiredPermission(userRoles []string, requiredPermission string, rolePermissions map[string][]string) bool {
-	for _, role := range userRoles {
-		if permissions, exists := rolePermissions[role]; exists {
-			for _, permission := range permissions {
-				if permission == requiredPermission {
-					return true
-				}
-			}
+func (m *AuthMiddleware) hasRequiredPermission(granted []string, requiredPermission string) bool {
+	for _, permission := range granted {
+		if permission == requiredPermission {
+			return true
 		}
 	}
 
@@ -241,6 +373,28 @@ func GetTokenFromContext(ctx context.Context) (string, bool) {
 	return token, ok
 }
 
+// GetActorTypeFromContext returns whether the request was made by a user or
+// a service account. Service account IDs are not user IDs, so they must not
+// be recorded where a user is referenced, such as audit_logs.changed_by.
+func GetActorTypeFromContext(ctx context.Context) (string, bool) {
+	actorType, ok := ctx.Value(ActorTypeContextKey).(string)
+	return actorType, ok
+}
+
+// GetOrgFromContext returns the slug of the organization the request acts
+// for, if any
+func GetOrgFromContext(ctx context.Context) (string, bool) {
+	org, ok := ctx.Value(OrgContextKey).(string)
+	return org, ok
+}
+
+// GetScopesFromContext returns the permissions the request is limited to when
+// it was authenticated with an API key scoped to permissions
+func GetScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(ScopesContextKey).([]string)
+	return scopes, ok
+}
+
 // OptionalAuthMiddleware creates middleware that doesn't require authentication
 // but will authenticate the user if a token is provided
 func OptionalAuthMiddleware(jwtService *JWTService, appLogger *logger.Logger) func(http.Handler) http.Handler {
@@ -716,3 +716,26 @@ func TestMiddlewareChain(t *testing.T) {
 		assert.Contains(t, recorder.Body.String(), "insufficient permissions")
 	})
 }
+
+func TestAuthMiddlewareExcludePrefixes(t *testing.T) {
+	middleware := NewAuthMiddleware(AuthConfig{
+		ExcludePaths:    []string{"/health"},
+		ExcludePrefixes: []string{"/api/v1/public/"},
+	})
+	handler := middleware.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for path, want := range map[string]int{
+		"/health":                   http.StatusOK,
+		"/api/v1/public/catalog/42": http.StatusOK,
+		"/api/v1/public":            http.StatusUnauthorized,
+		"/api/v1/cis":               http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(APIKeyHeader, "key")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, want, recorder.Code, path)
+	}
+}
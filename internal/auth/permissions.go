@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// DefaultPermissionCacheTTL is how long role permissions are cached when no TTL is configured
+const DefaultPermissionCacheTTL = 5 * time.Minute
+
+// permissionCachePrefix prefixes the cache key of each role's permissions
+const permissionCachePrefix = "auth:role_permissions:"
+
+// permissionCacheKey returns the cache key of a role's permissions for callers
+// acting for the tenant. Organizations may define roles of the same name, so
+// each tenant caches its own.
+func permissionCacheKey(tenant, role string) string {
+	if tenant == "" {
+		return permissionCachePrefix + role
+	}
+	return permissionCachePrefix + tenant + ":" + role
+}
+
+// PermissionResolver returns the permissions granted by a set of roles
+type PermissionResolver interface {
+	Permissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// RolePermissionStore loads roles and the permissions granted to them
+type RolePermissionStore interface {
+	GetRoleByName(ctx context.Context, name string) (*models.Role, error)
+	GetRolePermissionNames(ctx context.Context, roleID uuid.UUID) ([]string, error)
+}
+
+// PermissionCache stores the permissions of roles between requests. It is
+// satisfied by database.RedisClient.
+type PermissionCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	SetWithTTL(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Keys(ctx context.Context, pattern string) ([]string, error)
+}
+
+// CachedPermissionResolver resolves permissions from the role store, caching
+// each role's permissions so that authorizing a request does not query the
+// database. Changes to a role take effect once it is invalidated, or else
+// once the cached entries expire.
+type CachedPermissionResolver struct {
+	roles RolePermissionStore
+	cache PermissionCache
+	ttl   time.Duration
+}
+
+// NewCachedPermissionResolver creates a new CachedPermissionResolver. A nil
+// cache loads permissions on every call; a ttl of 0 uses DefaultPermissionCacheTTL.
+func NewCachedPermissionResolver(roles RolePermissionStore, cache PermissionCache, ttl time.Duration) *CachedPermissionResolver {
+	if ttl <= 0 {
+		ttl = DefaultPermissionCacheTTL
+	}
+	return &CachedPermissionResolver{roles: roles, cache: cache, ttl: ttl}
+}
+
+// Permissions returns the permissions granted by the given roles. Inactive
+// roles grant nothing.
+func (r *CachedPermissionResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		rolePermissions, err := r.rolePermissions(ctx, role)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, rolePermissions...)
+	}
+	return permissions, nil
+}
+
+func (r *CachedPermissionResolver) rolePermissions(ctx context.Context, name string) ([]string, error) {
+	key := permissionCacheKey(visibility.TenantFromContext(ctx), name)
+	if r.cache != nil {
+		// Misses and cache failures both fall back to the role store
+		if cached, err := r.cache.Get(ctx, key); err == nil {
+			var permissions []string
+			if err := json.Unmarshal([]byte(cached), &permissions); err == nil {
+				return permissions, nil
+			}
+		}
+	}
+
+	role, err := r.roles.GetRoleByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role %s: %w", name, err)
+	}
+
+	permissions := []string{}
+	if role.IsActive {
+		permissions, err = r.roles.GetRolePermissionNames(ctx, role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get permissions of role %s: %w", name, err)
+		}
+	}
+
+	if r.cache != nil {
+		if data, err := json.Marshal(permissions); err == nil {
+			// A failed write only costs a reload on the next request
+			_ = r.cache.SetWithTTL(ctx, key, string(data), r.ttl)
+		}
+	}
+	return permissions, nil
+}
+
+// Invalidate drops the cached permissions of a role for callers acting for
+// any tenant, so that changes to the role apply from the next request
+func (r *CachedPermissionResolver) Invalidate(ctx context.Context, role string) error {
+	if r.cache == nil {
+		return nil
+	}
+
+	keys, err := r.cache.Keys(ctx, permissionCachePrefix+"*:"+globEscaper.Replace(role))
+	if err != nil {
+		return fmt.Errorf("failed to list cached permissions of role %s: %w", role, err)
+	}
+	keys = append(keys, permissionCacheKey("", role))
+	for _, key := range keys {
+		if err := r.cache.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to invalidate cached permissions of role %s: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// globEscaper escapes the characters Redis key patterns treat specially
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// staticPermissionResolver resolves permissions from a fixed role mapping
+type staticPermissionResolver map[string][]string
+
+func (s staticPermissionResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, s[role]...)
+	}
+	return permissions, nil
+}
+
+// defaultRolePermissions grants the permissions of the seeded roles. It is
+// used when the middleware is not given a PermissionResolver.
+var defaultRolePermissions = staticPermissionResolver{
+	"admin": {
+		"ci:create", "ci:read", "ci:update", "ci:delete",
+		"relationship:manage", "audit_log:read", "user:manage", "import:csv",
+	},
+	"ci_manager": {
+		"ci:create", "ci:read", "ci:update", "ci:delete",
+		"relationship:manage", "import:csv",
+	},
+	"viewer": {
+		"ci:read",
+	},
+	"auditor": {
+		"ci:read", "audit_log:read",
+	},
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"connect/internal/logger"
+	"connect/internal/models"
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRoleStore struct {
+	roles       map[string]*models.Role
+	permissions map[uuid.UUID][]string
+	loads       int
+}
+
+func (s *fakeRoleStore) GetRoleByName(ctx context.Context, name string) (*models.Role, error) {
+	role, ok := s.roles[name]
+	if !ok {
+		return nil, errors.New("role not found")
+	}
+	return role, nil
+}
+
+func (s *fakeRoleStore) GetRolePermissionNames(ctx context.Context, roleID uuid.UUID) ([]string, error) {
+	s.loads++
+	return s.permissions[roleID], nil
+}
+
+type fakePermissionCache struct {
+	values map[string]string
+	ttls   map[string]time.Duration
+}
+
+func (c *fakePermissionCache) Get(ctx context.Context, key string) (string, error) {
+	value, ok := c.values[key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return value, nil
+}
+
+func (c *fakePermissionCache) SetWithTTL(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.values[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *fakePermissionCache) Delete(ctx context.Context, key string) error {
+	delete(c.values, key)
+	return nil
+}
+
+func (c *fakePermissionCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	for key := range c.values {
+		if matched, _ := path.Match(pattern, key); matched {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func newFakeRoleStore() *fakeRoleStore {
+	editor := &models.Role{ID: uuid.New(), Name: "editor", IsActive: true}
+	retired := &models.Role{ID: uuid.New(), Name: "retired", IsActive: false}
+	return &fakeRoleStore{
+		roles: map[string]*models.Role{"editor": editor, "retired": retired},
+		permissions: map[uuid.UUID][]string{
+			editor.ID:  {"ci:read", "ci:update"},
+			retired.ID: {"ci:delete"},
+		},
+	}
+}
+
+func TestCachedPermissionResolver(t *testing.T) {
+	store := newFakeRoleStore()
+	cache := &fakePermissionCache{values: map[string]string{}, ttls: map[string]time.Duration{}}
+	resolver := NewCachedPermissionResolver(store, cache, 0)
+
+	permissions, err := resolver.Permissions(context.Background(), []string{"editor", "retired"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci:read", "ci:update"}, permissions)
+	assert.Equal(t, 1, store.loads)
+	assert.Equal(t, DefaultPermissionCacheTTL, cache.ttls["auth:role_permissions:editor"])
+
+	// Cached permissions are served without loading the role again
+	permissions, err = resolver.Permissions(context.Background(), []string{"editor"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci:read", "ci:update"}, permissions)
+	assert.Equal(t, 1, store.loads)
+
+	// Callers acting for an organization cache its roles apart
+	permissions, err = resolver.Permissions(visibility.WithTenant(context.Background(), "acme"), []string{"editor"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci:read", "ci:update"}, permissions)
+	assert.Equal(t, 2, store.loads)
+	assert.Contains(t, cache.values, "auth:role_permissions:acme:editor")
+
+	_, err = resolver.Permissions(context.Background(), []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestCachedPermissionResolverInvalidate(t *testing.T) {
+	store := newFakeRoleStore()
+	cache := &fakePermissionCache{values: map[string]string{}, ttls: map[string]time.Duration{}}
+	resolver := NewCachedPermissionResolver(store, cache, 0)
+
+	acme := visibility.WithTenant(context.Background(), "acme")
+	for _, ctx := range []context.Context{context.Background(), acme} {
+		_, err := resolver.Permissions(ctx, []string{"editor", "retired"})
+		require.NoError(t, err)
+	}
+	require.Len(t, cache.values, 4)
+
+	// A role's permissions are dropped for every tenant, other roles stay cached
+	require.NoError(t, resolver.Invalidate(context.Background(), "editor"))
+	assert.NotContains(t, cache.values, "auth:role_permissions:editor")
+	assert.NotContains(t, cache.values, "auth:role_permissions:acme:editor")
+	assert.Contains(t, cache.values, "auth:role_permissions:retired")
+	assert.Contains(t, cache.values, "auth:role_permissions:acme:retired")
+
+	// The next request loads the changed permissions
+	editor := store.roles["editor"]
+	store.permissions[editor.ID] = []string{"ci:read"}
+	permissions, err := resolver.Permissions(acme, []string{"editor"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci:read"}, permissions)
+
+	// Without a cache there is nothing to invalidate
+	assert.NoError(t, NewCachedPermissionResolver(store, nil, 0).Invalidate(context.Background(), "editor"))
+}
+
+func TestCachedPermissionResolverWithoutCache(t *testing.T) {
+	store := newFakeRoleStore()
+	resolver := NewCachedPermissionResolver(store, nil, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		permissions, err := resolver.Permissions(context.Background(), []string{"editor"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ci:read", "ci:update"}, permissions)
+	}
+	assert.Equal(t, 2, store.loads)
+}
+
+func TestRequireMethodPermission(t *testing.T) {
+	middleware := NewAuthMiddleware(AuthConfig{
+		Logger:      logger.NewLogger("test"),
+		Permissions: NewCachedPermissionResolver(newFakeRoleStore(), nil, 0),
+	})
+	handler := middleware.RequireMethodPermission(MethodPermissions{
+		http.MethodPut:    "ci:update",
+		http.MethodDelete: "ci:delete",
+	}, "ci:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method string
+		roles  []string
+		status int
+	}{
+		{http.MethodGet, []string{"editor"}, http.StatusOK},
+		{http.MethodPut, []string{"editor"}, http.StatusOK},
+		{http.MethodDelete, []string{"editor"}, http.StatusForbidden},
+		{http.MethodDelete, []string{"retired"}, http.StatusForbidden},
+		{http.MethodGet, []string{"unknown"}, http.StatusInternalServerError},
+		{http.MethodGet, nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/cis", nil)
+		if tt.roles != nil {
+			req = req.WithContext(context.WithValue(req.Context(), RolesContextKey, tt.roles))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, "%s as %v", tt.method, tt.roles)
+	}
+}
+
+func TestRequirePermissionDefaultsToSeededRoles(t *testing.T) {
+	middleware := NewAuthMiddleware(AuthConfig{Logger: logger.NewLogger("test")})
+	handler := middleware.RequirePermission("relationship:manage")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for role, status := range map[string]int{"ci_manager": http.StatusOK, "viewer": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/relationships", nil)
+		req = req.WithContext(context.WithValue(req.Context(), RolesContextKey, []string{role}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, role)
+	}
+}
+
+func TestRequireMethodPermissionWithScopes(t *testing.T) {
+	middleware := NewAuthMiddleware(AuthConfig{
+		Logger:      logger.NewLogger("test"),
+		Permissions: NewCachedPermissionResolver(newFakeRoleStore(), nil, 0),
+	})
+	handler := middleware.RequirePermission("ci:update")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// API keys are granted their scopes alone, whatever roles are present
+	for _, tt := range []struct {
+		scopes []string
+		status int
+	}{
+		{[]string{"ci:read", "ci:update"}, http.StatusOK},
+		{[]string{"ci:read"}, http.StatusForbidden},
+		{[]string{}, http.StatusForbidden},
+	} {
+		ctx := context.WithValue(context.Background(), RolesContextKey, []string{"editor"})
+		ctx = context.WithValue(ctx, ScopesContextKey, tt.scopes)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/cis", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, "%v", tt.scopes)
+	}
+}
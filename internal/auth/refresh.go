@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	ErrRefreshTokenUnknown = errors.New("refresh token not found")
+)
+
+// Security event types
+const (
+	SecurityEventRefreshTokenReuse   = "refresh_token_reuse"
+	SecurityEventFingerprintMismatch = "session_fingerprint_mismatch"
+)
+
+// RefreshTokenRecord tracks an issued refresh token. Every token descends from a
+// login; the tokens rotated from the same login share a family.
+type RefreshTokenRecord struct {
+	ID           uuid.UUID
+	FamilyID     uuid.UUID
+	UserID       string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+	RotatedAt    *time.Time
+	ReplacedBy   *uuid.UUID
+	RevokedAt    *time.Time
+	RevokeReason string
+}
+
+// RefreshTokenStore persists refresh token records
+type RefreshTokenStore interface {
+	CreateRefreshToken(ctx context.Context, record *RefreshTokenRecord) error
+	GetRefreshToken(ctx context.Context, id uuid.UUID) (*RefreshTokenRecord, error)
+	// MarkRefreshTokenRotated marks a token as replaced unless it was already
+	// rotated or revoked, and reports whether it did
+	MarkRefreshTokenRotated(ctx context.Context, id, replacedBy uuid.UUID, at time.Time) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID, reason string, at time.Time) (int, error)
+}
+
+// SecurityEvent describes suspicious authentication activity
+type SecurityEvent struct {
+	Type       string
+	UserID     string
+	Details    map[string]interface{}
+	IPAddress  string
+	UserAgent  string
+	OccurredAt time.Time
+}
+
+// SecurityEventRecorder records security events
+type SecurityEventRecorder interface {
+	RecordSecurityEvent(ctx context.Context, event SecurityEvent) error
+}
+
+// ClientInfo identifies the client presenting a token
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+	DeviceID  string
+}
+
+// TokenPair is an access token with the refresh token that replaces it
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// RefreshTokenRotator issues single-use refresh tokens. Each refresh returns a
+// new refresh token and invalidates the one presented. Presenting a token that
+// was already rotated means it was copied, so the whole family is revoked and
+// a security event is raised.
+type RefreshTokenRotator struct {
+	jwtService *JWTService
+	store      RefreshTokenStore
+	events     SecurityEventRecorder
+	now        func() time.Time
+}
+
+// NewRefreshTokenRotator creates a new refresh token rotator
+func NewRefreshTokenRotator(jwtService *JWTService, store RefreshTokenStore, events SecurityEventRecorder) *RefreshTokenRotator {
+	return &RefreshTokenRotator{
+		jwtService: jwtService,
+		store:      store,
+		events:     events,
+		now:        time.Now,
+	}
+}
+
+// Issue starts a new token family, e.g. on login
+func (r *RefreshTokenRotator) Issue(ctx context.Context, userID, username string, roles []string) (*TokenPair, error) {
+	return r.IssueForOrg(ctx, userID, username, roles, "")
+}
+
+// IssueForOrg starts a new token family for a user acting for an
+// organization, e.g. on login or when switching organizations. Rotated
+// tokens keep acting for it.
+func (r *RefreshTokenRotator) IssueForOrg(ctx context.Context, userID, username string, roles []string, org string) (*TokenPair, error) {
+	return r.issue(ctx, uuid.New(), uuid.New(), userID, username, roles, org)
+}
+
+// Rotate exchanges a refresh token for a new token pair
+func (r *RefreshTokenRotator) Rotate(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	claims, err := r.jwtService.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		// Tokens issued before rotation carry no jti and cannot be tracked
+		return nil, ErrInvalidToken
+	}
+
+	record, err := r.store.GetRefreshToken(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if record.RevokedAt != nil {
+		return nil, ErrRefreshTokenRevoked
+	}
+	if record.RotatedAt != nil {
+		return nil, r.reused(ctx, record, client)
+	}
+
+	nextID := uuid.New()
+	rotated, err := r.store.MarkRefreshTokenRotated(ctx, record.ID, nextID, r.now())
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request rotated the token first: the token was used twice
+		return nil, r.reused(ctx, record, client)
+	}
+
+	return r.issue(ctx, nextID, record.FamilyID, claims.UserID, claims.Username, claims.Roles, claims.Org)
+}
+
+// reused revokes the family of a replayed token and raises a security event
+func (r *RefreshTokenRotator) reused(ctx context.Context, record *RefreshTokenRecord, client ClientInfo) error {
+	now := r.now()
+	revoked, err := r.store.RevokeRefreshTokenFamily(ctx, record.FamilyID, SecurityEventRefreshTokenReuse, now)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	if r.events != nil {
+		event := SecurityEvent{
+			Type:   SecurityEventRefreshTokenReuse,
+			UserID: record.UserID,
+			Details: map[string]interface{}{
+				"token_id":       record.ID.String(),
+				"family_id":      record.FamilyID.String(),
+				"revoked_tokens": revoked,
+			},
+			IPAddress:  client.IPAddress,
+			UserAgent:  client.UserAgent,
+			OccurredAt: now,
+		}
+		if err := r.events.RecordSecurityEvent(ctx, event); err != nil {
+			return fmt.Errorf("failed to record security event: %w", err)
+		}
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// issue generates a token pair and records the refresh token as part of familyID
+func (r *RefreshTokenRotator) issue(ctx context.Context, tokenID, familyID uuid.UUID, userID, username string, roles []string, org string) (*TokenPair, error) {
+	accessToken, err := r.jwtService.GenerateOrgAccessToken(userID, username, roles, org)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := r.jwtService.GenerateOrgRefreshToken(userID, username, roles, org, tokenID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	now := r.now()
+	record := &RefreshTokenRecord{
+		ID:        tokenID,
+		FamilyID:  familyID,
+		UserID:    userID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(r.jwtService.RefreshTTL()),
+	}
+	if err := r.store.CreateRefreshToken(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRefreshTokenStore struct {
+	mu      sync.Mutex
+	records map[uuid.UUID]*RefreshTokenRecord
+}
+
+func (s *memoryRefreshTokenStore) CreateRefreshToken(ctx context.Context, record *RefreshTokenRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *record
+	s.records[record.ID] = &copied
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) GetRefreshToken(ctx context.Context, id uuid.UUID) (*RefreshTokenRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return nil, ErrRefreshTokenUnknown
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (s *memoryRefreshTokenStore) MarkRefreshTokenRotated(ctx context.Context, id, replacedBy uuid.UUID, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[id]
+	if !ok || record.RotatedAt != nil || record.RevokedAt != nil {
+		return false, nil
+	}
+	record.RotatedAt = &at
+	record.ReplacedBy = &replacedBy
+	return true, nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID, reason string, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	revoked := 0
+	for _, record := range s.records {
+		if record.FamilyID == familyID && record.RevokedAt == nil {
+			record.RevokedAt = &at
+			record.RevokeReason = reason
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+type memorySecurityEvents struct {
+	events []SecurityEvent
+}
+
+func (r *memorySecurityEvents) RecordSecurityEvent(ctx context.Context, event SecurityEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestRefreshTokenRotator(t *testing.T) {
+	ctx := context.Background()
+	jwtService := NewJWTService("test-secret-key-that-is-at-least-32-characters-long", 15*time.Minute, time.Hour)
+	store := &memoryRefreshTokenStore{records: map[uuid.UUID]*RefreshTokenRecord{}}
+	events := &memorySecurityEvents{}
+	rotator := NewRefreshTokenRotator(jwtService, store, events)
+
+	login, err := rotator.Issue(ctx, "user-1", "alice", []string{"viewer"})
+	require.NoError(t, err)
+
+	rotated, err := rotator.Rotate(ctx, login.RefreshToken, ClientInfo{IPAddress: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, rotated.RefreshToken)
+
+	claims, err := jwtService.ValidateToken(rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+
+	t.Run("reuse revokes the family", func(t *testing.T) {
+		_, err := rotator.Rotate(ctx, login.RefreshToken, ClientInfo{IPAddress: "203.0.113.9", UserAgent: "curl"})
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		require.Len(t, events.events, 1)
+		event := events.events[0]
+		assert.Equal(t, SecurityEventRefreshTokenReuse, event.Type)
+		assert.Equal(t, "user-1", event.UserID)
+		assert.Equal(t, "203.0.113.9", event.IPAddress)
+		assert.Equal(t, 2, event.Details["revoked_tokens"])
+
+		// The legitimate holder's current token is revoked too
+		_, err = rotator.Rotate(ctx, rotated.RefreshToken, ClientInfo{})
+		assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+	})
+
+	t.Run("other families are unaffected", func(t *testing.T) {
+		other, err := rotator.Issue(ctx, "user-1", "alice", []string{"viewer"})
+		require.NoError(t, err)
+		_, err = rotator.Rotate(ctx, other.RefreshToken, ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("untracked tokens are rejected", func(t *testing.T) {
+		legacy, err := jwtService.GenerateRefreshToken("user-1", "alice", []string{"viewer"})
+		require.NoError(t, err)
+		_, err = rotator.Rotate(ctx, legacy, ClientInfo{})
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
@@ -0,0 +1,294 @@
+package autotag
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	mu    sync.Mutex
+	rules map[uuid.UUID]*Rule
+	cis   map[uuid.UUID]*CITags
+	lists int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{rules: map[uuid.UUID]*Rule{}, cis: map[uuid.UUID]*CITags{}}
+}
+
+func (m *memoryStore) ListRules(ctx context.Context) ([]*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lists++
+	rules := []*Rule{}
+	for _, rule := range m.rules {
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	return rules, nil
+}
+
+func (m *memoryStore) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, ErrRuleNotFound
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+func (m *memoryStore) CreateRule(ctx context.Context, rule *Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) UpdateRule(ctx context.Context, rule *Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rules[rule.ID]; !ok {
+		return ErrRuleNotFound
+	}
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rules[id]; !ok {
+		return ErrRuleNotFound
+	}
+	delete(m.rules, id)
+	return nil
+}
+
+func (m *memoryStore) ListCITags(ctx context.Context, after uuid.UUID, limit int) ([]CITags, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var cis []CITags
+	for _, ci := range m.cis {
+		if ci.ID.String() > after.String() {
+			cis = append(cis, *ci)
+		}
+	}
+	sort.Slice(cis, func(i, j int) bool { return cis[i].ID.String() < cis[j].ID.String() })
+	if len(cis) > limit {
+		cis = cis[:limit]
+	}
+	return cis, nil
+}
+
+func (m *memoryStore) UpdateCITags(ctx context.Context, id uuid.UUID, previous, tags []string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ci, ok := m.cis[id]
+	if !ok || !equal(ci.Tags, previous) {
+		return false, nil
+	}
+	ci.Tags = tags
+	return true, nil
+}
+
+func (m *memoryStore) addCI(attributes map[string]interface{}, tags ...string) uuid.UUID {
+	raw, _ := json.Marshal(attributes)
+	id := uuid.New()
+	m.cis[id] = &CITags{ID: id, Name: id.String()[:8], Attributes: raw, Tags: tags}
+	return id
+}
+
+func (m *memoryStore) tags(id uuid.UUID) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cis[id].Tags
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func windowsRule() *Rule {
+	return &Rule{Name: "Windows hosts", Attribute: "os", Operator: OperatorContains, Value: "windows", Tag: "windows", RemoveUnmatched: true, Enabled: true}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"missing name", Rule{Attribute: "os", Operator: OperatorExists, Tag: "os"}},
+		{"bad path", Rule{Name: "r", Attribute: "os..name", Operator: OperatorExists, Tag: "os"}},
+		{"tag with space", Rule{Name: "r", Attribute: "os", Operator: OperatorExists, Tag: "two words"}},
+		{"unknown operator", Rule{Name: "r", Attribute: "os", Operator: "starts_with", Value: "x", Tag: "os"}},
+		{"missing value", Rule{Name: "r", Attribute: "os", Operator: OperatorEquals, Tag: "os"}},
+		{"bad pattern", Rule{Name: "r", Attribute: "os", Operator: OperatorMatches, Value: "(", Tag: "os"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.rule.Validate(), ErrInvalidRule)
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	attributes := map[string]interface{}{
+		"os":       "Microsoft Windows Server 2022",
+		"cores":    float64(16),
+		"hardware": map[string]interface{}{"vendor": "Dell"},
+		"roles":    []interface{}{"web", "cache"},
+	}
+
+	tests := []struct {
+		rule Rule
+		want bool
+	}{
+		{Rule{Attribute: "os", Operator: OperatorContains, Value: "WINDOWS"}, true},
+		{Rule{Attribute: "os", Operator: OperatorEquals, Value: "windows"}, false},
+		{Rule{Attribute: "cores", Operator: OperatorEquals, Value: "16"}, true},
+		{Rule{Attribute: "hardware.vendor", Operator: OperatorEquals, Value: "dell"}, true},
+		{Rule{Attribute: "hardware.model", Operator: OperatorExists}, false},
+		{Rule{Attribute: "roles", Operator: OperatorEquals, Value: "cache"}, true},
+		{Rule{Attribute: "os", Operator: OperatorMatches, Value: `Server 20(19|22)$`}, true},
+		{Rule{Attribute: "os.name", Operator: OperatorExists}, false},
+	}
+
+	for _, tt := range tests {
+		rule := tt.rule
+		rule.Name, rule.Tag = "r", "t"
+		require.NoError(t, rule.Validate())
+		assert.Equal(t, tt.want, rule.Matches(attributes), "%s %s %q", rule.Attribute, rule.Operator, rule.Value)
+	}
+}
+
+func TestApply(t *testing.T) {
+	rule := windowsRule()
+	require.NoError(t, rule.Validate())
+	linux := &Rule{Name: "Linux", Attribute: "os", Operator: OperatorContains, Value: "linux", Tag: "linux", Enabled: true}
+	require.NoError(t, linux.Validate())
+	rules := []*Rule{rule, linux}
+
+	tags, change := Apply(rules, json.RawMessage(`{"os": "Windows 11"}`), []string{"prod"})
+	assert.Equal(t, []string{"prod", "windows"}, tags)
+	assert.Equal(t, []string{"windows"}, change.Added)
+
+	// Only rules asking for it remove their tag
+	tags, change = Apply(rules, json.RawMessage(`{"os": "macOS"}`), []string{"prod", "windows", "linux"})
+	assert.Equal(t, []string{"prod", "linux"}, tags)
+	assert.Equal(t, []string{"windows"}, change.Removed)
+
+	// A tag stays when any rule for it matches
+	other := &Rule{Name: "Workstations", Attribute: "class", Operator: OperatorEquals, Value: "workstation", Tag: "windows", RemoveUnmatched: true, Enabled: true}
+	require.NoError(t, other.Validate())
+	tags, change = Apply([]*Rule{rule, other}, json.RawMessage(`{"os": "Windows 11"}`), []string{"windows"})
+	assert.Equal(t, []string{"windows"}, tags)
+	assert.True(t, change.IsEmpty())
+}
+
+func TestService_ApplyReloadsAfterChanges(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, time.Hour)
+	ctx := context.Background()
+
+	tags, _, err := service.Apply(ctx, []byte(`{"os": "Windows"}`), nil)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	created, err := service.CreateRule(ctx, windowsRule(), "admin")
+	require.NoError(t, err)
+
+	tags, change, err := service.Apply(ctx, []byte(`{"os": "Windows"}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"windows"}, tags)
+	assert.Equal(t, []string{"windows"}, change.Added)
+
+	// Cached until the rules change
+	lists := store.lists
+	_, _, err = service.Apply(ctx, []byte(`{"os": "Windows"}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, lists, store.lists)
+
+	created.Enabled = false
+	_, err = service.UpdateRule(ctx, created, "admin")
+	require.NoError(t, err)
+	tags, _, err = service.Apply(ctx, []byte(`{"os": "Windows"}`), nil)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+}
+
+func TestService_CreateRuleRejectsInvalid(t *testing.T) {
+	service := NewService(newMemoryStore(), 0)
+	_, err := service.CreateRule(context.Background(), &Rule{Name: "r"}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidRule)
+}
+
+func TestService_DryRun(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, 0)
+	service.pageSize = 2
+	store.addCI(map[string]interface{}{"os": "Windows Server"})
+	store.addCI(map[string]interface{}{"os": "Windows 10"}, "windows")
+	store.addCI(map[string]interface{}{"os": "Ubuntu"}, "windows")
+	store.addCI(nil)
+
+	// A rule under test needs not be saved nor enabled
+	rule := windowsRule()
+	rule.Enabled = false
+	result, err := service.DryRun(context.Background(), []*Rule{rule})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, result.Scanned)
+	assert.Equal(t, 2, result.Changed)
+	assert.Equal(t, map[string]int{"windows": 1}, result.Added)
+	assert.Equal(t, map[string]int{"windows": 1}, result.Removed)
+	assert.Len(t, result.Samples, 2)
+}
+
+func TestService_Backfill(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, 0)
+	service.pageSize = 2
+	windows := store.addCI(map[string]interface{}{"os": "Windows Server"}, "prod")
+	ubuntu := store.addCI(map[string]interface{}{"os": "Ubuntu"}, "windows")
+	store.addCI(map[string]interface{}{"os": "Ubuntu"})
+
+	_, err := service.CreateRule(context.Background(), windowsRule(), "admin")
+	require.NoError(t, err)
+
+	started, err := service.StartBackfill("admin")
+	require.NoError(t, err)
+	assert.Equal(t, BackfillRunning, started.Status)
+
+	require.Eventually(t, func() bool {
+		return service.Backfill().Status != BackfillRunning
+	}, time.Second, 10*time.Millisecond)
+
+	backfill := service.Backfill()
+	assert.Equal(t, BackfillCompleted, backfill.Status)
+	assert.Equal(t, 3, backfill.Scanned)
+	assert.Equal(t, 2, backfill.Updated)
+	assert.Equal(t, []string{"prod", "windows"}, store.tags(windows))
+	assert.Empty(t, store.tags(ubuntu))
+}
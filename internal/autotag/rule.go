@@ -0,0 +1,223 @@
+// Package autotag applies and removes CI tags from attribute values. Rules are
+// evaluated whenever a CI is written, can be tried against the existing CIs
+// with a dry run, and applied to them all with a backfill.
+//
+// A rule adds its tag to every CI whose attribute matches, e.g.
+//
+//	attribute "os", operator "contains", value "Windows" → tag "windows"
+//
+// and, when RemoveUnmatched is set, removes the tag from CIs that no longer
+// match. A tag is kept when any enabled rule for it matches.
+package autotag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rule operators
+const (
+	OperatorEquals   = "equals"
+	OperatorContains = "contains"
+	OperatorMatches  = "matches"
+	OperatorExists   = "exists"
+)
+
+var (
+	ErrInvalidRule  = errors.New("invalid auto-tagging rule")
+	ErrRuleNotFound = errors.New("auto-tagging rule not found")
+)
+
+// Rule tags the CIs whose attribute matches a condition. Attribute is a dot
+// separated path into the CI attributes, e.g. "os" or "hardware.vendor".
+// equals and contains compare case-insensitively; matches takes a regular
+// expression. Values in arrays match when any element does.
+type Rule struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	Name            string    `json:"name" db:"name"`
+	Attribute       string    `json:"attribute" db:"attribute"`
+	Operator        string    `json:"operator" db:"operator"`
+	Value           string    `json:"value" db:"value"`
+	Tag             string    `json:"tag" db:"tag"`
+	RemoveUnmatched bool      `json:"remove_unmatched" db:"remove_unmatched"`
+	Enabled         bool      `json:"enabled" db:"enabled"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	UpdatedBy       string    `json:"updated_by" db:"updated_by"`
+
+	pattern *regexp.Regexp
+}
+
+// Validate checks the rule and prepares it for evaluation
+func (r *Rule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Attribute = strings.TrimSpace(r.Attribute)
+	r.Tag = strings.TrimSpace(r.Tag)
+
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if r.Attribute == "" || strings.Contains(r.Attribute, "..") || strings.HasPrefix(r.Attribute, ".") || strings.HasSuffix(r.Attribute, ".") {
+		return fmt.Errorf("%w: invalid attribute path %q", ErrInvalidRule, r.Attribute)
+	}
+	if r.Tag == "" || strings.ContainsAny(r.Tag, ", ") {
+		return fmt.Errorf("%w: tag must be a single word", ErrInvalidRule)
+	}
+
+	switch r.Operator {
+	case OperatorEquals, OperatorContains:
+		if r.Value == "" {
+			return fmt.Errorf("%w: %s needs a value", ErrInvalidRule, r.Operator)
+		}
+	case OperatorMatches:
+		pattern, err := regexp.Compile(r.Value)
+		if err != nil {
+			return fmt.Errorf("%w: invalid pattern: %v", ErrInvalidRule, err)
+		}
+		r.pattern = pattern
+	case OperatorExists:
+	default:
+		return fmt.Errorf("%w: unknown operator %q", ErrInvalidRule, r.Operator)
+	}
+
+	return nil
+}
+
+// Matches reports whether the rule condition holds for a CI's attributes. The
+// rule must have been validated.
+func (r *Rule) Matches(attributes map[string]interface{}) bool {
+	value, ok := lookup(attributes, r.Attribute)
+	if !ok || value == nil {
+		return false
+	}
+	if r.Operator == OperatorExists {
+		return true
+	}
+
+	if values, ok := value.([]interface{}); ok {
+		for _, v := range values {
+			if r.matchesValue(v) {
+				return true
+			}
+		}
+		return false
+	}
+	return r.matchesValue(value)
+}
+
+// matchesValue compares a single attribute value
+func (r *Rule) matchesValue(value interface{}) bool {
+	text, ok := scalar(value)
+	if !ok {
+		return false
+	}
+
+	switch r.Operator {
+	case OperatorEquals:
+		return strings.EqualFold(text, r.Value)
+	case OperatorContains:
+		return strings.Contains(strings.ToLower(text), strings.ToLower(r.Value))
+	case OperatorMatches:
+		return r.pattern != nil && r.pattern.MatchString(text)
+	}
+	return false
+}
+
+// lookup follows a dot separated path into nested attributes
+func lookup(attributes map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = attributes
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// scalar renders a JSON scalar as text
+func scalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// Change describes the tags rules added to and removed from a CI
+type Change struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// IsEmpty reports whether the rules left the tags unchanged
+func (c Change) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// Apply evaluates the enabled rules against a CI's attributes and returns its
+// resulting tags along with the change. Tags no rule manages are kept as they are.
+func Apply(rules []*Rule, attributes json.RawMessage, tags []string) ([]string, Change) {
+	var parsed map[string]interface{}
+	if len(attributes) > 0 {
+		// Attributes that are not an object match nothing
+		_ = json.Unmarshal(attributes, &parsed)
+	}
+
+	matched := map[string]bool{}
+	removable := map[string]bool{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		if rule.Matches(parsed) {
+			matched[rule.Tag] = true
+		} else if rule.RemoveUnmatched {
+			removable[rule.Tag] = true
+		}
+	}
+
+	var change Change
+	result := make([]string, 0, len(tags)+len(matched))
+	present := map[string]bool{}
+	for _, tag := range tags {
+		if removable[tag] && !matched[tag] {
+			if !present[tag] {
+				change.Removed = append(change.Removed, tag)
+			}
+			present[tag] = true
+			continue
+		}
+		if !present[tag] {
+			result = append(result, tag)
+			present[tag] = true
+		}
+	}
+
+	var added []string
+	for tag := range matched {
+		if !present[tag] {
+			added = append(added, tag)
+		}
+	}
+	sort.Strings(added)
+	change.Added = added
+	result = append(result, added...)
+
+	return result, change
+}
@@ -0,0 +1,353 @@
+package autotag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Evaluation defaults
+const (
+	// DefaultCacheTTL bounds how long rules are reused before being reloaded, so
+	// changes made through another instance apply within that delay
+	DefaultCacheTTL = time.Minute
+	// DefaultPageSize is the number of CIs read at a time by dry runs and backfills
+	DefaultPageSize = 500
+	// MaxDryRunSamples bounds the CI changes listed by a dry run
+	MaxDryRunSamples = 100
+)
+
+// Backfill statuses
+const (
+	BackfillRunning   = "running"
+	BackfillCompleted = "completed"
+	BackfillFailed    = "failed"
+)
+
+// ErrBackfillRunning is returned when a backfill is requested while one runs
+var ErrBackfillRunning = errors.New("auto-tagging backfill already running")
+
+// CIChange is the change rules make to one CI
+type CIChange struct {
+	CITags
+	Change
+}
+
+// DryRunResult reports what rules would change across the existing CIs
+type DryRunResult struct {
+	Scanned int `json:"scanned"`
+	Changed int `json:"changed"`
+	// Added and Removed count CIs per tag
+	Added   map[string]int `json:"added"`
+	Removed map[string]int `json:"removed"`
+	// Samples lists the first changed CIs, up to MaxDryRunSamples
+	Samples []CIChange `json:"samples"`
+}
+
+// Backfill is a run applying the rules to every existing CI. Only the latest
+// run is kept, in memory.
+type Backfill struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Scanned     int        `json:"scanned"`
+	Updated     int        `json:"updated"`
+	// Skipped counts CIs edited while the backfill ran; they were tagged on write
+	Skipped int    `json:"skipped"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Service manages rules and applies them
+type Service struct {
+	store    Store
+	cacheTTL time.Duration
+	pageSize int
+	now      func() time.Time
+
+	mu       sync.Mutex
+	rules    []*Rule
+	loadedAt time.Time
+	backfill *Backfill
+}
+
+// NewService creates a new auto-tagging service
+func NewService(store Store, cacheTTL time.Duration) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{
+		store:    store,
+		cacheTTL: cacheTTL,
+		pageSize: DefaultPageSize,
+		now:      time.Now,
+	}
+}
+
+// ListRules retrieves every rule
+func (s *Service) ListRules(ctx context.Context) ([]*Rule, error) {
+	return s.store.ListRules(ctx)
+}
+
+// GetRule retrieves a rule
+func (s *Service) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	return s.store.GetRule(ctx, id)
+}
+
+// CreateRule validates and stores a new rule
+func (s *Service) CreateRule(ctx context.Context, rule *Rule, by string) (*Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	rule.ID = uuid.New()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	rule.UpdatedBy = by
+	if err := s.store.CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Auto-tagging rule %s (%s) created by %s", rule.ID, rule.Name, by)
+	return rule, nil
+}
+
+// UpdateRule validates and replaces a rule
+func (s *Service) UpdateRule(ctx context.Context, rule *Rule, by string) (*Rule, error) {
+	existing, err := s.store.GetRule(ctx, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = s.now()
+	rule.UpdatedBy = by
+	if err := s.store.UpdateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Auto-tagging rule %s (%s) updated by %s", rule.ID, rule.Name, by)
+	return rule, nil
+}
+
+// DeleteRule removes a rule. Tags it applied stay on the CIs.
+func (s *Service) DeleteRule(ctx context.Context, id uuid.UUID, by string) error {
+	if err := s.store.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+
+	s.invalidate()
+	log.Printf("Auto-tagging rule %s deleted by %s", id, by)
+	return nil
+}
+
+// Apply runs the enabled rules against a CI about to be written, given its
+// attributes and tags, and returns the resulting tags
+func (s *Service) Apply(ctx context.Context, attributes []byte, tags []string) ([]string, Change, error) {
+	rules, err := s.enabledRules(ctx)
+	if err != nil {
+		return tags, Change{}, err
+	}
+	result, change := Apply(rules, attributes, tags)
+	return result, change, nil
+}
+
+// DryRun reports what the given rules would change across the existing CIs
+// without writing anything. Without rules, the stored enabled rules are used.
+func (s *Service) DryRun(ctx context.Context, rules []*Rule) (*DryRunResult, error) {
+	if len(rules) == 0 {
+		var err error
+		if rules, err = s.enabledRules(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, rule := range rules {
+			if err := rule.Validate(); err != nil {
+				return nil, err
+			}
+			// A rule under test applies whether or not it is enabled yet
+			rule.Enabled = true
+		}
+	}
+
+	result := &DryRunResult{Added: map[string]int{}, Removed: map[string]int{}, Samples: []CIChange{}}
+	err := s.scan(ctx, func(ci CITags) error {
+		result.Scanned++
+		_, change := Apply(rules, ci.Attributes, ci.Tags)
+		if change.IsEmpty() {
+			return nil
+		}
+
+		result.Changed++
+		for _, tag := range change.Added {
+			result.Added[tag]++
+		}
+		for _, tag := range change.Removed {
+			result.Removed[tag]++
+		}
+		if len(result.Samples) < MaxDryRunSamples {
+			result.Samples = append(result.Samples, CIChange{CITags: ci, Change: change})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StartBackfill applies the enabled rules to every existing CI in the
+// background. Poll Backfill for progress.
+func (s *Service) StartBackfill(requestedBy string) (*Backfill, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.backfill != nil && s.backfill.Status == BackfillRunning {
+		return nil, ErrBackfillRunning
+	}
+
+	s.backfill = &Backfill{
+		ID:          uuid.New(),
+		Status:      BackfillRunning,
+		RequestedBy: requestedBy,
+		StartedAt:   s.now(),
+	}
+	started := *s.backfill
+
+	go s.runBackfill(context.Background(), s.backfill.ID)
+	return &started, nil
+}
+
+// Backfill returns the latest backfill, or nil when none ran since startup
+func (s *Service) Backfill() *Backfill {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.backfill == nil {
+		return nil
+	}
+	backfill := *s.backfill
+	return &backfill
+}
+
+// runBackfill applies the rules CI by CI, recording progress on the backfill
+func (s *Service) runBackfill(ctx context.Context, id uuid.UUID) {
+	progress := func(update func(b *Backfill)) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.backfill != nil && s.backfill.ID == id {
+			update(s.backfill)
+		}
+	}
+
+	// Always read the latest rules
+	s.invalidate()
+	rules, err := s.enabledRules(ctx)
+	if err == nil {
+		err = s.scan(ctx, func(ci CITags) error {
+			tags, change := Apply(rules, ci.Attributes, ci.Tags)
+			if change.IsEmpty() {
+				progress(func(b *Backfill) { b.Scanned++ })
+				return nil
+			}
+
+			updated, err := s.store.UpdateCITags(ctx, ci.ID, ci.Tags, tags, s.now())
+			if err != nil {
+				return err
+			}
+			progress(func(b *Backfill) {
+				b.Scanned++
+				if updated {
+					b.Updated++
+				} else {
+					b.Skipped++
+				}
+			})
+			return nil
+		})
+	}
+
+	completedAt := s.now()
+	progress(func(b *Backfill) {
+		b.CompletedAt = &completedAt
+		if err != nil {
+			b.Status = BackfillFailed
+			b.Error = err.Error()
+		} else {
+			b.Status = BackfillCompleted
+		}
+		log.Printf("Auto-tagging backfill %s %s: %d scanned, %d updated, %d skipped", b.ID, b.Status, b.Scanned, b.Updated, b.Skipped)
+	})
+}
+
+// scan calls fn for every live CI, page by page
+func (s *Service) scan(ctx context.Context, fn func(ci CITags) error) error {
+	after := uuid.Nil
+	for {
+		cis, err := s.store.ListCITags(ctx, after, s.pageSize)
+		if err != nil {
+			return err
+		}
+		for _, ci := range cis {
+			if err := fn(ci); err != nil {
+				return fmt.Errorf("CI %s: %w", ci.ID, err)
+			}
+		}
+		if len(cis) < s.pageSize {
+			return nil
+		}
+		after = cis[len(cis)-1].ID
+	}
+}
+
+// enabledRules returns the enabled rules, reloading them once the cache expires
+func (s *Service) enabledRules(ctx context.Context) ([]*Rule, error) {
+	s.mu.Lock()
+	if s.rules != nil && s.now().Sub(s.loadedAt) < s.cacheTTL {
+		rules := s.rules
+		s.mu.Unlock()
+		return rules, nil
+	}
+	s.mu.Unlock()
+
+	stored, err := s.store.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*Rule, 0, len(stored))
+	for _, rule := range stored {
+		if !rule.Enabled {
+			continue
+		}
+		if err := rule.Validate(); err != nil {
+			log.Printf("Skipping invalid auto-tagging rule %s: %v", rule.ID, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+	return rules, nil
+}
+
+// invalidate drops the cached rules so the next evaluation reloads them
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.rules = nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,160 @@
+package autotag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// CITags is the part of a CI rules read and write
+type CITags struct {
+	ID         uuid.UUID       `json:"id"`
+	Name       string          `json:"name"`
+	Attributes json.RawMessage `json:"-"`
+	Tags       []string        `json:"tags"`
+}
+
+// Store persists rules and gives dry runs and backfills access to the CIs
+type Store interface {
+	ListRules(ctx context.Context) ([]*Rule, error)
+	GetRule(ctx context.Context, id uuid.UUID) (*Rule, error)
+	CreateRule(ctx context.Context, rule *Rule) error
+	UpdateRule(ctx context.Context, rule *Rule) error
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	// ListCITags returns up to limit live CIs with IDs greater than after, ordered by ID
+	ListCITags(ctx context.Context, after uuid.UUID, limit int) ([]CITags, error)
+	// UpdateCITags replaces the tags of a CI unless they changed since they
+	// were read, reporting whether the CI was updated
+	UpdateCITags(ctx context.Context, id uuid.UUID, previous, tags []string, at time.Time) (bool, error)
+}
+
+// PostgresStore keeps rules in the auto_tag_rules table and updates configuration_items
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed rule store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const ruleColumns = `id, name, attribute, operator, value, tag, remove_unmatched, enabled, created_at, updated_at, COALESCE(updated_by, '') AS updated_by`
+
+// ListRules retrieves every rule
+func (s *PostgresStore) ListRules(ctx context.Context) ([]*Rule, error) {
+	rules := []*Rule{}
+	if err := s.db.SelectContext(ctx, &rules, `SELECT `+ruleColumns+` FROM auto_tag_rules ORDER BY tag, name`); err != nil {
+		return nil, fmt.Errorf("failed to list auto-tagging rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRule retrieves a rule
+func (s *PostgresStore) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	var rule Rule
+	if err := s.db.GetContext(ctx, &rule, `SELECT `+ruleColumns+` FROM auto_tag_rules WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get auto-tagging rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// CreateRule inserts a rule
+func (s *PostgresStore) CreateRule(ctx context.Context, rule *Rule) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO auto_tag_rules (id, name, attribute, operator, value, tag, remove_unmatched, enabled, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		rule.ID, rule.Name, rule.Attribute, rule.Operator, rule.Value, rule.Tag,
+		rule.RemoveUnmatched, rule.Enabled, rule.CreatedAt, rule.UpdatedAt, rule.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create auto-tagging rule: %w", err)
+	}
+	return nil
+}
+
+// UpdateRule replaces a rule
+func (s *PostgresStore) UpdateRule(ctx context.Context, rule *Rule) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE auto_tag_rules
+		SET name = $2, attribute = $3, operator = $4, value = $5, tag = $6,
+		    remove_unmatched = $7, enabled = $8, updated_at = $9, updated_by = $10
+		WHERE id = $1`,
+		rule.ID, rule.Name, rule.Attribute, rule.Operator, rule.Value, rule.Tag,
+		rule.RemoveUnmatched, rule.Enabled, rule.UpdatedAt, rule.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update auto-tagging rule: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// DeleteRule removes a rule. Tags it applied stay on the CIs.
+func (s *PostgresStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM auto_tag_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete auto-tagging rule: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// ListCITags retrieves a page of CIs with their attributes and tags
+func (s *PostgresStore) ListCITags(ctx context.Context, after uuid.UUID, limit int) ([]CITags, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, COALESCE(attributes, '{}'::jsonb), COALESCE(tags, '{}')
+		FROM configuration_items
+		WHERE id > $1 AND is_deleted = false
+		ORDER BY id
+		LIMIT $2`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CI tags: %w", err)
+	}
+	defer rows.Close()
+
+	var cis []CITags
+	for rows.Next() {
+		var ci CITags
+		var attributes []byte
+		var tags pq.StringArray
+		if err := rows.Scan(&ci.ID, &ci.Name, &attributes, &tags); err != nil {
+			return nil, fmt.Errorf("failed to scan CI tags: %w", err)
+		}
+		ci.Attributes = attributes
+		ci.Tags = tags
+		cis = append(cis, ci)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list CI tags: %w", err)
+	}
+	return cis, nil
+}
+
+// UpdateCITags replaces the tags of a CI if they still match previous
+func (s *PostgresStore) UpdateCITags(ctx context.Context, id uuid.UUID, previous, tags []string, at time.Time) (bool, error) {
+	// A nil slice would be sent as NULL, which never compares equal
+	if previous == nil {
+		previous = []string{}
+	}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE configuration_items
+		SET tags = $3, updated_at = $4
+		WHERE id = $1 AND is_deleted = false AND COALESCE(tags, '{}') = $2`,
+		id, pq.Array(previous), pq.Array(tags), at)
+	if err != nil {
+		return false, fmt.Errorf("failed to update CI tags: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
@@ -0,0 +1,284 @@
+// Package backpressure throttles low-priority writes while the sync backlog is
+// deeper than the system can propagate. Past the slow threshold such writes
+// are delayed; past the reject threshold they are refused with 429 and the
+// current backlog, so bulk clients back off instead of piling up more events.
+package backpressure
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// PriorityHeader lets a client mark any write as low priority
+const PriorityHeader = "X-Write-Priority"
+
+// AdminPath is the backpressure status API
+const AdminPath = "/api/v1/admin/backpressure"
+
+// Throttling decisions
+const (
+	DecisionAllow  = "allow"
+	DecisionSlow   = "slow"
+	DecisionReject = "reject"
+)
+
+// Defaults
+const (
+	DefaultSlowDelay       = 500 * time.Millisecond
+	DefaultRetryAfter      = 30 * time.Second
+	DefaultRefreshInterval = 2 * time.Second
+)
+
+// Backlog reports how many sync events are waiting to be propagated
+type Backlog interface {
+	GetPendingEventsCount(ctx context.Context) (int64, error)
+}
+
+// Config defines when and which writes are throttled. A zero threshold
+// disables that stage.
+type Config struct {
+	SlowThreshold   int64
+	RejectThreshold int64
+	SlowDelay       time.Duration
+	RetryAfter      time.Duration
+	RefreshInterval time.Duration
+	// Routes are the low-priority writes, as "[METHOD ]/path/template"
+	Routes []string
+}
+
+// Stats describes the current backlog and the throttling decisions made so far
+type Stats struct {
+	Backlog         int64      `json:"backlog"`
+	CheckedAt       *time.Time `json:"checked_at,omitempty"`
+	SlowThreshold   int64      `json:"slow_threshold"`
+	RejectThreshold int64      `json:"reject_threshold"`
+	Decision        string     `json:"decision"`
+	Allowed         int64      `json:"allowed"`
+	Slowed          int64      `json:"slowed"`
+	Rejected        int64      `json:"rejected"`
+	LastThrottledAt *time.Time `json:"last_throttled_at,omitempty"`
+}
+
+// Throttle caches the backlog depth and applies it to low-priority writes
+type Throttle struct {
+	backlog Backlog
+	cfg     Config
+	routes  map[string]bool
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	mu              sync.Mutex
+	depth           int64
+	checkedAt       time.Time
+	allowed         int64
+	slowed          int64
+	rejected        int64
+	lastThrottledAt time.Time
+}
+
+// NewThrottle creates a new throttle over the sync backlog
+func NewThrottle(backlog Backlog, cfg Config) *Throttle {
+	if cfg.SlowDelay <= 0 {
+		cfg.SlowDelay = DefaultSlowDelay
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = DefaultRetryAfter
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultRefreshInterval
+	}
+
+	routes := make(map[string]bool, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		routes[normalizeRoute(route)] = true
+	}
+
+	return &Throttle{
+		backlog: backlog,
+		cfg:     cfg,
+		routes:  routes,
+		now:     time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+// Depth returns the backlog depth, reloading it when the cached value is stale.
+// If the backlog cannot be read the last known depth is kept.
+func (t *Throttle) Depth(ctx context.Context) int64 {
+	t.mu.Lock()
+	depth, checkedAt := t.depth, t.checkedAt
+	t.mu.Unlock()
+
+	if !checkedAt.IsZero() && t.now().Sub(checkedAt) < t.cfg.RefreshInterval {
+		return depth
+	}
+
+	count, err := t.backlog.GetPendingEventsCount(ctx)
+	if err != nil {
+		log.Printf("Failed to read sync backlog, keeping last known depth: %v", err)
+		return depth
+	}
+
+	t.mu.Lock()
+	t.depth = count
+	t.checkedAt = t.now()
+	t.mu.Unlock()
+
+	return count
+}
+
+// Decide returns what should happen to a low-priority write at a backlog depth
+func (t *Throttle) Decide(depth int64) string {
+	switch {
+	case t.cfg.RejectThreshold > 0 && depth >= t.cfg.RejectThreshold:
+		return DecisionReject
+	case t.cfg.SlowThreshold > 0 && depth >= t.cfg.SlowThreshold:
+		return DecisionSlow
+	}
+	return DecisionAllow
+}
+
+// Stats returns the current backlog and throttling counters
+func (t *Throttle) Stats(ctx context.Context) Stats {
+	depth := t.Depth(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := Stats{
+		Backlog:         depth,
+		SlowThreshold:   t.cfg.SlowThreshold,
+		RejectThreshold: t.cfg.RejectThreshold,
+		Decision:        t.Decide(depth),
+		Allowed:         t.allowed,
+		Slowed:          t.slowed,
+		Rejected:        t.rejected,
+	}
+	if !t.checkedAt.IsZero() {
+		checkedAt := t.checkedAt
+		stats.CheckedAt = &checkedAt
+	}
+	if !t.lastThrottledAt.IsZero() {
+		lastThrottledAt := t.lastThrottledAt
+		stats.LastThrottledAt = &lastThrottledAt
+	}
+	return stats
+}
+
+// Middleware delays or rejects low-priority writes while the backlog is over its thresholds
+func (t *Throttle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isReadRequest(r) || !t.isLowPriority(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		depth := t.Depth(r.Context())
+		decision := t.Decide(depth)
+		t.record(decision)
+
+		switch decision {
+		case DecisionReject:
+			log.Printf("Rejected low-priority write %s %s: sync backlog %d", r.Method, r.URL.Path, depth)
+			t.respondThrottled(w, depth)
+			return
+		case DecisionSlow:
+			if err := t.sleep(r.Context(), t.cfg.SlowDelay); err != nil {
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// respondThrottled rejects a write with 429, Retry-After and the backlog
+func (t *Throttle) respondThrottled(w http.ResponseWriter, depth int64) {
+	retryAfter := int(t.cfg.RetryAfter.Seconds())
+	response, _ := json.Marshal(map[string]interface{}{
+		"error":            "Sync backlog is too deep, retry later",
+		"success":          false,
+		"backlog":          depth,
+		"reject_threshold": t.cfg.RejectThreshold,
+		"retry_after":      retryAfter,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write(response)
+}
+
+// record counts a throttling decision
+func (t *Throttle) record(decision string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch decision {
+	case DecisionReject:
+		t.rejected++
+		t.lastThrottledAt = t.now()
+	case DecisionSlow:
+		t.slowed++
+		t.lastThrottledAt = t.now()
+	default:
+		t.allowed++
+	}
+}
+
+// isLowPriority reports whether a write is configured or marked as low priority
+func (t *Throttle) isLowPriority(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get(PriorityHeader), "low") {
+		return true
+	}
+	if len(t.routes) == 0 {
+		return false
+	}
+
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	return t.routes[strings.ToUpper(r.Method)+" "+template] || t.routes[template]
+}
+
+// normalizeRoute uppercases the method of a "[METHOD ]/path/template" entry
+func normalizeRoute(route string) string {
+	route = strings.TrimSpace(route)
+	if method, path, ok := strings.Cut(route, " "); ok {
+		return strings.ToUpper(method) + " " + strings.TrimSpace(path)
+	}
+	return route
+}
+
+// isReadRequest reports whether a request cannot modify data
+func isReadRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// sleepContext waits for d unless the request is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
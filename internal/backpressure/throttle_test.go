@@ -0,0 +1,128 @@
+package backpressure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBacklog reports a fixed depth
+type fakeBacklog struct {
+	depth int64
+	err   error
+	reads int
+}
+
+func (f *fakeBacklog) GetPendingEventsCount(ctx context.Context) (int64, error) {
+	f.reads++
+	return f.depth, f.err
+}
+
+func newTestRouter(throttle *Throttle) *mux.Router {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/import/xlsx", ok).Methods("POST")
+	router.HandleFunc("/api/v1/cis", ok).Methods("GET", "POST")
+	router.Use(throttle.Middleware)
+	return router
+}
+
+func TestThrottle_Decide(t *testing.T) {
+	throttle := NewThrottle(&fakeBacklog{}, Config{SlowThreshold: 100, RejectThreshold: 1000})
+
+	assert.Equal(t, DecisionAllow, throttle.Decide(99))
+	assert.Equal(t, DecisionSlow, throttle.Decide(100))
+	assert.Equal(t, DecisionReject, throttle.Decide(1000))
+
+	slowOnly := NewThrottle(&fakeBacklog{}, Config{SlowThreshold: 100})
+	assert.Equal(t, DecisionSlow, slowOnly.Decide(1000000))
+}
+
+func TestThrottle_Middleware(t *testing.T) {
+	backlog := &fakeBacklog{depth: 5000}
+	throttle := NewThrottle(backlog, Config{
+		SlowThreshold:   100,
+		RejectThreshold: 1000,
+		RetryAfter:      time.Minute,
+		Routes:          []string{"post /api/v1/import/xlsx"},
+	})
+	router := newTestRouter(throttle)
+
+	// Low-priority writes are rejected with the backlog
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/import/xlsx", nil))
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, float64(5000), body["backlog"])
+
+	// Other writes and reads go through
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/cis", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cis", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Unless the client marks them low priority
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cis", nil)
+	req.Header.Set(PriorityHeader, "low")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// The depth is cached between requests
+	assert.Equal(t, 1, backlog.reads)
+
+	stats := throttle.Stats(context.Background())
+	assert.Equal(t, int64(5000), stats.Backlog)
+	assert.Equal(t, DecisionReject, stats.Decision)
+	assert.Equal(t, int64(2), stats.Rejected)
+	assert.NotNil(t, stats.LastThrottledAt)
+}
+
+func TestThrottle_MiddlewareSlows(t *testing.T) {
+	throttle := NewThrottle(&fakeBacklog{depth: 200}, Config{
+		SlowThreshold:   100,
+		RejectThreshold: 1000,
+		SlowDelay:       time.Second,
+		Routes:          []string{"/api/v1/import/xlsx"},
+	})
+	var slept time.Duration
+	throttle.sleep = func(ctx context.Context, d time.Duration) error {
+		slept += d
+		return nil
+	}
+	router := newTestRouter(throttle)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/import/xlsx", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, time.Second, slept)
+	assert.Equal(t, int64(1), throttle.Stats(context.Background()).Slowed)
+}
+
+func TestThrottle_DepthKeepsLastKnownOnError(t *testing.T) {
+	backlog := &fakeBacklog{depth: 300}
+	throttle := NewThrottle(backlog, Config{RefreshInterval: time.Second})
+	now := time.Now()
+	throttle.now = func() time.Time { return now }
+
+	assert.Equal(t, int64(300), throttle.Depth(context.Background()))
+
+	backlog.depth, backlog.err = 0, errors.New("database unavailable")
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, int64(300), throttle.Depth(context.Background()))
+	assert.Equal(t, 2, backlog.reads)
+}
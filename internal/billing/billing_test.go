@@ -0,0 +1,293 @@
+package billing
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	costs        map[uuid.UUID]map[string]Cost
+	resources    map[string]uuid.UUID
+	dependencies map[uuid.UUID][]uuid.UUID
+	cis          map[uuid.UUID]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		costs:        map[uuid.UUID]map[string]Cost{},
+		resources:    map[string]uuid.UUID{},
+		dependencies: map[uuid.UUID][]uuid.UUID{},
+		cis:          map[uuid.UUID]bool{},
+	}
+}
+
+func (m *memoryStore) addCI(resourceID string) uuid.UUID {
+	id := uuid.New()
+	m.cis[id] = true
+	if resourceID != "" {
+		m.resources[strings.ToLower(resourceID)] = id
+	}
+	return id
+}
+
+func (m *memoryStore) ListCosts(ctx context.Context, ciID uuid.UUID) ([]Cost, error) {
+	costs := []Cost{}
+	for _, cost := range m.costs[ciID] {
+		costs = append(costs, cost)
+	}
+	sort.Slice(costs, func(i, j int) bool { return costs[i].Period > costs[j].Period })
+	return costs, nil
+}
+
+func (m *memoryStore) SetCost(ctx context.Context, cost *Cost) error {
+	if m.costs[cost.CIID] == nil {
+		m.costs[cost.CIID] = map[string]Cost{}
+	}
+	m.costs[cost.CIID][cost.Period] = *cost
+	return nil
+}
+
+func (m *memoryStore) UpsertProviderCosts(ctx context.Context, costs []Cost) (int64, error) {
+	var written int64
+	for _, cost := range costs {
+		if existing, ok := m.costs[cost.CIID][cost.Period]; ok && existing.Source == SourceManual {
+			continue
+		}
+		cost := cost
+		m.SetCost(ctx, &cost)
+		written++
+	}
+	return written, nil
+}
+
+func (m *memoryStore) GetCosts(ctx context.Context, ciIDs []uuid.UUID, period string) (map[uuid.UUID]Cost, error) {
+	costs := map[uuid.UUID]Cost{}
+	for _, id := range ciIDs {
+		if cost, ok := m.costs[id][period]; ok {
+			costs[id] = cost
+		}
+	}
+	return costs, nil
+}
+
+func (m *memoryStore) LatestPeriod(ctx context.Context) (string, error) {
+	latest := ""
+	for _, periods := range m.costs {
+		for period := range periods {
+			if period > latest {
+				latest = period
+			}
+		}
+	}
+	return latest, nil
+}
+
+func (m *memoryStore) ResolveResources(ctx context.Context, attribute string, resourceIDs []string) (map[string]uuid.UUID, error) {
+	resolved := map[string]uuid.UUID{}
+	for _, resource := range resourceIDs {
+		if id, ok := m.resources[strings.ToLower(resource)]; ok {
+			resolved[strings.ToLower(resource)] = id
+		}
+	}
+	return resolved, nil
+}
+
+func (m *memoryStore) ListDependencies(ctx context.Context, ciIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	dependencies := map[uuid.UUID][]uuid.UUID{}
+	for _, id := range ciIDs {
+		dependencies[id] = m.dependencies[id]
+	}
+	return dependencies, nil
+}
+
+func (m *memoryStore) CIExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	return m.cis[id], nil
+}
+
+const curExport = `identity/LineItemId,lineItem/UsageStartDate,lineItem/ResourceId,lineItem/UnblendedCost,lineItem/CurrencyCode
+1,2024-03-01T00:00:00Z,arn:aws:ec2:eu-west-1:123:instance/i-1,10.10,USD
+2,2024-03-15T00:00:00Z,arn:aws:ec2:eu-west-1:123:instance/i-1,5.005,USD
+3,2024-03-02T00:00:00Z,,1.00,USD
+4,2024-04-01T00:00:00Z,arn:aws:ec2:eu-west-1:123:instance/i-1,2,USD
+5,2024-03-01T00:00:00Z,arn:aws:s3:::logs,0.5,USD
+`
+
+func TestParseExport_AWSCUR(t *testing.T) {
+	items, err := ParseExport(ProviderAWSCUR, strings.NewReader(curExport))
+	require.NoError(t, err)
+	assert.Equal(t, []LineItem{
+		{ResourceID: "arn:aws:ec2:eu-west-1:123:instance/i-1", Period: "2024-03", Amount: 15.11, Currency: "USD"},
+		{ResourceID: "arn:aws:ec2:eu-west-1:123:instance/i-1", Period: "2024-04", Amount: 2, Currency: "USD"},
+		{ResourceID: "arn:aws:s3:::logs", Period: "2024-03", Amount: 0.5, Currency: "USD"},
+	}, items)
+}
+
+func TestParseExport_Azure(t *testing.T) {
+	export := "\ufeffDate,ResourceId,PreTaxCost,Currency\n" +
+		"03/05/2024,/subscriptions/s1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1,12.5,eur\n" +
+		"03/06/2024,/subscriptions/s1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1,7.5,eur\n"
+
+	items, err := ParseExport(ProviderAzure, strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "2024-03", items[0].Period)
+	assert.Equal(t, 20.0, items[0].Amount)
+	assert.Equal(t, "EUR", items[0].Currency)
+}
+
+func TestParseExport_Errors(t *testing.T) {
+	_, err := ParseExport("gcp", strings.NewReader(curExport))
+	assert.ErrorIs(t, err, ErrInvalidCost)
+
+	_, err = ParseExport(ProviderAzure, strings.NewReader(curExport))
+	assert.Error(t, err)
+
+	_, err = ParseExport(ProviderAWSCUR, strings.NewReader("lineItem/UsageStartDate,lineItem/ResourceId,lineItem/UnblendedCost\nyesterday,i-1,1\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func writeGzip(t *testing.T, path, content string) {
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	_, err = gz.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+}
+
+func TestService_Sync(t *testing.T) {
+	dir := t.TempDir()
+	writeGzip(t, filepath.Join(dir, "cur-00001.csv.gz"), curExport)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cur-00002.csv"), []byte(
+		"lineItem/UsageStartDate,lineItem/ResourceId,lineItem/UnblendedCost\n2024-03-20T00:00:00Z,ARN:AWS:EC2:EU-WEST-1:123:INSTANCE/I-1,4.89\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0o644))
+
+	store := newMemoryStore()
+	instance := store.addCI("arn:aws:ec2:eu-west-1:123:instance/i-1")
+	manual := store.addCI("arn:aws:s3:::logs")
+
+	connector := Connector{Name: "aws-prod", Provider: ProviderAWSCUR, Path: dir}
+	require.NoError(t, connector.Validate())
+	service := NewService(store, "", []Connector{connector})
+	ctx := context.Background()
+
+	_, err := service.SetCost(ctx, &Cost{CIID: manual, Period: "2024-03", Amount: 99}, "admin")
+	require.NoError(t, err)
+
+	runs, err := service.Sync(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	run := runs[0]
+	assert.Equal(t, RunCompleted, run.Status, run.Error)
+	assert.Equal(t, 2, run.Files)
+	assert.Equal(t, 3, run.Resources)
+	assert.Equal(t, 3, run.Matched)
+	assert.Equal(t, int64(2), run.Written)
+
+	costs, err := service.ListCosts(ctx, instance)
+	require.NoError(t, err)
+	require.Len(t, costs, 2)
+	assert.Equal(t, "2024-04", costs[0].Period)
+	assert.Equal(t, "2024-03", costs[1].Period)
+	assert.Equal(t, 20.0, costs[1].Amount)
+	assert.Equal(t, ProviderAWSCUR, costs[1].Source)
+
+	// Costs set by hand are kept
+	costs, err = service.ListCosts(ctx, manual)
+	require.NoError(t, err)
+	require.Len(t, costs, 1)
+	assert.Equal(t, 99.0, costs[0].Amount)
+	assert.Equal(t, SourceManual, costs[0].Source)
+}
+
+func TestService_SyncFailure(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.csv"), []byte("unrelated,columns\n1,2\n"), 0o644))
+
+	connector := Connector{Name: "azure", Provider: ProviderAzure, Path: dir}
+	require.NoError(t, connector.Validate())
+	service := NewService(newMemoryStore(), "", []Connector{connector})
+
+	runs, err := service.Sync(context.Background())
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, RunFailed, runs[0].Status)
+	assert.Contains(t, runs[0].Error, "broken.csv")
+
+	_, err = NewService(newMemoryStore(), "", nil).Sync(context.Background())
+	assert.ErrorIs(t, err, ErrNoConnectors)
+}
+
+func TestService_SetCost(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, "", nil)
+	service.now = func() time.Time { return time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+	ci := store.addCI("")
+
+	cost, err := service.SetCost(ctx, &Cost{CIID: ci, Period: "2024-03", Amount: 12.345, Currency: "eur"}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, 12.35, cost.Amount)
+	assert.Equal(t, "EUR", cost.Currency)
+
+	_, err = service.SetCost(ctx, &Cost{CIID: ci, Period: "March", Amount: 1}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidCost)
+	_, err = service.SetCost(ctx, &Cost{CIID: ci, Period: "2024-03", Amount: -1}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidCost)
+	_, err = service.SetCost(ctx, &Cost{CIID: uuid.New(), Period: "2024-03", Amount: 1}, "admin")
+	assert.ErrorIs(t, err, ErrCINotFound)
+}
+
+func TestService_Rollup(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, "", nil)
+	ctx := context.Background()
+
+	// app → web → vm, app → db → vm, vm → app (cycle), db → disk (depth 2)
+	app, web, db, vm, disk := store.addCI(""), store.addCI(""), store.addCI(""), store.addCI(""), store.addCI("")
+	store.dependencies[app] = []uuid.UUID{web, db}
+	store.dependencies[web] = []uuid.UUID{vm}
+	store.dependencies[db] = []uuid.UUID{vm, disk}
+	store.dependencies[vm] = []uuid.UUID{app}
+
+	for id, amount := range map[uuid.UUID]float64{app: 5, db: 30, vm: 100, disk: 7.5} {
+		_, err := service.SetCost(ctx, &Cost{CIID: id, Period: "2024-03", Amount: amount}, "admin")
+		require.NoError(t, err)
+	}
+	_, err := service.SetCost(ctx, &Cost{CIID: app, Period: "2024-02", Amount: 1000}, "admin")
+	require.NoError(t, err)
+
+	rollup, err := service.Rollup(ctx, app, "", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03", rollup.Period)
+	assert.Equal(t, map[string]float64{"USD": 5}, rollup.Own)
+	assert.Equal(t, map[string]float64{"USD": 142.5}, rollup.Total)
+	assert.Equal(t, 5, rollup.CIs)
+	assert.Equal(t, 1, rollup.WithoutCost)
+	require.Len(t, rollup.Items, 4)
+	assert.Equal(t, app, rollup.Items[0].CIID)
+	assert.Equal(t, db, rollup.Items[1].CIID)
+	assert.Equal(t, vm, rollup.Items[2].CIID)
+	assert.Equal(t, 2, rollup.Items[2].Depth)
+
+	rollup, err = service.Rollup(ctx, app, "2024-03", 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 35}, rollup.Total)
+
+	_, err = service.Rollup(ctx, app, "", 0)
+	assert.ErrorIs(t, err, ErrInvalidCost)
+	_, err = service.Rollup(ctx, uuid.New(), "", 1)
+	assert.ErrorIs(t, err, ErrCINotFound)
+}
@@ -0,0 +1,78 @@
+// Package billing keeps the monthly cost of CIs. Costs are set by hand or
+// enriched from cloud billing exports (AWS Cost and Usage Reports, Azure Cost
+// Management exports) matched to CIs by their cloud resource ID, and can be
+// rolled up along the relationship graph.
+//
+// Connectors read the exports from the directory they are delivered to, e.g. a
+// bucket synced to local storage, and rewrite the months they cover on every
+// run. Costs set by hand are never overwritten by a connector.
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cost sources besides the billing providers
+const SourceManual = "manual"
+
+// PeriodLayout is the format of cost periods, one per calendar month
+const PeriodLayout = "2006-01"
+
+// DefaultCurrency applies to costs recorded without a currency
+const DefaultCurrency = "USD"
+
+var (
+	ErrInvalidCost  = errors.New("invalid cost")
+	ErrSyncRunning  = errors.New("billing sync already running")
+	ErrCINotFound   = errors.New("CI not found")
+	ErrNoConnectors = errors.New("no billing connectors configured")
+)
+
+// Cost is what a CI cost over a month
+type Cost struct {
+	CIID     uuid.UUID `json:"ci_id" db:"ci_id"`
+	Period   string    `json:"period" db:"period"`
+	Amount   float64   `json:"amount" db:"amount"`
+	Currency string    `json:"currency" db:"currency"`
+	// Source is manual or the billing provider the cost was read from
+	Source     string    `json:"source" db:"source"`
+	ResourceID string    `json:"resource_id,omitempty" db:"resource_id"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Validate checks the period, amount and currency of the cost
+func (c *Cost) Validate() error {
+	if err := ValidatePeriod(c.Period); err != nil {
+		return err
+	}
+	if c.Amount < 0 || math.IsNaN(c.Amount) || math.IsInf(c.Amount, 0) {
+		return fmt.Errorf("%w: amount must be a non-negative number", ErrInvalidCost)
+	}
+	c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+	if c.Currency == "" {
+		c.Currency = DefaultCurrency
+	}
+	if len(c.Currency) != 3 {
+		return fmt.Errorf("%w: currency must be a 3-letter ISO 4217 code", ErrInvalidCost)
+	}
+	return nil
+}
+
+// ValidatePeriod checks that a period is a YYYY-MM month
+func ValidatePeriod(period string) error {
+	if _, err := time.Parse(PeriodLayout, period); err != nil {
+		return fmt.Errorf("%w: period must be YYYY-MM", ErrInvalidCost)
+	}
+	return nil
+}
+
+// round rounds an amount to the cent
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,169 @@
+package billing
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Billing export providers
+const (
+	ProviderAWSCUR = "aws_cur"
+	ProviderAzure  = "azure"
+)
+
+// Providers lists every supported billing export format
+var Providers = []string{ProviderAWSCUR, ProviderAzure}
+
+// exportColumns names the columns read from each export format. Alternatives
+// are listed by preference, as the column names differ between export versions.
+var exportColumns = map[string]struct {
+	resource, cost, date, currency []string
+}{
+	ProviderAWSCUR: {
+		resource: []string{"lineItem/ResourceId", "line_item_resource_id"},
+		cost:     []string{"lineItem/UnblendedCost", "line_item_unblended_cost"},
+		date:     []string{"lineItem/UsageStartDate", "line_item_usage_start_date", "bill/BillingPeriodStartDate"},
+		currency: []string{"lineItem/CurrencyCode", "line_item_currency_code"},
+	},
+	ProviderAzure: {
+		resource: []string{"ResourceId", "InstanceId", "InstanceName"},
+		cost:     []string{"CostInBillingCurrency", "PreTaxCost", "Cost"},
+		date:     []string{"Date", "UsageDateTime", "UsageDate"},
+		currency: []string{"BillingCurrency", "BillingCurrencyCode", "Currency"},
+	},
+}
+
+// LineItem is the cost of a cloud resource over a month, summed from the
+// export's line items
+type LineItem struct {
+	ResourceID string  `json:"resource_id"`
+	Period     string  `json:"period"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+}
+
+// ParseExport reads a billing export in CSV and sums its line items per
+// resource, month and currency. Line items without a resource, such as taxes
+// and support fees, are skipped.
+func ParseExport(provider string, r io.Reader) ([]LineItem, error) {
+	columns, ok := exportColumns[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown billing provider %q", ErrInvalidCost, provider)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read billing export header: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	resourceCol, costCol, dateCol, currencyCol := find(columns.resource), find(columns.cost), find(columns.date), find(columns.currency)
+	if resourceCol < 0 || costCol < 0 || dateCol < 0 {
+		return nil, fmt.Errorf("billing export is missing a resource, cost or date column for %s", provider)
+	}
+
+	type key struct{ resource, period, currency string }
+	totals := map[key]float64{}
+	var order []key
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read billing export line %d: %w", line, err)
+		}
+
+		resource := field(record, resourceCol)
+		if resource == "" {
+			continue
+		}
+		amount, err := strconv.ParseFloat(field(record, costCol), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cost on billing export line %d: %w", line, err)
+		}
+		period, err := periodOf(field(record, dateCol))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date on billing export line %d: %w", line, err)
+		}
+		currency := strings.ToUpper(field(record, currencyCol))
+		if currency == "" {
+			currency = DefaultCurrency
+		}
+
+		k := key{resource, period, currency}
+		if _, ok := totals[k]; !ok {
+			order = append(order, k)
+		}
+		totals[k] += amount
+	}
+
+	items := make([]LineItem, 0, len(order))
+	for _, k := range order {
+		items = append(items, LineItem{ResourceID: k.resource, Period: k.period, Amount: round(totals[k]), Currency: k.currency})
+	}
+	return items, nil
+}
+
+// ParseExportFile reads a billing export file, gzip compressed when its name ends in .gz
+func ParseExportFile(provider, path string) ([]LineItem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open billing export: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress billing export: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return ParseExport(provider, r)
+}
+
+// field returns a trimmed column of a record, or "" when the record is short
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// dateLayouts are the date formats found in billing exports
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "01/02/2006", "2006-01-02 15:04:05"}
+
+// periodOf returns the month of a billing date
+func periodOf(value string) (string, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(PeriodLayout), nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized date %q", value)
+}
@@ -0,0 +1,482 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults
+const (
+	// DefaultResourceAttribute is the CI attribute holding the cloud resource
+	// ID, an ARN for AWS and a resource ID for Azure
+	DefaultResourceAttribute = "cloud_resource_id"
+	// DefaultPattern matches the export files of a connector
+	DefaultPattern = "*.csv*"
+	// MaxRollupDepth bounds how far a roll-up follows relationships
+	MaxRollupDepth = 10
+)
+
+// Sync run statuses
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+// Connector reads the billing exports of one cloud account
+type Connector struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	// Path is the directory the exports are delivered to
+	Path    string `json:"path"`
+	Pattern string `json:"pattern"`
+}
+
+// Validate checks the connector and applies the default pattern
+func (c *Connector) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("billing connector name is required")
+	}
+	if _, ok := exportColumns[c.Provider]; !ok {
+		return fmt.Errorf("billing connector %s: provider must be one of %s", c.Name, strings.Join(Providers, ", "))
+	}
+	if strings.TrimSpace(c.Path) == "" {
+		return fmt.Errorf("billing connector %s: path is required", c.Name)
+	}
+	if c.Pattern == "" {
+		c.Pattern = DefaultPattern
+	}
+	if _, err := filepath.Match(c.Pattern, ""); err != nil {
+		return fmt.Errorf("billing connector %s: invalid pattern: %v", c.Name, err)
+	}
+	return nil
+}
+
+// Run is the latest enrichment run of a connector
+type Run struct {
+	Connector   string     `json:"connector"`
+	Provider    string     `json:"provider"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Files       int        `json:"files"`
+	// Resources counts the resource months read, Matched those matched to a CI
+	Resources int    `json:"resources"`
+	Matched   int    `json:"matched"`
+	Unmatched int    `json:"unmatched"`
+	Written   int64  `json:"written"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RollupItem is the cost of one CI reached by a roll-up
+type RollupItem struct {
+	CIID     uuid.UUID `json:"ci_id"`
+	Depth    int       `json:"depth"`
+	Amount   float64   `json:"amount"`
+	Currency string    `json:"currency"`
+	Source   string    `json:"source"`
+}
+
+// Rollup sums the cost of a CI and of the CIs it depends on, directly or not.
+// Each CI counts once however many paths lead to it. Amounts are summed per
+// currency.
+type Rollup struct {
+	CIID   uuid.UUID          `json:"ci_id"`
+	Period string             `json:"period"`
+	Depth  int                `json:"depth"`
+	Own    map[string]float64 `json:"own"`
+	Total  map[string]float64 `json:"total"`
+	// CIs counts the CIs reached, the CI itself included
+	CIs         int          `json:"cis"`
+	WithoutCost int          `json:"without_cost"`
+	Items       []RollupItem `json:"items"`
+}
+
+// Service records costs, enriches CIs from billing exports and rolls costs up
+type Service struct {
+	store             Store
+	resourceAttribute string
+	connectors        []Connector
+	now               func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	runs    map[string]*Run
+}
+
+// NewService creates a new billing service. Connectors must have been validated.
+func NewService(store Store, resourceAttribute string, connectors []Connector) *Service {
+	if resourceAttribute == "" {
+		resourceAttribute = DefaultResourceAttribute
+	}
+	return &Service{
+		store:             store,
+		resourceAttribute: resourceAttribute,
+		connectors:        connectors,
+		now:               time.Now,
+		runs:              map[string]*Run{},
+	}
+}
+
+// ListCosts retrieves the cost history of a CI, latest period first
+func (s *Service) ListCosts(ctx context.Context, ciID uuid.UUID) ([]Cost, error) {
+	if err := s.requireCI(ctx, ciID); err != nil {
+		return nil, err
+	}
+	return s.store.ListCosts(ctx, ciID)
+}
+
+// SetCost records the cost of a CI for a month by hand. Connectors leave it in place.
+func (s *Service) SetCost(ctx context.Context, cost *Cost, by string) (*Cost, error) {
+	if err := cost.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.requireCI(ctx, cost.CIID); err != nil {
+		return nil, err
+	}
+
+	cost.Amount = round(cost.Amount)
+	cost.Source = SourceManual
+	cost.ResourceID = ""
+	cost.UpdatedAt = s.now()
+	if err := s.store.SetCost(ctx, cost); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Cost of CI %s for %s set to %.2f %s by %s", cost.CIID, cost.Period, cost.Amount, cost.Currency, by)
+	return cost, nil
+}
+
+// Rollup sums the cost of a CI and its dependencies up to depth hops away.
+// Without a period, the latest period with a cost is used.
+func (s *Service) Rollup(ctx context.Context, ciID uuid.UUID, period string, depth int) (*Rollup, error) {
+	if depth < 1 || depth > MaxRollupDepth {
+		return nil, fmt.Errorf("%w: depth must be between 1 and %d", ErrInvalidCost, MaxRollupDepth)
+	}
+	if period != "" {
+		if err := ValidatePeriod(period); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.requireCI(ctx, ciID); err != nil {
+		return nil, err
+	}
+	if period == "" {
+		var err error
+		if period, err = s.store.LatestPeriod(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Walk the dependencies breadth first so each CI is counted at its shortest distance
+	depths := map[uuid.UUID]int{ciID: 0}
+	order := []uuid.UUID{ciID}
+	frontier := []uuid.UUID{ciID}
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		dependencies, err := s.store.ListDependencies(ctx, frontier)
+		if err != nil {
+			return nil, err
+		}
+		var next []uuid.UUID
+		for _, source := range frontier {
+			for _, target := range dependencies[source] {
+				if _, seen := depths[target]; seen {
+					continue
+				}
+				depths[target] = level
+				order = append(order, target)
+				next = append(next, target)
+			}
+		}
+		frontier = next
+	}
+
+	rollup := &Rollup{
+		CIID:   ciID,
+		Period: period,
+		Depth:  depth,
+		Own:    map[string]float64{},
+		Total:  map[string]float64{},
+		CIs:    len(order),
+		Items:  []RollupItem{},
+	}
+	if period == "" {
+		rollup.WithoutCost = len(order)
+		return rollup, nil
+	}
+
+	costs, err := s.store.GetCosts(ctx, order, period)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range order {
+		cost, ok := costs[id]
+		if !ok {
+			rollup.WithoutCost++
+			continue
+		}
+		if id == ciID {
+			rollup.Own[cost.Currency] = cost.Amount
+		}
+		rollup.Total[cost.Currency] = round(rollup.Total[cost.Currency] + cost.Amount)
+		rollup.Items = append(rollup.Items, RollupItem{
+			CIID:     id,
+			Depth:    depths[id],
+			Amount:   cost.Amount,
+			Currency: cost.Currency,
+			Source:   cost.Source,
+		})
+	}
+
+	sort.SliceStable(rollup.Items, func(i, j int) bool {
+		if rollup.Items[i].Depth != rollup.Items[j].Depth {
+			return rollup.Items[i].Depth < rollup.Items[j].Depth
+		}
+		return rollup.Items[i].Amount > rollup.Items[j].Amount
+	})
+	return rollup, nil
+}
+
+// Connectors returns the configured connectors
+func (s *Service) Connectors() []Connector {
+	return s.connectors
+}
+
+// Runs returns the latest run of each connector that ran since startup
+func (s *Service) Runs() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := []Run{}
+	for _, connector := range s.connectors {
+		if run, ok := s.runs[connector.Name]; ok {
+			runs = append(runs, *run)
+		}
+	}
+	return runs
+}
+
+// StartSync runs every connector in the background. Poll Runs for progress.
+func (s *Service) StartSync() error {
+	if len(s.connectors) == 0 {
+		return ErrNoConnectors
+	}
+	if !s.begin() {
+		return ErrSyncRunning
+	}
+	go func() {
+		defer s.end()
+		s.syncAll(context.Background())
+	}()
+	return nil
+}
+
+// Run enriches the CIs from every connector now and then at each interval
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if len(s.connectors) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if s.begin() {
+			s.syncAll(ctx)
+			s.end()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync runs every connector and returns their runs
+func (s *Service) Sync(ctx context.Context) ([]Run, error) {
+	if len(s.connectors) == 0 {
+		return nil, ErrNoConnectors
+	}
+	if !s.begin() {
+		return nil, ErrSyncRunning
+	}
+	defer s.end()
+
+	s.syncAll(ctx)
+	return s.Runs(), nil
+}
+
+// begin marks a sync as running, reporting false when one already is
+func (s *Service) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return false
+	}
+	s.running = true
+	return true
+}
+
+// end marks the running sync as done
+func (s *Service) end() {
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+}
+
+// syncAll runs the connectors one after the other
+func (s *Service) syncAll(ctx context.Context) {
+	for _, connector := range s.connectors {
+		s.syncConnector(ctx, connector)
+	}
+}
+
+// syncConnector reads the exports of a connector and records the costs of the
+// resources matched to a CI
+func (s *Service) syncConnector(ctx context.Context, connector Connector) {
+	run := &Run{Connector: connector.Name, Provider: connector.Provider, Status: RunRunning, StartedAt: s.now()}
+	s.setRun(run)
+
+	err := s.enrich(ctx, connector, run)
+
+	completedAt := s.now()
+	s.mu.Lock()
+	run.CompletedAt = &completedAt
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = RunCompleted
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Billing connector %s failed: %v", connector.Name, err)
+		return
+	}
+	log.Printf("Billing connector %s completed: %d files, %d resource months, %d matched, %d unmatched, %d costs written",
+		connector.Name, run.Files, run.Resources, run.Matched, run.Unmatched, run.Written)
+}
+
+// enrich does the work of a connector run, recording progress on run
+func (s *Service) enrich(ctx context.Context, connector Connector, run *Run) error {
+	files, err := filepath.Glob(filepath.Join(connector.Path, connector.Pattern))
+	if err != nil {
+		return fmt.Errorf("failed to list billing exports: %w", err)
+	}
+	sort.Strings(files)
+
+	// Exports may split a month over several files, so items are summed across them
+	type key struct{ resource, period, currency string }
+	totals := map[key]float64{}
+	var order []key
+	for _, file := range files {
+		items, err := ParseExportFile(connector.Provider, file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		for _, item := range items {
+			k := key{strings.ToLower(item.ResourceID), item.Period, item.Currency}
+			if _, ok := totals[k]; !ok {
+				order = append(order, k)
+			}
+			totals[k] += item.Amount
+		}
+	}
+
+	resourceSet := map[string]bool{}
+	resourceIDs := []string{}
+	for _, k := range order {
+		if !resourceSet[k.resource] {
+			resourceSet[k.resource] = true
+			resourceIDs = append(resourceIDs, k.resource)
+		}
+	}
+	resolved, err := s.store.ResolveResources(ctx, s.resourceAttribute, resourceIDs)
+	if err != nil {
+		return err
+	}
+
+	// A CI holding several resources, or billed in several currencies for a
+	// month, keeps one cost per month in the currency billed most
+	type ciPeriod struct {
+		ci     uuid.UUID
+		period string
+	}
+	costs := map[ciPeriod]*Cost{}
+	var costOrder []ciPeriod
+	matched, unmatched := 0, 0
+	for _, k := range order {
+		ci, ok := resolved[k.resource]
+		if !ok {
+			unmatched++
+			continue
+		}
+		matched++
+
+		cp := ciPeriod{ci, k.period}
+		cost, ok := costs[cp]
+		if !ok {
+			cost = &Cost{CIID: ci, Period: k.period, Currency: k.currency, Source: connector.Provider, ResourceID: k.resource, UpdatedAt: s.now()}
+			costs[cp] = cost
+			costOrder = append(costOrder, cp)
+		}
+		if cost.Currency == k.currency {
+			cost.Amount += totals[k]
+		} else if totals[k] > cost.Amount {
+			cost.Currency, cost.Amount, cost.ResourceID = k.currency, totals[k], k.resource
+		}
+	}
+
+	batch := make([]Cost, 0, len(costOrder))
+	for _, cp := range costOrder {
+		cost := costs[cp]
+		cost.Amount = round(cost.Amount)
+		batch = append(batch, *cost)
+	}
+
+	var written int64
+	if len(batch) > 0 {
+		if written, err = s.store.UpsertProviderCosts(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	run.Files = len(files)
+	run.Resources = len(order)
+	run.Matched = matched
+	run.Unmatched = unmatched
+	run.Written = written
+	s.mu.Unlock()
+	return nil
+}
+
+// setRun records the latest run of a connector
+func (s *Service) setRun(run *Run) {
+	s.mu.Lock()
+	s.runs[run.Connector] = run
+	s.mu.Unlock()
+}
+
+// requireCI returns ErrCINotFound unless the CI exists
+func (s *Service) requireCI(ctx context.Context, id uuid.UUID) error {
+	exists, err := s.store.CIExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrCINotFound, id)
+	}
+	return nil
+}
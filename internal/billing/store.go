@@ -0,0 +1,213 @@
+package billing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"connect/internal/organization"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// resolveChunkSize bounds the resource IDs looked up per query
+const resolveChunkSize = 1000
+
+// Store persists costs and gives connectors and roll-ups access to the CIs
+type Store interface {
+	// ListCosts returns the costs of a CI, latest period first
+	ListCosts(ctx context.Context, ciID uuid.UUID) ([]Cost, error)
+	// SetCost records a cost, replacing the CI's cost for the period
+	SetCost(ctx context.Context, cost *Cost) error
+	// UpsertProviderCosts records costs read from a billing export, leaving
+	// costs set by hand in place, and returns the number written
+	UpsertProviderCosts(ctx context.Context, costs []Cost) (int64, error)
+	// GetCosts returns the costs of the CIs for a period
+	GetCosts(ctx context.Context, ciIDs []uuid.UUID, period string) (map[uuid.UUID]Cost, error)
+	// LatestPeriod returns the latest period with a cost, or "" when there is none
+	LatestPeriod(ctx context.Context) (string, error)
+	// ResolveResources maps lower-cased cloud resource IDs to the live CIs
+	// holding them in the attribute
+	ResolveResources(ctx context.Context, attribute string, resourceIDs []string) (map[string]uuid.UUID, error)
+	// ListDependencies returns the targets of the active relationships from the CIs
+	ListDependencies(ctx context.Context, ciIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error)
+	CIExists(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// PostgresStore keeps costs in the ci_costs table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed cost store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const costColumns = `ci_id, period, amount, currency, source, COALESCE(resource_id, '') AS resource_id, updated_at`
+
+// ListCosts retrieves the cost history of a CI
+func (s *PostgresStore) ListCosts(ctx context.Context, ciID uuid.UUID) ([]Cost, error) {
+	costs := []Cost{}
+	if err := s.db.SelectContext(ctx, &costs, `SELECT `+costColumns+` FROM ci_costs WHERE ci_id = $1 ORDER BY period DESC`, ciID); err != nil {
+		return nil, fmt.Errorf("failed to list CI costs: %w", err)
+	}
+	return costs, nil
+}
+
+// SetCost upserts a cost
+func (s *PostgresStore) SetCost(ctx context.Context, cost *Cost) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO ci_costs (ci_id, period, amount, currency, source, resource_id, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		ON CONFLICT (ci_id, period) DO UPDATE
+		SET amount = EXCLUDED.amount, currency = EXCLUDED.currency, source = EXCLUDED.source,
+		    resource_id = EXCLUDED.resource_id, updated_at = EXCLUDED.updated_at`,
+		cost.CIID, cost.Period, cost.Amount, cost.Currency, cost.Source, cost.ResourceID, cost.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set CI cost: %w", err)
+	}
+	return nil
+}
+
+// UpsertProviderCosts upserts costs read from a billing export in one transaction
+func (s *PostgresStore) UpsertProviderCosts(ctx context.Context, costs []Cost) (int64, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO ci_costs (ci_id, period, amount, currency, source, resource_id, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (ci_id, period) DO UPDATE
+		SET amount = EXCLUDED.amount, currency = EXCLUDED.currency, source = EXCLUDED.source,
+		    resource_id = EXCLUDED.resource_id, updated_at = EXCLUDED.updated_at
+		WHERE ci_costs.source <> 'manual'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare cost upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	var written int64
+	for _, cost := range costs {
+		result, err := stmt.ExecContext(ctx, cost.CIID, cost.Period, cost.Amount, cost.Currency, cost.Source, cost.ResourceID, cost.UpdatedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert CI cost: %w", err)
+		}
+		rows, _ := result.RowsAffected()
+		written += rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit CI costs: %w", err)
+	}
+	return written, nil
+}
+
+// GetCosts retrieves the costs of the CIs for a period
+func (s *PostgresStore) GetCosts(ctx context.Context, ciIDs []uuid.UUID, period string) (map[uuid.UUID]Cost, error) {
+	var costs []Cost
+	err := s.db.SelectContext(ctx, &costs, `SELECT `+costColumns+` FROM ci_costs WHERE ci_id = ANY($1) AND period = $2`,
+		pq.Array(ciIDs), period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI costs: %w", err)
+	}
+
+	byCI := make(map[uuid.UUID]Cost, len(costs))
+	for _, cost := range costs {
+		byCI[cost.CIID] = cost
+	}
+	return byCI, nil
+}
+
+// LatestPeriod retrieves the latest period with a cost
+func (s *PostgresStore) LatestPeriod(ctx context.Context) (string, error) {
+	var period sql.NullString
+	if err := s.db.GetContext(ctx, &period, `SELECT MAX(period) FROM ci_costs`); err != nil {
+		return "", fmt.Errorf("failed to get latest cost period: %w", err)
+	}
+	return period.String, nil
+}
+
+// ResolveResources looks up the CIs holding the resource IDs, in chunks, among
+// those the request's organization may see. When several CIs hold the same
+// resource, the first by ID is used.
+func (s *PostgresStore) ResolveResources(ctx context.Context, attribute string, resourceIDs []string) (map[string]uuid.UUID, error) {
+	resolved := map[string]uuid.UUID{}
+	for start := 0; start < len(resourceIDs); start += resolveChunkSize {
+		end := start + resolveChunkSize
+		if end > len(resourceIDs) {
+			end = len(resourceIDs)
+		}
+		chunk := make([]string, 0, end-start)
+		for _, id := range resourceIDs[start:end] {
+			chunk = append(chunk, strings.ToLower(id))
+		}
+
+		orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{attribute, pq.Array(chunk)})
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT DISTINCT ON (LOWER(attributes->>$1)) LOWER(attributes->>$1), id
+			FROM configuration_items
+			WHERE is_deleted = false AND LOWER(attributes->>$1) = ANY($2)`+orgFilter+`
+			ORDER BY LOWER(attributes->>$1), id`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve cloud resources: %w", err)
+		}
+		for rows.Next() {
+			var resource string
+			var id uuid.UUID
+			if err := rows.Scan(&resource, &id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan cloud resource: %w", err)
+			}
+			resolved[resource] = id
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve cloud resources: %w", err)
+		}
+	}
+	return resolved, nil
+}
+
+// ListDependencies retrieves the targets of the active relationships from the CIs
+func (s *PostgresStore) ListDependencies(ctx context.Context, ciIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	orgFilter, args := organization.AndScopeCondition(ctx, "r.org_id", []interface{}{pq.Array(ciIDs)})
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.source_ci_id, r.target_ci_id
+		FROM ci_relationships r
+		JOIN configuration_items ci ON ci.id = r.target_ci_id AND ci.is_deleted = false
+		WHERE r.source_ci_id = ANY($1) AND r.state = 'active'`+orgFilter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	dependencies := map[uuid.UUID][]uuid.UUID{}
+	for rows.Next() {
+		var source, target uuid.UUID
+		if err := rows.Scan(&source, &target); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		dependencies[source] = append(dependencies[source], target)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list dependencies: %w", err)
+	}
+	return dependencies, nil
+}
+
+// CIExists reports whether a live CI exists
+func (s *PostgresStore) CIExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var exists bool
+	orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{id})
+	if err := s.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM configuration_items WHERE id = $1 AND is_deleted = false`+orgFilter+`)`, args...); err != nil {
+		return false, fmt.Errorf("failed to check CI: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,175 @@
+// Package bootstrap initializes an empty CMDB on first run: it creates the
+// initial admin user, the default roles and permissions and a starter set of
+// CI and relationship type schemas. Bootstrapping is refused once any user
+// exists, so it cannot be used to take over a running installation, and the
+// roles, permissions and schemas are only created when missing, so the
+// baseline can be applied on top of what migrations already seeded.
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// AdminRole is the role given to the initial admin user
+const AdminRole = "admin"
+
+var (
+	ErrAlreadyInitialized = errors.New("already initialized")
+	ErrInvalidAdmin       = errors.New("invalid admin user")
+)
+
+// usernamePattern matches the usernames accepted for the admin user
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9]{3,50}$`)
+
+// Admin is the initial admin user to create
+type Admin struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Validate checks the admin user, leaving password strength to the hasher
+func (a *Admin) Validate() error {
+	a.Username = strings.TrimSpace(a.Username)
+	a.Email = strings.TrimSpace(a.Email)
+	if !usernamePattern.MatchString(a.Username) {
+		return fmt.Errorf("%w: username must be 3 to 50 letters or digits", ErrInvalidAdmin)
+	}
+	if address, err := mail.ParseAddress(a.Email); err != nil || address.Address != a.Email {
+		return fmt.Errorf("%w: email must be a valid address", ErrInvalidAdmin)
+	}
+	if a.Password == "" {
+		return fmt.Errorf("%w: password is required", ErrInvalidAdmin)
+	}
+	return nil
+}
+
+// Permission is a permission of the baseline
+type Permission struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	ResourceType string `json:"resource_type"`
+}
+
+// Role is a role of the baseline with the permissions it is granted
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// Baseline is what bootstrapping creates besides the admin user
+type Baseline struct {
+	Permissions         []Permission                     `json:"permissions"`
+	Roles               []Role                           `json:"roles"`
+	CISchemas           []*models.CITypeSchema           `json:"ci_schemas"`
+	RelationshipSchemas []*models.RelationshipTypeSchema `json:"relationship_schemas"`
+}
+
+// DefaultBaseline returns the default roles and permissions, which match the
+// permissions the auth middleware grants the seeded roles, and the starter
+// CI and relationship type schemas
+func DefaultBaseline() Baseline {
+	baseline := Baseline{
+		Permissions: []Permission{
+			{"ci:create", "Create configuration items", "ci"},
+			{"ci:read", "Read configuration items", "ci"},
+			{"ci:update", "Update configuration items", "ci"},
+			{"ci:delete", "Delete configuration items", "ci"},
+			{"relationship:manage", "Manage relationships between CIs", "relationship"},
+			{"audit_log:read", "Read audit logs", "audit_log"},
+			{"user:manage", "Manage users and roles", "user"},
+			{"import:csv", "Import data from CSV files", "import"},
+		},
+		Roles: []Role{
+			{AdminRole, "System administrator with full access", []string{
+				"ci:create", "ci:read", "ci:update", "ci:delete",
+				"relationship:manage", "audit_log:read", "user:manage", "import:csv",
+			}},
+			{"ci_manager", "Can manage configuration items and relationships", []string{
+				"ci:create", "ci:read", "ci:update", "ci:delete",
+				"relationship:manage", "import:csv",
+			}},
+			{"viewer", "Read-only access to the system", []string{"ci:read"}},
+			{"auditor", "Read access plus audit logs", []string{"ci:read", "audit_log:read"}},
+		},
+	}
+
+	ciSchemas := []struct {
+		name        string
+		description string
+		attributes  []models.CITypeAttribute
+	}{
+		{"server", "Physical or virtual server", []models.CITypeAttribute{
+			{Name: "hostname", Type: models.AttributeTypeString, Required: true, Description: "Fully qualified hostname"},
+			{Name: "ip_address", Type: models.AttributeTypeString, Description: "Primary IP address"},
+			{Name: "os", Type: models.AttributeTypeString, Description: "Operating system"},
+			{Name: "cpu_cores", Type: models.AttributeTypeNumber, Description: "CPU cores"},
+			{Name: "memory_gb", Type: models.AttributeTypeNumber, Description: "Memory in GB"},
+		}},
+		{"application", "Deployable application component", []models.CITypeAttribute{
+			{Name: "version", Type: models.AttributeTypeString, Description: "Deployed version"},
+			{Name: "language", Type: models.AttributeTypeString, Description: "Implementation language"},
+			{Name: "repo", Type: models.AttributeTypeString, Description: "Source repository"},
+		}},
+		{"database", "Database instance", []models.CITypeAttribute{
+			{Name: "engine", Type: models.AttributeTypeString, Required: true, Description: "Database engine"},
+			{Name: "version", Type: models.AttributeTypeString, Description: "Engine version"},
+			{Name: "port", Type: models.AttributeTypeNumber, Description: "Listening port"},
+		}},
+		{"network_device", "Switch, router or firewall", []models.CITypeAttribute{
+			{Name: "vendor", Type: models.AttributeTypeString, Description: "Device vendor"},
+			{Name: "model", Type: models.AttributeTypeString, Description: "Device model"},
+			{Name: "ip_address", Type: models.AttributeTypeString, Description: "Management IP address"},
+		}},
+		{"business_service", "Business service offered to users", []models.CITypeAttribute{
+			{Name: "business_owner", Type: models.AttributeTypeString, Description: "Accountable business owner"},
+			{Name: "sla", Type: models.AttributeTypeString, Description: "Availability target in percent"},
+		}},
+	}
+	for _, s := range ciSchemas {
+		baseline.CISchemas = append(baseline.CISchemas, &models.CITypeSchema{
+			Name:        s.name,
+			Description: s.description,
+			Attributes:  s.attributes,
+			IsActive:    true,
+		})
+	}
+
+	relationshipSchemas := []struct {
+		name        string
+		description string
+	}{
+		{"depends_on", "Source requires the target to function"},
+		{"uses", "Source reads or writes data in the target"},
+		{"runs_on", "Source is deployed on the target"},
+		{"hosted_in", "Source is physically hosted in the target"},
+		{"connected_to", "Source has a network link to the target"},
+	}
+	for _, s := range relationshipSchemas {
+		baseline.RelationshipSchemas = append(baseline.RelationshipSchemas, &models.RelationshipTypeSchema{
+			Name:        s.name,
+			Description: s.description,
+			Attributes:  []models.CITypeAttribute{},
+			IsActive:    true,
+		})
+	}
+	return baseline
+}
+
+// Result reports what bootstrapping created; records that already existed
+// are not counted
+type Result struct {
+	AdminID            uuid.UUID `json:"admin_id"`
+	Admin              string    `json:"admin"`
+	PermissionsCreated int       `json:"permissions_created"`
+	RolesCreated       int       `json:"roles_created"`
+	SchemasCreated     int       `json:"schemas_created"`
+}
@@ -0,0 +1,102 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	users      []AdminUser
+	roles      map[string]bool
+	bootstraps int
+}
+
+func (f *fakeStore) Initialized(ctx context.Context) (bool, error) {
+	return len(f.users) > 0, nil
+}
+
+func (f *fakeStore) Bootstrap(ctx context.Context, admin AdminUser, baseline Baseline) (*Result, error) {
+	if len(f.users) > 0 {
+		return nil, ErrAlreadyInitialized
+	}
+	f.bootstraps++
+	result := &Result{AdminID: admin.ID, Admin: admin.Username}
+	for _, role := range baseline.Roles {
+		if !f.roles[role.Name] {
+			f.roles[role.Name] = true
+			result.RolesCreated++
+		}
+	}
+	f.users = append(f.users, admin)
+	return result, nil
+}
+
+type fakeHasher struct{}
+
+func (fakeHasher) ValidatePasswordStrength(password string) error {
+	if len(password) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+	return nil
+}
+
+func (fakeHasher) HashPassword(password string) (string, error) {
+	return "hashed:" + password, nil
+}
+
+func TestInitializeOnlyOnce(t *testing.T) {
+	ctx := context.Background()
+	// The migrations seeded some roles already
+	store := &fakeStore{roles: map[string]bool{"admin": true, "viewer": true}}
+	service := NewService(store, fakeHasher{})
+
+	initialized, err := service.Initialized(ctx)
+	require.NoError(t, err)
+	assert.False(t, initialized)
+
+	result, err := service.Initialize(ctx, Admin{Username: " root ", Email: "root@example.com", Password: "Sup3r$ecret"})
+	require.NoError(t, err)
+	assert.Equal(t, "root", result.Admin)
+	assert.Equal(t, 2, result.RolesCreated)
+	require.Len(t, store.users, 1)
+	assert.Equal(t, "hashed:Sup3r$ecret", store.users[0].PasswordHash)
+
+	_, err = service.Initialize(ctx, Admin{Username: "intruder", Email: "intruder@example.com", Password: "Sup3r$ecret"})
+	assert.ErrorIs(t, err, ErrAlreadyInitialized)
+	assert.Equal(t, 1, store.bootstraps)
+}
+
+func TestInitializeValidatesAdmin(t *testing.T) {
+	service := NewService(&fakeStore{roles: map[string]bool{}}, fakeHasher{})
+	for _, admin := range []Admin{
+		{Username: "ad", Email: "admin@example.com", Password: "Sup3r$ecret"},
+		{Username: "admin", Email: "not an email", Password: "Sup3r$ecret"},
+		{Username: "admin", Email: "admin@example.com", Password: "short"},
+	} {
+		_, err := service.Initialize(context.Background(), admin)
+		assert.ErrorIs(t, err, ErrInvalidAdmin)
+	}
+}
+
+func TestDefaultBaselineRolesGrantKnownPermissions(t *testing.T) {
+	baseline := DefaultBaseline()
+	permissions := map[string]bool{}
+	for _, permission := range baseline.Permissions {
+		permissions[permission.Name] = true
+	}
+
+	hasAdmin := false
+	for _, role := range baseline.Roles {
+		hasAdmin = hasAdmin || role.Name == AdminRole
+		for _, permission := range role.Permissions {
+			assert.True(t, permissions[permission], "role %s grants unknown permission %s", role.Name, permission)
+		}
+	}
+	assert.True(t, hasAdmin)
+	assert.NotEmpty(t, baseline.CISchemas)
+	assert.NotEmpty(t, baseline.RelationshipSchemas)
+}
@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// PasswordHasher hashes the admin password. auth.PasswordService satisfies it.
+type PasswordHasher interface {
+	ValidatePasswordStrength(password string) error
+	HashPassword(password string) (string, error)
+}
+
+// Service bootstraps empty databases
+type Service struct {
+	store    Store
+	hasher   PasswordHasher
+	baseline Baseline
+}
+
+// NewService creates a new bootstrap service creating the default baseline
+func NewService(store Store, hasher PasswordHasher) *Service {
+	return &Service{store: store, hasher: hasher, baseline: DefaultBaseline()}
+}
+
+// Initialized reports whether the database was bootstrapped, i.e. has any user
+func (s *Service) Initialized(ctx context.Context) (bool, error) {
+	return s.store.Initialized(ctx)
+}
+
+// Initialize creates the admin user with the admin role and the missing
+// baseline roles, permissions and schemas, all in one transaction. It fails
+// with ErrAlreadyInitialized, changing nothing, once any user exists.
+func (s *Service) Initialize(ctx context.Context, admin Admin) (*Result, error) {
+	if err := admin.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.hasher.ValidatePasswordStrength(admin.Password); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdmin, err)
+	}
+
+	// Checked before hashing, which is deliberately slow; the store checks
+	// again under its lock
+	initialized, err := s.store.Initialized(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if initialized {
+		return nil, ErrAlreadyInitialized
+	}
+
+	hash, err := s.hasher.HashPassword(admin.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := AdminUser{ID: uuid.New(), Username: admin.Username, Email: admin.Email, PasswordHash: hash}
+	return s.store.Bootstrap(ctx, user, s.baseline)
+}
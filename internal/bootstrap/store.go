@@ -0,0 +1,172 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// bootstrapLockKey is the advisory lock serializing concurrent bootstraps
+const bootstrapLockKey = 7263540911
+
+// AdminUser is the admin user as stored
+type AdminUser struct {
+	ID           uuid.UUID
+	Username     string
+	Email        string
+	PasswordHash string
+}
+
+// Store creates the admin user and the baseline
+type Store interface {
+	// Initialized reports whether any user exists
+	Initialized(ctx context.Context) (bool, error)
+	// Bootstrap creates the admin user, granting it AdminRole, and the
+	// missing baseline records. It fails with ErrAlreadyInitialized if any
+	// user exists.
+	Bootstrap(ctx context.Context, admin AdminUser, baseline Baseline) (*Result, error)
+}
+
+// PostgresStore bootstraps a Postgres database
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed bootstrap store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Initialized reports whether any user exists
+func (s *PostgresStore) Initialized(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM users)`); err != nil {
+		return false, fmt.Errorf("failed to check for users: %w", err)
+	}
+	return exists, nil
+}
+
+// Bootstrap creates everything in one transaction, holding an advisory lock
+// so that of two concurrent bootstraps only the first creates an admin
+func (s *PostgresStore) Bootstrap(ctx context.Context, admin AdminUser, baseline Baseline) (*Result, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, bootstrapLockKey); err != nil {
+		return nil, fmt.Errorf("failed to lock bootstrap: %w", err)
+	}
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM users)`); err != nil {
+		return nil, fmt.Errorf("failed to check for users: %w", err)
+	}
+	if exists {
+		return nil, ErrAlreadyInitialized
+	}
+
+	result := &Result{AdminID: admin.ID, Admin: admin.Username}
+	for _, permission := range baseline.Permissions {
+		created, err := insertIfMissing(ctx, tx, `
+			INSERT INTO permissions (id, name, description, resource_type)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (name) DO NOTHING`,
+			uuid.New(), permission.Name, permission.Description, permission.ResourceType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create permission %s: %w", permission.Name, err)
+		}
+		result.PermissionsCreated += created
+	}
+
+	for _, role := range baseline.Roles {
+		created, err := insertIfMissing(ctx, tx, `
+			INSERT INTO roles (id, name, description)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, name) DO NOTHING`,
+			uuid.New(), role.Name, role.Description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create role %s: %w", role.Name, err)
+		}
+		result.RolesCreated += created
+
+		// Permissions already granted, or revoked from an existing role, are left alone
+		if created > 0 {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO role_permissions (role_id, permission_id)
+				SELECT r.id, p.id FROM roles r, permissions p
+				WHERE r.name = $1 AND r.org_id IS NULL AND p.name = ANY($2)
+				ON CONFLICT DO NOTHING`,
+				role.Name, pq.Array(role.Permissions)); err != nil {
+				return nil, fmt.Errorf("failed to grant permissions to role %s: %w", role.Name, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO users (id, username, email, password_hash, is_active)
+		VALUES ($1, $2, $3, $4, true)`,
+		admin.ID, admin.Username, admin.Email, admin.PasswordHash); err != nil {
+		return nil, fmt.Errorf("failed to create admin user: %w", err)
+	}
+	granted, err := insertIfMissing(ctx, tx, `
+		INSERT INTO user_roles (user_id, role_id, assigned_at)
+		SELECT $1, id, NOW() FROM roles WHERE name = $2 AND org_id IS NULL`,
+		admin.ID, AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant the %s role to the admin user: %w", AdminRole, err)
+	}
+	if granted == 0 {
+		return nil, fmt.Errorf("failed to grant the %s role to the admin user: role not found", AdminRole)
+	}
+
+	now := time.Now()
+	for _, schema := range baseline.CISchemas {
+		created, err := insertSchema(ctx, tx, "ci_type_schemas", schema.Name, schema.Description, schema.Attributes, admin.ID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CI schema %s: %w", schema.Name, err)
+		}
+		result.SchemasCreated += created
+	}
+	for _, schema := range baseline.RelationshipSchemas {
+		created, err := insertSchema(ctx, tx, "relationship_type_schemas", schema.Name, schema.Description, schema.Attributes, admin.ID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create relationship schema %s: %w", schema.Name, err)
+		}
+		result.SchemasCreated += created
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bootstrap: %w", err)
+	}
+	return result, nil
+}
+
+// insertIfMissing runs an insert and returns the rows it created
+func insertIfMissing(ctx context.Context, tx *sqlx.Tx, query string, args ...interface{}) (int, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
+
+// insertSchema creates a CI or relationship type schema unless one of the
+// same name exists
+func insertSchema(ctx context.Context, tx *sqlx.Tx, table, name, description string, attributes interface{}, createdBy uuid.UUID, now time.Time) (int, error) {
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	return insertIfMissing(ctx, tx, fmt.Sprintf(`
+		INSERT INTO %s (id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, true, $5, $5, $6, $6)
+		ON CONFLICT (name) DO NOTHING`, table),
+		uuid.New(), name, description, attributesJSON, now, createdBy)
+}
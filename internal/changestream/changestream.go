@@ -0,0 +1,327 @@
+// Package changestream fans CI and relationship changes out to live
+// subscribers, such as UIs following a Server-Sent Events stream. The hub is
+// a sync projection: every event the sync service processes is turned into a
+// change and offered to each subscriber whose filter matches it. Recent
+// changes are kept so that reconnecting clients can resume where they left
+// off.
+package changestream
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"connect/internal/eventfilter"
+)
+
+// Entities of changes
+const (
+	EntityCI           = "ci"
+	EntityRelationship = "relationship"
+)
+
+// Actions of changes
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// Defaults used when options are not set
+const (
+	DefaultBufferSize = 64
+	DefaultReplaySize = 1000
+)
+
+// Change is a change to a CI or relationship
+type Change struct {
+	// Sequence orders the changes seen by this server; clients resume after it
+	Sequence   uint64                 `json:"sequence"`
+	Entity     string                 `json:"entity"`
+	Action     string                 `json:"action"`
+	EntityID   string                 `json:"entity_id"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Type returns the event type of the change, e.g. ci.updated
+func (c *Change) Type() string {
+	return c.Entity + "." + c.Action
+}
+
+// payload returns the change as filter expressions see it: as sent to
+// subscribers, with its type added
+func (c *Change) payload() map[string]interface{} {
+	return map[string]interface{}{
+		"sequence":    float64(c.Sequence),
+		"type":        c.Type(),
+		"entity":      c.Entity,
+		"action":      c.Action,
+		"entity_id":   c.EntityID,
+		"data":        c.Data,
+		"occurred_at": c.OccurredAt.Format(time.RFC3339Nano),
+	}
+}
+
+// Filter selects the changes a subscriber receives. Empty fields select
+// everything. CI types and tags narrow CI changes; relationship changes are
+// narrowed by the CI types of their ends when the change carries them. The
+// expression, if any, must also match the change, e.g.
+// event.data.criticality == "critical".
+type Filter struct {
+	Entities   []string
+	CITypes    []string
+	Tags       []string
+	Expression *eventfilter.Filter
+}
+
+// Matches reports whether a change passes the filter
+func (f Filter) Matches(change *Change) bool {
+	if len(f.Entities) > 0 && !containsFold(f.Entities, change.Entity) {
+		return false
+	}
+
+	switch change.Entity {
+	case EntityCI:
+		if len(f.CITypes) > 0 && !containsFold(f.CITypes, stringField(change.Data, "type")) {
+			return false
+		}
+		if len(f.Tags) > 0 && !anyTag(f.Tags, change.Data["tags"]) {
+			return false
+		}
+	case EntityRelationship:
+		if len(f.CITypes) > 0 {
+			source := stringField(change.Data, "source_ci_type")
+			target := stringField(change.Data, "target_ci_type")
+			if (source != "" || target != "") && !containsFold(f.CITypes, source) && !containsFold(f.CITypes, target) {
+				return false
+			}
+		}
+	}
+
+	if f.Expression != nil {
+		// An expression failing on a change, e.g. comparing values of
+		// different types, does not match it
+		matched, err := f.Expression.Match(change.payload())
+		return err == nil && matched
+	}
+	return true
+}
+
+// Options configure the hub
+type Options struct {
+	// BufferSize is how many changes a subscriber may fall behind before
+	// further changes are dropped for it
+	BufferSize int
+	// ReplaySize is how many recent changes are kept for resuming clients
+	ReplaySize int
+}
+
+// Hub broadcasts changes to subscribers
+type Hub struct {
+	opts Options
+	now  func() time.Time
+
+	mu          sync.Mutex
+	sequence    uint64
+	recent      []Change
+	subscribers map[*Subscription]struct{}
+}
+
+// NewHub creates a new change hub
+func NewHub(opts Options) *Hub {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.ReplaySize <= 0 {
+		opts.ReplaySize = DefaultReplaySize
+	}
+	return &Hub{opts: opts, now: time.Now, subscribers: make(map[*Subscription]struct{})}
+}
+
+// Apply publishes a processed sync event as a change. Entity types other than
+// CIs and relationships are ignored. It never fails, so a slow or absent
+// subscriber cannot hold back sync.
+func (h *Hub) Apply(ctx context.Context, entityType, entityID, action string, data map[string]interface{}) error {
+	change, ok := changeFromEvent(entityType, entityID, action, data)
+	if !ok {
+		return nil
+	}
+	h.Publish(change)
+	return nil
+}
+
+// Publish assigns a change its sequence and offers it to every subscriber
+// whose filter matches
+func (h *Hub) Publish(change Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sequence++
+	change.Sequence = h.sequence
+	if change.OccurredAt.IsZero() {
+		change.OccurredAt = h.now().UTC()
+	}
+
+	h.recent = append(h.recent, change)
+	if len(h.recent) > h.opts.ReplaySize {
+		h.recent = h.recent[len(h.recent)-h.opts.ReplaySize:]
+	}
+
+	for sub := range h.subscribers {
+		sub.offer(change)
+	}
+}
+
+// Subscribe registers a subscriber. With a non-zero after, the kept changes
+// following that sequence are replayed first; Missed reports whether some
+// were no longer kept, or the sequence is unknown to this server.
+func (h *Hub) Subscribe(filter Filter, after uint64) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan Change, h.opts.BufferSize),
+	}
+	// A sequence from before a restart of the server cannot be resumed from
+	if after > h.sequence {
+		sub.missed = true
+	} else if after > 0 && after < h.sequence {
+		if len(h.recent) == 0 || h.recent[0].Sequence > after+1 {
+			sub.missed = true
+		}
+		for _, change := range h.recent {
+			if change.Sequence > after {
+				sub.offer(change)
+			}
+		}
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Subscribers returns the number of current subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// Subscription receives the changes matching its filter
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	events chan Change
+
+	// Guarded by the hub's mutex
+	missed  bool
+	dropped int
+	closed  bool
+}
+
+// Changes returns the channel changes are delivered on. It is closed when
+// the subscription is.
+func (s *Subscription) Changes() <-chan Change {
+	return s.events
+}
+
+// Missed reports, and resets, whether changes were lost since the last call,
+// either because the subscriber fell too far behind or because they were no
+// longer kept for replay. Clients should then reload rather than rely on the
+// stream.
+func (s *Subscription) Missed() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	missed := s.missed || s.dropped > 0
+	s.missed = false
+	s.dropped = 0
+	return missed
+}
+
+// Close unregisters the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.hub.subscribers, s)
+	close(s.events)
+}
+
+// offer delivers a matching change without blocking, counting it as dropped
+// when the subscriber is too far behind. Callers hold the hub's mutex.
+func (s *Subscription) offer(change Change) {
+	if s.closed || !s.filter.Matches(&change) {
+		return
+	}
+	select {
+	case s.events <- change:
+	default:
+		s.dropped++
+	}
+}
+
+// changeFromEvent turns a sync event into a change
+func changeFromEvent(entityType, entityID, action string, data map[string]interface{}) (Change, bool) {
+	change := Change{EntityID: entityID, Data: data}
+	switch entityType {
+	case "configuration_item":
+		change.Entity = EntityCI
+	case "relationship":
+		change.Entity = EntityRelationship
+	default:
+		return change, false
+	}
+
+	switch strings.ToUpper(action) {
+	case "CREATE":
+		change.Action = ActionCreated
+	case "UPDATE":
+		change.Action = ActionUpdated
+	case "DELETE":
+		change.Action = ActionDeleted
+	default:
+		return change, false
+	}
+	return change, true
+}
+
+// stringField returns a string field of change data
+func stringField(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
+
+// anyTag reports whether tags, as decoded from event data, hold any of wanted
+func anyTag(wanted []string, tags interface{}) bool {
+	switch tags := tags.(type) {
+	case []string:
+		for _, tag := range tags {
+			if containsFold(wanted, tag) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok && containsFold(wanted, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values hold value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package changestream
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/eventfilter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ciEvent(ciType string, tags ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"name": "db-1", "type": ciType, "tags": tags}
+}
+
+func receive(t *testing.T, sub *Subscription) []Change {
+	t.Helper()
+	var changes []Change
+	for {
+		select {
+		case change := <-sub.Changes():
+			changes = append(changes, change)
+		default:
+			return changes
+		}
+	}
+}
+
+func TestApplyBroadcastsMatchingChanges(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(Options{})
+	everything := hub.Subscribe(Filter{}, 0)
+	databases := hub.Subscribe(Filter{CITypes: []string{"Database"}, Tags: []string{"prod"}}, 0)
+	relationships := hub.Subscribe(Filter{Entities: []string{EntityRelationship}}, 0)
+	defer everything.Close()
+
+	require.NoError(t, hub.Apply(ctx, "configuration_item", "ci-1", "CREATE", ciEvent("database", "prod", "eu")))
+	require.NoError(t, hub.Apply(ctx, "configuration_item", "ci-2", "UPDATE", ciEvent("database", "dev")))
+	require.NoError(t, hub.Apply(ctx, "configuration_item", "ci-3", "DELETE", ciEvent("server", "prod")))
+	require.NoError(t, hub.Apply(ctx, "relationship", "rel-1", "CREATE", map[string]interface{}{"source_id": "ci-1", "target_id": "ci-3"}))
+	require.NoError(t, hub.Apply(ctx, "audit_log", "a-1", "CREATE", nil))
+
+	all := receive(t, everything)
+	require.Len(t, all, 4)
+	assert.Equal(t, "ci.created", all[0].Type())
+	assert.Equal(t, "ci.deleted", all[2].Type())
+	assert.Equal(t, "relationship.created", all[3].Type())
+	for i, change := range all {
+		assert.Equal(t, uint64(i+1), change.Sequence)
+	}
+
+	// Relationship changes without CI types of their ends pass CI type filters
+	matched := receive(t, databases)
+	require.Len(t, matched, 2)
+	assert.Equal(t, "ci-1", matched[0].EntityID)
+	assert.Equal(t, "rel-1", matched[1].EntityID)
+
+	onlyRelationships := receive(t, relationships)
+	require.Len(t, onlyRelationships, 1)
+	assert.Equal(t, EntityRelationship, onlyRelationships[0].Entity)
+}
+
+func TestFilterExpressionNarrowsChanges(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(Options{})
+	expression, err := eventfilter.Compile(`type == "ci.created" && event.data.criticality == "critical" && "payment" in event.data.tags`)
+	require.NoError(t, err)
+	critical := hub.Subscribe(Filter{Expression: expression}, 0)
+	defer critical.Close()
+
+	event := func(criticality string, tags ...interface{}) map[string]interface{} {
+		data := ciEvent("service", tags...)
+		data["criticality"] = criticality
+		return data
+	}
+	require.NoError(t, hub.Apply(ctx, "configuration_item", "ci-1", "CREATE", event("critical", "payment")))
+	require.NoError(t, hub.Apply(ctx, "configuration_item", "ci-2", "CREATE", event("low", "payment")))
+	require.NoError(t, hub.Apply(ctx, "configuration_item", "ci-3", "UPDATE", event("critical", "payment")))
+	require.NoError(t, hub.Apply(ctx, "relationship", "rel-1", "CREATE", map[string]interface{}{"source_id": "ci-1"}))
+
+	matched := receive(t, critical)
+	require.Len(t, matched, 1)
+	assert.Equal(t, "ci-1", matched[0].EntityID)
+}
+
+func TestSlowSubscribersMissChangesWithoutBlocking(t *testing.T) {
+	hub := NewHub(Options{BufferSize: 2})
+	sub := hub.Subscribe(Filter{}, 0)
+
+	for i := 0; i < 5; i++ {
+		hub.Publish(Change{Entity: EntityCI, Action: ActionUpdated, EntityID: "ci-1"})
+	}
+
+	assert.Len(t, receive(t, sub), 2)
+	assert.True(t, sub.Missed())
+	assert.False(t, sub.Missed(), "missed is reset once reported")
+}
+
+func TestSubscribeReplaysChangesAfterSequence(t *testing.T) {
+	hub := NewHub(Options{ReplaySize: 3})
+	for i := 0; i < 5; i++ {
+		hub.Publish(Change{Entity: EntityCI, Action: ActionUpdated, EntityID: "ci-1"})
+	}
+
+	resumed := hub.Subscribe(Filter{}, 3)
+	changes := receive(t, resumed)
+	require.Len(t, changes, 2)
+	assert.Equal(t, uint64(4), changes[0].Sequence)
+	assert.False(t, resumed.Missed())
+
+	// Changes 2 to 5 are needed, but only 3 to 5 are kept
+	tooOld := hub.Subscribe(Filter{}, 1)
+	assert.Len(t, receive(t, tooOld), 3)
+	assert.True(t, tooOld.Missed())
+
+	// Sequences are per server, so one from before a restart is unknown
+	unknown := hub.Subscribe(Filter{}, 42)
+	assert.Empty(t, receive(t, unknown))
+	assert.True(t, unknown.Missed())
+}
+
+func TestCloseUnsubscribes(t *testing.T) {
+	hub := NewHub(Options{})
+	sub := hub.Subscribe(Filter{}, 0)
+	assert.Equal(t, 1, hub.Subscribers())
+
+	sub.Close()
+	sub.Close()
+	assert.Equal(t, 0, hub.Subscribers())
+	hub.Publish(Change{Entity: EntityCI, Action: ActionCreated, EntityID: "ci-1"})
+
+	_, open := <-sub.Changes()
+	assert.False(t, open)
+}
@@ -0,0 +1,73 @@
+// Package cialias keeps the former names and IDs of renamed and merged CIs, so
+// links to them keep resolving to the current CI. Renames are recorded by a
+// trigger on configuration_items, whichever way the CI is updated; merges
+// record the ID and name of the CI merged away. Aliases always point at the
+// current CI directly, so a CI renamed twice or merged after a rename has no
+// chains to follow.
+package cialias
+
+import (
+	"errors"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/reparent"
+	"github.com/google/uuid"
+)
+
+// Alias kinds
+const (
+	KindName = "name"
+	KindID   = "id"
+)
+
+// Reasons an alias was recorded
+const (
+	ReasonRename = "rename"
+	ReasonMerge  = "merge"
+)
+
+var (
+	ErrNotFound     = errors.New("no CI or alias found")
+	ErrMissingName  = errors.New("type and name are required")
+	ErrInvalidMerge = errors.New("a CI cannot be merged into itself")
+)
+
+// Alias is a former name or ID of a CI
+type Alias struct {
+	ID   uuid.UUID `json:"id" db:"id"`
+	CIID uuid.UUID `json:"ci_id" db:"ci_id"`
+	Kind string    `json:"kind" db:"kind"`
+	// CIType and Name are set on name aliases
+	CIType *string `json:"ci_type,omitempty" db:"ci_type"`
+	Name   *string `json:"name,omitempty" db:"name"`
+	// FormerCIID is set on ID aliases
+	FormerCIID *uuid.UUID `json:"former_ci_id,omitempty" db:"former_ci_id"`
+	Reason     string     `json:"reason" db:"reason"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+}
+
+// Resolution is the CI a name or ID refers to. Moved is set when the name or
+// ID is a former one, like an HTTP 301, and Alias is the alias it resolved by.
+type Resolution struct {
+	CI    *models.CI `json:"ci"`
+	Moved bool       `json:"moved"`
+	Alias *Alias     `json:"alias,omitempty"`
+}
+
+// MergeRequest asks to merge a CI into another
+type MergeRequest struct {
+	Into uuid.UUID `json:"into"`
+}
+
+// MergeResult is the outcome of a merge
+type MergeResult struct {
+	// CI is the CI merged into
+	CI *models.CI `json:"ci"`
+	// Relationships are the relationships moved to the CI, and those skipped
+	// because the CI already had them
+	Relationships *reparent.Result `json:"relationships"`
+	// Aliases are the aliases recorded or moved to the CI
+	Aliases []Alias `json:"aliases"`
+}
@@ -0,0 +1,178 @@
+package cialias
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/reparent"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps CIs and aliases in memory
+type memoryStore struct {
+	cis     map[uuid.UUID]*models.CI
+	aliases []Alias
+}
+
+func newMemoryStore(cis ...*models.CI) *memoryStore {
+	m := &memoryStore{cis: map[uuid.UUID]*models.CI{}}
+	for _, ci := range cis {
+		m.cis[ci.ID] = ci
+	}
+	return m
+}
+
+func (m *memoryStore) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	return m.cis[id], nil
+}
+
+func (m *memoryStore) FindCI(ctx context.Context, ciType, name string) (*models.CI, error) {
+	for _, ci := range m.cis {
+		if ci.Type == ciType && ci.Name == name {
+			return ci, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) FindFormerID(ctx context.Context, id uuid.UUID) (*Alias, error) {
+	for i, alias := range m.aliases {
+		if alias.Kind == KindID && *alias.FormerCIID == id {
+			return &m.aliases[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) FindFormerName(ctx context.Context, ciType, name string) (*Alias, error) {
+	for i, alias := range m.aliases {
+		if alias.Kind == KindName && *alias.CIType == ciType && *alias.Name == name {
+			return &m.aliases[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) ListAliases(ctx context.Context, ciID uuid.UUID) ([]Alias, error) {
+	aliases := []Alias{}
+	for _, alias := range m.aliases {
+		if alias.CIID == ciID {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases, nil
+}
+
+func (m *memoryStore) Merge(ctx context.Context, from *models.CI, into uuid.UUID, by uuid.UUID, at time.Time) ([]Alias, error) {
+	delete(m.cis, from.ID)
+	for i := range m.aliases {
+		if m.aliases[i].CIID == from.ID {
+			m.aliases[i].CIID = into
+		}
+	}
+	formerID, ciType, name := from.ID, from.Type, from.Name
+	m.aliases = append(m.aliases,
+		Alias{ID: uuid.New(), CIID: into, Kind: KindID, FormerCIID: &formerID, Reason: ReasonMerge, CreatedAt: at},
+		Alias{ID: uuid.New(), CIID: into, Kind: KindName, CIType: &ciType, Name: &name, Reason: ReasonMerge, CreatedAt: at},
+	)
+	return m.ListAliases(ctx, into)
+}
+
+// rename records a name alias as the trigger on configuration_items does
+func (m *memoryStore) rename(ci *models.CI, name string) {
+	ciType, former := ci.Type, ci.Name
+	m.aliases = append(m.aliases, Alias{ID: uuid.New(), CIID: ci.ID, Kind: KindName, CIType: &ciType, Name: &former, Reason: ReasonRename})
+	ci.Name = name
+}
+
+// fakeReparenter records the re-parenting requests it is asked for
+type fakeReparenter struct {
+	requests []reparent.Request
+	err      error
+}
+
+func (f *fakeReparenter) Reparent(ctx context.Context, req reparent.Request, changedBy uuid.UUID) (*reparent.Result, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.requests = append(f.requests, req)
+	return &reparent.Result{FromCIID: req.FromCIID, ToCIID: req.ToCIID}, nil
+}
+
+func TestResolveNameFollowsRename(t *testing.T) {
+	ci := &models.CI{ID: uuid.New(), Name: "web-01", Type: "server"}
+	store := newMemoryStore(ci)
+	store.rename(ci, "web-01.prod")
+	service := NewService(store, &fakeReparenter{})
+
+	resolution, err := service.ResolveName(context.Background(), "server", " web-01 ")
+	require.NoError(t, err)
+	assert.True(t, resolution.Moved)
+	assert.Equal(t, ci.ID, resolution.CI.ID)
+	assert.Equal(t, ReasonRename, resolution.Alias.Reason)
+
+	resolution, err = service.ResolveName(context.Background(), "server", "web-01.prod")
+	require.NoError(t, err)
+	assert.False(t, resolution.Moved)
+	assert.Nil(t, resolution.Alias)
+
+	_, err = service.ResolveName(context.Background(), "server", "web-02")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = service.ResolveName(context.Background(), "", "web-01")
+	assert.ErrorIs(t, err, ErrMissingName)
+}
+
+func TestMergeResolvesFormerIDAndName(t *testing.T) {
+	from := &models.CI{ID: uuid.New(), Name: "db-old", Type: "database"}
+	into := &models.CI{ID: uuid.New(), Name: "db", Type: "database"}
+	store := newMemoryStore(from, into)
+	store.rename(from, "db-legacy")
+	reparenter := &fakeReparenter{}
+	service := NewService(store, reparenter)
+
+	result, err := service.Merge(context.Background(), from.ID, MergeRequest{Into: into.ID}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, into.ID, result.CI.ID)
+	assert.Len(t, result.Aliases, 3)
+	require.Len(t, reparenter.requests, 1)
+	assert.Equal(t, reparent.DirectionBoth, reparenter.requests[0].Direction)
+
+	resolution, err := service.ResolveID(context.Background(), from.ID)
+	require.NoError(t, err)
+	assert.True(t, resolution.Moved)
+	assert.Equal(t, into.ID, resolution.CI.ID)
+
+	// The name from before the rename points straight at the CI merged into
+	resolution, err = service.ResolveName(context.Background(), "database", "db-old")
+	require.NoError(t, err)
+	assert.Equal(t, into.ID, resolution.CI.ID)
+}
+
+func TestMergeRejectsInvalidRequests(t *testing.T) {
+	ci := &models.CI{ID: uuid.New(), Name: "app", Type: "application"}
+	reparenter := &fakeReparenter{}
+	service := NewService(newMemoryStore(ci), reparenter)
+
+	_, err := service.Merge(context.Background(), ci.ID, MergeRequest{Into: ci.ID}, uuid.Nil)
+	assert.ErrorIs(t, err, ErrInvalidMerge)
+	_, err = service.Merge(context.Background(), ci.ID, MergeRequest{Into: uuid.New()}, uuid.Nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, reparenter.requests)
+}
+
+func TestMergeKeepsCIWhenReparentFails(t *testing.T) {
+	from := &models.CI{ID: uuid.New(), Name: "a", Type: "server"}
+	into := &models.CI{ID: uuid.New(), Name: "b", Type: "server"}
+	store := newMemoryStore(from, into)
+	service := NewService(store, &fakeReparenter{err: reparent.ErrConcurrentChange})
+
+	_, err := service.Merge(context.Background(), from.ID, MergeRequest{Into: into.ID}, uuid.Nil)
+	assert.True(t, errors.Is(err, reparent.ErrConcurrentChange))
+	assert.Contains(t, store.cis, from.ID)
+	assert.Empty(t, store.aliases)
+}
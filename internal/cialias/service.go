@@ -0,0 +1,127 @@
+package cialias
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"connect/internal/reparent"
+	"github.com/google/uuid"
+)
+
+// Reparenter moves the relationships of one CI to another, as the
+// re-parenting service does
+type Reparenter interface {
+	Reparent(ctx context.Context, req reparent.Request, changedBy uuid.UUID) (*reparent.Result, error)
+}
+
+// Service resolves CIs through their aliases and merges CIs
+type Service struct {
+	store      Store
+	reparenter Reparenter
+	now        func() time.Time
+}
+
+// NewService creates a new CI alias service
+func NewService(store Store, reparenter Reparenter) *Service {
+	return &Service{store: store, reparenter: reparenter, now: time.Now}
+}
+
+// ResolveID returns the CI with an ID or, when the ID is that of a CI merged
+// away, the CI it was merged into
+func (s *Service) ResolveID(ctx context.Context, id uuid.UUID) (*Resolution, error) {
+	ci, err := s.store.GetCI(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ci != nil {
+		return &Resolution{CI: ci}, nil
+	}
+
+	alias, err := s.store.FindFormerID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.moved(ctx, alias)
+}
+
+// ResolveName returns the CI of a type with a name or, when the name is a
+// former one, the CI that had it
+func (s *Service) ResolveName(ctx context.Context, ciType, name string) (*Resolution, error) {
+	ciType, name = strings.TrimSpace(ciType), strings.TrimSpace(name)
+	if ciType == "" || name == "" {
+		return nil, ErrMissingName
+	}
+
+	ci, err := s.store.FindCI(ctx, ciType, name)
+	if err != nil {
+		return nil, err
+	}
+	if ci != nil {
+		return &Resolution{CI: ci}, nil
+	}
+
+	alias, err := s.store.FindFormerName(ctx, ciType, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.moved(ctx, alias)
+}
+
+// moved resolves an alias to its CI
+func (s *Service) moved(ctx context.Context, alias *Alias) (*Resolution, error) {
+	if alias == nil {
+		return nil, ErrNotFound
+	}
+	ci, err := s.store.GetCI(ctx, alias.CIID)
+	if err != nil {
+		return nil, err
+	}
+	// The CI of an alias may have been deleted since
+	if ci == nil {
+		return nil, ErrNotFound
+	}
+	return &Resolution{CI: ci, Moved: true, Alias: alias}, nil
+}
+
+// List returns the former names and IDs of a CI, for reverse lookups
+func (s *Service) List(ctx context.Context, ciID uuid.UUID) ([]Alias, error) {
+	return s.store.ListAliases(ctx, ciID)
+}
+
+// Merge merges a CI into another: its relationships are moved to the other CI,
+// it is deleted, and its ID, name and aliases become aliases of the other CI.
+// Relationships the other CI already has are left on the deleted CI. The
+// relationships are moved before the CI is deleted, so when deleting fails the
+// merge can be retried and finishes with nothing left to move.
+func (s *Service) Merge(ctx context.Context, fromID uuid.UUID, req MergeRequest, by uuid.UUID) (*MergeResult, error) {
+	if fromID == req.Into {
+		return nil, ErrInvalidMerge
+	}
+	from, err := s.store.GetCI(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	into, err := s.store.GetCI(ctx, req.Into)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || into == nil {
+		return nil, ErrNotFound
+	}
+
+	relationships, err := s.reparenter.Reparent(ctx, reparent.Request{
+		FromCIID:  fromID,
+		ToCIID:    req.Into,
+		Direction: reparent.DirectionBoth,
+	}, by)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := s.store.Merge(ctx, from, req.Into, by, s.now())
+	if err != nil {
+		return nil, err
+	}
+	return &MergeResult{CI: into, Relationships: relationships, Aliases: aliases}, nil
+}
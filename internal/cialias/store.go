@@ -0,0 +1,176 @@
+package cialias
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store reads CIs and their aliases and applies merges
+type Store interface {
+	// GetCI returns a live CI, or nil when there is none
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+	// FindCI returns the live CI of a type with a name, or nil when there is none
+	FindCI(ctx context.Context, ciType, name string) (*models.CI, error)
+	FindFormerID(ctx context.Context, id uuid.UUID) (*Alias, error)
+	FindFormerName(ctx context.Context, ciType, name string) (*Alias, error)
+	ListAliases(ctx context.Context, ciID uuid.UUID) ([]Alias, error)
+	// Merge deletes a CI and makes its ID, name and aliases aliases of another
+	// in one transaction, returning the aliases of the other CI
+	Merge(ctx context.Context, from *models.CI, into uuid.UUID, by uuid.UUID, at time.Time) ([]Alias, error)
+}
+
+// PostgresStore keeps aliases in the ci_aliases table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed CI alias store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const ciColumns = `id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+	attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+	is_active, is_deleted, created_at, updated_at, created_by, updated_by`
+
+const aliasColumns = `id, ci_id, kind, ci_type, name, former_ci_id, reason, created_at, created_by`
+
+// GetCI returns a live CI, or nil when there is none
+func (s *PostgresStore) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	var ci models.CI
+	err := s.db.GetContext(ctx, &ci, `SELECT `+ciColumns+` FROM configuration_items WHERE id = $1 AND is_deleted = false`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI: %w", err)
+	}
+	return &ci, nil
+}
+
+// FindCI returns the live CI of a type with a name, or nil when there is none
+func (s *PostgresStore) FindCI(ctx context.Context, ciType, name string) (*models.CI, error) {
+	var ci models.CI
+	err := s.db.GetContext(ctx, &ci, `SELECT `+ciColumns+` FROM configuration_items WHERE type = $1 AND name = $2 AND is_deleted = false`, ciType, name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find CI: %w", err)
+	}
+	return &ci, nil
+}
+
+// FindFormerID returns the alias of the ID of a CI merged away, or nil when there is none
+func (s *PostgresStore) FindFormerID(ctx context.Context, id uuid.UUID) (*Alias, error) {
+	var alias Alias
+	err := s.db.GetContext(ctx, &alias, `SELECT `+aliasColumns+` FROM ci_aliases WHERE kind = 'id' AND former_ci_id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find CI alias: %w", err)
+	}
+	return &alias, nil
+}
+
+// FindFormerName returns the alias of a former name, or nil when there is none
+func (s *PostgresStore) FindFormerName(ctx context.Context, ciType, name string) (*Alias, error) {
+	var alias Alias
+	err := s.db.GetContext(ctx, &alias, `SELECT `+aliasColumns+` FROM ci_aliases WHERE kind = 'name' AND ci_type = $1 AND name = $2`, ciType, name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find CI alias: %w", err)
+	}
+	return &alias, nil
+}
+
+// ListAliases returns the aliases of a CI, most recent first
+func (s *PostgresStore) ListAliases(ctx context.Context, ciID uuid.UUID) ([]Alias, error) {
+	return listAliases(ctx, s.db, ciID)
+}
+
+func listAliases(ctx context.Context, q sqlx.QueryerContext, ciID uuid.UUID) ([]Alias, error) {
+	aliases := []Alias{}
+	if err := sqlx.SelectContext(ctx, q, &aliases, `SELECT `+aliasColumns+` FROM ci_aliases WHERE ci_id = $1 ORDER BY created_at DESC, id`, ciID); err != nil {
+		return nil, fmt.Errorf("failed to list CI aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// Merge deletes a CI and makes its ID, name and aliases aliases of another
+func (s *PostgresStore) Merge(ctx context.Context, from *models.CI, into uuid.UUID, by uuid.UUID, at time.Time) ([]Alias, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var changedBy *uuid.UUID
+	if by != uuid.Nil {
+		changedBy = &by
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE configuration_items SET is_deleted = true, updated_at = $2, updated_by = COALESCE($3, updated_by)
+		WHERE id = $1 AND is_deleted = false`, from.ID, at, changedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete merged CI: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to delete merged CI: %w", err)
+	} else if rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE ci_aliases SET ci_id = $2 WHERE ci_id = $1`, from.ID, into); err != nil {
+		return nil, fmt.Errorf("failed to move aliases: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ci_aliases (ci_id, kind, former_ci_id, reason, created_at, created_by)
+		VALUES ($1, 'id', $2, 'merge', $3, $4)
+		ON CONFLICT (former_ci_id) WHERE kind = 'id' DO UPDATE
+		SET ci_id = EXCLUDED.ci_id, reason = EXCLUDED.reason, created_at = EXCLUDED.created_at, created_by = EXCLUDED.created_by`,
+		into, from.ID, at, changedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record ID alias: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ci_aliases (ci_id, kind, ci_type, name, reason, created_at, created_by)
+		VALUES ($1, 'name', $2, $3, 'merge', $4, $5)
+		ON CONFLICT (ci_type, name) WHERE kind = 'name' DO UPDATE
+		SET ci_id = EXCLUDED.ci_id, reason = EXCLUDED.reason, created_at = EXCLUDED.created_at, created_by = EXCLUDED.created_by`,
+		into, from.Type, from.Name, at, changedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record name alias: %w", err)
+	}
+
+	details, err := json.Marshal(map[string]interface{}{"merged_into": into, "name": from.Name, "type": from.Type})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ('ci', $1, 'merged', $2, $3, $4)`, from.ID, changedBy, at, details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	aliases, err := listAliases(ctx, tx, into)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return aliases, nil
+}
@@ -0,0 +1,138 @@
+// Package ciarchive moves long-inactive CIs to an archive tier. CIs that are
+// inactive or deleted and have not changed for a configured period are moved,
+// with their relationships and history, out of the tables every query, listing
+// and sync run reads, so those stay small. Archived CIs can be listed through
+// their own endpoints and restored on demand.
+package ciarchive
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultBatchSize is the number of CIs archived per transaction
+const DefaultBatchSize = 500
+
+// Store moves CIs to and from the archive, as CIRepository does
+type Store interface {
+	// ArchiveInactiveCIs archives up to limit CIs inactive or deleted and
+	// unchanged since cutoff, and returns how many it archived
+	ArchiveInactiveCIs(ctx context.Context, cutoff time.Time, limit int) (int64, error)
+	ListArchivedCIs(ctx context.Context, filter models.ArchivedCIFilter) (*models.ArchivedCIList, error)
+	GetArchivedCI(ctx context.Context, id uuid.UUID) (*models.ArchivedCI, error)
+	RestoreArchivedCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+}
+
+// Result is the outcome of an archive run
+type Result struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Cutoff      time.Time `json:"cutoff"`
+	Archived    int64     `json:"archived"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Service archives inactive CIs and restores them
+type Service struct {
+	store       Store
+	inactiveFor time.Duration
+	batchSize   int
+	now         func() time.Time
+
+	// running serialises runs, so a manual run never overlaps the periodic one
+	running sync.Mutex
+
+	mu      sync.Mutex
+	lastRun *Result
+}
+
+// NewService creates a new archive service archiving CIs unchanged for inactiveFor
+func NewService(store Store, inactiveFor time.Duration, batchSize int) *Service {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Service{
+		store:       store,
+		inactiveFor: inactiveFor,
+		batchSize:   batchSize,
+		now:         time.Now,
+	}
+}
+
+// Archive archives every eligible CI, a batch at a time
+func (s *Service) Archive(ctx context.Context) *Result {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	now := s.now()
+	result := &Result{StartedAt: now, Cutoff: now.Add(-s.inactiveFor)}
+	for {
+		archived, err := s.store.ArchiveInactiveCIs(ctx, result.Cutoff, s.batchSize)
+		result.Archived += archived
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("Failed to archive inactive CIs: %v", err)
+			break
+		}
+		if archived < int64(s.batchSize) {
+			break
+		}
+	}
+	result.CompletedAt = s.now()
+
+	if result.Archived > 0 {
+		log.Printf("Archived %d CIs inactive since %s", result.Archived, result.Cutoff.Format(time.RFC3339))
+	}
+
+	s.mu.Lock()
+	s.lastRun = result
+	s.mu.Unlock()
+	return result
+}
+
+// LastRun returns the outcome of the last run, or nil if there was none yet
+func (s *Service) LastRun() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun
+}
+
+// Run archives at the given interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Archive(ctx)
+		}
+	}
+}
+
+// List lists archived CIs
+func (s *Service) List(ctx context.Context, filter models.ArchivedCIFilter) (*models.ArchivedCIList, error) {
+	return s.store.ListArchivedCIs(ctx, filter)
+}
+
+// Get retrieves an archived CI
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*models.ArchivedCI, error) {
+	return s.store.GetArchivedCI(ctx, id)
+}
+
+// Restore moves an archived CI back to the live tables
+func (s *Service) Restore(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	ci, err := s.store.RestoreArchivedCI(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Restored archived CI %s (%s)", ci.Name, ci.ID)
+	return ci, nil
+}
@@ -0,0 +1,150 @@
+package ciarchive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore archives CIs held in memory by their last change
+type memoryStore struct {
+	live     map[uuid.UUID]*models.CI
+	archived map[uuid.UUID]*models.CI
+	calls    int
+	failAt   int
+}
+
+func newMemoryStore(cis ...*models.CI) *memoryStore {
+	store := &memoryStore{live: map[uuid.UUID]*models.CI{}, archived: map[uuid.UUID]*models.CI{}}
+	for _, ci := range cis {
+		store.live[ci.ID] = ci
+	}
+	return store
+}
+
+func (m *memoryStore) ArchiveInactiveCIs(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	m.calls++
+	if m.calls == m.failAt {
+		return 0, errors.New("connection refused")
+	}
+	var archived int64
+	for id, ci := range m.live {
+		if archived == int64(limit) {
+			break
+		}
+		if (!ci.IsActive || ci.IsDeleted) && ci.UpdatedAt.Before(cutoff) {
+			m.archived[id] = ci
+			delete(m.live, id)
+			archived++
+		}
+	}
+	return archived, nil
+}
+
+func (m *memoryStore) ListArchivedCIs(ctx context.Context, filter models.ArchivedCIFilter) (*models.ArchivedCIList, error) {
+	list := &models.ArchivedCIList{CIs: []models.ArchivedCI{}}
+	for _, ci := range m.archived {
+		list.CIs = append(list.CIs, models.ArchivedCI{ID: ci.ID, Name: ci.Name, Type: ci.Type})
+	}
+	list.Total = int64(len(list.CIs))
+	return list, nil
+}
+
+func (m *memoryStore) GetArchivedCI(ctx context.Context, id uuid.UUID) (*models.ArchivedCI, error) {
+	ci, ok := m.archived[id]
+	if !ok {
+		return nil, models.ErrArchivedCINotFound
+	}
+	return &models.ArchivedCI{ID: ci.ID, Name: ci.Name, Type: ci.Type}, nil
+}
+
+func (m *memoryStore) RestoreArchivedCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	ci, ok := m.archived[id]
+	if !ok {
+		return nil, models.ErrArchivedCINotFound
+	}
+	delete(m.archived, id)
+	m.live[id] = ci
+	return ci, nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store, batchSize int) *Service {
+	service := NewService(store, 90*24*time.Hour, batchSize)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func testCI(name string, active bool, unchangedFor time.Duration) *models.CI {
+	return &models.CI{ID: uuid.New(), Name: name, Type: "server", IsActive: active, UpdatedAt: now.Add(-unchangedFor)}
+}
+
+func TestArchiveMovesLongInactiveCIs(t *testing.T) {
+	day := 24 * time.Hour
+	retired := testCI("retired", false, 120*day)
+	recent := testCI("recently-retired", false, 10*day)
+	live := testCI("live", true, 400*day)
+	store := newMemoryStore(retired, recent, live)
+	service := newTestService(store, 10)
+	assert.Nil(t, service.LastRun())
+
+	result := service.Archive(context.Background())
+	assert.Empty(t, result.Error)
+	assert.Equal(t, int64(1), result.Archived)
+	assert.Equal(t, now.Add(-90*day), result.Cutoff)
+	assert.Same(t, result, service.LastRun())
+	assert.Contains(t, store.archived, retired.ID)
+	assert.Contains(t, store.live, recent.ID)
+	assert.Contains(t, store.live, live.ID)
+
+	list, err := service.List(context.Background(), models.ArchivedCIFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), list.Total)
+
+	restored, err := service.Restore(context.Background(), retired.ID)
+	require.NoError(t, err)
+	assert.Equal(t, retired.ID, restored.ID)
+	assert.Contains(t, store.live, retired.ID)
+
+	_, err = service.Restore(context.Background(), retired.ID)
+	assert.ErrorIs(t, err, models.ErrArchivedCINotFound)
+}
+
+func TestArchiveWorksInBatches(t *testing.T) {
+	var cis []*models.CI
+	for i := 0; i < 25; i++ {
+		cis = append(cis, testCI("retired", false, 365*24*time.Hour))
+	}
+	store := newMemoryStore(cis...)
+	service := newTestService(store, 10)
+
+	result := service.Archive(context.Background())
+	assert.Equal(t, int64(25), result.Archived)
+	assert.Equal(t, 3, store.calls)
+}
+
+func TestArchiveStopsOnError(t *testing.T) {
+	var cis []*models.CI
+	for i := 0; i < 25; i++ {
+		cis = append(cis, testCI("retired", false, 365*24*time.Hour))
+	}
+	store := newMemoryStore(cis...)
+	store.failAt = 2
+	service := newTestService(store, 10)
+
+	result := service.Archive(context.Background())
+	assert.Equal(t, int64(10), result.Archived)
+	assert.Equal(t, "connection refused", result.Error)
+
+	// The next run picks up what is left
+	result = service.Archive(context.Background())
+	require.Empty(t, result.Error)
+	assert.Equal(t, int64(15), result.Archived)
+}
@@ -0,0 +1,323 @@
+// Package ciimport imports CIs from streamed CSV and NDJSON uploads. Rows are
+// read, validated against their CI type schema and written one at a time, so
+// an upload of any size is imported in constant memory, and a dry run reports
+// what an import would do without writing anything.
+package ciimport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"connect/internal/importexport"
+	"connect/internal/importjournal"
+	"connect/internal/importref"
+	"connect/internal/models"
+	"connect/internal/quota"
+	"github.com/google/uuid"
+)
+
+// MaxReportedErrors bounds the row errors kept in a report. Failed rows past
+// it are still counted.
+const MaxReportedErrors = 1000
+
+// Store reads and writes the CIs of an import
+type Store interface {
+	GetCISchemaByType(ctx context.Context, ciType string) (*models.CITypeSchema, error)
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+	CreateCI(ctx context.Context, ci *models.CI) (*models.CI, error)
+	UpdateCI(ctx context.Context, ci *models.CI) (*models.CI, error)
+	RecordAttributeProvenance(ctx context.Context, ciID uuid.UUID, attributes []string, source models.ProvenanceSource, setBy uuid.UUID) error
+}
+
+// QuotaChecker checks the CIs an import creates against the CI quotas
+type QuotaChecker interface {
+	Check(ctx context.Context, ciType, tenant string) error
+}
+
+// Options control an import
+type Options struct {
+	Format string
+	DryRun bool
+	By     uuid.UUID
+	// Source is recorded as the provenance of every attribute the import writes
+	Source models.ProvenanceSource
+	// Journal records the changes so the import can be rolled back; nil
+	// journals nothing
+	Journal *importjournal.Journal
+	// Links collects the references of the CIs written, to be related once
+	// the upload is imported. Unlike the rows, the references are held in
+	// memory until then; nil collects nothing.
+	Links *importref.Linker
+}
+
+// RowError describes a row that was not imported
+type RowError struct {
+	Row     int    `json:"row"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of an import. In a dry run the counts are what the
+// import would have done.
+type Report struct {
+	JobID   string     `json:"job_id,omitempty"`
+	Format  string     `json:"format"`
+	DryRun  bool       `json:"dry_run"`
+	Rows    int        `json:"rows"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors,omitempty"`
+	// ErrorsTruncated is set when more rows failed than MaxReportedErrors
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+	// Aborted is why the upload could not be read to its end; the rows
+	// before it were imported
+	Aborted string `json:"aborted,omitempty"`
+	// References is the outcome of relating the CIs to the CIs their
+	// reference attributes name, when any CI has such references
+	References *importref.Result `json:"references,omitempty"`
+	// RollbackURL is where the import can be rolled back, when it was journaled
+	RollbackURL string `json:"rollback_url,omitempty"`
+}
+
+// fail records a row that was not imported
+func (r *Report) fail(row int, name string, err error) {
+	r.Failed++
+	if len(r.Errors) >= MaxReportedErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, RowError{Row: row, Name: name, Message: err.Error()})
+}
+
+// Service imports CIs row by row
+type Service struct {
+	store     Store
+	quotas    QuotaChecker
+	validator *models.SchemaValidator
+}
+
+// NewService creates a new CI import service
+func NewService(store Store) *Service {
+	return &Service{store: store, validator: models.NewSchemaValidator()}
+}
+
+// SetQuotas checks the CIs an import creates against the per-tenant and per-type CI quotas
+func (s *Service) SetQuotas(quotas QuotaChecker) {
+	s.quotas = quotas
+}
+
+// Import reads every row of an upload and creates the CIs without an ID or
+// with an unknown one and updates the others. A failing row is reported and
+// does not stop the import.
+func (s *Service) Import(ctx context.Context, reader *importexport.CIReader, options Options) *Report {
+	report := &Report{Format: options.Format, DryRun: options.DryRun}
+	if !options.DryRun && options.Source.ID != "" {
+		report.JobID = options.Source.ID
+	}
+
+	schemas := make(map[string]*models.CITypeSchema)
+	// A dry run writes nothing, so the IDs it would have created are kept to
+	// report later rows with the same ID as updates
+	planned := make(map[uuid.UUID]bool)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			report.Aborted = err.Error()
+			return report
+		}
+
+		row, err := reader.Next()
+		if err == io.EOF {
+			return report
+		}
+		var rowErr importexport.RowError
+		if errors.As(err, &rowErr) {
+			report.Rows++
+			report.fail(rowErr.Row, "", errors.New(rowErr.Message))
+			continue
+		}
+		if err != nil {
+			report.Aborted = err.Error()
+			return report
+		}
+
+		report.Rows++
+		created, err := s.importRow(ctx, row, schemas, planned, options)
+		if err != nil {
+			report.fail(row.Line, row.Name, err)
+			continue
+		}
+		if created {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+}
+
+// importRow validates one row and, unless in a dry run, writes it. It reports
+// whether the row creates a CI.
+func (s *Service) importRow(ctx context.Context, row importexport.CIRow, schemas map[string]*models.CITypeSchema, planned map[uuid.UUID]bool, options Options) (bool, error) {
+	schema, err := s.schema(ctx, row.Type, schemas)
+	if err != nil {
+		return false, err
+	}
+
+	attributes, err := importexport.CoerceAttributes(row.Attributes, schema)
+	if err != nil {
+		return false, err
+	}
+
+	var existing *models.CI
+	if row.ID != uuid.Nil && !planned[row.ID] {
+		existing, _ = s.store.GetCI(ctx, row.ID)
+	}
+
+	if existing != nil || planned[row.ID] {
+		// existing is kept as it was for the journal
+		ci := &models.CI{ID: row.ID}
+		if existing != nil {
+			copied := *existing
+			ci = &copied
+		}
+		previousAttributes := ci.Attributes
+		applyRow(ci, row, attributes)
+		ci.UpdatedBy = options.By
+		if err := s.validate(ci, schema); err != nil {
+			return false, err
+		}
+		if options.DryRun {
+			return false, nil
+		}
+
+		updated, err := s.store.UpdateCI(ctx, ci)
+		if err != nil {
+			return false, err
+		}
+		s.recordProvenance(ctx, updated.ID, previousAttributes, updated.Attributes, options)
+		options.Journal.Updated(importjournal.EntityCI, updated.ID, existing, updated.UpdatedAt)
+		options.Links.Add(row.Line, updated, schema)
+		return false, nil
+	}
+
+	ci := &models.CI{
+		ID:        row.ID,
+		CreatedBy: options.By,
+		UpdatedBy: options.By,
+	}
+	if ci.ID == uuid.Nil {
+		ci.ID = uuid.New()
+	}
+	applyRow(ci, row, attributes)
+	if err := s.validate(ci, schema); err != nil {
+		return false, err
+	}
+	if s.quotas != nil {
+		if err := s.quotas.Check(ctx, ci.Type, quota.CITenant(ci.Attributes)); err != nil {
+			return false, err
+		}
+	}
+	if options.DryRun {
+		if row.ID != uuid.Nil {
+			planned[row.ID] = true
+		}
+		return true, nil
+	}
+
+	created, err := s.store.CreateCI(ctx, ci)
+	if err != nil {
+		return false, err
+	}
+	s.recordProvenance(ctx, created.ID, nil, created.Attributes, options)
+	options.Journal.Created(importjournal.EntityCI, created.ID, created.UpdatedAt)
+	options.Links.Add(row.Line, created, schema)
+	return true, nil
+}
+
+// schema returns the schema of a CI type, nil when the type has none, caching
+// the lookups of an import
+func (s *Service) schema(ctx context.Context, ciType string, schemas map[string]*models.CITypeSchema) (*models.CITypeSchema, error) {
+	if schema, ok := schemas[ciType]; ok {
+		return schema, nil
+	}
+
+	schema, err := s.store.GetCISchemaByType(ctx, ciType)
+	if errors.Is(err, sql.ErrNoRows) {
+		schema, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema of type %s: %w", ciType, err)
+	}
+	schemas[ciType] = schema
+	return schema, nil
+}
+
+// validate checks a CI against its schema and applies the schema defaults.
+// CIs of types without a schema are not validated.
+func (s *Service) validate(ci *models.CI, schema *models.CITypeSchema) error {
+	if schema == nil {
+		return nil
+	}
+
+	result := s.validator.ValidateCIAgainstSchema(*ci, *schema)
+	if !result.IsValid {
+		messages := make([]string, len(result.Errors))
+		for i, validationErr := range result.Errors {
+			messages[i] = validationErr.Message
+		}
+		return fmt.Errorf("CI validation failed: %s", strings.Join(messages, "; "))
+	}
+
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(ci.Attributes, &attributes); err != nil {
+		return fmt.Errorf("failed to unmarshal attributes: %w", err)
+	}
+	data, err := json.Marshal(s.validator.ApplyDefaults(attributes, *schema))
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	ci.Attributes = data
+	return nil
+}
+
+// recordProvenance attributes the changed attributes of a CI to the import
+func (s *Service) recordProvenance(ctx context.Context, ciID uuid.UUID, before, after json.RawMessage, options Options) {
+	changed, err := models.ChangedAttributes(before, after)
+	if err != nil {
+		log.Printf("Failed to diff attributes of CI %s: %v", ciID, err)
+		return
+	}
+	if err := s.store.RecordAttributeProvenance(ctx, ciID, changed, options.Source, options.By); err != nil {
+		log.Printf("Failed to record attribute provenance for CI %s: %v", ciID, err)
+	}
+}
+
+// applyRow copies the columns of a row onto a CI. Empty status, criticality
+// and is_active columns keep the values of the CI.
+func applyRow(ci *models.CI, row importexport.CIRow, attributes json.RawMessage) {
+	ci.Name = row.Name
+	ci.Type = row.Type
+	ci.Description = row.Description
+	ci.Owner = row.Owner
+	ci.Location = row.Location
+	ci.Tags = row.Tags
+	ci.Attributes = attributes
+	ci.InstallDate = row.InstallDate
+	ci.WarrantyExpiry = row.WarrantyExpiry
+	if row.Status != "" {
+		ci.Status = row.Status
+	}
+	if row.Criticality != "" {
+		ci.Criticality = row.Criticality
+	}
+	if row.IsActive != nil {
+		ci.IsActive = *row.IsActive
+	}
+}
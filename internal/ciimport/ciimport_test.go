@@ -0,0 +1,170 @@
+package ciimport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"connect/internal/importexport"
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	schemas map[string]*models.CITypeSchema
+	cis     map[uuid.UUID]*models.CI
+	writes  int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		schemas: map[string]*models.CITypeSchema{
+			"server": {
+				Name: "server",
+				Attributes: []models.CITypeAttribute{
+					{Name: "cpu", Type: models.AttributeTypeNumber, Required: true},
+					{Name: "virtual", Type: models.AttributeTypeBoolean, Default: false},
+				},
+			},
+		},
+		cis: map[uuid.UUID]*models.CI{},
+	}
+}
+
+func (m *memoryStore) GetCISchemaByType(ctx context.Context, ciType string) (*models.CITypeSchema, error) {
+	schema, ok := m.schemas[ciType]
+	if !ok {
+		return nil, fmt.Errorf("CI type schema not found: %w", sql.ErrNoRows)
+	}
+	return schema, nil
+}
+
+func (m *memoryStore) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	ci, ok := m.cis[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *ci
+	return &copied, nil
+}
+
+func (m *memoryStore) CreateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
+	m.writes++
+	m.cis[ci.ID] = ci
+	return ci, nil
+}
+
+func (m *memoryStore) UpdateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
+	m.writes++
+	m.cis[ci.ID] = ci
+	return ci, nil
+}
+
+func (m *memoryStore) RecordAttributeProvenance(ctx context.Context, ciID uuid.UUID, attributes []string, source models.ProvenanceSource, setBy uuid.UUID) error {
+	return nil
+}
+
+func importString(t *testing.T, service *Service, format, data string, dryRun bool) *Report {
+	reader, err := importexport.NewCIReader(strings.NewReader(data), format)
+	require.NoError(t, err)
+	return service.Import(context.Background(), reader, Options{Format: format, DryRun: dryRun, By: uuid.New()})
+}
+
+func TestImportCSV(t *testing.T) {
+	store := newMemoryStore()
+	existing := uuid.New()
+	store.cis[existing] = &models.CI{ID: existing, Name: "web-01", Type: "server", Status: models.CIStatusActive}
+
+	csv := "id,name,type,attr:cpu,tags\n" +
+		",db-01,server,8,\"prod, db\"\n" +
+		existing.String() + ",web-01,server,4,\n" +
+		",no-cpu,server,,\n" +
+		",bad-cpu,server,many,\n" +
+		",,server,2,\n" +
+		",printer-01,printer,,\n"
+	report := importString(t, NewService(store), importexport.FormatCSV, csv, false)
+
+	assert.Equal(t, 6, report.Rows)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 3, report.Failed)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, 4, report.Errors[0].Row)
+	assert.Equal(t, "no-cpu", report.Errors[0].Name)
+	assert.Contains(t, report.Errors[0].Message, "Required attribute 'cpu' is missing")
+	assert.Contains(t, report.Errors[1].Message, "not a number")
+	assert.Equal(t, 6, report.Errors[2].Row)
+
+	var attributes map[string]interface{}
+	require.NoError(t, json.Unmarshal(store.cis[existing].Attributes, &attributes))
+	assert.Equal(t, map[string]interface{}{"cpu": float64(4), "virtual": false}, attributes)
+}
+
+func TestImportNDJSON(t *testing.T) {
+	store := newMemoryStore()
+
+	ndjson := `{"name":"db-01","type":"server","attributes":{"cpu":8,"virtual":true},"tags":["prod","db"]}
+
+{"name":"db-02","type":"server","attributes":{"cpu":"eight"}}
+{"name":
+{"name":"db-03","type":"server","attributes":[1]}
+`
+	report := importString(t, NewService(store), importexport.FormatNDJSON, ndjson, false)
+
+	assert.Equal(t, 4, report.Rows)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 3, report.Failed)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, []int{3, 4, 5}, []int{report.Errors[0].Row, report.Errors[1].Row, report.Errors[2].Row})
+
+	require.Len(t, store.cis, 1)
+	for _, ci := range store.cis {
+		assert.Equal(t, []string{"prod", "db"}, ci.Tags)
+		assert.JSONEq(t, `{"cpu":8,"virtual":true}`, string(ci.Attributes))
+	}
+}
+
+func TestImportDryRunWritesNothing(t *testing.T) {
+	store := newMemoryStore()
+	id := uuid.New()
+
+	csv := "id,name,type,attr:cpu\n" +
+		id.String() + ",db-01,server,8\n" +
+		id.String() + ",db-01,server,16\n" +
+		",db-02,server,\n"
+	report := importString(t, NewService(store), importexport.FormatCSV, csv, true)
+
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Failed)
+	assert.Zero(t, store.writes)
+	assert.Empty(t, report.JobID)
+}
+
+func TestImportTruncatesErrors(t *testing.T) {
+	var data strings.Builder
+	data.WriteString("name,type,attr:cpu\n")
+	for i := 0; i < MaxReportedErrors+5; i++ {
+		fmt.Fprintf(&data, "ci-%d,server,\n", i)
+	}
+	report := importString(t, NewService(newMemoryStore()), importexport.FormatCSV, data.String(), true)
+
+	assert.Equal(t, MaxReportedErrors+5, report.Failed)
+	assert.Len(t, report.Errors, MaxReportedErrors)
+	assert.True(t, report.ErrorsTruncated)
+}
+
+func TestImportAbortsOnUnreadableUpload(t *testing.T) {
+	ndjson := `{"name":"db-01","type":"server","attributes":{"cpu":8}}` + "\n" +
+		`{"name":"` + strings.Repeat("x", 2<<20) + `"}` + "\n"
+	report := importString(t, NewService(newMemoryStore()), importexport.FormatNDJSON, ndjson, false)
+
+	assert.Equal(t, 1, report.Created)
+	assert.Contains(t, report.Aborted, "line 2 is longer than")
+}
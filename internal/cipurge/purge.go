@@ -0,0 +1,188 @@
+// Package cipurge permanently removes a CI and every trace of it, for legal
+// erasure requests that soft deletion cannot satisfy. A purge is requested
+// with a dry-run report of what it would remove, must be approved by admins
+// other than the requester, and is only executed while the CI still matches
+// the approved report. Audit entries about the CI are redacted or deleted;
+// entries under a legal hold block the purge.
+package cipurge
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purge request statuses
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusExecuted  = "executed"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// Statuses are the purge request statuses
+var Statuses = []string{StatusPending, StatusApproved, StatusExecuted, StatusCancelled, StatusExpired}
+
+// Audit policies, deciding what happens to the audit entries about a purged CI
+// and its relationships
+const (
+	// AuditRedact keeps the entries, so the history of changes stays
+	// complete, but replaces their details with a reference to the purge
+	AuditRedact = "redact"
+	// AuditDelete removes the entries
+	AuditDelete = "delete"
+)
+
+// AuditPolicies are the accepted audit policies
+var AuditPolicies = []string{AuditRedact, AuditDelete}
+
+// Defaults used when options are not set
+const (
+	DefaultRequiredApprovals = 1
+	DefaultRequestTTL        = 72 * time.Hour
+	DefaultListLimit         = 50
+	MaxReasonLength          = 1000
+)
+
+var (
+	ErrInvalidRequest  = errors.New("invalid purge request")
+	ErrCINotFound      = errors.New("CI not found")
+	ErrPurgeNotFound   = errors.New("purge request not found")
+	ErrBlocked         = errors.New("purge is blocked")
+	ErrSelfApproval    = errors.New("purge requests cannot be approved by their requester")
+	ErrAlreadyApproved = errors.New("purge request already approved by this user")
+	ErrNotApproved     = errors.New("purge request has not been approved")
+	ErrNotPending      = errors.New("purge request is no longer open")
+	ErrExpired         = errors.New("purge request has expired")
+	ErrPlanChanged     = errors.New("CI changed since the purge was approved")
+)
+
+// Request represents a request to purge a CI
+type Request struct {
+	CIID uuid.UUID `json:"ci_id"`
+	// Reason records the legal basis of the purge, e.g. an erasure request reference
+	Reason      string `json:"reason"`
+	AuditPolicy string `json:"audit_policy,omitempty"`
+}
+
+// Validate checks the request. Purges must be requested by a user, so that the
+// approvals can be checked to come from someone else.
+func (r *Request) Validate(by uuid.UUID) error {
+	if by == uuid.Nil {
+		return fmt.Errorf("%w: purges must be requested by a user", ErrInvalidRequest)
+	}
+	if r.CIID == uuid.Nil {
+		return fmt.Errorf("%w: ci_id is required", ErrInvalidRequest)
+	}
+	if r.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidRequest)
+	}
+	if len(r.Reason) > MaxReasonLength {
+		return fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidRequest, MaxReasonLength)
+	}
+	return ValidateAuditPolicy(r.AuditPolicy)
+}
+
+// ValidateAuditPolicy checks an audit policy
+func ValidateAuditPolicy(policy string) error {
+	if policy != AuditRedact && policy != AuditDelete {
+		return fmt.Errorf("%w: audit_policy must be %s or %s", ErrInvalidRequest, AuditRedact, AuditDelete)
+	}
+	return nil
+}
+
+// CI identifies the CI of a purge. Its name and type are cleared once the
+// purge is executed, so the request does not keep what was erased.
+type CI struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Type     string    `json:"type,omitempty"`
+	Archived bool      `json:"archived"`
+}
+
+// Report is the dry run of a purge: the rows it would remove per kind of
+// trace, and what would happen to the audit entries
+type Report struct {
+	CI CI `json:"ci"`
+	// Traces counts the rows removed per kind of trace, e.g. relationships
+	Traces map[string]int64 `json:"traces"`
+	// ConnectedCIs counts the other CIs losing a relationship
+	ConnectedCIs int64  `json:"connected_cis"`
+	AuditPolicy  string `json:"audit_policy"`
+	AuditEntries int64  `json:"audit_entries"`
+	// Blockers explain why the purge cannot go ahead, e.g. a legal hold
+	Blockers    []string  `json:"blockers,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// operationalTraces are the traces sync keeps adding to and removing from
+// while a request awaits approval, without anyone changing the CI
+var operationalTraces = map[string]bool{
+	"sync_events":              true,
+	"sync_log":                 true,
+	"sync_conflicts":           true,
+	"sync_fallback_operations": true,
+	"sync_fallback_log":        true,
+}
+
+// Matches reports whether a fresh dry run removes what an approved one did,
+// ignoring the operational traces
+func (r *Report) Matches(approved *Report) bool {
+	if r.AuditEntries != approved.AuditEntries {
+		return false
+	}
+	for _, traces := range []map[string]int64{r.Traces, approved.Traces} {
+		for trace := range traces {
+			if !operationalTraces[trace] && r.Traces[trace] != approved.Traces[trace] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Approval is the approval of a purge request by an admin
+type Approval struct {
+	By uuid.UUID `json:"by"`
+	At time.Time `json:"at"`
+}
+
+// Purge is a request to purge a CI and its progress towards execution
+type Purge struct {
+	ID                uuid.UUID  `json:"id"`
+	CIID              uuid.UUID  `json:"ci_id"`
+	Reason            string     `json:"reason"`
+	AuditPolicy       string     `json:"audit_policy"`
+	Status            string     `json:"status"`
+	RequestedBy       uuid.UUID  `json:"requested_by"`
+	RequestedAt       time.Time  `json:"requested_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RequiredApprovals int        `json:"required_approvals"`
+	Approvals         []Approval `json:"approvals"`
+	// Report is the dry run the approvers saw
+	Report     *Report    `json:"report"`
+	ExecutedBy *uuid.UUID `json:"executed_by,omitempty"`
+	ExecutedAt *time.Time `json:"executed_at,omitempty"`
+	// Removed counts the rows removed per kind of trace once executed
+	Removed map[string]int64 `json:"removed,omitempty"`
+	// CancelledBy is set on cancelled requests
+	CancelledBy *uuid.UUID `json:"cancelled_by,omitempty"`
+}
+
+// Open reports whether the request may still be approved or executed
+func (p *Purge) Open() bool {
+	return p.Status == StatusPending || p.Status == StatusApproved
+}
+
+// ApprovedBy reports whether a user approved the request
+func (p *Purge) ApprovedBy(by uuid.UUID) bool {
+	for _, approval := range p.Approvals {
+		if approval.By == by {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package cipurge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	reports  map[uuid.UUID]*Report
+	purges   map[uuid.UUID]*Purge
+	executed []uuid.UUID
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{reports: map[uuid.UUID]*Report{}, purges: map[uuid.UUID]*Purge{}}
+}
+
+func (m *memoryStore) addCI(traces map[string]int64, blockers ...string) uuid.UUID {
+	id := uuid.New()
+	m.reports[id] = &Report{CI: CI{ID: id, Name: "db-01", Type: "database"}, Traces: traces, AuditEntries: 3, Blockers: blockers}
+	return id
+}
+
+func (m *memoryStore) Plan(ctx context.Context, ciID uuid.UUID, auditPolicy string) (*Report, error) {
+	report, ok := m.reports[ciID]
+	if !ok {
+		return nil, ErrCINotFound
+	}
+	copied := *report
+	copied.Traces = map[string]int64{}
+	for trace, rows := range report.Traces {
+		copied.Traces[trace] = rows
+	}
+	copied.AuditPolicy = auditPolicy
+	return &copied, nil
+}
+
+func (m *memoryStore) Execute(ctx context.Context, purge *Purge, by uuid.UUID, at time.Time) error {
+	report, err := m.Plan(ctx, purge.CIID, purge.AuditPolicy)
+	if err != nil {
+		return err
+	}
+	if !report.Matches(purge.Report) {
+		return ErrPlanChanged
+	}
+	delete(m.reports, purge.CIID)
+	m.executed = append(m.executed, purge.CIID)
+	purge.Status = StatusExecuted
+	purge.ExecutedBy = &by
+	purge.ExecutedAt = &at
+	purge.Removed = report.Traces
+	purge.Report.CI.Name = ""
+	purge.Report.CI.Type = ""
+	return m.UpdatePurge(ctx, purge, StatusApproved)
+}
+
+func (m *memoryStore) CreatePurge(ctx context.Context, purge *Purge) error {
+	copied := *purge
+	m.purges[purge.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) GetPurge(ctx context.Context, id uuid.UUID) (*Purge, error) {
+	purge, ok := m.purges[id]
+	if !ok {
+		return nil, ErrPurgeNotFound
+	}
+	copied := *purge
+	copied.Approvals = append([]Approval(nil), purge.Approvals...)
+	return &copied, nil
+}
+
+func (m *memoryStore) ListPurges(ctx context.Context, status string, limit int) ([]*Purge, error) {
+	var purges []*Purge
+	for id := range m.purges {
+		purge, _ := m.GetPurge(ctx, id)
+		if status == "" || purge.Status == status {
+			purges = append(purges, purge)
+		}
+	}
+	return purges, nil
+}
+
+func (m *memoryStore) UpdatePurge(ctx context.Context, purge *Purge, from string) error {
+	stored, ok := m.purges[purge.ID]
+	if !ok || stored.Status != from {
+		return ErrNotPending
+	}
+	copied := *purge
+	m.purges[purge.ID] = &copied
+	return nil
+}
+
+func TestRequestValidation(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, Options{})
+	ctx := context.Background()
+	ciID := store.addCI(map[string]int64{"ci": 1})
+
+	_, err := service.Request(ctx, Request{CIID: ciID, Reason: "GDPR erasure #42"}, uuid.Nil)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = service.Request(ctx, Request{CIID: ciID, Reason: "  "}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = service.Request(ctx, Request{CIID: ciID, Reason: "GDPR erasure #42", AuditPolicy: "keep"}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = service.Request(ctx, Request{CIID: uuid.New(), Reason: "GDPR erasure #42"}, uuid.New())
+	assert.ErrorIs(t, err, ErrCINotFound)
+
+	held := store.addCI(map[string]int64{"ci": 1}, "2 audit entries are under an active legal hold")
+	_, err = service.Request(ctx, Request{CIID: held, Reason: "GDPR erasure #42"}, uuid.New())
+	assert.ErrorIs(t, err, ErrBlocked)
+	assert.Contains(t, err.Error(), "legal hold")
+	assert.Empty(t, store.purges)
+}
+
+func TestPurgeNeedsApprovalFromOthers(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, Options{RequiredApprovals: 2})
+	ctx := context.Background()
+	ciID := store.addCI(map[string]int64{"ci": 1, "relationships": 2, "revisions": 5})
+	requester, first, second := uuid.New(), uuid.New(), uuid.New()
+
+	purge, err := service.Request(ctx, Request{CIID: ciID, Reason: "GDPR erasure #42"}, requester)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, purge.Status)
+	assert.Equal(t, AuditRedact, purge.AuditPolicy)
+	assert.Equal(t, int64(2), purge.Report.Traces["relationships"])
+
+	_, err = service.Execute(ctx, purge.ID, requester)
+	assert.ErrorIs(t, err, ErrNotApproved)
+	_, err = service.Approve(ctx, purge.ID, requester)
+	assert.ErrorIs(t, err, ErrSelfApproval)
+
+	purge, err = service.Approve(ctx, purge.ID, first)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, purge.Status)
+	_, err = service.Approve(ctx, purge.ID, first)
+	assert.ErrorIs(t, err, ErrAlreadyApproved)
+
+	purge, err = service.Approve(ctx, purge.ID, second)
+	require.NoError(t, err)
+	assert.Equal(t, StatusApproved, purge.Status)
+	assert.Len(t, purge.Approvals, 2)
+
+	purge, err = service.Execute(ctx, purge.ID, requester)
+	require.NoError(t, err)
+	assert.Equal(t, StatusExecuted, purge.Status)
+	assert.Equal(t, []uuid.UUID{ciID}, store.executed)
+	assert.Equal(t, int64(5), purge.Removed["revisions"])
+	// The request keeps no trace of what was erased
+	assert.Empty(t, store.purges[purge.ID].Report.CI.Name)
+
+	_, err = service.Execute(ctx, purge.ID, requester)
+	assert.ErrorIs(t, err, ErrNotPending)
+}
+
+func TestExecuteRefusesChangedCI(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, Options{})
+	ctx := context.Background()
+	ciID := store.addCI(map[string]int64{"ci": 1, "relationships": 2, "sync_log": 4})
+
+	purge, err := service.Request(ctx, Request{CIID: ciID, Reason: "GDPR erasure #42"}, uuid.New())
+	require.NoError(t, err)
+	_, err = service.Approve(ctx, purge.ID, uuid.New())
+	require.NoError(t, err)
+
+	// Sync activity alone does not invalidate the approval, a new relationship does
+	store.reports[ciID].Traces["sync_log"] = 9
+	store.reports[ciID].Traces["relationships"] = 3
+	_, err = service.Execute(ctx, purge.ID, uuid.New())
+	assert.ErrorIs(t, err, ErrPlanChanged)
+	assert.Empty(t, store.executed)
+
+	store.reports[ciID].Traces["relationships"] = 2
+	_, err = service.Execute(ctx, purge.ID, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{ciID}, store.executed)
+}
+
+func TestRequestsExpireAndCancel(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, Options{RequestTTL: time.Hour})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	stale, err := service.Request(ctx, Request{CIID: store.addCI(nil), Reason: "GDPR erasure #42"}, uuid.New())
+	require.NoError(t, err)
+	cancelled, err := service.Request(ctx, Request{CIID: store.addCI(nil), Reason: "GDPR erasure #43"}, uuid.New())
+	require.NoError(t, err)
+
+	cancelled, err = service.Cancel(ctx, cancelled.ID, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelled.Status)
+	_, err = service.Approve(ctx, cancelled.ID, uuid.New())
+	assert.ErrorIs(t, err, ErrNotPending)
+
+	now = now.Add(2 * time.Hour)
+	_, err = service.Approve(ctx, stale.ID, uuid.New())
+	assert.ErrorIs(t, err, ErrExpired)
+	stale, err = service.Get(ctx, stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusExpired, stale.Status)
+}
+
+func TestReportMatches(t *testing.T) {
+	approved := &Report{Traces: map[string]int64{"ci": 1, "revisions": 4, "sync_events": 2}, AuditEntries: 6}
+
+	assert.True(t, (&Report{Traces: map[string]int64{"ci": 1, "revisions": 4}, AuditEntries: 6}).Matches(approved))
+	assert.False(t, (&Report{Traces: map[string]int64{"ci": 1, "revisions": 4, "costs": 1}, AuditEntries: 6}).Matches(approved))
+	assert.False(t, (&Report{Traces: map[string]int64{"ci": 1}, AuditEntries: 6}).Matches(approved))
+	assert.False(t, (&Report{Traces: map[string]int64{"ci": 1, "revisions": 4}, AuditEntries: 7}).Matches(approved))
+}
@@ -0,0 +1,214 @@
+package cipurge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Options configure the purge service
+type Options struct {
+	// RequiredApprovals is how many admins other than the requester must
+	// approve a purge before it can be executed
+	RequiredApprovals int
+	// RequestTTL is how long a request may wait for approval and execution
+	RequestTTL time.Duration
+}
+
+// Service runs purge requests through approval to execution
+type Service struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+}
+
+// NewService creates a new purge service
+func NewService(store Store, opts Options) *Service {
+	if opts.RequiredApprovals <= 0 {
+		opts.RequiredApprovals = DefaultRequiredApprovals
+	}
+	if opts.RequestTTL <= 0 {
+		opts.RequestTTL = DefaultRequestTTL
+	}
+	return &Service{store: store, opts: opts, now: time.Now}
+}
+
+// Preview returns the dry run of purging a CI without requesting it
+func (s *Service) Preview(ctx context.Context, ciID uuid.UUID, auditPolicy string) (*Report, error) {
+	if auditPolicy == "" {
+		auditPolicy = AuditRedact
+	}
+	if err := ValidateAuditPolicy(auditPolicy); err != nil {
+		return nil, err
+	}
+	return s.store.Plan(ctx, ciID, auditPolicy)
+}
+
+// Request records a purge request with its dry-run report. A CI whose purge
+// is blocked cannot be requested for purging.
+func (s *Service) Request(ctx context.Context, req Request, by uuid.UUID) (*Purge, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.AuditPolicy == "" {
+		req.AuditPolicy = AuditRedact
+	}
+	if err := req.Validate(by); err != nil {
+		return nil, err
+	}
+
+	report, err := s.store.Plan(ctx, req.CIID, req.AuditPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Blockers) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBlocked, strings.Join(report.Blockers, "; "))
+	}
+
+	now := s.now()
+	purge := &Purge{
+		ID:                uuid.New(),
+		CIID:              req.CIID,
+		Reason:            req.Reason,
+		AuditPolicy:       req.AuditPolicy,
+		Status:            StatusPending,
+		RequestedBy:       by,
+		RequestedAt:       now,
+		ExpiresAt:         now.Add(s.opts.RequestTTL),
+		RequiredApprovals: s.opts.RequiredApprovals,
+		Approvals:         []Approval{},
+		Report:            report,
+	}
+	if err := s.store.CreatePurge(ctx, purge); err != nil {
+		return nil, err
+	}
+	log.Printf("Purge %s of CI %s requested by %s", purge.ID, purge.CIID, by)
+	return purge, nil
+}
+
+// Approve records an admin's approval of a pending request. The request is
+// approved once enough admins other than the requester have approved it.
+func (s *Service) Approve(ctx context.Context, id, by uuid.UUID) (*Purge, error) {
+	if by == uuid.Nil {
+		return nil, fmt.Errorf("%w: purges must be approved by a user", ErrInvalidRequest)
+	}
+	purge, err := s.open(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if purge.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	if purge.RequestedBy == by {
+		return nil, ErrSelfApproval
+	}
+	if purge.ApprovedBy(by) {
+		return nil, ErrAlreadyApproved
+	}
+
+	purge.Approvals = append(purge.Approvals, Approval{By: by, At: s.now()})
+	if len(purge.Approvals) >= purge.RequiredApprovals {
+		purge.Status = StatusApproved
+	}
+	if err := s.store.UpdatePurge(ctx, purge, StatusPending); err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// Execute purges the CI of an approved request. It fails with ErrPlanChanged,
+// leaving the request approved, when the CI gained or lost traces since the
+// dry run the approvers saw; such a request should be cancelled and
+// requested again.
+func (s *Service) Execute(ctx context.Context, id, by uuid.UUID) (*Purge, error) {
+	if by == uuid.Nil {
+		return nil, fmt.Errorf("%w: purges must be executed by a user", ErrInvalidRequest)
+	}
+	purge, err := s.open(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if purge.Status != StatusApproved {
+		return nil, ErrNotApproved
+	}
+
+	if err := s.store.Execute(ctx, purge, by, s.now()); err != nil {
+		return nil, err
+	}
+	log.Printf("Purge %s of CI %s executed by %s", purge.ID, purge.CIID, by)
+	return purge, nil
+}
+
+// Cancel cancels an open request
+func (s *Service) Cancel(ctx context.Context, id, by uuid.UUID) (*Purge, error) {
+	purge, err := s.open(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := purge.Status
+	purge.Status = StatusCancelled
+	if by != uuid.Nil {
+		purge.CancelledBy = &by
+	}
+	if err := s.store.UpdatePurge(ctx, purge, from); err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// Get retrieves a purge request
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Purge, error) {
+	purge, err := s.store.GetPurge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.expire(ctx, purge)
+	return purge, nil
+}
+
+// List retrieves the most recent purge requests, optionally in one status
+func (s *Service) List(ctx context.Context, status string, limit int) ([]*Purge, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	purges, err := s.store.ListPurges(ctx, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, purge := range purges {
+		s.expire(ctx, purge)
+	}
+	return purges, nil
+}
+
+// open retrieves a request that may still be approved, executed or cancelled
+func (s *Service) open(ctx context.Context, id uuid.UUID) (*Purge, error) {
+	purge, err := s.store.GetPurge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.expire(ctx, purge) {
+		return nil, ErrExpired
+	}
+	if !purge.Open() {
+		return nil, ErrNotPending
+	}
+	return purge, nil
+}
+
+// expire marks an open request past its expiry as expired, and reports
+// whether it did
+func (s *Service) expire(ctx context.Context, purge *Purge) bool {
+	if !purge.Open() || s.now().Before(purge.ExpiresAt) {
+		return false
+	}
+	from := purge.Status
+	purge.Status = StatusExpired
+	if err := s.store.UpdatePurge(ctx, purge, from); err != nil {
+		log.Printf("Failed to expire purge %s: %v", purge.ID, err)
+	}
+	return true
+}
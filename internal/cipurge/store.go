@@ -0,0 +1,449 @@
+package cipurge
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"connect/internal/organization"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store plans and executes purges and persists purge requests
+type Store interface {
+	// Plan returns the dry run of purging a CI, live or archived, and
+	// ErrCINotFound when there is no such CI
+	Plan(ctx context.Context, ciID uuid.UUID, auditPolicy string) (*Report, error)
+	// Execute purges the CI of an approved request and marks the request
+	// executed in one transaction, failing with ErrPlanChanged when a fresh
+	// dry run no longer matches the request's report and with ErrBlocked when
+	// something blocks the purge
+	Execute(ctx context.Context, purge *Purge, by uuid.UUID, at time.Time) error
+	CreatePurge(ctx context.Context, purge *Purge) error
+	GetPurge(ctx context.Context, id uuid.UUID) (*Purge, error)
+	ListPurges(ctx context.Context, status string, limit int) ([]*Purge, error)
+	// UpdatePurge saves the status, approvals and cancellation of a request
+	// still in status from, ErrNotPending otherwise
+	UpdatePurge(ctx context.Context, purge *Purge, from string) error
+}
+
+// PostgresStore purges CIs in Postgres and keeps requests in the
+// ci_purge_requests table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed purge store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// trace is a table holding traces of a purged CI. Condition selects its rows
+// given $1, the IDs of the CI and of its live and archived relationships.
+type trace struct {
+	name      string
+	table     string
+	condition string
+}
+
+// traces are removed in this order, so rows are gone before those they
+// reference. Relationship versions come after relationships, since deleting
+// a relationship records a closing version of it. The Neo4j copies of the CI
+// and its relationships are removed by sync.
+var traces = []trace{
+	{"relationship_incident_scores", "relationship_incident_scores", "relationship_id = ANY($1)"},
+	{"relationships", "ci_relationships", "source_ci_id = ANY($1) OR target_ci_id = ANY($1)"},
+	{"relationship_versions", "ci_relationship_versions", "relationship_id = ANY($1) OR source_ci_id = ANY($1) OR target_ci_id = ANY($1)"},
+	{"revisions", "ci_revisions", "ci_id = ANY($1)"},
+	{"attribute_provenance", "ci_attribute_provenance", "ci_id = ANY($1)"},
+	{"ownership_transfer_items", "ownership_transfer_items", "ci_id = ANY($1)"},
+	{"costs", "ci_costs", "ci_id = ANY($1)"},
+	{"heartbeats", "ci_heartbeats", "ci_id = ANY($1)"},
+	{"external_ids", "ci_external_ids", "ci_id = ANY($1)"},
+	{"service_tree_members", "service_tree_members", "service_id = ANY($1) OR ci_id = ANY($1)"},
+	{"correction_suggestions", "ci_correction_suggestions", "ci_id = ANY($1)"},
+	{"aliases", "ci_aliases", "ci_id = ANY($1) OR former_ci_id = ANY($1)"},
+	{"script_hook_executions", "script_hook_executions", "ci_id = ANY($1)"},
+	{"attribute_trigger_executions", "attribute_trigger_executions", "ci_id = ANY($1)"},
+	{"incident_links", "incident_cis", "ci_id = ANY($1)"},
+	{"quarantined_items", "quarantined_cis", "created_ci_id = ANY($1)"},
+	{"import_journal_entries", "import_journal_entries", "entity_id = ANY($1)"},
+	// Events still to be synced are kept, so the CI and its relationships
+	// leave Neo4j; their data is stripped when the purge is executed
+	{"sync_events", "sync_events", "entity_id = ANY($1) AND status NOT IN ('PENDING', 'PROCESSING')"},
+	{"sync_log", "sync_log", "entity_id = ANY($1)"},
+	{"sync_conflicts", "sync_conflicts", "entity_id = ANY($1)"},
+	{"sync_fallback_operations", "sync_fallback_operations", "entity_id = ANY($1)"},
+	{"sync_fallback_log", "sync_fallback_log", "entity_id = ANY($1)"},
+	{"legacy_relationships", "relationships", "source_id = ANY($1) OR target_id = ANY($1)"},
+	{"archived_relationships", "archived_ci_relationships", "source_ci_id = ANY($1) OR target_ci_id = ANY($1)"},
+	{"archived_ci", "archived_cis", "id = ANY($1)"},
+	{"ci", "configuration_items", "id = ANY($1)"},
+}
+
+// heldAuditEntriesQuery counts the audit entries about the IDs in $1 covered
+// by an active legal hold, with the semantics of auditlog.LegalHold.Covers
+const heldAuditEntriesQuery = `
+	SELECT COUNT(*) FROM audit_logs a
+	WHERE a.entity_id = ANY($1) AND EXISTS (
+		SELECT 1 FROM audit_legal_holds h
+		WHERE h.released_at IS NULL
+		  AND (h.entity_type = '' OR h.entity_type = a.entity_type)
+		  AND (h.entity_id IS NULL OR h.entity_id = a.entity_id)
+		  AND (h.from_time IS NULL OR a.changed_at >= h.from_time)
+		  AND (h.to_time IS NULL OR a.changed_at < h.to_time)
+	)`
+
+// Plan returns the dry run of purging a CI
+func (s *PostgresStore) Plan(ctx context.Context, ciID uuid.UUID, auditPolicy string) (*Report, error) {
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	report, _, err := plan(ctx, tx, ciID, auditPolicy, false)
+	return report, err
+}
+
+// plan returns the dry run of purging a CI and the IDs whose traces it
+// removes, locking the CI when lock is set. CIs of other organizations than
+// the one the request acts for are not found.
+func plan(ctx context.Context, tx *sqlx.Tx, ciID uuid.UUID, auditPolicy string, lock bool) (*Report, []uuid.UUID, error) {
+	report := &Report{
+		CI:          CI{ID: ciID},
+		Traces:      make(map[string]int64, len(traces)),
+		AuditPolicy: auditPolicy,
+		GeneratedAt: time.Now(),
+	}
+
+	orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{ciID})
+	query := `SELECT name, type FROM configuration_items WHERE id = $1` + orgFilter
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	err := tx.QueryRowxContext(ctx, query, args...).Scan(&report.CI.Name, &report.CI.Type)
+	if err == sql.ErrNoRows {
+		report.CI.Archived = true
+		orgFilter, args := organization.AndScopeCondition(ctx, "(data->>'org_id')::uuid", []interface{}{ciID})
+		err = tx.QueryRowxContext(ctx, `SELECT name, type FROM archived_cis WHERE id = $1`+orgFilter, args...).
+			Scan(&report.CI.Name, &report.CI.Type)
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrCINotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get CI: %w", err)
+	}
+
+	ids := []uuid.UUID{ciID}
+	var relationshipIDs []uuid.UUID
+	err = tx.SelectContext(ctx, &relationshipIDs, `
+		SELECT id FROM ci_relationships WHERE source_ci_id = $1 OR target_ci_id = $1
+		UNION
+		SELECT id FROM archived_ci_relationships WHERE source_ci_id = $1 OR target_ci_id = $1`, ciID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	ids = append(ids, relationshipIDs...)
+
+	err = tx.GetContext(ctx, &report.ConnectedCIs, `
+		SELECT COUNT(DISTINCT other) FROM (
+			SELECT CASE WHEN source_ci_id = $1 THEN target_ci_id ELSE source_ci_id END AS other
+			FROM ci_relationships WHERE source_ci_id = $1 OR target_ci_id = $1
+			UNION ALL
+			SELECT CASE WHEN source_ci_id = $1 THEN target_ci_id ELSE source_ci_id END
+			FROM archived_ci_relationships WHERE source_ci_id = $1 OR target_ci_id = $1
+		) connected WHERE other <> $1`, ciID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count connected CIs: %w", err)
+	}
+
+	for _, t := range traces {
+		var rows int64
+		if err := tx.GetContext(ctx, &rows, "SELECT COUNT(*) FROM "+t.table+" WHERE "+t.condition, pq.Array(ids)); err != nil {
+			return nil, nil, fmt.Errorf("failed to count %s: %w", t.name, err)
+		}
+		if rows > 0 {
+			report.Traces[t.name] = rows
+		}
+	}
+
+	if err := tx.GetContext(ctx, &report.AuditEntries, `SELECT COUNT(*) FROM audit_logs WHERE entity_id = ANY($1)`, pq.Array(ids)); err != nil {
+		return nil, nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	var held int64
+	if err := tx.GetContext(ctx, &held, heldAuditEntriesQuery, pq.Array(ids)); err != nil {
+		return nil, nil, fmt.Errorf("failed to count audit entries under legal hold: %w", err)
+	}
+	if held > 0 {
+		report.Blockers = append(report.Blockers, fmt.Sprintf("%d audit entries are under an active legal hold", held))
+	}
+	return report, ids, nil
+}
+
+// Execute purges the CI of an approved request
+func (s *PostgresStore) Execute(ctx context.Context, purge *Purge, by uuid.UUID, at time.Time) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.GetContext(ctx, &status, `SELECT status FROM ci_purge_requests WHERE id = $1 FOR UPDATE`, purge.ID)
+	if err == sql.ErrNoRows {
+		return ErrPurgeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock purge request: %w", err)
+	}
+	if status != StatusApproved {
+		return ErrNotApproved
+	}
+
+	report, ids, err := plan(ctx, tx, purge.CIID, purge.AuditPolicy, true)
+	if err != nil {
+		return err
+	}
+	if len(report.Blockers) > 0 {
+		return fmt.Errorf("%w: %v", ErrBlocked, report.Blockers)
+	}
+	if !report.Matches(purge.Report) {
+		return ErrPlanChanged
+	}
+
+	// Queue the removal of the relationships from Neo4j, which has no trigger
+	// to do so, before the CI's own removal queued by its delete trigger
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sync_events (id, entity_type, entity_id, action, data, status, created_at)
+		SELECT gen_random_uuid(), 'relationship', rel.id, 'DELETE',
+		       jsonb_build_object('id', rel.id, 'source_id', rel.source_ci_id, 'target_id', rel.target_ci_id, 'type', rel.type),
+		       'PENDING', clock_timestamp()
+		FROM ci_relationships rel
+		WHERE rel.source_ci_id = $1 OR rel.target_ci_id = $1`, purge.CIID)
+	if err != nil {
+		return fmt.Errorf("failed to queue relationship removal: %w", err)
+	}
+
+	removed := make(map[string]int64, len(traces))
+	for _, t := range traces {
+		result, err := tx.ExecContext(ctx, "DELETE FROM "+t.table+" WHERE "+t.condition, pq.Array(ids))
+		if err != nil {
+			return fmt.Errorf("failed to purge %s: %w", t.name, err)
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+			removed[t.name] = rows
+		}
+	}
+
+	// Events still to be synced only need to identify what they remove
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sync_events
+		SET data = jsonb_strip_nulls(jsonb_build_object(
+		        'id', data->'id', 'source_id', data->'source_id', 'target_id', data->'target_id', 'type', data->'type'))
+		WHERE entity_id = ANY($1) AND status IN ('PENDING', 'PROCESSING')`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to strip pending sync events: %w", err)
+	}
+
+	switch purge.AuditPolicy {
+	case AuditDelete:
+		_, err = tx.ExecContext(ctx, `DELETE FROM audit_logs WHERE entity_id = ANY($1)`, pq.Array(ids))
+	default:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE audit_logs SET details = jsonb_build_object('redacted', true, 'purge_id', $2::uuid)
+			WHERE entity_id = ANY($1)`, pq.Array(ids), purge.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply audit policy: %w", err)
+	}
+
+	details, err := json.Marshal(map[string]interface{}{
+		"ci_id":        purge.CIID,
+		"reason":       purge.Reason,
+		"audit_policy": purge.AuditPolicy,
+		"approvals":    purge.Approvals,
+		"removed":      removed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ('ci_purge', $1, 'executed', $2, $3, $4)`, purge.ID, by, at, details)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	purge.Status = StatusExecuted
+	purge.ExecutedBy = &by
+	purge.ExecutedAt = &at
+	purge.Removed = removed
+	purge.Report.CI.Name = ""
+	purge.Report.CI.Type = ""
+	if err := updatePurge(ctx, tx, purge, StatusApproved); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return nil
+}
+
+// purgeRow is a row of ci_purge_requests
+type purgeRow struct {
+	ID                uuid.UUID  `db:"id"`
+	CIID              uuid.UUID  `db:"ci_id"`
+	Reason            string     `db:"reason"`
+	AuditPolicy       string     `db:"audit_policy"`
+	Status            string     `db:"status"`
+	RequestedBy       uuid.UUID  `db:"requested_by"`
+	RequestedAt       time.Time  `db:"requested_at"`
+	ExpiresAt         time.Time  `db:"expires_at"`
+	RequiredApprovals int        `db:"required_approvals"`
+	Approvals         []byte     `db:"approvals"`
+	Report            []byte     `db:"report"`
+	ExecutedBy        *uuid.UUID `db:"executed_by"`
+	ExecutedAt        *time.Time `db:"executed_at"`
+	Removed           []byte     `db:"removed"`
+	CancelledBy       *uuid.UUID `db:"cancelled_by"`
+}
+
+// purgeColumns are the columns of ci_purge_requests, in purgeRow order
+const purgeColumns = `id, ci_id, reason, audit_policy, status, requested_by, requested_at, expires_at,
+	required_approvals, approvals, report, executed_by, executed_at, removed, cancelled_by`
+
+// purge decodes the row
+func (r *purgeRow) purge() (*Purge, error) {
+	purge := &Purge{
+		ID:                r.ID,
+		CIID:              r.CIID,
+		Reason:            r.Reason,
+		AuditPolicy:       r.AuditPolicy,
+		Status:            r.Status,
+		RequestedBy:       r.RequestedBy,
+		RequestedAt:       r.RequestedAt,
+		ExpiresAt:         r.ExpiresAt,
+		RequiredApprovals: r.RequiredApprovals,
+		ExecutedBy:        r.ExecutedBy,
+		ExecutedAt:        r.ExecutedAt,
+		CancelledBy:       r.CancelledBy,
+	}
+	if err := json.Unmarshal(r.Approvals, &purge.Approvals); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal purge approvals: %w", err)
+	}
+	if err := json.Unmarshal(r.Report, &purge.Report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal purge report: %w", err)
+	}
+	if len(r.Removed) > 0 {
+		if err := json.Unmarshal(r.Removed, &purge.Removed); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal purge result: %w", err)
+		}
+	}
+	return purge, nil
+}
+
+// CreatePurge stores a new request
+func (s *PostgresStore) CreatePurge(ctx context.Context, purge *Purge) error {
+	approvals, err := json.Marshal(purge.Approvals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purge approvals: %w", err)
+	}
+	report, err := json.Marshal(purge.Report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purge report: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO ci_purge_requests (id, ci_id, reason, audit_policy, status, requested_by, requested_at, expires_at,
+		                               required_approvals, approvals, report)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		purge.ID, purge.CIID, purge.Reason, purge.AuditPolicy, purge.Status, purge.RequestedBy, purge.RequestedAt,
+		purge.ExpiresAt, purge.RequiredApprovals, approvals, report)
+	if err != nil {
+		return fmt.Errorf("failed to create purge request: %w", err)
+	}
+	return nil
+}
+
+// GetPurge retrieves a request
+func (s *PostgresStore) GetPurge(ctx context.Context, id uuid.UUID) (*Purge, error) {
+	var row purgeRow
+	err := s.db.GetContext(ctx, &row, `SELECT `+purgeColumns+` FROM ci_purge_requests WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrPurgeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purge request: %w", err)
+	}
+	return row.purge()
+}
+
+// ListPurges retrieves the most recent requests, optionally in one status
+func (s *PostgresStore) ListPurges(ctx context.Context, status string, limit int) ([]*Purge, error) {
+	var rows []purgeRow
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+purgeColumns+` FROM ci_purge_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY requested_at DESC
+		LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge requests: %w", err)
+	}
+
+	purges := make([]*Purge, 0, len(rows))
+	for i := range rows {
+		purge, err := rows[i].purge()
+		if err != nil {
+			return nil, err
+		}
+		purges = append(purges, purge)
+	}
+	return purges, nil
+}
+
+// UpdatePurge saves a request still in status from
+func (s *PostgresStore) UpdatePurge(ctx context.Context, purge *Purge, from string) error {
+	return updatePurge(ctx, s.db, purge, from)
+}
+
+// updatePurge saves a request still in status from
+func updatePurge(ctx context.Context, db sqlx.ExecerContext, purge *Purge, from string) error {
+	approvals, err := json.Marshal(purge.Approvals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purge approvals: %w", err)
+	}
+	report, err := json.Marshal(purge.Report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purge report: %w", err)
+	}
+	var removed []byte
+	if purge.Removed != nil {
+		if removed, err = json.Marshal(purge.Removed); err != nil {
+			return fmt.Errorf("failed to marshal purge result: %w", err)
+		}
+	}
+
+	result, err := db.ExecContext(ctx, `
+		UPDATE ci_purge_requests
+		SET status = $3, approvals = $4, report = $5, executed_by = $6, executed_at = $7, removed = $8, cancelled_by = $9
+		WHERE id = $1 AND status = $2`,
+		purge.ID, from, purge.Status, approvals, report, purge.ExecutedBy, purge.ExecutedAt, removed, purge.CancelledBy)
+	if err != nil {
+		return fmt.Errorf("failed to update purge request: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotPending
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// Package cisummary caches the ID, name, type, status and criticality of CIs,
+// which the relationship and graph endpoints need for every edge they render.
+// Entries are loaded in batches, so a page of edges costs at most one query,
+// and are invalidated by tailing the sync event stream.
+package cisummary
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Defaults used when Options leave a setting unset
+const (
+	DefaultTTL        = 5 * time.Minute
+	DefaultMaxEntries = 50000
+)
+
+// Loader loads CI summaries. CIs that do not exist are left out.
+type Loader interface {
+	GetCISummaries(ctx context.Context, ids []uuid.UUID) ([]models.CISummary, error)
+}
+
+// Options configure a Cache
+type Options struct {
+	// TTL bounds how long an entry is served, in case an invalidation is missed
+	TTL time.Duration
+	// MaxEntries bounds the cache size; expired entries are evicted first
+	MaxEntries int
+}
+
+// Stats describes the cache
+type Stats struct {
+	Entries       int    `json:"entries"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+	Purges        uint64 `json:"purges"`
+}
+
+type entry struct {
+	summary   models.CISummary
+	expiresAt time.Time
+}
+
+// Cache is a read-through cache of CI summaries, safe for concurrent use
+type Cache struct {
+	loader     Loader
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]entry
+	// generation changes on every invalidation, so a load that raced with one
+	// is not stored
+	generation uint64
+	stats      Stats
+}
+
+// NewCache creates a new CI summary cache
+func NewCache(loader Loader, options Options) *Cache {
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.MaxEntries <= 0 {
+		options.MaxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		loader:     loader,
+		ttl:        options.TTL,
+		maxEntries: options.MaxEntries,
+		now:        time.Now,
+		entries:    make(map[uuid.UUID]entry),
+	}
+}
+
+// Get returns the summaries of the given CIs, loading the ones not cached in a
+// single batch. CIs that do not exist are missing from the result.
+func (c *Cache) Get(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.CISummary, error) {
+	summaries := make(map[uuid.UUID]models.CISummary, len(ids))
+	queued := make(map[uuid.UUID]bool)
+	var missing []uuid.UUID
+
+	c.mu.Lock()
+	now := c.now()
+	for _, id := range ids {
+		if _, ok := summaries[id]; ok || queued[id] {
+			continue
+		}
+		if e, ok := c.entries[id]; ok && now.Before(e.expiresAt) {
+			summaries[id] = e.summary
+			c.stats.Hits++
+			continue
+		}
+		queued[id] = true
+		missing = append(missing, id)
+		c.stats.Misses++
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return summaries, nil
+	}
+
+	loaded, err := c.loader.GetCISummaries(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	store := generation == c.generation
+	if store && len(c.entries)+len(loaded) > c.maxEntries {
+		c.evict(len(loaded))
+	}
+	expiresAt := c.now().Add(c.ttl)
+	for _, summary := range loaded {
+		summaries[summary.ID] = summary
+		if store {
+			c.entries[summary.ID] = entry{summary: summary, expiresAt: expiresAt}
+		}
+	}
+	return summaries, nil
+}
+
+// Invalidate drops the given CIs from the cache
+func (c *Cache) Invalidate(ids ...uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		delete(c.entries, id)
+	}
+	c.generation++
+	c.stats.Invalidations += uint64(len(ids))
+}
+
+// Purge empties the cache
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[uuid.UUID]entry)
+	c.generation++
+	c.stats.Purges++
+}
+
+// Stats returns the cache statistics
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// evict makes room for n entries, dropping expired entries first and then
+// arbitrary ones. The caller holds the lock.
+func (c *Cache) evict(n int) {
+	now := c.now()
+	for id, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	for id := range c.entries {
+		if len(c.entries)+n <= c.maxEntries {
+			return
+		}
+		delete(c.entries, id)
+	}
+}
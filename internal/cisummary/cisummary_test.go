@@ -0,0 +1,220 @@
+package cisummary
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLoader serves summaries from a map and records each batch loaded
+type memoryLoader struct {
+	cis     map[uuid.UUID]models.CISummary
+	batches [][]uuid.UUID
+	err     error
+	// during runs inside a load, to simulate a change racing with it
+	during func()
+}
+
+func (m *memoryLoader) GetCISummaries(ctx context.Context, ids []uuid.UUID) ([]models.CISummary, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.batches = append(m.batches, ids)
+	if m.during != nil {
+		m.during()
+	}
+	summaries := []models.CISummary{}
+	for _, id := range ids {
+		if ci, ok := m.cis[id]; ok {
+			summaries = append(summaries, ci)
+		}
+	}
+	return summaries, nil
+}
+
+func (m *memoryLoader) add(name string) uuid.UUID {
+	id := uuid.New()
+	m.cis[id] = models.CISummary{ID: id, Name: name, Type: "server", Status: "active", Criticality: "high"}
+	return id
+}
+
+// memorySource serves a fixed list of changes
+type memorySource struct {
+	changes []Change
+	since   []time.Time
+	err     error
+}
+
+func (m *memorySource) CIChanges(ctx context.Context, since time.Time, limit int) ([]Change, error) {
+	m.since = append(m.since, since)
+	if m.err != nil {
+		return nil, m.err
+	}
+	changes := []Change{}
+	for _, change := range m.changes {
+		if change.ChangedAt.After(since) && len(changes) < limit {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestCache(loader Loader, options Options) *Cache {
+	cache := NewCache(loader, options)
+	cache.now = func() time.Time { return now }
+	return cache
+}
+
+func TestGetLoadsMissesInOneBatch(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}}
+	web, db := loader.add("web-01"), loader.add("db-01")
+	unknown := uuid.New()
+	cache := newTestCache(loader, Options{})
+
+	summaries, err := cache.Get(context.Background(), []uuid.UUID{web, db, web, unknown})
+	require.NoError(t, err)
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, "web-01", summaries[web].Name)
+	assert.Equal(t, "active", summaries[db].Status)
+	assert.Equal(t, "high", summaries[db].Criticality)
+	require.Len(t, loader.batches, 1)
+	assert.ElementsMatch(t, []uuid.UUID{web, db, unknown}, loader.batches[0])
+
+	// Cached CIs are not loaded again; unknown ones are
+	summaries, err = cache.Get(context.Background(), []uuid.UUID{web, db, unknown})
+	require.NoError(t, err)
+	assert.Len(t, summaries, 2)
+	require.Len(t, loader.batches, 2)
+	assert.Equal(t, []uuid.UUID{unknown}, loader.batches[1])
+
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+
+	// Nothing to load makes no query
+	_, err = cache.Get(context.Background(), []uuid.UUID{web})
+	require.NoError(t, err)
+	assert.Len(t, loader.batches, 2)
+}
+
+func TestGetExpiresEntries(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}}
+	web := loader.add("web-01")
+	cache := newTestCache(loader, Options{TTL: time.Minute})
+
+	_, err := cache.Get(context.Background(), []uuid.UUID{web})
+	require.NoError(t, err)
+
+	cache.now = func() time.Time { return now.Add(time.Minute) }
+	_, err = cache.Get(context.Background(), []uuid.UUID{web})
+	require.NoError(t, err)
+	assert.Len(t, loader.batches, 2)
+}
+
+func TestGetLoaderError(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}, err: errors.New("connection refused")}
+	cache := newTestCache(loader, Options{})
+
+	_, err := cache.Get(context.Background(), []uuid.UUID{uuid.New()})
+	assert.Error(t, err)
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestGetEvictsWhenFull(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}}
+	cache := newTestCache(loader, Options{MaxEntries: 3})
+
+	ids := []uuid.UUID{loader.add("a"), loader.add("b"), loader.add("c")}
+	_, err := cache.Get(context.Background(), ids)
+	require.NoError(t, err)
+	assert.Equal(t, 3, cache.Stats().Entries)
+
+	summaries, err := cache.Get(context.Background(), []uuid.UUID{loader.add("d"), loader.add("e")})
+	require.NoError(t, err)
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, 3, cache.Stats().Entries)
+}
+
+func TestInvalidate(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}}
+	web := loader.add("web-01")
+	cache := newTestCache(loader, Options{})
+
+	_, err := cache.Get(context.Background(), []uuid.UUID{web})
+	require.NoError(t, err)
+
+	loader.cis[web] = models.CISummary{ID: web, Name: "web-01-renamed", Type: "server", Status: "active"}
+	cache.Invalidate(web)
+
+	summaries, err := cache.Get(context.Background(), []uuid.UUID{web})
+	require.NoError(t, err)
+	assert.Equal(t, "web-01-renamed", summaries[web].Name)
+	assert.Equal(t, uint64(1), cache.Stats().Invalidations)
+}
+
+func TestLoadRacingInvalidationIsNotStored(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}}
+	web := loader.add("web-01")
+	cache := newTestCache(loader, Options{})
+	loader.during = func() { cache.Invalidate(web) }
+
+	summaries, err := cache.Get(context.Background(), []uuid.UUID{web})
+	require.NoError(t, err)
+	assert.Equal(t, "web-01", summaries[web].Name)
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestWatcherInvalidatesChangedCIs(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}}
+	web, db := loader.add("web-01"), loader.add("db-01")
+	cache := newTestCache(loader, Options{})
+	source := &memorySource{}
+	watcher := NewWatcher(cache, source)
+
+	// The first poll starts following from now
+	require.NoError(t, watcher.Poll(context.Background()))
+	assert.Equal(t, now.Add(-Overlap), source.since[0])
+
+	_, err := cache.Get(context.Background(), []uuid.UUID{web, db})
+	require.NoError(t, err)
+
+	source.changes = []Change{{EntityID: web, ChangedAt: now.Add(time.Second)}}
+	require.NoError(t, watcher.Poll(context.Background()))
+	assert.Equal(t, 1, cache.Stats().Entries)
+
+	// A failed poll keeps the cursor where it was
+	source.err = errors.New("connection refused")
+	assert.Error(t, watcher.Poll(context.Background()))
+	source.err = nil
+	require.NoError(t, watcher.Poll(context.Background()))
+	assert.Equal(t, source.since[2], source.since[3])
+	assert.Equal(t, now.Add(time.Second).Add(-Overlap), source.since[3])
+}
+
+func TestWatcherPurgesOnLargeBursts(t *testing.T) {
+	loader := &memoryLoader{cis: map[uuid.UUID]models.CISummary{}}
+	web := loader.add("web-01")
+	cache := newTestCache(loader, Options{})
+	source := &memorySource{}
+	watcher := NewWatcher(cache, source)
+	require.NoError(t, watcher.Poll(context.Background()))
+
+	_, err := cache.Get(context.Background(), []uuid.UUID{web})
+	require.NoError(t, err)
+
+	for i := 0; i < MaxChanges; i++ {
+		source.changes = append(source.changes, Change{EntityID: uuid.New(), ChangedAt: now.Add(time.Second)})
+	}
+	require.NoError(t, watcher.Poll(context.Background()))
+	assert.Equal(t, 0, cache.Stats().Entries)
+	assert.Equal(t, uint64(2), cache.Stats().Purges)
+}
@@ -0,0 +1,115 @@
+package cisummary
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Overlap is how far before the last change seen each poll looks again. Sync
+// events carry the time their transaction started, so one committed after a
+// later event was read would otherwise be missed.
+const Overlap = time.Minute
+
+// MaxChanges bounds the changes read by one poll; a poll reaching it purges
+// the whole cache instead
+const MaxChanges = 10000
+
+// Change is a CI changed according to the sync event stream
+type Change struct {
+	EntityID  uuid.UUID `db:"entity_id"`
+	ChangedAt time.Time `db:"changed_at"`
+}
+
+// EventSource reads the sync event stream
+type EventSource interface {
+	// CIChanges returns the CIs with sync events after a time, at most limit
+	CIChanges(ctx context.Context, since time.Time, limit int) ([]Change, error)
+}
+
+// PostgresEventSource reads CI changes from the sync_events table
+type PostgresEventSource struct {
+	db *sqlx.DB
+}
+
+// NewPostgresEventSource creates a new sync_events backed event source
+func NewPostgresEventSource(db *sqlx.DB) *PostgresEventSource {
+	return &PostgresEventSource{db: db}
+}
+
+// CIChanges returns the latest event time of each CI changed after a time
+func (s *PostgresEventSource) CIChanges(ctx context.Context, since time.Time, limit int) ([]Change, error) {
+	changes := []Change{}
+	err := s.db.SelectContext(ctx, &changes, `
+		SELECT entity_id, MAX(created_at) AS changed_at
+		FROM sync_events
+		WHERE entity_type = 'configuration_item' AND created_at > $1
+		GROUP BY entity_id
+		LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CI changes: %w", err)
+	}
+	return changes, nil
+}
+
+// Watcher invalidates cached summaries of CIs changed in the sync event stream
+type Watcher struct {
+	cache  *Cache
+	source EventSource
+	// cursor is the latest change seen
+	cursor time.Time
+}
+
+// NewWatcher creates a watcher following the events from now on. Summaries
+// cached before it was created are purged on the first poll.
+func NewWatcher(cache *Cache, source EventSource) *Watcher {
+	return &Watcher{cache: cache, source: source}
+}
+
+// Poll reads the changes since the last poll and invalidates them. The cursor
+// only moves on success, so a failed poll is caught up by the next one.
+func (w *Watcher) Poll(ctx context.Context) error {
+	if w.cursor.IsZero() {
+		w.cursor = w.cache.now()
+		w.cache.Purge()
+	}
+
+	changes, err := w.source.CIChanges(ctx, w.cursor.Add(-Overlap), MaxChanges)
+	if err != nil {
+		return err
+	}
+	if len(changes) >= MaxChanges {
+		w.cache.Purge()
+	}
+
+	ids := make([]uuid.UUID, 0, len(changes))
+	for _, change := range changes {
+		ids = append(ids, change.EntityID)
+		if change.ChangedAt.After(w.cursor) {
+			w.cursor = change.ChangedAt
+		}
+	}
+	w.cache.Invalidate(ids...)
+	return nil
+}
+
+// Run polls right away and then at each interval
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil {
+			log.Printf("Failed to poll CI changes for the summary cache: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
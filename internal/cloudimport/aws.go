@@ -0,0 +1,470 @@
+package cloudimport
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// API versions of the AWS query APIs
+const (
+	ec2APIVersion = "2016-11-15"
+	sqsAPIVersion = "2012-11-05"
+)
+
+// awsClient reads the dependencies between the resources of an AWS account
+type awsClient struct {
+	connector Connector
+	client    *http.Client
+	now       func() time.Time
+}
+
+func newAWSClient(connector Connector) *awsClient {
+	return &awsClient{connector: connector, client: &http.Client{Timeout: connector.Timeout}, now: time.Now}
+}
+
+// Edges reads the edges of every region
+func (c *awsClient) Edges(ctx context.Context) ([]Edge, error) {
+	var edges []Edge
+	for _, region := range c.connector.Regions {
+		for _, read := range []func(context.Context, string) ([]Edge, error){c.instanceEdges, c.eventSourceEdges, c.functionPolicyEdges, c.queuePolicyEdges} {
+			found, err := read(ctx, region)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", region, err)
+			}
+			edges = append(edges, found...)
+		}
+	}
+	return edges, nil
+}
+
+// describeInstancesResponse is the part of the EC2 DescribeInstances response read
+type describeInstancesResponse struct {
+	Reservations []struct {
+		OwnerID   string `xml:"ownerId"`
+		Instances []struct {
+			InstanceID string   `xml:"instanceId"`
+			VpcID      string   `xml:"vpcId"`
+			SubnetID   string   `xml:"subnetId"`
+			VolumeIDs  []string `xml:"blockDeviceMapping>item>ebs>volumeId"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// instanceEdges links EC2 instances to their EBS volumes, subnets and VPCs
+func (c *awsClient) instanceEdges(ctx context.Context, region string) ([]Edge, error) {
+	var edges []Edge
+	token := ""
+	for {
+		query := url.Values{"Action": {"DescribeInstances"}, "Version": {ec2APIVersion}}
+		if token != "" {
+			query.Set("NextToken", token)
+		}
+		var page describeInstancesResponse
+		if err := c.queryXML(ctx, "ec2", region, query, &page); err != nil {
+			return nil, fmt.Errorf("failed to describe EC2 instances: %w", err)
+		}
+
+		for _, reservation := range page.Reservations {
+			arn := func(kind, id string) string {
+				return fmt.Sprintf("arn:aws:ec2:%s:%s:%s/%s", region, reservation.OwnerID, kind, id)
+			}
+			for _, instance := range reservation.Instances {
+				source := arn("instance", instance.InstanceID)
+				for _, volume := range instance.VolumeIDs {
+					edges = append(edges, Edge{SourceID: source, TargetID: arn("volume", volume), DependencyType: DependencyStorage, Via: "ec2:DescribeInstances"})
+				}
+				if instance.SubnetID != "" {
+					edges = append(edges, Edge{SourceID: source, TargetID: arn("subnet", instance.SubnetID), DependencyType: DependencyNetwork, Via: "ec2:DescribeInstances"})
+				}
+				if instance.VpcID != "" {
+					edges = append(edges, Edge{SourceID: source, TargetID: arn("vpc", instance.VpcID), DependencyType: DependencyNetwork, Via: "ec2:DescribeInstances"})
+					if instance.SubnetID != "" {
+						edges = append(edges, Edge{SourceID: arn("subnet", instance.SubnetID), TargetID: arn("vpc", instance.VpcID), DependencyType: DependencyNetwork, Via: "ec2:DescribeInstances"})
+					}
+				}
+			}
+		}
+
+		if page.NextToken == "" {
+			return edges, nil
+		}
+		token = page.NextToken
+	}
+}
+
+// eventSourceEdges links Lambda functions to the queues and streams they consume
+func (c *awsClient) eventSourceEdges(ctx context.Context, region string) ([]Edge, error) {
+	var edges []Edge
+	marker := ""
+	for {
+		query := url.Values{}
+		if marker != "" {
+			query.Set("Marker", marker)
+		}
+		var page struct {
+			EventSourceMappings []struct {
+				EventSourceArn string `json:"EventSourceArn"`
+				FunctionArn    string `json:"FunctionArn"`
+			} `json:"EventSourceMappings"`
+			NextMarker string `json:"NextMarker"`
+		}
+		if _, err := c.getJSON(ctx, "lambda", region, "/2015-03-31/event-source-mappings/", query, &page); err != nil {
+			return nil, fmt.Errorf("failed to list Lambda event source mappings: %w", err)
+		}
+
+		for _, mapping := range page.EventSourceMappings {
+			if mapping.EventSourceArn == "" || mapping.FunctionArn == "" {
+				continue
+			}
+			edges = append(edges, Edge{SourceID: unqualifiedFunctionARN(mapping.FunctionArn), TargetID: mapping.EventSourceArn, DependencyType: DependencyEventSource, Via: "lambda:ListEventSourceMappings"})
+		}
+
+		if page.NextMarker == "" {
+			return edges, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+// functionPolicyEdges links Lambda functions to the sources their resource
+// policies allow to invoke them
+func (c *awsClient) functionPolicyEdges(ctx context.Context, region string) ([]Edge, error) {
+	var edges []Edge
+	marker := ""
+	for {
+		query := url.Values{}
+		if marker != "" {
+			query.Set("Marker", marker)
+		}
+		var page struct {
+			Functions []struct {
+				FunctionName string `json:"FunctionName"`
+				FunctionArn  string `json:"FunctionArn"`
+			} `json:"Functions"`
+			NextMarker string `json:"NextMarker"`
+		}
+		if _, err := c.getJSON(ctx, "lambda", region, "/2015-03-31/functions/", query, &page); err != nil {
+			return nil, fmt.Errorf("failed to list Lambda functions: %w", err)
+		}
+
+		for _, function := range page.Functions {
+			var policy struct {
+				Policy string `json:"Policy"`
+			}
+			found, err := c.getJSON(ctx, "lambda", region, "/2015-03-31/functions/"+url.PathEscape(function.FunctionName)+"/policy", nil, &policy)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get policy of Lambda function %s: %w", function.FunctionName, err)
+			}
+			if !found {
+				continue
+			}
+			for _, source := range policySourceARNs(policy.Policy) {
+				edges = append(edges, Edge{SourceID: unqualifiedFunctionARN(function.FunctionArn), TargetID: source, DependencyType: DependencyPolicy, Via: "lambda:GetPolicy"})
+			}
+		}
+
+		if page.NextMarker == "" {
+			return edges, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+// queuePolicyEdges links SQS queues to the sources their access policies allow
+// to send to them
+func (c *awsClient) queuePolicyEdges(ctx context.Context, region string) ([]Edge, error) {
+	var edges []Edge
+	token := ""
+	for {
+		query := url.Values{"Action": {"ListQueues"}, "Version": {sqsAPIVersion}}
+		if token != "" {
+			query.Set("NextToken", token)
+		}
+		var page struct {
+			QueueURLs []string `xml:"ListQueuesResult>QueueUrl"`
+			NextToken string   `xml:"ListQueuesResult>NextToken"`
+		}
+		if err := c.queryXML(ctx, "sqs", region, query, &page); err != nil {
+			return nil, fmt.Errorf("failed to list SQS queues: %w", err)
+		}
+
+		for _, queueURL := range page.QueueURLs {
+			var attributes struct {
+				Attributes []struct {
+					Name  string `xml:"Name"`
+					Value string `xml:"Value"`
+				} `xml:"GetQueueAttributesResult>Attribute"`
+			}
+			query := url.Values{
+				"Action":          {"GetQueueAttributes"},
+				"Version":         {sqsAPIVersion},
+				"QueueUrl":        {queueURL},
+				"AttributeName.1": {"QueueArn"},
+				"AttributeName.2": {"Policy"},
+			}
+			if err := c.queryXML(ctx, "sqs", region, query, &attributes); err != nil {
+				return nil, fmt.Errorf("failed to get attributes of SQS queue %s: %w", queueURL, err)
+			}
+
+			var queueARN, policy string
+			for _, attribute := range attributes.Attributes {
+				switch attribute.Name {
+				case "QueueArn":
+					queueARN = attribute.Value
+				case "Policy":
+					policy = attribute.Value
+				}
+			}
+			if queueARN == "" {
+				continue
+			}
+			for _, source := range policySourceARNs(policy) {
+				edges = append(edges, Edge{SourceID: queueARN, TargetID: source, DependencyType: DependencyPolicy, Via: "sqs:GetQueueAttributes"})
+			}
+		}
+
+		if page.NextToken == "" {
+			return edges, nil
+		}
+		token = page.NextToken
+	}
+}
+
+// queryXML calls an AWS query API and decodes its XML response
+func (c *awsClient) queryXML(ctx context.Context, service, region string, query url.Values, out interface{}) error {
+	body, _, err := c.do(ctx, service, region, "/", query)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// getJSON calls an AWS REST API and decodes its JSON response, reporting false
+// when the resource does not exist
+func (c *awsClient) getJSON(ctx context.Context, service, region, path string, query url.Values, out interface{}) (bool, error) {
+	body, status, err := c.do(ctx, service, region, path, query)
+	if status == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return false, fmt.Errorf("invalid response: %w", err)
+	}
+	return true, nil
+}
+
+// do sends a signed GET request to a service of a region
+func (c *awsClient) do(ctx context.Context, service, region, path string, query url.Values) ([]byte, int, error) {
+	endpoint := c.connector.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/") + path)
+	if err != nil {
+		return nil, 0, err
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	signV4(req, nil, c.connector.AccessKeyID, c.connector.SecretAccessKey, c.connector.SessionToken, region, service, c.now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("%s returned %d: %s", service, resp.StatusCode, truncate(string(body), 200))
+	}
+	return body, resp.StatusCode, nil
+}
+
+// signV4 signs a request with AWS Signature Version 4, signing the host, the
+// content type and the x-amz-* headers
+func signV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, sessionToken, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes a query with its keys and values sorted and spaces
+// as %20, as signing requires
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// unqualifiedFunctionARN strips the version or alias from a Lambda function ARN
+func unqualifiedFunctionARN(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) > 7 {
+		return strings.Join(parts[:7], ":")
+	}
+	return arn
+}
+
+// policySourceARNs returns the source ARNs the statements of a resource policy
+// allow, from their aws:SourceArn conditions. Wildcard ARNs name no single
+// resource and are left out.
+func policySourceARNs(policy string) []string {
+	if policy == "" {
+		return nil
+	}
+	var document struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return nil
+	}
+
+	type statement struct {
+		Effect    string                                `json:"Effect"`
+		Condition map[string]map[string]json.RawMessage `json:"Condition"`
+	}
+	var statements []statement
+	if err := json.Unmarshal(document.Statement, &statements); err != nil {
+		var single statement
+		if err := json.Unmarshal(document.Statement, &single); err != nil {
+			return nil
+		}
+		statements = []statement{single}
+	}
+
+	var sources []string
+	seen := map[string]bool{}
+	for _, s := range statements {
+		if s.Effect != "Allow" {
+			continue
+		}
+		for _, operator := range []string{"ArnLike", "ArnEquals", "StringLike", "StringEquals"} {
+			for key, raw := range s.Condition[operator] {
+				if !strings.EqualFold(key, "aws:SourceArn") {
+					continue
+				}
+				var values []string
+				if err := json.Unmarshal(raw, &values); err != nil {
+					var value string
+					if err := json.Unmarshal(raw, &value); err != nil {
+						continue
+					}
+					values = []string{value}
+				}
+				for _, value := range values {
+					if value != "" && !strings.ContainsAny(value, "*?") && !seen[value] {
+						seen[value] = true
+						sources = append(sources, value)
+					}
+				}
+			}
+		}
+	}
+	return sources
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
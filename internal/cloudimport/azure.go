@@ -0,0 +1,193 @@
+package cloudimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Azure endpoints
+const (
+	azureLoginURL      = "https://login.microsoftonline.com"
+	azureManagementURL = "https://management.azure.com"
+	azureScope         = "https://management.azure.com/.default"
+	resourceGraphPath  = "/providers/Microsoft.ResourceGraph/resources?api-version=2021-03-01"
+	resourceGraphPage  = 1000
+)
+
+// resourceGraphQuery is a Resource Graph query projecting the source, target
+// and dependency type of an edge
+type resourceGraphQuery struct {
+	via   string
+	query string
+}
+
+// resourceGraphQueries link virtual machines to their managed disks and network
+// interfaces, and network interfaces to their virtual networks
+var resourceGraphQueries = []resourceGraphQuery{
+	{
+		via: "resourcegraph:virtualmachines/disks",
+		query: `Resources
+| where type =~ 'microsoft.compute/virtualmachines'
+| mv-expand disk = array_concat(pack_array(properties.storageProfile.osDisk), properties.storageProfile.dataDisks)
+| where isnotempty(disk.managedDisk.id)
+| project source = tolower(id), target = tolower(tostring(disk.managedDisk.id)), dependency_type = 'storage'`,
+	},
+	{
+		via: "resourcegraph:virtualmachines/networkinterfaces",
+		query: `Resources
+| where type =~ 'microsoft.compute/virtualmachines'
+| mv-expand nic = properties.networkProfile.networkInterfaces
+| where isnotempty(nic.id)
+| project source = tolower(id), target = tolower(tostring(nic.id)), dependency_type = 'network'`,
+	},
+	{
+		via: "resourcegraph:networkinterfaces/virtualnetworks",
+		query: `Resources
+| where type =~ 'microsoft.network/networkinterfaces'
+| mv-expand ipconfig = properties.ipConfigurations
+| extend subnet = tolower(tostring(ipconfig.properties.subnet.id))
+| where isnotempty(subnet)
+| project source = tolower(id), target = substring(subnet, 0, indexof(subnet, '/subnets/')), dependency_type = 'network'`,
+	},
+}
+
+// azureClient reads the dependencies between the resources of Azure
+// subscriptions through Resource Graph
+type azureClient struct {
+	connector Connector
+	client    *http.Client
+}
+
+func newAzureClient(connector Connector) *azureClient {
+	return &azureClient{connector: connector, client: &http.Client{Timeout: connector.Timeout}}
+}
+
+// Edges runs every query
+func (c *azureClient) Edges(ctx context.Context) ([]Edge, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var edges []Edge
+	for _, q := range resourceGraphQueries {
+		found, err := c.query(ctx, token, q)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, found...)
+	}
+	return edges, nil
+}
+
+// token gets an access token for the management API with the client credentials
+func (c *azureClient) token(ctx context.Context) (string, error) {
+	login := azureLoginURL
+	if c.connector.Endpoint != "" {
+		login = strings.TrimSuffix(c.connector.Endpoint, "/")
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.connector.ClientID},
+		"client_secret": {c.connector.ClientSecret},
+		"scope":         {azureScope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, login+"/"+url.PathEscape(c.connector.TenantID)+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.do(req, &response); err != nil {
+		return "", fmt.Errorf("failed to get Azure access token: %w", err)
+	}
+	if response.AccessToken == "" {
+		return "", fmt.Errorf("failed to get Azure access token: no token returned")
+	}
+	return response.AccessToken, nil
+}
+
+// query runs a Resource Graph query, following its pages
+func (c *azureClient) query(ctx context.Context, token string, q resourceGraphQuery) ([]Edge, error) {
+	management := azureManagementURL
+	if c.connector.Endpoint != "" {
+		management = strings.TrimSuffix(c.connector.Endpoint, "/")
+	}
+
+	var edges []Edge
+	skipToken := ""
+	for {
+		options := map[string]interface{}{"$top": resourceGraphPage, "resultFormat": "objectArray"}
+		if skipToken != "" {
+			options["$skipToken"] = skipToken
+		}
+		request := map[string]interface{}{"query": q.query, "options": options}
+		if len(c.connector.Subscriptions) > 0 {
+			request["subscriptions"] = c.connector.Subscriptions
+		}
+		body, err := json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, management+resourceGraphPath, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		var page struct {
+			Data []struct {
+				Source         string `json:"source"`
+				Target         string `json:"target"`
+				DependencyType string `json:"dependency_type"`
+			} `json:"data"`
+			SkipToken string `json:"$skipToken"`
+		}
+		if err := c.do(req, &page); err != nil {
+			return nil, fmt.Errorf("failed to query Azure Resource Graph: %w", err)
+		}
+
+		for _, row := range page.Data {
+			if row.Source == "" || row.Target == "" {
+				continue
+			}
+			edges = append(edges, Edge{SourceID: row.Source, TargetID: row.Target, DependencyType: row.DependencyType, Via: q.via})
+		}
+
+		if page.SkipToken == "" {
+			return edges, nil
+		}
+		skipToken = page.SkipToken
+	}
+}
+
+// do sends a request and decodes its JSON response
+func (c *azureClient) do(req *http.Request, out interface{}) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("returned %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
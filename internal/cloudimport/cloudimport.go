@@ -0,0 +1,511 @@
+// Package cloudimport builds dependency relationships between CIs from the
+// resource APIs of cloud providers: EC2 instances on their EBS volumes and
+// VPCs and Lambda functions on the queues and streams feeding them in AWS, and
+// virtual machines on their disks and networks through Azure Resource Graph.
+// Resources are mapped to CIs through the external ID table, falling back to
+// the cloud resource ID attribute. Every connector is refreshed on a schedule;
+// relationships it no longer reports are deactivated.
+package cloudimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Providers
+const (
+	ProviderAWS   = "aws"
+	ProviderAzure = "azure"
+)
+
+// Providers are the supported cloud providers
+var Providers = []string{ProviderAWS, ProviderAzure}
+
+// Defaults
+const (
+	// DefaultResourceAttribute is the CI attribute holding the cloud resource
+	// ID, an ARN for AWS and a resource ID for Azure
+	DefaultResourceAttribute = "cloud_resource_id"
+	DefaultTimeout           = 30 * time.Second
+	// RelationshipType is the type of the imported relationships, the source
+	// depending on the target
+	RelationshipType = "depends_on"
+)
+
+// Dependency types recorded on the imported relationships
+const (
+	DependencyStorage     = "storage"
+	DependencyNetwork     = "network"
+	DependencyEventSource = "event_source"
+	// DependencyPolicy marks a resource depending on a source its resource
+	// policy allows to invoke it or send to it
+	DependencyPolicy = "resource_policy"
+)
+
+// Run statuses
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+var (
+	ErrNoConnectors = errors.New("no cloud import connectors are configured")
+	ErrSyncRunning  = errors.New("a cloud import is already running")
+	ErrCINotFound   = errors.New("CI not found")
+	ErrInvalidID    = errors.New("invalid external ID")
+	ErrIDConflict   = errors.New("external ID is already mapped to another CI")
+)
+
+// Connector reads the resources of one cloud account. Credentials are expanded
+// from the environment when the connector runs.
+type Connector struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	// Regions are the AWS regions read
+	Regions         []string `json:"regions,omitempty"`
+	AccessKeyID     string   `json:"-"`
+	SecretAccessKey string   `json:"-"`
+	SessionToken    string   `json:"-"`
+	// Subscriptions are the Azure subscriptions queried, all those the client
+	// can read when empty
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	TenantID      string   `json:"-"`
+	ClientID      string   `json:"-"`
+	ClientSecret  string   `json:"-"`
+	// Endpoint replaces the provider API endpoints, e.g. for a proxy
+	Endpoint string        `json:"endpoint,omitempty"`
+	Timeout  time.Duration `json:"timeout"`
+}
+
+// Validate checks the connector and applies the default timeout
+func (c *Connector) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("cloud import connector name is required")
+	}
+	switch c.Provider {
+	case ProviderAWS:
+		if len(c.Regions) == 0 {
+			return fmt.Errorf("cloud import connector %s: at least one region is required", c.Name)
+		}
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return fmt.Errorf("cloud import connector %s: an access key ID and secret are required", c.Name)
+		}
+	case ProviderAzure:
+		if c.TenantID == "" || c.ClientID == "" || c.ClientSecret == "" {
+			return fmt.Errorf("cloud import connector %s: a tenant ID, client ID and client secret are required", c.Name)
+		}
+	default:
+		return fmt.Errorf("cloud import connector %s: provider must be one of %s", c.Name, strings.Join(Providers, ", "))
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("cloud import connector %s: timeout may not be negative", c.Name)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	return nil
+}
+
+// expand returns a copy of the connector with its credentials expanded from
+// the environment
+func (c Connector) expand() Connector {
+	c.AccessKeyID = os.ExpandEnv(c.AccessKeyID)
+	c.SecretAccessKey = os.ExpandEnv(c.SecretAccessKey)
+	c.SessionToken = os.ExpandEnv(c.SessionToken)
+	c.TenantID = os.ExpandEnv(c.TenantID)
+	c.ClientID = os.ExpandEnv(c.ClientID)
+	c.ClientSecret = os.ExpandEnv(c.ClientSecret)
+	return c
+}
+
+// Edge is a dependency between two cloud resources reported by a provider,
+// the source depending on the target
+type Edge struct {
+	SourceID       string `json:"source_id"`
+	TargetID       string `json:"target_id"`
+	DependencyType string `json:"dependency_type"`
+	// Via is the API the edge was read from
+	Via string `json:"via"`
+}
+
+// Relationship is an edge resolved to CIs
+type Relationship struct {
+	SourceCIID     uuid.UUID
+	TargetCIID     uuid.UUID
+	DependencyType string
+	Via            string
+	Connector      string
+	Provider       string
+}
+
+// ExternalID maps the ID of a resource in an external system to a CI
+type ExternalID struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	CIID       uuid.UUID  `json:"ci_id" db:"ci_id"`
+	System     string     `json:"system" db:"system"`
+	ExternalID string     `json:"external_id" db:"external_id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+}
+
+// Validate checks the external ID and lower-cases it
+func (e *ExternalID) Validate() error {
+	e.System = strings.ToLower(strings.TrimSpace(e.System))
+	e.ExternalID = strings.ToLower(strings.TrimSpace(e.ExternalID))
+	if e.System == "" || len(e.System) > 50 {
+		return fmt.Errorf("%w: system is required and may be at most 50 characters", ErrInvalidID)
+	}
+	if e.ExternalID == "" {
+		return fmt.Errorf("%w: external_id is required", ErrInvalidID)
+	}
+	return nil
+}
+
+// Run is the latest import run of a connector
+type Run struct {
+	Connector   string     `json:"connector"`
+	Provider    string     `json:"provider"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Edges counts the edges read, Unresolved those with an end not mapped to a CI
+	Edges       int    `json:"edges"`
+	Unresolved  int    `json:"unresolved"`
+	Created     int    `json:"created"`
+	Refreshed   int    `json:"refreshed"`
+	Deactivated int64  `json:"deactivated"`
+	Failed      int    `json:"failed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Store maps resources to CIs and keeps the imported relationships
+type Store interface {
+	// ResolveResources maps lower-cased resource IDs to the live CIs holding
+	// them, in the external ID table under the system or in the attribute
+	ResolveResources(ctx context.Context, system, attribute string, resourceIDs []string) (map[string]uuid.UUID, error)
+	// Record creates or refreshes the relationship, reporting whether it was created
+	Record(ctx context.Context, relationship Relationship, at time.Time) (bool, error)
+	// DeactivateStale deactivates the relationships of the connector not seen since
+	DeactivateStale(ctx context.Context, connector string, since time.Time) (int64, error)
+
+	ListExternalIDs(ctx context.Context, ciID uuid.UUID) ([]ExternalID, error)
+	AddExternalID(ctx context.Context, id *ExternalID) error
+	DeleteExternalID(ctx context.Context, ciID uuid.UUID, system, externalID string) (bool, error)
+	CIExists(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// Fetcher reads the edges of a connector
+type Fetcher func(ctx context.Context, connector Connector) ([]Edge, error)
+
+// Service imports cloud dependencies and maps external IDs to CIs
+type Service struct {
+	store             Store
+	resourceAttribute string
+	connectors        []Connector
+	fetch             Fetcher
+	now               func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	runs    map[string]*Run
+}
+
+// NewService creates a new cloud import service. Connectors must have been validated.
+func NewService(store Store, resourceAttribute string, connectors []Connector) *Service {
+	if resourceAttribute == "" {
+		resourceAttribute = DefaultResourceAttribute
+	}
+	return &Service{
+		store:             store,
+		resourceAttribute: resourceAttribute,
+		connectors:        connectors,
+		fetch:             FetchEdges,
+		now:               time.Now,
+		runs:              map[string]*Run{},
+	}
+}
+
+// FetchEdges reads the edges of a connector from its provider
+func FetchEdges(ctx context.Context, connector Connector) ([]Edge, error) {
+	connector = connector.expand()
+	switch connector.Provider {
+	case ProviderAWS:
+		return newAWSClient(connector).Edges(ctx)
+	case ProviderAzure:
+		return newAzureClient(connector).Edges(ctx)
+	}
+	return nil, fmt.Errorf("unsupported provider %q", connector.Provider)
+}
+
+// ListExternalIDs retrieves the external IDs of a CI
+func (s *Service) ListExternalIDs(ctx context.Context, ciID uuid.UUID) ([]ExternalID, error) {
+	if err := s.requireCI(ctx, ciID); err != nil {
+		return nil, err
+	}
+	return s.store.ListExternalIDs(ctx, ciID)
+}
+
+// AddExternalID maps an external ID to a CI
+func (s *Service) AddExternalID(ctx context.Context, id *ExternalID, by *uuid.UUID) (*ExternalID, error) {
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.requireCI(ctx, id.CIID); err != nil {
+		return nil, err
+	}
+	id.CreatedBy = by
+	id.CreatedAt = s.now()
+	if err := s.store.AddExternalID(ctx, id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// DeleteExternalID removes an external ID from a CI, reporting whether it had it
+func (s *Service) DeleteExternalID(ctx context.Context, ciID uuid.UUID, system, externalID string) (bool, error) {
+	id := ExternalID{CIID: ciID, System: system, ExternalID: externalID}
+	if err := id.Validate(); err != nil {
+		return false, err
+	}
+	return s.store.DeleteExternalID(ctx, ciID, id.System, id.ExternalID)
+}
+
+// Connectors returns the configured connectors
+func (s *Service) Connectors() []Connector {
+	return s.connectors
+}
+
+// Runs returns the latest run of each connector that ran since startup
+func (s *Service) Runs() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := []Run{}
+	for _, connector := range s.connectors {
+		if run, ok := s.runs[connector.Name]; ok {
+			runs = append(runs, *run)
+		}
+	}
+	return runs
+}
+
+// StartSync runs every connector in the background. Poll Runs for progress.
+func (s *Service) StartSync() error {
+	if len(s.connectors) == 0 {
+		return ErrNoConnectors
+	}
+	if !s.begin() {
+		return ErrSyncRunning
+	}
+	go func() {
+		defer s.end()
+		s.syncAll(context.Background())
+	}()
+	return nil
+}
+
+// Run imports the edges of every connector now and then at each interval
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if len(s.connectors) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if s.begin() {
+			s.syncAll(ctx)
+			s.end()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync runs every connector and returns their runs
+func (s *Service) Sync(ctx context.Context) ([]Run, error) {
+	if len(s.connectors) == 0 {
+		return nil, ErrNoConnectors
+	}
+	if !s.begin() {
+		return nil, ErrSyncRunning
+	}
+	defer s.end()
+
+	s.syncAll(ctx)
+	return s.Runs(), nil
+}
+
+// begin marks a sync as running, reporting false when one already is
+func (s *Service) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return false
+	}
+	s.running = true
+	return true
+}
+
+// end marks the running sync as done
+func (s *Service) end() {
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+}
+
+// syncAll runs the connectors one after the other
+func (s *Service) syncAll(ctx context.Context) {
+	for _, connector := range s.connectors {
+		s.syncConnector(ctx, connector)
+	}
+}
+
+// syncConnector imports the edges of a connector
+func (s *Service) syncConnector(ctx context.Context, connector Connector) {
+	run := &Run{Connector: connector.Name, Provider: connector.Provider, Status: RunRunning, StartedAt: s.now()}
+	s.setRun(run)
+
+	err := s.importEdges(ctx, connector, run)
+
+	completedAt := s.now()
+	s.mu.Lock()
+	run.CompletedAt = &completedAt
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = RunCompleted
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Cloud import connector %s failed: %v", connector.Name, err)
+		return
+	}
+	log.Printf("Cloud import connector %s completed: %d edges, %d unresolved, %d created, %d refreshed, %d deactivated",
+		connector.Name, run.Edges, run.Unresolved, run.Created, run.Refreshed, run.Deactivated)
+}
+
+// importEdges does the work of a connector run, recording progress on run.
+// Stale relationships are only deactivated after a run that recorded every
+// edge, so a partial read never drops relationships.
+func (s *Service) importEdges(ctx context.Context, connector Connector, run *Run) error {
+	edges, err := s.fetch(ctx, connector)
+	if err != nil {
+		return err
+	}
+
+	resourceSet := map[string]bool{}
+	resourceIDs := []string{}
+	for _, edge := range edges {
+		for _, id := range []string{edge.SourceID, edge.TargetID} {
+			id = strings.ToLower(id)
+			if !resourceSet[id] {
+				resourceSet[id] = true
+				resourceIDs = append(resourceIDs, id)
+			}
+		}
+	}
+	sort.Strings(resourceIDs)
+	resolved, err := s.store.ResolveResources(ctx, connector.Provider, s.resourceAttribute, resourceIDs)
+	if err != nil {
+		return err
+	}
+
+	// Several edges may resolve to the same CIs, e.g. an instance with two
+	// volumes mapped to one CI, so each relationship is recorded once
+	type key struct {
+		source, target uuid.UUID
+	}
+	seen := map[key]bool{}
+	unresolved, created, refreshed, failed := 0, 0, 0, 0
+	startedAt := run.StartedAt
+	for _, edge := range edges {
+		source, sourceOK := resolved[strings.ToLower(edge.SourceID)]
+		target, targetOK := resolved[strings.ToLower(edge.TargetID)]
+		if !sourceOK || !targetOK {
+			unresolved++
+			continue
+		}
+		k := key{source, target}
+		if source == target || seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		isNew, err := s.store.Record(ctx, Relationship{
+			SourceCIID:     source,
+			TargetCIID:     target,
+			DependencyType: edge.DependencyType,
+			Via:            edge.Via,
+			Connector:      connector.Name,
+			Provider:       connector.Provider,
+		}, startedAt)
+		if err != nil {
+			log.Printf("Cloud import connector %s: %v", connector.Name, err)
+			failed++
+			continue
+		}
+		if isNew {
+			created++
+		} else {
+			refreshed++
+		}
+	}
+
+	var deactivated int64
+	if failed == 0 {
+		if deactivated, err = s.store.DeactivateStale(ctx, connector.Name, startedAt); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	run.Edges = len(edges)
+	run.Unresolved = unresolved
+	run.Created = created
+	run.Refreshed = refreshed
+	run.Deactivated = deactivated
+	run.Failed = failed
+	s.mu.Unlock()
+
+	if failed > 0 {
+		return fmt.Errorf("failed to record %d relationships", failed)
+	}
+	return nil
+}
+
+// setRun records the latest run of a connector
+func (s *Service) setRun(run *Run) {
+	s.mu.Lock()
+	s.runs[run.Connector] = run
+	s.mu.Unlock()
+}
+
+// requireCI returns ErrCINotFound unless the CI exists
+func (s *Service) requireCI(ctx context.Context, id uuid.UUID) error {
+	exists, err := s.store.CIExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrCINotFound, id)
+	}
+	return nil
+}
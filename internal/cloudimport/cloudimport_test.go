@@ -0,0 +1,338 @@
+package cloudimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore resolves resources from a map and records relationships in memory
+type memoryStore struct {
+	resources     map[string]uuid.UUID
+	relationships []Relationship
+	failFor       uuid.UUID
+	deactivated   []string
+}
+
+func (m *memoryStore) ResolveResources(ctx context.Context, system, attribute string, resourceIDs []string) (map[string]uuid.UUID, error) {
+	resolved := map[string]uuid.UUID{}
+	for _, id := range resourceIDs {
+		if ci, ok := m.resources[id]; ok {
+			resolved[id] = ci
+		}
+	}
+	return resolved, nil
+}
+
+func (m *memoryStore) Record(ctx context.Context, relationship Relationship, at time.Time) (bool, error) {
+	if relationship.SourceCIID == m.failFor {
+		return false, errors.New("database unavailable")
+	}
+	for _, existing := range m.relationships {
+		if existing.SourceCIID == relationship.SourceCIID && existing.TargetCIID == relationship.TargetCIID {
+			return false, nil
+		}
+	}
+	m.relationships = append(m.relationships, relationship)
+	return true, nil
+}
+
+func (m *memoryStore) DeactivateStale(ctx context.Context, connector string, since time.Time) (int64, error) {
+	m.deactivated = append(m.deactivated, connector)
+	return 2, nil
+}
+
+func (m *memoryStore) ListExternalIDs(ctx context.Context, ciID uuid.UUID) ([]ExternalID, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) AddExternalID(ctx context.Context, id *ExternalID) error {
+	return nil
+}
+
+func (m *memoryStore) DeleteExternalID(ctx context.Context, ciID uuid.UUID, system, externalID string) (bool, error) {
+	return true, nil
+}
+
+func (m *memoryStore) CIExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func TestConnectorValidate(t *testing.T) {
+	aws := Connector{Name: "prod", Provider: ProviderAWS, Regions: []string{"eu-west-1"}, AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	require.NoError(t, aws.Validate())
+	assert.Equal(t, DefaultTimeout, aws.Timeout)
+
+	azure := Connector{Name: "corp", Provider: ProviderAzure, TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}
+	require.NoError(t, azure.Validate())
+
+	assert.Error(t, (&Connector{Provider: ProviderAWS}).Validate())
+	assert.Error(t, (&Connector{Name: "prod", Provider: ProviderAWS, AccessKeyID: "AKID", SecretAccessKey: "secret"}).Validate())
+	assert.Error(t, (&Connector{Name: "corp", Provider: ProviderAzure, TenantID: "tenant"}).Validate())
+	assert.Error(t, (&Connector{Name: "gcp", Provider: "gcp"}).Validate())
+}
+
+func TestExternalIDValidate(t *testing.T) {
+	id := ExternalID{System: " AWS ", ExternalID: "arn:aws:ec2:eu-west-1:123:instance/I-ABC"}
+	require.NoError(t, id.Validate())
+	assert.Equal(t, "aws", id.System)
+	assert.Equal(t, "arn:aws:ec2:eu-west-1:123:instance/i-abc", id.ExternalID)
+
+	assert.ErrorIs(t, (&ExternalID{ExternalID: "x"}).Validate(), ErrInvalidID)
+	assert.ErrorIs(t, (&ExternalID{System: "aws"}).Validate(), ErrInvalidID)
+}
+
+func TestSyncRecordsResolvedEdges(t *testing.T) {
+	instance, volume, vpc := uuid.New(), uuid.New(), uuid.New()
+	store := &memoryStore{resources: map[string]uuid.UUID{
+		"arn:aws:ec2:eu-west-1:123:instance/i-1":  instance,
+		"arn:aws:ec2:eu-west-1:123:volume/vol-1":  volume,
+		"arn:aws:ec2:eu-west-1:123:volume/vol-2":  volume,
+		"arn:aws:ec2:eu-west-1:123:vpc/vpc-1":     vpc,
+		"arn:aws:ec2:eu-west-1:123:subnet/subnet": vpc,
+	}}
+	service := NewService(store, "", []Connector{{Name: "prod", Provider: ProviderAWS}})
+	service.fetch = func(ctx context.Context, connector Connector) ([]Edge, error) {
+		return []Edge{
+			{SourceID: "arn:aws:ec2:eu-west-1:123:instance/I-1", TargetID: "arn:aws:ec2:eu-west-1:123:volume/vol-1", DependencyType: DependencyStorage},
+			// A second volume mapped to the same CI is recorded once
+			{SourceID: "arn:aws:ec2:eu-west-1:123:instance/i-1", TargetID: "arn:aws:ec2:eu-west-1:123:volume/vol-2", DependencyType: DependencyStorage},
+			{SourceID: "arn:aws:ec2:eu-west-1:123:instance/i-1", TargetID: "arn:aws:ec2:eu-west-1:123:vpc/vpc-1", DependencyType: DependencyNetwork},
+			// Both ends on one CI
+			{SourceID: "arn:aws:ec2:eu-west-1:123:subnet/subnet", TargetID: "arn:aws:ec2:eu-west-1:123:vpc/vpc-1", DependencyType: DependencyNetwork},
+			{SourceID: "arn:aws:ec2:eu-west-1:123:instance/i-1", TargetID: "arn:aws:ec2:eu-west-1:123:volume/vol-unknown", DependencyType: DependencyStorage},
+		}, nil
+	}
+
+	runs, err := service.Sync(context.Background())
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	run := runs[0]
+	assert.Equal(t, RunCompleted, run.Status)
+	assert.Equal(t, 5, run.Edges)
+	assert.Equal(t, 1, run.Unresolved)
+	assert.Equal(t, 2, run.Created)
+	assert.Equal(t, int64(2), run.Deactivated)
+	assert.Equal(t, []string{"prod"}, store.deactivated)
+
+	require.Len(t, store.relationships, 2)
+	assert.Equal(t, Relationship{SourceCIID: instance, TargetCIID: volume, DependencyType: DependencyStorage, Connector: "prod", Provider: ProviderAWS}, store.relationships[0])
+	assert.Equal(t, vpc, store.relationships[1].TargetCIID)
+
+	// A second run refreshes them
+	runs, err = service.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, runs[0].Created)
+	assert.Equal(t, 2, runs[0].Refreshed)
+}
+
+func TestSyncKeepsRelationshipsAfterFailures(t *testing.T) {
+	instance, volume := uuid.New(), uuid.New()
+	store := &memoryStore{
+		resources: map[string]uuid.UUID{"instance": instance, "volume": volume},
+		failFor:   instance,
+	}
+	service := NewService(store, "", []Connector{{Name: "prod", Provider: ProviderAWS}})
+	service.fetch = func(ctx context.Context, connector Connector) ([]Edge, error) {
+		return []Edge{{SourceID: "instance", TargetID: "volume"}}, nil
+	}
+
+	runs, err := service.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, RunFailed, runs[0].Status)
+	assert.Equal(t, 1, runs[0].Failed)
+	assert.Empty(t, store.deactivated)
+
+	service.fetch = func(ctx context.Context, connector Connector) ([]Edge, error) {
+		return nil, errors.New("access denied")
+	}
+	runs, err = service.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, RunFailed, runs[0].Status)
+	assert.Equal(t, "access denied", runs[0].Error)
+	assert.Empty(t, store.deactivated)
+}
+
+func TestSyncWithoutConnectors(t *testing.T) {
+	service := NewService(&memoryStore{}, "", nil)
+	_, err := service.Sync(context.Background())
+	assert.ErrorIs(t, err, ErrNoConnectors)
+	assert.ErrorIs(t, service.StartSync(), ErrNoConnectors)
+}
+
+func TestSignV4(t *testing.T) {
+	// The example request of the AWS Signature Version 4 documentation
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signV4(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "", "us-east-1", "iam",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7", req.Header.Get("Authorization"))
+}
+
+func TestPolicySourceARNs(t *testing.T) {
+	policy := `{"Version":"2012-10-17","Statement":[
+		{"Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"lambda:InvokeFunction",
+		 "Condition":{"ArnLike":{"AWS:SourceArn":"arn:aws:sns:eu-west-1:123:alerts"}}},
+		{"Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},"Action":"lambda:InvokeFunction",
+		 "Condition":{"ArnLike":{"aws:SourceArn":["arn:aws:s3:::uploads","arn:aws:s3:::logs-*"]}}},
+		{"Effect":"Deny","Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:sns:eu-west-1:123:denied"}}}
+	]}`
+	assert.Equal(t, []string{"arn:aws:sns:eu-west-1:123:alerts", "arn:aws:s3:::uploads"}, policySourceARNs(policy))
+
+	single := `{"Statement":{"Effect":"Allow","Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:sns:eu-west-1:123:orders"}}}}`
+	assert.Equal(t, []string{"arn:aws:sns:eu-west-1:123:orders"}, policySourceARNs(single))
+	assert.Empty(t, policySourceARNs("not json"))
+}
+
+func TestAWSEdges(t *testing.T) {
+	queuePolicy := `{"Statement":[{"Effect":"Allow","Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:sns:eu-west-1:123:orders"}}}]}`
+	functionPolicy := `{"Statement":[{"Effect":"Allow","Condition":{"ArnLike":{"AWS:SourceArn":"arn:aws:s3:::uploads"}}}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		query := r.URL.Query()
+		switch {
+		case query.Get("Action") == "DescribeInstances" && query.Get("NextToken") == "":
+			fmt.Fprint(w, `<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+				<reservationSet><item><ownerId>123</ownerId><instancesSet><item>
+					<instanceId>i-1</instanceId><vpcId>vpc-1</vpcId><subnetId>subnet-1</subnetId>
+					<blockDeviceMapping><item><ebs><volumeId>vol-1</volumeId></ebs></item></blockDeviceMapping>
+				</item></instancesSet></item></reservationSet>
+				<nextToken>page-2</nextToken>
+			</DescribeInstancesResponse>`)
+		case query.Get("Action") == "DescribeInstances":
+			fmt.Fprint(w, `<DescribeInstancesResponse><reservationSet><item><ownerId>123</ownerId><instancesSet><item>
+				<instanceId>i-2</instanceId>
+				<blockDeviceMapping><item><ebs><volumeId>vol-2</volumeId></ebs></item></blockDeviceMapping>
+			</item></instancesSet></item></reservationSet></DescribeInstancesResponse>`)
+		case query.Get("Action") == "ListQueues":
+			fmt.Fprint(w, `<ListQueuesResponse><ListQueuesResult><QueueUrl>https://sqs.eu-west-1.amazonaws.com/123/orders</QueueUrl></ListQueuesResult></ListQueuesResponse>`)
+		case query.Get("Action") == "GetQueueAttributes":
+			assert.Equal(t, "https://sqs.eu-west-1.amazonaws.com/123/orders", query.Get("QueueUrl"))
+			w.Write([]byte(`<GetQueueAttributesResponse><GetQueueAttributesResult>
+				<Attribute><Name>QueueArn</Name><Value>arn:aws:sqs:eu-west-1:123:orders</Value></Attribute>
+				<Attribute><Name>Policy</Name><Value>` + xmlEscape(queuePolicy) + `</Value></Attribute>
+			</GetQueueAttributesResult></GetQueueAttributesResponse>`))
+		case r.URL.Path == "/2015-03-31/event-source-mappings/":
+			json.NewEncoder(w).Encode(map[string]interface{}{"EventSourceMappings": []map[string]string{
+				{"EventSourceArn": "arn:aws:sqs:eu-west-1:123:orders", "FunctionArn": "arn:aws:lambda:eu-west-1:123:function:process:live"},
+			}})
+		case r.URL.Path == "/2015-03-31/functions/":
+			json.NewEncoder(w).Encode(map[string]interface{}{"Functions": []map[string]string{
+				{"FunctionName": "process", "FunctionArn": "arn:aws:lambda:eu-west-1:123:function:process"},
+				{"FunctionName": "private", "FunctionArn": "arn:aws:lambda:eu-west-1:123:function:private"},
+			}})
+		case r.URL.Path == "/2015-03-31/functions/process/policy":
+			json.NewEncoder(w).Encode(map[string]string{"Policy": functionPolicy})
+		case r.URL.Path == "/2015-03-31/functions/private/policy":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	connector := Connector{Name: "prod", Provider: ProviderAWS, Regions: []string{"eu-west-1"}, AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: server.URL}
+	require.NoError(t, connector.Validate())
+	edges, err := FetchEdges(context.Background(), connector)
+	require.NoError(t, err)
+
+	type pair struct{ source, target, dependency string }
+	var pairs []pair
+	for _, edge := range edges {
+		pairs = append(pairs, pair{edge.SourceID, edge.TargetID, edge.DependencyType})
+	}
+	assert.Equal(t, []pair{
+		{"arn:aws:ec2:eu-west-1:123:instance/i-1", "arn:aws:ec2:eu-west-1:123:volume/vol-1", DependencyStorage},
+		{"arn:aws:ec2:eu-west-1:123:instance/i-1", "arn:aws:ec2:eu-west-1:123:subnet/subnet-1", DependencyNetwork},
+		{"arn:aws:ec2:eu-west-1:123:instance/i-1", "arn:aws:ec2:eu-west-1:123:vpc/vpc-1", DependencyNetwork},
+		{"arn:aws:ec2:eu-west-1:123:subnet/subnet-1", "arn:aws:ec2:eu-west-1:123:vpc/vpc-1", DependencyNetwork},
+		{"arn:aws:ec2:eu-west-1:123:instance/i-2", "arn:aws:ec2:eu-west-1:123:volume/vol-2", DependencyStorage},
+		{"arn:aws:lambda:eu-west-1:123:function:process", "arn:aws:sqs:eu-west-1:123:orders", DependencyEventSource},
+		{"arn:aws:lambda:eu-west-1:123:function:process", "arn:aws:s3:::uploads", DependencyPolicy},
+		{"arn:aws:sqs:eu-west-1:123:orders", "arn:aws:sns:eu-west-1:123:orders", DependencyPolicy},
+	}, pairs)
+
+	connector.AccessKeyID = "OTHER"
+	_, err = FetchEdges(context.Background(), connector)
+	assert.Error(t, err)
+}
+
+func TestAzureEdges(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tenant-1/oauth2/v2.0/token":
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "s3cret", r.PostForm.Get("client_secret"))
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "token-1"})
+		case "/providers/Microsoft.ResourceGraph/resources":
+			assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+			var request struct {
+				Subscriptions []string               `json:"subscriptions"`
+				Query         string                 `json:"query"`
+				Options       map[string]interface{} `json:"options"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal(t, []string{"sub-1"}, request.Subscriptions)
+			queries = append(queries, request.Query)
+
+			if !strings.Contains(request.Query, "dataDisks") {
+				json.NewEncoder(w).Encode(map[string]interface{}{"data": []interface{}{}})
+				return
+			}
+			if request.Options["$skipToken"] == nil {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"data":       []map[string]string{{"source": "/subscriptions/sub-1/vm/web", "target": "/subscriptions/sub-1/disks/os", "dependency_type": "storage"}},
+					"$skipToken": "next",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]string{{"source": "/subscriptions/sub-1/vm/web", "target": "/subscriptions/sub-1/disks/data", "dependency_type": "storage"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_AZURE_SECRET", "s3cret")
+	connector := Connector{
+		Name: "corp", Provider: ProviderAzure, Subscriptions: []string{"sub-1"},
+		TenantID: "tenant-1", ClientID: "client-1", ClientSecret: "${TEST_AZURE_SECRET}", Endpoint: server.URL,
+	}
+	require.NoError(t, connector.Validate())
+	edges, err := FetchEdges(context.Background(), connector)
+	require.NoError(t, err)
+
+	require.Len(t, edges, 2)
+	assert.Equal(t, Edge{SourceID: "/subscriptions/sub-1/vm/web", TargetID: "/subscriptions/sub-1/disks/os", DependencyType: DependencyStorage, Via: "resourcegraph:virtualmachines/disks"}, edges[0])
+	assert.Equal(t, "/subscriptions/sub-1/disks/data", edges[1].TargetID)
+	assert.Len(t, queries, len(resourceGraphQueries)+1)
+}
+
+// xmlEscape escapes the characters of s that XML text may not hold
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
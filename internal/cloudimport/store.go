@@ -0,0 +1,208 @@
+package cloudimport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"connect/internal/organization"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// resolveChunkSize bounds the resource IDs looked up per query
+const resolveChunkSize = 1000
+
+// PostgresStore maps resources through the ci_external_ids table and keeps the
+// imported relationships in ci_relationships
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed cloud import store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ResolveResources looks the resource IDs up in chunks, first in the external
+// ID table and then, for those it does not map, in the attribute. When several
+// CIs hold the same resource in the attribute, the first by ID is used.
+func (s *PostgresStore) ResolveResources(ctx context.Context, system, attribute string, resourceIDs []string) (map[string]uuid.UUID, error) {
+	resolved := map[string]uuid.UUID{}
+	for start := 0; start < len(resourceIDs); start += resolveChunkSize {
+		end := start + resolveChunkSize
+		if end > len(resourceIDs) {
+			end = len(resourceIDs)
+		}
+		chunk := resourceIDs[start:end]
+
+		orgFilter, args := organization.AndScopeCondition(ctx, "ci.org_id", []interface{}{system, pq.Array(chunk)})
+		err := s.resolve(ctx, resolved, `
+			SELECT e.external_id, e.ci_id
+			FROM ci_external_ids e
+			JOIN configuration_items ci ON ci.id = e.ci_id AND ci.is_deleted = false
+			WHERE e.system = $1 AND e.external_id = ANY($2)`+orgFilter, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := make([]string, 0, len(chunk))
+		for _, id := range chunk {
+			if _, ok := resolved[id]; !ok {
+				remaining = append(remaining, id)
+			}
+		}
+		if len(remaining) == 0 {
+			continue
+		}
+		orgFilter, args = organization.AndScopeCondition(ctx, "org_id", []interface{}{attribute, pq.Array(remaining)})
+		err = s.resolve(ctx, resolved, `
+			SELECT DISTINCT ON (LOWER(attributes->>$1)) LOWER(attributes->>$1), id
+			FROM configuration_items
+			WHERE is_deleted = false AND LOWER(attributes->>$1) = ANY($2)`+orgFilter+`
+			ORDER BY LOWER(attributes->>$1), id`, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// resolve adds the resource ID and CI ID rows of a query to resolved
+func (s *PostgresStore) resolve(ctx context.Context, resolved map[string]uuid.UUID, query string, args ...interface{}) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to resolve cloud resources: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var resource string
+		var id uuid.UUID
+		if err := rows.Scan(&resource, &id); err != nil {
+			return fmt.Errorf("failed to scan cloud resource: %w", err)
+		}
+		resolved[resource] = id
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to resolve cloud resources: %w", err)
+	}
+	return nil
+}
+
+// Record upserts the relationship. Relationships created by the import are
+// marked cloud_imported; relationships that existed before keep their
+// attributes, gaining those of the import, and are never deactivated by it.
+// New relationships take the organization of their CIs, the source's when
+// both have one.
+func (s *PostgresStore) Record(ctx context.Context, relationship Relationship, at time.Time) (bool, error) {
+	attributes, err := json.Marshal(map[string]interface{}{
+		"dependency_type":      relationship.DependencyType,
+		"cloud_provider":       relationship.Provider,
+		"cloud_connector":      relationship.Connector,
+		"cloud_discovered_via": relationship.Via,
+		"last_seen_at":         at.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var created bool
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO ci_relationships (
+			id, source_ci_id, target_ci_id, type, attributes, description,
+			is_active, state, created_at, updated_at, org_id
+		) VALUES ($1, $2, $3, $4, $5::jsonb || '{"cloud_imported": true}'::jsonb, $6, true, 'active', $7, $7,
+			COALESCE((SELECT org_id FROM configuration_items WHERE id = $2), (SELECT org_id FROM configuration_items WHERE id = $3)))
+		ON CONFLICT (source_ci_id, target_ci_id, type) DO UPDATE
+		SET attributes = ci_relationships.attributes || $5::jsonb, is_active = true, updated_at = $7
+		RETURNING (xmax = 0)`,
+		uuid.New(), relationship.SourceCIID, relationship.TargetCIID, RelationshipType, attributes,
+		fmt.Sprintf("Imported from %s connector %s", relationship.Provider, relationship.Connector), at).Scan(&created)
+	if err != nil {
+		return false, fmt.Errorf("failed to record relationship from %s to %s: %w", relationship.SourceCIID, relationship.TargetCIID, err)
+	}
+	return created, nil
+}
+
+// DeactivateStale deactivates the active relationships the connector created
+// and last saw before since
+func (s *PostgresStore) DeactivateStale(ctx context.Context, connector string, since time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE ci_relationships
+		SET is_active = false, updated_at = NOW()
+		WHERE attributes->>'cloud_connector' = $1
+		  AND attributes ? 'cloud_imported'
+		  AND is_active = true
+		  AND (attributes->>'last_seen_at')::timestamptz < $2`, connector, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate stale relationships: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+const externalIDColumns = `id, ci_id, system, external_id, created_at, created_by`
+
+// ListExternalIDs retrieves the external IDs of a CI
+func (s *PostgresStore) ListExternalIDs(ctx context.Context, ciID uuid.UUID) ([]ExternalID, error) {
+	ids := []ExternalID{}
+	if err := s.db.SelectContext(ctx, &ids, `SELECT `+externalIDColumns+` FROM ci_external_ids WHERE ci_id = $1 ORDER BY system, external_id`, ciID); err != nil {
+		return nil, fmt.Errorf("failed to list external IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// AddExternalID maps the external ID to its CI. Adding a mapping the CI already
+// has returns the existing one; an ID mapped to another CI is a conflict.
+func (s *PostgresStore) AddExternalID(ctx context.Context, id *ExternalID) error {
+	id.ID = uuid.New()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO ci_external_ids (id, ci_id, system, external_id, created_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		id.ID, id.CIID, id.System, id.ExternalID, id.CreatedAt, id.CreatedBy)
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return fmt.Errorf("failed to add external ID: %w", err)
+	}
+	var existing ExternalID
+	err = s.db.GetContext(ctx, &existing, `SELECT `+externalIDColumns+` FROM ci_external_ids WHERE system = $1 AND external_id = $2`, id.System, id.ExternalID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("failed to add external ID: %w", ErrIDConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get external ID: %w", err)
+	}
+	if existing.CIID != id.CIID {
+		return fmt.Errorf("%w: %s", ErrIDConflict, existing.CIID)
+	}
+	*id = existing
+	return nil
+}
+
+// DeleteExternalID removes an external ID from a CI
+func (s *PostgresStore) DeleteExternalID(ctx context.Context, ciID uuid.UUID, system, externalID string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM ci_external_ids WHERE ci_id = $1 AND system = $2 AND external_id = $3`, ciID, system, externalID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete external ID: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CIExists checks that a live CI the request's organization may see exists
+func (s *PostgresStore) CIExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var exists bool
+	orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{id})
+	if err := s.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM configuration_items WHERE id = $1 AND is_deleted = false`+orgFilter+`)`, args...); err != nil {
+		return false, fmt.Errorf("failed to check CI: %w", err)
+	}
+	return exists, nil
+}
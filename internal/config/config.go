@@ -2,22 +2,63 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Version     string            `yaml:"version"`
-	Environment string            `yaml:"environment"`
-	Server      ServerConfig      `yaml:"server"`
-	Database    DatabaseConfig    `yaml:"database"`
-	Auth        AuthConfig        `yaml:"auth"`
-	CORS        CORSConfig        `yaml:"cors"`
-	Logging     LoggingConfig     `yaml:"logging"`
-	Sync        *SyncConfig       `yaml:"sync,omitempty"`
+	Version      string             `yaml:"version"`
+	Environment  string             `yaml:"environment"`
+	Server       ServerConfig       `yaml:"server"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Auth         AuthConfig         `yaml:"auth"`
+	CORS         CORSConfig         `yaml:"cors"`
+	Logging      LoggingConfig      `yaml:"logging"`
+	Freshness    FreshnessConfig    `yaml:"freshness"`
+	FeatureFlags FeatureFlagsConfig `yaml:"feature_flags"`
+	Maintenance  MaintenanceConfig  `yaml:"maintenance"`
+	SchemaCheck  SchemaCheckConfig  `yaml:"schema_check"`
+	APIVersions  APIVersionsConfig  `yaml:"api_versions"`
+	PayloadLog   PayloadLogConfig   `yaml:"payload_logging"`
+	Ownership    OwnershipConfig    `yaml:"ownership"`
+	AssetLabels  AssetLabelConfig   `yaml:"asset_labels"`
+	Impact       ImpactConfig       `yaml:"impact"`
+	Backpressure BackpressureConfig `yaml:"backpressure"`
+	Resync       ResyncConfig       `yaml:"resync"`
+	Billing      BillingConfig      `yaml:"billing"`
+	Federation   FederationConfig   `yaml:"federation"`
+	Audit        AuditConfig        `yaml:"audit"`
+	Velocity     VelocityConfig     `yaml:"velocity"`
+	SummaryCache SummaryCacheConfig `yaml:"summary_cache"`
+	ForceSync    ForceSyncConfig    `yaml:"force_sync"`
+	SyncOverview SyncOverviewConfig `yaml:"sync_overview"`
+	Imports      ImportsConfig      `yaml:"imports"`
+	EdgeCleanup  EdgeCleanupConfig  `yaml:"edge_cleanup"`
+	Archive      ArchiveConfig      `yaml:"archive"`
+	Heartbeats   HeartbeatsConfig   `yaml:"heartbeats"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
+	Responses    ResponsesConfig    `yaml:"responses"`
+	Escalation   EscalationConfig   `yaml:"conflict_escalation"`
+	CloudImport  CloudImportConfig  `yaml:"cloud_import"`
+	Quotas       QuotasConfig       `yaml:"quotas"`
+	Residency    ResidencyConfig    `yaml:"residency"`
+	Faults       FaultsConfig       `yaml:"fault_injection"`
+	PublicCatalog PublicCatalogConfig `yaml:"public_catalog"`
+	FileScan     FileScanConfig     `yaml:"file_scan"`
+	IncidentLearning IncidentLearningConfig `yaml:"incident_learning"`
+	Bootstrap    BootstrapConfig    `yaml:"bootstrap"`
+	Webhooks     WebhooksConfig     `yaml:"webhooks"`
+	EventStream  EventStreamConfig  `yaml:"event_stream"`
+	Purge        PurgeConfig        `yaml:"purge"`
+	AlertRules   AlertRulesConfig   `yaml:"alert_rules"`
+	Pagination   PaginationConfig   `yaml:"pagination"`
+	SCIM         SCIMConfig         `yaml:"scim"`
+	Sync         *SyncConfig        `yaml:"sync,omitempty"`
 }
 
 type SyncConfig struct {
@@ -31,6 +72,12 @@ type SyncConfig struct {
 	EventTTL          *string       `yaml:"event_ttl,omitempty"`
 	CleanupInterval   *string       `yaml:"cleanup_interval,omitempty"`
 	MaxConcurrentSync *int          `yaml:"max_concurrent_sync,omitempty"`
+	// How long events and fallback operations may stay in processing before
+	// startup recovery returns them to the queue, e.g. "10m"
+	RecoveryTimeout *string `yaml:"recovery_timeout,omitempty"`
+	// Events of these entity types and tenants are held instead of synced
+	ExcludedEntityTypes []string `yaml:"excluded_entity_types,omitempty"`
+	ExcludedTenants     []string `yaml:"excluded_tenants,omitempty"`
 }
 
 type ServerConfig struct {
@@ -82,6 +129,39 @@ type AuthConfig struct {
 	PasswordMaxLength int         `yaml:"password_max_length"`
 	MaxLoginAttempts int          `yaml:"max_login_attempts"`
 	LockoutDuration  time.Duration `yaml:"lockout_duration"`
+	SigningKeys      []SigningKeyConfig `yaml:"signing_keys"` // empty signs HS256 with secret_key
+	KeyOverlap       time.Duration `yaml:"key_overlap"`       // how long retired keys stay valid; 0 uses refresh_token_ttl
+	RefreshTokenRotation bool    `yaml:"refresh_token_rotation"` // single-use refresh tokens with reuse detection
+	PermissionCacheTTL time.Duration `yaml:"permission_cache_ttl"` // how long role permissions are cached in Redis
+	Fingerprint      SessionFingerprintConfig `yaml:"fingerprint"`
+	SessionLimits    SessionLimitsConfig      `yaml:"session_limits"`
+}
+
+// SessionLimitsConfig defines how many concurrent sessions users may hold. Role
+// limits take precedence over tenant limits, which take precedence over the
+// default; 0 means unlimited. Limits can be adjusted at runtime through the admin API.
+type SessionLimitsConfig struct {
+	Default int            `yaml:"default"`
+	Roles   map[string]int `yaml:"roles"`
+	Tenants map[string]int `yaml:"tenants"`
+}
+
+// SessionFingerprintConfig defines how strictly sessions are bound to the client
+// (user agent family and IP subnet, or explicit device ID) they were created from
+type SessionFingerprintConfig struct {
+	Mode             string `yaml:"mode"` // off, flag, strict (reject drastic changes) or pinned (reject any change)
+	IPv4PrefixLength int    `yaml:"ipv4_prefix_length"`
+	IPv6PrefixLength int    `yaml:"ipv6_prefix_length"`
+}
+
+// SigningKeyConfig defines a JWT signing key. Exactly one key is active; keys with
+// retired_at set only verify tokens until retired_at plus the key overlap.
+type SigningKeyConfig struct {
+	ID             string     `yaml:"id"`
+	Algorithm      string     `yaml:"algorithm"`        // HS256, RS256 or ES256
+	Secret         string     `yaml:"secret"`           // HS256
+	PrivateKeyFile string     `yaml:"private_key_file"` // PEM, RS256 and ES256
+	RetiredAt      *time.Time `yaml:"retired_at"`
 }
 
 type CORSConfig struct {
@@ -93,10 +173,437 @@ type CORSConfig struct {
 	MaxAge           int      `yaml:"max_age"`
 }
 
+// LoggingConfig defines log output. The slow query threshold and sample rates
+// are the defaults of settings admins may change at runtime.
 type LoggingConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"`
 	Output string `yaml:"output"`
+	// Repository queries running at least this long are logged with their
+	// parameters redacted; 0 disables slow query logging
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	// Fraction of high-volume debug logs kept, 0 to 1, and the fraction per
+	// log category, e.g. "sync"
+	DebugSampleRate float64            `yaml:"debug_sample_rate"`
+	SampleRates     map[string]float64 `yaml:"sample_rates"`
+	// How often runtime changes made on other instances are picked up
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// FreshnessConfig defines how recently CIs are expected to be updated or scanned
+type FreshnessConfig struct {
+	DefaultMaxAge time.Duration            `yaml:"default_max_age"`
+	TypeMaxAge    map[string]time.Duration `yaml:"type_max_age"`
+}
+
+// FeatureFlagsConfig defines flag caching and which routes are gated behind flags
+type FeatureFlagsConfig struct {
+	CacheTTL time.Duration     `yaml:"cache_ttl"`
+	Routes   map[string]string `yaml:"routes"` // "[METHOD ]/path/template" -> flag key
+}
+
+// MaintenanceConfig defines how read-only maintenance mode is enforced
+type MaintenanceConfig struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	ExemptPaths     []string      `yaml:"exempt_paths"`
+}
+
+// SchemaCheckConfig defines the startup schema self-check. In strict mode the
+// server refuses to start on drift and services do not create missing tables at runtime.
+type SchemaCheckConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Strict  bool `yaml:"strict"`
+}
+
+// APIVersionsConfig defines which API versions are served and what is deprecated
+type APIVersionsConfig struct {
+	V2Enabled    bool                         `yaml:"v2_enabled"`
+	Deprecations map[string]DeprecationConfig `yaml:"deprecations"` // "v1" or "[METHOD ]/api/v1/path/template" -> deprecation
+}
+
+// DeprecationConfig announces a deprecation; dates are YYYY-MM-DD or RFC 3339
+type DeprecationConfig struct {
+	Since     string `yaml:"since"`
+	Sunset    string `yaml:"sunset"`
+	Link      string `yaml:"link"`
+	Successor string `yaml:"successor"`
+}
+
+// PayloadLogConfig defines debug logging of request and response bodies. Routes
+// listed here are logged from startup; others are switched on at runtime.
+type PayloadLogConfig struct {
+	Routes          []string      `yaml:"routes"` // "[METHOD ]/path/template"
+	DefaultDuration time.Duration `yaml:"default_duration"`
+	MaxBodyBytes    int           `yaml:"max_body_bytes"`
+	RedactKeys      []string      `yaml:"redact_keys"` // field names redacted in addition to the built-in list
+}
+
+// OwnershipConfig defines the CI ownership transfer workflow. Recipients must
+// accept a transfer before its deadline; overdue transfers expire unchanged.
+type OwnershipConfig struct {
+	DefaultDeadline time.Duration `yaml:"default_deadline"`
+	MaxDeadline     time.Duration `yaml:"max_deadline"`
+	ExpiryInterval  time.Duration `yaml:"expiry_interval"`
+}
+
+// AssetLabelConfig defines the QR codes printed on physical asset labels
+type AssetLabelConfig struct {
+	BaseURL         string `yaml:"base_url"`         // web UI address encoded in codes; empty encodes the bare CI ID
+	ErrorCorrection string `yaml:"error_correction"` // L, M, Q or H
+}
+
+// ImpactConfig defines impact analysis scoring. The weights set how much CI
+// criticality and SLA tier contribute to the impact score.
+type ImpactConfig struct {
+	DefaultDepth      int     `yaml:"default_depth"`
+	CriticalityWeight float64 `yaml:"criticality_weight"`
+	SLAWeight         float64 `yaml:"sla_weight"`
+}
+
+// BackpressureConfig defines write throttling on sync backlog depth. Low-priority
+// writes are delayed past slow_threshold and rejected with 429 past
+// reject_threshold; a zero threshold disables that stage.
+type BackpressureConfig struct {
+	SlowThreshold   int64         `yaml:"slow_threshold"`
+	RejectThreshold int64         `yaml:"reject_threshold"`
+	SlowDelay       time.Duration `yaml:"slow_delay"`
+	RetryAfter      time.Duration `yaml:"retry_after"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Routes          []string      `yaml:"routes"` // "[METHOD ]/path/template" of low-priority bulk writes
+}
+
+// ResyncConfig defines how fast full resyncs of the graph store run by default
+type ResyncConfig struct {
+	BatchSize int `yaml:"batch_size"` // entities read, and checkpointed, at a time
+	RateLimit int `yaml:"rate_limit"` // entities per second; 0 means unlimited
+}
+
+// BillingConfig defines the cloud billing connectors enriching CIs with
+// monthly cost. Exports are matched to CIs by the resource_attribute value.
+type BillingConfig struct {
+	Interval          time.Duration            `yaml:"interval"`
+	ResourceAttribute string                   `yaml:"resource_attribute"`
+	Connectors        []BillingConnectorConfig `yaml:"connectors"`
+}
+
+// BillingConnectorConfig defines a directory of billing exports to read
+type BillingConnectorConfig struct {
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"` // aws_cur or azure
+	Path     string `yaml:"path"`
+	Pattern  string `yaml:"pattern"` // file name glob, *.csv* by default
+}
+
+// FederationConfig defines the external systems of record whose CIs are read
+// through instead of being imported
+type FederationConfig struct {
+	Sources []FederationSourceConfig `yaml:"sources"`
+}
+
+// FederationSourceConfig defines an HTTP JSON API serving the CIs of one type.
+// Header values are expanded from the environment, e.g. "${LIBRENMS_TOKEN}".
+type FederationSourceConfig struct {
+	Name        string            `yaml:"name"`
+	Type        string            `yaml:"type"`
+	URL         string            `yaml:"url"`
+	ItemsPath   string            `yaml:"items_path"`
+	IDField     string            `yaml:"id_field"`
+	NameField   string            `yaml:"name_field"`
+	StatusField string            `yaml:"status_field"`
+	Attributes  map[string]string `yaml:"attributes"` // CI attribute to remote field
+	Headers     map[string]string `yaml:"headers"`
+	Timeout     time.Duration     `yaml:"timeout"`
+	CacheTTL    time.Duration     `yaml:"cache_ttl"`
+}
+
+// AuditConfig defines audit log retention enforcement. Expired entries are
+// archived below archive_path, typically a mounted object storage bucket;
+// without it, retention policies can only delete.
+type AuditConfig struct {
+	ArchivePath         string        `yaml:"archive_path"`
+	EnforcementInterval time.Duration `yaml:"enforcement_interval"`
+}
+
+// VelocityConfig defines the change velocity anomaly detectors. Each run looks
+// back over window; a zero threshold disables its detector.
+type VelocityConfig struct {
+	Interval        time.Duration `yaml:"interval"`
+	Window          time.Duration `yaml:"window"`
+	EntityChanges   int64         `yaml:"entity_changes"`
+	Deletions       int64         `yaml:"deletions"`
+	TypeChurnRatio  float64       `yaml:"type_churn_ratio"`
+	TypeChurnMinCIs int64         `yaml:"type_churn_min_cis"`
+	SpikeFactor     float64       `yaml:"spike_factor"`
+	SpikeMinEvents  int64         `yaml:"spike_min_events"`
+	Baseline        time.Duration `yaml:"baseline"`
+	Cooldown        time.Duration `yaml:"cooldown"`
+}
+
+// SummaryCacheConfig defines the cache of CI summaries shown at relationship
+// endpoints. Entries are invalidated from the sync event stream, polled every
+// poll_interval; ttl bounds staleness should an invalidation be missed.
+type SummaryCacheConfig struct {
+	TTL          time.Duration `yaml:"ttl"`
+	MaxEntries   int           `yaml:"max_entries"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// ForceSyncConfig defines how fast filtered force syncs enqueue sync events by default
+type ForceSyncConfig struct {
+	BatchSize int `yaml:"batch_size"` // entities read, and checkpointed, at a time
+	RateLimit int `yaml:"rate_limit"` // events per second; 0 means unlimited
+	MaxJobs   int `yaml:"max_jobs"`   // jobs running at once per instance
+}
+
+// SyncOverviewConfig defines how long the operations dashboard sync overview
+// is cached; 0 builds it on every request
+type SyncOverviewConfig struct {
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// ImportsConfig defines how long after completing an import can be rolled
+// back; its journal is deleted once the window has passed
+type ImportsConfig struct {
+	RollbackWindow time.Duration `yaml:"rollback_window"`
+	PurgeInterval  time.Duration `yaml:"purge_interval"`
+}
+
+// EdgeCleanupConfig defines how often relationships left pointing at deleted
+// CIs are deactivated, and how many are deactivated per statement
+type EdgeCleanupConfig struct {
+	Interval  time.Duration `yaml:"interval"`
+	BatchSize int           `yaml:"batch_size"`
+}
+
+// ArchiveConfig defines after how long without changes inactive or deleted CIs
+// are moved to the archive tier, how often that runs, and how many CIs are
+// archived per transaction
+type ArchiveConfig struct {
+	InactiveFor time.Duration `yaml:"inactive_for"`
+	Interval    time.Duration `yaml:"interval"`
+	BatchSize   int           `yaml:"batch_size"`
+}
+
+// HeartbeatsConfig defines after how long without a heartbeat monitored CIs
+// become unreachable, per type, and how often that is checked
+type HeartbeatsConfig struct {
+	DefaultThreshold time.Duration            `yaml:"default_threshold"`
+	TypeThreshold    map[string]time.Duration `yaml:"type_threshold"`
+	CheckInterval    time.Duration            `yaml:"check_interval"`
+}
+
+// MetricsConfig defines whether and where the inventory counts are exposed to
+// metrics scrapers, and how long they are cached; 0 reads them on every scrape
+type MetricsConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Path     string        `yaml:"path"`
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// ResponsesConfig defines how JSON responses are serialized: key naming
+// (snake_case or camelCase) and whether bodies are wrapped in a {data, meta,
+// errors} envelope, by default and per API version. Clients override the
+// format with the header; an empty header ignores client preferences.
+type ResponsesConfig struct {
+	Naming       string                          `yaml:"naming"`
+	Envelope     bool                            `yaml:"envelope"`
+	Versions     map[string]ResponseFormatConfig `yaml:"versions"` // API version name -> format
+	Header       string                          `yaml:"header"`
+	PreserveKeys []string                        `yaml:"preserve_keys"` // keys whose object values are user data, left as written
+}
+
+// ResponseFormatConfig is the response format of an API version
+type ResponseFormatConfig struct {
+	Naming   string `yaml:"naming"`
+	Envelope bool   `yaml:"envelope"`
+}
+
+// EscalationConfig defines how unresolved sync conflicts age: the age at which
+// they escalate to each severity (medium, high, critical), the role notified at
+// each step, and the age at which they are resolved with the timestamp
+// strategy, 0 never. Aging conflicts are checked every interval, in batches.
+type EscalationConfig struct {
+	EscalateAfter    map[string]time.Duration `yaml:"escalate_after"`
+	NotifyRole       string                   `yaml:"notify_role"`
+	AutoResolveAfter time.Duration            `yaml:"auto_resolve_after"`
+	Interval         time.Duration            `yaml:"interval"`
+	BatchSize        int                      `yaml:"batch_size"`
+}
+
+// CloudImportConfig defines the cloud connectors whose resource dependencies
+// are imported as relationships every interval. Resources are matched to CIs
+// through their external IDs, then by the resource_attribute value.
+type CloudImportConfig struct {
+	Interval          time.Duration          `yaml:"interval"`
+	ResourceAttribute string                 `yaml:"resource_attribute"`
+	Connectors        []CloudConnectorConfig `yaml:"connectors"`
+}
+
+// CloudConnectorConfig defines an AWS account or a set of Azure subscriptions
+// to import dependencies from. Credentials are expanded from the environment,
+// e.g. "${AWS_SECRET_ACCESS_KEY}".
+type CloudConnectorConfig struct {
+	Name            string        `yaml:"name"`
+	Provider        string        `yaml:"provider"` // aws or azure
+	Regions         []string      `yaml:"regions"`  // aws
+	AccessKeyID     string        `yaml:"access_key_id"`
+	SecretAccessKey string        `yaml:"secret_access_key"`
+	SessionToken    string        `yaml:"session_token"`
+	Subscriptions   []string      `yaml:"subscriptions"` // azure; all readable when empty
+	TenantID        string        `yaml:"tenant_id"`
+	ClientID        string        `yaml:"client_id"`
+	ClientSecret    string        `yaml:"client_secret"`
+	Endpoint        string        `yaml:"endpoint"`
+	Timeout         time.Duration `yaml:"timeout"`
+}
+
+// QuotasConfig defines the limits on live CIs per tenant (the CI's "tenant"
+// attribute) and per type; 0 means unlimited. Soft mode only notifies about
+// exceeded limits, hard mode rejects creates over them.
+type QuotasConfig struct {
+	Mode           string           `yaml:"mode"`
+	WarnPercent    int              `yaml:"warn_percent"`
+	DefaultTenant  int64            `yaml:"default_tenant"`
+	Tenants        map[string]int64 `yaml:"tenants"`
+	Types          map[string]int64 `yaml:"types"`
+	CountTTL       time.Duration    `yaml:"count_ttl"`
+	NotifyInterval time.Duration    `yaml:"notify_interval"`
+}
+
+// ResidencyConfig defines the databases that hold the CI data of tenants with
+// residency requirements. Tenants map to a shard by name; the tenant_shards
+// control table overrides them and is reloaded every refresh interval.
+// Unassigned tenants stay on the main database.
+type ResidencyConfig struct {
+	Shards          map[string]ShardConfig `yaml:"shards"`
+	Tenants         map[string]string      `yaml:"tenants"`
+	RefreshInterval time.Duration          `yaml:"refresh_interval"`
+}
+
+// ShardConfig defines a shard's database; empty fields inherit from the main
+// PostgreSQL database, so a shard can be just a schema of it
+type ShardConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Database string `yaml:"database"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	SSLMode  string `yaml:"ssl_mode"`
+	Schema   string `yaml:"schema"`
+}
+
+// FaultsConfig defines the failures sync injects into its Neo4j, Postgres and
+// Redis calls in reliability tests, as probabilities between 0 and 1. It
+// cannot be enabled in production.
+type FaultsConfig struct {
+	Enabled               bool    `yaml:"enabled"`
+	Seed                  int64   `yaml:"seed"`
+	Neo4jTimeout          float64 `yaml:"neo4j_timeout"`
+	PostgresSerialization float64 `yaml:"postgres_serialization"`
+	RedisOutage           float64 `yaml:"redis_outage"`
+}
+
+// PublicCatalogConfig defines the read-only catalog served without
+// authentication, e.g. for a public service catalog page. It exposes the CIs
+// with any of the tags or types, and only the listed fields and attributes of
+// them. It is disabled by default.
+type PublicCatalogConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	Tags       []string `yaml:"tags"`
+	Types      []string `yaml:"types"`
+	Fields     []string `yaml:"fields"`
+	Attributes []string `yaml:"attributes"`
+}
+
+// FileScanConfig defines the malware scanner uploaded files go through
+// before they are served: none, clamav or icap. Address is the host:port or
+// socket path of clamd, or the icap://host[:port]/service URL of the ICAP
+// server. Uploads are refused while no scanner is configured.
+type FileScanConfig struct {
+	Backend string        `yaml:"backend"`
+	Address string        `yaml:"address"`
+	Timeout time.Duration `yaml:"timeout"`
+	MaxSize int64         `yaml:"max_size"`
+}
+
+// IncidentLearningConfig defines how relationship confidence is learned from
+// ingested incidents. Incidents started within Window are counted every
+// Interval; a relationship whose target had at least MinIncidents of them,
+// and whose source was hit by at least CriticalConfidence of those, is
+// empirically critical.
+type IncidentLearningConfig struct {
+	Window             time.Duration `yaml:"window"`
+	MinIncidents       int           `yaml:"min_incidents"`
+	CriticalConfidence float64       `yaml:"critical_confidence"`
+	Interval           time.Duration `yaml:"interval"`
+}
+
+// BootstrapConfig defines the first-run setup API creating the initial admin
+// user, which only works while the database has no users. When Token is set,
+// setup requests must send it in the X-Bootstrap-Token header, so that only
+// whoever deployed the server can claim it.
+type BootstrapConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token"`
+}
+
+// WebhooksConfig defines webhook delivery. Due deliveries are dispatched
+// every DispatchInterval, and as soon as events are published; each attempt
+// is bounded by Timeout, and a delivery is given up as failed after
+// MaxAttempts.
+type WebhooksConfig struct {
+	DispatchInterval time.Duration `yaml:"dispatch_interval"`
+	Timeout          time.Duration `yaml:"timeout"`
+	MaxAttempts      int           `yaml:"max_attempts"`
+}
+
+// EventStreamConfig defines the live change stream. A keepalive is sent to
+// idle streams every Heartbeat; a client more than BufferSize changes behind
+// misses changes, and clients reconnecting within the last ReplaySize
+// changes resume without missing any.
+type EventStreamConfig struct {
+	Heartbeat  time.Duration `yaml:"heartbeat"`
+	BufferSize int           `yaml:"buffer_size"`
+	ReplaySize int           `yaml:"replay_size"`
+}
+
+// PurgeConfig defines CI purges for legal erasure. A purge must be approved
+// by RequiredApprovals admins other than its requester, and requests not
+// executed within RequestTTL expire.
+type PurgeConfig struct {
+	RequiredApprovals int           `yaml:"required_approvals"`
+	RequestTTL        time.Duration `yaml:"request_ttl"`
+}
+
+// AlertRulesConfig defines alert rule evaluation. Failed logins are counted
+// over the last FailedLoginWindow for the auth.failed_logins metric.
+type AlertRulesConfig struct {
+	FailedLoginWindow time.Duration `yaml:"failed_login_window"`
+}
+
+// PaginationConfig defines the page sizes of list endpoints. MaxPageSize is a
+// hard cap on the page size of every request; endpoints may change the default
+// and lower the maximum.
+type PaginationConfig struct {
+	DefaultPageSize int                       `yaml:"default_page_size"`
+	MaxPageSize     int                       `yaml:"max_page_size"`
+	Endpoints       map[string]PageSizeConfig `yaml:"endpoints"` // "[METHOD ]/path/template" -> page sizes
+}
+
+// PageSizeConfig overrides the page sizes of an endpoint; 0 keeps the global value
+type PageSizeConfig struct {
+	DefaultPageSize int `yaml:"default_page_size"`
+	MaxPageSize     int `yaml:"max_page_size"`
+}
+
+// SCIMConfig defines SCIM provisioning by identity providers, which
+// authenticate with Token as a bearer token. GroupRoles maps the display names
+// of provisioned groups to the roles they grant; other groups grant the role
+// of their name.
+type SCIMConfig struct {
+	Enabled    bool              `yaml:"enabled"`
+	Token      string            `yaml:"token"`
+	GroupRoles map[string]string `yaml:"group_roles"`
 }
 
 func Load() (*Config, error) {
@@ -177,12 +684,19 @@ func setDefaults() {
 	viper.SetDefault("auth.password_max_length", 128)
 	viper.SetDefault("auth.max_login_attempts", 5)
 	viper.SetDefault("auth.lockout_duration", "15m")
+	viper.SetDefault("auth.key_overlap", "0s")
+	viper.SetDefault("auth.refresh_token_rotation", true)
+	viper.SetDefault("auth.permission_cache_ttl", "5m")
+	viper.SetDefault("auth.fingerprint.mode", "off")
+	viper.SetDefault("auth.fingerprint.ipv4_prefix_length", 24)
+	viper.SetDefault("auth.fingerprint.ipv6_prefix_length", 64)
+	viper.SetDefault("auth.session_limits.default", 5)
 
 	// CORS
 	viper.SetDefault("cors.allowed_origins", []string{"*"})
 	viper.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
 	viper.SetDefault("cors.allowed_headers", []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"})
-	viper.SetDefault("cors.exposed_headers", []string{"Link"})
+	viper.SetDefault("cors.exposed_headers", []string{"Link", "Deprecation", "Sunset"})
 	viper.SetDefault("cors.allow_credentials", false)
 	viper.SetDefault("cors.max_age", 300)
 
@@ -190,6 +704,204 @@ func setDefaults() {
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
 	viper.SetDefault("logging.output", "stdout")
+	viper.SetDefault("logging.slow_query_threshold", "500ms")
+	viper.SetDefault("logging.debug_sample_rate", 1.0)
+	viper.SetDefault("logging.refresh_interval", "30s")
+
+	// Freshness
+	viper.SetDefault("freshness.default_max_age", "720h")
+	viper.SetDefault("freshness.type_max_age", map[string]string{
+		"server":         "168h",
+		"database":       "168h",
+		"network_device": "168h",
+		"application":    "720h",
+		"building":       "8760h",
+	})
+
+	// Feature flags
+	viper.SetDefault("feature_flags.cache_ttl", "30s")
+
+	// Maintenance
+	viper.SetDefault("maintenance.refresh_interval", "5s")
+	viper.SetDefault("maintenance.exempt_paths", []string{"/api/v1/auth/login", "/api/v1/auth/refresh"})
+
+	// Schema self-check
+	viper.SetDefault("schema_check.enabled", true)
+	viper.SetDefault("schema_check.strict", false)
+
+	// API versions
+	viper.SetDefault("api_versions.v2_enabled", false)
+
+	// Payload logging
+	viper.SetDefault("payload_logging.default_duration", "1h")
+	viper.SetDefault("payload_logging.max_body_bytes", 16384)
+
+	// Ownership transfers
+	viper.SetDefault("ownership.default_deadline", "168h")
+	viper.SetDefault("ownership.max_deadline", "720h")
+	viper.SetDefault("ownership.expiry_interval", "5m")
+
+	// Asset labels
+	viper.SetDefault("asset_labels.base_url", "")
+	viper.SetDefault("asset_labels.error_correction", "M")
+
+	// Impact analysis
+	viper.SetDefault("impact.default_depth", 5)
+	viper.SetDefault("impact.criticality_weight", 0.6)
+	viper.SetDefault("impact.sla_weight", 0.4)
+
+	// Backpressure
+	viper.SetDefault("backpressure.slow_threshold", 10000)
+	viper.SetDefault("backpressure.reject_threshold", 50000)
+	viper.SetDefault("backpressure.slow_delay", "500ms")
+	viper.SetDefault("backpressure.retry_after", "30s")
+	viper.SetDefault("backpressure.refresh_interval", "2s")
+	viper.SetDefault("backpressure.routes", []string{
+		"POST /api/v1/import/xlsx",
+		"POST /api/v1/cis/{id}/clone",
+		"POST /api/v1/ci-type-migrations",
+		"POST /api/v1/auto-tag-rules/backfill",
+	})
+
+	// Resync
+	viper.SetDefault("resync.batch_size", 500)
+	viper.SetDefault("resync.rate_limit", 200)
+
+	// Billing
+	viper.SetDefault("billing.interval", "6h")
+	viper.SetDefault("billing.resource_attribute", "cloud_resource_id")
+
+	// Audit
+	viper.SetDefault("audit.archive_path", "")
+	viper.SetDefault("audit.enforcement_interval", "24h")
+
+	// Velocity
+	viper.SetDefault("velocity.interval", "5m")
+	viper.SetDefault("velocity.window", "1h")
+	viper.SetDefault("velocity.entity_changes", 500)
+	viper.SetDefault("velocity.deletions", 100)
+	viper.SetDefault("velocity.type_churn_ratio", 0.8)
+	viper.SetDefault("velocity.type_churn_min_cis", 20)
+	viper.SetDefault("velocity.spike_factor", 5)
+	viper.SetDefault("velocity.spike_min_events", 1000)
+	viper.SetDefault("velocity.baseline", "168h")
+	viper.SetDefault("velocity.cooldown", "6h")
+
+	// Summary cache
+	viper.SetDefault("summary_cache.ttl", "5m")
+	viper.SetDefault("summary_cache.max_entries", 50000)
+	viper.SetDefault("summary_cache.poll_interval", "2s")
+
+	// Force sync
+	viper.SetDefault("force_sync.batch_size", 500)
+	viper.SetDefault("force_sync.rate_limit", 200)
+	viper.SetDefault("force_sync.max_jobs", 2)
+
+	// Sync overview
+	viper.SetDefault("sync_overview.cache_ttl", "10s")
+
+	// Imports
+	viper.SetDefault("imports.rollback_window", "168h")
+	viper.SetDefault("imports.purge_interval", "1h")
+
+	// Edge cleanup
+	viper.SetDefault("edge_cleanup.interval", "1h")
+	viper.SetDefault("edge_cleanup.batch_size", 1000)
+
+	// Archive
+	viper.SetDefault("archive.inactive_for", "4320h")
+	viper.SetDefault("archive.interval", "24h")
+	viper.SetDefault("archive.batch_size", 500)
+
+	// Heartbeats
+	viper.SetDefault("heartbeats.default_threshold", "15m")
+	viper.SetDefault("heartbeats.type_threshold", map[string]string{
+		"server":         "5m",
+		"database":       "5m",
+		"network_device": "5m",
+	})
+	viper.SetDefault("heartbeats.check_interval", "1m")
+
+	// Metrics
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("metrics.cache_ttl", "30s")
+
+	// Responses
+	viper.SetDefault("responses.naming", "snake_case")
+	viper.SetDefault("responses.envelope", false)
+	viper.SetDefault("responses.header", "X-Response-Format")
+	viper.SetDefault("responses.preserve_keys", []string{"attributes"})
+
+	// Conflict escalation
+	viper.SetDefault("conflict_escalation.escalate_after", map[string]string{
+		"medium":   "24h",
+		"high":     "72h",
+		"critical": "168h",
+	})
+	viper.SetDefault("conflict_escalation.notify_role", "admin")
+	viper.SetDefault("conflict_escalation.auto_resolve_after", "0s")
+	viper.SetDefault("conflict_escalation.interval", "15m")
+	viper.SetDefault("conflict_escalation.batch_size", 500)
+
+	// Cloud import
+	viper.SetDefault("cloud_import.interval", "6h")
+	viper.SetDefault("cloud_import.resource_attribute", "cloud_resource_id")
+
+	// Quotas
+	viper.SetDefault("quotas.mode", "soft")
+	viper.SetDefault("quotas.warn_percent", 80)
+	viper.SetDefault("quotas.count_ttl", "30s")
+	viper.SetDefault("quotas.notify_interval", "1h")
+
+	// Residency
+	viper.SetDefault("residency.refresh_interval", "1m")
+
+	// Fault injection
+	viper.SetDefault("fault_injection.enabled", false)
+	viper.SetDefault("fault_injection.seed", 1)
+
+	// Public catalog
+	viper.SetDefault("public_catalog.enabled", false)
+	viper.SetDefault("public_catalog.fields", []string{"id", "name", "type", "description", "status", "criticality", "tags"})
+
+	// File scanning
+	viper.SetDefault("file_scan.backend", "none")
+	viper.SetDefault("file_scan.timeout", "30s")
+	viper.SetDefault("file_scan.max_size", 25<<20)
+
+	// Incident learning
+	viper.SetDefault("incident_learning.window", "4320h")
+	viper.SetDefault("incident_learning.min_incidents", 3)
+	viper.SetDefault("incident_learning.critical_confidence", 0.6)
+	viper.SetDefault("incident_learning.interval", "1h")
+
+	// First-run bootstrap
+	viper.SetDefault("bootstrap.enabled", true)
+
+	// Webhooks
+	viper.SetDefault("webhooks.dispatch_interval", "10s")
+	viper.SetDefault("webhooks.timeout", "10s")
+	viper.SetDefault("webhooks.max_attempts", 8)
+
+	// Live change stream
+	viper.SetDefault("event_stream.heartbeat", "15s")
+	viper.SetDefault("event_stream.buffer_size", 64)
+	viper.SetDefault("event_stream.replay_size", 1000)
+
+	// CI purges
+	viper.SetDefault("purge.required_approvals", 1)
+	viper.SetDefault("purge.request_ttl", "72h")
+
+	// Alert rules
+	viper.SetDefault("alert_rules.failed_login_window", "5m")
+
+	// Pagination
+	viper.SetDefault("pagination.default_page_size", 20)
+	viper.SetDefault("pagination.max_page_size", 100)
+
+	// SCIM provisioning
+	viper.SetDefault("scim.enabled", false)
 }
 
 func validateConfig(config *Config) error {
@@ -266,6 +978,74 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("lockout duration must be positive")
 	}
 
+	if config.Auth.KeyOverlap < 0 {
+		return fmt.Errorf("key overlap cannot be negative")
+	}
+
+	if config.Auth.SessionLimits.Default < 0 {
+		return fmt.Errorf("default session limit cannot be negative")
+	}
+
+	for role, limit := range config.Auth.SessionLimits.Roles {
+		if limit < 0 {
+			return fmt.Errorf("session limit for role %s cannot be negative", role)
+		}
+	}
+
+	for tenant, limit := range config.Auth.SessionLimits.Tenants {
+		if limit < 0 {
+			return fmt.Errorf("session limit for tenant %s cannot be negative", tenant)
+		}
+	}
+
+	switch config.Auth.Fingerprint.Mode {
+	case "off", "flag", "strict", "pinned":
+	default:
+		return fmt.Errorf("invalid session fingerprint mode: %s", config.Auth.Fingerprint.Mode)
+	}
+
+	if config.Auth.Fingerprint.IPv4PrefixLength < 0 || config.Auth.Fingerprint.IPv4PrefixLength > 32 {
+		return fmt.Errorf("session fingerprint IPv4 prefix length must be between 0 and 32")
+	}
+
+	if config.Auth.Fingerprint.IPv6PrefixLength < 0 || config.Auth.Fingerprint.IPv6PrefixLength > 128 {
+		return fmt.Errorf("session fingerprint IPv6 prefix length must be between 0 and 128")
+	}
+
+	if len(config.Auth.SigningKeys) > 0 {
+		keyIDs := make(map[string]bool)
+		activeKeys := 0
+		for _, key := range config.Auth.SigningKeys {
+			if key.ID == "" {
+				return fmt.Errorf("signing key ID cannot be empty")
+			}
+			if keyIDs[key.ID] {
+				return fmt.Errorf("duplicate signing key ID: %s", key.ID)
+			}
+			keyIDs[key.ID] = true
+
+			switch key.Algorithm {
+			case "", "HS256":
+				if len(key.Secret) < 32 {
+					return fmt.Errorf("signing key %s: secret must be at least 32 characters long", key.ID)
+				}
+			case "RS256", "ES256":
+				if key.PrivateKeyFile == "" {
+					return fmt.Errorf("signing key %s: private key file is required for %s", key.ID, key.Algorithm)
+				}
+			default:
+				return fmt.Errorf("signing key %s: unsupported algorithm: %s", key.ID, key.Algorithm)
+			}
+
+			if key.RetiredAt == nil {
+				activeKeys++
+			}
+		}
+		if activeKeys != 1 {
+			return fmt.Errorf("exactly one signing key must be active, found %d", activeKeys)
+		}
+	}
+
 	// Validate CORS configuration
 	if len(config.CORS.AllowedOrigins) == 0 {
 		return fmt.Errorf("at least one allowed origin must be specified")
@@ -305,6 +1085,420 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid log output: %s", config.Logging.Output)
 	}
 
+	if config.Logging.SlowQueryThreshold < 0 {
+		return fmt.Errorf("slow query threshold cannot be negative")
+	}
+
+	if config.Logging.DebugSampleRate < 0 || config.Logging.DebugSampleRate > 1 {
+		return fmt.Errorf("debug sample rate must be between 0 and 1")
+	}
+
+	for category, rate := range config.Logging.SampleRates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sample rate of %s must be between 0 and 1", category)
+		}
+	}
+
+	if config.Logging.RefreshInterval < 0 {
+		return fmt.Errorf("log settings refresh interval cannot be negative")
+	}
+
+	// Validate freshness configuration
+	if config.Freshness.DefaultMaxAge <= 0 {
+		return fmt.Errorf("freshness default max age must be positive")
+	}
+
+	for ciType, maxAge := range config.Freshness.TypeMaxAge {
+		if maxAge <= 0 {
+			return fmt.Errorf("freshness max age for type %s must be positive", ciType)
+		}
+	}
+
+	// Validate feature flag configuration
+	if config.FeatureFlags.CacheTTL < 0 {
+		return fmt.Errorf("feature flag cache TTL cannot be negative")
+	}
+
+	// Validate maintenance configuration
+	if config.Maintenance.RefreshInterval < 0 {
+		return fmt.Errorf("maintenance refresh interval cannot be negative")
+	}
+
+	// Validate payload logging configuration
+	if config.PayloadLog.DefaultDuration <= 0 {
+		return fmt.Errorf("payload logging default duration must be positive")
+	}
+
+	if config.PayloadLog.MaxBodyBytes <= 0 {
+		return fmt.Errorf("payload logging max body bytes must be positive")
+	}
+
+	// Validate ownership transfer configuration
+	if config.Ownership.DefaultDeadline <= 0 || config.Ownership.MaxDeadline <= 0 {
+		return fmt.Errorf("ownership transfer deadlines must be positive")
+	}
+
+	if config.Ownership.DefaultDeadline > config.Ownership.MaxDeadline {
+		return fmt.Errorf("ownership default deadline cannot exceed the max deadline")
+	}
+
+	if config.Ownership.ExpiryInterval <= 0 {
+		return fmt.Errorf("ownership expiry interval must be positive")
+	}
+
+	// Validate asset label configuration
+	switch strings.ToUpper(config.AssetLabels.ErrorCorrection) {
+	case "L", "M", "Q", "H":
+	default:
+		return fmt.Errorf("invalid asset label error correction level: %s", config.AssetLabels.ErrorCorrection)
+	}
+
+	if config.AssetLabels.BaseURL != "" {
+		if u, err := url.Parse(config.AssetLabels.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid asset label base URL: %s", config.AssetLabels.BaseURL)
+		}
+	}
+
+	// Validate impact analysis configuration
+	if config.Impact.DefaultDepth < 1 || config.Impact.DefaultDepth > 10 {
+		return fmt.Errorf("impact default depth must be between 1 and 10")
+	}
+
+	if config.Impact.CriticalityWeight < 0 || config.Impact.SLAWeight < 0 ||
+		config.Impact.CriticalityWeight+config.Impact.SLAWeight <= 0 {
+		return fmt.Errorf("impact weights must not be negative and at least one must be positive")
+	}
+
+	// Validate backpressure configuration
+	if config.Backpressure.SlowThreshold < 0 || config.Backpressure.RejectThreshold < 0 {
+		return fmt.Errorf("backpressure thresholds cannot be negative")
+	}
+
+	if config.Backpressure.SlowThreshold > 0 && config.Backpressure.RejectThreshold > 0 &&
+		config.Backpressure.RejectThreshold < config.Backpressure.SlowThreshold {
+		return fmt.Errorf("backpressure reject threshold must not be below the slow threshold")
+	}
+
+	if config.Backpressure.SlowDelay < 0 || config.Backpressure.RetryAfter < 0 || config.Backpressure.RefreshInterval < 0 {
+		return fmt.Errorf("backpressure durations cannot be negative")
+	}
+
+	// Validate resync configuration
+	if config.Resync.BatchSize < 1 || config.Resync.BatchSize > 10000 {
+		return fmt.Errorf("resync batch size must be between 1 and 10000")
+	}
+
+	if config.Resync.RateLimit < 0 || config.Resync.RateLimit > 10000 {
+		return fmt.Errorf("resync rate limit must be between 0 and 10000")
+	}
+
+	// Validate billing configuration
+	if config.Billing.Interval <= 0 {
+		return fmt.Errorf("billing interval must be positive")
+	}
+
+	if config.Billing.ResourceAttribute == "" {
+		return fmt.Errorf("billing resource attribute is required")
+	}
+
+	connectorNames := make(map[string]bool)
+	for _, connector := range config.Billing.Connectors {
+		if connector.Name == "" || connector.Path == "" {
+			return fmt.Errorf("billing connectors require a name and a path")
+		}
+		if connector.Provider != "aws_cur" && connector.Provider != "azure" {
+			return fmt.Errorf("invalid provider %q for billing connector %s: expected aws_cur or azure", connector.Provider, connector.Name)
+		}
+		if connectorNames[connector.Name] {
+			return fmt.Errorf("duplicate billing connector: %s", connector.Name)
+		}
+		connectorNames[connector.Name] = true
+	}
+
+	// Validate federation configuration
+	sourceNames := make(map[string]bool)
+	for _, source := range config.Federation.Sources {
+		if source.Name == "" || source.Type == "" || source.URL == "" {
+			return fmt.Errorf("federation sources require a name, a type and a URL")
+		}
+		if source.IDField == "" || source.NameField == "" {
+			return fmt.Errorf("federation source %s requires an ID and a name field", source.Name)
+		}
+		if source.Timeout < 0 || source.CacheTTL < 0 {
+			return fmt.Errorf("federation source %s timeout and cache TTL cannot be negative", source.Name)
+		}
+		if sourceNames[source.Name] {
+			return fmt.Errorf("duplicate federation source: %s", source.Name)
+		}
+		sourceNames[source.Name] = true
+	}
+
+	// Validate audit configuration
+	if config.Audit.EnforcementInterval <= 0 {
+		return fmt.Errorf("audit enforcement interval must be positive")
+	}
+
+	// Validate velocity configuration
+	if config.Velocity.Interval <= 0 || config.Velocity.Window <= 0 {
+		return fmt.Errorf("velocity interval and window must be positive")
+	}
+	if config.Velocity.TypeChurnRatio < 0 || config.Velocity.TypeChurnRatio > 1 {
+		return fmt.Errorf("velocity type churn ratio must be between 0 and 1")
+	}
+	if config.Velocity.SpikeFactor > 0 && config.Velocity.Baseline < config.Velocity.Window {
+		return fmt.Errorf("velocity baseline must be at least one window")
+	}
+
+	// Validate summary cache configuration
+	if config.SummaryCache.TTL <= 0 || config.SummaryCache.PollInterval <= 0 {
+		return fmt.Errorf("summary cache TTL and poll interval must be positive")
+	}
+	if config.SummaryCache.MaxEntries <= 0 {
+		return fmt.Errorf("summary cache max entries must be positive")
+	}
+
+	// Validate force sync configuration
+	if config.ForceSync.BatchSize < 1 || config.ForceSync.BatchSize > 10000 {
+		return fmt.Errorf("force sync batch size must be between 1 and 10000")
+	}
+
+	if config.ForceSync.RateLimit < 0 || config.ForceSync.RateLimit > 10000 {
+		return fmt.Errorf("force sync rate limit must be between 0 and 10000")
+	}
+
+	if config.ForceSync.MaxJobs < 1 {
+		return fmt.Errorf("force sync max jobs must be at least 1")
+	}
+
+	// Validate sync overview configuration
+	if config.SyncOverview.CacheTTL < 0 {
+		return fmt.Errorf("sync overview cache TTL cannot be negative")
+	}
+
+	// Validate imports configuration
+	if config.Imports.RollbackWindow <= 0 || config.Imports.PurgeInterval <= 0 {
+		return fmt.Errorf("import rollback window and purge interval must be positive")
+	}
+
+	// Validate edge cleanup configuration
+	if config.EdgeCleanup.Interval <= 0 || config.EdgeCleanup.BatchSize < 1 {
+		return fmt.Errorf("edge cleanup interval and batch size must be positive")
+	}
+
+	// Validate archive configuration
+	if config.Archive.InactiveFor <= 0 || config.Archive.Interval <= 0 || config.Archive.BatchSize < 1 {
+		return fmt.Errorf("archive inactivity period, interval and batch size must be positive")
+	}
+
+	// Validate heartbeats configuration
+	if config.Heartbeats.DefaultThreshold <= 0 || config.Heartbeats.CheckInterval <= 0 {
+		return fmt.Errorf("heartbeat default threshold and check interval must be positive")
+	}
+
+	for ciType, threshold := range config.Heartbeats.TypeThreshold {
+		if threshold <= 0 {
+			return fmt.Errorf("heartbeat threshold for type %s must be positive", ciType)
+		}
+	}
+
+	// Validate metrics configuration
+	if config.Metrics.Enabled && !strings.HasPrefix(config.Metrics.Path, "/") {
+		return fmt.Errorf("metrics path must start with /")
+	}
+	if config.Metrics.CacheTTL < 0 {
+		return fmt.Errorf("metrics cache TTL cannot be negative")
+	}
+
+	// Validate response format configuration
+	if !isResponseNaming(config.Responses.Naming) {
+		return fmt.Errorf("invalid response naming: %s", config.Responses.Naming)
+	}
+	for version, format := range config.Responses.Versions {
+		if !isResponseNaming(format.Naming) {
+			return fmt.Errorf("invalid response naming for API version %s: %s", version, format.Naming)
+		}
+	}
+
+	// Validate conflict escalation configuration
+	if config.Escalation.Interval <= 0 || config.Escalation.BatchSize <= 0 {
+		return fmt.Errorf("conflict escalation interval and batch size must be positive")
+	}
+	if config.Escalation.AutoResolveAfter < 0 {
+		return fmt.Errorf("conflict auto-resolve age cannot be negative")
+	}
+	for severity, after := range config.Escalation.EscalateAfter {
+		if severity != "medium" && severity != "high" && severity != "critical" {
+			return fmt.Errorf("invalid conflict escalation severity: %s", severity)
+		}
+		if after <= 0 {
+			return fmt.Errorf("conflict escalation to %s must be after a positive age", severity)
+		}
+	}
+
+	// Validate cloud import configuration
+	if config.CloudImport.Interval <= 0 {
+		return fmt.Errorf("cloud import interval must be positive")
+	}
+
+	if config.CloudImport.ResourceAttribute == "" {
+		return fmt.Errorf("cloud import resource attribute is required")
+	}
+
+	cloudConnectorNames := make(map[string]bool)
+	for _, connector := range config.CloudImport.Connectors {
+		if connector.Name == "" {
+			return fmt.Errorf("cloud import connectors require a name")
+		}
+		if connector.Provider != "aws" && connector.Provider != "azure" {
+			return fmt.Errorf("invalid provider %q for cloud import connector %s: expected aws or azure", connector.Provider, connector.Name)
+		}
+		if connector.Timeout < 0 {
+			return fmt.Errorf("cloud import connector %s timeout cannot be negative", connector.Name)
+		}
+		if cloudConnectorNames[connector.Name] {
+			return fmt.Errorf("duplicate cloud import connector: %s", connector.Name)
+		}
+		cloudConnectorNames[connector.Name] = true
+	}
+
+	// Validate quotas configuration
+	if config.Quotas.Mode != "soft" && config.Quotas.Mode != "hard" {
+		return fmt.Errorf("invalid quota mode: %s", config.Quotas.Mode)
+	}
+	if config.Quotas.WarnPercent < 1 || config.Quotas.WarnPercent > 100 {
+		return fmt.Errorf("quota warn percent must be between 1 and 100")
+	}
+
+	// Validate residency configuration
+	if config.Residency.RefreshInterval <= 0 {
+		return fmt.Errorf("residency refresh interval must be positive")
+	}
+	for name, shard := range config.Residency.Shards {
+		if name == "" || name == "main" {
+			return fmt.Errorf("invalid residency shard name: %q", name)
+		}
+		if shard.Port < 0 || shard.Port > 65535 {
+			return fmt.Errorf("invalid port for residency shard %s: %d", name, shard.Port)
+		}
+		if shard.Schema != "" && !isSQLIdentifier(shard.Schema) {
+			return fmt.Errorf("invalid schema for residency shard %s: %s", name, shard.Schema)
+		}
+	}
+	for tenant, shard := range config.Residency.Tenants {
+		if _, ok := config.Residency.Shards[shard]; !ok {
+			return fmt.Errorf("tenant %s is assigned to unknown residency shard %s", tenant, shard)
+		}
+	}
+
+	// Validate fault injection configuration
+	if config.Faults.Enabled && config.IsProduction() {
+		return fmt.Errorf("fault injection cannot be enabled in production")
+	}
+	for _, probability := range []float64{config.Faults.Neo4jTimeout, config.Faults.PostgresSerialization, config.Faults.RedisOutage} {
+		if probability < 0 || probability > 1 {
+			return fmt.Errorf("fault injection probabilities must be between 0 and 1")
+		}
+	}
+
+	// Validate public catalog configuration
+	if config.PublicCatalog.Enabled && len(config.PublicCatalog.Tags) == 0 && len(config.PublicCatalog.Types) == 0 {
+		return fmt.Errorf("public catalog requires at least one tag or type to expose")
+	}
+
+	// Validate file scanning configuration
+	switch config.FileScan.Backend {
+	case "none":
+	case "clamav", "icap":
+		if config.FileScan.Address == "" {
+			return fmt.Errorf("file scan backend %s requires an address", config.FileScan.Backend)
+		}
+	default:
+		return fmt.Errorf("invalid file scan backend %q: expected none, clamav or icap", config.FileScan.Backend)
+	}
+	if config.FileScan.Timeout <= 0 || config.FileScan.MaxSize <= 0 {
+		return fmt.Errorf("file scan timeout and max size must be positive")
+	}
+
+	// Validate incident learning configuration
+	if config.IncidentLearning.Window <= 0 || config.IncidentLearning.Interval <= 0 {
+		return fmt.Errorf("incident learning window and interval must be positive")
+	}
+	if config.IncidentLearning.MinIncidents < 1 {
+		return fmt.Errorf("incident learning min incidents must be at least 1")
+	}
+	if config.IncidentLearning.CriticalConfidence <= 0 || config.IncidentLearning.CriticalConfidence > 1 {
+		return fmt.Errorf("incident learning critical confidence must be between 0 and 1")
+	}
+
+	// Validate webhooks configuration
+	if config.Webhooks.DispatchInterval <= 0 || config.Webhooks.Timeout <= 0 {
+		return fmt.Errorf("webhook dispatch interval and timeout must be positive")
+	}
+	if config.Webhooks.MaxAttempts < 1 {
+		return fmt.Errorf("webhook max attempts must be at least 1")
+	}
+
+	// Validate event stream configuration
+	if config.EventStream.Heartbeat <= 0 {
+		return fmt.Errorf("event stream heartbeat must be positive")
+	}
+	if config.EventStream.BufferSize < 1 || config.EventStream.ReplaySize < 1 {
+		return fmt.Errorf("event stream buffer and replay sizes must be at least 1")
+	}
+
+	// Validate purge configuration; purges always need someone else's approval
+	if config.Purge.RequiredApprovals < 1 {
+		return fmt.Errorf("purge required approvals must be at least 1")
+	}
+	if config.Purge.RequestTTL <= 0 {
+		return fmt.Errorf("purge request TTL must be positive")
+	}
+
+	// Validate alert rules configuration
+	if config.AlertRules.FailedLoginWindow <= 0 {
+		return fmt.Errorf("alert rules failed login window must be positive")
+	}
+
+	// Validate pagination configuration; no endpoint may exceed the hard cap
+	if config.Pagination.DefaultPageSize < 1 || config.Pagination.DefaultPageSize > config.Pagination.MaxPageSize {
+		return fmt.Errorf("default page size must be between 1 and the max page size")
+	}
+	for route, sizes := range config.Pagination.Endpoints {
+		if sizes.DefaultPageSize < 0 || sizes.MaxPageSize < 0 {
+			return fmt.Errorf("page sizes for %s cannot be negative", route)
+		}
+		if sizes.MaxPageSize > config.Pagination.MaxPageSize {
+			return fmt.Errorf("max page size for %s cannot exceed the max page size of %d", route, config.Pagination.MaxPageSize)
+		}
+		maxPageSize := sizes.MaxPageSize
+		if maxPageSize == 0 {
+			maxPageSize = config.Pagination.MaxPageSize
+		}
+		if sizes.DefaultPageSize > maxPageSize {
+			return fmt.Errorf("default page size for %s cannot exceed its max page size", route)
+		}
+	}
+
+	// Validate SCIM configuration; provisioning is never served unauthenticated
+	if config.SCIM.Enabled && len(config.SCIM.Token) < 32 {
+		return fmt.Errorf("SCIM requires a token of at least 32 characters")
+	}
+
+	// Validate API version deprecations
+	for route, deprecation := range config.APIVersions.Deprecations {
+		for _, date := range []string{deprecation.Since, deprecation.Sunset} {
+			if date == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", date); err == nil {
+				continue
+			}
+			if _, err := time.Parse(time.RFC3339, date); err != nil {
+				return fmt.Errorf("invalid deprecation date %q for %s: expected YYYY-MM-DD or RFC 3339", date, route)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -320,6 +1514,65 @@ func (c *Config) GetPostgreSQLConnectionString() string {
 	)
 }
 
+// GetShardConnectionStrings returns the PostgreSQL connection string of each
+// residency shard by name
+func (c *Config) GetShardConnectionStrings() map[string]string {
+	main := c.Database.PostgreSQL
+	dsns := make(map[string]string, len(c.Residency.Shards))
+	for name, shard := range c.Residency.Shards {
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			firstNonEmpty(shard.Host, main.Host),
+			firstPositive(shard.Port, main.Port),
+			firstNonEmpty(shard.Username, main.Username),
+			firstNonEmpty(shard.Password, main.Password),
+			firstNonEmpty(shard.Database, main.Database),
+			firstNonEmpty(shard.SSLMode, main.SSLMode),
+		)
+		if shard.Schema != "" {
+			dsn += " search_path=" + shard.Schema
+		}
+		dsns[name] = dsn
+	}
+	return dsns
+}
+
+func firstNonEmpty(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
+
+func firstPositive(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// isSQLIdentifier reports whether s is an unquoted SQL identifier
+func isSQLIdentifier(s string) bool {
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '$'):
+		default:
+			return false
+		}
+	}
+	return len(s) > 0 && len(s) <= 63
+}
+
+// isResponseNaming reports whether s is a response key naming style
+func isResponseNaming(s string) bool {
+	switch strings.ToLower(s) {
+	case "", "snake_case", "camelcase":
+		return true
+	default:
+		return false
+	}
+}
+
 // GetRedisConnectionString returns the Redis connection string
 func (c *Config) GetRedisConnectionString() string {
 	if c.Database.Redis.Password != "" {
@@ -0,0 +1,373 @@
+// Package conflictescalation keeps sync conflicts from sitting unresolved
+// forever. Conflicts start at low severity and escalate as they age past the
+// threshold of each severity, notifying the members of a configured role at
+// every step. Optionally, conflicts still unresolved after a longer age are
+// resolved with the timestamp strategy. Every step is recorded against the
+// conflict, including failed notifications and resolutions.
+package conflictescalation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Severities of a conflict, in escalation order
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Severities lists the severities in escalation order
+var Severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Actions recorded as escalation steps
+const (
+	ActionEscalated         = "escalated"
+	ActionNotified          = "notified"
+	ActionNotifyFailed      = "notify_failed"
+	ActionAutoResolved      = "auto_resolved"
+	ActionAutoResolveFailed = "auto_resolve_failed"
+)
+
+// Notification kinds
+const (
+	KindEscalated    = "escalated"
+	KindAutoResolved = "auto_resolved"
+)
+
+// ResolvedBy is recorded as the resolver of auto-resolved conflicts
+const ResolvedBy = "conflict-escalation"
+
+// DefaultBatchSize is how many conflicts are handled per run by default
+const DefaultBatchSize = 500
+
+// ErrConflictNotFound is returned for unknown conflicts
+var ErrConflictNotFound = errors.New("sync conflict not found")
+
+// Policy defines when conflicts escalate and are auto-resolved
+type Policy struct {
+	// EscalateAfter maps a severity to the age at which conflicts reach it
+	EscalateAfter map[string]time.Duration
+	// NotifyRole is the role whose members are notified; empty notifies nobody
+	NotifyRole string
+	// AutoResolveAfter is the age at which conflicts are resolved with the
+	// timestamp strategy; 0 leaves them to be resolved by hand
+	AutoResolveAfter time.Duration
+}
+
+// Validate checks that severities are known and that higher severities are
+// reached later
+func (p Policy) Validate() error {
+	var previous time.Duration
+	for _, severity := range Severities[1:] {
+		after, ok := p.EscalateAfter[severity]
+		if !ok {
+			continue
+		}
+		if after <= 0 {
+			return fmt.Errorf("escalation to %s must be after a positive age", severity)
+		}
+		if after <= previous {
+			return fmt.Errorf("escalation to %s must be after escalation to lower severities", severity)
+		}
+		previous = after
+	}
+	for severity := range p.EscalateAfter {
+		if rank(severity) < 1 {
+			return fmt.Errorf("conflicts cannot escalate to severity %q, expected medium, high or critical", severity)
+		}
+	}
+	if p.AutoResolveAfter < 0 {
+		return fmt.Errorf("auto-resolve age cannot be negative")
+	}
+	return nil
+}
+
+// SeverityAt returns the severity of a conflict of an age
+func (p Policy) SeverityAt(age time.Duration) string {
+	severity := SeverityLow
+	for _, s := range Severities[1:] {
+		if after, ok := p.EscalateAfter[s]; ok && age >= after {
+			severity = s
+		}
+	}
+	return severity
+}
+
+// Due are the creation times before which conflicts need a step: escalation to
+// a severity, or auto-resolution when AutoResolve is set
+type Due struct {
+	Escalate    map[string]time.Time
+	AutoResolve *time.Time
+}
+
+// Due returns the cutoffs of the policy at a time
+func (p Policy) Due(now time.Time) Due {
+	due := Due{Escalate: map[string]time.Time{}}
+	for severity, after := range p.EscalateAfter {
+		due.Escalate[severity] = now.Add(-after)
+	}
+	if p.AutoResolveAfter > 0 {
+		cutoff := now.Add(-p.AutoResolveAfter)
+		due.AutoResolve = &cutoff
+	}
+	return due
+}
+
+// rank returns the position of a severity in the escalation order, or -1
+func rank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Conflict is an unresolved sync conflict as seen by the escalation
+type Conflict struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	EntityType   string     `json:"entity_type" db:"entity_type"`
+	EntityID     uuid.UUID  `json:"entity_id" db:"entity_id"`
+	ConflictType string     `json:"conflict_type" db:"conflict_type"`
+	Severity     string     `json:"severity" db:"severity"`
+	EscalatedAt  *time.Time `json:"escalated_at,omitempty" db:"escalated_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Step is a recorded escalation step of a conflict
+type Step struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	ConflictID   uuid.UUID `json:"conflict_id" db:"conflict_id"`
+	Action       string    `json:"action" db:"action"`
+	FromSeverity string    `json:"from_severity,omitempty" db:"from_severity"`
+	ToSeverity   string    `json:"to_severity,omitempty" db:"to_severity"`
+	Role         string    `json:"role,omitempty" db:"role"`
+	Recipients   []string  `json:"recipients" db:"-"`
+	Details      string    `json:"details,omitempty" db:"details"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Notification tells the members of a role that a conflict escalated or was
+// auto-resolved
+type Notification struct {
+	Kind       string   `json:"kind"`
+	Role       string   `json:"role"`
+	Recipients []string `json:"recipients"`
+	Conflict   Conflict `json:"conflict"`
+}
+
+// Notifier delivers escalation notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the log. It is used until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the notification
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	c := n.Conflict
+	switch n.Kind {
+	case KindEscalated:
+		log.Printf("Sync conflict %s (%s on %s %s) unresolved since %s escalated to %s, notifying role %s: %s",
+			c.ID, c.ConflictType, c.EntityType, c.EntityID, c.CreatedAt.Format(time.RFC3339), c.Severity, n.Role, strings.Join(n.Recipients, ", "))
+	default:
+		log.Printf("Sync conflict %s (%s on %s %s) was auto-resolved by timestamp, notifying role %s: %s",
+			c.ID, c.ConflictType, c.EntityType, c.EntityID, n.Role, strings.Join(n.Recipients, ", "))
+	}
+	return nil
+}
+
+// Resolver resolves a conflict with the timestamp strategy and marks it
+// resolved by resolvedBy, as the sync conflict resolver does
+type Resolver func(ctx context.Context, conflictID uuid.UUID, resolvedBy string) error
+
+// Store reads conflicts and records escalation steps, as PostgresStore does
+type Store interface {
+	// ListDue returns up to limit unresolved conflicts that are due a step,
+	// oldest first
+	ListDue(ctx context.Context, due Due, limit int) ([]Conflict, error)
+	// Escalate raises the severity of an unresolved conflict and records the
+	// step. It returns false when the conflict was resolved or escalated since
+	// it was listed.
+	Escalate(ctx context.Context, conflict *Conflict, to string, at time.Time) (bool, error)
+	RecordStep(ctx context.Context, step *Step) error
+	// ListSteps returns the steps of a conflict, oldest first, or
+	// ErrConflictNotFound
+	ListSteps(ctx context.Context, conflictID uuid.UUID) ([]Step, error)
+	// RoleRecipients returns the email addresses of the active members of a role
+	RoleRecipients(ctx context.Context, role string) ([]string, error)
+}
+
+// Result is the outcome of an escalation run
+type Result struct {
+	StartedAt    time.Time `json:"started_at"`
+	CompletedAt  time.Time `json:"completed_at"`
+	Escalated    int       `json:"escalated"`
+	AutoResolved int       `json:"auto_resolved"`
+	Failed       int       `json:"failed"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// Service escalates and auto-resolves aging conflicts
+type Service struct {
+	store     Store
+	resolve   Resolver
+	notifier  Notifier
+	policy    Policy
+	batchSize int
+	now       func() time.Time
+
+	// running serialises runs, so a manual run never overlaps the periodic one
+	running sync.Mutex
+
+	mu      sync.Mutex
+	lastRun *Result
+}
+
+// NewService creates a new escalation service. The policy must have been
+// validated. Without a resolver, conflicts are never auto-resolved.
+func NewService(store Store, resolve Resolver, notifier Notifier, policy Policy, batchSize int) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	if resolve == nil {
+		policy.AutoResolveAfter = 0
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Service{store: store, resolve: resolve, notifier: notifier, policy: policy, batchSize: batchSize, now: time.Now}
+}
+
+// Steps returns the recorded escalation steps of a conflict
+func (s *Service) Steps(ctx context.Context, conflictID uuid.UUID) ([]Step, error) {
+	return s.store.ListSteps(ctx, conflictID)
+}
+
+// Check escalates the conflicts that aged past a severity threshold and
+// auto-resolves those past the auto-resolve age
+func (s *Service) Check(ctx context.Context) *Result {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	now := s.now()
+	result := &Result{StartedAt: now}
+	conflicts, err := s.store.ListDue(ctx, s.policy.Due(now), s.batchSize)
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("Failed to list sync conflicts due escalation: %v", err)
+	}
+
+	for i := range conflicts {
+		c := &conflicts[i]
+		age := now.Sub(c.CreatedAt)
+		if s.policy.AutoResolveAfter > 0 && age >= s.policy.AutoResolveAfter {
+			if s.autoResolve(ctx, c) {
+				result.AutoResolved++
+			} else {
+				result.Failed++
+			}
+			continue
+		}
+
+		to := s.policy.SeverityAt(age)
+		if rank(to) <= rank(c.Severity) {
+			continue
+		}
+		escalated, err := s.store.Escalate(ctx, c, to, now)
+		if err != nil {
+			result.Failed++
+			log.Printf("Failed to escalate sync conflict %s: %v", c.ID, err)
+			continue
+		}
+		if escalated {
+			result.Escalated++
+			c.Severity, c.EscalatedAt = to, &now
+			s.notify(ctx, KindEscalated, *c)
+		}
+	}
+	result.CompletedAt = s.now()
+
+	s.mu.Lock()
+	s.lastRun = result
+	s.mu.Unlock()
+	return result
+}
+
+// autoResolve resolves a conflict by timestamp and records the outcome
+func (s *Service) autoResolve(ctx context.Context, c *Conflict) bool {
+	step := &Step{ConflictID: c.ID, Action: ActionAutoResolved, FromSeverity: c.Severity}
+	err := s.resolve(ctx, c.ID, ResolvedBy)
+	if err != nil {
+		step.Action, step.Details = ActionAutoResolveFailed, err.Error()
+		log.Printf("Failed to auto-resolve sync conflict %s: %v", c.ID, err)
+	}
+	s.record(ctx, step)
+	if err == nil {
+		s.notify(ctx, KindAutoResolved, *c)
+	}
+	return err == nil
+}
+
+// notify tells the members of the role about a conflict and records the
+// delivery; failing to deliver does not undo the step notified about
+func (s *Service) notify(ctx context.Context, kind string, c Conflict) {
+	if s.policy.NotifyRole == "" {
+		return
+	}
+
+	step := &Step{ConflictID: c.ID, Action: ActionNotified, ToSeverity: c.Severity, Role: s.policy.NotifyRole}
+	recipients, err := s.store.RoleRecipients(ctx, s.policy.NotifyRole)
+	if err == nil {
+		sort.Strings(recipients)
+		step.Recipients = recipients
+		err = s.notifier.Notify(ctx, Notification{Kind: kind, Role: s.policy.NotifyRole, Recipients: recipients, Conflict: c})
+	}
+	if err != nil {
+		step.Action, step.Details = ActionNotifyFailed, err.Error()
+		log.Printf("Failed to notify role %s that sync conflict %s was %s: %v", s.policy.NotifyRole, c.ID, kind, err)
+	}
+	s.record(ctx, step)
+}
+
+// record records a step, logging failures
+func (s *Service) record(ctx context.Context, step *Step) {
+	if err := s.store.RecordStep(ctx, step); err != nil {
+		log.Printf("Failed to record %s step of sync conflict %s: %v", step.Action, step.ConflictID, err)
+	}
+}
+
+// LastRun returns the outcome of the last run, or nil if there was none yet
+func (s *Service) LastRun() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun
+}
+
+// Run escalates conflicts at the given interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx)
+		}
+	}
+}
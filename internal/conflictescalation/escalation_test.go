@@ -0,0 +1,188 @@
+package conflictescalation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps conflicts and steps in memory
+type memoryStore struct {
+	conflicts  []Conflict
+	steps      []Step
+	recipients []string
+	lastDue    Due
+}
+
+func (m *memoryStore) ListDue(ctx context.Context, due Due, limit int) ([]Conflict, error) {
+	m.lastDue = due
+	return append([]Conflict(nil), m.conflicts...), nil
+}
+
+func (m *memoryStore) Escalate(ctx context.Context, conflict *Conflict, to string, at time.Time) (bool, error) {
+	for i := range m.conflicts {
+		if m.conflicts[i].ID == conflict.ID && m.conflicts[i].Severity == conflict.Severity {
+			m.conflicts[i].Severity = to
+			m.steps = append(m.steps, Step{ConflictID: conflict.ID, Action: ActionEscalated, FromSeverity: conflict.Severity, ToSeverity: to})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryStore) RecordStep(ctx context.Context, step *Step) error {
+	m.steps = append(m.steps, *step)
+	return nil
+}
+
+func (m *memoryStore) ListSteps(ctx context.Context, conflictID uuid.UUID) ([]Step, error) {
+	return m.steps, nil
+}
+
+func (m *memoryStore) RoleRecipients(ctx context.Context, role string) ([]string, error) {
+	return m.recipients, nil
+}
+
+// recordingNotifier records notifications
+type recordingNotifier struct {
+	notifications []Notification
+	err           error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return n.err
+}
+
+var testPolicy = Policy{
+	EscalateAfter: map[string]time.Duration{
+		SeverityMedium:   24 * time.Hour,
+		SeverityHigh:     72 * time.Hour,
+		SeverityCritical: 120 * time.Hour,
+	},
+	NotifyRole:       "admin",
+	AutoResolveAfter: 240 * time.Hour,
+}
+
+func actions(steps []Step) []string {
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Action)
+	}
+	return names
+}
+
+func TestPolicyValidate(t *testing.T) {
+	require.NoError(t, testPolicy.Validate())
+	require.NoError(t, Policy{}.Validate())
+
+	assert.Error(t, Policy{EscalateAfter: map[string]time.Duration{SeverityLow: time.Hour}}.Validate())
+	assert.Error(t, Policy{EscalateAfter: map[string]time.Duration{"urgent": time.Hour}}.Validate())
+	assert.Error(t, Policy{EscalateAfter: map[string]time.Duration{SeverityMedium: 0}}.Validate())
+	assert.Error(t, Policy{EscalateAfter: map[string]time.Duration{SeverityMedium: 48 * time.Hour, SeverityHigh: 24 * time.Hour}}.Validate())
+	assert.Error(t, Policy{AutoResolveAfter: -time.Hour}.Validate())
+}
+
+func TestPolicySeverityAt(t *testing.T) {
+	assert.Equal(t, SeverityLow, testPolicy.SeverityAt(time.Hour))
+	assert.Equal(t, SeverityMedium, testPolicy.SeverityAt(24*time.Hour))
+	assert.Equal(t, SeverityHigh, testPolicy.SeverityAt(100*time.Hour))
+	assert.Equal(t, SeverityCritical, testPolicy.SeverityAt(500*time.Hour))
+
+	// Severities without a threshold are skipped
+	policy := Policy{EscalateAfter: map[string]time.Duration{SeverityCritical: time.Hour}}
+	assert.Equal(t, SeverityCritical, policy.SeverityAt(2*time.Hour))
+}
+
+func TestCheckEscalatesAndNotifies(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	fresh := Conflict{ID: uuid.New(), Severity: SeverityLow, CreatedAt: now.Add(-time.Hour)}
+	aged := Conflict{ID: uuid.New(), Severity: SeverityLow, CreatedAt: now.Add(-80 * time.Hour)}
+	already := Conflict{ID: uuid.New(), Severity: SeverityHigh, CreatedAt: now.Add(-80 * time.Hour)}
+	store := &memoryStore{conflicts: []Conflict{fresh, aged, already}, recipients: []string{"ops@example.com", "dba@example.com"}}
+	notifier := &recordingNotifier{}
+	service := NewService(store, nil, notifier, testPolicy, 0)
+	service.now = func() time.Time { return now }
+
+	result := service.Check(context.Background())
+	assert.Equal(t, 1, result.Escalated)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, result, service.LastRun())
+
+	// Aged past two thresholds, the conflict escalates straight to high
+	require.Equal(t, []string{ActionEscalated, ActionNotified}, actions(store.steps))
+	assert.Equal(t, SeverityLow, store.steps[0].FromSeverity)
+	assert.Equal(t, SeverityHigh, store.steps[0].ToSeverity)
+	assert.Equal(t, []string{"dba@example.com", "ops@example.com"}, store.steps[1].Recipients)
+	assert.Equal(t, "admin", store.steps[1].Role)
+
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, KindEscalated, notifier.notifications[0].Kind)
+	assert.Equal(t, aged.ID, notifier.notifications[0].Conflict.ID)
+	assert.Equal(t, SeverityHigh, notifier.notifications[0].Conflict.Severity)
+
+	// Without a resolver nothing is due auto-resolution
+	assert.Nil(t, store.lastDue.AutoResolve)
+}
+
+func TestCheckRecordsFailedNotifications(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{conflicts: []Conflict{{ID: uuid.New(), Severity: SeverityLow, CreatedAt: now.Add(-25 * time.Hour)}}}
+	service := NewService(store, nil, &recordingNotifier{err: errors.New("smtp unavailable")}, testPolicy, 0)
+	service.now = func() time.Time { return now }
+
+	service.Check(context.Background())
+	require.Equal(t, []string{ActionEscalated, ActionNotifyFailed}, actions(store.steps))
+	assert.Equal(t, "smtp unavailable", store.steps[1].Details)
+}
+
+func TestCheckAutoResolves(t *testing.T) {
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	stale := Conflict{ID: uuid.New(), Severity: SeverityCritical, CreatedAt: now.Add(-300 * time.Hour)}
+	failing := Conflict{ID: uuid.New(), Severity: SeverityCritical, CreatedAt: now.Add(-300 * time.Hour)}
+	store := &memoryStore{conflicts: []Conflict{stale, failing}}
+	notifier := &recordingNotifier{}
+
+	var resolved []uuid.UUID
+	resolve := func(ctx context.Context, conflictID uuid.UUID, resolvedBy string) error {
+		assert.Equal(t, ResolvedBy, resolvedBy)
+		if conflictID == failing.ID {
+			return errors.New("neo4j unavailable")
+		}
+		resolved = append(resolved, conflictID)
+		return nil
+	}
+	service := NewService(store, resolve, notifier, testPolicy, 0)
+	service.now = func() time.Time { return now }
+
+	result := service.Check(context.Background())
+	assert.Equal(t, 1, result.AutoResolved)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []uuid.UUID{stale.ID}, resolved)
+	require.NotNil(t, store.lastDue.AutoResolve)
+	assert.Equal(t, now.Add(-240*time.Hour), *store.lastDue.AutoResolve)
+
+	assert.Equal(t, []string{ActionAutoResolved, ActionNotified, ActionAutoResolveFailed}, actions(store.steps))
+	assert.Equal(t, "neo4j unavailable", store.steps[2].Details)
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, KindAutoResolved, notifier.notifications[0].Kind)
+}
+
+func TestCheckWithoutRoleDoesNotNotify(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{conflicts: []Conflict{{ID: uuid.New(), Severity: SeverityLow, CreatedAt: now.Add(-25 * time.Hour)}}}
+	notifier := &recordingNotifier{}
+	policy := testPolicy
+	policy.NotifyRole = ""
+	service := NewService(store, nil, notifier, policy, 0)
+	service.now = func() time.Time { return now }
+
+	service.Check(context.Background())
+	assert.Equal(t, []string{ActionEscalated}, actions(store.steps))
+	assert.Empty(t, notifier.notifications)
+}
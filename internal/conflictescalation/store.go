@@ -0,0 +1,163 @@
+package conflictescalation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PostgresStore reads conflicts from the sync_conflicts table and records
+// steps in the sync_conflict_escalations table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed escalation store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ListDue returns the unresolved conflicts created before the auto-resolve
+// cutoff or before the cutoff of a severity above their own
+func (s *PostgresStore) ListDue(ctx context.Context, due Due, limit int) ([]Conflict, error) {
+	// Cutoffs are passed as text, which the array cast parses
+	var severities, cutoffs []string
+	for severity, cutoff := range due.Escalate {
+		severities = append(severities, severity)
+		cutoffs = append(cutoffs, cutoff.Format(time.RFC3339Nano))
+	}
+
+	var conflicts []Conflict
+	err := s.db.SelectContext(ctx, &conflicts, `
+		SELECT c.id, c.entity_type, c.entity_id, c.conflict_type, c.severity, c.escalated_at, c.created_at
+		FROM sync_conflicts c
+		WHERE c.resolved = false
+		  AND (c.created_at < $1
+		       OR EXISTS (
+		           SELECT 1 FROM unnest($2::text[], $3::timestamptz[]) AS due(severity, cutoff)
+		           WHERE c.created_at < due.cutoff
+		             AND array_position($4::text[], due.severity::text) > array_position($4::text[], c.severity::text)))
+		ORDER BY c.created_at
+		LIMIT $5`,
+		due.AutoResolve, pq.Array(severities), pq.Array(cutoffs), pq.Array(Severities), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync conflicts due escalation: %w", err)
+	}
+	return conflicts, nil
+}
+
+// Escalate raises the severity of a conflict that is still unresolved at the
+// severity it was listed with, and records the step in the same transaction
+func (s *PostgresStore) Escalate(ctx context.Context, conflict *Conflict, to string, at time.Time) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE sync_conflicts
+		SET severity = $2, escalated_at = $3, updated_at = $3
+		WHERE id = $1 AND resolved = false AND severity = $4`,
+		conflict.ID, to, at, conflict.Severity)
+	if err != nil {
+		return false, fmt.Errorf("failed to escalate sync conflict: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sync_conflict_escalations (id, conflict_id, action, from_severity, to_severity, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		uuid.New(), conflict.ID, ActionEscalated, conflict.Severity, to, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to record escalation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit escalation: %w", err)
+	}
+	return true, nil
+}
+
+// RecordStep records a step of a conflict
+func (s *PostgresStore) RecordStep(ctx context.Context, step *Step) error {
+	if step.ID == uuid.Nil {
+		step.ID = uuid.New()
+	}
+	if step.CreatedAt.IsZero() {
+		step.CreatedAt = time.Now()
+	}
+	if step.Recipients == nil {
+		step.Recipients = []string{}
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sync_conflict_escalations (id, conflict_id, action, from_severity, to_severity, role, recipients, details, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9)`,
+		step.ID, step.ConflictID, step.Action, step.FromSeverity, step.ToSeverity, step.Role,
+		pq.Array(step.Recipients), step.Details, step.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record escalation step: %w", err)
+	}
+	return nil
+}
+
+// ListSteps returns the steps of a conflict, oldest first
+func (s *PostgresStore) ListSteps(ctx context.Context, conflictID uuid.UUID) ([]Step, error) {
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM sync_conflicts WHERE id = $1)`, conflictID); err != nil {
+		return nil, fmt.Errorf("failed to get sync conflict: %w", err)
+	}
+	if !exists {
+		return nil, ErrConflictNotFound
+	}
+
+	rows, err := s.db.QueryxContext(ctx, `
+		SELECT id, conflict_id, action, COALESCE(from_severity, '') AS from_severity,
+		       COALESCE(to_severity, '') AS to_severity, COALESCE(role, '') AS role,
+		       recipients, details, created_at
+		FROM sync_conflict_escalations
+		WHERE conflict_id = $1
+		ORDER BY created_at, id`, conflictID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escalation steps: %w", err)
+	}
+	defer rows.Close()
+
+	steps := []Step{}
+	for rows.Next() {
+		var step Step
+		var recipients pq.StringArray
+		if err := rows.Scan(&step.ID, &step.ConflictID, &step.Action, &step.FromSeverity, &step.ToSeverity,
+			&step.Role, &recipients, &step.Details, &step.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan escalation step: %w", err)
+		}
+		step.Recipients = []string(recipients)
+		steps = append(steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list escalation steps: %w", err)
+	}
+	return steps, nil
+}
+
+// RoleRecipients returns the email addresses of the active users with a role
+func (s *PostgresStore) RoleRecipients(ctx context.Context, role string) ([]string, error) {
+	var recipients []string
+	err := s.db.SelectContext(ctx, &recipients, `
+		SELECT u.email
+		FROM users u
+		JOIN user_roles ur ON ur.user_id = u.id
+		JOIN roles r ON r.id = ur.role_id
+		WHERE r.name = $1 AND u.is_active = true
+		ORDER BY u.email`, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members of role %s: %w", role, err)
+	}
+	return recipients, nil
+}
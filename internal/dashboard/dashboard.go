@@ -0,0 +1,185 @@
+// Package dashboard stores each user's home dashboard server-side: pinned
+// saved searches, watched CIs, favourite reports and the widgets laying them
+// out, so a dashboard follows its user across devices. Every change bumps the
+// version, so two devices editing at once cannot silently overwrite each other.
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Widget kinds
+const (
+	// WidgetSavedSearch shows the results of the saved search named by Ref
+	WidgetSavedSearch = "saved_search"
+	// WidgetWatchedCIs shows the watched CIs
+	WidgetWatchedCIs = "watched_cis"
+	// WidgetReport shows the favourite report named by Ref
+	WidgetReport = "report"
+)
+
+// Limits on what a dashboard holds
+const (
+	MaxSavedSearches   = 50
+	MaxWatchedCIs      = 200
+	MaxFavoriteReports = 50
+	MaxWidgets         = 30
+	MaxNameLength      = 100
+)
+
+var (
+	ErrInvalidPreferences = errors.New("invalid dashboard preferences")
+	ErrNotFound           = errors.New("dashboard item not found")
+	ErrVersionConflict    = errors.New("dashboard was changed by another session")
+)
+
+// Preferences is a user's dashboard
+type Preferences struct {
+	UserID          string           `json:"user_id"`
+	SavedSearches   []SavedSearch    `json:"saved_searches"`
+	WatchedCIs      []WatchedCI      `json:"watched_cis"`
+	FavoriteReports []FavoriteReport `json:"favorite_reports"`
+	Widgets         []Widget         `json:"widgets"`
+	// Version increases with every change; a replace must carry the version it read
+	Version   int        `json:"version"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// SavedSearch is a named CI list query, e.g. {"type": "server", "status": "active"}
+type SavedSearch struct {
+	ID        uuid.UUID         `json:"id"`
+	Name      string            `json:"name"`
+	Query     map[string]string `json:"query"`
+	Pinned    bool              `json:"pinned"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// WatchedCI is a CI the user keeps an eye on
+type WatchedCI struct {
+	CIID    uuid.UUID `json:"ci_id"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// FavoriteReport is a report the user runs often, with the parameters to run it with
+type FavoriteReport struct {
+	Report  string            `json:"report"`
+	Params  map[string]string `json:"params,omitempty"`
+	AddedAt time.Time         `json:"added_at"`
+}
+
+// Widget places a saved search, the watched CIs or a report on the dashboard
+type Widget struct {
+	ID    uuid.UUID `json:"id"`
+	Kind  string    `json:"kind"`
+	Ref   string    `json:"ref,omitempty"`
+	Title string    `json:"title,omitempty"`
+	// Position orders the widgets; Width and Height are in grid cells
+	Position int `json:"position"`
+	Width    int `json:"width"`
+	Height   int `json:"height"`
+}
+
+// empty returns the dashboard of a user who has not customised it yet
+func empty(userID string) *Preferences {
+	return &Preferences{
+		UserID:          userID,
+		SavedSearches:   []SavedSearch{},
+		WatchedCIs:      []WatchedCI{},
+		FavoriteReports: []FavoriteReport{},
+		Widgets:         []Widget{},
+	}
+}
+
+// normalize replaces nil lists with empty ones, so the JSON is stable
+func (p *Preferences) normalize() {
+	if p.SavedSearches == nil {
+		p.SavedSearches = []SavedSearch{}
+	}
+	if p.WatchedCIs == nil {
+		p.WatchedCIs = []WatchedCI{}
+	}
+	if p.FavoriteReports == nil {
+		p.FavoriteReports = []FavoriteReport{}
+	}
+	if p.Widgets == nil {
+		p.Widgets = []Widget{}
+	}
+}
+
+// Validate checks the limits and that every widget refers to something on the dashboard
+func (p *Preferences) Validate() error {
+	if len(p.SavedSearches) > MaxSavedSearches {
+		return fmt.Errorf("%w: at most %d saved searches", ErrInvalidPreferences, MaxSavedSearches)
+	}
+	if len(p.WatchedCIs) > MaxWatchedCIs {
+		return fmt.Errorf("%w: at most %d watched CIs", ErrInvalidPreferences, MaxWatchedCIs)
+	}
+	if len(p.FavoriteReports) > MaxFavoriteReports {
+		return fmt.Errorf("%w: at most %d favorite reports", ErrInvalidPreferences, MaxFavoriteReports)
+	}
+	if len(p.Widgets) > MaxWidgets {
+		return fmt.Errorf("%w: at most %d widgets", ErrInvalidPreferences, MaxWidgets)
+	}
+
+	searches := map[string]bool{}
+	for _, search := range p.SavedSearches {
+		if search.Name == "" || len(search.Name) > MaxNameLength {
+			return fmt.Errorf("%w: saved search names must be 1 to %d characters", ErrInvalidPreferences, MaxNameLength)
+		}
+		if searches[search.ID.String()] {
+			return fmt.Errorf("%w: duplicate saved search %s", ErrInvalidPreferences, search.ID)
+		}
+		searches[search.ID.String()] = true
+	}
+
+	watched := map[uuid.UUID]bool{}
+	for _, ci := range p.WatchedCIs {
+		if ci.CIID == uuid.Nil || watched[ci.CIID] {
+			return fmt.Errorf("%w: watched CIs must be distinct CI IDs", ErrInvalidPreferences)
+		}
+		watched[ci.CIID] = true
+	}
+
+	reports := map[string]bool{}
+	for _, report := range p.FavoriteReports {
+		if report.Report == "" || len(report.Report) > MaxNameLength || reports[report.Report] {
+			return fmt.Errorf("%w: favorite reports must be distinct names of 1 to %d characters", ErrInvalidPreferences, MaxNameLength)
+		}
+		reports[report.Report] = true
+	}
+
+	for _, widget := range p.Widgets {
+		switch widget.Kind {
+		case WidgetSavedSearch:
+			if !searches[widget.Ref] {
+				return fmt.Errorf("%w: widget refers to unknown saved search %q", ErrInvalidPreferences, widget.Ref)
+			}
+		case WidgetReport:
+			if !reports[widget.Ref] {
+				return fmt.Errorf("%w: widget refers to report %q, which is not a favorite", ErrInvalidPreferences, widget.Ref)
+			}
+		case WidgetWatchedCIs:
+		default:
+			return fmt.Errorf("%w: unknown widget kind %q", ErrInvalidPreferences, widget.Kind)
+		}
+		if widget.Width < 0 || widget.Height < 0 || len(widget.Title) > MaxNameLength {
+			return fmt.Errorf("%w: invalid widget size or title", ErrInvalidPreferences)
+		}
+	}
+	return nil
+}
+
+// removeWidgets drops the widgets of a kind referring to ref
+func (p *Preferences) removeWidgets(kind, ref string) {
+	widgets := p.Widgets[:0]
+	for _, widget := range p.Widgets {
+		if widget.Kind != kind || widget.Ref != ref {
+			widgets = append(widgets, widget)
+		}
+	}
+	p.Widgets = widgets
+}
@@ -0,0 +1,188 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps dashboards as JSON, like the Postgres store, so callers
+// never share a dashboard with the store
+type memoryStore struct {
+	dashboards map[string][]byte
+	// conflicts is the number of saves to fail as if another session won the race
+	conflicts int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{dashboards: map[string][]byte{}}
+}
+
+func (m *memoryStore) Get(ctx context.Context, userID string) (*Preferences, error) {
+	data, ok := m.dashboards[userID]
+	if !ok {
+		return nil, nil
+	}
+	var prefs Preferences
+	err := json.Unmarshal(data, &prefs)
+	return &prefs, err
+}
+
+func (m *memoryStore) Save(ctx context.Context, prefs *Preferences) error {
+	if m.conflicts > 0 {
+		m.conflicts--
+		return ErrVersionConflict
+	}
+	current, _ := m.Get(ctx, prefs.UserID)
+	if (current == nil && prefs.Version != 1) || (current != nil && current.Version != prefs.Version-1) {
+		return ErrVersionConflict
+	}
+	data, err := json.Marshal(prefs)
+	m.dashboards[prefs.UserID] = data
+	return err
+}
+
+func (m *memoryStore) Delete(ctx context.Context, userID string) error {
+	delete(m.dashboards, userID)
+	return nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store) *Service {
+	service := NewService(store)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestGetEmptyDashboard(t *testing.T) {
+	service := newTestService(newMemoryStore())
+
+	prefs, err := service.Get(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 0, prefs.Version)
+	assert.Empty(t, prefs.SavedSearches)
+	assert.NotNil(t, prefs.Widgets)
+}
+
+func TestSavedSearchLifecycle(t *testing.T) {
+	store := newMemoryStore()
+	service := newTestService(store)
+	ctx := context.Background()
+
+	search, err := service.AddSavedSearch(ctx, "alice", SavedSearch{Name: "Prod servers", Query: map[string]string{"type": "server"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, search.ID)
+
+	// Pin it as a widget
+	prefs, err := service.Get(ctx, "alice")
+	require.NoError(t, err)
+	prefs.Widgets = []Widget{{Kind: WidgetSavedSearch, Ref: search.ID.String(), Width: 2, Height: 1}}
+	prefs, err = service.Replace(ctx, "alice", prefs)
+	require.NoError(t, err)
+	assert.Equal(t, 2, prefs.Version)
+
+	updated, err := service.UpdateSavedSearch(ctx, "alice", search.ID, SavedSearch{Name: "All servers", Pinned: true})
+	require.NoError(t, err)
+	assert.Equal(t, "All servers", updated.Name)
+	assert.Equal(t, map[string]string{}, updated.Query)
+
+	// Deleting the search drops its widget
+	require.NoError(t, service.DeleteSavedSearch(ctx, "alice", search.ID))
+	prefs, err = service.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, prefs.SavedSearches)
+	assert.Empty(t, prefs.Widgets)
+
+	assert.ErrorIs(t, service.DeleteSavedSearch(ctx, "alice", search.ID), ErrNotFound)
+}
+
+func TestWatchedCIsAndFavoriteReports(t *testing.T) {
+	service := newTestService(newMemoryStore())
+	ctx := context.Background()
+	ciID := uuid.New()
+
+	_, err := service.WatchCI(ctx, "alice", ciID)
+	require.NoError(t, err)
+	prefs, err := service.WatchCI(ctx, "alice", ciID)
+	require.NoError(t, err)
+	assert.Len(t, prefs.WatchedCIs, 1)
+
+	_, err = service.FavoriteReport(ctx, "alice", FavoriteReport{Report: "stale-cis"})
+	require.NoError(t, err)
+	prefs, err = service.FavoriteReport(ctx, "alice", FavoriteReport{Report: "stale-cis", Params: map[string]string{"days": "30"}})
+	require.NoError(t, err)
+	require.Len(t, prefs.FavoriteReports, 1)
+	assert.Equal(t, "30", prefs.FavoriteReports[0].Params["days"])
+
+	prefs, err = service.UnwatchCI(ctx, "alice", ciID)
+	require.NoError(t, err)
+	assert.Empty(t, prefs.WatchedCIs)
+	_, err = service.UnwatchCI(ctx, "alice", ciID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Other users' dashboards are untouched
+	other, err := service.Get(ctx, "bob")
+	require.NoError(t, err)
+	assert.Empty(t, other.FavoriteReports)
+}
+
+func TestReplaceRejectsStaleVersion(t *testing.T) {
+	service := newTestService(newMemoryStore())
+	ctx := context.Background()
+
+	laptop, err := service.Get(ctx, "alice")
+	require.NoError(t, err)
+	phone, err := service.Get(ctx, "alice")
+	require.NoError(t, err)
+
+	_, err = service.Replace(ctx, "alice", laptop)
+	require.NoError(t, err)
+	_, err = service.Replace(ctx, "alice", phone)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Equal(t, 0, phone.Version)
+}
+
+func TestUpdateRetriesOnConflict(t *testing.T) {
+	store := newMemoryStore()
+	store.conflicts = 2
+	service := newTestService(store)
+
+	prefs, err := service.WatchCI(context.Background(), "alice", uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, prefs.Version)
+
+	store.conflicts = maxRetries + 1
+	_, err = service.WatchCI(context.Background(), "alice", uuid.New())
+	assert.ErrorIs(t, err, ErrVersionConflict)
+}
+
+func TestValidate(t *testing.T) {
+	search := SavedSearch{ID: uuid.New(), Name: "Servers"}
+	valid := &Preferences{
+		SavedSearches:   []SavedSearch{search},
+		FavoriteReports: []FavoriteReport{{Report: "chargeback"}},
+		Widgets: []Widget{
+			{Kind: WidgetSavedSearch, Ref: search.ID.String()},
+			{Kind: WidgetReport, Ref: "chargeback"},
+			{Kind: WidgetWatchedCIs},
+		},
+	}
+	assert.NoError(t, valid.Validate())
+
+	for name, prefs := range map[string]*Preferences{
+		"unknown search":  {Widgets: []Widget{{Kind: WidgetSavedSearch, Ref: uuid.New().String()}}},
+		"not a favorite":  {Widgets: []Widget{{Kind: WidgetReport, Ref: "chargeback"}}},
+		"unknown kind":    {Widgets: []Widget{{Kind: "clock"}}},
+		"unnamed search":  {SavedSearches: []SavedSearch{{ID: uuid.New()}}},
+		"duplicate watch": {WatchedCIs: []WatchedCI{{CIID: search.ID}, {CIID: search.ID}}},
+		"too many":        {Widgets: make([]Widget, MaxWidgets+1)},
+	} {
+		assert.ErrorIs(t, prefs.Validate(), ErrInvalidPreferences, name)
+	}
+}
@@ -0,0 +1,216 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxRetries bounds how often a single change is retried after losing a
+// version race to another session of the same user
+const maxRetries = 3
+
+// Service reads and changes users' dashboards
+type Service struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewService creates a new dashboard service
+func NewService(store Store) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// Get retrieves a user's dashboard, empty at version 0 if not customised yet
+func (s *Service) Get(ctx context.Context, userID string) (*Preferences, error) {
+	prefs, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return empty(userID), nil
+	}
+	prefs.normalize()
+	return prefs, nil
+}
+
+// Replace saves a whole dashboard, e.g. after rearranging its widgets. It fails
+// with ErrVersionConflict unless prefs carries the version currently stored.
+func (s *Service) Replace(ctx context.Context, userID string, prefs *Preferences) (*Preferences, error) {
+	prefs.UserID = userID
+	prefs.normalize()
+	for i := range prefs.SavedSearches {
+		if prefs.SavedSearches[i].ID == uuid.Nil {
+			prefs.SavedSearches[i].ID = uuid.New()
+		}
+	}
+	for i := range prefs.Widgets {
+		if prefs.Widgets[i].ID == uuid.Nil {
+			prefs.Widgets[i].ID = uuid.New()
+		}
+	}
+	if err := prefs.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// Reset deletes a user's dashboard, returning it to the empty one
+func (s *Service) Reset(ctx context.Context, userID string) error {
+	return s.store.Delete(ctx, userID)
+}
+
+// AddSavedSearch saves a search on the dashboard
+func (s *Service) AddSavedSearch(ctx context.Context, userID string, search SavedSearch) (*SavedSearch, error) {
+	search.ID = uuid.New()
+	search.CreatedAt = s.now()
+	if search.Query == nil {
+		search.Query = map[string]string{}
+	}
+	_, err := s.update(ctx, userID, func(prefs *Preferences) error {
+		prefs.SavedSearches = append(prefs.SavedSearches, search)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// UpdateSavedSearch renames, re-queries or (un)pins a saved search
+func (s *Service) UpdateSavedSearch(ctx context.Context, userID string, id uuid.UUID, search SavedSearch) (*SavedSearch, error) {
+	var updated SavedSearch
+	_, err := s.update(ctx, userID, func(prefs *Preferences) error {
+		for i := range prefs.SavedSearches {
+			if prefs.SavedSearches[i].ID == id {
+				prefs.SavedSearches[i].Name = search.Name
+				prefs.SavedSearches[i].Query = search.Query
+				prefs.SavedSearches[i].Pinned = search.Pinned
+				if prefs.SavedSearches[i].Query == nil {
+					prefs.SavedSearches[i].Query = map[string]string{}
+				}
+				updated = prefs.SavedSearches[i]
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: saved search %s", ErrNotFound, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteSavedSearch removes a saved search and the widgets showing it
+func (s *Service) DeleteSavedSearch(ctx context.Context, userID string, id uuid.UUID) error {
+	_, err := s.update(ctx, userID, func(prefs *Preferences) error {
+		for i, search := range prefs.SavedSearches {
+			if search.ID == id {
+				prefs.SavedSearches = append(prefs.SavedSearches[:i], prefs.SavedSearches[i+1:]...)
+				prefs.removeWidgets(WidgetSavedSearch, id.String())
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: saved search %s", ErrNotFound, id)
+	})
+	return err
+}
+
+// WatchCI adds a CI to the watched CIs; watching it again changes nothing
+func (s *Service) WatchCI(ctx context.Context, userID string, ciID uuid.UUID) (*Preferences, error) {
+	return s.update(ctx, userID, func(prefs *Preferences) error {
+		for _, watched := range prefs.WatchedCIs {
+			if watched.CIID == ciID {
+				return nil
+			}
+		}
+		prefs.WatchedCIs = append(prefs.WatchedCIs, WatchedCI{CIID: ciID, AddedAt: s.now()})
+		return nil
+	})
+}
+
+// UnwatchCI removes a CI from the watched CIs
+func (s *Service) UnwatchCI(ctx context.Context, userID string, ciID uuid.UUID) (*Preferences, error) {
+	return s.update(ctx, userID, func(prefs *Preferences) error {
+		for i, watched := range prefs.WatchedCIs {
+			if watched.CIID == ciID {
+				prefs.WatchedCIs = append(prefs.WatchedCIs[:i], prefs.WatchedCIs[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: CI %s is not watched", ErrNotFound, ciID)
+	})
+}
+
+// FavoriteReport adds a report to the favourites, or updates its parameters
+func (s *Service) FavoriteReport(ctx context.Context, userID string, report FavoriteReport) (*Preferences, error) {
+	return s.update(ctx, userID, func(prefs *Preferences) error {
+		for i := range prefs.FavoriteReports {
+			if prefs.FavoriteReports[i].Report == report.Report {
+				prefs.FavoriteReports[i].Params = report.Params
+				return nil
+			}
+		}
+		report.AddedAt = s.now()
+		prefs.FavoriteReports = append(prefs.FavoriteReports, report)
+		return nil
+	})
+}
+
+// UnfavoriteReport removes a report from the favourites and the widgets showing it
+func (s *Service) UnfavoriteReport(ctx context.Context, userID, report string) (*Preferences, error) {
+	return s.update(ctx, userID, func(prefs *Preferences) error {
+		for i, favorite := range prefs.FavoriteReports {
+			if favorite.Report == report {
+				prefs.FavoriteReports = append(prefs.FavoriteReports[:i], prefs.FavoriteReports[i+1:]...)
+				prefs.removeWidgets(WidgetReport, report)
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: report %s is not a favorite", ErrNotFound, report)
+	})
+}
+
+// update applies a change to the current dashboard and saves it, retrying on
+// top of the newer version when another session saved in the meantime
+func (s *Service) update(ctx context.Context, userID string, change func(prefs *Preferences) error) (*Preferences, error) {
+	for attempt := 0; ; attempt++ {
+		prefs, err := s.Get(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := change(prefs); err != nil {
+			return nil, err
+		}
+		if err := prefs.Validate(); err != nil {
+			return nil, err
+		}
+
+		err = s.save(ctx, prefs)
+		if errors.Is(err, ErrVersionConflict) && attempt < maxRetries {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return prefs, nil
+	}
+}
+
+// save stores a dashboard as the version after the one it was read at
+func (s *Service) save(ctx context.Context, prefs *Preferences) error {
+	updatedAt := s.now()
+	prefs.Version++
+	prefs.UpdatedAt = &updatedAt
+	if err := s.store.Save(ctx, prefs); err != nil {
+		prefs.Version--
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package dashboard
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Store persists dashboards
+type Store interface {
+	// Get retrieves a user's dashboard, or nil if the user has none yet
+	Get(ctx context.Context, userID string) (*Preferences, error)
+	// Save stores a dashboard at its version, failing with ErrVersionConflict
+	// unless the stored one is at the version before
+	Save(ctx context.Context, prefs *Preferences) error
+	Delete(ctx context.Context, userID string) error
+}
+
+// PostgresStore keeps dashboards in the user_dashboards table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed dashboard store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Get retrieves a user's dashboard
+func (s *PostgresStore) Get(ctx context.Context, userID string) (*Preferences, error) {
+	var data []byte
+	var version int
+	var updatedAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT preferences, version, updated_at FROM user_dashboards WHERE user_id = $1`, userID).
+		Scan(&data, &version, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get dashboard: %w", err)
+	}
+
+	var prefs Preferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dashboard: %w", err)
+	}
+	prefs.UserID = userID
+	prefs.Version = version
+	prefs.UpdatedAt = &updatedAt
+	return &prefs, nil
+}
+
+// Save inserts the first version of a dashboard or updates the one before
+func (s *PostgresStore) Save(ctx context.Context, prefs *Preferences) error {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dashboard: %w", err)
+	}
+
+	var result sql.Result
+	if prefs.Version == 1 {
+		result, err = s.db.ExecContext(ctx, `
+			INSERT INTO user_dashboards (user_id, preferences, version, updated_at)
+			VALUES ($1, $2, 1, $3)
+			ON CONFLICT (user_id) DO NOTHING`, prefs.UserID, data, prefs.UpdatedAt)
+	} else {
+		result, err = s.db.ExecContext(ctx, `
+			UPDATE user_dashboards SET preferences = $2, version = $3, updated_at = $4
+			WHERE user_id = $1 AND version = $3 - 1`, prefs.UserID, data, prefs.Version, prefs.UpdatedAt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save dashboard: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Delete removes a user's dashboard
+func (s *PostgresStore) Delete(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM user_dashboards WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete dashboard: %w", err)
+	}
+	return nil
+}
@@ -6,17 +6,27 @@ import (
 	"time"
 
 	"connect/internal/config"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/redis/go-redis/v9"
 )
 
-// NewPostgresConnection creates a new PostgreSQL connection pool
-func NewPostgresConnection(cfg *config.Config) (*pgxpool.Pool, error) {
+// NewPostgresConnection creates a new PostgreSQL connection pool. An optional
+// tracer, e.g. the slow query logger of the logtuning package, sees every query.
+func NewPostgresConnection(cfg *config.Config, tracer ...pgx.QueryTracer) (*pgxpool.Pool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, cfg.GetPostgreSQLConnectionString())
+	poolConfig, err := pgxpool.ParseConfig(cfg.GetPostgreSQLConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PostgreSQL connection string: %w", err)
+	}
+	if len(tracer) > 0 {
+		poolConfig.ConnConfig.Tracer = tracer[0]
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create PostgreSQL connection pool: %w", err)
 	}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"connect/internal/config"
+	"connect/internal/testfixtures"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -25,7 +26,7 @@ func TestNewPostgresConnection_Success(t *testing.T) {
 		postgres.WithDatabase("testdb"),
 		postgres.WithUsername("testuser"),
 		postgres.WithPassword("testpass"),
-		postgres.WithInitScripts("migrations/001_initial_schema.sql"),
+		postgres.WithInitScripts(testfixtures.InitScripts(t, 1)...),
 	)
 	require.NoError(t, err)
 	defer pgContainer.Terminate(ctx)
@@ -0,0 +1,107 @@
+// Package edgecleanup deactivates the relationships left pointing at CIs that
+// were soft-deleted after the relationships were created. New relationships to
+// deleted or inactive CIs are rejected when written; this job catches the
+// ones that became orphaned later, so traversals stop following them.
+package edgecleanup
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultBatchSize is the number of relationships deactivated per statement
+const DefaultBatchSize = 1000
+
+// Store deactivates orphaned relationships, as CIRepository does
+type Store interface {
+	// DeactivateOrphanedRelationships deactivates up to limit active
+	// relationships with a deleted endpoint and returns how many it deactivated
+	DeactivateOrphanedRelationships(ctx context.Context, limit int) (int64, error)
+}
+
+// Result is the outcome of a cleanup run
+type Result struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Deactivated int64     `json:"deactivated"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Service runs the cleanup and remembers the last run
+type Service struct {
+	store     Store
+	batchSize int
+	now       func() time.Time
+
+	// running serialises runs, so a manual run never overlaps the periodic one
+	running sync.Mutex
+
+	mu      sync.Mutex
+	lastRun *Result
+}
+
+// NewService creates a new relationship cleanup service
+func NewService(store Store, batchSize int) *Service {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Service{
+		store:     store,
+		batchSize: batchSize,
+		now:       time.Now,
+	}
+}
+
+// Cleanup deactivates every orphaned relationship, a batch at a time
+func (s *Service) Cleanup(ctx context.Context) *Result {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	result := &Result{StartedAt: s.now()}
+	for {
+		deactivated, err := s.store.DeactivateOrphanedRelationships(ctx, s.batchSize)
+		result.Deactivated += deactivated
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("Failed to deactivate orphaned relationships: %v", err)
+			break
+		}
+		if deactivated < int64(s.batchSize) {
+			break
+		}
+	}
+	result.CompletedAt = s.now()
+
+	if result.Deactivated > 0 {
+		log.Printf("Deactivated %d relationships pointing at deleted CIs", result.Deactivated)
+	}
+
+	s.mu.Lock()
+	s.lastRun = result
+	s.mu.Unlock()
+	return result
+}
+
+// LastRun returns the outcome of the last run, or nil if there was none yet
+func (s *Service) LastRun() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun
+}
+
+// Run cleans up at the given interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Cleanup(ctx)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package edgecleanup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore deactivates from a count of orphaned relationships
+type memoryStore struct {
+	orphaned int64
+	calls    int
+	failAt   int
+}
+
+func (m *memoryStore) DeactivateOrphanedRelationships(ctx context.Context, limit int) (int64, error) {
+	m.calls++
+	if m.calls == m.failAt {
+		return 0, errors.New("connection refused")
+	}
+	deactivated := m.orphaned
+	if deactivated > int64(limit) {
+		deactivated = int64(limit)
+	}
+	m.orphaned -= deactivated
+	return deactivated, nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store, batchSize int) *Service {
+	service := NewService(store, batchSize)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestCleanupWorksInBatches(t *testing.T) {
+	store := &memoryStore{orphaned: 25}
+	service := newTestService(store, 10)
+	assert.Nil(t, service.LastRun())
+
+	result := service.Cleanup(context.Background())
+	assert.Equal(t, int64(25), result.Deactivated)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, 3, store.calls)
+	assert.Equal(t, now, result.StartedAt)
+	assert.Same(t, result, service.LastRun())
+}
+
+func TestCleanupFullLastBatchChecksOnce(t *testing.T) {
+	store := &memoryStore{orphaned: 20}
+	service := newTestService(store, 10)
+
+	result := service.Cleanup(context.Background())
+	assert.Equal(t, int64(20), result.Deactivated)
+	assert.Equal(t, 3, store.calls)
+}
+
+func TestCleanupStopsOnError(t *testing.T) {
+	store := &memoryStore{orphaned: 25, failAt: 2}
+	service := newTestService(store, 10)
+
+	result := service.Cleanup(context.Background())
+	assert.Equal(t, int64(10), result.Deactivated)
+	assert.Equal(t, "connection refused", result.Error)
+
+	// The next run picks up what is left
+	result = service.Cleanup(context.Background())
+	require.Empty(t, result.Error)
+	assert.Equal(t, int64(15), result.Deactivated)
+}
@@ -0,0 +1,70 @@
+package eventfilter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expression is a compiled expression producing any value. Unlike a Filter,
+// its variables are exactly the ones given to Eval.
+type Expression struct {
+	source string
+	root   node
+}
+
+// CompileExpression parses an expression
+func CompileExpression(expression string) (*Expression, error) {
+	expression = strings.TrimSpace(expression)
+	if err := checkExpression(expression); err != nil {
+		return nil, err
+	}
+
+	root, err := parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	return &Expression{source: expression, root: root}, nil
+}
+
+// CompilePrefix parses the expression source starts with and returns the
+// source following it, for languages that embed expressions in statements
+// such as "log <expression> if <expression>"
+func CompilePrefix(source string) (*Expression, string, error) {
+	source = strings.TrimSpace(source)
+	if err := checkExpression(source); err != nil {
+		return nil, "", err
+	}
+
+	root, rest, err := parsePrefix(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid expression: %w", err)
+	}
+	expression := strings.TrimSpace(source[:len(source)-len(rest)])
+	return &Expression{source: expression, root: root}, rest, nil
+}
+
+func checkExpression(expression string) error {
+	if expression == "" {
+		return fmt.Errorf("expression is empty")
+	}
+	if len(expression) > MaxExpressionLength {
+		return fmt.Errorf("expression exceeds %d characters", MaxExpressionLength)
+	}
+	return nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against the variables
+func (e *Expression) Eval(vars map[string]interface{}) (interface{}, error) {
+	return eval(e.root, vars)
+}
+
+// Test evaluates the expression as a condition. It must produce a boolean or
+// null, which counts as false.
+func (e *Expression) Test(vars map[string]interface{}) (bool, error) {
+	return test(e.root, vars)
+}
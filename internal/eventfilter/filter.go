@@ -0,0 +1,448 @@
+// Package eventfilter evaluates subscription filters against event payloads.
+//
+// Filters use a subset of CEL: field access (event.data.criticality, tags[0]),
+// literals, ==, !=, <, <=, >, >=, in, &&, ||, !, + - * / %, parentheses, list
+// literals, has() and a fixed set of functions (size, lower, upper, trim,
+// startsWith, endsWith, contains, matches, replace, split, string, number and
+// keys) callable as f(x, y) or as the method x.f(y). For example:
+//
+//	event.data.criticality == "critical" && "payment" in event.data.tags
+//
+// Top level payload fields are variables, and the whole payload is also bound
+// to "event". Missing fields evaluate to null instead of failing, so a filter
+// written for one event type simply does not match events of another type.
+//
+// The same language computes values through Expression, which script hooks
+// use for their statements and conditions.
+package eventfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MaxExpressionLength bounds the size of a filter expression
+const MaxExpressionLength = 4096
+
+// Filter is a compiled filter expression
+type Filter struct {
+	source string
+	root   node
+}
+
+// Compile parses a filter expression
+func Compile(expression string) (*Filter, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, fmt.Errorf("filter expression is empty")
+	}
+	if len(expression) > MaxExpressionLength {
+		return nil, fmt.Errorf("filter expression exceeds %d characters", MaxExpressionLength)
+	}
+
+	root, err := parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression: %w", err)
+	}
+
+	return &Filter{source: expression, root: root}, nil
+}
+
+// String returns the source of the filter
+func (f *Filter) String() string {
+	return f.source
+}
+
+// Match evaluates the filter against a payload. The expression must produce a boolean.
+func (f *Filter) Match(payload map[string]interface{}) (bool, error) {
+	env := make(map[string]interface{}, len(payload)+1)
+	for key, value := range payload {
+		env[key] = value
+	}
+	env["event"] = payload
+
+	return test(f.root, env)
+}
+
+// test evaluates a condition; null counts as false
+func test(root node, env map[string]interface{}) (bool, error) {
+	result, err := eval(root, env)
+	if err != nil {
+		return false, err
+	}
+	if result == nil {
+		return false, nil
+	}
+
+	matched, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a boolean, got %T", result)
+	}
+	return matched, nil
+}
+
+// MatchJSON evaluates the filter against a JSON encoded payload
+func (f *Filter) MatchJSON(data []byte) (bool, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return false, fmt.Errorf("failed to decode event payload: %w", err)
+	}
+	return f.Match(payload)
+}
+
+// Matches is a convenience wrapper that treats an empty expression as match-all
+func Matches(expression string, payload map[string]interface{}) (bool, error) {
+	if strings.TrimSpace(expression) == "" {
+		return true, nil
+	}
+	filter, err := Compile(expression)
+	if err != nil {
+		return false, err
+	}
+	return filter.Match(payload)
+}
+
+// eval evaluates a node. Missing fields evaluate to nil.
+func eval(n node, env map[string]interface{}) (interface{}, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+
+	case *variableNode:
+		return normalize(env[n.name]), nil
+
+	case *fieldNode:
+		value, _, err := lookup(n, env)
+		return value, err
+
+	case *indexNode:
+		value, _, err := lookup(n, env)
+		return value, err
+
+	case *listNode:
+		items := make([]interface{}, 0, len(n.items))
+		for _, item := range n.items {
+			value, err := eval(item, env)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+
+	case *notNode:
+		value, err := eval(n.operand, env)
+		if err != nil {
+			return nil, err
+		}
+		b, err := truthy(value)
+		if err != nil {
+			return nil, err
+		}
+		return !b, nil
+
+	case *negateNode:
+		value, err := eval(n.operand, env)
+		if err != nil {
+			return nil, err
+		}
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %T", value)
+		}
+		return -number, nil
+
+	case *binaryNode:
+		return evalBinary(n, env)
+
+	case *callNode:
+		return evalCall(n, env)
+	}
+
+	return nil, fmt.Errorf("unsupported expression")
+}
+
+// lookup resolves a field or index path and reports whether it exists
+func lookup(n node, env map[string]interface{}) (interface{}, bool, error) {
+	switch n := n.(type) {
+	case *variableNode:
+		value, ok := env[n.name]
+		return normalize(value), ok, nil
+
+	case *fieldNode:
+		target, ok, err := lookup(n.target, env)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		m, isMap := target.(map[string]interface{})
+		if !isMap {
+			return nil, false, nil
+		}
+		value, ok := m[n.name]
+		return normalize(value), ok, nil
+
+	case *indexNode:
+		target, ok, err := lookup(n.target, env)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		index, err := eval(n.index, env)
+		if err != nil {
+			return nil, false, err
+		}
+		switch t := target.(type) {
+		case map[string]interface{}:
+			key, isString := index.(string)
+			if !isString {
+				return nil, false, fmt.Errorf("map index must be a string")
+			}
+			value, ok := t[key]
+			return normalize(value), ok, nil
+		case []interface{}:
+			f, isNumber := index.(float64)
+			if !isNumber {
+				return nil, false, fmt.Errorf("list index must be a number")
+			}
+			i := int(f)
+			if i < 0 || i >= len(t) {
+				return nil, false, nil
+			}
+			return normalize(t[i]), true, nil
+		}
+		return nil, false, nil
+	}
+
+	value, err := eval(n, env)
+	return value, err == nil && value != nil, err
+}
+
+func evalBinary(n *binaryNode, env map[string]interface{}) (interface{}, error) {
+	left, err := eval(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit
+	switch n.op {
+	case "&&", "||":
+		l, err := truthy(left)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := eval(n.right, env)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right)
+	}
+
+	right, err := eval(n.right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right), nil
+	case "+", "-", "*", "/", "%":
+		return arithmetic(n.op, left, right)
+	}
+
+	return nil, fmt.Errorf("unsupported operator %q", n.op)
+}
+
+// arithmetic applies + - * / % to numbers; + also joins strings and lists
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok || op != "+" {
+			return nil, fmt.Errorf("cannot apply %s to a string and %T", op, right)
+		}
+		if len(l)+len(r) > MaxStringLength {
+			return nil, fmt.Errorf("string exceeds %d bytes", MaxStringLength)
+		}
+		return l + r, nil
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || op != "+" {
+			return nil, fmt.Errorf("cannot apply %s to a list and %T", op, right)
+		}
+		return append(append([]interface{}{}, l...), r...), nil
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply %s to a number and %T", op, right)
+		}
+		switch op {
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		}
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return l / r, nil
+		}
+		return float64(int64(l) % int64(r)), nil
+	}
+	return nil, fmt.Errorf("cannot apply %s to %T", op, left)
+}
+
+// evalCall calls a function; the parser has checked it exists and its arity
+func evalCall(n *callNode, env map[string]interface{}) (interface{}, error) {
+	if n.name == "has" {
+		_, ok, err := lookup(n.args[0], env)
+		return ok, err
+	}
+
+	args := n.args
+	if n.target != nil {
+		args = append([]node{n.target}, n.args...)
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		value, err := eval(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	result, err := functions[n.name].call(values)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return result, nil
+}
+
+// truthy converts a logical operand; null counts as false
+func truthy(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("expected a boolean, got %T", value)
+}
+
+// equal compares two values, treating all numbers alike
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// contains implements the in operator for lists, map keys and substrings
+func contains(container, item interface{}) bool {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, element := range c {
+			if equal(normalize(element), item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if key, ok := item.(string); ok {
+			_, found := c[key]
+			return found
+		}
+	case string:
+		if s, ok := item.(string); ok {
+			return strings.Contains(c, s)
+		}
+	}
+	return false
+}
+
+// compare orders numbers and strings; other combinations never match
+func compare(op string, a, b interface{}) bool {
+	var cmp int
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case av < bv:
+			cmp = -1
+		case av > bv:
+			cmp = 1
+		}
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(av, bv)
+	default:
+		return false
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// size returns the length of a string, list or map
+func size(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(len(v)), nil
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("%T has no size", value)
+}
+
+// normalize converts Go values from payloads built in code to their JSON equivalents
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	case json.RawMessage:
+		var decoded interface{}
+		if err := json.Unmarshal(v, &decoded); err == nil {
+			return decoded
+		}
+	}
+	return value
+}
@@ -0,0 +1,170 @@
+package eventfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent() map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "ci.updated",
+		"action": "UPDATE",
+		"data": map[string]interface{}{
+			"name":        "payment-api-01",
+			"criticality": "critical",
+			"tags":        []interface{}{"payment", "pci"},
+			"attributes": map[string]interface{}{
+				"cpu_cores": 16.0,
+				"os":        "Ubuntu 22.04",
+			},
+		},
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`event.data.criticality == "critical" && "payment" in event.data.tags`, true},
+		{`data.criticality == "critical" && "billing" in data.tags`, false},
+		{`type == "ci.updated" || type == "ci.created"`, true},
+		{`!(action == "DELETE")`, true},
+		{`data.attributes.cpu_cores >= 8 && data.attributes.cpu_cores < 32`, true},
+		{`data.tags[1] == 'pci'`, true},
+		{`data.attributes["os"].startsWith("Ubuntu")`, true},
+		{`data.name.matches("^payment-[a-z]+-[0-9]+$")`, true},
+		{`data.name.lower().contains("api")`, true},
+		{`has(data.attributes.os) && !has(data.attributes.serial_number)`, true},
+		{`size(data.tags) == 2`, true},
+		{`data.criticality in ["critical", "high"]`, true},
+		{`data.missing.field == "x"`, false},
+		{`data.missing == null`, true},
+		{`data.missing.startsWith("x")`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			filter, err := Compile(tt.expression)
+			require.NoError(t, err)
+
+			matched, err := filter.Match(testEvent())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matched)
+		})
+	}
+}
+
+func TestFilter_MatchJSON(t *testing.T) {
+	filter, err := Compile(`event.data.criticality == "critical"`)
+	require.NoError(t, err)
+
+	matched, err := filter.MatchJSON([]byte(`{"data": {"criticality": "critical"}}`))
+	require.NoError(t, err)
+	assert.True(t, matched)
+
+	_, err = filter.MatchJSON([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, expression := range []string{
+		``,
+		`data.criticality ==`,
+		`data.criticality = "critical"`,
+		`"unterminated`,
+		`(data.name == "x"`,
+		`data.name == "x" data.type`,
+		`unknown(data.name)`,
+		`data.name.lower("x")`,
+		`has("x")`,
+	} {
+		_, err := Compile(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestFilter_RuntimeErrors(t *testing.T) {
+	for _, expression := range []string{
+		`data.name`,
+		`data.name && true`,
+		`data.name.matches("[")`,
+		`data.name + 1 == "x"`,
+		`data.attributes.cpu_cores / 0 == 1`,
+	} {
+		filter, err := Compile(expression)
+		require.NoError(t, err, expression)
+
+		_, err = filter.Match(testEvent())
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestMatches_EmptyExpressionMatchesAll(t *testing.T) {
+	matched, err := Matches("  ", testEvent())
+	require.NoError(t, err)
+	assert.True(t, matched)
+}
+
+func TestExpression_Eval(t *testing.T) {
+	vars := map[string]interface{}{
+		"ci": map[string]interface{}{
+			"name": "Web-01",
+			"tags": []interface{}{"prod", "web"},
+			"attributes": map[string]interface{}{
+				"cpu":      float64(8),
+				"hardware": map[string]interface{}{"vendor": "Dell"},
+			},
+		},
+		"previous": nil,
+	}
+
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{`1 + 2 * 3`, float64(7)},
+		{`(1 + 2) * 3 - -1`, float64(10)},
+		{`"a" + "b" == "ab"`, true},
+		{`lower(ci.name)`, "web-01"},
+		{`ci.name.upper()`, "WEB-01"},
+		{`ci.attributes["cpu"] / 2`, float64(4)},
+		{`ci.attributes.os.version`, nil},
+		{`previous == null || previous.name != ci.name`, true},
+		{`!matches(ci.name, "^db-")`, true},
+		{`size(ci.tags) + ci.name.size()`, float64(8)},
+		{`string(ci.attributes.cpu) + " cores"`, "8 cores"},
+		{`number("12") % 5`, float64(2)},
+		{`split("a,b", ",")[1]`, "b"},
+		{`replace(ci.name, "-", "_")`, "Web_01"},
+		{`keys(ci.attributes)`, []interface{}{"cpu", "hardware"}},
+		{`[1, 2] + [3]`, []interface{}{float64(1), float64(2), float64(3)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := CompileExpression(tt.expression)
+			require.NoError(t, err)
+
+			value, err := expression.Eval(vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestCompilePrefix(t *testing.T) {
+	expression, rest, err := CompilePrefix(`"owner " + ci.owner + " if" if ci.owner != ""`)
+	require.NoError(t, err)
+	assert.Equal(t, `"owner " + ci.owner + " if"`, expression.String())
+	assert.Equal(t, `if ci.owner != ""`, rest)
+
+	_, rest, err = CompilePrefix(`ci.name`)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+
+	_, _, err = CompilePrefix(`ci.name +`)
+	assert.Error(t, err)
+}
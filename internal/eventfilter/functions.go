@@ -0,0 +1,142 @@
+package eventfilter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits keeping a single evaluation cheap
+const (
+	// MaxStringLength bounds strings built by an expression
+	MaxStringLength = 64 * 1024
+	// MaxPatternLength bounds regular expressions given to matches
+	MaxPatternLength = 1024
+)
+
+// function is a builtin, callable as f(x, y) or as the method x.f(y). has() is
+// handled by the evaluator because it looks at a field rather than its value.
+type function struct {
+	args int
+	call func(args []interface{}) (interface{}, error)
+}
+
+// stringFunction adapts a function of strings. A null first argument, such as
+// a missing field, returns missing instead of failing.
+func stringFunction(args int, missing interface{}, fn func(s []string) (interface{}, error)) function {
+	return function{args: args, call: func(values []interface{}) (interface{}, error) {
+		if values[0] == nil {
+			return missing, nil
+		}
+		s := make([]string, len(values))
+		for i, value := range values {
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %T", value)
+			}
+			s[i] = text
+		}
+		return fn(s)
+	}}
+}
+
+var functions = map[string]function{
+	"size": {args: 1, call: func(args []interface{}) (interface{}, error) {
+		return size(args[0])
+	}},
+	"lower": stringFunction(1, "", func(s []string) (interface{}, error) {
+		return strings.ToLower(s[0]), nil
+	}),
+	"upper": stringFunction(1, "", func(s []string) (interface{}, error) {
+		return strings.ToUpper(s[0]), nil
+	}),
+	"trim": stringFunction(1, "", func(s []string) (interface{}, error) {
+		return strings.TrimSpace(s[0]), nil
+	}),
+	"startsWith": stringFunction(2, false, func(s []string) (interface{}, error) {
+		return strings.HasPrefix(s[0], s[1]), nil
+	}),
+	"endsWith": stringFunction(2, false, func(s []string) (interface{}, error) {
+		return strings.HasSuffix(s[0], s[1]), nil
+	}),
+	"contains": stringFunction(2, false, func(s []string) (interface{}, error) {
+		return strings.Contains(s[0], s[1]), nil
+	}),
+	"matches": stringFunction(2, false, func(s []string) (interface{}, error) {
+		if len(s[1]) > MaxPatternLength {
+			return nil, fmt.Errorf("pattern exceeds %d characters", MaxPatternLength)
+		}
+		re, err := regexp.Compile(s[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		return re.MatchString(s[0]), nil
+	}),
+	"replace": stringFunction(3, "", func(s []string) (interface{}, error) {
+		result := strings.ReplaceAll(s[0], s[1], s[2])
+		if len(result) > MaxStringLength {
+			return nil, fmt.Errorf("string exceeds %d bytes", MaxStringLength)
+		}
+		return result, nil
+	}),
+	"split": stringFunction(2, []interface{}{}, func(s []string) (interface{}, error) {
+		parts := strings.Split(s[0], s[1])
+		items := make([]interface{}, len(parts))
+		for i, part := range parts {
+			items[i] = part
+		}
+		return items, nil
+	}),
+	"string": {args: 1, call: func(args []interface{}) (interface{}, error) {
+		return ToString(args[0])
+	}},
+	"number": {args: 1, call: func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case float64:
+			return v, nil
+		case string:
+			number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			return number, nil
+		}
+		return nil, fmt.Errorf("cannot convert %T to a number", args[0])
+	}},
+	"keys": {args: 1, call: func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return []interface{}{}, nil
+		case map[string]interface{}:
+			names := make([]string, 0, len(v))
+			for name := range v {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			keys := make([]interface{}, len(names))
+			for i, name := range names {
+				keys[i] = name
+			}
+			return keys, nil
+		}
+		return nil, fmt.Errorf("%T has no keys", args[0])
+	}},
+}
+
+// ToString converts a scalar to its string form, the way string() does. Null
+// converts to the empty string.
+func ToString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("cannot convert %T to a string", value)
+}
@@ -0,0 +1,121 @@
+package eventfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// twoCharOperators are checked before single character operators
+var twoCharOperators = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+const singleCharOperators = "<>!().,[]+-*/%"
+
+// lex splits an expression into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := rune(input[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			s, n, err := lexString(input[i:])
+			if err != nil {
+				return nil, fmt.Errorf("at position %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: input[i : i+n], value: s, pos: i})
+			i += n
+
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(input) && (unicode.IsDigit(rune(input[i])) || input[i] == '.') {
+				i++
+			}
+			f, err := strconv.ParseFloat(input[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("at position %d: invalid number %q", start, input[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start:i], value: f, pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(input) && (unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i])) || input[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[start:i], pos: start})
+
+		default:
+			matched := false
+			for _, op := range twoCharOperators {
+				if strings.HasPrefix(input[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			if strings.ContainsRune(singleCharOperators, c) {
+				tokens = append(tokens, token{kind: tokenOperator, text: string(c), pos: i})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("at position %d: unexpected character %q", i, c)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(input)})
+	return tokens, nil
+}
+
+// lexString reads a quoted string literal and returns its value and length in the input
+func lexString(input string) (string, int, error) {
+	quote := input[0]
+	var b strings.Builder
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(input) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			switch input[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(input[i])
+			}
+		default:
+			b.WriteByte(input[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
@@ -0,0 +1,378 @@
+package eventfilter
+
+import (
+	"fmt"
+)
+
+// node is a parsed expression
+type node interface{}
+
+type literalNode struct{ value interface{} }
+
+type variableNode struct{ name string }
+
+type fieldNode struct {
+	target node
+	name   string
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+type callNode struct {
+	name   string
+	target node // receiver of a method call, nil for functions
+	args   []node
+}
+
+type notNode struct{ operand node }
+
+type negateNode struct{ operand node }
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type listNode struct{ items []node }
+
+// maxDepth bounds expression nesting so hostile filters cannot exhaust the stack
+const maxDepth = 32
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// parse builds the expression tree of a filter
+func parse(input string) (node, error) {
+	p, root, err := parseStart(input)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("at position %d: unexpected %q", tok.pos, tok.text)
+	}
+	return root, nil
+}
+
+// parsePrefix builds the tree of the expression input starts with and returns
+// the source following it
+func parsePrefix(input string) (node, string, error) {
+	p, root, err := parseStart(input)
+	if err != nil {
+		return nil, "", err
+	}
+	return root, input[p.peek().pos:], nil
+}
+
+// parseStart parses the longest expression at the start of input
+func parseStart(input string) (*parser, node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return fmt.Errorf("at position %d: expected %q, found %q", tok.pos, op, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return fmt.Errorf("expression is nested too deeply")
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseOr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	isComparison := tok.kind == tokenOperator && (tok.text == "==" || tok.text == "!=" || tok.text == "<" || tok.text == "<=" || tok.text == ">" || tok.text == ">=")
+	if tok.kind == tokenIdent && tok.text == "in" {
+		isComparison = true
+	}
+	if !isComparison {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: tok.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	return p.parseArithmetic(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	return p.parseArithmetic(p.parseUnary, "*", "/", "%")
+}
+
+// parseArithmetic parses the left associative operators of one precedence level
+func (p *parser) parseArithmetic(operand func() (node, error), ops ...string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range ops {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	for _, op := range []string{"!", "-"} {
+		if !p.accept(op) {
+			continue
+		}
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "-" {
+			return &negateNode{operand: operand}, nil
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	target, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			tok := p.next()
+			if tok.kind != tokenIdent {
+				return nil, fmt.Errorf("at position %d: expected field name after '.'", tok.pos)
+			}
+			if p.accept("(") {
+				args, err := p.parseArgs(")")
+				if err != nil {
+					return nil, err
+				}
+				if target, err = newCall(tok.text, target, args); err != nil {
+					return nil, err
+				}
+			} else {
+				target = &fieldNode{target: target, name: tok.text}
+			}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			target = &indexNode{target: target, index: index}
+		default:
+			return target, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenString, tokenNumber:
+		return &literalNode{value: tok.value}, nil
+
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			args, err := p.parseArgs(")")
+			if err != nil {
+				return nil, err
+			}
+			return newCall(tok.text, nil, args)
+		}
+		return &variableNode{name: tok.text}, nil
+
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			items, err := p.parseArgs("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+
+	if tok.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("at position %d: unexpected %q", tok.pos, tok.text)
+}
+
+// parseArgs parses a comma separated list up to the closing operator
+func (p *parser) parseArgs(closing string) ([]node, error) {
+	var args []node
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newCall checks the function exists and takes that many arguments, counting
+// the receiver of a method call as the first
+func newCall(name string, target node, args []node) (node, error) {
+	if name == "has" {
+		if target != nil || len(args) != 1 {
+			return nil, fmt.Errorf("has() takes one field argument")
+		}
+		switch args[0].(type) {
+		case *fieldNode, *indexNode, *variableNode:
+		default:
+			return nil, fmt.Errorf("has() argument must be a field")
+		}
+		return &callNode{name: name, args: args}, nil
+	}
+
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	count := len(args)
+	if target != nil {
+		count++
+	}
+	if count != fn.args {
+		return nil, fmt.Errorf("%s() takes %d arguments, got %d", name, fn.args, count)
+	}
+	return &callNode{name: name, target: target, args: args}, nil
+}
@@ -0,0 +1,163 @@
+// Package faultinject simulates failures of the databases sync depends on, so
+// the retry, fallback and circuit-breaker paths can be exercised in
+// integration tests. Each target fails with its configured probability, or
+// always when a test forces it on the context. It is meant for test
+// environments only; the configuration refuses to enable it in production.
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Targets that can be failed
+const (
+	// Neo4j fails with a timeout, as a query exceeding its deadline does
+	Neo4j = "neo4j"
+	// Postgres fails with a serialization failure (SQLSTATE 40001)
+	Postgres = "postgres"
+	// Redis fails as if the server were unreachable
+	Redis = "redis"
+)
+
+// Targets lists every target that can be failed
+var Targets = []string{Neo4j, Postgres, Redis}
+
+// SerializationFailure is the SQLSTATE of a Postgres serialization failure
+const SerializationFailure = "40001"
+
+var (
+	// ErrInjected is wrapped by every injected failure
+	ErrInjected = errors.New("injected fault")
+
+	ErrUnknownTarget      = errors.New("unknown fault target")
+	ErrInvalidProbability = errors.New("fault probability must be between 0 and 1")
+)
+
+// Injector fails calls to each target at random with its probability
+type Injector struct {
+	mu            sync.Mutex
+	rand          *rand.Rand
+	probabilities map[string]float64
+	injected      map[string]int64
+}
+
+// New creates an injector failing each target with the given probability;
+// targets left out never fail unless forced. The seed makes a test run's
+// failures repeatable.
+func New(probabilities map[string]float64, seed int64) (*Injector, error) {
+	injector := &Injector{
+		rand:          rand.New(rand.NewSource(seed)),
+		probabilities: map[string]float64{},
+		injected:      map[string]int64{},
+	}
+	for target, probability := range probabilities {
+		if err := injector.Set(target, probability); err != nil {
+			return nil, err
+		}
+	}
+	return injector, nil
+}
+
+// Set changes the probability a target fails with
+func (i *Injector) Set(target string, probability float64) error {
+	if !knownTarget(target) {
+		return fmt.Errorf("%w: %s", ErrUnknownTarget, target)
+	}
+	if probability < 0 || probability > 1 {
+		return fmt.Errorf("%w: %s has %v", ErrInvalidProbability, target, probability)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.probabilities[target] = probability
+	return nil
+}
+
+// Inject returns the failure of a target if one is forced on the context or
+// drawn at random, nil otherwise. A nil injector never fails.
+func (i *Injector) Inject(ctx context.Context, target string) error {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !forced(ctx, target) {
+		probability := i.probabilities[target]
+		if probability <= 0 || i.rand.Float64() >= probability {
+			return nil
+		}
+	}
+	i.injected[target]++
+	return Fault(target)
+}
+
+// Injected returns how many failures were injected per target
+func (i *Injector) Injected() map[string]int64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	counts := make(map[string]int64, len(i.injected))
+	for target, count := range i.injected {
+		counts[target] = count
+	}
+	return counts
+}
+
+// Fault returns the failure injected for a target. Each wraps ErrInjected
+// and the error the real failure is recognised by, so the code under test
+// takes the same path it would in production.
+func Fault(target string) error {
+	switch target {
+	case Neo4j:
+		return fmt.Errorf("%w: neo4j query timed out: %w", ErrInjected, context.DeadlineExceeded)
+	case Postgres:
+		return fmt.Errorf("%w: %w", ErrInjected, &pgconn.PgError{
+			Severity: "ERROR",
+			Code:     SerializationFailure,
+			Message:  "could not serialize access due to concurrent update",
+		})
+	case Redis:
+		return fmt.Errorf("%w: redis: connection refused", ErrInjected)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownTarget, target)
+}
+
+// forcedKey is the context key of the targets forced to fail
+type forcedKey struct{}
+
+// WithFault forces calls made with the returned context to fail for the
+// given targets, whatever their probability
+func WithFault(ctx context.Context, targets ...string) context.Context {
+	merged := map[string]bool{}
+	if existing, ok := ctx.Value(forcedKey{}).(map[string]bool); ok {
+		for target := range existing {
+			merged[target] = true
+		}
+	}
+	for _, target := range targets {
+		merged[target] = true
+	}
+	return context.WithValue(ctx, forcedKey{}, merged)
+}
+
+func forced(ctx context.Context, target string) bool {
+	existing, _ := ctx.Value(forcedKey{}).(map[string]bool)
+	return existing[target]
+}
+
+func knownTarget(target string) bool {
+	for _, known := range Targets {
+		if target == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectFollowsProbabilities(t *testing.T) {
+	injector, err := New(map[string]float64{Neo4j: 1, Postgres: 0.5}, 1)
+	require.NoError(t, err)
+
+	failures := 0
+	for n := 0; n < 1000; n++ {
+		assert.Error(t, injector.Inject(context.Background(), Neo4j))
+		assert.NoError(t, injector.Inject(context.Background(), Redis))
+		if injector.Inject(context.Background(), Postgres) != nil {
+			failures++
+		}
+	}
+	assert.InDelta(t, 500, failures, 75)
+	assert.Equal(t, map[string]int64{Neo4j: 1000, Postgres: int64(failures)}, injector.Injected())
+}
+
+func TestInjectIsRepeatableForASeed(t *testing.T) {
+	draw := func() []bool {
+		injector, err := New(map[string]float64{Redis: 0.3}, 42)
+		require.NoError(t, err)
+		var failed []bool
+		for n := 0; n < 50; n++ {
+			failed = append(failed, injector.Inject(context.Background(), Redis) != nil)
+		}
+		return failed
+	}
+	assert.Equal(t, draw(), draw())
+}
+
+func TestWithFaultForcesFailures(t *testing.T) {
+	injector, err := New(nil, 1)
+	require.NoError(t, err)
+
+	ctx := WithFault(WithFault(context.Background(), Redis), Postgres)
+	assert.Error(t, injector.Inject(ctx, Redis))
+	assert.Error(t, injector.Inject(ctx, Postgres))
+	assert.NoError(t, injector.Inject(ctx, Neo4j))
+	assert.NoError(t, injector.Inject(context.Background(), Redis))
+
+	var none *Injector
+	assert.NoError(t, none.Inject(ctx, Redis))
+}
+
+func TestFaultsLookLikeTheRealFailures(t *testing.T) {
+	assert.ErrorIs(t, Fault(Neo4j), context.DeadlineExceeded)
+	assert.ErrorIs(t, Fault(Redis), ErrInjected)
+
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(Fault(Postgres), &pgErr))
+	assert.Equal(t, SerializationFailure, pgErr.Code)
+	assert.ErrorIs(t, Fault(Postgres), ErrInjected)
+}
+
+func TestNewRejectsInvalidSettings(t *testing.T) {
+	_, err := New(map[string]float64{"kafka": 0.1}, 1)
+	assert.ErrorIs(t, err, ErrUnknownTarget)
+
+	_, err = New(map[string]float64{Neo4j: 1.5}, 1)
+	assert.ErrorIs(t, err, ErrInvalidProbability)
+}
@@ -0,0 +1,179 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	flags map[string]*Flag
+	reads int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{flags: make(map[string]*Flag)}
+}
+
+func (m *memoryStore) ListFlags(ctx context.Context) ([]*Flag, error) {
+	flags := make([]*Flag, 0, len(m.flags))
+	for _, flag := range m.flags {
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+func (m *memoryStore) GetFlag(ctx context.Context, key string) (*Flag, error) {
+	m.reads++
+	flag, ok := m.flags[key]
+	if !ok {
+		return nil, ErrFlagNotFound
+	}
+	copied := *flag
+	return &copied, nil
+}
+
+func (m *memoryStore) UpsertFlag(ctx context.Context, flag *Flag) (*Flag, error) {
+	if existing, ok := m.flags[flag.Key]; ok {
+		flag.Overrides = existing.Overrides
+	}
+	m.flags[flag.Key] = flag
+	return flag, nil
+}
+
+func (m *memoryStore) DeleteFlag(ctx context.Context, key string) error {
+	if _, ok := m.flags[key]; !ok {
+		return ErrFlagNotFound
+	}
+	delete(m.flags, key)
+	return nil
+}
+
+func (m *memoryStore) SetOverride(ctx context.Context, override *Override) error {
+	flag, ok := m.flags[override.FlagKey]
+	if !ok {
+		return ErrFlagNotFound
+	}
+	flag.Overrides = append(flag.Overrides, *override)
+	return nil
+}
+
+func (m *memoryStore) DeleteOverride(ctx context.Context, key, scope, value string) error {
+	return nil
+}
+
+type memoryCache struct {
+	entries map[string][]byte
+}
+
+func (c *memoryCache) GetJSON(ctx context.Context, key string, target interface{}) error {
+	data, ok := c.entries[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+	return json.Unmarshal(data, target)
+}
+
+func (c *memoryCache) SetJSONWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.entries[key] = data
+	return nil
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	delete(c.entries, key)
+	return nil
+}
+
+func TestFlag_EvaluatePrecedence(t *testing.T) {
+	flag := &Flag{
+		Key:     "graphql",
+		Enabled: false,
+		Overrides: []Override{
+			{Scope: ScopeEnvironment, Value: "staging", Enabled: true},
+			{Scope: ScopeTenant, Value: "acme", Enabled: false},
+		},
+	}
+
+	assert.Equal(t, Evaluation{Key: "graphql", Enabled: false, Source: "default"}, flag.Evaluate("production", ""))
+	assert.Equal(t, Evaluation{Key: "graphql", Enabled: true, Source: ScopeEnvironment}, flag.Evaluate("staging", "globex"))
+	assert.Equal(t, Evaluation{Key: "graphql", Enabled: false, Source: ScopeTenant}, flag.Evaluate("staging", "acme"))
+}
+
+func TestValidateKeyAndOverride(t *testing.T) {
+	assert.NoError(t, ValidateKey("bulk_delete.v2"))
+	assert.Error(t, ValidateKey("Bulk Delete"))
+	assert.Error(t, ValidateKey(""))
+
+	assert.NoError(t, ValidateOverride(Override{Scope: ScopeTenant, Value: "acme"}))
+	assert.Error(t, ValidateOverride(Override{Scope: "region", Value: "eu"}))
+	assert.Error(t, ValidateOverride(Override{Scope: ScopeTenant}))
+}
+
+func TestService_CachesAndInvalidates(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	cache := &memoryCache{entries: make(map[string][]byte)}
+	service := NewService(store, cache, "production", time.Minute)
+
+	_, err := service.SaveFlag(ctx, &Flag{Key: "bulk_delete", Enabled: false})
+	require.NoError(t, err)
+
+	assert.False(t, service.IsEnabled(ctx, "bulk_delete", "acme"))
+	assert.False(t, service.IsEnabled(ctx, "bulk_delete", "acme"))
+	assert.Equal(t, 1, store.reads, "second evaluation should be served from the cache")
+
+	require.NoError(t, service.SetOverride(ctx, &Override{FlagKey: "bulk_delete", Scope: ScopeTenant, Value: "acme", Enabled: true}))
+	assert.True(t, service.IsEnabled(ctx, "bulk_delete", "acme"))
+	assert.False(t, service.IsEnabled(ctx, "bulk_delete", "globex"))
+
+	// Unknown flags are disabled and cached as missing
+	assert.False(t, service.IsEnabled(ctx, "unknown", ""))
+	reads := store.reads
+	assert.False(t, service.IsEnabled(ctx, "unknown", ""))
+	assert.Equal(t, reads, store.reads)
+}
+
+func TestService_RouteMiddleware(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(newMemoryStore(), nil, "production", 0)
+	_, err := service.SaveFlag(ctx, &Flag{Key: "bulk_delete", Enabled: false})
+	require.NoError(t, err)
+	require.NoError(t, service.SetOverride(ctx, &Override{FlagKey: "bulk_delete", Scope: ScopeTenant, Value: "acme", Enabled: true}))
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/cis/{id}", ok).Methods("GET", "DELETE")
+	router.Use(service.RouteMiddleware(map[string]string{"DELETE /api/v1/cis/{id}": "bulk_delete"}))
+
+	tests := []struct {
+		method string
+		tenant string
+		status int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodDelete, "", http.StatusNotFound},
+		{http.MethodDelete, "acme", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/cis/123", nil)
+		if tt.tenant != "" {
+			req.Header.Set(TenantHeader, tt.tenant)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tt.status, rec.Code, "%s tenant=%q", tt.method, tt.tenant)
+	}
+}
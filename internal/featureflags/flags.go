@@ -0,0 +1,93 @@
+package featureflags
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Override scopes, from least to most specific
+const (
+	ScopeEnvironment = "environment"
+	ScopeTenant      = "tenant"
+)
+
+// TenantHeader carries the tenant a request is evaluated for
+const TenantHeader = "X-Tenant-ID"
+
+// ErrFlagNotFound is returned when a flag does not exist
+var ErrFlagNotFound = errors.New("feature flag not found")
+
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+// Flag is a named capability that can be toggled without a redeploy
+type Flag struct {
+	Key         string     `json:"key" db:"key"`
+	Description string     `json:"description" db:"description"`
+	Enabled     bool       `json:"enabled" db:"enabled"`
+	Overrides   []Override `json:"overrides"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	UpdatedBy   string     `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// Override enables or disables a flag for a single environment or tenant
+type Override struct {
+	FlagKey   string    `json:"flag_key" db:"flag_key"`
+	Scope     string    `json:"scope" db:"scope"`
+	Value     string    `json:"value" db:"value"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// Evaluation is the resolved state of a flag for a request
+type Evaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Source  string `json:"source"` // default, environment or tenant
+}
+
+// ValidateKey checks that a flag key is well formed
+func ValidateKey(key string) error {
+	if !flagKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid flag key %q: must be lowercase alphanumerics, '.', '_' or '-'", key)
+	}
+	return nil
+}
+
+// ValidateOverride checks that an override has a known scope and a value
+func ValidateOverride(o Override) error {
+	if o.Scope != ScopeEnvironment && o.Scope != ScopeTenant {
+		return fmt.Errorf("invalid override scope %q: must be %s or %s", o.Scope, ScopeEnvironment, ScopeTenant)
+	}
+	if o.Value == "" {
+		return fmt.Errorf("override value is required")
+	}
+	return nil
+}
+
+// Evaluate resolves the flag for an environment and tenant. A tenant override
+// wins over an environment override, which wins over the flag default.
+func (f *Flag) Evaluate(environment, tenant string) Evaluation {
+	eval := Evaluation{Key: f.Key, Enabled: f.Enabled, Source: "default"}
+
+	for _, o := range f.Overrides {
+		if o.Scope == ScopeEnvironment && o.Value == environment {
+			eval.Enabled = o.Enabled
+			eval.Source = ScopeEnvironment
+		}
+	}
+	if tenant == "" {
+		return eval
+	}
+	for _, o := range f.Overrides {
+		if o.Scope == ScopeTenant && o.Value == tenant {
+			eval.Enabled = o.Enabled
+			eval.Source = ScopeTenant
+		}
+	}
+
+	return eval
+}
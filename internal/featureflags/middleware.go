@@ -0,0 +1,59 @@
+package featureflags
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// RouteMiddleware gates routes behind flags. routes maps a route to a flag key,
+// where the route is a mux path template optionally prefixed by a method,
+// e.g. "DELETE /api/v1/cis/{id}" or "/api/v1/graphql".
+func (s *Service) RouteMiddleware(routes map[string]string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := routeFlag(routes, r); ok && !s.IsEnabled(r.Context(), key, r.Header.Get(TenantHeader)) {
+				respondDisabled(w, key)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeFlag finds the flag guarding the matched route, preferring a method specific entry
+func routeFlag(routes map[string]string, r *http.Request) (string, bool) {
+	if len(routes) == 0 {
+		return "", false
+	}
+
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+
+	if key, ok := routes[strings.ToUpper(r.Method)+" "+template]; ok {
+		return key, true
+	}
+	key, ok := routes[template]
+	return key, ok
+}
+
+// respondDisabled reports a disabled capability as if the route did not exist
+func respondDisabled(w http.ResponseWriter, key string) {
+	response, _ := json.Marshal(map[string]interface{}{
+		"error":   "Feature is not enabled",
+		"success": false,
+		"details": "feature flag " + key + " is disabled",
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(response)
+}
@@ -0,0 +1,201 @@
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DefaultCacheTTL is used when the service is created without a cache TTL
+const DefaultCacheTTL = 30 * time.Second
+
+// Cache is the subset of the Redis cache service used to cache flags.
+// database.CacheService satisfies it.
+type Cache interface {
+	GetJSON(ctx context.Context, key string, target interface{}) error
+	SetJSONWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// cacheEntry also records flags that do not exist so unknown keys do not hit the store
+type cacheEntry struct {
+	Exists bool  `json:"exists"`
+	Flag   *Flag `json:"flag,omitempty"`
+}
+
+// Service evaluates flags for the running environment and caches them
+type Service struct {
+	store       Store
+	cache       Cache
+	cacheTTL    time.Duration
+	environment string
+}
+
+// NewService creates a new flag service. cache may be nil to always read from the store.
+func NewService(store Store, cache Cache, environment string, cacheTTL time.Duration) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{
+		store:       store,
+		cache:       cache,
+		cacheTTL:    cacheTTL,
+		environment: environment,
+	}
+}
+
+// Environment returns the environment flags are evaluated for
+func (s *Service) Environment() string {
+	return s.environment
+}
+
+// IsEnabled reports whether a flag is on for the tenant. Unknown flags and
+// lookup failures evaluate to disabled.
+func (s *Service) IsEnabled(ctx context.Context, key, tenant string) bool {
+	eval, err := s.Evaluate(ctx, key, tenant)
+	if err != nil {
+		if !errors.Is(err, ErrFlagNotFound) {
+			log.Printf("Failed to evaluate feature flag %s: %v", key, err)
+		}
+		return false
+	}
+	return eval.Enabled
+}
+
+// Evaluate resolves a single flag for the tenant
+func (s *Service) Evaluate(ctx context.Context, key, tenant string) (*Evaluation, error) {
+	flag, err := s.getFlag(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	eval := flag.Evaluate(s.environment, tenant)
+	return &eval, nil
+}
+
+// EvaluateAll resolves every flag for the tenant
+func (s *Service) EvaluateAll(ctx context.Context, tenant string) ([]Evaluation, error) {
+	flags, err := s.store.ListFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	evals := make([]Evaluation, 0, len(flags))
+	for _, flag := range flags {
+		evals = append(evals, flag.Evaluate(s.environment, tenant))
+	}
+	return evals, nil
+}
+
+// ListFlags retrieves all flags with their overrides
+func (s *Service) ListFlags(ctx context.Context) ([]*Flag, error) {
+	return s.store.ListFlags(ctx)
+}
+
+// GetFlag retrieves a flag with its overrides
+func (s *Service) GetFlag(ctx context.Context, key string) (*Flag, error) {
+	return s.store.GetFlag(ctx, key)
+}
+
+// SaveFlag creates or updates a flag
+func (s *Service) SaveFlag(ctx context.Context, flag *Flag) (*Flag, error) {
+	if err := ValidateKey(flag.Key); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.store.UpsertFlag(ctx, flag)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, flag.Key)
+
+	return saved, nil
+}
+
+// DeleteFlag removes a flag
+func (s *Service) DeleteFlag(ctx context.Context, key string) error {
+	if err := s.store.DeleteFlag(ctx, key); err != nil {
+		return err
+	}
+	s.invalidate(ctx, key)
+	return nil
+}
+
+// SetOverride enables or disables a flag for an environment or tenant
+func (s *Service) SetOverride(ctx context.Context, override *Override) error {
+	if err := ValidateOverride(*override); err != nil {
+		return err
+	}
+	if err := s.store.SetOverride(ctx, override); err != nil {
+		return err
+	}
+	s.invalidate(ctx, override.FlagKey)
+	return nil
+}
+
+// DeleteOverride removes an override
+func (s *Service) DeleteOverride(ctx context.Context, key, scope, value string) error {
+	if err := s.store.DeleteOverride(ctx, key, scope, value); err != nil {
+		return err
+	}
+	s.invalidate(ctx, key)
+	return nil
+}
+
+// Require wraps a handler so it responds 404 unless the flag is enabled for the request's tenant
+func (s *Service) Require(key string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.IsEnabled(r.Context(), key, r.Header.Get(TenantHeader)) {
+			respondDisabled(w, key)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// getFlag reads a flag through the cache
+func (s *Service) getFlag(ctx context.Context, key string) (*Flag, error) {
+	if s.cache != nil {
+		var entry cacheEntry
+		if err := s.cache.GetJSON(ctx, cacheKey(key), &entry); err == nil {
+			if !entry.Exists {
+				return nil, ErrFlagNotFound
+			}
+			return entry.Flag, nil
+		}
+	}
+
+	flag, err := s.store.GetFlag(ctx, key)
+	if err != nil && !errors.Is(err, ErrFlagNotFound) {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		entry := cacheEntry{Exists: flag != nil, Flag: flag}
+		if cacheErr := s.cache.SetJSONWithTTL(ctx, cacheKey(key), entry, s.cacheTTL); cacheErr != nil {
+			log.Printf("Failed to cache feature flag %s: %v", key, cacheErr)
+		}
+	}
+
+	if flag == nil {
+		return nil, ErrFlagNotFound
+	}
+	return flag, nil
+}
+
+// invalidate drops a cached flag after a change
+func (s *Service) invalidate(ctx context.Context, key string) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, cacheKey(key)); err != nil {
+		log.Printf("Failed to invalidate cached feature flag %s: %v", key, err)
+	}
+}
+
+// cacheKey returns the cache key of a flag
+func cacheKey(key string) string {
+	return fmt.Sprintf("feature_flag:%s", key)
+}
@@ -0,0 +1,172 @@
+package featureflags
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Store persists flags and their overrides
+type Store interface {
+	ListFlags(ctx context.Context) ([]*Flag, error)
+	GetFlag(ctx context.Context, key string) (*Flag, error)
+	UpsertFlag(ctx context.Context, flag *Flag) (*Flag, error)
+	DeleteFlag(ctx context.Context, key string) error
+	SetOverride(ctx context.Context, override *Override) error
+	DeleteOverride(ctx context.Context, key, scope, value string) error
+}
+
+// PostgresStore stores flags in the feature_flags and feature_flag_overrides tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed flag store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ListFlags retrieves all flags with their overrides, ordered by key
+func (s *PostgresStore) ListFlags(ctx context.Context) ([]*Flag, error) {
+	query := `
+		SELECT key, description, enabled, created_at, updated_at, COALESCE(updated_by, '') AS updated_by
+		FROM feature_flags
+		ORDER BY key`
+
+	var flags []*Flag
+	if err := s.db.SelectContext(ctx, &flags, query); err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	overrides, err := s.listOverrides(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*Flag, len(flags))
+	for _, flag := range flags {
+		flag.Overrides = []Override{}
+		byKey[flag.Key] = flag
+	}
+	for _, o := range overrides {
+		if flag, ok := byKey[o.FlagKey]; ok {
+			flag.Overrides = append(flag.Overrides, o)
+		}
+	}
+
+	return flags, nil
+}
+
+// GetFlag retrieves a flag with its overrides
+func (s *PostgresStore) GetFlag(ctx context.Context, key string) (*Flag, error) {
+	query := `
+		SELECT key, description, enabled, created_at, updated_at, COALESCE(updated_by, '') AS updated_by
+		FROM feature_flags
+		WHERE key = $1`
+
+	var flag Flag
+	if err := s.db.GetContext(ctx, &flag, query, key); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFlagNotFound
+		}
+		return nil, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+
+	overrides, err := s.listOverrides(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	flag.Overrides = overrides
+
+	return &flag, nil
+}
+
+// UpsertFlag creates a flag or updates its description and default state
+func (s *PostgresStore) UpsertFlag(ctx context.Context, flag *Flag) (*Flag, error) {
+	query := `
+		INSERT INTO feature_flags (key, description, enabled, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $4, NULLIF($5, ''))
+		ON CONFLICT (key) DO UPDATE SET
+			description = EXCLUDED.description,
+			enabled = EXCLUDED.enabled,
+			updated_at = EXCLUDED.updated_at,
+			updated_by = EXCLUDED.updated_by`
+
+	if _, err := s.db.ExecContext(ctx, query, flag.Key, flag.Description, flag.Enabled, time.Now(), flag.UpdatedBy); err != nil {
+		return nil, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	return s.GetFlag(ctx, flag.Key)
+}
+
+// DeleteFlag removes a flag and its overrides
+func (s *PostgresStore) DeleteFlag(ctx context.Context, key string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrFlagNotFound
+	}
+
+	return nil
+}
+
+// SetOverride creates or replaces an override for an existing flag
+func (s *PostgresStore) SetOverride(ctx context.Context, override *Override) error {
+	query := `
+		INSERT INTO feature_flag_overrides (flag_key, scope, value, enabled, updated_at, updated_by)
+		SELECT key, $2, $3, $4, $5, NULLIF($6, '') FROM feature_flags WHERE key = $1
+		ON CONFLICT (flag_key, scope, value) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			updated_at = EXCLUDED.updated_at,
+			updated_by = EXCLUDED.updated_by`
+
+	result, err := s.db.ExecContext(ctx, query, override.FlagKey, override.Scope, override.Value, override.Enabled, time.Now(), override.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to save feature flag override: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrFlagNotFound
+	}
+
+	return nil
+}
+
+// DeleteOverride removes an override
+func (s *PostgresStore) DeleteOverride(ctx context.Context, key, scope, value string) error {
+	query := `DELETE FROM feature_flag_overrides WHERE flag_key = $1 AND scope = $2 AND value = $3`
+	if _, err := s.db.ExecContext(ctx, query, key, scope, value); err != nil {
+		return fmt.Errorf("failed to delete feature flag override: %w", err)
+	}
+	return nil
+}
+
+// listOverrides retrieves the overrides of one flag, or of all flags when key is empty
+func (s *PostgresStore) listOverrides(ctx context.Context, key string) ([]Override, error) {
+	query := `
+		SELECT flag_key, scope, value, enabled, updated_at, COALESCE(updated_by, '') AS updated_by
+		FROM feature_flag_overrides
+		WHERE $1 = '' OR flag_key = $1
+		ORDER BY flag_key, scope, value`
+
+	overrides := []Override{}
+	if err := s.db.SelectContext(ctx, &overrides, query, key); err != nil {
+		return nil, fmt.Errorf("failed to list feature flag overrides: %w", err)
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,178 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const devices = `{"status": "ok", "count": 3, "devices": [
+	{"device_id": 1, "hostname": "core-sw-01", "os": "ios", "location": {"name": "DC1"}, "status": true},
+	{"device_id": 2, "hostname": "edge-rtr-01", "os": "junos", "location": {"name": "DC2"}, "status": false},
+	{"device_id": 3, "hostname": "", "os": "ios"}
+]}`
+
+// librenms serves the devices, failing while fail is set
+func librenms(t *testing.T, calls *int32, fail *atomic.Bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("X-Auth-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(devices))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newSource(t *testing.T, url string) Source {
+	t.Setenv("LIBRENMS_TOKEN", "secret")
+	source := Source{
+		Name:       "librenms",
+		Type:       "network_device",
+		URL:        url,
+		ItemsPath:  "devices",
+		IDField:    "device_id",
+		NameField:  "hostname",
+		Attributes: map[string]string{"os": "os", "site": "location.name", "up": "status"},
+		Headers:    map[string]string{"X-Auth-Token": "${LIBRENMS_TOKEN}"},
+	}
+	require.NoError(t, source.Validate())
+	return source
+}
+
+func TestSource_Validate(t *testing.T) {
+	source := Source{Name: "nms", Type: "network_device", URL: "https://nms.example.com/api/v0/devices", IDField: "id", NameField: "name"}
+	require.NoError(t, source.Validate())
+	assert.Equal(t, DefaultTimeout, source.Timeout)
+	assert.Equal(t, DefaultCacheTTL, source.CacheTTL)
+
+	for _, invalid := range []Source{
+		{Name: LocalSource, Type: "t", URL: "https://x", IDField: "id", NameField: "name"},
+		{Name: "nms", URL: "https://x", IDField: "id", NameField: "name"},
+		{Name: "nms", Type: "t", URL: "ftp://x", IDField: "id", NameField: "name"},
+		{Name: "nms", Type: "t", URL: "https://x", NameField: "name"},
+	} {
+		assert.ErrorIs(t, invalid.Validate(), ErrInvalidSource)
+	}
+}
+
+func TestService_Merge(t *testing.T) {
+	var calls int32
+	var fail atomic.Bool
+	server := librenms(t, &calls, &fail)
+	source := newSource(t, server.URL)
+	service := NewService([]Source{source})
+	ctx := context.Background()
+
+	assert.True(t, service.Federated("network_device"))
+	assert.False(t, service.Federated("server"))
+
+	local := models.CI{ID: uuid.New(), Name: "Core-SW-01", Type: "network_device", Attributes: json.RawMessage(`{"os": "nx-os", "rack": "A1"}`)}
+	records, statuses := service.Merge(ctx, "network_device", []models.CI{local}, &models.ListCIsRequest{})
+	require.Len(t, statuses, 1)
+	assert.Empty(t, statuses[0].Error)
+	assert.Equal(t, 2, statuses[0].Records)
+	require.Len(t, records, 2)
+
+	// The local CI keeps its values and gains the remote ones it lacks
+	assert.Equal(t, local.ID, records[0].ID)
+	assert.Equal(t, LocalSource, records[0].Origin.Source)
+	assert.Equal(t, "librenms", records[0].Origin.MergedFrom)
+	assert.Equal(t, "1", records[0].Origin.ExternalID)
+	assert.JSONEq(t, `{"os": "nx-os", "rack": "A1", "site": "DC1", "up": true}`, string(records[0].Attributes))
+
+	remote := records[1]
+	assert.Equal(t, "edge-rtr-01", remote.Name)
+	assert.Equal(t, "network_device", remote.Type)
+	assert.Equal(t, source.RecordID("2"), remote.ID)
+	assert.Equal(t, "librenms", remote.Origin.Source)
+	assert.NotNil(t, remote.Origin.FetchedAt)
+	assert.JSONEq(t, `{"os": "junos", "site": "DC2", "up": false}`, string(remote.Attributes))
+
+	// Filters the remote records cannot satisfy exclude them
+	records, _ = service.Merge(ctx, "network_device", nil, &models.ListCIsRequest{Search: "network"})
+	assert.Len(t, records, 2)
+	records, _ = service.Merge(ctx, "network_device", nil, &models.ListCIsRequest{Search: "rtr"})
+	assert.Len(t, records, 1)
+	records, _ = service.Merge(ctx, "network_device", nil, &models.ListCIsRequest{Owner: "netops"})
+	assert.Empty(t, records)
+
+	// Served from cache
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	record, err := service.Get(ctx, source.RecordID("2"))
+	require.NoError(t, err)
+	assert.Equal(t, "edge-rtr-01", record.Name)
+	_, err = service.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestService_StaleCache(t *testing.T) {
+	var calls int32
+	var fail atomic.Bool
+	server := librenms(t, &calls, &fail)
+	service := NewService([]Source{newSource(t, server.URL)})
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	records, _ := service.Merge(ctx, "network_device", nil, nil)
+	require.Len(t, records, 2)
+
+	// After the TTL the source is asked again; its failure serves the cache marked stale
+	fail.Store(true)
+	now = now.Add(DefaultCacheTTL)
+	records, statuses := service.Merge(ctx, "network_device", nil, nil)
+	require.Len(t, records, 2)
+	assert.True(t, records[0].Origin.Stale)
+	assert.True(t, statuses[0].Stale)
+	assert.Contains(t, statuses[0].Error, "502")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// The failing source is not retried until the TTL passes again
+	service.Merge(ctx, "network_device", nil, nil)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	fail.Store(false)
+	status, err := service.Refresh(ctx, "librenms")
+	require.NoError(t, err)
+	assert.Empty(t, status.Error)
+	assert.False(t, status.Stale)
+	assert.Equal(t, 2, status.Records)
+
+	_, err = service.Refresh(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrSourceNotFound)
+}
+
+func TestService_UnreachableSource(t *testing.T) {
+	var calls int32
+	var fail atomic.Bool
+	fail.Store(true)
+	server := librenms(t, &calls, &fail)
+	service := NewService([]Source{newSource(t, server.URL)})
+
+	local := models.CI{ID: uuid.New(), Name: "core-sw-01", Type: "network_device"}
+	records, statuses := service.Merge(context.Background(), "network_device", []models.CI{local}, nil)
+	require.Len(t, records, 1)
+	assert.Equal(t, LocalSource, records[0].Origin.Source)
+	assert.Empty(t, records[0].Origin.MergedFrom)
+	require.Len(t, statuses, 1)
+	assert.NotEmpty(t, statuses[0].Error)
+	assert.False(t, statuses[0].Stale)
+	assert.Nil(t, statuses[0].FetchedAt)
+}
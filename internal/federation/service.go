@@ -0,0 +1,377 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxRecords bounds the records kept per source
+	MaxRecords = 10000
+	// MaxLocalRecords bounds the local CIs merged with the records of a federated type
+	MaxLocalRecords = 1000
+	// maxResponseSize bounds the response read from a source
+	maxResponseSize = 64 << 20
+)
+
+// entry caches the records of a source
+type entry struct {
+	mu          sync.Mutex // held while fetching so concurrent reads share one request
+	records     []Record
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	err         error
+}
+
+// Service fetches, caches and merges the records of the federation sources
+type Service struct {
+	sources []Source
+	client  *http.Client
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*entry
+}
+
+// NewService creates a new federation service. Sources must have been validated.
+func NewService(sources []Source) *Service {
+	return &Service{
+		sources: sources,
+		client:  &http.Client{},
+		now:     time.Now,
+		cache:   map[string]*entry{},
+	}
+}
+
+// Federated reports whether records of the CI type are served by a source
+func (s *Service) Federated(ciType string) bool {
+	for _, source := range s.sources {
+		if source.Type == ciType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sources reports the cache state of every source without fetching
+func (s *Service) Sources() []SourceStatus {
+	statuses := make([]SourceStatus, 0, len(s.sources))
+	for _, source := range s.sources {
+		e := s.entry(source.Name)
+		e.mu.Lock()
+		statuses = append(statuses, e.status(source, false))
+		e.mu.Unlock()
+	}
+	return statuses
+}
+
+// Refresh fetches the records of a source now, whatever the age of its cache
+func (s *Service) Refresh(ctx context.Context, name string) (*SourceStatus, error) {
+	for _, source := range s.sources {
+		if source.Name == name {
+			_, status := s.records(ctx, source, true)
+			return &status, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, name)
+}
+
+// Merge combines the local CIs of a federated type with the records of its
+// sources matching the list filters. A record named like a local CI is merged
+// into it rather than listed twice. Local CIs come first in their own order,
+// followed by the federated records by name. Sources that fail are reported in
+// the statuses and served from cache when possible.
+func (s *Service) Merge(ctx context.Context, ciType string, local []models.CI, filter *models.ListCIsRequest) ([]Record, []SourceStatus) {
+	records := make([]Record, 0, len(local))
+	byName := make(map[string]int, len(local))
+	for _, ci := range local {
+		byName[strings.ToLower(ci.Name)] = len(records)
+		records = append(records, Record{CI: ci, Origin: Origin{Source: LocalSource}})
+	}
+
+	var federated []Record
+	statuses := []SourceStatus{}
+	for _, source := range s.sources {
+		if source.Type != ciType {
+			continue
+		}
+		fetched, status := s.records(ctx, source, false)
+		statuses = append(statuses, status)
+
+		for _, record := range fetched {
+			if i, ok := byName[strings.ToLower(record.Name)]; ok {
+				if records[i].Origin.MergedFrom == "" {
+					mergeInto(&records[i], record)
+				}
+				continue
+			}
+			if matches(&record.CI, filter) {
+				federated = append(federated, record)
+			}
+		}
+	}
+
+	sort.SliceStable(federated, func(i, j int) bool { return federated[i].Name < federated[j].Name })
+	return append(records, federated...), statuses
+}
+
+// Get finds a federated record by its CI ID
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Record, error) {
+	for _, source := range s.sources {
+		records, _ := s.records(ctx, source, false)
+		for i := range records {
+			if records[i].ID == id {
+				record := records[i]
+				return &record, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRecordNotFound, id)
+}
+
+// entry returns the cache entry of a source
+func (s *Service) entry(name string) *entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.cache[name]
+	if !ok {
+		e = &entry{}
+		s.cache[name] = e
+	}
+	return e
+}
+
+// records returns the cached records of a source, fetching them when the cache
+// has expired or force is set. When the fetch fails, the previous records are
+// served marked stale and the source is not tried again until the TTL passes.
+func (s *Service) records(ctx context.Context, source Source, force bool) ([]Record, SourceStatus) {
+	e := s.entry(source.Name)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := s.now()
+	if force || e.attemptedAt.IsZero() || now.Sub(e.attemptedAt) >= source.CacheTTL {
+		e.attemptedAt = now
+		records, err := s.fetch(ctx, source, now)
+		if err != nil {
+			log.Printf("Failed to fetch federation source %s: %v", source.Name, err)
+			e.err = err
+		} else {
+			e.records, e.fetchedAt, e.err = records, now, nil
+		}
+	}
+
+	if e.err == nil {
+		return e.records, e.status(source, false)
+	}
+	stale := make([]Record, len(e.records))
+	for i, record := range e.records {
+		record.Origin.Stale = true
+		stale[i] = record
+	}
+	return stale, e.status(source, true)
+}
+
+// status reports the state of the cache entry of a source
+func (e *entry) status(source Source, stale bool) SourceStatus {
+	status := SourceStatus{Name: source.Name, Type: source.Type, Records: len(e.records), Stale: stale && len(e.records) > 0}
+	if !e.fetchedAt.IsZero() {
+		fetchedAt := e.fetchedAt
+		status.FetchedAt = &fetchedAt
+	}
+	if e.err != nil {
+		status.Error = e.err.Error()
+	}
+	return status
+}
+
+// fetch requests the records of a source
+func (s *Service) fetch(ctx context.Context, source Source, now time.Time) ([]Record, error) {
+	ctx, cancel := context.WithTimeout(ctx, source.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range source.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("source responded %s", resp.Status)
+	}
+
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	items, ok := lookup(body, source.ItemsPath).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("response has no record array at %q", source.ItemsPath)
+	}
+
+	records := make([]Record, 0, len(items))
+	skipped := 0
+	for _, item := range items {
+		if len(records) == MaxRecords {
+			log.Printf("Federation source %s returned more than %d records, the rest are ignored", source.Name, MaxRecords)
+			break
+		}
+		record, ok := toRecord(source, item, now)
+		if !ok {
+			skipped++
+			continue
+		}
+		records = append(records, record)
+	}
+	if skipped > 0 {
+		log.Printf("Skipped %d records without an ID or name from federation source %s", skipped, source.Name)
+	}
+	return records, nil
+}
+
+// toRecord maps a remote item to a federated CI
+func toRecord(source Source, item interface{}, now time.Time) (Record, bool) {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return Record{}, false
+	}
+	externalID := text(lookup(fields, source.IDField))
+	name := text(lookup(fields, source.NameField))
+	if externalID == "" || name == "" {
+		return Record{}, false
+	}
+
+	attributes := fields
+	if len(source.Attributes) > 0 {
+		attributes = make(map[string]interface{}, len(source.Attributes))
+		for attribute, field := range source.Attributes {
+			if value := lookup(fields, field); value != nil {
+				attributes[attribute] = value
+			}
+		}
+	}
+	raw, err := json.Marshal(attributes)
+	if err != nil {
+		return Record{}, false
+	}
+
+	status := "active"
+	if source.StatusField != "" {
+		if value := text(lookup(fields, source.StatusField)); value != "" {
+			status = value
+		}
+	}
+
+	fetchedAt := now
+	return Record{
+		CI: models.CI{
+			ID:         source.RecordID(externalID),
+			Name:       name,
+			Type:       source.Type,
+			Status:     status,
+			Attributes: raw,
+			Tags:       []string{},
+			IsActive:   true,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		},
+		Origin: Origin{Source: source.Name, ExternalID: externalID, FetchedAt: &fetchedAt},
+	}, true
+}
+
+// mergeInto fills in the attributes a local CI lacks from the federated record
+// of the same name
+func mergeInto(local *Record, remote Record) {
+	attributes := map[string]interface{}{}
+	json.Unmarshal(remote.Attributes, &attributes)
+	own := map[string]interface{}{}
+	if len(local.Attributes) > 0 {
+		json.Unmarshal(local.Attributes, &own)
+	}
+	for name, value := range own {
+		attributes[name] = value
+	}
+	if raw, err := json.Marshal(attributes); err == nil {
+		local.Attributes = raw
+	}
+
+	local.Origin.MergedFrom = remote.Origin.Source
+	local.Origin.ExternalID = remote.Origin.ExternalID
+	local.Origin.FetchedAt = remote.Origin.FetchedAt
+	local.Origin.Stale = remote.Origin.Stale
+}
+
+// matches applies the CI list filters to a federated record, which has no
+// owner, location, org unit, cost center or tags of its own
+func matches(ci *models.CI, filter *models.ListCIsRequest) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(ci.Name), search) && !strings.Contains(strings.ToLower(ci.Type), search) {
+			return false
+		}
+	}
+	if filter.Status != "" && filter.Status != ci.Status {
+		return false
+	}
+	if filter.Criticality != "" || filter.Owner != "" || filter.Location != "" ||
+		filter.OrgUnit != "" || filter.CostCenter != "" || len(filter.Tags) > 0 {
+		return false
+	}
+	return true
+}
+
+// lookup follows a dot-separated path through decoded JSON objects
+func lookup(value interface{}, path string) interface{} {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// text renders a scalar JSON value as a string
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number, bool:
+		return fmt.Sprint(v)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,114 @@
+// Package federation proxies reads of CI types whose system of record lives
+// elsewhere, e.g. network devices kept in LibreNMS, instead of duplicating them
+// in the CMDB. Records are fetched from each source's HTTP JSON API, cached for
+// the source's TTL and merged with the local CIs of the same type, each record
+// carrying its origin.
+package federation
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// LocalSource is the origin of records stored in the CMDB
+const LocalSource = "local"
+
+// Defaults for sources that leave them unset
+const (
+	DefaultTimeout  = 10 * time.Second
+	DefaultCacheTTL = 5 * time.Minute
+)
+
+var (
+	ErrInvalidSource  = errors.New("invalid federation source")
+	ErrSourceNotFound = errors.New("federation source not found")
+	ErrRecordNotFound = errors.New("federated record not found")
+)
+
+// namespace seeds the stable IDs of federated records
+var namespace = uuid.MustParse("6f1c2a9e-3d4b-5e8f-9a0b-1c2d3e4f5a6b")
+
+// Source is an external system of record serving one CI type
+type Source struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // CI type served
+	URL  string `json:"url"`
+	// ItemsPath is the dot-separated path to the record array in the
+	// response, e.g. "devices"; empty when the response is the array
+	ItemsPath   string `json:"items_path,omitempty"`
+	IDField     string `json:"id_field"`
+	NameField   string `json:"name_field"`
+	StatusField string `json:"status_field,omitempty"`
+	// Attributes maps CI attributes to remote fields; empty copies every field
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Headers are sent with every request, values expanded from the environment
+	Headers  map[string]string `json:"-"`
+	Timeout  time.Duration     `json:"timeout"`
+	CacheTTL time.Duration     `json:"cache_ttl"`
+}
+
+// Validate checks the source and fills in the default timeout and cache TTL
+func (s *Source) Validate() error {
+	if s.Name == "" || s.Name == LocalSource {
+		return fmt.Errorf("%w: name is required and may not be %q", ErrInvalidSource, LocalSource)
+	}
+	if s.Type == "" {
+		return fmt.Errorf("%w: %s has no CI type", ErrInvalidSource, s.Name)
+	}
+	parsed, err := url.Parse(s.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: %s needs an http or https URL", ErrInvalidSource, s.Name)
+	}
+	if s.IDField == "" || s.NameField == "" {
+		return fmt.Errorf("%w: %s needs an ID and a name field", ErrInvalidSource, s.Name)
+	}
+	if s.Timeout < 0 || s.CacheTTL < 0 {
+		return fmt.Errorf("%w: %s has a negative timeout or cache TTL", ErrInvalidSource, s.Name)
+	}
+	if s.Timeout == 0 {
+		s.Timeout = DefaultTimeout
+	}
+	if s.CacheTTL == 0 {
+		s.CacheTTL = DefaultCacheTTL
+	}
+	return nil
+}
+
+// RecordID returns the stable CI ID of a record of the source
+func (s *Source) RecordID(externalID string) uuid.UUID {
+	return uuid.NewSHA1(namespace, []byte(s.Name+"/"+externalID))
+}
+
+// Origin tells where a record came from
+type Origin struct {
+	// Source is "local" or the name of the federation source
+	Source     string `json:"source"`
+	ExternalID string `json:"external_id,omitempty"`
+	// MergedFrom names the source whose record of the same name was merged
+	// into a local CI; local attribute values take precedence
+	MergedFrom string     `json:"merged_from,omitempty"`
+	FetchedAt  *time.Time `json:"fetched_at,omitempty"`
+	// Stale is set when the source failed and the record was served from cache
+	Stale bool `json:"stale,omitempty"`
+}
+
+// Record is a CI, local or federated, with its origin
+type Record struct {
+	models.CI
+	Origin Origin `json:"origin"`
+}
+
+// SourceStatus reports the state of a source's cache
+type SourceStatus struct {
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Records   int        `json:"records"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	Stale     bool       `json:"stale,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
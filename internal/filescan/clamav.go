@@ -0,0 +1,97 @@
+package filescan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamAVChunkSize is the size of the chunks streamed to clamd, below its
+// default StreamMaxLength
+const clamAVChunkSize = 64 << 10
+
+// ClamAVScanner scans files with clamd, streaming them with the INSTREAM command
+type ClamAVScanner struct {
+	// Address is the host:port of clamd, or the path of its unix socket
+	Address string
+	Timeout time.Duration
+}
+
+// Name identifies the backend
+func (c *ClamAVScanner) Name() string {
+	return BackendClamAV
+}
+
+// Scan streams the content to clamd and reads its verdict
+func (c *ClamAVScanner) Scan(ctx context.Context, content io.Reader) (Verdict, error) {
+	network := "tcp"
+	if strings.HasPrefix(c.Address, "/") {
+		network = "unix"
+	}
+	dialer := net.Dialer{Timeout: c.Timeout}
+	conn, err := dialer.DialContext(ctx, network, c.Address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	setDeadline(ctx, conn, c.Timeout)
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	buf := make([]byte, clamAVChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return Verdict{}, fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return Verdict{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamAVReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamAVReply reads "stream: OK", "stream: <signature> FOUND" or an error reply
+func parseClamAVReply(reply string) (Verdict, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return Verdict{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	}
+	return Verdict{}, fmt.Errorf("clamd failed to scan: %s", reply)
+}
+
+// setDeadline bounds a connection by the earlier of the context deadline and the timeout
+func setDeadline(ctx context.Context, conn net.Conn, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+}
@@ -0,0 +1,163 @@
+// Package filescan scans uploaded files for viruses and malware through a
+// pluggable backend, ClamAV or an ICAP server. Uploads are to be stored
+// quarantined with a pending scan status, served only once their scan
+// status is clean, and rejected when infected. With no backend configured
+// uploads must not be accepted at all.
+package filescan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Scan statuses recorded on the metadata of an upload
+const (
+	// StatusPending uploads are quarantined until scanned
+	StatusPending = "pending"
+	// StatusClean uploads may be served
+	StatusClean = "clean"
+	// StatusInfected uploads are rejected
+	StatusInfected = "infected"
+	// StatusFailed uploads could not be scanned and stay quarantined until
+	// a scan succeeds
+	StatusFailed = "failed"
+)
+
+// Backends
+const (
+	BackendNone   = "none"
+	BackendClamAV = "clamav"
+	BackendICAP   = "icap"
+)
+
+// Defaults
+const (
+	DefaultTimeout = 30 * time.Second
+	DefaultMaxSize = 25 << 20
+)
+
+var (
+	ErrNoScanner = errors.New("no malware scanner is configured, uploads are disabled")
+	ErrInfected  = errors.New("file is infected")
+	ErrTooLarge  = errors.New("file exceeds the scan size limit")
+)
+
+// Verdict is what a scanner found in a file
+type Verdict struct {
+	Infected bool
+	// Signature names what was found, when the scanner tells
+	Signature string
+}
+
+// Scanner scans the content of a file
+type Scanner interface {
+	// Name identifies the backend in scan results
+	Name() string
+	Scan(ctx context.Context, content io.Reader) (Verdict, error)
+}
+
+// Options select and configure the scanner backend
+type Options struct {
+	Backend string
+	// Address is the host:port or unix socket path of clamd, or the
+	// icap://host[:port]/service URL of the ICAP server
+	Address string
+	Timeout time.Duration
+}
+
+// New creates the scanner of a backend. ErrNoScanner is returned when none
+// is configured.
+func New(options Options) (Scanner, error) {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	switch options.Backend {
+	case "", BackendNone:
+		return nil, ErrNoScanner
+	case BackendClamAV:
+		if options.Address == "" {
+			return nil, errors.New("clamav scanner requires an address")
+		}
+		return &ClamAVScanner{Address: options.Address, Timeout: options.Timeout}, nil
+	case BackendICAP:
+		return NewICAPScanner(options.Address, options.Timeout)
+	}
+	return nil, fmt.Errorf("unknown malware scanner backend %q, expected %s, %s or %s", options.Backend, BackendNone, BackendClamAV, BackendICAP)
+}
+
+// Result is the scan status of an upload, kept on its metadata
+type Result struct {
+	Status    string     `json:"status"`
+	Signature string     `json:"signature,omitempty"`
+	Scanner   string     `json:"scanner,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	ScannedAt *time.Time `json:"scanned_at,omitempty"`
+}
+
+// Released reports whether an upload with the result may be served
+func (r Result) Released() bool {
+	return r.Status == StatusClean
+}
+
+// Service scans uploads and tells their scan status
+type Service struct {
+	scanner Scanner
+	maxSize int64
+	now     func() time.Time
+}
+
+// NewService creates a new file scanning service. Files larger than maxSize
+// bytes are not scanned and fail.
+func NewService(scanner Scanner, maxSize int64) *Service {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &Service{scanner: scanner, maxSize: maxSize, now: time.Now}
+}
+
+// Pending returns the status of an upload stored in quarantine, before it is scanned
+func (s *Service) Pending() Result {
+	return Result{Status: StatusPending, Scanner: s.scanner.Name()}
+}
+
+// Scan scans an upload and returns the status to record on it. The error
+// is ErrInfected for infected files, which must be rejected, and describes
+// why the scan failed otherwise, in which case the upload stays quarantined.
+func (s *Service) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	counted := &countingReader{reader: io.LimitReader(content, s.maxSize+1)}
+	verdict, err := s.scanner.Scan(ctx, counted)
+	scannedAt := s.now()
+	result := Result{Scanner: s.scanner.Name(), ScannedAt: &scannedAt}
+
+	// A file cut short was not scanned whole, so the verdict cannot be trusted
+	if err == nil && counted.n > s.maxSize {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return result, err
+	}
+	if verdict.Infected {
+		result.Status = StatusInfected
+		result.Signature = verdict.Signature
+		return result, ErrInfected
+	}
+	result.Status = StatusClean
+	return result, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
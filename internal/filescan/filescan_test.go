@@ -0,0 +1,198 @@
+package filescan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// serve answers one connection on a local listener with handle
+func serve(t *testing.T, handle func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+	return listener.Addr().String()
+}
+
+// fakeClamd reads an INSTREAM command and flags content holding the EICAR string
+func fakeClamd(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	if command, err := reader.ReadString(0); err != nil || command != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var content bytes.Buffer
+	size := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, size); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+		if _, err := io.CopyN(&content, reader, int64(n)); err != nil {
+			return
+		}
+	}
+	if strings.Contains(content.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+		conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamAVScanner(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		verdict Verdict
+	}{
+		{"clean", strings.Repeat("inventory,", 20000), Verdict{}},
+		{"infected", eicar, Verdict{Infected: true, Signature: "Eicar-Test-Signature"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner := &ClamAVScanner{Address: serve(t, fakeClamd), Timeout: time.Second}
+			verdict, err := scanner.Scan(context.Background(), strings.NewReader(tc.content))
+			require.NoError(t, err)
+			assert.Equal(t, tc.verdict, verdict)
+		})
+	}
+}
+
+func TestParseClamAVReplyErrors(t *testing.T) {
+	_, err := parseClamAVReply("INSTREAM size limit exceeded. ERROR")
+	assert.Error(t, err)
+}
+
+// fakeICAP reads a RESPMOD request and blocks content holding the EICAR string
+func fakeICAP(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	requestLine, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	// Skip the ICAP headers and the encapsulated HTTP headers
+	for blank := 0; blank < 2; {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if line == "\r\n" {
+			blank++
+		}
+	}
+	var content bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+		if err != nil || n == 0 {
+			break
+		}
+		io.CopyN(&content, reader, n)
+		reader.ReadString('\n')
+	}
+
+	switch {
+	case !strings.HasPrefix(requestLine, "RESPMOD icap://"):
+		conn.Write([]byte("ICAP/1.0 400 Bad Request\r\n\r\n"))
+	case strings.Contains(content.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"):
+		conn.Write([]byte("ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Eicar-Test-Signature;\r\nEncapsulated: null-body=0\r\n\r\n"))
+	default:
+		conn.Write([]byte("ICAP/1.0 204 No Content\r\n\r\n"))
+	}
+}
+
+func TestICAPScanner(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		verdict Verdict
+	}{
+		{"clean", strings.Repeat("inventory,", 20000), Verdict{}},
+		{"infected", eicar, Verdict{Infected: true, Signature: "Eicar-Test-Signature"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner, err := NewICAPScanner("icap://"+serve(t, fakeICAP)+"/avscan", time.Second)
+			require.NoError(t, err)
+			verdict, err := scanner.Scan(context.Background(), strings.NewReader(tc.content))
+			require.NoError(t, err)
+			assert.Equal(t, tc.verdict, verdict)
+		})
+	}
+}
+
+type stubScanner struct {
+	verdict Verdict
+	err     error
+}
+
+func (s stubScanner) Name() string { return "stub" }
+
+func (s stubScanner) Scan(ctx context.Context, content io.Reader) (Verdict, error) {
+	io.Copy(io.Discard, content)
+	return s.verdict, s.err
+}
+
+func TestServiceScan(t *testing.T) {
+	ctx := context.Background()
+
+	result, err := NewService(stubScanner{}, 0).Scan(ctx, strings.NewReader("clean"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusClean, result.Status)
+	assert.True(t, result.Released())
+
+	result, err = NewService(stubScanner{verdict: Verdict{Infected: true, Signature: "Eicar"}}, 0).Scan(ctx, strings.NewReader(eicar))
+	assert.ErrorIs(t, err, ErrInfected)
+	assert.Equal(t, StatusInfected, result.Status)
+	assert.Equal(t, "Eicar", result.Signature)
+	assert.False(t, result.Released())
+
+	result, err = NewService(stubScanner{err: errors.New("connection refused")}, 0).Scan(ctx, strings.NewReader("file"))
+	assert.Error(t, err)
+	assert.Equal(t, StatusFailed, result.Status)
+	assert.False(t, result.Released())
+
+	// Files over the limit are never released, whatever the scanner read of them
+	result, err = NewService(stubScanner{}, 4).Scan(ctx, strings.NewReader("too large"))
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, StatusFailed, result.Status)
+
+	assert.Equal(t, StatusPending, NewService(stubScanner{}, 0).Pending().Status)
+}
+
+func TestNewRequiresBackend(t *testing.T) {
+	_, err := New(Options{Backend: BackendNone})
+	assert.ErrorIs(t, err, ErrNoScanner)
+
+	_, err = New(Options{Backend: BackendICAP, Address: "http://scanner"})
+	assert.Error(t, err)
+
+	scanner, err := New(Options{Backend: BackendClamAV, Address: "/var/run/clamav/clamd.ctl"})
+	require.NoError(t, err)
+	assert.Equal(t, BackendClamAV, scanner.Name())
+}
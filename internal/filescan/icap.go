@@ -0,0 +1,147 @@
+package filescan
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icapDefaultPort is the port of ICAP URLs that do not set one
+const icapDefaultPort = "1344"
+
+// icapInfectionHeaders are the headers ICAP servers name what they found in
+var icapInfectionHeaders = []string{"X-Infection-Found", "X-Virus-ID", "X-Violations-Found"}
+
+// encapsulatedResponseHeader wraps the file as the HTTP response an ICAP
+// server is asked to modify
+const encapsulatedResponseHeader = "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\n\r\n"
+
+// ICAPScanner scans files with an ICAP server (RFC 3507), sending them as a
+// RESPMOD request. The server answering 204 means the file is clean; any
+// modification of it means it was blocked.
+type ICAPScanner struct {
+	url     *url.URL
+	timeout time.Duration
+}
+
+// NewICAPScanner creates a scanner for an icap://host[:port]/service URL
+func NewICAPScanner(rawURL string, timeout time.Duration) (*ICAPScanner, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "icap" || parsed.Hostname() == "" {
+		return nil, errors.New("icap scanner requires an icap://host[:port]/service address")
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &ICAPScanner{url: parsed, timeout: timeout}, nil
+}
+
+// Name identifies the backend
+func (s *ICAPScanner) Name() string {
+	return BackendICAP
+}
+
+// Scan sends the content to the ICAP server and reads its verdict
+func (s *ICAPScanner) Scan(ctx context.Context, content io.Reader) (Verdict, error) {
+	port := s.url.Port()
+	if port == "" {
+		port = icapDefaultPort
+	}
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.url.Hostname(), port))
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to connect to ICAP server: %w", err)
+	}
+	defer conn.Close()
+	setDeadline(ctx, conn, s.timeout)
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", s.url.String())
+	fmt.Fprintf(w, "Host: %s\r\n", s.url.Host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(encapsulatedResponseHeader))
+	w.WriteString(encapsulatedResponseHeader)
+	if err := writeChunked(w, content); err != nil {
+		return Verdict{}, err
+	}
+	if err := w.Flush(); err != nil {
+		return Verdict{}, fmt.Errorf("failed to send to ICAP server: %w", err)
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	status, err := reader.ReadLine()
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to read ICAP reply: %w", err)
+	}
+	headers, err := reader.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Verdict{}, fmt.Errorf("failed to read ICAP reply: %w", err)
+	}
+	return parseICAPReply(status, headers)
+}
+
+// writeChunked writes the content with HTTP chunked encoding
+func writeChunked(w *bufio.Writer, content io.Reader) error {
+	buf := make([]byte, 64<<10)
+	for {
+		n, readErr := content.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+	_, err := w.WriteString("0\r\n\r\n")
+	return err
+}
+
+// parseICAPReply reads the verdict from the status line and headers of an ICAP reply
+func parseICAPReply(status string, headers textproto.MIMEHeader) (Verdict, error) {
+	fields := strings.Fields(status)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "ICAP/") {
+		return Verdict{}, fmt.Errorf("invalid ICAP reply: %q", status)
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Verdict{}, fmt.Errorf("invalid ICAP reply: %q", status)
+	}
+
+	switch code {
+	case 204:
+		return Verdict{}, nil
+	case 200:
+		// The server replaced the file, e.g. with a block page
+		for _, header := range icapInfectionHeaders {
+			if value := headers.Get(header); value != "" {
+				return Verdict{Infected: true, Signature: icapSignature(value)}, nil
+			}
+		}
+		return Verdict{Infected: true}, nil
+	}
+	return Verdict{}, fmt.Errorf("ICAP server failed to scan: %s", status)
+}
+
+// icapSignature extracts the threat name of an X-Infection-Found header,
+// "Type=0; Resolution=2; Threat=Eicar-Test-Signature;", or returns the header
+func icapSignature(value string) string {
+	for _, part := range strings.Split(value, ";") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(part), "Threat="); ok {
+			return name
+		}
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,393 @@
+package forcesync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCI is a CI in the memory store
+type testCI struct {
+	id        uuid.UUID
+	ciType    string
+	tags      []string
+	updatedAt time.Time
+}
+
+// testRelationship is a relationship in the memory store
+type testRelationship struct {
+	id             uuid.UUID
+	source, target uuid.UUID
+}
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	mu            sync.Mutex
+	cis           []testCI
+	relationships []testRelationship
+	jobs          map[uuid.UUID]*Job
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: map[uuid.UUID]*Job{}}
+}
+
+func (m *memoryStore) addCI(ciType string, updatedAt time.Time, tags ...string) uuid.UUID {
+	id := uuid.New()
+	m.cis = append(m.cis, testCI{id: id, ciType: ciType, tags: tags, updatedAt: updatedAt})
+	return id
+}
+
+func (m *memoryStore) matches(ci testCI, filter Filter) bool {
+	if len(filter.Types) > 0 && !contains(filter.Types, ci.ciType) {
+		return false
+	}
+	if len(filter.Tags) > 0 {
+		tagged := false
+		for _, tag := range ci.tags {
+			tagged = tagged || contains(filter.Tags, tag)
+		}
+		if !tagged {
+			return false
+		}
+	}
+	return filter.UpdatedSince == nil || !ci.updatedAt.Before(*filter.UpdatedSince)
+}
+
+func (m *memoryStore) matching(entity string, filter Filter) []Entity {
+	matched := map[uuid.UUID]bool{}
+	entities := []Entity{}
+	for _, ci := range m.cis {
+		if m.matches(ci, filter) {
+			matched[ci.id] = true
+			if entity == EntityConfigurationItem {
+				entities = append(entities, Entity{ID: ci.id, Data: map[string]interface{}{"type": ci.ciType}})
+			}
+		}
+	}
+	if entity == EntityRelationship {
+		for _, rel := range m.relationships {
+			if matched[rel.source] || matched[rel.target] {
+				entities = append(entities, Entity{ID: rel.id, Data: map[string]interface{}{}})
+			}
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool { return bytes.Compare(entities[i].ID[:], entities[j].ID[:]) < 0 })
+	return entities
+}
+
+func (m *memoryStore) CountEntities(ctx context.Context, entity string, filter Filter) (int64, error) {
+	return int64(len(m.matching(entity, filter))), nil
+}
+
+func (m *memoryStore) ListEntities(ctx context.Context, entity string, filter Filter, after uuid.UUID, limit int) ([]Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entities := []Entity{}
+	for _, e := range m.matching(entity, filter) {
+		if bytes.Compare(e.ID[:], after[:]) > 0 && len(entities) < limit {
+			entities = append(entities, e)
+		}
+	}
+	return entities, nil
+}
+
+func (m *memoryStore) save(job *Job) {
+	copied := *job
+	copied.Phases = append([]Phase(nil), job.Phases...)
+	m.jobs[job.ID] = &copied
+}
+
+func (m *memoryStore) CreateJob(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.save(job)
+	return nil
+}
+
+func (m *memoryStore) UpdateJob(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.jobs[job.ID]; !ok {
+		return ErrJobNotFound
+	}
+	m.save(job)
+	return nil
+}
+
+func (m *memoryStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	copied.Phases = append([]Phase(nil), job.Phases...)
+	return &copied, nil
+}
+
+func (m *memoryStore) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	m.mu.Lock()
+	ids := []uuid.UUID{}
+	for id := range m.jobs {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	jobs := []*Job{}
+	for _, id := range ids {
+		job, _ := m.GetJob(ctx, id)
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (m *memoryStore) ListRunningJobs(ctx context.Context) ([]*Job, error) {
+	jobs, _ := m.ListJobs(ctx, 0)
+	running := []*Job{}
+	for _, job := range jobs {
+		if !job.Finished() {
+			running = append(running, job)
+		}
+	}
+	return running, nil
+}
+
+// recordingRecorder records enqueued events. Once block is set it holds the
+// next event until the run is stopped; IDs in fail are rejected.
+type recordingRecorder struct {
+	mu       sync.Mutex
+	recorded []string
+	fail     map[string]bool
+	block    bool
+	blocked  chan struct{}
+}
+
+func (r *recordingRecorder) RecordEvent(ctx context.Context, entityType, entityID, action string, data map[string]interface{}) error {
+	r.mu.Lock()
+	if r.block {
+		r.block = false
+		r.mu.Unlock()
+		close(r.blocked)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	defer r.mu.Unlock()
+	if r.fail[entityID] {
+		return errors.New("redis unavailable")
+	}
+	r.recorded = append(r.recorded, entityType+":"+action)
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func waitForStatus(t *testing.T, service *Service, id uuid.UUID, status string) *Job {
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = service.GetJob(context.Background(), id)
+		return err == nil && job.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func unlimited() *int {
+	rate := 0
+	return &rate
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFilterValidate(t *testing.T) {
+	assert.ErrorIs(t, (&Filter{}).Validate(), ErrInvalidRequest)
+	assert.ErrorIs(t, (&Filter{IncludeRelationships: true}).Validate(), ErrInvalidRequest)
+	assert.ErrorIs(t, (&Filter{Types: []string{""}}).Validate(), ErrInvalidRequest)
+	assert.ErrorIs(t, (&Filter{Tags: []string{"prod", ""}}).Validate(), ErrInvalidRequest)
+
+	since := now
+	assert.NoError(t, (&Filter{UpdatedSince: &since}).Validate())
+	assert.NoError(t, (&Filter{Types: []string{"server"}, Tags: []string{"prod"}}).Validate())
+}
+
+func TestService_StartEnqueuesMatchingCIs(t *testing.T) {
+	store := newMemoryStore()
+	web := store.addCI("server", now, "prod")
+	store.addCI("server", now.Add(-48*time.Hour), "prod")
+	store.addCI("server", now, "dev")
+	db := store.addCI("database", now, "prod")
+	other := store.addCI("switch", now)
+	store.relationships = []testRelationship{
+		{id: uuid.New(), source: web, target: db},
+		{id: uuid.New(), source: other, target: web},
+		{id: uuid.New(), source: other, target: other},
+	}
+
+	recorder := &recordingRecorder{}
+	service := NewService(store, recorder, 1, 0, 0)
+	since := now.Add(-time.Hour)
+	job, err := service.Start(context.Background(), StartRequest{
+		Filter: Filter{
+			Types:                []string{"server", "database"},
+			Tags:                 []string{"prod"},
+			UpdatedSince:         &since,
+			IncludeRelationships: true,
+		},
+		RateLimit: unlimited(),
+	}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, job.Status)
+	require.Len(t, job.Phases, 2)
+	assert.Equal(t, int64(2), job.Phases[0].Total)
+	assert.Equal(t, int64(2), job.Phases[1].Total)
+
+	job = waitForStatus(t, service, job.ID, StatusCompleted)
+	assert.NotNil(t, job.CompletedAt)
+	assert.Equal(t, int64(2), job.Phases[0].Enqueued)
+	assert.Equal(t, int64(2), job.Phases[1].Enqueued)
+
+	// CIs are enqueued before the relationships touching them, as updates
+	assert.Equal(t, []string{
+		"configuration_item:UPDATE", "configuration_item:UPDATE",
+		"relationship:UPDATE", "relationship:UPDATE",
+	}, recorder.recorded)
+}
+
+func TestService_StartCountsFailures(t *testing.T) {
+	// Fixed IDs so the failing CI is last in keyset order and so the checkpoint,
+	// which advances past failed entities too, ends on it
+	store := newMemoryStore()
+	failing := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	store.cis = append(store.cis,
+		testCI{id: uuid.MustParse("00000000-0000-0000-0000-000000000001"), ciType: "server", updatedAt: now},
+		testCI{id: failing, ciType: "server", updatedAt: now},
+	)
+
+	recorder := &recordingRecorder{fail: map[string]bool{failing.String(): true}}
+	service := NewService(store, recorder, 10, 0, 0)
+	job, err := service.Start(context.Background(), StartRequest{Filter: Filter{Types: []string{"server"}}, RateLimit: unlimited()}, "admin")
+	require.NoError(t, err)
+
+	job = waitForStatus(t, service, job.ID, StatusCompleted)
+	require.Len(t, job.Phases, 1)
+	assert.Equal(t, int64(1), job.Phases[0].Enqueued)
+	assert.Equal(t, int64(1), job.Phases[0].Failed)
+	assert.Equal(t, failing, job.Phases[0].Checkpoint)
+}
+
+func TestService_StartRejectsInvalid(t *testing.T) {
+	service := NewService(newMemoryStore(), &recordingRecorder{}, 0, 0, 0)
+
+	_, err := service.Start(context.Background(), StartRequest{}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	rate := MaxRateLimit + 1
+	_, err = service.Start(context.Background(), StartRequest{Filter: Filter{Types: []string{"server"}}, RateLimit: &rate}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestService_CancelAndLimit(t *testing.T) {
+	store := newMemoryStore()
+	store.addCI("server", now)
+	store.addCI("server", now)
+	recorder := &recordingRecorder{block: true, blocked: make(chan struct{})}
+	service := NewService(store, recorder, 1, 0, 1)
+	ctx := context.Background()
+	req := StartRequest{Filter: Filter{Types: []string{"server"}}, RateLimit: unlimited()}
+
+	job, err := service.Start(ctx, req, "admin")
+	require.NoError(t, err)
+	<-recorder.blocked
+
+	// Only one job may run at a time here
+	_, err = service.Start(ctx, req, "admin")
+	assert.ErrorIs(t, err, ErrTooManyJobs)
+
+	job, err = service.Cancel(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, job.Status)
+	assert.Equal(t, int64(0), job.Phases[0].Enqueued)
+
+	_, err = service.Cancel(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+	_, err = service.Cancel(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_Recover(t *testing.T) {
+	store := newMemoryStore()
+	for i := 0; i < 3; i++ {
+		store.addCI("server", now)
+	}
+	entities, _ := store.ListEntities(context.Background(), EntityConfigurationItem, Filter{Types: []string{"server"}}, uuid.Nil, 10)
+
+	// A job interrupted after its first CI
+	interrupted := &Job{
+		ID:        uuid.New(),
+		Status:    StatusRunning,
+		Filter:    Filter{Types: []string{"server"}},
+		Phases:    []Phase{{Entity: EntityConfigurationItem, Total: 3, Enqueued: 1, Checkpoint: entities[0].ID}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, store.CreateJob(context.Background(), interrupted))
+
+	recorder := &recordingRecorder{}
+	service := NewService(store, recorder, 10, 0, 0)
+	require.NoError(t, service.Recover(context.Background()))
+
+	job := waitForStatus(t, service, interrupted.ID, StatusCompleted)
+	assert.Equal(t, int64(3), job.Phases[0].Enqueued)
+	assert.Len(t, recorder.recorded, 2)
+}
+
+func TestService_RateLimit(t *testing.T) {
+	store := newMemoryStore()
+	for i := 0; i < 4; i++ {
+		store.addCI("server", now)
+	}
+	service := NewService(store, &recordingRecorder{}, 10, 0, 0)
+
+	var mu sync.Mutex
+	var slept time.Duration
+	clock := now
+	service.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	service.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		slept += d
+		clock = clock.Add(d)
+		return nil
+	}
+
+	rate := 2
+	job, err := service.Start(context.Background(), StartRequest{Filter: Filter{Types: []string{"server"}}, RateLimit: &rate}, "admin")
+	require.NoError(t, err)
+	waitForStatus(t, service, job.ID, StatusCompleted)
+
+	// Four events at two per second: the first goes right away
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1500*time.Millisecond, slept)
+}
@@ -0,0 +1,107 @@
+// Package forcesync re-enqueues sync events for the CIs matching a filter, and
+// optionally the relationships touching them, so the graph store can be
+// repaired after a partial data loss without a full resync. Events are
+// enqueued in pages at a bounded rate by a background job whose position is
+// checkpointed, so a job interrupted by a restart resumes where it stopped.
+package forcesync
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// Entity types events are enqueued for
+const (
+	EntityConfigurationItem = "configuration_item"
+	EntityRelationship      = "relationship"
+)
+
+// MaxRateLimit bounds the events enqueued per second
+const MaxRateLimit = 10000
+
+var (
+	ErrInvalidRequest = errors.New("invalid force sync request")
+	ErrJobNotFound    = errors.New("force sync job not found")
+	ErrTooManyJobs    = errors.New("too many force sync jobs running")
+	ErrInvalidState   = errors.New("force sync job cannot change to that state")
+)
+
+// Filter selects the CIs to force sync. Criteria combine with AND; at least
+// one is required, a full rebuild being the job of a resync.
+type Filter struct {
+	Types []string `json:"types,omitempty"`
+	// Tags matches CIs carrying any of the tags
+	Tags         []string   `json:"tags,omitempty"`
+	UpdatedSince *time.Time `json:"updated_since,omitempty"`
+	// IncludeRelationships also enqueues the relationships touching the matched CIs
+	IncludeRelationships bool `json:"include_relationships"`
+}
+
+// Validate checks the filter
+func (f *Filter) Validate() error {
+	if len(f.Types) == 0 && len(f.Tags) == 0 && f.UpdatedSince == nil {
+		return fmt.Errorf("%w: select CIs by types, tags or updated_since", ErrInvalidRequest)
+	}
+	for _, t := range f.Types {
+		if t == "" {
+			return fmt.Errorf("%w: types cannot be empty", ErrInvalidRequest)
+		}
+	}
+	for _, tag := range f.Tags {
+		if tag == "" {
+			return fmt.Errorf("%w: tags cannot be empty", ErrInvalidRequest)
+		}
+	}
+	return nil
+}
+
+// Job is a force sync run. Phases are worked through in order; everything
+// before CurrentPhase is done.
+type Job struct {
+	ID           uuid.UUID `json:"id"`
+	Status       string    `json:"status"`
+	Filter       Filter    `json:"filter"`
+	Phases       []Phase   `json:"phases"`
+	CurrentPhase int       `json:"current_phase"`
+	// RateLimit is the maximum number of events enqueued per second; 0 means unlimited
+	RateLimit   int        `json:"rate_limit"`
+	RequestedBy string     `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Phase enqueues the events of one entity type
+type Phase struct {
+	Entity string `json:"entity"`
+	// Total is the number of matching entities counted when the job started
+	Total    int64 `json:"total"`
+	Enqueued int64 `json:"enqueued"`
+	// Failed counts entities whose event could not be recorded
+	Failed int64 `json:"failed"`
+	// Checkpoint is the ID of the last entity processed
+	Checkpoint uuid.UUID `json:"checkpoint"`
+}
+
+// Finished reports whether the job can no longer change
+func (j *Job) Finished() bool {
+	return j.Status != StatusRunning
+}
+
+// Entity is a row to enqueue a sync event for
+type Entity struct {
+	ID   uuid.UUID
+	Data map[string]interface{}
+}
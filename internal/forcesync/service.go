@@ -0,0 +1,309 @@
+package forcesync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults
+const (
+	DefaultBatchSize = 500
+	DefaultRateLimit = 200
+	DefaultMaxJobs   = 2
+)
+
+// Recorder enqueues a sync event, as SyncService.RecordEvent does
+type Recorder interface {
+	RecordEvent(ctx context.Context, entityType, entityID, action string, data map[string]interface{}) error
+}
+
+// StartRequest selects what a force sync enqueues and how fast
+type StartRequest struct {
+	Filter Filter `json:"filter"`
+	// RateLimit is the maximum number of events per second; nil uses the
+	// configured default and 0 means unlimited
+	RateLimit *int `json:"rate_limit"`
+}
+
+// Service runs force sync jobs in the background
+type Service struct {
+	store     Store
+	recorder  Recorder
+	batchSize int
+	rateLimit int
+	maxJobs   int
+	now       func() time.Time
+	sleep     func(ctx context.Context, d time.Duration) error
+
+	mu     sync.Mutex
+	active map[uuid.UUID]*activeRun
+}
+
+// activeRun is a job running on this instance. Cancelling it ends the job as
+// cancelled after the current event.
+type activeRun struct {
+	cancel    context.CancelFunc
+	cancelled bool
+	done      chan struct{}
+}
+
+// NewService creates a new force sync service
+func NewService(store Store, recorder Recorder, batchSize, rateLimit, maxJobs int) *Service {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if rateLimit < 0 {
+		rateLimit = DefaultRateLimit
+	}
+	if maxJobs <= 0 {
+		maxJobs = DefaultMaxJobs
+	}
+	return &Service{
+		store:     store,
+		recorder:  recorder,
+		batchSize: batchSize,
+		rateLimit: rateLimit,
+		maxJobs:   maxJobs,
+		now:       time.Now,
+		sleep:     sleepContext,
+		active:    make(map[uuid.UUID]*activeRun),
+	}
+}
+
+// Start counts the entities matching the filter and starts enqueueing their
+// sync events in the background
+func (s *Service) Start(ctx context.Context, req StartRequest, requestedBy string) (*Job, error) {
+	if err := req.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	rateLimit := s.rateLimit
+	if req.RateLimit != nil {
+		rateLimit = *req.RateLimit
+	}
+	if rateLimit < 0 || rateLimit > MaxRateLimit {
+		return nil, fmt.Errorf("%w: rate_limit must be between 0 and %d", ErrInvalidRequest, MaxRateLimit)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.active) >= s.maxJobs {
+		return nil, fmt.Errorf("%w: at most %d at a time", ErrTooManyJobs, s.maxJobs)
+	}
+
+	entities := []string{EntityConfigurationItem}
+	if req.Filter.IncludeRelationships {
+		entities = append(entities, EntityRelationship)
+	}
+
+	now := s.now()
+	job := &Job{
+		ID:          uuid.New(),
+		Status:      StatusRunning,
+		Filter:      req.Filter,
+		Phases:      make([]Phase, 0, len(entities)),
+		RateLimit:   rateLimit,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for _, entity := range entities {
+		total, err := s.store.CountEntities(ctx, entity, req.Filter)
+		if err != nil {
+			return nil, err
+		}
+		job.Phases = append(job.Phases, Phase{Entity: entity, Total: total})
+	}
+	if err := s.store.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Force sync %s of %d CIs started by %s at %d events/s", job.ID, job.Phases[0].Total, requestedBy, rateLimit)
+	return s.launch(job), nil
+}
+
+// Cancel ends a running job; the events already enqueued stay enqueued
+func (s *Service) Cancel(ctx context.Context, id uuid.UUID) (*Job, error) {
+	s.mu.Lock()
+	run := s.active[id]
+	if run != nil {
+		run.cancelled = true
+		run.cancel()
+	}
+	s.mu.Unlock()
+
+	if run == nil {
+		job, err := s.store.GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: job is %s", ErrInvalidState, job.Status)
+	}
+
+	<-run.done
+	log.Printf("Force sync %s cancelled", id)
+	return s.store.GetJob(ctx, id)
+}
+
+// Recover resumes the jobs left running by a crash or restart from their checkpoints
+func (s *Service) Recover(ctx context.Context) error {
+	jobs, err := s.store.ListRunningJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range jobs {
+		if _, ok := s.active[job.ID]; ok {
+			continue
+		}
+		log.Printf("Force sync %s was interrupted, resuming from its checkpoint", job.ID)
+		s.launch(job)
+	}
+	return nil
+}
+
+// GetJob retrieves a job with its progress
+func (s *Service) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	return s.store.GetJob(ctx, id)
+}
+
+// ListJobs retrieves the latest jobs, newest first
+func (s *Service) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.store.ListJobs(ctx, limit)
+}
+
+// launch runs a job in the background; s.mu must be held
+func (s *Service) launch(job *Job) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &activeRun{cancel: cancel, done: make(chan struct{})}
+	s.active[job.ID] = run
+
+	started := *job
+	started.Phases = append([]Phase(nil), job.Phases...)
+	go s.run(ctx, run, job)
+	return &started
+}
+
+// run enqueues the events and records how the job ended
+func (s *Service) run(ctx context.Context, run *activeRun, job *Job) {
+	err := s.enqueue(ctx, job)
+
+	s.mu.Lock()
+	switch {
+	case err == nil:
+		job.Status = StatusCompleted
+	case run.cancelled:
+		job.Status = StatusCancelled
+	default:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+	completedAt := s.now()
+	job.UpdatedAt = completedAt
+	job.CompletedAt = &completedAt
+	// Saved before the job leaves the active set, so it is never seen as
+	// running in the store while no longer running here
+	if err := s.store.UpdateJob(context.Background(), job); err != nil {
+		log.Printf("Failed to save force sync %s: %v", job.ID, err)
+	}
+	delete(s.active, job.ID)
+	s.mu.Unlock()
+
+	run.cancel()
+	close(run.done)
+
+	var enqueued, failed int64
+	for _, phase := range job.Phases {
+		enqueued += phase.Enqueued
+		failed += phase.Failed
+	}
+	log.Printf("Force sync %s %s: %d events enqueued, %d failed", job.ID, job.Status, enqueued, failed)
+}
+
+// enqueue works through the phases from the checkpoint, saving it after every page
+func (s *Service) enqueue(ctx context.Context, job *Job) error {
+	var pace time.Duration
+	if job.RateLimit > 0 {
+		pace = time.Second / time.Duration(job.RateLimit)
+	}
+	next := s.now()
+
+	for job.CurrentPhase < len(job.Phases) {
+		phase := &job.Phases[job.CurrentPhase]
+		for {
+			entities, err := s.store.ListEntities(ctx, phase.Entity, job.Filter, phase.Checkpoint, s.batchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+			if len(entities) == 0 {
+				break
+			}
+
+			for _, entity := range entities {
+				if pace > 0 {
+					if wait := next.Sub(s.now()); wait > 0 {
+						if err := s.sleep(ctx, wait); err != nil {
+							s.checkpoint(job)
+							return err
+						}
+					}
+					if now := s.now(); next.Before(now) {
+						next = now
+					}
+					next = next.Add(pace)
+				}
+				if ctx.Err() != nil {
+					s.checkpoint(job)
+					return ctx.Err()
+				}
+
+				if err := s.recorder.RecordEvent(ctx, phase.Entity, entity.ID.String(), "UPDATE", entity.Data); err != nil {
+					if ctx.Err() != nil {
+						s.checkpoint(job)
+						return ctx.Err()
+					}
+					phase.Failed++
+					log.Printf("Force sync %s failed to enqueue %s %s: %v", job.ID, phase.Entity, entity.ID, err)
+				} else {
+					phase.Enqueued++
+				}
+				phase.Checkpoint = entity.ID
+			}
+			s.checkpoint(job)
+		}
+		job.CurrentPhase++
+	}
+	return nil
+}
+
+// checkpoint saves the position reached; a failure only costs enqueueing a page again on resume
+func (s *Service) checkpoint(job *Job) {
+	job.UpdatedAt = s.now()
+	if err := s.store.UpdateJob(context.Background(), job); err != nil {
+		log.Printf("Failed to checkpoint force sync %s: %v", job.ID, err)
+	}
+}
+
+// sleepContext waits for d unless ctx is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
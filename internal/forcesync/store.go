@@ -0,0 +1,250 @@
+package forcesync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store reads the entities matching a filter and persists jobs
+type Store interface {
+	// CountEntities returns the number of live entities of a type matching the filter
+	CountEntities(ctx context.Context, entity string, filter Filter) (int64, error)
+	// ListEntities returns up to limit live entities of a type matching the
+	// filter with IDs greater than after, ordered by ID
+	ListEntities(ctx context.Context, entity string, filter Filter, after uuid.UUID, limit int) ([]Entity, error)
+	CreateJob(ctx context.Context, job *Job) error
+	// UpdateJob saves the status, progress and checkpoint of a job
+	UpdateJob(ctx context.Context, job *Job) error
+	GetJob(ctx context.Context, id uuid.UUID) (*Job, error)
+	ListJobs(ctx context.Context, limit int) ([]*Job, error)
+	// ListRunningJobs returns the jobs left running, oldest first
+	ListRunningJobs(ctx context.Context) ([]*Job, error)
+}
+
+// ciMatches selects the live CIs aliased c matching a filter given as $1
+// types, $2 tags and $3 updated since; empty criteria match everything
+const ciMatches = `c.is_deleted = false
+	AND (cardinality($1::text[]) = 0 OR c.type = ANY($1::text[]))
+	AND (cardinality($2::text[]) = 0 OR c.tags && $2::text[])
+	AND ($3::timestamptz IS NULL OR c.updated_at >= $3::timestamptz)`
+
+// entitySource describes how the entities of a type matching a filter are
+// read. data builds the payload the sync triggers record for that type.
+type entitySource struct {
+	from  string
+	id    string
+	data  string
+	match string
+}
+
+var entitySources = map[string]entitySource{
+	EntityConfigurationItem: {
+		from: "configuration_items c",
+		id:   "c.id",
+		data: `jsonb_build_object('id', c.id, 'name', c.name, 'type', c.type,
+			'description', COALESCE(c.description, ''), 'status', COALESCE(c.status, 'active'),
+			'attributes', COALESCE(c.attributes, '{}'::jsonb), 'tags', COALESCE(c.tags, '{}'::text[]),
+			'created_by', c.created_by, 'updated_by', c.updated_by, 'created_at', c.created_at, 'updated_at', c.updated_at)`,
+		match: ciMatches,
+	},
+	EntityRelationship: {
+		from: "ci_relationships e",
+		id:   "e.id",
+		data: `jsonb_build_object('id', e.id, 'source_id', e.source_ci_id, 'target_id', e.target_ci_id, 'type', e.type,
+			'description', COALESCE(e.description, ''), 'attributes', COALESCE(e.attributes, '{}'::jsonb),
+			'created_by', e.created_by, 'created_at', e.created_at, 'updated_at', e.updated_at)`,
+		match: `e.is_active = true AND EXISTS (
+			SELECT 1 FROM configuration_items c
+			WHERE c.id IN (e.source_ci_id, e.target_ci_id) AND ` + ciMatches + `)`,
+	},
+}
+
+// filterArgs returns the filter as the $1 to $3 query arguments
+func filterArgs(filter Filter) []interface{} {
+	types := filter.Types
+	if types == nil {
+		types = []string{}
+	}
+	tags := filter.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return []interface{}{pq.Array(types), pq.Array(tags), filter.UpdatedSince}
+}
+
+// PostgresStore reads entities from their tables and keeps jobs in the sync_force_jobs table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed force sync store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CountEntities counts the live entities of a type matching the filter
+func (s *PostgresStore) CountEntities(ctx context.Context, entity string, filter Filter) (int64, error) {
+	source, ok := entitySources[entity]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown entity %s", ErrInvalidRequest, entity)
+	}
+
+	var count int64
+	query := `SELECT COUNT(*) FROM ` + source.from + ` WHERE ` + source.match
+	if err := s.db.GetContext(ctx, &count, query, filterArgs(filter)...); err != nil {
+		return 0, fmt.Errorf("failed to count %s entities: %w", entity, err)
+	}
+	return count, nil
+}
+
+// ListEntities retrieves a page of live entities of a type matching the filter
+func (s *PostgresStore) ListEntities(ctx context.Context, entity string, filter Filter, after uuid.UUID, limit int) ([]Entity, error) {
+	source, ok := entitySources[entity]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown entity %s", ErrInvalidRequest, entity)
+	}
+
+	id := source.id
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+id+`, `+source.data+`
+		FROM `+source.from+`
+		WHERE `+id+` > $4 AND `+source.match+`
+		ORDER BY `+id+`
+		LIMIT $5`, append(filterArgs(filter), after, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s entities: %w", entity, err)
+	}
+	defer rows.Close()
+
+	entities := []Entity{}
+	for rows.Next() {
+		var e Entity
+		var data []byte
+		if err := rows.Scan(&e.ID, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan %s entity: %w", entity, err)
+		}
+		if err := json.Unmarshal(data, &e.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s entity: %w", entity, err)
+		}
+		entities = append(entities, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list %s entities: %w", entity, err)
+	}
+	return entities, nil
+}
+
+// CreateJob inserts a job
+func (s *PostgresStore) CreateJob(ctx context.Context, job *Job) error {
+	filter, err := json.Marshal(job.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal force sync filter: %w", err)
+	}
+	phases, err := json.Marshal(job.Phases)
+	if err != nil {
+		return fmt.Errorf("failed to marshal force sync phases: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO sync_force_jobs (id, status, filter, phases, current_phase, rate_limit, requested_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		job.ID, job.Status, filter, phases, job.CurrentPhase, job.RateLimit, job.RequestedBy, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create force sync job: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob saves the status, progress and checkpoint of a job
+func (s *PostgresStore) UpdateJob(ctx context.Context, job *Job) error {
+	phases, err := json.Marshal(job.Phases)
+	if err != nil {
+		return fmt.Errorf("failed to marshal force sync phases: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE sync_force_jobs
+		SET status = $2, phases = $3, current_phase = $4, updated_at = $5, completed_at = $6, error = NULLIF($7, '')
+		WHERE id = $1`,
+		job.ID, job.Status, phases, job.CurrentPhase, job.UpdatedAt, job.CompletedAt, job.Error)
+	if err != nil {
+		return fmt.Errorf("failed to update force sync job: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+const jobColumns = `id, status, filter, phases, current_phase, rate_limit, requested_by, created_at, updated_at, completed_at, COALESCE(error, '')`
+
+// scanJob reads a job row selected with jobColumns
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var job Job
+	var filter, phases []byte
+	var completedAt sql.NullTime
+	if err := row.Scan(&job.ID, &job.Status, &filter, &phases, &job.CurrentPhase, &job.RateLimit, &job.RequestedBy,
+		&job.CreatedAt, &job.UpdatedAt, &completedAt, &job.Error); err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if err := json.Unmarshal(filter, &job.Filter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal force sync filter: %w", err)
+	}
+	if err := json.Unmarshal(phases, &job.Phases); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal force sync phases: %w", err)
+	}
+	return &job, nil
+}
+
+// GetJob retrieves a job
+func (s *PostgresStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := scanJob(s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM sync_force_jobs WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get force sync job: %w", err)
+	}
+	return job, nil
+}
+
+// ListJobs retrieves the latest jobs, newest first
+func (s *PostgresStore) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	return s.listJobs(ctx, `SELECT `+jobColumns+` FROM sync_force_jobs ORDER BY created_at DESC LIMIT $1`, limit)
+}
+
+// ListRunningJobs retrieves the running jobs, oldest first
+func (s *PostgresStore) ListRunningJobs(ctx context.Context) ([]*Job, error) {
+	return s.listJobs(ctx, `SELECT `+jobColumns+` FROM sync_force_jobs WHERE status = 'running' ORDER BY created_at`)
+}
+
+// listJobs retrieves the jobs selected by a query
+func (s *PostgresStore) listJobs(ctx context.Context, query string, args ...interface{}) ([]*Job, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list force sync jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan force sync job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list force sync jobs: %w", err)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,212 @@
+// Package graph answers dependency questions by traversing the Neo4j graph the
+// sync pipeline maintains. Graph nodes only carry a CI's identity, so the CIs
+// reached are loaded from Postgres, which also leaves out CIs deleted since
+// they were synced.
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Limits
+const (
+	// MaxDepth bounds the relationship hops an impact analysis follows
+	MaxDepth = models.MaxImpactDepth
+	// MaxAffected bounds the CIs an impact analysis returns, keeping the
+	// traversal of dense graphs in check
+	MaxAffected = 5000
+)
+
+// UnknownCriticality groups affected CIs without a criticality
+const UnknownCriticality = "unknown"
+
+var (
+	ErrCINotFound   = errors.New("CI not found")
+	ErrInvalidQuery = errors.New("invalid impact query")
+)
+
+// criticalityOrder lists the criticality groups most critical first
+var criticalityOrder = []string{
+	models.CICriticalityCritical,
+	models.CICriticalityHigh,
+	models.CICriticalityMedium,
+	models.CICriticalityLow,
+	UnknownCriticality,
+}
+
+// Reached is a CI reached by a traversal, at the fewest hops it was reached in
+type Reached struct {
+	ID    uuid.UUID
+	Depth int
+}
+
+// Traverser follows the relationships of a CI in the graph
+type Traverser interface {
+	// Traverse returns the CIs within depth hops of a CI in a direction,
+	// excluding the CI itself, at most limit of them
+	Traverse(ctx context.Context, ciID uuid.UUID, direction string, depth, limit int) ([]Reached, error)
+}
+
+// CILookup loads the CIs reached by a traversal
+type CILookup interface {
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+	GetCIsByIDs(ctx context.Context, ids []uuid.UUID) ([]models.CI, error)
+}
+
+// AffectedCI is a CI affected by a CI, and how many hops away it is
+type AffectedCI struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	Criticality string    `json:"criticality"`
+	Depth       int       `json:"depth"`
+}
+
+// CriticalityGroup is the affected CIs of one criticality, nearest first
+type CriticalityGroup struct {
+	Criticality string       `json:"criticality"`
+	Count       int          `json:"count"`
+	CIs         []AffectedCI `json:"cis"`
+}
+
+// Impact is the result of an impact analysis
+type Impact struct {
+	CI         *models.CI `json:"-"`
+	Direction  string     `json:"direction"`
+	Depth      int        `json:"depth"`
+	TotalCount int        `json:"total_count"`
+	// Truncated tells that the traversal reached MaxAffected CIs and more
+	// may be affected
+	Truncated bool               `json:"truncated"`
+	Groups    []CriticalityGroup `json:"groups"`
+}
+
+// Service runs impact analyses
+type Service struct {
+	traverser Traverser
+	cis       CILookup
+}
+
+// NewService creates a new graph impact service
+func NewService(traverser Traverser, cis CILookup) *Service {
+	return &Service{traverser: traverser, cis: cis}
+}
+
+// Impact returns the CIs reached from a CI in a direction within depth hops,
+// grouped by criticality. visible, when not nil, leaves out the CIs it
+// rejects, e.g. those the caller may not see.
+func (s *Service) Impact(ctx context.Context, ciID uuid.UUID, direction string, depth int, visible func(ci *models.CI) bool) (*Impact, error) {
+	if !validDirection(direction) {
+		return nil, fmt.Errorf("%w: direction must be %s, %s or %s", ErrInvalidQuery,
+			models.GraphDirectionUpstream, models.GraphDirectionDownstream, models.GraphDirectionBoth)
+	}
+	if depth < 1 || depth > MaxDepth {
+		return nil, fmt.Errorf("%w: depth must be between 1 and %d", ErrInvalidQuery, MaxDepth)
+	}
+
+	ci, err := s.cis.GetCI(ctx, ciID)
+	if err != nil || ci == nil || (visible != nil && !visible(ci)) {
+		return nil, ErrCINotFound
+	}
+
+	reached, err := s.traverser.Traverse(ctx, ciID, direction, depth, MaxAffected+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to traverse dependencies: %w", err)
+	}
+	truncated := len(reached) > MaxAffected
+	if truncated {
+		reached = reached[:MaxAffected]
+	}
+
+	depths := make(map[uuid.UUID]int, len(reached))
+	ids := make([]uuid.UUID, 0, len(reached))
+	for _, r := range reached {
+		depths[r.ID] = r.Depth
+		ids = append(ids, r.ID)
+	}
+	cis, err := s.cis.GetCIsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string][]AffectedCI)
+	total := 0
+	for i := range cis {
+		affected := &cis[i]
+		if visible != nil && !visible(affected) {
+			continue
+		}
+		criticality := affected.Criticality
+		if criticality == "" {
+			criticality = UnknownCriticality
+		}
+		grouped[criticality] = append(grouped[criticality], AffectedCI{
+			ID:          affected.ID,
+			Name:        affected.Name,
+			Type:        affected.Type,
+			Status:      affected.Status,
+			Criticality: affected.Criticality,
+			Depth:       depths[affected.ID],
+		})
+		total++
+	}
+
+	return &Impact{
+		CI:         ci,
+		Direction:  direction,
+		Depth:      depth,
+		TotalCount: total,
+		Truncated:  truncated,
+		Groups:     groupsInOrder(grouped),
+	}, nil
+}
+
+// groupsInOrder orders the groups most critical first, followed by any
+// criticality not known to the model, and their CIs nearest first
+func groupsInOrder(grouped map[string][]AffectedCI) []CriticalityGroup {
+	order := append([]string(nil), criticalityOrder...)
+	var extra []string
+	for criticality := range grouped {
+		if !contains(criticalityOrder, criticality) {
+			extra = append(extra, criticality)
+		}
+	}
+	sort.Strings(extra)
+	order = append(order, extra...)
+
+	groups := make([]CriticalityGroup, 0, len(grouped))
+	for _, criticality := range order {
+		cis, ok := grouped[criticality]
+		if !ok {
+			continue
+		}
+		sort.Slice(cis, func(i, j int) bool {
+			if cis[i].Depth != cis[j].Depth {
+				return cis[i].Depth < cis[j].Depth
+			}
+			return cis[i].Name < cis[j].Name
+		})
+		groups = append(groups, CriticalityGroup{Criticality: criticality, Count: len(cis), CIs: cis})
+	}
+	return groups
+}
+
+func validDirection(direction string) bool {
+	return contains(models.GraphDirections, direction)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
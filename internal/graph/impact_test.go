@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTraverser struct {
+	reached   []Reached
+	direction string
+	depth     int
+}
+
+func (f *fakeTraverser) Traverse(ctx context.Context, ciID uuid.UUID, direction string, depth, limit int) ([]Reached, error) {
+	f.direction, f.depth = direction, depth
+	if len(f.reached) > limit {
+		return f.reached[:limit], nil
+	}
+	return f.reached, nil
+}
+
+type fakeLookup struct {
+	cis map[uuid.UUID]models.CI
+}
+
+func (f *fakeLookup) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	ci, ok := f.cis[id]
+	if !ok {
+		return nil, errors.New("CI not found")
+	}
+	return &ci, nil
+}
+
+func (f *fakeLookup) GetCIsByIDs(ctx context.Context, ids []uuid.UUID) ([]models.CI, error) {
+	cis := []models.CI{}
+	for _, id := range ids {
+		if ci, ok := f.cis[id]; ok {
+			cis = append(cis, ci)
+		}
+	}
+	return cis, nil
+}
+
+func (f *fakeLookup) add(name, criticality string) uuid.UUID {
+	ci := models.CI{ID: uuid.New(), Name: name, Type: "server", Criticality: criticality}
+	f.cis[ci.ID] = ci
+	return ci.ID
+}
+
+func TestImpactGroupsByCriticality(t *testing.T) {
+	lookup := &fakeLookup{cis: map[uuid.UUID]models.CI{}}
+	root := lookup.add("db-01", models.CICriticalityHigh)
+	app := lookup.add("app-01", models.CICriticalityHigh)
+	payments := lookup.add("payments", models.CICriticalityCritical)
+	batch := lookup.add("batch-01", models.CICriticalityLow)
+	legacy := lookup.add("legacy", "")
+	// Deleted since it was synced to the graph
+	deleted := uuid.New()
+
+	traverser := &fakeTraverser{reached: []Reached{
+		{ID: app, Depth: 1}, {ID: batch, Depth: 1}, {ID: deleted, Depth: 1},
+		{ID: payments, Depth: 2}, {ID: legacy, Depth: 3},
+	}}
+	impact, err := NewService(traverser, lookup).Impact(context.Background(), root, models.GraphDirectionUpstream, 3, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.GraphDirectionUpstream, traverser.direction)
+	assert.Equal(t, 3, traverser.depth)
+	assert.Equal(t, 4, impact.TotalCount)
+	assert.False(t, impact.Truncated)
+
+	var order []string
+	for _, group := range impact.Groups {
+		order = append(order, group.Criticality)
+	}
+	assert.Equal(t, []string{models.CICriticalityCritical, models.CICriticalityHigh, models.CICriticalityLow, UnknownCriticality}, order)
+	assert.Equal(t, payments, impact.Groups[0].CIs[0].ID)
+	assert.Equal(t, 2, impact.Groups[0].CIs[0].Depth)
+	assert.Equal(t, legacy, impact.Groups[3].CIs[0].ID)
+}
+
+func TestImpactHidesInvisibleCIs(t *testing.T) {
+	lookup := &fakeLookup{cis: map[uuid.UUID]models.CI{}}
+	root := lookup.add("db-01", models.CICriticalityHigh)
+	visible := lookup.add("app-01", models.CICriticalityHigh)
+	hidden := lookup.add("secret", models.CICriticalityCritical)
+	traverser := &fakeTraverser{reached: []Reached{{ID: visible, Depth: 1}, {ID: hidden, Depth: 1}}}
+	service := NewService(traverser, lookup)
+
+	canSee := func(ci *models.CI) bool { return ci.ID != hidden }
+	impact, err := service.Impact(context.Background(), root, models.GraphDirectionDownstream, 2, canSee)
+	require.NoError(t, err)
+	assert.Equal(t, 1, impact.TotalCount)
+	require.Len(t, impact.Groups, 1)
+	assert.Equal(t, visible, impact.Groups[0].CIs[0].ID)
+
+	_, err = service.Impact(context.Background(), hidden, models.GraphDirectionDownstream, 2, canSee)
+	assert.ErrorIs(t, err, ErrCINotFound)
+}
+
+func TestImpactValidatesQuery(t *testing.T) {
+	lookup := &fakeLookup{cis: map[uuid.UUID]models.CI{}}
+	root := lookup.add("db-01", models.CICriticalityHigh)
+	service := NewService(&fakeTraverser{}, lookup)
+
+	_, err := service.Impact(context.Background(), root, "sideways", 2, nil)
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = service.Impact(context.Background(), root, models.GraphDirectionBoth, MaxDepth+1, nil)
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = service.Impact(context.Background(), uuid.New(), models.GraphDirectionBoth, 2, nil)
+	assert.ErrorIs(t, err, ErrCINotFound)
+}
+
+func TestImpactTruncatesLargeGraphs(t *testing.T) {
+	lookup := &fakeLookup{cis: map[uuid.UUID]models.CI{}}
+	root := lookup.add("core-switch", models.CICriticalityCritical)
+	traverser := &fakeTraverser{}
+	for i := 0; i < MaxAffected+10; i++ {
+		traverser.reached = append(traverser.reached, Reached{ID: lookup.add("node", models.CICriticalityMedium), Depth: 1})
+	}
+
+	impact, err := NewService(traverser, lookup).Impact(context.Background(), root, models.GraphDirectionUpstream, 1, nil)
+	require.NoError(t, err)
+	assert.True(t, impact.Truncated)
+	assert.Equal(t, MaxAffected, impact.TotalCount)
+}
@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Neo4jTraverser traverses the ConfigurationItem nodes and RELATIONSHIP edges
+// written by the sync pipeline
+type Neo4jTraverser struct {
+	driver neo4j.DriverWithContext
+}
+
+// NewNeo4jTraverser creates a new Neo4j backed traverser
+func NewNeo4jTraverser(driver neo4j.DriverWithContext) *Neo4jTraverser {
+	return &Neo4jTraverser{driver: driver}
+}
+
+// traversalPatterns are the path patterns of each direction. Relationships
+// point from source to target, so upstream follows them backwards.
+var traversalPatterns = map[string]string{
+	models.GraphDirectionUpstream:   `(start)<-[:RELATIONSHIP*1..%d]-(n:ConfigurationItem)`,
+	models.GraphDirectionDownstream: `(start)-[:RELATIONSHIP*1..%d]->(n:ConfigurationItem)`,
+	models.GraphDirectionBoth:       `(start)-[:RELATIONSHIP*1..%d]-(n:ConfigurationItem)`,
+}
+
+// Traverse returns the CIs within depth hops of a CI, nearest first
+func (t *Neo4jTraverser) Traverse(ctx context.Context, ciID uuid.UUID, direction string, depth, limit int) ([]Reached, error) {
+	pattern, ok := traversalPatterns[direction]
+	if !ok {
+		return nil, fmt.Errorf("unknown direction %q", direction)
+	}
+	// Path lengths cannot be parameters, depth is validated by the service
+	query := `
+		MATCH (start:ConfigurationItem {id: $id})
+		MATCH p = ` + fmt.Sprintf(pattern, depth) + `
+		WHERE n.id <> $id
+		RETURN n.id AS id, min(length(p)) AS depth
+		ORDER BY depth, id
+		LIMIT $limit`
+
+	result, err := neo4j.ExecuteQuery(ctx, t.driver, query,
+		map[string]any{"id": ciID.String(), "limit": limit},
+		neo4j.EagerResultTransformer, neo4j.ExecuteQueryWithReadersRouting())
+	if err != nil {
+		return nil, err
+	}
+
+	reached := make([]Reached, 0, len(result.Records))
+	for _, record := range result.Records {
+		id, _, err := neo4j.GetRecordValue[string](record, "id")
+		if err != nil {
+			return nil, err
+		}
+		hops, _, err := neo4j.GetRecordValue[int64](record, "depth")
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		reached = append(reached, Reached{ID: parsed, Depth: int(hops)})
+	}
+	return reached, nil
+}
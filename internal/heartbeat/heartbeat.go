@@ -0,0 +1,270 @@
+// Package heartbeat tracks the heartbeats agents and monitoring integrations
+// send for CIs. A CI that has sent heartbeats and then misses them for longer
+// than the threshold of its type is moved to the unreachable status; it gets
+// its previous status back with its next heartbeat. Both transitions are
+// notified.
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Heartbeat errors
+var (
+	ErrCINotFound        = errors.New("CI not found")
+	ErrHeartbeatNotFound = errors.New("no heartbeat received for CI")
+	ErrInvalidSource     = errors.New("invalid heartbeat source")
+)
+
+// maxSourceLength bounds the name of the integration sending a heartbeat
+const maxSourceLength = 255
+
+// Notification kinds
+const (
+	KindUnreachable = "unreachable"
+	KindRecovered   = "recovered"
+)
+
+// Policy defines after how long without a heartbeat CIs are unreachable
+type Policy struct {
+	DefaultThreshold time.Duration
+	TypeThreshold    map[string]time.Duration
+}
+
+// Threshold returns how long a CI of a type may go without a heartbeat
+func (p Policy) Threshold(ciType string) time.Duration {
+	if threshold, ok := p.TypeThreshold[ciType]; ok && threshold > 0 {
+		return threshold
+	}
+	return p.DefaultThreshold
+}
+
+// Validate checks that every threshold is positive
+func (p Policy) Validate() error {
+	if p.DefaultThreshold <= 0 {
+		return fmt.Errorf("default heartbeat threshold must be positive")
+	}
+	for ciType, threshold := range p.TypeThreshold {
+		if threshold <= 0 {
+			return fmt.Errorf("heartbeat threshold for type %s must be positive", ciType)
+		}
+	}
+	return nil
+}
+
+// Cutoffs are the times before which the last heartbeat of a CI makes it
+// unreachable: per type where the type has its own threshold, else Default
+type Cutoffs struct {
+	Default time.Time
+	Types   map[string]time.Time
+}
+
+// Cutoffs returns the cutoffs of the policy at a time
+func (p Policy) Cutoffs(now time.Time) Cutoffs {
+	cutoffs := Cutoffs{Default: now.Add(-p.DefaultThreshold), Types: map[string]time.Time{}}
+	for ciType := range p.TypeThreshold {
+		cutoffs.Types[ciType] = now.Add(-p.Threshold(ciType))
+	}
+	return cutoffs
+}
+
+// Heartbeat is the heartbeat state of a CI
+type Heartbeat struct {
+	CIID            uuid.UUID `json:"ci_id" db:"ci_id"`
+	CIName          string    `json:"ci_name" db:"ci_name"`
+	CIType          string    `json:"ci_type" db:"ci_type"`
+	Status          string    `json:"status" db:"status"`
+	Source          string    `json:"source" db:"source"`
+	LastHeartbeatAt time.Time `json:"last_heartbeat_at" db:"last_heartbeat_at"`
+	// UnreachableSince is set while the CI is unreachable, and PreviousStatus
+	// then holds the status it gets back when it recovers
+	UnreachableSince *time.Time `json:"unreachable_since,omitempty" db:"unreachable_since"`
+	PreviousStatus   string     `json:"previous_status,omitempty" db:"previous_status"`
+	ThresholdSeconds float64    `json:"threshold_seconds" db:"-"`
+}
+
+// Notification tells operators a CI became unreachable or recovered
+type Notification struct {
+	Kind      string    `json:"kind"`
+	Heartbeat Heartbeat `json:"heartbeat"`
+}
+
+// Notifier delivers heartbeat notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the log. It is used until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the notification
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	hb := n.Heartbeat
+	switch n.Kind {
+	case KindUnreachable:
+		log.Printf("CI %s (%s, %s) is unreachable: last heartbeat at %s",
+			hb.CIName, hb.CIType, hb.CIID, hb.LastHeartbeatAt.Format(time.RFC3339))
+	default:
+		log.Printf("CI %s (%s, %s) recovered: heartbeat received, status back to %s",
+			hb.CIName, hb.CIType, hb.CIID, hb.Status)
+	}
+	return nil
+}
+
+// Store persists heartbeats and moves CIs in and out of the unreachable
+// status, as CIRepository does
+type Store interface {
+	// RecordHeartbeat records a heartbeat of a CI, returning ErrCINotFound
+	// for unknown or deleted CIs
+	RecordHeartbeat(ctx context.Context, ciID uuid.UUID, source string, at time.Time) (*Heartbeat, error)
+	// RecoverCI gives an unreachable CI its previous status back. It returns
+	// nil when the CI is not unreachable, e.g. because it already recovered.
+	RecoverCI(ctx context.Context, ciID uuid.UUID) (*Heartbeat, error)
+	// MarkUnreachable moves the monitored CIs whose last heartbeat is before
+	// their cutoff to the unreachable status and returns them
+	MarkUnreachable(ctx context.Context, cutoffs Cutoffs, at time.Time) ([]Heartbeat, error)
+	GetHeartbeat(ctx context.Context, ciID uuid.UUID) (*Heartbeat, error)
+	// ListHeartbeats returns the heartbeats of monitored CIs, least recent first
+	ListHeartbeats(ctx context.Context, unreachableOnly bool) ([]Heartbeat, error)
+}
+
+// Result is the outcome of a check for missed heartbeats
+type Result struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Unreachable int       `json:"unreachable"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Service records heartbeats and checks for missed ones
+type Service struct {
+	store    Store
+	notifier Notifier
+	policy   Policy
+	now      func() time.Time
+
+	// running serialises checks, so a manual check never overlaps the periodic one
+	running sync.Mutex
+
+	mu      sync.Mutex
+	lastRun *Result
+}
+
+// NewService creates a new heartbeat service. The policy must have been validated.
+func NewService(store Store, notifier Notifier, policy Policy) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	return &Service{store: store, notifier: notifier, policy: policy, now: time.Now}
+}
+
+// Record records a heartbeat of a CI. An unreachable CI recovers.
+func (s *Service) Record(ctx context.Context, ciID uuid.UUID, source string) (*Heartbeat, error) {
+	if len(source) > maxSourceLength {
+		return nil, fmt.Errorf("%w: must be at most %d characters", ErrInvalidSource, maxSourceLength)
+	}
+
+	hb, err := s.store.RecordHeartbeat(ctx, ciID, source, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if hb.UnreachableSince != nil {
+		recovered, err := s.store.RecoverCI(ctx, ciID)
+		if err != nil {
+			return nil, err
+		}
+		if recovered != nil {
+			hb = recovered
+			s.notify(ctx, KindRecovered, *hb)
+		}
+	}
+	return s.withThreshold(hb), nil
+}
+
+// Get retrieves the heartbeat state of a CI
+func (s *Service) Get(ctx context.Context, ciID uuid.UUID) (*Heartbeat, error) {
+	hb, err := s.store.GetHeartbeat(ctx, ciID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withThreshold(hb), nil
+}
+
+// List lists the heartbeat state of the monitored CIs
+func (s *Service) List(ctx context.Context, unreachableOnly bool) ([]Heartbeat, error) {
+	heartbeats, err := s.store.ListHeartbeats(ctx, unreachableOnly)
+	if err != nil {
+		return nil, err
+	}
+	for i := range heartbeats {
+		s.withThreshold(&heartbeats[i])
+	}
+	return heartbeats, nil
+}
+
+// Check moves the CIs that missed their heartbeats to the unreachable status
+func (s *Service) Check(ctx context.Context) *Result {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	now := s.now()
+	result := &Result{StartedAt: now}
+	unreachable, err := s.store.MarkUnreachable(ctx, s.policy.Cutoffs(now), now)
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("Failed to check for missed heartbeats: %v", err)
+	}
+	for _, hb := range unreachable {
+		s.notify(ctx, KindUnreachable, *s.withThreshold(&hb))
+	}
+	result.Unreachable = len(unreachable)
+	result.CompletedAt = s.now()
+
+	s.mu.Lock()
+	s.lastRun = result
+	s.mu.Unlock()
+	return result
+}
+
+// LastRun returns the outcome of the last check, or nil if there was none yet
+func (s *Service) LastRun() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun
+}
+
+// Run checks for missed heartbeats at the given interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx)
+		}
+	}
+}
+
+// notify delivers a notification; failing to deliver it does not undo the transition
+func (s *Service) notify(ctx context.Context, kind string, hb Heartbeat) {
+	if err := s.notifier.Notify(ctx, Notification{Kind: kind, Heartbeat: hb}); err != nil {
+		log.Printf("Failed to notify that CI %s is %s: %v", hb.CIID, kind, err)
+	}
+}
+
+// withThreshold sets the threshold of the CI's type on a heartbeat
+func (s *Service) withThreshold(hb *Heartbeat) *Heartbeat {
+	hb.ThresholdSeconds = s.policy.Threshold(hb.CIType).Seconds()
+	return hb
+}
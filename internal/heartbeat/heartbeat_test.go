@@ -0,0 +1,235 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCI struct {
+	name, ciType, status string
+}
+
+// memoryStore keeps CIs and their heartbeats in memory
+type memoryStore struct {
+	cis        map[uuid.UUID]*memoryCI
+	heartbeats map[uuid.UUID]*Heartbeat
+	err        error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{cis: map[uuid.UUID]*memoryCI{}, heartbeats: map[uuid.UUID]*Heartbeat{}}
+}
+
+func (m *memoryStore) addCI(name, ciType, status string) uuid.UUID {
+	id := uuid.New()
+	m.cis[id] = &memoryCI{name: name, ciType: ciType, status: status}
+	return id
+}
+
+func (m *memoryStore) heartbeat(id uuid.UUID) *Heartbeat {
+	hb := *m.heartbeats[id]
+	ci := m.cis[id]
+	hb.CIName, hb.CIType, hb.Status = ci.name, ci.ciType, ci.status
+	return &hb
+}
+
+func (m *memoryStore) RecordHeartbeat(ctx context.Context, ciID uuid.UUID, source string, at time.Time) (*Heartbeat, error) {
+	if _, ok := m.cis[ciID]; !ok {
+		return nil, ErrCINotFound
+	}
+	hb, ok := m.heartbeats[ciID]
+	if !ok {
+		hb = &Heartbeat{CIID: ciID}
+		m.heartbeats[ciID] = hb
+	}
+	hb.Source, hb.LastHeartbeatAt = source, at
+	return m.heartbeat(ciID), nil
+}
+
+func (m *memoryStore) RecoverCI(ctx context.Context, ciID uuid.UUID) (*Heartbeat, error) {
+	hb := m.heartbeats[ciID]
+	if hb.UnreachableSince == nil {
+		return nil, nil
+	}
+	m.cis[ciID].status = hb.PreviousStatus
+	hb.UnreachableSince, hb.PreviousStatus = nil, ""
+	return m.heartbeat(ciID), nil
+}
+
+func (m *memoryStore) MarkUnreachable(ctx context.Context, cutoffs Cutoffs, at time.Time) ([]Heartbeat, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var marked []Heartbeat
+	for id, hb := range m.heartbeats {
+		ci := m.cis[id]
+		cutoff, ok := cutoffs.Types[ci.ciType]
+		if !ok {
+			cutoff = cutoffs.Default
+		}
+		if hb.UnreachableSince != nil || ci.status == "maintenance" || !hb.LastHeartbeatAt.Before(cutoff) {
+			continue
+		}
+		since := at
+		hb.UnreachableSince, hb.PreviousStatus = &since, ci.status
+		ci.status = "unreachable"
+		marked = append(marked, *m.heartbeat(id))
+	}
+	return marked, nil
+}
+
+func (m *memoryStore) GetHeartbeat(ctx context.Context, ciID uuid.UUID) (*Heartbeat, error) {
+	if _, ok := m.heartbeats[ciID]; !ok {
+		return nil, ErrHeartbeatNotFound
+	}
+	return m.heartbeat(ciID), nil
+}
+
+func (m *memoryStore) ListHeartbeats(ctx context.Context, unreachableOnly bool) ([]Heartbeat, error) {
+	heartbeats := []Heartbeat{}
+	for id, hb := range m.heartbeats {
+		if !unreachableOnly || hb.UnreachableSince != nil {
+			heartbeats = append(heartbeats, *m.heartbeat(id))
+		}
+	}
+	return heartbeats, nil
+}
+
+// notificationRecorder records the notifications sent
+type notificationRecorder struct {
+	notifications []Notification
+}
+
+func (r *notificationRecorder) Notify(ctx context.Context, n Notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+var policy = Policy{
+	DefaultThreshold: 15 * time.Minute,
+	TypeThreshold:    map[string]time.Duration{"server": 5 * time.Minute},
+}
+
+func newTestService(store Store) (*Service, *notificationRecorder, *time.Time) {
+	notifier := &notificationRecorder{}
+	service := NewService(store, notifier, policy)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, notifier, &now
+}
+
+func TestPolicy(t *testing.T) {
+	assert.Equal(t, 5*time.Minute, policy.Threshold("server"))
+	assert.Equal(t, 15*time.Minute, policy.Threshold("application"))
+	assert.NoError(t, policy.Validate())
+
+	assert.Error(t, Policy{}.Validate())
+	assert.Error(t, Policy{DefaultThreshold: time.Minute, TypeThreshold: map[string]time.Duration{"server": 0}}.Validate())
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cutoffs := policy.Cutoffs(now)
+	assert.Equal(t, now.Add(-15*time.Minute), cutoffs.Default)
+	assert.Equal(t, now.Add(-5*time.Minute), cutoffs.Types["server"])
+}
+
+func TestRecord(t *testing.T) {
+	store := newMemoryStore()
+	id := store.addCI("web-01", "server", "active")
+	service, notifier, _ := newTestService(store)
+
+	hb, err := service.Record(context.Background(), id, "prometheus")
+	require.NoError(t, err)
+	assert.Equal(t, "prometheus", hb.Source)
+	assert.Equal(t, "active", hb.Status)
+	assert.Equal(t, 300.0, hb.ThresholdSeconds)
+	assert.Empty(t, notifier.notifications)
+
+	_, err = service.Record(context.Background(), uuid.New(), "prometheus")
+	assert.ErrorIs(t, err, ErrCINotFound)
+
+	long := make([]byte, maxSourceLength+1)
+	_, err = service.Record(context.Background(), id, string(long))
+	assert.ErrorIs(t, err, ErrInvalidSource)
+}
+
+func TestCheckMarksCIsThatMissedTheirThreshold(t *testing.T) {
+	store := newMemoryStore()
+	server := store.addCI("web-01", "server", "active")
+	app := store.addCI("shop", "application", "active")
+	inMaintenance := store.addCI("web-02", "server", "maintenance")
+	store.addCI("never-monitored", "server", "active")
+	service, notifier, now := newTestService(store)
+	assert.Nil(t, service.LastRun())
+
+	for _, id := range []uuid.UUID{server, app, inMaintenance} {
+		_, err := service.Record(context.Background(), id, "agent")
+		require.NoError(t, err)
+	}
+
+	// Past the server threshold but within the default one
+	*now = now.Add(10 * time.Minute)
+	result := service.Check(context.Background())
+	assert.Empty(t, result.Error)
+	assert.Equal(t, 1, result.Unreachable)
+	assert.Same(t, result, service.LastRun())
+	assert.Equal(t, "unreachable", store.cis[server].status)
+	assert.Equal(t, "active", store.cis[app].status)
+	assert.Equal(t, "maintenance", store.cis[inMaintenance].status)
+
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, KindUnreachable, notifier.notifications[0].Kind)
+	assert.Equal(t, server, notifier.notifications[0].Heartbeat.CIID)
+	assert.Equal(t, 300.0, notifier.notifications[0].Heartbeat.ThresholdSeconds)
+
+	// Unreachable CIs are not marked again
+	result = service.Check(context.Background())
+	assert.Equal(t, 0, result.Unreachable)
+	assert.Len(t, notifier.notifications, 1)
+
+	unreachable, err := service.List(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, unreachable, 1)
+	assert.Equal(t, server, unreachable[0].CIID)
+}
+
+func TestRecordRecoversUnreachableCI(t *testing.T) {
+	store := newMemoryStore()
+	id := store.addCI("db-01", "database", "fix_required")
+	service, notifier, now := newTestService(store)
+
+	_, err := service.Record(context.Background(), id, "agent")
+	require.NoError(t, err)
+	*now = now.Add(time.Hour)
+	service.Check(context.Background())
+	require.Equal(t, "unreachable", store.cis[id].status)
+
+	hb, err := service.Record(context.Background(), id, "agent")
+	require.NoError(t, err)
+	assert.Equal(t, "fix_required", hb.Status)
+	assert.Nil(t, hb.UnreachableSince)
+	assert.Equal(t, "fix_required", store.cis[id].status)
+
+	require.Len(t, notifier.notifications, 2)
+	assert.Equal(t, KindRecovered, notifier.notifications[1].Kind)
+
+	// Further heartbeats notify nothing
+	_, err = service.Record(context.Background(), id, "agent")
+	require.NoError(t, err)
+	assert.Len(t, notifier.notifications, 2)
+}
+
+func TestCheckReportsStoreErrors(t *testing.T) {
+	store := newMemoryStore()
+	store.err = errors.New("connection refused")
+	service, _, _ := newTestService(store)
+
+	result := service.Check(context.Background())
+	assert.Equal(t, "connection refused", result.Error)
+	assert.Equal(t, 0, result.Unreachable)
+}
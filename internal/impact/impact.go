@@ -0,0 +1,311 @@
+// Package impact ranks the CIs affected when a CI fails or is taken down.
+//
+// Affected CIs are those depending on the failed CI, directly or through other
+// CIs, by following active relationships from target to source. Each one is
+// scored from 0 to 100 by combining its criticality and SLA tier, weighted by
+// the strength of the dependency path leading to it, so operators know which
+// services to check first.
+package impact
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Attributes read when scoring
+const (
+	// StrengthAttribute is the relationship attribute overriding the default
+	// strength of its type, from 0 (no impact) to 1 (hard dependency)
+	StrengthAttribute = "strength"
+	// SLATierAttribute is the CI attribute naming an SLA tier, e.g. gold or tier1
+	SLATierAttribute = "sla_tier"
+	// SLAAttribute is the CI attribute holding an availability target in percent
+	SLAAttribute = "sla"
+)
+
+// defaultStrengths is the strength of the relationship types without an explicit one
+var defaultStrengths = map[string]float64{
+	"depends_on":   1.0,
+	"runs_on":      1.0,
+	"uses":         0.8,
+	"located_in":   0.6,
+	"connected_to": 0.5,
+}
+
+// unknownStrength applies to relationship types missing from defaultStrengths
+const unknownStrength = 0.7
+
+// criticalityFactors maps CI criticality to its share of the score
+var criticalityFactors = map[string]float64{
+	models.CICriticalityCritical: 1.0,
+	models.CICriticalityHigh:     0.75,
+	models.CICriticalityMedium:   0.5,
+	models.CICriticalityLow:      0.25,
+}
+
+// slaTierFactors maps named SLA tiers to their share of the score
+var slaTierFactors = map[string]float64{
+	"platinum": 1.0, "tier0": 1.0,
+	"gold": 0.85, "tier1": 0.85,
+	"silver": 0.65, "tier2": 0.65,
+	"bronze": 0.45, "tier3": 0.45,
+}
+
+// unknownFactor applies when a CI has no criticality or SLA recorded
+const unknownFactor = 0.5
+
+// Weights sets how much criticality and SLA tier contribute to the score
+type Weights struct {
+	Criticality float64 `json:"criticality"`
+	SLA         float64 `json:"sla"`
+}
+
+// DefaultWeights returns the weights used when none are configured
+func DefaultWeights() Weights {
+	return Weights{Criticality: 0.6, SLA: 0.4}
+}
+
+// Impact is a CI affected by the failure of another, with its score
+type Impact struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Criticality string    `json:"criticality"`
+	SLAFactor   float64   `json:"sla_factor"`
+	// Strength is the product of the relationship strengths along Path
+	Strength float64 `json:"strength"`
+	Depth    int     `json:"depth"`
+	Score    float64 `json:"score"`
+	// Path lists the CIs from this one down to the failed CI
+	Path []uuid.UUID `json:"path"`
+	// Primary is set when every relationship along Path is the primary one
+	// of its type for its source CI
+	Primary bool `json:"primary"`
+	// Empirical is set when a relationship along Path is empirically
+	// critical: incident history shows failures travel along it
+	Empirical bool `json:"empirical"`
+}
+
+// Analyze ranks the CIs depending on root, at most maxDepth relationships
+// upstream. cis and relationships describe the graph around root; only active
+// relationships are followed. Each CI is reached through its strongest path,
+// preferring primary relationships between paths of equal strength. The
+// result is sorted by descending score.
+func Analyze(root uuid.UUID, cis []models.CI, relationships []models.CIRelationship, weights Weights, maxDepth int) []Impact {
+	return AnalyzeLearned(root, cis, relationships, weights, maxDepth, nil)
+}
+
+// AnalyzeLearned ranks the CIs depending on root like Analyze, also using the
+// strengths learned from incident history for the empirically critical
+// relationships, by relationship ID. A learned strength only raises that of
+// a relationship, and paths through those relationships are marked empirical.
+func AnalyzeLearned(root uuid.UUID, cis []models.CI, relationships []models.CIRelationship, weights Weights, maxDepth int, learned map[uuid.UUID]float64) []Impact {
+	byID := make(map[uuid.UUID]*models.CI, len(cis))
+	for i := range cis {
+		byID[cis[i].ID] = &cis[i]
+	}
+
+	type edge struct {
+		source    uuid.UUID
+		strength  float64
+		primary   bool
+		empirical bool
+	}
+	dependents := make(map[uuid.UUID][]edge)
+	for _, rel := range relationships {
+		if !rel.IsActive || rel.State != models.RelationshipStateActive || rel.SourceCIID == rel.TargetCIID {
+			continue
+		}
+		strength := Strength(&rel)
+		empirical := false
+		if value, ok := learned[rel.ID]; ok {
+			empirical = true
+			strength = math.Max(strength, math.Min(value, 1))
+		}
+		if strength <= 0 {
+			continue
+		}
+		dependents[rel.TargetCIID] = append(dependents[rel.TargetCIID], edge{rel.SourceCIID, strength, rel.IsPrimary, empirical})
+	}
+
+	// Bellman-Ford style relaxation bounded by depth: after round n, best holds
+	// the strongest path of at most n relationships to every CI reached. Paths
+	// run from the CI down to root.
+	type reach struct {
+		strength  float64
+		primary   bool
+		empirical bool
+		path      []uuid.UUID
+	}
+	better := func(a reach, b reach) bool {
+		return a.strength > b.strength || a.strength == b.strength && a.primary && !b.primary
+	}
+	best := map[uuid.UUID]reach{root: {strength: 1, primary: true, path: []uuid.UUID{root}}}
+	frontier := map[uuid.UUID]bool{root: true}
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		next := make(map[uuid.UUID]reach)
+		for id := range frontier {
+			for _, e := range dependents[id] {
+				if e.source == root {
+					continue
+				}
+				candidate := reach{
+					strength:  best[id].strength * e.strength,
+					primary:   best[id].primary && e.primary,
+					empirical: best[id].empirical || e.empirical,
+					path:      append([]uuid.UUID{e.source}, best[id].path...),
+				}
+				if current, ok := best[e.source]; ok && !better(candidate, current) {
+					continue
+				}
+				if current, ok := next[e.source]; ok && !better(candidate, current) {
+					continue
+				}
+				next[e.source] = candidate
+			}
+		}
+
+		frontier = make(map[uuid.UUID]bool, len(next))
+		for id, r := range next {
+			best[id] = r
+			frontier[id] = true
+		}
+	}
+
+	impacts := make([]Impact, 0, len(best))
+	for id, r := range best {
+		ci, ok := byID[id]
+		if id == root || !ok {
+			continue
+		}
+
+		criticality, sla := CriticalityFactor(ci.Criticality), SLAFactor(ci)
+		impacts = append(impacts, Impact{
+			ID:          id,
+			Name:        ci.Name,
+			Type:        ci.Type,
+			Criticality: ci.Criticality,
+			SLAFactor:   sla,
+			Strength:    round(r.strength, 3),
+			Depth:       len(r.path) - 1,
+			Score:       Score(criticality, sla, r.strength, weights),
+			Path:        r.path,
+			Primary:     r.primary,
+			Empirical:   r.empirical,
+		})
+	}
+
+	sort.Slice(impacts, func(i, j int) bool {
+		a, b := impacts[i], impacts[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		return a.Name < b.Name
+	})
+	return impacts
+}
+
+// Score combines the criticality and SLA factors of a CI, weighted by the
+// strength of its dependency, into a score from 0 to 100
+func Score(criticality, sla, strength float64, weights Weights) float64 {
+	total := weights.Criticality + weights.SLA
+	if total <= 0 {
+		weights, total = DefaultWeights(), 1
+	}
+	return round(100*strength*(weights.Criticality*criticality+weights.SLA*sla)/total, 1)
+}
+
+// Strength returns the strength of a relationship: its strength attribute when
+// set to a number between 0 and 1, the default of its type otherwise
+func Strength(rel *models.CIRelationship) float64 {
+	var attributes map[string]interface{}
+	if len(rel.Attributes) > 0 && json.Unmarshal(rel.Attributes, &attributes) == nil {
+		if value, ok := number(attributes[StrengthAttribute]); ok && value >= 0 && value <= 1 {
+			return value
+		}
+	}
+
+	if strength, ok := defaultStrengths[rel.Type]; ok {
+		return strength
+	}
+	return unknownStrength
+}
+
+// DefaultStrengths returns the strength of each relationship type with a
+// default, and the strength of the other types, for code ranking
+// relationships by strength outside Go, e.g. in SQL
+func DefaultStrengths() (map[string]float64, float64) {
+	strengths := make(map[string]float64, len(defaultStrengths))
+	for relType, strength := range defaultStrengths {
+		strengths[relType] = strength
+	}
+	return strengths, unknownStrength
+}
+
+// CriticalityFactor maps a CI criticality to a factor between 0 and 1
+func CriticalityFactor(criticality string) float64 {
+	if factor, ok := criticalityFactors[strings.ToLower(criticality)]; ok {
+		return factor
+	}
+	return unknownFactor
+}
+
+// SLAFactor maps the SLA of a CI to a factor between 0 and 1, from its named
+// tier when set and from its availability target otherwise
+func SLAFactor(ci *models.CI) float64 {
+	var attributes map[string]interface{}
+	if len(ci.Attributes) == 0 || json.Unmarshal(ci.Attributes, &attributes) != nil {
+		return unknownFactor
+	}
+
+	if tier, ok := attributes[SLATierAttribute].(string); ok {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tier)), " ", "")
+		if factor, ok := slaTierFactors[key]; ok {
+			return factor
+		}
+	}
+
+	availability, ok := number(attributes[SLAAttribute])
+	if !ok {
+		return unknownFactor
+	}
+	switch {
+	case availability >= 99.99:
+		return 1.0
+	case availability >= 99.95:
+		return 0.9
+	case availability >= 99.9:
+		return 0.8
+	case availability >= 99.5:
+		return 0.6
+	default:
+		return 0.4
+	}
+}
+
+// number reads a JSON number, or a string holding one such as "99.9" or "99.9%"
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "%"), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// round rounds to the given number of decimals
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
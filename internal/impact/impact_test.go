@@ -0,0 +1,212 @@
+package impact
+
+import (
+	"encoding/json"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCI(name, criticality string, attributes map[string]interface{}) models.CI {
+	raw, _ := json.Marshal(attributes)
+	return models.CI{ID: uuid.New(), Name: name, Type: "application", Criticality: criticality, Attributes: raw}
+}
+
+func testRelationship(source, target models.CI, relType string, attributes map[string]interface{}) models.CIRelationship {
+	raw, _ := json.Marshal(attributes)
+	return models.CIRelationship{
+		ID:         uuid.New(),
+		SourceCIID: source.ID,
+		TargetCIID: target.ID,
+		Type:       relType,
+		Attributes: raw,
+		IsActive:   true,
+		State:      models.RelationshipStateActive,
+	}
+}
+
+func TestAnalyze_RanksByScore(t *testing.T) {
+	db := testCI("orders-db", models.CICriticalityHigh, nil)
+	app := testCI("orders-api", models.CICriticalityHigh, nil)
+	payments := testCI("payments", models.CICriticalityCritical, map[string]interface{}{"sla": "99.99"})
+	reporting := testCI("reporting", models.CICriticalityLow, map[string]interface{}{"sla_tier": "Bronze"})
+
+	cis := []models.CI{db, app, payments, reporting}
+	relationships := []models.CIRelationship{
+		testRelationship(app, db, "uses", nil),
+		testRelationship(payments, app, "depends_on", nil),
+		testRelationship(reporting, db, "uses", map[string]interface{}{"strength": 0.2}),
+	}
+
+	impacts := Analyze(db.ID, cis, relationships, DefaultWeights(), 5)
+	require.Len(t, impacts, 3)
+
+	// payments: strength 0.8, 0.6*1 + 0.4*1
+	assert.Equal(t, payments.ID, impacts[0].ID)
+	assert.Equal(t, 80.0, impacts[0].Score)
+	assert.Equal(t, 2, impacts[0].Depth)
+	assert.Equal(t, []uuid.UUID{payments.ID, app.ID, db.ID}, impacts[0].Path)
+
+	// orders-api: strength 0.8, 0.6*0.75 + 0.4*0.5
+	assert.Equal(t, app.ID, impacts[1].ID)
+	assert.Equal(t, 52.0, impacts[1].Score)
+
+	// reporting: strength 0.2, 0.6*0.25 + 0.4*0.45
+	assert.Equal(t, reporting.ID, impacts[2].ID)
+	assert.Equal(t, 6.6, impacts[2].Score)
+}
+
+func TestAnalyze_StrongestPathWithinDepth(t *testing.T) {
+	db := testCI("db", models.CICriticalityHigh, nil)
+	direct := testCI("direct", models.CICriticalityHigh, nil)
+	via := testCI("via", models.CICriticalityHigh, nil)
+	service := testCI("service", models.CICriticalityCritical, nil)
+
+	cis := []models.CI{db, direct, via, service}
+	relationships := []models.CIRelationship{
+		testRelationship(service, db, "connected_to", nil),
+		testRelationship(via, db, "depends_on", nil),
+		testRelationship(direct, via, "depends_on", nil),
+		testRelationship(service, direct, "depends_on", nil),
+	}
+
+	// Three strong hops beat the weak direct edge when the depth allows them
+	impacts := Analyze(db.ID, cis, relationships, DefaultWeights(), 3)
+	byID := map[uuid.UUID]Impact{}
+	for _, impact := range impacts {
+		byID[impact.ID] = impact
+	}
+	assert.Equal(t, 3, byID[service.ID].Depth)
+	assert.Equal(t, 1.0, byID[service.ID].Strength)
+
+	impacts = Analyze(db.ID, cis, relationships, DefaultWeights(), 1)
+	require.Len(t, impacts, 2)
+	for _, impact := range impacts {
+		if impact.ID == service.ID {
+			assert.Equal(t, 1, impact.Depth)
+			assert.Equal(t, 0.5, impact.Strength)
+		}
+	}
+}
+
+func TestAnalyze_IgnoresInactiveAndCycles(t *testing.T) {
+	db := testCI("db", models.CICriticalityHigh, nil)
+	app := testCI("app", models.CICriticalityHigh, nil)
+	proposed := testCI("proposed", models.CICriticalityHigh, nil)
+
+	suggestion := testRelationship(proposed, db, "depends_on", nil)
+	suggestion.State = models.RelationshipStateProposed
+
+	relationships := []models.CIRelationship{
+		testRelationship(app, db, "depends_on", nil),
+		testRelationship(db, app, "depends_on", nil),
+		suggestion,
+	}
+
+	impacts := Analyze(db.ID, []models.CI{db, app, proposed}, relationships, DefaultWeights(), 5)
+	require.Len(t, impacts, 1)
+	assert.Equal(t, app.ID, impacts[0].ID)
+}
+
+func TestSLAFactor(t *testing.T) {
+	tests := []struct {
+		attributes map[string]interface{}
+		want       float64
+	}{
+		{map[string]interface{}{"sla_tier": "gold"}, 0.85},
+		{map[string]interface{}{"sla_tier": "Tier 0"}, 1.0},
+		{map[string]interface{}{"sla": 99.95}, 0.9},
+		{map[string]interface{}{"sla": "99.9%"}, 0.8},
+		{map[string]interface{}{"sla": "98"}, 0.4},
+		{map[string]interface{}{"sla": "best effort"}, unknownFactor},
+		{nil, unknownFactor},
+	}
+
+	for _, tt := range tests {
+		ci := testCI("ci", "", tt.attributes)
+		assert.Equal(t, tt.want, SLAFactor(&ci), "%v", tt.attributes)
+	}
+}
+
+func TestScore_ZeroWeightsFallBackToDefaults(t *testing.T) {
+	assert.Equal(t, Score(1, 0.5, 1, DefaultWeights()), Score(1, 0.5, 1, Weights{}))
+	assert.Equal(t, 100.0, Score(1, 0, 1, Weights{Criticality: 1}))
+}
+
+func TestAnalyze_PrefersPrimaryPath(t *testing.T) {
+	primaryDB := testCI("primary-db", models.CICriticalityHigh, nil)
+	replicaDB := testCI("replica-db", models.CICriticalityHigh, nil)
+	storage := testCI("storage", models.CICriticalityHigh, nil)
+	app := testCI("app", models.CICriticalityHigh, nil)
+
+	toReplica := testRelationship(app, replicaDB, "uses", nil)
+	toPrimary := testRelationship(app, primaryDB, "uses", nil)
+	toPrimary.IsPrimary = true
+	primaryOnStorage := testRelationship(primaryDB, storage, "uses", nil)
+	primaryOnStorage.IsPrimary = true
+
+	cis := []models.CI{primaryDB, replicaDB, storage, app}
+	relationships := []models.CIRelationship{
+		toReplica,
+		toPrimary,
+		primaryOnStorage,
+		testRelationship(replicaDB, storage, "uses", nil),
+	}
+
+	// Both databases give app an equally strong path to storage; the primary one wins
+	impacts := Analyze(storage.ID, cis, relationships, DefaultWeights(), 3)
+	byID := map[uuid.UUID]Impact{}
+	for _, impact := range impacts {
+		byID[impact.ID] = impact
+	}
+	require.Contains(t, byID, app.ID)
+	assert.Equal(t, []uuid.UUID{app.ID, primaryDB.ID, storage.ID}, byID[app.ID].Path)
+	assert.True(t, byID[app.ID].Primary)
+	assert.True(t, byID[primaryDB.ID].Primary)
+	assert.False(t, byID[replicaDB.ID].Primary)
+}
+
+func TestAnalyzeLearned_RaisesEmpiricallyCriticalRelationships(t *testing.T) {
+	db := testCI("orders-db", models.CICriticalityHigh, nil)
+	reporting := testCI("reporting", models.CICriticalityLow, map[string]interface{}{"sla_tier": "Bronze"})
+	batch := testCI("batch", models.CICriticalityLow, map[string]interface{}{"sla_tier": "Bronze"})
+	cis := []models.CI{db, reporting, batch}
+
+	weak := testRelationship(reporting, db, "uses", map[string]interface{}{"strength": 0.2})
+	strong := testRelationship(batch, db, "depends_on", nil)
+	learned := map[uuid.UUID]float64{weak.ID: 0.9, strong.ID: 0.5}
+
+	impacts := AnalyzeLearned(db.ID, cis, []models.CIRelationship{weak, strong}, DefaultWeights(), 3, learned)
+	require.Len(t, impacts, 2)
+	byID := map[uuid.UUID]Impact{impacts[0].ID: impacts[0], impacts[1].ID: impacts[1]}
+
+	// Incidents show reporting fails with the database despite its low strength
+	assert.Equal(t, 0.9, byID[reporting.ID].Strength)
+	assert.True(t, byID[reporting.ID].Empirical)
+	// A learned strength never lowers a configured one
+	assert.Equal(t, 1.0, byID[batch.ID].Strength)
+	assert.True(t, byID[batch.ID].Empirical)
+
+	for _, item := range Analyze(db.ID, cis, []models.CIRelationship{weak, strong}, DefaultWeights(), 3) {
+		assert.False(t, item.Empirical)
+	}
+}
+
+func TestDefaultStrengths_MatchStrength(t *testing.T) {
+	var source, target models.CI
+	defaults, unknown := DefaultStrengths()
+	for relType, strength := range defaults {
+		rel := testRelationship(source, target, relType, nil)
+		assert.Equal(t, strength, Strength(&rel), relType)
+	}
+	rel := testRelationship(source, target, "monitors", nil)
+	assert.Equal(t, unknown, Strength(&rel))
+
+	// Changing the copy leaves the defaults alone
+	defaults["depends_on"] = 0
+	again, _ := DefaultStrengths()
+	assert.Equal(t, 1.0, again["depends_on"])
+}
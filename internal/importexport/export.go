@@ -0,0 +1,199 @@
+package importexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"connect/internal/models"
+)
+
+// Streamed export formats, besides FormatCSV and FormatNDJSON
+const (
+	FormatJSON = "json"
+	FormatXLSX = "xlsx"
+)
+
+// FormatEdgeList is the CSV edge list the dependency graph is exported as
+const FormatEdgeList = "edgelist"
+
+// ExportFormats are the formats CIs and relationships can be exported in
+var ExportFormats = []string{FormatCSV, FormatJSON, FormatNDJSON, FormatXLSX}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return ContentTypeXLSX
+	default:
+		return "application/json"
+	}
+}
+
+// Exporter streams CIs or relationships to a writer one at a time. CSV and
+// .xlsx exports use the columns of the sheets of a workbook, so they can be
+// imported again; JSON and NDJSON exports hold the API representation.
+type Exporter struct {
+	table interface {
+		WriteRow(values ...string) error
+		Close() error
+	}
+	records        *recordWriter
+	attributeNames []string
+	edgeList       bool
+}
+
+// NewCIExporter starts an export of CIs. CSV and .xlsx exports have one column
+// per name in attributeNames, which should hold every attribute of the CIs.
+func NewCIExporter(w io.Writer, format string, attributeNames []string) (*Exporter, error) {
+	exporter, err := newExporter(w, format, SheetCIs, ciHeader(attributeNames))
+	if err != nil {
+		return nil, err
+	}
+	exporter.attributeNames = attributeNames
+	return exporter, nil
+}
+
+// NewRelationshipExporter starts an export of relationships
+func NewRelationshipExporter(w io.Writer, format string) (*Exporter, error) {
+	return newExporter(w, format, SheetRelationships, relationshipColumns)
+}
+
+// NewEdgeListExporter starts an export of relationships as a CSV edge list,
+// one source_id, source_name, target_id, target_name, type, strength row per
+// relationship, for loading the dependency graph into BI tools. strength is
+// the relationship's impact strength from 0 to 1.
+func NewEdgeListExporter(w io.Writer) (*Exporter, error) {
+	exporter, err := newExporter(w, FormatCSV, "", edgeListColumns)
+	if err != nil {
+		return nil, err
+	}
+	exporter.edgeList = true
+	return exporter, nil
+}
+
+func newExporter(w io.Writer, format, sheet string, header []string) (*Exporter, error) {
+	switch format {
+	case FormatCSV:
+		table := &csvTable{w: csv.NewWriter(w)}
+		if err := table.WriteRow(header...); err != nil {
+			return nil, err
+		}
+		return &Exporter{table: table}, nil
+	case FormatXLSX:
+		table, err := NewXLSXStreamWriter(w, sheet, header)
+		if err != nil {
+			return nil, err
+		}
+		return &Exporter{table: table}, nil
+	case FormatJSON, FormatNDJSON:
+		return &Exporter{records: &recordWriter{w: bufio.NewWriter(w), array: format == FormatJSON}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// WriteCI exports a CI
+func (e *Exporter) WriteCI(ci *models.CI) error {
+	if e.records != nil {
+		return e.records.write(ci)
+	}
+
+	attributes := make(map[string]interface{})
+	if len(ci.Attributes) > 0 && string(ci.Attributes) != "null" {
+		if err := json.Unmarshal(ci.Attributes, &attributes); err != nil {
+			return fmt.Errorf("failed to unmarshal attributes of CI %s: %w", ci.ID, err)
+		}
+	}
+	row, err := ciRow(ci, attributes, e.attributeNames)
+	if err != nil {
+		return err
+	}
+	return e.table.WriteRow(row...)
+}
+
+// WriteRelationship exports a relationship with the names of its CIs
+func (e *Exporter) WriteRelationship(rel *models.CIRelationship, sourceName, targetName string) error {
+	if e.records != nil {
+		return e.records.write(struct {
+			*models.CIRelationship
+			SourceCIName string `json:"source_ci_name"`
+			TargetCIName string `json:"target_ci_name"`
+		}{rel, sourceName, targetName})
+	}
+	if e.edgeList {
+		return e.table.WriteRow(edgeListRow(rel, sourceName, targetName)...)
+	}
+	return e.table.WriteRow(relationshipRow(rel, sourceName, targetName)...)
+}
+
+// Close ends the export and flushes what is left to the writer
+func (e *Exporter) Close() error {
+	if e.records != nil {
+		return e.records.close()
+	}
+	return e.table.Close()
+}
+
+// csvTable writes the rows of an export as CSV
+type csvTable struct {
+	w *csv.Writer
+}
+
+func (t *csvTable) WriteRow(values ...string) error {
+	return t.w.Write(values)
+}
+
+func (t *csvTable) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// recordWriter writes the records of an export as a JSON array or as NDJSON
+type recordWriter struct {
+	w     *bufio.Writer
+	array bool
+	count int
+}
+
+func (r *recordWriter) write(record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	separator := "\n"
+	if r.array {
+		separator = ",\n"
+		if r.count == 0 {
+			separator = "[\n"
+		}
+	} else if r.count == 0 {
+		separator = ""
+	}
+	r.count++
+
+	if _, err := r.w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = r.w.Write(data)
+	return err
+}
+
+func (r *recordWriter) close() error {
+	switch {
+	case r.array && r.count == 0:
+		r.w.WriteString("[]\n")
+	case r.array:
+		r.w.WriteString("\n]\n")
+	case r.count > 0:
+		r.w.WriteString("\n")
+	}
+	return r.w.Flush()
+}
@@ -0,0 +1,110 @@
+package importexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportCIs(t *testing.T, format string, cis ...*models.CI) []byte {
+	var buf bytes.Buffer
+	exporter, err := NewCIExporter(&buf, format, []string{"cpu", "env"})
+	require.NoError(t, err)
+	for _, ci := range cis {
+		require.NoError(t, exporter.WriteCI(ci))
+	}
+	require.NoError(t, exporter.Close())
+	return buf.Bytes()
+}
+
+func TestCIExport_CSVRoundTrip(t *testing.T) {
+	ci := &models.CI{
+		ID:         uuid.New(),
+		Name:       "db-01",
+		Type:       "server",
+		Tags:       []string{"prod", "db"},
+		Attributes: json.RawMessage(`{"cpu": 8, "env": "prod, eu"}`),
+		IsActive:   true,
+	}
+	data := exportCIs(t, FormatCSV, ci)
+
+	reader, err := NewCIReader(bytes.NewReader(data), FormatCSV)
+	require.NoError(t, err)
+	row, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, ci.ID, row.ID)
+	assert.Equal(t, 2, row.Line)
+	assert.Equal(t, []string{"prod", "db"}, row.Tags)
+	assert.Equal(t, map[string]string{"cpu": "8", "env": "prod, eu"}, row.Attributes)
+
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCIExport_XLSXStreamReadsAsWorkbook(t *testing.T) {
+	ci := &models.CI{ID: uuid.New(), Name: "web-01", Type: "server", Attributes: json.RawMessage(`{"env": "test"}`)}
+	data := exportCIs(t, FormatXLSX, ci)
+
+	wb, err := ReadXLSXBytes(data)
+	require.NoError(t, err)
+	parsed := ParseCIWorkbook(wb)
+	require.Empty(t, parsed.Errors)
+	require.Len(t, parsed.CIs, 1)
+	assert.Equal(t, "web-01", parsed.CIs[0].Name)
+	assert.Equal(t, map[string]string{"env": "test"}, parsed.CIs[0].Attributes)
+}
+
+func TestCIExport_JSON(t *testing.T) {
+	var empty []models.CI
+	require.NoError(t, json.Unmarshal(exportCIs(t, FormatJSON), &empty))
+	assert.Empty(t, empty)
+
+	var cis []models.CI
+	require.NoError(t, json.Unmarshal(exportCIs(t, FormatJSON, &models.CI{Name: "a"}, &models.CI{Name: "b"}), &cis))
+	require.Len(t, cis, 2)
+	assert.Equal(t, "b", cis[1].Name)
+
+	lines := bytes.Split(bytes.TrimSpace(exportCIs(t, FormatNDJSON, &models.CI{Name: "a"}, &models.CI{Name: "b"})), []byte("\n"))
+	assert.Len(t, lines, 2)
+}
+
+func TestRelationshipExport_JSONIncludesNames(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := NewRelationshipExporter(&buf, FormatNDJSON)
+	require.NoError(t, err)
+	require.NoError(t, exporter.WriteRelationship(&models.CIRelationship{ID: uuid.New(), Type: "depends_on"}, "app", "db"))
+	require.NoError(t, exporter.Close())
+
+	var rel map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rel))
+	assert.Equal(t, "depends_on", rel["type"])
+	assert.Equal(t, "app", rel["source_ci_name"])
+	assert.Equal(t, "db", rel["target_ci_name"])
+}
+
+func TestEdgeListExport(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := NewEdgeListExporter(&buf)
+	require.NoError(t, err)
+	rel := &models.CIRelationship{
+		ID:         uuid.New(),
+		SourceCIID: uuid.New(),
+		TargetCIID: uuid.New(),
+		Type:       "connects_to",
+		Attributes: json.RawMessage(`{"strength": 0.25}`),
+	}
+	require.NoError(t, exporter.WriteRelationship(rel, "app, eu", "db"))
+	require.NoError(t, exporter.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "source_id,source_name,target_id,target_name,type,strength", lines[0])
+	assert.Equal(t, rel.SourceCIID.String()+`,"app, eu",`+rel.TargetCIID.String()+",db,connects_to,0.25", lines[1])
+}
@@ -0,0 +1,54 @@
+package importexport
+
+import "connect/internal/importref"
+
+// SheetResult counts the outcome of importing the rows of one sheet
+type SheetResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+}
+
+// ImportResult summarizes a workbook import
+type ImportResult struct {
+	JobID         string      `json:"job_id,omitempty"`
+	Schemas       SheetResult `json:"schemas"`
+	CIs           SheetResult `json:"cis"`
+	Relationships SheetResult `json:"relationships"`
+	Errors        []RowError  `json:"errors,omitempty"`
+	// References is the outcome of relating the CIs to the CIs their
+	// reference attributes name, when any CI has such references
+	References *importref.Result `json:"references,omitempty"`
+	// RollbackURL is where the import can be rolled back, when it was journaled
+	RollbackURL string `json:"rollback_url,omitempty"`
+}
+
+// NewImportResult creates a result seeded with the parse errors of a workbook
+func NewImportResult(parsed *ParsedWorkbook) *ImportResult {
+	result := &ImportResult{}
+	for _, rowErr := range parsed.Errors {
+		result.Errors = append(result.Errors, rowErr)
+		switch rowErr.Sheet {
+		case SheetSchemas:
+			result.Schemas.Failed++
+		case SheetCIs:
+			result.CIs.Failed++
+		case SheetRelationships:
+			result.Relationships.Failed++
+		}
+	}
+	return result
+}
+
+// Fail records a row that could not be imported
+func (r *ImportResult) Fail(sheet string, row int, err error) {
+	r.Errors = append(r.Errors, RowError{Sheet: sheet, Row: row, Message: err.Error()})
+	switch sheet {
+	case SheetSchemas:
+		r.Schemas.Failed++
+	case SheetCIs:
+		r.CIs.Failed++
+	case SheetRelationships:
+		r.Relationships.Failed++
+	}
+}
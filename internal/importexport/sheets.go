@@ -0,0 +1,619 @@
+package importexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"connect/internal/impact"
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Sheet names used by CMDB workbooks
+const (
+	SheetCIs           = "CIs"
+	SheetRelationships = "Relationships"
+	SheetSchemas       = "Schemas"
+)
+
+// Schema kinds on the schemas sheet
+const (
+	SchemaKindCI           = "ci"
+	SchemaKindRelationship = "relationship"
+)
+
+// attributeColumnPrefix marks CI sheet columns that hold flexible attributes
+const attributeColumnPrefix = "attr:"
+
+// excelEpoch is the zero date of the 1900 date system (accounting for the 1900 leap year bug)
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+var ciColumns = []string{
+	"id", "name", "type", "description", "status", "criticality", "owner", "location",
+	"tags", "install_date", "warranty_expiry", "is_active",
+}
+
+var relationshipColumns = []string{
+	"id", "source_ci_id", "source_ci_name", "target_ci_id", "target_ci_name",
+	"type", "description", "attributes", "is_active", "state",
+}
+
+var edgeListColumns = []string{
+	"source_id", "source_name", "target_id", "target_name", "type", "strength",
+}
+
+var schemaColumns = []string{
+	"kind", "schema_name", "schema_description", "attribute_name", "attribute_type",
+	"required", "attribute_description", "default", "validation",
+}
+
+// BuildCIWorkbook renders CIs, relationships and schemas as a multi-sheet workbook.
+// CI attributes are flattened into one "attr:<name>" column per attribute.
+func BuildCIWorkbook(cis []models.CI, relationships []*models.CIRelationship, ciSchemas []*models.CITypeSchema, relSchemas []*models.RelationshipTypeSchema) (*Workbook, error) {
+	wb := NewWorkbook()
+
+	// Collect the union of attribute names so every CI shares the same columns
+	ciAttributes := make([]map[string]interface{}, len(cis))
+	attributeSet := make(map[string]bool)
+	for i, ci := range cis {
+		attrs := make(map[string]interface{})
+		if len(ci.Attributes) > 0 && string(ci.Attributes) != "null" {
+			if err := json.Unmarshal(ci.Attributes, &attrs); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal attributes of CI %s: %w", ci.ID, err)
+			}
+		}
+		ciAttributes[i] = attrs
+		for name := range attrs {
+			attributeSet[name] = true
+		}
+	}
+	attributeNames := make([]string, 0, len(attributeSet))
+	for name := range attributeSet {
+		attributeNames = append(attributeNames, name)
+	}
+	sort.Strings(attributeNames)
+
+	ciSheet := wb.AddSheet(SheetCIs, ciHeader(attributeNames))
+
+	names := make(map[uuid.UUID]string, len(cis))
+	for i := range cis {
+		ci := &cis[i]
+		names[ci.ID] = ci.Name
+		row, err := ciRow(ci, ciAttributes[i], attributeNames)
+		if err != nil {
+			return nil, err
+		}
+		ciSheet.AddRow(row...)
+	}
+
+	relSheet := wb.AddSheet(SheetRelationships, relationshipColumns)
+	for _, rel := range relationships {
+		relSheet.AddRow(relationshipRow(rel, names[rel.SourceCIID], names[rel.TargetCIID])...)
+	}
+
+	schemaSheet := wb.AddSheet(SheetSchemas, schemaColumns)
+	for _, schema := range ciSchemas {
+		if err := addSchemaRows(schemaSheet, SchemaKindCI, schema.Name, schema.Description, schema.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	for _, schema := range relSchemas {
+		if err := addSchemaRows(schemaSheet, SchemaKindRelationship, schema.Name, schema.Description, schema.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	return wb, nil
+}
+
+// ciHeader returns the CI columns followed by one column per attribute
+func ciHeader(attributeNames []string) []string {
+	header := append([]string{}, ciColumns...)
+	for _, name := range attributeNames {
+		header = append(header, attributeColumnPrefix+name)
+	}
+	return header
+}
+
+// ciRow renders a CI as the cells of a CI row, with its attributes in the
+// order of attributeNames
+func ciRow(ci *models.CI, attributes map[string]interface{}, attributeNames []string) ([]string, error) {
+	row := []string{
+		ci.ID.String(),
+		ci.Name,
+		ci.Type,
+		ci.Description,
+		ci.Status,
+		ci.Criticality,
+		ci.Owner,
+		ci.Location,
+		strings.Join(ci.Tags, ", "),
+		formatDate(ci.InstallDate),
+		formatDate(ci.WarrantyExpiry),
+		strconv.FormatBool(ci.IsActive),
+	}
+	for _, name := range attributeNames {
+		value, err := formatValue(attributes[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to format attribute %s of CI %s: %w", name, ci.ID, err)
+		}
+		row = append(row, value)
+	}
+	return row, nil
+}
+
+// relationshipRow renders a relationship as the cells of a relationship row
+func relationshipRow(rel *models.CIRelationship, sourceName, targetName string) []string {
+	attributes := ""
+	if len(rel.Attributes) > 0 && string(rel.Attributes) != "null" {
+		attributes = string(rel.Attributes)
+	}
+	return []string{
+		rel.ID.String(),
+		rel.SourceCIID.String(),
+		sourceName,
+		rel.TargetCIID.String(),
+		targetName,
+		rel.Type,
+		rel.Description,
+		attributes,
+		strconv.FormatBool(rel.IsActive),
+		rel.State,
+	}
+}
+
+func edgeListRow(rel *models.CIRelationship, sourceName, targetName string) []string {
+	return []string{
+		rel.SourceCIID.String(),
+		sourceName,
+		rel.TargetCIID.String(),
+		targetName,
+		rel.Type,
+		strconv.FormatFloat(impact.Strength(rel), 'f', -1, 64),
+	}
+}
+
+// addSchemaRows writes one row per schema attribute (or a single row for schemas without attributes)
+func addSchemaRows(sheet *Sheet, kind, name, description string, attributes []models.CITypeAttribute) error {
+	if len(attributes) == 0 {
+		sheet.AddRow(kind, name, description)
+		return nil
+	}
+
+	for _, attr := range attributes {
+		defaultValue := ""
+		if attr.Default != nil {
+			data, err := json.Marshal(attr.Default)
+			if err != nil {
+				return fmt.Errorf("failed to marshal default of %s.%s: %w", name, attr.Name, err)
+			}
+			defaultValue = string(data)
+		}
+		validation := ""
+		if len(attr.Validation) > 0 {
+			data, err := json.Marshal(attr.Validation)
+			if err != nil {
+				return fmt.Errorf("failed to marshal validation of %s.%s: %w", name, attr.Name, err)
+			}
+			validation = string(data)
+		}
+		sheet.AddRow(kind, name, description, attr.Name, attr.Type,
+			strconv.FormatBool(attr.Required), attr.Description, defaultValue, validation)
+	}
+
+	return nil
+}
+
+// CIRow is a CI parsed from the CIs sheet. Attributes holds the raw cell text
+// keyed by attribute name; use CoerceAttributes to type them against a schema.
+type CIRow struct {
+	Line           int
+	ID             uuid.UUID
+	Name           string
+	Type           string
+	Description    string
+	Status         string
+	Criticality    string
+	Owner          string
+	Location       string
+	Tags           []string
+	InstallDate    *time.Time
+	WarrantyExpiry *time.Time
+	IsActive       *bool
+	Attributes     map[string]string
+}
+
+// RelationshipRow is a relationship parsed from the Relationships sheet
+type RelationshipRow struct {
+	Line         int
+	ID           uuid.UUID
+	SourceCIID   uuid.UUID
+	SourceCIName string
+	TargetCIID   uuid.UUID
+	TargetCIName string
+	Type         string
+	Description  string
+	Attributes   json.RawMessage
+	IsActive     *bool
+	State        string
+}
+
+// SchemaDefinition is a schema assembled from one or more rows of the Schemas sheet
+type SchemaDefinition struct {
+	Line        int
+	Kind        string
+	Name        string
+	Description string
+	Attributes  []models.CITypeAttribute
+}
+
+// RowError describes a row that could not be parsed
+type RowError struct {
+	Sheet   string `json:"sheet"`
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// ParsedWorkbook holds the rows read from a CMDB workbook
+type ParsedWorkbook struct {
+	CIs           []CIRow
+	Relationships []RelationshipRow
+	Schemas       []SchemaDefinition
+	Errors        []RowError
+}
+
+// ParseCIWorkbook extracts CIs, relationships and schemas from a workbook.
+// Missing sheets are ignored; malformed rows are reported in Errors and skipped.
+func ParseCIWorkbook(wb *Workbook) *ParsedWorkbook {
+	parsed := &ParsedWorkbook{}
+
+	if sheet := wb.Sheet(SheetSchemas); sheet != nil {
+		parsed.parseSchemas(sheet)
+	}
+	if sheet := wb.Sheet(SheetCIs); sheet != nil {
+		parsed.parseCIs(sheet)
+	}
+	if sheet := wb.Sheet(SheetRelationships); sheet != nil {
+		parsed.parseRelationships(sheet)
+	}
+
+	return parsed
+}
+
+func (p *ParsedWorkbook) addError(sheet string, line int, format string, args ...interface{}) {
+	p.Errors = append(p.Errors, RowError{Sheet: sheet, Row: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *ParsedWorkbook) parseCIs(sheet *Sheet) {
+	records, lines := sheet.Records()
+	for i, record := range records {
+		row, err := parseCIRecord(record, lines[i])
+		if err != nil {
+			p.addError(SheetCIs, lines[i], "%v", err)
+			continue
+		}
+		p.CIs = append(p.CIs, row)
+	}
+}
+
+// parseCIRecord parses a CI from a record keyed by lower-case column name
+func parseCIRecord(record map[string]string, line int) (CIRow, error) {
+	row := CIRow{
+		Line:        line,
+		Name:        record["name"],
+		Type:        record["type"],
+		Description: record["description"],
+		Status:      record["status"],
+		Criticality: record["criticality"],
+		Owner:       record["owner"],
+		Location:    record["location"],
+		Attributes:  make(map[string]string),
+	}
+
+	if row.Name == "" || row.Type == "" {
+		return row, fmt.Errorf("name and type are required")
+	}
+
+	var err error
+	if row.ID, err = parseOptionalUUID(record["id"]); err != nil {
+		return row, fmt.Errorf("invalid id: %v", err)
+	}
+	if row.InstallDate, err = ParseDate(record["install_date"]); err != nil {
+		return row, fmt.Errorf("invalid install_date: %v", err)
+	}
+	if row.WarrantyExpiry, err = ParseDate(record["warranty_expiry"]); err != nil {
+		return row, fmt.Errorf("invalid warranty_expiry: %v", err)
+	}
+	if row.IsActive, err = parseOptionalBool(record["is_active"]); err != nil {
+		return row, fmt.Errorf("invalid is_active: %v", err)
+	}
+	row.Tags = splitList(record["tags"])
+
+	for key, value := range record {
+		if strings.HasPrefix(key, attributeColumnPrefix) && value != "" {
+			row.Attributes[strings.TrimPrefix(key, attributeColumnPrefix)] = value
+		}
+	}
+
+	return row, nil
+}
+
+func (p *ParsedWorkbook) parseRelationships(sheet *Sheet) {
+	records, lines := sheet.Records()
+	for i, record := range records {
+		line := lines[i]
+		row := RelationshipRow{
+			Line:         line,
+			SourceCIName: record["source_ci_name"],
+			TargetCIName: record["target_ci_name"],
+			Type:         record["type"],
+			Description:  record["description"],
+		}
+
+		if row.Type == "" {
+			p.addError(SheetRelationships, line, "type is required")
+			continue
+		}
+
+		var err error
+		if row.ID, err = parseOptionalUUID(record["id"]); err != nil {
+			p.addError(SheetRelationships, line, "invalid id: %v", err)
+			continue
+		}
+		if row.SourceCIID, err = parseOptionalUUID(record["source_ci_id"]); err != nil {
+			p.addError(SheetRelationships, line, "invalid source_ci_id: %v", err)
+			continue
+		}
+		if row.TargetCIID, err = parseOptionalUUID(record["target_ci_id"]); err != nil {
+			p.addError(SheetRelationships, line, "invalid target_ci_id: %v", err)
+			continue
+		}
+		if (row.SourceCIID == uuid.Nil && row.SourceCIName == "") || (row.TargetCIID == uuid.Nil && row.TargetCIName == "") {
+			p.addError(SheetRelationships, line, "source and target CI must be given by id or name")
+			continue
+		}
+		if row.IsActive, err = parseOptionalBool(record["is_active"]); err != nil {
+			p.addError(SheetRelationships, line, "invalid is_active: %v", err)
+			continue
+		}
+		if row.State = strings.ToLower(record["state"]); row.State != "" && !models.IsValidRelationshipState(row.State) {
+			p.addError(SheetRelationships, line, "invalid state %q", row.State)
+			continue
+		}
+		if attrs := record["attributes"]; attrs != "" {
+			if !json.Valid([]byte(attrs)) {
+				p.addError(SheetRelationships, line, "attributes must be valid JSON")
+				continue
+			}
+			row.Attributes = json.RawMessage(attrs)
+		}
+
+		p.Relationships = append(p.Relationships, row)
+	}
+}
+
+func (p *ParsedWorkbook) parseSchemas(sheet *Sheet) {
+	records, lines := sheet.Records()
+	index := make(map[string]int)
+
+	for i, record := range records {
+		line := lines[i]
+		kind := strings.ToLower(record["kind"])
+		if kind == "" {
+			kind = SchemaKindCI
+		}
+		if kind != SchemaKindCI && kind != SchemaKindRelationship {
+			p.addError(SheetSchemas, line, "kind must be %q or %q", SchemaKindCI, SchemaKindRelationship)
+			continue
+		}
+		name := record["schema_name"]
+		if name == "" {
+			p.addError(SheetSchemas, line, "schema_name is required")
+			continue
+		}
+
+		key := kind + "/" + name
+		pos, ok := index[key]
+		if !ok {
+			p.Schemas = append(p.Schemas, SchemaDefinition{Line: line, Kind: kind, Name: name})
+			pos = len(p.Schemas) - 1
+			index[key] = pos
+		}
+		schema := &p.Schemas[pos]
+		if schema.Description == "" {
+			schema.Description = record["schema_description"]
+		}
+
+		attrName := record["attribute_name"]
+		if attrName == "" {
+			continue
+		}
+
+		attr := models.CITypeAttribute{
+			Name:        attrName,
+			Type:        strings.ToLower(record["attribute_type"]),
+			Description: record["attribute_description"],
+		}
+		if attr.Type == "" {
+			attr.Type = models.AttributeTypeString
+		}
+		required, err := parseOptionalBool(record["required"])
+		if err != nil {
+			p.addError(SheetSchemas, line, "invalid required: %v", err)
+			continue
+		}
+		if required != nil {
+			attr.Required = *required
+		}
+		if value := record["default"]; value != "" {
+			if err := json.Unmarshal([]byte(value), &attr.Default); err != nil {
+				// Plain text defaults don't need JSON quoting
+				attr.Default = value
+			}
+		}
+		if value := record["validation"]; value != "" {
+			if err := json.Unmarshal([]byte(value), &attr.Validation); err != nil {
+				p.addError(SheetSchemas, line, "validation must be a JSON object: %v", err)
+				continue
+			}
+		}
+
+		schema.Attributes = append(schema.Attributes, attr)
+	}
+}
+
+// CoerceAttributes converts raw attribute cell values to typed JSON using the
+// attribute types declared in schema. Attributes unknown to the schema are kept as strings.
+func CoerceAttributes(raw map[string]string, schema *models.CITypeSchema) (json.RawMessage, error) {
+	types := make(map[string]string)
+	if schema != nil {
+		for _, attr := range schema.Attributes {
+			types[attr.Name] = attr.Type
+		}
+	}
+
+	attributes := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		typed, err := coerceValue(value, types[name])
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		attributes[name] = typed
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+
+	return data, nil
+}
+
+func coerceValue(value, attrType string) (interface{}, error) {
+	switch attrType {
+	case models.AttributeTypeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return n, nil
+	case models.AttributeTypeBoolean:
+		b, err := parseOptionalBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return *b, nil
+	case models.AttributeTypeDate:
+		t, err := ParseDate(value)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(time.RFC3339), nil
+	case models.AttributeTypeArray:
+		var arr []interface{}
+		if err := json.Unmarshal([]byte(value), &arr); err == nil {
+			return arr, nil
+		}
+		list := splitList(value)
+		arr = make([]interface{}, len(list))
+		for i, item := range list {
+			arr[i] = item
+		}
+		return arr, nil
+	case models.AttributeTypeObject:
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &obj); err != nil {
+			return nil, fmt.Errorf("%q is not a JSON object", value)
+		}
+		return obj, nil
+	default:
+		return value, nil
+	}
+}
+
+// ParseDate accepts RFC 3339 timestamps, plain YYYY-MM-DD dates and Excel
+// serial date numbers. An empty value yields nil.
+func ParseDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		days, frac := math.Modf(serial)
+		t := excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(frac * float64(24*time.Hour)).Round(time.Second))
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("unrecognized date %q", value)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatValue renders an attribute value as cell text
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+func parseOptionalUUID(value string) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(value)
+}
+
+func parseOptionalBool(value string) (*bool, error) {
+	var b bool
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return nil, nil
+	case "true", "yes", "y", "1":
+		b = true
+	case "false", "no", "n", "0":
+		b = false
+	default:
+		return nil, fmt.Errorf("%q is not a boolean", value)
+	}
+	return &b, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
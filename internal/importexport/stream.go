@@ -0,0 +1,219 @@
+package importexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Streamed CI upload formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// maxNDJSONLineSize is the longest NDJSON line, i.e. the largest single CI, accepted
+const maxNDJSONLineSize = 1 << 20
+
+// DetectFormat guesses the format of an upload from its file name and content
+// type, returning "" when it is neither CSV nor NDJSON
+func DetectFormat(filename, contentType string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return FormatNDJSON
+	}
+	return ""
+}
+
+// CIReader streams CI rows out of a CSV or NDJSON upload one row at a time, so
+// uploads of any size are read in constant memory.
+//
+// CSV uploads use the columns of the CIs sheet of a workbook, including the
+// "attr:<name>" attribute columns. NDJSON uploads hold one CI object per line
+// with the same fields, attributes given as an "attributes" object and tags as
+// a list or a comma-separated string. Attribute values are typed against the
+// CI type schema with CoerceAttributes either way.
+type CIReader struct {
+	next func() (map[string]string, int, error)
+}
+
+// NewCIReader creates a reader of CI rows in the given format. A CSV upload
+// must start with its header row.
+func NewCIReader(r io.Reader, format string) (*CIReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected %q or %q", format, FormatCSV, FormatNDJSON)
+	}
+}
+
+// Next returns the next CI row, or io.EOF after the last one. A row that
+// cannot be parsed is returned as a RowError and reading can go on; any other
+// error means the upload cannot be read any further.
+func (c *CIReader) Next() (CIRow, error) {
+	for {
+		record, line, err := c.next()
+		if err != nil {
+			return CIRow{}, err
+		}
+		if record == nil {
+			continue
+		}
+
+		row, err := parseCIRecord(record, line)
+		if err != nil {
+			return CIRow{}, RowError{Row: line, Message: err.Error()}
+		}
+		return row, nil
+	}
+}
+
+// Error implements error, so rows can be skipped by the readers of an upload
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+func newCSVReader(r io.Reader) (*CIReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV upload is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	keys := make([]string, len(header))
+	for i, name := range header {
+		keys[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	return &CIReader{next: func() (map[string]string, int, error) {
+		fields, err := reader.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, 0, RowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		record := make(map[string]string, len(keys))
+		blank := true
+		for i, value := range fields {
+			if i >= len(keys) || keys[i] == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if value != "" {
+				blank = false
+			}
+			record[keys[i]] = value
+		}
+		if blank {
+			return nil, line, nil
+		}
+		return record, line, nil
+	}}, nil
+}
+
+func newNDJSONReader(r io.Reader) *CIReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLineSize)
+	line := 0
+
+	return &CIReader{next: func() (map[string]string, int, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				if errors.Is(err, bufio.ErrTooLong) {
+					return nil, 0, fmt.Errorf("line %d is longer than %d bytes", line+1, maxNDJSONLineSize)
+				}
+				return nil, 0, err
+			}
+			return nil, 0, io.EOF
+		}
+		line++
+
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			return nil, line, nil
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &object); err != nil {
+			return nil, 0, RowError{Row: line, Message: fmt.Sprintf("invalid JSON: %v", err)}
+		}
+		record, err := flattenCIObject(object)
+		if err != nil {
+			return nil, 0, RowError{Row: line, Message: err.Error()}
+		}
+		return record, line, nil
+	}}
+}
+
+// flattenCIObject turns an NDJSON CI object into a record keyed like the
+// columns of a CSV upload
+func flattenCIObject(object map[string]interface{}) (map[string]string, error) {
+	record := make(map[string]string, len(object))
+	for key, value := range object {
+		key = strings.ToLower(key)
+		switch key {
+		case "attributes":
+			if value == nil {
+				continue
+			}
+			attributes, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("attributes must be an object")
+			}
+			for name, attr := range attributes {
+				text, err := formatValue(attr)
+				if err != nil {
+					return nil, fmt.Errorf("invalid attribute %s: %w", name, err)
+				}
+				record[attributeColumnPrefix+name] = text
+			}
+		case "tags":
+			if list, ok := value.([]interface{}); ok {
+				tags := make([]string, 0, len(list))
+				for _, tag := range list {
+					text, ok := tag.(string)
+					if !ok {
+						return nil, errors.New("tags must be strings")
+					}
+					tags = append(tags, text)
+				}
+				record[key] = strings.Join(tags, ",")
+				continue
+			}
+			fallthrough
+		default:
+			text, err := formatValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			record[key] = strings.TrimSpace(text)
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,545 @@
+package importexport
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ContentTypeXLSX is the MIME type of an Office Open XML workbook
+const ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// maxPartSize caps the uncompressed size of a single workbook part to guard against zip bombs
+const maxPartSize = 64 << 20
+
+// Workbook is a minimal, format-agnostic representation of a spreadsheet
+type Workbook struct {
+	Sheets []*Sheet
+}
+
+// Sheet is a named grid of cell values. The first row is treated as the header row.
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// NewWorkbook creates an empty workbook
+func NewWorkbook() *Workbook {
+	return &Workbook{}
+}
+
+// AddSheet appends a new sheet with the given header row and returns it
+func (wb *Workbook) AddSheet(name string, header []string) *Sheet {
+	sheet := &Sheet{Name: name}
+	if len(header) > 0 {
+		sheet.Rows = append(sheet.Rows, header)
+	}
+	wb.Sheets = append(wb.Sheets, sheet)
+	return sheet
+}
+
+// Sheet returns the sheet with the given name (case-insensitive) or nil
+func (wb *Workbook) Sheet(name string) *Sheet {
+	for _, sheet := range wb.Sheets {
+		if strings.EqualFold(sheet.Name, name) {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// AddRow appends a data row to the sheet
+func (s *Sheet) AddRow(values ...string) {
+	s.Rows = append(s.Rows, values)
+}
+
+// Header returns the header row of the sheet
+func (s *Sheet) Header() []string {
+	if len(s.Rows) == 0 {
+		return nil
+	}
+	return s.Rows[0]
+}
+
+// Records returns the data rows as maps keyed by normalized header name.
+// Blank rows are skipped; the returned line numbers are 1-based spreadsheet rows.
+func (s *Sheet) Records() ([]map[string]string, []int) {
+	header := s.Header()
+	keys := make([]string, len(header))
+	for i, name := range header {
+		keys[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	var records []map[string]string
+	var lines []int
+	for i := 1; i < len(s.Rows); i++ {
+		record := make(map[string]string)
+		blank := true
+		for j, value := range s.Rows[i] {
+			if j >= len(keys) || keys[j] == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if value != "" {
+				blank = false
+			}
+			record[keys[j]] = value
+		}
+		if blank {
+			continue
+		}
+		records = append(records, record)
+		lines = append(lines, i+1)
+	}
+
+	return records, lines
+}
+
+// WriteXLSX encodes the workbook as an .xlsx file
+func WriteXLSX(w io.Writer, wb *Workbook) error {
+	if len(wb.Sheets) == 0 {
+		return fmt.Errorf("workbook has no sheets")
+	}
+
+	zw := zip.NewWriter(w)
+
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", contentTypesXML(len(wb.Sheets))},
+		{"_rels/.rels", []byte(rootRelsXML)},
+		{"xl/workbook.xml", workbookXML(wb)},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML(len(wb.Sheets))},
+		{"xl/styles.xml", []byte(stylesXML)},
+	}
+	for i, sheet := range wb.Sheets {
+		parts = append(parts, struct {
+			name    string
+			content []byte
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), worksheetXML(sheet)})
+	}
+
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", part.name, err)
+		}
+		if _, err := f.Write(part.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", part.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize workbook: %w", err)
+	}
+
+	return nil
+}
+
+// XLSXStreamWriter writes a single-sheet .xlsx file row by row, so sheets of
+// any size are written without holding them in memory
+type XLSXStreamWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// NewXLSXStreamWriter starts a workbook with one sheet and writes its header row
+func NewXLSXStreamWriter(w io.Writer, name string, header []string) (*XLSXStreamWriter, error) {
+	zw := zip.NewWriter(w)
+	wb := &Workbook{Sheets: []*Sheet{{Name: name}}}
+
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", contentTypesXML(1)},
+		{"_rels/.rels", []byte(rootRelsXML)},
+		{"xl/workbook.xml", workbookXML(wb)},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML(1)},
+		{"xl/styles.xml", []byte(stylesXML)},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", part.name, err)
+		}
+		if _, err := f.Write(part.content); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", part.name, err)
+		}
+	}
+
+	// The worksheet is the last part, so it can be left open for the rows
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worksheet: %w", err)
+	}
+	sw := &XLSXStreamWriter{zw: zw, sheet: bufio.NewWriter(f)}
+	sw.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	sw.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sw.sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	sw.sheet.WriteString(`<sheetData>`)
+	sw.rows = 1
+	if err := writeRowXML(sw.sheet, sw.rows, header, 1); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	return sw, nil
+}
+
+// WriteRow appends a data row to the sheet
+func (sw *XLSXStreamWriter) WriteRow(values ...string) error {
+	sw.rows++
+	return writeRowXML(sw.sheet, sw.rows, values, 0)
+}
+
+// Close ends the sheet and finalizes the workbook
+func (sw *XLSXStreamWriter) Close() error {
+	sw.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := sw.sheet.Flush(); err != nil {
+		return fmt.Errorf("failed to write worksheet: %w", err)
+	}
+	if err := sw.zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize workbook: %w", err)
+	}
+	return nil
+}
+
+// ReadXLSX decodes an .xlsx file into a workbook
+func ReadXLSX(r io.ReaderAt, size int64) (*Workbook, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	var wbDoc xlsxWorkbook
+	if err := decodePart(files, "xl/workbook.xml", &wbDoc); err != nil {
+		return nil, err
+	}
+
+	var relsDoc xlsxRelationships
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &relsDoc); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(relsDoc.Relationships))
+	for _, rel := range relsDoc.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var sharedStrings []string
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst xlsxSST
+		if err := decodePart(files, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			sharedStrings = append(sharedStrings, item.text())
+		}
+	}
+
+	wb := NewWorkbook()
+	for _, s := range wbDoc.Sheets {
+		target, ok := targets[s.RID]
+		if !ok {
+			return nil, fmt.Errorf("sheet %q has no target part", s.Name)
+		}
+
+		var ws xlsxWorksheet
+		if err := decodePart(files, target, &ws); err != nil {
+			return nil, err
+		}
+
+		sheet, err := ws.toSheet(s.Name, sharedStrings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet %q: %w", s.Name, err)
+		}
+		wb.Sheets = append(wb.Sheets, sheet)
+	}
+
+	return wb, nil
+}
+
+// ReadXLSXBytes decodes an in-memory .xlsx file
+func ReadXLSXBytes(data []byte) (*Workbook, error) {
+	return ReadXLSX(bytes.NewReader(data), int64(len(data)))
+}
+
+// decodePart unmarshals an XML part of the workbook package
+func decodePart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("workbook is missing part %s", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// XML part structures used when reading
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSST struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt *xlsxRichText) text() string {
+	if len(rt.R) == 0 {
+		return rt.T
+	}
+	var sb strings.Builder
+	sb.WriteString(rt.T)
+	for _, run := range rt.R {
+		sb.WriteString(run.T)
+	}
+	return sb.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string        `xml:"r,attr"`
+			T  string        `xml:"t,attr"`
+			V  string        `xml:"v"`
+			IS *xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func (ws *xlsxWorksheet) toSheet(name string, sharedStrings []string) (*Sheet, error) {
+	sheet := &Sheet{Name: name}
+
+	for i, row := range ws.Rows {
+		rowIndex := i
+		if row.R > 0 {
+			rowIndex = row.R - 1
+		}
+		for len(sheet.Rows) <= rowIndex {
+			sheet.Rows = append(sheet.Rows, nil)
+		}
+
+		var values []string
+		for j, cell := range row.Cells {
+			col := j
+			if cell.R != "" {
+				parsed, err := columnIndex(cell.R)
+				if err != nil {
+					return nil, err
+				}
+				col = parsed
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch cell.T {
+			case "s":
+				idx, err := strconv.Atoi(strings.TrimSpace(cell.V))
+				if err != nil || idx < 0 || idx >= len(sharedStrings) {
+					return nil, fmt.Errorf("invalid shared string reference in cell %s", cell.R)
+				}
+				values[col] = sharedStrings[idx]
+			case "inlineStr":
+				if cell.IS != nil {
+					values[col] = cell.IS.text()
+				}
+			case "b":
+				if cell.V == "1" {
+					values[col] = "true"
+				} else {
+					values[col] = "false"
+				}
+			default:
+				values[col] = cell.V
+			}
+		}
+		sheet.Rows[rowIndex] = values
+	}
+
+	return sheet, nil
+}
+
+// columnIndex converts a cell reference such as "AB12" to a zero-based column index
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch >= 'a' && ch <= 'z' {
+			ch -= 'a' - 'A'
+		}
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+// columnName converts a zero-based column index to its letter form, e.g. 27 -> "AB"
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// XML part generation used when writing
+
+const rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+// stylesXML defines two cell formats: 0 is the default, 1 is bold (used for header rows)
+const stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+func contentTypesXML(sheetCount int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	buf.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	buf.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	buf.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	buf.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	buf.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&buf, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	buf.WriteString(`</Types>`)
+	return buf.Bytes()
+}
+
+func workbookXML(wb *Workbook) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	buf.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range wb.Sheets {
+		buf.WriteString(`<sheet name="`)
+		xml.EscapeText(&buf, []byte(sheetName(sheet.Name, i)))
+		fmt.Fprintf(&buf, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	buf.WriteString(`</sheets></workbook>`)
+	return buf.Bytes()
+}
+
+func workbookRelsXML(sheetCount int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	buf.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&buf, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&buf, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheetCount+1)
+	buf.WriteString(`</Relationships>`)
+	return buf.Bytes()
+}
+
+func worksheetXML(sheet *Sheet) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(sheet.Rows) > 0 {
+		// Freeze the header row so it stays visible while scrolling
+		buf.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	buf.WriteString(`<sheetData>`)
+	for i, row := range sheet.Rows {
+		style := 0
+		if i == 0 {
+			style = 1
+		}
+		writeRowXML(&buf, i+1, row, style)
+	}
+	buf.WriteString(`</sheetData></worksheet>`)
+	return buf.Bytes()
+}
+
+// writeRowXML writes a worksheet row of inline strings in the given cell format
+func writeRowXML(w io.Writer, number int, row []string, style int) error {
+	if _, err := fmt.Fprintf(w, `<row r="%d">`, number); err != nil {
+		return err
+	}
+	for j, value := range row {
+		if value == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, `<c r="%s%d" t="inlineStr" s="%d"><is><t xml:space="preserve">`, columnName(j), number, style); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(w, []byte(value)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, `</t></is></c>`); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, `</row>`)
+	return err
+}
+
+// sheetName returns a name Excel accepts: at most 31 characters and none of []:*?/\
+func sheetName(name string, index int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if strings.TrimSpace(name) == "" {
+		name = fmt.Sprintf("Sheet%d", index+1)
+	}
+	return name
+}
@@ -0,0 +1,142 @@
+package importexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadXLSX_RoundTrip(t *testing.T) {
+	wb := NewWorkbook()
+	sheet := wb.AddSheet("Data", []string{"name", "notes"})
+	sheet.AddRow("café", "line1\nline2 <&>")
+	sheet.AddRow("", "sparse")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteXLSX(&buf, wb))
+
+	read, err := ReadXLSXBytes(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, read.Sheets, 1)
+
+	got := read.Sheet("data")
+	require.NotNil(t, got)
+	assert.Equal(t, []string{"name", "notes"}, got.Rows[0])
+	assert.Equal(t, []string{"café", "line1\nline2 <&>"}, got.Rows[1])
+	assert.Equal(t, []string{"", "sparse"}, got.Rows[2])
+}
+
+func TestReadXLSX_SharedStrings(t *testing.T) {
+	// Worksheets written by Excel reference a shared string table instead of inline strings
+	ws := xlsxWorksheet{}
+	require.NoError(t, xml.Unmarshal([]byte(`<worksheet><sheetData><row r="2"><c r="B2" t="s"><v>1</v></c><c r="C2"><v>42.5</v></c><c r="D2" t="b"><v>1</v></c></row></sheetData></worksheet>`), &ws))
+
+	sheet, err := ws.toSheet("Sheet1", []string{"zero", "one"})
+	require.NoError(t, err)
+	require.Len(t, sheet.Rows, 2)
+	assert.Equal(t, []string{"", "one", "42.5", "true"}, sheet.Rows[1])
+}
+
+func TestColumnNames(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, name, columnName(index))
+		parsed, err := columnIndex(name + "12")
+		require.NoError(t, err)
+		assert.Equal(t, index, parsed)
+	}
+}
+
+func TestCIWorkbook_RoundTrip(t *testing.T) {
+	install := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	server := models.CI{
+		ID:          uuid.New(),
+		Name:        "web-01",
+		Type:        "server",
+		Status:      models.CIStatusActive,
+		Criticality: models.CICriticalityHigh,
+		Tags:        []string{"prod", "web"},
+		InstallDate: &install,
+		IsActive:    true,
+		Attributes:  json.RawMessage(`{"cpu_cores": 8, "hostname": "web-01.local"}`),
+	}
+	app := models.CI{ID: uuid.New(), Name: "shop", Type: "application", IsActive: true}
+	rel := &models.CIRelationship{ID: uuid.New(), SourceCIID: app.ID, TargetCIID: server.ID, Type: "runs_on", IsActive: true, State: models.RelationshipStateProposed}
+	schemas := []*models.CITypeSchema{{
+		Name: "server",
+		Attributes: []models.CITypeAttribute{
+			{Name: "cpu_cores", Type: models.AttributeTypeNumber, Required: true, Validation: map[string]interface{}{"min": float64(1)}},
+			{Name: "hostname", Type: models.AttributeTypeString},
+		},
+	}}
+
+	wb, err := BuildCIWorkbook([]models.CI{server, app}, []*models.CIRelationship{rel}, schemas, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteXLSX(&buf, wb))
+	read, err := ReadXLSXBytes(buf.Bytes())
+	require.NoError(t, err)
+
+	parsed := ParseCIWorkbook(read)
+	assert.Empty(t, parsed.Errors)
+
+	require.Len(t, parsed.CIs, 2)
+	assert.Equal(t, server.ID, parsed.CIs[0].ID)
+	assert.Equal(t, []string{"prod", "web"}, parsed.CIs[0].Tags)
+	require.NotNil(t, parsed.CIs[0].InstallDate)
+	assert.True(t, install.Equal(*parsed.CIs[0].InstallDate))
+	assert.Equal(t, map[string]string{"cpu_cores": "8", "hostname": "web-01.local"}, parsed.CIs[0].Attributes)
+
+	require.Len(t, parsed.Relationships, 1)
+	assert.Equal(t, app.ID, parsed.Relationships[0].SourceCIID)
+	assert.Equal(t, "web-01", parsed.Relationships[0].TargetCIName)
+	assert.Equal(t, models.RelationshipStateProposed, parsed.Relationships[0].State)
+
+	require.Len(t, parsed.Schemas, 1)
+	assert.Equal(t, SchemaKindCI, parsed.Schemas[0].Kind)
+	assert.Equal(t, schemas[0].Attributes, parsed.Schemas[0].Attributes)
+
+	attrs, err := CoerceAttributes(parsed.CIs[0].Attributes, schemas[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"cpu_cores": 8, "hostname": "web-01.local"}`, string(attrs))
+}
+
+func TestParseCIWorkbook_RowErrors(t *testing.T) {
+	wb := NewWorkbook()
+	cis := wb.AddSheet(SheetCIs, ciColumns)
+	cis.AddRow("", "missing-type")
+	cis.AddRow("not-a-uuid", "db-01", "database")
+	cis.AddRow("", "db-02", "database", "", "", "", "", "", "", "45000")
+
+	parsed := ParseCIWorkbook(wb)
+	require.Len(t, parsed.Errors, 2)
+	assert.Equal(t, 2, parsed.Errors[0].Row)
+	assert.Equal(t, 3, parsed.Errors[1].Row)
+
+	require.Len(t, parsed.CIs, 1)
+	require.NotNil(t, parsed.CIs[0].InstallDate)
+	assert.Equal(t, "2023-03-15", parsed.CIs[0].InstallDate.Format("2006-01-02"))
+}
+
+func TestParseCIWorkbook_RelationshipStates(t *testing.T) {
+	wb := NewWorkbook()
+	rels := wb.AddSheet(SheetRelationships, relationshipColumns)
+	rels.AddRow("", "", "shop", "", "web-01", "runs_on", "", "", "", "Deprecated")
+	rels.AddRow("", "", "shop", "", "db-01", "depends_on", "", "", "", "retired")
+	rels.AddRow("", "", "shop", "", "lb-01", "routes_to")
+
+	parsed := ParseCIWorkbook(wb)
+	require.Len(t, parsed.Errors, 1)
+	assert.Equal(t, 3, parsed.Errors[0].Row)
+
+	require.Len(t, parsed.Relationships, 2)
+	assert.Equal(t, models.RelationshipStateDeprecated, parsed.Relationships[0].State)
+	assert.Empty(t, parsed.Relationships[1].State, "an empty state is left to the importer's default")
+}
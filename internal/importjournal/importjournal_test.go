@@ -0,0 +1,262 @@
+package importjournal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps jobs and entries in maps
+type memoryStore struct {
+	jobs    map[uuid.UUID]*Job
+	entries map[uuid.UUID][]Entry
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: map[uuid.UUID]*Job{}, entries: map[uuid.UUID][]Entry{}}
+}
+
+func (m *memoryStore) CreateJob(ctx context.Context, job *Job, entries []Entry) error {
+	saved := *job
+	m.jobs[job.ID] = &saved
+	m.entries[job.ID] = append([]Entry(nil), entries...)
+	return nil
+}
+
+func (m *memoryStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (m *memoryStore) ListEntries(ctx context.Context, id uuid.UUID) ([]Entry, error) {
+	return m.entries[id], nil
+}
+
+func (m *memoryStore) BeginRollback(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.RolledBackAt != nil {
+		return ErrAlreadyRolledBack
+	}
+	job.RolledBackAt, job.RolledBackBy = &at, &by
+	return nil
+}
+
+func (m *memoryStore) FinishRollback(ctx context.Context, id uuid.UUID, result *RollbackResult) error {
+	m.jobs[id].Rollback = result
+	return nil
+}
+
+func (m *memoryStore) DeleteJobsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	for id, job := range m.jobs {
+		if job.CompletedAt.Before(cutoff) {
+			delete(m.jobs, id)
+			delete(m.entries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+var errNotFound = errors.New("not found")
+
+// memoryEntities keeps CIs and relationships in maps; schemas are not needed
+// by these tests
+type memoryEntities struct {
+	Entities
+	cis  map[uuid.UUID]*models.CI
+	rels map[uuid.UUID]*models.CIRelationship
+	now  time.Time
+}
+
+func newMemoryEntities() *memoryEntities {
+	return &memoryEntities{cis: map[uuid.UUID]*models.CI{}, rels: map[uuid.UUID]*models.CIRelationship{}, now: now}
+}
+
+func (m *memoryEntities) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	ci, ok := m.cis[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	copied := *ci
+	return &copied, nil
+}
+
+func (m *memoryEntities) UpdateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
+	if _, ok := m.cis[ci.ID]; !ok {
+		return nil, errNotFound
+	}
+	m.now = m.now.Add(time.Second)
+	ci.UpdatedAt = m.now
+	copied := *ci
+	m.cis[ci.ID] = &copied
+	return ci, nil
+}
+
+func (m *memoryEntities) DeleteCI(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.cis[id]; !ok {
+		return errNotFound
+	}
+	delete(m.cis, id)
+	return nil
+}
+
+func (m *memoryEntities) GetRelationship(ctx context.Context, id uuid.UUID) (*models.CIRelationship, error) {
+	rel, ok := m.rels[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	copied := *rel
+	return &copied, nil
+}
+
+func (m *memoryEntities) DeleteRelationship(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.rels[id]; !ok {
+		return errNotFound
+	}
+	delete(m.rels, id)
+	return nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store, entities Entities) *Service {
+	service := NewService(store, entities, 24*time.Hour)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+// importFixture journals an import that updated one CI and created another
+// with a relationship between them
+func importFixture(t *testing.T, service *Service, entities *memoryEntities) (jobID, updated, created, rel uuid.UUID) {
+	updated, created, rel = uuid.New(), uuid.New(), uuid.New()
+	before := &models.CI{ID: updated, Name: "web-01", Type: "server", UpdatedAt: now.Add(-time.Hour)}
+	entities.cis[updated] = &models.CI{ID: updated, Name: "web-01-imported", Type: "server", UpdatedAt: now}
+	entities.cis[created] = &models.CI{ID: created, Name: "db-01", Type: "database", UpdatedAt: now}
+	entities.rels[rel] = &models.CIRelationship{ID: rel, SourceCIID: updated, TargetCIID: created, UpdatedAt: now}
+
+	jobID = uuid.New()
+	journal := service.Begin(jobID, "xlsx", uuid.New())
+	journal.Updated(EntityCI, updated, before, now)
+	journal.Created(EntityCI, created, now)
+	journal.Created(EntityRelationship, rel, now)
+	require.NoError(t, service.Commit(context.Background(), journal))
+	return jobID, updated, created, rel
+}
+
+func TestCommitRecordsEntriesInOrder(t *testing.T) {
+	store := newMemoryStore()
+	entities := newMemoryEntities()
+	service := newTestService(store, entities)
+
+	jobID, updated, _, _ := importFixture(t, service, entities)
+	job, err := service.GetJob(context.Background(), jobID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Created)
+	assert.Equal(t, 1, job.Updated)
+	assert.Equal(t, now.Add(24*time.Hour), job.RollbackDeadline)
+
+	entries := store.entries[jobID]
+	require.Len(t, entries, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{entries[0].Seq, entries[1].Seq, entries[2].Seq})
+	assert.Equal(t, updated, entries[0].EntityID)
+
+	var before models.CI
+	require.NoError(t, json.Unmarshal(entries[0].Before, &before))
+	assert.Equal(t, "web-01", before.Name)
+}
+
+func TestNilJournalRecordsNothing(t *testing.T) {
+	var journal *Journal
+	journal.Created(EntityCI, uuid.New(), now)
+	journal.Updated(EntityCI, uuid.New(), &models.CI{}, now)
+}
+
+func TestRollbackRevertsChanges(t *testing.T) {
+	store := newMemoryStore()
+	entities := newMemoryEntities()
+	service := newTestService(store, entities)
+	jobID, updated, created, rel := importFixture(t, service, entities)
+
+	by := uuid.New()
+	job, err := service.Rollback(context.Background(), jobID, false, by)
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Rollback.Deleted)
+	assert.Equal(t, 1, job.Rollback.Restored)
+	assert.Empty(t, job.Rollback.Skipped)
+	assert.Equal(t, by, *job.RolledBackBy)
+
+	assert.NotContains(t, entities.cis, created)
+	assert.NotContains(t, entities.rels, rel)
+	assert.Equal(t, "web-01", entities.cis[updated].Name)
+	assert.Equal(t, by, entities.cis[updated].UpdatedBy)
+
+	// A job is only rolled back once
+	_, err = service.Rollback(context.Background(), jobID, false, by)
+	assert.ErrorIs(t, err, ErrAlreadyRolledBack)
+	assert.NotNil(t, store.jobs[jobID].Rollback)
+}
+
+func TestRollbackSkipsEntitiesChangedSinceImport(t *testing.T) {
+	store := newMemoryStore()
+	entities := newMemoryEntities()
+	service := newTestService(store, entities)
+	jobID, updated, created, _ := importFixture(t, service, entities)
+
+	entities.cis[updated].Name = "web-01-edited"
+	entities.cis[updated].UpdatedAt = now.Add(time.Minute)
+	delete(entities.cis, created)
+
+	job, err := service.Rollback(context.Background(), jobID, false, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Rollback.Deleted)
+	assert.Equal(t, 0, job.Rollback.Restored)
+	require.Len(t, job.Rollback.Skipped, 2)
+	assert.Equal(t, created, job.Rollback.Skipped[0].EntityID)
+	assert.Contains(t, job.Rollback.Skipped[0].Reason, "no longer exists")
+	assert.Equal(t, Skip{EntityType: EntityCI, EntityID: updated, Reason: "changed since the import"}, job.Rollback.Skipped[1])
+	assert.Equal(t, "web-01-edited", entities.cis[updated].Name)
+}
+
+func TestRollbackForceOverwritesLaterChanges(t *testing.T) {
+	store := newMemoryStore()
+	entities := newMemoryEntities()
+	service := newTestService(store, entities)
+	jobID, updated, _, _ := importFixture(t, service, entities)
+
+	entities.cis[updated].UpdatedAt = now.Add(time.Minute)
+
+	job, err := service.Rollback(context.Background(), jobID, true, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Rollback.Restored)
+	assert.Equal(t, "web-01", entities.cis[updated].Name)
+}
+
+func TestRollbackWindow(t *testing.T) {
+	store := newMemoryStore()
+	entities := newMemoryEntities()
+	service := newTestService(store, entities)
+	jobID, _, _, _ := importFixture(t, service, entities)
+
+	service.now = func() time.Time { return now.Add(25 * time.Hour) }
+	_, err := service.Rollback(context.Background(), jobID, false, uuid.New())
+	assert.ErrorIs(t, err, ErrWindowExpired)
+	assert.Nil(t, store.jobs[jobID].RolledBackAt)
+
+	_, err = service.Rollback(context.Background(), uuid.New(), false, uuid.New())
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
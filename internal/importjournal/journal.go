@@ -0,0 +1,114 @@
+// Package importjournal records the entities each import job creates or
+// modifies, keeping the version every modified entity had before the import,
+// so a completed import can be rolled back within a configurable window:
+// created entities are deleted and modified ones restored, newest first.
+package importjournal
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entity types
+const (
+	EntityCI                     = "ci"
+	EntityRelationship           = "relationship"
+	EntityCITypeSchema           = "ci_type_schema"
+	EntityRelationshipTypeSchema = "relationship_type_schema"
+)
+
+// Actions
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+)
+
+// DefaultWindow is how long after completing an import can be rolled back
+const DefaultWindow = 7 * 24 * time.Hour
+
+var (
+	ErrJobNotFound       = errors.New("import job not found")
+	ErrAlreadyRolledBack = errors.New("import job already rolled back")
+	ErrWindowExpired     = errors.New("import job rollback window expired")
+)
+
+// Job is a completed import with what rolling it back did, if it was
+type Job struct {
+	ID          uuid.UUID `json:"id"`
+	Source      string    `json:"source"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	CompletedAt time.Time `json:"completed_at"`
+	Created     int       `json:"created"`
+	Updated     int       `json:"updated"`
+	// RollbackDeadline is when the import stops being rollback-able
+	RollbackDeadline time.Time       `json:"rollback_deadline"`
+	RolledBackAt     *time.Time      `json:"rolled_back_at,omitempty"`
+	RolledBackBy     *uuid.UUID      `json:"rolled_back_by,omitempty"`
+	Rollback         *RollbackResult `json:"rollback,omitempty"`
+}
+
+// Entry is one entity an import created or modified
+type Entry struct {
+	Seq        int       `json:"seq"`
+	EntityType string    `json:"entity_type"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	Action     string    `json:"action"`
+	// Before is the version an updated entity had before the import
+	Before json.RawMessage `json:"before,omitempty"`
+	// ImportedAt is the updated_at the import left the entity with; an
+	// entity updated after it was changed again since the import
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// RollbackResult counts what a rollback reverted and lists what it left alone
+type RollbackResult struct {
+	Deleted  int    `json:"deleted"`
+	Restored int    `json:"restored"`
+	Skipped  []Skip `json:"skipped"`
+}
+
+// Skip is an entity a rollback did not revert, and why
+type Skip struct {
+	EntityType string    `json:"entity_type"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	Reason     string    `json:"reason"`
+}
+
+// Journal collects the changes of an import as it runs. A nil journal
+// records nothing, so importers need not check whether journaling is on.
+type Journal struct {
+	job     Job
+	entries []Entry
+}
+
+// Created records an entity the import created
+func (j *Journal) Created(entityType string, id uuid.UUID, importedAt time.Time) {
+	if j == nil {
+		return
+	}
+	j.job.Created++
+	j.add(Entry{EntityType: entityType, EntityID: id, Action: ActionCreated, ImportedAt: importedAt})
+}
+
+// Updated records an entity the import modified, with its version beforehand.
+// A version that cannot be encoded is left out and a rollback skips the entity.
+func (j *Journal) Updated(entityType string, id uuid.UUID, before interface{}, importedAt time.Time) {
+	if j == nil {
+		return
+	}
+	data, err := json.Marshal(before)
+	if err != nil {
+		data = nil
+	}
+	j.job.Updated++
+	j.add(Entry{EntityType: entityType, EntityID: id, Action: ActionUpdated, Before: data, ImportedAt: importedAt})
+}
+
+// add appends an entry in import order
+func (j *Journal) add(entry Entry) {
+	entry.Seq = len(j.entries) + 1
+	j.entries = append(j.entries, entry)
+}
@@ -0,0 +1,266 @@
+package importjournal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Entities reads, restores and deletes the entities an import touched, as
+// CIRepository does
+type Entities interface {
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+	UpdateCI(ctx context.Context, ci *models.CI) (*models.CI, error)
+	DeleteCI(ctx context.Context, id uuid.UUID) error
+	GetRelationship(ctx context.Context, id uuid.UUID) (*models.CIRelationship, error)
+	UpdateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error)
+	TransitionRelationshipState(ctx context.Context, id uuid.UUID, state string, changedBy uuid.UUID) (*models.CIRelationship, error)
+	DeleteRelationship(ctx context.Context, id uuid.UUID) error
+	GetCITypeSchema(ctx context.Context, id uuid.UUID) (*models.CITypeSchema, error)
+	UpdateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error)
+	DeleteCITypeSchema(ctx context.Context, id uuid.UUID) error
+	GetRelationshipTypeSchema(ctx context.Context, id uuid.UUID) (*models.RelationshipTypeSchema, error)
+	UpdateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error)
+	DeleteRelationshipTypeSchema(ctx context.Context, id uuid.UUID) error
+}
+
+// Service journals imports and rolls them back
+type Service struct {
+	store    Store
+	entities Entities
+	window   time.Duration
+	now      func() time.Time
+}
+
+// NewService creates a new import journal service
+func NewService(store Store, entities Entities, window time.Duration) *Service {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Service{
+		store:    store,
+		entities: entities,
+		window:   window,
+		now:      time.Now,
+	}
+}
+
+// Begin starts journaling an import job
+func (s *Service) Begin(jobID uuid.UUID, source string, createdBy uuid.UUID) *Journal {
+	return &Journal{job: Job{ID: jobID, Source: source, CreatedBy: createdBy}}
+}
+
+// Commit saves the journal of a completed import
+func (s *Service) Commit(ctx context.Context, journal *Journal) error {
+	journal.job.CompletedAt = s.now()
+	return s.store.CreateJob(ctx, &journal.job, journal.entries)
+}
+
+// GetJob retrieves a journaled import job
+func (s *Service) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := s.store.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job.RollbackDeadline = job.CompletedAt.Add(s.window)
+	return job, nil
+}
+
+// Rollback reverts an import job: the entities it created are deleted and
+// those it modified restored to their previous version, newest first.
+// Entities changed again since the import are skipped unless force is set,
+// so a rollback never silently discards later work.
+func (s *Service) Rollback(ctx context.Context, id uuid.UUID, force bool, by uuid.UUID) (*Job, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.RolledBackAt != nil {
+		return nil, ErrAlreadyRolledBack
+	}
+	now := s.now()
+	if now.After(job.RollbackDeadline) {
+		return nil, fmt.Errorf("%w: the import completed at %s", ErrWindowExpired, job.CompletedAt.Format(time.RFC3339))
+	}
+
+	entries, err := s.store.ListEntries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.BeginRollback(ctx, id, by, now); err != nil {
+		return nil, err
+	}
+
+	result := &RollbackResult{Skipped: []Skip{}}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if reason := s.revert(ctx, entry, force, by); reason != "" {
+			result.Skipped = append(result.Skipped, Skip{EntityType: entry.EntityType, EntityID: entry.EntityID, Reason: reason})
+			continue
+		}
+		if entry.Action == ActionCreated {
+			result.Deleted++
+		} else {
+			result.Restored++
+		}
+	}
+
+	if err := s.store.FinishRollback(ctx, id, result); err != nil {
+		return nil, err
+	}
+	log.Printf("Import %s rolled back by %s: %d deleted, %d restored, %d skipped",
+		id, by, result.Deleted, result.Restored, len(result.Skipped))
+
+	job.RolledBackAt = &now
+	job.RolledBackBy = &by
+	job.Rollback = result
+	return job, nil
+}
+
+// revert undoes one entry and returns why it was skipped, or "" if it was reverted
+func (s *Service) revert(ctx context.Context, entry Entry, force bool, by uuid.UUID) string {
+	if entry.Action == ActionUpdated && len(entry.Before) == 0 {
+		return "no previous version was recorded"
+	}
+
+	updatedAt, err := s.updatedAt(ctx, entry)
+	if err != nil {
+		return fmt.Sprintf("no longer exists: %v", err)
+	}
+	if updatedAt.After(entry.ImportedAt) && !force {
+		return "changed since the import"
+	}
+
+	if entry.Action == ActionCreated {
+		err = s.delete(ctx, entry)
+	} else {
+		err = s.restore(ctx, entry, by)
+	}
+	if err != nil {
+		return fmt.Sprintf("failed to revert: %v", err)
+	}
+	return ""
+}
+
+// updatedAt returns when the entity of an entry was last updated
+func (s *Service) updatedAt(ctx context.Context, entry Entry) (time.Time, error) {
+	switch entry.EntityType {
+	case EntityCI:
+		ci, err := s.entities.GetCI(ctx, entry.EntityID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return ci.UpdatedAt, nil
+	case EntityRelationship:
+		rel, err := s.entities.GetRelationship(ctx, entry.EntityID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return rel.UpdatedAt, nil
+	case EntityCITypeSchema:
+		schema, err := s.entities.GetCITypeSchema(ctx, entry.EntityID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return schema.UpdatedAt, nil
+	case EntityRelationshipTypeSchema:
+		schema, err := s.entities.GetRelationshipTypeSchema(ctx, entry.EntityID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return schema.UpdatedAt, nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown entity type %s", entry.EntityType)
+	}
+}
+
+// delete removes an entity the import created
+func (s *Service) delete(ctx context.Context, entry Entry) error {
+	switch entry.EntityType {
+	case EntityCI:
+		return s.entities.DeleteCI(ctx, entry.EntityID)
+	case EntityRelationship:
+		return s.entities.DeleteRelationship(ctx, entry.EntityID)
+	case EntityCITypeSchema:
+		return s.entities.DeleteCITypeSchema(ctx, entry.EntityID)
+	case EntityRelationshipTypeSchema:
+		return s.entities.DeleteRelationshipTypeSchema(ctx, entry.EntityID)
+	default:
+		return fmt.Errorf("unknown entity type %s", entry.EntityType)
+	}
+}
+
+// restore puts back the version an entity had before the import
+func (s *Service) restore(ctx context.Context, entry Entry, by uuid.UUID) error {
+	switch entry.EntityType {
+	case EntityCI:
+		var ci models.CI
+		if err := json.Unmarshal(entry.Before, &ci); err != nil {
+			return fmt.Errorf("failed to unmarshal previous version: %w", err)
+		}
+		ci.UpdatedBy = by
+		_, err := s.entities.UpdateCI(ctx, &ci)
+		return err
+	case EntityRelationship:
+		var rel models.CIRelationship
+		if err := json.Unmarshal(entry.Before, &rel); err != nil {
+			return fmt.Errorf("failed to unmarshal previous version: %w", err)
+		}
+		rel.UpdatedBy = by
+		restored, err := s.entities.UpdateRelationship(ctx, &rel)
+		if err != nil {
+			return err
+		}
+		if rel.State != "" && restored.State != rel.State {
+			if _, err := s.entities.TransitionRelationshipState(ctx, rel.ID, rel.State, by); err != nil {
+				return fmt.Errorf("restored but could not return to state %s: %w", rel.State, err)
+			}
+		}
+		return nil
+	case EntityCITypeSchema:
+		var schema models.CITypeSchema
+		if err := json.Unmarshal(entry.Before, &schema); err != nil {
+			return fmt.Errorf("failed to unmarshal previous version: %w", err)
+		}
+		schema.UpdatedBy = by
+		_, err := s.entities.UpdateCITypeSchema(ctx, &schema)
+		return err
+	case EntityRelationshipTypeSchema:
+		var schema models.RelationshipTypeSchema
+		if err := json.Unmarshal(entry.Before, &schema); err != nil {
+			return fmt.Errorf("failed to unmarshal previous version: %w", err)
+		}
+		schema.UpdatedBy = by
+		_, err := s.entities.UpdateRelationshipTypeSchema(ctx, &schema)
+		return err
+	default:
+		return fmt.Errorf("unknown entity type %s", entry.EntityType)
+	}
+}
+
+// Run deletes the jobs past their rollback window at the given interval
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.store.DeleteJobsBefore(ctx, s.now().Add(-s.window))
+			if err != nil {
+				log.Printf("Failed to delete expired import jobs: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Deleted %d import jobs past their rollback window", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package importjournal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store persists import jobs and their entries
+type Store interface {
+	// CreateJob saves a completed job with its entries
+	CreateJob(ctx context.Context, job *Job, entries []Entry) error
+	GetJob(ctx context.Context, id uuid.UUID) (*Job, error)
+	// ListEntries returns the entries of a job in import order
+	ListEntries(ctx context.Context, id uuid.UUID) ([]Entry, error)
+	// BeginRollback marks a job rolled back, failing with ErrAlreadyRolledBack
+	// if it already was, so a job is only ever rolled back once
+	BeginRollback(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error
+	// FinishRollback saves what rolling a job back did
+	FinishRollback(ctx context.Context, id uuid.UUID, result *RollbackResult) error
+	// DeleteJobsBefore deletes the jobs completed before cutoff with their entries
+	DeleteJobsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// PostgresStore keeps jobs in the import_journal_jobs and import_journal_entries tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed import journal store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CreateJob inserts a job and its entries in one transaction
+func (s *PostgresStore) CreateJob(ctx context.Context, job *Job, entries []Entry) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO import_journal_jobs (id, source, created_by, completed_at, created_count, updated_count)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		job.ID, job.Source, job.CreatedBy, job.CompletedAt, job.Created, job.Updated)
+	if err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	for _, entry := range entries {
+		var before interface{}
+		if len(entry.Before) > 0 {
+			before = []byte(entry.Before)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO import_journal_entries (job_id, seq, entity_type, entity_id, action, before, imported_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			job.ID, entry.Seq, entry.EntityType, entry.EntityID, entry.Action, before, entry.ImportedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record import entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import job: %w", err)
+	}
+	return nil
+}
+
+// GetJob retrieves a job
+func (s *PostgresStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	var job Job
+	var rolledBackAt sql.NullTime
+	var rolledBackBy uuid.NullUUID
+	var rollback []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, source, created_by, completed_at, created_count, updated_count, rolled_back_at, rolled_back_by, rollback
+		FROM import_journal_jobs
+		WHERE id = $1`, id).Scan(&job.ID, &job.Source, &job.CreatedBy, &job.CompletedAt, &job.Created, &job.Updated,
+		&rolledBackAt, &rolledBackBy, &rollback)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	if rolledBackAt.Valid {
+		job.RolledBackAt = &rolledBackAt.Time
+	}
+	if rolledBackBy.Valid {
+		job.RolledBackBy = &rolledBackBy.UUID
+	}
+	if rollback != nil {
+		job.Rollback = &RollbackResult{}
+		if err := json.Unmarshal(rollback, job.Rollback); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal import rollback: %w", err)
+		}
+	}
+	return &job, nil
+}
+
+// ListEntries retrieves the entries of a job in import order
+func (s *PostgresStore) ListEntries(ctx context.Context, id uuid.UUID) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT seq, entity_type, entity_id, action, before, imported_at
+		FROM import_journal_entries
+		WHERE job_id = $1
+		ORDER BY seq`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var before []byte
+		if err := rows.Scan(&entry.Seq, &entry.EntityType, &entry.EntityID, &entry.Action, &before, &entry.ImportedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan import entry: %w", err)
+		}
+		entry.Before = before
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list import entries: %w", err)
+	}
+	return entries, nil
+}
+
+// BeginRollback marks a job rolled back unless it already was
+func (s *PostgresStore) BeginRollback(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE import_journal_jobs
+		SET rolled_back_at = $2, rolled_back_by = $3
+		WHERE id = $1 AND rolled_back_at IS NULL`, id, at, by)
+	if err != nil {
+		return fmt.Errorf("failed to begin import rollback: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		if _, err := s.GetJob(ctx, id); err != nil {
+			return err
+		}
+		return ErrAlreadyRolledBack
+	}
+	return nil
+}
+
+// FinishRollback saves the rollback result
+func (s *PostgresStore) FinishRollback(ctx context.Context, id uuid.UUID, result *RollbackResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal import rollback: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE import_journal_jobs SET rollback = $2 WHERE id = $1`, id, data); err != nil {
+		return fmt.Errorf("failed to save import rollback: %w", err)
+	}
+	return nil
+}
+
+// DeleteJobsBefore deletes the jobs completed before cutoff; entries cascade
+func (s *PostgresStore) DeleteJobsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM import_journal_jobs WHERE completed_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired import jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
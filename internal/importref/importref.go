@@ -0,0 +1,197 @@
+// Package importref relates imported CIs to the CIs their reference
+// attributes name, so imports produce connected graphs instead of islands. A
+// CI type schema flags an attribute as a reference, e.g. a VM's
+// hypervisor_name naming a hypervisor. While an import writes CIs their
+// references are collected; once every CI is written, each reference is
+// resolved by name to an existing CI of the target type and the relationship
+// between the two is created unless it already exists. Resolving only then
+// lets CIs reference rows further down the same upload. References that do not
+// resolve to exactly one CI are reported separately and do not fail the CI.
+package importref
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"connect/internal/importjournal"
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// MaxReported bounds the references listed in a result. References past it
+// are still counted.
+const MaxReported = 1000
+
+// Store resolves references and writes their relationships
+type Store interface {
+	// FindCIsByName returns the CIs of a type with a name; more than one
+	// makes a reference to them ambiguous
+	FindCIsByName(ctx context.Context, ciType, name string) ([]*models.CI, error)
+	// FindRelationship returns the relationship of a type between two CIs,
+	// or nil if there is none
+	FindRelationship(ctx context.Context, sourceID, targetID uuid.UUID, relType string) (*models.CIRelationship, error)
+	GetRelationshipSchemaByType(ctx context.Context, relType string) (*models.RelationshipTypeSchema, error)
+	CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error)
+	CreateRelationshipWithValidation(ctx context.Context, rel *models.CIRelationship, schema *models.RelationshipTypeSchema) (*models.CIRelationship, error)
+}
+
+// Reference is an attribute of an imported CI naming another CI
+type Reference struct {
+	Row       int    `json:"row"`
+	CI        string `json:"ci"`
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	// Message is why the reference was not related
+	Message string `json:"message,omitempty"`
+}
+
+// Result is the outcome of relating the references of an import
+type Result struct {
+	// Created counts the relationships created
+	Created int `json:"created"`
+	// Existing counts the references whose relationship already existed
+	Existing int `json:"existing"`
+	// Unresolved lists the references naming no CI or several
+	Unresolved []Reference `json:"unresolved,omitempty"`
+	// Failed lists the resolved references whose relationship could not be
+	// created, e.g. because it breaks its relationship type schema
+	Failed []Reference `json:"failed,omitempty"`
+	// Truncated is set when more references were listed than MaxReported
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// report lists a reference that was not related
+func (r *Result) report(list *[]Reference, ref Reference, format string, args ...interface{}) {
+	if len(r.Unresolved)+len(r.Failed) >= MaxReported {
+		r.Truncated = true
+		return
+	}
+	ref.Message = fmt.Sprintf(format, args...)
+	*list = append(*list, ref)
+}
+
+// pending is a reference waiting to be resolved
+type pending struct {
+	Reference
+	ciID uuid.UUID
+	spec models.AttributeReference
+}
+
+// Linker collects the references of the CIs an import writes and relates them
+// once the import has written every CI. A nil Linker collects nothing.
+type Linker struct {
+	store   Store
+	pending []pending
+}
+
+// NewLinker creates a linker for one import
+func NewLinker(store Store) *Linker {
+	return &Linker{store: store}
+}
+
+// Add collects the references of a written CI, as flagged by its schema
+func (l *Linker) Add(row int, ci *models.CI, schema *models.CITypeSchema) {
+	if l == nil || schema == nil {
+		return
+	}
+
+	var attributes map[string]interface{}
+	for _, attr := range schema.Attributes {
+		if attr.Reference == nil {
+			continue
+		}
+		if attributes == nil {
+			if len(ci.Attributes) == 0 || json.Unmarshal(ci.Attributes, &attributes) != nil {
+				return
+			}
+		}
+		value, _ := attributes[attr.Name].(string)
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		l.pending = append(l.pending, pending{
+			Reference: Reference{Row: row, CI: ci.Name, Attribute: attr.Name, Value: value},
+			ciID:      ci.ID,
+			spec:      *attr.Reference,
+		})
+	}
+}
+
+// Link resolves the collected references and creates their relationships,
+// journaling them with the import. It returns nil when no reference was
+// collected.
+func (l *Linker) Link(ctx context.Context, by uuid.UUID, journal *importjournal.Journal) *Result {
+	if l == nil || len(l.pending) == 0 {
+		return nil
+	}
+
+	result := &Result{}
+	for _, ref := range l.pending {
+		if err := ctx.Err(); err != nil {
+			result.report(&result.Failed, ref.Reference, "import cancelled: %v", err)
+			continue
+		}
+
+		matches, err := l.store.FindCIsByName(ctx, ref.spec.TargetType, ref.Value)
+		if err != nil {
+			result.report(&result.Failed, ref.Reference, "%v", err)
+			continue
+		}
+		switch len(matches) {
+		case 0:
+			result.report(&result.Unresolved, ref.Reference, "no %s named %q", ref.spec.TargetType, ref.Value)
+			continue
+		case 1:
+		default:
+			result.report(&result.Unresolved, ref.Reference, "%d CIs of type %s are named %q", len(matches), ref.spec.TargetType, ref.Value)
+			continue
+		}
+
+		sourceID, targetID := ref.ciID, matches[0].ID
+		if ref.spec.Incoming() {
+			sourceID, targetID = targetID, sourceID
+		}
+		if sourceID == targetID {
+			result.report(&result.Failed, ref.Reference, "a CI cannot reference itself")
+			continue
+		}
+
+		existing, err := l.store.FindRelationship(ctx, sourceID, targetID, ref.spec.RelationshipType)
+		if err != nil {
+			result.report(&result.Failed, ref.Reference, "%v", err)
+			continue
+		}
+		if existing != nil {
+			result.Existing++
+			continue
+		}
+
+		created, err := l.create(ctx, &models.CIRelationship{
+			ID:          uuid.New(),
+			SourceCIID:  sourceID,
+			TargetCIID:  targetID,
+			Type:        ref.spec.RelationshipType,
+			Description: fmt.Sprintf("Created on import from attribute %s", ref.Attribute),
+			CreatedBy:   by,
+			UpdatedBy:   by,
+		})
+		if err != nil {
+			result.report(&result.Failed, ref.Reference, "%v", err)
+			continue
+		}
+		journal.Created(importjournal.EntityRelationship, created.ID, created.UpdatedAt)
+		result.Created++
+	}
+	return result
+}
+
+// create writes a relationship, validating it against the schema of its type
+// when there is one
+func (l *Linker) create(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error) {
+	if schema, err := l.store.GetRelationshipSchemaByType(ctx, rel.Type); err == nil {
+		return l.store.CreateRelationshipWithValidation(ctx, rel, schema)
+	}
+	return l.store.CreateRelationship(ctx, rel)
+}
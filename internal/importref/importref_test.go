@@ -0,0 +1,134 @@
+package importref
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps CIs and relationships in memory
+type memoryStore struct {
+	cis           []*models.CI
+	relationships []*models.CIRelationship
+	// invalid fails creating relationships of this type
+	invalid string
+}
+
+func (m *memoryStore) FindCIsByName(ctx context.Context, ciType, name string) ([]*models.CI, error) {
+	var matches []*models.CI
+	for _, ci := range m.cis {
+		if ci.Type == ciType && ci.Name == name {
+			matches = append(matches, ci)
+		}
+	}
+	return matches, nil
+}
+
+func (m *memoryStore) FindRelationship(ctx context.Context, sourceID, targetID uuid.UUID, relType string) (*models.CIRelationship, error) {
+	for _, rel := range m.relationships {
+		if rel.SourceCIID == sourceID && rel.TargetCIID == targetID && rel.Type == relType {
+			return rel, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) GetRelationshipSchemaByType(ctx context.Context, relType string) (*models.RelationshipTypeSchema, error) {
+	return nil, errors.New("not found")
+}
+
+func (m *memoryStore) CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error) {
+	if rel.Type == m.invalid {
+		return nil, errors.New("relationship is invalid")
+	}
+	m.relationships = append(m.relationships, rel)
+	return rel, nil
+}
+
+func (m *memoryStore) CreateRelationshipWithValidation(ctx context.Context, rel *models.CIRelationship, schema *models.RelationshipTypeSchema) (*models.CIRelationship, error) {
+	return m.CreateRelationship(ctx, rel)
+}
+
+func (m *memoryStore) add(ciType, name string, attributes map[string]interface{}) *models.CI {
+	raw, _ := json.Marshal(attributes)
+	ci := &models.CI{ID: uuid.New(), Type: ciType, Name: name, Attributes: raw}
+	m.cis = append(m.cis, ci)
+	return ci
+}
+
+var vmSchema = &models.CITypeSchema{
+	Name: "virtual_machine",
+	Attributes: []models.CITypeAttribute{
+		{Name: "hypervisor_name", Type: models.AttributeTypeString, Reference: &models.AttributeReference{
+			TargetType: "hypervisor", RelationshipType: "runs_on",
+		}},
+		{Name: "backup_server", Type: models.AttributeTypeString, Reference: &models.AttributeReference{
+			TargetType: "server", RelationshipType: "backs_up", Direction: models.ReferenceIncoming,
+		}},
+		{Name: "os", Type: models.AttributeTypeString},
+	},
+}
+
+func TestLinkerLink(t *testing.T) {
+	store := &memoryStore{}
+	ctx := context.Background()
+	linker := NewLinker(store)
+
+	// The first VM references a hypervisor imported after it
+	vm1 := store.add("virtual_machine", "vm-1", map[string]interface{}{"hypervisor_name": "esx-01", "backup_server": "bkp", "os": "linux"})
+	linker.Add(2, vm1, vmSchema)
+	hypervisor := store.add("hypervisor", "esx-01", nil)
+	backup := store.add("server", "bkp", nil)
+
+	vm2 := store.add("virtual_machine", "vm-2", map[string]interface{}{"hypervisor_name": "esx-99", "backup_server": " "})
+	linker.Add(3, vm2, vmSchema)
+	store.add("server", "dup", nil)
+	store.add("server", "dup", nil)
+	vm3 := store.add("virtual_machine", "vm-3", map[string]interface{}{"backup_server": "dup"})
+	linker.Add(4, vm3, vmSchema)
+
+	// The same CI imported twice relates once
+	linker.Add(5, vm1, vmSchema)
+
+	result := linker.Link(ctx, uuid.New(), nil)
+	require.NotNil(t, result)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 2, result.Existing)
+	assert.Empty(t, result.Failed)
+	require.Len(t, result.Unresolved, 2)
+	assert.Equal(t, Reference{Row: 3, CI: "vm-2", Attribute: "hypervisor_name", Value: "esx-99", Message: `no hypervisor named "esx-99"`}, result.Unresolved[0])
+	assert.Equal(t, "backup_server", result.Unresolved[1].Attribute)
+	assert.Contains(t, result.Unresolved[1].Message, "2 CIs of type server")
+
+	require.Len(t, store.relationships, 2)
+	assert.Equal(t, vm1.ID, store.relationships[0].SourceCIID)
+	assert.Equal(t, hypervisor.ID, store.relationships[0].TargetCIID)
+	assert.Equal(t, "runs_on", store.relationships[0].Type)
+	assert.Equal(t, backup.ID, store.relationships[1].SourceCIID)
+	assert.Equal(t, vm1.ID, store.relationships[1].TargetCIID)
+}
+
+func TestLinkerReportsFailures(t *testing.T) {
+	store := &memoryStore{invalid: "runs_on"}
+	linker := NewLinker(store)
+	store.add("hypervisor", "esx-01", nil)
+	linker.Add(2, store.add("virtual_machine", "vm-1", map[string]interface{}{"hypervisor_name": "esx-01"}), vmSchema)
+
+	result := linker.Link(context.Background(), uuid.New(), nil)
+	require.NotNil(t, result)
+	assert.Zero(t, result.Created)
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, "relationship is invalid", result.Failed[0].Message)
+
+	// Nothing to relate reports nothing
+	var none *Linker
+	none.Add(2, store.cis[0], vmSchema)
+	assert.Nil(t, none.Link(context.Background(), uuid.New(), nil))
+	assert.Nil(t, NewLinker(store).Link(context.Background(), uuid.New(), nil))
+}
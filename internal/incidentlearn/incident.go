@@ -0,0 +1,123 @@
+// Package incidentlearn learns how strongly CIs depend on each other from
+// incident history. Incident and outage records referencing the CIs they hit
+// are ingested from incident tools; a relationship whose target and source
+// are repeatedly hit by the same incidents is given a confidence, the share
+// of its target's incidents that also hit its source. Relationships with
+// enough incidents and a high confidence are empirically critical: impact
+// analysis follows them at their learned strength and marks the paths
+// through them.
+package incidentlearn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxIncidentCIs bounds the CIs an incident references
+const MaxIncidentCIs = 500
+
+// MaxBatchSize bounds the incidents ingested in one request
+const MaxBatchSize = 1000
+
+var ErrInvalidIncident = errors.New("invalid incident")
+
+// Incident is an incident or outage, identified by its source system and the
+// ID it has there, with the CIs it hit
+type Incident struct {
+	ID         uuid.UUID   `json:"id"`
+	Source     string      `json:"source"`
+	ExternalID string      `json:"external_id"`
+	Title      string      `json:"title"`
+	StartedAt  time.Time   `json:"started_at"`
+	ResolvedAt *time.Time  `json:"resolved_at,omitempty"`
+	CIIDs      []uuid.UUID `json:"ci_ids"`
+	IngestedAt time.Time   `json:"ingested_at"`
+}
+
+// Validate checks an incident and drops duplicate CI references
+func (i *Incident) Validate() error {
+	i.Source = strings.TrimSpace(i.Source)
+	i.ExternalID = strings.TrimSpace(i.ExternalID)
+	if i.Source == "" || i.ExternalID == "" {
+		return fmt.Errorf("%w: source and external_id are required", ErrInvalidIncident)
+	}
+	if i.StartedAt.IsZero() {
+		return fmt.Errorf("%w: %s/%s: started_at is required", ErrInvalidIncident, i.Source, i.ExternalID)
+	}
+	if i.ResolvedAt != nil && i.ResolvedAt.Before(i.StartedAt) {
+		return fmt.Errorf("%w: %s/%s: resolved_at is before started_at", ErrInvalidIncident, i.Source, i.ExternalID)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(i.CIIDs))
+	unique := make([]uuid.UUID, 0, len(i.CIIDs))
+	for _, id := range i.CIIDs {
+		if id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return fmt.Errorf("%w: %s/%s: at least one CI is required", ErrInvalidIncident, i.Source, i.ExternalID)
+	}
+	if len(unique) > MaxIncidentCIs {
+		return fmt.Errorf("%w: %s/%s: at most %d CIs may be referenced", ErrInvalidIncident, i.Source, i.ExternalID, MaxIncidentCIs)
+	}
+	i.CIIDs = unique
+	return nil
+}
+
+// CoOccurrence counts the incidents that hit the target of a relationship,
+// and those of them that also hit its source
+type CoOccurrence struct {
+	RelationshipID  uuid.UUID `json:"relationship_id" db:"relationship_id"`
+	SourceCIID      uuid.UUID `json:"source_ci_id" db:"source_ci_id"`
+	TargetCIID      uuid.UUID `json:"target_ci_id" db:"target_ci_id"`
+	Type            string    `json:"type" db:"type"`
+	CoOccurrences   int       `json:"co_occurrences" db:"co_occurrences"`
+	TargetIncidents int       `json:"target_incidents" db:"target_incidents"`
+}
+
+// Score is what incident history tells about a relationship
+type Score struct {
+	CoOccurrence
+	// Confidence is the share of the target's incidents that hit the source too
+	Confidence float64 `json:"confidence" db:"confidence"`
+	// Critical marks empirically critical relationships
+	Critical  bool      `json:"critical" db:"critical"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Settings control what is learned
+type Settings struct {
+	// Window is how far back incidents are learned from
+	Window time.Duration
+	// MinIncidents is the incidents a relationship's target must have had
+	// before the relationship can be critical, so a single coincidence does
+	// not make it one
+	MinIncidents int
+	// CriticalConfidence is the confidence from which a relationship is critical
+	CriticalConfidence float64
+}
+
+// DefaultSettings returns the settings used when none are configured
+func DefaultSettings() Settings {
+	return Settings{Window: 180 * 24 * time.Hour, MinIncidents: 3, CriticalConfidence: 0.6}
+}
+
+// score turns co-occurrence counts into a score
+func (s Settings) score(co CoOccurrence, now time.Time) Score {
+	score := Score{CoOccurrence: co, UpdatedAt: now}
+	if co.TargetIncidents > 0 {
+		score.Confidence = float64(co.CoOccurrences) / float64(co.TargetIncidents)
+		if score.Confidence > 1 {
+			score.Confidence = 1
+		}
+	}
+	score.Critical = co.TargetIncidents >= s.MinIncidents && score.Confidence >= s.CriticalConfidence
+	return score
+}
@@ -0,0 +1,130 @@
+package incidentlearn
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IngestResult reports an ingested batch
+type IngestResult struct {
+	Ingested int `json:"ingested"`
+}
+
+// LearnResult reports a learning run
+type LearnResult struct {
+	Relationships int       `json:"relationships"`
+	Critical      int       `json:"critical"`
+	Since         time.Time `json:"since"`
+	LearnedAt     time.Time `json:"learned_at"`
+}
+
+// Service ingests incidents and learns relationship scores from them
+type Service struct {
+	store    Store
+	settings Settings
+	now      func() time.Time
+}
+
+// NewService creates a new incident learning service
+func NewService(store Store, settings Settings) *Service {
+	defaults := DefaultSettings()
+	if settings.Window <= 0 {
+		settings.Window = defaults.Window
+	}
+	if settings.MinIncidents <= 0 {
+		settings.MinIncidents = defaults.MinIncidents
+	}
+	if settings.CriticalConfidence <= 0 || settings.CriticalConfidence > 1 {
+		settings.CriticalConfidence = defaults.CriticalConfidence
+	}
+	return &Service{store: store, settings: settings, now: time.Now}
+}
+
+// Ingest validates and stores a batch of incidents. An incident already
+// ingested from the same source is replaced, so records can be re-sent as
+// they are updated. Scores are learned on the next learning run.
+func (s *Service) Ingest(ctx context.Context, incidents []Incident) (*IngestResult, error) {
+	if len(incidents) == 0 || len(incidents) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: between 1 and %d incidents may be ingested at once", ErrInvalidIncident, MaxBatchSize)
+	}
+	now := s.now()
+	for i := range incidents {
+		if err := incidents[i].Validate(); err != nil {
+			return nil, err
+		}
+		incidents[i].ID = uuid.New()
+		incidents[i].IngestedAt = now
+	}
+
+	if err := s.store.UpsertIncidents(ctx, incidents); err != nil {
+		return nil, err
+	}
+	return &IngestResult{Ingested: len(incidents)}, nil
+}
+
+// ListIncidents returns the latest incidents, optionally those that hit a CI
+func (s *Service) ListIncidents(ctx context.Context, ciID *uuid.UUID, limit int) ([]Incident, error) {
+	return s.store.ListIncidents(ctx, ciID, limit)
+}
+
+// Learn recomputes the score of every relationship from the incidents
+// within the window, replacing the previous scores
+func (s *Service) Learn(ctx context.Context) (*LearnResult, error) {
+	now := s.now()
+	since := now.Add(-s.settings.Window)
+	counts, err := s.store.CoOccurrences(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]Score, 0, len(counts))
+	critical := 0
+	for _, co := range counts {
+		score := s.settings.score(co, now)
+		if score.Critical {
+			critical++
+		}
+		scores = append(scores, score)
+	}
+	if err := s.store.ReplaceScores(ctx, scores); err != nil {
+		return nil, err
+	}
+	return &LearnResult{Relationships: len(scores), Critical: critical, Since: since, LearnedAt: now}, nil
+}
+
+// ListScores returns the learned scores, highest confidence first
+func (s *Service) ListScores(ctx context.Context, criticalOnly bool, limit int) ([]Score, error) {
+	return s.store.ListScores(ctx, criticalOnly, limit)
+}
+
+// LearnedStrengths returns the confidence of the empirically critical
+// relationships among the given ones, by relationship ID, for impact analysis
+func (s *Service) LearnedStrengths(ctx context.Context, relationshipIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	if len(relationshipIDs) == 0 {
+		return map[uuid.UUID]float64{}, nil
+	}
+	return s.store.CriticalConfidences(ctx, relationshipIDs)
+}
+
+// Run learns the scores every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if result, err := s.Learn(ctx); err != nil {
+				log.Printf("Failed to learn relationship scores from incidents: %v", err)
+			} else {
+				log.Printf("Learned scores of %d relationships from incidents, %d empirically critical", result.Relationships, result.Critical)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package incidentlearn
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type relationship struct {
+	id, source, target uuid.UUID
+}
+
+// memoryStore keeps incidents in memory and counts co-occurrences the way the
+// Postgres store does
+type memoryStore struct {
+	incidents     map[string]Incident
+	relationships []relationship
+	scores        map[uuid.UUID]Score
+}
+
+func newMemoryStore(relationships ...relationship) *memoryStore {
+	return &memoryStore{incidents: map[string]Incident{}, relationships: relationships, scores: map[uuid.UUID]Score{}}
+}
+
+func (m *memoryStore) UpsertIncidents(ctx context.Context, incidents []Incident) error {
+	for _, incident := range incidents {
+		key := incident.Source + "/" + incident.ExternalID
+		if existing, ok := m.incidents[key]; ok {
+			incident.ID = existing.ID
+		}
+		m.incidents[key] = incident
+	}
+	return nil
+}
+
+func (m *memoryStore) ListIncidents(ctx context.Context, ciID *uuid.UUID, limit int) ([]Incident, error) {
+	incidents := []Incident{}
+	for _, incident := range m.incidents {
+		if ciID == nil || hits(incident, *ciID) {
+			incidents = append(incidents, incident)
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool { return incidents[i].StartedAt.After(incidents[j].StartedAt) })
+	if len(incidents) > limit {
+		incidents = incidents[:limit]
+	}
+	return incidents, nil
+}
+
+func (m *memoryStore) CoOccurrences(ctx context.Context, since time.Time) ([]CoOccurrence, error) {
+	counts := []CoOccurrence{}
+	for _, rel := range m.relationships {
+		co := CoOccurrence{RelationshipID: rel.id, SourceCIID: rel.source, TargetCIID: rel.target}
+		for _, incident := range m.incidents {
+			if incident.StartedAt.Before(since) || !hits(incident, rel.target) {
+				continue
+			}
+			co.TargetIncidents++
+			if hits(incident, rel.source) {
+				co.CoOccurrences++
+			}
+		}
+		if co.CoOccurrences > 0 {
+			counts = append(counts, co)
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) ReplaceScores(ctx context.Context, scores []Score) error {
+	m.scores = map[uuid.UUID]Score{}
+	for _, score := range scores {
+		m.scores[score.RelationshipID] = score
+	}
+	return nil
+}
+
+func (m *memoryStore) ListScores(ctx context.Context, criticalOnly bool, limit int) ([]Score, error) {
+	scores := []Score{}
+	for _, score := range m.scores {
+		if !criticalOnly || score.Critical {
+			scores = append(scores, score)
+		}
+	}
+	return scores, nil
+}
+
+func (m *memoryStore) CriticalConfidences(ctx context.Context, relationshipIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	confidences := map[uuid.UUID]float64{}
+	for _, id := range relationshipIDs {
+		if score, ok := m.scores[id]; ok && score.Critical {
+			confidences[id] = score.Confidence
+		}
+	}
+	return confidences, nil
+}
+
+func hits(incident Incident, ciID uuid.UUID) bool {
+	for _, id := range incident.CIIDs {
+		if id == ciID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIncidentValidate(t *testing.T) {
+	ci := uuid.New()
+	incident := Incident{Source: " pagerduty ", ExternalID: "P1", StartedAt: time.Now(), CIIDs: []uuid.UUID{ci, ci, uuid.Nil}}
+	require.NoError(t, incident.Validate())
+	assert.Equal(t, "pagerduty", incident.Source)
+	assert.Equal(t, []uuid.UUID{ci}, incident.CIIDs)
+
+	started := time.Now()
+	resolved := started.Add(-time.Minute)
+	for _, invalid := range []Incident{
+		{ExternalID: "P1", StartedAt: started, CIIDs: []uuid.UUID{ci}},
+		{Source: "pagerduty", ExternalID: "P1", CIIDs: []uuid.UUID{ci}},
+		{Source: "pagerduty", ExternalID: "P1", StartedAt: started},
+		{Source: "pagerduty", ExternalID: "P1", StartedAt: started, ResolvedAt: &resolved, CIIDs: []uuid.UUID{ci}},
+	} {
+		assert.ErrorIs(t, invalid.Validate(), ErrInvalidIncident)
+	}
+}
+
+func TestLearnMarksEmpiricallyCriticalRelationships(t *testing.T) {
+	ctx := context.Background()
+	app, db, cache := uuid.New(), uuid.New(), uuid.New()
+	appOnDB := relationship{id: uuid.New(), source: app, target: db}
+	appOnCache := relationship{id: uuid.New(), source: app, target: cache}
+	store := newMemoryStore(appOnDB, appOnCache)
+
+	now := time.Now()
+	service := NewService(store, Settings{Window: 30 * 24 * time.Hour, MinIncidents: 3, CriticalConfidence: 0.6})
+	service.now = func() time.Time { return now }
+
+	incidents := []Incident{
+		// The app went down with the database three times out of four
+		{Source: "pagerduty", ExternalID: "1", StartedAt: now.Add(-time.Hour), CIIDs: []uuid.UUID{app, db}},
+		{Source: "pagerduty", ExternalID: "2", StartedAt: now.Add(-48 * time.Hour), CIIDs: []uuid.UUID{app, db}},
+		{Source: "pagerduty", ExternalID: "3", StartedAt: now.Add(-72 * time.Hour), CIIDs: []uuid.UUID{db, app}},
+		{Source: "pagerduty", ExternalID: "4", StartedAt: now.Add(-96 * time.Hour), CIIDs: []uuid.UUID{db}},
+		// The app and the cache went down together once, which is not enough
+		{Source: "pagerduty", ExternalID: "5", StartedAt: now.Add(-time.Hour), CIIDs: []uuid.UUID{app, cache}},
+		// Outside the window
+		{Source: "pagerduty", ExternalID: "6", StartedAt: now.Add(-60 * 24 * time.Hour), CIIDs: []uuid.UUID{app, cache}},
+		{Source: "pagerduty", ExternalID: "7", StartedAt: now.Add(-61 * 24 * time.Hour), CIIDs: []uuid.UUID{app, cache}},
+	}
+	ingested, err := service.Ingest(ctx, incidents)
+	require.NoError(t, err)
+	assert.Equal(t, 7, ingested.Ingested)
+
+	result, err := service.Learn(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Relationships)
+	assert.Equal(t, 1, result.Critical)
+
+	dbScore := store.scores[appOnDB.id]
+	assert.Equal(t, 3, dbScore.CoOccurrences)
+	assert.Equal(t, 4, dbScore.TargetIncidents)
+	assert.InDelta(t, 0.75, dbScore.Confidence, 1e-9)
+	assert.True(t, dbScore.Critical)
+
+	cacheScore := store.scores[appOnCache.id]
+	assert.InDelta(t, 1.0, cacheScore.Confidence, 1e-9)
+	assert.False(t, cacheScore.Critical)
+
+	strengths, err := service.LearnedStrengths(ctx, []uuid.UUID{appOnDB.id, appOnCache.id})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]float64{appOnDB.id: 0.75}, strengths)
+}
+
+func TestIngestReplacesIncidentsFromTheSameSource(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	service := NewService(store, Settings{})
+
+	ci := uuid.New()
+	_, err := service.Ingest(ctx, []Incident{{Source: "opsgenie", ExternalID: "A", StartedAt: time.Now(), CIIDs: []uuid.UUID{ci}}})
+	require.NoError(t, err)
+	_, err = service.Ingest(ctx, []Incident{{Source: "opsgenie", ExternalID: "A", Title: "updated", StartedAt: time.Now(), CIIDs: []uuid.UUID{ci}}})
+	require.NoError(t, err)
+
+	incidents, err := service.ListIncidents(ctx, &ci, 10)
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+	assert.Equal(t, "updated", incidents[0].Title)
+
+	_, err = service.Ingest(ctx, nil)
+	assert.ErrorIs(t, err, ErrInvalidIncident)
+}
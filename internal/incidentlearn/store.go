@@ -0,0 +1,227 @@
+package incidentlearn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store persists incidents and the scores learned from them
+type Store interface {
+	// UpsertIncidents stores incidents, replacing those with the same source
+	// and external ID along with the CIs they hit
+	UpsertIncidents(ctx context.Context, incidents []Incident) error
+	ListIncidents(ctx context.Context, ciID *uuid.UUID, limit int) ([]Incident, error)
+	// CoOccurrences counts, for every active relationship whose target was
+	// hit by an incident started since the given time, the incidents that hit
+	// its target and those that hit its source too. Relationships never hit
+	// at both ends are left out.
+	CoOccurrences(ctx context.Context, since time.Time) ([]CoOccurrence, error)
+	ReplaceScores(ctx context.Context, scores []Score) error
+	ListScores(ctx context.Context, criticalOnly bool, limit int) ([]Score, error)
+	CriticalConfidences(ctx context.Context, relationshipIDs []uuid.UUID) (map[uuid.UUID]float64, error)
+}
+
+// PostgresStore keeps incidents in the incidents and incident_cis tables and
+// scores in relationship_incident_scores
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed incident store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// UpsertIncidents stores incidents in one transaction
+func (s *PostgresStore) UpsertIncidents(ctx context.Context, incidents []Incident) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range incidents {
+		incident := &incidents[i]
+		// An incident ingested before keeps its ID
+		err := tx.QueryRowxContext(ctx, `
+			INSERT INTO incidents (id, source, external_id, title, started_at, resolved_at, ingested_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (source, external_id) DO UPDATE SET
+				title = EXCLUDED.title,
+				started_at = EXCLUDED.started_at,
+				resolved_at = EXCLUDED.resolved_at,
+				ingested_at = EXCLUDED.ingested_at
+			RETURNING id`,
+			incident.ID, incident.Source, incident.ExternalID, incident.Title,
+			incident.StartedAt, incident.ResolvedAt, incident.IngestedAt).Scan(&incident.ID)
+		if err != nil {
+			return fmt.Errorf("failed to store incident %s/%s: %w", incident.Source, incident.ExternalID, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM incident_cis WHERE incident_id = $1`, incident.ID); err != nil {
+			return fmt.Errorf("failed to store incident CIs: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO incident_cis (incident_id, ci_id)
+			SELECT $1, unnest($2::uuid[])`,
+			incident.ID, pq.Array(uuidStrings(incident.CIIDs))); err != nil {
+			return fmt.Errorf("failed to store incident CIs: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit incidents: %w", err)
+	}
+	return nil
+}
+
+// ListIncidents retrieves the latest incidents with the CIs they hit
+func (s *PostgresStore) ListIncidents(ctx context.Context, ciID *uuid.UUID, limit int) ([]Incident, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT i.id, i.source, i.external_id, i.title, i.started_at, i.resolved_at, i.ingested_at,
+		       ARRAY(SELECT ci_id::text FROM incident_cis WHERE incident_id = i.id ORDER BY ci_id)
+		FROM incidents i
+		WHERE $1::uuid IS NULL OR EXISTS (SELECT 1 FROM incident_cis WHERE incident_id = i.id AND ci_id = $1)
+		ORDER BY i.started_at DESC
+		LIMIT $2`, ciID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []Incident{}
+	for rows.Next() {
+		var incident Incident
+		var ciIDs pq.StringArray
+		if err := rows.Scan(&incident.ID, &incident.Source, &incident.ExternalID, &incident.Title,
+			&incident.StartedAt, &incident.ResolvedAt, &incident.IngestedAt, &ciIDs); err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incident.CIIDs = make([]uuid.UUID, 0, len(ciIDs))
+		for _, id := range ciIDs {
+			if parsed, err := uuid.Parse(id); err == nil {
+				incident.CIIDs = append(incident.CIIDs, parsed)
+			}
+		}
+		incidents = append(incidents, incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+// CoOccurrences counts incidents per relationship in one query
+func (s *PostgresStore) CoOccurrences(ctx context.Context, since time.Time) ([]CoOccurrence, error) {
+	counts := []CoOccurrence{}
+	err := s.db.SelectContext(ctx, &counts, `
+		WITH recent AS (
+			SELECT ic.incident_id, ic.ci_id
+			FROM incident_cis ic
+			JOIN incidents i ON i.id = ic.incident_id
+			WHERE i.started_at >= $1
+		), target_incidents AS (
+			SELECT ci_id, COUNT(*) AS incidents FROM recent GROUP BY ci_id
+		)
+		SELECT r.id AS relationship_id, r.source_ci_id, r.target_ci_id, r.type,
+		       COUNT(*) AS co_occurrences, t.incidents AS target_incidents
+		FROM ci_relationships r
+		JOIN target_incidents t ON t.ci_id = r.target_ci_id
+		JOIN recent target ON target.ci_id = r.target_ci_id
+		JOIN recent source ON source.incident_id = target.incident_id AND source.ci_id = r.source_ci_id
+		WHERE r.is_active = true AND r.state = 'active' AND r.source_ci_id <> r.target_ci_id
+		GROUP BY r.id, r.source_ci_id, r.target_ci_id, r.type, t.incidents`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count incident co-occurrences: %w", err)
+	}
+	return counts, nil
+}
+
+// ReplaceScores replaces every score in one transaction, so readers see
+// either the previous or the new run
+func (s *PostgresStore) ReplaceScores(ctx context.Context, scores []Score) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM relationship_incident_scores`); err != nil {
+		return fmt.Errorf("failed to clear relationship scores: %w", err)
+	}
+	for _, score := range scores {
+		// The relationship may have been deleted since it was counted
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO relationship_incident_scores
+				(relationship_id, co_occurrences, target_incidents, confidence, critical, updated_at)
+			SELECT $1, $2, $3, $4, $5, $6
+			WHERE EXISTS (SELECT 1 FROM ci_relationships WHERE id = $1)`,
+			score.RelationshipID, score.CoOccurrences, score.TargetIncidents,
+			score.Confidence, score.Critical, score.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to store relationship score: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit relationship scores: %w", err)
+	}
+	return nil
+}
+
+// ListScores retrieves the learned scores, highest confidence first
+func (s *PostgresStore) ListScores(ctx context.Context, criticalOnly bool, limit int) ([]Score, error) {
+	scores := []Score{}
+	err := s.db.SelectContext(ctx, &scores, `
+		SELECT s.relationship_id, r.source_ci_id, r.target_ci_id, r.type,
+		       s.co_occurrences, s.target_incidents, s.confidence, s.critical, s.updated_at
+		FROM relationship_incident_scores s
+		JOIN ci_relationships r ON r.id = s.relationship_id
+		WHERE NOT $1 OR s.critical
+		ORDER BY s.confidence DESC, s.target_incidents DESC
+		LIMIT $2`, criticalOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationship scores: %w", err)
+	}
+	return scores, nil
+}
+
+// CriticalConfidences retrieves the confidence of the critical relationships among the given ones
+func (s *PostgresStore) CriticalConfidences(ctx context.Context, relationshipIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT relationship_id, confidence
+		FROM relationship_incident_scores
+		WHERE critical AND relationship_id = ANY($1::uuid[])`,
+		pq.Array(uuidStrings(relationshipIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationship scores: %w", err)
+	}
+	defer rows.Close()
+
+	confidences := make(map[uuid.UUID]float64)
+	for rows.Next() {
+		var id uuid.UUID
+		var confidence float64
+		if err := rows.Scan(&id, &confidence); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship score: %w", err)
+		}
+		confidences[id] = confidence
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get relationship scores: %w", err)
+	}
+	return confidences, nil
+}
+
+// uuidStrings formats IDs for a uuid[] parameter
+func uuidStrings(ids []uuid.UUID) []string {
+	params := make([]string, len(ids))
+	for i, id := range ids {
+		params[i] = id.String()
+	}
+	return params
+}
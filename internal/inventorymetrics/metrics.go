@@ -0,0 +1,224 @@
+// Package inventorymetrics exposes inventory counts as gauges in the
+// OpenMetrics text format, for capacity dashboards to scrape: live CIs per
+// type, status and criticality, relationships per type and state, and schemas
+// per kind. Every series of a metric has the same label set, so queries and
+// dashboards do not break as values come and go. The counts are cached
+// briefly, so any number of scrapers cost one set of queries.
+package inventorymetrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long counts are served before they are read again
+const DefaultCacheTTL = 30 * time.Second
+
+// Content types of the two text formats
+const (
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	ContentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Schema kinds
+const (
+	SchemaKindCIType           = "ci_type"
+	SchemaKindRelationshipType = "relationship_type"
+)
+
+// CICount is the number of live CIs with a type, status and criticality
+type CICount struct {
+	Type        string `db:"type"`
+	Status      string `db:"status"`
+	Criticality string `db:"criticality"`
+	Count       int64  `db:"count"`
+}
+
+// RelationshipCount is the number of relationships with a type and state
+type RelationshipCount struct {
+	Type  string `db:"type"`
+	State string `db:"state"`
+	Count int64  `db:"count"`
+}
+
+// SchemaCount is the number of active or inactive schemas of a kind
+type SchemaCount struct {
+	Kind   string `db:"kind"`
+	Active bool   `db:"active"`
+	Count  int64  `db:"count"`
+}
+
+// Counts are the inventory counts exposed as metrics
+type Counts struct {
+	CIs           []CICount
+	Relationships []RelationshipCount
+	Schemas       []SchemaCount
+	ReadAt        time.Time
+}
+
+// Store reads the inventory counts, as CIRepository does
+type Store interface {
+	InventoryCounts(ctx context.Context) (*Counts, error)
+}
+
+// Service reads and caches the counts
+type Service struct {
+	store    Store
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	// loading serialises reads, so concurrent scrapes on a stale cache wait
+	// for one read instead of each querying
+	loading sync.Mutex
+
+	mu     sync.Mutex
+	cached *Counts
+}
+
+// NewService creates a new inventory metrics service
+func NewService(store Store, cacheTTL time.Duration) *Service {
+	if cacheTTL < 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{store: store, cacheTTL: cacheTTL, now: time.Now}
+}
+
+// Counts returns the counts, read at most cacheTTL ago
+func (s *Service) Counts(ctx context.Context) (*Counts, error) {
+	if counts := s.fresh(); counts != nil {
+		return counts, nil
+	}
+
+	s.loading.Lock()
+	defer s.loading.Unlock()
+	// Another scrape may have read them while this one waited
+	if counts := s.fresh(); counts != nil {
+		return counts, nil
+	}
+
+	counts, err := s.store.InventoryCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts.ReadAt = s.now()
+	s.mu.Lock()
+	s.cached = counts
+	s.mu.Unlock()
+	return counts, nil
+}
+
+// fresh returns the cached counts if they have not expired
+func (s *Service) fresh() *Counts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached == nil || s.now().Sub(s.cached.ReadAt) >= s.cacheTTL {
+		return nil
+	}
+	return s.cached
+}
+
+// sample is one series of a metric
+type sample struct {
+	labels []string // alternating names and values
+	value  float64
+}
+
+// family is a metric with its samples
+type family struct {
+	name, help, unit string
+	samples          []sample
+}
+
+// families turns counts into metric families, in a stable order
+func families(c *Counts) []family {
+	cis := family{name: "conx_inventory_cis", help: "Live CIs by type, status and criticality."}
+	for _, n := range c.CIs {
+		cis.samples = append(cis.samples, sample{[]string{"type", n.Type, "status", n.Status, "criticality", n.Criticality}, float64(n.Count)})
+	}
+
+	relationships := family{name: "conx_inventory_relationships", help: "Relationships by type and lifecycle state."}
+	for _, n := range c.Relationships {
+		relationships.samples = append(relationships.samples, sample{[]string{"type", n.Type, "state", n.State}, float64(n.Count)})
+	}
+
+	// Both kinds and both states are always exposed, so the series exist at zero
+	schemaCounts := map[string]int64{}
+	for _, n := range c.Schemas {
+		schemaCounts[n.Kind+"/"+strconv.FormatBool(n.Active)] += n.Count
+	}
+	schemas := family{name: "conx_inventory_schemas", help: "Schemas by kind and whether they are active."}
+	for _, kind := range []string{SchemaKindCIType, SchemaKindRelationshipType} {
+		for _, active := range []string{"true", "false"} {
+			schemas.samples = append(schemas.samples, sample{[]string{"kind", kind, "active", active}, float64(schemaCounts[kind+"/"+active])})
+		}
+	}
+
+	readAt := family{
+		name:    "conx_inventory_read_timestamp_seconds",
+		help:    "When the inventory counts were read.",
+		unit:    "seconds",
+		samples: []sample{{nil, float64(c.ReadAt.UnixNano()) / 1e9}},
+	}
+
+	for _, f := range []*family{&cis, &relationships} {
+		sort.Slice(f.samples, func(i, j int) bool {
+			return strings.Join(f.samples[i].labels, "\x00") < strings.Join(f.samples[j].labels, "\x00")
+		})
+	}
+	return []family{cis, relationships, schemas, readAt}
+}
+
+// Write writes the counts as gauges in the OpenMetrics text format, or in the
+// Prometheus text format it extends when openMetrics is false
+func Write(w io.Writer, c *Counts, openMetrics bool) error {
+	var b strings.Builder
+	for _, f := range families(c) {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escape(f.help, false))
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", f.name)
+		if openMetrics && f.unit != "" {
+			fmt.Fprintf(&b, "# UNIT %s %s\n", f.name, f.unit)
+		}
+		for _, s := range f.samples {
+			b.WriteString(f.name)
+			if len(s.labels) > 0 {
+				b.WriteByte('{')
+				for i := 0; i < len(s.labels); i += 2 {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", s.labels[i], escape(s.labels[i+1], true))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatFloat(s.value, 'f', -1, 64))
+			b.WriteByte('\n')
+		}
+	}
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escape escapes a HELP text, or a label value when quoted is set
+func escape(s string, quoted bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quoted {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+// AcceptsOpenMetrics reports whether an Accept header asks for OpenMetrics
+func AcceptsOpenMetrics(accept string) bool {
+	return strings.Contains(accept, "application/openmetrics-text")
+}
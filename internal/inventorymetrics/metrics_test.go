@@ -0,0 +1,118 @@
+package inventorymetrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore returns fixed counts and counts the reads
+type countingStore struct {
+	counts Counts
+	reads  int
+	err    error
+}
+
+func (s *countingStore) InventoryCounts(ctx context.Context) (*Counts, error) {
+	s.reads++
+	if s.err != nil {
+		return nil, s.err
+	}
+	counts := s.counts
+	return &counts, nil
+}
+
+var testCounts = Counts{
+	CIs: []CICount{
+		{Type: "server", Status: "active", Criticality: "high", Count: 12},
+		{Type: "database", Status: "active", Criticality: "critical", Count: 3},
+		{Type: "server", Status: "", Criticality: "low", Count: 1},
+	},
+	Relationships: []RelationshipCount{
+		{Type: "RUNS_ON", State: "active", Count: 40},
+		{Type: "DEPENDS_ON", State: "deprecated", Count: 2},
+	},
+	Schemas: []SchemaCount{
+		{Kind: SchemaKindCIType, Active: true, Count: 8},
+	},
+	ReadAt: time.Unix(1717243200, 500000000),
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, Write(&b, &testCounts, true))
+
+	assert.Equal(t, `# HELP conx_inventory_cis Live CIs by type, status and criticality.
+# TYPE conx_inventory_cis gauge
+conx_inventory_cis{type="database",status="active",criticality="critical"} 3
+conx_inventory_cis{type="server",status="",criticality="low"} 1
+conx_inventory_cis{type="server",status="active",criticality="high"} 12
+# HELP conx_inventory_relationships Relationships by type and lifecycle state.
+# TYPE conx_inventory_relationships gauge
+conx_inventory_relationships{type="DEPENDS_ON",state="deprecated"} 2
+conx_inventory_relationships{type="RUNS_ON",state="active"} 40
+# HELP conx_inventory_schemas Schemas by kind and whether they are active.
+# TYPE conx_inventory_schemas gauge
+conx_inventory_schemas{kind="ci_type",active="true"} 8
+conx_inventory_schemas{kind="ci_type",active="false"} 0
+conx_inventory_schemas{kind="relationship_type",active="true"} 0
+conx_inventory_schemas{kind="relationship_type",active="false"} 0
+# HELP conx_inventory_read_timestamp_seconds When the inventory counts were read.
+# TYPE conx_inventory_read_timestamp_seconds gauge
+# UNIT conx_inventory_read_timestamp_seconds seconds
+conx_inventory_read_timestamp_seconds 1717243200.5
+# EOF
+`, b.String())
+}
+
+func TestWritePrometheus(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, Write(&b, &testCounts, false))
+
+	assert.NotContains(t, b.String(), "# EOF")
+	assert.NotContains(t, b.String(), "# UNIT")
+	assert.Contains(t, b.String(), `conx_inventory_cis{type="server",status="active",criticality="high"} 12`)
+}
+
+func TestWriteEscapesLabelValues(t *testing.T) {
+	counts := Counts{CIs: []CICount{{Type: "odd\"type\\\n", Status: "active", Criticality: "low", Count: 1}}}
+	var b strings.Builder
+	require.NoError(t, Write(&b, &counts, true))
+
+	assert.Contains(t, b.String(), `conx_inventory_cis{type="odd\"type\\\n",status="active",criticality="low"} 1`)
+}
+
+func TestCountsAreCached(t *testing.T) {
+	store := &countingStore{counts: testCounts}
+	service := NewService(store, time.Minute)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	counts, err := service.Counts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, now, counts.ReadAt)
+	_, err = service.Counts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.reads)
+
+	now = now.Add(time.Minute)
+	_, err = service.Counts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.reads)
+
+	store.err = errors.New("connection refused")
+	now = now.Add(time.Minute)
+	_, err = service.Counts(context.Background())
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestAcceptsOpenMetrics(t *testing.T) {
+	assert.True(t, AcceptsOpenMetrics("application/openmetrics-text; version=1.0.0,text/plain;version=0.0.4;q=0.5"))
+	assert.False(t, AcceptsOpenMetrics("text/plain"))
+	assert.False(t, AcceptsOpenMetrics(""))
+}
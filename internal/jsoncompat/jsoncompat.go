@@ -0,0 +1,448 @@
+// Package jsoncompat serializes JSON responses in the format a client expects,
+// without each handler knowing about it. Handlers keep writing their native
+// format, bare snake_case bodies; a middleware rewrites the body when the API
+// version or the client asks for camelCase keys or for an envelope of the form
+// {"data": ..., "meta": {...}, "errors": [...]}.
+//
+// The format of a request comes from the configured default, overridden per API
+// version, overridden per client by a request header such as
+//
+//	X-Response-Format: camelCase, envelope
+//
+// Key order is preserved, and the keys of user-defined data, such as CI
+// attributes, are left as written.
+package jsoncompat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"connect/internal/apiversion"
+)
+
+// Key naming styles
+const (
+	NamingSnake = "snake_case"
+	NamingCamel = "camelCase"
+)
+
+// DefaultHeader is the request header clients select a format with
+const DefaultHeader = "X-Response-Format"
+
+// DefaultPreserveKeys are the keys whose object values are user data
+var DefaultPreserveKeys = []string{"attributes"}
+
+// Format is how a JSON response is serialized
+type Format struct {
+	Naming   string
+	Envelope bool
+}
+
+// native reports whether the format is the one handlers write
+func (f Format) native() bool {
+	return f.Naming != NamingCamel && !f.Envelope
+}
+
+// String returns the format as written in the header, e.g. "camelCase, envelope"
+func (f Format) String() string {
+	naming, shape := NamingSnake, "bare"
+	if f.Naming == NamingCamel {
+		naming = NamingCamel
+	}
+	if f.Envelope {
+		shape = "envelope"
+	}
+	return naming + ", " + shape
+}
+
+// ParseNaming parses a naming style; "" is the native snake_case
+func ParseNaming(naming string) (string, error) {
+	switch strings.ToLower(naming) {
+	case "", "snake", "snake_case":
+		return NamingSnake, nil
+	case "camel", "camelcase":
+		return NamingCamel, nil
+	default:
+		return "", fmt.Errorf("unknown naming %q, expected %s or %s", naming, NamingSnake, NamingCamel)
+	}
+}
+
+// Config selects the format of responses
+type Config struct {
+	Default  Format
+	Versions map[string]Format // API version name -> format
+	// Header is the request header clients override the format with; empty
+	// ignores client preferences
+	Header string
+	// PreserveKeys are the keys whose object values keep their keys as written
+	PreserveKeys []string
+}
+
+// Formatter rewrites responses into the selected format
+type Formatter struct {
+	cfg      Config
+	preserve map[string]bool
+}
+
+// NewFormatter creates a new formatter
+func NewFormatter(cfg Config) *Formatter {
+	preserve := map[string]bool{}
+	for _, key := range cfg.PreserveKeys {
+		preserve[key] = true
+	}
+	return &Formatter{cfg: cfg, preserve: preserve}
+}
+
+// Select returns the format of the response to a request: the version's format
+// or the default, with the parts named in the request header overridden
+func (f *Formatter) Select(r *http.Request) (Format, error) {
+	format := f.cfg.Default
+	if versionFormat, ok := f.cfg.Versions[apiversion.Name(r.URL.Path)]; ok {
+		format = versionFormat
+	}
+	if f.cfg.Header == "" {
+		return format, nil
+	}
+
+	value := r.Header.Get(f.cfg.Header)
+	tokens := strings.FieldsFunc(value, func(c rune) bool { return c == ',' || c == ';' || c == ' ' })
+	for _, token := range tokens {
+		switch strings.ToLower(token) {
+		case "envelope":
+			format.Envelope = true
+		case "bare":
+			format.Envelope = false
+		default:
+			naming, err := ParseNaming(token)
+			if err != nil {
+				return Format{}, fmt.Errorf("unknown response format %q, expected %s, %s, envelope or bare", token, NamingSnake, NamingCamel)
+			}
+			format.Naming = naming
+		}
+	}
+	return format, nil
+}
+
+// Middleware serializes JSON responses in the selected format and names the
+// format in the response header. Other responses pass through unchanged.
+func (f *Formatter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format, err := f.Select(r)
+		if err != nil {
+			response, _ := json.Marshal(map[string]interface{}{
+				"error":   "Invalid " + f.cfg.Header + " header",
+				"success": false,
+				"details": err.Error(),
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(response)
+			return
+		}
+
+		if f.cfg.Header != "" {
+			w.Header().Add("Vary", f.cfg.Header)
+			w.Header().Set(f.cfg.Header, format.String())
+		}
+		if format.native() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		capture := &bufferingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(capture, r)
+		if !capture.buffer {
+			return
+		}
+
+		body := capture.body.Bytes()
+		if rewritten, err := f.Transform(body, capture.status, format); err == nil {
+			body = rewritten
+		}
+		w.Header().Del("Content-Length")
+		w.WriteHeader(capture.status)
+		w.Write(body)
+	})
+}
+
+// Transform rewrites a native JSON body, sent with the status, into the format
+func (f *Formatter) Transform(body []byte, status int, format Format) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return body, nil
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("response body is not valid JSON")
+	}
+	if format.Envelope {
+		var err error
+		if body, err = envelope(body, status); err != nil {
+			return nil, err
+		}
+	}
+	if format.Naming != NamingCamel {
+		return body, nil
+	}
+
+	var out bytes.Buffer
+	if err := f.camelCase(&out, body); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// member is a key and value of a JSON object, which are kept in order
+type member struct {
+	key   string
+	value json.RawMessage
+}
+
+// decodeObject returns the members of a JSON object, or false when the value
+// is not an object
+func decodeObject(value []byte) ([]member, bool, error) {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || value[0] != '{' {
+		return nil, false, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(value))
+	if _, err := dec.Token(); err != nil {
+		return nil, false, err
+	}
+	var members []member
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, false, err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, false, err
+		}
+		members = append(members, member{key: token.(string), value: raw})
+	}
+	return members, true, nil
+}
+
+// writeObject writes members as a JSON object
+func writeObject(out *bytes.Buffer, members []member) {
+	out.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(m.key)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(m.value)
+	}
+	out.WriteByte('}')
+}
+
+// envelope wraps a native body in {data, meta, errors}. A body with a data
+// member, as v2 lists have, contributes its other members to meta. Error
+// bodies, {"error": "message", "details": ...} in v1 and {"error": {...}} in
+// v2, become the single entry of errors. The v1 success flag is dropped, as
+// errors carries it.
+func envelope(body []byte, status int) ([]byte, error) {
+	members, isObject, err := decodeObject(body)
+	if err != nil {
+		return nil, err
+	}
+	var rest []member
+	for _, m := range members {
+		if m.key != "success" {
+			rest = append(rest, m)
+		}
+	}
+
+	data := json.RawMessage("null")
+	meta := []member{}
+	errs := []json.RawMessage{}
+
+	switch {
+	case status >= http.StatusBadRequest:
+		errs = append(errs, errorEntry(body, status, rest))
+	case !isObject:
+		data = bytes.TrimSpace(body)
+	default:
+		var out bytes.Buffer
+		writeObject(&out, rest)
+		data = out.Bytes()
+		for i, m := range rest {
+			if m.key == "data" {
+				data = m.value
+				meta = append(append(meta, rest[:i]...), rest[i+1:]...)
+				break
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(`{"data":`)
+	out.Write(data)
+	out.WriteString(`,"meta":`)
+	writeObject(&out, meta)
+	out.WriteString(`,"errors":[`)
+	for i, e := range errs {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(e)
+	}
+	out.WriteString("]}")
+	return out.Bytes(), nil
+}
+
+// errorEntry turns a native error body into an entry of errors
+func errorEntry(body []byte, status int, members []member) json.RawMessage {
+	for i, m := range members {
+		if m.key != "error" {
+			continue
+		}
+		if _, nested, _ := decodeObject(m.value); nested {
+			return m.value
+		}
+		entry := append([]member{{key: "message", value: m.value}}, members[:i]...)
+		entry = append(entry, members[i+1:]...)
+		var out bytes.Buffer
+		writeObject(&out, entry)
+		return out.Bytes()
+	}
+
+	// Other bodies are kept whole as the details of the status
+	message, _ := json.Marshal(http.StatusText(status))
+	var out bytes.Buffer
+	writeObject(&out, []member{{key: "message", value: message}, {key: "details", value: bytes.TrimSpace(body)}})
+	return out.Bytes()
+}
+
+// camelCase writes value with the keys of its objects in camelCase
+func (f *Formatter) camelCase(out *bytes.Buffer, value []byte) error {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		return nil
+	}
+
+	switch value[0] {
+	case '{':
+		members, _, err := decodeObject(value)
+		if err != nil {
+			return err
+		}
+		out.WriteByte('{')
+		for i, m := range members {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			key, _ := json.Marshal(CamelCase(m.key))
+			out.Write(key)
+			out.WriteByte(':')
+			if _, isObject, _ := decodeObject(m.value); isObject && f.preserve[m.key] {
+				if err := json.Compact(out, m.value); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := f.camelCase(out, m.value); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case '[':
+		var elements []json.RawMessage
+		if err := json.Unmarshal(value, &elements); err != nil {
+			return err
+		}
+		out.WriteByte('[')
+		for i, element := range elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := f.camelCase(out, element); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	default:
+		return json.Compact(out, value)
+	}
+	return nil
+}
+
+// CamelCase converts a snake_case key to camelCase, e.g. "total_count" to
+// "totalCount". Leading underscores are kept.
+func CamelCase(key string) string {
+	if !strings.Contains(strings.TrimLeft(key, "_"), "_") {
+		return key
+	}
+
+	trimmed := strings.TrimLeft(key, "_")
+	var b strings.Builder
+	b.WriteString(key[:len(key)-len(trimmed)])
+	for i, part := range strings.Split(trimmed, "_") {
+		if i == 0 || part == "" {
+			b.WriteString(part)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// bufferingWriter holds JSON responses back for rewriting and passes other
+// responses through
+type bufferingWriter struct {
+	http.ResponseWriter
+	status  int
+	decided bool
+	buffer  bool
+	body    bytes.Buffer
+}
+
+func (w *bufferingWriter) WriteHeader(status int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.status = status
+	w.buffer = isJSON(w.Header().Get("Content-Type"))
+	if !w.buffer {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *bufferingWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffer {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes responses that are passed through, so streams keep streaming
+func (w *bufferingWriter) Flush() {
+	if w.buffer {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// lift the write deadline of event streams
+func (w *bufferingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isJSON reports whether a content type is JSON
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,193 @@
+package jsoncompat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFormatter() *Formatter {
+	return NewFormatter(Config{
+		Default:      Format{Naming: NamingSnake},
+		Versions:     map[string]Format{"v2": {Naming: NamingCamel}},
+		Header:       DefaultHeader,
+		PreserveKeys: DefaultPreserveKeys,
+	})
+}
+
+func TestCamelCase(t *testing.T) {
+	assert.Equal(t, "totalCount", CamelCase("total_count"))
+	assert.Equal(t, "primaryRelationshipId", CamelCase("primary_relationship_id"))
+	assert.Equal(t, "name", CamelCase("name"))
+	assert.Equal(t, "_links", CamelCase("_links"))
+	assert.Equal(t, "_ciId", CamelCase("_ci_id"))
+	assert.Equal(t, "alreadyCamel", CamelCase("alreadyCamel"))
+}
+
+func TestSelect(t *testing.T) {
+	f := newTestFormatter()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/cis", nil)
+	format, err := f.Select(r)
+	require.NoError(t, err)
+	assert.Equal(t, Format{Naming: NamingSnake}, format)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/v2/cis", nil)
+	format, err = f.Select(r)
+	require.NoError(t, err)
+	assert.Equal(t, Format{Naming: NamingCamel}, format)
+
+	// The header overrides only the parts it names
+	r.Header.Set(DefaultHeader, "envelope")
+	format, err = f.Select(r)
+	require.NoError(t, err)
+	assert.Equal(t, Format{Naming: NamingCamel, Envelope: true}, format)
+
+	r.Header.Set(DefaultHeader, "snake_case; bare")
+	format, err = f.Select(r)
+	require.NoError(t, err)
+	assert.Equal(t, Format{Naming: NamingSnake}, format)
+
+	r.Header.Set(DefaultHeader, "kebab-case")
+	_, err = f.Select(r)
+	assert.Error(t, err)
+
+	// Without a header, client preferences are ignored
+	f = NewFormatter(Config{Default: Format{Naming: NamingSnake}})
+	format, err = f.Select(r)
+	require.NoError(t, err)
+	assert.Equal(t, Format{Naming: NamingSnake}, format)
+}
+
+func TestTransformCamelCasePreservesOrderAndAttributes(t *testing.T) {
+	f := newTestFormatter()
+	body := `{"ci_id":"a","total_count":2,"attributes":{"os_version":"12"},"items":[{"created_at":"x","attributes":[{"default_value":1}]}]}`
+
+	out, err := f.Transform([]byte(body), http.StatusOK, Format{Naming: NamingCamel})
+	require.NoError(t, err)
+	assert.Equal(t, `{"ciId":"a","totalCount":2,"attributes":{"os_version":"12"},"items":[{"createdAt":"x","attributes":[{"defaultValue":1}]}]}`, string(out))
+}
+
+func TestTransformEnvelope(t *testing.T) {
+	f := newTestFormatter()
+	envelope := Format{Naming: NamingSnake, Envelope: true}
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{
+			name:   "object",
+			status: http.StatusOK,
+			body:   `{"id":"a","name":"web-01"}`,
+			want:   `{"data":{"id":"a","name":"web-01"},"meta":{},"errors":[]}`,
+		},
+		{
+			name:   "array",
+			status: http.StatusOK,
+			body:   `[1,2]`,
+			want:   `{"data":[1,2],"meta":{},"errors":[]}`,
+		},
+		{
+			name:   "data member with pagination",
+			status: http.StatusOK,
+			body:   `{"data":[{"id":"a"}],"pagination":{"page":1}}`,
+			want:   `{"data":[{"id":"a"}],"meta":{"pagination":{"page":1}},"errors":[]}`,
+		},
+		{
+			name:   "v1 success flag",
+			status: http.StatusOK,
+			body:   `{"message":"deleted","success":true}`,
+			want:   `{"data":{"message":"deleted"},"meta":{},"errors":[]}`,
+		},
+		{
+			name:   "v1 error",
+			status: http.StatusConflict,
+			body:   `{"error":"Already primary","primary_relationship_id":"b","success":false,"details":"conflict"}`,
+			want:   `{"data":null,"meta":{},"errors":[{"message":"Already primary","primary_relationship_id":"b","details":"conflict"}]}`,
+		},
+		{
+			name:   "v2 error",
+			status: http.StatusNotFound,
+			body:   `{"error":{"code":"not_found","message":"CI not found"}}`,
+			want:   `{"data":null,"meta":{},"errors":[{"code":"not_found","message":"CI not found"}]}`,
+		},
+		{
+			name:   "other error",
+			status: http.StatusBadGateway,
+			body:   `"upstream failed"`,
+			want:   `{"data":null,"meta":{},"errors":[{"message":"Bad Gateway","details":"upstream failed"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := f.Transform([]byte(tt.body), tt.status, envelope)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(out))
+		})
+	}
+
+	out, err := f.Transform([]byte(`{"error":"Bad id","success":false}`), http.StatusBadRequest, Format{Naming: NamingCamel, Envelope: true})
+	require.NoError(t, err)
+	assert.Equal(t, `{"data":null,"meta":{},"errors":[{"message":"Bad id"}]}`, string(out))
+}
+
+func TestMiddleware(t *testing.T) {
+	f := newTestFormatter()
+	handler := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("conx_inventory_cis 1\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ci_type":"server"}`))
+	}))
+
+	t.Run("version default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v2/cis", nil))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"ciType":"server"}`, rec.Body.String())
+		assert.Equal(t, "camelCase, bare", rec.Header().Get(DefaultHeader))
+	})
+
+	t.Run("native format passes through", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/cis", nil))
+		assert.Equal(t, `{"ci_type":"server"}`, rec.Body.String())
+		assert.Equal(t, "snake_case, bare", rec.Header().Get(DefaultHeader))
+	})
+
+	t.Run("client header", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/cis", nil)
+		r.Header.Set(DefaultHeader, "envelope")
+		handler.ServeHTTP(rec, r)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"data":{"ci_type":"server"},"meta":{},"errors":[]}`, rec.Body.String())
+		assert.Equal(t, DefaultHeader, rec.Header().Get("Vary"))
+	})
+
+	t.Run("non-JSON responses are untouched", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Header.Set(DefaultHeader, "camelCase, envelope")
+		handler.ServeHTTP(rec, r)
+		assert.Equal(t, "conx_inventory_cis 1\n", rec.Body.String())
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/cis", nil)
+		r.Header.Set(DefaultHeader, "xml")
+		handler.ServeHTTP(rec, r)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,106 @@
+// Package logtuning holds the logging settings operators change at runtime to
+// diagnose production latency without a redeploy: the threshold above which
+// repository queries are logged as slow, and the rates at which high-volume
+// debug logs are sampled.
+//
+// Slow queries are logged with their SQL and the types of their parameters,
+// never the values, which may hold personal data or secrets. Settings changed
+// through the admin API are stored, so every instance picks them up within its
+// refresh interval; resetting them restores the configured values.
+package logtuning
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxSlowQueryThreshold bounds the slow query threshold; slower queries are
+// better found with the database's own tooling
+const MaxSlowQueryThreshold = 10 * time.Minute
+
+var ErrInvalidSettings = errors.New("invalid log settings")
+
+// Settings are the logging settings in effect
+type Settings struct {
+	// SlowQueryThresholdMS logs queries running at least this long; 0
+	// disables slow query logging
+	SlowQueryThresholdMS int64 `json:"slow_query_threshold_ms"`
+	// DebugSampleRate is the fraction of high-volume debug logs kept, from 0
+	// to 1, for categories without a rate of their own
+	DebugSampleRate float64 `json:"debug_sample_rate"`
+	// SampleRates overrides DebugSampleRate per log category, e.g. "sync"
+	SampleRates map[string]float64 `json:"sample_rates"`
+	UpdatedBy   string             `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time         `json:"updated_at,omitempty"`
+}
+
+// SlowQueryThreshold returns the slow query threshold, 0 when disabled
+func (s Settings) SlowQueryThreshold() time.Duration {
+	return time.Duration(s.SlowQueryThresholdMS) * time.Millisecond
+}
+
+// SampleRate returns the fraction of the debug logs of a category kept
+func (s Settings) SampleRate(category string) float64 {
+	if rate, ok := s.SampleRates[category]; ok {
+		return rate
+	}
+	return s.DebugSampleRate
+}
+
+// Validate checks the threshold and sample rates are in range
+func (s Settings) Validate() error {
+	if s.SlowQueryThresholdMS < 0 || s.SlowQueryThreshold() > MaxSlowQueryThreshold {
+		return fmt.Errorf("%w: slow_query_threshold_ms must be 0 to %d", ErrInvalidSettings, MaxSlowQueryThreshold.Milliseconds())
+	}
+	if s.DebugSampleRate < 0 || s.DebugSampleRate > 1 {
+		return fmt.Errorf("%w: debug_sample_rate must be 0 to 1", ErrInvalidSettings)
+	}
+	for category, rate := range s.SampleRates {
+		if strings.TrimSpace(category) == "" {
+			return fmt.Errorf("%w: sample rate categories cannot be empty", ErrInvalidSettings)
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%w: sample rate of %s must be 0 to 1", ErrInvalidSettings, category)
+		}
+	}
+	return nil
+}
+
+// clone returns a copy of the settings sharing no map
+func (s Settings) clone() Settings {
+	s.SampleRates = cloneRates(s.SampleRates)
+	return s
+}
+
+func cloneRates(rates map[string]float64) map[string]float64 {
+	cloned := make(map[string]float64, len(rates))
+	for category, rate := range rates {
+		cloned[category] = rate
+	}
+	return cloned
+}
+
+// UpdateRequest changes the settings it sets, leaving the others as they are.
+// SampleRates, when set, replaces every per-category rate.
+type UpdateRequest struct {
+	SlowQueryThresholdMS *int64             `json:"slow_query_threshold_ms,omitempty"`
+	DebugSampleRate      *float64           `json:"debug_sample_rate,omitempty"`
+	SampleRates          map[string]float64 `json:"sample_rates,omitempty"`
+}
+
+// apply returns the settings with the request's changes
+func (r UpdateRequest) apply(settings Settings) Settings {
+	settings = settings.clone()
+	if r.SlowQueryThresholdMS != nil {
+		settings.SlowQueryThresholdMS = *r.SlowQueryThresholdMS
+	}
+	if r.DebugSampleRate != nil {
+		settings.DebugSampleRate = *r.DebugSampleRate
+	}
+	if r.SampleRates != nil {
+		settings.SampleRates = cloneRates(r.SampleRates)
+	}
+	return settings
+}
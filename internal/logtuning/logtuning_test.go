@@ -0,0 +1,169 @@
+package logtuning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// memoryStore holds the stored settings in memory
+type memoryStore struct {
+	settings *Settings
+	err      error
+}
+
+func (m *memoryStore) LoadSettings(ctx context.Context) (*Settings, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.settings == nil {
+		return nil, nil
+	}
+	settings := m.settings.clone()
+	return &settings, nil
+}
+
+func (m *memoryStore) SaveSettings(ctx context.Context, settings *Settings) error {
+	stored := settings.clone()
+	m.settings = &stored
+	return nil
+}
+
+func (m *memoryStore) DeleteSettings(ctx context.Context) error {
+	m.settings = nil
+	return nil
+}
+
+var configured = Settings{SlowQueryThresholdMS: 500, DebugSampleRate: 1}
+
+func newTestService(buf *bytes.Buffer) (*Service, *memoryStore) {
+	store := &memoryStore{}
+	service := NewService(store, zerolog.New(buf), configured)
+	service.now = func() time.Time { return now }
+	return service, store
+}
+
+func TestUpdateAndReset(t *testing.T) {
+	service, store := newTestService(&bytes.Buffer{})
+	ctx := context.Background()
+
+	threshold := int64(100)
+	settings, err := service.Update(ctx, UpdateRequest{
+		SlowQueryThresholdMS: &threshold,
+		SampleRates:          map[string]float64{"sync": 0.1},
+	}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), settings.SlowQueryThresholdMS)
+	assert.Equal(t, 1.0, settings.DebugSampleRate)
+	assert.Equal(t, 0.1, settings.SampleRate("sync"))
+	assert.Equal(t, 1.0, settings.SampleRate("graph"))
+	assert.Equal(t, "admin", settings.UpdatedBy)
+	assert.Equal(t, 100*time.Millisecond, service.Settings().SlowQueryThreshold())
+	assert.Equal(t, int64(100), store.settings.SlowQueryThresholdMS)
+
+	for name, req := range map[string]UpdateRequest{
+		"negative threshold": {SlowQueryThresholdMS: func() *int64 { v := int64(-1); return &v }()},
+		"rate above one":     {DebugSampleRate: func() *float64 { v := 1.5; return &v }()},
+		"negative rate":      {SampleRates: map[string]float64{"sync": -0.1}},
+		"empty category":     {SampleRates: map[string]float64{" ": 0.5}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.Update(ctx, req, "admin")
+			assert.ErrorIs(t, err, ErrInvalidSettings)
+		})
+	}
+	assert.Equal(t, int64(100), service.Settings().SlowQueryThresholdMS)
+
+	settings, err = service.Reset(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, configured.SlowQueryThresholdMS, settings.SlowQueryThresholdMS)
+	assert.Nil(t, store.settings)
+}
+
+func TestReloadPicksUpStoredSettings(t *testing.T) {
+	service, store := newTestService(&bytes.Buffer{})
+	ctx := context.Background()
+
+	// Settings changed by another instance
+	store.settings = &Settings{SlowQueryThresholdMS: 50, DebugSampleRate: 0.5}
+	require.NoError(t, service.Reload(ctx))
+	assert.Equal(t, int64(50), service.Settings().SlowQueryThresholdMS)
+
+	// A failing store keeps the last known settings
+	store.err = errors.New("connection refused")
+	assert.Error(t, service.Reload(ctx))
+	assert.Equal(t, int64(50), service.Settings().SlowQueryThresholdMS)
+
+	store.err, store.settings = nil, nil
+	require.NoError(t, service.Reload(ctx))
+	assert.Equal(t, configured.SlowQueryThresholdMS, service.Settings().SlowQueryThresholdMS)
+}
+
+func TestSampler(t *testing.T) {
+	service, _ := newTestService(&bytes.Buffer{})
+	ctx := context.Background()
+	sampler := service.Sampler("sync")
+
+	assert.True(t, sampler.Sample(zerolog.DebugLevel))
+
+	_, err := service.Update(ctx, UpdateRequest{SampleRates: map[string]float64{"sync": 0.25}}, "admin")
+	require.NoError(t, err)
+	service.random = func() float64 { return 0.2 }
+	assert.True(t, sampler.Sample(zerolog.DebugLevel))
+	service.random = func() float64 { return 0.3 }
+	assert.False(t, sampler.Sample(zerolog.DebugLevel))
+	assert.True(t, service.Sampler("graph").Sample(zerolog.DebugLevel))
+
+	// Only debug logs are sampled
+	_, err = service.Update(ctx, UpdateRequest{SampleRates: map[string]float64{"sync": 0}}, "admin")
+	require.NoError(t, err)
+	assert.False(t, sampler.Sample(zerolog.DebugLevel))
+	assert.True(t, sampler.Sample(zerolog.InfoLevel))
+	assert.True(t, sampler.Sample(zerolog.ErrorLevel))
+}
+
+func TestSlowQueriesAreLoggedRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	service, _ := newTestService(&buf)
+	tracer := service.QueryTracer()
+	sql := "SELECT *\n\t\tFROM users WHERE email = $1 AND id = $2 AND deleted_at IS $3"
+	args := []interface{}{"jane@example.com", uuid.New(), nil}
+
+	trace := func(elapsed time.Duration) {
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql, Args: args})
+		service.now = func() time.Time { return now.Add(elapsed) }
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+		service.now = func() time.Time { return now }
+	}
+
+	trace(100 * time.Millisecond)
+	assert.Empty(t, buf.String())
+
+	trace(600 * time.Millisecond)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "Slow query", entry["message"])
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1 AND id = $2 AND deleted_at IS $3", entry["sql"])
+	assert.Equal(t, []interface{}{"$1 string", "$2 uuid.UUID", "$3 null"}, entry["params"])
+	assert.Equal(t, float64(500), entry["threshold_ms"])
+	assert.NotContains(t, buf.String(), "jane@example.com")
+
+	// A threshold of 0 disables slow query logging
+	buf.Reset()
+	disabled := int64(0)
+	_, err := service.Update(context.Background(), UpdateRequest{SlowQueryThresholdMS: &disabled}, "admin")
+	require.NoError(t, err)
+	trace(time.Hour)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,178 @@
+package logtuning
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultRefreshInterval bounds how stale settings changed on another
+// instance may be
+const DefaultRefreshInterval = 30 * time.Second
+
+// maxLoggedSQLLength truncates the SQL of slow queries in logs
+const maxLoggedSQLLength = 2000
+
+// Service keeps the settings in effect on this instance. Reading them never
+// touches the store, so sampling and timing queries stay cheap.
+type Service struct {
+	store      Store
+	logger     zerolog.Logger
+	configured Settings
+
+	current atomic.Pointer[Settings]
+	random  func() float64
+	now     func() time.Time
+}
+
+// NewService creates a new log tuning service. configured holds the settings
+// from configuration; stored settings override them.
+func NewService(store Store, logger zerolog.Logger, configured Settings) *Service {
+	configured = configured.clone()
+	s := &Service{
+		store:      store,
+		logger:     logger,
+		configured: configured,
+		random:     rand.Float64,
+		now:        time.Now,
+	}
+	s.current.Store(&configured)
+	return s
+}
+
+// Settings returns the settings in effect
+func (s *Service) Settings() Settings {
+	return s.current.Load().clone()
+}
+
+// Configured returns the settings from configuration
+func (s *Service) Configured() Settings {
+	return s.configured.clone()
+}
+
+// Update changes settings, storing them for every instance and applying them
+// immediately on this one
+func (s *Service) Update(ctx context.Context, req UpdateRequest, by string) (Settings, error) {
+	settings := req.apply(s.Settings())
+	if err := settings.Validate(); err != nil {
+		return Settings{}, err
+	}
+
+	now := s.now()
+	settings.UpdatedBy, settings.UpdatedAt = by, &now
+	if err := s.store.SaveSettings(ctx, &settings); err != nil {
+		return Settings{}, err
+	}
+	s.current.Store(&settings)
+
+	log.Printf("Log settings changed by %s: slow query threshold %dms, debug sample rate %g, sample rates %v",
+		by, settings.SlowQueryThresholdMS, settings.DebugSampleRate, settings.SampleRates)
+	return settings.clone(), nil
+}
+
+// Reset removes the stored settings so the configured ones apply again
+func (s *Service) Reset(ctx context.Context) (Settings, error) {
+	if err := s.store.DeleteSettings(ctx); err != nil {
+		return Settings{}, err
+	}
+	configured := s.configured.clone()
+	s.current.Store(&configured)
+	return configured.clone(), nil
+}
+
+// Reload applies the stored settings, or the configured ones when none are
+// stored
+func (s *Service) Reload(ctx context.Context) error {
+	stored, err := s.store.LoadSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings := s.configured.clone()
+	if stored != nil {
+		settings = stored.clone()
+	}
+	s.current.Store(&settings)
+	return nil
+}
+
+// Run reloads the settings every interval, so changes made on other instances
+// apply here too. If the store cannot be read the last known settings are kept.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	if err := s.Reload(ctx); err != nil {
+		log.Printf("Failed to load log settings, keeping configured settings: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				log.Printf("Failed to reload log settings, keeping last known settings: %v", err)
+			}
+		}
+	}
+}
+
+// Sampler returns a sampler keeping the debug logs of a category at its
+// sample rate. Logs above debug level are always kept.
+func (s *Service) Sampler(category string) zerolog.Sampler {
+	return &sampler{service: s, category: category}
+}
+
+type sampler struct {
+	service  *Service
+	category string
+}
+
+// Sample implements zerolog.Sampler
+func (s *sampler) Sample(level zerolog.Level) bool {
+	if level > zerolog.DebugLevel {
+		return true
+	}
+	rate := s.service.current.Load().SampleRate(s.category)
+	if rate >= 1 {
+		return true
+	}
+	return rate > 0 && s.service.random() < rate
+}
+
+// ObserveQuery logs a query that ran for at least the slow query threshold.
+// Its parameters are logged by type only.
+func (s *Service) ObserveQuery(sql string, args []interface{}, elapsed time.Duration, err error) {
+	threshold := s.current.Load().SlowQueryThreshold()
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+
+	event := s.logger.Warn().
+		Str("sql", compactSQL(sql)).
+		Strs("params", redactParams(args)).
+		Dur("duration", elapsed).
+		Int64("threshold_ms", threshold.Milliseconds())
+	if err != nil {
+		event = event.Err(err)
+	}
+	event.Msg("Slow query")
+}
+
+// compactSQL collapses the whitespace of a query and truncates long ones
+func compactSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxLoggedSQLLength {
+		sql = sql[:maxLoggedSQLLength] + "..."
+	}
+	return sql
+}
@@ -0,0 +1,91 @@
+package logtuning
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Store persists the settings changed at runtime
+type Store interface {
+	// LoadSettings retrieves the stored settings, or nil if there are none
+	LoadSettings(ctx context.Context) (*Settings, error)
+	SaveSettings(ctx context.Context, settings *Settings) error
+	DeleteSettings(ctx context.Context) error
+}
+
+// PostgresStore keeps the settings in the single row of the log_settings table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed settings store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// LoadSettings retrieves the stored settings
+func (s *PostgresStore) LoadSettings(ctx context.Context) (*Settings, error) {
+	var row struct {
+		SlowQueryThresholdMS int64     `db:"slow_query_threshold_ms"`
+		DebugSampleRate      float64   `db:"debug_sample_rate"`
+		SampleRates          []byte    `db:"sample_rates"`
+		UpdatedBy            string    `db:"updated_by"`
+		UpdatedAt            time.Time `db:"updated_at"`
+	}
+	err := s.db.GetContext(ctx, &row, `
+		SELECT slow_query_threshold_ms, debug_sample_rate, sample_rates, updated_by, updated_at
+		FROM log_settings WHERE id = 1`)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load log settings: %w", err)
+	}
+
+	settings := &Settings{
+		SlowQueryThresholdMS: row.SlowQueryThresholdMS,
+		DebugSampleRate:      row.DebugSampleRate,
+		UpdatedBy:            row.UpdatedBy,
+		UpdatedAt:            &row.UpdatedAt,
+	}
+	if err := json.Unmarshal(row.SampleRates, &settings.SampleRates); err != nil {
+		return nil, fmt.Errorf("failed to decode log sample rates: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveSettings stores the settings, replacing those stored before
+func (s *PostgresStore) SaveSettings(ctx context.Context, settings *Settings) error {
+	rates, err := json.Marshal(cloneRates(settings.SampleRates))
+	if err != nil {
+		return fmt.Errorf("failed to encode log sample rates: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO log_settings (id, slow_query_threshold_ms, debug_sample_rate, sample_rates, updated_by, updated_at)
+		VALUES (1, $1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET
+			slow_query_threshold_ms = EXCLUDED.slow_query_threshold_ms,
+			debug_sample_rate = EXCLUDED.debug_sample_rate,
+			sample_rates = EXCLUDED.sample_rates,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at`,
+		settings.SlowQueryThresholdMS, settings.DebugSampleRate, rates, settings.UpdatedBy, settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save log settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteSettings removes the stored settings
+func (s *PostgresStore) DeleteSettings(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM log_settings WHERE id = 1`); err != nil {
+		return fmt.Errorf("failed to delete log settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package logtuning
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryTracer times the queries of a pgx connection pool, logging the slow
+// ones. Set it as the Tracer of the pool's connection config.
+type QueryTracer struct {
+	service *Service
+}
+
+// QueryTracer returns a tracer logging slow queries at the threshold in effect
+func (s *Service) QueryTracer() *QueryTracer {
+	return &QueryTracer{service: s}
+}
+
+type queryContextKey struct{}
+
+// tracedQuery is a query being timed
+type tracedQuery struct {
+	sql     string
+	args    []interface{}
+	started time.Time
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if t.service.current.Load().SlowQueryThreshold() <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryContextKey{}, tracedQuery{sql: data.SQL, args: data.Args, started: t.service.now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(queryContextKey{}).(tracedQuery)
+	if !ok {
+		return
+	}
+	t.service.ObserveQuery(query.sql, query.args, t.service.now().Sub(query.started), data.Err)
+}
+
+// redactParams describes query parameters by their type, e.g. "$1 string",
+// so slow queries can be reproduced without logging personal data or secrets
+func redactParams(args []interface{}) []string {
+	params := make([]string, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			params[i] = fmt.Sprintf("$%d null", i+1)
+			continue
+		}
+		params[i] = fmt.Sprintf("$%d %T", i+1, arg)
+	}
+	return params
+}
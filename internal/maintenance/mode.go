@@ -0,0 +1,88 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultRetryAfter is suggested to clients when no retry interval is configured
+const DefaultRetryAfter = 5 * time.Minute
+
+// Mode is the persisted read-only maintenance state
+type Mode struct {
+	ReadOnly          bool       `json:"read_only" db:"read_only"`
+	Reason            string     `json:"reason" db:"reason"`
+	RetryAfterSeconds int        `json:"retry_after_seconds" db:"retry_after_seconds"`
+	ExpectedEnd       *time.Time `json:"expected_end,omitempty" db:"expected_end"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	UpdatedBy         string     `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// RetryAfter returns how long clients should wait before retrying a rejected write
+func (m Mode) RetryAfter(now time.Time) time.Duration {
+	if m.ExpectedEnd != nil && m.ExpectedEnd.After(now) {
+		return m.ExpectedEnd.Sub(now).Round(time.Second)
+	}
+	if m.RetryAfterSeconds > 0 {
+		return time.Duration(m.RetryAfterSeconds) * time.Second
+	}
+	return DefaultRetryAfter
+}
+
+// Store persists the maintenance mode
+type Store interface {
+	GetMode(ctx context.Context) (*Mode, error)
+	SaveMode(ctx context.Context, mode *Mode) error
+}
+
+// PostgresStore keeps the maintenance mode in the single row maintenance_mode table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed maintenance store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// GetMode retrieves the current maintenance mode
+func (s *PostgresStore) GetMode(ctx context.Context) (*Mode, error) {
+	query := `
+		SELECT read_only, reason, retry_after_seconds, expected_end, updated_at, COALESCE(updated_by, '') AS updated_by
+		FROM maintenance_mode
+		WHERE id = 1`
+
+	var mode Mode
+	if err := s.db.GetContext(ctx, &mode, query); err != nil {
+		if err == sql.ErrNoRows {
+			return &Mode{}, nil
+		}
+		return nil, fmt.Errorf("failed to get maintenance mode: %w", err)
+	}
+
+	return &mode, nil
+}
+
+// SaveMode stores the maintenance mode
+func (s *PostgresStore) SaveMode(ctx context.Context, mode *Mode) error {
+	query := `
+		INSERT INTO maintenance_mode (id, read_only, reason, retry_after_seconds, expected_end, updated_at, updated_by)
+		VALUES (1, $1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (id) DO UPDATE SET
+			read_only = EXCLUDED.read_only,
+			reason = EXCLUDED.reason,
+			retry_after_seconds = EXCLUDED.retry_after_seconds,
+			expected_end = EXCLUDED.expected_end,
+			updated_at = EXCLUDED.updated_at,
+			updated_by = EXCLUDED.updated_by`
+
+	if _, err := s.db.ExecContext(ctx, query, mode.ReadOnly, mode.Reason, mode.RetryAfterSeconds, mode.ExpectedEnd, mode.UpdatedAt, mode.UpdatedBy); err != nil {
+		return fmt.Errorf("failed to save maintenance mode: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval bounds how stale the in-memory mode may be on other instances
+const DefaultRefreshInterval = 5 * time.Second
+
+// AdminPath is the maintenance API, which stays writable so read-only mode can be turned off
+const AdminPath = "/api/v1/admin/maintenance"
+
+// Service caches the persisted maintenance mode and enforces it on requests
+type Service struct {
+	store           Store
+	refreshInterval time.Duration
+	exemptPaths     []string
+
+	mu       sync.RWMutex
+	mode     Mode
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+// NewService creates a new maintenance service. Requests whose path starts with
+// one of exemptPaths are never rejected; the maintenance API itself is always exempt.
+func NewService(store Store, refreshInterval time.Duration, exemptPaths []string) *Service {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	return &Service{
+		store:           store,
+		refreshInterval: refreshInterval,
+		exemptPaths:     append([]string{AdminPath}, exemptPaths...),
+		now:             time.Now,
+	}
+}
+
+// Current returns the maintenance mode, reloading it from the store when the cached copy is stale.
+// If the store cannot be read the last known mode is kept.
+func (s *Service) Current(ctx context.Context) Mode {
+	s.mu.RLock()
+	mode, loadedAt := s.mode, s.loadedAt
+	s.mu.RUnlock()
+
+	if !loadedAt.IsZero() && s.now().Sub(loadedAt) < s.refreshInterval {
+		return mode
+	}
+
+	stored, err := s.store.GetMode(ctx)
+	if err != nil {
+		log.Printf("Failed to load maintenance mode, keeping last known state: %v", err)
+		return mode
+	}
+
+	s.mu.Lock()
+	s.mode = *stored
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+
+	return *stored
+}
+
+// Set persists a new maintenance mode and applies it immediately on this instance
+func (s *Service) Set(ctx context.Context, mode Mode) (Mode, error) {
+	mode.UpdatedAt = s.now()
+	if err := s.store.SaveMode(ctx, &mode); err != nil {
+		return Mode{}, err
+	}
+
+	s.mu.Lock()
+	s.mode = mode
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+
+	if mode.ReadOnly {
+		log.Printf("Maintenance read-only mode enabled by %s: %s", mode.UpdatedBy, mode.Reason)
+	} else {
+		log.Printf("Maintenance read-only mode disabled by %s", mode.UpdatedBy)
+	}
+
+	return mode, nil
+}
+
+// Middleware rejects mutating requests with 503 and Retry-After while read-only mode is on
+func (s *Service) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isReadRequest(r) || s.isExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mode := s.Current(r.Context())
+		if !mode.ReadOnly {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		retryAfter := mode.RetryAfter(s.now())
+		response, _ := json.Marshal(map[string]interface{}{
+			"error":       "Service is in read-only maintenance mode",
+			"success":     false,
+			"details":     mode.Reason,
+			"retry_after": int(retryAfter.Seconds()),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(response)
+	})
+}
+
+// isExempt reports whether a path may be written during maintenance
+func (s *Service) isExempt(path string) bool {
+	for _, prefix := range s.exemptPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadRequest reports whether a request cannot modify data
+func isReadRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mode  Mode
+	reads int
+	err   error
+}
+
+func (m *memoryStore) GetMode(ctx context.Context) (*Mode, error) {
+	m.reads++
+	if m.err != nil {
+		return nil, m.err
+	}
+	mode := m.mode
+	return &mode, nil
+}
+
+func (m *memoryStore) SaveMode(ctx context.Context, mode *Mode) error {
+	m.mode = *mode
+	return nil
+}
+
+func TestMode_RetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := now.Add(90 * time.Second)
+
+	assert.Equal(t, DefaultRetryAfter, Mode{}.RetryAfter(now))
+	assert.Equal(t, 2*time.Minute, Mode{RetryAfterSeconds: 120}.RetryAfter(now))
+	assert.Equal(t, 90*time.Second, Mode{RetryAfterSeconds: 120, ExpectedEnd: &end}.RetryAfter(now))
+}
+
+func TestService_MiddlewareRejectsWritesOnly(t *testing.T) {
+	store := &memoryStore{}
+	service := NewService(store, time.Minute, []string{"/api/v1/auth/login"})
+	_, err := service.Set(context.Background(), Mode{ReadOnly: true, Reason: "Neo4j upgrade", RetryAfterSeconds: 600})
+	require.NoError(t, err)
+
+	handler := service.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/api/v1/cis", http.StatusOK},
+		{http.MethodPost, "/api/v1/cis", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/v1/cis/123", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/auth/login", http.StatusOK},
+		{http.MethodPut, AdminPath, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.status, rec.Code, "%s %s", tt.method, tt.path)
+		if tt.status == http.StatusServiceUnavailable {
+			assert.Equal(t, "600", rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestService_CurrentRefreshesAndSurvivesStoreErrors(t *testing.T) {
+	store := &memoryStore{mode: Mode{ReadOnly: true}}
+	service := NewService(store, time.Minute, nil)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	assert.True(t, service.Current(context.Background()).ReadOnly)
+	assert.True(t, service.Current(context.Background()).ReadOnly)
+	assert.Equal(t, 1, store.reads)
+
+	// Another instance turned maintenance off
+	store.mode = Mode{}
+	now = now.Add(2 * time.Minute)
+	assert.False(t, service.Current(context.Background()).ReadOnly)
+
+	// A failing store keeps the last known state
+	store.err = errors.New("connection refused")
+	now = now.Add(2 * time.Minute)
+	assert.False(t, service.Current(context.Background()).ReadOnly)
+}
@@ -0,0 +1,237 @@
+// Package manifest applies declarative desired-state manifests describing CIs,
+// relationships and schemas, so critical CMDB entries can be managed from git.
+//
+// A manifest is planned against the current state first: each entry it
+// declares is created, updated or left unchanged, and with pruning the
+// entities it created before but no longer declares are deleted. Applying the
+// same manifest twice changes nothing. CIs are identified by type and name,
+// relationships by their endpoints and type, and schemas by name.
+//
+// The fields an entry declares are enforced and the ones it omits left alone,
+// including attributes, so discovery can keep enriching managed CIs. Entities
+// a manifest creates or adopts are marked with its name in the
+// ManagedByAttribute attribute; another manifest cannot take them over.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"connect/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion is the manifest format version this package reads
+const APIVersion = "conx/v1"
+
+// ManagedByAttribute is the attribute naming the manifest that manages a CI
+// or relationship
+const ManagedByAttribute = "conx_manifest"
+
+// MaxBytes bounds the size of a manifest
+const MaxBytes = 10 << 20
+
+// MaxEntries bounds the schemas, CIs and relationships a manifest declares
+const MaxEntries = 10000
+
+// maxNameLength bounds the length of a manifest name
+const maxNameLength = 100
+
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// Manifest is the desired state of a set of CIs, relationships and schemas
+type Manifest struct {
+	APIVersion    string             `json:"apiVersion"`
+	Metadata      Metadata           `json:"metadata"`
+	Schemas       Schemas            `json:"schemas"`
+	CIs           []CISpec           `json:"cis"`
+	Relationships []RelationshipSpec `json:"relationships"`
+}
+
+// Metadata identifies a manifest. The name scopes pruning to the entities
+// the manifest manages.
+type Metadata struct {
+	Name string `json:"name"`
+}
+
+// Schemas are the CI and relationship type schemas a manifest declares
+type Schemas struct {
+	CITypes           []SchemaSpec `json:"ci_types"`
+	RelationshipTypes []SchemaSpec `json:"relationship_types"`
+}
+
+// SchemaSpec is the desired state of a CI or relationship type schema
+type SchemaSpec struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Attributes  []models.CITypeAttribute `json:"attributes"`
+}
+
+// CISpec is the desired state of a CI. Empty fields are not managed.
+type CISpec struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Status      string                 `json:"status,omitempty"`
+	Criticality string                 `json:"criticality,omitempty"`
+	Owner       string                 `json:"owner,omitempty"`
+	Location    string                 `json:"location,omitempty"`
+	OrgUnit     string                 `json:"org_unit,omitempty"`
+	CostCenter  string                 `json:"cost_center,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Key identifies the CI as type/name, the form relationships refer to it by
+func (c CISpec) Key() string {
+	return c.Type + "/" + c.Name
+}
+
+// RelationshipSpec is the desired state of a relationship between two CIs,
+// each referred to as type/name. The CIs need not be in the manifest.
+type RelationshipSpec struct {
+	Source      string                 `json:"source"`
+	Target      string                 `json:"target"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Key identifies the relationship by its endpoints and type
+func (r RelationshipSpec) Key() string {
+	return relationshipKey(r.Source, r.Type, r.Target)
+}
+
+func relationshipKey(source, relType, target string) string {
+	return fmt.Sprintf("%s -%s-> %s", source, relType, target)
+}
+
+// Parse reads a manifest in YAML or JSON and validates it. Unknown fields are
+// rejected, so a typo cannot silently leave a field unmanaged.
+func Parse(data []byte) (*Manifest, error) {
+	if len(data) > MaxBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidManifest, MaxBytes)
+	}
+
+	// YAML is decoded generically and re-encoded as JSON, so the JSON field
+	// names of the models apply to both formats
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+
+	var manifest Manifest
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Validate checks the manifest is complete and declares each entry once
+func (m *Manifest) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if m.APIVersion != APIVersion {
+		add("apiVersion must be %s", APIVersion)
+	}
+	if !validName(m.Metadata.Name) {
+		add("metadata.name must be 1 to %d lowercase letters, digits, '-' or '_'", maxNameLength)
+	}
+	entries := len(m.Schemas.CITypes) + len(m.Schemas.RelationshipTypes) + len(m.CIs) + len(m.Relationships)
+	if entries > MaxEntries {
+		add("declares %d entries, more than %d", entries, MaxEntries)
+	}
+
+	for kind, schemas := range map[string][]SchemaSpec{
+		"schemas.ci_types":           m.Schemas.CITypes,
+		"schemas.relationship_types": m.Schemas.RelationshipTypes,
+	} {
+		seen := map[string]bool{}
+		for i, schema := range schemas {
+			switch {
+			case schema.Name == "":
+				add("%s[%d] has no name", kind, i)
+			case seen[schema.Name]:
+				add("%s declares %s twice", kind, schema.Name)
+			}
+			seen[schema.Name] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, ci := range m.CIs {
+		switch {
+		case ci.Type == "" || ci.Name == "":
+			add("cis[%d] needs a type and a name", i)
+			continue
+		case strings.Contains(ci.Type, "/"):
+			add("cis[%d] type %s must not contain '/'", i, ci.Type)
+		case seen[ci.Key()]:
+			add("cis declares %s twice", ci.Key())
+		}
+		if _, ok := ci.Attributes[ManagedByAttribute]; ok {
+			add("cis[%d] must not set the %s attribute", i, ManagedByAttribute)
+		}
+		seen[ci.Key()] = true
+	}
+
+	seen = map[string]bool{}
+	for i, rel := range m.Relationships {
+		_, _, sourceOK := splitRef(rel.Source)
+		_, _, targetOK := splitRef(rel.Target)
+		switch {
+		case !sourceOK || !targetOK:
+			add("relationships[%d] source and target must be type/name", i)
+		case rel.Type == "":
+			add("relationships[%d] has no type", i)
+		case rel.Source == rel.Target:
+			add("relationships[%d] relates %s to itself", i, rel.Source)
+		case seen[rel.Key()]:
+			add("relationships declares %s twice", rel.Key())
+		}
+		if _, ok := rel.Attributes[ManagedByAttribute]; ok {
+			add("relationships[%d] must not set the %s attribute", i, ManagedByAttribute)
+		}
+		seen[rel.Key()] = true
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidManifest, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// splitRef splits a type/name CI reference
+func splitRef(ref string) (ciType, name string, ok bool) {
+	ciType, name, ok = strings.Cut(ref, "/")
+	return ciType, name, ok && ciType != "" && name != ""
+}
+
+// validName reports whether a manifest name is usable as an attribute value
+// that identifies it
+func validName(name string) bool {
+	if name == "" || len(name) > maxNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
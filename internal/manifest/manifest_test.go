@@ -0,0 +1,422 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore holds CIs, relationships and schemas in memory
+type memoryStore struct {
+	ciSchemas  map[string]*models.CITypeSchema
+	relSchemas map[string]*models.RelationshipTypeSchema
+	cis        map[uuid.UUID]*models.CI
+	rels       map[uuid.UUID]*models.CIRelationship
+	failCreate string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		ciSchemas:  map[string]*models.CITypeSchema{},
+		relSchemas: map[string]*models.RelationshipTypeSchema{},
+		cis:        map[uuid.UUID]*models.CI{},
+		rels:       map[uuid.UUID]*models.CIRelationship{},
+	}
+}
+
+func (m *memoryStore) FindCITypeSchema(ctx context.Context, name string) (*models.CITypeSchema, error) {
+	return m.ciSchemas[name], nil
+}
+
+func (m *memoryStore) CreateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error) {
+	m.ciSchemas[schema.Name] = schema
+	return schema, nil
+}
+
+func (m *memoryStore) UpdateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error) {
+	m.ciSchemas[schema.Name] = schema
+	return schema, nil
+}
+
+func (m *memoryStore) FindRelationshipTypeSchema(ctx context.Context, name string) (*models.RelationshipTypeSchema, error) {
+	return m.relSchemas[name], nil
+}
+
+func (m *memoryStore) CreateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error) {
+	m.relSchemas[schema.Name] = schema
+	return schema, nil
+}
+
+func (m *memoryStore) UpdateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error) {
+	m.relSchemas[schema.Name] = schema
+	return schema, nil
+}
+
+func (m *memoryStore) FindCIsByName(ctx context.Context, ciType, name string) ([]*models.CI, error) {
+	var found []*models.CI
+	for _, ci := range m.cis {
+		if ci.Type == ciType && ci.Name == name {
+			found = append(found, ci)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryStore) ListManagedCIs(ctx context.Context, manifest string) ([]*models.CI, error) {
+	var found []*models.CI
+	for _, ci := range m.cis {
+		if managedBy(ci.Attributes) == manifest {
+			found = append(found, ci)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryStore) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	ci, ok := m.cis[id]
+	if !ok {
+		return nil, errors.New("CI not found")
+	}
+	return ci, nil
+}
+
+func (m *memoryStore) CreateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
+	if ci.Name == m.failCreate {
+		return nil, errors.New("connection reset")
+	}
+	m.cis[ci.ID] = ci
+	return ci, nil
+}
+
+func (m *memoryStore) UpdateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
+	m.cis[ci.ID] = ci
+	return ci, nil
+}
+
+func (m *memoryStore) DeleteCI(ctx context.Context, id uuid.UUID) error {
+	delete(m.cis, id)
+	return nil
+}
+
+func (m *memoryStore) FindRelationship(ctx context.Context, sourceID, targetID uuid.UUID, relType string) (*models.CIRelationship, error) {
+	for _, rel := range m.rels {
+		if rel.SourceCIID == sourceID && rel.TargetCIID == targetID && rel.Type == relType {
+			return rel, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) ListManagedRelationships(ctx context.Context, manifest string) ([]*models.CIRelationship, error) {
+	var found []*models.CIRelationship
+	for _, rel := range m.rels {
+		if managedBy(rel.Attributes) == manifest {
+			found = append(found, rel)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryStore) CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error) {
+	m.rels[rel.ID] = rel
+	return rel, nil
+}
+
+func (m *memoryStore) UpdateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error) {
+	m.rels[rel.ID] = rel
+	return rel, nil
+}
+
+func (m *memoryStore) DeleteRelationship(ctx context.Context, id uuid.UUID) error {
+	delete(m.rels, id)
+	return nil
+}
+
+func (m *memoryStore) TransitionRelationshipState(ctx context.Context, id uuid.UUID, state string, changedBy uuid.UUID) (*models.CIRelationship, error) {
+	rel := *m.rels[id]
+	rel.State = state
+	m.rels[id] = &rel
+	return &rel, nil
+}
+
+// addCI stores an existing CI
+func (m *memoryStore) addCI(ciType, name string, attributes map[string]interface{}) *models.CI {
+	encoded, _ := json.Marshal(attributes)
+	ci := &models.CI{ID: uuid.New(), Type: ciType, Name: name, Attributes: encoded}
+	m.cis[ci.ID] = ci
+	return ci
+}
+
+// ci returns the stored CI with a type and name
+func (m *memoryStore) ci(t *testing.T, ciType, name string) *models.CI {
+	found, _ := m.FindCIsByName(context.Background(), ciType, name)
+	require.Len(t, found, 1)
+	return found[0]
+}
+
+func attributesOf(t *testing.T, raw json.RawMessage) map[string]interface{} {
+	var attributes map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &attributes))
+	return attributes
+}
+
+const testManifest = `
+apiVersion: conx/v1
+metadata:
+  name: payments
+schemas:
+  ci_types:
+    - name: service
+      description: A deployable service
+      attributes:
+        - name: port
+          type: number
+          required: true
+cis:
+  - type: service
+    name: checkout
+    owner: payments-team
+    tags: [pci]
+    attributes:
+      port: 8443
+  - type: database
+    name: orders-db
+    criticality: high
+relationships:
+  - source: service/checkout
+    target: database/orders-db
+    type: DEPENDS_ON
+`
+
+func parseTest(t *testing.T, data string) *Manifest {
+	m, err := Parse([]byte(data))
+	require.NoError(t, err)
+	return m
+}
+
+func TestParse(t *testing.T) {
+	m := parseTest(t, testManifest)
+
+	assert.Equal(t, "payments", m.Metadata.Name)
+	require.Len(t, m.CIs, 2)
+	assert.Equal(t, "service/checkout", m.CIs[0].Key())
+	assert.Equal(t, float64(8443), m.CIs[0].Attributes["port"])
+	assert.Equal(t, "number", m.Schemas.CITypes[0].Attributes[0].Type)
+	assert.Equal(t, "service/checkout -DEPENDS_ON-> database/orders-db", m.Relationships[0].Key())
+
+	// JSON is YAML too
+	_, err := Parse([]byte(`{"apiVersion": "conx/v1", "metadata": {"name": "empty"}}`))
+	assert.NoError(t, err)
+}
+
+func TestParseRejectsInvalidManifests(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown field":  "apiVersion: conx/v1\nmetadata: {name: x}\ncis:\n  - type: server\n    name: a\n    ownr: me\n",
+		"wrong version":  "apiVersion: conx/v2\nmetadata: {name: x}\n",
+		"bad name":       "apiVersion: conx/v1\nmetadata: {name: Payments Team}\n",
+		"duplicate CI":   "apiVersion: conx/v1\nmetadata: {name: x}\ncis:\n  - {type: server, name: a}\n  - {type: server, name: a}\n",
+		"bad reference":  "apiVersion: conx/v1\nmetadata: {name: x}\nrelationships:\n  - {source: a, target: server/b, type: RUNS_ON}\n",
+		"managed marker": "apiVersion: conx/v1\nmetadata: {name: x}\ncis:\n  - {type: server, name: a, attributes: {conx_manifest: y}}\n",
+		"not yaml":       "apiVersion: [",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.ErrorIs(t, err, ErrInvalidManifest)
+		})
+	}
+}
+
+func TestApplyCreatesThenIsIdempotent(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	m := parseTest(t, testManifest)
+	ctx := context.Background()
+
+	plan, err := service.Plan(ctx, m, DefaultOptions())
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Create: 4}, plan.Summary)
+	assert.Empty(t, store.cis, "planning changes nothing")
+
+	result, err := service.Apply(ctx, m, DefaultOptions(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Create: 4}, result.Applied)
+	assert.Empty(t, result.Failed)
+
+	checkout := store.ci(t, "service", "checkout")
+	ordersDB := store.ci(t, "database", "orders-db")
+	assert.Equal(t, "payments-team", checkout.Owner)
+	assert.Equal(t, "payments", attributesOf(t, checkout.Attributes)[ManagedByAttribute])
+	require.Len(t, store.rels, 1)
+	for _, rel := range store.rels {
+		assert.Equal(t, checkout.ID, rel.SourceCIID)
+		assert.Equal(t, ordersDB.ID, rel.TargetCIID)
+	}
+	require.Contains(t, store.ciSchemas, "service")
+
+	plan, err = service.Plan(ctx, m, DefaultOptions())
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Unchanged: 4}, plan.Summary)
+}
+
+func TestPlanUpdatesDeclaredFieldsOnly(t *testing.T) {
+	store := newMemoryStore()
+	existing := store.addCI("database", "orders-db", map[string]interface{}{"engine": "postgres", "version": 14})
+	existing.Owner = "dba"
+	existing.Criticality = models.CICriticalityMedium
+
+	m := parseTest(t, `
+apiVersion: conx/v1
+metadata: {name: payments}
+cis:
+  - type: database
+    name: orders-db
+    criticality: high
+    attributes: {version: 15}
+`)
+	service := NewService(store)
+	plan, err := service.Plan(context.Background(), m, DefaultOptions())
+	require.NoError(t, err)
+
+	require.Len(t, plan.Changes, 1)
+	change := plan.Changes[0]
+	assert.Equal(t, ActionUpdate, change.Action)
+	assert.Equal(t, []FieldChange{
+		{Field: "criticality", From: models.CICriticalityMedium, To: "high"},
+		{Field: "attributes." + ManagedByAttribute, From: nil, To: "payments"},
+		{Field: "attributes.version", From: float64(14), To: float64(15)},
+	}, change.Fields)
+
+	_, err = service.Apply(context.Background(), m, DefaultOptions(), uuid.New())
+	require.NoError(t, err)
+	updated := store.ci(t, "database", "orders-db")
+	assert.Equal(t, "dba", updated.Owner, "undeclared fields are left alone")
+	assert.Equal(t, "postgres", attributesOf(t, updated.Attributes)["engine"])
+
+	var out bytes.Buffer
+	require.NoError(t, plan.Write(&out))
+	assert.Contains(t, out.String(), "~ ci database/orders-db\n    criticality: \"medium\" -> \"high\"\n")
+	assert.Contains(t, out.String(), "1 to update")
+}
+
+func TestPlanOptions(t *testing.T) {
+	store := newMemoryStore()
+	store.addCI("database", "orders-db", nil)
+	m := parseTest(t, testManifest)
+
+	plan, err := NewService(store).Plan(context.Background(), m, Options{Update: true})
+	require.NoError(t, err)
+
+	actions := map[string]string{}
+	for _, change := range plan.Changes {
+		actions[change.Key] = change.Action
+	}
+	assert.Equal(t, map[string]string{
+		"service":            ActionSkip,
+		"service/checkout":   ActionSkip,
+		"database/orders-db": ActionUpdate,
+		"service/checkout -DEPENDS_ON-> database/orders-db": ActionSkip,
+	}, actions)
+	assert.False(t, plan.HasConflicts())
+}
+
+func TestPlanConflicts(t *testing.T) {
+	store := newMemoryStore()
+	store.addCI("database", "orders-db", map[string]interface{}{ManagedByAttribute: "billing"})
+	m := parseTest(t, testManifest)
+	m.CIs[0].Attributes = map[string]interface{}{"port": "https"}
+	service := NewService(store)
+
+	plan, err := service.Plan(context.Background(), m, DefaultOptions())
+	require.NoError(t, err)
+	assert.Equal(t, 2, plan.Summary.Conflict)
+	assert.Equal(t, "managed by manifest billing", plan.Changes[2].Reason)
+	assert.Contains(t, plan.Changes[1].Reason, "fails schema validation")
+
+	result, err := service.Apply(context.Background(), m, DefaultOptions(), uuid.New())
+	assert.ErrorIs(t, err, ErrPlanConflicts)
+	require.NotNil(t, result)
+	assert.Len(t, store.cis, 1, "nothing is applied")
+}
+
+func TestPlanRejectsUnknownReference(t *testing.T) {
+	m := parseTest(t, `
+apiVersion: conx/v1
+metadata: {name: payments}
+relationships:
+  - {source: service/checkout, target: database/orders-db, type: DEPENDS_ON}
+`)
+	plan, err := NewService(newMemoryStore()).Plan(context.Background(), m, DefaultOptions())
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 1)
+	assert.Equal(t, ActionConflict, plan.Changes[0].Action)
+	assert.Equal(t, "CI service/checkout does not exist", plan.Changes[0].Reason)
+}
+
+func TestApplyPrunesOnlyManagedEntities(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	ctx := context.Background()
+	m := parseTest(t, testManifest)
+	_, err := service.Apply(ctx, m, DefaultOptions(), uuid.New())
+	require.NoError(t, err)
+	store.addCI("database", "reports-db", nil)
+
+	// Dropping the database drops its relationship too
+	m.CIs = m.CIs[:1]
+	m.Relationships = nil
+
+	plan, err := service.Plan(ctx, m, DefaultOptions())
+	require.NoError(t, err)
+	assert.Zero(t, plan.Summary.Delete, "pruning is off by default")
+
+	opts := DefaultOptions()
+	opts.Prune = true
+	result, err := service.Apply(ctx, m, opts, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Applied.Delete)
+	assert.Equal(t, KindRelationship, result.Plan.Changes[len(result.Plan.Changes)-2].Kind)
+	assert.Equal(t, "service/checkout -DEPENDS_ON-> database/orders-db", result.Plan.Changes[len(result.Plan.Changes)-2].Key)
+	assert.Empty(t, store.rels)
+	assert.Len(t, store.cis, 2)
+	store.ci(t, "database", "reports-db")
+}
+
+func TestApplyReactivatesDeprecatedRelationship(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	ctx := context.Background()
+	m := parseTest(t, testManifest)
+	_, err := service.Apply(ctx, m, DefaultOptions(), uuid.New())
+	require.NoError(t, err)
+	for _, rel := range store.rels {
+		rel.State = models.RelationshipStateDeprecated
+	}
+
+	result, err := service.Apply(ctx, m, DefaultOptions(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Applied.Update)
+	for _, rel := range store.rels {
+		assert.Equal(t, models.RelationshipStateActive, rel.State)
+	}
+}
+
+func TestApplyContinuesPastFailures(t *testing.T) {
+	store := newMemoryStore()
+	store.failCreate = "orders-db"
+	m := parseTest(t, testManifest)
+
+	result, err := NewService(store).Apply(context.Background(), m, DefaultOptions(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Applied.Create)
+	assert.Equal(t, []Failure{
+		{Kind: KindCI, Key: "database/orders-db", Error: "connection reset"},
+		{Kind: KindRelationship, Key: "service/checkout -DEPENDS_ON-> database/orders-db", Error: "CI database/orders-db was not created"},
+	}, result.Failed)
+}
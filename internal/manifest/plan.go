@@ -0,0 +1,181 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"connect/internal/models"
+)
+
+// Kinds of entities a manifest manages
+const (
+	KindCITypeSchema           = "ci_type_schema"
+	KindRelationshipTypeSchema = "relationship_type_schema"
+	KindCI                     = "ci"
+	KindRelationship           = "relationship"
+)
+
+// Planned actions
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDelete    = "delete"
+	ActionUnchanged = "unchanged"
+	// ActionSkip is a change the options leave out, e.g. a create when
+	// creating is disabled
+	ActionSkip = "skip"
+	// ActionConflict is an entry that cannot be applied, e.g. a CI managed by
+	// another manifest or failing its schema; a plan with conflicts is not applied
+	ActionConflict = "conflict"
+)
+
+// Options select the changes a plan makes
+type Options struct {
+	Create bool `json:"create"`
+	Update bool `json:"update"`
+	// Prune deletes the CIs and relationships the manifest manages but no
+	// longer declares. Schemas are never pruned.
+	Prune bool `json:"prune"`
+}
+
+// DefaultOptions creates and updates but does not prune
+func DefaultOptions() Options {
+	return Options{Create: true, Update: true}
+}
+
+// FieldChange is a field an update changes
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Change is the action planned for one entity
+type Change struct {
+	Kind   string        `json:"kind"`
+	Key    string        `json:"key"`
+	Action string        `json:"action"`
+	Fields []FieldChange `json:"fields,omitempty"`
+	Reason string        `json:"reason,omitempty"`
+
+	// The entity as it will be saved, or deleted
+	ci        *models.CI
+	rel       *models.CIRelationship
+	ciSchema  *models.CITypeSchema
+	relSchema *models.RelationshipTypeSchema
+	// sourceRef and targetRef name the endpoints of a relationship, which
+	// may be created by the same apply
+	sourceRef, targetRef string
+	// activate is set when a relationship must be moved back to active
+	activate bool
+}
+
+// Summary counts changes by action
+type Summary struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Delete    int `json:"delete"`
+	Unchanged int `json:"unchanged"`
+	Skip      int `json:"skip"`
+	Conflict  int `json:"conflict"`
+}
+
+func (s *Summary) count(action string) {
+	switch action {
+	case ActionCreate:
+		s.Create++
+	case ActionUpdate:
+		s.Update++
+	case ActionDelete:
+		s.Delete++
+	case ActionUnchanged:
+		s.Unchanged++
+	case ActionSkip:
+		s.Skip++
+	case ActionConflict:
+		s.Conflict++
+	}
+}
+
+// Plan is the difference between a manifest and the current state, in the
+// order it is applied
+type Plan struct {
+	Manifest string   `json:"manifest"`
+	Options  Options  `json:"options"`
+	Changes  []Change `json:"changes"`
+	Summary  Summary  `json:"summary"`
+}
+
+// add appends a change to the plan
+func (p *Plan) add(change Change) {
+	p.Changes = append(p.Changes, change)
+	p.Summary.count(change.Action)
+}
+
+// HasConflicts reports whether any entry cannot be applied
+func (p *Plan) HasConflicts() bool {
+	return p.Summary.Conflict > 0
+}
+
+// planSymbols prefix each change when a plan is written
+var planSymbols = map[string]string{
+	ActionCreate:   "+",
+	ActionUpdate:   "~",
+	ActionDelete:   "-",
+	ActionSkip:     "?",
+	ActionConflict: "!",
+}
+
+// Write writes the plan for people to review, leaving out unchanged entities
+func (p *Plan) Write(w io.Writer) error {
+	for _, change := range p.Changes {
+		symbol, ok := planSymbols[change.Action]
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("%s %s %s", symbol, change.Kind, change.Key)
+		if change.Reason != "" {
+			line += ": " + change.Reason
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, field := range change.Fields {
+			if _, err := fmt.Fprintf(w, "    %s: %s -> %s\n", field.Field, formatValue(field.From), formatValue(field.To)); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "Plan for %s: %d to create, %d to update, %d to delete, %d unchanged, %d skipped, %d conflicts.\n",
+		p.Manifest, p.Summary.Create, p.Summary.Update, p.Summary.Delete, p.Summary.Unchanged, p.Summary.Skip, p.Summary.Conflict)
+	return err
+}
+
+// formatValue formats a field value as JSON
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// Failure is a change that failed to apply
+type Failure struct {
+	Kind  string `json:"kind"`
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// Result is the outcome of applying a plan
+type Result struct {
+	Plan *Plan `json:"plan"`
+	// Applied counts the changes made, by action
+	Applied Summary   `json:"applied"`
+	Failed  []Failure `json:"failed"`
+}
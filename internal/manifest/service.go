@@ -0,0 +1,757 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+var ErrPlanConflicts = errors.New("manifest plan has conflicts")
+
+// Store reads and writes the entities a manifest manages. The Find methods
+// return nil when nothing matches.
+type Store interface {
+	FindCITypeSchema(ctx context.Context, name string) (*models.CITypeSchema, error)
+	CreateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error)
+	UpdateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error)
+	FindRelationshipTypeSchema(ctx context.Context, name string) (*models.RelationshipTypeSchema, error)
+	CreateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error)
+	UpdateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error)
+
+	// FindCIsByName returns the CIs of a type with a name; more than one makes
+	// a reference to them ambiguous
+	FindCIsByName(ctx context.Context, ciType, name string) ([]*models.CI, error)
+	ListManagedCIs(ctx context.Context, manifest string) ([]*models.CI, error)
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+	CreateCI(ctx context.Context, ci *models.CI) (*models.CI, error)
+	UpdateCI(ctx context.Context, ci *models.CI) (*models.CI, error)
+	DeleteCI(ctx context.Context, id uuid.UUID) error
+
+	FindRelationship(ctx context.Context, sourceID, targetID uuid.UUID, relType string) (*models.CIRelationship, error)
+	ListManagedRelationships(ctx context.Context, manifest string) ([]*models.CIRelationship, error)
+	CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error)
+	UpdateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error)
+	DeleteRelationship(ctx context.Context, id uuid.UUID) error
+	TransitionRelationshipState(ctx context.Context, id uuid.UUID, state string, changedBy uuid.UUID) (*models.CIRelationship, error)
+}
+
+// Service plans and applies manifests
+type Service struct {
+	store     Store
+	validator *models.SchemaValidator
+}
+
+// NewService creates a new Service
+func NewService(store Store) *Service {
+	return &Service{store: store, validator: models.NewSchemaValidator()}
+}
+
+// Plan computes the changes applying a manifest would make, without making them
+func (s *Service) Plan(ctx context.Context, m *Manifest, opts Options) (*Plan, error) {
+	p, err := s.plan(ctx, m, opts)
+	if err != nil {
+		return nil, err
+	}
+	return p.plan, nil
+}
+
+// Apply plans a manifest and makes the changes. A plan with conflicts is not
+// applied; ErrPlanConflicts is returned with the plan so they can be shown.
+// Otherwise a change that fails is recorded and the rest are still made, so
+// applying again after fixing the cause completes the manifest.
+func (s *Service) Apply(ctx context.Context, m *Manifest, opts Options, by uuid.UUID) (*Result, error) {
+	p, err := s.plan(ctx, m, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Plan: p.plan, Failed: []Failure{}}
+	if p.plan.HasConflicts() {
+		return result, fmt.Errorf("%w: %d conflicts", ErrPlanConflicts, p.plan.Summary.Conflict)
+	}
+
+	for _, change := range p.plan.Changes {
+		if change.Action != ActionCreate && change.Action != ActionUpdate && change.Action != ActionDelete {
+			continue
+		}
+		if err := p.apply(ctx, change, by); err != nil {
+			result.Failed = append(result.Failed, Failure{Kind: change.Kind, Key: change.Key, Error: err.Error()})
+			continue
+		}
+		result.Applied.count(change.Action)
+	}
+	return result, nil
+}
+
+// planner holds the state of planning one manifest
+type planner struct {
+	store     Store
+	validator *models.SchemaValidator
+	manifest  *Manifest
+	opts      Options
+	plan      *Plan
+
+	// ciIDs resolves type/name references to existing CIs, and to the CIs
+	// created while applying
+	ciIDs map[string]uuid.UUID
+	// pending holds the CIs the plan creates
+	pending map[string]bool
+	// unresolved holds the declared CIs the plan neither finds nor creates
+	unresolved map[string]string
+
+	ciSchemas  map[string]*models.CITypeSchema
+	relSchemas map[string]*models.RelationshipTypeSchema
+
+	// matched holds the managed entities the manifest still declares, which
+	// are not pruned
+	matched map[uuid.UUID]bool
+}
+
+func (s *Service) plan(ctx context.Context, m *Manifest, opts Options) (*planner, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &planner{
+		store:      s.store,
+		validator:  s.validator,
+		manifest:   m,
+		opts:       opts,
+		plan:       &Plan{Manifest: m.Metadata.Name, Options: opts, Changes: []Change{}},
+		ciIDs:      map[string]uuid.UUID{},
+		pending:    map[string]bool{},
+		unresolved: map[string]string{},
+		ciSchemas:  map[string]*models.CITypeSchema{},
+		relSchemas: map[string]*models.RelationshipTypeSchema{},
+		matched:    map[uuid.UUID]bool{},
+	}
+
+	if err := p.planCITypeSchemas(ctx); err != nil {
+		return nil, err
+	}
+	if err := p.planRelationshipTypeSchemas(ctx); err != nil {
+		return nil, err
+	}
+	if err := p.planCIs(ctx); err != nil {
+		return nil, err
+	}
+	if err := p.planRelationships(ctx); err != nil {
+		return nil, err
+	}
+	if opts.Prune {
+		if err := p.planPrune(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *planner) planCITypeSchemas(ctx context.Context) error {
+	for _, spec := range p.manifest.Schemas.CITypes {
+		existing, err := p.store.FindCITypeSchema(ctx, spec.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get CI type schema %s: %w", spec.Name, err)
+		}
+
+		change := Change{Kind: KindCITypeSchema, Key: spec.Name}
+		target := &models.CITypeSchema{ID: uuid.New(), Name: spec.Name, Description: spec.Description, Attributes: spec.Attributes, IsActive: true}
+		if existing != nil {
+			copied := *existing
+			copied.Description = spec.Description
+			copied.Attributes = spec.Attributes
+			target = &copied
+			change.Fields = schemaFieldChanges(existing.Description, spec.Description, existing.Attributes, spec.Attributes)
+		}
+		change.ciSchema = target
+
+		if result := p.validator.ValidateSchemaDefinition(*target); !result.IsValid {
+			change.Action, change.Reason = ActionConflict, validationReason(result)
+		} else {
+			change.Action, change.Reason = p.action(existing != nil, len(change.Fields) > 0)
+		}
+		p.plan.add(change)
+
+		// CIs are validated against the schema as it will be
+		p.ciSchemas[spec.Name] = existing
+		if change.Action == ActionCreate || change.Action == ActionUpdate {
+			p.ciSchemas[spec.Name] = target
+		}
+	}
+	return nil
+}
+
+func (p *planner) planRelationshipTypeSchemas(ctx context.Context) error {
+	for _, spec := range p.manifest.Schemas.RelationshipTypes {
+		existing, err := p.store.FindRelationshipTypeSchema(ctx, spec.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get relationship type schema %s: %w", spec.Name, err)
+		}
+
+		change := Change{Kind: KindRelationshipTypeSchema, Key: spec.Name}
+		target := &models.RelationshipTypeSchema{ID: uuid.New(), Name: spec.Name, Description: spec.Description, Attributes: spec.Attributes, IsActive: true}
+		if existing != nil {
+			copied := *existing
+			copied.Description = spec.Description
+			copied.Attributes = spec.Attributes
+			target = &copied
+			change.Fields = schemaFieldChanges(existing.Description, spec.Description, existing.Attributes, spec.Attributes)
+		}
+		change.relSchema = target
+
+		definition := models.CITypeSchema{Name: target.Name, Attributes: target.Attributes}
+		if result := p.validator.ValidateSchemaDefinition(definition); !result.IsValid {
+			change.Action, change.Reason = ActionConflict, validationReason(result)
+		} else {
+			change.Action, change.Reason = p.action(existing != nil, len(change.Fields) > 0)
+		}
+		p.plan.add(change)
+
+		p.relSchemas[spec.Name] = existing
+		if change.Action == ActionCreate || change.Action == ActionUpdate {
+			p.relSchemas[spec.Name] = target
+		}
+	}
+	return nil
+}
+
+func (p *planner) planCIs(ctx context.Context) error {
+	for _, spec := range p.manifest.CIs {
+		key := spec.Key()
+		change := Change{Kind: KindCI, Key: key}
+
+		found, err := p.store.FindCIsByName(ctx, spec.Type, spec.Name)
+		if err != nil {
+			return fmt.Errorf("failed to find CI %s: %w", key, err)
+		}
+		if len(found) > 1 {
+			change.Action, change.Reason = ActionConflict, fmt.Sprintf("%d CIs are named %s", len(found), key)
+			p.unresolved[key] = change.Reason
+			p.plan.add(change)
+			continue
+		}
+
+		var existing *models.CI
+		if len(found) == 1 {
+			existing = found[0]
+			if owner := managedBy(existing.Attributes); owner != "" && owner != p.manifest.Metadata.Name {
+				change.Action, change.Reason = ActionConflict, fmt.Sprintf("managed by manifest %s", owner)
+				p.unresolved[key] = change.Reason
+				p.plan.add(change)
+				continue
+			}
+			p.ciIDs[key] = existing.ID
+			p.matched[existing.ID] = true
+		}
+
+		target, fields, err := p.desiredCI(spec, existing)
+		if err != nil {
+			return err
+		}
+		change.ci, change.Fields = target, fields
+		change.Action, change.Reason = p.action(existing != nil, len(fields) > 0)
+
+		if change.Action == ActionCreate || change.Action == ActionUpdate {
+			if reason, err := p.validateCI(ctx, target); err != nil {
+				return err
+			} else if reason != "" {
+				change.Action, change.Reason = ActionConflict, reason
+			}
+		}
+
+		switch {
+		case change.Action == ActionCreate:
+			p.pending[key] = true
+		case existing == nil:
+			p.unresolved[key] = change.Reason
+		}
+		p.plan.add(change)
+	}
+	return nil
+}
+
+// desiredCI applies a spec to the existing CI, or to a new one, and returns
+// the fields it changes
+func (p *planner) desiredCI(spec CISpec, existing *models.CI) (*models.CI, []FieldChange, error) {
+	target := &models.CI{ID: uuid.New(), Type: spec.Type, Name: spec.Name}
+	if existing != nil {
+		copied := *existing
+		target = &copied
+	}
+
+	var fields []FieldChange
+	for _, field := range []struct {
+		name    string
+		current *string
+		desired string
+	}{
+		{"description", &target.Description, spec.Description},
+		{"status", &target.Status, spec.Status},
+		{"criticality", &target.Criticality, spec.Criticality},
+		{"owner", &target.Owner, spec.Owner},
+		{"location", &target.Location, spec.Location},
+		{"org_unit", &target.OrgUnit, spec.OrgUnit},
+		{"cost_center", &target.CostCenter, spec.CostCenter},
+	} {
+		if field.desired == "" || *field.current == field.desired {
+			continue
+		}
+		if existing != nil {
+			fields = append(fields, FieldChange{Field: field.name, From: *field.current, To: field.desired})
+		}
+		*field.current = field.desired
+	}
+
+	if spec.Tags != nil && !sameTags(target.Tags, spec.Tags) {
+		if existing != nil {
+			fields = append(fields, FieldChange{Field: "tags", From: target.Tags, To: spec.Tags})
+		}
+		target.Tags = spec.Tags
+	}
+
+	attributes, attributeFields, err := p.desiredAttributes(target.Attributes, spec.Attributes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to plan attributes of CI %s: %w", spec.Key(), err)
+	}
+	target.Attributes = attributes
+	if existing != nil {
+		fields = append(fields, attributeFields...)
+	}
+	return target, fields, nil
+}
+
+// validateCI returns why a CI fails its type schema, if it does
+func (p *planner) validateCI(ctx context.Context, ci *models.CI) (string, error) {
+	schema, ok := p.ciSchemas[ci.Type]
+	if !ok {
+		var err error
+		if schema, err = p.store.FindCITypeSchema(ctx, ci.Type); err != nil {
+			return "", fmt.Errorf("failed to get CI type schema %s: %w", ci.Type, err)
+		}
+		p.ciSchemas[ci.Type] = schema
+	}
+	if schema == nil {
+		return "", nil
+	}
+	if result := p.validator.ValidateCIAgainstSchema(*ci, *schema); !result.IsValid {
+		return validationReason(result), nil
+	}
+	return "", nil
+}
+
+func (p *planner) planRelationships(ctx context.Context) error {
+	for _, spec := range p.manifest.Relationships {
+		change := Change{Kind: KindRelationship, Key: spec.Key()}
+
+		sourceID, sourceReason, err := p.resolve(ctx, spec.Source)
+		if err != nil {
+			return err
+		}
+		targetID, targetReason, err := p.resolve(ctx, spec.Target)
+		if err != nil {
+			return err
+		}
+		if reason := firstReason(sourceReason, targetReason); reason != "" {
+			change.Action, change.Reason = ActionConflict, reason
+			if p.unresolvedRef(spec.Source) || p.unresolvedRef(spec.Target) {
+				change.Action = ActionSkip
+			}
+			p.plan.add(change)
+			continue
+		}
+
+		var existing *models.CIRelationship
+		if sourceID != uuid.Nil && targetID != uuid.Nil {
+			existing, err = p.store.FindRelationship(ctx, sourceID, targetID, spec.Type)
+			if err != nil {
+				return fmt.Errorf("failed to find relationship %s: %w", change.Key, err)
+			}
+		}
+		if existing != nil {
+			if owner := managedBy(existing.Attributes); owner != "" && owner != p.manifest.Metadata.Name {
+				change.Action, change.Reason = ActionConflict, fmt.Sprintf("managed by manifest %s", owner)
+				p.plan.add(change)
+				continue
+			}
+			p.matched[existing.ID] = true
+		}
+
+		target := &models.CIRelationship{
+			ID:         uuid.New(),
+			SourceCIID: sourceID,
+			TargetCIID: targetID,
+			Type:       spec.Type,
+			State:      models.RelationshipStateActive,
+		}
+		if existing != nil {
+			copied := *existing
+			target = &copied
+		}
+
+		var fields []FieldChange
+		if spec.Description != "" && target.Description != spec.Description {
+			fields = append(fields, FieldChange{Field: "description", From: target.Description, To: spec.Description})
+			target.Description = spec.Description
+		}
+		attributes, attributeFields, err := p.desiredAttributes(target.Attributes, spec.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to plan attributes of relationship %s: %w", change.Key, err)
+		}
+		target.Attributes = attributes
+		fields = append(fields, attributeFields...)
+		if existing != nil && existing.State != models.RelationshipStateActive {
+			fields = append(fields, FieldChange{Field: "state", From: existing.State, To: models.RelationshipStateActive})
+			change.activate = true
+		}
+		if existing == nil {
+			fields = nil
+		}
+
+		change.rel, change.Fields = target, fields
+		change.sourceRef, change.targetRef = spec.Source, spec.Target
+		change.Action, change.Reason = p.action(existing != nil, len(fields) > 0)
+
+		if change.Action == ActionCreate || change.Action == ActionUpdate {
+			if reason, err := p.validateRelationship(ctx, target); err != nil {
+				return err
+			} else if reason != "" {
+				change.Action, change.Reason = ActionConflict, reason
+			}
+		}
+		p.plan.add(change)
+	}
+	return nil
+}
+
+// resolve returns the ID of the CI a type/name reference names, uuid.Nil for
+// a CI the plan creates, or why it cannot be resolved
+func (p *planner) resolve(ctx context.Context, ref string) (uuid.UUID, string, error) {
+	if id, ok := p.ciIDs[ref]; ok {
+		return id, "", nil
+	}
+	if p.pending[ref] {
+		return uuid.Nil, "", nil
+	}
+	if reason, ok := p.unresolved[ref]; ok {
+		if reason == "" {
+			reason = "not created"
+		}
+		return uuid.Nil, fmt.Sprintf("CI %s: %s", ref, reason), nil
+	}
+
+	ciType, name, _ := splitRef(ref)
+	found, err := p.store.FindCIsByName(ctx, ciType, name)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("failed to find CI %s: %w", ref, err)
+	}
+	switch len(found) {
+	case 0:
+		return uuid.Nil, fmt.Sprintf("CI %s does not exist", ref), nil
+	case 1:
+		p.ciIDs[ref] = found[0].ID
+		return found[0].ID, "", nil
+	}
+	return uuid.Nil, fmt.Sprintf("%d CIs are named %s", len(found), ref), nil
+}
+
+// unresolvedRef reports whether a reference names a CI the manifest declares
+// but the plan does not resolve, so the reason is already in the plan
+func (p *planner) unresolvedRef(ref string) bool {
+	_, ok := p.unresolved[ref]
+	return ok
+}
+
+// validateRelationship returns why a relationship fails its type schema, if it does
+func (p *planner) validateRelationship(ctx context.Context, rel *models.CIRelationship) (string, error) {
+	schema, ok := p.relSchemas[rel.Type]
+	if !ok {
+		var err error
+		if schema, err = p.store.FindRelationshipTypeSchema(ctx, rel.Type); err != nil {
+			return "", fmt.Errorf("failed to get relationship type schema %s: %w", rel.Type, err)
+		}
+		p.relSchemas[rel.Type] = schema
+	}
+	if schema == nil {
+		return "", nil
+	}
+	if result := p.validator.ValidateRelationshipAgainstSchema(*rel, *schema); !result.IsValid {
+		return validationReason(result), nil
+	}
+	return "", nil
+}
+
+// planPrune deletes the relationships and then the CIs the manifest manages
+// but no longer declares
+func (p *planner) planPrune(ctx context.Context) error {
+	name := p.manifest.Metadata.Name
+
+	relationships, err := p.store.ListManagedRelationships(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to list relationships managed by %s: %w", name, err)
+	}
+	keys := map[uuid.UUID]string{}
+	ciKey := func(id uuid.UUID) string {
+		if key, ok := keys[id]; ok {
+			return key
+		}
+		key := id.String()
+		if ci, err := p.store.GetCI(ctx, id); err == nil {
+			key = ci.Type + "/" + ci.Name
+		}
+		keys[id] = key
+		return key
+	}
+	for _, rel := range relationships {
+		if p.matched[rel.ID] {
+			continue
+		}
+		p.plan.add(Change{
+			Kind:   KindRelationship,
+			Key:    relationshipKey(ciKey(rel.SourceCIID), rel.Type, ciKey(rel.TargetCIID)),
+			Action: ActionDelete,
+			rel:    rel,
+		})
+	}
+
+	cis, err := p.store.ListManagedCIs(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to list CIs managed by %s: %w", name, err)
+	}
+	for _, ci := range cis {
+		if p.matched[ci.ID] {
+			continue
+		}
+		p.plan.add(Change{Kind: KindCI, Key: ci.Type + "/" + ci.Name, Action: ActionDelete, ci: ci})
+	}
+	return nil
+}
+
+// action returns the action for an entry that exists or not and differs or
+// not from its spec, given the options
+func (p *planner) action(exists, changed bool) (string, string) {
+	switch {
+	case !exists && !p.opts.Create:
+		return ActionSkip, "creating is disabled"
+	case !exists:
+		return ActionCreate, ""
+	case !changed:
+		return ActionUnchanged, ""
+	case !p.opts.Update:
+		return ActionSkip, "updating is disabled"
+	}
+	return ActionUpdate, ""
+}
+
+// desiredAttributes sets the declared attributes and the manifest marker on
+// the current ones, leaving the rest alone, and returns the attributes changed
+func (p *planner) desiredAttributes(current json.RawMessage, declared map[string]interface{}) (json.RawMessage, []FieldChange, error) {
+	attributes := map[string]interface{}{}
+	if len(current) > 0 && string(current) != "null" {
+		if err := json.Unmarshal(current, &attributes); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal attributes: %w", err)
+		}
+	}
+
+	desired := map[string]interface{}{}
+	for name, value := range declared {
+		desired[name] = value
+	}
+	desired[ManagedByAttribute] = p.manifest.Metadata.Name
+	desired, err := normalize(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fields []FieldChange
+	for _, name := range names {
+		value, exists := attributes[name]
+		if exists && reflect.DeepEqual(value, desired[name]) {
+			continue
+		}
+		fields = append(fields, FieldChange{Field: "attributes." + name, From: value, To: desired[name]})
+		attributes[name] = desired[name]
+	}
+
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	return encoded, fields, nil
+}
+
+// apply makes one planned change
+func (p *planner) apply(ctx context.Context, change Change, by uuid.UUID) error {
+	switch change.Kind {
+	case KindCITypeSchema:
+		schema := *change.ciSchema
+		schema.UpdatedBy = by
+		if change.Action == ActionCreate {
+			schema.CreatedBy = by
+			_, err := p.store.CreateCITypeSchema(ctx, &schema)
+			return err
+		}
+		_, err := p.store.UpdateCITypeSchema(ctx, &schema)
+		return err
+
+	case KindRelationshipTypeSchema:
+		schema := *change.relSchema
+		schema.UpdatedBy = by
+		if change.Action == ActionCreate {
+			schema.CreatedBy = by
+			_, err := p.store.CreateRelationshipTypeSchema(ctx, &schema)
+			return err
+		}
+		_, err := p.store.UpdateRelationshipTypeSchema(ctx, &schema)
+		return err
+
+	case KindCI:
+		if change.Action == ActionDelete {
+			return p.store.DeleteCI(ctx, change.ci.ID)
+		}
+		ci := *change.ci
+		ci.UpdatedBy = by
+		if change.Action == ActionCreate {
+			ci.CreatedBy = by
+			created, err := p.store.CreateCI(ctx, &ci)
+			if err != nil {
+				return err
+			}
+			p.ciIDs[change.Key] = created.ID
+			return nil
+		}
+		_, err := p.store.UpdateCI(ctx, &ci)
+		return err
+
+	case KindRelationship:
+		if change.Action == ActionDelete {
+			return p.store.DeleteRelationship(ctx, change.rel.ID)
+		}
+		rel := *change.rel
+		rel.UpdatedBy = by
+		if change.Action == ActionCreate {
+			for _, endpoint := range []struct {
+				ref string
+				id  *uuid.UUID
+			}{{change.sourceRef, &rel.SourceCIID}, {change.targetRef, &rel.TargetCIID}} {
+				id, ok := p.ciIDs[endpoint.ref]
+				if !ok {
+					return fmt.Errorf("CI %s was not created", endpoint.ref)
+				}
+				*endpoint.id = id
+			}
+			rel.CreatedBy = by
+			_, err := p.store.CreateRelationship(ctx, &rel)
+			return err
+		}
+
+		for _, field := range change.Fields {
+			if field.Field != "state" {
+				if _, err := p.store.UpdateRelationship(ctx, &rel); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		if change.activate {
+			_, err := p.store.TransitionRelationshipState(ctx, rel.ID, models.RelationshipStateActive, by)
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown change kind %s", change.Kind)
+}
+
+// schemaFieldChanges returns the fields of a schema a spec changes
+func schemaFieldChanges(currentDescription, desiredDescription string, current, desired []models.CITypeAttribute) []FieldChange {
+	var fields []FieldChange
+	if currentDescription != desiredDescription {
+		fields = append(fields, FieldChange{Field: "description", From: currentDescription, To: desiredDescription})
+	}
+	// No attributes compare equal however they were stored
+	if len(current) == 0 {
+		current = []models.CITypeAttribute{}
+	}
+	if len(desired) == 0 {
+		desired = []models.CITypeAttribute{}
+	}
+	currentJSON, _ := json.Marshal(current)
+	desiredJSON, _ := json.Marshal(desired)
+	if string(currentJSON) != string(desiredJSON) {
+		fields = append(fields, FieldChange{Field: "attributes", From: attributeNames(current), To: attributeNames(desired)})
+	}
+	return fields
+}
+
+func attributeNames(attributes []models.CITypeAttribute) []string {
+	names := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		names = append(names, attribute.Name)
+	}
+	return names
+}
+
+// managedBy returns the manifest named in attributes, if any
+func managedBy(attributes json.RawMessage) string {
+	var marker map[string]interface{}
+	if err := json.Unmarshal(attributes, &marker); err != nil {
+		return ""
+	}
+	name, _ := marker[ManagedByAttribute].(string)
+	return name
+}
+
+// normalize round-trips values through JSON so they compare equal to the
+// values unmarshalled from stored attributes
+func normalize(values map[string]interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attributes: %w", err)
+	}
+	return normalized, nil
+}
+
+// sameTags compares tags regardless of order
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return reflect.DeepEqual(sortedA, sortedB)
+}
+
+// validationReason joins the errors of a failed validation
+func validationReason(result models.ValidationResult) string {
+	messages := make([]string, 0, len(result.Errors))
+	for _, validationError := range result.Errors {
+		messages = append(messages, validationError.Message)
+	}
+	return "fails schema validation: " + strings.Join(messages, "; ")
+}
+
+func firstReason(reasons ...string) string {
+	for _, reason := range reasons {
+		if reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AssetTagAttribute is the CI attribute holding the tag printed on a physical asset
+const AssetTagAttribute = "asset_tag"
+
+// CIURL returns the canonical URL of a CI, as encoded in its QR code. Without a
+// base URL the bare CI ID is encoded instead.
+func CIURL(baseURL string, id uuid.UUID) string {
+	if baseURL == "" {
+		return id.String()
+	}
+	return strings.TrimRight(baseURL, "/") + "/cis/" + id.String()
+}
+
+// ParseScannedCode interprets a scanned QR code or barcode. A CI URL or ID
+// yields the CI ID; anything else is returned as an asset tag.
+func ParseScannedCode(code string) (uuid.UUID, string) {
+	code = strings.TrimSpace(code)
+
+	if id, err := uuid.Parse(code); err == nil {
+		return id, ""
+	}
+
+	if u, err := url.Parse(code); err == nil && u.Scheme != "" && u.Host != "" {
+		if id, err := uuid.Parse(path.Base(u.Path)); err == nil {
+			return id, ""
+		}
+	}
+
+	return uuid.Nil, code
+}
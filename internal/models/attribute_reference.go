@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Directions of the relationship created for an attribute reference
+const (
+	// ReferenceOutgoing relates the CI to the CI it references
+	ReferenceOutgoing = "outgoing"
+	// ReferenceIncoming relates the referenced CI to the CI
+	ReferenceIncoming = "incoming"
+)
+
+// AttributeReference flags a string attribute as naming another CI, e.g. a VM's
+// hypervisor_name naming the hypervisor it runs on. Imports resolve the name
+// to an existing CI of the target type and relate the two CIs.
+type AttributeReference struct {
+	// TargetType is the CI type the referenced CI is looked up in by name
+	TargetType string `json:"target_type"`
+	// RelationshipType is the type of the relationship created, e.g. "runs_on"
+	RelationshipType string `json:"relationship_type"`
+	// Direction is ReferenceOutgoing unless set to ReferenceIncoming
+	Direction string `json:"direction,omitempty"`
+}
+
+// Incoming reports whether the relationship runs from the referenced CI
+func (r *AttributeReference) Incoming() bool {
+	return r.Direction == ReferenceIncoming
+}
+
+// validate checks a reference made by an attribute of a type
+func (r *AttributeReference) validate(attrType string) error {
+	if attrType != AttributeTypeString {
+		return fmt.Errorf("only string attributes can reference CIs")
+	}
+	if strings.TrimSpace(r.TargetType) == "" {
+		return fmt.Errorf("target_type is required")
+	}
+	if strings.TrimSpace(r.RelationshipType) == "" {
+		return fmt.Errorf("relationship_type is required")
+	}
+	if r.Direction != "" && r.Direction != ReferenceOutgoing && r.Direction != ReferenceIncoming {
+		return fmt.Errorf("direction must be %s or %s", ReferenceOutgoing, ReferenceIncoming)
+	}
+	return nil
+}
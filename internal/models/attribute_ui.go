@@ -0,0 +1,49 @@
+package models
+
+// Form widgets an attribute can be rendered with
+const (
+	WidgetText        = "text"
+	WidgetTextarea    = "textarea"
+	WidgetNumber      = "number"
+	WidgetCheckbox    = "checkbox"
+	WidgetDate        = "date"
+	WidgetSelect      = "select"
+	WidgetMultiSelect = "multiselect"
+	WidgetTags        = "tags"
+	WidgetEmail       = "email"
+	WidgetURL         = "url"
+	WidgetJSON        = "json"
+)
+
+// Widgets lists every form widget
+var Widgets = []string{
+	WidgetText, WidgetTextarea, WidgetNumber, WidgetCheckbox, WidgetDate, WidgetSelect,
+	WidgetMultiSelect, WidgetTags, WidgetEmail, WidgetURL, WidgetJSON,
+}
+
+// AttributeUI holds optional presentation hints for an attribute. Anything left
+// empty is derived from the attribute's type and validation rules.
+type AttributeUI struct {
+	Label string `json:"label,omitempty"`
+	Group string `json:"group,omitempty"`
+	// Order positions the field in its group; fields without one follow in schema order
+	Order       *int   `json:"order,omitempty"`
+	Widget      string `json:"widget,omitempty"`
+	Placeholder string `json:"placeholder,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty"`
+	ReadOnly    bool   `json:"read_only,omitempty"`
+	// EnumLabels maps enum values, as text, to the labels shown for them
+	EnumLabels map[string]string `json:"enum_labels,omitempty"`
+	// Messages overrides the validation messages shown, by rule name
+	Messages map[string]string `json:"messages,omitempty"`
+}
+
+// IsValidWidget reports whether a widget can be rendered
+func IsValidWidget(widget string) bool {
+	for _, w := range Widgets {
+		if w == widget {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatchGetIDs bounds the IDs fetched by one batch get
+const MaxBatchGetIDs = 500
+
+// BatchGetRequest lists the IDs of the CIs or relationships to fetch at once
+type BatchGetRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// Validate checks the number of IDs and drops duplicates, keeping the
+// requested order
+func (r *BatchGetRequest) Validate() error {
+	if len(r.IDs) == 0 {
+		return fmt.Errorf("ids must not be empty")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(r.IDs))
+	ids := make([]uuid.UUID, 0, len(r.IDs))
+	for _, id := range r.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > MaxBatchGetIDs {
+		return fmt.Errorf("at most %d ids may be fetched at once, got %d", MaxBatchGetIDs, len(ids))
+	}
+	r.IDs = ids
+	return nil
+}
+
+// BatchGetResult splits the requested IDs into the found entities, in
+// requested order, and the IDs that do not exist or may not be seen
+type BatchGetResult struct {
+	Found   interface{} `json:"found"`
+	Missing []uuid.UUID `json:"missing"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchGetRequest_Validate(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	req := &BatchGetRequest{IDs: []uuid.UUID{b, a, b, c, a}}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, []uuid.UUID{b, a, c}, req.IDs, "duplicates are dropped, keeping the requested order")
+
+	assert.Error(t, (&BatchGetRequest{}).Validate())
+
+	ids := make([]uuid.UUID, MaxBatchGetIDs)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	req = &BatchGetRequest{IDs: append(ids, ids[0])}
+	require.NoError(t, req.Validate(), "duplicates do not count against the limit")
+	assert.Len(t, req.IDs, MaxBatchGetIDs)
+
+	req = &BatchGetRequest{IDs: append(ids, uuid.New())}
+	assert.Error(t, req.Validate())
+}
@@ -23,6 +23,10 @@ type CI struct {
 	Owner          string     `json:"owner" db:"owner"`
 	Location       string     `json:"location" db:"location"`
 	
+	// Chargeback, validated against the managed org unit and cost center lists
+	OrgUnit        string     `json:"org_unit" db:"org_unit"`
+	CostCenter     string     `json:"cost_center" db:"cost_center"`
+	
 	// FSD-Compliant Flexible Attributes
 	Attributes     json.RawMessage `json:"attributes" db:"attributes"`  // JSONB for user-defined schema
 	Tags           []string        `json:"tags" db:"tags"`              // String array for flexible tagging
@@ -42,6 +46,9 @@ type CI struct {
 	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
 	CreatedBy      uuid.UUID  `json:"created_by" db:"created_by"`
 	UpdatedBy      uuid.UUID  `json:"updated_by" db:"updated_by"`
+
+	// Organization the CI belongs to, nil for CIs shared by every organization
+	OrgID          *uuid.UUID `json:"org_id,omitempty" db:"org_id"`
 }
 
 // CITypeSchema represents a user-defined CI type schema
@@ -55,6 +62,8 @@ type CITypeSchema struct {
 	UpdatedAt   time.Time            `json:"updated_at" db:"updated_at"`
 	CreatedBy   uuid.UUID            `json:"created_by" db:"created_by"`
 	UpdatedBy   uuid.UUID            `json:"updated_by" db:"updated_by"`
+	// OrgID is the organization defining the schema, nil for shared schemas
+	OrgID       *uuid.UUID           `json:"org_id,omitempty" db:"org_id"`
 }
 
 // CITypeAttribute represents an attribute definition in a CI type schema
@@ -65,6 +74,8 @@ type CITypeAttribute struct {
 	Description string                 `json:"description"`
 	Default     interface{}            `json:"default,omitempty"`
 	Validation  map[string]interface{} `json:"validation,omitempty"`
+	UI          *AttributeUI           `json:"ui,omitempty"`        // presentation hints for generated forms
+	Reference   *AttributeReference    `json:"reference,omitempty"` // the CI the value names, related on import
 }
 
 // CIRelationship represents a relationship between CIs with FSD-compliant flexible attributes
@@ -77,10 +88,19 @@ type CIRelationship struct {
 	Attributes   json.RawMessage `json:"attributes" db:"attributes"`  // JSONB for user-defined relationship attributes
 	Description  string         `json:"description" db:"description"`
 	IsActive     bool           `json:"is_active" db:"is_active"`
+	// Lifecycle state: proposed, active or deprecated
+	State          string     `json:"state" db:"state"`
+	StateChangedAt *time.Time `json:"state_changed_at,omitempty" db:"state_changed_at"`
+	StateChangedBy *uuid.UUID `json:"state_changed_by,omitempty" db:"state_changed_by"`
+	// IsPrimary marks the one relationship of its type that is the primary
+	// one of the source CI, e.g. the primary database of an application
+	IsPrimary    bool           `json:"is_primary" db:"is_primary"`
 	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
 	CreatedBy    uuid.UUID      `json:"created_by" db:"created_by"`
 	UpdatedBy    uuid.UUID      `json:"updated_by" db:"updated_by"`
+	// OrgID is the organization of the related CIs, nil when both are shared
+	OrgID        *uuid.UUID     `json:"org_id,omitempty" db:"org_id"`
 }
 
 // RelationshipTypeSchema represents a user-defined relationship type schema
@@ -94,6 +114,8 @@ type RelationshipTypeSchema struct {
 	UpdatedAt   time.Time            `json:"updated_at" db:"updated_at"`
 	CreatedBy   uuid.UUID            `json:"created_by" db:"created_by"`
 	UpdatedBy   uuid.UUID            `json:"updated_by" db:"updated_by"`
+	// OrgID is the organization defining the schema, nil for shared schemas
+	OrgID       *uuid.UUID           `json:"org_id,omitempty" db:"org_id"`
 }
 
 // ValidationError represents a schema validation error
@@ -122,6 +144,8 @@ type CreateCIRequest struct {
 	Criticality  string                 `json:"criticality"`
 	Owner        string                 `json:"owner"`
 	Location     string                 `json:"location"`
+	OrgUnit      string                 `json:"org_unit"`
+	CostCenter   string                 `json:"cost_center"`
 	Attributes   json.RawMessage        `json:"attributes"`
 	Tags         []string               `json:"tags"`
 	InstallDate  *time.Time            `json:"install_date"`
@@ -137,6 +161,8 @@ type UpdateCIRequest struct {
 	Criticality  string                 `json:"criticality"`
 	Owner        string                 `json:"owner"`
 	Location     string                 `json:"location"`
+	OrgUnit      string                 `json:"org_unit"`
+	CostCenter   string                 `json:"cost_center"`
 	Attributes   json.RawMessage        `json:"attributes"`
 	Tags         []string               `json:"tags"`
 	InstallDate  *time.Time            `json:"install_date"`
@@ -156,9 +182,15 @@ type ListCIsRequest struct {
 	Criticality  string   `json:"criticality"`
 	Owner        string   `json:"owner"`
 	Location     string   `json:"location"`
+	OrgUnit      string   `json:"org_unit"`
+	CostCenter   string   `json:"cost_center"`
 	Tags         []string `json:"tags"`
 	SortBy       string   `json:"sort_by"`
 	SortOrder    string   `json:"sort_order" validate:"oneof=asc desc"`
+	// Attributes restricts results to CIs matching every attribute filter
+	Attributes   []AttributeFilter `json:"attributes,omitempty"`
+	// Scope restricts results to CIs the caller may see; nil means no restriction
+	Scope        *VisibilityScope `json:"-"`
 }
 
 // ListCIsResponse represents a response for listing CIs
@@ -177,6 +209,13 @@ type CreateRelationshipRequest struct {
 	Type         string         `json:"type" validate:"required"`
 	Attributes   json.RawMessage `json:"attributes"`
 	Description  string         `json:"description"`
+	State        string         `json:"state"`
+	IsPrimary    bool           `json:"is_primary"`
+}
+
+// TransitionRelationshipStateRequest represents a request to move a relationship to a new lifecycle state
+type TransitionRelationshipStateRequest struct {
+	State string `json:"state" validate:"required"`
 }
 
 // UpdateRelationshipRequest represents a request to update a relationship
@@ -237,6 +276,7 @@ const (
 	CIStatusMaintenance = "maintenance"
 	CIStatusRetired     = "retired"
 	CIStatusFixRequired = "fix_required"
+	CIStatusUnreachable = "unreachable"
 
 	// CI Criticality values
 	CICriticalityLow    = "low"
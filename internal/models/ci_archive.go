@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Archive errors
+var (
+	ErrArchivedCINotFound = errors.New("archived CI not found")
+	// ErrArchiveRestoreConflict is returned when a live CI took the name and
+	// type of an archived one since it was archived
+	ErrArchiveRestoreConflict = errors.New("a live CI has the name and type of the archived CI")
+)
+
+// ArchivedCI is a CI moved to the archive tier. CI holds the record as it was
+// when archived and is only set when a single archived CI is retrieved.
+type ArchivedCI struct {
+	ID                uuid.UUID       `json:"id" db:"id"`
+	Name              string          `json:"name" db:"name"`
+	Type              string          `json:"type" db:"type"`
+	LastActivityAt    time.Time       `json:"last_activity_at" db:"last_activity_at"`
+	ArchivedAt        time.Time       `json:"archived_at" db:"archived_at"`
+	RelationshipCount int             `json:"relationship_count" db:"relationship_count"`
+	CI                json.RawMessage `json:"ci,omitempty" db:"data"`
+}
+
+// ArchivedCIFilter selects archived CIs to list
+type ArchivedCIFilter struct {
+	Type     string
+	Search   string
+	Page     int
+	PageSize int
+}
+
+// ArchivedCIList is a page of archived CIs
+type ArchivedCIList struct {
+	CIs        []ArchivedCI `json:"cis"`
+	Total      int64        `json:"total"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalPages int          `json:"total_pages"`
+}
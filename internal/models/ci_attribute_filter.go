@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AttributeFilterPrefix prefixes the query parameters filtering CIs by
+// attribute: attr.<key>=<value> or attr.<key>.<operator>=<value>
+const AttributeFilterPrefix = "attr."
+
+// MaxAttributeFilters bounds the attribute filters of one listing
+const MaxAttributeFilters = 10
+
+// Attribute filter operators
+const (
+	AttributeOpEq       = "eq"
+	AttributeOpNeq      = "neq"
+	AttributeOpGt       = "gt"
+	AttributeOpGte      = "gte"
+	AttributeOpLt       = "lt"
+	AttributeOpLte      = "lte"
+	AttributeOpContains = "contains"
+)
+
+// AttributeFilterOperators are the accepted attribute filter operators
+var AttributeFilterOperators = []string{
+	AttributeOpEq, AttributeOpNeq, AttributeOpGt, AttributeOpGte, AttributeOpLt, AttributeOpLte, AttributeOpContains,
+}
+
+// attributeKeyPattern matches the attribute keys that can be filtered on
+var attributeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// AttributeFilter restricts a CI listing to the CIs whose attribute Key
+// compares to Value with Operator. eq and neq compare scalar values, matching
+// numbers and booleans whatever their JSON type; gt, gte, lt and lte compare
+// numerically; contains matches a substring of a string attribute, ignoring
+// case, or an element of an array attribute. neq matches CIs without the
+// attribute too.
+type AttributeFilter struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// ParseAttributeFilter parses an attr.<key>[.<operator>] query parameter
+func ParseAttributeFilter(param, value string) (AttributeFilter, error) {
+	filter := AttributeFilter{Key: strings.TrimPrefix(param, AttributeFilterPrefix), Operator: AttributeOpEq, Value: value}
+	if i := strings.LastIndex(filter.Key, "."); i >= 0 {
+		filter.Operator = strings.ToLower(filter.Key[i+1:])
+		filter.Key = filter.Key[:i]
+		if !contains(AttributeFilterOperators, filter.Operator) {
+			return filter, fmt.Errorf("operator must be one of %s", strings.Join(AttributeFilterOperators, ", "))
+		}
+	}
+	if !attributeKeyPattern.MatchString(filter.Key) {
+		return filter, fmt.Errorf("attribute key must be 1 to 64 letters, digits, underscores or hyphens")
+	}
+	if filter.Value == "" {
+		return filter, fmt.Errorf("value is required")
+	}
+	if filter.Numeric() {
+		if n, err := strconv.ParseFloat(filter.Value, 64); err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return filter, fmt.Errorf("%s requires a number", filter.Operator)
+		}
+	}
+	return filter, nil
+}
+
+// Numeric reports whether the filter compares numerically
+func (f AttributeFilter) Numeric() bool {
+	switch f.Operator {
+	case AttributeOpGt, AttributeOpGte, AttributeOpLt, AttributeOpLte:
+		return true
+	}
+	return false
+}
+
+// ScalarValues returns the JSON values an eq or neq filter matches: the value
+// as a string and, when it reads as one, as a number or boolean
+func (f AttributeFilter) ScalarValues() []interface{} {
+	values := []interface{}{f.Value}
+	if n, err := strconv.ParseFloat(f.Value, 64); err == nil {
+		values = append(values, n)
+	} else if f.Value == "true" || f.Value == "false" {
+		values = append(values, f.Value == "true")
+	}
+	return values
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAttributeFilter(t *testing.T) {
+	tests := []struct {
+		param, value string
+		expected     AttributeFilter
+	}{
+		{"attr.os", "linux", AttributeFilter{Key: "os", Operator: AttributeOpEq, Value: "linux"}},
+		{"attr.cpu.GTE", "8", AttributeFilter{Key: "cpu", Operator: AttributeOpGte, Value: "8"}},
+		{"attr.disk_gb.lt", "-0.5", AttributeFilter{Key: "disk_gb", Operator: AttributeOpLt, Value: "-0.5"}},
+		{"attr.host-name.contains", "web", AttributeFilter{Key: "host-name", Operator: AttributeOpContains, Value: "web"}},
+		{"attr.os.neq", "windows", AttributeFilter{Key: "os", Operator: AttributeOpNeq, Value: "windows"}},
+	}
+	for _, tt := range tests {
+		filter, err := ParseAttributeFilter(tt.param, tt.value)
+		require.NoError(t, err, tt.param)
+		assert.Equal(t, tt.expected, filter, tt.param)
+	}
+
+	invalid := []struct {
+		param, value, reason string
+	}{
+		{"attr.os.like", "linux", "operator must be one of eq, neq, gt, gte, lt, lte, contains"},
+		{"attr.", "linux", "attribute key must be 1 to 64 letters, digits, underscores or hyphens"},
+		{"attr.a.b.eq", "linux", "attribute key must be 1 to 64 letters, digits, underscores or hyphens"},
+		{"attr.os", "", "value is required"},
+		{"attr.cpu.gt", "many", "gt requires a number"},
+		{"attr.cpu.lte", "Inf", "lte requires a number"},
+		{"attr.cpu.gte", "NaN", "gte requires a number"},
+	}
+	for _, tt := range invalid {
+		_, err := ParseAttributeFilter(tt.param, tt.value)
+		require.Error(t, err, tt.param)
+		assert.Equal(t, tt.reason, err.Error(), tt.param)
+	}
+}
+
+func TestAttributeFilter_Numeric(t *testing.T) {
+	for _, operator := range AttributeFilterOperators {
+		numeric := operator == AttributeOpGt || operator == AttributeOpGte || operator == AttributeOpLt || operator == AttributeOpLte
+		assert.Equal(t, numeric, AttributeFilter{Operator: operator}.Numeric(), operator)
+	}
+}
+
+func TestAttributeFilter_ScalarValues(t *testing.T) {
+	assert.Equal(t, []interface{}{"linux"}, AttributeFilter{Value: "linux"}.ScalarValues())
+	assert.Equal(t, []interface{}{"8", 8.0}, AttributeFilter{Value: "8"}.ScalarValues())
+	assert.Equal(t, []interface{}{"true", true}, AttributeFilter{Value: "true"}.ScalarValues())
+	assert.Equal(t, []interface{}{"False"}, AttributeFilter{Value: "False"}.ScalarValues(), "only JSON booleans are matched as booleans")
+}
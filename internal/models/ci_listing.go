@@ -0,0 +1,59 @@
+package models
+
+// Sort orders
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// SortOrders are the accepted sort orders
+var SortOrders = []string{SortOrderAsc, SortOrderDesc}
+
+// CIStatuses are the accepted CI statuses
+var CIStatuses = []string{CIStatusActive, CIStatusInactive, CIStatusMaintenance, CIStatusRetired, CIStatusFixRequired, CIStatusUnreachable}
+
+// CICriticalities are the accepted CI criticalities
+var CICriticalities = []string{CICriticalityLow, CICriticalityMedium, CICriticalityHigh, CICriticalityCritical}
+
+// CISortFields are the fields CI lists can be sorted by
+var CISortFields = []string{
+	"name", "type", "status", "criticality", "owner", "location", "org_unit", "cost_center", "created_at", "updated_at",
+}
+
+// RelationshipStates are the relationship lifecycle states
+var RelationshipStates = []string{RelationshipStateProposed, RelationshipStateActive, RelationshipStateDeprecated}
+
+// Relationship sort fields
+const (
+	RelationshipSortCreatedAt = "created_at"
+	RelationshipSortStrength  = "strength"
+)
+
+// RelationshipSortFields are the fields relationship lists can be sorted by.
+// Strength is the impact strength: the strength attribute when set to a
+// number between 0 and 1, the default of the relationship type otherwise.
+var RelationshipSortFields = []string{RelationshipSortCreatedAt, RelationshipSortStrength}
+
+// ListRelationshipsRequest represents a request for listing relationships
+type ListRelationshipsRequest struct {
+	State string `json:"state"`
+	// Attributes restricts results to relationships matching every attribute filter
+	Attributes []AttributeFilter `json:"attributes,omitempty"`
+	SortBy     string            `json:"sort_by"`
+	SortOrder  string            `json:"sort_order"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	// Scope restricts results to relationships whose source and target the
+	// caller may both see; nil means no restriction
+	Scope *VisibilityScope `json:"-"`
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CI revision actions, naming the change a revision was recorded before
+const (
+	CIRevisionUpdate  = "update"
+	CIRevisionDelete  = "delete"
+	CIRevisionRestore = "restore"
+)
+
+// Revision errors
+var (
+	ErrCIRevisionNotFound = errors.New("CI revision not found")
+	// ErrCIRevisionRestoreConflict is returned when another CI took the name
+	// and type the CI had at the restored revision
+	ErrCIRevisionRestoreConflict = errors.New("another CI has the name and type of the CI at this revision")
+)
+
+// CIRevision is the state of a CI just before one of its changes. Revisions
+// are numbered from 1 per CI; restoring revision n puts the CI back as it was
+// before the change n recorded.
+type CIRevision struct {
+	CIID      uuid.UUID       `json:"ci_id" db:"ci_id"`
+	Revision  int             `json:"revision" db:"revision"`
+	Action    string          `json:"action" db:"action"`
+	CI        json.RawMessage `json:"ci" db:"snapshot"`
+	ChangedBy *uuid.UUID      `json:"changed_by,omitempty" db:"changed_by"`
+	ChangedAt time.Time       `json:"changed_at" db:"changed_at"`
+}
+
+// CIHistory is a page of the revisions of a CI, newest first
+type CIHistory struct {
+	CIID      uuid.UUID    `json:"ci_id"`
+	Revisions []CIRevision `json:"revisions"`
+	// NextBefore is the before cursor of the next page, when there is one
+	NextBefore *int `json:"next_before,omitempty"`
+}
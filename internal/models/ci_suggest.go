@@ -0,0 +1,31 @@
+package models
+
+import "github.com/google/uuid"
+
+// Search suggestion limits
+const (
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 25
+	// MinSubstringSuggestLength is the shortest query also matched inside
+	// names; trigram indexes cannot serve shorter ones
+	MinSubstringSuggestLength = 3
+)
+
+// CISuggestion is a CI offered while a search query is being typed
+type CISuggestion struct {
+	ID   uuid.UUID `json:"id" db:"id"`
+	Name string    `json:"name" db:"name"`
+	Type string    `json:"type" db:"type"`
+}
+
+// SuggestCIsRequest asks for the CIs whose name starts with, or for longer
+// queries contains, Query
+type SuggestCIsRequest struct {
+	// Query is matched case-insensitively
+	Query string `json:"query"`
+	// Type restricts suggestions to a CI type when set
+	Type  string `json:"type,omitempty"`
+	Limit int    `json:"limit"`
+	// Scope restricts suggestions to CIs the caller may see; nil means no restriction
+	Scope *VisibilityScope `json:"scope,omitempty"`
+}
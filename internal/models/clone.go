@@ -0,0 +1,184 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxCloneCount is the largest number of copies a single clone request may create
+const MaxCloneCount = 100
+
+// DefaultCloneExclusions are attributes that identify a single physical or network
+// asset and are therefore cleared on clones unless a template is given for them
+var DefaultCloneExclusions = []string{
+	"serial_number", "asset_tag", "ip_address", "management_ip", "mac_address", "hostname",
+}
+
+// CloneCIRequest represents a request to clone a CI.
+// Name and attribute templates may use the placeholders {n} (clone index),
+// {name} (clone name), {source_name} and {attr.<name>} (source attribute value).
+// In the name template, which renders the clone name, {name} is the source name.
+type CloneCIRequest struct {
+	Name                string                 `json:"name" validate:"required"`
+	Count               int                    `json:"count"`
+	StartIndex          int                    `json:"start_index"`
+	ExcludeAttributes   []string               `json:"exclude_attributes"`
+	KeepDefaultExcluded bool                   `json:"keep_default_excluded"`
+	AttributeTemplates  map[string]string      `json:"attribute_templates"`
+	Attributes          map[string]interface{} `json:"attributes"`
+	Tags                []string               `json:"tags"`
+	CloneRelationships  bool                   `json:"clone_relationships"`
+	RelationshipTypes   []string               `json:"relationship_types"`
+}
+
+// CloneCIResponse represents the result of cloning a CI
+type CloneCIResponse struct {
+	SourceID      string            `json:"source_id"`
+	CIs           []*CI             `json:"cis"`
+	Relationships []*CIRelationship `json:"relationships"`
+}
+
+// Normalize applies defaults and validates the request
+func (r *CloneCIRequest) Normalize() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Count <= 0 {
+		r.Count = 1
+	}
+	if r.Count > MaxCloneCount {
+		return fmt.Errorf("count cannot exceed %d", MaxCloneCount)
+	}
+	if r.StartIndex <= 0 {
+		r.StartIndex = 1
+	}
+	// Every clone needs a distinct name when more than one is requested
+	if r.Count > 1 && !strings.Contains(r.Name, "{n}") {
+		r.Name += "-{n}"
+	}
+	return nil
+}
+
+// CloneName renders the name of the clone with the given index
+func (r *CloneCIRequest) CloneName(source *CI, index int) (string, error) {
+	sourceAttrs, err := decodeAttributes(source.Attributes)
+	if err != nil {
+		return "", err
+	}
+	return renderCloneTemplate(r.Name, index, source.Name, source, sourceAttrs), nil
+}
+
+// CloneAttributes builds the attributes of a clone from the source CI. Excluded
+// attributes (explicit, schema-unique and default identifiers) are dropped, templates
+// are rendered and explicit attribute values applied last.
+func (r *CloneCIRequest) CloneAttributes(source *CI, schema *CITypeSchema, index int, name string) (json.RawMessage, error) {
+	sourceAttrs, err := decodeAttributes(source.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool)
+	for _, attr := range r.ExcludeAttributes {
+		excluded[attr] = true
+	}
+	if !r.KeepDefaultExcluded {
+		for _, attr := range DefaultCloneExclusions {
+			excluded[attr] = true
+		}
+	}
+	if schema != nil {
+		for _, attr := range schema.Attributes {
+			if unique, _ := attr.Validation["unique"].(bool); unique {
+				excluded[attr.Name] = true
+			}
+		}
+	}
+
+	attrs := make(map[string]interface{}, len(sourceAttrs))
+	for key, value := range sourceAttrs {
+		if !excluded[key] {
+			attrs[key] = value
+		}
+	}
+	for key, tmpl := range r.AttributeTemplates {
+		attrs[key] = renderCloneTemplate(tmpl, index, name, source, sourceAttrs)
+	}
+	for key, value := range r.Attributes {
+		attrs[key] = value
+	}
+
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+
+	return data, nil
+}
+
+// renderCloneTemplate substitutes clone placeholders in tmpl
+func renderCloneTemplate(tmpl string, index int, name string, source *CI, sourceAttrs map[string]interface{}) string {
+	replacements := []string{
+		"{n}", strconv.Itoa(index),
+		"{name}", name,
+		"{source_name}", source.Name,
+	}
+	for key, value := range sourceAttrs {
+		replacements = append(replacements, "{attr."+key+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(tmpl)
+}
+
+// SelectRelationships returns the relationships of the source CI to copy onto
+// its clones: none unless CloneRelationships is set, and only those of the
+// requested types when RelationshipTypes is given
+func (r *CloneCIRequest) SelectRelationships(relationships []*CIRelationship) []*CIRelationship {
+	if !r.CloneRelationships {
+		return nil
+	}
+	if len(r.RelationshipTypes) == 0 {
+		return relationships
+	}
+
+	types := make(map[string]bool, len(r.RelationshipTypes))
+	for _, relType := range r.RelationshipTypes {
+		types[relType] = true
+	}
+	selected := make([]*CIRelationship, 0, len(relationships))
+	for _, rel := range relationships {
+		if types[rel.Type] {
+			selected = append(selected, rel)
+		}
+	}
+	return selected
+}
+
+// CloneRelationships copies relationships of the source CI onto a clone,
+// replacing the source CI by the clone at whichever end it appears
+func CloneRelationships(relationships []*CIRelationship, sourceID, cloneID, userID uuid.UUID) []*CIRelationship {
+	clones := make([]*CIRelationship, 0, len(relationships))
+	for _, rel := range relationships {
+		clone := &CIRelationship{
+			ID:          uuid.New(),
+			SourceCIID:  rel.SourceCIID,
+			TargetCIID:  rel.TargetCIID,
+			Type:        rel.Type,
+			Attributes:  rel.Attributes,
+			Description: rel.Description,
+			State:       rel.State,
+			CreatedBy:   userID,
+			UpdatedBy:   userID,
+		}
+		if rel.SourceCIID == sourceID {
+			clone.SourceCIID = cloneID
+		}
+		if rel.TargetCIID == sourceID {
+			clone.TargetCIID = cloneID
+		}
+		clones = append(clones, clone)
+	}
+	return clones
+}
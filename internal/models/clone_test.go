@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cloneSource() *CI {
+	return &CI{
+		ID:         uuid.New(),
+		Name:       "web-01",
+		Type:       "server",
+		Attributes: json.RawMessage(`{"rack": "R12", "serial_number": "SN1", "ip_address": "10.0.0.1", "cpu": 8}`),
+	}
+}
+
+func TestCloneCIRequest_Normalize(t *testing.T) {
+	req := &CloneCIRequest{Name: "web", Count: 3}
+	require.NoError(t, req.Normalize())
+	assert.Equal(t, "web-{n}", req.Name, "several clones need distinct names")
+	assert.Equal(t, 1, req.StartIndex)
+
+	req = &CloneCIRequest{Name: "web"}
+	require.NoError(t, req.Normalize())
+	assert.Equal(t, "web", req.Name)
+	assert.Equal(t, 1, req.Count)
+
+	assert.Error(t, (&CloneCIRequest{Name: " "}).Normalize())
+	assert.Error(t, (&CloneCIRequest{Name: "web", Count: MaxCloneCount + 1}).Normalize())
+}
+
+func TestCloneCIRequest_CloneName(t *testing.T) {
+	source := cloneSource()
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{name}-copy-{n}", "web-01-copy-7"},
+		{"{source_name}-{n}", "web-01-7"},
+		{"{attr.rack}-web-{n}", "R12-web-7"},
+		{"cpu{attr.cpu}-{attr.missing}", "cpu8-{attr.missing}"},
+	}
+	for _, tt := range tests {
+		req := &CloneCIRequest{Name: tt.template}
+		name, err := req.CloneName(source, 7)
+		require.NoError(t, err, tt.template)
+		assert.Equal(t, tt.expected, name, tt.template)
+	}
+
+	source.Attributes = json.RawMessage(`not json`)
+	_, err := (&CloneCIRequest{Name: "{n}"}).CloneName(source, 1)
+	assert.Error(t, err)
+}
+
+func TestCloneCIRequest_CloneAttributes(t *testing.T) {
+	source := cloneSource()
+	schema := &CITypeSchema{Attributes: []CITypeAttribute{
+		{Name: "rack", Validation: map[string]interface{}{"unique": true}},
+	}}
+	req := &CloneCIRequest{
+		Name:               "web-{n}",
+		AttributeTemplates: map[string]string{"ip_address": "10.0.1.{n}", "hostname": "{name}.example.com"},
+		Attributes:         map[string]interface{}{"cpu": 16},
+	}
+
+	attributes, err := req.CloneAttributes(source, schema, 4, "web-04")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ip_address": "10.0.1.4", "hostname": "web-04.example.com", "cpu": 16}`, string(attributes),
+		"unique and identifying attributes are cleared unless templated, and explicit values win")
+
+	req = &CloneCIRequest{Name: "web", KeepDefaultExcluded: true, ExcludeAttributes: []string{"cpu"}}
+	attributes, err = req.CloneAttributes(source, nil, 1, "web")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"rack": "R12", "serial_number": "SN1", "ip_address": "10.0.0.1"}`, string(attributes))
+}
+
+func TestCloneRelationships(t *testing.T) {
+	sourceID, cloneID, userID := uuid.New(), uuid.New(), uuid.New()
+	peer := uuid.New()
+	relationships := []*CIRelationship{
+		{ID: uuid.New(), SourceCIID: sourceID, TargetCIID: peer, Type: "runs_on", State: RelationshipStateActive},
+		{ID: uuid.New(), SourceCIID: peer, TargetCIID: sourceID, Type: "depends_on", State: RelationshipStateActive},
+	}
+
+	req := &CloneCIRequest{}
+	assert.Empty(t, req.SelectRelationships(relationships), "relationships are only cloned on request")
+
+	req = &CloneCIRequest{CloneRelationships: true}
+	assert.Len(t, req.SelectRelationships(relationships), 2)
+
+	req = &CloneCIRequest{CloneRelationships: true, RelationshipTypes: []string{"depends_on"}}
+	selected := req.SelectRelationships(relationships)
+	require.Len(t, selected, 1)
+	assert.Equal(t, "depends_on", selected[0].Type)
+
+	clones := CloneRelationships(relationships, sourceID, cloneID, userID)
+	require.Len(t, clones, 2)
+	assert.Equal(t, cloneID, clones[0].SourceCIID)
+	assert.Equal(t, peer, clones[0].TargetCIID)
+	assert.Equal(t, peer, clones[1].SourceCIID)
+	assert.Equal(t, cloneID, clones[1].TargetCIID)
+	for i, clone := range clones {
+		assert.NotEqual(t, relationships[i].ID, clone.ID)
+		assert.Equal(t, userID, clone.CreatedBy)
+		assert.Equal(t, relationships[i].Type, clone.Type)
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CompactCI is the condensed CI representation served with ?profile=compact to
+// mobile and field tooling. User references carry display names instead of IDs.
+type CompactCI struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	Criticality string    `json:"criticality"`
+	Owner       string    `json:"owner,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+}
+
+// UserReferences returns the distinct user IDs referenced by the CIs: their
+// last editor, and their owner when it is recorded as a user ID
+func UserReferences(cis []CI) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	add := func(id uuid.UUID) {
+		if id != uuid.Nil && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, ci := range cis {
+		add(ci.UpdatedBy)
+		if id, err := uuid.Parse(ci.Owner); err == nil {
+			add(id)
+		}
+	}
+	return ids
+}
+
+// NewCompactCI projects a CI onto the compact profile. names maps user IDs to
+// display names; references without a name are left as they are.
+func NewCompactCI(ci *CI, names map[uuid.UUID]string) CompactCI {
+	compact := CompactCI{
+		ID:          ci.ID,
+		Name:        ci.Name,
+		Type:        ci.Type,
+		Status:      ci.Status,
+		Criticality: ci.Criticality,
+		Owner:       ci.Owner,
+		Location:    ci.Location,
+		UpdatedAt:   ci.UpdatedAt,
+	}
+
+	if name, ok := names[ci.UpdatedBy]; ok {
+		compact.UpdatedBy = name
+	} else if ci.UpdatedBy != uuid.Nil {
+		compact.UpdatedBy = ci.UpdatedBy.String()
+	}
+	if id, err := uuid.Parse(ci.Owner); err == nil {
+		if name, ok := names[id]; ok {
+			compact.Owner = name
+		}
+	}
+
+	return compact
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserReferences(t *testing.T) {
+	editor, owner := uuid.New(), uuid.New()
+	cis := []CI{
+		{UpdatedBy: editor, Owner: owner.String()},
+		{UpdatedBy: owner, Owner: "team-platform"},
+		{UpdatedBy: uuid.Nil, Owner: editor.String()},
+	}
+
+	assert.Equal(t, []uuid.UUID{editor, owner}, UserReferences(cis), "each user once, in order of first reference")
+	assert.Empty(t, UserReferences(nil))
+}
+
+func TestNewCompactCI(t *testing.T) {
+	editor, owner, unknown := uuid.New(), uuid.New(), uuid.New()
+	updatedAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	names := map[uuid.UUID]string{editor: "Ada Lovelace", owner: "grace"}
+
+	ci := &CI{
+		ID:          uuid.New(),
+		Name:        "web-01",
+		Type:        "server",
+		Status:      "active",
+		Criticality: "high",
+		Owner:       owner.String(),
+		Location:    "dc-1",
+		Attributes:  []byte(`{"cpu": 8}`),
+		UpdatedAt:   updatedAt,
+		UpdatedBy:   editor,
+	}
+	assert.Equal(t, CompactCI{
+		ID:          ci.ID,
+		Name:        "web-01",
+		Type:        "server",
+		Status:      "active",
+		Criticality: "high",
+		Owner:       "grace",
+		Location:    "dc-1",
+		UpdatedAt:   updatedAt,
+		UpdatedBy:   "Ada Lovelace",
+	}, NewCompactCI(ci, names))
+
+	// References without a display name are served as they are
+	ci.Owner, ci.UpdatedBy = "team-platform", unknown
+	compact := NewCompactCI(ci, names)
+	assert.Equal(t, "team-platform", compact.Owner)
+	assert.Equal(t, unknown.String(), compact.UpdatedBy)
+
+	ci.UpdatedBy = uuid.Nil
+	assert.Empty(t, NewCompactCI(ci, names).UpdatedBy)
+}
@@ -0,0 +1,67 @@
+package models
+
+import "github.com/google/uuid"
+
+// BusinessServiceType is the CI type of the business services reported as
+// affected by a deletion
+const BusinessServiceType = "business_service"
+
+// MaxImpactDepth bounds how many relationship hops upstream a deletion preview
+// looks for affected business services
+const MaxImpactDepth = 10
+
+// CISummary identifies a CI in a report or at the end of a relationship. CIs
+// the caller may not see are reported as hidden, without their details, so
+// counts stay accurate.
+type CISummary struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	Name   string    `json:"name,omitempty" db:"name"`
+	Type   string    `json:"type,omitempty" db:"type"`
+	Status string    `json:"status,omitempty" db:"status"`
+	// Criticality is only loaded for relationship endpoints
+	Criticality string `json:"criticality,omitempty" db:"criticality"`
+	Hidden      bool   `json:"hidden,omitempty" db:"hidden"`
+}
+
+// Redact blanks the details of a hidden CI
+func (s *CISummary) Redact() {
+	if s.Hidden {
+		s.Name = ""
+		s.Type = ""
+		s.Status = ""
+		s.Criticality = ""
+	}
+}
+
+// DeletionRelationship is a relationship removed along with a deleted CI.
+// Direction is seen from the deleted CI; CI is the CI at the other end.
+type DeletionRelationship struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Direction string    `json:"direction"`
+	CI        CISummary `json:"ci"`
+}
+
+// OrphanedDependent is a CI whose only active relationship of a type points at
+// the deleted CI, e.g. an application running on no other server
+type OrphanedDependent struct {
+	CI               CISummary `json:"ci"`
+	RelationshipType string    `json:"relationship_type"`
+}
+
+// AffectedService is a business service depending on the deleted CI, Depth
+// relationship hops upstream of it
+type AffectedService struct {
+	CI    CISummary `json:"ci"`
+	Depth int       `json:"depth"`
+}
+
+// DeletionPreview describes what deleting a CI would affect, for the UI to show
+// before asking for confirmation
+type DeletionPreview struct {
+	CI                 CISummary              `json:"ci"`
+	Relationships      []DeletionRelationship `json:"relationships"`
+	OrphanedDependents []OrphanedDependent    `json:"orphaned_dependents"`
+	AffectedServices   []AffectedService      `json:"affected_services"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCISummary_Redact(t *testing.T) {
+	id := uuid.New()
+	visible := CISummary{ID: id, Name: "web-01", Type: "server", Status: "active", Criticality: "high"}
+	summary := visible
+	summary.Redact()
+	assert.Equal(t, visible, summary, "visible CIs keep their details")
+
+	hidden := CISummary{ID: id, Name: "web-01", Type: "server", Status: "active", Criticality: "high", Hidden: true}
+	hidden.Redact()
+	assert.Equal(t, CISummary{ID: id, Hidden: true}, hidden, "hidden CIs keep only their ID so counts stay accurate")
+}
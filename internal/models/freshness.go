@@ -0,0 +1,136 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// staleScoreHorizon is how many multiples of the expected max age it takes for
+// the freshness score to decay from 1 to 0
+const staleScoreHorizon = 3
+
+// FreshnessPolicy defines how recently CIs of each type are expected to be updated or scanned
+type FreshnessPolicy struct {
+	DefaultMaxAge time.Duration            `json:"default_max_age"`
+	TypeMaxAge    map[string]time.Duration `json:"type_max_age"`
+}
+
+// CIFreshness describes how current the data of a single CI is
+type CIFreshness struct {
+	CIID          uuid.UUID  `json:"ci_id"`
+	Type          string     `json:"type"`
+	ReferenceTime *time.Time `json:"reference_time"`
+	AgeHours      float64    `json:"age_hours"`
+	MaxAgeHours   float64    `json:"max_age_hours"`
+	Score         float64    `json:"score"`
+	IsStale       bool       `json:"is_stale"`
+}
+
+// StaleCI is a CI listed together with its freshness
+type StaleCI struct {
+	CI        CI          `json:"ci"`
+	Freshness CIFreshness `json:"freshness"`
+}
+
+// ListStaleCIsResponse represents a response for listing stale CIs
+type ListStaleCIsResponse struct {
+	CIs        []StaleCI `json:"cis"`
+	TotalCount int64     `json:"total_count"`
+	Page       int       `json:"page"`
+	PageSize   int       `json:"page_size"`
+	TotalPages int       `json:"total_pages"`
+}
+
+// TypeQualityStats aggregates data quality figures for one CI type
+type TypeQualityStats struct {
+	Type            string  `json:"type"`
+	TotalCount      int64   `json:"total_count"`
+	StaleCount      int64   `json:"stale_count"`
+	AverageScore    float64 `json:"average_freshness_score"`
+	MaxAgeHours     float64 `json:"max_age_hours"`
+	MissingOwner    int64   `json:"missing_owner"`
+	MissingLocation int64   `json:"missing_location"`
+	NeverScanned    int64   `json:"never_scanned"`
+}
+
+// DataQualityReport summarizes freshness and completeness across the CMDB
+type DataQualityReport struct {
+	GeneratedAt     time.Time          `json:"generated_at"`
+	TotalCount      int64              `json:"total_count"`
+	StaleCount      int64              `json:"stale_count"`
+	StalePercentage float64            `json:"stale_percentage"`
+	AverageScore    float64            `json:"average_freshness_score"`
+	MissingOwner    int64              `json:"missing_owner"`
+	MissingLocation int64              `json:"missing_location"`
+	NeverScanned    int64              `json:"never_scanned"`
+	ByType          []TypeQualityStats `json:"by_type"`
+}
+
+// MaxAge returns the expected maximum data age for a CI type
+func (p FreshnessPolicy) MaxAge(ciType string) time.Duration {
+	if maxAge, ok := p.TypeMaxAge[ciType]; ok && maxAge > 0 {
+		return maxAge
+	}
+	return p.DefaultMaxAge
+}
+
+// FreshnessReferenceTime returns the most recent of last_scanned and last_updated,
+// falling back to updated_at when the CI has never been scanned or explicitly updated
+func FreshnessReferenceTime(ci *CI) *time.Time {
+	var ref *time.Time
+	for _, t := range []*time.Time{ci.LastScanned, ci.LastUpdated} {
+		if t != nil && (ref == nil || t.After(*ref)) {
+			ref = t
+		}
+	}
+	if ref == nil && !ci.UpdatedAt.IsZero() {
+		updatedAt := ci.UpdatedAt
+		ref = &updatedAt
+	}
+	return ref
+}
+
+// Evaluate computes the freshness of a CI at the given time. The score is 1 while
+// the data is within its expected max age and decays linearly to 0 at
+// staleScoreHorizon times the max age.
+func (p FreshnessPolicy) Evaluate(ci *CI, now time.Time) CIFreshness {
+	maxAge := p.MaxAge(ci.Type)
+	freshness := CIFreshness{
+		CIID:          ci.ID,
+		Type:          ci.Type,
+		ReferenceTime: FreshnessReferenceTime(ci),
+		MaxAgeHours:   maxAge.Hours(),
+	}
+
+	if freshness.ReferenceTime == nil {
+		freshness.IsStale = true
+		return freshness
+	}
+
+	age := now.Sub(*freshness.ReferenceTime)
+	if age < 0 {
+		age = 0
+	}
+	freshness.AgeHours = age.Hours()
+	freshness.IsStale = age > maxAge
+	freshness.Score = FreshnessScore(age, maxAge)
+
+	return freshness
+}
+
+// FreshnessScore maps a data age to a score between 0 and 1 given the expected max age
+func FreshnessScore(age, maxAge time.Duration) float64 {
+	if maxAge <= 0 {
+		return 0
+	}
+	if age <= maxAge {
+		return 1
+	}
+
+	score := 1 - float64(age-maxAge)/float64((staleScoreHorizon-1)*maxAge)
+	if score < 0 {
+		return 0
+	}
+	return score
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreshnessPolicy_MaxAge(t *testing.T) {
+	policy := FreshnessPolicy{
+		DefaultMaxAge: 30 * 24 * time.Hour,
+		TypeMaxAge:    map[string]time.Duration{"server": 24 * time.Hour, "rack": 0},
+	}
+	assert.Equal(t, 24*time.Hour, policy.MaxAge("server"))
+	assert.Equal(t, 30*24*time.Hour, policy.MaxAge("rack"), "non-positive overrides fall back to the default")
+	assert.Equal(t, 30*24*time.Hour, policy.MaxAge("database"))
+}
+
+func TestFreshnessReferenceTime(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scanned := updatedAt.Add(48 * time.Hour)
+	updated := updatedAt.Add(24 * time.Hour)
+
+	ci := &CI{UpdatedAt: updatedAt}
+	assert.Equal(t, &updatedAt, FreshnessReferenceTime(ci), "falls back to updated_at")
+
+	ci.LastUpdated = &updated
+	assert.Equal(t, &updated, FreshnessReferenceTime(ci))
+
+	ci.LastScanned = &scanned
+	assert.Equal(t, &scanned, FreshnessReferenceTime(ci), "the most recent of last_scanned and last_updated wins")
+
+	assert.Nil(t, FreshnessReferenceTime(&CI{}))
+}
+
+func TestFreshnessScore(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age      time.Duration
+		expected float64
+	}{
+		{0, 1},
+		{day, 1},
+		{2 * day, 0.5},
+		{3 * day, 0},
+		{10 * day, 0},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.expected, FreshnessScore(tt.age, day), 1e-9, tt.age.String())
+	}
+	assert.Zero(t, FreshnessScore(time.Hour, 0))
+}
+
+func TestFreshnessPolicy_Evaluate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := FreshnessPolicy{DefaultMaxAge: 24 * time.Hour}
+
+	scanned := now.Add(-36 * time.Hour)
+	freshness := policy.Evaluate(&CI{Type: "server", LastScanned: &scanned}, now)
+	assert.True(t, freshness.IsStale)
+	assert.InDelta(t, 36, freshness.AgeHours, 1e-9)
+	assert.InDelta(t, 24, freshness.MaxAgeHours, 1e-9)
+	assert.InDelta(t, 0.75, freshness.Score, 1e-9)
+
+	// Clock skew must not make data look fresher than brand new
+	future := now.Add(time.Hour)
+	freshness = policy.Evaluate(&CI{LastScanned: &future}, now)
+	assert.False(t, freshness.IsStale)
+	assert.Zero(t, freshness.AgeHours)
+	assert.Equal(t, 1.0, freshness.Score)
+
+	freshness = policy.Evaluate(&CI{}, now)
+	assert.True(t, freshness.IsStale, "CIs with no timestamps at all are stale")
+	assert.Nil(t, freshness.ReferenceTime)
+	assert.Zero(t, freshness.Score)
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidOrgAssignment is returned when a CI references an org unit or cost
+// center that is not on the managed list, or a cost center outside its org unit
+var ErrInvalidOrgAssignment = errors.New("invalid org unit or cost center")
+
+// OrgUnit is an entry in the managed list of organization units
+type OrgUnit struct {
+	Code       string    `json:"code" db:"code"`
+	Name       string    `json:"name" db:"name"`
+	ParentCode string    `json:"parent_code,omitempty" db:"parent_code"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CostCenter is an entry in the managed list of cost centers. A cost center
+// may belong to an org unit, in which case CIs charged to it must be in that unit.
+type CostCenter struct {
+	Code        string    `json:"code" db:"code"`
+	Name        string    `json:"name" db:"name"`
+	OrgUnitCode string    `json:"org_unit_code,omitempty" db:"org_unit_code"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UpsertOrgUnitRequest represents a request to create or update an org unit
+type UpsertOrgUnitRequest struct {
+	Name       string `json:"name" validate:"required"`
+	ParentCode string `json:"parent_code"`
+	IsActive   *bool  `json:"is_active"`
+}
+
+// UpsertCostCenterRequest represents a request to create or update a cost center
+type UpsertCostCenterRequest struct {
+	Name        string `json:"name" validate:"required"`
+	OrgUnitCode string `json:"org_unit_code"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+// CheckOrgAssignment validates the org unit and cost center a CI is assigned to.
+// unit and center are the looked up list entries, nil when the code is unknown.
+// Empty codes leave the CI unassigned and are always valid.
+func CheckOrgAssignment(orgUnit, costCenter string, unit *OrgUnit, center *CostCenter) error {
+	if orgUnit != "" {
+		if unit == nil {
+			return fmt.Errorf("%w: unknown org unit %q", ErrInvalidOrgAssignment, orgUnit)
+		}
+		if !unit.IsActive {
+			return fmt.Errorf("%w: org unit %q is inactive", ErrInvalidOrgAssignment, orgUnit)
+		}
+	}
+
+	if costCenter != "" {
+		if center == nil {
+			return fmt.Errorf("%w: unknown cost center %q", ErrInvalidOrgAssignment, costCenter)
+		}
+		if !center.IsActive {
+			return fmt.Errorf("%w: cost center %q is inactive", ErrInvalidOrgAssignment, costCenter)
+		}
+		if center.OrgUnitCode != "" && orgUnit != "" && center.OrgUnitCode != orgUnit {
+			return fmt.Errorf("%w: cost center %q belongs to org unit %q", ErrInvalidOrgAssignment, costCenter, center.OrgUnitCode)
+		}
+	}
+
+	return nil
+}
+
+// ChargebackLine aggregates the CIs charged to one cost center. Capacity figures
+// are summed from the numeric cpu_cores, memory_gb and storage attributes, and
+// the monthly cost from the CI costs of the report period, per currency.
+type ChargebackLine struct {
+	CostCenter     string             `json:"cost_center"`
+	CostCenterName string             `json:"cost_center_name,omitempty"`
+	OrgUnit        string             `json:"org_unit,omitempty"`
+	CICount        int64              `json:"ci_count"`
+	ByType         map[string]int64   `json:"by_type"`
+	CPUCores       float64            `json:"cpu_cores"`
+	MemoryGB       float64            `json:"memory_gb"`
+	StorageGB      float64            `json:"storage_gb"`
+	MonthlyCost    map[string]float64 `json:"monthly_cost,omitempty"`
+}
+
+// ChargebackReport aggregates CI counts and capacity per cost center. CIs without
+// a cost center are reported under an empty cost center so nothing goes uncharged.
+type ChargebackReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	OrgUnit     string           `json:"org_unit,omitempty"`
+	Period      string           `json:"period,omitempty"` // month of the costs, YYYY-MM
+	TotalCIs    int64            `json:"total_cis"`
+	CostCenters []ChargebackLine `json:"cost_centers"`
+}
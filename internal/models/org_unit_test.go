@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckOrgAssignment(t *testing.T) {
+	platform := &OrgUnit{Code: "PLAT", IsActive: true}
+	retired := &OrgUnit{Code: "OLD", IsActive: false}
+	shared := &CostCenter{Code: "CC-SHARED", IsActive: true}
+	platformCenter := &CostCenter{Code: "CC-100", OrgUnitCode: "PLAT", IsActive: true}
+	closedCenter := &CostCenter{Code: "CC-900", IsActive: false}
+
+	tests := []struct {
+		name       string
+		orgUnit    string
+		costCenter string
+		unit       *OrgUnit
+		center     *CostCenter
+		valid      bool
+	}{
+		{"unassigned", "", "", nil, nil, true},
+		{"unit only", "PLAT", "", platform, nil, true},
+		{"center of the unit", "PLAT", "CC-100", platform, platformCenter, true},
+		{"center without a unit", "PLAT", "CC-SHARED", platform, shared, true},
+		{"center only", "", "CC-100", nil, platformCenter, true},
+		{"unknown unit", "NOPE", "", nil, nil, false},
+		{"inactive unit", "OLD", "", retired, nil, false},
+		{"unknown center", "PLAT", "CC-404", platform, nil, false},
+		{"inactive center", "", "CC-900", nil, closedCenter, false},
+		{"center of another unit", "OLD", "CC-100", &OrgUnit{Code: "OLD", IsActive: true}, platformCenter, false},
+	}
+	for _, tt := range tests {
+		err := CheckOrgAssignment(tt.orgUnit, tt.costCenter, tt.unit, tt.center)
+		if tt.valid {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.True(t, errors.Is(err, ErrInvalidOrgAssignment), tt.name)
+		}
+	}
+}
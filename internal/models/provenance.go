@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Provenance source types
+const (
+	ProvenanceSourceManual    = "manual"
+	ProvenanceSourceDiscovery = "discovery"
+	ProvenanceSourceImport    = "import"
+)
+
+// ProvenanceHeader is the request header discovery connectors and import jobs use
+// to identify themselves, formatted as "<type>:<name>", e.g. "discovery:aws-scanner"
+const ProvenanceHeader = "X-Change-Source"
+
+// ProvenanceSource identifies who or what set an attribute value
+type ProvenanceSource struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	ID   string `json:"id,omitempty"`
+}
+
+// AttributeProvenance records the source that last set a CI attribute
+type AttributeProvenance struct {
+	CIID       uuid.UUID  `json:"-" db:"ci_id"`
+	Attribute  string     `json:"attribute" db:"attribute"`
+	SourceType string     `json:"source_type" db:"source_type"`
+	SourceName *string    `json:"source_name,omitempty" db:"source_name"`
+	SourceID   *string    `json:"source_id,omitempty" db:"source_id"`
+	SetBy      *uuid.UUID `json:"set_by,omitempty" db:"set_by"`
+	SetAt      time.Time  `json:"set_at" db:"set_at"`
+}
+
+// CIWithProvenance is a CI expanded with per-attribute provenance
+type CIWithProvenance struct {
+	*CI
+	Provenance map[string]AttributeProvenance `json:"provenance"`
+}
+
+// ParseProvenanceSource parses a "<type>:<name>" source descriptor. An empty value
+// means the change was made manually.
+func ParseProvenanceSource(value string) (ProvenanceSource, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ProvenanceSource{Type: ProvenanceSourceManual}, nil
+	}
+
+	sourceType, name, _ := strings.Cut(value, ":")
+	sourceType = strings.ToLower(strings.TrimSpace(sourceType))
+	name = strings.TrimSpace(name)
+
+	switch sourceType {
+	case ProvenanceSourceManual:
+		return ProvenanceSource{Type: sourceType}, nil
+	case ProvenanceSourceDiscovery, ProvenanceSourceImport:
+		if name == "" {
+			return ProvenanceSource{}, fmt.Errorf("%s source requires a name", sourceType)
+		}
+		return ProvenanceSource{Type: sourceType, Name: name}, nil
+	default:
+		return ProvenanceSource{}, fmt.Errorf("unknown provenance source type: %s", sourceType)
+	}
+}
+
+// ChangedAttributes returns the sorted names of attributes that were added,
+// modified or removed between two JSON attribute documents
+func ChangedAttributes(before, after json.RawMessage) ([]string, error) {
+	oldAttrs, err := decodeAttributes(before)
+	if err != nil {
+		return nil, err
+	}
+	newAttrs, err := decodeAttributes(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for name, value := range newAttrs {
+		if oldValue, ok := oldAttrs[name]; !ok || !reflect.DeepEqual(oldValue, value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldAttrs {
+		if _, ok := newAttrs[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed, nil
+}
+
+func decodeAttributes(data json.RawMessage) (map[string]interface{}, error) {
+	attrs := make(map[string]interface{})
+	if len(data) == 0 || string(data) == "null" {
+		return attrs, nil
+	}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attributes: %w", err)
+	}
+	return attrs, nil
+}
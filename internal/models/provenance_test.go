@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProvenanceSource(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected ProvenanceSource
+	}{
+		{"", ProvenanceSource{Type: ProvenanceSourceManual}},
+		{"manual", ProvenanceSource{Type: ProvenanceSourceManual}},
+		{"discovery:aws-scanner", ProvenanceSource{Type: ProvenanceSourceDiscovery, Name: "aws-scanner"}},
+		{" Import : nightly-csv ", ProvenanceSource{Type: ProvenanceSourceImport, Name: "nightly-csv"}},
+	}
+	for _, tt := range tests {
+		source, err := ParseProvenanceSource(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, source, tt.value)
+	}
+
+	for _, value := range []string{"discovery", "import:", "robot:x"} {
+		_, err := ParseProvenanceSource(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestChangedAttributes(t *testing.T) {
+	changed, err := ChangedAttributes(
+		json.RawMessage(`{"os": "linux", "cpu": 4, "ram": 16, "tags": ["a"]}`),
+		json.RawMessage(`{"os": "linux", "cpu": 8, "tags": ["a", "b"], "disk": 100}`),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cpu", "disk", "ram", "tags"}, changed, "added, modified and removed attributes, sorted")
+
+	changed, err = ChangedAttributes(nil, json.RawMessage(`{"os": "linux"}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"os"}, changed)
+
+	changed, err = ChangedAttributes(json.RawMessage(`null`), json.RawMessage(`{}`))
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	_, err = ChangedAttributes(json.RawMessage(`[1]`), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Relationship endpoint errors; a relationship may only be created between
+// existing, live, active CIs
+var (
+	ErrRelationshipEndpointNotFound = errors.New("CI not found")
+	ErrRelationshipEndpointDeleted  = errors.New("CI is deleted")
+	ErrRelationshipEndpointInactive = errors.New("CI is inactive")
+)
+
+// Relationship endpoint roles
+const (
+	RelationshipEndpointSource = "source"
+	RelationshipEndpointTarget = "target"
+)
+
+// RelationshipEndpointError rejects a relationship because of the CI at one end
+type RelationshipEndpointError struct {
+	Endpoint string
+	CIID     uuid.UUID
+	Err      error
+}
+
+func (e *RelationshipEndpointError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Endpoint, e.CIID, e.Err)
+}
+
+func (e *RelationshipEndpointError) Unwrap() error {
+	return e.Err
+}
+
+// Code is the machine-readable reason, e.g. "source_ci_deleted"
+func (e *RelationshipEndpointError) Code() string {
+	switch {
+	case errors.Is(e.Err, ErrRelationshipEndpointNotFound):
+		return e.Endpoint + "_ci_not_found"
+	case errors.Is(e.Err, ErrRelationshipEndpointDeleted):
+		return e.Endpoint + "_ci_deleted"
+	default:
+		return e.Endpoint + "_ci_inactive"
+	}
+}
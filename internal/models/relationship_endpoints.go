@@ -0,0 +1,10 @@
+package models
+
+// RelationshipWithEndpoints is a relationship expanded with summaries of the
+// CIs at both ends, so edges can be rendered with human-readable endpoints.
+// An end is left out when its CI no longer exists.
+type RelationshipWithEndpoints struct {
+	*CIRelationship
+	Source *CISummary `json:"source,omitempty"`
+	Target *CISummary `json:"target,omitempty"`
+}
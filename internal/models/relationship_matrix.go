@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxMatrixGroupSize bounds the members of each side of a relationship matrix
+const MaxMatrixGroupSize = 500
+
+// ErrMatrixGroupTooLarge is returned when a relationship matrix group matches
+// more CIs than MaxMatrixGroupSize; the filter must be narrowed
+var ErrMatrixGroupTooLarge = errors.New("relationship matrix group too large")
+
+// Relationship directions as seen from a given CI, e.g. the row CI of a matrix cell
+const (
+	RelationshipDirectionOutgoing = "outgoing"
+	RelationshipDirectionIncoming = "incoming"
+)
+
+// CIGroup selects the CIs on one side of a relationship matrix. Empty fields do
+// not filter, but at least one must be set.
+type CIGroup struct {
+	Type     string   `json:"type,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Owner    string   `json:"owner,omitempty"`
+	Location string   `json:"location,omitempty"`
+	OrgUnit  string   `json:"org_unit,omitempty"`
+}
+
+// IsEmpty reports whether the group has no filter, which would select every CI
+func (g CIGroup) IsEmpty() bool {
+	return g.Type == "" && len(g.Tags) == 0 && g.Owner == "" && g.Location == "" && g.OrgUnit == ""
+}
+
+// MatrixMember is a CI on one side of a relationship matrix
+type MatrixMember struct {
+	ID   uuid.UUID `json:"id" db:"id"`
+	Name string    `json:"name" db:"name"`
+	Type string    `json:"type" db:"type"`
+}
+
+// MatrixRelationship is a relationship between a row and a column CI
+type MatrixRelationship struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Direction string    `json:"direction"`
+}
+
+// MatrixCell lists the relationships between a row and a column CI. Only
+// non-empty cells are reported.
+type MatrixCell struct {
+	RowID         uuid.UUID            `json:"row_id"`
+	ColumnID      uuid.UUID            `json:"column_id"`
+	Relationships []MatrixRelationship `json:"relationships"`
+}
+
+// RelationshipMatrix reports the relationships between two groups of CIs.
+// UnlinkedRows lists the row CIs with no relationship to any column CI, the
+// usual completeness finding (e.g. applications with no database recorded).
+type RelationshipMatrix struct {
+	GeneratedAt      time.Time      `json:"generated_at"`
+	RowGroup         CIGroup        `json:"row_group"`
+	ColumnGroup      CIGroup        `json:"column_group"`
+	RelationshipType string         `json:"relationship_type,omitempty"`
+	Rows             []MatrixMember `json:"rows"`
+	Columns          []MatrixMember `json:"columns"`
+	Cells            []MatrixCell   `json:"cells"`
+	UnlinkedRows     []uuid.UUID    `json:"unlinked_rows"`
+	UnlinkedColumns  []uuid.UUID    `json:"unlinked_columns"`
+}
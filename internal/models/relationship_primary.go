@@ -0,0 +1,31 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Primary relationship errors; a CI has at most one primary relationship of
+// each type, and only live relationships can be primary
+var (
+	ErrPrimaryRelationshipTaken      = errors.New("CI already has a primary relationship of this type")
+	ErrPrimaryRelationshipDeprecated = errors.New("deprecated relationships cannot be primary")
+)
+
+// PrimaryRelationshipError rejects a primary relationship because its source
+// CI already has one of the same type
+type PrimaryRelationshipError struct {
+	SourceCIID uuid.UUID
+	Type       string
+	ExistingID uuid.UUID
+}
+
+func (e *PrimaryRelationshipError) Error() string {
+	return fmt.Sprintf("%v: CI %s, type %s, relationship %s", ErrPrimaryRelationshipTaken, e.SourceCIID, e.Type, e.ExistingID)
+}
+
+func (e *PrimaryRelationshipError) Unwrap() error {
+	return ErrPrimaryRelationshipTaken
+}
@@ -0,0 +1,42 @@
+package models
+
+import "fmt"
+
+// Relationship lifecycle states
+const (
+	// RelationshipStateProposed marks an edge suggested by discovery or inference that
+	// still needs confirmation; it is excluded from traversals and impact analysis
+	RelationshipStateProposed = "proposed"
+	// RelationshipStateActive marks a confirmed edge
+	RelationshipStateActive = "active"
+	// RelationshipStateDeprecated marks an edge kept for history only; it is excluded from traversals
+	RelationshipStateDeprecated = "deprecated"
+)
+
+// relationshipStateTransitions lists the allowed target states for each state
+var relationshipStateTransitions = map[string][]string{
+	RelationshipStateProposed:   {RelationshipStateActive, RelationshipStateDeprecated},
+	RelationshipStateActive:     {RelationshipStateDeprecated},
+	RelationshipStateDeprecated: {RelationshipStateActive},
+}
+
+// IsValidRelationshipState reports whether state is a known relationship lifecycle state
+func IsValidRelationshipState(state string) bool {
+	_, ok := relationshipStateTransitions[state]
+	return ok
+}
+
+// ValidateRelationshipStateTransition checks that a relationship may move from one state to another
+func ValidateRelationshipStateTransition(from, to string) error {
+	if !IsValidRelationshipState(to) {
+		return fmt.Errorf("invalid relationship state: %s", to)
+	}
+
+	for _, allowed := range relationshipStateTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot transition relationship from %s to %s", from, to)
+}
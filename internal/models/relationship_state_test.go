@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidRelationshipState(t *testing.T) {
+	for _, state := range []string{RelationshipStateProposed, RelationshipStateActive, RelationshipStateDeprecated} {
+		assert.True(t, IsValidRelationshipState(state), state)
+	}
+	assert.False(t, IsValidRelationshipState(""))
+	assert.False(t, IsValidRelationshipState("Active"))
+}
+
+func TestValidateRelationshipStateTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{RelationshipStateProposed, RelationshipStateActive, true},
+		{RelationshipStateProposed, RelationshipStateDeprecated, true},
+		{RelationshipStateActive, RelationshipStateDeprecated, true},
+		{RelationshipStateDeprecated, RelationshipStateActive, true},
+		{RelationshipStateActive, RelationshipStateProposed, false},
+		{RelationshipStateDeprecated, RelationshipStateProposed, false},
+		{RelationshipStateActive, RelationshipStateActive, false},
+		{RelationshipStateActive, "archived", false},
+	}
+	for _, tt := range tests {
+		err := ValidateRelationshipStateTransition(tt.from, tt.to)
+		if tt.allowed {
+			assert.NoError(t, err, "%s -> %s", tt.from, tt.to)
+		} else {
+			assert.Error(t, err, "%s -> %s", tt.from, tt.to)
+		}
+	}
+}
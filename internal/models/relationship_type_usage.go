@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrRelationshipTypeInUse is returned when deleting a relationship type that
+// live relationships still use; they must be removed or retyped first
+var ErrRelationshipTypeInUse = errors.New("relationship type is in use")
+
+// RelationshipTypeUsage counts the relationships of a relationship type
+type RelationshipTypeUsage struct {
+	SchemaID uuid.UUID `json:"schema_id" db:"schema_id"`
+	Name     string    `json:"name" db:"name"`
+	IsActive bool      `json:"is_active" db:"is_active"`
+	// ActiveCount counts the confirmed relationships of the type
+	ActiveCount int64 `json:"active_count" db:"active_count"`
+	// LiveCount counts the relationships not deleted, whatever their state;
+	// a type with any cannot be deleted
+	LiveCount int64 `json:"live_count" db:"live_count"`
+	// LastUsedAt is when a relationship of the type, deleted or not, was last
+	// created or changed
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
+
+// Unused reports whether no live relationship uses the type, so it can be deleted
+func (u RelationshipTypeUsage) Unused() bool {
+	return u.LiveCount == 0
+}
+
+// RelationshipTypeUsageReport lists the usage of every relationship type, by name
+type RelationshipTypeUsageReport struct {
+	Types []RelationshipTypeUsage `json:"types"`
+	// Unused names the types no live relationship uses
+	Unused []string `json:"unused"`
+}
+
+// NewRelationshipTypeUsageReport builds the report of the usage of each type
+func NewRelationshipTypeUsageReport(types []RelationshipTypeUsage) *RelationshipTypeUsageReport {
+	report := &RelationshipTypeUsageReport{Types: types, Unused: []string{}}
+	if report.Types == nil {
+		report.Types = []RelationshipTypeUsage{}
+	}
+	for _, usage := range report.Types {
+		if usage.Unused() {
+			report.Unused = append(report.Unused, usage.Name)
+		}
+	}
+	return report
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRelationshipTypeUsageReport(t *testing.T) {
+	report := NewRelationshipTypeUsageReport([]RelationshipTypeUsage{
+		{Name: "depends_on", ActiveCount: 3, LiveCount: 4},
+		{Name: "legacy_link"},
+		{Name: "proposed_only", LiveCount: 2},
+	})
+	assert.Len(t, report.Types, 3)
+	assert.Equal(t, []string{"legacy_link"}, report.Unused, "types with only proposed or deprecated relationships are still in use")
+
+	report = NewRelationshipTypeUsageReport(nil)
+	assert.NotNil(t, report.Types)
+	assert.NotNil(t, report.Unused)
+}
@@ -19,6 +19,8 @@ type Role struct {
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 	CreatedBy   uuid.UUID `json:"created_by" db:"created_by"`
 	UpdatedBy   uuid.UUID `json:"updated_by" db:"updated_by"`
+	// OrgID is the organization defining the role, nil for global roles
+	OrgID       *uuid.UUID `json:"org_id,omitempty" db:"org_id"`
 }
 
 // Permission represents a permission in the system
@@ -174,6 +176,27 @@ type RolePermissionResponse struct {
 	IsActive     bool              `json:"is_active"`
 }
 
+// RoleFilterOptions represents filtering options for role queries
+type RoleFilterOptions struct {
+	Name      string `json:"name,omitempty"`
+	IsActive  *bool  `json:"is_active,omitempty"`
+	IsDefault *bool  `json:"is_default,omitempty"`
+	IsSystem  *bool  `json:"is_system,omitempty"`
+	SortBy    string `json:"sort_by,omitempty"`
+	SortOrder string `json:"sort_order,omitempty"`
+}
+
+// PermissionFilterOptions represents filtering options for permission queries
+type PermissionFilterOptions struct {
+	Name      string `json:"name,omitempty"`
+	Resource  string `json:"resource,omitempty"`
+	Action    string `json:"action,omitempty"`
+	IsActive  *bool  `json:"is_active,omitempty"`
+	IsSystem  *bool  `json:"is_system,omitempty"`
+	SortBy    string `json:"sort_by,omitempty"`
+	SortOrder string `json:"sort_order,omitempty"`
+}
+
 // RoleList represents a paginated list of roles
 type RoleList struct {
 	Roles []RoleResponse `json:"roles"`
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// SchemaSortFields are the fields schema listings can be sorted by
+var SchemaSortFields = []string{"name", "created_at", "updated_at"}
+
+// ListSchemasRequest filters and sorts a CI or relationship type schema
+// listing. Empty fields do not filter.
+type ListSchemasRequest struct {
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+	// Search matches a substring of the schema name, case-insensitively
+	Search string `json:"search"`
+	// IsActive restricts the listing to active or inactive schemas
+	IsActive *bool `json:"is_active"`
+	// Attribute restricts the listing to schemas defining an attribute of this name
+	Attribute string     `json:"attribute"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	SortBy    string     `json:"sort_by"`
+	SortOrder string     `json:"sort_order"`
+}
+
+// Validate checks the sort options and defaults the page; the page size is
+// bounded by the page size limits of the request when the listing is run
+func (r *ListSchemasRequest) Validate() error {
+	if r.SortBy == "" {
+		r.SortBy = "name"
+	}
+	if !contains(SchemaSortFields, r.SortBy) {
+		return fmt.Errorf("invalid sort field %q: must be name, created_at or updated_at", r.SortBy)
+	}
+	if r.SortOrder == "" {
+		r.SortOrder = SortOrderAsc
+	}
+	if !contains(SortOrders, r.SortOrder) {
+		return fmt.Errorf("invalid sort order %q: must be asc or desc", r.SortOrder)
+	}
+
+	if r.Page <= 0 {
+		r.Page = 1
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSchemasRequest_Validate(t *testing.T) {
+	req := &ListSchemasRequest{}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, "name", req.SortBy)
+	assert.Equal(t, SortOrderAsc, req.SortOrder)
+	assert.Equal(t, 1, req.Page)
+
+	req = &ListSchemasRequest{SortBy: "updated_at", SortOrder: SortOrderDesc, Page: 3}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, 3, req.Page)
+
+	assert.Error(t, (&ListSchemasRequest{SortBy: "attributes"}).Validate())
+	assert.Error(t, (&ListSchemasRequest{SortOrder: "random"}).Validate())
+}
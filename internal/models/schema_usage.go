@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// MaxUsageValues bounds the distinct values reported per enum attribute; the
+// most frequent are kept, which leaves room for values outside the enum
+const MaxUsageValues = 100
+
+// SchemaUsage reports how the live CIs of a type use the attributes of its
+// schema, so schema owners can tell what is safe to deprecate or make required
+type SchemaUsage struct {
+	SchemaID   uuid.UUID        `json:"schema_id"`
+	SchemaName string           `json:"schema_name"`
+	TotalCIs   int64            `json:"total_cis"`
+	Attributes []AttributeUsage `json:"attributes"`
+	// Undeclared counts the attributes set on CIs of the type that the schema
+	// does not define, e.g. leftovers of an attribute removed from it
+	Undeclared []UndeclaredAttribute `json:"undeclared"`
+}
+
+// AttributeUsage counts the CIs setting a schema attribute
+type AttributeUsage struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Required   bool    `json:"required"`
+	SetCount   int64   `json:"set_count"`
+	UnsetCount int64   `json:"unset_count"`
+	SetPercent float64 `json:"set_percent"`
+	// Values is the distribution of an enum attribute, every enum value
+	// included; values outside the enum are flagged
+	Values []AttributeValueCount `json:"values,omitempty"`
+}
+
+// AttributeValueCount is the number of CIs setting an attribute to a value
+type AttributeValueCount struct {
+	Value  string `json:"value"`
+	Count  int64  `json:"count"`
+	InEnum bool   `json:"in_enum"`
+}
+
+// UndeclaredAttribute counts the CIs setting an attribute the schema does not define
+type UndeclaredAttribute struct {
+	Name     string `json:"name"`
+	SetCount int64  `json:"set_count"`
+}
+
+// EnumValues returns the allowed values of an enum attribute as text, or nil if
+// the attribute is not an enum
+func (a CITypeAttribute) EnumValues() []string {
+	var values []string
+	switch enum := a.Validation["enum"].(type) {
+	case []interface{}:
+		for _, value := range enum {
+			values = append(values, fmt.Sprint(value))
+		}
+	case []string:
+		values = append(values, enum...)
+	}
+	return values
+}
+
+// NewSchemaUsage assembles a usage report from the number of live CIs of the
+// type, the number setting each attribute and the value counts of its enum
+// attributes
+func NewSchemaUsage(schema *CITypeSchema, total int64, setCounts map[string]int64, valueCounts map[string]map[string]int64) *SchemaUsage {
+	usage := &SchemaUsage{
+		SchemaID:   schema.ID,
+		SchemaName: schema.Name,
+		TotalCIs:   total,
+		Attributes: []AttributeUsage{},
+		Undeclared: []UndeclaredAttribute{},
+	}
+
+	declared := map[string]bool{}
+	for _, attr := range schema.Attributes {
+		declared[attr.Name] = true
+		set := setCounts[attr.Name]
+		attrUsage := AttributeUsage{
+			Name:       attr.Name,
+			Type:       attr.Type,
+			Required:   attr.Required,
+			SetCount:   set,
+			UnsetCount: total - set,
+		}
+		if total > 0 {
+			attrUsage.SetPercent = float64(set) / float64(total) * 100
+		}
+		if enum := attr.EnumValues(); enum != nil {
+			attrUsage.Values = valueDistribution(enum, valueCounts[attr.Name])
+		}
+		usage.Attributes = append(usage.Attributes, attrUsage)
+	}
+
+	for name, set := range setCounts {
+		if !declared[name] {
+			usage.Undeclared = append(usage.Undeclared, UndeclaredAttribute{Name: name, SetCount: set})
+		}
+	}
+	sort.Slice(usage.Undeclared, func(i, j int) bool {
+		return usage.Undeclared[i].Name < usage.Undeclared[j].Name
+	})
+
+	return usage
+}
+
+// valueDistribution lists every enum value in enum order, then the values
+// outside the enum, most frequent first
+func valueDistribution(enum []string, counts map[string]int64) []AttributeValueCount {
+	values := []AttributeValueCount{}
+	inEnum := map[string]bool{}
+	for _, value := range enum {
+		inEnum[value] = true
+		values = append(values, AttributeValueCount{Value: value, Count: counts[value], InEnum: true})
+	}
+
+	var others []AttributeValueCount
+	for value, count := range counts {
+		if !inEnum[value] {
+			others = append(others, AttributeValueCount{Value: value, Count: count})
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		if others[i].Count != others[j].Count {
+			return others[i].Count > others[j].Count
+		}
+		return others[i].Value < others[j].Value
+	})
+
+	return append(values, others...)
+}
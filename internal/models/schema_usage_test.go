@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCITypeAttribute_EnumValues(t *testing.T) {
+	assert.Equal(t, []string{"prod", "1"}, CITypeAttribute{Validation: map[string]interface{}{"enum": []interface{}{"prod", 1}}}.EnumValues())
+	assert.Equal(t, []string{"a", "b"}, CITypeAttribute{Validation: map[string]interface{}{"enum": []string{"a", "b"}}}.EnumValues())
+	assert.Nil(t, CITypeAttribute{Validation: map[string]interface{}{"min": 1}}.EnumValues())
+	assert.Nil(t, CITypeAttribute{}.EnumValues())
+}
+
+func TestNewSchemaUsage(t *testing.T) {
+	schema := &CITypeSchema{
+		ID:   uuid.New(),
+		Name: "server",
+		Attributes: []CITypeAttribute{
+			{Name: "hostname", Type: AttributeTypeString, Required: true},
+			{Name: "environment", Type: AttributeTypeString, Validation: map[string]interface{}{"enum": []interface{}{"prod", "staging", "dev"}}},
+			{Name: "rack", Type: AttributeTypeString},
+		},
+	}
+
+	usage := NewSchemaUsage(schema, 4,
+		map[string]int64{"hostname": 4, "environment": 3, "legacy_id": 2, "asset": 1},
+		map[string]map[string]int64{"environment": {"prod": 1, "PROD": 1, "qa": 1}},
+	)
+	assert.Equal(t, schema.ID, usage.SchemaID)
+	assert.Equal(t, int64(4), usage.TotalCIs)
+
+	require.Len(t, usage.Attributes, 3)
+	hostname, environment, rack := usage.Attributes[0], usage.Attributes[1], usage.Attributes[2]
+	assert.Equal(t, AttributeUsage{Name: "hostname", Type: AttributeTypeString, Required: true, SetCount: 4, UnsetCount: 0, SetPercent: 100}, hostname)
+	assert.InDelta(t, 75, environment.SetPercent, 1e-9)
+	assert.Equal(t, []AttributeValueCount{
+		{Value: "prod", Count: 1, InEnum: true},
+		{Value: "staging", InEnum: true},
+		{Value: "dev", InEnum: true},
+		{Value: "PROD", Count: 1},
+		{Value: "qa", Count: 1},
+	}, environment.Values, "enum values in enum order, then the rest by count and value")
+	assert.Equal(t, int64(4), rack.UnsetCount)
+	assert.Nil(t, rack.Values)
+
+	assert.Equal(t, []UndeclaredAttribute{{Name: "asset", SetCount: 1}, {Name: "legacy_id", SetCount: 2}}, usage.Undeclared)
+
+	usage = NewSchemaUsage(schema, 0, nil, nil)
+	assert.Zero(t, usage.Attributes[0].SetPercent, "no CIs must not divide by zero")
+	assert.Empty(t, usage.Undeclared)
+}
@@ -530,6 +530,28 @@ func (v *SchemaValidator) ValidateSchemaDefinition(schema CITypeSchema) Validati
 				})
 			}
 		}
+
+		// Validate form widget hint
+		if attr.UI != nil && attr.UI.Widget != "" && !IsValidWidget(attr.UI.Widget) {
+			result.IsValid = false
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   fmt.Sprintf("attributes[%d].ui.widget", i),
+				Value:   attr.UI.Widget,
+				Message: fmt.Sprintf("Invalid widget: %s", attr.UI.Widget),
+			})
+		}
+
+		// Validate CI reference
+		if attr.Reference != nil {
+			if err := attr.Reference.validate(attr.Type); err != nil {
+				result.IsValid = false
+				result.Errors = append(result.Errors, ValidationError{
+					Field:   fmt.Sprintf("attributes[%d].reference", i),
+					Value:   attr.Reference,
+					Message: fmt.Sprintf("Invalid reference: %v", err),
+				})
+			}
+		}
 	}
 
 	return result
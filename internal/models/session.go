@@ -114,6 +114,8 @@ type CreateSessionRequest struct {
 	RefreshToken string    `json:"refresh_token" validate:"required"`
 	IPAddress    string    `json:"ip_address" validate:"required"`
 	UserAgent    string    `json:"user_agent" validate:"required"`
+	DeviceID     string    `json:"device_id,omitempty"`
+	TenantID     string    `json:"tenant_id,omitempty"`
 	ExpiresAt    time.Time `json:"expires_at" validate:"required"`
 }
 
@@ -253,6 +255,7 @@ const (
 	SessionActionRevoked    = "revoked"
 	SessionActionExpired    = "expired"
 	SessionActionLoggedOut  = "logged_out"
+	SessionActionFingerprintChanged = "fingerprint_changed"
 )
 
 // Constants for session validation
@@ -260,6 +263,6 @@ const (
 	DefaultSessionTTL       = 24 * time.Hour
 	MaxSessionTTL          = 30 * 24 * time.Hour
 	MinSessionTTL          = 15 * time.Minute
-	MaxConcurrentSessions  = 5
+	MaxConcurrentSessions  = 5 // used when no per-role session limits are configured
 	SessionCleanupInterval = 1 * time.Hour
 )
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Directions a dependency graph is followed in from a CI
+const (
+	// GraphDirectionUpstream follows relationships from target to source, to
+	// the CIs depending on the CI
+	GraphDirectionUpstream = "upstream"
+	// GraphDirectionDownstream follows relationships from source to target, to
+	// the CIs the CI depends on
+	GraphDirectionDownstream = "downstream"
+	// GraphDirectionBoth follows relationships either way
+	GraphDirectionBoth = "both"
+)
+
+// GraphDirections lists the directions a dependency graph can be followed in
+var GraphDirections = []string{GraphDirectionUpstream, GraphDirectionDownstream, GraphDirectionBoth}
+
+// DefaultTopologyDepth is the relationship hops followed when none are requested
+const DefaultTopologyDepth = 3
+
+// RelationshipVersion is a relationship as it was during the period it was valid
+type RelationshipVersion struct {
+	RelationshipID uuid.UUID       `json:"id" db:"relationship_id"`
+	SourceCIID     uuid.UUID       `json:"source_ci_id" db:"source_ci_id"`
+	TargetCIID     uuid.UUID       `json:"target_ci_id" db:"target_ci_id"`
+	Type           string          `json:"type" db:"type"`
+	Attributes     json.RawMessage `json:"attributes" db:"attributes"`
+	Description    string          `json:"description" db:"description"`
+	IsActive       bool            `json:"is_active" db:"is_active"`
+	State          string          `json:"state" db:"state"`
+	IsPrimary      bool            `json:"is_primary" db:"is_primary"`
+	ValidFrom      time.Time       `json:"valid_from" db:"valid_from"`
+	ValidTo        *time.Time      `json:"valid_to,omitempty" db:"valid_to"`
+	ChangedBy      *uuid.UUID      `json:"changed_by,omitempty" db:"changed_by"`
+	// Backfilled versions were recorded from the relationships existing when
+	// versioning started; their content may postdate ValidFrom
+	Backfilled bool `json:"backfilled" db:"backfilled"`
+}
+
+// Relationship returns the version as a relationship, e.g. for impact analysis
+func (v RelationshipVersion) Relationship() CIRelationship {
+	rel := CIRelationship{
+		ID:          v.RelationshipID,
+		SourceCIID:  v.SourceCIID,
+		TargetCIID:  v.TargetCIID,
+		Type:        v.Type,
+		Attributes:  v.Attributes,
+		Description: v.Description,
+		IsActive:    v.IsActive,
+		State:       v.State,
+		IsPrimary:   v.IsPrimary,
+		UpdatedAt:   v.ValidFrom,
+	}
+	if v.ChangedBy != nil {
+		rel.UpdatedBy = *v.ChangedBy
+	}
+	return rel
+}
+
+// TopologySnapshot is the dependency graph around a CI as it was at a time.
+// CIs are reported as they are now, including those deleted since.
+type TopologySnapshot struct {
+	CIID          uuid.UUID             `json:"ci_id"`
+	AsOf          time.Time             `json:"as_of"`
+	Direction     string                `json:"direction"`
+	Depth         int                   `json:"depth"`
+	CIs           []CI                  `json:"cis"`
+	Relationships []RelationshipVersion `json:"relationships"`
+	// Approximate is set when some relationships come from backfilled
+	// versions, which may not reflect their content at the time
+	Approximate bool `json:"approximate"`
+	HiddenCount int  `json:"hidden_count"`
+}
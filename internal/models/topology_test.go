@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelationshipVersion_Relationship(t *testing.T) {
+	validFrom := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	validTo := validFrom.Add(48 * time.Hour)
+	changedBy := uuid.New()
+	version := RelationshipVersion{
+		RelationshipID: uuid.New(),
+		SourceCIID:     uuid.New(),
+		TargetCIID:     uuid.New(),
+		Type:           "depends_on",
+		Attributes:     json.RawMessage(`{"port": 5432}`),
+		Description:    "primary database",
+		IsActive:       true,
+		State:          RelationshipStateActive,
+		IsPrimary:      true,
+		ValidFrom:      validFrom,
+		ValidTo:        &validTo,
+		ChangedBy:      &changedBy,
+	}
+
+	rel := version.Relationship()
+	assert.Equal(t, version.RelationshipID, rel.ID)
+	assert.Equal(t, version.SourceCIID, rel.SourceCIID)
+	assert.Equal(t, version.TargetCIID, rel.TargetCIID)
+	assert.Equal(t, "depends_on", rel.Type)
+	assert.JSONEq(t, `{"port": 5432}`, string(rel.Attributes))
+	assert.Equal(t, "primary database", rel.Description)
+	assert.True(t, rel.IsActive)
+	assert.Equal(t, RelationshipStateActive, rel.State)
+	assert.True(t, rel.IsPrimary)
+	assert.Equal(t, validFrom, rel.UpdatedAt, "the version's start is when the relationship was last updated")
+	assert.Equal(t, changedBy, rel.UpdatedBy)
+
+	version.ChangedBy = nil
+	assert.Equal(t, uuid.Nil, version.Relationship().UpdatedBy)
+}
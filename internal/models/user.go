@@ -22,6 +22,9 @@ type User struct {
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 	CreatedBy      uuid.UUID `json:"created_by" db:"created_by"`
 	UpdatedBy      uuid.UUID `json:"updated_by" db:"updated_by"`
+	// OrgID is the organization the user was created in, nil for users
+	// created outside any organization
+	OrgID          *uuid.UUID `json:"org_id,omitempty" db:"org_id"`
 }
 
 // UserResponse represents a user response without sensitive data
@@ -75,6 +78,13 @@ type LoginResponse struct {
 	TokenType    string     `json:"token_type"`
 	ExpiresIn    int64      `json:"expires_in"`
 	User         UserResponse `json:"user"`
+	// Organization is the slug of the organization the tokens act for
+	Organization string     `json:"organization,omitempty"`
+}
+
+// SwitchOrganizationRequest represents a request to act for another organization
+type SwitchOrganizationRequest struct {
+	Organization string `json:"organization" validate:"required"`
 }
 
 // RefreshTokenRequest represents a refresh token request
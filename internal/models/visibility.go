@@ -0,0 +1,64 @@
+package models
+
+// TenantAttribute is the CI attribute holding the tenant a CI belongs to.
+// CIs without it are shared and visible to every tenant.
+const TenantAttribute = "tenant_id"
+
+// VisibilityScope describes which CIs a caller may see. An unrestricted scope sees
+// every CI; otherwise a CI is visible when it matches any of the listed types,
+// tags, owners or locations. A non-empty Tenant additionally hides CIs of other tenants.
+type VisibilityScope struct {
+	Unrestricted bool     `json:"unrestricted"`
+	Types        []string `json:"types,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Owners       []string `json:"owners,omitempty"`
+	Locations    []string `json:"locations,omitempty"`
+	Tenant       string   `json:"tenant,omitempty"`
+}
+
+// SeesNothing reports whether the scope cannot match any CI
+func (s *VisibilityScope) SeesNothing() bool {
+	return !s.Unrestricted && len(s.Types) == 0 && len(s.Tags) == 0 && len(s.Owners) == 0 && len(s.Locations) == 0
+}
+
+// Allows reports whether a CI is visible within the scope
+func (s *VisibilityScope) Allows(ci *CI) bool {
+	if s.Tenant != "" {
+		tenant, err := CITenant(ci)
+		if err != nil || (tenant != "" && tenant != s.Tenant) {
+			return false
+		}
+	}
+
+	if s.Unrestricted {
+		return true
+	}
+	if containsString(s.Types, ci.Type) || containsString(s.Owners, ci.Owner) || containsString(s.Locations, ci.Location) {
+		return true
+	}
+	for _, tag := range ci.Tags {
+		if containsString(s.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// CITenant returns the tenant a CI belongs to, empty for shared CIs
+func CITenant(ci *CI) (string, error) {
+	attrs, err := decodeAttributes(ci.Attributes)
+	if err != nil {
+		return "", err
+	}
+	tenant, _ := attrs[TenantAttribute].(string)
+	return tenant, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
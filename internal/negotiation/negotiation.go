@@ -0,0 +1,235 @@
+// Package negotiation validates the media types of requests per route. Each
+// route has a rule listing the media types it consumes and produces: a request
+// body of another type, or in a charset other than UTF-8, is refused with 415,
+// and a request accepting none of the produced types with 406. Both errors
+// list the supported types. Routes without a rule of their own use the default
+// rule, JSON in and out, so endpoints taking uploads, CSV or YAML declare so
+// instead of being blocked by a global JSON-only check.
+package negotiation
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Media types of request and response bodies
+const (
+	JSON      = "application/json"
+	NDJSON    = "application/x-ndjson"
+	Form      = "application/x-www-form-urlencoded"
+	Multipart = "multipart/form-data"
+	CSV       = "text/csv"
+	YAML      = "application/yaml"
+	XLSX      = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// yamlAliases are the other media types YAML is sent as
+var yamlAliases = []string{"application/x-yaml", "text/yaml", "text/x-yaml"}
+
+// Rule lists the media types a route consumes and produces. Empty Consumes
+// accepts bodies of any type and empty Produces any Accept header.
+type Rule struct {
+	Consumes []string
+	Produces []string
+}
+
+// JSONRule is the rule of routes taking and returning JSON
+var JSONRule = Rule{Consumes: []string{JSON}, Produces: []string{JSON}}
+
+// consumes reports whether the rule accepts bodies of a media type
+func (rule Rule) consumes(mediaType string) bool {
+	if len(rule.Consumes) == 0 {
+		return true
+	}
+	for _, consumed := range rule.Consumes {
+		if mediaType == consumed {
+			return true
+		}
+		if consumed == YAML && contains(yamlAliases, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy resolves the rule of requests and enforces it
+type Policy struct {
+	rule   Rule
+	routes map[string]Rule
+}
+
+// NewPolicy creates a policy applying rule to every route but those in
+// routes. Routes are keyed by a mux path template, or the literal path on
+// routers resolving routes after their middleware, optionally prefixed by a
+// method, e.g. "POST /api/v1/import/xlsx"; a path ending in "/*" covers every
+// path below it.
+func NewPolicy(rule Rule, routes map[string]Rule) *Policy {
+	p := &Policy{rule: rule, routes: make(map[string]Rule, len(routes))}
+	for route, routeRule := range routes {
+		p.routes[normalizeRoute(route)] = routeRule
+	}
+	return p
+}
+
+// For returns the rule of the route a request matched, preferring a method
+// specific entry, else the default rule
+func (p *Policy) For(r *http.Request) Rule {
+	if len(p.routes) == 0 {
+		return p.rule
+	}
+	method := strings.ToUpper(r.Method)
+
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			path = template
+		}
+	}
+	if rule, ok := p.routes[method+" "+path]; ok {
+		return rule
+	}
+	if rule, ok := p.routes[path]; ok {
+		return rule
+	}
+
+	// The longest wildcard covering the path wins
+	for prefix := path; prefix != ""; {
+		i := strings.LastIndex(prefix, "/")
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+		if rule, ok := p.routes[method+" "+prefix+"/*"]; ok {
+			return rule
+		}
+		if rule, ok := p.routes[prefix+"/*"]; ok {
+			return rule
+		}
+	}
+	return p.rule
+}
+
+// Middleware refuses requests whose body or Accept header the rule of their
+// route does not allow
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rule := p.For(r)
+
+		if hasBody(r) {
+			if err := checkContentType(rule, r.Header.Get("Content-Type")); err != nil {
+				respond(w, http.StatusUnsupportedMediaType, "Unsupported media type", err.Error(), rule.Consumes)
+				return
+			}
+		}
+		if len(rule.Produces) > 0 && !Accepts(r.Header.Get("Accept"), rule.Produces...) {
+			respond(w, http.StatusNotAcceptable, "Not acceptable",
+				fmt.Sprintf("none of %q can be produced", r.Header.Get("Accept")), rule.Produces)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasBody reports whether a request carries a body, whose length is unknown
+// when it is chunked
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
+
+// checkContentType checks the Content-Type of a request body against a rule.
+// Text bodies must be UTF-8, the only charset the API decodes.
+func checkContentType(rule Rule, contentType string) error {
+	if len(rule.Consumes) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(contentType) == "" {
+		return fmt.Errorf("a Content-Type is required")
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid Content-Type %q", contentType)
+	}
+	if !rule.consumes(mediaType) {
+		return fmt.Errorf("Content-Type %s is not supported", mediaType)
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "utf8") {
+		return fmt.Errorf("charset %s is not supported, send UTF-8", charset)
+	}
+	return nil
+}
+
+// Accepts reports whether an Accept header allows any of the given media
+// types. A missing header accepts anything; ranges with q=0 are refused.
+func Accepts(accept string, mediaTypes ...string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	for _, accepted := range strings.Split(accept, ",") {
+		accepted, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+		for _, mediaType := range mediaTypes {
+			if matches(accepted, mediaType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether a media range covers a media type
+func matches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	if kind, ok := strings.CutSuffix(mediaRange, "/*"); ok {
+		return strings.HasPrefix(mediaType, kind+"/")
+	}
+	return false
+}
+
+// respond reports a refused request with the media types that are supported
+func respond(w http.ResponseWriter, status int, message, details string, supported []string) {
+	response, _ := json.Marshal(map[string]interface{}{
+		"error":     message,
+		"success":   false,
+		"details":   details,
+		"supported": supported,
+	})
+
+	w.Header().Set("Content-Type", JSON)
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// normalizeRoute uppercases the method of a "[METHOD ]/path/template" entry
+func normalizeRoute(route string) string {
+	route = strings.TrimSpace(route)
+	if method, path, ok := strings.Cut(route, " "); ok {
+		return strings.ToUpper(method) + " " + strings.TrimSpace(path)
+	}
+	return route
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package negotiation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccepts(t *testing.T) {
+	assert.True(t, Accepts("", JSON))
+	assert.True(t, Accepts("application/json, text/plain, */*", CSV))
+	assert.True(t, Accepts("text/*;q=0.5", CSV))
+	assert.True(t, Accepts("text/csv; charset=utf-8", JSON, CSV))
+	assert.False(t, Accepts("text/html", JSON))
+	assert.False(t, Accepts("application/json;q=0, text/html", JSON))
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	policy := NewPolicy(JSONRule, map[string]Rule{
+		"post /api/v1/import/xlsx":   {Consumes: []string{Multipart}, Produces: []string{JSON}},
+		"/api/v1/manifests/{action}": {Consumes: []string{YAML, JSON}, Produces: []string{JSON}},
+		"GET /api/v1/cis/export":     {Produces: []string{JSON, CSV}},
+		"/scim/v2/*":                 {Consumes: []string{"application/scim+json"}},
+	})
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/cis", ok).Methods("GET", "POST")
+	router.HandleFunc("/api/v1/import/xlsx", ok).Methods("POST")
+	router.HandleFunc("/api/v1/manifests/{action}", ok).Methods("POST")
+	router.HandleFunc("/api/v1/cis/export", ok).Methods("GET")
+	router.HandleFunc("/scim/v2/Users/{id}", ok).Methods("PUT")
+	router.Use(policy.Middleware)
+
+	cases := []struct {
+		method, path, contentType, accept string
+		status                            int
+	}{
+		{"GET", "/api/v1/cis", "", "", http.StatusOK},
+		{"GET", "/api/v1/cis", "", "application/json", http.StatusOK},
+		{"GET", "/api/v1/cis", "", "text/csv", http.StatusNotAcceptable},
+		{"POST", "/api/v1/cis", "application/json; charset=UTF-8", "", http.StatusOK},
+		{"POST", "/api/v1/cis", "application/json; charset=latin1", "", http.StatusUnsupportedMediaType},
+		{"POST", "/api/v1/cis", "text/plain", "", http.StatusUnsupportedMediaType},
+		{"POST", "/api/v1/cis", "", "", http.StatusUnsupportedMediaType},
+		{"POST", "/api/v1/import/xlsx", "multipart/form-data; boundary=x", "", http.StatusOK},
+		{"POST", "/api/v1/import/xlsx", "application/json", "", http.StatusUnsupportedMediaType},
+		{"POST", "/api/v1/manifests/apply", "application/x-yaml", "", http.StatusOK},
+		{"GET", "/api/v1/cis/export", "", "text/csv", http.StatusOK},
+		{"GET", "/api/v1/cis/export", "", "application/xml", http.StatusNotAcceptable},
+		{"PUT", "/scim/v2/Users/1", "application/scim+json", "", http.StatusOK},
+		{"PUT", "/scim/v2/Users/1", "application/json", "", http.StatusUnsupportedMediaType},
+	}
+	for _, c := range cases {
+		body := ""
+		if c.method != "GET" {
+			body = "{}"
+		}
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(body))
+		if c.contentType != "" {
+			req.Header.Set("Content-Type", c.contentType)
+		}
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, c.status, rec.Code, "%s %s %q %q", c.method, c.path, c.contentType, c.accept)
+	}
+
+	// Refusals list what is supported
+	req := httptest.NewRequest("POST", "/api/v1/import/xlsx", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "Unsupported media type", response["error"])
+	assert.Equal(t, []interface{}{Multipart}, response["supported"])
+}
+
+func TestPolicyForLiteralPaths(t *testing.T) {
+	// Without mux routes, as on routers resolving routes after middleware,
+	// rules are found by the literal path
+	policy := NewPolicy(JSONRule, map[string]Rule{
+		"GET /api/v1/graph/export": {Produces: []string{CSV}},
+		"/scim/v2/*":               {Consumes: []string{"application/scim+json"}},
+	})
+	assert.Equal(t, []string{CSV}, policy.For(httptest.NewRequest("GET", "/api/v1/graph/export", nil)).Produces)
+	assert.Equal(t, JSONRule, policy.For(httptest.NewRequest("POST", "/api/v1/graph/export", nil)))
+	assert.Equal(t, []string{"application/scim+json"}, policy.For(httptest.NewRequest("PATCH", "/scim/v2/Groups/1", nil)).Consumes)
+	assert.Equal(t, JSONRule, policy.For(httptest.NewRequest("GET", "/scim", nil)))
+}
@@ -0,0 +1,251 @@
+// Package netflow ingests aggregated network flow records (NetFlow, sFlow,
+// IPFIX) and turns them into "communicates_with" relationships between the
+// CIs resolved by IP address. Each relationship carries the ports and
+// protocols observed with their packet, byte and flow counters, giving an
+// empirical dependency layer next to the declared dependencies.
+package netflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RelationshipType is the type of the relationships created from flows
+const RelationshipType = "communicates_with"
+
+// Flow sources
+const (
+	SourceNetFlow = "netflow"
+	SourceSFlow   = "sflow"
+	SourceIPFIX   = "ipfix"
+)
+
+// Limits
+const (
+	// MaxRecords bounds the flow records ingested at once
+	MaxRecords = 10000
+	// MaxPorts bounds the port/protocol pairs kept on a relationship; the
+	// least recently seen are dropped first
+	MaxPorts = 256
+	// MaxUnresolvedIPs bounds the unresolved addresses listed in a result
+	MaxUnresolvedIPs = 100
+)
+
+var ErrInvalidBatch = errors.New("invalid flow batch")
+
+// protocols maps the accepted protocol names and IANA numbers to names
+var protocols = map[string]string{
+	"tcp": "tcp", "6": "tcp",
+	"udp": "udp", "17": "udp",
+	"icmp": "icmp", "1": "icmp",
+	"sctp": "sctp", "132": "sctp",
+}
+
+var sources = map[string]bool{SourceNetFlow: true, SourceSFlow: true, SourceIPFIX: true}
+
+// Batch is a set of aggregated flow records from one collector
+type Batch struct {
+	// Source is the flow technology the records come from; netflow if empty
+	Source  string   `json:"source"`
+	Records []Record `json:"records"`
+}
+
+// Record is an aggregated flow from a source address to a destination port.
+// Protocol is a name (tcp, udp, icmp, sctp) or its IANA number.
+type Record struct {
+	SourceIP        string    `json:"source_ip"`
+	DestinationIP   string    `json:"destination_ip"`
+	DestinationPort int       `json:"destination_port"`
+	Protocol        string    `json:"protocol"`
+	Packets         int64     `json:"packets"`
+	Bytes           int64     `json:"bytes"`
+	Flows           int64     `json:"flows"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+// normalize validates a record and returns it with canonical addresses and
+// protocol; a record without a flow count counts as one flow
+func (r Record) normalize() (Record, error) {
+	src, err := netip.ParseAddr(r.SourceIP)
+	if err != nil {
+		return r, fmt.Errorf("invalid source_ip %q", r.SourceIP)
+	}
+	dst, err := netip.ParseAddr(r.DestinationIP)
+	if err != nil {
+		return r, fmt.Errorf("invalid destination_ip %q", r.DestinationIP)
+	}
+	protocol, ok := protocols[strings.ToLower(strings.TrimSpace(r.Protocol))]
+	if !ok {
+		return r, fmt.Errorf("unsupported protocol %q", r.Protocol)
+	}
+	if protocol == "icmp" {
+		r.DestinationPort = 0
+	} else if r.DestinationPort < 1 || r.DestinationPort > 65535 {
+		return r, fmt.Errorf("destination_port must be between 1 and 65535")
+	}
+	if r.Packets < 0 || r.Bytes < 0 || r.Flows < 0 {
+		return r, fmt.Errorf("counters must not be negative")
+	}
+	if r.FirstSeen.IsZero() || r.LastSeen.IsZero() {
+		return r, fmt.Errorf("first_seen and last_seen are required")
+	}
+	if r.LastSeen.Before(r.FirstSeen) {
+		return r, fmt.Errorf("last_seen must not be before first_seen")
+	}
+	if r.Flows == 0 {
+		r.Flows = 1
+	}
+
+	r.SourceIP = src.Unmap().String()
+	r.DestinationIP = dst.Unmap().String()
+	r.Protocol = protocol
+	return r, nil
+}
+
+// PortStats are the counters of the traffic to one port and protocol
+type PortStats struct {
+	Port      int       `json:"port"`
+	Protocol  string    `json:"protocol"`
+	Packets   int64     `json:"packets"`
+	Bytes     int64     `json:"bytes"`
+	Flows     int64     `json:"flows"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// add folds the counters of other into s
+func (s *PortStats) add(other PortStats) {
+	s.Packets += other.Packets
+	s.Bytes += other.Bytes
+	s.Flows += other.Flows
+	if s.FirstSeen.IsZero() || other.FirstSeen.Before(s.FirstSeen) {
+		s.FirstSeen = other.FirstSeen
+	}
+	if other.LastSeen.After(s.LastSeen) {
+		s.LastSeen = other.LastSeen
+	}
+}
+
+// Observation is the traffic seen from one CI to another in a batch
+type Observation struct {
+	SourceCIID uuid.UUID   `json:"source_ci_id"`
+	TargetCIID uuid.UUID   `json:"target_ci_id"`
+	Source     string      `json:"source"`
+	Ports      []PortStats `json:"ports"`
+}
+
+// Annotation is what a communicates_with relationship records in its
+// attributes: the totals over every port and the counters of each port
+type Annotation struct {
+	ObservedBy []string    `json:"observed_by"`
+	Packets    int64       `json:"packets"`
+	Bytes      int64       `json:"bytes"`
+	Flows      int64       `json:"flows"`
+	FirstSeen  time.Time   `json:"first_seen"`
+	LastSeen   time.Time   `json:"last_seen"`
+	Ports      []PortStats `json:"ports"`
+}
+
+// add folds an observation into the annotation
+func (a *Annotation) add(obs Observation) {
+	found := false
+	for _, source := range a.ObservedBy {
+		found = found || source == obs.Source
+	}
+	if !found {
+		a.ObservedBy = append(a.ObservedBy, obs.Source)
+		sort.Strings(a.ObservedBy)
+	}
+
+	for _, observed := range obs.Ports {
+		merged := false
+		for i := range a.Ports {
+			if a.Ports[i].Port == observed.Port && a.Ports[i].Protocol == observed.Protocol {
+				a.Ports[i].add(observed)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			a.Ports = append(a.Ports, observed)
+		}
+	}
+
+	if len(a.Ports) > MaxPorts {
+		sort.SliceStable(a.Ports, func(i, j int) bool { return a.Ports[i].LastSeen.After(a.Ports[j].LastSeen) })
+		a.Ports = a.Ports[:MaxPorts]
+	}
+	sort.Slice(a.Ports, func(i, j int) bool {
+		if a.Ports[i].Protocol != a.Ports[j].Protocol {
+			return a.Ports[i].Protocol < a.Ports[j].Protocol
+		}
+		return a.Ports[i].Port < a.Ports[j].Port
+	})
+
+	// Totals are kept across dropped ports, so they only ever grow
+	total := PortStats{Packets: a.Packets, Bytes: a.Bytes, Flows: a.Flows, FirstSeen: a.FirstSeen, LastSeen: a.LastSeen}
+	for _, observed := range obs.Ports {
+		total.add(observed)
+	}
+	a.Packets, a.Bytes, a.Flows = total.Packets, total.Bytes, total.Flows
+	a.FirstSeen, a.LastSeen = total.FirstSeen, total.LastSeen
+}
+
+// MergeAttributes folds an observation into the attributes of a relationship,
+// keeping any attribute the annotation does not own
+func MergeAttributes(existing json.RawMessage, obs Observation) (json.RawMessage, error) {
+	attributes := map[string]json.RawMessage{}
+	var annotation Annotation
+	if len(existing) > 0 && string(existing) != "null" {
+		if err := json.Unmarshal(existing, &attributes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal relationship attributes: %w", err)
+		}
+		if err := json.Unmarshal(existing, &annotation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal flow annotation: %w", err)
+		}
+	}
+	annotation.add(obs)
+
+	data, err := json.Marshal(annotation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal flow annotation: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to marshal flow annotation: %w", err)
+	}
+	for key, value := range fields {
+		attributes[key] = value
+	}
+	return json.Marshal(attributes)
+}
+
+// Rejection is a record that was not ingested, by index in the batch
+type Rejection struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// Result is the outcome of ingesting a batch
+type Result struct {
+	Received int         `json:"received"`
+	Rejected []Rejection `json:"rejected"`
+	// Unresolved counts the records whose source or destination matched no
+	// CI, or more than one
+	Unresolved    int      `json:"unresolved"`
+	UnresolvedIPs []string `json:"unresolved_ips"`
+	// SelfFlows counts the records between two addresses of the same CI
+	SelfFlows int `json:"self_flows"`
+	Created   int `json:"created"`
+	Refreshed int `json:"refreshed"`
+	// Failed lists the CI pairs whose relationship could not be recorded
+	Failed []string `json:"failed"`
+}
@@ -0,0 +1,212 @@
+package netflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore resolves addresses from a map and keeps relationship attributes
+// by CI pair
+type memoryStore struct {
+	ips       map[string][]uuid.UUID
+	relations map[pair]json.RawMessage
+	fail      map[pair]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{ips: map[string][]uuid.UUID{}, relations: map[pair]json.RawMessage{}, fail: map[pair]bool{}}
+}
+
+func (m *memoryStore) ResolveIPs(ctx context.Context, ips []string) (map[string][]uuid.UUID, error) {
+	resolved := map[string][]uuid.UUID{}
+	for _, ip := range ips {
+		if ids, ok := m.ips[ip]; ok {
+			resolved[ip] = ids
+		}
+	}
+	return resolved, nil
+}
+
+func (m *memoryStore) Record(ctx context.Context, obs Observation, at time.Time, by uuid.UUID) (bool, error) {
+	key := pair{source: obs.SourceCIID, target: obs.TargetCIID}
+	if m.fail[key] {
+		return false, errors.New("endpoint is deleted")
+	}
+	existing, found := m.relations[key]
+	attributes, err := MergeAttributes(existing, obs)
+	if err != nil {
+		return false, err
+	}
+	m.relations[key] = attributes
+	return !found, nil
+}
+
+func (m *memoryStore) annotation(t *testing.T, source, target uuid.UUID) Annotation {
+	var annotation Annotation
+	require.NoError(t, json.Unmarshal(m.relations[pair{source: source, target: target}], &annotation))
+	return annotation
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store) *Service {
+	service := NewService(store)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func flow(src, dst string, port int, protocol string, packets int64) Record {
+	return Record{
+		SourceIP:        src,
+		DestinationIP:   dst,
+		DestinationPort: port,
+		Protocol:        protocol,
+		Packets:         packets,
+		Bytes:           packets * 100,
+		FirstSeen:       now.Add(-time.Hour),
+		LastSeen:        now.Add(-time.Minute),
+	}
+}
+
+func TestIngestAggregatesPerPairAndPort(t *testing.T) {
+	store := newMemoryStore()
+	web, db := uuid.New(), uuid.New()
+	store.ips["10.0.0.1"] = []uuid.UUID{web}
+	store.ips["10.0.0.2"] = []uuid.UUID{db}
+	service := newTestService(store)
+
+	result, err := service.Ingest(context.Background(), Batch{Records: []Record{
+		flow("10.0.0.1", "10.0.0.2", 5432, "tcp", 10),
+		flow("10.0.0.1", "10.0.0.2", 5432, "6", 5),
+		flow("10.0.0.1", "10.0.0.2", 53, "udp", 1),
+	}}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Received)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 0, result.Refreshed)
+
+	annotation := store.annotation(t, web, db)
+	assert.Equal(t, []string{SourceNetFlow}, annotation.ObservedBy)
+	assert.Equal(t, int64(16), annotation.Packets)
+	assert.Equal(t, int64(3), annotation.Flows)
+	require.Len(t, annotation.Ports, 2)
+	assert.Equal(t, PortStats{Port: 5432, Protocol: "tcp", Packets: 15, Bytes: 1500, Flows: 2,
+		FirstSeen: now.Add(-time.Hour), LastSeen: now.Add(-time.Minute)}, annotation.Ports[0])
+	assert.Equal(t, "udp", annotation.Ports[1].Protocol)
+}
+
+func TestIngestRefreshesExistingRelationship(t *testing.T) {
+	store := newMemoryStore()
+	web, db := uuid.New(), uuid.New()
+	store.ips["10.0.0.1"] = []uuid.UUID{web}
+	store.ips["10.0.0.2"] = []uuid.UUID{db}
+	store.relations[pair{source: web, target: db}] = json.RawMessage(`{"owner":"network-team"}`)
+	service := newTestService(store)
+
+	_, err := service.Ingest(context.Background(), Batch{Records: []Record{flow("10.0.0.1", "10.0.0.2", 443, "tcp", 10)}}, uuid.New())
+	require.NoError(t, err)
+
+	later := flow("10.0.0.1", "10.0.0.2", 443, "tcp", 4)
+	later.LastSeen = now
+	result, err := service.Ingest(context.Background(), Batch{Source: SourceSFlow, Records: []Record{later}}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Refreshed)
+
+	annotation := store.annotation(t, web, db)
+	assert.Equal(t, []string{SourceNetFlow, SourceSFlow}, annotation.ObservedBy)
+	assert.Equal(t, int64(14), annotation.Ports[0].Packets)
+	assert.Equal(t, now, annotation.LastSeen)
+
+	var attributes map[string]interface{}
+	require.NoError(t, json.Unmarshal(store.relations[pair{source: web, target: db}], &attributes))
+	assert.Equal(t, "network-team", attributes["owner"])
+}
+
+func TestIngestReportsUnresolvedAndInvalidRecords(t *testing.T) {
+	store := newMemoryStore()
+	web, shared := uuid.New(), uuid.New()
+	store.ips["10.0.0.1"] = []uuid.UUID{web}
+	store.ips["10.0.0.3"] = []uuid.UUID{web}
+	store.ips["10.0.0.9"] = []uuid.UUID{shared, uuid.New()}
+	service := newTestService(store)
+
+	invalid := flow("10.0.0.1", "10.0.0.2", 443, "tcp", 1)
+	invalid.LastSeen = invalid.FirstSeen.Add(-time.Second)
+	result, err := service.Ingest(context.Background(), Batch{Records: []Record{
+		flow("10.0.0.1", "10.0.0.2", 443, "tcp", 1),
+		flow("10.0.0.1", "10.0.0.9", 443, "tcp", 1),
+		flow("10.0.0.1", "10.0.0.3", 22, "tcp", 1),
+		flow("not-an-ip", "10.0.0.1", 443, "tcp", 1),
+		flow("10.0.0.1", "10.0.0.3", 0, "tcp", 1),
+		flow("10.0.0.1", "10.0.0.3", 0, "gre", 1),
+		invalid,
+	}}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Unresolved)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.9"}, result.UnresolvedIPs)
+	assert.Equal(t, 1, result.SelfFlows)
+	require.Len(t, result.Rejected, 4)
+	assert.Equal(t, 3, result.Rejected[0].Index)
+	assert.Contains(t, result.Rejected[1].Reason, "destination_port")
+	assert.Contains(t, result.Rejected[2].Reason, "unsupported protocol")
+	assert.Contains(t, result.Rejected[3].Reason, "last_seen")
+	assert.Equal(t, 0, result.Created)
+	assert.Empty(t, store.relations)
+}
+
+func TestIngestContinuesPastFailedPairs(t *testing.T) {
+	store := newMemoryStore()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	store.ips["10.0.0.1"] = []uuid.UUID{a}
+	store.ips["10.0.0.2"] = []uuid.UUID{b}
+	store.ips["10.0.0.3"] = []uuid.UUID{c}
+	store.fail[pair{source: a, target: b}] = true
+	service := newTestService(store)
+
+	result, err := service.Ingest(context.Background(), Batch{Records: []Record{
+		flow("10.0.0.1", "10.0.0.2", 443, "tcp", 1),
+		flow("10.0.0.1", "10.0.0.3", 443, "tcp", 1),
+	}}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed[0], "endpoint is deleted")
+}
+
+func TestIngestRejectsInvalidBatches(t *testing.T) {
+	service := newTestService(newMemoryStore())
+
+	_, err := service.Ingest(context.Background(), Batch{}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+
+	_, err = service.Ingest(context.Background(), Batch{Source: "pcap", Records: []Record{flow("10.0.0.1", "10.0.0.2", 1, "tcp", 1)}}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+
+	_, err = service.Ingest(context.Background(), Batch{Records: make([]Record, MaxRecords+1)}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+}
+
+func TestMergeAttributesDropsLeastRecentlySeenPorts(t *testing.T) {
+	obs := Observation{Source: SourceNetFlow}
+	for port := 1; port <= MaxPorts+1; port++ {
+		obs.Ports = append(obs.Ports, PortStats{
+			Port: port, Protocol: "tcp", Packets: 1, Flows: 1,
+			FirstSeen: now, LastSeen: now.Add(time.Duration(port) * time.Second),
+		})
+	}
+
+	attributes, err := MergeAttributes(nil, obs)
+	require.NoError(t, err)
+	var annotation Annotation
+	require.NoError(t, json.Unmarshal(attributes, &annotation))
+	require.Len(t, annotation.Ports, MaxPorts)
+	assert.Equal(t, 2, annotation.Ports[0].Port)
+	assert.Equal(t, int64(MaxPorts+1), annotation.Packets)
+}
@@ -0,0 +1,172 @@
+package netflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Service ingests flow batches
+type Service struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewService creates a new flow ingestion service
+func NewService(store Store) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// pair is the source and target CI of an observation
+type pair struct {
+	source uuid.UUID
+	target uuid.UUID
+}
+
+// Ingest validates the records of a batch, resolves their addresses to CIs and
+// aggregates them per CI pair and port, then creates or refreshes the
+// communicates_with relationship of every pair. Records that are invalid or
+// cannot be resolved to exactly one CI at each end are reported, not ingested.
+func (s *Service) Ingest(ctx context.Context, batch Batch, by uuid.UUID) (*Result, error) {
+	source := batch.Source
+	if source == "" {
+		source = SourceNetFlow
+	}
+	if !sources[source] {
+		return nil, fmt.Errorf("%w: unsupported source %q", ErrInvalidBatch, batch.Source)
+	}
+	if len(batch.Records) == 0 {
+		return nil, fmt.Errorf("%w: no records", ErrInvalidBatch)
+	}
+	if len(batch.Records) > MaxRecords {
+		return nil, fmt.Errorf("%w: at most %d records may be ingested at once", ErrInvalidBatch, MaxRecords)
+	}
+
+	result := &Result{
+		Received:      len(batch.Records),
+		Rejected:      []Rejection{},
+		UnresolvedIPs: []string{},
+		Failed:        []string{},
+	}
+
+	records := make([]Record, 0, len(batch.Records))
+	ips := []string{}
+	seen := map[string]bool{}
+	for i, record := range batch.Records {
+		normalized, err := record.normalize()
+		if err != nil {
+			result.Rejected = append(result.Rejected, Rejection{Index: i, Reason: err.Error()})
+			continue
+		}
+		records = append(records, normalized)
+		for _, ip := range []string{normalized.SourceIP, normalized.DestinationIP} {
+			if !seen[ip] {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(records) == 0 {
+		return result, nil
+	}
+
+	resolved, err := s.store.ResolveIPs(ctx, ips)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aggregate per CI pair, then per port, keeping the pairs in batch order
+	observations := map[pair]map[PortStats]*PortStats{}
+	order := []pair{}
+	unresolved := map[string]bool{}
+	for _, record := range records {
+		src, srcOK := resolveOne(resolved, record.SourceIP)
+		dst, dstOK := resolveOne(resolved, record.DestinationIP)
+		if !srcOK || !dstOK {
+			result.Unresolved++
+			if !srcOK {
+				unresolved[record.SourceIP] = true
+			}
+			if !dstOK {
+				unresolved[record.DestinationIP] = true
+			}
+			continue
+		}
+		if src == dst {
+			result.SelfFlows++
+			continue
+		}
+
+		key := pair{source: src, target: dst}
+		ports, ok := observations[key]
+		if !ok {
+			ports = map[PortStats]*PortStats{}
+			observations[key] = ports
+			order = append(order, key)
+		}
+		portKey := PortStats{Port: record.DestinationPort, Protocol: record.Protocol}
+		stats, ok := ports[portKey]
+		if !ok {
+			stats = &PortStats{Port: record.DestinationPort, Protocol: record.Protocol}
+			ports[portKey] = stats
+		}
+		stats.add(PortStats{
+			Packets:   record.Packets,
+			Bytes:     record.Bytes,
+			Flows:     record.Flows,
+			FirstSeen: record.FirstSeen,
+			LastSeen:  record.LastSeen,
+		})
+	}
+
+	for ip := range unresolved {
+		result.UnresolvedIPs = append(result.UnresolvedIPs, ip)
+	}
+	sort.Strings(result.UnresolvedIPs)
+	if len(result.UnresolvedIPs) > MaxUnresolvedIPs {
+		result.UnresolvedIPs = result.UnresolvedIPs[:MaxUnresolvedIPs]
+	}
+
+	now := s.now()
+	for _, key := range order {
+		obs := Observation{SourceCIID: key.source, TargetCIID: key.target, Source: source}
+		for _, stats := range observations[key] {
+			obs.Ports = append(obs.Ports, *stats)
+		}
+		sort.Slice(obs.Ports, func(i, j int) bool {
+			if obs.Ports[i].Protocol != obs.Ports[j].Protocol {
+				return obs.Ports[i].Protocol < obs.Ports[j].Protocol
+			}
+			return obs.Ports[i].Port < obs.Ports[j].Port
+		})
+
+		created, err := s.store.Record(ctx, obs, now, by)
+		if err != nil {
+			log.Printf("Failed to record flows from CI %s to CI %s: %v", key.source, key.target, err)
+			result.Failed = append(result.Failed, fmt.Sprintf("%s -> %s: %v", key.source, key.target, err))
+			continue
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Refreshed++
+		}
+	}
+
+	log.Printf("Ingested %d %s records: %d relationships created, %d refreshed, %d rejected, %d unresolved",
+		result.Received, source, result.Created, result.Refreshed, len(result.Rejected), result.Unresolved)
+	return result, nil
+}
+
+// resolveOne returns the CI holding an address when exactly one does
+func resolveOne(resolved map[string][]uuid.UUID, ip string) (uuid.UUID, bool) {
+	ids := resolved[ip]
+	if len(ids) != 1 {
+		return uuid.Nil, false
+	}
+	return ids[0], true
+}
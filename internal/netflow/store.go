@@ -0,0 +1,155 @@
+package netflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"connect/internal/organization"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// IPAttributes are the CI attributes an address is resolved against
+var IPAttributes = []string{"ip_address", "management_ip"}
+
+// Store resolves addresses to CIs and records observations on relationships
+type Store interface {
+	// ResolveIPs returns the live, active CIs holding each address; an address
+	// held by no CI is left out
+	ResolveIPs(ctx context.Context, ips []string) (map[string][]uuid.UUID, error)
+	// Record folds an observation into the communicates_with relationship
+	// between its CIs, creating it if there is none, and reports whether it
+	// was created
+	Record(ctx context.Context, obs Observation, at time.Time, by uuid.UUID) (bool, error)
+}
+
+// PostgresStore resolves addresses in configuration_items and keeps the
+// observations in ci_relationships
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed flow store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ResolveIPs looks the addresses up in the IP attributes of the live CIs the
+// request's organization may see
+func (s *PostgresStore) ResolveIPs(ctx context.Context, ips []string) (map[string][]uuid.UUID, error) {
+	resolved := map[string][]uuid.UUID{}
+	orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{pq.Array(ips)})
+	for _, attribute := range IPAttributes {
+		rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+			SELECT attributes->>'%s', id
+			FROM configuration_items
+			WHERE attributes->>'%s' = ANY($1) AND is_deleted = false AND is_active = true%s`, attribute, attribute, orgFilter),
+			args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve flow addresses: %w", err)
+		}
+		for rows.Next() {
+			var ip string
+			var id uuid.UUID
+			if err := rows.Scan(&ip, &id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan resolved address: %w", err)
+			}
+			if !containsID(resolved[ip], id) {
+				resolved[ip] = append(resolved[ip], id)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve flow addresses: %w", err)
+		}
+	}
+	return resolved, nil
+}
+
+// Record updates the relationship in a transaction holding its row, so
+// concurrent batches never lose each other's counters. A relationship
+// created concurrently is picked up on a second pass.
+func (s *PostgresStore) Record(ctx context.Context, obs Observation, at time.Time, by uuid.UUID) (bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		created, done, err := s.record(ctx, obs, at, by)
+		if err != nil || done {
+			return created, err
+		}
+	}
+	return false, fmt.Errorf("failed to record flows from %s to %s: relationship changed concurrently", obs.SourceCIID, obs.TargetCIID)
+}
+
+// record makes one attempt at Record, reporting whether it completed
+func (s *PostgresStore) record(ctx context.Context, obs Observation, at time.Time, by uuid.UUID) (created, done bool, err error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	var existing []byte
+	orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{obs.SourceCIID, obs.TargetCIID, RelationshipType})
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, attributes
+		FROM ci_relationships
+		WHERE source_ci_id = $1 AND target_ci_id = $2 AND type = $3`+orgFilter+`
+		FOR UPDATE`, args...).Scan(&id, &existing)
+	if err != nil && err != sql.ErrNoRows {
+		return false, false, fmt.Errorf("failed to get flow relationship: %w", err)
+	}
+	found := err == nil
+
+	attributes, err := MergeAttributes(existing, obs)
+	if err != nil {
+		return false, false, err
+	}
+
+	if found {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE ci_relationships
+			SET attributes = $2, is_active = true, updated_at = $3, updated_by = $4
+			WHERE id = $1`, id, []byte(attributes), at, by)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to refresh flow relationship: %w", err)
+		}
+	} else {
+		// Flows are recorded by a background job, so the relationship takes
+		// the organization of its CIs, the source's when both have one
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO ci_relationships (
+				id, source_ci_id, target_ci_id, type, attributes, description,
+				is_active, state, created_at, updated_at, created_by, updated_by, org_id
+			) VALUES ($1, $2, $3, $4, $5, $6, true, 'active', $7, $7, $8, $8,
+				COALESCE((SELECT org_id FROM configuration_items WHERE id = $2), (SELECT org_id FROM configuration_items WHERE id = $3)))
+			ON CONFLICT (source_ci_id, target_ci_id, type) DO NOTHING`,
+			uuid.New(), obs.SourceCIID, obs.TargetCIID, RelationshipType, []byte(attributes),
+			"Observed in network flows", at, by)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to create flow relationship: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return false, false, nil
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, false, fmt.Errorf("failed to commit flow relationship: %w", err)
+	}
+	return !found, true, nil
+}
+
+// containsID reports whether ids holds id
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
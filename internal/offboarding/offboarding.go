@@ -0,0 +1,147 @@
+// Package offboarding deactivates departing users in one step: their accounts
+// are deactivated, their sessions, refresh tokens and the API keys of service
+// accounts they own are revoked, and the CIs they own are reassigned or flagged
+// for a new owner. Each run produces a report of what was done per user.
+package offboarding
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bounds of an offboarding request
+const (
+	MaxUsers        = 100
+	MaxReasonLength = 1000
+)
+
+// FlagTag is added to the CIs of offboarded users when they are not reassigned,
+// so the CIs needing a new owner can be found
+const FlagTag = "owner-offboarded"
+
+// RevokeReason is recorded on the refresh tokens revoked by offboarding
+const RevokeReason = "offboarded"
+
+// User result statuses
+const (
+	StatusOffboarded = "offboarded"
+	StatusNotFound   = "not_found"
+	StatusFailed     = "failed"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid offboarding request")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrReportNotFound = errors.New("offboarding report not found")
+)
+
+// Request represents the users to offboard
+type Request struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+	// ReassignTo becomes the owner of the CIs the users own; when empty the
+	// CIs keep their owner and are tagged with FlagTag instead
+	ReassignTo string `json:"reassign_to,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// Validate checks the request. Admins cannot offboard themselves.
+func (r *Request) Validate(by uuid.UUID) error {
+	if len(r.UserIDs) == 0 {
+		return fmt.Errorf("%w: at least one user is required", ErrInvalidRequest)
+	}
+	if len(r.UserIDs) > MaxUsers {
+		return fmt.Errorf("%w: at most %d users can be offboarded at once", ErrInvalidRequest, MaxUsers)
+	}
+	if len(r.Reason) > MaxReasonLength {
+		return fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidRequest, MaxReasonLength)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(r.UserIDs))
+	for _, id := range r.UserIDs {
+		if id == uuid.Nil {
+			return fmt.Errorf("%w: user IDs cannot be empty", ErrInvalidRequest)
+		}
+		if seen[id] {
+			return fmt.Errorf("%w: user %s is listed twice", ErrInvalidRequest, id)
+		}
+		if id == by {
+			return fmt.Errorf("%w: you cannot offboard yourself", ErrInvalidRequest)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Options control how a single user is offboarded
+type Options struct {
+	ReportID   uuid.UUID
+	ReassignTo string
+	By         uuid.UUID
+	At         time.Time
+}
+
+// UserResult is what offboarding did for one user
+type UserResult struct {
+	UserID                     uuid.UUID `json:"user_id"`
+	Username                   string    `json:"username,omitempty"`
+	Email                      string    `json:"email,omitempty"`
+	Status                     string    `json:"status"`
+	WasActive                  bool      `json:"was_active"`
+	SessionsRevoked            int64     `json:"sessions_revoked"`
+	RefreshTokensRevoked       int64     `json:"refresh_tokens_revoked"`
+	ServiceAccountsDeactivated int64     `json:"service_accounts_deactivated"`
+	APIKeysRevoked             int64     `json:"api_keys_revoked"`
+	CIsReassigned              int64     `json:"cis_reassigned"`
+	CIsFlagged                 int64     `json:"cis_flagged"`
+	Error                      string    `json:"error,omitempty"`
+}
+
+// Summary totals the results of a report
+type Summary struct {
+	Users                      int   `json:"users"`
+	Offboarded                 int   `json:"offboarded"`
+	NotFound                   int   `json:"not_found"`
+	Failed                     int   `json:"failed"`
+	SessionsRevoked            int64 `json:"sessions_revoked"`
+	RefreshTokensRevoked       int64 `json:"refresh_tokens_revoked"`
+	ServiceAccountsDeactivated int64 `json:"service_accounts_deactivated"`
+	APIKeysRevoked             int64 `json:"api_keys_revoked"`
+	CIsReassigned              int64 `json:"cis_reassigned"`
+	CIsFlagged                 int64 `json:"cis_flagged"`
+}
+
+// Report is the outcome of an offboarding run
+type Report struct {
+	ID          uuid.UUID    `json:"id"`
+	RequestedBy string       `json:"requested_by"`
+	RequestedAt time.Time    `json:"requested_at"`
+	Reason      string       `json:"reason,omitempty"`
+	ReassignTo  string       `json:"reassign_to,omitempty"`
+	Summary     Summary      `json:"summary"`
+	Users       []UserResult `json:"users,omitempty"`
+}
+
+// summarize totals the user results
+func summarize(results []UserResult) Summary {
+	summary := Summary{Users: len(results)}
+	for _, result := range results {
+		switch result.Status {
+		case StatusOffboarded:
+			summary.Offboarded++
+		case StatusNotFound:
+			summary.NotFound++
+		default:
+			summary.Failed++
+		}
+		summary.SessionsRevoked += result.SessionsRevoked
+		summary.RefreshTokensRevoked += result.RefreshTokensRevoked
+		summary.ServiceAccountsDeactivated += result.ServiceAccountsDeactivated
+		summary.APIKeysRevoked += result.APIKeysRevoked
+		summary.CIsReassigned += result.CIsReassigned
+		summary.CIsFlagged += result.CIsFlagged
+	}
+	return summary
+}
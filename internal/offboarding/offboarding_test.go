@@ -0,0 +1,138 @@
+package offboarding
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	users   map[uuid.UUID]*UserResult
+	failing map[uuid.UUID]bool
+	options []Options
+	reports map[uuid.UUID]*Report
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users:   map[uuid.UUID]*UserResult{},
+		failing: map[uuid.UUID]bool{},
+		reports: map[uuid.UUID]*Report{},
+	}
+}
+
+func (m *memoryStore) OffboardUser(ctx context.Context, userID uuid.UUID, options Options) (*UserResult, error) {
+	m.options = append(m.options, options)
+	if m.failing[userID] {
+		return nil, errors.New("connection reset")
+	}
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	result := *user
+	result.Status = StatusOffboarded
+	return &result, nil
+}
+
+func (m *memoryStore) CreateReport(ctx context.Context, report *Report) error {
+	m.reports[report.ID] = report
+	return nil
+}
+
+func (m *memoryStore) GetReport(ctx context.Context, id uuid.UUID) (*Report, error) {
+	report, ok := m.reports[id]
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+	return report, nil
+}
+
+func (m *memoryStore) ListReports(ctx context.Context, limit int) ([]*Report, error) {
+	var reports []*Report
+	for _, report := range m.reports {
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func TestRequestValidate(t *testing.T) {
+	admin := uuid.New()
+	user := uuid.New()
+
+	assert.NoError(t, (&Request{UserIDs: []uuid.UUID{user}}).Validate(admin))
+
+	tooMany := make([]uuid.UUID, MaxUsers+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	for name, req := range map[string]Request{
+		"empty":     {},
+		"too many":  {UserIDs: tooMany},
+		"duplicate": {UserIDs: []uuid.UUID{user, user}},
+		"nil":       {UserIDs: []uuid.UUID{uuid.Nil}},
+		"self":      {UserIDs: []uuid.UUID{user, admin}},
+	} {
+		assert.ErrorIs(t, req.Validate(admin), ErrInvalidRequest, name)
+	}
+}
+
+func TestOffboardReportsEachUser(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	admin := uuid.New()
+
+	leaver := uuid.New()
+	store.users[leaver] = &UserResult{UserID: leaver, Username: "jdoe", WasActive: true, SessionsRevoked: 2, APIKeysRevoked: 1, CIsReassigned: 4}
+	unknown := uuid.New()
+	broken := uuid.New()
+	store.failing[broken] = true
+
+	report, err := service.Offboard(context.Background(), Request{
+		UserIDs:    []uuid.UUID{leaver, unknown, broken},
+		ReassignTo: " platform-team ",
+		Reason:     "left the company",
+	}, admin)
+	require.NoError(t, err)
+
+	require.Len(t, report.Users, 3)
+	assert.Equal(t, StatusOffboarded, report.Users[0].Status)
+	assert.Equal(t, "jdoe", report.Users[0].Username)
+	assert.Equal(t, StatusNotFound, report.Users[1].Status)
+	assert.Equal(t, StatusFailed, report.Users[2].Status)
+	assert.Equal(t, "connection reset", report.Users[2].Error)
+
+	assert.Equal(t, Summary{
+		Users:           3,
+		Offboarded:      1,
+		NotFound:        1,
+		Failed:          1,
+		SessionsRevoked: 2,
+		APIKeysRevoked:  1,
+		CIsReassigned:   4,
+	}, report.Summary)
+
+	require.Len(t, store.options, 3)
+	assert.Equal(t, "platform-team", store.options[0].ReassignTo)
+	assert.Equal(t, admin, store.options[0].By)
+	assert.Equal(t, report.ID, store.options[0].ReportID)
+
+	stored, err := service.Get(context.Background(), report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, admin.String(), stored.RequestedBy)
+}
+
+func TestOffboardRejectsInvalidRequests(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	admin := uuid.New()
+
+	_, err := service.Offboard(context.Background(), Request{UserIDs: []uuid.UUID{admin}}, admin)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Empty(t, store.options)
+	assert.Empty(t, store.reports)
+}
@@ -0,0 +1,80 @@
+package offboarding
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultListLimit bounds a report listing without a limit
+const DefaultListLimit = 50
+
+// Service offboards users and keeps the reports of each run
+type Service struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewService creates a new offboarding service
+func NewService(store Store) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// Offboard offboards each user in its own transaction, so one failing user
+// does not keep the others active. Unknown users and failures are reported per
+// user rather than failing the run. Access tokens already issued stay valid
+// until they expire.
+func (s *Service) Offboard(ctx context.Context, req Request, by uuid.UUID) (*Report, error) {
+	req.ReassignTo = strings.TrimSpace(req.ReassignTo)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if err := req.Validate(by); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		ID:          uuid.New(),
+		RequestedBy: by.String(),
+		RequestedAt: s.now(),
+		Reason:      req.Reason,
+		ReassignTo:  req.ReassignTo,
+		Users:       make([]UserResult, 0, len(req.UserIDs)),
+	}
+	options := Options{ReportID: report.ID, ReassignTo: req.ReassignTo, By: by, At: report.RequestedAt}
+
+	for _, userID := range req.UserIDs {
+		result, err := s.store.OffboardUser(ctx, userID, options)
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			result = &UserResult{UserID: userID, Status: StatusNotFound}
+		case err != nil:
+			log.Printf("Failed to offboard user %s: %v", userID, err)
+			result = &UserResult{UserID: userID, Status: StatusFailed, Error: err.Error()}
+		}
+		report.Users = append(report.Users, *result)
+	}
+	report.Summary = summarize(report.Users)
+
+	// The users are offboarded and audited by now, so a report that cannot be
+	// stored is still returned
+	if err := s.store.CreateReport(ctx, report); err != nil {
+		log.Printf("Failed to store offboarding report %s: %v", report.ID, err)
+	}
+	return report, nil
+}
+
+// Get retrieves a report with its user results
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Report, error) {
+	return s.store.GetReport(ctx, id)
+}
+
+// List retrieves the most recent reports without their user results
+func (s *Service) List(ctx context.Context, limit int) ([]*Report, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	return s.store.ListReports(ctx, limit)
+}
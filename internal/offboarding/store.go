@@ -0,0 +1,227 @@
+package offboarding
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store offboards users and persists reports
+type Store interface {
+	// OffboardUser deactivates a user, revokes its access and reassigns or
+	// flags its CIs in one transaction, ErrUserNotFound when there is no user
+	OffboardUser(ctx context.Context, userID uuid.UUID, options Options) (*UserResult, error)
+	CreateReport(ctx context.Context, report *Report) error
+	GetReport(ctx context.Context, id uuid.UUID) (*Report, error)
+	ListReports(ctx context.Context, limit int) ([]*Report, error)
+}
+
+// PostgresStore offboards users in Postgres and keeps reports in the
+// user_offboarding_reports table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed offboarding store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ownedCondition matches the CIs a user owns. Owners are free text, so a user
+// may be named by ID, username or email.
+const ownedCondition = `is_deleted = false AND LOWER(owner) IN (LOWER($1), LOWER($2), LOWER($3))`
+
+// step is a statement of offboarding whose affected rows are counted in the result
+type step struct {
+	name   string
+	count  *int64
+	query  string
+	params []interface{}
+}
+
+// OffboardUser offboards one user and writes an audit entry with the result
+func (s *PostgresStore) OffboardUser(ctx context.Context, userID uuid.UUID, options Options) (*UserResult, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &UserResult{UserID: userID}
+	err = tx.QueryRowxContext(ctx, `SELECT username, email, COALESCE(is_active, false) FROM users WHERE id = $1 FOR UPDATE`, userID).
+		Scan(&result.Username, &result.Email, &result.WasActive)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var by *uuid.UUID
+	if options.By != uuid.Nil {
+		by = &options.By
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET is_active = false, updated_at = $2 WHERE id = $1`, userID, options.At); err != nil {
+		return nil, fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	steps := []step{
+		{
+			name:  "revoke sessions",
+			count: &result.SessionsRevoked,
+			query: `UPDATE sessions SET is_active = false, revoked_at = $2, updated_at = $2
+				WHERE user_id = $1 AND revoked_at IS NULL`,
+			params: []interface{}{userID, options.At},
+		},
+		{
+			name:  "revoke refresh tokens",
+			count: &result.RefreshTokensRevoked,
+			query: `UPDATE refresh_tokens SET revoked_at = $2, revoke_reason = $3
+				WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2`,
+			params: []interface{}{userID.String(), options.At, RevokeReason},
+		},
+		{
+			name:  "revoke API keys",
+			count: &result.APIKeysRevoked,
+			query: `UPDATE service_account_credentials SET revoked_at = $2
+				WHERE revoked_at IS NULL AND account_id IN (SELECT id FROM service_accounts WHERE owner_id = $1)`,
+			params: []interface{}{userID, options.At},
+		},
+		{
+			name:  "deactivate service accounts",
+			count: &result.ServiceAccountsDeactivated,
+			query: `UPDATE service_accounts SET is_active = false, updated_at = $2, updated_by = $3
+				WHERE owner_id = $1 AND is_active = true`,
+			params: []interface{}{userID, options.At, by},
+		},
+	}
+	if options.ReassignTo != "" {
+		steps = append(steps, step{
+			name:  "reassign CIs",
+			count: &result.CIsReassigned,
+			query: `UPDATE configuration_items SET owner = $4, updated_at = $5, updated_by = COALESCE($6, updated_by)
+				WHERE ` + ownedCondition,
+			params: []interface{}{userID.String(), result.Username, result.Email, options.ReassignTo, options.At, by},
+		})
+	} else {
+		steps = append(steps, step{
+			name:  "flag CIs",
+			count: &result.CIsFlagged,
+			query: `UPDATE configuration_items SET tags = array_append(COALESCE(tags, '{}'), $4), updated_at = $5
+				WHERE ` + ownedCondition + ` AND NOT ($4 = ANY(COALESCE(tags, '{}')))`,
+			params: []interface{}{userID.String(), result.Username, result.Email, FlagTag, options.At},
+		})
+	}
+
+	for _, statement := range steps {
+		res, err := tx.ExecContext(ctx, statement.query, statement.params...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s: %w", statement.name, err)
+		}
+		if *statement.count, err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to %s: %w", statement.name, err)
+		}
+	}
+	result.Status = StatusOffboarded
+
+	details, err := json.Marshal(map[string]interface{}{
+		"report_id":   options.ReportID,
+		"reassign_to": options.ReassignTo,
+		"result":      result,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ('user', $1, 'offboarded', $2, $3, $4)`, userID, by, options.At, details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit offboarding: %w", err)
+	}
+	return result, nil
+}
+
+// CreateReport stores a report with its user results
+func (s *PostgresStore) CreateReport(ctx context.Context, report *Report) error {
+	summary, err := json.Marshal(report.Summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offboarding summary: %w", err)
+	}
+	users, err := json.Marshal(report.Users)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offboarding results: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO user_offboarding_reports (id, requested_by, requested_at, reason, reassign_to, summary, users)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)`,
+		report.ID, report.RequestedBy, report.RequestedAt, report.Reason, report.ReassignTo, summary, users)
+	if err != nil {
+		return fmt.Errorf("failed to create offboarding report: %w", err)
+	}
+	return nil
+}
+
+// GetReport retrieves a report with its user results
+func (s *PostgresStore) GetReport(ctx context.Context, id uuid.UUID) (*Report, error) {
+	var report Report
+	var summary, users []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, requested_by, requested_at, COALESCE(reason, ''), COALESCE(reassign_to, ''), summary, users
+		FROM user_offboarding_reports
+		WHERE id = $1`, id).Scan(
+		&report.ID, &report.RequestedBy, &report.RequestedAt, &report.Reason, &report.ReassignTo, &summary, &users)
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offboarding report: %w", err)
+	}
+
+	if err := json.Unmarshal(summary, &report.Summary); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal offboarding summary: %w", err)
+	}
+	if err := json.Unmarshal(users, &report.Users); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal offboarding results: %w", err)
+	}
+	return &report, nil
+}
+
+// ListReports retrieves the most recent reports without their user results
+func (s *PostgresStore) ListReports(ctx context.Context, limit int) ([]*Report, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, requested_by, requested_at, COALESCE(reason, ''), COALESCE(reassign_to, ''), summary
+		FROM user_offboarding_reports
+		ORDER BY requested_at DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offboarding reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		var report Report
+		var summary []byte
+		if err := rows.Scan(&report.ID, &report.RequestedBy, &report.RequestedAt, &report.Reason, &report.ReassignTo, &summary); err != nil {
+			return nil, fmt.Errorf("failed to scan offboarding report: %w", err)
+		}
+		if err := json.Unmarshal(summary, &report.Summary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal offboarding summary: %w", err)
+		}
+		reports = append(reports, &report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list offboarding reports: %w", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,101 @@
+// Package organization lets one CMDB serve several customers, e.g. for a
+// managed service provider. Users are members of organizations and act for
+// one of them at a time: the organization is carried by their tokens, and its
+// slug is the tenant their requests act for. CIs created within an
+// organization are stamped with its tenant, and callers acting for an
+// organization only see its CIs, shared CIs without a tenant, and the
+// relationships between them. Users that are members of no organization are
+// not scoped, as in a single-tenant CMDB.
+//
+// The stores enforce this on their own: CIs, relationships, type schemas,
+// users and roles record the organization they were created in as org_id,
+// and the repositories, and the stores of other packages through
+// ScopeCondition, narrow their queries of them to the rows of the
+// organization a request acts for and the shared rows, whose org_id is NULL.
+// Organizations only change the schemas and roles they own, and users are
+// never shared. Background jobs act for no organization unless started by a
+// request, and see every row.
+package organization
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits
+const (
+	MaxSlugLength = 63
+	MaxNameLength = 255
+)
+
+var (
+	ErrInvalidOrganization  = errors.New("invalid organization")
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrDuplicateSlug        = errors.New("an organization with this slug already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrNotMember            = errors.New("user is not a member of the organization")
+)
+
+// slugPattern accepts lowercase DNS labels, e.g. "acme-corp"
+var slugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Organization is a customer whose users and CIs are kept apart from those of
+// other organizations
+type Organization struct {
+	ID uuid.UUID `json:"id" db:"id"`
+	// Slug identifies the organization in tokens and is the tenant its CIs
+	// carry; it cannot be changed
+	Slug      string    `json:"slug" db:"slug"`
+	Name      string    `json:"name" db:"name"`
+	CreatedBy uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Member is a user's membership of an organization
+type Member struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Username       string    `json:"username,omitempty"`
+	// Default marks the organization the user acts for when logging in
+	Default   bool      `json:"default"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership is an organization a user is a member of
+type Membership struct {
+	Organization
+	Default bool `json:"default"`
+}
+
+// CreateRequest creates an organization
+type CreateRequest struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// validate checks the request, trimming its fields
+func (r *CreateRequest) validate() error {
+	r.Slug = strings.TrimSpace(r.Slug)
+	r.Name = strings.TrimSpace(r.Name)
+	if len(r.Slug) > MaxSlugLength || !slugPattern.MatchString(r.Slug) {
+		return fmt.Errorf("%w: slug must be at most %d lowercase letters, digits and hyphens", ErrInvalidOrganization, MaxSlugLength)
+	}
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidOrganization)
+	}
+	if len(r.Name) > MaxNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidOrganization, MaxNameLength)
+	}
+	return nil
+}
+
+// AddMemberRequest adds a user to an organization
+type AddMemberRequest struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Default bool      `json:"default"`
+}
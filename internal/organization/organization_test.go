@@ -0,0 +1,235 @@
+package organization
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps organizations and memberships in memory
+type memoryStore struct {
+	orgs    map[uuid.UUID]*Organization
+	members []*Member
+	// userOrgs holds the organization each user was created in
+	userOrgs map[uuid.UUID]uuid.UUID
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{orgs: map[uuid.UUID]*Organization{}, userOrgs: map[uuid.UUID]uuid.UUID{}}
+}
+
+func (m *memoryStore) CreateOrganization(ctx context.Context, org *Organization) error {
+	for _, o := range m.orgs {
+		if o.Slug == org.Slug {
+			return ErrDuplicateSlug
+		}
+	}
+	m.orgs[org.ID] = org
+	return nil
+}
+
+func (m *memoryStore) GetOrganization(ctx context.Context, id uuid.UUID) (*Organization, error) {
+	org, ok := m.orgs[id]
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	return org, nil
+}
+
+func (m *memoryStore) ListOrganizations(ctx context.Context) ([]*Organization, error) {
+	orgs := []*Organization{}
+	for _, org := range m.orgs {
+		orgs = append(orgs, org)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Slug < orgs[j].Slug })
+	return orgs, nil
+}
+
+func (m *memoryStore) DeleteOrganization(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.orgs[id]; !ok {
+		return ErrOrganizationNotFound
+	}
+	delete(m.orgs, id)
+	return nil
+}
+
+func (m *memoryStore) AddMember(ctx context.Context, member *Member) error {
+	if _, ok := m.orgs[member.OrganizationID]; !ok {
+		return ErrOrganizationNotFound
+	}
+	hasDefault := false
+	var existing *Member
+	for _, other := range m.members {
+		if other.UserID != member.UserID {
+			continue
+		}
+		if other.OrganizationID == member.OrganizationID {
+			existing = other
+			continue
+		}
+		if member.Default {
+			other.Default = false
+		}
+		hasDefault = hasDefault || other.Default
+	}
+	if existing != nil {
+		existing.Default = existing.Default || member.Default
+		member.Default = existing.Default
+		return nil
+	}
+	member.Default = member.Default || !hasDefault
+	m.members = append(m.members, member)
+	return nil
+}
+
+func (m *memoryStore) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	for i, member := range m.members {
+		if member.OrganizationID == orgID && member.UserID == userID {
+			m.members = append(m.members[:i], m.members[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotMember
+}
+
+func (m *memoryStore) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*Member, error) {
+	members := []*Member{}
+	for _, member := range m.members {
+		if member.OrganizationID == orgID {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (m *memoryStore) ListMemberships(ctx context.Context, userID uuid.UUID) ([]*Membership, error) {
+	memberships := []*Membership{}
+	for _, member := range m.members {
+		if member.UserID == userID {
+			memberships = append(memberships, &Membership{Organization: *m.orgs[member.OrganizationID], Default: member.Default})
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool { return memberships[i].Slug < memberships[j].Slug })
+	return memberships, nil
+}
+
+func (m *memoryStore) UserOrganization(ctx context.Context, userID uuid.UUID) (*Organization, error) {
+	orgID, ok := m.userOrgs[userID]
+	if !ok {
+		return nil, nil
+	}
+	return m.orgs[orgID], nil
+}
+
+func TestCreateValidates(t *testing.T) {
+	service := NewService(newMemoryStore())
+	ctx := context.Background()
+
+	org, err := service.Create(ctx, CreateRequest{Slug: " acme ", Name: " Acme Corp "}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, "acme", org.Slug)
+	assert.Equal(t, "Acme Corp", org.Name)
+
+	_, err = service.Create(ctx, CreateRequest{Slug: "acme", Name: "Other"}, uuid.New())
+	assert.ErrorIs(t, err, ErrDuplicateSlug)
+
+	for _, slug := range []string{"", "Acme", "-acme", "acme-", "acme corp", "acme_corp"} {
+		_, err := service.Create(ctx, CreateRequest{Slug: slug, Name: "Acme"}, uuid.New())
+		assert.ErrorIs(t, err, ErrInvalidOrganization, slug)
+	}
+	_, err = service.Create(ctx, CreateRequest{Slug: "globex"}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidOrganization)
+}
+
+func TestMembershipsAndSwitching(t *testing.T) {
+	service := NewService(newMemoryStore())
+	ctx := context.Background()
+	acme, err := service.Create(ctx, CreateRequest{Slug: "acme", Name: "Acme"}, uuid.New())
+	require.NoError(t, err)
+	globex, err := service.Create(ctx, CreateRequest{Slug: "globex", Name: "Globex"}, uuid.New())
+	require.NoError(t, err)
+	user := uuid.New()
+
+	tenant, err := service.DefaultTenant(ctx, user)
+	require.NoError(t, err)
+	assert.Empty(t, tenant, "users of no organization are not scoped")
+
+	// The first organization is the default
+	member, err := service.AddMember(ctx, globex.ID, AddMemberRequest{UserID: user})
+	require.NoError(t, err)
+	assert.True(t, member.Default)
+	member, err = service.AddMember(ctx, acme.ID, AddMemberRequest{UserID: user})
+	require.NoError(t, err)
+	assert.False(t, member.Default)
+	tenant, _ = service.DefaultTenant(ctx, user)
+	assert.Equal(t, "globex", tenant)
+
+	_, err = service.AddMember(ctx, acme.ID, AddMemberRequest{UserID: user, Default: true})
+	require.NoError(t, err)
+	tenant, _ = service.DefaultTenant(ctx, user)
+	assert.Equal(t, "acme", tenant)
+
+	org, err := service.Switch(ctx, user, "globex")
+	require.NoError(t, err)
+	assert.Equal(t, globex.ID, org.ID)
+	_, err = service.Switch(ctx, uuid.New(), "globex")
+	assert.ErrorIs(t, err, ErrNotMember)
+
+	require.NoError(t, service.RemoveMember(ctx, globex.ID, user))
+	_, err = service.Switch(ctx, user, "globex")
+	assert.ErrorIs(t, err, ErrNotMember)
+	assert.ErrorIs(t, service.RemoveMember(ctx, globex.ID, user), ErrNotMember)
+
+	_, err = service.AddMember(ctx, acme.ID, AddMemberRequest{})
+	assert.ErrorIs(t, err, ErrInvalidOrganization)
+	_, err = service.Members(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrOrganizationNotFound)
+}
+
+func TestDefaultTenantOfUserCreatedInOrganization(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	ctx := context.Background()
+	acme, err := service.Create(ctx, CreateRequest{Slug: "acme", Name: "Acme"}, uuid.New())
+	require.NoError(t, err)
+	globex, err := service.Create(ctx, CreateRequest{Slug: "globex", Name: "Globex"}, uuid.New())
+	require.NoError(t, err)
+
+	// A user created while acting for acme logs in acting for it even
+	// without a membership, rather than unscoped
+	user := uuid.New()
+	store.userOrgs[user] = acme.ID
+	tenant, err := service.DefaultTenant(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant)
+
+	// Memberships take precedence
+	_, err = service.AddMember(ctx, globex.ID, AddMemberRequest{UserID: user})
+	require.NoError(t, err)
+	tenant, err = service.DefaultTenant(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, "globex", tenant)
+}
+
+func TestScopeCondition(t *testing.T) {
+	ctx := context.Background()
+	condition, args := ScopeCondition(ctx, "org_id", 1)
+	assert.Equal(t, "TRUE", condition)
+	assert.Nil(t, args)
+	condition, args = AndScopeCondition(ctx, "org_id", []interface{}{"ci"})
+	assert.Empty(t, condition)
+	assert.Equal(t, []interface{}{"ci"}, args)
+
+	ctx = visibility.WithTenant(ctx, "acme")
+	condition, args = ScopeCondition(ctx, "ci.org_id", 3)
+	assert.Equal(t, "(ci.org_id IS NULL OR ci.org_id = (SELECT id FROM organizations WHERE slug = $3))", condition)
+	assert.Equal(t, []interface{}{"acme"}, args)
+	condition, args = AndScopeCondition(ctx, "org_id", []interface{}{"ci"})
+	assert.Equal(t, " AND (org_id IS NULL OR org_id = (SELECT id FROM organizations WHERE slug = $2))", condition)
+	assert.Equal(t, []interface{}{"ci", "acme"}, args)
+}
@@ -0,0 +1,32 @@
+package organization
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/visibility"
+)
+
+// ScopeCondition narrows a query outside the repositories to the rows of the
+// organization a request acts for and the shared rows, whose org_id is NULL,
+// matching what the repositories do. The organization is looked up by the
+// slug bound to placeholder argCount. Requests acting for no organization are
+// not scoped, and the condition is TRUE with no argument.
+func ScopeCondition(ctx context.Context, column string, argCount int) (string, []interface{}) {
+	slug := visibility.TenantFromContext(ctx)
+	if slug == "" {
+		return "TRUE", nil
+	}
+	return fmt.Sprintf("(%[1]s IS NULL OR %[1]s = (SELECT id FROM organizations WHERE slug = $%[2]d))", column, argCount), []interface{}{slug}
+}
+
+// AndScopeCondition returns ScopeCondition prefixed with AND, appending its
+// argument to args, for fixed queries. It is empty when the request acts for
+// no organization.
+func AndScopeCondition(ctx context.Context, column string, args []interface{}) (string, []interface{}) {
+	condition, scopeArgs := ScopeCondition(ctx, column, len(args)+1)
+	if scopeArgs == nil {
+		return "", args
+	}
+	return " AND " + condition, append(args, scopeArgs...)
+}
@@ -0,0 +1,131 @@
+package organization
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Service manages organizations and their members
+type Service struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewService creates a new organization service
+func NewService(store Store) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// Create creates an organization
+func (s *Service) Create(ctx context.Context, req CreateRequest, by uuid.UUID) (*Organization, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	org := &Organization{
+		ID:        uuid.New(),
+		Slug:      req.Slug,
+		Name:      req.Name,
+		CreatedBy: by,
+		CreatedAt: s.now(),
+	}
+	if err := s.store.CreateOrganization(ctx, org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Get retrieves an organization
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Organization, error) {
+	return s.store.GetOrganization(ctx, id)
+}
+
+// List retrieves the organizations by slug
+func (s *Service) List(ctx context.Context) ([]*Organization, error) {
+	return s.store.ListOrganizations(ctx)
+}
+
+// Delete deletes an organization and its memberships. Its CIs keep their
+// tenant, so they stay hidden from other organizations.
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.store.DeleteOrganization(ctx, id)
+}
+
+// AddMember adds a user to an organization, or updates whether it is the
+// user's default organization. A user's first organization is their default.
+func (s *Service) AddMember(ctx context.Context, orgID uuid.UUID, req AddMemberRequest) (*Member, error) {
+	if req.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidOrganization)
+	}
+	member := &Member{
+		OrganizationID: orgID,
+		UserID:         req.UserID,
+		Default:        req.Default,
+		CreatedAt:      s.now(),
+	}
+	if err := s.store.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes a user from an organization. Tokens already issued
+// for it stay valid until they expire.
+func (s *Service) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	return s.store.RemoveMember(ctx, orgID, userID)
+}
+
+// Members retrieves the members of an organization
+func (s *Service) Members(ctx context.Context, orgID uuid.UUID) ([]*Member, error) {
+	if _, err := s.store.GetOrganization(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return s.store.ListMembers(ctx, orgID)
+}
+
+// Memberships retrieves the organizations a user is a member of
+func (s *Service) Memberships(ctx context.Context, userID uuid.UUID) ([]*Membership, error) {
+	return s.store.ListMemberships(ctx, userID)
+}
+
+// DefaultTenant returns the slug of the organization a user acts for when
+// logging in: their default organization, else the first of their
+// organizations. Users that are members of none act for the organization
+// they were created in, and it is empty for users created for none.
+func (s *Service) DefaultTenant(ctx context.Context, userID uuid.UUID) (string, error) {
+	memberships, err := s.store.ListMemberships(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(memberships) == 0 {
+		org, err := s.store.UserOrganization(ctx, userID)
+		if err != nil || org == nil {
+			return "", err
+		}
+		return org.Slug, nil
+	}
+	for _, membership := range memberships {
+		if membership.Default {
+			return membership.Slug, nil
+		}
+	}
+	return memberships[0].Slug, nil
+}
+
+// Switch returns the organization with a slug for a user to act for,
+// provided they are a member of it
+func (s *Service) Switch(ctx context.Context, userID uuid.UUID, slug string) (*Organization, error) {
+	memberships, err := s.store.ListMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		if membership.Slug == slug {
+			org := membership.Organization
+			return &org, nil
+		}
+	}
+	return nil, ErrNotMember
+}
@@ -0,0 +1,226 @@
+package organization
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store persists organizations and their members
+type Store interface {
+	CreateOrganization(ctx context.Context, org *Organization) error
+	GetOrganization(ctx context.Context, id uuid.UUID) (*Organization, error)
+	ListOrganizations(ctx context.Context) ([]*Organization, error)
+	DeleteOrganization(ctx context.Context, id uuid.UUID) error
+	// AddMember adds or updates a membership. A default membership replaces
+	// the user's previous default; the user's first membership is made
+	// their default.
+	AddMember(ctx context.Context, member *Member) error
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]*Member, error)
+	// ListMemberships returns the organizations of a user by slug
+	ListMemberships(ctx context.Context, userID uuid.UUID) ([]*Membership, error)
+	// UserOrganization returns the organization a user was created in, nil
+	// when the user was created for none
+	UserOrganization(ctx context.Context, userID uuid.UUID) (*Organization, error)
+}
+
+// PostgresStore keeps organizations in organizations and memberships in
+// organization_members
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed organization store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CreateOrganization inserts an organization
+func (s *PostgresStore) CreateOrganization(ctx context.Context, org *Organization) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO organizations (id, slug, name, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		org.ID, org.Slug, org.Name, org.CreatedBy, org.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateSlug
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+// GetOrganization retrieves an organization
+func (s *PostgresStore) GetOrganization(ctx context.Context, id uuid.UUID) (*Organization, error) {
+	var org Organization
+	err := s.db.GetContext(ctx, &org, `SELECT id, slug, name, created_by, created_at FROM organizations WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+// ListOrganizations retrieves the organizations by slug
+func (s *PostgresStore) ListOrganizations(ctx context.Context) ([]*Organization, error) {
+	orgs := []*Organization{}
+	if err := s.db.SelectContext(ctx, &orgs, `SELECT id, slug, name, created_by, created_at FROM organizations ORDER BY slug`); err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// DeleteOrganization deletes an organization; its memberships cascade
+func (s *PostgresStore) DeleteOrganization(ctx context.Context, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	return requireRow(res, ErrOrganizationNotFound)
+}
+
+// AddMember adds or updates a membership in one transaction
+func (s *PostgresStore) AddMember(ctx context.Context, member *Member) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the user so that concurrent additions agree on their default
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, member.UserID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	if member.Default {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE organization_members SET is_default = false
+			WHERE user_id = $1 AND organization_id <> $2 AND is_default`,
+			member.UserID, member.OrganizationID); err != nil {
+			return fmt.Errorf("failed to clear default organization: %w", err)
+		}
+	}
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, is_default, created_at)
+		VALUES ($1, $2, $3 OR NOT EXISTS (
+			SELECT 1 FROM organization_members WHERE user_id = $2 AND organization_id <> $1 AND is_default
+		), $4)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET is_default = EXCLUDED.is_default OR organization_members.is_default
+		RETURNING is_default, created_at`,
+		member.OrganizationID, member.UserID, member.Default, member.CreatedAt).Scan(&member.Default, &member.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		if pqErr.Constraint == "organization_members_user_id_fkey" {
+			return ErrUserNotFound
+		}
+		return ErrOrganizationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add organization member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit organization member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember removes a membership
+func (s *PostgresStore) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+	return requireRow(res, ErrNotMember)
+}
+
+// ListMembers retrieves the members of an organization by username
+func (s *PostgresStore) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*Member, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.organization_id, m.user_id, u.username, m.is_default, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY u.username`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*Member{}
+	for rows.Next() {
+		m := &Member{}
+		if err := rows.Scan(&m.OrganizationID, &m.UserID, &m.Username, &m.Default, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	return members, nil
+}
+
+// ListMemberships retrieves the organizations of a user by slug
+func (s *PostgresStore) ListMemberships(ctx context.Context, userID uuid.UUID) ([]*Membership, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.id, o.slug, o.name, o.created_by, o.created_at, m.is_default
+		FROM organization_members m
+		JOIN organizations o ON o.id = m.organization_id
+		WHERE m.user_id = $1
+		ORDER BY o.slug`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization memberships: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := []*Membership{}
+	for rows.Next() {
+		m := &Membership{}
+		if err := rows.Scan(&m.ID, &m.Slug, &m.Name, &m.CreatedBy, &m.CreatedAt, &m.Default); err != nil {
+			return nil, fmt.Errorf("failed to scan organization membership: %w", err)
+		}
+		memberships = append(memberships, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list organization memberships: %w", err)
+	}
+	return memberships, nil
+}
+
+// UserOrganization retrieves the organization a user was created in
+func (s *PostgresStore) UserOrganization(ctx context.Context, userID uuid.UUID) (*Organization, error) {
+	var org Organization
+	err := s.db.GetContext(ctx, &org, `
+		SELECT o.id, o.slug, o.name, o.created_by, o.created_at
+		FROM users u
+		JOIN organizations o ON o.id = u.org_id
+		WHERE u.id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user organization: %w", err)
+	}
+	return &org, nil
+}
+
+// requireRow returns notFound if a statement affected no row
+func requireRow(res sql.Result, notFound error) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rows == 0 {
+		return notFound
+	}
+	return nil
+}
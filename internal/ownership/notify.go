@@ -0,0 +1,62 @@
+package ownership
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// Notification events
+const (
+	EventTransferInitiated = "ownership_transfer.initiated"
+	EventTransferAccepted  = "ownership_transfer.accepted"
+	EventTransferDeclined  = "ownership_transfer.declined"
+	EventTransferCancelled = "ownership_transfer.cancelled"
+	EventTransferExpired   = "ownership_transfer.expired"
+)
+
+// Notification tells the parties of a transfer about a step in the workflow
+type Notification struct {
+	Event      string    `json:"event"`
+	Recipients []string  `json:"recipients"`
+	Transfer   *Transfer `json:"transfer"`
+}
+
+// Notifier delivers transfer notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the log. It is used until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the notification
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	log.Printf("Ownership transfer %s: %s (%s -> %s, %d CIs), notifying %s",
+		n.Transfer.ID, n.Event, n.Transfer.FromOwner, n.Transfer.ToOwner, len(n.Transfer.CIIDs), strings.Join(n.Recipients, ", "))
+	return nil
+}
+
+// recipientsFor returns who is told about an event
+func recipientsFor(event string, transfer *Transfer) []string {
+	var recipients []string
+	switch event {
+	case EventTransferInitiated, EventTransferCancelled:
+		recipients = []string{transfer.ToOwner}
+	case EventTransferAccepted, EventTransferDeclined:
+		recipients = []string{transfer.FromOwner, transfer.InitiatedBy}
+	case EventTransferExpired:
+		recipients = []string{transfer.FromOwner, transfer.InitiatedBy, transfer.ToOwner}
+	}
+
+	seen := make(map[string]bool, len(recipients))
+	unique := recipients[:0]
+	for _, recipient := range recipients {
+		if recipient != "" && !seen[recipient] {
+			seen[recipient] = true
+			unique = append(unique, recipient)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,283 @@
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// systemActor is recorded as the responder when the expiry job closes a transfer
+const systemActor = "system"
+
+// InitiateRequest represents a request to offer CIs to a new owner
+type InitiateRequest struct {
+	CIIDs   []uuid.UUID `json:"ci_ids"`
+	ToOwner string      `json:"to_owner"`
+	Reason  string      `json:"reason,omitempty"`
+	// Deadline defaults to the service deadline when not set
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// Validate checks the request fields that do not depend on stored state
+func (r *InitiateRequest) Validate() error {
+	if len(r.CIIDs) == 0 {
+		return fmt.Errorf("%w: at least one CI is required", ErrInvalidTransfer)
+	}
+	if strings.TrimSpace(r.ToOwner) == "" {
+		return fmt.Errorf("%w: to_owner is required", ErrInvalidTransfer)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(r.CIIDs))
+	for _, id := range r.CIIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: CI %s is listed more than once", ErrInvalidTransfer, id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Service runs the ownership transfer workflow
+type Service struct {
+	store           Store
+	notifier        Notifier
+	defaultDeadline time.Duration
+	maxDeadline     time.Duration
+	now             func() time.Time
+}
+
+// NewService creates a new ownership transfer service. A nil notifier logs notifications.
+func NewService(store Store, notifier Notifier, defaultDeadline, maxDeadline time.Duration) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	if maxDeadline <= 0 {
+		maxDeadline = MaxDeadline
+	}
+	if defaultDeadline <= 0 || defaultDeadline > maxDeadline {
+		defaultDeadline = DefaultDeadline
+		if defaultDeadline > maxDeadline {
+			defaultDeadline = maxDeadline
+		}
+	}
+	return &Service{
+		store:           store,
+		notifier:        notifier,
+		defaultDeadline: defaultDeadline,
+		maxDeadline:     maxDeadline,
+		now:             time.Now,
+	}
+}
+
+// Initiate offers the CIs to a new owner. All CIs must share the same owner,
+// which the actor must be able to act as unless they are an admin.
+func (s *Service) Initiate(ctx context.Context, actor Actor, req InitiateRequest) (*Transfer, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	owners, err := s.store.CurrentOwners(ctx, req.CIIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromOwner string
+	for i, id := range req.CIIDs {
+		owner, ok := owners[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrCINotFound, id)
+		}
+		if i > 0 && owner != fromOwner {
+			return nil, ErrMixedOwners
+		}
+		fromOwner = owner
+	}
+
+	toOwner := strings.TrimSpace(req.ToOwner)
+	if fromOwner == toOwner {
+		return nil, ErrSameOwner
+	}
+	// Unowned CIs can only be assigned by an admin
+	if !actor.IsAdmin && (fromOwner == "" || !actor.Is(fromOwner)) {
+		return nil, ErrNotAuthorized
+	}
+
+	now := s.now()
+	deadline := now.Add(s.defaultDeadline)
+	if req.Deadline != nil {
+		deadline = *req.Deadline
+		if !deadline.After(now) {
+			return nil, fmt.Errorf("%w: deadline must be in the future", ErrInvalidTransfer)
+		}
+		if deadline.Sub(now) > s.maxDeadline {
+			return nil, fmt.Errorf("%w: deadline must be within %s", ErrInvalidTransfer, s.maxDeadline)
+		}
+	}
+
+	transfer := &Transfer{
+		ID:          uuid.New(),
+		CIIDs:       req.CIIDs,
+		FromOwner:   fromOwner,
+		ToOwner:     toOwner,
+		Status:      StatusPending,
+		Reason:      req.Reason,
+		InitiatedBy: actor.ID,
+		Deadline:    deadline,
+		CreatedAt:   now,
+	}
+
+	if err := s.store.CreateTransfer(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, EventTransferInitiated, transfer)
+	return transfer, nil
+}
+
+// Get retrieves a transfer
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Transfer, error) {
+	return s.store.GetTransfer(ctx, id)
+}
+
+// List retrieves transfers matching the filter
+func (s *Service) List(ctx context.Context, filter Filter) ([]*Transfer, error) {
+	return s.store.ListTransfers(ctx, filter)
+}
+
+// Accept completes a transfer on behalf of the recipient. A transfer past its
+// deadline is expired instead and ErrTransferExpired is returned.
+func (s *Service) Accept(ctx context.Context, actor Actor, id uuid.UUID, note string) (*Transfer, error) {
+	transfer, err := s.store.GetTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin && !actor.Is(transfer.ToOwner) {
+		return nil, ErrNotAuthorized
+	}
+	if transfer.Status != StatusPending {
+		return nil, ErrTransferNotPending
+	}
+
+	if transfer.Overdue(s.now()) {
+		if _, err := s.expire(ctx, id); err != nil && err != ErrTransferNotPending {
+			return nil, err
+		}
+		return nil, ErrTransferExpired
+	}
+
+	transfer, err = s.store.CompleteTransfer(ctx, id, StatusAccepted, actor.ID, note, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transfer.Skipped) > 0 {
+		log.Printf("Ownership transfer %s accepted, %d CIs skipped because their owner changed", transfer.ID, len(transfer.Skipped))
+	}
+	s.notify(ctx, EventTransferAccepted, transfer)
+	return transfer, nil
+}
+
+// Decline rejects a transfer on behalf of the recipient; ownership is unchanged
+func (s *Service) Decline(ctx context.Context, actor Actor, id uuid.UUID, note string) (*Transfer, error) {
+	transfer, err := s.store.GetTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin && !actor.Is(transfer.ToOwner) {
+		return nil, ErrNotAuthorized
+	}
+
+	transfer, err = s.store.CompleteTransfer(ctx, id, StatusDeclined, actor.ID, note, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, EventTransferDeclined, transfer)
+	return transfer, nil
+}
+
+// Cancel withdraws a transfer on behalf of the current owner or the initiator
+func (s *Service) Cancel(ctx context.Context, actor Actor, id uuid.UUID, note string) (*Transfer, error) {
+	transfer, err := s.store.GetTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin && !actor.Is(transfer.FromOwner) && actor.ID != transfer.InitiatedBy {
+		return nil, ErrNotAuthorized
+	}
+
+	transfer, err = s.store.CompleteTransfer(ctx, id, StatusCancelled, actor.ID, note, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, EventTransferCancelled, transfer)
+	return transfer, nil
+}
+
+// ExpireOverdue expires pending transfers past their deadline and returns how many were expired
+func (s *Service) ExpireOverdue(ctx context.Context) (int, error) {
+	ids, err := s.store.ListOverdue(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		if _, err := s.expire(ctx, id); err != nil {
+			// Responded to since it was listed
+			if err == ErrTransferNotPending {
+				continue
+			}
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// Run expires overdue transfers every interval until the context is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.ExpireOverdue(ctx); err != nil {
+				log.Printf("Failed to expire ownership transfers: %v", err)
+			} else if n > 0 {
+				log.Printf("Expired %d ownership transfers", n)
+			}
+		}
+	}
+}
+
+// expire closes an overdue transfer
+func (s *Service) expire(ctx context.Context, id uuid.UUID) (*Transfer, error) {
+	transfer, err := s.store.CompleteTransfer(ctx, id, StatusExpired, systemActor, "", s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, EventTransferExpired, transfer)
+	return transfer, nil
+}
+
+// notify delivers a notification; failures are logged since the workflow step has already been stored
+func (s *Service) notify(ctx context.Context, event string, transfer *Transfer) {
+	notification := Notification{
+		Event:      event,
+		Recipients: recipientsFor(event, transfer),
+		Transfer:   transfer,
+	}
+	if err := s.notifier.Notify(ctx, notification); err != nil {
+		log.Printf("Failed to send %s notification for ownership transfer %s: %v", event, transfer.ID, err)
+	}
+}
@@ -0,0 +1,260 @@
+package ownership
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	owners    map[uuid.UUID]string
+	transfers map[uuid.UUID]*Transfer
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{owners: map[uuid.UUID]string{}, transfers: map[uuid.UUID]*Transfer{}}
+}
+
+func (m *memoryStore) CurrentOwners(ctx context.Context, ciIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	owners := map[uuid.UUID]string{}
+	for _, id := range ciIDs {
+		if owner, ok := m.owners[id]; ok {
+			owners[id] = owner
+		}
+	}
+	return owners, nil
+}
+
+func (m *memoryStore) CreateTransfer(ctx context.Context, transfer *Transfer) error {
+	for _, existing := range m.transfers {
+		if existing.Status != StatusPending {
+			continue
+		}
+		for _, a := range existing.CIIDs {
+			for _, b := range transfer.CIIDs {
+				if a == b {
+					return ErrAlreadyPending
+				}
+			}
+		}
+	}
+	stored := *transfer
+	m.transfers[transfer.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) GetTransfer(ctx context.Context, id uuid.UUID) (*Transfer, error) {
+	transfer, ok := m.transfers[id]
+	if !ok {
+		return nil, ErrTransferNotFound
+	}
+	copied := *transfer
+	return &copied, nil
+}
+
+func (m *memoryStore) ListTransfers(ctx context.Context, filter Filter) ([]*Transfer, error) {
+	var transfers []*Transfer
+	for _, transfer := range m.transfers {
+		if filter.Status == "" || transfer.Status == filter.Status {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
+
+func (m *memoryStore) CompleteTransfer(ctx context.Context, id uuid.UUID, status, respondedBy, note string, at time.Time) (*Transfer, error) {
+	transfer, ok := m.transfers[id]
+	if !ok {
+		return nil, ErrTransferNotFound
+	}
+	if transfer.Status != StatusPending {
+		return nil, ErrTransferNotPending
+	}
+	if status == StatusAccepted {
+		for _, ciID := range transfer.CIIDs {
+			if m.owners[ciID] == transfer.FromOwner {
+				m.owners[ciID] = transfer.ToOwner
+			} else {
+				transfer.Skipped = append(transfer.Skipped, ciID)
+			}
+		}
+	}
+	transfer.Status = status
+	transfer.RespondedAt = &at
+	transfer.RespondedBy = respondedBy
+	transfer.Note = note
+	copied := *transfer
+	return &copied, nil
+}
+
+func (m *memoryStore) ListOverdue(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, transfer := range m.transfers {
+		if transfer.Overdue(now) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type recordingNotifier struct {
+	notifications []Notification
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, n Notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func newTestService() (*Service, *memoryStore, *recordingNotifier, *time.Time) {
+	store := newMemoryStore()
+	notifier := &recordingNotifier{}
+	service := NewService(store, notifier, 48*time.Hour, 7*24*time.Hour)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, store, notifier, &now
+}
+
+func TestService_InitiateAndAccept(t *testing.T) {
+	ctx := context.Background()
+	service, store, notifier, now := newTestService()
+
+	ci1, ci2 := uuid.New(), uuid.New()
+	store.owners[ci1] = "alice"
+	store.owners[ci2] = "alice"
+
+	alice := Actor{ID: "alice", Identities: []string{"alice"}}
+	bob := Actor{ID: "bob", Identities: []string{"bob", "platform-team"}}
+
+	transfer, err := service.Initiate(ctx, alice, InitiateRequest{CIIDs: []uuid.UUID{ci1, ci2}, ToOwner: "platform-team"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", transfer.FromOwner)
+	assert.Equal(t, StatusPending, transfer.Status)
+	assert.Equal(t, now.Add(48*time.Hour), transfer.Deadline)
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, []string{"platform-team"}, notifier.notifications[0].Recipients)
+
+	// Ownership does not change until the recipient accepts
+	assert.Equal(t, "alice", store.owners[ci1])
+
+	_, err = service.Accept(ctx, alice, transfer.ID, "")
+	assert.ErrorIs(t, err, ErrNotAuthorized)
+
+	// A CI reassigned in the meantime is left alone
+	store.owners[ci2] = "carol"
+
+	accepted, err := service.Accept(ctx, bob, transfer.ID, "taking over")
+	require.NoError(t, err)
+	assert.Equal(t, StatusAccepted, accepted.Status)
+	assert.Equal(t, []uuid.UUID{ci2}, accepted.Skipped)
+	assert.Equal(t, "platform-team", store.owners[ci1])
+	assert.Equal(t, "carol", store.owners[ci2])
+	assert.Equal(t, EventTransferAccepted, notifier.notifications[1].Event)
+	assert.Equal(t, []string{"alice"}, notifier.notifications[1].Recipients)
+
+	_, err = service.Decline(ctx, bob, transfer.ID, "")
+	assert.ErrorIs(t, err, ErrTransferNotPending)
+}
+
+func TestService_InitiateValidation(t *testing.T) {
+	ctx := context.Background()
+	service, store, _, now := newTestService()
+
+	ci1, ci2, unowned := uuid.New(), uuid.New(), uuid.New()
+	store.owners[ci1] = "alice"
+	store.owners[ci2] = "dave"
+	store.owners[unowned] = ""
+
+	alice := Actor{ID: "alice", Identities: []string{"alice"}}
+	admin := Actor{ID: "root", IsAdmin: true}
+	tooLate := now.Add(30 * 24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		actor Actor
+		req   InitiateRequest
+		err   error
+	}{
+		{"mixed owners", admin, InitiateRequest{CIIDs: []uuid.UUID{ci1, ci2}, ToOwner: "bob"}, ErrMixedOwners},
+		{"not the owner", alice, InitiateRequest{CIIDs: []uuid.UUID{ci2}, ToOwner: "bob"}, ErrNotAuthorized},
+		{"unowned needs admin", alice, InitiateRequest{CIIDs: []uuid.UUID{unowned}, ToOwner: "alice"}, ErrNotAuthorized},
+		{"same owner", alice, InitiateRequest{CIIDs: []uuid.UUID{ci1}, ToOwner: "alice"}, ErrSameOwner},
+		{"unknown CI", admin, InitiateRequest{CIIDs: []uuid.UUID{uuid.New()}, ToOwner: "bob"}, ErrCINotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Initiate(ctx, tt.actor, tt.req)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	_, err := service.Initiate(ctx, alice, InitiateRequest{CIIDs: []uuid.UUID{ci1}, ToOwner: "bob", Deadline: &tooLate})
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+	_, err = service.Initiate(ctx, alice, InitiateRequest{CIIDs: []uuid.UUID{ci1, ci1}, ToOwner: "bob"})
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+
+	// Admins may hand out unowned CIs, and a CI can only be in one pending transfer
+	_, err = service.Initiate(ctx, admin, InitiateRequest{CIIDs: []uuid.UUID{unowned}, ToOwner: "bob"})
+	require.NoError(t, err)
+	_, err = service.Initiate(ctx, admin, InitiateRequest{CIIDs: []uuid.UUID{unowned}, ToOwner: "carol"})
+	assert.ErrorIs(t, err, ErrAlreadyPending)
+}
+
+func TestService_Expiry(t *testing.T) {
+	ctx := context.Background()
+	service, store, notifier, now := newTestService()
+
+	ci1, ci2 := uuid.New(), uuid.New()
+	store.owners[ci1] = "alice"
+	store.owners[ci2] = "alice"
+	alice := Actor{ID: "alice", Identities: []string{"alice"}}
+	bob := Actor{ID: "bob", Identities: []string{"bob"}}
+
+	first, err := service.Initiate(ctx, alice, InitiateRequest{CIIDs: []uuid.UUID{ci1}, ToOwner: "bob"})
+	require.NoError(t, err)
+	second, err := service.Initiate(ctx, alice, InitiateRequest{CIIDs: []uuid.UUID{ci2}, ToOwner: "bob"})
+	require.NoError(t, err)
+
+	*now = now.Add(49 * time.Hour)
+
+	// Accepting after the deadline expires the transfer instead
+	_, err = service.Accept(ctx, bob, first.ID, "")
+	assert.ErrorIs(t, err, ErrTransferExpired)
+	assert.Equal(t, "alice", store.owners[ci1])
+	assert.Equal(t, StatusExpired, store.transfers[first.ID].Status)
+
+	n, err := service.ExpireOverdue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, StatusExpired, store.transfers[second.ID].Status)
+
+	last := notifier.notifications[len(notifier.notifications)-1]
+	assert.Equal(t, EventTransferExpired, last.Event)
+	assert.Equal(t, []string{"alice", "bob"}, last.Recipients)
+}
+
+func TestService_Cancel(t *testing.T) {
+	ctx := context.Background()
+	service, store, _, _ := newTestService()
+
+	ci := uuid.New()
+	store.owners[ci] = "alice"
+	alice := Actor{ID: "alice", Identities: []string{"alice"}}
+	bob := Actor{ID: "bob", Identities: []string{"bob"}}
+
+	transfer, err := service.Initiate(ctx, alice, InitiateRequest{CIIDs: []uuid.UUID{ci}, ToOwner: "bob"})
+	require.NoError(t, err)
+
+	_, err = service.Cancel(ctx, bob, transfer.ID, "")
+	assert.ErrorIs(t, err, ErrNotAuthorized)
+
+	cancelled, err := service.Cancel(ctx, alice, transfer.ID, "wrong team")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelled.Status)
+	assert.Equal(t, "alice", store.owners[ci])
+}
@@ -0,0 +1,314 @@
+package ownership
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store persists transfers and applies accepted ones
+type Store interface {
+	// CurrentOwners returns the owner of each CI
+	CurrentOwners(ctx context.Context, ciIDs []uuid.UUID) (map[uuid.UUID]string, error)
+	CreateTransfer(ctx context.Context, transfer *Transfer) error
+	GetTransfer(ctx context.Context, id uuid.UUID) (*Transfer, error)
+	ListTransfers(ctx context.Context, filter Filter) ([]*Transfer, error)
+	// CompleteTransfer moves a pending transfer to status. Accepting it reassigns
+	// the CIs still owned by the original owner. Audit entries are written in
+	// the same transaction.
+	CompleteTransfer(ctx context.Context, id uuid.UUID, status, respondedBy, note string, at time.Time) (*Transfer, error)
+	ListOverdue(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+}
+
+// PostgresStore keeps transfers in the ownership_transfers and ownership_transfer_items tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed transfer store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CurrentOwners retrieves the owner of each CI that exists and is not deleted
+func (s *PostgresStore) CurrentOwners(ctx context.Context, ciIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, COALESCE(owner, '')
+		FROM configuration_items
+		WHERE id = ANY($1) AND is_deleted = false`, pq.Array(ciIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := make(map[uuid.UUID]string, len(ciIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var owner string
+		if err := rows.Scan(&id, &owner); err != nil {
+			return nil, fmt.Errorf("failed to scan CI owner: %w", err)
+		}
+		owners[id] = owner
+	}
+	return owners, rows.Err()
+}
+
+// CreateTransfer stores a pending transfer. The CIs are locked so the owner
+// check and the pending check cannot race with a concurrent transfer.
+func (s *PostgresStore) CreateTransfer(ctx context.Context, transfer *Transfer) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owned int
+	err = tx.GetContext(ctx, &owned, `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM configuration_items
+			WHERE id = ANY($1) AND is_deleted = false AND owner = $2
+			FOR UPDATE
+		) locked`, pq.Array(transfer.CIIDs), transfer.FromOwner)
+	if err != nil {
+		return fmt.Errorf("failed to lock CIs: %w", err)
+	}
+	if owned != len(transfer.CIIDs) {
+		return ErrOwnerChanged
+	}
+
+	var pending int
+	err = tx.GetContext(ctx, &pending, `
+		SELECT COUNT(*)
+		FROM ownership_transfer_items i
+		JOIN ownership_transfers t ON t.id = i.transfer_id
+		WHERE i.ci_id = ANY($1) AND t.status = $2`, pq.Array(transfer.CIIDs), StatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to check pending transfers: %w", err)
+	}
+	if pending > 0 {
+		return ErrAlreadyPending
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ownership_transfers (id, from_owner, to_owner, status, reason, initiated_by, deadline, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		transfer.ID, transfer.FromOwner, transfer.ToOwner, transfer.Status, transfer.Reason,
+		transfer.InitiatedBy, transfer.Deadline, transfer.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create ownership transfer: %w", err)
+	}
+
+	for _, ciID := range transfer.CIIDs {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO ownership_transfer_items (transfer_id, ci_id) VALUES ($1, $2)`, transfer.ID, ciID); err != nil {
+			return fmt.Errorf("failed to add CI to ownership transfer: %w", err)
+		}
+	}
+
+	if err := insertAudit(ctx, tx, "ownership_transfer", transfer.ID, "initiated", transfer.InitiatedBy, transfer.CreatedAt, map[string]interface{}{
+		"from_owner": transfer.FromOwner,
+		"to_owner":   transfer.ToOwner,
+		"ci_ids":     transfer.CIIDs,
+		"deadline":   transfer.Deadline,
+		"reason":     transfer.Reason,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ownership transfer: %w", err)
+	}
+	return nil
+}
+
+const transferColumns = `id, from_owner, to_owner, status, COALESCE(reason, '') AS reason, initiated_by, deadline,
+	created_at, responded_at, COALESCE(responded_by, '') AS responded_by, COALESCE(note, '') AS note`
+
+// GetTransfer retrieves a transfer with its CIs
+func (s *PostgresStore) GetTransfer(ctx context.Context, id uuid.UUID) (*Transfer, error) {
+	return getTransfer(ctx, s.db, id, false)
+}
+
+// getTransfer reads a transfer, optionally locking it for update
+func getTransfer(ctx context.Context, q sqlx.QueryerContext, id uuid.UUID, lock bool) (*Transfer, error) {
+	query := "SELECT " + transferColumns + " FROM ownership_transfers WHERE id = $1"
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	var transfer Transfer
+	if err := sqlx.GetContext(ctx, q, &transfer, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("failed to get ownership transfer: %w", err)
+	}
+
+	if err := sqlx.SelectContext(ctx, q, &transfer.CIIDs, `
+		SELECT ci_id FROM ownership_transfer_items WHERE transfer_id = $1 ORDER BY ci_id`, id); err != nil {
+		return nil, fmt.Errorf("failed to get ownership transfer CIs: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+// ListTransfers retrieves transfers, newest first, without their CI lists
+func (s *PostgresStore) ListTransfers(ctx context.Context, filter Filter) ([]*Transfer, error) {
+	var conditions []string
+	args := []interface{}{}
+	argCount := 1
+
+	if filter.Owner != "" {
+		conditions = append(conditions, fmt.Sprintf("from_owner = $%d", argCount))
+		args = append(args, filter.Owner)
+		argCount++
+	}
+	if filter.Recipient != "" {
+		conditions = append(conditions, fmt.Sprintf("to_owner = $%d", argCount))
+		args = append(args, filter.Recipient)
+		argCount++
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argCount))
+		args = append(args, filter.Status)
+		argCount++
+	}
+	if filter.CIID != nil {
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT transfer_id FROM ownership_transfer_items WHERE ci_id = $%d)", argCount))
+		args = append(args, *filter.CIID)
+		argCount++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf("SELECT %s FROM ownership_transfers %s ORDER BY created_at DESC LIMIT $%d", transferColumns, whereClause, argCount)
+
+	var transfers []*Transfer
+	if err := s.db.SelectContext(ctx, &transfers, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list ownership transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// CompleteTransfer moves a pending transfer to its final status
+func (s *PostgresStore) CompleteTransfer(ctx context.Context, id uuid.UUID, status, respondedBy, note string, at time.Time) (*Transfer, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transfer, err := getTransfer(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != StatusPending {
+		return nil, ErrTransferNotPending
+	}
+
+	if status == StatusAccepted {
+		var moved []uuid.UUID
+		err := tx.SelectContext(ctx, &moved, `
+			UPDATE configuration_items
+			SET owner = $1, updated_at = $2
+			WHERE id = ANY($3) AND owner = $4 AND is_deleted = false
+			RETURNING id`, transfer.ToOwner, at, pq.Array(transfer.CIIDs), transfer.FromOwner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer CI ownership: %w", err)
+		}
+
+		movedSet := make(map[uuid.UUID]bool, len(moved))
+		for _, ciID := range moved {
+			movedSet[ciID] = true
+			if err := insertAudit(ctx, tx, "ci", ciID, "ownership_transferred", respondedBy, at, map[string]interface{}{
+				"transfer_id": transfer.ID,
+				"from_owner":  transfer.FromOwner,
+				"to_owner":    transfer.ToOwner,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		for _, ciID := range transfer.CIIDs {
+			if !movedSet[ciID] {
+				transfer.Skipped = append(transfer.Skipped, ciID)
+			}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE ownership_transfers
+		SET status = $1, responded_at = $2, responded_by = NULLIF($3, ''), note = NULLIF($4, '')
+		WHERE id = $5`, status, at, respondedBy, note, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update ownership transfer: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, "ownership_transfer", transfer.ID, status, respondedBy, at, map[string]interface{}{
+		"from_owner": transfer.FromOwner,
+		"to_owner":   transfer.ToOwner,
+		"ci_ids":     transfer.CIIDs,
+		"skipped":    transfer.Skipped,
+		"note":       note,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit ownership transfer: %w", err)
+	}
+
+	transfer.Status = status
+	transfer.RespondedAt = &at
+	transfer.RespondedBy = respondedBy
+	transfer.Note = note
+	return transfer, nil
+}
+
+// ListOverdue retrieves pending transfers whose deadline has passed
+func (s *PostgresStore) ListOverdue(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := s.db.SelectContext(ctx, &ids, `
+		SELECT id FROM ownership_transfers WHERE status = $1 AND deadline < $2`, StatusPending, now); err != nil {
+		return nil, fmt.Errorf("failed to list overdue ownership transfers: %w", err)
+	}
+	return ids, nil
+}
+
+// insertAudit writes an audit log entry. changed_by references users, so actors
+// that are not user IDs, such as the expiry job, are recorded in the details.
+func insertAudit(ctx context.Context, tx *sqlx.Tx, entityType string, entityID uuid.UUID, action, actor string, at time.Time, details map[string]interface{}) error {
+	var changedBy *uuid.UUID
+	if id, err := uuid.Parse(actor); err == nil {
+		changedBy = &id
+	} else if actor != "" {
+		details["actor"] = actor
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ($1, $2, $3, $4, $5, $6)`, entityType, entityID, action, changedBy, at, data)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
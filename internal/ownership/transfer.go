@@ -0,0 +1,91 @@
+// Package ownership implements the CI ownership transfer workflow. The current
+// owner of a set of CIs, or an admin, offers them to another user or team; the
+// recipient must accept before the deadline for ownership to change. Every step
+// is written to the audit log and announced to the parties involved, so assets
+// are never handed over, or orphaned, silently.
+package ownership
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transfer statuses
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// Deadline defaults
+const (
+	DefaultDeadline = 7 * 24 * time.Hour
+	MaxDeadline     = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidTransfer    = errors.New("invalid ownership transfer")
+	ErrTransferNotFound   = errors.New("ownership transfer not found")
+	ErrTransferNotPending = errors.New("ownership transfer is no longer pending")
+	ErrTransferExpired    = errors.New("ownership transfer deadline has passed")
+	ErrNotAuthorized      = errors.New("not authorized for this ownership transfer")
+	ErrCINotFound         = errors.New("CI not found")
+	ErrMixedOwners        = errors.New("CIs in a transfer must have the same owner")
+	ErrSameOwner          = errors.New("recipient already owns the CIs")
+	ErrAlreadyPending     = errors.New("CI already has a pending ownership transfer")
+	ErrOwnerChanged       = errors.New("CI owner changed while the transfer was being created")
+)
+
+// Transfer is an offer to hand a set of CIs from one owner to another
+type Transfer struct {
+	ID          uuid.UUID   `json:"id" db:"id"`
+	CIIDs       []uuid.UUID `json:"ci_ids" db:"-"`
+	FromOwner   string      `json:"from_owner" db:"from_owner"`
+	ToOwner     string      `json:"to_owner" db:"to_owner"`
+	Status      string      `json:"status" db:"status"`
+	Reason      string      `json:"reason,omitempty" db:"reason"`
+	InitiatedBy string      `json:"initiated_by" db:"initiated_by"`
+	Deadline    time.Time   `json:"deadline" db:"deadline"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	RespondedAt *time.Time  `json:"responded_at,omitempty" db:"responded_at"`
+	RespondedBy string      `json:"responded_by,omitempty" db:"responded_by"`
+	Note        string      `json:"note,omitempty" db:"note"`
+	// Skipped lists CIs whose owner changed before acceptance and were therefore not transferred
+	Skipped []uuid.UUID `json:"skipped,omitempty" db:"-"`
+}
+
+// Overdue reports whether a pending transfer has passed its deadline
+func (t *Transfer) Overdue(now time.Time) bool {
+	return t.Status == StatusPending && now.After(t.Deadline)
+}
+
+// Actor is the caller of a workflow step
+type Actor struct {
+	ID string
+	// Identities are the owner values the actor may act as, e.g. their user ID, username and teams
+	Identities []string
+	IsAdmin    bool
+}
+
+// Is reports whether the actor may act as the given owner
+func (a Actor) Is(owner string) bool {
+	for _, identity := range a.Identities {
+		if identity != "" && identity == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter narrows a transfer listing
+type Filter struct {
+	Owner     string // transfers from this owner
+	Recipient string // transfers to this owner
+	Status    string
+	CIID      *uuid.UUID
+	Limit     int
+}
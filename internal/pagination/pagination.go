@@ -0,0 +1,165 @@
+// Package pagination bounds the page sizes of list endpoints. Page sizes
+// default to and are capped at configured limits, which endpoints may
+// override; the global maximum is a hard cap enforced for every request. The
+// limits of the matched endpoint travel in the request context down to the
+// repositories, which fall back to the default page size when asked for a page
+// size outside them.
+package pagination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Page sizes used when none are configured
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageSizeParams are the query parameters list endpoints take page sizes in
+var PageSizeParams = []string{"page_size", "per_page", "size"}
+
+// Limits are the default and maximum page sizes of a list endpoint
+type Limits struct {
+	Default int `json:"default_page_size"`
+	Max     int `json:"max_page_size"`
+}
+
+// DefaultLimits are the limits of requests no policy has set limits for
+var DefaultLimits = Limits{Default: DefaultPageSize, Max: MaxPageSize}
+
+// PageSize returns size when it is within the limits, else the default page size
+func (l Limits) PageSize(size int) int {
+	if size <= 0 || size > l.Max {
+		return l.Default
+	}
+	return size
+}
+
+type contextKey struct{}
+
+// WithLimits returns a context carrying the page size limits of a request
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, contextKey{}, limits)
+}
+
+// FromContext returns the page size limits of a request, DefaultLimits when
+// none were set
+func FromContext(ctx context.Context) Limits {
+	if limits, ok := ctx.Value(contextKey{}).(Limits); ok {
+		return limits
+	}
+	return DefaultLimits
+}
+
+// Config defines the page size limits. Endpoints maps a route to its limits,
+// where the route is a mux path template optionally prefixed by a method,
+// e.g. "GET /api/v1/cis"; zero fields fall back to the global limits.
+type Config struct {
+	Limits    Limits
+	Endpoints map[string]Limits
+}
+
+// Policy resolves the page size limits of requests and enforces the hard cap
+type Policy struct {
+	limits    Limits
+	endpoints map[string]Limits
+}
+
+// NewPolicy creates a policy from validated limits
+func NewPolicy(cfg Config) *Policy {
+	p := &Policy{limits: cfg.Limits, endpoints: make(map[string]Limits, len(cfg.Endpoints))}
+	if p.limits.Default <= 0 {
+		p.limits.Default = DefaultPageSize
+	}
+	if p.limits.Max <= 0 {
+		p.limits.Max = MaxPageSize
+	}
+	for route, limits := range cfg.Endpoints {
+		if limits.Default <= 0 {
+			limits.Default = p.limits.Default
+		}
+		if limits.Max <= 0 || limits.Max > p.limits.Max {
+			limits.Max = p.limits.Max
+		}
+		if limits.Default > limits.Max {
+			limits.Default = limits.Max
+		}
+		p.endpoints[normalizeRoute(route)] = limits
+	}
+	return p
+}
+
+// Limits returns the global limits; their maximum is the hard cap
+func (p *Policy) Limits() Limits {
+	return p.limits
+}
+
+// For returns the limits of the route a request matched, preferring a method
+// specific entry, else the global limits
+func (p *Policy) For(r *http.Request) Limits {
+	if len(p.endpoints) == 0 {
+		return p.limits
+	}
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return p.limits
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return p.limits
+	}
+
+	if limits, ok := p.endpoints[strings.ToUpper(r.Method)+" "+template]; ok {
+		return limits
+	}
+	if limits, ok := p.endpoints[template]; ok {
+		return limits
+	}
+	return p.limits
+}
+
+// Middleware rejects requests for pages larger than the hard cap and passes
+// the limits of the matched route on in the request context
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		for _, name := range PageSizeParams {
+			size, err := strconv.Atoi(strings.TrimSpace(query.Get(name)))
+			if err == nil && size > p.limits.Max {
+				respondOverCap(w, name, p.limits.Max)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(WithLimits(r.Context(), p.For(r))))
+	})
+}
+
+// respondOverCap reports a page size over the hard cap
+func respondOverCap(w http.ResponseWriter, param string, max int) {
+	response, _ := json.Marshal(map[string]interface{}{
+		"error":   "Page size too large",
+		"success": false,
+		"details": fmt.Sprintf("%s must be at most %d", param, max),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(response)
+}
+
+// normalizeRoute uppercases the method of a "[METHOD ]/path/template" entry
+func normalizeRoute(route string) string {
+	route = strings.TrimSpace(route)
+	if method, path, ok := strings.Cut(route, " "); ok {
+		return strings.ToUpper(method) + " " + strings.TrimSpace(path)
+	}
+	return route
+}
@@ -0,0 +1,78 @@
+package pagination
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsPageSize(t *testing.T) {
+	limits := Limits{Default: 25, Max: 200}
+	assert.Equal(t, 25, limits.PageSize(0))
+	assert.Equal(t, 25, limits.PageSize(-1))
+	assert.Equal(t, 150, limits.PageSize(150))
+	assert.Equal(t, 25, limits.PageSize(201))
+
+	assert.Equal(t, DefaultLimits, FromContext(context.Background()))
+	assert.Equal(t, limits, FromContext(WithLimits(context.Background(), limits)))
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	policy := NewPolicy(Config{
+		Limits: Limits{Default: 50, Max: 500},
+		Endpoints: map[string]Limits{
+			"get /api/v1/cis":          {Default: 100},
+			"/api/v1/relationships":    {Max: 1000},
+			"/api/v1/schemas/ci-types": {Default: 10, Max: 40},
+		},
+	})
+
+	var seen Limits
+	record := func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/cis", record).Methods("GET", "POST")
+	router.HandleFunc("/api/v1/relationships", record).Methods("GET")
+	router.HandleFunc("/api/v1/schemas/ci-types", record).Methods("GET")
+	router.HandleFunc("/api/v1/users", record).Methods("GET")
+	router.Use(policy.Middleware)
+
+	cases := map[string]Limits{
+		"GET /api/v1/cis":              {Default: 100, Max: 500},
+		"POST /api/v1/cis":             {Default: 50, Max: 500},
+		"GET /api/v1/relationships":    {Default: 50, Max: 500},
+		"GET /api/v1/schemas/ci-types": {Default: 10, Max: 40},
+		"GET /api/v1/users":            {Default: 50, Max: 500},
+	}
+	for route, expected := range cases {
+		method, path, _ := strings.Cut(route, " ")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, route)
+		assert.Equal(t, expected, seen, route)
+	}
+
+	// The hard cap applies to every endpoint and page size parameter
+	for _, target := range []string{"/api/v1/cis?page_size=501", "/api/v1/users?size=1000", "/api/v1/relationships?per_page=600"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code, target)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Contains(t, body["details"], "must be at most 500")
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cis?page_size=500", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,206 @@
+package pathpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	rules         map[uuid.UUID]*Rule
+	relationships []Relationship
+	reads         int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{rules: map[uuid.UUID]*Rule{}}
+}
+
+func (m *memoryStore) ListRules(ctx context.Context) ([]*Rule, error) {
+	m.reads++
+	rules := []*Rule{}
+	for _, rule := range m.rules {
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+func (m *memoryStore) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, ErrRuleNotFound
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+func (m *memoryStore) CreateRule(ctx context.Context, rule *Rule) error {
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) UpdateRule(ctx context.Context, rule *Rule) error {
+	if _, ok := m.rules[rule.ID]; !ok {
+		return ErrRuleNotFound
+	}
+	copied := *rule
+	m.rules[rule.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.rules[id]; !ok {
+		return ErrRuleNotFound
+	}
+	delete(m.rules, id)
+	return nil
+}
+
+func (m *memoryStore) ListRelationships(ctx context.Context, after uuid.UUID, limit int) ([]Relationship, error) {
+	sorted := append([]Relationship(nil), m.relationships...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID.String() < sorted[j].ID.String() })
+
+	var page []Relationship
+	for _, rel := range sorted {
+		if rel.ID.String() > after.String() && len(page) < limit {
+			page = append(page, rel)
+		}
+	}
+	return page, nil
+}
+
+func noProdOnDev() *Rule {
+	return &Rule{
+		Name:              "no prod on dev",
+		RelationshipTypes: []string{"depends_on"},
+		Attribute:         "environment",
+		Constraint:        ConstraintForbid,
+		SourceValues:      []string{"production"},
+		TargetValues:      []string{"development", "test"},
+		Enabled:           true,
+	}
+}
+
+func attrs(pairs ...string) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		attributes[pairs[i]] = pairs[i+1]
+	}
+	return attributes
+}
+
+func raw(t *testing.T, attributes map[string]interface{}) json.RawMessage {
+	data, err := json.Marshal(attributes)
+	require.NoError(t, err)
+	return data
+}
+
+func TestRule_Validate(t *testing.T) {
+	assert.NoError(t, noProdOnDev().Validate())
+	assert.NoError(t, (&Rule{Name: "tenants", Attribute: "tenant", Constraint: ConstraintSameValue}).Validate())
+
+	invalid := []*Rule{
+		{Attribute: "environment", Constraint: ConstraintSameValue},
+		{Name: "bad path", Attribute: "env..name", Constraint: ConstraintSameValue},
+		{Name: "no targets", Attribute: "environment", Constraint: ConstraintForbid, SourceValues: []string{"production"}, TargetValues: []string{" "}},
+		{Name: "values", Attribute: "tenant", Constraint: ConstraintSameValue, SourceValues: []string{"acme"}},
+		{Name: "unknown", Attribute: "tenant", Constraint: "allow"},
+	}
+	for _, rule := range invalid {
+		assert.ErrorIs(t, rule.Validate(), ErrInvalidRule, rule.Name)
+	}
+}
+
+func TestRule_Evaluate(t *testing.T) {
+	rule := noProdOnDev()
+	require.NoError(t, rule.Validate())
+
+	prod, dev := attrs("environment", "Production"), attrs("environment", "development")
+	assert.Equal(t, "environment Production CIs may not be related to environment development CIs", rule.Evaluate("depends_on", prod, dev))
+	assert.Empty(t, rule.Evaluate("DEPENDS_ON", dev, prod))
+	assert.Empty(t, rule.Evaluate("runs_on", prod, dev))
+	assert.Empty(t, rule.Evaluate("depends_on", prod, attrs()))
+
+	tenants := &Rule{Name: "tenants", Attribute: "tenant", Constraint: ConstraintSameValue, Enabled: true}
+	require.NoError(t, tenants.Validate())
+	assert.Empty(t, tenants.Evaluate("depends_on", attrs("tenant", "acme"), attrs("tenant", "ACME")))
+	assert.Contains(t, tenants.Evaluate("depends_on", attrs("tenant", "acme"), attrs("tenant", "globex")), "within one tenant")
+	assert.Empty(t, tenants.Evaluate("depends_on", attrs("tenant", "acme"), nil))
+}
+
+func TestService_Check(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, 0)
+	ctx := context.Background()
+
+	rule, err := service.CreateRule(ctx, noProdOnDev(), "admin")
+	require.NoError(t, err)
+
+	err = service.Check(ctx, "depends_on", raw(t, attrs("environment", "production")), raw(t, attrs("environment", "test")))
+	var violation *ViolationError
+	require.True(t, errors.As(err, &violation))
+	require.Len(t, violation.Violations, 1)
+	assert.Equal(t, rule.ID, violation.Violations[0].RuleID)
+
+	assert.NoError(t, service.Check(ctx, "depends_on", raw(t, attrs("environment", "production")), raw(t, attrs("environment", "production"))))
+	assert.NoError(t, service.Check(ctx, "depends_on", json.RawMessage(`[]`), nil))
+
+	// Rules are cached between checks
+	assert.Equal(t, 1, store.reads)
+
+	rule.Enabled = false
+	_, err = service.UpdateRule(ctx, rule, "admin")
+	require.NoError(t, err)
+	assert.NoError(t, service.Check(ctx, "depends_on", raw(t, attrs("environment", "production")), raw(t, attrs("environment", "test"))))
+
+	_, err = service.UpdateRule(ctx, &Rule{ID: uuid.New(), Name: "missing", Attribute: "tenant", Constraint: ConstraintSameValue}, "admin")
+	assert.ErrorIs(t, err, ErrRuleNotFound)
+}
+
+func TestService_Scan(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, 0)
+	service.pageSize = 2
+	ctx := context.Background()
+
+	endpoint := func(env, tenant string) Endpoint {
+		return Endpoint{ID: uuid.New(), Name: env + "-" + tenant, Attributes: attrs("environment", env, "tenant", tenant)}
+	}
+	store.relationships = []Relationship{
+		{ID: uuid.New(), Type: "depends_on", Source: endpoint("production", "acme"), Target: endpoint("development", "acme")},
+		{ID: uuid.New(), Type: "depends_on", Source: endpoint("production", "acme"), Target: endpoint("production", "globex")},
+		{ID: uuid.New(), Type: "depends_on", Source: endpoint("production", "acme"), Target: endpoint("test", "globex")},
+		{ID: uuid.New(), Type: "runs_on", Source: endpoint("production", "acme"), Target: endpoint("development", "acme")},
+		{ID: uuid.New(), Type: "depends_on", Source: endpoint("development", "acme"), Target: endpoint("production", "acme")},
+	}
+
+	_, err := service.CreateRule(ctx, noProdOnDev(), "admin")
+	require.NoError(t, err)
+
+	report, err := service.Scan(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Scanned)
+	assert.Equal(t, 2, report.Violating)
+	assert.Equal(t, map[string]int{"no prod on dev": 2}, report.ByRule)
+	require.Len(t, report.Violations, 2)
+
+	// Rules under test are scanned without being stored
+	report, err = service.Scan(ctx, []*Rule{noProdOnDev(), {Name: "tenants", Attribute: "tenant", Constraint: ConstraintSameValue}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Violating)
+	assert.Equal(t, map[string]int{"no prod on dev": 2, "tenants": 2}, report.ByRule)
+	assert.Len(t, report.Violations, 4)
+
+	_, err = service.Scan(ctx, []*Rule{{Name: "invalid"}})
+	assert.ErrorIs(t, err, ErrInvalidRule)
+}
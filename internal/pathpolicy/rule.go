@@ -0,0 +1,195 @@
+// Package pathpolicy constrains relationships across environment or tenant
+// boundaries. Rules are checked when a relationship is created or made active
+// again, and the compliance scan reports the existing relationships breaking them.
+//
+// A forbid rule rejects relationships whose source and target attributes hold
+// the listed values, e.g.
+//
+//	type "depends_on", attribute "environment", source "production", target "development"
+//
+// keeps production CIs from depending on development ones. A same_value rule
+// requires both ends to hold the same value, e.g. attribute "tenant" keeps
+// relationships within a tenant. CIs without the attribute never break a rule.
+package pathpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rule constraints
+const (
+	ConstraintForbid    = "forbid"
+	ConstraintSameValue = "same_value"
+)
+
+var (
+	ErrInvalidRule  = errors.New("invalid relationship path rule")
+	ErrRuleNotFound = errors.New("relationship path rule not found")
+)
+
+// Rule constrains the relationships between CIs by the value of an attribute.
+// Attribute is a dot separated path into the CI attributes. Values compare
+// case-insensitively.
+type Rule struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	// RelationshipTypes restricts the rule to these types; empty means every type
+	RelationshipTypes []string `json:"relationship_types"`
+	Attribute         string   `json:"attribute"`
+	Constraint        string   `json:"constraint"`
+	// SourceValues and TargetValues are the forbidden pairs of a forbid rule
+	SourceValues []string  `json:"source_values"`
+	TargetValues []string  `json:"target_values"`
+	Enabled      bool      `json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	UpdatedBy    string    `json:"updated_by"`
+}
+
+// Validate checks the rule
+func (r *Rule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Attribute = strings.TrimSpace(r.Attribute)
+	r.RelationshipTypes = trimAll(r.RelationshipTypes)
+	r.SourceValues = trimAll(r.SourceValues)
+	r.TargetValues = trimAll(r.TargetValues)
+
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if r.Attribute == "" || strings.Contains(r.Attribute, "..") || strings.HasPrefix(r.Attribute, ".") || strings.HasSuffix(r.Attribute, ".") {
+		return fmt.Errorf("%w: invalid attribute path %q", ErrInvalidRule, r.Attribute)
+	}
+
+	switch r.Constraint {
+	case ConstraintForbid:
+		if len(r.SourceValues) == 0 || len(r.TargetValues) == 0 {
+			return fmt.Errorf("%w: forbid needs source and target values", ErrInvalidRule)
+		}
+	case ConstraintSameValue:
+		if len(r.SourceValues) > 0 || len(r.TargetValues) > 0 {
+			return fmt.Errorf("%w: same_value takes no source or target values", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: unknown constraint %q", ErrInvalidRule, r.Constraint)
+	}
+
+	return nil
+}
+
+// AppliesTo reports whether the rule constrains relationships of a type
+func (r *Rule) AppliesTo(relationshipType string) bool {
+	if len(r.RelationshipTypes) == 0 {
+		return true
+	}
+	for _, t := range r.RelationshipTypes {
+		if strings.EqualFold(t, relationshipType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate checks a relationship of the given type between two CIs, given
+// their attributes. It returns why the relationship breaks the rule, or "" when
+// it does not.
+func (r *Rule) Evaluate(relationshipType string, source, target map[string]interface{}) string {
+	if !r.AppliesTo(relationshipType) {
+		return ""
+	}
+	sourceValue, ok := attributeValue(source, r.Attribute)
+	if !ok {
+		return ""
+	}
+	targetValue, ok := attributeValue(target, r.Attribute)
+	if !ok {
+		return ""
+	}
+
+	switch r.Constraint {
+	case ConstraintForbid:
+		if contains(r.SourceValues, sourceValue) && contains(r.TargetValues, targetValue) {
+			return fmt.Sprintf("%s %s CIs may not be related to %s %s CIs", r.Attribute, sourceValue, r.Attribute, targetValue)
+		}
+	case ConstraintSameValue:
+		if !strings.EqualFold(sourceValue, targetValue) {
+			return fmt.Sprintf("relationships must stay within one %s, source is %s and target is %s", r.Attribute, sourceValue, targetValue)
+		}
+	}
+	return ""
+}
+
+// Violation is a relationship breaking a rule
+type Violation struct {
+	RuleID  uuid.UUID `json:"rule_id"`
+	Rule    string    `json:"rule"`
+	Message string    `json:"message"`
+}
+
+// ViolationError is returned when a relationship breaks one or more rules
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return "relationship breaks path rules: " + strings.Join(messages, "; ")
+}
+
+// attributeValue follows a dot separated path into CI attributes and renders
+// the scalar found as text
+func attributeValue(attributes map[string]interface{}, path string) (string, bool) {
+	var current interface{} = attributes
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if current, ok = object[key]; !ok {
+			return "", false
+		}
+	}
+
+	switch v := current.(type) {
+	case string:
+		if v = strings.TrimSpace(v); v != "" {
+			return v, true
+		}
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// contains reports whether values holds value, ignoring case
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimAll trims the values and drops the empty ones
+func trimAll(values []string) []string {
+	trimmed := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			trimmed = append(trimmed, v)
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,265 @@
+package pathpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Evaluation defaults
+const (
+	// DefaultCacheTTL bounds how long rules are reused before being reloaded, so
+	// changes made through another instance apply within that delay
+	DefaultCacheTTL = time.Minute
+	// DefaultPageSize is the number of relationships read at a time by scans
+	DefaultPageSize = 500
+	// MaxScanViolations bounds the violations listed by a scan
+	MaxScanViolations = 500
+)
+
+// RelationshipViolation is an existing relationship breaking a rule
+type RelationshipViolation struct {
+	Relationship
+	Violation
+}
+
+// ScanReport lists the existing relationships breaking the rules
+type ScanReport struct {
+	Scanned int `json:"scanned"`
+	// Violating counts relationships breaking at least one rule
+	Violating int `json:"violating"`
+	// ByRule counts violating relationships per rule name
+	ByRule map[string]int `json:"by_rule"`
+	// Violations lists the first violations, up to MaxScanViolations
+	Violations []RelationshipViolation `json:"violations"`
+	Truncated  bool                    `json:"truncated"`
+	ScannedAt  time.Time               `json:"scanned_at"`
+}
+
+// Service manages rules and checks relationships against them
+type Service struct {
+	store    Store
+	cacheTTL time.Duration
+	pageSize int
+	now      func() time.Time
+
+	mu       sync.Mutex
+	rules    []*Rule
+	loadedAt time.Time
+}
+
+// NewService creates a new relationship path rule service
+func NewService(store Store, cacheTTL time.Duration) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{
+		store:    store,
+		cacheTTL: cacheTTL,
+		pageSize: DefaultPageSize,
+		now:      time.Now,
+	}
+}
+
+// ListRules retrieves every rule
+func (s *Service) ListRules(ctx context.Context) ([]*Rule, error) {
+	return s.store.ListRules(ctx)
+}
+
+// GetRule retrieves a rule
+func (s *Service) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	return s.store.GetRule(ctx, id)
+}
+
+// CreateRule validates and stores a new rule. It applies to relationships
+// written from now on; run a scan to find the existing ones breaking it.
+func (s *Service) CreateRule(ctx context.Context, rule *Rule, by string) (*Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	rule.ID = uuid.New()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	rule.UpdatedBy = by
+	if err := s.store.CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Relationship path rule %s (%s) created by %s", rule.ID, rule.Name, by)
+	return rule, nil
+}
+
+// UpdateRule validates and replaces a rule
+func (s *Service) UpdateRule(ctx context.Context, rule *Rule, by string) (*Rule, error) {
+	existing, err := s.store.GetRule(ctx, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = s.now()
+	rule.UpdatedBy = by
+	if err := s.store.UpdateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Relationship path rule %s (%s) updated by %s", rule.ID, rule.Name, by)
+	return rule, nil
+}
+
+// DeleteRule removes a rule
+func (s *Service) DeleteRule(ctx context.Context, id uuid.UUID, by string) error {
+	if err := s.store.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+
+	s.invalidate()
+	log.Printf("Relationship path rule %s deleted by %s", id, by)
+	return nil
+}
+
+// Check evaluates the enabled rules against a relationship about to be
+// written, given the attributes of its CIs. It returns a *ViolationError when
+// the relationship breaks any of them.
+func (s *Service) Check(ctx context.Context, relationshipType string, source, target json.RawMessage) error {
+	rules, err := s.enabledRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	violations := Evaluate(rules, relationshipType, parseAttributes(source), parseAttributes(target))
+	if len(violations) > 0 {
+		return &ViolationError{Violations: violations}
+	}
+	return nil
+}
+
+// Scan reports the existing relationships breaking the given rules. Without
+// rules, the stored enabled rules are used.
+func (s *Service) Scan(ctx context.Context, rules []*Rule) (*ScanReport, error) {
+	if len(rules) == 0 {
+		var err error
+		if rules, err = s.enabledRules(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, rule := range rules {
+			if err := rule.Validate(); err != nil {
+				return nil, err
+			}
+			// A rule under test applies whether or not it is enabled yet
+			rule.Enabled = true
+		}
+	}
+
+	report := &ScanReport{ByRule: map[string]int{}, Violations: []RelationshipViolation{}}
+	after := uuid.Nil
+	for {
+		relationships, err := s.store.ListRelationships(ctx, after, s.pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rel := range relationships {
+			report.Scanned++
+			violations := Evaluate(rules, rel.Type, rel.Source.Attributes, rel.Target.Attributes)
+			if len(violations) == 0 {
+				continue
+			}
+
+			report.Violating++
+			for _, violation := range violations {
+				report.ByRule[violation.Rule]++
+				if len(report.Violations) < MaxScanViolations {
+					report.Violations = append(report.Violations, RelationshipViolation{Relationship: rel, Violation: violation})
+				} else {
+					report.Truncated = true
+				}
+			}
+		}
+
+		if len(relationships) < s.pageSize {
+			break
+		}
+		after = relationships[len(relationships)-1].ID
+	}
+
+	report.ScannedAt = s.now()
+	log.Printf("Relationship path scan: %d relationships scanned, %d violating", report.Scanned, report.Violating)
+	return report, nil
+}
+
+// Evaluate checks a relationship against the enabled rules and returns the
+// violations found
+func Evaluate(rules []*Rule, relationshipType string, source, target map[string]interface{}) []Violation {
+	var violations []Violation
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		if message := rule.Evaluate(relationshipType, source, target); message != "" {
+			violations = append(violations, Violation{RuleID: rule.ID, Rule: rule.Name, Message: message})
+		}
+	}
+	return violations
+}
+
+// parseAttributes decodes CI attributes; attributes that are not an object break no rule
+func parseAttributes(attributes json.RawMessage) map[string]interface{} {
+	var parsed map[string]interface{}
+	if len(attributes) > 0 {
+		_ = json.Unmarshal(attributes, &parsed)
+	}
+	return parsed
+}
+
+// enabledRules returns the enabled rules, reloading them once the cache expires
+func (s *Service) enabledRules(ctx context.Context) ([]*Rule, error) {
+	s.mu.Lock()
+	if s.rules != nil && s.now().Sub(s.loadedAt) < s.cacheTTL {
+		rules := s.rules
+		s.mu.Unlock()
+		return rules, nil
+	}
+	s.mu.Unlock()
+
+	stored, err := s.store.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*Rule, 0, len(stored))
+	for _, rule := range stored {
+		if !rule.Enabled {
+			continue
+		}
+		if err := rule.Validate(); err != nil {
+			log.Printf("Skipping invalid relationship path rule %s: %v", rule.ID, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+	return rules, nil
+}
+
+// invalidate drops the cached rules so the next check reloads them
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.rules = nil
+	s.mu.Unlock()
+}
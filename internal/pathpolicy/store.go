@@ -0,0 +1,182 @@
+package pathpolicy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Endpoint is a CI at one end of a relationship
+type Endpoint struct {
+	ID         uuid.UUID              `json:"id"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Attributes map[string]interface{} `json:"-"`
+}
+
+// Relationship is an existing relationship with both of its CIs
+type Relationship struct {
+	ID     uuid.UUID `json:"id"`
+	Type   string    `json:"type"`
+	State  string    `json:"state"`
+	Source Endpoint  `json:"source"`
+	Target Endpoint  `json:"target"`
+}
+
+// Store persists rules and gives the compliance scan access to the relationships
+type Store interface {
+	ListRules(ctx context.Context) ([]*Rule, error)
+	GetRule(ctx context.Context, id uuid.UUID) (*Rule, error)
+	CreateRule(ctx context.Context, rule *Rule) error
+	UpdateRule(ctx context.Context, rule *Rule) error
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	// ListRelationships returns up to limit proposed or active relationships
+	// between live CIs with IDs greater than after, ordered by ID
+	ListRelationships(ctx context.Context, after uuid.UUID, limit int) ([]Relationship, error)
+}
+
+// PostgresStore keeps rules in the relationship_path_rules table and reads ci_relationships
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed rule store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const ruleColumns = `id, name, COALESCE(description, ''), relationship_types, attribute, constraint_kind, source_values, target_values, enabled, created_at, updated_at, COALESCE(updated_by, '')`
+
+// scanRule reads a rule row selected with ruleColumns
+func scanRule(row interface{ Scan(...interface{}) error }) (*Rule, error) {
+	var rule Rule
+	var types, sources, targets pq.StringArray
+	if err := row.Scan(&rule.ID, &rule.Name, &rule.Description, &types, &rule.Attribute, &rule.Constraint,
+		&sources, &targets, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt, &rule.UpdatedBy); err != nil {
+		return nil, err
+	}
+	rule.RelationshipTypes = []string(types)
+	rule.SourceValues = []string(sources)
+	rule.TargetValues = []string(targets)
+	return &rule, nil
+}
+
+// ListRules retrieves every rule
+func (s *PostgresStore) ListRules(ctx context.Context) ([]*Rule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+ruleColumns+` FROM relationship_path_rules ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationship path rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []*Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan relationship path rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list relationship path rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRule retrieves a rule
+func (s *PostgresStore) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	rule, err := scanRule(s.db.QueryRowContext(ctx, `SELECT `+ruleColumns+` FROM relationship_path_rules WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get relationship path rule: %w", err)
+	}
+	return rule, nil
+}
+
+// CreateRule inserts a rule
+func (s *PostgresStore) CreateRule(ctx context.Context, rule *Rule) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO relationship_path_rules (id, name, description, relationship_types, attribute, constraint_kind,
+		                                     source_values, target_values, enabled, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		rule.ID, rule.Name, rule.Description, pq.Array(rule.RelationshipTypes), rule.Attribute, rule.Constraint,
+		pq.Array(rule.SourceValues), pq.Array(rule.TargetValues), rule.Enabled, rule.CreatedAt, rule.UpdatedAt, rule.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create relationship path rule: %w", err)
+	}
+	return nil
+}
+
+// UpdateRule replaces a rule
+func (s *PostgresStore) UpdateRule(ctx context.Context, rule *Rule) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE relationship_path_rules
+		SET name = $2, description = $3, relationship_types = $4, attribute = $5, constraint_kind = $6,
+		    source_values = $7, target_values = $8, enabled = $9, updated_at = $10, updated_by = $11
+		WHERE id = $1`,
+		rule.ID, rule.Name, rule.Description, pq.Array(rule.RelationshipTypes), rule.Attribute, rule.Constraint,
+		pq.Array(rule.SourceValues), pq.Array(rule.TargetValues), rule.Enabled, rule.UpdatedAt, rule.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update relationship path rule: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// DeleteRule removes a rule
+func (s *PostgresStore) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM relationship_path_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete relationship path rule: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// ListRelationships retrieves a page of relationships with the attributes of their CIs
+func (s *PostgresStore) ListRelationships(ctx context.Context, after uuid.UUID, limit int) ([]Relationship, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, r.type, r.state,
+		       src.id, src.name, src.type, COALESCE(src.attributes, '{}'::jsonb),
+		       tgt.id, tgt.name, tgt.type, COALESCE(tgt.attributes, '{}'::jsonb)
+		FROM ci_relationships r
+		JOIN configuration_items src ON src.id = r.source_ci_id AND src.is_deleted = false
+		JOIN configuration_items tgt ON tgt.id = r.target_ci_id AND tgt.is_deleted = false
+		WHERE r.id > $1 AND r.state IN ('proposed', 'active')
+		ORDER BY r.id
+		LIMIT $2`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var relationships []Relationship
+	for rows.Next() {
+		var rel Relationship
+		var sourceAttributes, targetAttributes []byte
+		if err := rows.Scan(&rel.ID, &rel.Type, &rel.State,
+			&rel.Source.ID, &rel.Source.Name, &rel.Source.Type, &sourceAttributes,
+			&rel.Target.ID, &rel.Target.Name, &rel.Target.Type, &targetAttributes); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship: %w", err)
+		}
+		// Attributes that are not an object break no rule
+		_ = json.Unmarshal(sourceAttributes, &rel.Source.Attributes)
+		_ = json.Unmarshal(targetAttributes, &rel.Target.Attributes)
+		relationships = append(relationships, rel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	return relationships, nil
+}
@@ -0,0 +1,131 @@
+package payloadlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"connect/internal/models"
+)
+
+// Redacted replaces sensitive values in logged payloads
+const Redacted = "[REDACTED]"
+
+// DefaultSensitiveKeys are redacted wherever they appear as part of a field name,
+// ignoring case, '-' and '_' (so "refresh_token" and "X-Api-Key" both match)
+var DefaultSensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "apikey", "authorization",
+	"cookie", "credential", "privatekey", "sessionid",
+}
+
+// sensitiveHeaders are never logged in clear text
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+	"X-Api-Key":     true,
+}
+
+// Redactor removes secrets and sensitive CI attributes from payloads
+type Redactor struct {
+	keys       []string
+	attributes map[string]bool
+}
+
+// NewRedactor creates a redactor matching DefaultSensitiveKeys and extraKeys
+func NewRedactor(extraKeys ...string) *Redactor {
+	keys := make([]string, 0, len(DefaultSensitiveKeys)+len(extraKeys))
+	for _, key := range append(append([]string{}, DefaultSensitiveKeys...), extraKeys...) {
+		if key = normalizeKey(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return &Redactor{keys: keys, attributes: map[string]bool{}}
+}
+
+// WithAttributes returns a copy of the redactor that also redacts fields named exactly
+// like one of the given attributes
+func (r *Redactor) WithAttributes(names []string) *Redactor {
+	attributes := make(map[string]bool, len(names))
+	for _, name := range names {
+		attributes[name] = true
+	}
+	return &Redactor{keys: r.keys, attributes: attributes}
+}
+
+// IsSensitive reports whether a field name must be redacted
+func (r *Redactor) IsSensitive(field string) bool {
+	if r.attributes[field] {
+		return true
+	}
+	normalized := normalizeKey(field)
+	for _, key := range r.keys {
+		if strings.Contains(normalized, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactJSON returns a redacted copy of a JSON document. ok is false when the
+// body is not valid JSON, in which case it must not be logged.
+func (r *Redactor) RedactJSON(body []byte) (redacted []byte, ok bool) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, false
+	}
+
+	redacted, err := json.Marshal(r.redactValue(value))
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+// RedactHeaders returns a flattened copy of headers with credentials removed
+func (r *Redactor) RedactHeaders(headers http.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] || r.IsSensitive(name) {
+			result[name] = Redacted
+			continue
+		}
+		result[name] = strings.Join(values, ", ")
+	}
+	return result
+}
+
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if r.IsSensitive(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = r.redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+	}
+	return value
+}
+
+// SensitiveAttributes returns the attributes marked {"sensitive": true} in their schema validation
+func SensitiveAttributes(schemas []*models.CITypeSchema) []string {
+	var names []string
+	for _, schema := range schemas {
+		for _, attr := range schema.Attributes {
+			if sensitive, _ := attr.Validation["sensitive"].(bool); sensitive {
+				names = append(names, attr.Name)
+			}
+		}
+	}
+	return names
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
@@ -0,0 +1,278 @@
+// Package payloadlog logs request and response bodies of selected routes for
+// incident debugging. Logging is switched on per route at runtime, expires on
+// its own, and every logged payload is redacted first: credentials, tokens and
+// CI attributes whose schema marks them sensitive never reach the logs.
+package payloadlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+// Defaults applied when the configuration leaves them unset
+const (
+	DefaultMaxBodyBytes      = 16 * 1024
+	DefaultDuration          = time.Hour
+	DefaultSchemaRefresh     = 5 * time.Minute
+	schemaPageSize           = 100
+	truncatedBodyPlaceholder = "[body exceeds logging limit]"
+)
+
+// SchemaSource lists CI type schemas so sensitive attributes can be redacted
+type SchemaSource interface {
+	ListCITypeSchemas(ctx context.Context, page, pageSize int) ([]*models.CITypeSchema, int64, error)
+}
+
+// RouteSetting is the logging state of a route
+type RouteSetting struct {
+	Route     string     `json:"route"` // "[METHOD ]/path/template"
+	EnabledAt time.Time  `json:"enabled_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Service decides which routes are logged and writes the redacted payloads
+type Service struct {
+	schemas      SchemaSource
+	logger       zerolog.Logger
+	redactor     *Redactor
+	maxBodyBytes int
+
+	mu                sync.RWMutex
+	routes            map[string]RouteSetting
+	sensitive         *Redactor
+	sensitiveLoadedAt time.Time
+	now               func() time.Time
+}
+
+// NewService creates a payload logging service. schemas may be nil, in which case
+// only the key based redaction applies.
+func NewService(schemas SchemaSource, logger zerolog.Logger, maxBodyBytes int, extraRedactKeys []string) *Service {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	redactor := NewRedactor(extraRedactKeys...)
+	return &Service{
+		schemas:      schemas,
+		logger:       logger,
+		redactor:     redactor,
+		maxBodyBytes: maxBodyBytes,
+		routes:       make(map[string]RouteSetting),
+		sensitive:    redactor,
+		now:          time.Now,
+	}
+}
+
+// Enable turns on logging for a route. A positive duration makes the setting expire;
+// zero keeps it until disabled.
+func (s *Service) Enable(ctx context.Context, route string, duration time.Duration) RouteSetting {
+	now := s.now()
+	setting := RouteSetting{Route: route, EnabledAt: now}
+	if duration > 0 {
+		expiresAt := now.Add(duration)
+		setting.ExpiresAt = &expiresAt
+	}
+
+	s.mu.Lock()
+	s.routes[route] = setting
+	s.mu.Unlock()
+
+	s.refreshSensitiveAttributes(ctx)
+	return setting
+}
+
+// Disable turns off logging for a route
+func (s *Service) Disable(route string) {
+	s.mu.Lock()
+	delete(s.routes, route)
+	s.mu.Unlock()
+}
+
+// Routes returns the routes currently being logged
+func (s *Service) Routes() []RouteSetting {
+	now := s.now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routes := make([]RouteSetting, 0, len(s.routes))
+	for _, setting := range s.routes {
+		if !setting.expired(now) {
+			routes = append(routes, setting)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Route < routes[j].Route })
+	return routes
+}
+
+// enabledFor returns the route key being logged for the matched mux route
+func (s *Service) enabledFor(r *http.Request) (string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range []string{strings.ToUpper(r.Method) + " " + template, template} {
+		if setting, ok := s.routes[key]; ok && !setting.expired(now) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Middleware logs redacted request and response bodies of enabled routes
+func (s *Service) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := s.enabledFor(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := s.now()
+		requestBody := s.captureRequestBody(r)
+		capture := &captureWriter{ResponseWriter: w, status: http.StatusOK, limit: s.maxBodyBytes}
+
+		next.ServeHTTP(capture, r)
+
+		if s.now().Sub(s.sensitiveLoaded()) > DefaultSchemaRefresh {
+			s.refreshSensitiveAttributes(r.Context())
+		}
+		redactor := s.currentRedactor()
+
+		event := s.logger.Info().
+			Str("route", route).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", capture.status).
+			Dur("duration", s.now().Sub(start)).
+			Interface("request_headers", redactor.RedactHeaders(r.Header))
+		event = s.addBody(event, redactor, "request_body", requestBody, int(r.ContentLength) > s.maxBodyBytes)
+		event = s.addBody(event, redactor, "response_body", capture.body.Bytes(), capture.truncated)
+		event.Msg("HTTP payload")
+	})
+}
+
+// captureRequestBody reads up to the logging limit of the request body and restores it for the handler
+func (s *Service) captureRequestBody(r *http.Request) []byte {
+	if r.Body == nil {
+		return nil
+	}
+
+	captured, _ := io.ReadAll(io.LimitReader(r.Body, int64(s.maxBodyBytes)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(captured), r.Body), r.Body}
+	return captured
+}
+
+// addBody adds a redacted body to the log event. Bodies that are truncated or not
+// JSON cannot be redacted reliably and are replaced by a placeholder.
+func (s *Service) addBody(event *zerolog.Event, redactor *Redactor, field string, body []byte, truncated bool) *zerolog.Event {
+	switch {
+	case len(body) == 0:
+		return event
+	case truncated || len(body) > s.maxBodyBytes:
+		return event.Str(field, truncatedBodyPlaceholder).Int(field+"_size", len(body))
+	}
+
+	redacted, ok := redactor.RedactJSON(body)
+	if !ok {
+		return event.Str(field, "[non-JSON body omitted]").Int(field+"_size", len(body))
+	}
+	return event.RawJSON(field, redacted)
+}
+
+// refreshSensitiveAttributes reloads the attribute names schemas mark as sensitive.
+// On failure the previous list is kept.
+func (s *Service) refreshSensitiveAttributes(ctx context.Context) {
+	if s.schemas == nil {
+		return
+	}
+
+	var all []*models.CITypeSchema
+	for page := 1; ; page++ {
+		schemas, total, err := s.schemas.ListCITypeSchemas(ctx, page, schemaPageSize)
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("Failed to load schemas for payload redaction, keeping previous sensitive attributes")
+			return
+		}
+		all = append(all, schemas...)
+		if len(schemas) == 0 || int64(len(all)) >= total {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.sensitive = s.redactor.WithAttributes(SensitiveAttributes(all))
+	s.sensitiveLoadedAt = s.now()
+	s.mu.Unlock()
+}
+
+func (s *Service) sensitiveLoaded() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sensitiveLoadedAt
+}
+
+func (s *Service) currentRedactor() *Redactor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sensitive
+}
+
+func (rs RouteSetting) expired(now time.Time) bool {
+	return rs.ExpiresAt != nil && !now.Before(*rs.ExpiresAt)
+}
+
+// captureWriter records the status and the first bytes of a response
+type captureWriter struct {
+	http.ResponseWriter
+	status    int
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if remaining := w.limit - w.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			w.body.Write(b[:remaining])
+			w.truncated = true
+		} else {
+			w.body.Write(b)
+		}
+	} else if len(b) > 0 {
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// lift the write deadline of event streams
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
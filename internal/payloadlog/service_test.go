@@ -0,0 +1,178 @@
+package payloadlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSchemas struct {
+	schemas []*models.CITypeSchema
+}
+
+func (f *fakeSchemas) ListCITypeSchemas(ctx context.Context, page, pageSize int) ([]*models.CITypeSchema, int64, error) {
+	if page > 1 {
+		return nil, int64(len(f.schemas)), nil
+	}
+	return f.schemas, int64(len(f.schemas)), nil
+}
+
+func newTestRouter(service *Service) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/cis/{id}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"1","echo":` + string(body) + `,"access_token":"abc"}`))
+	}).Methods("PUT")
+	router.Use(service.Middleware)
+	return router
+}
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestRedactor_RedactJSON(t *testing.T) {
+	redactor := NewRedactor("ssn").WithAttributes([]string{"serial_number"})
+
+	redacted, ok := redactor.RedactJSON([]byte(`{
+		"name": "db-01",
+		"password": "hunter2",
+		"Refresh-Token": "r",
+		"attributes": {"serial_number": "SN-1", "os": "linux", "employee_SSN": "123"},
+		"items": [{"api_key": "k"}]
+	}`))
+	require.True(t, ok)
+
+	var value map[string]interface{}
+	require.NoError(t, json.Unmarshal(redacted, &value))
+	assert.Equal(t, "db-01", value["name"])
+	assert.Equal(t, Redacted, value["password"])
+	assert.Equal(t, Redacted, value["Refresh-Token"])
+
+	attributes := value["attributes"].(map[string]interface{})
+	assert.Equal(t, Redacted, attributes["serial_number"])
+	assert.Equal(t, Redacted, attributes["employee_SSN"])
+	assert.Equal(t, "linux", attributes["os"])
+	assert.Equal(t, Redacted, value["items"].([]interface{})[0].(map[string]interface{})["api_key"])
+
+	_, ok = redactor.RedactJSON([]byte("password=hunter2"))
+	assert.False(t, ok)
+}
+
+func TestRedactor_RedactHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer abc")
+	headers.Set("X-Api-Key", "key")
+	headers.Set("Content-Type", "application/json")
+
+	redacted := NewRedactor().RedactHeaders(headers)
+	assert.Equal(t, Redacted, redacted["Authorization"])
+	assert.Equal(t, Redacted, redacted["X-Api-Key"])
+	assert.Equal(t, "application/json", redacted["Content-Type"])
+}
+
+func TestSensitiveAttributes(t *testing.T) {
+	schemas := []*models.CITypeSchema{{
+		Name: "server",
+		Attributes: []models.CITypeAttribute{
+			{Name: "serial_number", Validation: map[string]interface{}{"sensitive": true}},
+			{Name: "hostname"},
+		},
+	}}
+	assert.Equal(t, []string{"serial_number"}, SensitiveAttributes(schemas))
+}
+
+func TestService_LogsOnlyEnabledRoutes(t *testing.T) {
+	var buf bytes.Buffer
+	service := NewService(nil, zerolog.New(&buf), 0, nil)
+	router := newTestRouter(service)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/v1/cis/1", strings.NewReader(`{"name":"a"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Zero(t, buf.Len())
+
+	service.Enable(context.Background(), "PUT /api/v1/cis/{id}", time.Hour)
+
+	req := httptest.NewRequest("PUT", "/api/v1/cis/1", strings.NewReader(`{"name":"a","password":"hunter2"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	// The handler still sees the full body and the client the full response
+	assert.Contains(t, rec.Body.String(), `"password":"hunter2"`)
+
+	entry := decodeLogLine(t, &buf)
+	assert.Equal(t, "PUT /api/v1/cis/{id}", entry["route"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, Redacted, entry["request_body"].(map[string]interface{})["password"])
+	assert.Equal(t, Redacted, entry["response_body"].(map[string]interface{})["access_token"])
+	assert.Equal(t, Redacted, entry["request_headers"].(map[string]interface{})["Authorization"])
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "Bearer secret")
+}
+
+func TestService_RedactsSchemaSensitiveAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	schemas := &fakeSchemas{schemas: []*models.CITypeSchema{{
+		Name:       "server",
+		Attributes: []models.CITypeAttribute{{Name: "license_key", Validation: map[string]interface{}{"sensitive": true}}},
+	}}}
+	service := NewService(schemas, zerolog.New(&buf), 0, nil)
+	router := newTestRouter(service)
+	service.Enable(context.Background(), "/api/v1/cis/{id}", 0)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/v1/cis/1", strings.NewReader(`{"attributes":{"license_key":"L-1"}}`)))
+
+	assert.NotContains(t, buf.String(), "L-1")
+}
+
+func TestService_OmitsTruncatedBodies(t *testing.T) {
+	var buf bytes.Buffer
+	service := NewService(nil, zerolog.New(&buf), 16, nil)
+	router := newTestRouter(service)
+	service.Enable(context.Background(), "/api/v1/cis/{id}", 0)
+
+	body := `{"name":"a","password":"hunter2"}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/v1/cis/1", strings.NewReader(body)))
+
+	assert.Contains(t, rec.Body.String(), body)
+	entry := decodeLogLine(t, &buf)
+	assert.Equal(t, truncatedBodyPlaceholder, entry["request_body"])
+	assert.Equal(t, truncatedBodyPlaceholder, entry["response_body"])
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestService_SettingsExpire(t *testing.T) {
+	service := NewService(nil, zerolog.Nop(), 0, nil)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	service.Enable(context.Background(), "/api/v1/cis", 10*time.Minute)
+	require.Len(t, service.Routes(), 1)
+
+	now = now.Add(11 * time.Minute)
+	assert.Empty(t, service.Routes())
+
+	service.Enable(context.Background(), "/api/v1/cis", 0)
+	service.Disable("/api/v1/cis")
+	assert.Empty(t, service.Routes())
+}
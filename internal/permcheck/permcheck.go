@@ -0,0 +1,126 @@
+// Package permcheck answers many permission checks in one call, so clients
+// can decide which actions to offer without asking for each one.
+//
+// A check names a resource and an action, and optionally an object. The
+// permission "<resource>:<action>" allows it, as does "<resource>:manage".
+// When the object is a CI, the CI must also be visible to the caller: a
+// caller cannot act on a CI their ci:read grants hide.
+package permcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// MaxChecks bounds the number of checks in one request
+const MaxChecks = 200
+
+// ManageAction grants every action on its resource
+const ManageAction = "manage"
+
+// ciResource is the resource whose objects are checked against visibility
+const ciResource = "ci"
+
+// Reasons a check is denied
+const (
+	ReasonNotGranted    = "not_granted"
+	ReasonNotVisible    = "not_visible"
+	ReasonInvalidObject = "invalid_object"
+)
+
+var ErrInvalidChecks = errors.New("invalid permission checks")
+
+// PermissionSource returns the permissions granted by roles
+type PermissionSource interface {
+	Permissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// Check asks whether the caller may perform an action
+type Check struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Object   string `json:"object,omitempty"`
+}
+
+// Result is the answer to a check
+type Result struct {
+	Check
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Checker evaluates permission checks
+type Checker struct {
+	permissions PermissionSource
+	cis         visibility.CILookup
+}
+
+// NewChecker creates a new Checker
+func NewChecker(permissions PermissionSource, cis visibility.CILookup) *Checker {
+	return &Checker{permissions: permissions, cis: cis}
+}
+
+// Validate checks the shape of a batch of checks
+func Validate(checks []Check) error {
+	if len(checks) == 0 {
+		return fmt.Errorf("%w: at least one check is required", ErrInvalidChecks)
+	}
+	if len(checks) > MaxChecks {
+		return fmt.Errorf("%w: at most %d checks are allowed", ErrInvalidChecks, MaxChecks)
+	}
+	for i, check := range checks {
+		if check.Resource == "" || check.Action == "" {
+			return fmt.Errorf("%w: check %d needs a resource and an action", ErrInvalidChecks, i)
+		}
+	}
+	return nil
+}
+
+// Evaluate answers each check for a caller with the given roles, in order
+func (c *Checker) Evaluate(ctx context.Context, roles []string, checks []Check) ([]Result, error) {
+	if err := Validate(checks); err != nil {
+		return nil, err
+	}
+
+	permissions, err := c.permissions.Permissions(ctx, roles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve permissions: %w", err)
+	}
+	granted := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		granted[permission] = true
+	}
+
+	// One filter serves every check, so each CI is loaded at most once
+	filter := visibility.NewFilter(visibility.ScopeFromPermissions(permissions, visibility.TenantFromContext(ctx)), c.cis)
+
+	results := make([]Result, len(checks))
+	for i, check := range checks {
+		results[i] = Result{Check: check}
+		switch {
+		case !granted[check.Resource+":"+check.Action] && !granted[check.Resource+":"+ManageAction]:
+			results[i].Reason = ReasonNotGranted
+		case check.Resource == ciResource && check.Object != "":
+			results[i].Allowed, results[i].Reason = canSee(ctx, filter, check.Object)
+		default:
+			results[i].Allowed = true
+		}
+	}
+	return results, nil
+}
+
+// canSee checks that a CI object is visible
+func canSee(ctx context.Context, filter *visibility.Filter, object string) (bool, string) {
+	id, err := uuid.Parse(object)
+	if err != nil {
+		return false, ReasonInvalidObject
+	}
+	if !filter.CanSee(ctx, id) {
+		return false, ReasonNotVisible
+	}
+	return true, ""
+}
@@ -0,0 +1,102 @@
+package permcheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePermissions map[string][]string
+
+func (f fakePermissions) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		granted, ok := f[role]
+		if !ok {
+			return nil, errors.New("role not found")
+		}
+		permissions = append(permissions, granted...)
+	}
+	return permissions, nil
+}
+
+type fakeCIs struct {
+	cis   map[uuid.UUID]*models.CI
+	loads int
+}
+
+func (f *fakeCIs) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	f.loads++
+	if ci, ok := f.cis[id]; ok {
+		return ci, nil
+	}
+	return nil, errors.New("CI not found")
+}
+
+func TestEvaluate(t *testing.T) {
+	server := &models.CI{ID: uuid.New(), Type: "server"}
+	database := &models.CI{ID: uuid.New(), Type: "database"}
+	cis := &fakeCIs{cis: map[uuid.UUID]*models.CI{server.ID: server, database.ID: database}}
+	checker := NewChecker(fakePermissions{
+		"server_operator": {"ci:read:type=server", "ci:update"},
+		"user_admin":      {"user:manage"},
+	}, cis)
+
+	results, err := checker.Evaluate(context.Background(), []string{"server_operator", "user_admin"}, []Check{
+		{Resource: "ci", Action: "update", Object: server.ID.String()},
+		{Resource: "ci", Action: "update", Object: database.ID.String()},
+		{Resource: "ci", Action: "update", Object: server.ID.String()},
+		{Resource: "ci", Action: "update", Object: "not-a-uuid"},
+		{Resource: "ci", Action: "delete", Object: server.ID.String()},
+		{Resource: "ci", Action: "update"},
+		{Resource: "user", Action: "delete"},
+		{Resource: "role", Action: "read"},
+	})
+	require.NoError(t, err)
+
+	var allowed []bool
+	var reasons []string
+	for _, result := range results {
+		allowed = append(allowed, result.Allowed)
+		reasons = append(reasons, result.Reason)
+	}
+	assert.Equal(t, []bool{true, false, true, false, false, true, true, false}, allowed)
+	assert.Equal(t, []string{"", ReasonNotVisible, "", ReasonInvalidObject, ReasonNotGranted, "", "", ReasonNotGranted}, reasons)
+	assert.Equal(t, "delete", results[4].Action)
+	assert.Equal(t, 2, cis.loads, "each CI is loaded once")
+}
+
+func TestEvaluateRespectsTenant(t *testing.T) {
+	other := &models.CI{ID: uuid.New(), Type: "server", Attributes: []byte(`{"tenant_id":"globex"}`)}
+	cis := &fakeCIs{cis: map[uuid.UUID]*models.CI{other.ID: other}}
+	checker := NewChecker(fakePermissions{"viewer": {"ci:read"}}, cis)
+
+	ctx := visibility.WithTenant(context.Background(), "acme")
+	results, err := checker.Evaluate(ctx, []string{"viewer"}, []Check{{Resource: "ci", Action: "read", Object: other.ID.String()}})
+	require.NoError(t, err)
+	assert.False(t, results[0].Allowed)
+
+	results, err = checker.Evaluate(context.Background(), []string{"viewer"}, []Check{{Resource: "ci", Action: "read", Object: other.ID.String()}})
+	require.NoError(t, err)
+	assert.True(t, results[0].Allowed)
+}
+
+func TestEvaluateRejectsInvalidChecks(t *testing.T) {
+	checker := NewChecker(fakePermissions{"viewer": {"ci:read"}}, &fakeCIs{})
+
+	_, err := checker.Evaluate(context.Background(), []string{"viewer"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidChecks)
+	_, err = checker.Evaluate(context.Background(), []string{"viewer"}, []Check{{Resource: "ci"}})
+	assert.ErrorIs(t, err, ErrInvalidChecks)
+	_, err = checker.Evaluate(context.Background(), []string{"viewer"}, make([]Check, MaxChecks+1))
+	assert.ErrorIs(t, err, ErrInvalidChecks)
+
+	_, err = checker.Evaluate(context.Background(), []string{"missing"}, []Check{{Resource: "ci", Action: "read"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,546 @@
+// Package qrcode encodes short payloads, such as CI URLs and IDs printed on
+// asset labels, as QR codes (ISO/IEC 18004) and renders them as PNG or SVG.
+// Only byte mode and versions 1 to 10 are supported, which holds up to 271
+// bytes at the lowest error correction level.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level is the error correction level. Higher levels survive more label damage
+// at the cost of a denser code.
+type Level int
+
+// Error correction levels, recovering roughly 7%, 15%, 25% and 30% of the code
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+// MaxVersion is the largest supported QR version
+const MaxVersion = 10
+
+// ErrDataTooLong is returned when the payload does not fit in a supported version
+var ErrDataTooLong = errors.New("data too long for QR code")
+
+// ParseLevel parses an error correction level name (L, M, Q or H)
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L", "LOW":
+		return Low, nil
+	case "", "M", "MEDIUM":
+		return Medium, nil
+	case "Q", "QUARTILE":
+		return Quartile, nil
+	case "H", "HIGH":
+		return High, nil
+	}
+	return Medium, fmt.Errorf("invalid error correction level: %s", s)
+}
+
+// formatBits returns the level's two-bit value in the format information
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// blockLayout describes how a version and level split codewords into blocks
+type blockLayout struct {
+	ecPerBlock int
+	group1     int // blocks in group 1
+	group1Data int // data codewords per group 1 block
+	group2     int // blocks in group 2, each with one more data codeword
+}
+
+func (b blockLayout) dataCodewords() int {
+	return b.group1*b.group1Data + b.group2*(b.group1Data+1)
+}
+
+// layouts is indexed by version and level
+var layouts = [MaxVersion + 1][4]blockLayout{
+	1:  {{7, 1, 19, 0}, {10, 1, 16, 0}, {13, 1, 13, 0}, {17, 1, 9, 0}},
+	2:  {{10, 1, 34, 0}, {16, 1, 28, 0}, {22, 1, 22, 0}, {28, 1, 16, 0}},
+	3:  {{15, 1, 55, 0}, {26, 1, 44, 0}, {18, 2, 17, 0}, {22, 2, 13, 0}},
+	4:  {{20, 1, 80, 0}, {18, 2, 32, 0}, {26, 2, 24, 0}, {16, 4, 9, 0}},
+	5:  {{26, 1, 108, 0}, {24, 2, 43, 0}, {18, 2, 15, 2}, {22, 2, 11, 2}},
+	6:  {{18, 2, 68, 0}, {16, 4, 27, 0}, {24, 4, 19, 0}, {28, 4, 15, 0}},
+	7:  {{20, 2, 78, 0}, {18, 4, 31, 0}, {18, 2, 14, 4}, {26, 4, 13, 1}},
+	8:  {{24, 2, 97, 0}, {22, 2, 38, 2}, {22, 4, 18, 2}, {26, 4, 14, 2}},
+	9:  {{30, 2, 116, 0}, {22, 3, 36, 2}, {20, 4, 16, 4}, {24, 4, 12, 4}},
+	10: {{18, 2, 68, 2}, {26, 4, 43, 1}, {24, 6, 19, 2}, {28, 6, 15, 2}},
+}
+
+// alignmentPositions is indexed by version
+var alignmentPositions = [MaxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// Code is an encoded QR symbol
+type Code struct {
+	Version int
+	Level   Level
+	Size    int
+	Mask    int
+
+	modules    [][]bool // [y][x], true is dark
+	isFunction [][]bool
+}
+
+// Dark reports whether the module at column x, row y is dark. Coordinates
+// outside the symbol, such as the quiet zone, are light.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// Encode encodes data in byte mode using the smallest version that fits
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("invalid error correction level: %d", level)
+	}
+
+	version := 0
+	for v := 1; v <= MaxVersion; v++ {
+		if 4+charCountBits(v)+len(data)*8 <= layouts[v][level].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrDataTooLong
+	}
+
+	codewords := encodeData(data, version, level)
+	code := newCode(version, level)
+	code.drawCodewords(addErrorCorrection(codewords, layouts[version][level]))
+	code.applyBestMask()
+	return code, nil
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData builds the data codewords: mode, length, payload, terminator and padding
+func encodeData(data []byte, version int, level Level) []byte {
+	capacity := layouts[version][level].dataCodewords() * 8
+
+	var bits bitBuffer
+	bits.append(0x4, 4) // byte mode
+	bits.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	return bits.bytes()
+}
+
+// addErrorCorrection splits the data into blocks, appends Reed-Solomon codewords
+// to each and interleaves the result
+func addErrorCorrection(data []byte, layout blockLayout) []byte {
+	divisor := reedSolomonDivisor(layout.ecPerBlock)
+
+	var blocks, ecBlocks [][]byte
+	offset := 0
+	for i := 0; i < layout.group1+layout.group2; i++ {
+		n := layout.group1Data
+		if i >= layout.group1 {
+			n++
+		}
+		block := data[offset : offset+n]
+		offset += n
+		blocks = append(blocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+	}
+
+	result := make([]byte, 0, len(data)+layout.ecPerBlock*len(blocks))
+	for i := 0; i <= layout.group1Data; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, ec := range ecBlocks {
+			result = append(result, ec[i])
+		}
+	}
+	return result
+}
+
+// newCode creates a symbol with its function patterns drawn
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{Version: version, Level: level, Size: size}
+	c.modules = make([][]bool, size)
+	c.isFunction = make([][]bool, size)
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.isFunction[y] = make([]bool, size)
+	}
+
+	// Timing patterns
+	for i := 0; i < size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with their separators
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+
+	// Alignment patterns, except where they would overlap the finders
+	positions := alignmentPositions[version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format areas; the real bits are drawn once the mask is chosen
+	c.drawFormat(0)
+	c.drawVersion()
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information and the dark module
+func (c *Code) drawFormat(mask int) {
+	data := c.Level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	// Around the top left finder
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	// Split between the top right and bottom left finders
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion draws both copies of the version information, present from version 7
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag pattern, two columns at a
+// time from the bottom right, skipping function modules
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by the mask; applying it twice undoes it
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.isFunction[y][x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty score
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+
+	c.Mask = best
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// penalty scores the symbol by the four rules of the specification: long runs,
+// 2x2 blocks, finder-like patterns and dark/light imbalance
+func (c *Code) penalty() int {
+	penalty := 0
+	finderLike := []bool{true, false, true, true, true, false, true}
+
+	for _, line := range c.lines() {
+		run := 1
+		for i := 1; i <= len(line); i++ {
+			if i < len(line) && line[i] == line[i-1] {
+				run++
+				continue
+			}
+			if run >= 5 {
+				penalty += run - 2
+			}
+			run = 1
+		}
+
+		for i := 0; i+len(finderLike) <= len(line); i++ {
+			if !matches(line[i:], finderLike) {
+				continue
+			}
+			if lightRun(line, i-4, i) || lightRun(line, i+len(finderLike), i+len(finderLike)+4) {
+				penalty += 40
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				m := c.modules[y][x]
+				if m == c.modules[y][x+1] && m == c.modules[y+1][x] && m == c.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	total := c.Size * c.Size
+	deviation := abs(dark*100/total - 50)
+	penalty += deviation / 5 * 10
+
+	return penalty
+}
+
+// lines returns every row and column of the symbol
+func (c *Code) lines() [][]bool {
+	lines := make([][]bool, 0, 2*c.Size)
+	for y := 0; y < c.Size; y++ {
+		lines = append(lines, c.modules[y])
+	}
+	for x := 0; x < c.Size; x++ {
+		column := make([]bool, c.Size)
+		for y := 0; y < c.Size; y++ {
+			column[y] = c.modules[y][x]
+		}
+		lines = append(lines, column)
+	}
+	return lines
+}
+
+func matches(line, pattern []bool) bool {
+	for i, p := range pattern {
+		if line[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+// lightRun reports whether line[from:to] is light; modules beyond the edge count as light
+func lightRun(line []bool, from, to int) bool {
+	for i := from; i < to; i++ {
+		if i >= 0 && i < len(line) && line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// bitBuffer accumulates bits most significant first
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 != 0)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, (len(b)+7)/8)
+	for i, bit := range b {
+		if bit {
+			result[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree,
+// without its leading term, over GF(2^8) with the QR primitive polynomial
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
@@ -0,0 +1,198 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at version 1-M, from the worked example of the specification
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	ec := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	assert.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, ec)
+}
+
+func TestEncodeData(t *testing.T) {
+	codewords := encodeData([]byte("hi"), 1, High)
+	// Mode 0100, length 00000010, 'h' 01101000, 'i' 01101001, terminator, pad bytes
+	assert.Equal(t, []byte{0x40, 0x26, 0x86, 0x90, 0xEC, 0x11, 0xEC, 0x11, 0xEC}, codewords)
+}
+
+func TestLayouts(t *testing.T) {
+	// Every level of a version uses all of its codewords
+	total := map[int]int{1: 26, 2: 44, 3: 70, 4: 100, 5: 134, 6: 172, 7: 196, 8: 242, 9: 292, 10: 346}
+	for version := 1; version <= MaxVersion; version++ {
+		for level := Low; level <= High; level++ {
+			layout := layouts[version][level]
+			blocks := layout.group1 + layout.group2
+			assert.Equal(t, total[version], layout.dataCodewords()+blocks*layout.ecPerBlock, "version %d level %d", version, level)
+		}
+	}
+}
+
+func TestFormatAndVersionInformation(t *testing.T) {
+	code := newCode(7, Low)
+	code.drawFormat(4)
+
+	// Format information for level L, mask 4 is 110011000101111
+	var bits []byte
+	for y := 0; y <= 8; y++ {
+		if y != 6 {
+			bits = append(bits, module(code, 8, y))
+		}
+	}
+	for x := 7; x >= 0; x-- {
+		if x != 6 {
+			bits = append(bits, module(code, x, 8))
+		}
+	}
+	// The first copy reads bit 0 up to bit 14 along the column, then the row
+	assert.Equal(t, reverse("110011000101111"), string(bits))
+
+	// Version information for version 7 is 000111110010010100, stored bottom left
+	// in a 3x6 block read from the least significant bit
+	var version []byte
+	for i := 0; i < 18; i++ {
+		version = append(version, module(code, i/3, code.Size-11+i%3))
+	}
+	assert.Equal(t, reverse("000111110010010100"), string(version))
+}
+
+func TestEncode(t *testing.T) {
+	code, err := Encode([]byte("https://cmdb.example.com/cis/6f1c2a4e-8a36-4d47-9b1e-3f0c5d2b7a91"), Medium)
+	require.NoError(t, err)
+	assert.Equal(t, 5, code.Version)
+	assert.Equal(t, 37, code.Size)
+
+	// Finder pattern corners and the always dark module
+	assert.True(t, code.Dark(0, 0))
+	assert.True(t, code.Dark(code.Size-1, 0))
+	assert.True(t, code.Dark(0, code.Size-1))
+	assert.True(t, code.Dark(8, code.Size-8))
+	assert.False(t, code.Dark(-1, 0))
+
+	_, err = Encode(bytes.Repeat([]byte("x"), 300), Low)
+	assert.ErrorIs(t, err, ErrDataTooLong)
+}
+
+func TestRender(t *testing.T) {
+	code, err := Encode([]byte("6f1c2a4e-8a36-4d47-9b1e-3f0c5d2b7a91"), Quartile)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, code.WritePNG(&buf, 4))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, (code.Size+2*QuietZone)*4, img.Bounds().Dx())
+
+	buf.Reset()
+	require.NoError(t, code.WriteSVG(&buf, 200))
+	assert.True(t, strings.Contains(buf.String(), `width="200"`))
+	assert.True(t, strings.Contains(buf.String(), "M4,4h1v1h-1z"))
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	payloads := []string{"a", "6f1c2a4e-8a36-4d47-9b1e-3f0c5d2b7a91", strings.Repeat("https://cmdb.example.com/", 4)}
+	for _, payload := range payloads {
+		for level := Low; level <= High; level++ {
+			code, err := Encode([]byte(payload), level)
+			require.NoError(t, err)
+			assert.Equal(t, payload, decode(t, code), "version %d level %d", code.Version, level)
+		}
+	}
+}
+
+// decode reads a symbol back: format information, unmasking, de-interleaving,
+// error correction check and the byte mode segment
+func decode(t *testing.T, c *Code) string {
+	var format int
+	for i := 14; i >= 8; i-- {
+		format = format<<1 | int(module(c, 8, c.Size-15+i)-'0')
+	}
+	for i := 7; i >= 0; i-- {
+		format = format<<1 | int(module(c, c.Size-1-i, 8)-'0')
+	}
+	format ^= 0x5412
+	require.Equal(t, c.Level.formatBits(), format>>13)
+	mask := format >> 10 & 7
+
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+
+	var bits bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				if !c.isFunction[y][right-j] {
+					bits = append(bits, c.modules[y][right-j])
+				}
+			}
+		}
+	}
+	codewords := bits.bytes()
+
+	layout := layouts[c.Version][c.Level]
+	blocks := layout.group1 + layout.group2
+	data := make([][]byte, blocks)
+	i := 0
+	for n := 0; n <= layout.group1Data; n++ {
+		for b := 0; b < blocks; b++ {
+			if n < layout.group1Data || b >= layout.group1 {
+				data[b] = append(data[b], codewords[i])
+				i++
+			}
+		}
+	}
+	var payload []byte
+	for b := 0; b < blocks; b++ {
+		var ec []byte
+		for n := 0; n < layout.ecPerBlock; n++ {
+			ec = append(ec, codewords[i+n*blocks+b])
+		}
+		require.Equal(t, reedSolomonRemainder(data[b], reedSolomonDivisor(layout.ecPerBlock)), ec)
+		payload = append(payload, data[b]...)
+	}
+
+	// Byte mode header: 4 bit mode and the character count
+	var stream bitBuffer
+	for _, b := range payload {
+		stream.append(int(b), 8)
+	}
+	require.Equal(t, bitBuffer{false, true, false, false}, stream[:4])
+	countBits := charCountBits(c.Version)
+	length := 0
+	for _, bit := range stream[4 : 4+countBits] {
+		length <<= 1
+		if bit {
+			length |= 1
+		}
+	}
+	return string(stream[4+countBits:].bytes()[:length])
+}
+
+func module(c *Code, x, y int) byte {
+	if c.Dark(x, y) {
+		return '1'
+	}
+	return '0'
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
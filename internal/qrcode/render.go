@@ -0,0 +1,66 @@
+package qrcode
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// QuietZone is the light border, in modules, that scanners need around the symbol
+const QuietZone = 4
+
+// Image returns the symbol as a grayscale image, scale pixels per module,
+// including the quiet zone
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+
+	width := (c.Size + 2*QuietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for py := 0; py < width; py++ {
+		for px := 0; px < width; px++ {
+			shade := color.Gray{Y: 0xFF}
+			if c.Dark(px/scale-QuietZone, py/scale-QuietZone) {
+				shade = color.Gray{Y: 0x00}
+			}
+			img.SetGray(px, py, shade)
+		}
+	}
+	return img
+}
+
+// WritePNG writes the symbol as a PNG image, scale pixels per module
+func (c *Code) WritePNG(w io.Writer, scale int) error {
+	return png.Encode(w, c.Image(scale))
+}
+
+// WriteSVG writes the symbol as an SVG image measuring size user units, or one
+// unit per module when size is 0. Dark modules are drawn as a single path so
+// the output stays small and scales without blurring.
+func (c *Code) WriteSVG(w io.Writer, size int) error {
+	modules := c.Size + 2*QuietZone
+	if size <= 0 {
+		size = modules
+	}
+
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">
+<rect width="100%%" height="100%%" fill="#FFFFFF"/>
+<path d="%s" fill="#000000"/>
+</svg>
+`, size, size, modules, modules, path.String())
+	return err
+}
@@ -0,0 +1,114 @@
+// Package quarantine holds the assets discovery reports that cannot be matched
+// or validated against a CI type schema, instead of dropping them. Quarantined
+// items stay out of the CMDB until they are classified and approved, which
+// creates the CI, or rejected.
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Reasons an asset is quarantined
+const (
+	// ReasonNoSchema is an asset of a type without a schema
+	ReasonNoSchema = "no_schema"
+	// ReasonValidationFailed is an asset failing the schema of its type
+	ReasonValidationFailed = "validation_failed"
+)
+
+// Limits
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+	// MaxBulkItems bounds the items classified, approved or rejected at once
+	MaxBulkItems = 500
+)
+
+var (
+	ErrItemNotFound = errors.New("quarantined item not found")
+	ErrNotPending   = errors.New("quarantined item already reviewed")
+	ErrInvalidBulk  = errors.New("invalid bulk quarantine request")
+)
+
+// Item is a discovered asset held in quarantine. CI is the CI discovery
+// proposed, as corrected by classification.
+type Item struct {
+	ID     uuid.UUID `json:"id"`
+	Source string    `json:"source"`
+	Reason string    `json:"reason"`
+	// Errors are the schema validation errors of the CI as last checked
+	Errors       []string   `json:"errors"`
+	CI           models.CI  `json:"ci"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	ReviewedBy   *uuid.UUID `json:"reviewed_by,omitempty"`
+	RejectReason string     `json:"reject_reason,omitempty"`
+	// CreatedCIID is the CI created when the item was approved
+	CreatedCIID *uuid.UUID `json:"created_ci_id,omitempty"`
+}
+
+// Filter selects quarantined items; empty fields do not filter
+type Filter struct {
+	Status string `json:"status"`
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// Classification corrects the type and attributes of quarantined items, e.g.
+// to map a vendor's asset class onto an existing CI type. Attributes are
+// merged into the item's, a null value removing the attribute.
+type Classification struct {
+	Type       string          `json:"type"`
+	Attributes json.RawMessage `json:"attributes,omitempty"`
+}
+
+// BulkResult lists the items a bulk operation applied to and those it failed for
+type BulkResult struct {
+	Succeeded []uuid.UUID   `json:"succeeded"`
+	Failed    []BulkFailure `json:"failed"`
+}
+
+// BulkFailure is an item a bulk operation failed for, and why
+type BulkFailure struct {
+	ID    uuid.UUID `json:"id"`
+	Error string    `json:"error"`
+}
+
+// mergeAttributes merges a patch into a CI's attributes; null removes a key
+func mergeAttributes(attributes, patch json.RawMessage) (json.RawMessage, error) {
+	merged := map[string]interface{}{}
+	if len(attributes) > 0 {
+		if err := json.Unmarshal(attributes, &merged); err != nil {
+			return nil, err
+		}
+	}
+	var changes map[string]interface{}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return json.Marshal(merged)
+}
@@ -0,0 +1,198 @@
+package quarantine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps items in a map
+type memoryStore struct {
+	items map[uuid.UUID]*Item
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{items: map[uuid.UUID]*Item{}}
+}
+
+func (m *memoryStore) CreateItem(ctx context.Context, item *Item) error {
+	saved := *item
+	m.items[item.ID] = &saved
+	return nil
+}
+
+func (m *memoryStore) GetItem(ctx context.Context, id uuid.UUID) (*Item, error) {
+	item, ok := m.items[id]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	copied := *item
+	return &copied, nil
+}
+
+func (m *memoryStore) ListItems(ctx context.Context, filter Filter) ([]Item, int64, error) {
+	items := []Item{}
+	for _, item := range m.items {
+		if filter.Status == "" || item.Status == filter.Status {
+			items = append(items, *item)
+		}
+	}
+	return items, int64(len(items)), nil
+}
+
+func (m *memoryStore) UpdatePendingItem(ctx context.Context, item *Item) error {
+	if m.items[item.ID].Status != StatusPending {
+		return ErrNotPending
+	}
+	saved := *item
+	m.items[item.ID] = &saved
+	return nil
+}
+
+// memoryCIs knows a server schema requiring an hostname attribute
+type memoryCIs struct {
+	created []*models.CI
+}
+
+var serverSchema = &models.CITypeSchema{
+	Name:       "server",
+	Attributes: []models.CITypeAttribute{{Name: "hostname", Type: models.AttributeTypeString, Required: true}},
+}
+
+func (m *memoryCIs) GetCISchemaByType(ctx context.Context, ciType string) (*models.CITypeSchema, error) {
+	if ciType != serverSchema.Name {
+		return nil, errors.New("CI type schema not found")
+	}
+	return serverSchema, nil
+}
+
+func (m *memoryCIs) ValidateCIAgainstSchema(ctx context.Context, ci *models.CI, schema *models.CITypeSchema) (*models.ValidationResult, error) {
+	result := models.NewSchemaValidator().ValidateCIAgainstSchema(*ci, *schema)
+	return &result, nil
+}
+
+func (m *memoryCIs) CreateCIWithValidation(ctx context.Context, ci *models.CI, schema *models.CITypeSchema) (*models.CI, error) {
+	result, _ := m.ValidateCIAgainstSchema(ctx, ci, schema)
+	if !result.IsValid {
+		return nil, errors.New("CI validation failed")
+	}
+	m.created = append(m.created, ci)
+	return ci, nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store, cis CIs) *Service {
+	service := NewService(store, cis)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestScreen(t *testing.T) {
+	store := newMemoryStore()
+	service := newTestService(store, &memoryCIs{})
+	ctx := context.Background()
+
+	item, err := service.Screen(ctx, &models.CI{Name: "web-01", Type: "server", Attributes: json.RawMessage(`{"hostname": "web-01"}`)}, "aws-scanner")
+	require.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Empty(t, store.items)
+
+	item, err = service.Screen(ctx, &models.CI{Name: "blade-7", Type: "hpe_blade"}, "aws-scanner")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, ReasonNoSchema, item.Reason)
+	assert.Equal(t, StatusPending, item.Status)
+	assert.Equal(t, "aws-scanner", item.Source)
+
+	item, err = service.Screen(ctx, &models.CI{Name: "web-02", Type: "server", Attributes: json.RawMessage(`{}`)}, "aws-scanner")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, ReasonValidationFailed, item.Reason)
+	require.Len(t, item.Errors, 1)
+	assert.Contains(t, item.Errors[0], "hostname")
+	assert.Len(t, store.items, 2)
+}
+
+func TestClassifyThenApprove(t *testing.T) {
+	store := newMemoryStore()
+	cis := &memoryCIs{}
+	service := newTestService(store, cis)
+	ctx := context.Background()
+
+	item, err := service.Screen(ctx, &models.CI{Name: "blade-7", Type: "hpe_blade", Attributes: json.RawMessage(`{"vendor_class": "blade"}`)}, "aws-scanner")
+	require.NoError(t, err)
+
+	// Approving an item without a schema fails and leaves it pending
+	result, err := service.Approve(ctx, []uuid.UUID{item.ID}, uuid.New())
+	require.NoError(t, err)
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed[0].Error, "classify the item first")
+
+	result, err = service.Classify(ctx, []uuid.UUID{item.ID}, Classification{
+		Type:       "server",
+		Attributes: json.RawMessage(`{"hostname": "blade-7", "vendor_class": null}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{item.ID}, result.Succeeded)
+	classified := store.items[item.ID]
+	assert.Equal(t, "server", classified.CI.Type)
+	assert.JSONEq(t, `{"hostname": "blade-7"}`, string(classified.CI.Attributes))
+	assert.Empty(t, classified.Errors)
+
+	by := uuid.New()
+	result, err = service.Approve(ctx, []uuid.UUID{item.ID, item.ID}, by)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{item.ID}, result.Succeeded)
+	assert.Empty(t, result.Failed)
+	require.Len(t, cis.created, 1)
+	assert.Equal(t, by, cis.created[0].CreatedBy)
+
+	approved := store.items[item.ID]
+	assert.Equal(t, StatusApproved, approved.Status)
+	assert.Equal(t, cis.created[0].ID, *approved.CreatedCIID)
+
+	// A reviewed item cannot be reviewed again
+	result, err = service.Reject(ctx, []uuid.UUID{item.ID}, "duplicate", by)
+	require.NoError(t, err)
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, ErrNotPending.Error(), result.Failed[0].Error)
+}
+
+func TestReject(t *testing.T) {
+	store := newMemoryStore()
+	service := newTestService(store, &memoryCIs{})
+	ctx := context.Background()
+
+	item, err := service.Screen(ctx, &models.CI{Name: "printer", Type: "printer"}, "netscan")
+	require.NoError(t, err)
+	missing := uuid.New()
+
+	result, err := service.Reject(ctx, []uuid.UUID{item.ID, missing}, "not tracked in the CMDB", uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{item.ID}, result.Succeeded)
+	assert.Equal(t, []BulkFailure{{ID: missing, Error: ErrItemNotFound.Error()}}, result.Failed)
+	assert.Equal(t, StatusRejected, store.items[item.ID].Status)
+	assert.Equal(t, "not tracked in the CMDB", store.items[item.ID].RejectReason)
+}
+
+func TestBulkLimits(t *testing.T) {
+	service := newTestService(newMemoryStore(), &memoryCIs{})
+	ctx := context.Background()
+
+	_, err := service.Reject(ctx, nil, "", uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidBulk)
+
+	_, err = service.Reject(ctx, make([]uuid.UUID, MaxBulkItems+1), "", uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidBulk)
+
+	_, err = service.Classify(ctx, []uuid.UUID{uuid.New()}, Classification{})
+	assert.ErrorIs(t, err, ErrInvalidBulk)
+}
@@ -0,0 +1,213 @@
+package quarantine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// CIs looks up schemas, validates CIs and creates the approved ones, as
+// CIRepository does
+type CIs interface {
+	GetCISchemaByType(ctx context.Context, ciType string) (*models.CITypeSchema, error)
+	ValidateCIAgainstSchema(ctx context.Context, ci *models.CI, schema *models.CITypeSchema) (*models.ValidationResult, error)
+	CreateCIWithValidation(ctx context.Context, ci *models.CI, schema *models.CITypeSchema) (*models.CI, error)
+}
+
+// Service screens discovered CIs and reviews the quarantined ones
+type Service struct {
+	store Store
+	cis   CIs
+	now   func() time.Time
+}
+
+// NewService creates a new quarantine service
+func NewService(store Store, cis CIs) *Service {
+	return &Service{store: store, cis: cis, now: time.Now}
+}
+
+// assess checks a CI against the schema of its type and returns why it must be
+// quarantined, or "" if it matches
+func (s *Service) assess(ctx context.Context, ci *models.CI) (string, []string, error) {
+	schema, err := s.cis.GetCISchemaByType(ctx, ci.Type)
+	if err != nil {
+		return ReasonNoSchema, []string{fmt.Sprintf("no schema for CI type %q", ci.Type)}, nil
+	}
+	result, err := s.cis.ValidateCIAgainstSchema(ctx, ci, schema)
+	if err != nil {
+		return "", nil, err
+	}
+	if result.IsValid {
+		return "", nil, nil
+	}
+	errs := make([]string, 0, len(result.Errors))
+	for _, validationErr := range result.Errors {
+		errs = append(errs, fmt.Sprintf("%s: %s", validationErr.Field, validationErr.Message))
+	}
+	return ReasonValidationFailed, errs, nil
+}
+
+// Screen quarantines a CI reported by a discovery source when it cannot be
+// matched or validated against a schema, returning the item; a CI that
+// matches is left for the caller to create and nil is returned
+func (s *Service) Screen(ctx context.Context, ci *models.CI, source string) (*Item, error) {
+	reason, errs, err := s.assess(ctx, ci)
+	if err != nil || reason == "" {
+		return nil, err
+	}
+
+	now := s.now()
+	item := &Item{
+		ID:        uuid.New(),
+		Source:    source,
+		Reason:    reason,
+		Errors:    errs,
+		CI:        *ci,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.CreateItem(ctx, item); err != nil {
+		return nil, err
+	}
+	log.Printf("Quarantined CI %q of type %s from %s: %s", ci.Name, ci.Type, source, reason)
+	return item, nil
+}
+
+// List retrieves quarantined items, oldest first
+func (s *Service) List(ctx context.Context, filter Filter) ([]Item, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit > MaxListLimit {
+		filter.Limit = MaxListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.store.ListItems(ctx, filter)
+}
+
+// Get retrieves a quarantined item
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Item, error) {
+	return s.store.GetItem(ctx, id)
+}
+
+// Classify corrects the type and attributes of pending items and checks them
+// against the schema again, so the errors show what is left to fix
+func (s *Service) Classify(ctx context.Context, ids []uuid.UUID, classification Classification) (*BulkResult, error) {
+	if classification.Type == "" && len(classification.Attributes) == 0 {
+		return nil, fmt.Errorf("%w: a type or attributes are required", ErrInvalidBulk)
+	}
+	return s.bulk(ctx, ids, func(item *Item) error {
+		if classification.Type != "" {
+			item.CI.Type = classification.Type
+		}
+		if len(classification.Attributes) > 0 {
+			attributes, err := mergeAttributes(item.CI.Attributes, classification.Attributes)
+			if err != nil {
+				return fmt.Errorf("failed to merge attributes: %w", err)
+			}
+			item.CI.Attributes = attributes
+		}
+
+		reason, errs, err := s.assess(ctx, &item.CI)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			item.Reason = reason
+		}
+		item.Errors = errs
+		if item.Errors == nil {
+			item.Errors = []string{}
+		}
+		return nil
+	})
+}
+
+// Approve creates the CIs of pending items; an item still failing the schema
+// of its type is left pending with the failure reported
+func (s *Service) Approve(ctx context.Context, ids []uuid.UUID, by uuid.UUID) (*BulkResult, error) {
+	return s.bulk(ctx, ids, func(item *Item) error {
+		schema, err := s.cis.GetCISchemaByType(ctx, item.CI.Type)
+		if err != nil {
+			return fmt.Errorf("no schema for CI type %q: classify the item first", item.CI.Type)
+		}
+
+		ci := item.CI
+		ci.ID = uuid.New()
+		ci.CreatedBy = by
+		ci.UpdatedBy = by
+		created, err := s.cis.CreateCIWithValidation(ctx, &ci, schema)
+		if err != nil {
+			return err
+		}
+
+		reviewedAt := s.now()
+		item.Status = StatusApproved
+		item.ReviewedAt = &reviewedAt
+		item.ReviewedBy = &by
+		item.CreatedCIID = &created.ID
+		return nil
+	})
+}
+
+// Reject discards pending items
+func (s *Service) Reject(ctx context.Context, ids []uuid.UUID, reason string, by uuid.UUID) (*BulkResult, error) {
+	return s.bulk(ctx, ids, func(item *Item) error {
+		reviewedAt := s.now()
+		item.Status = StatusRejected
+		item.ReviewedAt = &reviewedAt
+		item.ReviewedBy = &by
+		item.RejectReason = reason
+		return nil
+	})
+}
+
+// bulk applies a change to each pending item and saves it. An item the change
+// fails for is reported and left as it was.
+func (s *Service) bulk(ctx context.Context, ids []uuid.UUID, apply func(item *Item) error) (*BulkResult, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: no items given", ErrInvalidBulk)
+	}
+	if len(ids) > MaxBulkItems {
+		return nil, fmt.Errorf("%w: at most %d items at once", ErrInvalidBulk, MaxBulkItems)
+	}
+
+	result := &BulkResult{Succeeded: []uuid.UUID{}, Failed: []BulkFailure{}}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if err := s.apply(ctx, id, apply); err != nil {
+			result.Failed = append(result.Failed, BulkFailure{ID: id, Error: err.Error()})
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, id)
+	}
+	return result, nil
+}
+
+// apply changes and saves one pending item
+func (s *Service) apply(ctx context.Context, id uuid.UUID, apply func(item *Item) error) error {
+	item, err := s.store.GetItem(ctx, id)
+	if err != nil {
+		return err
+	}
+	if item.Status != StatusPending {
+		return ErrNotPending
+	}
+	if err := apply(item); err != nil {
+		return err
+	}
+	item.UpdatedAt = s.now()
+	return s.store.UpdatePendingItem(ctx, item)
+}
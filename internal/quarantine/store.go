@@ -0,0 +1,181 @@
+package quarantine
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store persists quarantined items
+type Store interface {
+	CreateItem(ctx context.Context, item *Item) error
+	GetItem(ctx context.Context, id uuid.UUID) (*Item, error)
+	ListItems(ctx context.Context, filter Filter) ([]Item, int64, error)
+	// UpdatePendingItem saves an item, failing with ErrNotPending if it was
+	// reviewed in the meantime, so an item is only ever approved or rejected once
+	UpdatePendingItem(ctx context.Context, item *Item) error
+}
+
+// PostgresStore keeps items in the quarantined_cis table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed quarantine store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const itemColumns = `id, source, reason, errors, ci, ci_type, status, created_at, updated_at,
+	reviewed_at, reviewed_by, reject_reason, created_ci_id`
+
+// CreateItem inserts an item
+func (s *PostgresStore) CreateItem(ctx context.Context, item *Item) error {
+	errs, ci, err := marshalItem(item)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO quarantined_cis (id, source, reason, errors, ci, ci_type, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		item.ID, item.Source, item.Reason, errs, ci, item.CI.Type, item.Status, item.CreatedAt, item.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine CI: %w", err)
+	}
+	return nil
+}
+
+// GetItem retrieves an item
+func (s *PostgresStore) GetItem(ctx context.Context, id uuid.UUID) (*Item, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+itemColumns+` FROM quarantined_cis WHERE id = $1`, id)
+	item, err := scanItem(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrItemNotFound
+		}
+		return nil, fmt.Errorf("failed to get quarantined CI: %w", err)
+	}
+	return item, nil
+}
+
+// ListItems retrieves a page of items, oldest first, with the number matching the filter
+func (s *PostgresStore) ListItems(ctx context.Context, filter Filter) ([]Item, int64, error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	for _, f := range []struct {
+		column string
+		value  string
+	}{
+		{"status", filter.Status},
+		{"ci_type", filter.Type},
+		{"source", filter.Source},
+		{"reason", filter.Reason},
+	} {
+		if f.value == "" {
+			continue
+		}
+		args = append(args, f.value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", f.column, len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int64
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM quarantined_cis WHERE `+where, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count quarantined CIs: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM quarantined_cis WHERE %s ORDER BY created_at, id LIMIT $%d OFFSET $%d`,
+		itemColumns, where, len(args)+1, len(args)+2)
+	rows, err := s.db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list quarantined CIs: %w", err)
+	}
+	defer rows.Close()
+
+	items := []Item{}
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan quarantined CI: %w", err)
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list quarantined CIs: %w", err)
+	}
+	return items, total, nil
+}
+
+// UpdatePendingItem saves an item unless it was reviewed in the meantime
+func (s *PostgresStore) UpdatePendingItem(ctx context.Context, item *Item) error {
+	errs, ci, err := marshalItem(item)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE quarantined_cis
+		SET reason = $2, errors = $3, ci = $4, ci_type = $5, status = $6, updated_at = $7,
+		    reviewed_at = $8, reviewed_by = $9, reject_reason = $10, created_ci_id = $11
+		WHERE id = $1 AND status = 'pending'`,
+		item.ID, item.Reason, errs, ci, item.CI.Type, item.Status, item.UpdatedAt,
+		item.ReviewedAt, item.ReviewedBy, item.RejectReason, item.CreatedCIID)
+	if err != nil {
+		return fmt.Errorf("failed to update quarantined CI: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		if _, err := s.GetItem(ctx, item.ID); err != nil {
+			return err
+		}
+		return ErrNotPending
+	}
+	return nil
+}
+
+// marshalItem encodes the JSONB columns of an item
+func marshalItem(item *Item) ([]byte, []byte, error) {
+	errs, err := json.Marshal(item.Errors)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal validation errors: %w", err)
+	}
+	ci, err := json.Marshal(item.CI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal quarantined CI: %w", err)
+	}
+	return errs, ci, nil
+}
+
+// scanItem reads an item selected with itemColumns
+func scanItem(row interface{ Scan(...interface{}) error }) (*Item, error) {
+	var item Item
+	var errs, ci []byte
+	var ciType string
+	var reviewedAt sql.NullTime
+	var reviewedBy, createdCIID uuid.NullUUID
+	var rejectReason sql.NullString
+	if err := row.Scan(&item.ID, &item.Source, &item.Reason, &errs, &ci, &ciType, &item.Status,
+		&item.CreatedAt, &item.UpdatedAt, &reviewedAt, &reviewedBy, &rejectReason, &createdCIID); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(errs, &item.Errors); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal validation errors: %w", err)
+	}
+	if err := json.Unmarshal(ci, &item.CI); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal quarantined CI: %w", err)
+	}
+	if reviewedAt.Valid {
+		item.ReviewedAt = &reviewedAt.Time
+	}
+	if reviewedBy.Valid {
+		item.ReviewedBy = &reviewedBy.UUID
+	}
+	if createdCIID.Valid {
+		item.CreatedCIID = &createdCIID.UUID
+	}
+	item.RejectReason = rejectReason.String
+	return &item, nil
+}
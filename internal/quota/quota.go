@@ -0,0 +1,162 @@
+// Package quota limits the number of live CIs per tenant and per CI type, so a
+// runaway discovery connector cannot balloon the database unnoticed. Crossing
+// the warning threshold of a limit raises a notification; exceeding the limit
+// raises another and, in hard mode, rejects the create.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Scopes a limit applies to
+const (
+	ScopeTenant = "tenant"
+	ScopeType   = "type"
+)
+
+// Modes
+const (
+	// ModeSoft only notifies about exceeded limits
+	ModeSoft = "soft"
+	// ModeHard rejects creates over a limit
+	ModeHard = "hard"
+)
+
+// Usage levels
+const (
+	LevelOK       = "ok"
+	LevelWarning  = "warning"
+	LevelExceeded = "exceeded"
+)
+
+// Defaults
+const (
+	DefaultWarnPercent    = 80
+	DefaultCountTTL       = 30 * time.Second
+	DefaultNotifyInterval = time.Hour
+)
+
+// TenantAttribute is the CI attribute holding the tenant a CI belongs to, as
+// used by the sync exclusions
+const TenantAttribute = "tenant"
+
+// ErrQuotaExceeded is returned in hard mode for a create over a limit
+var ErrQuotaExceeded = errors.New("CI quota exceeded")
+
+// Limits configure the quotas. A zero limit means unlimited.
+type Limits struct {
+	Mode string `json:"mode"`
+	// WarnPercent is the share of a limit at which a warning is raised
+	WarnPercent int `json:"warn_percent"`
+	// DefaultTenant applies to every tenant without a limit of its own
+	DefaultTenant int64            `json:"default_tenant"`
+	Tenants       map[string]int64 `json:"tenants"`
+	Types         map[string]int64 `json:"types"`
+}
+
+// Validate checks the mode, warning threshold and limits
+func (l Limits) Validate() error {
+	if l.Mode != ModeSoft && l.Mode != ModeHard {
+		return fmt.Errorf("invalid quota mode %q: must be %s or %s", l.Mode, ModeSoft, ModeHard)
+	}
+	if l.WarnPercent < 1 || l.WarnPercent > 100 {
+		return fmt.Errorf("quota warn percent must be between 1 and 100")
+	}
+	if l.DefaultTenant < 0 {
+		return fmt.Errorf("default tenant quota cannot be negative")
+	}
+	for scope, limits := range map[string]map[string]int64{ScopeTenant: l.Tenants, ScopeType: l.Types} {
+		for value, limit := range limits {
+			if limit < 0 {
+				return fmt.Errorf("quota for %s %s cannot be negative", scope, value)
+			}
+		}
+	}
+	return nil
+}
+
+// limitFor returns the limit of a tenant or type, or 0 if it is unlimited
+func (l Limits) limitFor(scope, value string) int64 {
+	switch scope {
+	case ScopeTenant:
+		if limit, ok := l.Tenants[value]; ok {
+			return limit
+		}
+		return l.DefaultTenant
+	case ScopeType:
+		return l.Types[value]
+	}
+	return 0
+}
+
+// warnAt returns the count at which a limit raises a warning
+func (l Limits) warnAt(limit int64) int64 {
+	warnAt := limit * int64(l.WarnPercent) / 100
+	if warnAt < 1 {
+		warnAt = 1
+	}
+	return warnAt
+}
+
+// Usage is the number of live CIs of a tenant or type against its limit
+type Usage struct {
+	Scope   string  `json:"scope"`
+	Value   string  `json:"value"`
+	Count   int64   `json:"count"`
+	Limit   int64   `json:"limit"`
+	WarnAt  int64   `json:"warn_at"`
+	Percent float64 `json:"percent"`
+	Level   string  `json:"level"`
+}
+
+// newUsage computes the level of a count against its limit
+func (l Limits) newUsage(scope, value string, count, limit int64) Usage {
+	usage := Usage{Scope: scope, Value: value, Count: count, Limit: limit, WarnAt: l.warnAt(limit), Level: LevelOK}
+	usage.Percent = float64(count) / float64(limit) * 100
+	switch {
+	case count > limit:
+		usage.Level = LevelExceeded
+	case count >= usage.WarnAt:
+		usage.Level = LevelWarning
+	}
+	return usage
+}
+
+// Notification tells operators a tenant or type reached a warning threshold or
+// exceeded its limit
+type Notification struct {
+	Level string `json:"level"`
+	Mode  string `json:"mode"`
+	Usage Usage  `json:"usage"`
+}
+
+// Notifier delivers quota notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the log. It is used until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the notification
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	log.Printf("CI quota %s for %s %s: %d of %d CIs (%s mode)",
+		n.Level, n.Usage.Scope, n.Usage.Value, n.Usage.Count, n.Usage.Limit, n.Mode)
+	return nil
+}
+
+// CITenant returns the tenant of a CI from its attributes, or "" when it has none
+func CITenant(attributes json.RawMessage) string {
+	var values map[string]interface{}
+	if len(attributes) == 0 || json.Unmarshal(attributes, &values) != nil {
+		return ""
+	}
+	tenant, _ := values[TenantAttribute].(string)
+	return tenant
+}
@@ -0,0 +1,169 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps CI counts per scope and value
+type memoryStore struct {
+	counts map[string]map[string]int64
+	reads  int
+	err    error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{counts: map[string]map[string]int64{ScopeTenant: {}, ScopeType: {}}}
+}
+
+func (m *memoryStore) CountCIs(ctx context.Context, scope, value string) (int64, error) {
+	m.reads++
+	if m.err != nil {
+		return 0, m.err
+	}
+	return m.counts[scope][value], nil
+}
+
+func (m *memoryStore) CountAllCIs(ctx context.Context, scope string) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for value, count := range m.counts[scope] {
+		counts[value] = count
+	}
+	return counts, nil
+}
+
+// recordingNotifier keeps the notifications raised
+type recordingNotifier struct {
+	notifications []Notification
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, n Notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store, notifier Notifier, limits Limits) *Service {
+	service := NewService(store, notifier, limits, time.Minute, time.Hour)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestLimitsValidate(t *testing.T) {
+	limits := Limits{Mode: ModeSoft, WarnPercent: 80, Types: map[string]int64{"server": 100}}
+	assert.NoError(t, limits.Validate())
+
+	assert.Error(t, Limits{Mode: "strict", WarnPercent: 80}.Validate())
+	assert.Error(t, Limits{Mode: ModeHard, WarnPercent: 0}.Validate())
+	assert.Error(t, Limits{Mode: ModeHard, WarnPercent: 80, Tenants: map[string]int64{"acme": -1}}.Validate())
+}
+
+func TestTenantLimitFallsBackToDefault(t *testing.T) {
+	limits := Limits{DefaultTenant: 1000, Tenants: map[string]int64{"acme": 50000, "lab": 0}}
+	assert.Equal(t, int64(50000), limits.limitFor(ScopeTenant, "acme"))
+	assert.Equal(t, int64(1000), limits.limitFor(ScopeTenant, "globex"))
+	assert.Equal(t, int64(0), limits.limitFor(ScopeTenant, "lab"))
+	assert.Equal(t, int64(0), limits.limitFor(ScopeType, "server"))
+}
+
+func TestCheckWarnsOnceAtThreshold(t *testing.T) {
+	store := newMemoryStore()
+	store.counts[ScopeType]["server"] = 78
+	notifier := &recordingNotifier{}
+	service := newTestService(store, notifier, Limits{Mode: ModeHard, WarnPercent: 80, Types: map[string]int64{"server": 100}})
+
+	require.NoError(t, service.Check(context.Background(), "server", ""))
+	assert.Empty(t, notifier.notifications)
+
+	// The 80th CI reaches the threshold; later ones do not notify again
+	require.NoError(t, service.Check(context.Background(), "server", ""))
+	require.NoError(t, service.Check(context.Background(), "server", ""))
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, LevelWarning, notifier.notifications[0].Level)
+	assert.Equal(t, int64(80), notifier.notifications[0].Usage.Count)
+
+	// Counts come from the cache while it is fresh
+	assert.Equal(t, 1, store.reads)
+}
+
+func TestCheckHardModeRejects(t *testing.T) {
+	store := newMemoryStore()
+	store.counts[ScopeTenant]["acme"] = 10
+	notifier := &recordingNotifier{}
+	service := newTestService(store, notifier, Limits{Mode: ModeHard, WarnPercent: 80, DefaultTenant: 10})
+
+	err := service.Check(context.Background(), "server", "acme")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "tenant acme is limited to 10 CIs")
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, LevelExceeded, notifier.notifications[0].Level)
+
+	// A rejected create is not counted
+	assert.Equal(t, int64(10), service.counts[ScopeTenant+":acme"].count)
+
+	// Tenants and types without a limit are not checked
+	assert.NoError(t, service.Check(context.Background(), "server", ""))
+}
+
+func TestCheckSoftModeOnlyNotifies(t *testing.T) {
+	store := newMemoryStore()
+	store.counts[ScopeType]["server"] = 100
+	notifier := &recordingNotifier{}
+	service := newTestService(store, notifier, Limits{Mode: ModeSoft, WarnPercent: 80, Types: map[string]int64{"server": 100}})
+
+	require.NoError(t, service.Check(context.Background(), "server", ""))
+	require.NoError(t, service.Check(context.Background(), "server", ""))
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, LevelExceeded, notifier.notifications[0].Level)
+	assert.Equal(t, ModeSoft, notifier.notifications[0].Mode)
+
+	// The notification is repeated after the notify interval
+	service.now = func() time.Time { return now.Add(2 * time.Hour) }
+	require.NoError(t, service.Check(context.Background(), "server", ""))
+	assert.Len(t, notifier.notifications, 2)
+}
+
+func TestCheckAllowsWhenCountFails(t *testing.T) {
+	store := newMemoryStore()
+	store.err = errors.New("connection refused")
+	service := newTestService(store, &recordingNotifier{}, Limits{Mode: ModeHard, WarnPercent: 80, Types: map[string]int64{"server": 1}})
+
+	assert.NoError(t, service.Check(context.Background(), "server", ""))
+}
+
+func TestUsage(t *testing.T) {
+	store := newMemoryStore()
+	store.counts[ScopeType]["server"] = 90
+	store.counts[ScopeTenant]["acme"] = 20
+	store.counts[ScopeTenant]["globex"] = 5
+	service := newTestService(store, nil, Limits{
+		Mode:          ModeSoft,
+		WarnPercent:   80,
+		DefaultTenant: 10,
+		Tenants:       map[string]int64{"globex": 100, "initech": 100},
+		Types:         map[string]int64{"server": 100},
+	})
+
+	usages, err := service.Usage(context.Background())
+	require.NoError(t, err)
+	require.Len(t, usages, 4)
+	assert.Equal(t, Usage{Scope: ScopeTenant, Value: "acme", Count: 20, Limit: 10, WarnAt: 8, Percent: 200, Level: LevelExceeded}, usages[0])
+	assert.Equal(t, "globex", usages[1].Value)
+	assert.Equal(t, LevelOK, usages[1].Level)
+	assert.Equal(t, "initech", usages[2].Value)
+	assert.Equal(t, int64(0), usages[2].Count)
+	assert.Equal(t, Usage{Scope: ScopeType, Value: "server", Count: 90, Limit: 100, WarnAt: 80, Percent: 90, Level: LevelWarning}, usages[3])
+}
+
+func TestCITenant(t *testing.T) {
+	assert.Equal(t, "acme", CITenant(json.RawMessage(`{"tenant": "acme", "cpu": 4}`)))
+	assert.Equal(t, "", CITenant(json.RawMessage(`{"tenant": 7}`)))
+	assert.Equal(t, "", CITenant(nil))
+}
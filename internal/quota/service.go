@@ -0,0 +1,198 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cachedCount is a CI count read from the store, kept up to date with the
+// creates allowed since
+type cachedCount struct {
+	count     int64
+	fetchedAt time.Time
+}
+
+// Service checks creates against the limits and reports usage
+type Service struct {
+	store          Store
+	notifier       Notifier
+	limits         Limits
+	countTTL       time.Duration
+	notifyInterval time.Duration
+	now            func() time.Time
+
+	mu       sync.Mutex
+	counts   map[string]cachedCount
+	notified map[string]time.Time
+}
+
+// NewService creates a new quota service. Limits must have been validated.
+// Counts are cached for countTTL, so an import does not count the CIs of a
+// type for every row; notifications are repeated at most every notifyInterval.
+func NewService(store Store, notifier Notifier, limits Limits, countTTL, notifyInterval time.Duration) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	if countTTL <= 0 {
+		countTTL = DefaultCountTTL
+	}
+	if notifyInterval <= 0 {
+		notifyInterval = DefaultNotifyInterval
+	}
+	return &Service{
+		store:          store,
+		notifier:       notifier,
+		limits:         limits,
+		countTTL:       countTTL,
+		notifyInterval: notifyInterval,
+		now:            time.Now,
+		counts:         map[string]cachedCount{},
+		notified:       map[string]time.Time{},
+	}
+}
+
+// Limits returns the configured limits
+func (s *Service) Limits() Limits {
+	return s.limits
+}
+
+// Check checks creating a CI of a type and tenant against the limits; tenant
+// may be "" for a CI without one. It notifies about every limit the create
+// reaches the warning threshold of or exceeds, and in hard mode rejects it
+// with ErrQuotaExceeded. A count that cannot be read never blocks the create.
+func (s *Service) Check(ctx context.Context, ciType, tenant string) error {
+	var usages []Usage
+	for scope, value := range map[string]string{ScopeType: ciType, ScopeTenant: tenant} {
+		if value == "" {
+			continue
+		}
+		limit := s.limits.limitFor(scope, value)
+		if limit <= 0 {
+			continue
+		}
+		count, err := s.count(ctx, scope, value)
+		if err != nil {
+			log.Printf("Failed to check CI quota of %s %s: %v", scope, value, err)
+			continue
+		}
+		usages = append(usages, s.limits.newUsage(scope, value, count+1, limit))
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Scope < usages[j].Scope })
+
+	var rejected *Usage
+	for i, usage := range usages {
+		if usage.Level == LevelOK {
+			continue
+		}
+		s.notify(ctx, usage)
+		if usage.Level == LevelExceeded && s.limits.Mode == ModeHard && rejected == nil {
+			rejected = &usages[i]
+		}
+	}
+	if rejected != nil {
+		return fmt.Errorf("%w: %s %s is limited to %d CIs", ErrQuotaExceeded, rejected.Scope, rejected.Value, rejected.Limit)
+	}
+
+	s.mu.Lock()
+	for _, usage := range usages {
+		key := usage.Scope + ":" + usage.Value
+		if cached, ok := s.counts[key]; ok {
+			cached.count++
+			s.counts[key] = cached
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// count returns the live CIs of a tenant or type, from the cache while fresh
+func (s *Service) count(ctx context.Context, scope, value string) (int64, error) {
+	key := scope + ":" + value
+	s.mu.Lock()
+	cached, ok := s.counts[key]
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.fetchedAt) < s.countTTL {
+		return cached.count, nil
+	}
+
+	count, err := s.store.CountCIs(ctx, scope, value)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.counts[key] = cachedCount{count: count, fetchedAt: s.now()}
+	s.mu.Unlock()
+	return count, nil
+}
+
+// notify raises a notification unless one was raised for the same limit and
+// level within the notify interval
+func (s *Service) notify(ctx context.Context, usage Usage) {
+	key := usage.Scope + ":" + usage.Value + ":" + usage.Level
+	now := s.now()
+	s.mu.Lock()
+	if last, ok := s.notified[key]; ok && now.Sub(last) < s.notifyInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.notified[key] = now
+	s.mu.Unlock()
+
+	notification := Notification{Level: usage.Level, Mode: s.limits.Mode, Usage: usage}
+	if err := s.notifier.Notify(ctx, notification); err != nil {
+		log.Printf("Failed to send CI quota notification for %s %s: %v", usage.Scope, usage.Value, err)
+	}
+}
+
+// Usage reports every limited tenant and type against its limit, read from
+// the store. With a default tenant limit, every tenant with CIs is reported.
+func (s *Service) Usage(ctx context.Context) ([]Usage, error) {
+	usages := []Usage{}
+
+	for ciType, limit := range s.limits.Types {
+		if limit <= 0 {
+			continue
+		}
+		count, err := s.store.CountCIs(ctx, ScopeType, ciType)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, s.limits.newUsage(ScopeType, ciType, count, limit))
+	}
+
+	tenants := map[string]int64{}
+	if s.limits.DefaultTenant > 0 {
+		counts, err := s.store.CountAllCIs(ctx, ScopeTenant)
+		if err != nil {
+			return nil, err
+		}
+		tenants = counts
+	}
+	for tenant := range s.limits.Tenants {
+		if _, ok := tenants[tenant]; ok {
+			continue
+		}
+		count, err := s.store.CountCIs(ctx, ScopeTenant, tenant)
+		if err != nil {
+			return nil, err
+		}
+		tenants[tenant] = count
+	}
+	for tenant, count := range tenants {
+		if limit := s.limits.limitFor(ScopeTenant, tenant); limit > 0 {
+			usages = append(usages, s.limits.newUsage(ScopeTenant, tenant, count, limit))
+		}
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Scope != usages[j].Scope {
+			return usages[i].Scope < usages[j].Scope
+		}
+		return usages[i].Value < usages[j].Value
+	})
+	return usages, nil
+}
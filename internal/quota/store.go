@@ -0,0 +1,82 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Store counts the live CIs of tenants and types
+type Store interface {
+	// CountCIs counts the live CIs of a tenant or type
+	CountCIs(ctx context.Context, scope, value string) (int64, error)
+	// CountAllCIs counts the live CIs of every tenant or type that has any
+	CountAllCIs(ctx context.Context, scope string) (map[string]int64, error)
+}
+
+// PostgresStore counts the CIs in the configuration_items table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed quota store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// scopeColumn returns the expression a scope groups CIs by
+func scopeColumn(scope string) (string, error) {
+	switch scope {
+	case ScopeTenant:
+		return "attributes->>'" + TenantAttribute + "'", nil
+	case ScopeType:
+		return "type", nil
+	}
+	return "", fmt.Errorf("unknown quota scope %s", scope)
+}
+
+// CountCIs counts the live CIs of a tenant or type
+func (s *PostgresStore) CountCIs(ctx context.Context, scope, value string) (int64, error) {
+	column, err := scopeColumn(scope)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM configuration_items WHERE is_deleted = false AND %s = $1`, column)
+	if err := s.db.GetContext(ctx, &count, query, value); err != nil {
+		return 0, fmt.Errorf("failed to count CIs of %s %s: %w", scope, value, err)
+	}
+	return count, nil
+}
+
+// CountAllCIs counts the live CIs of every tenant or type
+func (s *PostgresStore) CountAllCIs(ctx context.Context, scope string) (map[string]int64, error) {
+	column, err := scopeColumn(scope)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*)
+		FROM configuration_items
+		WHERE is_deleted = false AND %[1]s IS NOT NULL
+		GROUP BY %[1]s`, column))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count CIs per %s: %w", scope, err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var value string
+		var count int64
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan CI count: %w", err)
+		}
+		counts[value] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count CIs per %s: %w", scope, err)
+	}
+	return counts, nil
+}
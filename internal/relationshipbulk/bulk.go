@@ -0,0 +1,173 @@
+// Package relationshipbulk creates and deletes many relationships in one
+// request, e.g. for discovery imports that would be far too slow creating
+// them one by one. The operations of a request are applied in one
+// transaction, so either all of them are or none is. Created relationships
+// are checked for cycles together, and the whole batch is synced to the
+// graph with a single sync event.
+package relationshipbulk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Operations
+const (
+	OpCreate = "create"
+	OpDelete = "delete"
+)
+
+// DefaultMaxOperations is how many operations a request may hold when the
+// service options do not say otherwise
+const DefaultMaxOperations = 1000
+
+// Bulk operation errors
+var (
+	ErrNoOperations       = errors.New("at least one operation is required")
+	ErrTooManyOperations  = errors.New("too many operations")
+	ErrInvalidOperation   = errors.New("invalid operation")
+	ErrDuplicateOperation = errors.New("operation repeats an earlier one")
+	ErrNotFound           = errors.New("relationship not found")
+	ErrCycle              = errors.New("the created relationships would create a cycle")
+)
+
+// OperationError rejects a request because of one of its operations
+type OperationError struct {
+	// Index is the position of the operation in the request
+	Index int
+	Err   error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError rejects a request whose created relationships would close a
+// cycle of relationships of the same type
+type CycleError struct {
+	// Indexes are the create operations of the relationships closing a cycle
+	Indexes []int
+}
+
+func (e *CycleError) Error() string {
+	indexes := make([]string, len(e.Indexes))
+	for i, index := range e.Indexes {
+		indexes[i] = fmt.Sprint(index)
+	}
+	return fmt.Sprintf("%v: operations %s", ErrCycle, strings.Join(indexes, ", "))
+}
+
+func (e *CycleError) Unwrap() error {
+	return ErrCycle
+}
+
+// Operation creates or deletes one relationship. Deletes only need the ID of
+// the relationship; creates need its CIs and type and are given a new ID.
+type Operation struct {
+	Op          string          `json:"op"`
+	ID          uuid.UUID       `json:"id,omitempty"`
+	SourceCIID  uuid.UUID       `json:"source_ci_id,omitempty"`
+	TargetCIID  uuid.UUID       `json:"target_ci_id,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Attributes  json.RawMessage `json:"attributes,omitempty"`
+	Description string          `json:"description,omitempty"`
+	// State is proposed or active; it defaults to the request's default state
+	State string `json:"state,omitempty"`
+}
+
+// Request holds the operations to apply together. Deletes are applied before
+// creates, so an import can replace edges in one request.
+type Request struct {
+	Operations []Operation `json:"operations"`
+	// DryRun validates the operations, including the cycle check, without
+	// applying them
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// Validate checks the operations and defaults the state of created
+// relationships to defaultState. A relationship may only be deleted once, and
+// the same edge only created once.
+func (r *Request) Validate(maxOperations int, defaultState string) error {
+	if len(r.Operations) == 0 {
+		return ErrNoOperations
+	}
+	if len(r.Operations) > maxOperations {
+		return fmt.Errorf("%w: at most %d are allowed, got %d", ErrTooManyOperations, maxOperations, len(r.Operations))
+	}
+
+	seen := map[string]int{}
+	for i := range r.Operations {
+		op := &r.Operations[i]
+		if err := op.validate(defaultState); err != nil {
+			return &OperationError{Index: i, Err: err}
+		}
+		key := op.key()
+		if first, ok := seen[key]; ok {
+			return &OperationError{Index: i, Err: fmt.Errorf("%w %d", ErrDuplicateOperation, first)}
+		}
+		seen[key] = i
+	}
+	return nil
+}
+
+// validate checks one operation and defaults its state
+func (o *Operation) validate(defaultState string) error {
+	switch o.Op {
+	case OpDelete:
+		if o.ID == uuid.Nil {
+			return fmt.Errorf("%w: id is required to delete a relationship", ErrInvalidOperation)
+		}
+	case OpCreate:
+		if o.ID != uuid.Nil {
+			return fmt.Errorf("%w: created relationships are given a new id", ErrInvalidOperation)
+		}
+		if o.SourceCIID == uuid.Nil || o.TargetCIID == uuid.Nil {
+			return fmt.Errorf("%w: source_ci_id and target_ci_id are required", ErrInvalidOperation)
+		}
+		if strings.TrimSpace(o.Type) == "" {
+			return fmt.Errorf("%w: type is required", ErrInvalidOperation)
+		}
+		if o.State == "" {
+			o.State = defaultState
+		}
+		if o.State != models.RelationshipStateProposed && o.State != models.RelationshipStateActive {
+			return fmt.Errorf("%w: new relationships must be proposed or active", ErrInvalidOperation)
+		}
+	default:
+		return fmt.Errorf("%w: op must be %s or %s", ErrInvalidOperation, OpCreate, OpDelete)
+	}
+	return nil
+}
+
+// key identifies what an operation changes, to find repeated operations
+func (o *Operation) key() string {
+	if o.Op == OpDelete {
+		return OpDelete + "/" + o.ID.String()
+	}
+	return OpCreate + "/" + o.SourceCIID.String() + "/" + o.Type + "/" + o.TargetCIID.String()
+}
+
+// Change is a relationship created or deleted by an operation
+type Change struct {
+	// Index is the position of the operation in the request
+	Index        int                    `json:"index"`
+	Relationship *models.CIRelationship `json:"relationship"`
+}
+
+// Result is a validated or applied request. ID identifies the sync event of
+// the batch.
+type Result struct {
+	ID      uuid.UUID `json:"id"`
+	DryRun  bool      `json:"dry_run"`
+	Created []Change  `json:"created"`
+	Deleted []Change  `json:"deleted"`
+}
@@ -0,0 +1,135 @@
+package relationshipbulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore records applied batches
+type memoryStore struct {
+	schemas  map[string]*models.RelationshipTypeSchema
+	applyErr error
+	applied  []*Result
+}
+
+func (m *memoryStore) GetRelationshipSchemaByType(ctx context.Context, relType string) (*models.RelationshipTypeSchema, error) {
+	if schema, ok := m.schemas[relType]; ok {
+		return schema, nil
+	}
+	return nil, errors.New("relationship type schema not found")
+}
+
+func (m *memoryStore) ApplyRelationshipBulk(ctx context.Context, result *Result, changedBy uuid.UUID) error {
+	if m.applyErr != nil {
+		return m.applyErr
+	}
+	m.applied = append(m.applied, result)
+	return nil
+}
+
+func create(source, target uuid.UUID, relType string) Operation {
+	return Operation{Op: OpCreate, SourceCIID: source, TargetCIID: target, Type: relType}
+}
+
+func TestRequestValidate(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+
+	assert.ErrorIs(t, (&Request{}).Validate(10, models.RelationshipStateActive), ErrNoOperations)
+	ops := []Operation{create(a, b, "RUNS_ON"), create(b, a, "RUNS_ON")}
+	assert.ErrorIs(t, (&Request{Operations: ops}).Validate(1, models.RelationshipStateActive), ErrTooManyOperations)
+
+	req := Request{Operations: []Operation{create(a, b, "RUNS_ON"), {Op: OpDelete, ID: uuid.New()}}}
+	require.NoError(t, req.Validate(10, models.RelationshipStateProposed))
+	assert.Equal(t, models.RelationshipStateProposed, req.Operations[0].State)
+
+	cases := map[string]Operation{
+		"unknown op":        {Op: "update", ID: uuid.New()},
+		"delete without id": {Op: OpDelete},
+		"create with id":    {Op: OpCreate, ID: uuid.New(), SourceCIID: a, TargetCIID: b, Type: "RUNS_ON"},
+		"create without ci": {Op: OpCreate, SourceCIID: a, Type: "RUNS_ON"},
+		"create no type":    {Op: OpCreate, SourceCIID: a, TargetCIID: b},
+		"deprecated":        {Op: OpCreate, SourceCIID: a, TargetCIID: b, Type: "RUNS_ON", State: models.RelationshipStateDeprecated},
+	}
+	for name, op := range cases {
+		req := Request{Operations: []Operation{create(a, b, "DEPENDS_ON"), op}}
+		err := req.Validate(10, models.RelationshipStateActive)
+		var opErr *OperationError
+		require.ErrorAs(t, err, &opErr, name)
+		assert.Equal(t, 1, opErr.Index, name)
+		assert.ErrorIs(t, err, ErrInvalidOperation, name)
+	}
+
+	id := uuid.New()
+	dup := Request{Operations: []Operation{{Op: OpDelete, ID: id}, create(a, b, "RUNS_ON"), {Op: OpDelete, ID: id}}}
+	err := dup.Validate(10, models.RelationshipStateActive)
+	assert.ErrorIs(t, err, ErrDuplicateOperation)
+	assert.Contains(t, err.Error(), "operation 2")
+	dup = Request{Operations: []Operation{create(a, b, "RUNS_ON"), create(a, b, "RUNS_ON")}}
+	assert.ErrorIs(t, dup.Validate(10, models.RelationshipStateActive), ErrDuplicateOperation)
+}
+
+func TestApply(t *testing.T) {
+	store := &memoryStore{schemas: map[string]*models.RelationshipTypeSchema{
+		"CONNECTS_TO": {Attributes: []models.CITypeAttribute{{Name: "protocol", Type: "string", Default: "tcp"}}},
+	}}
+	service := NewService(store, nil, Options{})
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	deleted, by := uuid.New(), uuid.New()
+
+	result, err := service.Apply(context.Background(), Request{Operations: []Operation{
+		create(a, b, "RUNS_ON"),
+		{Op: OpDelete, ID: deleted},
+		create(b, c, "CONNECTS_TO"),
+	}}, models.RelationshipStateActive, by)
+	require.NoError(t, err)
+	require.Len(t, store.applied, 1)
+
+	require.Len(t, result.Created, 2)
+	assert.Equal(t, 0, result.Created[0].Index)
+	assert.Equal(t, 2, result.Created[1].Index)
+	created := result.Created[1].Relationship
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.Equal(t, by, created.CreatedBy)
+	assert.Equal(t, models.RelationshipStateActive, created.State)
+	var attributes map[string]interface{}
+	require.NoError(t, json.Unmarshal(created.Attributes, &attributes))
+	assert.Equal(t, "tcp", attributes["protocol"])
+
+	require.Len(t, result.Deleted, 1)
+	assert.Equal(t, 1, result.Deleted[0].Index)
+	assert.Equal(t, deleted, result.Deleted[0].Relationship.ID)
+}
+
+func TestApplyRejectsBatch(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	ruleErr := errors.New("RUNS_ON may not point at a database")
+	store := &memoryStore{}
+	service := NewService(store, func(ctx context.Context, relationshipType string, sourceID, targetID uuid.UUID) error {
+		if targetID == b {
+			return ruleErr
+		}
+		return nil
+	}, Options{MaxOperations: 5})
+
+	_, err := service.Apply(context.Background(), Request{Operations: []Operation{
+		create(b, a, "RUNS_ON"),
+		create(a, b, "RUNS_ON"),
+	}}, models.RelationshipStateActive, uuid.New())
+	var opErr *OperationError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, 1, opErr.Index)
+	assert.ErrorIs(t, err, ruleErr)
+	assert.Empty(t, store.applied)
+
+	store.applyErr = &CycleError{Indexes: []int{0, 3}}
+	_, err = service.Apply(context.Background(), Request{Operations: []Operation{create(b, a, "RUNS_ON")}}, models.RelationshipStateActive, uuid.New())
+	assert.ErrorIs(t, err, ErrCycle)
+	assert.Contains(t, err.Error(), "operations 0, 3")
+}
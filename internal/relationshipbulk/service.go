@@ -0,0 +1,132 @@
+package relationshipbulk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Store applies batches of operations, as CIRepository does
+type Store interface {
+	// GetRelationshipSchemaByType returns the schema of a relationship type,
+	// or an error when the type has none
+	GetRelationshipSchemaByType(ctx context.Context, relType string) (*models.RelationshipTypeSchema, error)
+	// ApplyRelationshipBulk deletes then creates the relationships of the
+	// result in one transaction and records one sync event for them. It fills
+	// in the deleted relationships, returns an *OperationError wrapping
+	// ErrNotFound or a *models.RelationshipEndpointError for an operation that
+	// cannot be applied, and a *CycleError when the created relationships
+	// close a cycle. A dry run is rolled back.
+	ApplyRelationshipBulk(ctx context.Context, result *Result, changedBy uuid.UUID) error
+}
+
+// PathChecker checks a relationship against the path rules, as the path rule
+// service does for created relationships
+type PathChecker func(ctx context.Context, relationshipType string, sourceID, targetID uuid.UUID) error
+
+// Options configure the bulk service
+type Options struct {
+	// MaxOperations is how many operations a request may hold
+	MaxOperations int
+}
+
+// Service validates and applies bulk requests
+type Service struct {
+	store Store
+	paths PathChecker
+	opts  Options
+}
+
+// NewService creates a new bulk service; paths may be nil when no path rules
+// are enforced
+func NewService(store Store, paths PathChecker, opts Options) *Service {
+	if opts.MaxOperations <= 0 {
+		opts.MaxOperations = DefaultMaxOperations
+	}
+	return &Service{store: store, paths: paths, opts: opts}
+}
+
+// Apply validates the operations of a request and applies them together.
+// Created relationships without a state get defaultState. Relationships of a
+// type with a schema are validated against it and given its defaults, and
+// every created relationship is checked against the path rules.
+func (s *Service) Apply(ctx context.Context, req Request, defaultState string, changedBy uuid.UUID) (*Result, error) {
+	if err := req.Validate(s.opts.MaxOperations, defaultState); err != nil {
+		return nil, err
+	}
+
+	result := &Result{ID: uuid.New(), DryRun: req.DryRun, Created: []Change{}, Deleted: []Change{}}
+	schemas := map[string]*models.RelationshipTypeSchema{}
+	for i, op := range req.Operations {
+		if op.Op == OpDelete {
+			result.Deleted = append(result.Deleted, Change{Index: i, Relationship: &models.CIRelationship{ID: op.ID}})
+			continue
+		}
+
+		rel := &models.CIRelationship{
+			ID:          uuid.New(),
+			SourceCIID:  op.SourceCIID,
+			TargetCIID:  op.TargetCIID,
+			Type:        op.Type,
+			Attributes:  op.Attributes,
+			Description: op.Description,
+			State:       op.State,
+			IsActive:    true,
+			CreatedBy:   changedBy,
+			UpdatedBy:   changedBy,
+		}
+		schema, ok := schemas[op.Type]
+		if !ok {
+			// Types without a schema are created without validation
+			schema, _ = s.store.GetRelationshipSchemaByType(ctx, op.Type)
+			schemas[op.Type] = schema
+		}
+		if schema != nil {
+			if err := applySchema(rel, schema); err != nil {
+				return nil, &OperationError{Index: i, Err: err}
+			}
+		}
+		if s.paths != nil {
+			if err := s.paths(ctx, rel.Type, rel.SourceCIID, rel.TargetCIID); err != nil {
+				return nil, &OperationError{Index: i, Err: err}
+			}
+		}
+		result.Created = append(result.Created, Change{Index: i, Relationship: rel})
+	}
+
+	if err := s.store.ApplyRelationshipBulk(ctx, result, changedBy); err != nil {
+		return nil, err
+	}
+	if !result.DryRun {
+		log.Printf("Relationship batch %s applied: %d created, %d deleted", result.ID, len(result.Created), len(result.Deleted))
+	}
+	return result, nil
+}
+
+// applySchema validates a relationship against the schema of its type and
+// fills in the schema's attribute defaults
+func applySchema(rel *models.CIRelationship, schema *models.RelationshipTypeSchema) error {
+	validator := models.NewSchemaValidator()
+	validation := validator.ValidateRelationshipAgainstSchema(*rel, *schema)
+	if !validation.IsValid {
+		return fmt.Errorf("%w: relationship validation failed: %v", ErrInvalidOperation, validation.Errors)
+	}
+
+	attributes := map[string]interface{}{}
+	if len(rel.Attributes) > 0 {
+		if err := json.Unmarshal(rel.Attributes, &attributes); err != nil {
+			return fmt.Errorf("%w: invalid attributes: %v", ErrInvalidOperation, err)
+		}
+	}
+	attributes = validator.ApplyDefaults(attributes, models.CITypeSchema{Attributes: schema.Attributes})
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	rel.Attributes = encoded
+	return nil
+}
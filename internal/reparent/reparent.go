@@ -0,0 +1,278 @@
+// Package reparent moves the relationships of one CI to another in one
+// operation, e.g. when a hypervisor is replaced or duplicate applications are
+// consolidated. Relationships keep their IDs, attributes and version history.
+// The move is planned from the current relationships, checked against the
+// path rules and for cycles, and applied atomically with one sync event pair
+// per moved relationship.
+package reparent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Directions of the relationships moved, as seen from the CI they are moved from
+const (
+	DirectionBoth     = "both"
+	DirectionOutgoing = "outgoing"
+	DirectionIncoming = "incoming"
+)
+
+// Reasons a relationship is not moved
+const (
+	// SkipSelfReference is a relationship between the two CIs, which would
+	// point from the new CI to itself
+	SkipSelfReference = "self_reference"
+	// SkipDuplicate is a relationship the new CI already has
+	SkipDuplicate = "duplicate"
+)
+
+// Re-parenting errors
+var (
+	ErrMissingCI        = errors.New("from_ci_id and to_ci_id are required")
+	ErrSameCI           = errors.New("relationships cannot be moved to the CI they belong to")
+	ErrInvalidDirection = errors.New("direction must be both, outgoing or incoming")
+	ErrCycle            = errors.New("moving the relationships would create a cycle")
+	// ErrConcurrentChange is returned when a planned relationship changed
+	// before the move was applied; the request can be retried
+	ErrConcurrentChange = errors.New("relationships changed while they were being moved")
+)
+
+// CycleError rejects a move that would close a cycle of relationships of the
+// same type through the listed moved relationships
+type CycleError struct {
+	RelationshipIDs []uuid.UUID
+}
+
+func (e *CycleError) Error() string {
+	ids := make([]string, len(e.RelationshipIDs))
+	for i, id := range e.RelationshipIDs {
+		ids[i] = id.String()
+	}
+	return fmt.Sprintf("%v: relationships %s", ErrCycle, strings.Join(ids, ", "))
+}
+
+func (e *CycleError) Unwrap() error {
+	return ErrCycle
+}
+
+// Request asks to move the relationships of FromCIID to ToCIID. Types narrows
+// the move to relationships of those types; all are moved when empty.
+type Request struct {
+	FromCIID  uuid.UUID `json:"from_ci_id"`
+	ToCIID    uuid.UUID `json:"to_ci_id"`
+	Types     []string  `json:"types,omitempty"`
+	Direction string    `json:"direction,omitempty"`
+	// DryRun plans and validates the move without applying it
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// Validate checks the request and defaults its direction
+func (r *Request) Validate() error {
+	if r.FromCIID == uuid.Nil || r.ToCIID == uuid.Nil {
+		return ErrMissingCI
+	}
+	if r.FromCIID == r.ToCIID {
+		return ErrSameCI
+	}
+	switch r.Direction {
+	case "":
+		r.Direction = DirectionBoth
+	case DirectionBoth, DirectionOutgoing, DirectionIncoming:
+	default:
+		return ErrInvalidDirection
+	}
+	return nil
+}
+
+// Move is a relationship moved to the new CI
+type Move struct {
+	RelationshipID uuid.UUID `json:"relationship_id"`
+	Type           string    `json:"type"`
+	Direction      string    `json:"direction"`
+	OldSourceCIID  uuid.UUID `json:"old_source_ci_id"`
+	OldTargetCIID  uuid.UUID `json:"old_target_ci_id"`
+	SourceCIID     uuid.UUID `json:"source_ci_id"`
+	TargetCIID     uuid.UUID `json:"target_ci_id"`
+	// ClearPrimary is set when the relationship was primary but the new CI
+	// already has a primary relationship of its type
+	ClearPrimary bool `json:"clear_primary,omitempty"`
+	// UpdatedAt is when the relationship last changed as planned; it is not
+	// moved if it changed since
+	UpdatedAt time.Time `json:"-"`
+}
+
+// Skip is a relationship left on the old CI
+type Skip struct {
+	RelationshipID uuid.UUID `json:"relationship_id"`
+	Type           string    `json:"type"`
+	Direction      string    `json:"direction"`
+	Reason         string    `json:"reason"`
+}
+
+// Result is a planned or applied move. ID tags the sync events of the move.
+type Result struct {
+	ID       uuid.UUID `json:"id"`
+	FromCIID uuid.UUID `json:"from_ci_id"`
+	ToCIID   uuid.UUID `json:"to_ci_id"`
+	DryRun   bool      `json:"dry_run"`
+	Moved    []Move    `json:"moved"`
+	Skipped  []Skip    `json:"skipped"`
+}
+
+// Store reads relationships and applies moves, as CIRepository does
+type Store interface {
+	// CheckRelationshipEndpoints rejects CIs that are missing, deleted or
+	// inactive with a *models.RelationshipEndpointError
+	CheckRelationshipEndpoints(ctx context.Context, sourceID, targetID uuid.UUID) error
+	GetRelationshipsByCIAndState(ctx context.Context, ciID uuid.UUID, states []string) ([]*models.CIRelationship, error)
+	// ApplyReparent moves the planned relationships in one transaction,
+	// returning ErrConcurrentChange when one changed since it was planned and
+	// a *CycleError when the move closes a cycle. A dry run is rolled back.
+	ApplyReparent(ctx context.Context, result *Result, changedBy uuid.UUID) error
+}
+
+// PathChecker checks a relationship against the path rules, as the path rule
+// service does for created relationships
+type PathChecker func(ctx context.Context, relationshipType string, sourceID, targetID uuid.UUID) error
+
+// Service plans and applies moves
+type Service struct {
+	store Store
+	paths PathChecker
+}
+
+// NewService creates a new re-parenting service; paths may be nil when no path
+// rules are enforced
+func NewService(store Store, paths PathChecker) *Service {
+	return &Service{store: store, paths: paths}
+}
+
+// Reparent moves the relationships of a CI to another. The CI they are moved
+// to must be live and active; the one they are moved from may already be
+// deleted or inactive, as when it is being decommissioned.
+func (s *Service) Reparent(ctx context.Context, req Request, changedBy uuid.UUID) (*Result, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.store.CheckRelationshipEndpoints(ctx, req.ToCIID, req.ToCIID); err != nil {
+		return nil, err
+	}
+
+	from, err := s.store.GetRelationshipsByCIAndState(ctx, req.FromCIID, models.RelationshipStates)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.store.GetRelationshipsByCIAndState(ctx, req.ToCIID, models.RelationshipStates)
+	if err != nil {
+		return nil, err
+	}
+
+	result := Plan(req, from, to)
+	if s.paths != nil {
+		for _, move := range result.Moved {
+			if err := s.paths(ctx, move.Type, move.SourceCIID, move.TargetCIID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(result.Moved) == 0 {
+		return result, nil
+	}
+	if err := s.store.ApplyReparent(ctx, result, changedBy); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Plan decides which of the relationships of the old CI move to the new one,
+// given the relationships the new CI already has
+func Plan(req Request, from, to []*models.CIRelationship) *Result {
+	result := &Result{
+		ID:       uuid.New(),
+		FromCIID: req.FromCIID,
+		ToCIID:   req.ToCIID,
+		DryRun:   req.DryRun,
+		Moved:    []Move{},
+		Skipped:  []Skip{},
+	}
+
+	types := map[string]bool{}
+	for _, t := range req.Types {
+		types[t] = true
+	}
+
+	// What the new CI already has, by direction, type and other end, and the
+	// types it already has a primary relationship of
+	existing := map[string]bool{}
+	primaries := map[string]bool{}
+	for _, rel := range to {
+		if rel.SourceCIID == req.ToCIID {
+			existing[edgeKey(DirectionOutgoing, rel.Type, rel.TargetCIID)] = true
+			if rel.IsPrimary {
+				primaries[rel.Type] = true
+			}
+		}
+		if rel.TargetCIID == req.ToCIID {
+			existing[edgeKey(DirectionIncoming, rel.Type, rel.SourceCIID)] = true
+		}
+	}
+
+	sort.Slice(from, func(i, j int) bool { return from[i].ID.String() < from[j].ID.String() })
+	for _, rel := range from {
+		if len(types) > 0 && !types[rel.Type] {
+			continue
+		}
+		direction, other := DirectionOutgoing, rel.TargetCIID
+		if rel.SourceCIID != req.FromCIID {
+			direction, other = DirectionIncoming, rel.SourceCIID
+		}
+		if req.Direction != DirectionBoth && req.Direction != direction {
+			continue
+		}
+
+		skip := Skip{RelationshipID: rel.ID, Type: rel.Type, Direction: direction}
+		switch {
+		case other == req.ToCIID || other == req.FromCIID:
+			skip.Reason = SkipSelfReference
+		case existing[edgeKey(direction, rel.Type, other)]:
+			skip.Reason = SkipDuplicate
+		}
+		if skip.Reason != "" {
+			result.Skipped = append(result.Skipped, skip)
+			continue
+		}
+
+		move := Move{
+			RelationshipID: rel.ID,
+			Type:           rel.Type,
+			Direction:      direction,
+			OldSourceCIID:  rel.SourceCIID,
+			OldTargetCIID:  rel.TargetCIID,
+			SourceCIID:     rel.SourceCIID,
+			TargetCIID:     rel.TargetCIID,
+			UpdatedAt:      rel.UpdatedAt,
+		}
+		if direction == DirectionOutgoing {
+			move.SourceCIID = req.ToCIID
+			move.ClearPrimary = rel.IsPrimary && primaries[rel.Type]
+		} else {
+			move.TargetCIID = req.ToCIID
+		}
+		existing[edgeKey(direction, rel.Type, other)] = true
+		result.Moved = append(result.Moved, move)
+	}
+	return result
+}
+
+// edgeKey identifies a relationship of a CI by direction, type and other end
+func edgeKey(direction, relationshipType string, other uuid.UUID) string {
+	return direction + "/" + relationshipType + "/" + other.String()
+}
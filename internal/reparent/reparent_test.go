@@ -0,0 +1,185 @@
+package reparent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps relationships in memory and records applied moves
+type memoryStore struct {
+	relationships []*models.CIRelationship
+	endpointErr   error
+	applyErr      error
+	applied       []*Result
+}
+
+func (m *memoryStore) CheckRelationshipEndpoints(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	return m.endpointErr
+}
+
+func (m *memoryStore) GetRelationshipsByCIAndState(ctx context.Context, ciID uuid.UUID, states []string) ([]*models.CIRelationship, error) {
+	var found []*models.CIRelationship
+	for _, rel := range m.relationships {
+		if rel.SourceCIID == ciID || rel.TargetCIID == ciID {
+			found = append(found, rel)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryStore) ApplyReparent(ctx context.Context, result *Result, changedBy uuid.UUID) error {
+	if m.applyErr != nil {
+		return m.applyErr
+	}
+	m.applied = append(m.applied, result)
+	return nil
+}
+
+func (m *memoryStore) relate(source, target uuid.UUID, relType string, primary bool) *models.CIRelationship {
+	rel := &models.CIRelationship{ID: uuid.New(), SourceCIID: source, TargetCIID: target, Type: relType, IsPrimary: primary, State: models.RelationshipStateActive}
+	m.relationships = append(m.relationships, rel)
+	return rel
+}
+
+func TestRequestValidate(t *testing.T) {
+	from, to := uuid.New(), uuid.New()
+
+	req := Request{FromCIID: from, ToCIID: to}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, DirectionBoth, req.Direction)
+
+	assert.ErrorIs(t, (&Request{FromCIID: from}).Validate(), ErrMissingCI)
+	assert.ErrorIs(t, (&Request{FromCIID: from, ToCIID: from}).Validate(), ErrSameCI)
+	assert.ErrorIs(t, (&Request{FromCIID: from, ToCIID: to, Direction: "sideways"}).Validate(), ErrInvalidDirection)
+}
+
+func TestReparentMovesBothDirections(t *testing.T) {
+	oldHost, newHost := uuid.New(), uuid.New()
+	vm, storage := uuid.New(), uuid.New()
+	store := &memoryStore{}
+	runsOn := store.relate(vm, oldHost, "RUNS_ON", true)
+	dependsOn := store.relate(oldHost, storage, "DEPENDS_ON", false)
+	service := NewService(store, nil)
+
+	result, err := service.Reparent(context.Background(), Request{FromCIID: oldHost, ToCIID: newHost}, uuid.New())
+	require.NoError(t, err)
+	require.Len(t, result.Moved, 2)
+	assert.Empty(t, result.Skipped)
+	require.Len(t, store.applied, 1)
+
+	moves := map[uuid.UUID]Move{}
+	for _, move := range result.Moved {
+		moves[move.RelationshipID] = move
+	}
+	assert.Equal(t, DirectionIncoming, moves[runsOn.ID].Direction)
+	assert.Equal(t, vm, moves[runsOn.ID].SourceCIID)
+	assert.Equal(t, newHost, moves[runsOn.ID].TargetCIID)
+	assert.Equal(t, oldHost, moves[runsOn.ID].OldTargetCIID)
+	// Primary flags belong to the source CI, which did not change
+	assert.False(t, moves[runsOn.ID].ClearPrimary)
+
+	assert.Equal(t, DirectionOutgoing, moves[dependsOn.ID].Direction)
+	assert.Equal(t, newHost, moves[dependsOn.ID].SourceCIID)
+	assert.Equal(t, storage, moves[dependsOn.ID].TargetCIID)
+}
+
+func TestReparentSkipsSelfReferencesAndDuplicates(t *testing.T) {
+	oldApp, newApp, db, lb := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := &memoryStore{}
+	between := store.relate(oldApp, newApp, "REPLACED_BY", false)
+	duplicate := store.relate(oldApp, db, "DEPENDS_ON", false)
+	store.relate(newApp, db, "DEPENDS_ON", false)
+	moved := store.relate(lb, oldApp, "ROUTES_TO", false)
+	service := NewService(store, nil)
+
+	result, err := service.Reparent(context.Background(), Request{FromCIID: oldApp, ToCIID: newApp}, uuid.New())
+	require.NoError(t, err)
+	require.Len(t, result.Moved, 1)
+	assert.Equal(t, moved.ID, result.Moved[0].RelationshipID)
+
+	skipped := map[uuid.UUID]string{}
+	for _, skip := range result.Skipped {
+		skipped[skip.RelationshipID] = skip.Reason
+	}
+	assert.Equal(t, map[uuid.UUID]string{between.ID: SkipSelfReference, duplicate.ID: SkipDuplicate}, skipped)
+}
+
+func TestReparentClearsPrimaryTakenOnNewCI(t *testing.T) {
+	oldApp, newApp, oldDB, newDB := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := &memoryStore{}
+	primary := store.relate(oldApp, oldDB, "DEPENDS_ON", true)
+	store.relate(newApp, newDB, "DEPENDS_ON", true)
+	service := NewService(store, nil)
+
+	result, err := service.Reparent(context.Background(), Request{FromCIID: oldApp, ToCIID: newApp}, uuid.New())
+	require.NoError(t, err)
+	require.Len(t, result.Moved, 1)
+	assert.Equal(t, primary.ID, result.Moved[0].RelationshipID)
+	assert.True(t, result.Moved[0].ClearPrimary)
+}
+
+func TestReparentFilters(t *testing.T) {
+	oldHost, newHost, vm, storage := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := &memoryStore{}
+	runsOn := store.relate(vm, oldHost, "RUNS_ON", false)
+	store.relate(oldHost, storage, "DEPENDS_ON", false)
+	store.relate(oldHost, vm, "MANAGES", false)
+	service := NewService(store, nil)
+
+	result, err := service.Reparent(context.Background(), Request{FromCIID: oldHost, ToCIID: newHost, Direction: DirectionIncoming}, uuid.New())
+	require.NoError(t, err)
+	require.Len(t, result.Moved, 1)
+	assert.Equal(t, runsOn.ID, result.Moved[0].RelationshipID)
+
+	result, err = service.Reparent(context.Background(), Request{FromCIID: oldHost, ToCIID: newHost, Types: []string{"DEPENDS_ON", "MANAGES"}}, uuid.New())
+	require.NoError(t, err)
+	assert.Len(t, result.Moved, 2)
+}
+
+func TestReparentRejections(t *testing.T) {
+	oldHost, newHost, vm := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("inactive target CI", func(t *testing.T) {
+		store := &memoryStore{endpointErr: &models.RelationshipEndpointError{Endpoint: models.RelationshipEndpointSource, CIID: newHost, Err: models.ErrRelationshipEndpointInactive}}
+		store.relate(vm, oldHost, "RUNS_ON", false)
+		_, err := NewService(store, nil).Reparent(context.Background(), Request{FromCIID: oldHost, ToCIID: newHost}, uuid.New())
+		assert.ErrorIs(t, err, models.ErrRelationshipEndpointInactive)
+		assert.Empty(t, store.applied)
+	})
+
+	t.Run("path rules", func(t *testing.T) {
+		store := &memoryStore{}
+		store.relate(vm, oldHost, "RUNS_ON", false)
+		violation := errors.New("violates path rule")
+		paths := func(ctx context.Context, relationshipType string, sourceID, targetID uuid.UUID) error {
+			assert.Equal(t, newHost, targetID)
+			return violation
+		}
+		_, err := NewService(store, paths).Reparent(context.Background(), Request{FromCIID: oldHost, ToCIID: newHost}, uuid.New())
+		assert.ErrorIs(t, err, violation)
+		assert.Empty(t, store.applied)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		rel := uuid.New()
+		store := &memoryStore{applyErr: &CycleError{RelationshipIDs: []uuid.UUID{rel}}}
+		store.relate(vm, oldHost, "RUNS_ON", false)
+		_, err := NewService(store, nil).Reparent(context.Background(), Request{FromCIID: oldHost, ToCIID: newHost}, uuid.New())
+		assert.ErrorIs(t, err, ErrCycle)
+		assert.Contains(t, err.Error(), rel.String())
+	})
+
+	t.Run("nothing to move", func(t *testing.T) {
+		store := &memoryStore{}
+		result, err := NewService(store, nil).Reparent(context.Background(), Request{FromCIID: oldHost, ToCIID: newHost}, uuid.New())
+		require.NoError(t, err)
+		assert.Empty(t, result.Moved)
+		assert.Empty(t, store.applied)
+	})
+}
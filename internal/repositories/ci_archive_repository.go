@@ -0,0 +1,267 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/pagination"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// archivedOrgColumn is the organization of an archived CI or relationship,
+// kept in its archived record
+const archivedOrgColumn = "(a.data->>'org_id')::uuid"
+
+// archivedHistoryTables are the tables keyed by a CI whose rows are archived
+// with it; they would otherwise be lost to ON DELETE CASCADE
+var archivedHistoryTables = []string{"ci_attribute_provenance", "ci_costs", "ci_revisions", "ownership_transfer_items"}
+
+// ArchiveInactiveCIs moves up to limit CIs that are inactive or deleted and
+// unchanged since cutoff to the archive tables, together with their
+// relationships and history, and returns how many it archived
+func (r *CIRepository) ArchiveInactiveCIs(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids []string
+	err = tx.SelectContext(ctx, &ids, `
+		SELECT id::text FROM configuration_items
+		WHERE (is_active = false OR is_deleted = true) AND updated_at < $1
+		ORDER BY updated_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find inactive CIs: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO archived_ci_relationships (id, source_ci_id, target_ci_id, data, archived_at)
+		SELECT rel.id, rel.source_ci_id, rel.target_ci_id, to_jsonb(rel), $2
+		FROM ci_relationships rel
+		WHERE rel.source_ci_id = ANY($1::uuid[]) OR rel.target_ci_id = ANY($1::uuid[])
+		ON CONFLICT (id) DO NOTHING`, pq.Array(ids), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive relationships: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM ci_relationships
+		WHERE source_ci_id = ANY($1::uuid[]) OR target_ci_id = ANY($1::uuid[])`, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove archived relationships: %w", err)
+	}
+
+	history := make([]string, len(archivedHistoryTables))
+	for i, table := range archivedHistoryTables {
+		history[i] = fmt.Sprintf(`'%[1]s', COALESCE((SELECT jsonb_agg(to_jsonb(h)) FROM %[1]s h WHERE h.ci_id = ci.id), '[]'::jsonb)`, table)
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO archived_cis (id, name, type, data, history, last_activity_at, archived_at)
+		SELECT ci.id, ci.name, ci.type, to_jsonb(ci), jsonb_build_object(%s), ci.updated_at, $2
+		FROM configuration_items ci
+		WHERE ci.id = ANY($1::uuid[])`, strings.Join(history, ", ")), pq.Array(ids), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive CIs: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM configuration_items WHERE id = ANY($1::uuid[])`, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove archived CIs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit archive: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// ListArchivedCIs lists archived CIs, most recently archived first
+func (r *CIRepository) ListArchivedCIs(ctx context.Context, filter models.ArchivedCIFilter) (*models.ArchivedCIList, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	if orgID != nil {
+		args = append(args, *orgID)
+		conditions = append(conditions, orgCondition(archivedOrgColumn, len(args)))
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("a.type = $%d", len(args)))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		conditions = append(conditions, fmt.Sprintf("a.name ILIKE $%d", len(args)))
+	}
+	whereClause := strings.Join(conditions, " AND ")
+
+	var totalCount int64
+	if err := r.conn(ctx).GetContext(ctx, &totalCount, "SELECT COUNT(*) FROM archived_cis a WHERE "+whereClause, args...); err != nil {
+		return nil, fmt.Errorf("failed to count archived CIs: %w", err)
+	}
+
+	// Calculate pagination
+	page, pageSize := filter.Page, filter.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	pageSize = pagination.FromContext(ctx).PageSize(pageSize)
+	offset := (page - 1) * pageSize
+	totalPages := int((totalCount + int64(pageSize) - 1) / int64(pageSize))
+
+	args = append(args, pageSize, offset)
+	query := fmt.Sprintf(`
+		SELECT a.id, a.name, a.type, a.last_activity_at, a.archived_at,
+		       (SELECT COUNT(*) FROM archived_ci_relationships rel
+		        WHERE rel.source_ci_id = a.id OR rel.target_ci_id = a.id) AS relationship_count
+		FROM archived_cis a
+		WHERE %s
+		ORDER BY a.archived_at DESC, a.name
+		LIMIT $%d OFFSET $%d`, whereClause, len(args)-1, len(args))
+
+	cis := []models.ArchivedCI{}
+	if err := r.conn(ctx).SelectContext(ctx, &cis, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list archived CIs: %w", err)
+	}
+
+	return &models.ArchivedCIList{
+		CIs:        cis,
+		Total:      totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// GetArchivedCI retrieves an archived CI with its record as it was archived
+func (r *CIRepository) GetArchivedCI(ctx context.Context, id uuid.UUID) (*models.ArchivedCI, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, archivedOrgColumn, []interface{}{id})
+	query := `
+		SELECT a.id, a.name, a.type, a.last_activity_at, a.archived_at, a.data,
+		       (SELECT COUNT(*) FROM archived_ci_relationships rel
+		        WHERE rel.source_ci_id = a.id OR rel.target_ci_id = a.id) AS relationship_count
+		FROM archived_cis a
+		WHERE a.id = $1` + orgFilter
+
+	var ci models.ArchivedCI
+	if err := r.conn(ctx).GetContext(ctx, &ci, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrArchivedCINotFound
+		}
+		return nil, fmt.Errorf("failed to get archived CI: %w", err)
+	}
+	return &ci, nil
+}
+
+// RestoreArchivedCI moves an archived CI back to the live tables with its
+// history. Its relationships are restored when the CI at their other end is
+// live; the others stay archived until it is restored too. Restored
+// relationships lose their primary flag when another took it meanwhile.
+func (r *CIRepository) RestoreArchivedCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var archived struct {
+		Data    json.RawMessage `db:"data"`
+		History json.RawMessage `db:"history"`
+	}
+	orgFilter, args := andOrgCondition(orgID, archivedOrgColumn, []interface{}{id})
+	err = tx.GetContext(ctx, &archived, `SELECT data, history FROM archived_cis a WHERE id = $1`+orgFilter+` FOR UPDATE`, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrArchivedCINotFound
+		}
+		return nil, fmt.Errorf("failed to get archived CI: %w", err)
+	}
+
+	// The restored CI counts as changed now, so it is not archived again at
+	// once. CIs archived before they recorded an organization are restored
+	// into the one the request acts for.
+	var ci models.CI
+	err = tx.GetContext(ctx, &ci, `
+		INSERT INTO configuration_items
+		SELECT * FROM jsonb_populate_record(NULL::configuration_items, $1::jsonb || jsonb_build_object(
+			'updated_at', $2::timestamptz,
+			'org_id', COALESCE($1::jsonb->>'org_id', $3::text)))
+		RETURNING id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		          attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		          is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id`,
+		string(archived.Data), time.Now(), orgID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: %s", models.ErrArchiveRestoreConflict, pqErr.Detail)
+		}
+		return nil, fmt.Errorf("failed to restore CI: %w", err)
+	}
+
+	for _, table := range archivedHistoryTables {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %[1]s
+			SELECT * FROM jsonb_populate_recordset(NULL::%[1]s, $1::jsonb -> '%[1]s')
+			ON CONFLICT DO NOTHING`, table), string(archived.History))
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s of CI: %w", table, err)
+		}
+	}
+
+	// Relationships archived before they recorded an organization take the
+	// restored CI's
+	_, err = tx.ExecContext(ctx, `
+		WITH restorable AS (
+			DELETE FROM archived_ci_relationships a
+			WHERE (a.source_ci_id = $1 OR a.target_ci_id = $1)
+			  AND EXISTS (SELECT 1 FROM configuration_items ci WHERE ci.id = a.source_ci_id)
+			  AND EXISTS (SELECT 1 FROM configuration_items ci WHERE ci.id = a.target_ci_id)
+			RETURNING a.source_ci_id, a.data || jsonb_build_object('org_id', COALESCE(a.data->>'org_id', $2::text)) AS data
+		)
+		INSERT INTO ci_relationships
+		SELECT (jsonb_populate_record(NULL::ci_relationships, CASE
+			WHEN EXISTS (
+				SELECT 1 FROM ci_relationships p
+				WHERE p.source_ci_id = r.source_ci_id AND p.type = r.data->>'type' AND p.is_primary
+			) THEN r.data || '{"is_primary": false}'::jsonb
+			ELSE r.data
+		END)).*
+		FROM restorable r`, id, ci.OrgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore relationships of CI: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM archived_cis WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to remove restored CI from archive: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+
+	return &ci, nil
+}
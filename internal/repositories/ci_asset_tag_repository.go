@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+)
+
+// FindCIsByAssetTag retrieves the CIs carrying an asset tag. Tags are expected to
+// be unique, but duplicates from imports are returned rather than hidden.
+func (r *CIRepository) FindCIsByAssetTag(ctx context.Context, tag string) ([]*models.CI, error) {
+	query := fmt.Sprintf(`
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by
+		FROM configuration_items
+		WHERE attributes->>'%s' = $1 AND is_deleted = false
+		ORDER BY created_at
+		LIMIT 10`, models.AssetTagAttribute)
+
+	cis := []*models.CI{}
+	if err := r.conn(ctx).SelectContext(ctx, &cis, query, tag); err != nil {
+		return nil, fmt.Errorf("failed to find CIs by asset tag: %w", err)
+	}
+	return cis, nil
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"connect/internal/models"
+)
+
+// numericAttributePattern matches the text of attributes compared numerically,
+// whether they are stored as JSON numbers or as numeric strings
+const numericAttributePattern = `^-?[0-9]+(\.[0-9]+)?$`
+
+// attributeSQLOperators maps the numeric attribute filter operators to SQL
+var attributeSQLOperators = map[string]string{
+	models.AttributeOpGt:  ">",
+	models.AttributeOpGte: ">=",
+	models.AttributeOpLt:  "<",
+	models.AttributeOpLte: "<=",
+}
+
+// attributeFilterCondition returns the SQL form of an attribute filter for
+// configuration_items or ci_relationships, numbering its placeholders from
+// argCount. Equality and array membership are containment checks and every
+// other operator first checks the key exists, so the GIN indexes on
+// attributes narrow the rows before their values are compared.
+func attributeFilterCondition(filter models.AttributeFilter, argCount int) (string, []interface{}) {
+	switch filter.Operator {
+	case models.AttributeOpEq, models.AttributeOpNeq:
+		values := filter.ScalarValues()
+		conditions := make([]string, len(values))
+		args := make([]interface{}, len(values))
+		for i, value := range values {
+			conditions[i] = fmt.Sprintf("attributes @> $%d::jsonb", argCount+i)
+			args[i] = attributeDocument(filter.Key, value)
+		}
+		condition := "(" + strings.Join(conditions, " OR ") + ")"
+		if filter.Operator == models.AttributeOpNeq {
+			// Rows without the attribute, or without attributes, do not equal it either
+			condition = "NOT COALESCE(" + condition + ", false)"
+		}
+		return condition, args
+
+	case models.AttributeOpContains:
+		condition := fmt.Sprintf(
+			"(attributes @> $%d::jsonb OR (jsonb_typeof(attributes->$%d::text) = 'string' AND attributes->>$%d::text ILIKE $%d))",
+			argCount, argCount+1, argCount+1, argCount+2,
+		)
+		return condition, []interface{}{
+			attributeDocument(filter.Key, []string{filter.Value}),
+			filter.Key,
+			"%" + likeEscaper.Replace(filter.Value) + "%",
+		}
+
+	default:
+		// The value was checked to be a number when the filter was parsed
+		value, _ := strconv.ParseFloat(filter.Value, 64)
+		condition := fmt.Sprintf(
+			"(attributes ? $%[1]d::text AND CASE WHEN attributes->>$%[1]d::text ~ '%[2]s' THEN (attributes->>$%[1]d::text)::numeric %[3]s $%[4]d::numeric ELSE false END)",
+			argCount, numericAttributePattern, attributeSQLOperators[filter.Operator], argCount+1,
+		)
+		return condition, []interface{}{filter.Key, value}
+	}
+}
+
+// attributeDocument returns the JSON object {key: value} attributes are
+// checked to contain
+func attributeDocument(key string, value interface{}) string {
+	document, _ := json.Marshal(map[string]interface{}{key: value})
+	return string(document)
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeFilterCondition(t *testing.T) {
+	condition, args := attributeFilterCondition(models.AttributeFilter{Key: "cpu", Operator: models.AttributeOpEq, Value: "8"}, 3)
+	assert.Equal(t, "(attributes @> $3::jsonb OR attributes @> $4::jsonb)", condition)
+	assert.Equal(t, []interface{}{`{"cpu":"8"}`, `{"cpu":8}`}, args)
+
+	condition, args = attributeFilterCondition(models.AttributeFilter{Key: "os", Operator: models.AttributeOpNeq, Value: "linux"}, 1)
+	assert.Equal(t, "NOT COALESCE((attributes @> $1::jsonb), false)", condition)
+	assert.Equal(t, []interface{}{`{"os":"linux"}`}, args)
+
+	condition, args = attributeFilterCondition(models.AttributeFilter{Key: "os", Operator: models.AttributeOpContains, Value: "50%_off"}, 2)
+	assert.Equal(t, "(attributes @> $2::jsonb OR (jsonb_typeof(attributes->$3::text) = 'string' AND attributes->>$3::text ILIKE $4))", condition)
+	assert.Equal(t, []interface{}{`{"os":["50%_off"]}`, "os", `%50\%\_off%`}, args)
+
+	condition, args = attributeFilterCondition(models.AttributeFilter{Key: "cpu", Operator: models.AttributeOpGte, Value: "2.5"}, 5)
+	assert.Equal(t, "(attributes ? $5::text AND CASE WHEN attributes->>$5::text ~ '"+numericAttributePattern+"' "+
+		"THEN (attributes->>$5::text)::numeric >= $6::numeric ELSE false END)", condition)
+	assert.Equal(t, []interface{}{"cpu", 2.5}, args)
+}
+
+func TestCIRepository_ListCIsByAttributes(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{Name: "web-01", Type: "server", Attributes: map[string]interface{}{"os": "linux", "cpu": 8, "ports": []string{"http", "https"}}},
+			{Name: "web-02", Type: "server", Attributes: map[string]interface{}{"os": "Ubuntu Linux", "cpu": "16"}},
+			{Name: "db-01", Type: "database", Attributes: map[string]interface{}{"os": "windows", "cpu": 4, "ha": true}},
+			{Name: "rack-01", Type: "rack"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+	repo := NewCIRepository(db)
+
+	filter := func(param, value string) models.AttributeFilter {
+		f, err := models.ParseAttributeFilter(param, value)
+		require.NoError(t, err)
+		return f
+	}
+	tests := []struct {
+		name     string
+		filters  []models.AttributeFilter
+		expected []string
+	}{
+		{"string equality", []models.AttributeFilter{filter("attr.os", "linux")}, []string{"web-01"}},
+		{"number equality", []models.AttributeFilter{filter("attr.cpu", "8")}, []string{"web-01"}},
+		{"numeric string equality", []models.AttributeFilter{filter("attr.cpu", "16")}, []string{"web-02"}},
+		{"boolean equality", []models.AttributeFilter{filter("attr.ha", "true")}, []string{"db-01"}},
+		{"inequality", []models.AttributeFilter{filter("attr.os.neq", "linux")}, []string{"db-01", "rack-01", "web-02"}},
+		{"numeric comparison", []models.AttributeFilter{filter("attr.cpu.gte", "8")}, []string{"web-01", "web-02"}},
+		{"numeric upper bound", []models.AttributeFilter{filter("attr.cpu.lt", "8")}, []string{"db-01"}},
+		{"substring", []models.AttributeFilter{filter("attr.os.contains", "LINUX")}, []string{"web-01", "web-02"}},
+		{"array element", []models.AttributeFilter{filter("attr.ports.contains", "https")}, []string{"web-01"}},
+		{"wildcards are literal", []models.AttributeFilter{filter("attr.os.contains", "%")}, nil},
+		{"every filter must match", []models.AttributeFilter{filter("attr.os.contains", "linux"), filter("attr.cpu.gt", "10")}, []string{"web-02"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.ListCIs(ctx, &models.ListCIsRequest{
+				Page: 1, PageSize: 100, SortBy: "name", SortOrder: models.SortOrderAsc, Attributes: tt.filters,
+			})
+			require.NoError(t, err)
+			var names []string
+			for _, ci := range result.CIs {
+				names = append(names, ci.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+			assert.Equal(t, int64(len(tt.expected)), result.TotalCount)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetCIsByIDs loads several CIs in one query. Deleted and unknown CIs, and
+// those of other organizations, are left out; the order of the result is
+// unspecified.
+func (r *CIRepository) GetCIsByIDs(ctx context.Context, ids []uuid.UUID) ([]models.CI, error) {
+	cis := []models.CI{}
+	if len(ids) == 0 {
+		return cis, nil
+	}
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{pq.Array(uuidStrings(ids))})
+	query := `
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items
+		WHERE id = ANY($1::uuid[]) AND is_deleted = false` + orgFilter
+
+	if err := r.conn(ctx).SelectContext(ctx, &cis, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get CIs: %w", err)
+	}
+	return cis, nil
+}
+
+// GetRelationshipsByIDs loads several relationships in one query. Unknown
+// relationships are left out; the order of the result is unspecified.
+func (r *CIRepository) GetRelationshipsByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.CIRelationship, error) {
+	relationships := []*models.CIRelationship{}
+	if len(ids) == 0 {
+		return relationships, nil
+	}
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{pq.Array(uuidStrings(ids))})
+	query := `
+		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
+		FROM ci_relationships
+		WHERE id = ANY($1::uuid[])` + orgFilter
+
+	if err := r.conn(ctx).SelectContext(ctx, &relationships, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+// uuidStrings formats IDs for a uuid[] parameter
+func uuidStrings(ids []uuid.UUID) []string {
+	params := make([]string, len(ids))
+	for i, id := range ids {
+		params[i] = id.String()
+	}
+	return params
+}
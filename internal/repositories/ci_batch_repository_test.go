@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDStrings(t *testing.T) {
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	assert.Equal(t, []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, uuidStrings([]uuid.UUID{id}))
+	assert.Empty(t, uuidStrings(nil))
+}
+
+func TestCIRepository_GetRelationshipsByIDs(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	app, server, database := uuid.New(), uuid.New(), uuid.New()
+	runsOn, dependsOn, unrelated := uuid.New(), uuid.New(), uuid.New()
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{ID: app, Name: "shop", Type: "application"},
+			{ID: server, Name: "web-01", Type: "server"},
+			{ID: database, Name: "db-01", Type: "database"},
+		},
+		Relationships: []testfixtures.Relationship{
+			{ID: runsOn, SourceID: app, TargetID: server, Type: "runs_on"},
+			{ID: dependsOn, SourceID: app, TargetID: database, Type: "depends_on"},
+			{ID: unrelated, SourceID: server, TargetID: database, Type: "connects_to"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+
+	repo := NewCIRepository(db)
+	relationships, err := repo.GetRelationshipsByIDs(ctx, []uuid.UUID{dependsOn, uuid.New(), runsOn})
+	require.NoError(t, err)
+
+	var ids []uuid.UUID
+	for _, rel := range relationships {
+		ids = append(ids, rel.ID)
+	}
+	assert.ElementsMatch(t, []uuid.UUID{runsOn, dependsOn}, ids, "unknown IDs are left out")
+
+	relationships, err = repo.GetRelationshipsByIDs(ctx, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, relationships)
+	assert.Empty(t, relationships)
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// CreateCIClones creates the clones of a CI and the copies of its
+// relationships in one transaction, so a failure leaves none of them behind.
+// The clones have been validated by the caller; the schema, when given, only
+// supplies default attribute values. Relationships whose other end has been
+// deleted or deactivated since are not carried over.
+func (r *CIRepository) CreateCIClones(ctx context.Context, clones []*models.CI, schema *models.CITypeSchema, relationships []*models.CIRelationship) ([]*models.CI, []*models.CIRelationship, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	createdCIs := make([]*models.CI, 0, len(clones))
+	for _, ci := range clones {
+		setCIDefaults(ci)
+		if schema != nil {
+			if err := applySchemaDefaults(ci, schema); err != nil {
+				return nil, nil, err
+			}
+		}
+		ci.OrgID = orgID
+
+		var created models.CI
+		if err := namedGet(ctx, tx, &created, createCIQuery, ci); err != nil {
+			return nil, nil, fmt.Errorf("failed to create clone %s: %w", ci.Name, err)
+		}
+		createdCIs = append(createdCIs, &created)
+	}
+
+	createdRels := []*models.CIRelationship{}
+	if len(relationships) > 0 {
+		endpoints, err := lockCloneEndpoints(ctx, tx, orgID, relationships)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, rel := range relationships {
+			setRelationshipDefaults(rel)
+			rel.OrgID = orgID
+
+			// Deprecated relationships are kept for history only and may point at retired CIs
+			if rel.State != models.RelationshipStateDeprecated {
+				var endpointErr *models.RelationshipEndpointError
+				if err := checkRelationshipEndpoints(endpoints, rel.SourceCIID, rel.TargetCIID); errors.As(err, &endpointErr) {
+					continue
+				}
+			}
+
+			var created models.CIRelationship
+			if err := namedGet(ctx, tx, &created, createRelationshipQuery, rel); err != nil {
+				return nil, nil, fmt.Errorf("failed to clone relationship: %w", err)
+			}
+			createdRels = append(createdRels, &created)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit clones: %w", err)
+	}
+	return createdCIs, createdRels, nil
+}
+
+// lockCloneEndpoints share-locks the CIs the relationships start or end at, so
+// they cannot be deleted or deactivated before the clones commit. CIs of other
+// organizations are not found.
+func lockCloneEndpoints(ctx context.Context, tx *sqlx.Tx, orgID *uuid.UUID, relationships []*models.CIRelationship) ([]relationshipEndpoint, error) {
+	seen := map[uuid.UUID]bool{}
+	var ids []string
+	for _, rel := range relationships {
+		for _, id := range []uuid.UUID{rel.SourceCIID, rel.TargetCIID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id.String())
+			}
+		}
+	}
+
+	query, args := scopeToOrg(orgID, "org_id", "SELECT id, is_active, is_deleted FROM configuration_items WHERE id = ANY($1::uuid[])", []interface{}{pq.Array(ids)})
+	var endpoints []relationshipEndpoint
+	if err := tx.SelectContext(ctx, &endpoints, query+" FOR SHARE", args...); err != nil {
+		return nil, fmt.Errorf("failed to check relationship endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// namedGet runs a named query returning one row and scans it into dest
+func namedGet(ctx context.Context, tx *sqlx.Tx, dest interface{}, query string, arg interface{}) error {
+	rows, err := sqlx.NamedQueryContext(ctx, tx, query, arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("no row returned")
+	}
+	if err := rows.StructScan(dest); err != nil {
+		return err
+	}
+	return rows.Close()
+}
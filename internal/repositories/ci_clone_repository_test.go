@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_CreateCIClones(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	userID, sourceID, dbID, lbID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	scenario := &testfixtures.Scenario{
+		Users: []testfixtures.User{{ID: userID, Username: "cloner"}},
+		CIs: []testfixtures.CI{
+			{ID: sourceID, Name: "web-01", Type: "server"},
+			{ID: dbID, Name: "db-01", Type: "database"},
+			{ID: lbID, Name: "lb-01", Type: "load_balancer"},
+		},
+		Relationships: []testfixtures.Relationship{
+			{SourceID: sourceID, TargetID: dbID, Type: "depends_on"},
+			{SourceID: lbID, TargetID: sourceID, Type: "routes_to"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+
+	repo := NewCIRepository(db)
+	sourceRelationships, err := repo.GetRelationshipsByCI(ctx, sourceID)
+	require.NoError(t, err)
+	require.Len(t, sourceRelationships, 2)
+
+	newClones := func(names ...string) ([]*models.CI, []*models.CIRelationship) {
+		var clones []*models.CI
+		var rels []*models.CIRelationship
+		for _, name := range names {
+			clone := &models.CI{ID: uuid.New(), Name: name, Type: "server", Attributes: []byte(`{}`), CreatedBy: userID, UpdatedBy: userID}
+			clones = append(clones, clone)
+			rels = append(rels, models.CloneRelationships(sourceRelationships, sourceID, clone.ID, userID)...)
+		}
+		return clones, rels
+	}
+	countNamed := func(names ...string) int {
+		var count int
+		require.NoError(t, db.GetContext(ctx, &count, `SELECT COUNT(*) FROM configuration_items WHERE name = ANY($1)`, pq.Array(names)))
+		return count
+	}
+
+	t.Run("creates every clone and relationship", func(t *testing.T) {
+		clones, rels := newClones("web-02", "web-03")
+		createdCIs, createdRels, err := repo.CreateCIClones(ctx, clones, nil, rels)
+		require.NoError(t, err)
+		require.Len(t, createdCIs, 2)
+		assert.Equal(t, "web-02", createdCIs[0].Name)
+		assert.True(t, createdCIs[0].IsActive)
+		assert.Len(t, createdRels, 4)
+		assert.Equal(t, 2, countNamed("web-02", "web-03"))
+
+		cloneRelationships, err := repo.GetRelationshipsByCI(ctx, createdCIs[1].ID)
+		require.NoError(t, err)
+		assert.Len(t, cloneRelationships, 2)
+	})
+
+	t.Run("a failing clone leaves none behind", func(t *testing.T) {
+		clones, rels := newClones("web-04", "web-05")
+		clones[1].ID = sourceID // collides with the source CI
+		_, _, err := repo.CreateCIClones(ctx, clones, nil, rels)
+		require.Error(t, err)
+		assert.Zero(t, countNamed("web-04", "web-05"))
+
+		var relCount int
+		require.NoError(t, db.GetContext(ctx, &relCount, `SELECT COUNT(*) FROM ci_relationships WHERE source_ci_id = $1 OR target_ci_id = $1`, clones[0].ID))
+		assert.Zero(t, relCount)
+	})
+
+	t.Run("relationships to deleted CIs are not carried over", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, `UPDATE configuration_items SET is_deleted = true WHERE id = $1`, dbID)
+		require.NoError(t, err)
+
+		clones, rels := newClones("web-06")
+		createdCIs, createdRels, err := repo.CreateCIClones(ctx, clones, nil, rels)
+		require.NoError(t, err)
+		require.Len(t, createdCIs, 1)
+		require.Len(t, createdRels, 1)
+		assert.Equal(t, "routes_to", createdRels[0].Type)
+	})
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// summaryTable returns a subquery over the live CIs of an organization
+// exposing id, name, type and whether the scope allows the caller to see
+// them, with placeholders numbered from argCount
+func summaryTable(scope *models.VisibilityScope, orgID *uuid.UUID, argCount int) (string, []interface{}) {
+	visible, args := "TRUE", []interface{}(nil)
+	if scope != nil {
+		visible, args = visibilityCondition(scope, argCount)
+	}
+	orgFilter := ""
+	if orgID != nil {
+		args = append(args, *orgID)
+		orgFilter = " AND " + orgCondition("org_id", argCount+len(args)-1)
+	}
+	return fmt.Sprintf("(SELECT id, name, type, NOT (%s) AS hidden FROM configuration_items WHERE is_deleted = false%s)", visible, orgFilter), args
+}
+
+// GetDeletionPreview reports what deleting a CI would affect: the relationships
+// removed with it, the CIs left without any other active relationship of a type
+// they had to it, and the business services depending on it up to
+// MaxImpactDepth hops upstream. scope hides CIs the caller may not see.
+func (r *CIRepository) GetDeletionPreview(ctx context.Context, ci *models.CI, scope *models.VisibilityScope) (*models.DeletionPreview, error) {
+	preview := &models.DeletionPreview{
+		CI:                 models.CISummary{ID: ci.ID, Name: ci.Name, Type: ci.Type},
+		Relationships:      []models.DeletionRelationship{},
+		OrphanedDependents: []models.OrphanedDependent{},
+		AffectedServices:   []models.AffectedService{},
+	}
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relationships to be removed, in every state; those to CIs of other
+	// organizations are left out
+	cis, scopeArgs := summaryTable(scope, orgID, 2)
+	var relationships []struct {
+		models.CISummary
+		RelationshipID uuid.UUID `db:"relationship_id"`
+		Relationship   string    `db:"relationship_type"`
+		State          string    `db:"state"`
+		Direction      string    `db:"direction"`
+	}
+	query := fmt.Sprintf(`
+		SELECT r.id AS relationship_id, r.type AS relationship_type, r.state,
+		       CASE WHEN r.source_ci_id = $1 THEN '%s' ELSE '%s' END AS direction,
+		       c.id, c.name, c.type, c.hidden
+		FROM ci_relationships r
+		JOIN %s c ON c.id = CASE WHEN r.source_ci_id = $1 THEN r.target_ci_id ELSE r.source_ci_id END
+		WHERE (r.source_ci_id = $1 OR r.target_ci_id = $1) AND r.is_active = true
+		ORDER BY r.type, c.name, r.id`,
+		models.RelationshipDirectionOutgoing, models.RelationshipDirectionIncoming, cis)
+	if err := r.conn(ctx).SelectContext(ctx, &relationships, query, append([]interface{}{ci.ID}, scopeArgs...)...); err != nil {
+		return nil, fmt.Errorf("failed to get relationships to remove: %w", err)
+	}
+	for _, rel := range relationships {
+		rel.CISummary.Redact()
+		preview.Relationships = append(preview.Relationships, models.DeletionRelationship{
+			ID:        rel.RelationshipID,
+			Type:      rel.Relationship,
+			State:     rel.State,
+			Direction: rel.Direction,
+			CI:        rel.CISummary,
+		})
+	}
+
+	// Dependents whose only active relationship of a type points at the CI
+	cis, scopeArgs = summaryTable(scope, orgID, 3)
+	var orphans []struct {
+		models.CISummary
+		Relationship string `db:"relationship_type"`
+	}
+	query = fmt.Sprintf(`
+		SELECT DISTINCT r.type AS relationship_type, c.id, c.name, c.type, c.hidden
+		FROM ci_relationships r
+		JOIN %s c ON c.id = r.source_ci_id
+		WHERE r.target_ci_id = $1 AND r.source_ci_id <> $1 AND r.is_active = true AND r.state = $2
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM ci_relationships o
+			JOIN configuration_items t ON t.id = o.target_ci_id AND t.is_deleted = false
+			WHERE o.source_ci_id = r.source_ci_id AND o.type = r.type AND o.target_ci_id <> $1
+			  AND o.is_active = true AND o.state = $2
+		  )
+		ORDER BY c.name, r.type`, cis)
+	args := append([]interface{}{ci.ID, models.RelationshipStateActive}, scopeArgs...)
+	if err := r.conn(ctx).SelectContext(ctx, &orphans, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get orphaned dependents: %w", err)
+	}
+	for _, orphan := range orphans {
+		orphan.CISummary.Redact()
+		preview.OrphanedDependents = append(preview.OrphanedDependents, models.OrphanedDependent{
+			CI:               orphan.CISummary,
+			RelationshipType: orphan.Relationship,
+		})
+	}
+
+	// Business services upstream through active relationships
+	cis, scopeArgs = summaryTable(scope, orgID, 5)
+	edgeFilter := ""
+	if orgID != nil {
+		edgeFilter = " AND " + orgCondition("r.org_id", 5+len(scopeArgs)-1)
+	}
+	var services []struct {
+		models.CISummary
+		Depth int `db:"depth"`
+	}
+	query = fmt.Sprintf(`
+		WITH RECURSIVE upstream(id, depth) AS (
+			SELECT $1::uuid, 0
+			UNION
+			SELECT r.source_ci_id, u.depth + 1
+			FROM ci_relationships r
+			JOIN upstream u ON r.target_ci_id = u.id
+			WHERE r.is_active = true AND r.state = $2 AND u.depth < $3%s
+		)
+		SELECT c.id, c.name, c.type, c.hidden, MIN(u.depth) AS depth
+		FROM upstream u
+		JOIN %s c ON c.id = u.id
+		WHERE u.depth > 0 AND c.type = $4
+		GROUP BY c.id, c.name, c.type, c.hidden
+		ORDER BY depth, c.name`, edgeFilter, cis)
+	args = append([]interface{}{ci.ID, models.RelationshipStateActive, models.MaxImpactDepth, models.BusinessServiceType}, scopeArgs...)
+	if err := r.conn(ctx).SelectContext(ctx, &services, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get affected business services: %w", err)
+	}
+	for _, service := range services {
+		service.CISummary.Redact()
+		preview.AffectedServices = append(preview.AffectedServices, models.AffectedService{
+			CI:    service.CISummary,
+			Depth: service.Depth,
+		})
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryTable(t *testing.T) {
+	table, args := summaryTable(nil, nil, 2)
+	assert.Equal(t, "(SELECT id, name, type, NOT (TRUE) AS hidden FROM configuration_items WHERE is_deleted = false)", table)
+	assert.Empty(t, args)
+
+	orgID := uuid.New()
+	table, args = summaryTable(&models.VisibilityScope{Types: []string{"server"}}, &orgID, 3)
+	assert.Equal(t, "(SELECT id, name, type, NOT ((type = ANY($3))) AS hidden FROM configuration_items"+
+		" WHERE is_deleted = false AND (org_id IS NULL OR org_id = $4))", table)
+	assert.Equal(t, []interface{}{pq.Array([]string{"server"}), orgID}, args)
+}
+
+func TestCIRepository_GetDeletionPreview(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	server, spare, volume := uuid.New(), uuid.New(), uuid.New()
+	shop, blog := uuid.New(), uuid.New()
+	checkout, billing := uuid.New(), uuid.New()
+	ops := []string{"ops"}
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{ID: server, Name: "web-01", Type: "server", Tags: ops},
+			{ID: spare, Name: "web-02", Type: "server", Tags: ops},
+			{ID: volume, Name: "vol-01", Type: "storage", Tags: ops},
+			{ID: shop, Name: "shop", Type: "application", Tags: ops},
+			{ID: blog, Name: "blog", Type: "application", Tags: ops},
+			{ID: checkout, Name: "checkout", Type: models.BusinessServiceType, Tags: ops},
+			{ID: billing, Name: "billing", Type: models.BusinessServiceType},
+		},
+		Relationships: []testfixtures.Relationship{
+			{SourceID: shop, TargetID: server, Type: "runs_on"},
+			{SourceID: blog, TargetID: server, Type: "runs_on"},
+			{SourceID: blog, TargetID: spare, Type: "runs_on"},
+			{SourceID: server, TargetID: volume, Type: "uses"},
+			{SourceID: billing, TargetID: server, Type: "depends_on"},
+			{SourceID: checkout, TargetID: shop, Type: "depends_on"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+
+	repo := NewCIRepository(db)
+	ci := &models.CI{ID: server, Name: "web-01", Type: "server"}
+
+	t.Run("reports everything the deletion affects", func(t *testing.T) {
+		preview, err := repo.GetDeletionPreview(ctx, ci, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "web-01", preview.CI.Name)
+
+		require.Len(t, preview.Relationships, 4)
+		for i, expected := range []struct {
+			relType, direction, ci string
+		}{
+			{"depends_on", models.RelationshipDirectionIncoming, "billing"},
+			{"runs_on", models.RelationshipDirectionIncoming, "blog"},
+			{"runs_on", models.RelationshipDirectionIncoming, "shop"},
+			{"uses", models.RelationshipDirectionOutgoing, "vol-01"},
+		} {
+			assert.Equal(t, expected.relType, preview.Relationships[i].Type)
+			assert.Equal(t, expected.direction, preview.Relationships[i].Direction)
+			assert.Equal(t, expected.ci, preview.Relationships[i].CI.Name)
+		}
+
+		// blog still runs on web-02, so only shop and billing are orphaned
+		require.Len(t, preview.OrphanedDependents, 2)
+		assert.Equal(t, "billing", preview.OrphanedDependents[0].CI.Name)
+		assert.Equal(t, "depends_on", preview.OrphanedDependents[0].RelationshipType)
+		assert.Equal(t, "shop", preview.OrphanedDependents[1].CI.Name)
+		assert.Equal(t, "runs_on", preview.OrphanedDependents[1].RelationshipType)
+
+		require.Len(t, preview.AffectedServices, 2)
+		assert.Equal(t, "billing", preview.AffectedServices[0].CI.Name)
+		assert.Equal(t, 1, preview.AffectedServices[0].Depth)
+		assert.Equal(t, "checkout", preview.AffectedServices[1].CI.Name)
+		assert.Equal(t, 2, preview.AffectedServices[1].Depth)
+	})
+
+	t.Run("redacts CIs outside the scope", func(t *testing.T) {
+		preview, err := repo.GetDeletionPreview(ctx, ci, &models.VisibilityScope{Tags: ops})
+		require.NoError(t, err)
+
+		require.Len(t, preview.Relationships, 4, "hidden CIs are still counted")
+		assert.Equal(t, models.CISummary{ID: billing, Hidden: true}, preview.Relationships[0].CI)
+		assert.True(t, preview.OrphanedDependents[0].CI.Hidden)
+		assert.Empty(t, preview.OrphanedDependents[0].CI.Name)
+		assert.Equal(t, models.CISummary{ID: billing, Hidden: true}, preview.AffectedServices[0].CI)
+		assert.Equal(t, "checkout", preview.AffectedServices[1].CI.Name)
+	})
+
+	t.Run("ignores relationships that are no longer active", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, `UPDATE ci_relationships SET state = 'deprecated' WHERE source_ci_id = $1 AND target_ci_id = $2`, blog, spare)
+		require.NoError(t, err)
+
+		preview, err := repo.GetDeletionPreview(ctx, ci, nil)
+		require.NoError(t, err)
+		require.Len(t, preview.OrphanedDependents, 3)
+		assert.Equal(t, "blog", preview.OrphanedDependents[1].CI.Name)
+	})
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ResolveUserDisplayNames maps user IDs to display names, the full name when one
+// is recorded and the username otherwise. Unknown IDs are left out of the map.
+func (r *CIRepository) ResolveUserDisplayNames(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	names := make(map[uuid.UUID]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	query := `
+		SELECT id, COALESCE(NULLIF(TRIM(CONCAT_WS(' ', first_name, last_name)), ''), username) AS display_name
+		FROM users
+		WHERE id = ANY($1::uuid[])`
+
+	params := make([]string, len(ids))
+	for i, id := range ids {
+		params[i] = id.String()
+	}
+
+	var rows []struct {
+		ID          uuid.UUID `db:"id"`
+		DisplayName string    `db:"display_name"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(params)); err != nil {
+		return nil, fmt.Errorf("failed to resolve user display names: %w", err)
+	}
+
+	for _, row := range rows {
+		names[row.ID] = row.DisplayName
+	}
+	return names, nil
+}
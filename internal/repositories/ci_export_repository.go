@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"connect/internal/models"
+)
+
+// ListCIAttributeNames retrieves the sorted names of the attributes set on the
+// CIs matching a listing filter
+func (r *CIRepository) ListCIAttributeNames(ctx context.Context, req *models.ListCIsRequest) ([]string, error) {
+	whereClause, args, err := r.scopedCIListConditions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`
+		SELECT DISTINCT jsonb_object_keys(attributes) AS name
+		FROM configuration_items
+		WHERE %s AND jsonb_typeof(attributes) = 'object'
+		ORDER BY name`, whereClause)
+
+	var names []string
+	if err := r.conn(ctx).SelectContext(ctx, &names, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list CI attribute names: %w", err)
+	}
+	return names, nil
+}
+
+// StreamCIs calls fn with every CI matching a listing filter, ignoring its
+// paging. Rows are read as fn consumes them, so any number of CIs is streamed
+// in constant memory; an error from fn stops the stream and is returned.
+func (r *CIRepository) StreamCIs(ctx context.Context, req *models.ListCIsRequest, fn func(*models.CI) error) error {
+	whereClause, args, err := r.scopedCIListConditions(ctx, req)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items
+		WHERE %s
+		ORDER BY %s, id`, whereClause, ciListOrder(req))
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to stream CIs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ci models.CI
+		if err := rows.StructScan(&ci); err != nil {
+			return fmt.Errorf("failed to scan CI: %w", err)
+		}
+		if err := fn(&ci); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to stream CIs: %w", err)
+	}
+	return nil
+}
+
+// RelationshipExportFilter selects the relationships of an export. Empty
+// fields do not filter.
+type RelationshipExportFilter struct {
+	Type  string
+	State string
+	// CIType restricts the export to relationships whose source or target is
+	// a CI of this type
+	CIType string
+	// Scope restricts the export to relationships whose source and target the
+	// caller may both see; nil means no restriction
+	Scope *models.VisibilityScope
+}
+
+// StreamRelationships calls fn with every relationship matching a filter
+// whose source and target CIs are not deleted, along with the names of both
+// CIs. Like StreamCIs, rows are read as fn consumes them.
+func (r *CIRepository) StreamRelationships(ctx context.Context, filter RelationshipExportFilter, fn func(rel *models.CIRelationship, sourceName, targetName string) error) error {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+
+	conditions := []string{"s.is_deleted = false", "t.is_deleted = false"}
+	var args []interface{}
+	if orgID != nil {
+		args = append(args, *orgID)
+		conditions = append(conditions, orgCondition("rel.org_id", len(args)))
+	}
+
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("rel.type = $%d", len(args)))
+	}
+	if filter.State != "" {
+		args = append(args, filter.State)
+		conditions = append(conditions, fmt.Sprintf("rel.state = $%d", len(args)))
+	}
+	if filter.CIType != "" {
+		args = append(args, filter.CIType)
+		conditions = append(conditions, fmt.Sprintf("(s.type = $%[1]d OR t.type = $%[1]d)", len(args)))
+	}
+	if filter.Scope != nil {
+		for _, column := range []string{"rel.source_ci_id", "rel.target_ci_id"} {
+			condition, scopeArgs := visibilityCondition(filter.Scope, len(args)+1)
+			conditions = append(conditions, fmt.Sprintf("%s IN (SELECT id FROM configuration_items WHERE %s)", column, condition))
+			args = append(args, scopeArgs...)
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT rel.id, rel.source_ci_id, rel.target_ci_id, rel.type, rel.attributes, rel.description,
+		       rel.is_active, rel.state, rel.state_changed_at, rel.state_changed_by, rel.is_primary,
+		       rel.created_at, rel.updated_at, rel.created_by, rel.updated_by, rel.org_id,
+		       s.name AS source_name, t.name AS target_name
+		FROM ci_relationships rel
+		JOIN configuration_items s ON s.id = rel.source_ci_id
+		JOIN configuration_items t ON t.id = rel.target_ci_id
+		WHERE %s
+		ORDER BY rel.created_at, rel.id`, strings.Join(conditions, " AND "))
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to stream relationships: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row struct {
+			models.CIRelationship
+			SourceName string `db:"source_name"`
+			TargetName string `db:"target_name"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			return fmt.Errorf("failed to scan relationship: %w", err)
+		}
+		if err := fn(&row.CIRelationship, row.SourceName, row.TargetName); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to stream relationships: %w", err)
+	}
+	return nil
+}
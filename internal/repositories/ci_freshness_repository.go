@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/pagination"
+)
+
+// freshnessReferenceExpr is the SQL form of models.FreshnessReferenceTime.
+// It matches the expression index created by the freshness migration.
+const freshnessReferenceExpr = "COALESCE(GREATEST(last_scanned, last_updated), updated_at)"
+
+// ListStaleCIs retrieves CIs whose data is older than the max age of their type, oldest first
+func (r *CIRepository) ListStaleCIs(ctx context.Context, policy models.FreshnessPolicy, ciType string, page, pageSize int) (*models.ListStaleCIsResponse, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	whereClause, args := staleCIConditions(policy, ciType, now)
+	whereClause, args = scopeToOrg(orgID, "org_id", whereClause, args)
+	argCount := len(args) + 1
+
+	// Count total records
+	var totalCount int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM configuration_items WHERE %s", whereClause)
+	if err := r.conn(ctx).GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to count stale CIs: %w", err)
+	}
+
+	// Calculate pagination
+	if page <= 0 {
+		page = 1
+	}
+	pageSize = pagination.FromContext(ctx).PageSize(pageSize)
+
+	offset := (page - 1) * pageSize
+	totalPages := int((totalCount + int64(pageSize) - 1) / int64(pageSize))
+
+	query := fmt.Sprintf(`
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items
+		WHERE %s
+		ORDER BY %s ASC
+		LIMIT $%d OFFSET $%d`, whereClause, freshnessReferenceExpr, argCount, argCount+1)
+
+	args = append(args, pageSize, offset)
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale CIs: %w", err)
+	}
+	defer rows.Close()
+
+	staleCIs := []models.StaleCI{}
+	for rows.Next() {
+		var ci models.CI
+		if err := rows.StructScan(&ci); err != nil {
+			return nil, fmt.Errorf("failed to scan CI: %w", err)
+		}
+		staleCIs = append(staleCIs, models.StaleCI{CI: ci, Freshness: policy.Evaluate(&ci, now)})
+	}
+
+	return &models.ListStaleCIsResponse{
+		CIs:        staleCIs,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// staleCIConditions builds the WHERE clause matching CIs whose data is older
+// than the max age of their type at now. It has one condition per configured
+// type so each can use the (type, reference) index.
+func staleCIConditions(policy models.FreshnessPolicy, ciType string, now time.Time) (string, []interface{}) {
+	args := []interface{}{}
+	argCount := 1
+
+	var staleConditions []string
+	if ciType != "" {
+		staleConditions = append(staleConditions, fmt.Sprintf("(type = $%d AND %s < $%d)", argCount, freshnessReferenceExpr, argCount+1))
+		args = append(args, ciType, now.Add(-policy.MaxAge(ciType)))
+	} else {
+		types := make([]string, 0, len(policy.TypeMaxAge))
+		for t := range policy.TypeMaxAge {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+
+		placeholders := make([]string, 0, len(types))
+		for _, t := range types {
+			staleConditions = append(staleConditions, fmt.Sprintf("(type = $%d AND %s < $%d)", argCount, freshnessReferenceExpr, argCount+1))
+			placeholders = append(placeholders, fmt.Sprintf("$%d", argCount))
+			args = append(args, t, now.Add(-policy.MaxAge(t)))
+			argCount += 2
+		}
+
+		defaultCondition := fmt.Sprintf("%s < $%d", freshnessReferenceExpr, argCount)
+		if len(placeholders) > 0 {
+			defaultCondition = fmt.Sprintf("(type NOT IN (%s) AND %s)", strings.Join(placeholders, ", "), defaultCondition)
+		}
+		staleConditions = append(staleConditions, defaultCondition)
+		args = append(args, now.Add(-policy.DefaultMaxAge))
+	}
+
+	return fmt.Sprintf("is_deleted = false AND (%s)", strings.Join(staleConditions, " OR ")), args
+}
+
+// GetDataQualityReport computes freshness and completeness figures per CI type
+func (r *CIRepository) GetDataQualityReport(ctx context.Context, policy models.FreshnessPolicy) (*models.DataQualityReport, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{}
+	argCount := 1
+
+	// Resolve each type's max age (in seconds) inside the query
+	maxAgeExpr := fmt.Sprintf("$%d::float8", argCount)
+	if len(policy.TypeMaxAge) > 0 {
+		types := make([]string, 0, len(policy.TypeMaxAge))
+		for t := range policy.TypeMaxAge {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+
+		var cases []string
+		for _, t := range types {
+			cases = append(cases, fmt.Sprintf("WHEN $%d THEN $%d::float8", argCount, argCount+1))
+			args = append(args, t, policy.MaxAge(t).Seconds())
+			argCount += 2
+		}
+		maxAgeExpr = fmt.Sprintf("CASE type %s ELSE $%d::float8 END", strings.Join(cases, " "), argCount)
+	}
+	args = append(args, policy.DefaultMaxAge.Seconds())
+	orgFilter, args := andOrgCondition(orgID, "org_id", args)
+
+	query := fmt.Sprintf(`
+		WITH scored AS (
+			SELECT type, owner, location, last_scanned,
+			       EXTRACT(EPOCH FROM (NOW() - %s)) AS age,
+			       %s AS max_age
+			FROM configuration_items
+			WHERE is_deleted = false%s
+		)
+		SELECT type,
+		       COUNT(*) AS total_count,
+		       COUNT(*) FILTER (WHERE age IS NULL OR age > max_age) AS stale_count,
+		       COALESCE(AVG(CASE
+		           WHEN age IS NULL THEN 0
+		           WHEN age <= max_age THEN 1
+		           ELSE GREATEST(0, 1 - (age - max_age) / (2 * max_age))
+		       END), 0) AS average_score,
+		       MAX(max_age) / 3600 AS max_age_hours,
+		       COUNT(*) FILTER (WHERE owner IS NULL OR owner = '') AS missing_owner,
+		       COUNT(*) FILTER (WHERE location IS NULL OR location = '') AS missing_location,
+		       COUNT(*) FILTER (WHERE last_scanned IS NULL) AS never_scanned
+		FROM scored
+		GROUP BY type
+		ORDER BY type`, freshnessReferenceExpr, maxAgeExpr, orgFilter)
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute data quality report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.DataQualityReport{
+		GeneratedAt: time.Now(),
+		ByType:      []models.TypeQualityStats{},
+	}
+	var scoreSum float64
+	for rows.Next() {
+		var stats models.TypeQualityStats
+		if err := rows.Scan(&stats.Type, &stats.TotalCount, &stats.StaleCount, &stats.AverageScore,
+			&stats.MaxAgeHours, &stats.MissingOwner, &stats.MissingLocation, &stats.NeverScanned); err != nil {
+			return nil, fmt.Errorf("failed to scan data quality stats: %w", err)
+		}
+
+		report.TotalCount += stats.TotalCount
+		report.StaleCount += stats.StaleCount
+		report.MissingOwner += stats.MissingOwner
+		report.MissingLocation += stats.MissingLocation
+		report.NeverScanned += stats.NeverScanned
+		scoreSum += stats.AverageScore * float64(stats.TotalCount)
+		report.ByType = append(report.ByType, stats)
+	}
+
+	if report.TotalCount > 0 {
+		report.AverageScore = scoreSum / float64(report.TotalCount)
+		report.StalePercentage = float64(report.StaleCount) / float64(report.TotalCount) * 100
+	}
+
+	return report, nil
+}
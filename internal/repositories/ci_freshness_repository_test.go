@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_GetDataQualityReport(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{Name: "web-fresh", Type: "server"},
+			{Name: "web-old", Type: "server"},
+			{Name: "db-recent", Type: "database"},
+			{Name: "db-ancient", Type: "database"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+
+	// Servers are expected to be scanned daily, everything else monthly
+	for name, age := range map[string]string{
+		"web-fresh":  "1 hour",
+		"web-old":    "3 days",
+		"db-recent":  "3 days",
+		"db-ancient": "90 days",
+	} {
+		_, err := db.ExecContext(ctx, `
+			UPDATE configuration_items
+			SET last_scanned = NOW() - $2::interval, updated_at = NOW() - $2::interval, owner = 'ops'
+			WHERE id = $1`, scenario.CIID(name), age)
+		require.NoError(t, err)
+	}
+	_, err = db.ExecContext(ctx, `UPDATE configuration_items SET last_scanned = NULL, owner = '' WHERE id = $1`, scenario.CIID("db-recent"))
+	require.NoError(t, err)
+
+	repo := NewCIRepository(db)
+	policy := models.FreshnessPolicy{
+		DefaultMaxAge: 30 * 24 * time.Hour,
+		TypeMaxAge:    map[string]time.Duration{"server": 24 * time.Hour},
+	}
+
+	report, err := repo.GetDataQualityReport(ctx, policy)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), report.TotalCount)
+	assert.Equal(t, int64(2), report.StaleCount)
+	assert.InDelta(t, 50, report.StalePercentage, 1e-9)
+	assert.Equal(t, int64(1), report.MissingOwner)
+	assert.Equal(t, int64(1), report.NeverScanned)
+
+	require.Len(t, report.ByType, 2)
+	database, server := report.ByType[0], report.ByType[1]
+	assert.Equal(t, "database", database.Type)
+	assert.Equal(t, int64(1), database.StaleCount)
+	assert.InDelta(t, 30*24, database.MaxAgeHours, 1e-6)
+	assert.Equal(t, "server", server.Type)
+	assert.Equal(t, int64(1), server.StaleCount)
+	assert.InDelta(t, 24, server.MaxAgeHours, 1e-6)
+	// web-old is two days past its max age and so scores 0
+	assert.InDelta(t, 0.5, server.AverageScore, 1e-3)
+}
+
+func TestStaleCIConditions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := models.FreshnessPolicy{
+		DefaultMaxAge: 30 * 24 * time.Hour,
+		TypeMaxAge:    map[string]time.Duration{"server": 24 * time.Hour, "database": 7 * 24 * time.Hour},
+	}
+
+	whereClause, args := staleCIConditions(policy, "", now)
+	assert.Equal(t, "is_deleted = false AND ("+
+		"(type = $1 AND "+freshnessReferenceExpr+" < $2) OR "+
+		"(type = $3 AND "+freshnessReferenceExpr+" < $4) OR "+
+		"(type NOT IN ($1, $3) AND "+freshnessReferenceExpr+" < $5))", whereClause)
+	assert.Equal(t, []interface{}{
+		"database", now.Add(-7 * 24 * time.Hour),
+		"server", now.Add(-24 * time.Hour),
+		now.Add(-30 * 24 * time.Hour),
+	}, args)
+
+	// A type filter needs only that type's max age
+	whereClause, args = staleCIConditions(policy, "server", now)
+	assert.Equal(t, "is_deleted = false AND ((type = $1 AND "+freshnessReferenceExpr+" < $2))", whereClause)
+	assert.Equal(t, []interface{}{"server", now.Add(-24 * time.Hour)}, args)
+
+	whereClause, args = staleCIConditions(models.FreshnessPolicy{DefaultMaxAge: time.Hour}, "", now)
+	assert.Equal(t, "is_deleted = false AND ("+freshnessReferenceExpr+" < $1)", whereClause)
+	assert.Equal(t, []interface{}{now.Add(-time.Hour)}, args)
+}
@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"connect/internal/heartbeat"
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// heartbeatColumns selects a heartbeat joined with its CI as h and ci
+const heartbeatColumns = `
+	h.ci_id, ci.name AS ci_name, ci.type AS ci_type, ci.status, h.source, h.last_heartbeat_at,
+	h.unreachable_since, COALESCE(h.previous_status, '') AS previous_status`
+
+// RecordHeartbeat records a heartbeat of a live CI
+func (r *CIRepository) RecordHeartbeat(ctx context.Context, ciID uuid.UUID, source string, at time.Time) (*heartbeat.Heartbeat, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{ciID, source, at})
+	query := `
+		INSERT INTO ci_heartbeats (ci_id, source, last_heartbeat_at)
+		SELECT id, $2, $3 FROM configuration_items WHERE id = $1 AND is_deleted = false` + orgFilter + `
+		ON CONFLICT (ci_id) DO UPDATE SET
+			source = EXCLUDED.source,
+			last_heartbeat_at = GREATEST(ci_heartbeats.last_heartbeat_at, EXCLUDED.last_heartbeat_at)
+		RETURNING ci_id`
+
+	var id uuid.UUID
+	if err := r.conn(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, heartbeat.ErrCINotFound
+		}
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+	return r.GetHeartbeat(ctx, ciID)
+}
+
+// RecoverCI restores the status an unreachable CI had before it missed its
+// heartbeats. A status set by hand meanwhile is kept.
+func (r *CIRepository) RecoverCI(ctx context.Context, ciID uuid.UUID) (*heartbeat.Heartbeat, error) {
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousStatus string
+	err = tx.GetContext(ctx, &previousStatus, `
+		WITH recovered AS (
+			SELECT ci_id, previous_status FROM ci_heartbeats
+			WHERE ci_id = $1 AND unreachable_since IS NOT NULL
+			FOR UPDATE
+		)
+		UPDATE ci_heartbeats h SET unreachable_since = NULL, previous_status = NULL
+		FROM recovered
+		WHERE h.ci_id = recovered.ci_id
+		RETURNING COALESCE(recovered.previous_status, '')`, ciID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to recover CI: %w", err)
+	}
+
+	if previousStatus != "" {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE configuration_items SET status = $2, updated_at = NOW()
+			WHERE id = $1 AND status = $3`, ciID, previousStatus, models.CIStatusUnreachable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore CI status: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit recovery: %w", err)
+	}
+	return r.GetHeartbeat(ctx, ciID)
+}
+
+// MarkUnreachable moves the active, monitored CIs whose last heartbeat is
+// before the cutoff of their type to the unreachable status. CIs in
+// maintenance or retired are expected to be silent and are left alone.
+func (r *CIRepository) MarkUnreachable(ctx context.Context, cutoffs heartbeat.Cutoffs, at time.Time) ([]heartbeat.Heartbeat, error) {
+	args := []interface{}{at, models.CIStatusUnreachable, models.CIStatusMaintenance, models.CIStatusRetired}
+
+	// One condition per type with its own threshold, as for stale CIs
+	types := make([]string, 0, len(cutoffs.Types))
+	for t := range cutoffs.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	var conditions, placeholders []string
+	for _, t := range types {
+		args = append(args, t, cutoffs.Types[t])
+		conditions = append(conditions, fmt.Sprintf("(ci.type = $%d AND h.last_heartbeat_at < $%d)", len(args)-1, len(args)))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)-1))
+	}
+	args = append(args, cutoffs.Default)
+	defaultCondition := fmt.Sprintf("h.last_heartbeat_at < $%d", len(args))
+	if len(placeholders) > 0 {
+		defaultCondition = fmt.Sprintf("(ci.type NOT IN (%s) AND %s)", strings.Join(placeholders, ", "), defaultCondition)
+	}
+	conditions = append(conditions, defaultCondition)
+
+	query := fmt.Sprintf(`
+		WITH missed AS (
+			SELECT h.ci_id, ci.status
+			FROM ci_heartbeats h
+			JOIN configuration_items ci ON ci.id = h.ci_id
+			WHERE h.unreachable_since IS NULL
+			  AND ci.is_active = true AND ci.is_deleted = false
+			  AND ci.status NOT IN ($2, $3, $4)
+			  AND (%s)
+			FOR UPDATE OF h, ci SKIP LOCKED
+		), marked AS (
+			UPDATE ci_heartbeats h SET unreachable_since = $1, previous_status = missed.status
+			FROM missed
+			WHERE h.ci_id = missed.ci_id
+			RETURNING h.ci_id, h.source, h.last_heartbeat_at, h.unreachable_since, h.previous_status
+		)
+		UPDATE configuration_items ci SET status = $2, updated_at = $1
+		FROM marked
+		WHERE ci.id = marked.ci_id
+		RETURNING ci.id AS ci_id, ci.name AS ci_name, ci.type AS ci_type, ci.status, marked.source,
+		          marked.last_heartbeat_at, marked.unreachable_since, COALESCE(marked.previous_status, '') AS previous_status`, strings.Join(conditions, " OR "))
+
+	heartbeats := []heartbeat.Heartbeat{}
+	if err := r.conn(ctx).SelectContext(ctx, &heartbeats, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to mark unreachable CIs: %w", err)
+	}
+	return heartbeats, nil
+}
+
+// GetHeartbeat retrieves the heartbeat state of a CI
+func (r *CIRepository) GetHeartbeat(ctx context.Context, ciID uuid.UUID) (*heartbeat.Heartbeat, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "ci.org_id", []interface{}{ciID})
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM ci_heartbeats h
+		JOIN configuration_items ci ON ci.id = h.ci_id
+		WHERE h.ci_id = $1%s`, heartbeatColumns, orgFilter)
+
+	var hb heartbeat.Heartbeat
+	if err := r.conn(ctx).GetContext(ctx, &hb, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, heartbeat.ErrHeartbeatNotFound
+		}
+		return nil, fmt.Errorf("failed to get heartbeat: %w", err)
+	}
+	return &hb, nil
+}
+
+// ListHeartbeats lists the heartbeat state of the monitored live CIs, least recent heartbeat first
+func (r *CIRepository) ListHeartbeats(ctx context.Context, unreachableOnly bool) ([]heartbeat.Heartbeat, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "ci.org_id", []interface{}{unreachableOnly})
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM ci_heartbeats h
+		JOIN configuration_items ci ON ci.id = h.ci_id
+		WHERE ci.is_deleted = false AND ($1 = false OR h.unreachable_since IS NOT NULL)%s
+		ORDER BY h.last_heartbeat_at, ci.name`, heartbeatColumns, orgFilter)
+
+	heartbeats := []heartbeat.Heartbeat{}
+	if err := r.conn(ctx).SelectContext(ctx, &heartbeats, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list heartbeats: %w", err)
+	}
+	return heartbeats, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetUpstreamGraph retrieves the CIs depending on a CI through active
+// relationships, at most maxDepth hops away, along with the active
+// relationships between them. The CI itself is included.
+func (r *CIRepository) GetUpstreamGraph(ctx context.Context, ciID uuid.UUID, maxDepth int) ([]models.CI, []models.CIRelationship, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ids []string
+	orgFilter, args := andOrgCondition(orgID, "r.org_id", []interface{}{ciID, models.RelationshipStateActive, maxDepth})
+	err = r.conn(ctx).SelectContext(ctx, &ids, `
+		WITH RECURSIVE upstream(id, depth) AS (
+			SELECT $1::uuid, 0
+			UNION
+			SELECT r.source_ci_id, u.depth + 1
+			FROM ci_relationships r
+			JOIN upstream u ON r.target_ci_id = u.id
+			WHERE r.is_active = true AND r.state = $2 AND u.depth < $3`+orgFilter+`
+		)
+		SELECT DISTINCT id::text FROM upstream`,
+		args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to traverse upstream CIs: %w", err)
+	}
+
+	cis := []models.CI{}
+	orgFilter, args = andOrgCondition(orgID, "org_id", []interface{}{pq.Array(ids)})
+	err = r.conn(ctx).SelectContext(ctx, &cis, `
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items
+		WHERE id = ANY($1::uuid[]) AND is_deleted = false`+orgFilter, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get upstream CIs: %w", err)
+	}
+
+	relationships := []models.CIRelationship{}
+	orgFilter, args = andOrgCondition(orgID, "org_id", []interface{}{pq.Array(ids), models.RelationshipStateActive})
+	err = r.conn(ctx).SelectContext(ctx, &relationships, `
+		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
+		FROM ci_relationships
+		WHERE source_ci_id = ANY($1::uuid[]) AND target_ci_id = ANY($1::uuid[])
+		  AND is_active = true AND state = $2`+orgFilter, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get upstream relationships: %w", err)
+	}
+
+	return cis, relationships, nil
+}
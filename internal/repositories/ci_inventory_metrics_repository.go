@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/inventorymetrics"
+)
+
+// InventoryCounts counts the live CIs, relationships and schemas exposed as inventory metrics
+func (r *CIRepository) InventoryCounts(ctx context.Context) (*inventorymetrics.Counts, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := &inventorymetrics.Counts{
+		CIs:           []inventorymetrics.CICount{},
+		Relationships: []inventorymetrics.RelationshipCount{},
+		Schemas:       []inventorymetrics.SchemaCount{},
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", nil)
+	err = r.conn(ctx).SelectContext(ctx, &counts.CIs, `
+		SELECT type, COALESCE(status, '') AS status, COALESCE(criticality, '') AS criticality, COUNT(*) AS count
+		FROM configuration_items
+		WHERE is_deleted = false`+orgFilter+`
+		GROUP BY 1, 2, 3`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count CIs: %w", err)
+	}
+
+	err = r.conn(ctx).SelectContext(ctx, &counts.Relationships, `
+		SELECT type, state, COUNT(*) AS count
+		FROM ci_relationships
+		WHERE TRUE`+orgFilter+`
+		GROUP BY 1, 2`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count relationships: %w", err)
+	}
+
+	orgFilter, args = andOrgCondition(orgID, "org_id", []interface{}{inventorymetrics.SchemaKindCIType, inventorymetrics.SchemaKindRelationshipType})
+	err = r.conn(ctx).SelectContext(ctx, &counts.Schemas, `
+		SELECT $1::text AS kind, COALESCE(is_active, false) AS active, COUNT(*) AS count FROM ci_type_schemas WHERE TRUE`+orgFilter+` GROUP BY 2
+		UNION ALL
+		SELECT $2::text AS kind, COALESCE(is_active, false) AS active, COUNT(*) AS count FROM relationship_type_schemas WHERE TRUE`+orgFilter+` GROUP BY 2`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count schemas: %w", err)
+	}
+
+	return counts, nil
+}
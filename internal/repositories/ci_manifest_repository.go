@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"connect/internal/manifest"
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// FindCITypeSchema retrieves an active CI type schema by name, or nil if there is none
+func (r *CIRepository) FindCITypeSchema(ctx context.Context, name string) (*models.CITypeSchema, error) {
+	schema, err := r.GetCITypeSchemaByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return schema, err
+}
+
+// FindRelationshipTypeSchema retrieves an active relationship type schema by name, or nil if there is none
+func (r *CIRepository) FindRelationshipTypeSchema(ctx context.Context, name string) (*models.RelationshipTypeSchema, error) {
+	schema, err := r.GetRelationshipTypeSchemaByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return schema, err
+}
+
+// FindCIsByName retrieves the CIs of a type with a name
+func (r *CIRepository) FindCIsByName(ctx context.Context, ciType, name string) ([]*models.CI, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{ciType, name})
+	query := `
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items
+		WHERE type = $1 AND name = $2 AND is_deleted = false` + orgFilter + `
+		ORDER BY created_at`
+
+	var cis []*models.CI
+	if err := r.conn(ctx).SelectContext(ctx, &cis, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to find CIs by name: %w", err)
+	}
+	return cis, nil
+}
+
+// ListManagedCIs retrieves the CIs a manifest manages
+func (r *CIRepository) ListManagedCIs(ctx context.Context, manifestName string) ([]*models.CI, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{manifest.ManagedByAttribute, manifestName})
+	query := `
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items
+		WHERE attributes->>$1 = $2 AND is_deleted = false` + orgFilter + `
+		ORDER BY type, name`
+
+	var cis []*models.CI
+	if err := r.conn(ctx).SelectContext(ctx, &cis, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list managed CIs: %w", err)
+	}
+	return cis, nil
+}
+
+// FindRelationship retrieves the relationship of a type between two CIs in
+// any state, or nil if there is none
+func (r *CIRepository) FindRelationship(ctx context.Context, sourceID, targetID uuid.UUID, relType string) (*models.CIRelationship, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{sourceID, targetID, relType})
+	query := `
+		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
+		FROM ci_relationships
+		WHERE source_ci_id = $1 AND target_ci_id = $2 AND type = $3` + orgFilter + `
+		ORDER BY created_at
+		LIMIT 1`
+
+	var rel models.CIRelationship
+	err = r.conn(ctx).GetContext(ctx, &rel, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find relationship: %w", err)
+	}
+	return &rel, nil
+}
+
+// ListManagedRelationships retrieves the relationships a manifest manages
+func (r *CIRepository) ListManagedRelationships(ctx context.Context, manifestName string) ([]*models.CIRelationship, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{manifest.ManagedByAttribute, manifestName})
+	query := `
+		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
+		FROM ci_relationships
+		WHERE attributes->>$1 = $2` + orgFilter + `
+		ORDER BY created_at`
+
+	var rels []*models.CIRelationship
+	if err := r.conn(ctx).SelectContext(ctx, &rels, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list managed relationships: %w", err)
+	}
+	return rels, nil
+}
@@ -0,0 +1,285 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"connect/internal/models"
+)
+
+// capacityExpr sums a numeric CI attribute, ignoring CIs where it is missing or not a number
+func capacityExpr(attributes ...string) string {
+	expr := "0"
+	for i := len(attributes) - 1; i >= 0; i-- {
+		expr = fmt.Sprintf("CASE WHEN jsonb_typeof(attributes->'%s') = 'number' THEN (attributes->>'%s')::float8 ELSE %s END",
+			attributes[i], attributes[i], expr)
+	}
+	return "COALESCE(SUM(" + expr + "), 0)"
+}
+
+// ListOrgUnits retrieves the managed list of org units
+func (r *CIRepository) ListOrgUnits(ctx context.Context, activeOnly bool) ([]*models.OrgUnit, error) {
+	query := `
+		SELECT code, name, COALESCE(parent_code, '') AS parent_code, is_active, created_at, updated_at
+		FROM org_units`
+	if activeOnly {
+		query += " WHERE is_active = true"
+	}
+	query += " ORDER BY code"
+
+	units := []*models.OrgUnit{}
+	if err := r.conn(ctx).SelectContext(ctx, &units, query); err != nil {
+		return nil, fmt.Errorf("failed to list org units: %w", err)
+	}
+	return units, nil
+}
+
+// GetOrgUnit retrieves an org unit by code
+func (r *CIRepository) GetOrgUnit(ctx context.Context, code string) (*models.OrgUnit, error) {
+	var unit models.OrgUnit
+	err := r.conn(ctx).GetContext(ctx, &unit, `
+		SELECT code, name, COALESCE(parent_code, '') AS parent_code, is_active, created_at, updated_at
+		FROM org_units WHERE code = $1`, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("org unit not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get org unit: %w", err)
+	}
+	return &unit, nil
+}
+
+// UpsertOrgUnit creates or updates an org unit
+func (r *CIRepository) UpsertOrgUnit(ctx context.Context, unit *models.OrgUnit) (*models.OrgUnit, error) {
+	now := time.Now()
+	var saved models.OrgUnit
+	err := r.conn(ctx).GetContext(ctx, &saved, `
+		INSERT INTO org_units (code, name, parent_code, is_active, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $5)
+		ON CONFLICT (code) DO UPDATE SET
+			name = EXCLUDED.name,
+			parent_code = EXCLUDED.parent_code,
+			is_active = EXCLUDED.is_active,
+			updated_at = EXCLUDED.updated_at
+		RETURNING code, name, COALESCE(parent_code, '') AS parent_code, is_active, created_at, updated_at`,
+		unit.Code, unit.Name, unit.ParentCode, unit.IsActive, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save org unit: %w", err)
+	}
+	return &saved, nil
+}
+
+// ListCostCenters retrieves the managed list of cost centers, optionally for one org unit
+func (r *CIRepository) ListCostCenters(ctx context.Context, orgUnit string, activeOnly bool) ([]*models.CostCenter, error) {
+	query := `
+		SELECT code, name, COALESCE(org_unit_code, '') AS org_unit_code, is_active, created_at, updated_at
+		FROM cost_centers
+		WHERE ($1 = '' OR org_unit_code = $1)`
+	if activeOnly {
+		query += " AND is_active = true"
+	}
+	query += " ORDER BY code"
+
+	centers := []*models.CostCenter{}
+	if err := r.conn(ctx).SelectContext(ctx, &centers, query, orgUnit); err != nil {
+		return nil, fmt.Errorf("failed to list cost centers: %w", err)
+	}
+	return centers, nil
+}
+
+// GetCostCenter retrieves a cost center by code
+func (r *CIRepository) GetCostCenter(ctx context.Context, code string) (*models.CostCenter, error) {
+	var center models.CostCenter
+	err := r.conn(ctx).GetContext(ctx, &center, `
+		SELECT code, name, COALESCE(org_unit_code, '') AS org_unit_code, is_active, created_at, updated_at
+		FROM cost_centers WHERE code = $1`, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("cost center not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get cost center: %w", err)
+	}
+	return &center, nil
+}
+
+// UpsertCostCenter creates or updates a cost center
+func (r *CIRepository) UpsertCostCenter(ctx context.Context, center *models.CostCenter) (*models.CostCenter, error) {
+	now := time.Now()
+	var saved models.CostCenter
+	err := r.conn(ctx).GetContext(ctx, &saved, `
+		INSERT INTO cost_centers (code, name, org_unit_code, is_active, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $5)
+		ON CONFLICT (code) DO UPDATE SET
+			name = EXCLUDED.name,
+			org_unit_code = EXCLUDED.org_unit_code,
+			is_active = EXCLUDED.is_active,
+			updated_at = EXCLUDED.updated_at
+		RETURNING code, name, COALESCE(org_unit_code, '') AS org_unit_code, is_active, created_at, updated_at`,
+		center.Code, center.Name, center.OrgUnitCode, center.IsActive, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save cost center: %w", err)
+	}
+	return &saved, nil
+}
+
+// ValidateOrgAssignment checks an org unit and cost center against the managed
+// lists. The returned error wraps models.ErrInvalidOrgAssignment when the
+// assignment itself is invalid.
+func (r *CIRepository) ValidateOrgAssignment(ctx context.Context, orgUnit, costCenter string) error {
+	var unit *models.OrgUnit
+	if orgUnit != "" {
+		var found models.OrgUnit
+		err := r.conn(ctx).GetContext(ctx, &found, `SELECT code, is_active FROM org_units WHERE code = $1`, orgUnit)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to look up org unit: %w", err)
+		}
+		if err == nil {
+			unit = &found
+		}
+	}
+
+	var center *models.CostCenter
+	if costCenter != "" {
+		var found models.CostCenter
+		err := r.conn(ctx).GetContext(ctx, &found, `
+			SELECT code, COALESCE(org_unit_code, '') AS org_unit_code, is_active
+			FROM cost_centers WHERE code = $1`, costCenter)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to look up cost center: %w", err)
+		}
+		if err == nil {
+			center = &found
+		}
+	}
+
+	return models.CheckOrgAssignment(orgUnit, costCenter, unit, center)
+}
+
+// chargebackUnits selects the org unit $1 and its descendants
+const chargebackUnits = `
+		WITH RECURSIVE units AS (
+			SELECT code FROM org_units WHERE code = $1
+			UNION
+			SELECT o.code FROM org_units o JOIN units u ON o.parent_code = u.code
+		)`
+
+// GetChargebackReport aggregates CI counts, capacity and monthly cost per cost
+// center. A non-empty orgUnit restricts the report to CIs in that unit and its
+// descendants. Costs are those of period, or of the latest month with costs
+// when period is empty.
+func (r *CIRepository) GetChargebackReport(ctx context.Context, orgUnit, period string) (*models.ChargebackReport, error) {
+	query := fmt.Sprintf(chargebackUnits+`
+		SELECT ci.cost_center, COALESCE(cc.name, '') AS cost_center_name, COALESCE(cc.org_unit_code, '') AS org_unit,
+		       ci.type, COUNT(*) AS ci_count,
+		       %s AS cpu_cores,
+		       %s AS memory_gb,
+		       %s AS storage_gb
+		FROM configuration_items ci
+		LEFT JOIN cost_centers cc ON cc.code = ci.cost_center
+		WHERE ci.is_deleted = false AND ($1 = '' OR ci.org_unit IN (SELECT code FROM units))
+		GROUP BY ci.cost_center, cc.name, cc.org_unit_code, ci.type
+		ORDER BY ci.cost_center, ci.type`,
+		capacityExpr("cpu_cores"), capacityExpr("memory_gb"), capacityExpr("storage_gb", "size_gb"))
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, orgUnit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute chargeback report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.ChargebackReport{
+		GeneratedAt: time.Now(),
+		OrgUnit:     orgUnit,
+		CostCenters: []models.ChargebackLine{},
+	}
+	lines := map[string]*models.ChargebackLine{}
+	for rows.Next() {
+		var (
+			costCenter, name, unit, ciType string
+			count                          int64
+			cpu, memory, storage           float64
+		)
+		if err := rows.Scan(&costCenter, &name, &unit, &ciType, &count, &cpu, &memory, &storage); err != nil {
+			return nil, fmt.Errorf("failed to scan chargeback line: %w", err)
+		}
+
+		line, ok := lines[costCenter]
+		if !ok {
+			line = &models.ChargebackLine{CostCenter: costCenter, CostCenterName: name, OrgUnit: unit, ByType: map[string]int64{}}
+			lines[costCenter] = line
+		}
+		line.CICount += count
+		line.ByType[ciType] += count
+		line.CPUCores += cpu
+		line.MemoryGB += memory
+		line.StorageGB += storage
+		report.TotalCIs += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chargeback report: %w", err)
+	}
+
+	if err := r.addChargebackCosts(ctx, report, lines, period); err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		report.CostCenters = append(report.CostCenters, *line)
+	}
+	// Unassigned CIs sort last
+	sort.Slice(report.CostCenters, func(i, j int) bool {
+		a, b := report.CostCenters[i].CostCenter, report.CostCenters[j].CostCenter
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		return a < b
+	})
+
+	return report, nil
+}
+
+// addChargebackCosts sums the CI costs of a period into the chargeback lines
+func (r *CIRepository) addChargebackCosts(ctx context.Context, report *models.ChargebackReport, lines map[string]*models.ChargebackLine, period string) error {
+	if period == "" {
+		if err := r.conn(ctx).GetContext(ctx, &period, `SELECT COALESCE(MAX(period), '') FROM ci_costs`); err != nil {
+			return fmt.Errorf("failed to get latest cost period: %w", err)
+		}
+		if period == "" {
+			return nil
+		}
+	}
+	report.Period = period
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, chargebackUnits+`
+		SELECT ci.cost_center, c.currency, SUM(c.amount)
+		FROM ci_costs c
+		JOIN configuration_items ci ON ci.id = c.ci_id
+		WHERE ci.is_deleted = false AND ($1 = '' OR ci.org_unit IN (SELECT code FROM units)) AND c.period = $2
+		GROUP BY ci.cost_center, c.currency`, report.OrgUnit, period)
+	if err != nil {
+		return fmt.Errorf("failed to compute chargeback costs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var costCenter, currency string
+		var amount float64
+		if err := rows.Scan(&costCenter, &currency, &amount); err != nil {
+			return fmt.Errorf("failed to scan chargeback cost: %w", err)
+		}
+		// Every CI with a cost is counted, so its cost center has a line
+		if line, ok := lines[costCenter]; ok {
+			if line.MonthlyCost == nil {
+				line.MonthlyCost = map[string]float64{}
+			}
+			line.MonthlyCost[currency] += amount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read chargeback costs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapacityExpr(t *testing.T) {
+	assert.Equal(t,
+		"COALESCE(SUM(CASE WHEN jsonb_typeof(attributes->'cpu_cores') = 'number' THEN (attributes->>'cpu_cores')::float8 ELSE 0 END), 0)",
+		capacityExpr("cpu_cores"))
+
+	// Later attributes are fallbacks for CIs missing the earlier ones
+	assert.Equal(t,
+		"COALESCE(SUM(CASE WHEN jsonb_typeof(attributes->'storage_gb') = 'number' THEN (attributes->>'storage_gb')::float8 ELSE "+
+			"CASE WHEN jsonb_typeof(attributes->'size_gb') = 'number' THEN (attributes->>'size_gb')::float8 ELSE 0 END END), 0)",
+		capacityExpr("storage_gb", "size_gb"))
+}
+
+func TestCIRepository_OrgUnitsAndChargeback(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewCIRepository(db)
+	for _, unit := range []*models.OrgUnit{
+		{Code: "ENG", Name: "Engineering", IsActive: true},
+		{Code: "PLAT", Name: "Platform", ParentCode: "ENG", IsActive: true},
+		{Code: "SALES", Name: "Sales", IsActive: true},
+	} {
+		_, err := repo.UpsertOrgUnit(ctx, unit)
+		require.NoError(t, err)
+	}
+	for _, center := range []*models.CostCenter{
+		{Code: "CC-100", Name: "Platform hosting", OrgUnitCode: "PLAT", IsActive: true},
+		{Code: "CC-200", Name: "Sales tools", OrgUnitCode: "SALES", IsActive: true},
+	} {
+		_, err := repo.UpsertCostCenter(ctx, center)
+		require.NoError(t, err)
+	}
+
+	t.Run("upserts and lists the managed lists", func(t *testing.T) {
+		renamed, err := repo.UpsertOrgUnit(ctx, &models.OrgUnit{Code: "SALES", Name: "Sales & Marketing", IsActive: false})
+		require.NoError(t, err)
+		assert.Equal(t, "Sales & Marketing", renamed.Name)
+		assert.False(t, renamed.IsActive)
+
+		active, err := repo.ListOrgUnits(ctx, true)
+		require.NoError(t, err)
+		require.Len(t, active, 2)
+		assert.Equal(t, "ENG", active[0].Code)
+		assert.Equal(t, "ENG", active[1].ParentCode)
+
+		centers, err := repo.ListCostCenters(ctx, "PLAT", false)
+		require.NoError(t, err)
+		require.Len(t, centers, 1)
+		assert.Equal(t, "CC-100", centers[0].Code)
+
+		_, err = repo.GetCostCenter(ctx, "CC-404")
+		assert.Error(t, err)
+	})
+
+	t.Run("validates assignments against the lists", func(t *testing.T) {
+		assert.NoError(t, repo.ValidateOrgAssignment(ctx, "PLAT", "CC-100"))
+		assert.NoError(t, repo.ValidateOrgAssignment(ctx, "", ""))
+
+		for _, assignment := range [][2]string{{"NOPE", ""}, {"SALES", ""}, {"ENG", "CC-200"}, {"", "CC-404"}} {
+			err := repo.ValidateOrgAssignment(ctx, assignment[0], assignment[1])
+			assert.True(t, errors.Is(err, models.ErrInvalidOrgAssignment), "%v", assignment)
+		}
+	})
+
+	t.Run("charges CIs to their cost centers", func(t *testing.T) {
+		scenario := &testfixtures.Scenario{
+			CIs: []testfixtures.CI{
+				{Name: "web-01", Type: "server", Attributes: map[string]interface{}{"cpu_cores": 8, "memory_gb": 32}},
+				{Name: "web-02", Type: "server", Attributes: map[string]interface{}{"cpu_cores": 4, "memory_gb": "lots"}},
+				{Name: "vol-01", Type: "storage", Attributes: map[string]interface{}{"size_gb": 500}},
+				{Name: "crm", Type: "application"},
+				{Name: "scratch", Type: "server", Attributes: map[string]interface{}{"cpu_cores": 2}},
+			},
+		}
+		require.NoError(t, scenario.Seed(ctx, db))
+		for name, assignment := range map[string][2]string{
+			"web-01": {"PLAT", "CC-100"},
+			"web-02": {"PLAT", "CC-100"},
+			"vol-01": {"PLAT", "CC-100"},
+			"crm":    {"SALES", "CC-200"},
+		} {
+			_, err := db.ExecContext(ctx, `UPDATE configuration_items SET org_unit = $2, cost_center = $3 WHERE id = $1`,
+				scenario.CIID(name), assignment[0], assignment[1])
+			require.NoError(t, err)
+		}
+		for name, amount := range map[string]float64{"web-01": 120.50, "vol-01": 30} {
+			_, err := db.ExecContext(ctx, `INSERT INTO ci_costs (ci_id, period, amount, currency) VALUES ($1, '2024-05', $2, 'EUR')`,
+				scenario.CIID(name), amount)
+			require.NoError(t, err)
+		}
+
+		report, err := repo.GetChargebackReport(ctx, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), report.TotalCIs)
+		assert.Equal(t, "2024-05", report.Period, "the latest period with costs is the default")
+		require.Len(t, report.CostCenters, 3)
+
+		platform := report.CostCenters[0]
+		assert.Equal(t, "CC-100", platform.CostCenter)
+		assert.Equal(t, "Platform hosting", platform.CostCenterName)
+		assert.Equal(t, "PLAT", platform.OrgUnit)
+		assert.Equal(t, int64(3), platform.CICount)
+		assert.Equal(t, map[string]int64{"server": 2, "storage": 1}, platform.ByType)
+		assert.InDelta(t, 12, platform.CPUCores, 1e-9)
+		assert.InDelta(t, 32, platform.MemoryGB, 1e-9, "non-numeric attributes are ignored")
+		assert.InDelta(t, 500, platform.StorageGB, 1e-9)
+		assert.InDelta(t, 150.50, platform.MonthlyCost["EUR"], 1e-9)
+
+		assert.Equal(t, "CC-200", report.CostCenters[1].CostCenter)
+		assert.Empty(t, report.CostCenters[1].MonthlyCost)
+		assert.Equal(t, "", report.CostCenters[2].CostCenter, "unassigned CIs are reported last")
+		assert.Equal(t, int64(1), report.CostCenters[2].CICount)
+
+		// Restricting to a parent unit includes its descendants
+		report, err = repo.GetChargebackReport(ctx, "ENG", "2024-04")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), report.TotalCIs)
+		require.Len(t, report.CostCenters, 1)
+		assert.Equal(t, "CC-100", report.CostCenters[0].CostCenter)
+		assert.Empty(t, report.CostCenters[0].MonthlyCost, "there are no costs for the period")
+	})
+}
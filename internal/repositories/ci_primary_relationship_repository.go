@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// primaryRelationshipIndex is the unique index allowing one primary
+// relationship per type for each source CI
+const primaryRelationshipIndex = "idx_ci_relationships_primary"
+
+// CheckPrimaryRelationship rejects making a relationship the primary one of
+// its type when its source CI already has another, with a
+// *models.PrimaryRelationshipError
+func (r *CIRepository) CheckPrimaryRelationship(ctx context.Context, sourceID uuid.UUID, relType string, id uuid.UUID) error {
+	var existing uuid.UUID
+	query := `
+		SELECT id FROM ci_relationships
+		WHERE source_ci_id = $1 AND type = $2 AND is_primary AND id <> $3
+		LIMIT 1`
+
+	err := r.conn(ctx).GetContext(ctx, &existing, query, sourceID, relType, id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check primary relationship: %w", err)
+	}
+	return &models.PrimaryRelationshipError{SourceCIID: sourceID, Type: relType, ExistingID: existing}
+}
+
+// SetPrimaryRelationship marks a relationship as the primary one of its type
+// for its source CI, clearing the flag from the one that was, or clears it
+func (r *CIRepository) SetPrimaryRelationship(ctx context.Context, id uuid.UUID, primary bool, changedBy uuid.UUID) (*models.CIRelationship, error) {
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rel models.CIRelationship
+	err = tx.GetContext(ctx, &rel, `
+		SELECT id, source_ci_id, target_ci_id, type, state
+		FROM ci_relationships
+		WHERE id = $1
+		FOR UPDATE`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("relationship not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get relationship: %w", err)
+	}
+	if primary && rel.State == models.RelationshipStateDeprecated {
+		return nil, models.ErrPrimaryRelationshipDeprecated
+	}
+
+	now := time.Now()
+	if primary {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE ci_relationships SET is_primary = false, updated_at = $1, updated_by = $2
+			WHERE source_ci_id = $3 AND type = $4 AND is_primary AND id <> $5`,
+			now, changedBy, rel.SourceCIID, rel.Type, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear primary relationship: %w", err)
+		}
+	}
+
+	var updatedRel models.CIRelationship
+	err = tx.GetContext(ctx, &updatedRel, `
+		UPDATE ci_relationships SET is_primary = $1, updated_at = $2, updated_by = $3
+		WHERE id = $4
+		RETURNING id, source_ci_id, target_ci_id, type, attributes, description,
+		          is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by`,
+		primary, now, changedBy, id)
+	if err != nil {
+		if isPrimaryViolation(err) {
+			return nil, r.primaryConflict(ctx, &rel)
+		}
+		return nil, fmt.Errorf("failed to set primary relationship: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		if isPrimaryViolation(err) {
+			return nil, r.primaryConflict(ctx, &rel)
+		}
+		return nil, fmt.Errorf("failed to commit primary relationship: %w", err)
+	}
+
+	return &updatedRel, nil
+}
+
+// primaryConflict reports the relationship that became primary concurrently
+func (r *CIRepository) primaryConflict(ctx context.Context, rel *models.CIRelationship) error {
+	if err := r.CheckPrimaryRelationship(ctx, rel.SourceCIID, rel.Type, rel.ID); err != nil {
+		return err
+	}
+	return &models.PrimaryRelationshipError{SourceCIID: rel.SourceCIID, Type: rel.Type}
+}
+
+// isPrimaryViolation reports whether err violates the single primary index
+func isPrimaryViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == primaryRelationshipIndex
+}
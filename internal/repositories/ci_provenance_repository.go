@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// RecordAttributeProvenance marks source as the last writer of the given CI attributes
+func (r *CIRepository) RecordAttributeProvenance(ctx context.Context, ciID uuid.UUID, attributes []string, source models.ProvenanceSource, setBy uuid.UUID) error {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ci_attribute_provenance (ci_id, attribute, source_type, source_name, source_id, set_by, set_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+		ON CONFLICT (ci_id, attribute) DO UPDATE SET
+			source_type = EXCLUDED.source_type,
+			source_name = EXCLUDED.source_name,
+			source_id = EXCLUDED.source_id,
+			set_by = EXCLUDED.set_by,
+			set_at = EXCLUDED.set_at`
+
+	now := time.Now()
+	for _, attribute := range attributes {
+		if _, err := tx.ExecContext(ctx, query, ciID, attribute, source.Type, source.Name, source.ID, setBy, now); err != nil {
+			return fmt.Errorf("failed to record provenance for attribute %s: %w", attribute, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit provenance: %w", err)
+	}
+
+	return nil
+}
+
+// GetAttributeProvenance retrieves the provenance of every attribute of a CI, keyed by attribute name
+func (r *CIRepository) GetAttributeProvenance(ctx context.Context, ciID uuid.UUID) (map[string]models.AttributeProvenance, error) {
+	query := `
+		SELECT ci_id, attribute, source_type, source_name, source_id, set_by, set_at
+		FROM ci_attribute_provenance
+		WHERE ci_id = $1
+		ORDER BY attribute`
+
+	var records []models.AttributeProvenance
+	if err := r.conn(ctx).SelectContext(ctx, &records, query, ciID); err != nil {
+		return nil, fmt.Errorf("failed to get attribute provenance: %w", err)
+	}
+
+	provenance := make(map[string]models.AttributeProvenance, len(records))
+	for _, record := range records {
+		provenance[record.Attribute] = record
+	}
+
+	return provenance, nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_AttributeProvenance(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	userID, ciID := uuid.New(), uuid.New()
+	scenario := &testfixtures.Scenario{
+		Users: []testfixtures.User{{ID: userID, Username: "scanner-owner"}},
+		CIs:   []testfixtures.CI{{ID: ciID, Name: "web-01", Type: "server"}},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+	repo := NewCIRepository(db)
+
+	discovery := models.ProvenanceSource{Type: models.ProvenanceSourceDiscovery, Name: "aws-scanner"}
+	require.NoError(t, repo.RecordAttributeProvenance(ctx, ciID, []string{"os", "cpu"}, discovery, userID))
+	require.NoError(t, repo.RecordAttributeProvenance(ctx, ciID, nil, discovery, userID))
+
+	// A later manual change takes over only the attributes it set
+	manual := models.ProvenanceSource{Type: models.ProvenanceSourceManual}
+	require.NoError(t, repo.RecordAttributeProvenance(ctx, ciID, []string{"cpu"}, manual, userID))
+
+	provenance, err := repo.GetAttributeProvenance(ctx, ciID)
+	require.NoError(t, err)
+	require.Len(t, provenance, 2)
+
+	assert.Equal(t, models.ProvenanceSourceDiscovery, provenance["os"].SourceType)
+	require.NotNil(t, provenance["os"].SourceName)
+	assert.Equal(t, "aws-scanner", *provenance["os"].SourceName)
+	assert.Equal(t, &userID, provenance["os"].SetBy)
+
+	assert.Equal(t, models.ProvenanceSourceManual, provenance["cpu"].SourceType)
+	assert.Nil(t, provenance["cpu"].SourceName, "empty source names are stored as NULL")
+
+	empty, err := repo.GetAttributeProvenance(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
@@ -0,0 +1,222 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/relationshipbulk"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// relationshipBatchEntityType is the sync event entity type of a bulk
+// request's changes; the sync service applies its operations in order
+const relationshipBatchEntityType = "relationship_batch"
+
+// ApplyRelationshipBulk deletes then creates the relationships of a bulk
+// request in one transaction. The created relationships are inserted in one
+// statement and checked for cycles together, and a single sync event listing
+// every change, tagged with the result ID, is recorded in the same
+// transaction.
+func (r *CIRepository) ApplyRelationshipBulk(ctx context.Context, result *relationshipbulk.Result, changedBy uuid.UUID) error {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if len(result.Deleted) > 0 {
+		ids := make([]uuid.UUID, len(result.Deleted))
+		for i, change := range result.Deleted {
+			ids[i] = change.Relationship.ID
+		}
+		// Relationships of other organizations are not found
+		query, args := scopeToOrg(orgID, "org_id", "DELETE FROM ci_relationships WHERE id = ANY($1::uuid[])", []interface{}{pq.Array(uuidStrings(ids))})
+		var deleted []*models.CIRelationship
+		err := tx.SelectContext(ctx, &deleted, query+`
+			RETURNING id, source_ci_id, target_ci_id, type, attributes, description,
+			          is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id`,
+			args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete relationships: %w", err)
+		}
+		found := map[uuid.UUID]*models.CIRelationship{}
+		for _, rel := range deleted {
+			found[rel.ID] = rel
+		}
+		for i, change := range result.Deleted {
+			rel, ok := found[change.Relationship.ID]
+			if !ok {
+				return &relationshipbulk.OperationError{Index: change.Index, Err: relationshipbulk.ErrNotFound}
+			}
+			result.Deleted[i].Relationship = rel
+		}
+	}
+
+	if len(result.Created) > 0 {
+		if err := r.insertBulkRelationships(ctx, tx, orgID, result.Created); err != nil {
+			return err
+		}
+	}
+
+	if result.DryRun {
+		return nil
+	}
+
+	data, err := relationshipBatchEventData(result)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sync_events (id, entity_type, entity_id, action, data, status, created_at)
+		VALUES (gen_random_uuid(), $1, $2, 'UPDATE', $3, 'PENDING', clock_timestamp())`,
+		relationshipBatchEntityType, result.ID, data)
+	if err != nil {
+		return fmt.Errorf("failed to record sync event for relationship batch %s: %w", result.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit relationship batch: %w", err)
+	}
+	return nil
+}
+
+// insertBulkRelationships checks the endpoints of the created relationships,
+// inserts them for an organization and rejects the batch when they close a
+// cycle
+func (r *CIRepository) insertBulkRelationships(ctx context.Context, tx *sqlx.Tx, orgID *uuid.UUID, created []relationshipbulk.Change) error {
+	ciIDs := map[uuid.UUID]bool{}
+	for _, change := range created {
+		ciIDs[change.Relationship.SourceCIID] = true
+		ciIDs[change.Relationship.TargetCIID] = true
+	}
+	ids := make([]uuid.UUID, 0, len(ciIDs))
+	for id := range ciIDs {
+		ids = append(ids, id)
+	}
+
+	// Share-lock the endpoints so they cannot be deleted or deactivated
+	// before the transaction commits. CIs of other organizations are not
+	// found.
+	query, args := scopeToOrg(orgID, "org_id", "SELECT id, is_active, is_deleted FROM configuration_items WHERE id = ANY($1::uuid[])", []interface{}{pq.Array(uuidStrings(ids))})
+	var endpoints []relationshipEndpoint
+	err := tx.SelectContext(ctx, &endpoints, query+" FOR SHARE", args...)
+	if err != nil {
+		return fmt.Errorf("failed to check relationship endpoints: %w", err)
+	}
+	for _, change := range created {
+		rel := change.Relationship
+		if err := checkRelationshipEndpoints(endpoints, rel.SourceCIID, rel.TargetCIID); err != nil {
+			return &relationshipbulk.OperationError{Index: change.Index, Err: err}
+		}
+	}
+
+	now := time.Now()
+	var (
+		relIDs, sources, targets []string
+		types, descriptions      []string
+		states                   []string
+		attributes               []sql.NullString
+		createdBy, updatedBy     []string
+		indexes                  = map[uuid.UUID]int{}
+	)
+	for _, change := range created {
+		rel := change.Relationship
+		rel.CreatedAt, rel.UpdatedAt = now, now
+		rel.OrgID = orgID
+		relIDs = append(relIDs, rel.ID.String())
+		sources = append(sources, rel.SourceCIID.String())
+		targets = append(targets, rel.TargetCIID.String())
+		types = append(types, rel.Type)
+		descriptions = append(descriptions, rel.Description)
+		states = append(states, rel.State)
+		attributes = append(attributes, sql.NullString{String: string(rel.Attributes), Valid: len(rel.Attributes) > 0})
+		createdBy = append(createdBy, rel.CreatedBy.String())
+		updatedBy = append(updatedBy, rel.UpdatedBy.String())
+		indexes[rel.ID] = change.Index
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ci_relationships (
+			id, source_ci_id, target_ci_id, type, attributes, description,
+			is_active, state, is_primary, created_at, updated_at, created_by, updated_by, org_id
+		)
+		SELECT rel.id, rel.source_ci_id, rel.target_ci_id, rel.type, rel.attributes, rel.description,
+		       true, rel.state, false, $10, $10, rel.created_by, rel.updated_by, $11::uuid
+		FROM unnest($1::uuid[], $2::uuid[], $3::uuid[], $4::text[], $5::jsonb[], $6::text[], $7::text[], $8::uuid[], $9::uuid[])
+		     AS rel (id, source_ci_id, target_ci_id, type, attributes, description, state, created_by, updated_by)`,
+		pq.Array(relIDs), pq.Array(sources), pq.Array(targets), pq.Array(types), pq.Array(attributes),
+		pq.Array(descriptions), pq.Array(states), pq.Array(createdBy), pq.Array(updatedBy), now, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to create relationships: %w", err)
+	}
+
+	cycles, err := findRelationshipCycles(ctx, tx, relationshipIDs(created))
+	if err != nil {
+		return fmt.Errorf("failed to check created relationships for cycles: %w", err)
+	}
+	if len(cycles) > 0 {
+		cycle := &relationshipbulk.CycleError{}
+		for _, id := range cycles {
+			cycle.Indexes = append(cycle.Indexes, indexes[id])
+		}
+		return cycle
+	}
+	return nil
+}
+
+// relationshipIDs returns the IDs of the changed relationships
+func relationshipIDs(changes []relationshipbulk.Change) []uuid.UUID {
+	ids := make([]uuid.UUID, len(changes))
+	for i, change := range changes {
+		ids[i] = change.Relationship.ID
+	}
+	return ids
+}
+
+// relationshipBatchEventData builds the data of a batch's sync event: its
+// operations in the order they must be synced, each with the fields of a
+// relationship sync event
+func relationshipBatchEventData(result *relationshipbulk.Result) ([]byte, error) {
+	operations := make([]map[string]interface{}, 0, len(result.Deleted)+len(result.Created))
+	for _, action := range []string{"DELETE", "CREATE"} {
+		changes := result.Created
+		if action == "DELETE" {
+			changes = result.Deleted
+		}
+		for _, change := range changes {
+			rel := change.Relationship
+			attributes := json.RawMessage(`{}`)
+			if len(rel.Attributes) > 0 {
+				attributes = rel.Attributes
+			}
+			operations = append(operations, map[string]interface{}{
+				"action":      action,
+				"id":          rel.ID,
+				"source_id":   rel.SourceCIID,
+				"target_id":   rel.TargetCIID,
+				"type":        rel.Type,
+				"description": rel.Description,
+				"attributes":  attributes,
+				"created_by":  rel.CreatedBy,
+				"created_at":  rel.CreatedAt,
+				"updated_at":  rel.UpdatedAt,
+			})
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"batch_id": result.ID, "operations": operations})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode relationship batch %s: %w", result.ID, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// CheckRelationshipEndpoints rejects a relationship whose source or target CI
+// is missing, soft-deleted or inactive, with a *models.RelationshipEndpointError
+func (r *CIRepository) CheckRelationshipEndpoints(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	var endpoints []relationshipEndpoint
+	query := `SELECT id, is_active, is_deleted FROM configuration_items WHERE id IN ($1, $2)`
+	if err := r.conn(ctx).SelectContext(ctx, &endpoints, query, sourceID, targetID); err != nil {
+		return fmt.Errorf("failed to check relationship endpoints: %w", err)
+	}
+	return checkRelationshipEndpoints(endpoints, sourceID, targetID)
+}
+
+// relationshipEndpoint is a CI a relationship starts or ends at
+type relationshipEndpoint struct {
+	ID        uuid.UUID `db:"id"`
+	IsActive  bool      `db:"is_active"`
+	IsDeleted bool      `db:"is_deleted"`
+}
+
+// checkRelationshipEndpoints rejects a relationship whose source or target is
+// not among the live and active endpoints given
+func checkRelationshipEndpoints(endpoints []relationshipEndpoint, sourceID, targetID uuid.UUID) error {
+	for _, end := range []struct {
+		role string
+		id   uuid.UUID
+	}{
+		{models.RelationshipEndpointSource, sourceID},
+		{models.RelationshipEndpointTarget, targetID},
+	} {
+		reason := models.ErrRelationshipEndpointNotFound
+		for _, endpoint := range endpoints {
+			if endpoint.ID != end.id {
+				continue
+			}
+			switch {
+			case endpoint.IsDeleted:
+				reason = models.ErrRelationshipEndpointDeleted
+			case !endpoint.IsActive:
+				reason = models.ErrRelationshipEndpointInactive
+			default:
+				reason = nil
+			}
+		}
+		if reason != nil {
+			return &models.RelationshipEndpointError{Endpoint: end.role, CIID: end.id, Err: reason}
+		}
+	}
+	return nil
+}
+
+// DeactivateOrphanedRelationships deactivates up to limit active relationships
+// whose source or target CI was deleted after they were created, and returns
+// how many it deactivated
+func (r *CIRepository) DeactivateOrphanedRelationships(ctx context.Context, limit int) (int64, error) {
+	query := `
+		UPDATE ci_relationships SET is_active = false, updated_at = $1
+		WHERE id IN (
+			SELECT rel.id FROM configuration_items ci
+			JOIN ci_relationships rel ON rel.source_ci_id = ci.id OR rel.target_ci_id = ci.id
+			WHERE ci.is_deleted = true AND rel.is_active = true
+			LIMIT $2)`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, time.Now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate orphaned relationships: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// listGroupMembers retrieves the CIs selected by a relationship matrix group
+func (r *CIRepository) listGroupMembers(ctx context.Context, group models.CIGroup, orgID *uuid.UUID, scope *models.VisibilityScope) ([]models.MatrixMember, error) {
+	conditions := []string{"is_deleted = false"}
+	args := []interface{}{}
+	argCount := 1
+
+	if orgID != nil {
+		conditions = append(conditions, orgCondition("org_id", argCount))
+		args = append(args, *orgID)
+		argCount++
+	}
+
+	for _, filter := range []struct {
+		column string
+		value  string
+	}{
+		{"type", group.Type},
+		{"owner", group.Owner},
+		{"location", group.Location},
+		{"org_unit", group.OrgUnit},
+	} {
+		if filter.value == "" {
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", filter.column, argCount))
+		args = append(args, filter.value)
+		argCount++
+	}
+
+	if len(group.Tags) > 0 {
+		conditions = append(conditions, fmt.Sprintf("tags && $%d", argCount))
+		args = append(args, pq.Array(group.Tags))
+		argCount++
+	}
+
+	if scope != nil {
+		condition, scopeArgs := visibilityCondition(scope, argCount)
+		conditions = append(conditions, condition)
+		args = append(args, scopeArgs...)
+	}
+
+	// Fetch one more than allowed to detect oversized groups
+	query := fmt.Sprintf(`
+		SELECT id, name, type
+		FROM configuration_items
+		WHERE %s
+		ORDER BY name, id
+		LIMIT %d`, strings.Join(conditions, " AND "), models.MaxMatrixGroupSize+1)
+
+	members := []models.MatrixMember{}
+	if err := r.conn(ctx).SelectContext(ctx, &members, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list group members: %w", err)
+	}
+	if len(members) > models.MaxMatrixGroupSize {
+		return nil, fmt.Errorf("%w: more than %d CIs match", models.ErrMatrixGroupTooLarge, models.MaxMatrixGroupSize)
+	}
+	return members, nil
+}
+
+// GetRelationshipMatrix reports the relationships, in either direction, between
+// the CIs of two groups. Deprecated relationships are left out. relType
+// optionally restricts the relationship type; scope hides CIs the caller may not see.
+func (r *CIRepository) GetRelationshipMatrix(ctx context.Context, rowGroup, columnGroup models.CIGroup, relType string, scope *models.VisibilityScope) (*models.RelationshipMatrix, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.listGroupMembers(ctx, rowGroup, orgID, scope)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := r.listGroupMembers(ctx, columnGroup, orgID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &models.RelationshipMatrix{
+		GeneratedAt:      time.Now(),
+		RowGroup:         rowGroup,
+		ColumnGroup:      columnGroup,
+		RelationshipType: relType,
+		Rows:             rows,
+		Columns:          columns,
+		Cells:            []models.MatrixCell{},
+		UnlinkedRows:     []uuid.UUID{},
+		UnlinkedColumns:  []uuid.UUID{},
+	}
+	if len(rows) == 0 || len(columns) == 0 {
+		for _, row := range rows {
+			matrix.UnlinkedRows = append(matrix.UnlinkedRows, row.ID)
+		}
+		for _, column := range columns {
+			matrix.UnlinkedColumns = append(matrix.UnlinkedColumns, column.ID)
+		}
+		return matrix, nil
+	}
+
+	rowIDs := make([]string, len(rows))
+	for i, row := range rows {
+		rowIDs[i] = row.ID.String()
+	}
+	columnIDs := make([]string, len(columns))
+	for i, column := range columns {
+		columnIDs[i] = column.ID.String()
+	}
+
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{pq.Array(rowIDs), pq.Array(columnIDs), models.RelationshipStateDeprecated, relType})
+	query := `
+		SELECT id, source_ci_id, target_ci_id, type, state
+		FROM ci_relationships
+		WHERE is_active = true AND state <> $3 AND ($4 = '' OR type = $4)
+		  AND ((source_ci_id = ANY($1::uuid[]) AND target_ci_id = ANY($2::uuid[]))
+		    OR (source_ci_id = ANY($2::uuid[]) AND target_ci_id = ANY($1::uuid[])))` + orgFilter + `
+		ORDER BY type, id`
+
+	var edges []struct {
+		ID       uuid.UUID `db:"id"`
+		SourceID uuid.UUID `db:"source_ci_id"`
+		TargetID uuid.UUID `db:"target_ci_id"`
+		Type     string    `db:"type"`
+		State    string    `db:"state"`
+	}
+	if err := r.conn(ctx).SelectContext(ctx, &edges, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get relationship matrix: %w", err)
+	}
+
+	isRow := make(map[uuid.UUID]bool, len(rows))
+	for _, row := range rows {
+		isRow[row.ID] = true
+	}
+	isColumn := make(map[uuid.UUID]bool, len(columns))
+	for _, column := range columns {
+		isColumn[column.ID] = true
+	}
+
+	type key struct{ row, column uuid.UUID }
+	cells := map[key]*models.MatrixCell{}
+	var order []key
+	linkedRows := map[uuid.UUID]bool{}
+	linkedColumns := map[uuid.UUID]bool{}
+	add := func(rowID, columnID uuid.UUID, rel models.MatrixRelationship) {
+		k := key{rowID, columnID}
+		cell, ok := cells[k]
+		if !ok {
+			cell = &models.MatrixCell{RowID: rowID, ColumnID: columnID}
+			cells[k] = cell
+			order = append(order, k)
+		}
+		cell.Relationships = append(cell.Relationships, rel)
+		linkedRows[rowID] = true
+		linkedColumns[columnID] = true
+	}
+
+	// A CI in both groups can sit on either side of an edge, so check each orientation
+	for _, edge := range edges {
+		if isRow[edge.SourceID] && isColumn[edge.TargetID] {
+			add(edge.SourceID, edge.TargetID, models.MatrixRelationship{ID: edge.ID, Type: edge.Type, State: edge.State, Direction: models.RelationshipDirectionOutgoing})
+		}
+		if isRow[edge.TargetID] && isColumn[edge.SourceID] {
+			add(edge.TargetID, edge.SourceID, models.MatrixRelationship{ID: edge.ID, Type: edge.Type, State: edge.State, Direction: models.RelationshipDirectionIncoming})
+		}
+	}
+
+	for _, k := range order {
+		matrix.Cells = append(matrix.Cells, *cells[k])
+	}
+	for _, row := range rows {
+		if !linkedRows[row.ID] {
+			matrix.UnlinkedRows = append(matrix.UnlinkedRows, row.ID)
+		}
+	}
+	for _, column := range columns {
+		if !linkedColumns[column.ID] {
+			matrix.UnlinkedColumns = append(matrix.UnlinkedColumns, column.ID)
+		}
+	}
+
+	return matrix, nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_GetRelationshipMatrix(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	shop, crm, wiki := uuid.New(), uuid.New(), uuid.New()
+	ordersDB, crmDB := uuid.New(), uuid.New()
+	deprecated := uuid.New()
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{ID: shop, Name: "shop", Type: "application", Tags: []string{"prod"}},
+			{ID: crm, Name: "crm", Type: "application", Tags: []string{"prod"}},
+			{ID: wiki, Name: "wiki", Type: "application", Tags: []string{"internal"}},
+			{ID: ordersDB, Name: "orders-db", Type: "database"},
+			{ID: crmDB, Name: "crm-db", Type: "database"},
+		},
+		Relationships: []testfixtures.Relationship{
+			{SourceID: shop, TargetID: ordersDB, Type: "depends_on"},
+			{SourceID: ordersDB, TargetID: shop, Type: "replicates_to"},
+			{ID: deprecated, SourceID: crm, TargetID: crmDB, Type: "depends_on"},
+			{SourceID: wiki, TargetID: crmDB, Type: "depends_on"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+	_, err = db.ExecContext(ctx, `UPDATE ci_relationships SET state = 'deprecated' WHERE id = $1`, deprecated)
+	require.NoError(t, err)
+
+	repo := NewCIRepository(db)
+	apps := models.CIGroup{Type: "application", Tags: []string{"prod"}}
+	databases := models.CIGroup{Type: "database"}
+
+	t.Run("reports cells in both directions", func(t *testing.T) {
+		matrix, err := repo.GetRelationshipMatrix(ctx, apps, databases, "", nil)
+		require.NoError(t, err)
+		require.Len(t, matrix.Rows, 2)
+		assert.Equal(t, "crm", matrix.Rows[0].Name)
+		assert.Equal(t, "shop", matrix.Rows[1].Name)
+		assert.Len(t, matrix.Columns, 2)
+
+		require.Len(t, matrix.Cells, 1)
+		cell := matrix.Cells[0]
+		assert.Equal(t, shop, cell.RowID)
+		assert.Equal(t, ordersDB, cell.ColumnID)
+		require.Len(t, cell.Relationships, 2)
+		assert.Equal(t, "depends_on", cell.Relationships[0].Type)
+		assert.Equal(t, models.RelationshipDirectionOutgoing, cell.Relationships[0].Direction)
+		assert.Equal(t, "replicates_to", cell.Relationships[1].Type)
+		assert.Equal(t, models.RelationshipDirectionIncoming, cell.Relationships[1].Direction)
+
+		// crm's only relationship is deprecated, and wiki is not tagged prod
+		assert.Equal(t, []uuid.UUID{crm}, matrix.UnlinkedRows)
+		assert.Equal(t, []uuid.UUID{crmDB}, matrix.UnlinkedColumns)
+	})
+
+	t.Run("filters by relationship type", func(t *testing.T) {
+		matrix, err := repo.GetRelationshipMatrix(ctx, apps, databases, "replicates_to", nil)
+		require.NoError(t, err)
+		require.Len(t, matrix.Cells, 1)
+		require.Len(t, matrix.Cells[0].Relationships, 1)
+		assert.Equal(t, "replicates_to", matrix.Cells[0].Relationships[0].Type)
+	})
+
+	t.Run("an empty group leaves everything unlinked", func(t *testing.T) {
+		matrix, err := repo.GetRelationshipMatrix(ctx, apps, models.CIGroup{Type: "load_balancer"}, "", nil)
+		require.NoError(t, err)
+		assert.Empty(t, matrix.Columns)
+		assert.Empty(t, matrix.Cells)
+		assert.ElementsMatch(t, []uuid.UUID{shop, crm}, matrix.UnlinkedRows)
+	})
+
+	t.Run("rejects oversized groups", func(t *testing.T) {
+		for i := 0; i <= models.MaxMatrixGroupSize; i++ {
+			_, err := db.ExecContext(ctx, `INSERT INTO configuration_items (id, name, type) VALUES ($1, $2, 'container')`,
+				uuid.New(), fmt.Sprintf("container-%03d", i))
+			require.NoError(t, err)
+		}
+		_, err := repo.GetRelationshipMatrix(ctx, apps, models.CIGroup{Type: "container"}, "", nil)
+		assert.True(t, errors.Is(err, models.ErrMatrixGroupTooLarge))
+	})
+}
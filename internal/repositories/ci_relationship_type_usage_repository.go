@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// GetRelationshipTypeUsage counts the relationships of every relationship type
+func (r *CIRepository) GetRelationshipTypeUsage(ctx context.Context) (*models.RelationshipTypeUsageReport, error) {
+	query := `
+		SELECT s.id AS schema_id, s.name, s.is_active,
+		       COUNT(rel.id) FILTER (WHERE rel.is_active = true AND rel.state = $1) AS active_count,
+		       COUNT(rel.id) FILTER (WHERE rel.is_active = true) AS live_count,
+		       MAX(rel.updated_at) AS last_used_at
+		FROM relationship_type_schemas s
+		LEFT JOIN ci_relationships rel ON rel.type = s.name
+		GROUP BY s.id, s.name, s.is_active
+		ORDER BY s.name`
+
+	var types []models.RelationshipTypeUsage
+	if err := r.conn(ctx).SelectContext(ctx, &types, query, models.RelationshipStateActive); err != nil {
+		return nil, fmt.Errorf("failed to get relationship type usage: %w", err)
+	}
+	return models.NewRelationshipTypeUsageReport(types), nil
+}
+
+// countLiveRelationshipsOfType counts the relationships of the type of a
+// relationship type schema that are not deleted
+func (r *CIRepository) countLiveRelationshipsOfType(ctx context.Context, schemaID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.conn(ctx).GetContext(ctx, &count, `
+		SELECT COUNT(*)
+		FROM ci_relationships rel
+		JOIN relationship_type_schemas s ON s.name = rel.type
+		WHERE s.id = $1 AND rel.is_active = true`, schemaID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count relationships of type: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_RelationshipTypeUsage(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	app, server, database := uuid.New(), uuid.New(), uuid.New()
+	proposed, removed := uuid.New(), uuid.New()
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{ID: app, Name: "shop", Type: "application"},
+			{ID: server, Name: "web-01", Type: "server"},
+			{ID: database, Name: "db-01", Type: "database"},
+		},
+		Relationships: []testfixtures.Relationship{
+			{SourceID: app, TargetID: server, Type: "runs_on"},
+			{ID: proposed, SourceID: app, TargetID: database, Type: "depends_on"},
+			{ID: removed, SourceID: server, TargetID: database, Type: "legacy_link"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+	_, err = db.ExecContext(ctx, `UPDATE ci_relationships SET state = 'proposed' WHERE id = $1`, proposed)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE ci_relationships SET is_active = false WHERE id = $1`, removed)
+	require.NoError(t, err)
+
+	schemaIDs := map[string]uuid.UUID{}
+	for _, name := range []string{"depends_on", "legacy_link", "runs_on", "unused_type"} {
+		schemaIDs[name] = uuid.New()
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO relationship_type_schemas (id, name, attributes, is_active)
+			VALUES ($1, $2, '[]', true)`, schemaIDs[name], name)
+		require.NoError(t, err)
+	}
+
+	repo := NewCIRepository(db)
+	report, err := repo.GetRelationshipTypeUsage(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Types, 4)
+
+	dependsOn, legacy, runsOn, unused := report.Types[0], report.Types[1], report.Types[2], report.Types[3]
+	assert.Equal(t, schemaIDs["depends_on"], dependsOn.SchemaID)
+	assert.Equal(t, int64(0), dependsOn.ActiveCount)
+	assert.Equal(t, int64(1), dependsOn.LiveCount)
+	assert.Equal(t, int64(0), legacy.LiveCount)
+	assert.NotNil(t, legacy.LastUsedAt, "deleted relationships still date the last use")
+	assert.Equal(t, int64(1), runsOn.ActiveCount)
+	assert.Nil(t, unused.LastUsedAt)
+	assert.Equal(t, []string{"legacy_link", "unused_type"}, report.Unused)
+
+	err = repo.DeleteRelationshipTypeSchema(ctx, schemaIDs["depends_on"])
+	assert.True(t, errors.Is(err, models.ErrRelationshipTypeInUse), "proposed relationships keep their type in use")
+
+	require.NoError(t, repo.DeleteRelationshipTypeSchema(ctx, schemaIDs["legacy_link"]))
+	err = repo.DeleteRelationshipTypeSchema(ctx, schemaIDs["legacy_link"])
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, models.ErrRelationshipTypeInUse))
+}
@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/reparent"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// relationshipCycleDepth bounds the paths followed when checking moved or
+// created relationships for cycles
+const relationshipCycleDepth = 32
+
+// ApplyReparent moves the planned relationships to their new CI in one
+// transaction. Relationships keep their IDs, so the version trigger records
+// the move in their history. Each move emits a DELETE sync event for the old
+// edge and a CREATE event for the new one, tagged with the move ID, in the
+// same transaction.
+func (r *CIRepository) ApplyReparent(ctx context.Context, result *reparent.Result, changedBy uuid.UUID) error {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]uuid.UUID, len(result.Moved))
+	for i, move := range result.Moved {
+		ids[i] = move.RelationshipID
+	}
+
+	// Lock the planned relationships and make sure none changed since the
+	// plan; those of other organizations count as gone
+	var current []struct {
+		ID         uuid.UUID `db:"id"`
+		SourceCIID uuid.UUID `db:"source_ci_id"`
+		TargetCIID uuid.UUID `db:"target_ci_id"`
+		UpdatedAt  time.Time `db:"updated_at"`
+	}
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{pq.Array(uuidStrings(ids))})
+	err = tx.SelectContext(ctx, &current, `
+		SELECT id, source_ci_id, target_ci_id, updated_at FROM ci_relationships
+		WHERE id = ANY($1::uuid[])`+orgFilter+`
+		FOR UPDATE`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to lock relationships: %w", err)
+	}
+	if len(current) != len(result.Moved) {
+		return reparent.ErrConcurrentChange
+	}
+	planned := map[uuid.UUID]reparent.Move{}
+	for _, move := range result.Moved {
+		planned[move.RelationshipID] = move
+	}
+	for _, rel := range current {
+		move := planned[rel.ID]
+		if rel.SourceCIID != move.OldSourceCIID || rel.TargetCIID != move.OldTargetCIID || !rel.UpdatedAt.Equal(move.UpdatedAt) {
+			return reparent.ErrConcurrentChange
+		}
+	}
+
+	now := time.Now()
+	for _, move := range result.Moved {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE ci_relationships
+			SET source_ci_id = $2, target_ci_id = $3, is_primary = is_primary AND NOT $4,
+			    updated_at = $5, updated_by = $6
+			WHERE id = $1`,
+			move.RelationshipID, move.SourceCIID, move.TargetCIID, move.ClearPrimary, now, changedBy)
+		if err != nil {
+			if isPrimaryViolation(err) {
+				return reparent.ErrConcurrentChange
+			}
+			return fmt.Errorf("failed to move relationship %s: %w", move.RelationshipID, err)
+		}
+	}
+
+	// A moved relationship closes a cycle when its new target leads back to
+	// its new source through live relationships of the same type
+	cycles, err := findRelationshipCycles(ctx, tx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to check moved relationships for cycles: %w", err)
+	}
+	if len(cycles) > 0 {
+		return &reparent.CycleError{RelationshipIDs: cycles}
+	}
+
+	if result.DryRun {
+		return nil
+	}
+
+	// Old edges are removed before new ones are added, in this order, when
+	// the events are processed
+	for _, action := range []string{"DELETE", "CREATE"} {
+		for _, move := range result.Moved {
+			source, target := move.SourceCIID, move.TargetCIID
+			if action == "DELETE" {
+				source, target = move.OldSourceCIID, move.OldTargetCIID
+			}
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO sync_events (id, entity_type, entity_id, action, data, status, created_at)
+				SELECT gen_random_uuid(), 'relationship', rel.id, $2,
+				       jsonb_build_object(
+				           'id', rel.id,
+				           'source_id', $3::uuid,
+				           'target_id', $4::uuid,
+				           'type', rel.type,
+				           'description', COALESCE(rel.description, ''),
+				           'attributes', COALESCE(rel.attributes, '{}'::jsonb),
+				           'created_by', rel.created_by,
+				           'created_at', rel.created_at,
+				           'updated_at', rel.updated_at,
+				           'reparent_id', $5::uuid
+				       ),
+				       'PENDING', clock_timestamp()
+				FROM ci_relationships rel
+				WHERE rel.id = $1`,
+				move.RelationshipID, action, source, target, result.ID)
+			if err != nil {
+				return fmt.Errorf("failed to record sync event for relationship %s: %w", move.RelationshipID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit relationship move: %w", err)
+	}
+	return nil
+}
+
+// findRelationshipCycles returns the relationships among ids whose target
+// leads back to their source through live relationships of the same type
+func findRelationshipCycles(ctx context.Context, tx *sqlx.Tx, ids []uuid.UUID) ([]uuid.UUID, error) {
+	var cycles []uuid.UUID
+	err := tx.SelectContext(ctx, &cycles, `
+		WITH RECURSIVE walk (start_id, source_ci_id, ci_id, type, path) AS (
+			SELECT rel.id, rel.source_ci_id, rel.target_ci_id, rel.type, ARRAY[rel.target_ci_id]
+			FROM ci_relationships rel
+			WHERE rel.id = ANY($1::uuid[]) AND rel.is_active = true AND rel.state <> $2
+			UNION ALL
+			SELECT w.start_id, w.source_ci_id, next.target_ci_id, w.type, w.path || next.target_ci_id
+			FROM walk w
+			JOIN ci_relationships next ON next.source_ci_id = w.ci_id AND next.type = w.type
+			WHERE next.is_active = true AND next.state <> $2
+			  AND w.ci_id <> w.source_ci_id
+			  AND NOT next.target_ci_id = ANY(w.path)
+			  AND array_length(w.path, 1) < $3
+		)
+		SELECT DISTINCT start_id FROM walk WHERE ci_id = source_ci_id ORDER BY start_id`,
+		pq.Array(uuidStrings(ids)), models.RelationshipStateDeprecated, relationshipCycleDepth)
+	return cycles, err
+}
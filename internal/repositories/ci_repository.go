@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"connect/internal/models"
+	"connect/internal/pagination"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -17,29 +18,82 @@ import (
 // CIRepository handles database operations for CIs
 type CIRepository struct {
 	db *sqlx.DB
+	// router resolves the database of the tenant a request acts for; nil
+	// keeps every tenant on db
+	router DBRouter
+	// orgs resolves the organization whose rows a request may see
+	orgs *orgResolver
+}
+
+// DBRouter resolves the database holding the CI data of the tenant a context
+// acts for
+type DBRouter interface {
+	DB(ctx context.Context) *sqlx.DB
 }
 
 // NewCIRepository creates a new CI repository
 func NewCIRepository(db *sqlx.DB) *CIRepository {
-	return &CIRepository{db: db}
+	return &CIRepository{db: db, orgs: newOrgResolver(sqlxOrgLookup(db))}
+}
+
+// SetRouter routes the CI data of each tenant to the database the router
+// resolves. User data stays on the main database.
+func (r *CIRepository) SetRouter(router DBRouter) {
+	r.router = router
+}
+
+// conn returns the database holding the CI data for a request
+func (r *CIRepository) conn(ctx context.Context) *sqlx.DB {
+	if r.router == nil {
+		return r.db
+	}
+	return r.router.DB(ctx)
 }
 
+// createCIQuery inserts a CI and returns it as stored
+const createCIQuery = `
+	INSERT INTO configuration_items (
+		id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+	) VALUES (
+		:id, :name, :type, :description, :status, :criticality, :owner, :location, :org_unit, :cost_center,
+		:attributes, :tags, :install_date, :warranty_expiry, :last_updated, :last_scanned,
+		:is_active, :is_deleted, :created_at, :updated_at, :created_by, :updated_by, :org_id
+	)
+	RETURNING id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+	          attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+	          is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id`
+
 // CreateCI creates a new CI in the database
 func (r *CIRepository) CreateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
-	query := `
-		INSERT INTO configuration_items (
-			id, name, type, description, status, criticality, owner, location,
-			attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
-			is_active, is_deleted, created_at, updated_at, created_by, updated_by
-		) VALUES (
-			:id, :name, :type, :description, :status, :criticality, :owner, :location,
-			:attributes, :tags, :install_date, :warranty_expiry, :last_updated, :last_scanned,
-			:is_active, :is_deleted, :created_at, :updated_at, :created_by, :updated_by
-		)
-		RETURNING id, name, type, description, status, criticality, owner, location,
-		          attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
-		          is_active, is_deleted, created_at, updated_at, created_by, updated_by`
+	setCIDefaults(ci)
+
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ci.OrgID = orgID
+
+	rows, err := r.conn(ctx).NamedQueryContext(ctx, createCIQuery, ci)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CI: %w", err)
+	}
+	defer rows.Close()
+
+	var createdCI models.CI
+	if rows.Next() {
+		if err := rows.StructScan(&createdCI); err != nil {
+			return nil, fmt.Errorf("failed to scan created CI: %w", err)
+		}
+	}
 
+	return &createdCI, nil
+}
+
+// setCIDefaults fills in the timestamps, status, criticality and active flag
+// of a new CI
+func setCIDefaults(ci *models.CI) {
 	// Set timestamps if not provided
 	if ci.CreatedAt.IsZero() {
 		ci.CreatedAt = time.Now()
@@ -58,34 +112,25 @@ func (r *CIRepository) CreateCI(ctx context.Context, ci *models.CI) (*models.CI,
 	if !ci.IsActive {
 		ci.IsActive = true
 	}
-
-	rows, err := r.db.NamedQueryContext(ctx, query, ci)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create CI: %w", err)
-	}
-	defer rows.Close()
-
-	var createdCI models.CI
-	if rows.Next() {
-		if err := rows.StructScan(&createdCI); err != nil {
-			return nil, fmt.Errorf("failed to scan created CI: %w", err)
-		}
-	}
-
-	return &createdCI, nil
 }
 
 // GetCI retrieves a CI by ID
 func (r *CIRepository) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
 	query := `
-		SELECT id, name, type, description, status, criticality, owner, location,
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
 		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
-		       is_active, is_deleted, created_at, updated_at, created_by, updated_by
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
 		FROM configuration_items 
 		WHERE id = $1 AND is_deleted = false`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, []interface{}{id})
+
 	var ci models.CI
-	err := r.db.GetContext(ctx, &ci, query, id)
+	err = r.conn(ctx).GetContext(ctx, &ci, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("CI not found: %w", err)
@@ -96,7 +141,8 @@ func (r *CIRepository) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, err
 	return &ci, nil
 }
 
-// UpdateCI updates an existing CI
+// UpdateCI updates an existing CI, recording its prior state as a revision
+// in the same transaction
 func (r *CIRepository) UpdateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
 	query := `
 		UPDATE configuration_items SET
@@ -107,6 +153,8 @@ func (r *CIRepository) UpdateCI(ctx context.Context, ci *models.CI) (*models.CI,
 			criticality = :criticality,
 			owner = :owner,
 			location = :location,
+			org_unit = :org_unit,
+			cost_center = :cost_center,
 			attributes = :attributes,
 			tags = :tags,
 			install_date = :install_date,
@@ -117,14 +165,37 @@ func (r *CIRepository) UpdateCI(ctx context.Context, ci *models.CI) (*models.CI,
 			updated_at = :updated_at,
 			updated_by = :updated_by
 		WHERE id = :id AND is_deleted = false
-		RETURNING id, name, type, description, status, criticality, owner, location,
+		RETURNING id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
 		          attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
-		          is_active, is_deleted, created_at, updated_at, created_by, updated_by`
+		          is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id`
 
 	// Set updated timestamp
 	ci.UpdatedAt = time.Now()
 
-	rows, err := r.db.NamedQueryContext(ctx, query, ci)
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkOrgRow(ctx, tx, orgID, "configuration_items", ci.ID, fmt.Errorf("CI not found")); err != nil {
+		return nil, err
+	}
+
+	var changedBy *uuid.UUID
+	if ci.UpdatedBy != uuid.Nil {
+		changedBy = &ci.UpdatedBy
+	}
+	if _, err := recordCIRevision(ctx, tx, ci.ID, models.CIRevisionUpdate, changedBy, false); err != nil {
+		return nil, err
+	}
+
+	rows, err := sqlx.NamedQueryContext(ctx, tx, query, ci)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update CI: %w", err)
 	}
@@ -138,18 +209,43 @@ func (r *CIRepository) UpdateCI(ctx context.Context, ci *models.CI) (*models.CI,
 	} else {
 		return nil, fmt.Errorf("CI not found")
 	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit CI update: %w", err)
+	}
 
 	return &updatedCI, nil
 }
 
-// DeleteCI soft-deletes a CI
+// DeleteCI soft-deletes a CI, recording its prior state as a revision in the
+// same transaction
 func (r *CIRepository) DeleteCI(ctx context.Context, id uuid.UUID) error {
 	query := `
 		UPDATE configuration_items 
 		SET is_deleted = true, updated_at = $1
 		WHERE id = $2 AND is_deleted = false`
 
-	result, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkOrgRow(ctx, tx, orgID, "configuration_items", id, fmt.Errorf("CI not found")); err != nil {
+		return err
+	}
+
+	if _, err := recordCIRevision(ctx, tx, id, models.CIRevisionDelete, nil, false); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete CI: %w", err)
 	}
@@ -163,11 +259,90 @@ func (r *CIRepository) DeleteCI(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf("CI not found")
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit CI delete: %w", err)
+	}
+
 	return nil
 }
 
 // ListCIs retrieves CIs with pagination and filtering
 func (r *CIRepository) ListCIs(ctx context.Context, req *models.ListCIsRequest) (*models.ListCIsResponse, error) {
+	whereClause, args, err := r.scopedCIListConditions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	argCount := len(args) + 1
+
+	orderBy := ciListOrder(req)
+
+	// Count total records
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM configuration_items WHERE %s", whereClause)
+	var totalCount int64
+	err = r.conn(ctx).GetContext(ctx, &totalCount, countQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count CIs: %w", err)
+	}
+
+	// Calculate pagination
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	req.PageSize = pagination.FromContext(ctx).PageSize(req.PageSize)
+
+	offset := (req.Page - 1) * req.PageSize
+	totalPages := int((totalCount + int64(req.PageSize) - 1) / int64(req.PageSize))
+
+	// Build SELECT query
+	query := fmt.Sprintf(`
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items 
+		WHERE %s 
+		ORDER BY %s 
+		LIMIT $%d OFFSET $%d`, whereClause, orderBy, argCount, argCount+1)
+
+	args = append(args, req.PageSize, offset)
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CIs: %w", err)
+	}
+	defer rows.Close()
+
+	var cis []models.CI
+	for rows.Next() {
+		var ci models.CI
+		if err := rows.StructScan(&ci); err != nil {
+			return nil, fmt.Errorf("failed to scan CI: %w", err)
+		}
+		cis = append(cis, &ci)
+	}
+
+	return &models.ListCIsResponse{
+		CIs:        cis,
+		TotalCount: totalCount,
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// scopedCIListConditions builds the WHERE clause and arguments of a CI
+// listing, narrowed to the CIs of the organization the request acts for
+func (r *CIRepository) scopedCIListConditions(ctx context.Context, req *models.ListCIsRequest) (string, []interface{}, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	whereClause, args := ciListConditions(req)
+	whereClause, args = scopeToOrg(orgID, "org_id", whereClause, args)
+	return whereClause, args, nil
+}
+
+// ciListConditions builds the WHERE clause and arguments of a CI listing
+func ciListConditions(req *models.ListCIsRequest) (string, []interface{}) {
 	// Build WHERE clause
 	whereConditions := []string{"is_deleted = false"}
 	args := []interface{}{}
@@ -213,99 +388,118 @@ func (r *CIRepository) ListCIs(ctx context.Context, req *models.ListCIsRequest)
 		argCount++
 	}
 
+	if req.OrgUnit != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("org_unit = $%d", argCount))
+		args = append(args, req.OrgUnit)
+		argCount++
+	}
+
+	if req.CostCenter != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("cost_center = $%d", argCount))
+		args = append(args, req.CostCenter)
+		argCount++
+	}
+
 	if len(req.Tags) > 0 {
 		whereConditions = append(whereConditions, fmt.Sprintf("tags && $%d", argCount))
 		args = append(args, pq.Array(req.Tags))
 		argCount++
 	}
 
-	whereClause := strings.Join(whereConditions, " AND ")
+	for _, filter := range req.Attributes {
+		condition, filterArgs := attributeFilterCondition(filter, argCount)
+		whereConditions = append(whereConditions, condition)
+		args = append(args, filterArgs...)
+		argCount += len(filterArgs)
+	}
+
+	if req.Scope != nil {
+		condition, scopeArgs := visibilityCondition(req.Scope, argCount)
+		whereConditions = append(whereConditions, condition)
+		args = append(args, scopeArgs...)
+		argCount += len(scopeArgs)
+	}
 
+	return strings.Join(whereConditions, " AND "), args
+}
+
+// ciListOrder builds the ORDER BY clause of a CI listing
+func ciListOrder(req *models.ListCIsRequest) string {
 	// Build ORDER BY clause
 	orderBy := "created_at DESC"
-	if req.SortBy != "" {
-		validSortFields := map[string]bool{
-			"name": true, "type": true, "status": true, "criticality": true,
-			"owner": true, "location": true, "created_at": true, "updated_at": true,
-		}
-		if validSortFields[req.SortBy] {
+	for _, field := range models.CISortFields {
+		if req.SortBy == field {
 			orderBy = req.SortBy
-			if req.SortOrder == "desc" {
+			if req.SortOrder == models.SortOrderDesc {
 				orderBy += " DESC"
 			} else {
 				orderBy += " ASC"
 			}
 		}
 	}
+	return orderBy
+}
 
-	// Count total records
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM configuration_items WHERE %s", whereClause)
-	var totalCount int64
-	err := r.db.GetContext(ctx, &totalCount, countQuery, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count CIs: %w", err)
-	}
+// createRelationshipQuery inserts a relationship and returns it as stored
+const createRelationshipQuery = `
+	INSERT INTO ci_relationships (
+		id, source_ci_id, target_ci_id, type, attributes, description,
+		is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
+	) VALUES (
+		:id, :source_ci_id, :target_ci_id, :type, :attributes, :description,
+		:is_active, :state, :state_changed_at, :state_changed_by, :is_primary, :created_at, :updated_at, :created_by, :updated_by, :org_id
+	)
+	RETURNING id, source_ci_id, target_ci_id, type, attributes, description,
+	          is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id`
 
-	// Calculate pagination
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	if req.PageSize <= 0 || req.PageSize > 100 {
-		req.PageSize = 20
-	}
+// CreateRelationship creates a new relationship between CIs
+func (r *CIRepository) CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error) {
+	setRelationshipDefaults(rel)
 
-	offset := (req.Page - 1) * req.PageSize
-	totalPages := int((totalCount + int64(req.PageSize) - 1) / int64(req.PageSize))
+	// Deprecated relationships are kept for history only and may point at retired CIs
+	if rel.State != models.RelationshipStateDeprecated {
+		if err := r.CheckRelationshipEndpoints(ctx, rel.SourceCIID, rel.TargetCIID); err != nil {
+			return nil, err
+		}
+	}
 
-	// Build SELECT query
-	query := fmt.Sprintf(`
-		SELECT id, name, type, description, status, criticality, owner, location,
-		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
-		       is_active, is_deleted, created_at, updated_at, created_by, updated_by
-		FROM configuration_items 
-		WHERE %s 
-		ORDER BY %s 
-		LIMIT $%d OFFSET $%d`, whereClause, orderBy, argCount, argCount+1)
+	if rel.IsPrimary {
+		if rel.State == models.RelationshipStateDeprecated {
+			return nil, models.ErrPrimaryRelationshipDeprecated
+		}
+		if err := r.CheckPrimaryRelationship(ctx, rel.SourceCIID, rel.Type, rel.ID); err != nil {
+			return nil, err
+		}
+	}
 
-	args = append(args, req.PageSize, offset)
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rel.OrgID = orgID
 
-	rows, err := r.db.QueryxContext(ctx, query, args...)
+	rows, err := r.conn(ctx).NamedQueryContext(ctx, createRelationshipQuery, rel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list CIs: %w", err)
+		if isPrimaryViolation(err) {
+			return nil, r.primaryConflict(ctx, rel)
+		}
+		return nil, fmt.Errorf("failed to create relationship: %w", err)
 	}
 	defer rows.Close()
 
-	var cis []models.CI
-	for rows.Next() {
-		var ci models.CI
-		if err := rows.StructScan(&ci); err != nil {
-			return nil, fmt.Errorf("failed to scan CI: %w", err)
+	var createdRel models.CIRelationship
+	if rows.Next() {
+		if err := rows.StructScan(&createdRel); err != nil {
+			return nil, fmt.Errorf("failed to scan created relationship: %w", err)
 		}
-		cis = append(cis, &ci)
 	}
 
-	return &models.ListCIsResponse{
-		CIs:        cis,
-		TotalCount: totalCount,
-		Page:       req.Page,
-		PageSize:   req.PageSize,
-		TotalPages: totalPages,
-	}, nil
+	return &createdRel, nil
 }
 
-// CreateRelationship creates a new relationship between CIs
-func (r *CIRepository) CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error) {
-	query := `
-		INSERT INTO ci_relationships (
-			id, source_ci_id, target_ci_id, type, attributes, description,
-			is_active, created_at, updated_at, created_by, updated_by
-		) VALUES (
-			:id, :source_ci_id, :target_ci_id, :type, :attributes, :description,
-			:is_active, :created_at, :updated_at, :created_by, :updated_by
-		)
-		RETURNING id, source_ci_id, target_ci_id, type, attributes, description,
-		          is_active, created_at, updated_at, created_by, updated_by`
-
+// setRelationshipDefaults fills in the timestamps, active flag and state of a
+// new relationship
+func setRelationshipDefaults(rel *models.CIRelationship) {
 	// Set timestamps if not provided
 	if rel.CreatedAt.IsZero() {
 		rel.CreatedAt = time.Now()
@@ -318,33 +512,27 @@ func (r *CIRepository) CreateRelationship(ctx context.Context, rel *models.CIRel
 	if !rel.IsActive {
 		rel.IsActive = true
 	}
-
-	rows, err := r.db.NamedQueryContext(ctx, query, rel)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create relationship: %w", err)
-	}
-	defer rows.Close()
-
-	var createdRel models.CIRelationship
-	if rows.Next() {
-		if err := rows.StructScan(&createdRel); err != nil {
-			return nil, fmt.Errorf("failed to scan created relationship: %w", err)
-		}
+	if rel.State == "" {
+		rel.State = models.RelationshipStateActive
 	}
-
-	return &createdRel, nil
 }
 
 // GetRelationship retrieves a relationship by ID
 func (r *CIRepository) GetRelationship(ctx context.Context, id uuid.UUID) (*models.CIRelationship, error) {
 	query := `
 		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
-		       is_active, created_at, updated_at, created_by, updated_by
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
 		FROM ci_relationships 
 		WHERE id = $1`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, []interface{}{id})
+
 	var rel models.CIRelationship
-	err := r.db.GetContext(ctx, &rel, query, id)
+	err = r.conn(ctx).GetContext(ctx, &rel, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("relationship not found: %w", err)
@@ -367,12 +555,20 @@ func (r *CIRepository) UpdateRelationship(ctx context.Context, rel *models.CIRel
 			updated_by = :updated_by
 		WHERE id = :id
 		RETURNING id, source_ci_id, target_ci_id, type, attributes, description,
-		          is_active, created_at, updated_at, created_by, updated_by`
+		          is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id`
 
 	// Set updated timestamp
 	rel.UpdatedAt = time.Now()
 
-	rows, err := r.db.NamedQueryContext(ctx, query, rel)
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOrgRow(ctx, r.conn(ctx), orgID, "ci_relationships", rel.ID, fmt.Errorf("relationship not found")); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn(ctx).NamedQueryContext(ctx, query, rel)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update relationship: %w", err)
 	}
@@ -392,9 +588,13 @@ func (r *CIRepository) UpdateRelationship(ctx context.Context, rel *models.CIRel
 
 // DeleteRelationship deletes a relationship
 func (r *CIRepository) DeleteRelationship(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM ci_relationships WHERE id = $1`
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+	query, args := scopeToOrg(orgID, "org_id", `DELETE FROM ci_relationships WHERE id = $1`, []interface{}{id})
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to delete relationship: %w", err)
 	}
@@ -411,15 +611,27 @@ func (r *CIRepository) DeleteRelationship(ctx context.Context, id uuid.UUID) err
 	return nil
 }
 
-// GetRelationshipsByCI retrieves all relationships for a CI
+// GetRelationshipsByCI retrieves the active relationships for a CI.
+// Proposed and deprecated relationships are excluded; use GetRelationshipsByCIAndState to include them.
 func (r *CIRepository) GetRelationshipsByCI(ctx context.Context, ciID uuid.UUID) ([]*models.CIRelationship, error) {
+	return r.GetRelationshipsByCIAndState(ctx, ciID, []string{models.RelationshipStateActive})
+}
+
+// GetRelationshipsByCIAndState retrieves the relationships for a CI that are in one of the given states
+func (r *CIRepository) GetRelationshipsByCIAndState(ctx context.Context, ciID uuid.UUID, states []string) ([]*models.CIRelationship, error) {
 	query := `
 		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
-		       is_active, created_at, updated_at, created_by, updated_by
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
 		FROM ci_relationships 
-		WHERE (source_ci_id = $1 OR target_ci_id = $1) AND is_active = true`
+		WHERE (source_ci_id = $1 OR target_ci_id = $1) AND is_active = true AND state = ANY($2)`
+
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, []interface{}{ciID, pq.Array(states)})
 
-	rows, err := r.db.QueryxContext(ctx, query, ciID)
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get relationships by CI: %w", err)
 	}
@@ -437,16 +649,143 @@ func (r *CIRepository) GetRelationshipsByCI(ctx context.Context, ciID uuid.UUID)
 	return relationships, nil
 }
 
+// ListRelationships retrieves all relationships, ordered by creation time
+func (r *CIRepository) ListRelationships(ctx context.Context) ([]*models.CIRelationship, error) {
+	query := `
+		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
+		FROM ci_relationships
+		WHERE TRUE`
+
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, nil)
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query+" ORDER BY created_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var relationships []*models.CIRelationship
+	for rows.Next() {
+		var rel models.CIRelationship
+		if err := rows.StructScan(&rel); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship: %w", err)
+		}
+		relationships = append(relationships, &rel)
+	}
+
+	return relationships, nil
+}
+
+// SearchRelationships retrieves relationships in a lifecycle state matching the
+// request's attribute filters, with pagination
+func (r *CIRepository) SearchRelationships(ctx context.Context, req *models.ListRelationshipsRequest) ([]*models.CIRelationship, int64, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause, args := relationshipListConditions(req)
+	whereClause, args = scopeToOrg(orgID, "org_id", whereClause, args)
+
+	var totalCount int64
+	err = r.conn(ctx).GetContext(ctx, &totalCount, "SELECT COUNT(*) FROM ci_relationships WHERE "+whereClause, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count relationships: %w", err)
+	}
+
+	// Calculate pagination
+	page := req.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := pagination.FromContext(ctx).PageSize(req.PageSize)
+
+	offset := (page - 1) * pageSize
+
+	query := fmt.Sprintf(`
+		SELECT id, source_ci_id, target_ci_id, type, attributes, description,
+		       is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id
+		FROM ci_relationships
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`, whereClause, relationshipListOrder(req), len(args)+1, len(args)+2)
+
+	rows, err := r.conn(ctx).QueryxContext(ctx, query, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var relationships []*models.CIRelationship
+	for rows.Next() {
+		var rel models.CIRelationship
+		if err := rows.StructScan(&rel); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan relationship: %w", err)
+		}
+		relationships = append(relationships, &rel)
+	}
+
+	return relationships, totalCount, nil
+}
+
+// TransitionRelationshipState moves a relationship to a new lifecycle state.
+// Deprecating a primary relationship clears its primary flag.
+func (r *CIRepository) TransitionRelationshipState(ctx context.Context, id uuid.UUID, state string, changedBy uuid.UUID) (*models.CIRelationship, error) {
+	rel, err := r.GetRelationship(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := models.ValidateRelationshipStateTransition(rel.State, state); err != nil {
+		return nil, err
+	}
+
+	if state == models.RelationshipStateActive {
+		if err := r.CheckRelationshipEndpoints(ctx, rel.SourceCIID, rel.TargetCIID); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		UPDATE ci_relationships SET
+			state = $1,
+			state_changed_at = $2,
+			state_changed_by = $3,
+			is_primary = is_primary AND $1 <> 'deprecated',
+			updated_at = $2,
+			updated_by = $3
+		WHERE id = $4 AND state = $5
+		RETURNING id, source_ci_id, target_ci_id, type, attributes, description,
+		          is_active, state, state_changed_at, state_changed_by, is_primary, created_at, updated_at, created_by, updated_by, org_id`
+
+	// Guard on the previous state so concurrent transitions can't both succeed
+	var updatedRel models.CIRelationship
+	err = r.conn(ctx).GetContext(ctx, &updatedRel, query, state, time.Now(), changedBy, id, rel.State)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("relationship state changed concurrently: %w", err)
+		}
+		return nil, fmt.Errorf("failed to transition relationship state: %w", err)
+	}
+
+	return &updatedRel, nil
+}
+
 // CheckCircularDependency checks for circular dependencies in relationships
 func (r *CIRepository) CheckCircularDependency(ctx context.Context, sourceCIID, targetCIID uuid.UUID, relationshipType string) (bool, error) {
 	// This is a simplified check - in a real implementation, you'd use graph traversal
 	// For now, we'll check if there's already a reverse relationship of the same type
 	query := `
 		SELECT COUNT(*) FROM ci_relationships 
-		WHERE source_ci_id = $1 AND target_ci_id = $2 AND type = $3 AND is_active = true`
+		WHERE source_ci_id = $1 AND target_ci_id = $2 AND type = $3 AND is_active = true
+		  AND state <> 'deprecated'`
 
 	var count int
-	err := r.db.GetContext(ctx, &count, query, targetCIID, sourceCIID, relationshipType)
+	err := r.conn(ctx).GetContext(ctx, &count, query, targetCIID, sourceCIID, relationshipType)
 	if err != nil {
 		return false, fmt.Errorf("failed to check circular dependency: %w", err)
 	}
@@ -460,11 +799,11 @@ func (r *CIRepository) CheckCircularDependency(ctx context.Context, sourceCIID,
 func (r *CIRepository) CreateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error) {
 	query := `
 		INSERT INTO ci_type_schemas (
-			id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
+			id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
 		) VALUES (
-			:id, :name, :description, :attributes, :is_active, :created_at, :updated_at, :created_by, :updated_by
+			:id, :name, :description, :attributes, :is_active, :created_at, :updated_at, :created_by, :updated_by, :org_id
 		)
-		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by`
+		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id`
 
 	// Set timestamps if not provided
 	if schema.CreatedAt.IsZero() {
@@ -479,6 +818,11 @@ func (r *CIRepository) CreateCITypeSchema(ctx context.Context, schema *models.CI
 		schema.IsActive = true
 	}
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert attributes to JSON
 	attributesJSON, err := json.Marshal(schema.Attributes)
 	if err != nil {
@@ -496,9 +840,10 @@ func (r *CIRepository) CreateCITypeSchema(ctx context.Context, schema *models.CI
 		"updated_at":  schema.UpdatedAt,
 		"created_by":  schema.CreatedBy,
 		"updated_by":  schema.UpdatedBy,
+		"org_id":      orgID,
 	}
 
-	rows, err := r.db.NamedQueryContext(ctx, query, schemaMap)
+	rows, err := r.conn(ctx).NamedQueryContext(ctx, query, schemaMap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CI type schema: %w", err)
 	}
@@ -521,12 +866,18 @@ func (r *CIRepository) CreateCITypeSchema(ctx context.Context, schema *models.CI
 // GetCITypeSchema retrieves a CI type schema by ID
 func (r *CIRepository) GetCITypeSchema(ctx context.Context, id uuid.UUID) (*models.CITypeSchema, error) {
 	query := `
-		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
+		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
 		FROM ci_type_schemas 
 		WHERE id = $1`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, []interface{}{id})
+
 	var schema models.CITypeSchema
-	err := r.db.GetContext(ctx, &schema, query, id)
+	err = r.conn(ctx).GetContext(ctx, &schema, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("CI type schema not found: %w", err)
@@ -545,12 +896,18 @@ func (r *CIRepository) GetCITypeSchema(ctx context.Context, id uuid.UUID) (*mode
 // GetCITypeSchemaByName retrieves a CI type schema by name
 func (r *CIRepository) GetCITypeSchemaByName(ctx context.Context, name string) (*models.CITypeSchema, error) {
 	query := `
-		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
+		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
 		FROM ci_type_schemas 
 		WHERE name = $1 AND is_active = true`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, []interface{}{name})
+
 	var schema models.CITypeSchema
-	err := r.db.GetContext(ctx, &schema, query, name)
+	err = r.conn(ctx).GetContext(ctx, &schema, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("CI type schema not found: %w", err)
@@ -577,11 +934,20 @@ func (r *CIRepository) UpdateCITypeSchema(ctx context.Context, schema *models.CI
 			updated_at = :updated_at,
 			updated_by = :updated_by
 		WHERE id = :id
-		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by`
+		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id`
 
 	// Set updated timestamp
 	schema.UpdatedAt = time.Now()
 
+	// Organizations only change the schemas they own
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if orgID != nil {
+		query = strings.Replace(query, "WHERE id = :id", "WHERE id = :id AND org_id = :org_id", 1)
+	}
+
 	// Convert attributes to JSON
 	attributesJSON, err := json.Marshal(schema.Attributes)
 	if err != nil {
@@ -597,9 +963,10 @@ func (r *CIRepository) UpdateCITypeSchema(ctx context.Context, schema *models.CI
 		"is_active":   schema.IsActive,
 		"updated_at":  schema.UpdatedAt,
 		"updated_by":  schema.UpdatedBy,
+		"org_id":      orgID,
 	}
 
-	rows, err := r.db.NamedQueryContext(ctx, query, schemaMap)
+	rows, err := r.conn(ctx).NamedQueryContext(ctx, query, schemaMap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update CI type schema: %w", err)
 	}
@@ -623,9 +990,14 @@ func (r *CIRepository) UpdateCITypeSchema(ctx context.Context, schema *models.CI
 
 // DeleteCITypeSchema deletes a CI type schema
 func (r *CIRepository) DeleteCITypeSchema(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM ci_type_schemas WHERE id = $1`
+	// Organizations only delete the schemas they own
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+	query, args := scopeToOwnOrg(orgID, "org_id", `DELETE FROM ci_type_schemas WHERE id = $1`, []interface{}{id})
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to delete CI type schema: %w", err)
 	}
@@ -644,49 +1016,7 @@ func (r *CIRepository) DeleteCITypeSchema(ctx context.Context, id uuid.UUID) err
 
 // ListCITypeSchemas retrieves CI type schemas with pagination
 func (r *CIRepository) ListCITypeSchemas(ctx context.Context, page, pageSize int) ([]*models.CITypeSchema, int64, error) {
-	// Count total records
-	var totalCount int64
-	err := r.db.GetContext(ctx, &totalCount, "SELECT COUNT(*) FROM ci_type_schemas")
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count CI type schemas: %w", err)
-	}
-
-	// Calculate pagination
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 || pageSize > 100 {
-		pageSize = 20
-	}
-
-	offset := (page - 1) * pageSize
-
-	query := `
-		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
-		FROM ci_type_schemas 
-		ORDER BY name 
-		LIMIT $1 OFFSET $2`
-
-	rows, err := r.db.QueryxContext(ctx, query, pageSize, offset)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list CI type schemas: %w", err)
-	}
-	defer rows.Close()
-
-	var schemas []*models.CITypeSchema
-	for rows.Next() {
-		var schema models.CITypeSchema
-		if err := rows.StructScan(&schema); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan CI type schema: %w", err)
-		}
-		// Unmarshal attributes
-		if err := json.Unmarshal(schema.Attributes, &schema.Attributes); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal attributes: %w", err)
-		}
-		schemas = append(schemas, &schema)
-	}
-
-	return schemas, totalCount, nil
+	return r.SearchCITypeSchemas(ctx, &models.ListSchemasRequest{Page: page, PageSize: pageSize})
 }
 
 // Relationship Type Schema Methods
@@ -695,11 +1025,11 @@ func (r *CIRepository) ListCITypeSchemas(ctx context.Context, page, pageSize int
 func (r *CIRepository) CreateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error) {
 	query := `
 		INSERT INTO relationship_type_schemas (
-			id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
+			id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
 		) VALUES (
-			:id, :name, :description, :attributes, :is_active, :created_at, :updated_at, :created_by, :updated_by
+			:id, :name, :description, :attributes, :is_active, :created_at, :updated_at, :created_by, :updated_by, :org_id
 		)
-		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by`
+		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id`
 
 	// Set timestamps if not provided
 	if schema.CreatedAt.IsZero() {
@@ -714,6 +1044,11 @@ func (r *CIRepository) CreateRelationshipTypeSchema(ctx context.Context, schema
 		schema.IsActive = true
 	}
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert attributes to JSON
 	attributesJSON, err := json.Marshal(schema.Attributes)
 	if err != nil {
@@ -731,9 +1066,10 @@ func (r *CIRepository) CreateRelationshipTypeSchema(ctx context.Context, schema
 		"updated_at":  schema.UpdatedAt,
 		"created_by":  schema.CreatedBy,
 		"updated_by":  schema.UpdatedBy,
+		"org_id":      orgID,
 	}
 
-	rows, err := r.db.NamedQueryContext(ctx, query, schemaMap)
+	rows, err := r.conn(ctx).NamedQueryContext(ctx, query, schemaMap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create relationship type schema: %w", err)
 	}
@@ -756,12 +1092,18 @@ func (r *CIRepository) CreateRelationshipTypeSchema(ctx context.Context, schema
 // GetRelationshipTypeSchema retrieves a relationship type schema by ID
 func (r *CIRepository) GetRelationshipTypeSchema(ctx context.Context, id uuid.UUID) (*models.RelationshipTypeSchema, error) {
 	query := `
-		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
+		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
 		FROM relationship_type_schemas 
 		WHERE id = $1`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, []interface{}{id})
+
 	var schema models.RelationshipTypeSchema
-	err := r.db.GetContext(ctx, &schema, query, id)
+	err = r.conn(ctx).GetContext(ctx, &schema, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("relationship type schema not found: %w", err)
@@ -780,12 +1122,18 @@ func (r *CIRepository) GetRelationshipTypeSchema(ctx context.Context, id uuid.UU
 // GetRelationshipTypeSchemaByName retrieves a relationship type schema by name
 func (r *CIRepository) GetRelationshipTypeSchemaByName(ctx context.Context, name string) (*models.RelationshipTypeSchema, error) {
 	query := `
-		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
+		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
 		FROM relationship_type_schemas 
 		WHERE name = $1 AND is_active = true`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args := scopeToOrg(orgID, "org_id", query, []interface{}{name})
+
 	var schema models.RelationshipTypeSchema
-	err := r.db.GetContext(ctx, &schema, query, name)
+	err = r.conn(ctx).GetContext(ctx, &schema, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("relationship type schema not found: %w", err)
@@ -812,11 +1160,20 @@ func (r *CIRepository) UpdateRelationshipTypeSchema(ctx context.Context, schema
 			updated_at = :updated_at,
 			updated_by = :updated_by
 		WHERE id = :id
-		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by`
+		RETURNING id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id`
 
 	// Set updated timestamp
 	schema.UpdatedAt = time.Now()
 
+	// Organizations only change the schemas they own
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if orgID != nil {
+		query = strings.Replace(query, "WHERE id = :id", "WHERE id = :id AND org_id = :org_id", 1)
+	}
+
 	// Convert attributes to JSON
 	attributesJSON, err := json.Marshal(schema.Attributes)
 	if err != nil {
@@ -832,9 +1189,10 @@ func (r *CIRepository) UpdateRelationshipTypeSchema(ctx context.Context, schema
 		"is_active":   schema.IsActive,
 		"updated_at":  schema.UpdatedAt,
 		"updated_by":  schema.UpdatedBy,
+		"org_id":      orgID,
 	}
 
-	rows, err := r.db.NamedQueryContext(ctx, query, schemaMap)
+	rows, err := r.conn(ctx).NamedQueryContext(ctx, query, schemaMap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update relationship type schema: %w", err)
 	}
@@ -856,11 +1214,25 @@ func (r *CIRepository) UpdateRelationshipTypeSchema(ctx context.Context, schema
 	return &updatedSchema, nil
 }
 
-// DeleteRelationshipTypeSchema deletes a relationship type schema
+// DeleteRelationshipTypeSchema deletes a relationship type schema. Types
+// still used by relationships that are not deleted are kept and
+// models.ErrRelationshipTypeInUse is returned.
 func (r *CIRepository) DeleteRelationshipTypeSchema(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM relationship_type_schemas WHERE id = $1`
+	query := `
+		DELETE FROM relationship_type_schemas s
+		WHERE s.id = $1
+		  AND NOT EXISTS (SELECT 1 FROM ci_relationships rel WHERE rel.type = s.name AND rel.is_active = true)`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	// Organizations only delete the schemas they own
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkOwnOrgRow(ctx, r.conn(ctx), orgID, "relationship_type_schemas", id, fmt.Errorf("relationship type schema not found")); err != nil {
+		return err
+	}
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete relationship type schema: %w", err)
 	}
@@ -871,6 +1243,13 @@ func (r *CIRepository) DeleteRelationshipTypeSchema(ctx context.Context, id uuid
 	}
 
 	if rowsAffected == 0 {
+		count, err := r.countLiveRelationshipsOfType(ctx, id)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w by %d relationships", models.ErrRelationshipTypeInUse, count)
+		}
 		return fmt.Errorf("relationship type schema not found")
 	}
 
@@ -879,49 +1258,7 @@ func (r *CIRepository) DeleteRelationshipTypeSchema(ctx context.Context, id uuid
 
 // ListRelationshipTypeSchemas retrieves relationship type schemas with pagination
 func (r *CIRepository) ListRelationshipTypeSchemas(ctx context.Context, page, pageSize int) ([]*models.RelationshipTypeSchema, int64, error) {
-	// Count total records
-	var totalCount int64
-	err := r.db.GetContext(ctx, &totalCount, "SELECT COUNT(*) FROM relationship_type_schemas")
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count relationship type schemas: %w", err)
-	}
-
-	// Calculate pagination
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 || pageSize > 100 {
-		pageSize = 20
-	}
-
-	offset := (page - 1) * pageSize
-
-	query := `
-		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by
-		FROM relationship_type_schemas 
-		ORDER BY name 
-		LIMIT $1 OFFSET $2`
-
-	rows, err := r.db.QueryxContext(ctx, query, pageSize, offset)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list relationship type schemas: %w", err)
-	}
-	defer rows.Close()
-
-	var schemas []*models.RelationshipTypeSchema
-	for rows.Next() {
-		var schema models.RelationshipTypeSchema
-		if err := rows.StructScan(&schema); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan relationship type schema: %w", err)
-		}
-		// Unmarshal attributes
-		if err := json.Unmarshal(schema.Attributes, &schema.Attributes); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal attributes: %w", err)
-		}
-		schemas = append(schemas, &schema)
-	}
-
-	return schemas, totalCount, nil
+	return r.SearchRelationshipTypeSchemas(ctx, &models.ListSchemasRequest{Page: page, PageSize: pageSize})
 }
 
 // Schema Validation Methods
@@ -962,11 +1299,21 @@ func (r *CIRepository) CreateCIWithValidation(ctx context.Context, ci *models.CI
 		return nil, fmt.Errorf("CI validation failed: %v", validationResult.Errors)
 	}
 
-	// Apply defaults if needed
+	if err := applySchemaDefaults(ci, schema); err != nil {
+		return nil, err
+	}
+
+	// Create the CI
+	return r.CreateCI(ctx, ci)
+}
+
+// applySchemaDefaults sets the schema's default values of the attributes a CI
+// leaves out
+func applySchemaDefaults(ci *models.CI, schema *models.CITypeSchema) error {
 	var attributes map[string]interface{}
 	if len(ci.Attributes) > 0 {
 		if err := json.Unmarshal(ci.Attributes, &attributes); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal attributes: %w", err)
+			return fmt.Errorf("failed to unmarshal attributes: %w", err)
 		}
 	} else {
 		attributes = make(map[string]interface{})
@@ -978,12 +1325,10 @@ func (r *CIRepository) CreateCIWithValidation(ctx context.Context, ci *models.CI
 	// Marshal attributes back to JSON
 	attributesJSON, err := json.Marshal(attributes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+		return fmt.Errorf("failed to marshal attributes: %w", err)
 	}
 	ci.Attributes = attributesJSON
-
-	// Create the CI
-	return r.CreateCI(ctx, ci)
+	return nil
 }
 
 // UpdateCIWithValidation updates a CI with schema validation
@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ciRevisionColumns are the CI columns a revision snapshots and a restore writes back
+const ciRevisionColumns = `id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+	attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+	is_active, is_deleted, created_at, updated_at, created_by, updated_by`
+
+// errCINotFound is returned by writes to a CI that does not exist or is deleted
+var errCINotFound = errors.New("CI not found")
+
+// recordCIRevision locks a CI and records its current state as a new
+// revision before a change, in the transaction making the change. Deleted
+// CIs are only found when includeDeleted is set.
+func recordCIRevision(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, action string, changedBy *uuid.UUID, includeDeleted bool) (*models.CI, error) {
+	query := `SELECT ` + ciRevisionColumns + ` FROM configuration_items WHERE id = $1`
+	if !includeDeleted {
+		query += ` AND is_deleted = false`
+	}
+	var current models.CI
+	if err := tx.GetContext(ctx, &current, query+` FOR UPDATE`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errCINotFound
+		}
+		return nil, fmt.Errorf("failed to lock CI: %w", err)
+	}
+
+	snapshot, err := json.Marshal(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CI revision: %w", err)
+	}
+	// The CI row is locked, so revisions of a CI are numbered one at a time
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ci_revisions (ci_id, revision, action, snapshot, changed_by, changed_at)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5
+		FROM ci_revisions WHERE ci_id = $1`,
+		id, action, snapshot, changedBy, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to record CI revision: %w", err)
+	}
+	return &current, nil
+}
+
+// ListCIRevisions retrieves up to limit revisions of a CI, newest first,
+// starting below the before revision when it is set
+func (r *CIRepository) ListCIRevisions(ctx context.Context, ciID uuid.UUID, before *int, limit int) (*models.CIHistory, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one more to tell whether there is a next page
+	args := []interface{}{ciID, before, limit + 1}
+	orgFilter := ""
+	if orgID != nil {
+		args = append(args, *orgID)
+		orgFilter = " AND " + orgCIsCondition("ci_id", len(args))
+	}
+	query := `
+		SELECT ci_id, revision, action, snapshot, changed_by, changed_at
+		FROM ci_revisions
+		WHERE ci_id = $1 AND ($2::integer IS NULL OR revision < $2)` + orgFilter + `
+		ORDER BY revision DESC
+		LIMIT $3`
+
+	revisions := []models.CIRevision{}
+	if err := r.conn(ctx).SelectContext(ctx, &revisions, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list CI revisions: %w", err)
+	}
+
+	history := &models.CIHistory{CIID: ciID, Revisions: revisions}
+	if len(revisions) > limit {
+		history.Revisions = revisions[:limit]
+		next := revisions[limit-1].Revision
+		history.NextBefore = &next
+	}
+	return history, nil
+}
+
+// GetCIRevision retrieves a revision of a CI
+func (r *CIRepository) GetCIRevision(ctx context.Context, ciID uuid.UUID, revision int) (*models.CIRevision, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{ciID, revision}
+	orgFilter := ""
+	if orgID != nil {
+		args = append(args, *orgID)
+		orgFilter = " AND " + orgCIsCondition("ci_id", len(args))
+	}
+	query := `
+		SELECT ci_id, revision, action, snapshot, changed_by, changed_at
+		FROM ci_revisions
+		WHERE ci_id = $1 AND revision = $2` + orgFilter
+
+	var rev models.CIRevision
+	if err := r.conn(ctx).GetContext(ctx, &rev, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCIRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to get CI revision: %w", err)
+	}
+	return &rev, nil
+}
+
+// RestoreCIRevision puts a CI back as it was at a revision, undeleting it
+// when it was deleted since. The state it replaces is recorded as a new
+// revision first, so a restore can itself be undone.
+func (r *CIRepository) RestoreCIRevision(ctx context.Context, ciID uuid.UUID, revision int, changedBy uuid.UUID) (*models.CI, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.conn(ctx).BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkOrgRow(ctx, tx, orgID, "configuration_items", ciID, models.ErrCIRevisionNotFound); err != nil {
+		return nil, err
+	}
+
+	// A CI that no longer exists has no revisions left to restore
+	if _, err := recordCIRevision(ctx, tx, ciID, models.CIRevisionRestore, &changedBy, true); err != nil {
+		if errors.Is(err, errCINotFound) {
+			return nil, models.ErrCIRevisionNotFound
+		}
+		return nil, err
+	}
+
+	var snapshot json.RawMessage
+	err = tx.GetContext(ctx, &snapshot, `SELECT snapshot FROM ci_revisions WHERE ci_id = $1 AND revision = $2`, ciID, revision)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCIRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to get CI revision: %w", err)
+	}
+	var restored models.CI
+	if err := json.Unmarshal(snapshot, &restored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CI revision: %w", err)
+	}
+
+	var ci models.CI
+	err = tx.GetContext(ctx, &ci, `
+		UPDATE configuration_items SET
+			name = $2, type = $3, description = $4, status = $5, criticality = $6, owner = $7, location = $8,
+			org_unit = $9, cost_center = $10, attributes = $11, tags = $12, install_date = $13,
+			warranty_expiry = $14, last_updated = $15, last_scanned = $16, is_active = $17,
+			is_deleted = false, updated_at = $18, updated_by = $19
+		WHERE id = $1
+		RETURNING `+ciRevisionColumns,
+		ciID, restored.Name, restored.Type, restored.Description, restored.Status, restored.Criticality,
+		restored.Owner, restored.Location, restored.OrgUnit, restored.CostCenter, restored.Attributes,
+		pq.Array(restored.Tags), restored.InstallDate, restored.WarrantyExpiry, restored.LastUpdated,
+		restored.LastScanned, restored.IsActive, time.Now(), changedBy)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: %s", models.ErrCIRevisionRestoreConflict, pqErr.Detail)
+		}
+		return nil, fmt.Errorf("failed to restore CI revision: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return &ci, nil
+}
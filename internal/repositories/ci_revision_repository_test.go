@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_CIRevisions(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	userID, ciID := uuid.New(), uuid.New()
+	scenario := &testfixtures.Scenario{
+		Users: []testfixtures.User{{ID: userID, Username: "operator"}},
+		CIs: []testfixtures.CI{{
+			ID: ciID, Name: "web-01", Type: "server",
+			Attributes: map[string]interface{}{"os": "linux"},
+		}},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+	repo := NewCIRepository(db)
+
+	_, err = repo.UpdateCI(ctx, &models.CI{
+		ID: ciID, Name: "web-02", Type: "server", Status: "active",
+		Attributes: json.RawMessage(`{"os": "windows"}`), Tags: []string{}, IsActive: true, UpdatedBy: userID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteCI(ctx, ciID))
+
+	// Deleted CIs are not updated, so no revision is recorded for the attempt
+	_, err = repo.UpdateCI(ctx, &models.CI{ID: ciID, Name: "web-03", Type: "server", Attributes: json.RawMessage(`{}`)})
+	assert.Error(t, err)
+
+	history, err := repo.ListCIRevisions(ctx, ciID, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, history.Revisions, 2)
+	assert.Nil(t, history.NextBefore)
+	deleted, updated := history.Revisions[0], history.Revisions[1]
+	assert.Equal(t, 2, deleted.Revision)
+	assert.Equal(t, models.CIRevisionDelete, deleted.Action)
+	assert.Nil(t, deleted.ChangedBy)
+	assert.Equal(t, 1, updated.Revision)
+	assert.Equal(t, models.CIRevisionUpdate, updated.Action)
+	assert.Equal(t, &userID, updated.ChangedBy)
+
+	// Each revision holds the CI as it was before the change
+	var before models.CI
+	require.NoError(t, json.Unmarshal(updated.CI, &before))
+	assert.Equal(t, "web-01", before.Name)
+	assert.JSONEq(t, `{"os": "linux"}`, string(before.Attributes))
+	require.NoError(t, json.Unmarshal(deleted.CI, &before))
+	assert.Equal(t, "web-02", before.Name)
+	assert.False(t, before.IsDeleted)
+
+	page, err := repo.ListCIRevisions(ctx, ciID, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, page.Revisions, 1)
+	require.NotNil(t, page.NextBefore)
+	assert.Equal(t, 2, *page.NextBefore)
+	page, err = repo.ListCIRevisions(ctx, ciID, page.NextBefore, 1)
+	require.NoError(t, err)
+	require.Len(t, page.Revisions, 1)
+	assert.Equal(t, 1, page.Revisions[0].Revision)
+	assert.Nil(t, page.NextBefore)
+
+	revision, err := repo.GetCIRevision(ctx, ciID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.CIRevisionUpdate, revision.Action)
+	_, err = repo.GetCIRevision(ctx, ciID, 3)
+	assert.ErrorIs(t, err, models.ErrCIRevisionNotFound)
+
+	// Restoring undeletes the CI and records the replaced state first
+	restored, err := repo.RestoreCIRevision(ctx, ciID, 1, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-01", restored.Name)
+	assert.JSONEq(t, `{"os": "linux"}`, string(restored.Attributes))
+	assert.False(t, restored.IsDeleted)
+	assert.Equal(t, userID, restored.UpdatedBy)
+
+	revision, err = repo.GetCIRevision(ctx, ciID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, models.CIRevisionRestore, revision.Action)
+	require.NoError(t, json.Unmarshal(revision.CI, &before))
+	assert.True(t, before.IsDeleted, "the restore can itself be undone")
+
+	_, err = repo.RestoreCIRevision(ctx, ciID, 42, userID)
+	assert.ErrorIs(t, err, models.ErrCIRevisionNotFound)
+	_, err = repo.RestoreCIRevision(ctx, uuid.New(), 1, userID)
+	assert.ErrorIs(t, err, models.ErrCIRevisionNotFound)
+
+	// Another CI took the name the CI had before it was deleted
+	_, err = db.ExecContext(ctx, `UPDATE configuration_items SET name = 'web-04' WHERE id = $1`, ciID)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO configuration_items (id, name, type) VALUES ($1, 'web-02', 'server')`, uuid.New())
+	require.NoError(t, err)
+	_, err = repo.RestoreCIRevision(ctx, ciID, 2, userID)
+	assert.ErrorIs(t, err, models.ErrCIRevisionRestoreConflict)
+
+	history, err = repo.ListCIRevisions(ctx, ciID, nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, history.Revisions, 3, "a failed restore records no revision")
+}
@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"connect/internal/models"
+	"connect/internal/pagination"
+)
+
+// schemaListConditions builds the WHERE and ORDER BY clauses of a schema
+// listing; both schema tables share the filtered columns
+func schemaListConditions(req *models.ListSchemasRequest) (string, string, []interface{}) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	argCount := 1
+
+	if req.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", argCount))
+		args = append(args, "%"+req.Search+"%")
+		argCount++
+	}
+
+	if req.IsActive != nil {
+		conditions = append(conditions, fmt.Sprintf("is_active = $%d", argCount))
+		args = append(args, *req.IsActive)
+		argCount++
+	}
+
+	if req.Attribute != "" {
+		// attributes is an array of definitions, so containment matches any
+		// definition with that name
+		conditions = append(conditions, fmt.Sprintf("attributes @> jsonb_build_array(jsonb_build_object('name', $%d::text))", argCount))
+		args = append(args, req.Attribute)
+		argCount++
+	}
+
+	if req.CreatedBy != nil {
+		conditions = append(conditions, fmt.Sprintf("created_by = $%d", argCount))
+		args = append(args, *req.CreatedBy)
+		argCount++
+	}
+
+	// The sort field was validated against a fixed list; id keeps pages stable
+	// when sorting by a timestamp several schemas share
+	orderBy := fmt.Sprintf("%s %s, id", req.SortBy, strings.ToUpper(req.SortOrder))
+
+	return strings.Join(conditions, " AND "), orderBy, args
+}
+
+// SearchCITypeSchemas retrieves the CI type schemas matching the filters, a page at a time
+func (r *CIRepository) SearchCITypeSchemas(ctx context.Context, req *models.ListSchemasRequest) ([]*models.CITypeSchema, int64, error) {
+	if err := req.Validate(); err != nil {
+		return nil, 0, err
+	}
+	req.PageSize = pagination.FromContext(ctx).PageSize(req.PageSize)
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause, orderBy, args := schemaListConditions(req)
+	whereClause, args = scopeToOrg(orgID, "org_id", whereClause, args)
+
+	var totalCount int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM ci_type_schemas WHERE %s", whereClause)
+	if err := r.conn(ctx).GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count CI type schemas: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
+		FROM ci_type_schemas
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`, whereClause, orderBy, len(args)+1, len(args)+2)
+	args = append(args, req.PageSize, (req.Page-1)*req.PageSize)
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list CI type schemas: %w", err)
+	}
+	defer rows.Close()
+
+	schemas := []*models.CITypeSchema{}
+	for rows.Next() {
+		var schema models.CITypeSchema
+		var attributes []byte
+		if err := rows.Scan(&schema.ID, &schema.Name, &schema.Description, &attributes, &schema.IsActive,
+			&schema.CreatedAt, &schema.UpdatedAt, &schema.CreatedBy, &schema.UpdatedBy, &schema.OrgID); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan CI type schema: %w", err)
+		}
+		if err := json.Unmarshal(attributes, &schema.Attributes); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal attributes: %w", err)
+		}
+		schemas = append(schemas, &schema)
+	}
+
+	return schemas, totalCount, nil
+}
+
+// SearchRelationshipTypeSchemas retrieves the relationship type schemas matching the filters, a page at a time
+func (r *CIRepository) SearchRelationshipTypeSchemas(ctx context.Context, req *models.ListSchemasRequest) ([]*models.RelationshipTypeSchema, int64, error) {
+	if err := req.Validate(); err != nil {
+		return nil, 0, err
+	}
+	req.PageSize = pagination.FromContext(ctx).PageSize(req.PageSize)
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause, orderBy, args := schemaListConditions(req)
+	whereClause, args = scopeToOrg(orgID, "org_id", whereClause, args)
+
+	var totalCount int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM relationship_type_schemas WHERE %s", whereClause)
+	if err := r.conn(ctx).GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count relationship type schemas: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name, description, attributes, is_active, created_at, updated_at, created_by, updated_by, org_id
+		FROM relationship_type_schemas
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`, whereClause, orderBy, len(args)+1, len(args)+2)
+	args = append(args, req.PageSize, (req.Page-1)*req.PageSize)
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list relationship type schemas: %w", err)
+	}
+	defer rows.Close()
+
+	schemas := []*models.RelationshipTypeSchema{}
+	for rows.Next() {
+		var schema models.RelationshipTypeSchema
+		var attributes []byte
+		if err := rows.Scan(&schema.ID, &schema.Name, &schema.Description, &attributes, &schema.IsActive,
+			&schema.CreatedAt, &schema.UpdatedAt, &schema.CreatedBy, &schema.UpdatedBy, &schema.OrgID); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan relationship type schema: %w", err)
+		}
+		if err := json.Unmarshal(attributes, &schema.Attributes); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal attributes: %w", err)
+		}
+		schemas = append(schemas, &schema)
+	}
+
+	return schemas, totalCount, nil
+}
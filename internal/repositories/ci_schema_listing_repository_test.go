@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaListConditions(t *testing.T) {
+	req := &models.ListSchemasRequest{}
+	require.NoError(t, req.Validate())
+	whereClause, orderBy, args := schemaListConditions(req)
+	assert.Equal(t, "TRUE", whereClause)
+	assert.Equal(t, "name ASC, id", orderBy)
+	assert.Empty(t, args)
+
+	active, creator := false, uuid.New()
+	req = &models.ListSchemasRequest{Search: "serv", IsActive: &active, Attribute: "ip_address", CreatedBy: &creator, SortBy: "updated_at", SortOrder: "desc"}
+	require.NoError(t, req.Validate())
+	whereClause, orderBy, args = schemaListConditions(req)
+	assert.Equal(t, "TRUE AND name ILIKE $1 AND is_active = $2"+
+		" AND attributes @> jsonb_build_array(jsonb_build_object('name', $3::text)) AND created_by = $4", whereClause)
+	assert.Equal(t, "updated_at DESC, id", orderBy)
+	assert.Equal(t, []interface{}{"%serv%", false, "ip_address", creator}, args)
+}
+
+func TestCIRepository_SearchCITypeSchemas(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	creator := uuid.New()
+	// The fixture schema has no default schemas, so these are all there is
+	for _, schema := range []struct {
+		name       string
+		attributes string
+		active     bool
+		createdBy  *uuid.UUID
+	}{
+		{"server", `[{"name": "ip_address", "type": "string"}, {"name": "cpu_cores", "type": "number"}]`, true, &creator},
+		{"virtual_server", `[{"name": "ip_address", "type": "string"}]`, false, nil},
+		{"network_switch", `[{"name": "port_count", "type": "number"}]`, true, &creator},
+	} {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO ci_type_schemas (id, name, attributes, is_active, created_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`,
+			uuid.New(), schema.name, schema.attributes, schema.active, schema.createdBy)
+		require.NoError(t, err)
+	}
+
+	repo := NewCIRepository(db)
+	search := func(req *models.ListSchemasRequest) ([]string, int64) {
+		schemas, total, err := repo.SearchCITypeSchemas(ctx, req)
+		require.NoError(t, err)
+		var names []string
+		for _, schema := range schemas {
+			names = append(names, schema.Name)
+		}
+		return names, total
+	}
+
+	names, total := search(&models.ListSchemasRequest{})
+	assert.Equal(t, []string{"network_switch", "server", "virtual_server"}, names)
+	assert.Equal(t, int64(3), total)
+
+	names, _ = search(&models.ListSchemasRequest{Search: "SERVER"})
+	assert.Equal(t, []string{"server", "virtual_server"}, names, "search ignores case")
+
+	active := true
+	names, _ = search(&models.ListSchemasRequest{IsActive: &active, SortOrder: models.SortOrderDesc})
+	assert.Equal(t, []string{"server", "network_switch"}, names)
+
+	names, _ = search(&models.ListSchemasRequest{Attribute: "ip_address"})
+	assert.Equal(t, []string{"server", "virtual_server"}, names)
+
+	names, _ = search(&models.ListSchemasRequest{CreatedBy: &creator, Attribute: "port_count"})
+	assert.Equal(t, []string{"network_switch"}, names)
+
+	// Pages count every match, not just the page
+	names, total = search(&models.ListSchemasRequest{Page: 2, PageSize: 2})
+	assert.Equal(t, []string{"virtual_server"}, names)
+	assert.Equal(t, int64(3), total)
+
+	_, _, err = repo.SearchCITypeSchemas(ctx, &models.ListSchemasRequest{SortBy: "name; DROP TABLE users"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// GetCITypeSchemaUsage reports how the live CIs of a schema's type use its
+// attributes. An attribute explicitly set to null counts as unset.
+func (r *CIRepository) GetCITypeSchemaUsage(ctx context.Context, schema *models.CITypeSchema) (*models.SchemaUsage, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	orgFilter, args := andOrgCondition(orgID, "org_id", []interface{}{schema.Name})
+	err = r.conn(ctx).GetContext(ctx, &total, `
+		SELECT COUNT(*) FROM configuration_items WHERE type = $1 AND is_deleted = false`+orgFilter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count CIs of type: %w", err)
+	}
+
+	orgFilter, args = andOrgCondition(orgID, "ci.org_id", []interface{}{schema.Name})
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT attr.key, COUNT(*)
+		FROM configuration_items ci
+		CROSS JOIN LATERAL jsonb_each(
+			CASE WHEN jsonb_typeof(ci.attributes) = 'object' THEN ci.attributes ELSE '{}'::jsonb END
+		) AS attr
+		WHERE ci.type = $1 AND ci.is_deleted = false AND attr.value <> 'null'::jsonb`+orgFilter+`
+		GROUP BY attr.key`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attribute usage: %w", err)
+	}
+	defer rows.Close()
+
+	setCounts := map[string]int64{}
+	for rows.Next() {
+		var name string
+		var count int64
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute usage: %w", err)
+		}
+		setCounts[name] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count attribute usage: %w", err)
+	}
+
+	valueCounts := map[string]map[string]int64{}
+	for _, attr := range schema.Attributes {
+		if attr.EnumValues() == nil || setCounts[attr.Name] == 0 {
+			continue
+		}
+		counts, err := r.attributeValueCounts(ctx, orgID, schema.Name, attr.Name)
+		if err != nil {
+			return nil, err
+		}
+		valueCounts[attr.Name] = counts
+	}
+
+	return models.NewSchemaUsage(schema, total, setCounts, valueCounts), nil
+}
+
+// attributeValueCounts counts the CIs of a type an organization may see per
+// value of an attribute; each element of an array attribute counts as a value
+func (r *CIRepository) attributeValueCounts(ctx context.Context, orgID *uuid.UUID, ciType, attribute string) (map[string]int64, error) {
+	orgFilter, args := andOrgCondition(orgID, "ci.org_id", []interface{}{ciType, attribute, models.MaxUsageValues})
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT value, COUNT(*)
+		FROM configuration_items ci
+		CROSS JOIN LATERAL jsonb_array_elements_text(
+			CASE WHEN jsonb_typeof(ci.attributes->$2) = 'array' THEN ci.attributes->$2
+			     ELSE jsonb_build_array(ci.attributes->$2) END
+		) AS value
+		WHERE ci.type = $1 AND ci.is_deleted = false
+		  AND jsonb_typeof(ci.attributes) = 'object' AND ci.attributes ? $2
+		  AND ci.attributes->$2 <> 'null'::jsonb`+orgFilter+`
+		GROUP BY value
+		ORDER BY COUNT(*) DESC, value
+		LIMIT $3`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count values of attribute %s: %w", attribute, err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var value string
+		var count int64
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute value count: %w", err)
+		}
+		counts[value] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count values of attribute %s: %w", attribute, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_GetCITypeSchemaUsage(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{Name: "web-01", Type: "server", Attributes: map[string]interface{}{"hostname": "web-01", "environment": "prod", "roles": []string{"web", "cache"}}},
+			{Name: "web-02", Type: "server", Attributes: map[string]interface{}{"hostname": "web-02", "environment": "qa", "legacy_id": 7}},
+			{Name: "web-03", Type: "server", Attributes: map[string]interface{}{"hostname": "web-03", "environment": nil}},
+			{Name: "web-04", Type: "server", Attributes: map[string]interface{}{"environment": "prod"}},
+			{Name: "old-01", Type: "server", Attributes: map[string]interface{}{"hostname": "old-01", "environment": "prod"}},
+			{Name: "db-01", Type: "database", Attributes: map[string]interface{}{"hostname": "db-01", "environment": "prod"}},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+	_, err = db.ExecContext(ctx, `UPDATE configuration_items SET is_deleted = true WHERE id = $1`, scenario.CIID("old-01"))
+	require.NoError(t, err)
+
+	schema := &models.CITypeSchema{
+		Name: "server",
+		Attributes: []models.CITypeAttribute{
+			{Name: "hostname", Type: models.AttributeTypeString, Required: true},
+			{Name: "environment", Type: models.AttributeTypeString, Validation: map[string]interface{}{"enum": []interface{}{"prod", "dev"}}},
+			{Name: "roles", Type: models.AttributeTypeArray, Validation: map[string]interface{}{"enum": []interface{}{"web", "db"}}},
+		},
+	}
+
+	usage, err := NewCIRepository(db).GetCITypeSchemaUsage(ctx, schema)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), usage.TotalCIs, "deleted CIs and other types are not counted")
+
+	require.Len(t, usage.Attributes, 3)
+	assert.Equal(t, int64(3), usage.Attributes[0].SetCount)
+	assert.Equal(t, int64(3), usage.Attributes[1].SetCount, "null values count as unset")
+	assert.Equal(t, []models.AttributeValueCount{
+		{Value: "prod", Count: 2, InEnum: true},
+		{Value: "dev", InEnum: true},
+		{Value: "qa", Count: 1},
+	}, usage.Attributes[1].Values)
+	assert.Equal(t, []models.AttributeValueCount{
+		{Value: "web", Count: 1, InEnum: true},
+		{Value: "db", InEnum: true},
+		{Value: "cache", Count: 1},
+	}, usage.Attributes[2].Values, "each array element counts as a value")
+
+	assert.Equal(t, []models.UndeclaredAttribute{{Name: "legacy_id", SetCount: 1}}, usage.Undeclared)
+}
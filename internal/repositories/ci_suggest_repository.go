@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"connect/internal/models"
+)
+
+// likeEscaper escapes the LIKE wildcards of a query so they match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SuggestCIs returns live CIs whose name starts with the query, in name
+// order, followed for longer queries by those containing it, closest first.
+// Prefixes are read in order from idx_cis_name_prefix, so they stop after
+// the limit, and substrings are found with idx_cis_name_trgm.
+func (r *CIRepository) SuggestCIs(ctx context.Context, req *models.SuggestCIsRequest) ([]models.CISuggestion, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := strings.ToLower(req.Query)
+	substring := len([]rune(query)) >= models.MinSubstringSuggestLength
+	args := []interface{}{likeEscaper.Replace(query)}
+	if substring {
+		args = append(args, query)
+	}
+	conditions := []string{"is_deleted = false"}
+	if req.Type != "" {
+		args = append(args, req.Type)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+	}
+	if req.Scope != nil {
+		condition, scopeArgs := visibilityCondition(req.Scope, len(args)+1)
+		conditions = append(conditions, condition)
+		args = append(args, scopeArgs...)
+	}
+	if orgID != nil {
+		args = append(args, *orgID)
+		conditions = append(conditions, orgCondition("org_id", len(args)))
+	}
+	args = append(args, req.Limit)
+	where := strings.Join(conditions, " AND ")
+	limit := fmt.Sprintf("$%d", len(args))
+
+	branches := `
+		(SELECT id, name, type, 0 AS rank, row_number() OVER (ORDER BY LOWER(name) COLLATE "C") AS position
+		 FROM configuration_items
+		 WHERE LOWER(name) COLLATE "C" LIKE $1 || '%' AND ` + where + `
+		 ORDER BY LOWER(name) COLLATE "C"
+		 LIMIT ` + limit + `)`
+	if substring {
+		branches += `
+		UNION ALL
+		(SELECT id, name, type, 1 AS rank, row_number() OVER (ORDER BY similarity(LOWER(name), $2) DESC, name) AS position
+		 FROM configuration_items
+		 WHERE LOWER(name) LIKE '%' || $1 || '%' AND LOWER(name) NOT LIKE $1 || '%' AND ` + where + `
+		 ORDER BY similarity(LOWER(name), $2) DESC, name
+		 LIMIT ` + limit + `)`
+	}
+	sql := `SELECT id, name, type FROM (` + branches + `
+		) AS suggestions
+		ORDER BY rank, position
+		LIMIT ` + limit
+
+	suggestions := []models.CISuggestion{}
+	if err := r.conn(ctx).SelectContext(ctx, &suggestions, sql, args...); err != nil {
+		return nil, fmt.Errorf("failed to suggest CIs: %w", err)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetCISummaries loads the ID, name, type, status and criticality of several
+// CIs in one query. Deleted and unknown CIs are left out.
+func (r *CIRepository) GetCISummaries(ctx context.Context, ids []uuid.UUID) ([]models.CISummary, error) {
+	summaries := []models.CISummary{}
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	params := make([]string, len(ids))
+	for i, id := range ids {
+		params[i] = id.String()
+	}
+
+	query := `
+		SELECT id, name, type, status, criticality
+		FROM configuration_items
+		WHERE id = ANY($1::uuid[]) AND is_deleted = false`
+
+	if err := r.conn(ctx).SelectContext(ctx, &summaries, query, pq.Array(params)); err != nil {
+		return nil, fmt.Errorf("failed to get CI summaries: %w", err)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// topologyJoins are the conditions extending a dependency graph by an edge,
+// and the CI reached, per direction
+var topologyJoins = map[string][2]string{
+	models.GraphDirectionUpstream:   {"e.target_ci_id = g.id", "e.source_ci_id"},
+	models.GraphDirectionDownstream: {"e.source_ci_id = g.id", "e.target_ci_id"},
+	models.GraphDirectionBoth: {"(e.source_ci_id = g.id OR e.target_ci_id = g.id)",
+		"CASE WHEN e.source_ci_id = g.id THEN e.target_ci_id ELSE e.source_ci_id END"},
+}
+
+// GetGraphAsOf reconstructs the dependency graph around a CI as it was at
+// asOf from the relationship versions: the CIs at most maxDepth hops away in
+// direction through relationships active then, and the active relationships
+// between them. CIs are loaded as they are now, deleted ones included.
+func (r *CIRepository) GetGraphAsOf(ctx context.Context, ciID uuid.UUID, asOf time.Time, direction string, maxDepth int) ([]models.CI, []models.RelationshipVersion, error) {
+	join, ok := topologyJoins[direction]
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid graph direction: %s", direction)
+	}
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Versions carry no organization, so they are narrowed by their CIs
+	args := []interface{}{ciID, asOf, models.RelationshipStateActive, maxDepth}
+	edgeFilter := ""
+	if orgID != nil {
+		args = append(args, *orgID)
+		edgeFilter = " AND " + orgCIsCondition("source_ci_id", len(args)) + " AND " + orgCIsCondition("target_ci_id", len(args))
+	}
+
+	var ids []string
+	err = r.conn(ctx).SelectContext(ctx, &ids, fmt.Sprintf(`
+		WITH RECURSIVE edges AS (
+			SELECT source_ci_id, target_ci_id
+			FROM ci_relationship_versions
+			WHERE valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+			  AND is_active = true AND state = $3%s
+		), graph(id, depth) AS (
+			SELECT $1::uuid, 0
+			UNION
+			SELECT %s, g.depth + 1
+			FROM edges e
+			JOIN graph g ON %s
+			WHERE g.depth < $4
+		)
+		SELECT DISTINCT id::text FROM graph`, edgeFilter, join[1], join[0]),
+		args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to traverse relationship history: %w", err)
+	}
+
+	orgFilter, ciArgs := andOrgCondition(orgID, "org_id", []interface{}{pq.Array(ids)})
+	cis := []models.CI{}
+	err = r.conn(ctx).SelectContext(ctx, &cis, `
+		SELECT id, name, type, description, status, criticality, owner, location, org_unit, cost_center,
+		       attributes, tags, install_date, warranty_expiry, last_updated, last_scanned,
+		       is_active, is_deleted, created_at, updated_at, created_by, updated_by, org_id
+		FROM configuration_items
+		WHERE id = ANY($1::uuid[])`+orgFilter, ciArgs...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get CIs: %w", err)
+	}
+
+	// The CI the graph starts from is among the IDs even when it is not
+	// visible, so its versions are only kept between visible CIs
+	if orgID != nil {
+		ids = ids[:0]
+		for _, ci := range cis {
+			ids = append(ids, ci.ID.String())
+		}
+	}
+
+	versions := []models.RelationshipVersion{}
+	err = r.conn(ctx).SelectContext(ctx, &versions, `
+		SELECT relationship_id, source_ci_id, target_ci_id, type, attributes, COALESCE(description, '') AS description,
+		       is_active, state, is_primary, valid_from, valid_to, changed_by, backfilled
+		FROM ci_relationship_versions
+		WHERE source_ci_id = ANY($1::uuid[]) AND target_ci_id = ANY($1::uuid[])
+		  AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+		  AND is_active = true AND state = $3
+		ORDER BY relationship_id`, pq.Array(ids), asOf, models.RelationshipStateActive)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get relationship versions: %w", err)
+	}
+
+	return cis, versions, nil
+}
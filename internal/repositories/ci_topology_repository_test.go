@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_GetGraphAsOf(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	shopID, webID, dbID, cacheID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	scenario := &testfixtures.Scenario{
+		CIs: []testfixtures.CI{
+			{ID: shopID, Name: "shop", Type: "application"},
+			{ID: webID, Name: "web-01", Type: "server"},
+			{ID: dbID, Name: "db-01", Type: "database"},
+			{ID: cacheID, Name: "cache-01", Type: "cache"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+
+	// The shop has run on web-01 all along, depended on db-01 until five
+	// days ago, and web-01 has used cache-01 for two days
+	runsOn, usedDB, usesCache := uuid.New(), uuid.New(), uuid.New()
+	for _, version := range []struct {
+		id             uuid.UUID
+		source, target uuid.UUID
+		typ            string
+		validFrom      string
+		validTo        *string
+		backfilled     bool
+	}{
+		{runsOn, shopID, webID, "runs_on", "10 days", nil, true},
+		{usedDB, shopID, dbID, "depends_on", "10 days", stringPtr("5 days"), false},
+		{usesCache, webID, cacheID, "depends_on", "2 days", nil, false},
+	} {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO ci_relationship_versions (relationship_id, source_ci_id, target_ci_id, type, state,
+				valid_from, valid_to, backfilled)
+			VALUES ($1, $2, $3, $4, 'active', NOW() - $5::interval, NOW() - $6::interval, $7)`,
+			version.id, version.source, version.target, version.typ, version.validFrom, version.validTo, version.backfilled)
+		require.NoError(t, err)
+	}
+
+	repo := NewCIRepository(db)
+	ciIDs := func(cis []models.CI) []uuid.UUID {
+		ids := make([]uuid.UUID, 0, len(cis))
+		for _, ci := range cis {
+			ids = append(ids, ci.ID)
+		}
+		return ids
+	}
+	relationshipIDs := func(versions []models.RelationshipVersion) []uuid.UUID {
+		ids := make([]uuid.UUID, 0, len(versions))
+		for _, version := range versions {
+			ids = append(ids, version.RelationshipID)
+		}
+		return ids
+	}
+
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+	cis, versions, err := repo.GetGraphAsOf(ctx, shopID, weekAgo, models.GraphDirectionDownstream, 3)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{shopID, webID, dbID}, ciIDs(cis))
+	assert.ElementsMatch(t, []uuid.UUID{runsOn, usedDB}, relationshipIDs(versions))
+
+	cis, versions, err = repo.GetGraphAsOf(ctx, shopID, time.Now(), models.GraphDirectionDownstream, 3)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{shopID, webID, cacheID}, ciIDs(cis))
+	assert.ElementsMatch(t, []uuid.UUID{runsOn, usesCache}, relationshipIDs(versions))
+	for _, version := range versions {
+		assert.Equal(t, version.RelationshipID == runsOn, version.Backfilled)
+	}
+
+	cis, versions, err = repo.GetGraphAsOf(ctx, shopID, time.Now(), models.GraphDirectionDownstream, 1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{shopID, webID}, ciIDs(cis), "depth bounds the hops followed")
+	assert.ElementsMatch(t, []uuid.UUID{runsOn}, relationshipIDs(versions))
+
+	cis, _, err = repo.GetGraphAsOf(ctx, cacheID, time.Now(), models.GraphDirectionUpstream, 3)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{cacheID, webID, shopID}, ciIDs(cis))
+
+	cis, _, err = repo.GetGraphAsOf(ctx, webID, weekAgo, models.GraphDirectionBoth, 1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{webID, shopID}, ciIDs(cis), "cache-01 was not used yet")
+
+	// Relationships created now are versioned by the trigger
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO ci_relationships (id, source_ci_id, target_ci_id, type)
+		VALUES ($1, $2, $3, 'depends_on')`, uuid.New(), cacheID, dbID)
+	require.NoError(t, err)
+	cis, _, err = repo.GetGraphAsOf(ctx, shopID, time.Now(), models.GraphDirectionDownstream, 3)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{shopID, webID, cacheID, dbID}, ciIDs(cis))
+
+	_, _, err = repo.GetGraphAsOf(ctx, shopID, time.Now(), "sideways", 3)
+	assert.Error(t, err)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
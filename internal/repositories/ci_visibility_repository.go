@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"connect/internal/models"
+	"github.com/lib/pq"
+)
+
+// visibilityCondition returns the SQL form of models.VisibilityScope.Allows for
+// configuration_items, numbering its placeholders from argCount
+func visibilityCondition(scope *models.VisibilityScope, argCount int) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if scope.Tenant != "" {
+		conditions = append(conditions, fmt.Sprintf("COALESCE(attributes->>'%s', '') IN ('', $%d)", models.TenantAttribute, argCount))
+		args = append(args, scope.Tenant)
+		argCount++
+	}
+
+	if !scope.Unrestricted {
+		var grants []string
+		for _, grant := range []struct {
+			expr   string
+			values []string
+		}{
+			{"type = ANY($%d)", scope.Types},
+			{"tags && $%d", scope.Tags},
+			{"owner = ANY($%d)", scope.Owners},
+			{"location = ANY($%d)", scope.Locations},
+		} {
+			if len(grant.values) == 0 {
+				continue
+			}
+			grants = append(grants, fmt.Sprintf(grant.expr, argCount))
+			args = append(args, pq.Array(grant.values))
+			argCount++
+		}
+
+		if len(grants) == 0 {
+			conditions = append(conditions, "FALSE")
+		} else {
+			conditions = append(conditions, "("+strings.Join(grants, " OR ")+")")
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "TRUE", nil
+	}
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrUnknownOrganization is returned when a request acts for an organization
+// slug that does not exist, so that it sees nothing rather than everything
+var ErrUnknownOrganization = errors.New("unknown organization")
+
+// orgLookup resolves the ID of the organization with a slug
+type orgLookup func(ctx context.Context, slug string) (uuid.UUID, error)
+
+// orgResolver resolves the organization a request acts for to the org_id its
+// rows are scoped by. Slugs never change, so resolved IDs are cached.
+type orgResolver struct {
+	lookup orgLookup
+	ids    sync.Map
+}
+
+func newOrgResolver(lookup orgLookup) *orgResolver {
+	return &orgResolver{lookup: lookup}
+}
+
+// orgID returns the ID of the organization the context acts for, nil when it
+// acts for none
+func (o *orgResolver) orgID(ctx context.Context) (*uuid.UUID, error) {
+	slug := visibility.TenantFromContext(ctx)
+	if slug == "" {
+		return nil, nil
+	}
+	if id, ok := o.ids.Load(slug); ok {
+		orgID := id.(uuid.UUID)
+		return &orgID, nil
+	}
+
+	orgID, err := o.lookup(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	o.ids.Store(slug, orgID)
+	return &orgID, nil
+}
+
+const orgBySlugQuery = `SELECT id FROM organizations WHERE slug = $1`
+
+// sqlxOrgLookup looks organizations up in the main database; residency shards
+// do not hold the organizations table
+func sqlxOrgLookup(db *sqlx.DB) orgLookup {
+	return func(ctx context.Context, slug string) (uuid.UUID, error) {
+		var id uuid.UUID
+		if err := db.GetContext(ctx, &id, orgBySlugQuery, slug); err != nil {
+			if err == sql.ErrNoRows {
+				return uuid.Nil, fmt.Errorf("%w: %s", ErrUnknownOrganization, slug)
+			}
+			return uuid.Nil, fmt.Errorf("failed to resolve organization: %w", err)
+		}
+		return id, nil
+	}
+}
+
+// pgxOrgLookup looks organizations up through a pgx pool
+func pgxOrgLookup(pool *pgxpool.Pool) orgLookup {
+	return func(ctx context.Context, slug string) (uuid.UUID, error) {
+		var id uuid.UUID
+		if err := pool.QueryRow(ctx, orgBySlugQuery, slug).Scan(&id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return uuid.Nil, fmt.Errorf("%w: %s", ErrUnknownOrganization, slug)
+			}
+			return uuid.Nil, fmt.Errorf("failed to resolve organization: %w", err)
+		}
+		return id, nil
+	}
+}
+
+// orgCondition matches the rows of an organization and the shared rows, which
+// have no org_id, numbering its placeholder argCount
+func orgCondition(column string, argCount int) string {
+	return fmt.Sprintf("(%[1]s IS NULL OR %[1]s = $%[2]d)", column, argCount)
+}
+
+// andOrgCondition returns the condition narrowing a fixed query to the rows
+// an organization may see, prefixed with AND, appending the organization's
+// ID to args. It is empty when the request acts for no organization.
+func andOrgCondition(orgID *uuid.UUID, column string, args []interface{}) (string, []interface{}) {
+	if orgID == nil {
+		return "", args
+	}
+	args = append(args, *orgID)
+	return " AND " + orgCondition(column, len(args)), args
+}
+
+// orgCIsCondition matches the rows of a table without org_id, such as
+// relationship versions, whose CI in column an organization may see
+func orgCIsCondition(column string, argCount int) string {
+	return fmt.Sprintf("%s IN (SELECT id FROM configuration_items WHERE %s)", column, orgCondition("org_id", argCount))
+}
+
+// scopeToOrg narrows a WHERE clause to the rows an organization may see. It
+// leaves the clause as it is when the request acts for no organization.
+func scopeToOrg(orgID *uuid.UUID, column, whereClause string, args []interface{}) (string, []interface{}) {
+	if orgID == nil {
+		return whereClause, args
+	}
+	return whereClause + " AND " + orgCondition(column, len(args)+1), append(args, *orgID)
+}
+
+// orgUserCondition matches the users created in an organization and its
+// members, numbering its placeholder argCount. Users are never shared.
+func orgUserCondition(argCount int) string {
+	return fmt.Sprintf("(org_id = $%[1]d OR id IN (SELECT user_id FROM organization_members WHERE organization_id = $%[1]d))", argCount)
+}
+
+// scopeToOwnOrg narrows a WHERE clause to the rows an organization owns, for
+// changes to definitions it shares with no one else. Shared rows stay
+// read-only to organizations.
+func scopeToOwnOrg(orgID *uuid.UUID, column, whereClause string, args []interface{}) (string, []interface{}) {
+	if orgID == nil {
+		return whereClause, args
+	}
+	return whereClause + fmt.Sprintf(" AND %s = $%d", column, len(args)+1), append(args, *orgID)
+}
+
+// checkOrgRow returns notFound when the row of a table with an ID is not
+// visible to an organization, for writes whose own WHERE clause is fixed
+func checkOrgRow(ctx context.Context, q sqlx.QueryerContext, orgID *uuid.UUID, table string, id uuid.UUID, notFound error) error {
+	return checkRowCondition(ctx, q, orgID, table, id, orgCondition("org_id", 2), notFound)
+}
+
+// checkOwnOrgRow returns notFound when the row of a table with an ID is not
+// owned by an organization
+func checkOwnOrgRow(ctx context.Context, q sqlx.QueryerContext, orgID *uuid.UUID, table string, id uuid.UUID, notFound error) error {
+	return checkRowCondition(ctx, q, orgID, table, id, "org_id = $2", notFound)
+}
+
+func checkRowCondition(ctx context.Context, q sqlx.QueryerContext, orgID *uuid.UUID, table string, id uuid.UUID, condition string, notFound error) error {
+	if orgID == nil {
+		return nil
+	}
+
+	var matches bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND %s)", table, condition)
+	if err := sqlx.GetContext(ctx, q, &matches, query, id, *orgID); err != nil {
+		return fmt.Errorf("failed to check organization of %s: %w", table, err)
+	}
+	if !matches {
+		return notFound
+	}
+	return nil
+}
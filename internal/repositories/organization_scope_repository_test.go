@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgResolver(t *testing.T) {
+	acme := uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	lookups := 0
+	resolver := newOrgResolver(func(ctx context.Context, slug string) (uuid.UUID, error) {
+		lookups++
+		if slug == "acme" {
+			return acme, nil
+		}
+		return uuid.Nil, fmt.Errorf("%w: %s", ErrUnknownOrganization, slug)
+	})
+
+	// Requests acting for no organization are not scoped
+	orgID, err := resolver.orgID(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, orgID)
+	assert.Equal(t, 0, lookups)
+
+	ctx := visibility.WithTenant(context.Background(), "acme")
+	for i := 0; i < 2; i++ {
+		orgID, err = resolver.orgID(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, &acme, orgID)
+	}
+	assert.Equal(t, 1, lookups, "slugs never change, so their IDs are cached")
+
+	// An unknown organization sees nothing rather than everything
+	_, err = resolver.orgID(visibility.WithTenant(context.Background(), "globex"))
+	assert.ErrorIs(t, err, ErrUnknownOrganization)
+}
+
+func TestScopeToOrg(t *testing.T) {
+	acme := uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+
+	whereClause, args := scopeToOrg(nil, "org_id", "id = $1", []interface{}{"ci"})
+	assert.Equal(t, "id = $1", whereClause)
+	assert.Equal(t, []interface{}{"ci"}, args)
+
+	whereClause, args = scopeToOrg(&acme, "rel.org_id", "id = $1", []interface{}{"ci"})
+	assert.Equal(t, "id = $1 AND (rel.org_id IS NULL OR rel.org_id = $2)", whereClause)
+	assert.Equal(t, []interface{}{"ci", acme}, args)
+
+	condition, args := andOrgCondition(nil, "org_id", []interface{}{"ci"})
+	assert.Empty(t, condition)
+	assert.Equal(t, []interface{}{"ci"}, args)
+	condition, args = andOrgCondition(&acme, "c.org_id", []interface{}{"ci"})
+	assert.Equal(t, " AND (c.org_id IS NULL OR c.org_id = $2)", condition)
+	assert.Equal(t, []interface{}{"ci", acme}, args)
+	assert.Equal(t,
+		"v.source_ci_id IN (SELECT id FROM configuration_items WHERE (org_id IS NULL OR org_id = $4))",
+		orgCIsCondition("v.source_ci_id", 4))
+
+	// Shared rows are left out of changes made for an organization
+	whereClause, args = scopeToOwnOrg(&acme, "org_id", "id = $1", []interface{}{"schema"})
+	assert.Equal(t, "id = $1 AND org_id = $2", whereClause)
+	assert.Equal(t, []interface{}{"schema", acme}, args)
+
+	whereClause, _ = scopeToOwnOrg(nil, "org_id", "id = $1", []interface{}{"schema"})
+	assert.Equal(t, "id = $1", whereClause)
+
+	assert.Equal(t,
+		"(org_id = $3 OR id IN (SELECT user_id FROM organization_members WHERE organization_id = $3))",
+		orgUserCondition(3))
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"connect/internal/auth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RefreshTokenRepository stores refresh token families and security events
+type RefreshTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(pool *pgxpool.Pool) *RefreshTokenRepository {
+	return &RefreshTokenRepository{pool: pool}
+}
+
+// CreateRefreshToken records an issued refresh token
+func (r *RefreshTokenRepository) CreateRefreshToken(ctx context.Context, record *auth.RefreshTokenRecord) error {
+	query := `
+		INSERT INTO refresh_tokens (id, family_id, user_id, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.pool.Exec(ctx, query, record.ID, record.FamilyID, record.UserID, record.IssuedAt, record.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token record by its jti
+func (r *RefreshTokenRepository) GetRefreshToken(ctx context.Context, id uuid.UUID) (*auth.RefreshTokenRecord, error) {
+	query := `
+		SELECT id, family_id, user_id, issued_at, expires_at, rotated_at, replaced_by, revoked_at, COALESCE(revoke_reason, '')
+		FROM refresh_tokens WHERE id = $1
+	`
+
+	record := &auth.RefreshTokenRecord{}
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&record.ID, &record.FamilyID, &record.UserID, &record.IssuedAt, &record.ExpiresAt,
+		&record.RotatedAt, &record.ReplacedBy, &record.RevokedAt, &record.RevokeReason,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, auth.ErrRefreshTokenUnknown
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return record, nil
+}
+
+// MarkRefreshTokenRotated marks a token as replaced. The update only matches a
+// token that is neither rotated nor revoked, so concurrent refreshes with the
+// same token cannot both succeed.
+func (r *RefreshTokenRepository) MarkRefreshTokenRotated(ctx context.Context, id, replacedBy uuid.UUID, at time.Time) (bool, error) {
+	query := `
+		UPDATE refresh_tokens SET
+			rotated_at = $1,
+			replaced_by = $2
+		WHERE id = $3 AND rotated_at IS NULL AND revoked_at IS NULL
+	`
+
+	tag, err := r.pool.Exec(ctx, query, at, replacedBy, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token descending from the same login
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID, reason string, at time.Time) (int, error) {
+	query := `
+		UPDATE refresh_tokens SET
+			revoked_at = $1,
+			revoke_reason = $2
+		WHERE family_id = $3 AND revoked_at IS NULL
+	`
+
+	tag, err := r.pool.Exec(ctx, query, at, reason, familyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// RecordSecurityEvent stores a security event
+func (r *RefreshTokenRepository) RecordSecurityEvent(ctx context.Context, event auth.SecurityEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal security event details: %w", err)
+	}
+
+	var ipAddress *string
+	if event.IPAddress != "" {
+		ipAddress = &event.IPAddress
+	}
+
+	query := `
+		INSERT INTO security_events (id, event_type, user_id, details, ip_address, user_agent, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = r.pool.Exec(ctx, query, uuid.New(), event.Type, event.UserID, details, ipAddress, event.UserAgent, event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to record security event: %w", err)
+	}
+
+	return nil
+}
+
+// CleanupExpiredRefreshTokens deletes refresh tokens that expired before the given time
+func (r *RefreshTokenRepository) CleanupExpiredRefreshTokens(ctx context.Context, before time.Time) (int, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up refresh tokens: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
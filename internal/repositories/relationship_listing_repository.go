@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"connect/internal/impact"
+	"connect/internal/models"
+	"github.com/lib/pq"
+)
+
+// relationshipListConditions builds the WHERE clause of a relationship
+// listing and its arguments
+func relationshipListConditions(req *models.ListRelationshipsRequest) (string, []interface{}) {
+	state := req.State
+	if state == "" {
+		state = models.RelationshipStateActive
+	}
+	whereConditions := []string{"state = $1"}
+	args := []interface{}{state}
+	argCount := 2
+
+	for _, filter := range req.Attributes {
+		condition, filterArgs := attributeFilterCondition(filter, argCount)
+		whereConditions = append(whereConditions, condition)
+		args = append(args, filterArgs...)
+		argCount += len(filterArgs)
+	}
+
+	// Filtering here rather than after loading keeps the total count from
+	// revealing edges the caller may not see
+	if req.Scope != nil {
+		for _, column := range []string{"source_ci_id", "target_ci_id"} {
+			condition, scopeArgs := visibilityCondition(req.Scope, argCount)
+			whereConditions = append(whereConditions, fmt.Sprintf("%s IN (SELECT id FROM configuration_items WHERE %s)", column, condition))
+			args = append(args, scopeArgs...)
+			argCount += len(scopeArgs)
+		}
+	}
+
+	return strings.Join(whereConditions, " AND "), args
+}
+
+// relationshipListOrder builds the ORDER BY clause of a relationship listing.
+// Ties are broken by ID so that pages do not overlap.
+func relationshipListOrder(req *models.ListRelationshipsRequest) string {
+	direction := "DESC"
+	if req.SortOrder == models.SortOrderAsc {
+		direction = "ASC"
+	}
+	if req.SortBy == models.RelationshipSortStrength {
+		return fmt.Sprintf("%s %s, created_at DESC, id", relationshipStrengthExpression(), direction)
+	}
+	return fmt.Sprintf("created_at %s, id", direction)
+}
+
+// relationshipStrengthExpression returns SQL computing the impact strength of
+// a relationship as impact.Strength does: its strength attribute when set to
+// a number between 0 and 1, the default of its type otherwise. The attribute
+// is only cast once it is known to be numeric.
+func relationshipStrengthExpression() string {
+	attribute := "attributes->>" + pq.QuoteLiteral(impact.StrengthAttribute)
+	explicit := fmt.Sprintf(
+		"CASE WHEN %[1]s ~ '%[2]s' THEN CASE WHEN (%[1]s)::numeric BETWEEN 0 AND 1 THEN (%[1]s)::numeric END END",
+		attribute, numericAttributePattern,
+	)
+
+	defaults, unknown := impact.DefaultStrengths()
+	types := make([]string, 0, len(defaults))
+	for relType := range defaults {
+		types = append(types, relType)
+	}
+	sort.Strings(types)
+
+	var byType strings.Builder
+	byType.WriteString("CASE type")
+	for _, relType := range types {
+		fmt.Fprintf(&byType, " WHEN %s THEN %s", pq.QuoteLiteral(relType), formatStrength(defaults[relType]))
+	}
+	fmt.Fprintf(&byType, " ELSE %s END", formatStrength(unknown))
+
+	return fmt.Sprintf("COALESCE(%s, %s)", explicit, byType.String())
+}
+
+// formatStrength formats a strength as a SQL numeric literal
+func formatStrength(strength float64) string {
+	return strconv.FormatFloat(strength, 'f', -1, 64) + "::numeric"
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"testing"
+
+	"connect/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelationshipListConditions_Scope(t *testing.T) {
+	req := &models.ListRelationshipsRequest{State: models.RelationshipStateActive}
+
+	whereClause, args := relationshipListConditions(req)
+	assert.Equal(t, "state = $1", whereClause)
+	assert.Equal(t, []interface{}{models.RelationshipStateActive}, args)
+
+	// SearchRelationships counts with the same clause it pages with, so a
+	// scoped caller's total_count only includes edges between CIs they can see
+	req.Scope = &models.VisibilityScope{Types: []string{"server"}, Tenant: "acme"}
+	whereClause, args = relationshipListConditions(req)
+	assert.Equal(t, "state = $1"+
+		" AND source_ci_id IN (SELECT id FROM configuration_items WHERE COALESCE(attributes->>'tenant_id', '') IN ('', $2) AND (type = ANY($3)))"+
+		" AND target_ci_id IN (SELECT id FROM configuration_items WHERE COALESCE(attributes->>'tenant_id', '') IN ('', $4) AND (type = ANY($5)))",
+		whereClause)
+	assert.Equal(t, []interface{}{
+		models.RelationshipStateActive,
+		"acme", pq.Array([]string{"server"}),
+		"acme", pq.Array([]string{"server"}),
+	}, args)
+
+	// A scope granting nothing matches no relationship at all
+	req.Scope = &models.VisibilityScope{}
+	whereClause, _ = relationshipListConditions(req)
+	assert.Equal(t, "state = $1"+
+		" AND source_ci_id IN (SELECT id FROM configuration_items WHERE FALSE)"+
+		" AND target_ci_id IN (SELECT id FROM configuration_items WHERE FALSE)",
+		whereClause)
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"connect/internal/models"
+	"connect/internal/testfixtures"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIRepository_RelationshipStates(t *testing.T) {
+	connStr := testfixtures.StartPostgres(t, 0)
+	ctx := context.Background()
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	userID, shopID, webID, dbID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	runsOn, dependsOn := uuid.New(), uuid.New()
+	scenario := &testfixtures.Scenario{
+		Users: []testfixtures.User{{ID: userID, Username: "reviewer"}},
+		CIs: []testfixtures.CI{
+			{ID: shopID, Name: "shop", Type: "application"},
+			{ID: webID, Name: "web-01", Type: "server"},
+			{ID: dbID, Name: "db-01", Type: "database"},
+		},
+		Relationships: []testfixtures.Relationship{
+			{ID: runsOn, SourceID: shopID, TargetID: webID, Type: "runs_on"},
+			{ID: dependsOn, SourceID: shopID, TargetID: dbID, Type: "depends_on"},
+		},
+	}
+	require.NoError(t, scenario.Seed(ctx, db))
+
+	_, err = db.ExecContext(ctx, `UPDATE ci_relationships SET state = 'proposed' WHERE id = $1`, dependsOn)
+	require.NoError(t, err)
+
+	repo := NewCIRepository(db)
+
+	active, err := repo.GetRelationshipsByCI(ctx, shopID)
+	require.NoError(t, err)
+	require.Len(t, active, 1, "proposed relationships are left out of the active ones")
+	assert.Equal(t, runsOn, active[0].ID)
+
+	all, err := repo.GetRelationshipsByCIAndState(ctx, shopID, []string{models.RelationshipStateActive, models.RelationshipStateProposed})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	proposed, total, err := repo.SearchRelationships(ctx, &models.ListRelationshipsRequest{State: models.RelationshipStateProposed})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, proposed, 1)
+	assert.Equal(t, dependsOn, proposed[0].ID)
+
+	confirmed, err := repo.TransitionRelationshipState(ctx, dependsOn, models.RelationshipStateActive, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RelationshipStateActive, confirmed.State)
+	require.NotNil(t, confirmed.StateChangedBy)
+	assert.Equal(t, userID, *confirmed.StateChangedBy)
+	assert.NotNil(t, confirmed.StateChangedAt)
+
+	// Active relationships can only be deprecated, never sent back to proposed
+	_, err = repo.TransitionRelationshipState(ctx, dependsOn, models.RelationshipStateProposed, userID)
+	assert.Error(t, err)
+
+	deprecated, err := repo.TransitionRelationshipState(ctx, runsOn, models.RelationshipStateDeprecated, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RelationshipStateDeprecated, deprecated.State)
+
+	active, err = repo.GetRelationshipsByCI(ctx, shopID)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, dependsOn, active[0].ID)
+}
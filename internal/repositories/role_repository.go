@@ -24,17 +24,23 @@ var (
 	ErrRolePermissionAlreadyExists = errors.New("role permission already exists")
 	ErrCannotDeleteDefaultRole = errors.New("cannot delete default role")
 	ErrCannotDeleteSystemPermission = errors.New("cannot delete system permission")
+	ErrRoleInUse = errors.New("role is assigned to users")
+	ErrPermissionInUse = errors.New("permission is granted to roles")
+	ErrSharedRole = errors.New("global roles cannot be changed by an organization")
 )
 
 type RoleRepository struct {
 	pool   *pgxpool.Pool
 	logger *database.HealthCheck
+	// orgs resolves the organization whose roles a request may see
+	orgs *orgResolver
 }
 
 func NewRoleRepository(pool *pgxpool.Pool) *RoleRepository {
 	return &RoleRepository{
 		pool:   pool,
 		logger: &database.HealthCheck{Name: "role_repository"},
+		orgs:   newOrgResolver(pgxOrgLookup(pool)),
 	}
 }
 
@@ -51,6 +57,12 @@ func (r *RoleRepository) CreateRole(ctx context.Context, req *models.CreateRoleR
 		return nil, ErrRoleAlreadyExists
 	}
 
+	// Roles created while acting for an organization belong to it
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	role := &models.Role{
 		ID:          uuid.New(),
 		Name:        req.Name,
@@ -61,21 +73,22 @@ func (r *RoleRepository) CreateRole(ctx context.Context, req *models.CreateRoleR
 		IsActive:    req.IsActive,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		OrgID:       orgID,
 	}
 
 	query := `
 		INSERT INTO roles (
-			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at
+			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at, org_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 		) RETURNING 
-			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at
+			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at, org_id
 	`
 
 	err = r.pool.QueryRow(ctx, query,
-		role.ID, role.Name, role.DisplayName, role.Description, role.IsDefault, role.IsSystem, role.IsActive, role.CreatedAt, role.UpdatedAt,
+		role.ID, role.Name, role.DisplayName, role.Description, role.IsDefault, role.IsSystem, role.IsActive, role.CreatedAt, role.UpdatedAt, role.OrgID,
 	).Scan(
-		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt, &role.OrgID,
 	)
 
 	if err != nil {
@@ -89,13 +102,24 @@ func (r *RoleRepository) CreateRole(ctx context.Context, req *models.CreateRoleR
 func (r *RoleRepository) GetRoleByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
 	query := `
 		SELECT 
-			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at
+			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at, org_id
 		FROM roles WHERE id = $1
 	`
 
+	// Organizations see their own roles and the global ones
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{id}
+	if orgID != nil {
+		query += " AND " + orgCondition("org_id", 2)
+		args = append(args, *orgID)
+	}
+
 	role := &models.Role{}
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+	err = r.pool.QueryRow(ctx, query, args...).Scan(
+		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt, &role.OrgID,
 	)
 
 	if err != nil {
@@ -108,17 +132,29 @@ func (r *RoleRepository) GetRoleByID(ctx context.Context, id uuid.UUID) (*models
 	return role, nil
 }
 
-// GetRoleByName retrieves a role by name
+// GetRoleByName retrieves a role by name. An organization's own role takes
+// precedence over the global role of the same name.
 func (r *RoleRepository) GetRoleByName(ctx context.Context, name string) (*models.Role, error) {
 	query := `
 		SELECT 
-			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at
+			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at, org_id
 		FROM roles WHERE name = $1
 	`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{name}
+	if orgID != nil {
+		query += " AND " + orgCondition("org_id", 2)
+		args = append(args, *orgID)
+	}
+	query += " ORDER BY org_id NULLS LAST LIMIT 1"
+
 	role := &models.Role{}
-	err := r.pool.QueryRow(ctx, query, name).Scan(
-		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+	err = r.pool.QueryRow(ctx, query, args...).Scan(
+		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt, &role.OrgID,
 	)
 
 	if err != nil {
@@ -143,6 +179,9 @@ func (r *RoleRepository) UpdateRole(ctx context.Context, id uuid.UUID, req *mode
 	if role.IsSystem {
 		return nil, ErrCannotDeleteDefaultRole
 	}
+	if err := r.checkOwnRole(ctx, role); err != nil {
+		return nil, err
+	}
 
 	query := `
 		UPDATE roles SET
@@ -152,14 +191,14 @@ func (r *RoleRepository) UpdateRole(ctx context.Context, id uuid.UUID, req *mode
 			updated_at = $4
 		WHERE id = $5
 		RETURNING 
-			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at
+			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at, org_id
 	`
 
 	now := time.Now()
 	err = r.pool.QueryRow(ctx, query,
 		req.DisplayName, req.Description, req.IsActive, now, id,
 	).Scan(
-		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+		&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt, &role.OrgID,
 	)
 
 	if err != nil {
@@ -180,6 +219,9 @@ func (r *RoleRepository) DeleteRole(ctx context.Context, id uuid.UUID) error {
 	if role.IsDefault || role.IsSystem {
 		return ErrCannotDeleteDefaultRole
 	}
+	if err := r.checkOwnRole(ctx, role); err != nil {
+		return err
+	}
 
 	// Check if role is assigned to any users
 	count, err := r.CountUsersByRole(ctx, id)
@@ -187,7 +229,7 @@ func (r *RoleRepository) DeleteRole(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf("failed to check role usage: %w", err)
 	}
 	if count > 0 {
-		return fmt.Errorf("cannot delete role: %d users have this role", count)
+		return fmt.Errorf("cannot delete role: %w: %d users have this role", ErrRoleInUse, count)
 	}
 
 	query := `DELETE FROM roles WHERE id = $1`
@@ -203,13 +245,37 @@ func (r *RoleRepository) DeleteRole(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// checkOwnRole returns ErrSharedRole when a request acting for an
+// organization changes a global role
+func (r *RoleRepository) checkOwnRole(ctx context.Context, role *models.Role) error {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
+	if orgID != nil && role.OrgID == nil {
+		return ErrSharedRole
+	}
+	return nil
+}
+
 // ListRoles retrieves a paginated list of roles
 func (r *RoleRepository) ListRoles(ctx context.Context, filter *models.RoleFilterOptions, page, size int) (*models.RoleList, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build WHERE clause
 	whereClause := ""
 	args := []interface{}{}
 	argIndex := 1
 
+	if orgID != nil {
+		whereClause += " AND " + orgCondition("org_id", argIndex)
+		args = append(args, *orgID)
+		argIndex++
+	}
+
 	if filter.Name != "" {
 		whereClause += fmt.Sprintf(" AND (name ILIKE $%d OR display_name ILIKE $%d)", argIndex, argIndex+1)
 		searchPattern := "%" + filter.Name + "%"
@@ -254,7 +320,7 @@ func (r *RoleRepository) ListRoles(ctx context.Context, filter *models.RoleFilte
 	// Get total count
 	countQuery := "SELECT COUNT(*) FROM roles WHERE 1=1" + whereClause
 	var total int
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	err = r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count roles: %w", err)
 	}
@@ -263,7 +329,7 @@ func (r *RoleRepository) ListRoles(ctx context.Context, filter *models.RoleFilte
 	offset := (page - 1) * size
 	query := `
 		SELECT 
-			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at
+			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at, org_id
 		FROM roles WHERE 1=1` + whereClause + fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, argIndex, argIndex+1)
 	args = append(args, size, offset)
 
@@ -277,7 +343,7 @@ func (r *RoleRepository) ListRoles(ctx context.Context, filter *models.RoleFilte
 	for rows.Next() {
 		var role models.Role
 		err := rows.Scan(
-			&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+			&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt, &role.OrgID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan role: %w", err)
@@ -303,11 +369,21 @@ func (r *RoleRepository) ListRoles(ctx context.Context, filter *models.RoleFilte
 func (r *RoleRepository) GetAllRoles(ctx context.Context) ([]models.Role, error) {
 	query := `
 		SELECT 
-			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at
-		FROM roles WHERE is_active = true ORDER BY name
+			id, name, display_name, description, is_default, is_system, is_active, created_at, updated_at, org_id
+		FROM roles WHERE is_active = true
 	`
 
-	rows, err := r.pool.Query(ctx, query)
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	if orgID != nil {
+		query += " AND " + orgCondition("org_id", 1)
+		args = append(args, *orgID)
+	}
+
+	rows, err := r.pool.Query(ctx, query+" ORDER BY name", args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all roles: %w", err)
 	}
@@ -317,7 +393,7 @@ func (r *RoleRepository) GetAllRoles(ctx context.Context) ([]models.Role, error)
 	for rows.Next() {
 		var role models.Role
 		err := rows.Scan(
-			&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+			&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt, &role.OrgID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan role: %w", err)
@@ -328,11 +404,16 @@ func (r *RoleRepository) GetAllRoles(ctx context.Context) ([]models.Role, error)
 	return roles, nil
 }
 
-// RoleExists checks if a role exists by name
+// RoleExists checks if a role exists by name among the roles of the
+// organization the request acts for, or the global roles when it acts for none
 func (r *RoleRepository) RoleExists(ctx context.Context, name string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)`
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return false, err
+	}
+	query := `SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1 AND org_id IS NOT DISTINCT FROM $2)`
 	var exists bool
-	err := r.pool.QueryRow(ctx, query, name).Scan(&exists)
+	err = r.pool.QueryRow(ctx, query, name, orgID).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check role existence: %w", err)
 	}
@@ -489,7 +570,7 @@ func (r *RoleRepository) DeletePermission(ctx context.Context, id uuid.UUID) err
 		return fmt.Errorf("failed to check permission usage: %w", err)
 	}
 	if count > 0 {
-		return fmt.Errorf("cannot delete permission: %d roles have this permission", count)
+		return fmt.Errorf("cannot delete permission: %w: %d roles have this permission", ErrPermissionInUse, count)
 	}
 
 	query := `DELETE FROM permissions WHERE id = $1`
@@ -714,7 +795,7 @@ func (r *RoleRepository) RevokeRoleFromUser(ctx context.Context, userID, roleID
 func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
 	query := `
 		SELECT 
-			r.id, r.name, r.display_name, r.description, r.is_default, r.is_system, r.is_active, r.created_at, r.updated_at
+			r.id, r.name, r.display_name, r.description, r.is_default, r.is_system, r.is_active, r.created_at, r.updated_at, r.org_id
 		FROM roles r
 		JOIN user_roles ur ON r.id = ur.role_id
 		WHERE ur.user_id = $1 AND r.is_active = true
@@ -731,7 +812,7 @@ func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]
 	for rows.Next() {
 		var role models.Role
 		err := rows.Scan(
-			&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+			&role.ID, &role.Name, &role.DisplayName, &role.Description, &role.IsDefault, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt, &role.OrgID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan role: %w", err)
@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connect/internal/models"
+	"connect/internal/scim"
+	"github.com/google/uuid"
+)
+
+// SCIMRepository provisions users and role assignments for SCIM, adapting the
+// user and role repositories to scim.Store
+type SCIMRepository struct {
+	users *UserRepository
+	roles *RoleRepository
+}
+
+// NewSCIMRepository creates a new SCIM repository
+func NewSCIMRepository(users *UserRepository, roles *RoleRepository) *SCIMRepository {
+	return &SCIMRepository{users: users, roles: roles}
+}
+
+// FindUser retrieves a user by ID, or nil if there is none
+func (r *SCIMRepository) FindUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return foundUser(r.users.GetByID(ctx, id))
+}
+
+// FindUserByUsername retrieves a user by username, or nil if there is none
+func (r *SCIMRepository) FindUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return foundUser(r.users.GetByUsername(ctx, username))
+}
+
+// FindUserByEmail retrieves a user by email, or nil if there is none
+func (r *SCIMRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return foundUser(r.users.GetByEmail(ctx, email))
+}
+
+func foundUser(user *models.User, err error) (*models.User, error) {
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// ListUsers retrieves a page of users ordered by username and the number of users
+func (r *SCIMRepository) ListUsers(ctx context.Context, offset, limit int) ([]*models.User, int, error) {
+	var total int
+	if err := r.users.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := `
+		SELECT
+			id, username, email, password_hash, first_name, last_name,
+			is_active, is_verified, last_login_at, password_changed_at,
+			created_at, updated_at, created_by, updated_by
+		FROM users
+		ORDER BY username
+		OFFSET $1 LIMIT $2
+	`
+	users, err := r.queryUsers(ctx, query, offset, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, total, nil
+}
+
+// CreateUser creates a user, returning scim.ErrConflict if the username or
+// email is taken
+func (r *SCIMRepository) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
+	user, err := r.users.Create(ctx, req, uuid.Nil)
+	if errors.Is(err, ErrUserAlreadyExists) {
+		return nil, scim.ErrConflict
+	}
+	return user, err
+}
+
+// UpdateUser updates a user
+func (r *SCIMRepository) UpdateUser(ctx context.Context, id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error) {
+	return r.users.Update(ctx, id, req, uuid.Nil)
+}
+
+// DeleteUser deletes a user
+func (r *SCIMRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return r.users.Delete(ctx, id)
+}
+
+// FindRole retrieves a role by ID, or nil if there is none
+func (r *SCIMRepository) FindRole(ctx context.Context, id uuid.UUID) (*models.Role, error) {
+	return foundRole(r.roles.GetRoleByID(ctx, id))
+}
+
+// FindRoleByName retrieves a role by name, or nil if there is none
+func (r *SCIMRepository) FindRoleByName(ctx context.Context, name string) (*models.Role, error) {
+	return foundRole(r.roles.GetRoleByName(ctx, name))
+}
+
+func foundRole(role *models.Role, err error) (*models.Role, error) {
+	if errors.Is(err, ErrRoleNotFound) {
+		return nil, nil
+	}
+	return role, err
+}
+
+// ListRoles retrieves all roles
+func (r *SCIMRepository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return r.roles.GetAllRoles(ctx)
+}
+
+// UserRoles retrieves the roles of a user
+func (r *SCIMRepository) UserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
+	return r.roles.GetUserRoles(ctx, userID)
+}
+
+// RoleMembers retrieves the users holding a role ordered by username
+func (r *SCIMRepository) RoleMembers(ctx context.Context, roleID uuid.UUID) ([]*models.User, error) {
+	query := `
+		SELECT
+			u.id, u.username, u.email, u.password_hash, u.first_name, u.last_name,
+			u.is_active, u.is_verified, u.last_login_at, u.password_changed_at,
+			u.created_at, u.updated_at, u.created_by, u.updated_by
+		FROM users u
+		JOIN user_roles ur ON u.id = ur.user_id
+		WHERE ur.role_id = $1
+		ORDER BY u.username
+	`
+	users, err := r.queryUsers(ctx, query, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role members: %w", err)
+	}
+	return users, nil
+}
+
+// AssignRole assigns a role to a user unless they already hold it
+func (r *SCIMRepository) AssignRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	if err := r.roles.AssignRoleToUser(ctx, userID, roleID); err != nil && !errors.Is(err, ErrUserRoleAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+// RevokeRole revokes a role from a user if they hold it
+func (r *SCIMRepository) RevokeRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	if err := r.roles.RevokeRoleFromUser(ctx, userID, roleID); err != nil && !errors.Is(err, ErrUserRoleNotFound) {
+		return err
+	}
+	return nil
+}
+
+// queryUsers runs a query selecting the columns of users
+func (r *SCIMRepository) queryUsers(ctx context.Context, query string, args ...interface{}) ([]*models.User, error) {
+	rows, err := r.users.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		err := rows.Scan(
+			&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
+			&user.IsActive, &user.IsVerified, &user.LastLoginAt, &user.PasswordChangedAt,
+			&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
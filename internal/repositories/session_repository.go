@@ -3,12 +3,15 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
+	"connect/internal/auth"
 	"connect/internal/database"
 	"connect/internal/models"
+	"connect/internal/sessionlimits"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -23,31 +26,92 @@ var (
 )
 
 type SessionRepository struct {
-	pool   *pgxpool.Pool
-	logger *database.HealthCheck
+	pool        *pgxpool.Pool
+	logger      *database.HealthCheck
+	fingerprint auth.FingerprintPolicy
+	events      auth.SecurityEventRecorder
+	limits      *sessionlimits.Service
 }
 
 func NewSessionRepository(pool *pgxpool.Pool) *SessionRepository {
 	return &SessionRepository{
 		pool:   pool,
 		logger: &database.HealthCheck{Name: "session_repository"},
+		fingerprint: auth.FingerprintPolicy{
+			Mode:             auth.FingerprintOff,
+			IPv4PrefixLength: auth.DefaultIPv4PrefixLength,
+			IPv6PrefixLength: auth.DefaultIPv6PrefixLength,
+		},
 	}
 }
 
+// SetFingerprintPolicy binds sessions to the client fingerprint they were created
+// with; changes are recorded as session activity and security events, and
+// rejected as the policy mode demands
+func (r *SessionRepository) SetFingerprintPolicy(policy auth.FingerprintPolicy, events auth.SecurityEventRecorder) {
+	r.fingerprint = policy
+	r.events = events
+}
+
+// SetSessionLimits replaces the global concurrent session limit with limits per role and tenant
+func (r *SessionRepository) SetSessionLimits(limits *sessionlimits.Service) {
+	r.limits = limits
+}
+
+// sessionLimit returns how many concurrent sessions the user of a new session may hold
+func (r *SessionRepository) sessionLimit(ctx context.Context, req *models.CreateSessionRequest) (int, error) {
+	if r.limits == nil {
+		return models.MaxConcurrentSessions, nil
+	}
+
+	query := `
+		SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+	`
+
+	rows, err := r.pool.Query(ctx, query, req.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return 0, fmt.Errorf("failed to scan user role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	return r.limits.LimitFor(ctx, roles, req.TenantID), nil
+}
+
 // Create creates a new session in the database
 func (r *SessionRepository) Create(ctx context.Context, req *models.CreateSessionRequest) (*models.Session, error) {
 	// Check if user already has too many active sessions
+	maxSessions, err := r.sessionLimit(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve session limit: %w", err)
+	}
+
 	activeSessions, err := r.CountActiveSessions(ctx, req.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check active sessions: %w", err)
 	}
 
-	if activeSessions >= models.MaxConcurrentSessions {
-		// Revoke oldest active session
+	// Revoke oldest active sessions; a lowered limit may require revoking several
+	for maxSessions != sessionlimits.Unlimited && activeSessions >= maxSessions {
 		err := r.RevokeOldestActiveSession(ctx, req.UserID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to revoke oldest session: %w", err)
 		}
+		activeSessions--
 	}
 
 	// Create session
@@ -65,12 +129,20 @@ func (r *SessionRepository) Create(ctx context.Context, req *models.CreateSessio
 		IsActive:     true,
 	}
 
+	// Bind the session to the client it was created from
+	fingerprint := r.fingerprint.Compute(auth.ClientInfo{
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+		DeviceID:  req.DeviceID,
+	})
+
 	query := `
 		INSERT INTO sessions (
 			id, user_id, token, refresh_token, ip_address, user_agent,
-			expires_at, last_active_at, created_at, is_active
+			expires_at, last_active_at, created_at, is_active,
+			device_id, ua_family, ip_prefix
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
 		) RETURNING 
 			id, user_id, token, refresh_token, ip_address, user_agent,
 			expires_at, last_active_at, created_at, revoked_at, is_active
@@ -79,6 +151,7 @@ func (r *SessionRepository) Create(ctx context.Context, req *models.CreateSessio
 	err = r.pool.QueryRow(ctx, query,
 		session.ID, session.UserID, session.Token, session.RefreshToken, session.IPAddress, session.UserAgent,
 		session.ExpiresAt, session.LastActiveAt, session.CreatedAt, session.IsActive,
+		fingerprint.DeviceID, fingerprint.UAFamily, fingerprint.IPPrefix,
 	).Scan(
 		&session.ID, &session.UserID, &session.Token, &session.RefreshToken, &session.IPAddress, &session.UserAgent,
 		&session.ExpiresAt, &session.LastActiveAt, &session.CreatedAt, &session.RevokedAt, &session.IsActive,
@@ -276,6 +349,12 @@ func (r *SessionRepository) Revoke(ctx context.Context, id uuid.UUID, reason str
 
 // ValidateSession validates a session and updates last active time
 func (r *SessionRepository) ValidateSession(ctx context.Context, token string, ipAddress, userAgent string) (*models.Session, error) {
+	return r.ValidateClientSession(ctx, token, auth.ClientInfo{IPAddress: ipAddress, UserAgent: userAgent})
+}
+
+// ValidateClientSession validates a session, checks the client against the
+// fingerprint the session is bound to and updates last active time
+func (r *SessionRepository) ValidateClientSession(ctx context.Context, token string, client auth.ClientInfo) (*models.Session, error) {
 	session, err := r.GetByToken(ctx, token)
 	if err != nil {
 		if err == ErrSessionNotFound {
@@ -304,6 +383,12 @@ func (r *SessionRepository) ValidateSession(ctx context.Context, token string, i
 		}
 	}
 
+	if r.fingerprint.Mode != auth.FingerprintOff {
+		if err := r.checkFingerprint(ctx, session, client); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update last active time
 	_, err = r.Update(ctx, session.ID, &models.UpdateSessionRequest{
 		LastActiveAt: timePtr(time.Now()),
@@ -317,8 +402,8 @@ func (r *SessionRepository) ValidateSession(ctx context.Context, token string, i
 		SessionID: session.ID,
 		Action:    models.SessionActionAccessed,
 		Details:   "Session accessed",
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
 	}
 	_, err = r.CreateActivity(ctx, activity)
 	if err != nil {
@@ -329,6 +414,64 @@ func (r *SessionRepository) ValidateSession(ctx context.Context, token string, i
 	return session, nil
 }
 
+// checkFingerprint compares the client with the fingerprint the session is bound
+// to and records any change as session activity and a security event
+func (r *SessionRepository) checkFingerprint(ctx context.Context, session *models.Session, client auth.ClientInfo) error {
+	var bound auth.Fingerprint
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(device_id, ''), COALESCE(ua_family, ''), COALESCE(ip_prefix, '')
+		FROM sessions WHERE id = $1
+	`, session.ID).Scan(&bound.DeviceID, &bound.UAFamily, &bound.IPPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to get session fingerprint: %w", err)
+	}
+
+	presented := r.fingerprint.Compute(client)
+	change, checkErr := r.fingerprint.Check(bound, presented)
+	if change.Level == auth.ChangeNone {
+		return nil
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"level":    change.Level,
+		"changed":  change.Changed,
+		"bound":    bound,
+		"observed": presented,
+		"rejected": checkErr != nil,
+	})
+	activity := &models.CreateSessionActivityRequest{
+		SessionID: session.ID,
+		Action:    models.SessionActionFingerprintChanged,
+		Details:   string(details),
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+	}
+	if _, err := r.CreateActivity(ctx, activity); err != nil {
+		fmt.Printf("failed to log session fingerprint change: %v\n", err)
+	}
+
+	if r.events != nil {
+		event := auth.SecurityEvent{
+			Type:   auth.SecurityEventFingerprintMismatch,
+			UserID: session.UserID.String(),
+			Details: map[string]interface{}{
+				"session_id": session.ID.String(),
+				"level":      change.Level,
+				"changed":    change.Changed,
+				"rejected":   checkErr != nil,
+			},
+			IPAddress:  client.IPAddress,
+			UserAgent:  client.UserAgent,
+			OccurredAt: time.Now(),
+		}
+		if err := r.events.RecordSecurityEvent(ctx, event); err != nil {
+			fmt.Printf("failed to record session fingerprint security event: %v\n", err)
+		}
+	}
+
+	return checkErr
+}
+
 // List retrieves a paginated list of sessions
 func (r *SessionRepository) List(ctx context.Context, filter *models.SessionFilterOptions, page, size int) (*models.SessionList, error) {
 	// Build WHERE clause
@@ -25,6 +25,8 @@ type UserRepository struct {
 	pool           *pgxpool.Pool
 	passwordService *auth.PasswordService
 	logger         *database.HealthCheck
+	// orgs resolves the organization whose users a request may see
+	orgs           *orgResolver
 }
 
 func NewUserRepository(pool *pgxpool.Pool, passwordService *auth.PasswordService) *UserRepository {
@@ -32,6 +34,7 @@ func NewUserRepository(pool *pgxpool.Pool, passwordService *auth.PasswordService
 		pool:           pool,
 		passwordService: passwordService,
 		logger:         database.HealthCheck{Name: "user_repository"},
+		orgs:           newOrgResolver(pgxOrgLookup(pool)),
 	}
 }
 
@@ -46,6 +49,12 @@ func (r *UserRepository) Create(ctx context.Context, req *models.CreateUserReque
 		return nil, ErrUserAlreadyExists
 	}
 
+	// Users created while acting for an organization belong to it
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	passwordHash, err := r.passwordService.HashPassword(req.Password)
 	if err != nil {
@@ -68,35 +77,58 @@ func (r *UserRepository) Create(ctx context.Context, req *models.CreateUserReque
 		CreatedBy:      createdBy,
 		UpdatedBy:      createdBy,
 		PasswordChangedAt: &now,
+		OrgID:          orgID,
 	}
 
 	query := `
 		INSERT INTO users (
 			id, username, email, password_hash, first_name, last_name, 
 			is_active, is_verified, created_at, updated_at, created_by, updated_by,
-			password_changed_at
+			password_changed_at, org_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		) RETURNING 
 			id, username, email, password_hash, first_name, last_name,
 			is_active, is_verified, last_login_at, password_changed_at,
-			created_at, updated_at, created_by, updated_by
+			created_at, updated_at, created_by, updated_by, org_id
 	`
 
-	err = r.pool.QueryRow(ctx, query,
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query,
 		user.ID, user.Username, user.Email, user.PasswordHash, user.FirstName, user.LastName,
 		user.IsActive, user.IsVerified, user.CreatedAt, user.UpdatedAt, user.CreatedBy, user.UpdatedBy,
-		user.PasswordChangedAt,
+		user.PasswordChangedAt, user.OrgID,
 	).Scan(
 		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
 		&user.IsActive, &user.IsVerified, &user.LastLoginAt, &user.PasswordChangedAt,
-		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy, &user.OrgID,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// Logins act for the user's default organization, so the organization
+	// the user was created in becomes it; without the membership the user's
+	// tokens would carry no organization and see every tenant
+	if user.OrgID != nil {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO organization_members (organization_id, user_id, is_default, created_at)
+			VALUES ($1, $2, true, $3)
+		`, *user.OrgID, user.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to add user to organization: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit user: %w", err)
+	}
+
 	return user, nil
 }
 
@@ -106,15 +138,25 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 		SELECT 
 			id, username, email, password_hash, first_name, last_name,
 			is_active, is_verified, last_login_at, password_changed_at,
-			created_at, updated_at, created_by, updated_by
+			created_at, updated_at, created_by, updated_by, org_id
 		FROM users WHERE id = $1
 	`
 
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{id}
+	if orgID != nil {
+		query += " AND " + orgUserCondition(2)
+		args = append(args, *orgID)
+	}
+
 	user := &models.User{}
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err = r.pool.QueryRow(ctx, query, args...).Scan(
 		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
 		&user.IsActive, &user.IsVerified, &user.LastLoginAt, &user.PasswordChangedAt,
-		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy, &user.OrgID,
 	)
 
 	if err != nil {
@@ -133,7 +175,7 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 		SELECT 
 			id, username, email, password_hash, first_name, last_name,
 			is_active, is_verified, last_login_at, password_changed_at,
-			created_at, updated_at, created_by, updated_by
+			created_at, updated_at, created_by, updated_by, org_id
 		FROM users WHERE username = $1
 	`
 
@@ -141,7 +183,7 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 	err := r.pool.QueryRow(ctx, query, username).Scan(
 		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
 		&user.IsActive, &user.IsVerified, &user.LastLoginAt, &user.PasswordChangedAt,
-		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy, &user.OrgID,
 	)
 
 	if err != nil {
@@ -160,7 +202,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 		SELECT 
 			id, username, email, password_hash, first_name, last_name,
 			is_active, is_verified, last_login_at, password_changed_at,
-			created_at, updated_at, created_by, updated_by
+			created_at, updated_at, created_by, updated_by, org_id
 		FROM users WHERE email = $1
 	`
 
@@ -168,7 +210,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	err := r.pool.QueryRow(ctx, query, email).Scan(
 		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
 		&user.IsActive, &user.IsVerified, &user.LastLoginAt, &user.PasswordChangedAt,
-		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy, &user.OrgID,
 	)
 
 	if err != nil {
@@ -203,7 +245,7 @@ func (r *UserRepository) Update(ctx context.Context, id uuid.UUID, req *models.U
 		RETURNING 
 			id, username, email, password_hash, first_name, last_name,
 			is_active, is_verified, last_login_at, password_changed_at,
-			created_at, updated_at, created_by, updated_by
+			created_at, updated_at, created_by, updated_by, org_id
 	`
 
 	// Prepare values
@@ -239,7 +281,7 @@ func (r *UserRepository) Update(ctx context.Context, id uuid.UUID, req *models.U
 	).Scan(
 		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
 		&user.IsActive, &user.IsVerified, &user.LastLoginAt, &user.PasswordChangedAt,
-		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy, &user.OrgID,
 	)
 
 	if err != nil {
@@ -251,8 +293,18 @@ func (r *UserRepository) Update(ctx context.Context, id uuid.UUID, req *models.U
 
 // Delete deletes a user from the database
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return err
+	}
 	query := `DELETE FROM users WHERE id = $1`
-	result, err := r.pool.Exec(ctx, query, id)
+	args := []interface{}{id}
+	if orgID != nil {
+		query += " AND " + orgUserCondition(2)
+		args = append(args, *orgID)
+	}
+
+	result, err := r.pool.Exec(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -266,11 +318,22 @@ func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 // List retrieves a paginated list of users
 func (r *UserRepository) List(ctx context.Context, filter *models.UserFilterOptions, page, size int) (*models.UserList, error) {
+	orgID, err := r.orgs.orgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build WHERE clause
 	whereClause := ""
 	args := []interface{}{}
 	argIndex := 1
 
+	if orgID != nil {
+		whereClause += " AND " + orgUserCondition(argIndex)
+		args = append(args, *orgID)
+		argIndex++
+	}
+
 	if filter.Search != "" {
 		whereClause += fmt.Sprintf(" AND (username ILIKE $%d OR email ILIKE $%d OR first_name ILIKE $%d OR last_name ILIKE $%d)", 
 			argIndex, argIndex+1, argIndex+2, argIndex+3)
@@ -285,6 +348,12 @@ func (r *UserRepository) List(ctx context.Context, filter *models.UserFilterOpti
 		argIndex++
 	}
 
+	if len(filter.Roles) > 0 {
+		whereClause += fmt.Sprintf(" AND id IN (SELECT ur.user_id FROM user_roles ur JOIN roles r ON r.id = ur.role_id WHERE r.name = ANY($%d))", argIndex)
+		args = append(args, filter.Roles)
+		argIndex++
+	}
+
 	if filter.CreatedFrom != nil {
 		whereClause += fmt.Sprintf(" AND created_at >= $%d", argIndex)
 		args = append(args, filter.CreatedFrom)
@@ -317,7 +386,7 @@ func (r *UserRepository) List(ctx context.Context, filter *models.UserFilterOpti
 	// Get total count
 	countQuery := "SELECT COUNT(*) FROM users WHERE 1=1" + whereClause
 	var total int
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	err = r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -328,7 +397,7 @@ func (r *UserRepository) List(ctx context.Context, filter *models.UserFilterOpti
 		SELECT 
 			id, username, email, password_hash, first_name, last_name,
 			is_active, is_verified, last_login_at, password_changed_at,
-			created_at, updated_at, created_by, updated_by
+			created_at, updated_at, created_by, updated_by, org_id
 		FROM users WHERE 1=1` + whereClause + fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, argIndex, argIndex+1)
 	args = append(args, size, offset)
 
@@ -344,7 +413,7 @@ func (r *UserRepository) List(ctx context.Context, filter *models.UserFilterOpti
 		err := rows.Scan(
 			&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
 			&user.IsActive, &user.IsVerified, &user.LastLoginAt, &user.PasswordChangedAt,
-			&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+			&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy, &user.OrgID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
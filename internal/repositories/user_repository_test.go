@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"connect/internal/models"
+	"connect/internal/testfixtures"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
@@ -629,7 +630,7 @@ func setupTestDatabase(t *testing.T, ctx context.Context) (*postgres.PostgresCon
 		postgres.WithDatabase("testdb"),
 		postgres.WithUsername("testuser"),
 		postgres.WithPassword("testpass"),
-		postgres.WithInitScripts("../../migrations/001_initial_schema.sql", "../../migrations/002_session_management.sql"),
+		postgres.WithInitScripts(testfixtures.InitScripts(t, 2)...),
 	)
 	require.NoError(t, err)
 
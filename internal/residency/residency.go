@@ -0,0 +1,250 @@
+// Package residency routes the CI data of regulated tenants to their own
+// Postgres database or schema. Tenants are assigned to shards in the
+// configuration or in the tenant_shards control table, which takes precedence
+// and is reloaded periodically; unassigned tenants stay on the main database.
+// The repositories ask the router for the pool of the tenant each request acts
+// for, and the shard health check covers every pool.
+package residency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"connect/internal/visibility"
+	"github.com/jmoiron/sqlx"
+)
+
+// MainShard names the main database in health reports and assignments
+const MainShard = "main"
+
+// DefaultHealthTimeout bounds how long a shard health check waits for a pool
+const DefaultHealthTimeout = 5 * time.Second
+
+// Health statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+var ErrUnknownShard = errors.New("unknown shard")
+
+// Pinger checks a pool is reachable, as *sqlx.DB does
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Store loads the tenant assignments of the control table
+type Store interface {
+	// ListAssignments returns the shard of every tenant in the control table
+	ListAssignments(ctx context.Context) (map[string]string, error)
+}
+
+// ShardHealth is the health of one pool
+type ShardHealth struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Assignment is the shard a tenant's data lives in and where that was decided
+type Assignment struct {
+	Tenant string `json:"tenant"`
+	Shard  string `json:"shard"`
+	// Source is config or table
+	Source string `json:"source"`
+}
+
+// Router resolves the pool holding the CI data of a tenant
+type Router struct {
+	main    *sqlx.DB
+	shards  map[string]*sqlx.DB
+	static  map[string]string
+	store   Store
+	timeout time.Duration
+
+	mu      sync.RWMutex
+	dynamic map[string]string
+}
+
+// NewRouter creates a router over the main pool and the shard pools by name.
+// tenants are the assignments from the configuration; store may be nil when
+// there is no control table.
+func NewRouter(main *sqlx.DB, shards map[string]*sqlx.DB, tenants map[string]string, store Store) (*Router, error) {
+	for tenant, shard := range tenants {
+		if _, ok := shards[shard]; !ok {
+			return nil, fmt.Errorf("%w %q for tenant %q", ErrUnknownShard, shard, tenant)
+		}
+	}
+	return &Router{
+		main:    main,
+		shards:  shards,
+		static:  tenants,
+		store:   store,
+		timeout: DefaultHealthTimeout,
+		dynamic: map[string]string{},
+	}, nil
+}
+
+// Shard returns the shard a tenant is assigned to, or MainShard
+func (r *Router) Shard(tenant string) string {
+	if tenant == "" {
+		return MainShard
+	}
+	r.mu.RLock()
+	shard, ok := r.dynamic[tenant]
+	r.mu.RUnlock()
+	if ok {
+		return shard
+	}
+	if shard, ok := r.static[tenant]; ok {
+		return shard
+	}
+	return MainShard
+}
+
+// DB returns the pool holding the data of the tenant the context acts for
+func (r *Router) DB(ctx context.Context) *sqlx.DB {
+	if shard := r.Shard(visibility.TenantFromContext(ctx)); shard != MainShard {
+		return r.shards[shard]
+	}
+	return r.main
+}
+
+// Assignments lists every tenant not on the main database, by tenant
+func (r *Router) Assignments() []Assignment {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assignments := []Assignment{}
+	for tenant, shard := range r.static {
+		if _, overridden := r.dynamic[tenant]; !overridden {
+			assignments = append(assignments, Assignment{Tenant: tenant, Shard: shard, Source: "config"})
+		}
+	}
+	for tenant, shard := range r.dynamic {
+		if shard != MainShard {
+			assignments = append(assignments, Assignment{Tenant: tenant, Shard: shard, Source: "table"})
+		}
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].Tenant < assignments[j].Tenant })
+	return assignments
+}
+
+// Reload replaces the control table assignments. Rows naming an unknown shard
+// are skipped, leaving their tenant where it was, so a typo in the table never
+// sends a tenant's requests to a pool that does not exist.
+func (r *Router) Reload(ctx context.Context) error {
+	if r.store == nil {
+		return nil
+	}
+	loaded, err := r.store.ListAssignments(ctx)
+	if err != nil {
+		return err
+	}
+
+	dynamic := make(map[string]string, len(loaded))
+	for tenant, shard := range loaded {
+		if _, ok := r.shards[shard]; !ok && shard != MainShard {
+			log.Printf("Ignoring tenant %s assigned to %v %q", tenant, ErrUnknownShard, shard)
+			if previous, ok := r.currentDynamic(tenant); ok {
+				dynamic[tenant] = previous
+			}
+			continue
+		}
+		dynamic[tenant] = shard
+	}
+
+	r.mu.Lock()
+	r.dynamic = dynamic
+	r.mu.Unlock()
+	return nil
+}
+
+// currentDynamic returns the control table assignment of a tenant
+func (r *Router) currentDynamic(tenant string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	shard, ok := r.dynamic[tenant]
+	return shard, ok
+}
+
+// Run reloads the control table at the given interval
+func (r *Router) Run(ctx context.Context, interval time.Duration) {
+	if r.store == nil {
+		return
+	}
+	if err := r.Reload(ctx); err != nil {
+		log.Printf("Failed to load tenant shard assignments: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil {
+				log.Printf("Failed to reload tenant shard assignments: %v", err)
+			}
+		}
+	}
+}
+
+// Health pings the main pool and every shard concurrently, main first and
+// the shards by name
+func (r *Router) Health(ctx context.Context) []ShardHealth {
+	names := make([]string, 0, len(r.shards))
+	for name := range r.shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pools := make([]Pinger, 0, len(names)+1)
+	pools = append(pools, r.main)
+	for _, name := range names {
+		pools = append(pools, r.shards[name])
+	}
+	return checkHealth(ctx, append([]string{MainShard}, names...), pools, r.timeout)
+}
+
+// checkHealth pings the pools concurrently, each within timeout
+func checkHealth(ctx context.Context, names []string, pools []Pinger, timeout time.Duration) []ShardHealth {
+	health := make([]ShardHealth, len(pools))
+	var wg sync.WaitGroup
+	for i := range pools {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := pools[i].PingContext(pingCtx)
+			health[i] = ShardHealth{Name: names[i], Status: StatusUp, ResponseTimeMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				health[i].Status = StatusDown
+				health[i].Error = err.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return health
+}
+
+// Healthy reports whether every pool is up
+func Healthy(health []ShardHealth) bool {
+	for _, h := range health {
+		if h.Status != StatusUp {
+			return false
+		}
+	}
+	return true
+}
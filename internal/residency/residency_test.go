@@ -0,0 +1,114 @@
+package residency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"connect/internal/visibility"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore holds the control table assignments
+type memoryStore struct {
+	assignments map[string]string
+	err         error
+}
+
+func (m *memoryStore) ListAssignments(ctx context.Context) (map[string]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	copied := map[string]string{}
+	for tenant, shard := range m.assignments {
+		copied[tenant] = shard
+	}
+	return copied, nil
+}
+
+// The pools are only compared, never used
+var (
+	mainDB = &sqlx.DB{}
+	euDB   = &sqlx.DB{}
+	usDB   = &sqlx.DB{}
+)
+
+func newTestRouter(t *testing.T, store Store) *Router {
+	router, err := NewRouter(mainDB, map[string]*sqlx.DB{"eu": euDB, "us": usDB}, map[string]string{"acme": "eu"}, store)
+	require.NoError(t, err)
+	return router
+}
+
+func TestRouterResolvesTenantPool(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	assert.Same(t, euDB, router.DB(visibility.WithTenant(context.Background(), "acme")))
+	assert.Same(t, mainDB, router.DB(visibility.WithTenant(context.Background(), "globex")))
+	assert.Same(t, mainDB, router.DB(context.Background()))
+	assert.Equal(t, MainShard, router.Shard(""))
+}
+
+func TestNewRouterRejectsUnknownShards(t *testing.T) {
+	_, err := NewRouter(mainDB, map[string]*sqlx.DB{"eu": euDB}, map[string]string{"acme": "apac"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownShard)
+}
+
+func TestReloadOverridesConfiguration(t *testing.T) {
+	store := &memoryStore{assignments: map[string]string{"acme": "us", "globex": "eu", "initech": MainShard}}
+	router := newTestRouter(t, store)
+	require.NoError(t, router.Reload(context.Background()))
+
+	assert.Equal(t, "us", router.Shard("acme"))
+	assert.Equal(t, "eu", router.Shard("globex"))
+	assert.Equal(t, MainShard, router.Shard("initech"))
+	assert.Equal(t, []Assignment{
+		{Tenant: "acme", Shard: "us", Source: "table"},
+		{Tenant: "globex", Shard: "eu", Source: "table"},
+	}, router.Assignments())
+
+	// Removing the row falls back to the configuration
+	delete(store.assignments, "acme")
+	require.NoError(t, router.Reload(context.Background()))
+	assert.Equal(t, "eu", router.Shard("acme"))
+}
+
+func TestReloadKeepsTenantOnUnknownShard(t *testing.T) {
+	store := &memoryStore{assignments: map[string]string{"globex": "us"}}
+	router := newTestRouter(t, store)
+	require.NoError(t, router.Reload(context.Background()))
+
+	store.assignments["globex"] = "apac"
+	store.assignments["hooli"] = "apac"
+	require.NoError(t, router.Reload(context.Background()))
+	assert.Equal(t, "us", router.Shard("globex"))
+	assert.Equal(t, MainShard, router.Shard("hooli"))
+
+	store.err = errors.New("connection refused")
+	assert.Error(t, router.Reload(context.Background()))
+	assert.Equal(t, "us", router.Shard("globex"))
+}
+
+// pinger fails with err, if set
+type pinger struct {
+	err error
+}
+
+func (p pinger) PingContext(ctx context.Context) error {
+	return p.err
+}
+
+func TestCheckHealth(t *testing.T) {
+	health := checkHealth(context.Background(), []string{MainShard, "eu"},
+		[]Pinger{pinger{}, pinger{err: errors.New("connection refused")}}, time.Second)
+
+	require.Len(t, health, 2)
+	assert.Equal(t, StatusUp, health[0].Status)
+	assert.Equal(t, "eu", health[1].Name)
+	assert.Equal(t, StatusDown, health[1].Status)
+	assert.Equal(t, "connection refused", health[1].Error)
+	assert.False(t, Healthy(health))
+	assert.True(t, Healthy(health[:1]))
+}
@@ -0,0 +1,60 @@
+package residency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore reads the tenant_shards control table of the main database
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed assignment store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ListAssignments returns the shard of every tenant in the control table
+func (s *PostgresStore) ListAssignments(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tenant, shard FROM tenant_shards`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant shards: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := map[string]string{}
+	for rows.Next() {
+		var tenant, shard string
+		if err := rows.Scan(&tenant, &shard); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant shard: %w", err)
+		}
+		assignments[tenant] = shard
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tenant shards: %w", err)
+	}
+	return assignments, nil
+}
+
+// Open opens a pool per shard from its connection string. The postgres
+// driver must be registered by the caller. Pools already opened are closed
+// if one fails.
+func Open(dsns map[string]string, maxOpenConns, maxIdleConns int) (map[string]*sqlx.DB, error) {
+	shards := make(map[string]*sqlx.DB, len(dsns))
+	for name, dsn := range dsns {
+		db, err := sqlx.Open("postgres", dsn)
+		if err != nil {
+			for _, opened := range shards {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to open shard %s: %w", name, err)
+		}
+		db.SetMaxOpenConns(maxOpenConns)
+		db.SetMaxIdleConns(maxIdleConns)
+		shards[name] = db
+	}
+	return shards, nil
+}
@@ -0,0 +1,118 @@
+// Package resync rebuilds the graph store from PostgreSQL as an orchestrated
+// job. Entities are replayed in ID order, one entity type after another, at a
+// bounded rate; the position reached is checkpointed after every page so a
+// paused job, or one interrupted by a crash, resumes where it stopped instead
+// of starting over.
+package resync
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// Entity types, in the order they are resynced. Relationships go last so
+// the CIs they connect exist in the graph.
+const (
+	EntityConfigurationItem = "configuration_item"
+	EntityRelationship      = "relationship"
+)
+
+// Entities lists every entity type a resync can replay, in resync order
+var Entities = []string{EntityConfigurationItem, EntityRelationship}
+
+// MaxRateLimit bounds the entities replayed per second
+const MaxRateLimit = 10000
+
+var (
+	ErrInvalidRequest = errors.New("invalid resync request")
+	ErrJobNotFound    = errors.New("resync job not found")
+	ErrJobRunning     = errors.New("a resync is already in progress")
+	ErrInvalidState   = errors.New("resync job cannot change to that state")
+)
+
+// Job is a resync run. Phases are worked through in order; everything before
+// CurrentPhase is done.
+type Job struct {
+	ID           uuid.UUID `json:"id"`
+	Status       string    `json:"status"`
+	Phases       []Phase   `json:"phases"`
+	CurrentPhase int       `json:"current_phase"`
+	// RateLimit is the maximum number of entities replayed per second; 0 means unlimited
+	RateLimit   int        `json:"rate_limit"`
+	RequestedBy string     `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ElapsedMs is the time spent running, excluding pauses
+	ElapsedMs int64  `json:"elapsed_ms"`
+	Error     string `json:"error,omitempty"`
+
+	// Progress is the percentage of entities replayed and ETA the expected
+	// completion at the observed rate. Both are derived, not stored.
+	Progress float64    `json:"progress"`
+	ETA      *time.Time `json:"eta,omitempty"`
+}
+
+// Phase is the resync of one entity type
+type Phase struct {
+	Entity string `json:"entity"`
+	// Total is the number of entities counted when the job started; it grows
+	// if more are replayed
+	Total     int64 `json:"total"`
+	Processed int64 `json:"processed"`
+	// Failed counts processed entities the graph store rejected
+	Failed int64 `json:"failed"`
+	// Checkpoint is the ID of the last entity processed
+	Checkpoint uuid.UUID `json:"checkpoint"`
+}
+
+// Finished reports whether the job can no longer change
+func (j *Job) Finished() bool {
+	switch j.Status {
+	case StatusCompleted, StatusCancelled, StatusFailed:
+		return true
+	}
+	return false
+}
+
+// estimate fills in the progress percentage and, while running, the ETA
+func (j *Job) estimate(now time.Time) {
+	var total, processed int64
+	for _, phase := range j.Phases {
+		total += phase.Total
+		processed += phase.Processed
+	}
+
+	j.Progress = 100
+	if total > 0 && processed < total {
+		j.Progress = float64(processed*10000/total) / 100
+	}
+	if j.Status == StatusCompleted {
+		j.Progress = 100
+	}
+
+	j.ETA = nil
+	if j.Status != StatusRunning || processed == 0 || j.ElapsedMs <= 0 || processed >= total {
+		return
+	}
+	remaining := time.Duration(float64(total-processed) * float64(j.ElapsedMs) / float64(processed) * float64(time.Millisecond))
+	eta := now.Add(remaining)
+	j.ETA = &eta
+}
+
+// Entity is a row to replay into the graph store
+type Entity struct {
+	ID   uuid.UUID
+	Data map[string]interface{}
+}
@@ -0,0 +1,312 @@
+package resync
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	mu       sync.Mutex
+	entities map[string][]Entity
+	jobs     map[uuid.UUID]*Job
+}
+
+func newMemoryStore(cis, relationships int) *memoryStore {
+	m := &memoryStore{entities: map[string][]Entity{}, jobs: map[uuid.UUID]*Job{}}
+	for entity, count := range map[string]int{EntityConfigurationItem: cis, EntityRelationship: relationships} {
+		for i := 0; i < count; i++ {
+			m.entities[entity] = append(m.entities[entity], Entity{ID: uuid.New(), Data: map[string]interface{}{}})
+		}
+		sort.Slice(m.entities[entity], func(i, j int) bool {
+			return bytes.Compare(m.entities[entity][i].ID[:], m.entities[entity][j].ID[:]) < 0
+		})
+	}
+	return m
+}
+
+func (m *memoryStore) CountEntities(ctx context.Context, entity string) (int64, error) {
+	return int64(len(m.entities[entity])), nil
+}
+
+func (m *memoryStore) ListEntities(ctx context.Context, entity string, after uuid.UUID, limit int) ([]Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entities := []Entity{}
+	for _, e := range m.entities[entity] {
+		if bytes.Compare(e.ID[:], after[:]) > 0 && len(entities) < limit {
+			entities = append(entities, e)
+		}
+	}
+	return entities, nil
+}
+
+func (m *memoryStore) save(job *Job) {
+	copied := *job
+	copied.Phases = append([]Phase(nil), job.Phases...)
+	m.jobs[job.ID] = &copied
+}
+
+func (m *memoryStore) CreateJob(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.save(job)
+	return nil
+}
+
+func (m *memoryStore) UpdateJob(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.jobs[job.ID]; !ok {
+		return ErrJobNotFound
+	}
+	m.save(job)
+	return nil
+}
+
+func (m *memoryStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	copied.Phases = append([]Phase(nil), job.Phases...)
+	return &copied, nil
+}
+
+func (m *memoryStore) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	m.mu.Lock()
+	ids := []uuid.UUID{}
+	for id := range m.jobs {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	jobs := []*Job{}
+	for _, id := range ids {
+		job, _ := m.GetJob(ctx, id)
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (m *memoryStore) GetUnfinishedJob(ctx context.Context) (*Job, error) {
+	jobs, _ := m.ListJobs(ctx, 0)
+	for _, job := range jobs {
+		if !job.Finished() {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// recordingProcessor records replayed entities. Once block is set it holds
+// the next entity until the run is stopped.
+type recordingProcessor struct {
+	mu       sync.Mutex
+	replayed []string
+	block    bool
+	blocked  chan struct{}
+}
+
+func (p *recordingProcessor) ResyncEntity(ctx context.Context, entityType, entityID string, data map[string]interface{}) error {
+	p.mu.Lock()
+	if p.block {
+		p.block = false
+		p.mu.Unlock()
+		close(p.blocked)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	p.replayed = append(p.replayed, entityType)
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *recordingProcessor) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.replayed)
+}
+
+func waitForStatus(t *testing.T, service *Service, id uuid.UUID, status string) *Job {
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = service.GetJob(context.Background(), id)
+		return err == nil && job.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func unlimited() *int {
+	rate := 0
+	return &rate
+}
+
+func TestService_Start(t *testing.T) {
+	processor := &recordingProcessor{}
+	service := NewService(newMemoryStore(3, 2), processor, 2, 0)
+
+	job, err := service.Start(context.Background(), StartRequest{RateLimit: unlimited()}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, job.Status)
+
+	job = waitForStatus(t, service, job.ID, StatusCompleted)
+	assert.Equal(t, float64(100), job.Progress)
+	assert.Nil(t, job.ETA)
+	assert.NotNil(t, job.CompletedAt)
+	assert.Equal(t, int64(3), job.Phases[0].Processed)
+	assert.Equal(t, int64(2), job.Phases[1].Processed)
+
+	// CIs are replayed before the relationships between them
+	assert.Equal(t, []string{
+		EntityConfigurationItem, EntityConfigurationItem, EntityConfigurationItem,
+		EntityRelationship, EntityRelationship,
+	}, processor.replayed)
+}
+
+func TestService_StartRejectsInvalid(t *testing.T) {
+	service := NewService(newMemoryStore(0, 0), &recordingProcessor{}, 0, 0)
+
+	_, err := service.Start(context.Background(), StartRequest{Entities: []string{"users"}}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	rate := MaxRateLimit + 1
+	_, err = service.Start(context.Background(), StartRequest{RateLimit: &rate}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestService_PauseResumeCancel(t *testing.T) {
+	processor := &recordingProcessor{block: true, blocked: make(chan struct{})}
+	service := NewService(newMemoryStore(4, 0), processor, 1, 0)
+	ctx := context.Background()
+
+	// Pausing interrupts the first CI, which is replayed on resume
+	job, err := service.Start(ctx, StartRequest{RateLimit: unlimited()}, "admin")
+	require.NoError(t, err)
+	<-processor.blocked
+
+	paused, err := service.Pause(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaused, paused.Status)
+	assert.Equal(t, int64(0), paused.Phases[0].Processed)
+	assert.Equal(t, uuid.Nil, paused.Phases[0].Checkpoint)
+
+	// A paused job blocks new ones and cannot be paused again
+	_, err = service.Start(ctx, StartRequest{}, "admin")
+	assert.ErrorIs(t, err, ErrJobRunning)
+	_, err = service.Pause(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	_, err = service.Resume(ctx, job.ID)
+	require.NoError(t, err)
+	resumed := waitForStatus(t, service, job.ID, StatusCompleted)
+	assert.Equal(t, int64(4), resumed.Phases[0].Processed)
+	assert.Equal(t, 4, processor.count())
+
+	_, err = service.Cancel(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	_, err = service.Pause(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_CancelPaused(t *testing.T) {
+	store := newMemoryStore(2, 0)
+	processor := &recordingProcessor{block: true, blocked: make(chan struct{})}
+	service := NewService(store, processor, 1, 0)
+	ctx := context.Background()
+
+	job, err := service.Start(ctx, StartRequest{RateLimit: unlimited()}, "admin")
+	require.NoError(t, err)
+	<-processor.blocked
+	_, err = service.Pause(ctx, job.ID)
+	require.NoError(t, err)
+
+	cancelled, err := service.Cancel(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelled.Status)
+	assert.NotNil(t, cancelled.CompletedAt)
+
+	_, err = service.Resume(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+func TestService_Recover(t *testing.T) {
+	store := newMemoryStore(5, 1)
+	cis := store.entities[EntityConfigurationItem]
+
+	// A job interrupted after the first three CIs
+	interrupted := &Job{
+		ID:     uuid.New(),
+		Status: StatusRunning,
+		Phases: []Phase{
+			{Entity: EntityConfigurationItem, Total: 5, Processed: 3, Checkpoint: cis[2].ID},
+			{Entity: EntityRelationship, Total: 1},
+		},
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.CreateJob(context.Background(), interrupted))
+
+	processor := &recordingProcessor{}
+	service := NewService(store, processor, 10, 0)
+	require.NoError(t, service.Recover(context.Background()))
+
+	job := waitForStatus(t, service, interrupted.ID, StatusCompleted)
+	assert.Equal(t, int64(5), job.Phases[0].Processed)
+	assert.Equal(t, []string{EntityConfigurationItem, EntityConfigurationItem, EntityRelationship}, processor.replayed)
+}
+
+func TestService_RateLimit(t *testing.T) {
+	service := NewService(newMemoryStore(3, 0), &recordingProcessor{}, 10, 0)
+	var mu sync.Mutex
+	waits := []time.Duration{}
+	service.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		waits = append(waits, d)
+		return nil
+	}
+
+	rate := 10
+	job, err := service.Start(context.Background(), StartRequest{RateLimit: &rate}, "admin")
+	require.NoError(t, err)
+	waitForStatus(t, service, job.ID, StatusCompleted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, waits, 2)
+	assert.InDelta(t, float64(200*time.Millisecond), float64(waits[1]), float64(20*time.Millisecond))
+}
+
+func TestJob_Estimate(t *testing.T) {
+	now := time.Now()
+	job := &Job{
+		Status:    StatusRunning,
+		ElapsedMs: 10000,
+		Phases: []Phase{
+			{Entity: EntityConfigurationItem, Total: 100, Processed: 100},
+			{Entity: EntityRelationship, Total: 300, Processed: 0},
+		},
+	}
+	job.estimate(now)
+	assert.Equal(t, float64(25), job.Progress)
+	require.NotNil(t, job.ETA)
+	assert.Equal(t, now.Add(30*time.Second), *job.ETA)
+
+	job.Status = StatusPaused
+	job.estimate(now)
+	assert.Nil(t, job.ETA)
+}
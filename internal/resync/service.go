@@ -0,0 +1,429 @@
+package resync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults
+const (
+	DefaultBatchSize = 500
+	DefaultRateLimit = 200
+)
+
+// Processor replays one entity into the graph store. It must be idempotent:
+// entities processed after the last checkpoint are replayed again on resume.
+type Processor interface {
+	ResyncEntity(ctx context.Context, entityType, entityID string, data map[string]interface{}) error
+}
+
+// StartRequest selects what a resync replays and how fast
+type StartRequest struct {
+	// Entities restricts the resync to these entity types; empty means all of them
+	Entities []string `json:"entities"`
+	// RateLimit is the maximum number of entities per second; nil uses the
+	// configured default and 0 means unlimited
+	RateLimit *int `json:"rate_limit"`
+}
+
+// Service runs resync jobs in the background, one at a time
+type Service struct {
+	store     Store
+	processor Processor
+	batchSize int
+	rateLimit int
+	now       func() time.Time
+	sleep     func(ctx context.Context, d time.Duration) error
+
+	mu     sync.Mutex
+	active *activeRun
+}
+
+// activeRun is the job running on this instance. Setting stop and cancelling
+// the context ends it with that status after the current entity.
+type activeRun struct {
+	jobID  uuid.UUID
+	cancel context.CancelFunc
+	stop   string
+	done   chan struct{}
+}
+
+// NewService creates a new resync service
+func NewService(store Store, processor Processor, batchSize, rateLimit int) *Service {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if rateLimit < 0 {
+		rateLimit = DefaultRateLimit
+	}
+	return &Service{
+		store:     store,
+		processor: processor,
+		batchSize: batchSize,
+		rateLimit: rateLimit,
+		now:       time.Now,
+		sleep:     sleepContext,
+	}
+}
+
+// Start counts the entities to replay and starts a resync in the background.
+// Only one job may be running or paused at a time.
+func (s *Service) Start(ctx context.Context, req StartRequest, requestedBy string) (*Job, error) {
+	entities, err := resolveEntities(req.Entities)
+	if err != nil {
+		return nil, err
+	}
+	rateLimit := s.rateLimit
+	if req.RateLimit != nil {
+		rateLimit = *req.RateLimit
+	}
+	if rateLimit < 0 || rateLimit > MaxRateLimit {
+		return nil, fmt.Errorf("%w: rate_limit must be between 0 and %d", ErrInvalidRequest, MaxRateLimit)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		return nil, ErrJobRunning
+	}
+	unfinished, err := s.store.GetUnfinishedJob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if unfinished != nil {
+		return nil, fmt.Errorf("%w: job %s is %s, resume or cancel it first", ErrJobRunning, unfinished.ID, unfinished.Status)
+	}
+
+	now := s.now()
+	job := &Job{
+		ID:          uuid.New(),
+		Status:      StatusRunning,
+		Phases:      make([]Phase, 0, len(entities)),
+		RateLimit:   rateLimit,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for _, entity := range entities {
+		total, err := s.store.CountEntities(ctx, entity)
+		if err != nil {
+			return nil, err
+		}
+		job.Phases = append(job.Phases, Phase{Entity: entity, Total: total})
+	}
+	if err := s.store.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Resync %s of %v started by %s at %d entities/s", job.ID, entities, requestedBy, rateLimit)
+	return s.launch(job), nil
+}
+
+// Pause stops the running job at its current position
+func (s *Service) Pause(ctx context.Context, id uuid.UUID) (*Job, error) {
+	if err := s.stop(id, StatusPaused); err != nil {
+		return nil, s.stateError(ctx, id)
+	}
+	log.Printf("Resync %s paused", id)
+	return s.GetJob(ctx, id)
+}
+
+// Resume continues a paused job from its checkpoint
+func (s *Service) Resume(ctx context.Context, id uuid.UUID) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		return nil, ErrJobRunning
+	}
+
+	job, err := s.store.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusPaused {
+		return nil, fmt.Errorf("%w: job is %s", ErrInvalidState, job.Status)
+	}
+
+	job.Status = StatusRunning
+	job.UpdatedAt = s.now()
+	if err := s.store.UpdateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Resync %s resumed", id)
+	return s.launch(job), nil
+}
+
+// Cancel ends a running or paused job; what was already replayed stays replayed
+func (s *Service) Cancel(ctx context.Context, id uuid.UUID) (*Job, error) {
+	if err := s.stop(id, StatusCancelled); err == nil {
+		log.Printf("Resync %s cancelled", id)
+		return s.GetJob(ctx, id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, err := s.store.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusPaused {
+		return nil, fmt.Errorf("%w: job is %s", ErrInvalidState, job.Status)
+	}
+
+	completedAt := s.now()
+	job.Status = StatusCancelled
+	job.UpdatedAt = completedAt
+	job.CompletedAt = &completedAt
+	if err := s.store.UpdateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Resync %s cancelled", id)
+	job.estimate(s.now())
+	return job, nil
+}
+
+// Recover resumes a job left running by a crash or restart. Paused jobs stay paused.
+func (s *Service) Recover(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		return nil
+	}
+
+	job, err := s.store.GetUnfinishedJob(ctx)
+	if err != nil {
+		return err
+	}
+	if job == nil || job.Status != StatusRunning {
+		return nil
+	}
+
+	log.Printf("Resync %s was interrupted, resuming from its checkpoint", job.ID)
+	s.launch(job)
+	return nil
+}
+
+// GetJob retrieves a job with its progress
+func (s *Service) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := s.store.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job.estimate(s.now())
+	return job, nil
+}
+
+// ListJobs retrieves the latest jobs with their progress, newest first
+func (s *Service) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	jobs, err := s.store.ListJobs(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	for _, job := range jobs {
+		job.estimate(now)
+	}
+	return jobs, nil
+}
+
+// launch runs a job in the background; s.mu must be held
+func (s *Service) launch(job *Job) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &activeRun{jobID: job.ID, cancel: cancel, done: make(chan struct{})}
+	s.active = run
+
+	started := *job
+	started.Phases = append([]Phase(nil), job.Phases...)
+	started.estimate(s.now())
+	go s.run(ctx, run, job)
+	return &started
+}
+
+// stop ends the active run with a status and waits for it to save its checkpoint
+func (s *Service) stop(id uuid.UUID, status string) error {
+	s.mu.Lock()
+	run := s.active
+	if run == nil || run.jobID != id {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: job is not running", ErrInvalidState)
+	}
+	run.stop = status
+	run.cancel()
+	s.mu.Unlock()
+
+	<-run.done
+	return nil
+}
+
+// stateError reports a missing job as not found rather than in the wrong state
+func (s *Service) stateError(ctx context.Context, id uuid.UUID) error {
+	job, err := s.store.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: job is %s", ErrInvalidState, job.Status)
+}
+
+// run replays the entities and records how the job ended
+func (s *Service) run(ctx context.Context, run *activeRun, job *Job) {
+	err := s.resync(ctx, job)
+
+	// A new job can start once this one is recorded as stopped
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case err == nil:
+		job.Status = StatusCompleted
+	case run.stop != "":
+		job.Status = run.stop
+	default:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+	job.UpdatedAt = s.now()
+	if job.Finished() {
+		completedAt := job.UpdatedAt
+		job.CompletedAt = &completedAt
+	}
+	if err := s.store.UpdateJob(context.Background(), job); err != nil {
+		log.Printf("Failed to save resync %s: %v", job.ID, err)
+	}
+
+	run.cancel()
+	s.active = nil
+	close(run.done)
+
+	var processed, failed int64
+	for _, phase := range job.Phases {
+		processed += phase.Processed
+		failed += phase.Failed
+	}
+	log.Printf("Resync %s %s: %d entities processed, %d failed", job.ID, job.Status, processed, failed)
+}
+
+// resync works through the phases from the checkpoint, saving it after every page
+func (s *Service) resync(ctx context.Context, job *Job) error {
+	mark := s.now()
+	defer s.tick(job, &mark)
+
+	var pace time.Duration
+	if job.RateLimit > 0 {
+		pace = time.Second / time.Duration(job.RateLimit)
+	}
+	next := s.now()
+
+	for job.CurrentPhase < len(job.Phases) {
+		phase := &job.Phases[job.CurrentPhase]
+		for {
+			entities, err := s.store.ListEntities(ctx, phase.Entity, phase.Checkpoint, s.batchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+			if len(entities) == 0 {
+				break
+			}
+
+			for _, entity := range entities {
+				if pace > 0 {
+					if wait := next.Sub(s.now()); wait > 0 {
+						if err := s.sleep(ctx, wait); err != nil {
+							s.checkpoint(job, &mark)
+							return err
+						}
+					}
+					if now := s.now(); next.Before(now) {
+						next = now
+					}
+					next = next.Add(pace)
+				}
+				if ctx.Err() != nil {
+					s.checkpoint(job, &mark)
+					return ctx.Err()
+				}
+
+				if err := s.processor.ResyncEntity(ctx, phase.Entity, entity.ID.String(), entity.Data); err != nil {
+					if ctx.Err() != nil {
+						// Interrupted mid-entity: replay it on resume
+						s.checkpoint(job, &mark)
+						return ctx.Err()
+					}
+					phase.Failed++
+					log.Printf("Resync %s failed to replay %s %s: %v", job.ID, phase.Entity, entity.ID, err)
+				}
+				phase.Processed++
+				phase.Checkpoint = entity.ID
+				if phase.Processed > phase.Total {
+					phase.Total = phase.Processed
+				}
+			}
+			s.checkpoint(job, &mark)
+		}
+		job.CurrentPhase++
+	}
+	return nil
+}
+
+// checkpoint saves the position reached; a failure only costs replaying a page on resume
+func (s *Service) checkpoint(job *Job, mark *time.Time) {
+	s.tick(job, mark)
+	job.UpdatedAt = s.now()
+	if err := s.store.UpdateJob(context.Background(), job); err != nil {
+		log.Printf("Failed to checkpoint resync %s: %v", job.ID, err)
+	}
+}
+
+// tick adds the whole milliseconds run since mark to the job, carrying the rest over
+func (s *Service) tick(job *Job, mark *time.Time) {
+	elapsed := s.now().Sub(*mark).Milliseconds()
+	job.ElapsedMs += elapsed
+	*mark = mark.Add(time.Duration(elapsed) * time.Millisecond)
+}
+
+// resolveEntities validates the requested entity types and puts them in resync order
+func resolveEntities(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return append([]string(nil), Entities...), nil
+	}
+
+	wanted := map[string]bool{}
+	for _, entity := range requested {
+		if _, ok := entitySources[entity]; !ok {
+			return nil, fmt.Errorf("%w: unknown entity %q", ErrInvalidRequest, entity)
+		}
+		wanted[entity] = true
+	}
+
+	entities := []string{}
+	for _, entity := range Entities {
+		if wanted[entity] {
+			entities = append(entities, entity)
+		}
+	}
+	return entities, nil
+}
+
+// sleepContext waits for d unless ctx is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
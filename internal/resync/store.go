@@ -0,0 +1,219 @@
+package resync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store reads the entities to replay and persists jobs
+type Store interface {
+	// CountEntities returns the number of live entities of a type
+	CountEntities(ctx context.Context, entity string) (int64, error)
+	// ListEntities returns up to limit live entities of a type with IDs
+	// greater than after, ordered by ID
+	ListEntities(ctx context.Context, entity string, after uuid.UUID, limit int) ([]Entity, error)
+	CreateJob(ctx context.Context, job *Job) error
+	// UpdateJob saves the progress and checkpoint of a job
+	UpdateJob(ctx context.Context, job *Job) error
+	GetJob(ctx context.Context, id uuid.UUID) (*Job, error)
+	ListJobs(ctx context.Context, limit int) ([]*Job, error)
+	// GetUnfinishedJob returns the latest running or paused job, or nil when there is none
+	GetUnfinishedJob(ctx context.Context) (*Job, error)
+}
+
+// entitySource describes where an entity type is read from. data builds the
+// payload the sync service expects for that type.
+type entitySource struct {
+	table string
+	data  string
+	live  string
+}
+
+var entitySources = map[string]entitySource{
+	EntityConfigurationItem: {
+		table: "configuration_items",
+		data: `jsonb_build_object('id', id, 'name', name, 'type', type,
+			'attributes', COALESCE(attributes, '{}'::jsonb), 'tags', COALESCE(to_jsonb(tags), '[]'::jsonb))`,
+		live: "is_deleted = false",
+	},
+	EntityRelationship: {
+		table: "ci_relationships",
+		data: `jsonb_build_object('id', id, 'source_id', source_ci_id, 'target_id', target_ci_id, 'type', type,
+			'attributes', COALESCE(attributes, '{}'::jsonb))`,
+		live: "is_active = true",
+	},
+}
+
+// PostgresStore reads entities from their tables and keeps jobs in the sync_resync_jobs table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed resync store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// CountEntities counts the live entities of a type
+func (s *PostgresStore) CountEntities(ctx context.Context, entity string) (int64, error) {
+	source, ok := entitySources[entity]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown entity %s", ErrInvalidRequest, entity)
+	}
+
+	var count int64
+	if err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM `+source.table+` WHERE `+source.live); err != nil {
+		return 0, fmt.Errorf("failed to count %s entities: %w", entity, err)
+	}
+	return count, nil
+}
+
+// ListEntities retrieves a page of live entities of a type
+func (s *PostgresStore) ListEntities(ctx context.Context, entity string, after uuid.UUID, limit int) ([]Entity, error) {
+	source, ok := entitySources[entity]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown entity %s", ErrInvalidRequest, entity)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, `+source.data+`
+		FROM `+source.table+`
+		WHERE id > $1 AND `+source.live+`
+		ORDER BY id
+		LIMIT $2`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s entities: %w", entity, err)
+	}
+	defer rows.Close()
+
+	entities := []Entity{}
+	for rows.Next() {
+		var e Entity
+		var data []byte
+		if err := rows.Scan(&e.ID, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan %s entity: %w", entity, err)
+		}
+		if err := json.Unmarshal(data, &e.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s entity: %w", entity, err)
+		}
+		entities = append(entities, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list %s entities: %w", entity, err)
+	}
+	return entities, nil
+}
+
+// CreateJob inserts a job
+func (s *PostgresStore) CreateJob(ctx context.Context, job *Job) error {
+	phases, err := json.Marshal(job.Phases)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resync phases: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO sync_resync_jobs (id, status, phases, current_phase, rate_limit, requested_by, created_at, updated_at, elapsed_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		job.ID, job.Status, phases, job.CurrentPhase, job.RateLimit, job.RequestedBy, job.CreatedAt, job.UpdatedAt, job.ElapsedMs)
+	if err != nil {
+		return fmt.Errorf("failed to create resync job: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob saves the status, progress and checkpoint of a job
+func (s *PostgresStore) UpdateJob(ctx context.Context, job *Job) error {
+	phases, err := json.Marshal(job.Phases)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resync phases: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE sync_resync_jobs
+		SET status = $2, phases = $3, current_phase = $4, updated_at = $5, completed_at = $6,
+		    elapsed_ms = $7, error = NULLIF($8, '')
+		WHERE id = $1`,
+		job.ID, job.Status, phases, job.CurrentPhase, job.UpdatedAt, job.CompletedAt, job.ElapsedMs, job.Error)
+	if err != nil {
+		return fmt.Errorf("failed to update resync job: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+const jobColumns = `id, status, phases, current_phase, rate_limit, requested_by, created_at, updated_at, completed_at, elapsed_ms, COALESCE(error, '')`
+
+// scanJob reads a job row selected with jobColumns
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var job Job
+	var phases []byte
+	var completedAt sql.NullTime
+	if err := row.Scan(&job.ID, &job.Status, &phases, &job.CurrentPhase, &job.RateLimit, &job.RequestedBy,
+		&job.CreatedAt, &job.UpdatedAt, &completedAt, &job.ElapsedMs, &job.Error); err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if err := json.Unmarshal(phases, &job.Phases); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resync phases: %w", err)
+	}
+	return &job, nil
+}
+
+// GetJob retrieves a job
+func (s *PostgresStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := scanJob(s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM sync_resync_jobs WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get resync job: %w", err)
+	}
+	return job, nil
+}
+
+// ListJobs retrieves the latest jobs, newest first
+func (s *PostgresStore) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM sync_resync_jobs ORDER BY created_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resync jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan resync job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list resync jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetUnfinishedJob retrieves the latest running or paused job
+func (s *PostgresStore) GetUnfinishedJob(ctx context.Context) (*Job, error) {
+	job, err := scanJob(s.db.QueryRowContext(ctx, `
+		SELECT `+jobColumns+` FROM sync_resync_jobs
+		WHERE status IN ('running', 'paused')
+		ORDER BY created_at DESC
+		LIMIT 1`))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get unfinished resync job: %w", err)
+	}
+	return job, nil
+}
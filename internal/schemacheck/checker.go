@@ -0,0 +1,171 @@
+package schemacheck
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Discrepancy kinds
+const (
+	KindMissingTable      = "missing_table"
+	KindMissingColumn     = "missing_column"
+	KindMissingIndex      = "missing_index"
+	KindMissingConstraint = "missing_constraint"
+	KindInspectionFailed  = "inspection_failed"
+)
+
+// Report statuses
+const (
+	StatusOK    = "ok"
+	StatusDrift = "drift"
+)
+
+// PostgresSchema is the observed PostgreSQL schema: columns by table and index names
+type PostgresSchema struct {
+	Columns map[string]map[string]bool
+	Indexes map[string]bool
+}
+
+// PostgresInspector reads the live PostgreSQL schema
+type PostgresInspector interface {
+	InspectPostgres(ctx context.Context) (*PostgresSchema, error)
+}
+
+// Neo4jInspector reads the names of the live Neo4j constraints
+type Neo4jInspector interface {
+	InspectNeo4jConstraints(ctx context.Context) (map[string]bool, error)
+}
+
+// Discrepancy is one difference between the expected and the live schema
+type Discrepancy struct {
+	Kind     string `json:"kind"`
+	Database string `json:"database"`
+	Object   string `json:"object"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Report is the outcome of a schema check
+type Report struct {
+	Status        string        `json:"status"`
+	CheckedAt     time.Time     `json:"checked_at"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// OK reports whether the live schema matches the expectations
+func (r *Report) OK() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// Checker compares the live schema against the expectations and keeps the last report
+type Checker struct {
+	expected Expectations
+	postgres PostgresInspector
+	neo4j    Neo4jInspector
+
+	mu   sync.RWMutex
+	last *Report
+}
+
+// NewChecker creates a new schema checker. neo4j may be nil to skip graph checks.
+func NewChecker(expected Expectations, postgres PostgresInspector, neo4j Neo4jInspector) *Checker {
+	return &Checker{expected: expected, postgres: postgres, neo4j: neo4j}
+}
+
+// Check inspects the databases and reports every discrepancy
+func (c *Checker) Check(ctx context.Context) *Report {
+	report := &Report{CheckedAt: time.Now(), Discrepancies: []Discrepancy{}}
+
+	if c.postgres != nil {
+		live, err := c.postgres.InspectPostgres(ctx)
+		if err != nil {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{Kind: KindInspectionFailed, Database: "postgres", Detail: err.Error()})
+		} else {
+			report.Discrepancies = append(report.Discrepancies, comparePostgres(c.expected.Tables, live)...)
+		}
+	}
+
+	if c.neo4j != nil && len(c.expected.Neo4jConstraints) > 0 {
+		constraints, err := c.neo4j.InspectNeo4jConstraints(ctx)
+		if err != nil {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{Kind: KindInspectionFailed, Database: "neo4j", Detail: err.Error()})
+		} else {
+			for _, name := range c.expected.Neo4jConstraints {
+				if !constraints[name] {
+					report.Discrepancies = append(report.Discrepancies, Discrepancy{Kind: KindMissingConstraint, Database: "neo4j", Object: name})
+				}
+			}
+		}
+	}
+
+	report.Status = StatusOK
+	if !report.OK() {
+		report.Status = StatusDrift
+	}
+
+	c.mu.Lock()
+	c.last = report
+	c.mu.Unlock()
+
+	return report
+}
+
+// LastReport returns the most recent report, or nil if no check has run
+func (c *Checker) LastReport() *Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.last
+}
+
+// RunStartupCheck checks the schema, logs every discrepancy and, in strict mode,
+// returns an error so the caller can refuse to start
+func (c *Checker) RunStartupCheck(ctx context.Context, strict bool) (*Report, error) {
+	report := c.Check(ctx)
+	if report.OK() {
+		log.Printf("Schema self-check passed")
+		return report, nil
+	}
+
+	for _, d := range report.Discrepancies {
+		log.Printf("Schema drift detected: %s %s %s %s", d.Database, d.Kind, d.Object, d.Detail)
+	}
+
+	if strict {
+		return report, fmt.Errorf("schema self-check found %d discrepancies", len(report.Discrepancies))
+	}
+	return report, nil
+}
+
+// comparePostgres lists the expected tables, columns and indexes missing from the live schema
+func comparePostgres(tables []Table, live *PostgresSchema) []Discrepancy {
+	var discrepancies []Discrepancy
+
+	for _, table := range tables {
+		columns, ok := live.Columns[table.Name]
+		if !ok {
+			discrepancies = append(discrepancies, Discrepancy{Kind: KindMissingTable, Database: "postgres", Object: table.Name})
+			continue
+		}
+
+		for _, column := range table.Columns {
+			if !columns[column] {
+				discrepancies = append(discrepancies, Discrepancy{Kind: KindMissingColumn, Database: "postgres", Object: table.Name + "." + column})
+			}
+		}
+
+		for _, index := range table.Indexes {
+			if !live.Indexes[index] {
+				discrepancies = append(discrepancies, Discrepancy{Kind: KindMissingIndex, Database: "postgres", Object: index, Detail: "on " + table.Name})
+			}
+		}
+	}
+
+	sort.SliceStable(discrepancies, func(i, j int) bool {
+		return discrepancies[i].Object < discrepancies[j].Object
+	})
+
+	return discrepancies
+}
@@ -0,0 +1,90 @@
+package schemacheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePostgres struct {
+	schema *PostgresSchema
+	err    error
+}
+
+func (f *fakePostgres) InspectPostgres(ctx context.Context) (*PostgresSchema, error) {
+	return f.schema, f.err
+}
+
+type fakeNeo4j struct {
+	constraints map[string]bool
+}
+
+func (f *fakeNeo4j) InspectNeo4jConstraints(ctx context.Context) (map[string]bool, error) {
+	return f.constraints, nil
+}
+
+func testExpectations() Expectations {
+	return Expectations{
+		Tables: []Table{
+			{Name: "configuration_items", Columns: []string{"id", "name"}, Indexes: []string{"idx_cis_type"}},
+			{Name: "ci_relationships", Columns: []string{"id", "state"}},
+		},
+		Neo4jConstraints: []string{"ci_id_unique"},
+	}
+}
+
+func TestChecker_MatchingSchema(t *testing.T) {
+	postgres := &fakePostgres{schema: &PostgresSchema{
+		Columns: map[string]map[string]bool{
+			"configuration_items": {"id": true, "name": true, "extra": true},
+			"ci_relationships":    {"id": true, "state": true},
+		},
+		Indexes: map[string]bool{"idx_cis_type": true},
+	}}
+	checker := NewChecker(testExpectations(), postgres, &fakeNeo4j{constraints: map[string]bool{"ci_id_unique": true}})
+
+	report, err := checker.RunStartupCheck(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Empty(t, report.Discrepancies)
+	assert.Same(t, report, checker.LastReport())
+}
+
+func TestChecker_ReportsDrift(t *testing.T) {
+	postgres := &fakePostgres{schema: &PostgresSchema{
+		Columns: map[string]map[string]bool{
+			"configuration_items": {"id": true},
+		},
+		Indexes: map[string]bool{},
+	}}
+	checker := NewChecker(testExpectations(), postgres, &fakeNeo4j{constraints: map[string]bool{}})
+
+	report := checker.Check(context.Background())
+	assert.Equal(t, StatusDrift, report.Status)
+
+	kinds := make(map[string]string)
+	for _, d := range report.Discrepancies {
+		kinds[d.Object] = d.Kind
+	}
+	assert.Equal(t, map[string]string{
+		"configuration_items.name": KindMissingColumn,
+		"idx_cis_type":             KindMissingIndex,
+		"ci_relationships":         KindMissingTable,
+		"ci_id_unique":             KindMissingConstraint,
+	}, kinds)
+}
+
+func TestChecker_StrictModeFailsOnDrift(t *testing.T) {
+	checker := NewChecker(testExpectations(), &fakePostgres{err: errors.New("connection refused")}, nil)
+
+	report, err := checker.RunStartupCheck(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, report.Discrepancies, 1)
+	assert.Equal(t, KindInspectionFailed, report.Discrepancies[0].Kind)
+
+	_, err = checker.RunStartupCheck(context.Background(), true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package schemacheck
+
+// Table is a table the code reads or writes, with the columns and indexes it relies on
+type Table struct {
+	Name    string
+	Columns []string
+	Indexes []string
+}
+
+// Expectations describes the database objects the code expects to exist
+type Expectations struct {
+	Tables           []Table
+	Neo4jConstraints []string
+}
+
+// DefaultExpectations returns the schema expected by the repositories and the sync service.
+// Keep it in line with the migrations when adding tables, columns or indexes the code depends on.
+func DefaultExpectations() Expectations {
+	return Expectations{
+		Tables: []Table{
+			{
+				Name: "configuration_items",
+				Columns: []string{
+					"id", "name", "type", "description", "status", "criticality", "owner", "location",
+					"org_unit", "cost_center",
+					"attributes", "tags", "install_date", "warranty_expiry", "last_updated", "last_scanned",
+					"is_active", "is_deleted", "created_at", "updated_at", "created_by", "updated_by", "org_id",
+				},
+				Indexes: []string{"idx_configuration_items_freshness", "idx_configuration_items_freshness_reference", "idx_configuration_items_asset_tag", "idx_cis_updated_at", "idx_cis_deleted", "idx_cis_tenant", "idx_cis_ip_address", "idx_cis_management_ip", "idx_cis_name_prefix", "idx_cis_name_trgm", "idx_cis_attributes_path_ops", "idx_cis_org_id"},
+			},
+			{
+				Name: "ci_relationships",
+				Columns: []string{
+					"id", "source_ci_id", "target_ci_id", "type", "attributes", "description", "is_active",
+					"state", "state_changed_at", "state_changed_by", "created_at", "updated_at", "created_by", "updated_by", "is_primary", "org_id",
+				},
+				Indexes: []string{"idx_ci_relationships_state", "idx_ci_relationships_source_state", "idx_ci_relationships_target_state", "idx_ci_relationships_primary", "idx_ci_relationships_cloud_connector", "idx_ci_relationships_attributes", "idx_ci_relationships_state_created", "idx_ci_relationships_org_id"},
+			},
+			{
+				Name:    "ci_type_schemas",
+				Columns: []string{"id", "name", "description", "attributes", "is_active", "created_at", "updated_at", "created_by", "updated_by", "org_id"},
+			},
+			{
+				Name:    "relationship_type_schemas",
+				Columns: []string{"id", "name", "description", "attributes", "is_active", "created_at", "updated_at", "created_by", "updated_by", "org_id"},
+			},
+			{
+				Name:    "ci_attribute_provenance",
+				Columns: []string{"ci_id", "attribute", "source_type", "source_name", "source_id", "set_by", "set_at"},
+			},
+			{
+				Name: "users",
+				Columns: []string{
+					"id", "username", "email", "password_hash", "first_name", "last_name",
+					"is_active", "is_verified", "created_at", "updated_at", "password_changed_at", "org_id",
+				},
+				Indexes: []string{"idx_users_org_id"},
+			},
+			{
+				Name:    "sessions",
+				Columns: []string{"id", "user_id", "token", "refresh_token", "ip_address", "user_agent", "expires_at", "last_active_at", "created_at", "is_active", "device_id", "ua_family", "ip_prefix"},
+				Indexes: []string{"idx_sessions_user_id", "idx_sessions_token", "idx_sessions_refresh_token"},
+			},
+			{Name: "roles", Columns: []string{"id", "name", "org_id"}, Indexes: []string{"idx_roles_org_id", "idx_roles_org_id_name"}},
+			{Name: "permissions", Columns: []string{"id", "name"}},
+			{Name: "user_roles", Columns: []string{"user_id", "role_id"}},
+			{Name: "role_permissions", Columns: []string{"role_id", "permission_id"}},
+			{
+				Name:    "sync_events",
+				Columns: []string{"id", "entity_type", "entity_id", "action", "data", "status", "retry_count", "error_message", "created_at", "updated_at", "processed_at"},
+				Indexes: []string{"idx_sync_events_status", "idx_sync_events_entity", "idx_sync_events_created_at"},
+			},
+			{Name: "sync_stats"},
+			{Name: "sync_log", Columns: []string{"id", "event_id", "entity_type", "entity_id", "action", "status", "duration_ms", "error_message", "created_at"}},
+			{Name: "sync_conflicts", Columns: []string{"severity", "escalated_at"}, Indexes: []string{"idx_sync_conflicts_unresolved_age"}},
+			{Name: "sync_alerts"},
+			{Name: "sync_fallback_operations"},
+			{Name: "feature_flags", Columns: []string{"key", "description", "enabled", "created_at", "updated_at", "updated_by"}},
+			{Name: "feature_flag_overrides", Columns: []string{"flag_key", "scope", "value", "enabled", "updated_at", "updated_by"}},
+			{Name: "maintenance_mode", Columns: []string{"id", "read_only", "reason", "retry_after_seconds", "expected_end", "updated_at", "updated_by"}},
+			{Name: "role_inheritance", Columns: []string{"role_id", "inherits_role_id"}},
+			{
+				Name:    "access_review_reports",
+				Columns: []string{"id", "status", "requested_by", "requested_at", "completed_at", "error", "user_count", "matrix"},
+				Indexes: []string{"idx_access_review_reports_requested_at"},
+			},
+			{
+				Name:    "refresh_tokens",
+				Columns: []string{"id", "family_id", "user_id", "issued_at", "expires_at", "rotated_at", "replaced_by", "revoked_at", "revoke_reason"},
+				Indexes: []string{"idx_refresh_tokens_family_id"},
+			},
+			{Name: "security_events", Columns: []string{"id", "event_type", "user_id", "details", "ip_address", "user_agent", "occurred_at"}},
+			{Name: "session_limits", Columns: []string{"scope", "name", "max_sessions", "updated_at", "updated_by"}},
+			{
+				Name:    "ownership_transfers",
+				Columns: []string{"id", "from_owner", "to_owner", "status", "reason", "initiated_by", "deadline", "created_at", "responded_at", "responded_by", "note"},
+				Indexes: []string{"idx_ownership_transfers_pending_deadline"},
+			},
+			{Name: "ownership_transfer_items", Columns: []string{"transfer_id", "ci_id"}},
+			{Name: "org_units", Columns: []string{"code", "name", "parent_code", "is_active", "created_at", "updated_at"}},
+			{Name: "cost_centers", Columns: []string{"code", "name", "org_unit_code", "is_active", "created_at", "updated_at"}},
+			{Name: "auto_tag_rules", Columns: []string{"id", "name", "attribute", "operator", "value", "tag", "remove_unmatched", "enabled", "created_at", "updated_at", "updated_by"}},
+			{
+				Name:    "ci_type_migrations",
+				Columns: []string{"id", "source_type", "plan", "status", "requested_by", "created_at", "completed_at", "scanned", "migrated", "unmatched", "invalid", "failed", "skipped", "relationships_updated", "failures", "error"},
+				Indexes: []string{"idx_ci_type_migrations_created_at"},
+			},
+			{Name: "script_hooks", Columns: []string{"id", "name", "phase", "ci_types", "script", "priority", "timeout_ms", "enabled", "created_at", "updated_at", "updated_by"}},
+			{
+				Name:    "script_hook_executions",
+				Columns: []string{"id", "hook_id", "hook_name", "ci_id", "phase", "event", "outcome", "message", "logs", "duration_ms", "executed_at"},
+				Indexes: []string{"idx_script_hook_executions_hook_id"},
+			},
+			{
+				Name:    "sync_resync_jobs",
+				Columns: []string{"id", "status", "phases", "current_phase", "rate_limit", "requested_by", "created_at", "updated_at", "completed_at", "elapsed_ms", "error"},
+				Indexes: []string{"idx_sync_resync_jobs_created_at", "idx_sync_resync_jobs_unfinished"},
+			},
+			{Name: "sync_exclusions", Columns: []string{"scope", "value", "reason", "created_at", "created_by"}},
+			{Name: "relationship_path_rules", Columns: []string{"id", "name", "description", "relationship_types", "attribute", "constraint_kind", "source_values", "target_values", "enabled", "created_at", "updated_at", "updated_by"}},
+			{Name: "ci_costs", Columns: []string{"ci_id", "period", "amount", "currency", "source", "resource_id", "updated_at"}, Indexes: []string{"idx_ci_costs_period"}},
+			{Name: "audit_retention_policies", Columns: []string{"id", "name", "entity_type", "retain_days", "archive", "enabled", "created_at", "updated_at", "updated_by"}},
+			{Name: "audit_legal_holds", Columns: []string{"id", "name", "reason", "entity_type", "entity_id", "from_time", "to_time", "created_by", "created_at", "released_at", "released_by"}, Indexes: []string{"idx_audit_legal_holds_active"}},
+			{Name: "audit_archives", Columns: []string{"id", "archive_key", "policy_id", "from_time", "to_time", "entry_count", "created_at"}, Indexes: []string{"idx_audit_archives_time"}},
+			{Name: "change_anomalies", Columns: []string{"id", "kind", "severity", "subject", "message", "count", "threshold", "window_start", "window_end", "detected_at", "acknowledged_at", "acknowledged_by"}, Indexes: []string{"idx_change_anomalies_subject", "idx_change_anomalies_detected_at"}},
+			{Name: "sync_force_jobs", Columns: []string{"id", "status", "filter", "phases", "current_phase", "rate_limit", "requested_by", "created_at", "updated_at", "completed_at", "error"}, Indexes: []string{"idx_sync_force_jobs_created_at", "idx_sync_force_jobs_running"}},
+			{Name: "import_journal_jobs", Columns: []string{"id", "source", "created_by", "completed_at", "created_count", "updated_count", "rolled_back_at", "rolled_back_by", "rollback"}, Indexes: []string{"idx_import_journal_jobs_completed_at"}},
+			{Name: "import_journal_entries", Columns: []string{"job_id", "seq", "entity_type", "entity_id", "action", "before", "imported_at"}},
+			{Name: "quarantined_cis", Columns: []string{"id", "source", "reason", "errors", "ci", "ci_type", "status", "created_at", "updated_at", "reviewed_at", "reviewed_by", "reject_reason", "created_ci_id"}, Indexes: []string{"idx_quarantined_cis_status"}},
+			{Name: "user_dashboards", Columns: []string{"user_id", "preferences", "version", "updated_at"}},
+			{Name: "tenant_shards", Columns: []string{"tenant", "shard", "updated_at"}},
+			{Name: "ci_relationship_versions", Columns: []string{"version_id", "relationship_id", "source_ci_id", "target_ci_id", "type", "attributes", "description", "is_active", "state", "valid_from", "valid_to", "changed_by", "backfilled", "is_primary"}, Indexes: []string{"idx_ci_relationship_versions_relationship", "idx_ci_relationship_versions_source", "idx_ci_relationship_versions_target"}},
+			{Name: "service_accounts", Columns: []string{"id", "name", "description", "owner_id", "owner_team", "roles", "expires_at", "is_active", "last_used_at", "created_at", "updated_at", "created_by", "updated_by"}, Indexes: []string{"idx_service_accounts_owner_id"}},
+			{Name: "service_account_credentials", Columns: []string{"id", "account_id", "kind", "prefix", "secret_hash", "description", "expires_at", "last_used_at", "revoked_at", "created_at", "created_by"}, Indexes: []string{"idx_service_account_credentials_account_id"}},
+			{Name: "archived_cis", Columns: []string{"id", "name", "type", "data", "history", "last_activity_at", "archived_at"}, Indexes: []string{"idx_archived_cis_type_name"}},
+			{Name: "archived_ci_relationships", Columns: []string{"id", "source_ci_id", "target_ci_id", "data", "archived_at"}, Indexes: []string{"idx_archived_ci_relationships_source", "idx_archived_ci_relationships_target"}},
+			{Name: "ci_heartbeats", Columns: []string{"ci_id", "source", "last_heartbeat_at", "unreachable_since", "previous_status", "created_at"}, Indexes: []string{"idx_ci_heartbeats_reachable"}},
+			{Name: "sync_conflict_escalations", Columns: []string{"id", "conflict_id", "action", "from_severity", "to_severity", "role", "recipients", "details", "created_at"}, Indexes: []string{"idx_sync_conflict_escalations_conflict"}},
+			{Name: "ci_external_ids", Columns: []string{"id", "ci_id", "system", "external_id", "created_at", "created_by"}, Indexes: []string{"idx_ci_external_ids_ci"}},
+			{Name: "service_tree_members", Columns: []string{"service_id", "ci_id", "depth", "ci_name", "ci_type", "ci_status", "updated_at"}, Indexes: []string{"idx_service_tree_members_ci"}},
+			{Name: "ci_correction_suggestions", Columns: []string{"id", "ci_id", "changes", "reason", "status", "suggested_by", "created_at", "reviewed_by", "reviewed_at", "review_note"}, Indexes: []string{"idx_ci_correction_suggestions_queue", "idx_ci_correction_suggestions_ci"}},
+			{Name: "user_offboarding_reports", Columns: []string{"id", "requested_by", "requested_at", "reason", "reassign_to", "summary", "users"}, Indexes: []string{"idx_user_offboarding_reports_requested_at"}},
+			{Name: "ci_aliases", Columns: []string{"id", "ci_id", "kind", "ci_type", "name", "former_ci_id", "reason", "created_at", "created_by"}, Indexes: []string{"idx_ci_aliases_name", "idx_ci_aliases_former_ci", "idx_ci_aliases_ci"}},
+			{Name: "attribute_triggers", Columns: []string{"id", "name", "ci_types", "attribute", "from_value", "to_value", "actions", "enabled", "created_at", "updated_at", "updated_by"}},
+			{
+				Name:    "attribute_trigger_executions",
+				Columns: []string{"id", "trigger_id", "trigger_name", "ci_id", "action", "attribute", "from_value", "to_value", "outcome", "message", "duration_ms", "executed_at"},
+				Indexes: []string{"idx_attribute_trigger_executions_trigger_id"},
+			},
+			{Name: "ci_revisions", Columns: []string{"ci_id", "revision", "action", "snapshot", "changed_by", "changed_at"}},
+			{Name: "incidents", Columns: []string{"id", "source", "external_id", "title", "started_at", "resolved_at", "ingested_at"}, Indexes: []string{"idx_incidents_started_at"}},
+			{Name: "incident_cis", Columns: []string{"incident_id", "ci_id"}, Indexes: []string{"idx_incident_cis_ci_id"}},
+			{Name: "relationship_incident_scores", Columns: []string{"relationship_id", "co_occurrences", "target_incidents", "confidence", "critical", "updated_at"}},
+			{Name: "webhook_endpoints", Columns: []string{"id", "name", "url", "secret", "events", "filter", "active", "created_by", "created_at", "updated_at"}},
+			{Name: "webhook_deliveries", Columns: []string{"id", "endpoint_id", "event_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"}, Indexes: []string{"idx_webhook_deliveries_due", "idx_webhook_deliveries_endpoint"}},
+			{Name: "ci_purge_requests", Columns: []string{"id", "ci_id", "reason", "audit_policy", "status", "requested_by", "requested_at", "expires_at", "required_approvals", "approvals", "report", "executed_by", "executed_at", "removed", "cancelled_by"}, Indexes: []string{"idx_ci_purge_requests_requested_at", "idx_ci_purge_requests_status"}},
+			{Name: "alert_rules", Columns: []string{"id", "name", "description", "metric", "operator", "threshold", "for_seconds", "severity", "enabled", "state", "last_value", "pending_since", "firing_since", "last_evaluated_at", "created_by", "created_at", "updated_at"}, Indexes: []string{"idx_alert_rules_enabled"}},
+			{Name: "organizations", Columns: []string{"id", "slug", "name", "created_by", "created_at"}},
+			{Name: "organization_members", Columns: []string{"organization_id", "user_id", "is_default", "created_at"}, Indexes: []string{"idx_organization_members_user", "idx_organization_members_default"}},
+			{Name: "api_keys", Columns: []string{"id", "name", "description", "owner_id", "org", "permissions", "prefix", "secret_hash", "expires_at", "last_used_at", "rotated_at", "revoked_at", "revoked_by", "created_at"}, Indexes: []string{"idx_api_keys_owner_id"}},
+			{Name: "log_settings", Columns: []string{"id", "slow_query_threshold_ms", "debug_sample_rate", "sample_rates", "updated_by", "updated_at"}},
+		},
+		Neo4jConstraints: []string{"ci_id_unique"},
+	}
+}
@@ -0,0 +1,91 @@
+package schemacheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// SQLInspector inspects PostgreSQL through information_schema and pg_indexes
+type SQLInspector struct {
+	db *sqlx.DB
+}
+
+// NewSQLInspector creates a new PostgreSQL inspector
+func NewSQLInspector(db *sqlx.DB) *SQLInspector {
+	return &SQLInspector{db: db}
+}
+
+// InspectPostgres reads the columns and indexes of the current schema
+func (i *SQLInspector) InspectPostgres(ctx context.Context) (*PostgresSchema, error) {
+	schema := &PostgresSchema{
+		Columns: make(map[string]map[string]bool),
+		Indexes: make(map[string]bool),
+	}
+
+	columnRows, err := i.db.QueryxContext(ctx, `
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	defer columnRows.Close()
+
+	for columnRows.Next() {
+		var table, column string
+		if err := columnRows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		if schema.Columns[table] == nil {
+			schema.Columns[table] = make(map[string]bool)
+		}
+		schema.Columns[table][column] = true
+	}
+
+	var indexes []string
+	if err := i.db.SelectContext(ctx, &indexes, `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema()`); err != nil {
+		return nil, fmt.Errorf("failed to read indexes: %w", err)
+	}
+	for _, index := range indexes {
+		schema.Indexes[index] = true
+	}
+
+	return schema, nil
+}
+
+// GraphInspector inspects Neo4j constraints
+type GraphInspector struct {
+	driver   neo4j.DriverWithContext
+	database string
+}
+
+// NewGraphInspector creates a new Neo4j inspector
+func NewGraphInspector(driver neo4j.DriverWithContext, database string) *GraphInspector {
+	return &GraphInspector{driver: driver, database: database}
+}
+
+// InspectNeo4jConstraints reads the names of all constraints
+func (i *GraphInspector) InspectNeo4jConstraints(ctx context.Context) (map[string]bool, error) {
+	session := i.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead, DatabaseName: i.database})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, "SHOW CONSTRAINTS YIELD name RETURN name", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read constraints: %w", err)
+	}
+
+	constraints := make(map[string]bool)
+	for result.Next(ctx) {
+		if name, ok := result.Record().Values[0].(string); ok {
+			constraints[name] = true
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read constraints: %w", err)
+	}
+
+	return constraints, nil
+}
@@ -0,0 +1,323 @@
+// Package schemaform derives UI form metadata from CI type schemas, so create
+// and edit forms can be generated instead of hard-coded per type. Labels,
+// groups, ordering and widgets come from the attributes' UI hints when set and
+// are otherwise inferred from their types and validation rules.
+package schemaform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"connect/internal/models"
+)
+
+// DefaultGroup holds the fields without a group; it is always listed first
+const DefaultGroup = "general"
+
+// textareaThreshold is the maxLength above which strings get a textarea
+const textareaThreshold = 255
+
+// Form describes the fields of a CI type, grouped and in display order
+type Form struct {
+	CIType      string  `json:"ci_type"`
+	Description string  `json:"description,omitempty"`
+	Groups      []Group `json:"groups"`
+}
+
+// Group is a section of the form
+type Group struct {
+	Name   string  `json:"name"`
+	Label  string  `json:"label"`
+	Fields []Field `json:"fields"`
+}
+
+// Field describes how to render and validate one attribute
+type Field struct {
+	Name        string      `json:"name"`
+	Label       string      `json:"label"`
+	Type        string      `json:"type"`
+	Widget      string      `json:"widget"`
+	Required    bool        `json:"required"`
+	ReadOnly    bool        `json:"read_only,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	HelpText    string      `json:"help_text,omitempty"`
+	Placeholder string      `json:"placeholder,omitempty"`
+	Options     []Option    `json:"options,omitempty"`
+	Constraints Constraints `json:"constraints"`
+	// Messages are the validation messages to show, by rule name
+	Messages map[string]string `json:"messages"`
+}
+
+// Option is a choice of an enum attribute
+type Option struct {
+	Value interface{} `json:"value"`
+	Label string      `json:"label"`
+}
+
+// Constraints are the validation rules a form can enforce client side
+type Constraints struct {
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MinLength *int     `json:"min_length,omitempty"`
+	MaxLength *int     `json:"max_length,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Format    string   `json:"format,omitempty"`
+}
+
+// Build derives the form of a CI type schema. Hidden attributes are left out.
+func Build(schema *models.CITypeSchema) *Form {
+	type positioned struct {
+		field Field
+		group string
+		order int
+		index int
+	}
+
+	var fields []positioned
+	groupLabels := map[string]string{}
+	for i, attr := range schema.Attributes {
+		ui := attr.UI
+		if ui == nil {
+			ui = &models.AttributeUI{}
+		}
+		if ui.Hidden {
+			continue
+		}
+
+		group := DefaultGroup
+		if strings.TrimSpace(ui.Group) != "" {
+			group = groupKey(ui.Group)
+			if _, ok := groupLabels[group]; !ok {
+				groupLabels[group] = strings.TrimSpace(ui.Group)
+			}
+		}
+
+		order := len(schema.Attributes) + i
+		if ui.Order != nil {
+			order = *ui.Order
+		}
+		fields = append(fields, positioned{field: buildField(attr, ui), group: group, order: order, index: i})
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].order != fields[j].order {
+			return fields[i].order < fields[j].order
+		}
+		return fields[i].index < fields[j].index
+	})
+
+	form := &Form{CIType: schema.Name, Description: schema.Description, Groups: []Group{}}
+	positions := map[string]int{}
+	for _, f := range fields {
+		position, ok := positions[f.group]
+		if !ok {
+			label := groupLabels[f.group]
+			if f.group == DefaultGroup {
+				label = "General"
+			}
+			position = len(form.Groups)
+			positions[f.group] = position
+			form.Groups = append(form.Groups, Group{Name: f.group, Label: label, Fields: []Field{}})
+		}
+		form.Groups[position].Fields = append(form.Groups[position].Fields, f.field)
+	}
+
+	// The default group leads regardless of where its first field sorts
+	if position, ok := positions[DefaultGroup]; ok && position > 0 {
+		general := form.Groups[position]
+		copy(form.Groups[1:position+1], form.Groups[:position])
+		form.Groups[0] = general
+	}
+	return form
+}
+
+// buildField derives the metadata of one attribute
+func buildField(attr models.CITypeAttribute, ui *models.AttributeUI) Field {
+	field := Field{
+		Name:        attr.Name,
+		Label:       ui.Label,
+		Type:        attr.Type,
+		Widget:      ui.Widget,
+		Required:    attr.Required,
+		ReadOnly:    ui.ReadOnly,
+		Default:     attr.Default,
+		HelpText:    attr.Description,
+		Placeholder: ui.Placeholder,
+		Constraints: constraints(attr.Validation),
+		Messages:    map[string]string{},
+	}
+	if field.Label == "" {
+		field.Label = humanize(attr.Name)
+	}
+
+	if values, ok := attr.Validation["enum"].([]interface{}); ok {
+		for _, value := range values {
+			text := fmt.Sprint(value)
+			label, ok := ui.EnumLabels[text]
+			if !ok {
+				label = humanize(text)
+			}
+			field.Options = append(field.Options, Option{Value: value, Label: label})
+		}
+	}
+
+	if field.Widget == "" {
+		field.Widget = inferWidget(attr.Type, field.Constraints, len(field.Options) > 0)
+	}
+
+	for rule, message := range defaultMessages(field) {
+		field.Messages[rule] = message
+	}
+	for rule, message := range ui.Messages {
+		field.Messages[rule] = message
+	}
+	return field
+}
+
+// constraints reads the validation rules understood by the schema validator
+func constraints(validation map[string]interface{}) Constraints {
+	var c Constraints
+	if value, ok := number(validation["min"]); ok {
+		c.Min = &value
+	}
+	if value, ok := number(validation["max"]); ok {
+		c.Max = &value
+	}
+	if value, ok := number(validation["minLength"]); ok {
+		length := int(value)
+		c.MinLength = &length
+	}
+	if value, ok := number(validation["maxLength"]); ok {
+		length := int(value)
+		c.MaxLength = &length
+	}
+	c.Pattern, _ = validation["pattern"].(string)
+	c.Format, _ = validation["format"].(string)
+	return c
+}
+
+// number reads a numeric rule; stored schemas hold float64 but the built-in
+// defaults are declared with ints
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// inferWidget picks the widget for an attribute without a widget hint
+func inferWidget(attrType string, c Constraints, hasOptions bool) string {
+	switch attrType {
+	case models.AttributeTypeNumber:
+		return models.WidgetNumber
+	case models.AttributeTypeBoolean:
+		return models.WidgetCheckbox
+	case models.AttributeTypeDate:
+		return models.WidgetDate
+	case models.AttributeTypeObject:
+		return models.WidgetJSON
+	case models.AttributeTypeArray:
+		if hasOptions {
+			return models.WidgetMultiSelect
+		}
+		return models.WidgetTags
+	}
+
+	switch {
+	case hasOptions:
+		return models.WidgetSelect
+	case c.Format == "email":
+		return models.WidgetEmail
+	case c.Format == "url":
+		return models.WidgetURL
+	case c.MaxLength != nil && *c.MaxLength > textareaThreshold:
+		return models.WidgetTextarea
+	}
+	return models.WidgetText
+}
+
+// defaultMessages returns a message for each rule the field enforces
+func defaultMessages(field Field) map[string]string {
+	messages := map[string]string{}
+	c := field.Constraints
+	if field.Required {
+		messages["required"] = fmt.Sprintf("%s is required", field.Label)
+	}
+	if c.Min != nil {
+		messages["min"] = fmt.Sprintf("%s must be at least %s", field.Label, formatNumber(*c.Min))
+	}
+	if c.Max != nil {
+		messages["max"] = fmt.Sprintf("%s must be at most %s", field.Label, formatNumber(*c.Max))
+	}
+	if c.MinLength != nil {
+		messages["minLength"] = fmt.Sprintf("%s must be at least %d characters long", field.Label, *c.MinLength)
+	}
+	if c.MaxLength != nil {
+		messages["maxLength"] = fmt.Sprintf("%s must be at most %d characters long", field.Label, *c.MaxLength)
+	}
+	if c.Pattern != "" {
+		messages["pattern"] = fmt.Sprintf("%s is not in the expected format", field.Label)
+	}
+	switch c.Format {
+	case "email":
+		messages["format"] = fmt.Sprintf("%s must be a valid email address", field.Label)
+	case "ipv4":
+		messages["format"] = fmt.Sprintf("%s must be a valid IPv4 address", field.Label)
+	case "url":
+		messages["format"] = fmt.Sprintf("%s must be a valid URL", field.Label)
+	}
+	if len(field.Options) > 0 {
+		messages["enum"] = fmt.Sprintf("%s must be one of the listed options", field.Label)
+	}
+	return messages
+}
+
+// formatNumber prints whole numbers without decimals
+func formatNumber(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%f", value), "0"), ".")
+}
+
+// groupKey normalizes a group name so differently cased hints share a group
+func groupKey(group string) string {
+	return strings.ToLower(strings.Join(strings.Fields(group), "_"))
+}
+
+// humanize turns an attribute name or enum value such as "cpu_count",
+// "cpuCount" or "cpu-count" into a label such as "Cpu count"
+func humanize(name string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || unicode.IsSpace(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	if len(words) == 0 {
+		return name
+	}
+	label := []rune(strings.Join(words, " "))
+	label[0] = unicode.ToUpper(label[0])
+	return string(label)
+}
@@ -0,0 +1,128 @@
+package schemaform
+
+import (
+	"testing"
+
+	"connect/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestBuild_GroupsAndOrder(t *testing.T) {
+	schema := &models.CITypeSchema{
+		Name:        "server",
+		Description: "Physical or virtual server",
+		Attributes: []models.CITypeAttribute{
+			{Name: "memory_gb", Type: models.AttributeTypeNumber, UI: &models.AttributeUI{Group: "Hardware", Order: intPtr(2)}},
+			{Name: "hostname", Type: models.AttributeTypeString, Required: true},
+			{Name: "cpu_cores", Type: models.AttributeTypeNumber, UI: &models.AttributeUI{Group: "Hardware", Order: intPtr(1)}},
+			{Name: "internal_id", Type: models.AttributeTypeString, UI: &models.AttributeUI{Hidden: true}},
+			{Name: "ip_address", Type: models.AttributeTypeString, UI: &models.AttributeUI{Label: "IP address", Order: intPtr(0)}},
+		},
+	}
+
+	form := Build(schema)
+	assert.Equal(t, "server", form.CIType)
+	require.Len(t, form.Groups, 2)
+
+	general := form.Groups[0]
+	assert.Equal(t, DefaultGroup, general.Name)
+	require.Len(t, general.Fields, 2)
+	assert.Equal(t, "ip_address", general.Fields[0].Name)
+	assert.Equal(t, "IP address", general.Fields[0].Label)
+	assert.Equal(t, "hostname", general.Fields[1].Name)
+
+	hardware := form.Groups[1]
+	assert.Equal(t, "hardware", hardware.Name)
+	assert.Equal(t, "Hardware", hardware.Label)
+	require.Len(t, hardware.Fields, 2)
+	assert.Equal(t, "cpu_cores", hardware.Fields[0].Name)
+	assert.Equal(t, "Cpu cores", hardware.Fields[0].Label)
+	assert.Equal(t, "memory_gb", hardware.Fields[1].Name)
+}
+
+func TestBuild_Widgets(t *testing.T) {
+	schema := &models.CITypeSchema{
+		Name: "application",
+		Attributes: []models.CITypeAttribute{
+			{Name: "name", Type: models.AttributeTypeString},
+			{Name: "notes", Type: models.AttributeTypeString, Validation: map[string]interface{}{"maxLength": float64(2000)}},
+			{Name: "owner_email", Type: models.AttributeTypeString, Validation: map[string]interface{}{"format": "email"}},
+			{Name: "homepage", Type: models.AttributeTypeString, Validation: map[string]interface{}{"format": "url"}},
+			{Name: "tier", Type: models.AttributeTypeString, Validation: map[string]interface{}{"enum": []interface{}{"gold", "silver"}}},
+			{Name: "replicas", Type: models.AttributeTypeNumber},
+			{Name: "critical", Type: models.AttributeTypeBoolean},
+			{Name: "go_live", Type: models.AttributeTypeDate},
+			{Name: "labels", Type: models.AttributeTypeArray},
+			{Name: "regions", Type: models.AttributeTypeArray, Validation: map[string]interface{}{"enum": []interface{}{"eu", "us"}}},
+			{Name: "settings", Type: models.AttributeTypeObject},
+			{Name: "summary", Type: models.AttributeTypeString, UI: &models.AttributeUI{Widget: models.WidgetTextarea}},
+		},
+	}
+
+	widgets := map[string]string{}
+	for _, field := range Build(schema).Groups[0].Fields {
+		widgets[field.Name] = field.Widget
+	}
+
+	assert.Equal(t, map[string]string{
+		"name":        models.WidgetText,
+		"notes":       models.WidgetTextarea,
+		"owner_email": models.WidgetEmail,
+		"homepage":    models.WidgetURL,
+		"tier":        models.WidgetSelect,
+		"replicas":    models.WidgetNumber,
+		"critical":    models.WidgetCheckbox,
+		"go_live":     models.WidgetDate,
+		"labels":      models.WidgetTags,
+		"regions":     models.WidgetMultiSelect,
+		"settings":    models.WidgetJSON,
+		"summary":     models.WidgetTextarea,
+	}, widgets)
+}
+
+func TestBuild_OptionsAndMessages(t *testing.T) {
+	schema := &models.CITypeSchema{
+		Name: "server",
+		Attributes: []models.CITypeAttribute{
+			{
+				Name:       "environment",
+				Type:       models.AttributeTypeString,
+				Required:   true,
+				Validation: map[string]interface{}{"enum": []interface{}{"prod", "non_prod"}},
+				UI: &models.AttributeUI{
+					EnumLabels: map[string]string{"prod": "Production"},
+					Messages:   map[string]string{"required": "Pick an environment"},
+				},
+			},
+			{Name: "cpu_cores", Type: models.AttributeTypeNumber, Validation: map[string]interface{}{"min": 1, "max": float64(128)}},
+		},
+	}
+
+	fields := Build(schema).Groups[0].Fields
+	require.Len(t, fields, 2)
+
+	environment := fields[0]
+	assert.Equal(t, []Option{{Value: "prod", Label: "Production"}, {Value: "non_prod", Label: "Non prod"}}, environment.Options)
+	assert.Equal(t, "Pick an environment", environment.Messages["required"])
+	assert.Equal(t, "Environment must be one of the listed options", environment.Messages["enum"])
+
+	cpu := fields[1]
+	require.NotNil(t, cpu.Constraints.Min)
+	assert.Equal(t, float64(1), *cpu.Constraints.Min)
+	assert.Equal(t, "Cpu cores must be at least 1", cpu.Messages["min"])
+	assert.Equal(t, "Cpu cores must be at most 128", cpu.Messages["max"])
+	assert.NotContains(t, cpu.Messages, "required")
+}
+
+func TestHumanize(t *testing.T) {
+	assert.Equal(t, "Cpu count", humanize("cpu_count"))
+	assert.Equal(t, "Cpu count", humanize("cpuCount"))
+	assert.Equal(t, "Cpu count", humanize("cpu-count"))
+	assert.Equal(t, "Ipv4", humanize("IPV4"))
+	assert.Equal(t, "", humanize(""))
+}
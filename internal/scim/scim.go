@@ -0,0 +1,183 @@
+// Package scim provisions users over SCIM 2.0 (RFC 7643 and 7644), so identity
+// providers such as Okta and Azure AD can create, update and deprovision CMDB
+// users and map their groups to roles. SCIM users are CMDB users. SCIM groups
+// are backed by roles: a group's display name maps to a role through the
+// configured group mappings, or else to the role of the same name, and its
+// members are the users holding the role. Groups only link to existing roles,
+// since roles carry the permissions managed in the CMDB; deleting a group
+// revokes its role from its members and keeps the role.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Schema URNs of the resources and messages
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// MaxResults bounds the resources returned by a list
+const MaxResults = 200
+
+// Error types reported in scimType
+const (
+	ErrorInvalidFilter = "invalidFilter"
+	ErrorInvalidValue  = "invalidValue"
+	ErrorUniqueness    = "uniqueness"
+	ErrorMutability    = "mutability"
+	ErrorInvalidSyntax = "invalidSyntax"
+	ErrorNoTarget      = "noTarget"
+)
+
+// Error is a SCIM error, reported with its HTTP status
+type Error struct {
+	Status int
+	// Type is the scimType of a 400 or 409 error, if any
+	Type   string
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// MarshalJSON encodes the error as a SCIM error message
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail,omitempty"`
+	}{[]string{SchemaError}, fmt.Sprint(e.Status), e.Type, e.Detail})
+}
+
+func notFound(format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusNotFound, Detail: fmt.Sprintf(format, args...)}
+}
+
+func badRequest(scimType, format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusBadRequest, Type: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusConflict, Type: ErrorUniqueness, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Name is the name of a user
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is a value of a multi-valued attribute, e.g. an email or a
+// group member
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Meta describes a resource
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// User is a SCIM user
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Active      *bool        `json:"active,omitempty"`
+	// Password is only read; users provisioned without one sign in through
+	// the identity provider
+	Password string       `json:"password,omitempty"`
+	Groups   []MultiValue `json:"groups,omitempty"`
+	Meta     *Meta        `json:"meta,omitempty"`
+}
+
+// email returns the primary email of a user, else the first
+func (u *User) email() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return strings.TrimSpace(email.Value)
+		}
+	}
+	if len(u.Emails) > 0 {
+		return strings.TrimSpace(u.Emails[0].Value)
+	}
+	return ""
+}
+
+// Group is a SCIM group, backed by a role
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// ListResponse is a page of resources
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// PatchRequest modifies a resource
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is an add, remove or replace operation. Ops are compared
+// case-insensitively, as Azure AD capitalizes them.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// filterPattern matches the equality filters identity providers look
+// resources up with, e.g. userName eq "jdoe@example.com"
+var filterPattern = regexp.MustCompile(`^\s*([A-Za-z][\w.]*)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parseFilter parses an equality filter into its attribute, lowercased, and value
+func parseFilter(filter string) (string, string, error) {
+	match := filterPattern.FindStringSubmatch(filter)
+	if match == nil {
+		return "", "", badRequest(ErrorInvalidFilter, "unsupported filter %q: only attribute eq \"value\" is supported", filter)
+	}
+	var value string
+	if err := json.Unmarshal([]byte(`"`+match[2]+`"`), &value); err != nil {
+		return "", "", badRequest(ErrorInvalidFilter, "invalid filter value in %q", filter)
+	}
+	return strings.ToLower(match[1]), value, nil
+}
+
+// memberFilterPattern matches the member paths of remove operations, e.g.
+// members[value eq "2819c223-7f76-453a-919d-413861904646"]
+var memberFilterPattern = regexp.MustCompile(`^(?i:members)\[\s*(?i:value)\s+(?i:eq)\s+"([^"]*)"\s*\]$`)
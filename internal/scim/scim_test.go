@@ -0,0 +1,279 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps users and role assignments in memory
+type memoryStore struct {
+	users       map[uuid.UUID]*models.User
+	roles       []models.Role
+	assignments map[uuid.UUID]map[uuid.UUID]bool // role -> users
+}
+
+func newMemoryStore(roles ...string) *memoryStore {
+	m := &memoryStore{users: map[uuid.UUID]*models.User{}, assignments: map[uuid.UUID]map[uuid.UUID]bool{}}
+	for _, name := range roles {
+		role := models.Role{ID: uuid.New(), Name: name}
+		m.roles = append(m.roles, role)
+		m.assignments[role.ID] = map[uuid.UUID]bool{}
+	}
+	return m
+}
+
+func (m *memoryStore) FindUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return m.users[id], nil
+}
+
+func (m *memoryStore) FindUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) ListUsers(ctx context.Context, offset, limit int) ([]*models.User, int, error) {
+	users := m.sorted(func(*models.User) bool { return true })
+	if offset > len(users) {
+		offset = len(users)
+	}
+	page := users[offset:]
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page, len(users), nil
+}
+
+func (m *memoryStore) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
+	for _, user := range m.users {
+		if user.Username == req.Username || user.Email == req.Email {
+			return nil, ErrConflict
+		}
+	}
+	user := &models.User{ID: uuid.New(), Username: req.Username, Email: req.Email, PasswordHash: req.Password,
+		FirstName: req.FirstName, LastName: req.LastName, IsActive: true}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *memoryStore) UpdateUser(ctx context.Context, id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error) {
+	user := m.users[id]
+	if req.FirstName != nil {
+		user.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	if req.IsActive != nil {
+		user.IsActive = *req.IsActive
+	}
+	return user, nil
+}
+
+func (m *memoryStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	delete(m.users, id)
+	for _, users := range m.assignments {
+		delete(users, id)
+	}
+	return nil
+}
+
+func (m *memoryStore) FindRole(ctx context.Context, id uuid.UUID) (*models.Role, error) {
+	for i := range m.roles {
+		if m.roles[i].ID == id {
+			return &m.roles[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) FindRoleByName(ctx context.Context, name string) (*models.Role, error) {
+	for i := range m.roles {
+		if m.roles[i].Name == name {
+			return &m.roles[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return m.roles, nil
+}
+
+func (m *memoryStore) UserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
+	var roles []models.Role
+	for _, role := range m.roles {
+		if m.assignments[role.ID][userID] {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (m *memoryStore) RoleMembers(ctx context.Context, roleID uuid.UUID) ([]*models.User, error) {
+	return m.sorted(func(user *models.User) bool { return m.assignments[roleID][user.ID] }), nil
+}
+
+func (m *memoryStore) AssignRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	m.assignments[roleID][userID] = true
+	return nil
+}
+
+func (m *memoryStore) RevokeRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	delete(m.assignments[roleID], userID)
+	return nil
+}
+
+func (m *memoryStore) sorted(keep func(*models.User) bool) []*models.User {
+	var users []*models.User
+	for _, user := range m.users {
+		if keep(user) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users
+}
+
+func scimStatus(err error) int {
+	var scimErr *Error
+	if errors.As(err, &scimErr) {
+		return scimErr.Status
+	}
+	return 0
+}
+
+func TestUserLifecycle(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, nil)
+	ctx := context.Background()
+
+	created, err := service.CreateUser(ctx, User{
+		UserName: "jdoe@example.com",
+		Name:     &Name{GivenName: "Jane", FamilyName: "Doe"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe@example.com", created.Emails[0].Value)
+	assert.True(t, *created.Active)
+	// Provisioned users get a password nobody knows
+	assert.Len(t, store.users[uuid.MustParse(created.ID)].PasswordHash, 43)
+
+	_, err = service.CreateUser(ctx, User{UserName: "jdoe@example.com"})
+	assert.Equal(t, http.StatusConflict, scimStatus(err))
+
+	list, err := service.ListUsers(ctx, `userName eq "jdoe@example.com"`, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.TotalResults)
+	list, err = service.ListUsers(ctx, `userName eq "nobody"`, 1, 10)
+	require.NoError(t, err)
+	assert.Zero(t, list.TotalResults)
+	_, err = service.ListUsers(ctx, `title sw "Dev"`, 1, 10)
+	assert.Equal(t, http.StatusBadRequest, scimStatus(err))
+
+	// Deprovisioning deactivates the user, as Azure AD sends it
+	patched, err := service.PatchUser(ctx, created.ID, PatchRequest{Operations: []PatchOperation{
+		{Op: "Replace", Path: "active", Value: json.RawMessage(`false`)},
+		{Op: "replace", Value: json.RawMessage(`{"name.familyName": "Smith", "title": "ignored"}`)},
+	}})
+	require.NoError(t, err)
+	assert.False(t, *patched.Active)
+	assert.Equal(t, "Smith", patched.Name.FamilyName)
+
+	_, err = service.PatchUser(ctx, created.ID, PatchRequest{Operations: []PatchOperation{
+		{Op: "replace", Path: "userName", Value: json.RawMessage(`"other"`)},
+	}})
+	assert.Equal(t, http.StatusBadRequest, scimStatus(err))
+
+	replaced, err := service.ReplaceUser(ctx, created.ID, User{UserName: "jdoe@example.com", Emails: []MultiValue{{Value: "jane@example.com", Primary: true}}})
+	require.NoError(t, err)
+	assert.True(t, *replaced.Active)
+	assert.Equal(t, "jane@example.com", replaced.Emails[0].Value)
+
+	require.NoError(t, service.DeleteUser(ctx, created.ID))
+	_, err = service.GetUser(ctx, created.ID)
+	assert.Equal(t, http.StatusNotFound, scimStatus(err))
+}
+
+func TestGroupsMapToRoles(t *testing.T) {
+	store := newMemoryStore("admin", "viewer")
+	service := NewService(store, map[string]string{"CMDB Admins": "admin"})
+	ctx := context.Background()
+
+	alice, err := service.CreateUser(ctx, User{UserName: "alice", Emails: []MultiValue{{Value: "alice@example.com"}}})
+	require.NoError(t, err)
+	bob, err := service.CreateUser(ctx, User{UserName: "bob", Emails: []MultiValue{{Value: "bob@example.com"}}})
+	require.NoError(t, err)
+
+	// Groups only link to existing roles
+	_, err = service.CreateGroup(ctx, Group{DisplayName: "Finance"})
+	assert.Equal(t, http.StatusBadRequest, scimStatus(err))
+
+	group, err := service.CreateGroup(ctx, Group{DisplayName: "CMDB Admins", Members: []MultiValue{{Value: alice.ID}}})
+	require.NoError(t, err)
+	assert.Equal(t, store.roles[0].ID.String(), group.ID)
+	assert.Equal(t, "CMDB Admins", group.DisplayName)
+	require.Len(t, group.Members, 1)
+
+	user, err := service.GetUser(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []MultiValue{{Value: group.ID, Display: "CMDB Admins"}}, user.Groups)
+
+	list, err := service.ListGroups(ctx, `displayName eq "CMDB Admins"`, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.TotalResults)
+	list, err = service.ListGroups(ctx, "", 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.TotalResults)
+	assert.Equal(t, 1, list.ItemsPerPage)
+
+	group, err = service.PatchGroup(ctx, group.ID, PatchRequest{Operations: []PatchOperation{
+		{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "` + bob.ID + `"}]`)},
+		{Op: "remove", Path: `members[value eq "` + alice.ID + `"]`},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []MultiValue{{Value: bob.ID, Display: "bob"}}, group.Members)
+
+	_, err = service.PatchGroup(ctx, group.ID, PatchRequest{Operations: []PatchOperation{
+		{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "` + uuid.NewString() + `"}]`)},
+	}})
+	assert.Equal(t, http.StatusBadRequest, scimStatus(err))
+
+	group, err = service.ReplaceGroup(ctx, group.ID, Group{DisplayName: "CMDB Admins", Members: []MultiValue{{Value: alice.ID}}})
+	require.NoError(t, err)
+	assert.Equal(t, []MultiValue{{Value: alice.ID, Display: "alice"}}, group.Members)
+
+	// Deleting a group revokes its role and keeps it
+	require.NoError(t, service.DeleteGroup(ctx, group.ID))
+	assert.Empty(t, store.assignments[store.roles[0].ID])
+	assert.Len(t, store.roles, 2)
+}
+
+func TestErrorMarshal(t *testing.T) {
+	raw, err := json.Marshal(conflict("taken"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:Error"],"status":"409","scimType":"uniqueness","detail":"taken"}`, string(raw))
+}
@@ -0,0 +1,638 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrConflict is returned by a store creating a user whose username or email
+// is taken
+var ErrConflict = errors.New("user already exists")
+
+// Store reads and writes the users and role assignments provisioned. Find
+// methods return nil when there is no match. Assigning a role a user holds and
+// revoking one they do not hold succeed.
+type Store interface {
+	FindUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+	FindUserByUsername(ctx context.Context, username string) (*models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// ListUsers returns a page of users by username and the number of users
+	ListUsers(ctx context.Context, offset, limit int) ([]*models.User, int, error)
+	CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+
+	FindRole(ctx context.Context, id uuid.UUID) (*models.Role, error)
+	FindRoleByName(ctx context.Context, name string) (*models.Role, error)
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	UserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error)
+	// RoleMembers returns the users holding a role by username
+	RoleMembers(ctx context.Context, roleID uuid.UUID) ([]*models.User, error)
+	AssignRole(ctx context.Context, userID, roleID uuid.UUID) error
+	RevokeRole(ctx context.Context, userID, roleID uuid.UUID) error
+}
+
+// Service translates SCIM requests into user and role changes
+type Service struct {
+	store Store
+	// groupRoles maps group display names to role names and roleGroups back
+	groupRoles map[string]string
+	roleGroups map[string]string
+}
+
+// NewService creates a SCIM service. groupRoles maps the display names of
+// groups to the roles they grant; other groups grant the role of their name.
+func NewService(store Store, groupRoles map[string]string) *Service {
+	s := &Service{store: store, groupRoles: map[string]string{}, roleGroups: map[string]string{}}
+	for group, role := range groupRoles {
+		s.groupRoles[group] = role
+		s.roleGroups[role] = group
+	}
+	return s
+}
+
+// Users
+
+// CreateUser provisions a user. Users provisioned without a password get a
+// random one, so they can only sign in through the identity provider.
+func (s *Service) CreateUser(ctx context.Context, user User) (*User, error) {
+	username := strings.TrimSpace(user.UserName)
+	if username == "" {
+		return nil, badRequest(ErrorInvalidValue, "userName is required")
+	}
+	email := user.email()
+	if email == "" && strings.Contains(username, "@") {
+		email = username
+	}
+	if email == "" {
+		return nil, badRequest(ErrorInvalidValue, "an email is required")
+	}
+
+	password := user.Password
+	if password == "" {
+		generated, err := randomPassword()
+		if err != nil {
+			return nil, err
+		}
+		password = generated
+	}
+
+	req := &models.CreateUserRequest{Username: username, Email: email, Password: password}
+	if user.Name != nil {
+		req.FirstName = strings.TrimSpace(user.Name.GivenName)
+		req.LastName = strings.TrimSpace(user.Name.FamilyName)
+	}
+	created, err := s.store.CreateUser(ctx, req)
+	if errors.Is(err, ErrConflict) {
+		return nil, conflict("a user with userName %q or email %q already exists", username, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Active != nil && !*user.Active {
+		created, err = s.store.UpdateUser(ctx, created.ID, &models.UpdateUserRequest{IsActive: user.Active})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return toUser(created, nil), nil
+}
+
+// GetUser returns a user with the groups of their roles
+func (s *Service) GetUser(ctx context.Context, id string) (*User, error) {
+	user, err := s.user(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.store.UserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user, s.groups(roles)), nil
+}
+
+// ListUsers returns a page of users, narrowed by an equality filter on
+// userName, emails.value or id. Listed users do not carry their groups.
+func (s *Service) ListUsers(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error) {
+	startIndex, count = pageBounds(startIndex, count)
+	users := []*User{}
+
+	if filter != "" {
+		attribute, value, err := parseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		var user *models.User
+		switch attribute {
+		case "username":
+			user, err = s.store.FindUserByUsername(ctx, value)
+		case "emails", "emails.value":
+			user, err = s.store.FindUserByEmail(ctx, value)
+		case "id":
+			if id, parseErr := uuid.Parse(value); parseErr == nil {
+				user, err = s.store.FindUser(ctx, id)
+			}
+		default:
+			return nil, badRequest(ErrorInvalidFilter, "users cannot be filtered by %s", attribute)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user != nil && startIndex == 1 && count > 0 {
+			users = append(users, toUser(user, nil))
+		}
+		total := 0
+		if user != nil {
+			total = 1
+		}
+		return listResponse(users, total, startIndex), nil
+	}
+
+	page, total, err := s.store.ListUsers(ctx, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range page {
+		users = append(users, toUser(user, nil))
+	}
+	return listResponse(users, total, startIndex), nil
+}
+
+// ReplaceUser replaces the name, email and active state of a user. The
+// userName cannot be changed.
+func (s *Service) ReplaceUser(ctx context.Context, id string, user User) (*User, error) {
+	existing, err := s.user(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if username := strings.TrimSpace(user.UserName); username != "" && username != existing.Username {
+		return nil, badRequest(ErrorMutability, "userName cannot be changed")
+	}
+
+	req := &models.UpdateUserRequest{}
+	if user.Name != nil {
+		given, family := strings.TrimSpace(user.Name.GivenName), strings.TrimSpace(user.Name.FamilyName)
+		req.FirstName, req.LastName = &given, &family
+	}
+	if email := user.email(); email != "" {
+		req.Email = &email
+	}
+	active := user.Active == nil || *user.Active
+	req.IsActive = &active
+	return s.update(ctx, existing.ID, req)
+}
+
+// PatchUser applies patch operations to a user. Deprovisioning identity
+// providers replace active with false. Attributes the CMDB does not keep are
+// ignored.
+func (s *Service) PatchUser(ctx context.Context, id string, patch PatchRequest) (*User, error) {
+	existing, err := s.user(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.UpdateUserRequest{}
+	for _, op := range patch.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		default:
+			return nil, badRequest(ErrorInvalidValue, "unsupported operation %q on users", op.Op)
+		}
+
+		values := map[string]json.RawMessage{}
+		if op.Path == "" {
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return nil, badRequest(ErrorInvalidSyntax, "operation without a path needs an object value")
+			}
+		} else {
+			values[op.Path] = op.Value
+		}
+		for path, value := range values {
+			if err := applyUserPatch(req, path, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.update(ctx, existing.ID, req)
+}
+
+// applyUserPatch applies the value of one attribute path to an update
+func applyUserPatch(req *models.UpdateUserRequest, path string, value json.RawMessage) error {
+	var err error
+	switch strings.ToLower(path) {
+	case "active":
+		var active bool
+		if err = json.Unmarshal(value, &active); err == nil {
+			req.IsActive = &active
+		}
+	case "name.givenname":
+		var given string
+		if err = json.Unmarshal(value, &given); err == nil {
+			req.FirstName = &given
+		}
+	case "name.familyname":
+		var family string
+		if err = json.Unmarshal(value, &family); err == nil {
+			req.LastName = &family
+		}
+	case "name":
+		var name Name
+		if err = json.Unmarshal(value, &name); err == nil {
+			req.FirstName, req.LastName = &name.GivenName, &name.FamilyName
+		}
+	case "emails", `emails[type eq "work"].value`, `emails[primary eq true].value`:
+		var email string
+		if err = json.Unmarshal(value, &email); err != nil {
+			var emails []MultiValue
+			if err = json.Unmarshal(value, &emails); err == nil {
+				email = (&User{Emails: emails}).email()
+			}
+		}
+		if err == nil && email != "" {
+			req.Email = &email
+		}
+	case "username":
+		return badRequest(ErrorMutability, "userName cannot be changed")
+	}
+	if err != nil {
+		return badRequest(ErrorInvalidValue, "invalid value for %s", path)
+	}
+	return nil
+}
+
+// DeleteUser deletes a user
+func (s *Service) DeleteUser(ctx context.Context, id string) error {
+	user, err := s.user(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteUser(ctx, user.ID)
+}
+
+// user returns the user with a SCIM id
+func (s *Service) user(ctx context.Context, id string) (*models.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, notFound("user %s not found", id)
+	}
+	user, err := s.store.FindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, notFound("user %s not found", id)
+	}
+	return user, nil
+}
+
+// update updates a user and returns it with the groups of their roles
+func (s *Service) update(ctx context.Context, id uuid.UUID, req *models.UpdateUserRequest) (*User, error) {
+	if _, err := s.store.UpdateUser(ctx, id, req); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, conflict("the email is taken by another user")
+		}
+		return nil, err
+	}
+	return s.GetUser(ctx, id.String())
+}
+
+// Groups
+
+// GetGroup returns a group with its members
+func (s *Service) GetGroup(ctx context.Context, id string) (*Group, error) {
+	role, err := s.role(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.groupWithMembers(ctx, role)
+}
+
+// ListGroups returns a page of groups, narrowed by an equality filter on
+// displayName or id. Listed groups do not carry their members.
+func (s *Service) ListGroups(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error) {
+	startIndex, count = pageBounds(startIndex, count)
+
+	var roles []models.Role
+	if filter != "" {
+		attribute, value, err := parseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		var role *models.Role
+		switch attribute {
+		case "displayname":
+			role, err = s.store.FindRoleByName(ctx, s.roleName(value))
+		case "id":
+			if id, parseErr := uuid.Parse(value); parseErr == nil {
+				role, err = s.store.FindRole(ctx, id)
+			}
+		default:
+			return nil, badRequest(ErrorInvalidFilter, "groups cannot be filtered by %s", attribute)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			roles = append(roles, *role)
+		}
+	} else {
+		all, err := s.store.ListRoles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		roles = all
+	}
+
+	groups := []*Group{}
+	for i := startIndex - 1; i < len(roles) && len(groups) < count; i++ {
+		groups = append(groups, s.toGroup(&roles[i], nil))
+	}
+	return listResponse(groups, len(roles), startIndex), nil
+}
+
+// CreateGroup links a group to the role its display name maps to and adds
+// its members to the role
+func (s *Service) CreateGroup(ctx context.Context, group Group) (*Group, error) {
+	name := strings.TrimSpace(group.DisplayName)
+	if name == "" {
+		return nil, badRequest(ErrorInvalidValue, "displayName is required")
+	}
+	role, err := s.store.FindRoleByName(ctx, s.roleName(name))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, badRequest(ErrorInvalidValue, "no role maps to group %q", name)
+	}
+
+	for _, member := range group.Members {
+		if err := s.assign(ctx, member.Value, role); err != nil {
+			return nil, err
+		}
+	}
+	return s.groupWithMembers(ctx, role)
+}
+
+// ReplaceGroup makes a group's members exactly the given ones
+func (s *Service) ReplaceGroup(ctx context.Context, id string, group Group) (*Group, error) {
+	role, err := s.role(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setMembers(ctx, role, group.Members); err != nil {
+		return nil, err
+	}
+	return s.groupWithMembers(ctx, role)
+}
+
+// PatchGroup adds, removes or replaces the members of a group. Changes to the
+// display name are ignored, as it identifies the role.
+func (s *Service) PatchGroup(ctx context.Context, id string, patch PatchRequest) (*Group, error) {
+	role, err := s.role(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range patch.Operations {
+		path := strings.TrimSpace(op.Path)
+		if match := memberFilterPattern.FindStringSubmatch(path); match != nil && strings.EqualFold(op.Op, "remove") {
+			if err := s.revoke(ctx, match[1], role); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if path != "" && !strings.EqualFold(path, "members") {
+			continue
+		}
+
+		var members []MultiValue
+		if len(op.Value) > 0 {
+			if path == "" {
+				var value struct {
+					Members []MultiValue `json:"members"`
+				}
+				if err := json.Unmarshal(op.Value, &value); err != nil {
+					return nil, badRequest(ErrorInvalidSyntax, "invalid value of %s operation", op.Op)
+				}
+				if value.Members == nil {
+					continue
+				}
+				members = value.Members
+			} else if err := json.Unmarshal(op.Value, &members); err != nil {
+				return nil, badRequest(ErrorInvalidSyntax, "members must be a list of values")
+			}
+		}
+
+		switch strings.ToLower(op.Op) {
+		case "add":
+			for _, member := range members {
+				if err := s.assign(ctx, member.Value, role); err != nil {
+					return nil, err
+				}
+			}
+		case "remove":
+			// Removing members without naming them removes every member
+			if len(op.Value) == 0 {
+				if err := s.setMembers(ctx, role, nil); err != nil {
+					return nil, err
+				}
+			}
+			for _, member := range members {
+				if err := s.revoke(ctx, member.Value, role); err != nil {
+					return nil, err
+				}
+			}
+		case "replace":
+			if err := s.setMembers(ctx, role, members); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, badRequest(ErrorInvalidValue, "unsupported operation %q on groups", op.Op)
+		}
+	}
+	return s.groupWithMembers(ctx, role)
+}
+
+// DeleteGroup unlinks a group by revoking its role from its members. The role
+// itself is kept.
+func (s *Service) DeleteGroup(ctx context.Context, id string) error {
+	role, err := s.role(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.setMembers(ctx, role, nil)
+}
+
+// role returns the role backing the group with a SCIM id
+func (s *Service) role(ctx context.Context, id string) (*models.Role, error) {
+	roleID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, notFound("group %s not found", id)
+	}
+	role, err := s.store.FindRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, notFound("group %s not found", id)
+	}
+	return role, nil
+}
+
+// roleName returns the name of the role a group's display name maps to
+func (s *Service) roleName(group string) string {
+	if role, ok := s.groupRoles[group]; ok {
+		return role
+	}
+	return group
+}
+
+// groupName returns the display name of the group backed by a role
+func (s *Service) groupName(role string) string {
+	if group, ok := s.roleGroups[role]; ok {
+		return group
+	}
+	return role
+}
+
+// setMembers assigns a role to the given users and revokes it from the others
+func (s *Service) setMembers(ctx context.Context, role *models.Role, members []MultiValue) error {
+	wanted := make(map[string]bool, len(members))
+	for _, member := range members {
+		if err := s.assign(ctx, member.Value, role); err != nil {
+			return err
+		}
+		wanted[strings.TrimSpace(member.Value)] = true
+	}
+
+	current, err := s.store.RoleMembers(ctx, role.ID)
+	if err != nil {
+		return err
+	}
+	for _, user := range current {
+		if !wanted[user.ID.String()] {
+			if err := s.store.RevokeRole(ctx, user.ID, role.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// assign assigns a role to the user with a SCIM id
+func (s *Service) assign(ctx context.Context, id string, role *models.Role) error {
+	user, err := s.member(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.store.AssignRole(ctx, user.ID, role.ID)
+}
+
+// revoke revokes a role from the user with a SCIM id
+func (s *Service) revoke(ctx context.Context, id string, role *models.Role) error {
+	user, err := s.member(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.store.RevokeRole(ctx, user.ID, role.ID)
+}
+
+// member returns the user a member value names
+func (s *Service) member(ctx context.Context, id string) (*models.User, error) {
+	user, err := s.user(ctx, strings.TrimSpace(id))
+	var scimErr *Error
+	if errors.As(err, &scimErr) && scimErr.Status == 404 {
+		return nil, badRequest(ErrorNoTarget, "member %s is not a user", id)
+	}
+	return user, err
+}
+
+// groupWithMembers returns the group backed by a role with its members
+func (s *Service) groupWithMembers(ctx context.Context, role *models.Role) (*Group, error) {
+	members, err := s.store.RoleMembers(ctx, role.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(role, members), nil
+}
+
+// groups returns the groups backed by roles, as listed on users
+func (s *Service) groups(roles []models.Role) []MultiValue {
+	groups := make([]MultiValue, 0, len(roles))
+	for _, role := range roles {
+		groups = append(groups, MultiValue{Value: role.ID.String(), Display: s.groupName(role.Name)})
+	}
+	return groups
+}
+
+// toGroup converts a role and its members to a group
+func (s *Service) toGroup(role *models.Role, members []*models.User) *Group {
+	group := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          role.ID.String(),
+		DisplayName: s.groupName(role.Name),
+		Meta:        &Meta{ResourceType: "Group", Created: role.CreatedAt, LastModified: role.UpdatedAt},
+	}
+	for _, member := range members {
+		group.Members = append(group.Members, MultiValue{Value: member.ID.String(), Display: member.Username})
+	}
+	return group
+}
+
+// toUser converts a user to a SCIM user
+func toUser(user *models.User, groups []MultiValue) *User {
+	active := user.IsActive
+	return &User{
+		Schemas:     []string{SchemaUser},
+		ID:          user.ID.String(),
+		UserName:    user.Username,
+		Name:        &Name{GivenName: user.FirstName, FamilyName: user.LastName, Formatted: strings.TrimSpace(user.FirstName + " " + user.LastName)},
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Emails:      []MultiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Groups:      groups,
+		Meta:        &Meta{ResourceType: "User", Created: user.CreatedAt, LastModified: user.UpdatedAt},
+	}
+}
+
+// pageBounds applies the defaults and bounds of startIndex and count
+func pageBounds(startIndex, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > MaxResults {
+		count = MaxResults
+	}
+	return startIndex, count
+}
+
+// listResponse wraps a page of resources
+func listResponse[T any](resources []T, total, startIndex int) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// randomPassword returns a password nobody knows
+func randomPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
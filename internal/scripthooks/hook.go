@@ -0,0 +1,137 @@
+// Package scripthooks runs small admin-defined scripts before and after CIs
+// are saved. Pre-save scripts can enrich attributes or veto the save;
+// post-save scripts can only log. Scripts are written in a restricted
+// expression language with no I/O, run under a per-hook timeout, and never
+// break a save by failing: errors are isolated to the hook and recorded in
+// its execution log along with every run.
+package scripthooks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Hook phases
+const (
+	PhasePreSave  = "pre_save"
+	PhasePostSave = "post_save"
+)
+
+// Save events
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+)
+
+// Execution outcomes
+const (
+	OutcomeOK       = "ok"
+	OutcomeRejected = "rejected"
+	OutcomeError    = "error"
+	OutcomeTimeout  = "timeout"
+)
+
+// Timeouts
+const (
+	DefaultTimeout = 50 * time.Millisecond
+	MaxTimeout     = time.Second
+)
+
+var (
+	ErrInvalidHook  = errors.New("invalid script hook")
+	ErrHookNotFound = errors.New("script hook not found")
+)
+
+// Hook is a script run on every save of matching CIs. Hooks of a phase run by
+// ascending priority, each seeing the attributes set by the previous ones.
+type Hook struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Phase string    `json:"phase"`
+	// CITypes restricts the hook to these CI types; empty means every type
+	CITypes   []string  `json:"ci_types"`
+	Script    string    `json:"script"`
+	Priority  int       `json:"priority"`
+	TimeoutMs int       `json:"timeout_ms"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by"`
+
+	compiled *Script
+}
+
+// Validate checks the hook and compiles its script
+func (h *Hook) Validate() error {
+	h.Name = strings.TrimSpace(h.Name)
+	if h.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidHook)
+	}
+	if h.Phase != PhasePreSave && h.Phase != PhasePostSave {
+		return fmt.Errorf("%w: phase must be %s or %s", ErrInvalidHook, PhasePreSave, PhasePostSave)
+	}
+	if h.TimeoutMs < 0 || time.Duration(h.TimeoutMs)*time.Millisecond > MaxTimeout {
+		return fmt.Errorf("%w: timeout_ms must be between 0 and %d", ErrInvalidHook, MaxTimeout.Milliseconds())
+	}
+	if h.CITypes == nil {
+		h.CITypes = []string{}
+	}
+
+	compiled, err := Compile(h.Script, h.Phase)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHook, err)
+	}
+	h.compiled = compiled
+	return nil
+}
+
+// Timeout returns the hook's time budget per run
+func (h *Hook) Timeout() time.Duration {
+	if h.TimeoutMs <= 0 {
+		return DefaultTimeout
+	}
+	return time.Duration(h.TimeoutMs) * time.Millisecond
+}
+
+// AppliesTo reports whether the hook runs for a CI type
+func (h *Hook) AppliesTo(ciType string) bool {
+	if len(h.CITypes) == 0 {
+		return true
+	}
+	for _, t := range h.CITypes {
+		if t == ciType {
+			return true
+		}
+	}
+	return false
+}
+
+// Execution is the log entry of one hook run
+type Execution struct {
+	ID         uuid.UUID `json:"id"`
+	HookID     uuid.UUID `json:"hook_id"`
+	HookName   string    `json:"hook_name"`
+	CIID       uuid.UUID `json:"ci_id"`
+	Phase      string    `json:"phase"`
+	Event      string    `json:"event"`
+	Outcome    string    `json:"outcome"`
+	Message    string    `json:"message,omitempty"`
+	Logs       []string  `json:"logs"`
+	DurationMs float64   `json:"duration_ms"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+// RejectedError is returned when a pre-save hook vetoes a save
+type RejectedError struct {
+	HookID  uuid.UUID
+	Hook    string
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("rejected by script hook %q: %s", e.Hook, e.Message)
+}
@@ -0,0 +1,235 @@
+package scripthooks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"connect/internal/eventfilter"
+)
+
+// MaxScriptLength bounds the source of a script
+const MaxScriptLength = 8 * 1024
+
+// A script is a list of statements, one per line. Lines starting with # are
+// comments. Each statement may end with "if <condition>".
+//
+//	set attributes.fqdn = lower(ci.name) + ".corp.example.com" if !has(ci.attributes.fqdn)
+//	reject "production servers need an owner" if ci.status == "production" && ci.owner == ""
+//	log "criticality raised to " + ci.criticality if previous != null && ci.criticality != previous.criticality
+//
+// Scripts see the CI being saved as ci, the stored CI as previous (null on
+// create) and the kind of save as event ("create" or "update"). Values and
+// conditions are eventfilter expressions.
+
+// errDeadline is returned when a script runs past its deadline
+var errDeadline = errors.New("script timed out")
+
+// Statement kinds
+const (
+	statementSet    = "set"
+	statementReject = "reject"
+	statementLog    = "log"
+)
+
+type statement struct {
+	line      int
+	kind      string
+	path      []string // attribute path for set
+	value     *eventfilter.Expression
+	condition *eventfilter.Expression
+}
+
+// Script is a compiled script
+type Script struct {
+	statements []statement
+}
+
+// Compile parses a script. Post-save scripts run once the CI is stored, so
+// they may only log.
+func Compile(source, phase string) (*Script, error) {
+	if len(source) > MaxScriptLength {
+		return nil, fmt.Errorf("script longer than %d bytes", MaxScriptLength)
+	}
+
+	script := &Script{}
+	for i, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		stmt, err := compileStatement(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if phase == PhasePostSave && stmt.kind != statementLog {
+			return nil, fmt.Errorf("line %d: post-save scripts can only log", i+1)
+		}
+		stmt.line = i + 1
+		script.statements = append(script.statements, stmt)
+	}
+	if len(script.statements) == 0 {
+		return nil, fmt.Errorf("script has no statements")
+	}
+	return script, nil
+}
+
+// compileStatement parses one statement line
+func compileStatement(line string) (statement, error) {
+	keyword, rest := cutKeyword(line)
+	stmt := statement{kind: keyword}
+	switch keyword {
+	case statementSet:
+		// set attributes.a.b = <expr>
+		target, value, ok := strings.Cut(rest, "=")
+		if !ok || strings.HasPrefix(value, "=") {
+			return statement{}, fmt.Errorf("set needs an attribute and a value, e.g. set attributes.name = ...")
+		}
+		path := strings.Split(strings.TrimSpace(target), ".")
+		if path[0] != "attributes" {
+			return statement{}, fmt.Errorf("set only writes attributes, e.g. set attributes.name = ...")
+		}
+		if len(path) == 1 {
+			return statement{}, fmt.Errorf("set needs an attribute name")
+		}
+		for _, name := range path[1:] {
+			if name == "" || !isIdentifier(name) {
+				return statement{}, fmt.Errorf("invalid attribute name %q", name)
+			}
+		}
+		stmt.path = path[1:]
+		rest = value
+	case statementReject, statementLog:
+	default:
+		return statement{}, fmt.Errorf("unknown statement %q; use set, reject or log", keyword)
+	}
+
+	value, rest, err := eventfilter.CompilePrefix(rest)
+	if err != nil {
+		return statement{}, err
+	}
+	stmt.value = value
+	if rest != "" {
+		keyword, condition := cutKeyword(rest)
+		if keyword != "if" {
+			return statement{}, fmt.Errorf("unexpected %q", rest)
+		}
+		if stmt.condition, err = eventfilter.CompileExpression(condition); err != nil {
+			return statement{}, fmt.Errorf("condition: %w", err)
+		}
+	}
+	return stmt, nil
+}
+
+// cutKeyword splits the leading word off a statement
+func cutKeyword(s string) (string, string) {
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], strings.TrimSpace(s[end:])
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Result is the outcome of running a script
+type Result struct {
+	// Changed reports whether the script set any attribute
+	Changed bool
+	// Rejection is the message of the reject statement that vetoed the save
+	Rejection string
+	Logs      []string
+}
+
+// Run executes the script against the variables until it ends, rejects the
+// save or passes the deadline. set statements write to vars["ci"]'s attributes.
+func (s *Script) Run(vars map[string]interface{}, deadline time.Time) (*Result, error) {
+	result := &Result{}
+
+	for _, stmt := range s.statements {
+		if time.Now().After(deadline) {
+			return result, errDeadline
+		}
+
+		if stmt.condition != nil {
+			ok, err := stmt.condition.Test(vars)
+			if err != nil {
+				return result, fmt.Errorf("line %d: condition: %w", stmt.line, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		value, err := stmt.value.Eval(vars)
+		if err != nil {
+			return result, fmt.Errorf("line %d: %w", stmt.line, err)
+		}
+
+		switch stmt.kind {
+		case statementSet:
+			if err := setAttribute(vars, stmt.path, value); err != nil {
+				return result, fmt.Errorf("line %d: %w", stmt.line, err)
+			}
+			result.Changed = true
+		case statementReject:
+			message, ok := value.(string)
+			if !ok {
+				return result, fmt.Errorf("line %d: reject needs a string message, got %T", stmt.line, value)
+			}
+			result.Rejection = message
+			return result, nil
+		case statementLog:
+			text, err := eventfilter.ToString(value)
+			if err != nil {
+				return result, fmt.Errorf("line %d: %w", stmt.line, err)
+			}
+			result.Logs = append(result.Logs, text)
+		}
+	}
+	return result, nil
+}
+
+// setAttribute writes a value into the CI attributes, creating intermediate
+// objects. Setting null removes the attribute.
+func setAttribute(vars map[string]interface{}, path []string, value interface{}) error {
+	ci, ok := vars["ci"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("no CI to set attributes on")
+	}
+	attributes, ok := ci["attributes"].(map[string]interface{})
+	if !ok {
+		attributes = map[string]interface{}{}
+		ci["attributes"] = attributes
+	}
+
+	current := attributes
+	for _, key := range path[:len(path)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			if current[key] != nil {
+				return fmt.Errorf("attribute %q is not an object", key)
+			}
+			next = map[string]interface{}{}
+			current[key] = next
+		}
+		current = next
+	}
+
+	last := path[len(path)-1]
+	if value == nil {
+		delete(current, last)
+	} else {
+		current[last] = value
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package scripthooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	mu         sync.Mutex
+	hooks      map[uuid.UUID]*Hook
+	executions []Execution
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{hooks: map[uuid.UUID]*Hook{}}
+}
+
+func (m *memoryStore) ListHooks(ctx context.Context) ([]*Hook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hooks := []*Hook{}
+	for _, hook := range m.hooks {
+		copied := *hook
+		hooks = append(hooks, &copied)
+	}
+	return hooks, nil
+}
+
+func (m *memoryStore) GetHook(ctx context.Context, id uuid.UUID) (*Hook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hook, ok := m.hooks[id]
+	if !ok {
+		return nil, ErrHookNotFound
+	}
+	copied := *hook
+	return &copied, nil
+}
+
+func (m *memoryStore) CreateHook(ctx context.Context, hook *Hook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *hook
+	m.hooks[hook.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) UpdateHook(ctx context.Context, hook *Hook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.hooks[hook.ID]; !ok {
+		return ErrHookNotFound
+	}
+	copied := *hook
+	m.hooks[hook.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteHook(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.hooks[id]; !ok {
+		return ErrHookNotFound
+	}
+	delete(m.hooks, id)
+	return nil
+}
+
+func (m *memoryStore) RecordExecutions(ctx context.Context, executions []Execution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.executions = append(m.executions, executions...)
+	return nil
+}
+
+func (m *memoryStore) ListExecutions(ctx context.Context, hookID uuid.UUID, outcome string, limit int) ([]Execution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	executions := []Execution{}
+	for i := len(m.executions) - 1; i >= 0 && len(executions) < limit; i-- {
+		if m.executions[i].HookID == hookID && (outcome == "" || m.executions[i].Outcome == outcome) {
+			executions = append(executions, m.executions[i])
+		}
+	}
+	return executions, nil
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile("# only a comment\n", PhasePreSave)
+	assert.Error(t, err)
+
+	_, err = Compile(`set name = "x"`, PhasePreSave)
+	assert.Error(t, err)
+
+	_, err = Compile(`delete attributes.x`, PhasePreSave)
+	assert.Error(t, err)
+
+	_, err = Compile(`set attributes.x = 1`, PhasePostSave)
+	assert.Error(t, err)
+
+	for _, source := range []string{
+		`set attributes.x == 1`,
+		`set attributes..x = 1`,
+		`log`,
+		`log 1 +`,
+		`log 1 when true`,
+		`log 1 if`,
+		`log unknown(1)`,
+	} {
+		_, err = Compile(source, PhasePreSave)
+		assert.Error(t, err, source)
+	}
+
+	_, err = Compile("log ci.name\nreject \"no\" if ci.owner == \"\"", PhasePreSave)
+	assert.NoError(t, err)
+
+	// Quoted text is not mistaken for a condition
+	script, err := Compile(`log "only if needed" + ci.name`, PhasePreSave)
+	require.NoError(t, err)
+	assert.Nil(t, script.statements[0].condition)
+}
+
+func TestScriptRun(t *testing.T) {
+	script, err := Compile(`
+		set attributes.network.fqdn = lower(ci.name) + ".example.com" if !has(ci.attributes.network.fqdn)
+		set attributes.legacy = null
+		log "enriched " + ci.name
+		reject "owner required" if ci.owner == ""
+		log "unreachable"
+	`, PhasePreSave)
+	require.NoError(t, err)
+
+	vars := map[string]interface{}{
+		"ci": map[string]interface{}{
+			"name":       "Web-01",
+			"owner":      "",
+			"attributes": map[string]interface{}{"legacy": true},
+		},
+	}
+	result, err := script.Run(vars, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.Equal(t, "owner required", result.Rejection)
+	assert.Equal(t, []string{"enriched Web-01"}, result.Logs)
+	assert.Equal(t, map[string]interface{}{
+		"network": map[string]interface{}{"fqdn": "web-01.example.com"},
+	}, vars["ci"].(map[string]interface{})["attributes"])
+
+	// A run past its deadline stops
+	_, err = script.Run(vars, time.Now().Add(-time.Second))
+	assert.ErrorIs(t, err, errDeadline)
+}
+
+func newCI(attributes string) *models.CI {
+	return &models.CI{ID: uuid.New(), Name: "web-01", Type: "server", Owner: "ops", Attributes: json.RawMessage(attributes)}
+}
+
+func TestService_PreSave(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, time.Hour)
+	ctx := context.Background()
+
+	_, err := service.CreateHook(ctx, &Hook{
+		Name: "fqdn", Phase: PhasePreSave, Priority: 1, Enabled: true,
+		Script: `set attributes.fqdn = ci.name + ".example.com"`,
+	}, "admin")
+	require.NoError(t, err)
+	_, err = service.CreateHook(ctx, &Hook{
+		Name: "broken", Phase: PhasePreSave, Priority: 2, Enabled: true,
+		Script: "set attributes.partial = true\nset attributes.bad = 1 + \"x\"",
+	}, "admin")
+	require.NoError(t, err)
+	_, err = service.CreateHook(ctx, &Hook{
+		Name: "routers only", Phase: PhasePreSave, CITypes: []string{"router"}, Enabled: true,
+		Script: `reject "no"`,
+	}, "admin")
+	require.NoError(t, err)
+	veto, err := service.CreateHook(ctx, &Hook{
+		Name: "protect prod", Phase: PhasePreSave, Priority: 3, Enabled: true,
+		Script: `reject "production servers cannot be renamed" if previous != null && previous.name != ci.name && ci.attributes.env == "prod"`,
+	}, "admin")
+	require.NoError(t, err)
+
+	// The failing hook is skipped without leaving its partial change
+	ci := newCI(`{"env": "prod"}`)
+	require.NoError(t, service.PreSave(ctx, ci, nil))
+	assert.JSONEq(t, `{"env": "prod", "fqdn": "web-01.example.com"}`, string(ci.Attributes))
+
+	outcomes := map[string]string{}
+	for _, execution := range store.executions {
+		outcomes[execution.HookName] = execution.Outcome
+	}
+	assert.Equal(t, map[string]string{"fqdn": OutcomeOK, "broken": OutcomeError, "protect prod": OutcomeOK}, outcomes)
+
+	// A veto stops the save
+	previous := *ci
+	renamed := *ci
+	renamed.Name = "web-02"
+	err = service.PreSave(ctx, &renamed, &previous)
+	var rejected *RejectedError
+	require.True(t, errors.As(err, &rejected))
+	assert.Equal(t, veto.ID, rejected.HookID)
+	assert.Equal(t, "production servers cannot be renamed", rejected.Message)
+
+	executions, err := service.ListExecutions(ctx, veto.ID, OutcomeRejected, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, EventUpdate, executions[0].Event)
+}
+
+func TestService_PostSave(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, time.Hour)
+	ctx := context.Background()
+
+	hook, err := service.CreateHook(ctx, &Hook{
+		Name: "audit", Phase: PhasePostSave, Enabled: true,
+		Script: `log event + " " + ci.name`,
+	}, "admin")
+	require.NoError(t, err)
+
+	service.PostSave(ctx, newCI(`{}`), nil)
+	executions, err := service.ListExecutions(ctx, hook.ID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, []string{"create web-01"}, executions[0].Logs)
+}
+
+func TestService_CreateHookRejectsInvalid(t *testing.T) {
+	service := NewService(newMemoryStore(), 0)
+	ctx := context.Background()
+
+	_, err := service.CreateHook(ctx, &Hook{Name: "x", Phase: PhasePreSave, Script: `set attributes.x = (`}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidHook)
+
+	_, err = service.CreateHook(ctx, &Hook{Name: "x", Phase: "on_delete", Script: `log 1`}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidHook)
+
+	_, err = service.CreateHook(ctx, &Hook{Name: "x", Phase: PhasePreSave, Script: `log 1`, TimeoutMs: 5000}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidHook)
+}
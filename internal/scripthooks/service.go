@@ -0,0 +1,338 @@
+package scripthooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultCacheTTL bounds how long hooks are reused before being reloaded, so
+// changes made through another instance apply within that delay
+const DefaultCacheTTL = time.Minute
+
+// Service manages hooks and runs them around CI saves
+type Service struct {
+	store    Store
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	hooks    []*Hook
+	loadedAt time.Time
+}
+
+// NewService creates a new script hook service
+func NewService(store Store, cacheTTL time.Duration) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{store: store, cacheTTL: cacheTTL, now: time.Now}
+}
+
+// ListHooks retrieves every hook
+func (s *Service) ListHooks(ctx context.Context) ([]*Hook, error) {
+	return s.store.ListHooks(ctx)
+}
+
+// GetHook retrieves a hook
+func (s *Service) GetHook(ctx context.Context, id uuid.UUID) (*Hook, error) {
+	return s.store.GetHook(ctx, id)
+}
+
+// CreateHook validates and stores a new hook
+func (s *Service) CreateHook(ctx context.Context, hook *Hook, by string) (*Hook, error) {
+	if err := hook.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	hook.ID = uuid.New()
+	hook.CreatedAt = now
+	hook.UpdatedAt = now
+	hook.UpdatedBy = by
+	if err := s.store.CreateHook(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Script hook %s (%s) created by %s", hook.ID, hook.Name, by)
+	return hook, nil
+}
+
+// UpdateHook validates and replaces a hook
+func (s *Service) UpdateHook(ctx context.Context, hook *Hook, by string) (*Hook, error) {
+	existing, err := s.store.GetHook(ctx, hook.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := hook.Validate(); err != nil {
+		return nil, err
+	}
+
+	hook.CreatedAt = existing.CreatedAt
+	hook.UpdatedAt = s.now()
+	hook.UpdatedBy = by
+	if err := s.store.UpdateHook(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Script hook %s (%s) updated by %s", hook.ID, hook.Name, by)
+	return hook, nil
+}
+
+// DeleteHook removes a hook
+func (s *Service) DeleteHook(ctx context.Context, id uuid.UUID, by string) error {
+	if err := s.store.DeleteHook(ctx, id); err != nil {
+		return err
+	}
+
+	s.invalidate()
+	log.Printf("Script hook %s deleted by %s", id, by)
+	return nil
+}
+
+// ListExecutions retrieves the latest runs of a hook
+func (s *Service) ListExecutions(ctx context.Context, hookID uuid.UUID, outcome string, limit int) ([]Execution, error) {
+	if _, err := s.store.GetHook(ctx, hookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	return s.store.ListExecutions(ctx, hookID, outcome, limit)
+}
+
+// PreSave runs the enabled pre-save hooks against a CI about to be saved,
+// updating its attributes with what they set. previous is the stored CI on
+// update and nil on create. It returns a *RejectedError when a hook vetoes
+// the save; hooks that fail are skipped and never block it.
+func (s *Service) PreSave(ctx context.Context, ci *models.CI, previous *models.CI) error {
+	hooks, err := s.enabledHooks(ctx, PhasePreSave, ci.Type)
+	if err != nil {
+		log.Printf("Skipping pre-save script hooks for CI %s: %v", ci.ID, err)
+		return nil
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	event := saveEvent(previous)
+	executions := make([]Execution, 0, len(hooks))
+	defer func() { s.record(ctx, executions) }()
+
+	for _, hook := range hooks {
+		execution, result, attributes := s.run(hook, event, ci, previous)
+		executions = append(executions, execution)
+		if result == nil {
+			continue
+		}
+		if result.Rejection != "" {
+			return &RejectedError{HookID: hook.ID, Hook: hook.Name, Message: result.Rejection}
+		}
+		if result.Changed {
+			ci.Attributes = attributes
+		}
+	}
+	return nil
+}
+
+// PostSave runs the enabled post-save hooks for a saved CI. Their outcome
+// only goes to the execution log.
+func (s *Service) PostSave(ctx context.Context, ci *models.CI, previous *models.CI) {
+	hooks, err := s.enabledHooks(ctx, PhasePostSave, ci.Type)
+	if err != nil {
+		log.Printf("Skipping post-save script hooks for CI %s: %v", ci.ID, err)
+		return
+	}
+
+	event := saveEvent(previous)
+	executions := make([]Execution, 0, len(hooks))
+	for _, hook := range hooks {
+		execution, _, _ := s.run(hook, event, ci, previous)
+		executions = append(executions, execution)
+	}
+	s.record(ctx, executions)
+}
+
+// TestResult is the outcome of trying a script against a sample CI
+type TestResult struct {
+	Execution  Execution       `json:"execution"`
+	Attributes json.RawMessage `json:"attributes,omitempty"`
+}
+
+// Test runs an unsaved hook against a sample CI without logging the run
+func (s *Service) Test(hook *Hook, ci *models.CI, previous *models.CI) (*TestResult, error) {
+	if err := hook.Validate(); err != nil {
+		return nil, err
+	}
+	execution, result, attributes := s.run(hook, saveEvent(previous), ci, previous)
+	test := &TestResult{Execution: execution}
+	if result != nil && result.Changed {
+		test.Attributes = attributes
+	}
+	return test, nil
+}
+
+// run executes one hook in isolation: it works on a copy of the CI, so a hook
+// failing halfway leaves no partial changes, and panics are contained
+func (s *Service) run(hook *Hook, event string, ci *models.CI, previous *models.CI) (execution Execution, result *Result, attributes json.RawMessage) {
+	started := s.now()
+	execution = Execution{
+		ID:         uuid.New(),
+		HookID:     hook.ID,
+		HookName:   hook.Name,
+		CIID:       ci.ID,
+		Phase:      hook.Phase,
+		Event:      event,
+		Logs:       []string{},
+		ExecutedAt: started,
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, attributes = nil, nil
+			execution.Outcome = OutcomeError
+			execution.Message = fmt.Sprintf("script panicked: %v", r)
+		}
+		execution.DurationMs = float64(time.Since(started).Microseconds()) / 1000
+	}()
+
+	vars, err := scriptVars(event, ci, previous)
+	if err != nil {
+		execution.Outcome = OutcomeError
+		execution.Message = err.Error()
+		return execution, nil, nil
+	}
+
+	result, err = hook.compiled.Run(vars, time.Now().Add(hook.Timeout()))
+	if result != nil {
+		execution.Logs = append(execution.Logs, result.Logs...)
+	}
+	switch {
+	case errors.Is(err, errDeadline):
+		execution.Outcome = OutcomeTimeout
+		execution.Message = fmt.Sprintf("script exceeded its %s timeout", hook.Timeout())
+		return execution, nil, nil
+	case err != nil:
+		execution.Outcome = OutcomeError
+		execution.Message = err.Error()
+		return execution, nil, nil
+	case result.Rejection != "":
+		execution.Outcome = OutcomeRejected
+		execution.Message = result.Rejection
+		return execution, result, nil
+	}
+
+	execution.Outcome = OutcomeOK
+	if result.Changed {
+		ciVars := vars["ci"].(map[string]interface{})
+		if attributes, err = json.Marshal(ciVars["attributes"]); err != nil {
+			execution.Outcome = OutcomeError
+			execution.Message = fmt.Sprintf("failed to encode attributes: %v", err)
+			return execution, nil, nil
+		}
+	}
+	return execution, result, attributes
+}
+
+// record writes executions to the log; failing to do so never fails a save
+func (s *Service) record(ctx context.Context, executions []Execution) {
+	if len(executions) == 0 {
+		return
+	}
+	if err := s.store.RecordExecutions(ctx, executions); err != nil {
+		log.Printf("Failed to record script hook executions: %v", err)
+	}
+}
+
+// scriptVars exposes the CIs to scripts with their JSON field names
+func scriptVars(event string, ci *models.CI, previous *models.CI) (map[string]interface{}, error) {
+	current, err := toValue(ci)
+	if err != nil {
+		return nil, err
+	}
+	var stored interface{}
+	if previous != nil {
+		if stored, err = toValue(previous); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{"ci": current, "previous": stored, "event": event}, nil
+}
+
+// toValue converts a CI to the maps and lists scripts work with
+func toValue(ci *models.CI) (map[string]interface{}, error) {
+	data, err := json.Marshal(ci)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CI: %w", err)
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode CI: %w", err)
+	}
+	return value, nil
+}
+
+func saveEvent(previous *models.CI) string {
+	if previous == nil {
+		return EventCreate
+	}
+	return EventUpdate
+}
+
+// enabledHooks returns the enabled hooks of a phase for a CI type in run
+// order, reloading them once the cache expires
+func (s *Service) enabledHooks(ctx context.Context, phase, ciType string) ([]*Hook, error) {
+	s.mu.Lock()
+	hooks := s.hooks
+	fresh := hooks != nil && s.now().Sub(s.loadedAt) < s.cacheTTL
+	s.mu.Unlock()
+
+	if !fresh {
+		stored, err := s.store.ListHooks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		hooks = make([]*Hook, 0, len(stored))
+		for _, hook := range stored {
+			if !hook.Enabled {
+				continue
+			}
+			if err := hook.Validate(); err != nil {
+				log.Printf("Skipping invalid script hook %s: %v", hook.ID, err)
+				continue
+			}
+			hooks = append(hooks, hook)
+		}
+		sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].Priority < hooks[j].Priority })
+
+		s.mu.Lock()
+		s.hooks = hooks
+		s.loadedAt = s.now()
+		s.mu.Unlock()
+	}
+
+	var matching []*Hook
+	for _, hook := range hooks {
+		if hook.Phase == phase && hook.AppliesTo(ciType) {
+			matching = append(matching, hook)
+		}
+	}
+	return matching, nil
+}
+
+// invalidate drops the cached hooks so the next save reloads them
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.hooks = nil
+	s.mu.Unlock()
+}
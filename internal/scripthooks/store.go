@@ -0,0 +1,176 @@
+package scripthooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store persists hooks and their execution logs
+type Store interface {
+	ListHooks(ctx context.Context) ([]*Hook, error)
+	GetHook(ctx context.Context, id uuid.UUID) (*Hook, error)
+	CreateHook(ctx context.Context, hook *Hook) error
+	UpdateHook(ctx context.Context, hook *Hook) error
+	DeleteHook(ctx context.Context, id uuid.UUID) error
+	RecordExecutions(ctx context.Context, executions []Execution) error
+	// ListExecutions returns the latest runs of a hook, newest first
+	ListExecutions(ctx context.Context, hookID uuid.UUID, outcome string, limit int) ([]Execution, error)
+}
+
+// PostgresStore keeps hooks in the script_hooks and script_hook_executions tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed hook store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const hookColumns = `id, name, phase, ci_types, script, priority, timeout_ms, enabled, created_at, updated_at, COALESCE(updated_by, '')`
+
+// scanHook reads a hook row selected with hookColumns
+func scanHook(row interface{ Scan(...interface{}) error }) (*Hook, error) {
+	var hook Hook
+	var ciTypes pq.StringArray
+	if err := row.Scan(&hook.ID, &hook.Name, &hook.Phase, &ciTypes, &hook.Script, &hook.Priority,
+		&hook.TimeoutMs, &hook.Enabled, &hook.CreatedAt, &hook.UpdatedAt, &hook.UpdatedBy); err != nil {
+		return nil, err
+	}
+	hook.CITypes = []string(ciTypes)
+	return &hook, nil
+}
+
+// ListHooks retrieves every hook in run order
+func (s *PostgresStore) ListHooks(ctx context.Context) ([]*Hook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+hookColumns+` FROM script_hooks ORDER BY phase, priority, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list script hooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []*Hook{}
+	for rows.Next() {
+		hook, err := scanHook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan script hook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list script hooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// GetHook retrieves a hook
+func (s *PostgresStore) GetHook(ctx context.Context, id uuid.UUID) (*Hook, error) {
+	hook, err := scanHook(s.db.QueryRowContext(ctx, `SELECT `+hookColumns+` FROM script_hooks WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrHookNotFound
+		}
+		return nil, fmt.Errorf("failed to get script hook: %w", err)
+	}
+	return hook, nil
+}
+
+// CreateHook inserts a hook
+func (s *PostgresStore) CreateHook(ctx context.Context, hook *Hook) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO script_hooks (id, name, phase, ci_types, script, priority, timeout_ms, enabled, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		hook.ID, hook.Name, hook.Phase, pq.Array(hook.CITypes), hook.Script, hook.Priority,
+		hook.TimeoutMs, hook.Enabled, hook.CreatedAt, hook.UpdatedAt, hook.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create script hook: %w", err)
+	}
+	return nil
+}
+
+// UpdateHook replaces a hook
+func (s *PostgresStore) UpdateHook(ctx context.Context, hook *Hook) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE script_hooks
+		SET name = $2, phase = $3, ci_types = $4, script = $5, priority = $6, timeout_ms = $7,
+		    enabled = $8, updated_at = $9, updated_by = $10
+		WHERE id = $1`,
+		hook.ID, hook.Name, hook.Phase, pq.Array(hook.CITypes), hook.Script, hook.Priority,
+		hook.TimeoutMs, hook.Enabled, hook.UpdatedAt, hook.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update script hook: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrHookNotFound
+	}
+	return nil
+}
+
+// DeleteHook removes a hook along with its execution log
+func (s *PostgresStore) DeleteHook(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM script_hooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete script hook: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrHookNotFound
+	}
+	return nil
+}
+
+// RecordExecutions appends runs to the execution log
+func (s *PostgresStore) RecordExecutions(ctx context.Context, executions []Execution) error {
+	for _, execution := range executions {
+		logs, err := json.Marshal(execution.Logs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal script hook logs: %w", err)
+		}
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO script_hook_executions (id, hook_id, hook_name, ci_id, phase, event, outcome, message, logs, duration_ms, executed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11)`,
+			execution.ID, execution.HookID, execution.HookName, execution.CIID, execution.Phase, execution.Event,
+			execution.Outcome, execution.Message, logs, execution.DurationMs, execution.ExecutedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record script hook execution: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListExecutions retrieves the latest runs of a hook, optionally by outcome
+func (s *PostgresStore) ListExecutions(ctx context.Context, hookID uuid.UUID, outcome string, limit int) ([]Execution, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, hook_id, hook_name, ci_id, phase, event, outcome, COALESCE(message, ''), logs, duration_ms, executed_at
+		FROM script_hook_executions
+		WHERE hook_id = $1 AND ($2 = '' OR outcome = $2)
+		ORDER BY executed_at DESC
+		LIMIT $3`, hookID, outcome, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list script hook executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions := []Execution{}
+	for rows.Next() {
+		var execution Execution
+		var logs []byte
+		if err := rows.Scan(&execution.ID, &execution.HookID, &execution.HookName, &execution.CIID, &execution.Phase,
+			&execution.Event, &execution.Outcome, &execution.Message, &logs, &execution.DurationMs, &execution.ExecutedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan script hook execution: %w", err)
+		}
+		if err := json.Unmarshal(logs, &execution.Logs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal script hook logs: %w", err)
+		}
+		executions = append(executions, execution)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list script hook executions: %w", err)
+	}
+	return executions, nil
+}
@@ -0,0 +1,249 @@
+package seeder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// BaseTime is the fixed reference time of generated timestamps, so datasets are reproducible
+var BaseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// seedNamespace scopes the name-based UUIDs of generated records
+var seedNamespace = uuid.MustParse("6f1f0d5e-3c1b-4f0e-9a4e-2d8c5b7a9e10")
+
+// Dataset is a generated set of schemas, CIs and relationships
+type Dataset struct {
+	Profile             Profile                          `json:"profile"`
+	Seed                int64                            `json:"seed"`
+	CISchemas           []*models.CITypeSchema           `json:"ci_schemas"`
+	RelationshipSchemas []*models.RelationshipTypeSchema `json:"relationship_schemas"`
+	CIs                 []*models.CI                     `json:"cis"`
+	Relationships       []*models.CIRelationship         `json:"relationships"`
+}
+
+// generator holds the state of one Generate call
+type generator struct {
+	rng     *rand.Rand
+	seed    int64
+	actor   uuid.UUID
+	dataset *Dataset
+	names   map[string]int
+}
+
+// Generate builds a dataset for the profile. The same profile and seed always produce
+// the same dataset, including IDs and timestamps.
+func Generate(profile Profile, seed int64) *Dataset {
+	g := &generator{
+		rng:   rand.New(rand.NewSource(seed)),
+		seed:  seed,
+		names: make(map[string]int),
+		dataset: &Dataset{
+			Profile: profile,
+			Seed:    seed,
+		},
+	}
+	g.actor = g.id("user", "seeder")
+
+	g.addSchemas()
+
+	var datacenters, switches []*models.CI
+	for d := 0; d < profile.Datacenters; d++ {
+		city := pick(g.rng, cities)
+		dc := g.addCI("building", fmt.Sprintf("dc-%s-%02d", city.code, d+1), city.name, models.CICriticalityCritical, map[string]interface{}{
+			"address":    city.name,
+			"tier":       3 + g.rng.Intn(2),
+			"power_kw":   500 + g.rng.Intn(20)*100,
+			"rack_count": 50 + g.rng.Intn(150),
+		})
+		datacenters = append(datacenters, dc)
+
+		for n := 0; n < profile.NetworkDevicesPerDatacenter; n++ {
+			sw := g.addCI("network_device", fmt.Sprintf("sw-%s%02d-%03d", city.code, d+1, n+1), city.name, models.CICriticalityHigh, map[string]interface{}{
+				"vendor":        pick(g.rng, networkVendors),
+				"model":         fmt.Sprintf("NX-%d", 9000+g.rng.Intn(300)),
+				"ip_address":    g.ip(10, d, n),
+				"port_count":    pick(g.rng, []int{24, 48, 96}),
+				"serial_number": g.serial("NW"),
+			})
+			g.addRelationship(sw, dc, "located_in")
+			if n > 0 {
+				g.addRelationship(sw, switches[len(switches)-1], "connected_to")
+			}
+			switches = append(switches, sw)
+		}
+	}
+
+	var applications []*models.CI
+	for s := 0; s < profile.Services; s++ {
+		domain := businessDomains[s%len(businessDomains)]
+		serviceName := g.uniqueName(fmt.Sprintf("%s-service", domain))
+		criticality := pick(g.rng, []string{models.CICriticalityCritical, models.CICriticalityHigh, models.CICriticalityHigh, models.CICriticalityMedium})
+		service := g.addCI("business_service", serviceName, "", criticality, map[string]interface{}{
+			"business_owner": fmt.Sprintf("%s-owner@example.com", domain),
+			"sla":            pick(g.rng, []string{"99.9", "99.95", "99.99"}),
+		})
+		service.Tags = append(service.Tags, domain)
+
+		var databases []*models.CI
+		for db := 0; db < profile.DatabasesPerService; db++ {
+			database := g.addCI("database", g.uniqueName(fmt.Sprintf("%s-db", domain)), "", criticality, map[string]interface{}{
+				"engine":     pick(g.rng, databaseEngines),
+				"version":    fmt.Sprintf("%d.%d", 12+g.rng.Intn(5), g.rng.Intn(10)),
+				"size_gb":    10 * (1 + g.rng.Intn(200)),
+				"replicated": g.rng.Intn(2) == 0,
+			})
+			databases = append(databases, database)
+		}
+
+		for a := 0; a < profile.ApplicationsPerService; a++ {
+			component := pick(g.rng, applicationComponents)
+			app := g.addCI("application", g.uniqueName(fmt.Sprintf("%s-%s", domain, component)), "", criticality, map[string]interface{}{
+				"language": pick(g.rng, languages),
+				"version":  fmt.Sprintf("%d.%d.%d", 1+g.rng.Intn(4), g.rng.Intn(20), g.rng.Intn(10)),
+				"repo":     fmt.Sprintf("git.example.com/%s/%s", domain, component),
+			})
+			app.Tags = append(app.Tags, domain)
+			g.addRelationship(service, app, "depends_on")
+
+			if len(databases) > 0 {
+				g.addRelationship(app, databases[g.rng.Intn(len(databases))], "uses")
+			}
+			if len(applications) > 0 && g.rng.Float64() < profile.SharedDependencyRate {
+				g.addRelationship(app, applications[g.rng.Intn(len(applications))], "depends_on")
+			}
+
+			for v := 0; v < profile.ServersPerApplication; v++ {
+				server := g.addServer(datacenters, switches, domain)
+				g.addRelationship(app, server, "runs_on")
+			}
+			applications = append(applications, app)
+		}
+
+		for _, database := range databases {
+			server := g.addServer(datacenters, switches, domain)
+			g.addRelationship(database, server, "runs_on")
+		}
+	}
+
+	return g.dataset
+}
+
+// addServer adds a server hosted in a random datacenter and connected to one of its switches
+func (g *generator) addServer(datacenters, switches []*models.CI, domain string) *models.CI {
+	serverIndex := g.names["srv"]
+	g.names["srv"]++
+
+	var datacenter *models.CI
+	location := ""
+	if len(datacenters) > 0 {
+		datacenter = datacenters[g.rng.Intn(len(datacenters))]
+		location = datacenter.Location
+	}
+
+	server := g.addCI("server", fmt.Sprintf("srv-%05d", serverIndex+1), location, pick(g.rng, []string{models.CICriticalityHigh, models.CICriticalityMedium, models.CICriticalityLow}), map[string]interface{}{
+		"hostname":      fmt.Sprintf("srv-%05d.%s.example.com", serverIndex+1, domain),
+		"ip_address":    g.ip(10, 100+serverIndex/65025, serverIndex%65025),
+		"os":            pick(g.rng, operatingSystems),
+		"cpu_cores":     pick(g.rng, []int{2, 4, 8, 16, 32, 64}),
+		"memory_gb":     pick(g.rng, []int{4, 8, 16, 32, 64, 128, 256}),
+		"serial_number": g.serial("SV"),
+		"virtual":       g.rng.Intn(3) > 0,
+	})
+	server.Tags = append(server.Tags, domain)
+
+	if datacenter != nil {
+		g.addRelationship(server, datacenter, "hosted_in")
+	}
+	if len(switches) > 0 {
+		g.addRelationship(server, switches[g.rng.Intn(len(switches))], "connected_to")
+	}
+
+	return server
+}
+
+// addCI appends a CI with deterministic ID, timestamps and status
+func (g *generator) addCI(ciType, name, location, criticality string, attributes map[string]interface{}) *models.CI {
+	attributesJSON, _ := json.Marshal(attributes)
+
+	created := BaseTime.Add(time.Duration(g.rng.Intn(365*24)) * time.Hour)
+	updated := created.Add(time.Duration(g.rng.Intn(90*24)) * time.Hour)
+	status := models.CIStatusActive
+	if g.rng.Intn(20) == 0 {
+		status = pick(g.rng, []string{models.CIStatusMaintenance, models.CIStatusInactive})
+	}
+
+	ci := &models.CI{
+		ID:          g.id("ci", name),
+		Name:        name,
+		Type:        ciType,
+		Description: fmt.Sprintf("Generated %s %s", ciType, name),
+		Status:      status,
+		Criticality: criticality,
+		Owner:       pick(g.rng, teams),
+		Location:    location,
+		Attributes:  attributesJSON,
+		Tags:        []string{"seed", g.dataset.Profile.Name},
+		LastUpdated: &updated,
+		IsActive:    true,
+		CreatedAt:   created,
+		UpdatedAt:   updated,
+		CreatedBy:   g.actor,
+		UpdatedBy:   g.actor,
+	}
+	g.dataset.CIs = append(g.dataset.CIs, ci)
+	return ci
+}
+
+// addRelationship appends an active relationship between two CIs
+func (g *generator) addRelationship(source, target *models.CI, relType string) {
+	created := source.CreatedAt
+	if target.CreatedAt.After(created) {
+		created = target.CreatedAt
+	}
+
+	g.dataset.Relationships = append(g.dataset.Relationships, &models.CIRelationship{
+		ID:          g.id("relationship", source.Name+"/"+relType+"/"+target.Name),
+		SourceCIID:  source.ID,
+		TargetCIID:  target.ID,
+		Type:        relType,
+		Attributes:  json.RawMessage(`{}`),
+		Description: fmt.Sprintf("%s %s %s", source.Name, relType, target.Name),
+		IsActive:    true,
+		State:       models.RelationshipStateActive,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+		CreatedBy:   g.actor,
+		UpdatedBy:   g.actor,
+	})
+}
+
+// id derives a stable UUID from the seed, a kind and a name
+func (g *generator) id(kind, name string) uuid.UUID {
+	return uuid.NewSHA1(seedNamespace, []byte(fmt.Sprintf("%d/%s/%s", g.seed, kind, name)))
+}
+
+// uniqueName numbers names so repeated prefixes stay unique
+func (g *generator) uniqueName(prefix string) string {
+	g.names[prefix]++
+	return fmt.Sprintf("%s-%02d", prefix, g.names[prefix])
+}
+
+// serial returns a random serial number with a prefix
+func (g *generator) serial(prefix string) string {
+	return fmt.Sprintf("%s%010d", prefix, g.rng.Int63n(1e10))
+}
+
+// ip returns a private IPv4 address, spreading n over the last two octets
+func (g *generator) ip(first, second, n int) string {
+	return fmt.Sprintf("%d.%d.%d.%d", first, second%256, (n/254)%256, n%254+1)
+}
+
+// pick returns a random element of values
+func pick[T any](rng *rand.Rand, values []T) T {
+	return values[rng.Intn(len(values))]
+}
@@ -0,0 +1,152 @@
+package seeder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_IsDeterministic(t *testing.T) {
+	profile, err := GetProfile("demo")
+	require.NoError(t, err)
+
+	first, err := json.Marshal(Generate(profile, 42))
+	require.NoError(t, err)
+	second, err := json.Marshal(Generate(profile, 42))
+	require.NoError(t, err)
+	other, err := json.Marshal(Generate(profile, 7))
+	require.NoError(t, err)
+
+	assert.Equal(t, string(first), string(second))
+	assert.NotEqual(t, string(first), string(other))
+}
+
+func TestGenerate_ProducesConsistentGraph(t *testing.T) {
+	profile, err := GetProfile("demo")
+	require.NoError(t, err)
+	dataset := Generate(profile, 1)
+
+	ids := make(map[uuid.UUID]bool)
+	names := make(map[string]bool)
+	for _, ci := range dataset.CIs {
+		assert.False(t, ids[ci.ID], "duplicate CI ID %s", ci.ID)
+		assert.False(t, names[ci.Type+"/"+ci.Name], "duplicate CI name %s", ci.Name)
+		ids[ci.ID] = true
+		names[ci.Type+"/"+ci.Name] = true
+	}
+
+	schemaTypes := make(map[string]bool)
+	for _, schema := range dataset.CISchemas {
+		schemaTypes[schema.Name] = true
+	}
+	for _, ci := range dataset.CIs {
+		assert.True(t, schemaTypes[ci.Type], "CI %s has no schema for type %s", ci.Name, ci.Type)
+	}
+
+	relTypes := make(map[string]bool)
+	for _, schema := range dataset.RelationshipSchemas {
+		relTypes[schema.Name] = true
+	}
+	for _, rel := range dataset.Relationships {
+		assert.True(t, ids[rel.SourceCIID] && ids[rel.TargetCIID], "relationship %s references unknown CIs", rel.ID)
+		assert.True(t, relTypes[rel.Type], "relationship type %s has no schema", rel.Type)
+	}
+
+	expectedCIs := profile.Datacenters*(1+profile.NetworkDevicesPerDatacenter) +
+		profile.Services*(1+profile.DatabasesPerService*2+profile.ApplicationsPerService*(1+profile.ServersPerApplication))
+	assert.Equal(t, expectedCIs, len(dataset.CIs))
+}
+
+func TestGetProfile_Unknown(t *testing.T) {
+	_, err := GetProfile("huge")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"demo", "large"}, ProfileNames())
+}
+
+type memorySink struct {
+	ciSchemas  map[string]*models.CITypeSchema
+	relSchemas map[string]*models.RelationshipTypeSchema
+	cis        map[uuid.UUID]*models.CI
+	rels       map[uuid.UUID]*models.CIRelationship
+}
+
+func newMemorySink() *memorySink {
+	return &memorySink{
+		ciSchemas:  make(map[string]*models.CITypeSchema),
+		relSchemas: make(map[string]*models.RelationshipTypeSchema),
+		cis:        make(map[uuid.UUID]*models.CI),
+		rels:       make(map[uuid.UUID]*models.CIRelationship),
+	}
+}
+
+func (m *memorySink) GetCITypeSchemaByName(ctx context.Context, name string) (*models.CITypeSchema, error) {
+	if schema, ok := m.ciSchemas[name]; ok {
+		return schema, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *memorySink) CreateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error) {
+	m.ciSchemas[schema.Name] = schema
+	return schema, nil
+}
+
+func (m *memorySink) GetRelationshipTypeSchemaByName(ctx context.Context, name string) (*models.RelationshipTypeSchema, error) {
+	if schema, ok := m.relSchemas[name]; ok {
+		return schema, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *memorySink) CreateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error) {
+	m.relSchemas[schema.Name] = schema
+	return schema, nil
+}
+
+func (m *memorySink) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	if ci, ok := m.cis[id]; ok {
+		return ci, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *memorySink) CreateCI(ctx context.Context, ci *models.CI) (*models.CI, error) {
+	m.cis[ci.ID] = ci
+	return ci, nil
+}
+
+func (m *memorySink) GetRelationship(ctx context.Context, id uuid.UUID) (*models.CIRelationship, error) {
+	if rel, ok := m.rels[id]; ok {
+		return rel, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *memorySink) CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error) {
+	m.rels[rel.ID] = rel
+	return rel, nil
+}
+
+func TestLoad_IsIdempotent(t *testing.T) {
+	profile, err := GetProfile("demo")
+	require.NoError(t, err)
+	dataset := Generate(profile, 42)
+	sink := newMemorySink()
+
+	stats, err := Load(context.Background(), sink, dataset, nil)
+	require.NoError(t, err)
+	assert.Equal(t, len(dataset.CIs), stats.CIsCreated)
+	assert.Equal(t, len(dataset.Relationships), stats.RelationshipsCreated)
+	assert.Equal(t, len(dataset.CISchemas)+len(dataset.RelationshipSchemas), stats.SchemasCreated)
+
+	stats, err = Load(context.Background(), sink, dataset, nil)
+	require.NoError(t, err)
+	assert.Zero(t, stats.CIsCreated+stats.RelationshipsCreated+stats.SchemasCreated)
+	assert.Equal(t, len(dataset.CIs)+len(dataset.Relationships)+len(dataset.CISchemas)+len(dataset.RelationshipSchemas), stats.Skipped)
+}
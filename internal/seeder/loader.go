@@ -0,0 +1,95 @@
+package seeder
+
+import (
+	"context"
+	"fmt"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Sink receives generated records. repositories.CIRepository satisfies it.
+type Sink interface {
+	GetCITypeSchemaByName(ctx context.Context, name string) (*models.CITypeSchema, error)
+	CreateCITypeSchema(ctx context.Context, schema *models.CITypeSchema) (*models.CITypeSchema, error)
+	GetRelationshipTypeSchemaByName(ctx context.Context, name string) (*models.RelationshipTypeSchema, error)
+	CreateRelationshipTypeSchema(ctx context.Context, schema *models.RelationshipTypeSchema) (*models.RelationshipTypeSchema, error)
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+	CreateCI(ctx context.Context, ci *models.CI) (*models.CI, error)
+	GetRelationship(ctx context.Context, id uuid.UUID) (*models.CIRelationship, error)
+	CreateRelationship(ctx context.Context, rel *models.CIRelationship) (*models.CIRelationship, error)
+}
+
+// LoadStats counts the records written and skipped by Load
+type LoadStats struct {
+	SchemasCreated       int `json:"schemas_created"`
+	CIsCreated           int `json:"cis_created"`
+	RelationshipsCreated int `json:"relationships_created"`
+	Skipped              int `json:"skipped"`
+}
+
+// Load writes a dataset to the sink. Records that already exist are skipped, so
+// loading the same profile and seed twice is a no-op.
+func Load(ctx context.Context, sink Sink, dataset *Dataset, progress func(LoadStats)) (*LoadStats, error) {
+	stats := &LoadStats{}
+	report := func() {
+		if progress != nil {
+			progress(*stats)
+		}
+	}
+
+	for _, schema := range dataset.CISchemas {
+		if existing, err := sink.GetCITypeSchemaByName(ctx, schema.Name); err == nil && existing != nil {
+			stats.Skipped++
+			continue
+		}
+		if _, err := sink.CreateCITypeSchema(ctx, schema); err != nil {
+			return stats, fmt.Errorf("failed to seed CI schema %s: %w", schema.Name, err)
+		}
+		stats.SchemasCreated++
+	}
+
+	for _, schema := range dataset.RelationshipSchemas {
+		if existing, err := sink.GetRelationshipTypeSchemaByName(ctx, schema.Name); err == nil && existing != nil {
+			stats.Skipped++
+			continue
+		}
+		if _, err := sink.CreateRelationshipTypeSchema(ctx, schema); err != nil {
+			return stats, fmt.Errorf("failed to seed relationship schema %s: %w", schema.Name, err)
+		}
+		stats.SchemasCreated++
+	}
+	report()
+
+	for i, ci := range dataset.CIs {
+		if existing, err := sink.GetCI(ctx, ci.ID); err == nil && existing != nil {
+			stats.Skipped++
+		} else {
+			if _, err := sink.CreateCI(ctx, ci); err != nil {
+				return stats, fmt.Errorf("failed to seed CI %s: %w", ci.Name, err)
+			}
+			stats.CIsCreated++
+		}
+		if (i+1)%500 == 0 {
+			report()
+		}
+	}
+	report()
+
+	for i, rel := range dataset.Relationships {
+		if existing, err := sink.GetRelationship(ctx, rel.ID); err == nil && existing != nil {
+			stats.Skipped++
+		} else {
+			if _, err := sink.CreateRelationship(ctx, rel); err != nil {
+				return stats, fmt.Errorf("failed to seed relationship %s: %w", rel.Description, err)
+			}
+			stats.RelationshipsCreated++
+		}
+		if (i+1)%500 == 0 {
+			report()
+		}
+	}
+	report()
+
+	return stats, nil
+}
@@ -0,0 +1,60 @@
+package seeder
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Profile controls the size and shape of a generated dataset
+type Profile struct {
+	Name                        string  `json:"name"`
+	Datacenters                 int     `json:"datacenters"`
+	NetworkDevicesPerDatacenter int     `json:"network_devices_per_datacenter"`
+	Services                    int     `json:"services"`
+	ApplicationsPerService      int     `json:"applications_per_service"`
+	ServersPerApplication       int     `json:"servers_per_application"`
+	DatabasesPerService         int     `json:"databases_per_service"`
+	SharedDependencyRate        float64 `json:"shared_dependency_rate"` // chance an application also depends on another service's application
+}
+
+var profiles = map[string]Profile{
+	"demo": {
+		Name:                        "demo",
+		Datacenters:                 2,
+		NetworkDevicesPerDatacenter: 4,
+		Services:                    4,
+		ApplicationsPerService:      3,
+		ServersPerApplication:       2,
+		DatabasesPerService:         1,
+		SharedDependencyRate:        0.2,
+	},
+	"large": {
+		Name:                        "large",
+		Datacenters:                 6,
+		NetworkDevicesPerDatacenter: 40,
+		Services:                    60,
+		ApplicationsPerService:      8,
+		ServersPerApplication:       6,
+		DatabasesPerService:         4,
+		SharedDependencyRate:        0.1,
+	},
+}
+
+// GetProfile returns a built-in profile by name
+func GetProfile(name string) (Profile, error) {
+	profile, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown seed profile %q (available: %v)", name, ProfileNames())
+	}
+	return profile, nil
+}
+
+// ProfileNames returns the names of the built-in profiles
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+package seeder
+
+import (
+	"connect/internal/models"
+)
+
+type city struct {
+	code string
+	name string
+}
+
+var (
+	cities = []city{
+		{"ams", "Amsterdam"}, {"fra", "Frankfurt"}, {"lon", "London"}, {"nyc", "New York"},
+		{"sin", "Singapore"}, {"syd", "Sydney"}, {"tyo", "Tokyo"}, {"sao", "Sao Paulo"},
+	}
+	businessDomains       = []string{"payment", "checkout", "inventory", "shipping", "billing", "identity", "search", "catalog", "reporting", "notification"}
+	applicationComponents = []string{"api", "web", "worker", "gateway", "scheduler", "cache", "ingest", "export"}
+	networkVendors        = []string{"Cisco", "Juniper", "Arista", "Nokia"}
+	databaseEngines       = []string{"postgresql", "mysql", "mongodb", "redis", "oracle"}
+	languages             = []string{"go", "java", "python", "node", "dotnet"}
+	operatingSystems      = []string{"Ubuntu 22.04", "RHEL 9", "Debian 12", "Windows Server 2022"}
+	teams                 = []string{"platform-team", "sre-team", "network-team", "dba-team", "payments-team", "commerce-team"}
+)
+
+// addSchemas adds the CI and relationship type schemas used by the generated CIs
+func (g *generator) addSchemas() {
+	ciSchemas := []struct {
+		name        string
+		description string
+		attributes  []models.CITypeAttribute
+	}{
+		{"building", "Datacenter or office building", []models.CITypeAttribute{
+			{Name: "address", Type: models.AttributeTypeString, Required: true, Description: "Street address"},
+			{Name: "tier", Type: models.AttributeTypeNumber, Description: "Uptime tier"},
+			{Name: "power_kw", Type: models.AttributeTypeNumber, Description: "Power capacity in kW"},
+			{Name: "rack_count", Type: models.AttributeTypeNumber, Description: "Number of racks"},
+		}},
+		{"network_device", "Switch, router or firewall", []models.CITypeAttribute{
+			{Name: "vendor", Type: models.AttributeTypeString, Required: true, Description: "Device vendor"},
+			{Name: "model", Type: models.AttributeTypeString, Description: "Device model"},
+			{Name: "ip_address", Type: models.AttributeTypeString, Required: true, Description: "Management IP address"},
+			{Name: "port_count", Type: models.AttributeTypeNumber, Description: "Number of ports"},
+			{Name: "serial_number", Type: models.AttributeTypeString, Description: "Serial number", Validation: map[string]interface{}{"unique": true}},
+		}},
+		{"server", "Physical or virtual server", []models.CITypeAttribute{
+			{Name: "hostname", Type: models.AttributeTypeString, Required: true, Description: "Fully qualified hostname"},
+			{Name: "ip_address", Type: models.AttributeTypeString, Required: true, Description: "Primary IP address"},
+			{Name: "os", Type: models.AttributeTypeString, Description: "Operating system"},
+			{Name: "cpu_cores", Type: models.AttributeTypeNumber, Description: "CPU cores"},
+			{Name: "memory_gb", Type: models.AttributeTypeNumber, Description: "Memory in GB"},
+			{Name: "serial_number", Type: models.AttributeTypeString, Description: "Serial number", Validation: map[string]interface{}{"unique": true}},
+			{Name: "virtual", Type: models.AttributeTypeBoolean, Description: "Whether the server is a virtual machine", Default: false},
+		}},
+		{"database", "Database instance", []models.CITypeAttribute{
+			{Name: "engine", Type: models.AttributeTypeString, Required: true, Description: "Database engine"},
+			{Name: "version", Type: models.AttributeTypeString, Description: "Engine version"},
+			{Name: "size_gb", Type: models.AttributeTypeNumber, Description: "Data size in GB"},
+			{Name: "replicated", Type: models.AttributeTypeBoolean, Description: "Whether the instance is replicated", Default: false},
+		}},
+		{"application", "Deployable application component", []models.CITypeAttribute{
+			{Name: "language", Type: models.AttributeTypeString, Description: "Implementation language"},
+			{Name: "version", Type: models.AttributeTypeString, Required: true, Description: "Deployed version"},
+			{Name: "repo", Type: models.AttributeTypeString, Description: "Source repository"},
+		}},
+		{"business_service", "Business service offered to users", []models.CITypeAttribute{
+			{Name: "business_owner", Type: models.AttributeTypeString, Required: true, Description: "Accountable business owner"},
+			{Name: "sla", Type: models.AttributeTypeString, Description: "Availability target in percent"},
+		}},
+	}
+
+	for _, s := range ciSchemas {
+		g.dataset.CISchemas = append(g.dataset.CISchemas, &models.CITypeSchema{
+			ID:          g.id("ci_schema", s.name),
+			Name:        s.name,
+			Description: s.description,
+			Attributes:  s.attributes,
+			IsActive:    true,
+			CreatedAt:   BaseTime,
+			UpdatedAt:   BaseTime,
+			CreatedBy:   g.actor,
+			UpdatedBy:   g.actor,
+		})
+	}
+
+	relationshipSchemas := []struct {
+		name        string
+		description string
+	}{
+		{"depends_on", "Source requires the target to function"},
+		{"uses", "Source reads or writes data in the target"},
+		{"runs_on", "Source is deployed on the target"},
+		{"hosted_in", "Source is physically hosted in the target"},
+		{"located_in", "Source is installed in the target"},
+		{"connected_to", "Source has a network link to the target"},
+	}
+
+	for _, s := range relationshipSchemas {
+		g.dataset.RelationshipSchemas = append(g.dataset.RelationshipSchemas, &models.RelationshipTypeSchema{
+			ID:          g.id("relationship_schema", s.name),
+			Name:        s.name,
+			Description: s.description,
+			Attributes:  []models.CITypeAttribute{},
+			IsActive:    true,
+			CreatedAt:   BaseTime,
+			UpdatedAt:   BaseTime,
+			CreatedBy:   g.actor,
+			UpdatedBy:   g.actor,
+		})
+	}
+}
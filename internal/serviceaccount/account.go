@@ -0,0 +1,204 @@
+// Package serviceaccount manages the identities automation uses, kept apart
+// from the users people log in as. A service account has no password: it
+// authenticates with an API key sent on each request, or exchanges a client
+// secret for an access token with the OAuth client credentials grant. Every
+// account has a human owner responsible for it and may expire; its
+// credentials expire no later than it does.
+//
+// Tokens issued to a service account carry its name prefixed with
+// auth.ServiceAccountPrefix as the username, so what it does can be told
+// apart from what people do.
+package serviceaccount
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Credential kinds
+const (
+	// KindAPIKey is sent as the X-API-Key header on each request
+	KindAPIKey = "api_key"
+	// KindClientSecret is exchanged for an access token with the client
+	// credentials grant
+	KindClientSecret = "client_secret"
+)
+
+// Credential lifetimes
+const (
+	DefaultCredentialLifetime = 90 * 24 * time.Hour
+	MaxCredentialLifetime     = 365 * 24 * time.Hour
+)
+
+// secretPrefix starts every credential, so leaked ones are easy to scan for
+const secretPrefix = "conxsa_"
+
+// maxNameLength bounds the length of an account name
+const maxNameLength = 63
+
+var (
+	ErrInvalidAccount     = errors.New("invalid service account")
+	ErrAccountNotFound    = errors.New("service account not found")
+	ErrNameTaken          = errors.New("service account name already in use")
+	ErrInvalidCredential  = errors.New("invalid service account credential")
+	ErrCredentialNotFound = errors.New("service account credential not found")
+	ErrUnknownRole        = errors.New("unknown role")
+)
+
+// Account is an automation identity
+type Account struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	// OwnerID is the user responsible for the account
+	OwnerID   uuid.UUID  `json:"owner_id" db:"owner_id"`
+	OwnerTeam string     `json:"owner_team" db:"owner_team"`
+	Roles     []string   `json:"roles" db:"-"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	IsActive  bool       `json:"is_active" db:"is_active"`
+	// LastUsedAt is when one of its credentials last authenticated
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy  uuid.UUID  `json:"created_by" db:"created_by"`
+	UpdatedBy  uuid.UUID  `json:"updated_by" db:"updated_by"`
+}
+
+// Expired reports whether the account has expired at now
+func (a *Account) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// Credential is an API key or client secret of an account. Only a hash of
+// the secret is kept; the prefix identifies it.
+type Credential struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	AccountID   uuid.UUID  `json:"account_id" db:"account_id"`
+	Kind        string     `json:"kind" db:"kind"`
+	Prefix      string     `json:"prefix" db:"prefix"`
+	SecretHash  string     `json:"-" db:"secret_hash"`
+	Description string     `json:"description" db:"description"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CreatedBy   uuid.UUID  `json:"created_by" db:"created_by"`
+}
+
+// Expired reports whether the credential has expired at now
+func (c *Credential) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
+}
+
+// IssuedCredential is a new credential with its secret, which is only
+// returned when it is issued
+type IssuedCredential struct {
+	Credential
+	Secret string `json:"secret"`
+}
+
+// CreateRequest represents a request to create a service account
+type CreateRequest struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	OwnerID     uuid.UUID  `json:"owner_id"`
+	OwnerTeam   string     `json:"owner_team"`
+	Roles       []string   `json:"roles"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate checks the request fields that do not depend on stored state
+func (r *CreateRequest) Validate(now time.Time) error {
+	if !validName(r.Name) {
+		return fmt.Errorf("%w: name must be 3 to %d lowercase letters, digits or '-'", ErrInvalidAccount, maxNameLength)
+	}
+	if r.OwnerID == uuid.Nil {
+		return fmt.Errorf("%w: owner_id is required", ErrInvalidAccount)
+	}
+	if len(r.Roles) == 0 {
+		return fmt.Errorf("%w: at least one role is required", ErrInvalidAccount)
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAccount)
+	}
+	return nil
+}
+
+// UpdateRequest represents a request to update a service account. Unset
+// fields are left unchanged; the name cannot be changed.
+type UpdateRequest struct {
+	Description *string    `json:"description"`
+	OwnerID     *uuid.UUID `json:"owner_id"`
+	OwnerTeam   *string    `json:"owner_team"`
+	Roles       []string   `json:"roles"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	// ClearExpiry removes the expiry
+	ClearExpiry bool  `json:"clear_expiry"`
+	IsActive    *bool `json:"is_active"`
+}
+
+// IssueRequest represents a request to issue a credential
+type IssueRequest struct {
+	Kind        string     `json:"kind"`
+	Description string     `json:"description"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// newSecret generates a credential secret, returning it with its prefix
+// and hash
+func newSecret() (secret, prefix, hash string, err error) {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate credential: %w", err)
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate credential: %w", err)
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	raw := base64.RawURLEncoding.EncodeToString(secretBytes)
+	return secretPrefix + prefix + "." + raw, prefix, hashSecret(raw), nil
+}
+
+// parseSecret splits a credential into its prefix and secret
+func parseSecret(secret string) (prefix, raw string, ok bool) {
+	rest, ok := strings.CutPrefix(secret, secretPrefix)
+	if !ok {
+		return "", "", false
+	}
+	prefix, raw, ok = strings.Cut(rest, ".")
+	return prefix, raw, ok && prefix != "" && raw != ""
+}
+
+// hashSecret hashes a secret. Secrets are random, so a plain hash is enough.
+func hashSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// secretMatches compares a secret with a stored hash in constant time
+func secretMatches(hash, raw string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(raw))) == 1
+}
+
+func validName(name string) bool {
+	if len(name) < 3 || len(name) > maxNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,344 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"connect/internal/auth"
+	"github.com/google/uuid"
+)
+
+// Security event types
+const (
+	SecurityEventCredentialRejected = "service_account_credential_rejected"
+)
+
+// TokenIssuer signs access tokens
+type TokenIssuer interface {
+	GenerateAccessToken(userID, username string, roles []string) (string, error)
+	AccessTTL() time.Duration
+}
+
+// TokenResponse is the response of the client credentials grant
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Service manages service accounts and authenticates them
+type Service struct {
+	store  Store
+	issuer TokenIssuer
+	events auth.SecurityEventRecorder
+	now    func() time.Time
+}
+
+// NewService creates a new service account service. A nil issuer disables
+// the client credentials grant; a nil recorder logs rejected credentials.
+func NewService(store Store, issuer TokenIssuer, events auth.SecurityEventRecorder) *Service {
+	return &Service{store: store, issuer: issuer, events: events, now: time.Now}
+}
+
+// Create creates a service account
+func (s *Service) Create(ctx context.Context, req CreateRequest, by uuid.UUID) (*Account, error) {
+	now := s.now()
+	if err := req.Validate(now); err != nil {
+		return nil, err
+	}
+	if err := s.checkRoles(ctx, req.Roles); err != nil {
+		return nil, err
+	}
+
+	account := &Account{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: strings.TrimSpace(req.Description),
+		OwnerID:     req.OwnerID,
+		OwnerTeam:   strings.TrimSpace(req.OwnerTeam),
+		Roles:       req.Roles,
+		ExpiresAt:   req.ExpiresAt,
+		IsActive:    true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   by,
+		UpdatedBy:   by,
+	}
+	if err := s.store.CreateAccount(ctx, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Get retrieves a service account
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Account, error) {
+	return s.store.GetAccount(ctx, id)
+}
+
+// List lists the service accounts, leaving out disabled ones unless asked
+func (s *Service) List(ctx context.Context, includeInactive bool) ([]*Account, error) {
+	return s.store.ListAccounts(ctx, includeInactive)
+}
+
+// Update changes a service account. Disabling it stops its credentials
+// working until it is enabled again.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, req UpdateRequest, by uuid.UUID) (*Account, error) {
+	account, err := s.store.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	changes := map[string]interface{}{}
+	if req.Description != nil {
+		account.Description = strings.TrimSpace(*req.Description)
+		changes["description"] = account.Description
+	}
+	if req.OwnerID != nil {
+		if *req.OwnerID == uuid.Nil {
+			return nil, fmt.Errorf("%w: owner_id cannot be empty", ErrInvalidAccount)
+		}
+		account.OwnerID = *req.OwnerID
+		changes["owner_id"] = account.OwnerID
+	}
+	if req.OwnerTeam != nil {
+		account.OwnerTeam = strings.TrimSpace(*req.OwnerTeam)
+		changes["owner_team"] = account.OwnerTeam
+	}
+	if req.Roles != nil {
+		if len(req.Roles) == 0 {
+			return nil, fmt.Errorf("%w: at least one role is required", ErrInvalidAccount)
+		}
+		if err := s.checkRoles(ctx, req.Roles); err != nil {
+			return nil, err
+		}
+		account.Roles = req.Roles
+		changes["roles"] = account.Roles
+	}
+	switch {
+	case req.ClearExpiry && req.ExpiresAt != nil:
+		return nil, fmt.Errorf("%w: expires_at and clear_expiry are exclusive", ErrInvalidAccount)
+	case req.ClearExpiry:
+		account.ExpiresAt = nil
+		changes["expires_at"] = nil
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAccount)
+		}
+		account.ExpiresAt = req.ExpiresAt
+		changes["expires_at"] = account.ExpiresAt
+	}
+	if req.IsActive != nil {
+		account.IsActive = *req.IsActive
+		changes["is_active"] = account.IsActive
+	}
+	if len(changes) == 0 {
+		return account, nil
+	}
+
+	account.UpdatedAt = now
+	account.UpdatedBy = by
+	if err := s.store.UpdateAccount(ctx, account, changes); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Delete deletes a service account and its credentials
+func (s *Service) Delete(ctx context.Context, id uuid.UUID, by uuid.UUID) error {
+	return s.store.DeleteAccount(ctx, id, by, s.now())
+}
+
+// IssueCredential issues an API key or client secret. It expires after
+// DefaultCredentialLifetime unless asked otherwise, and never after the account.
+func (s *Service) IssueCredential(ctx context.Context, accountID uuid.UUID, req IssueRequest, by uuid.UUID) (*IssuedCredential, error) {
+	if req.Kind != KindAPIKey && req.Kind != KindClientSecret {
+		return nil, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidAccount, KindAPIKey, KindClientSecret)
+	}
+
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !account.IsActive || account.Expired(now) {
+		return nil, fmt.Errorf("%w: account %s is disabled or expired", ErrInvalidAccount, account.Name)
+	}
+
+	expiresAt := now.Add(DefaultCredentialLifetime)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAccount)
+		}
+		if req.ExpiresAt.Sub(now) > MaxCredentialLifetime {
+			return nil, fmt.Errorf("%w: credentials expire within %s", ErrInvalidAccount, MaxCredentialLifetime)
+		}
+		expiresAt = *req.ExpiresAt
+	}
+	if account.ExpiresAt != nil && account.ExpiresAt.Before(expiresAt) {
+		expiresAt = *account.ExpiresAt
+	}
+
+	secret, prefix, hash, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	credential := Credential{
+		ID:          uuid.New(),
+		AccountID:   account.ID,
+		Kind:        req.Kind,
+		Prefix:      prefix,
+		SecretHash:  hash,
+		Description: strings.TrimSpace(req.Description),
+		ExpiresAt:   &expiresAt,
+		CreatedAt:   now,
+		CreatedBy:   by,
+	}
+	if err := s.store.CreateCredential(ctx, &credential); err != nil {
+		return nil, err
+	}
+	return &IssuedCredential{Credential: credential, Secret: secret}, nil
+}
+
+// ListCredentials lists the credentials of an account, without their secrets
+func (s *Service) ListCredentials(ctx context.Context, accountID uuid.UUID) ([]*Credential, error) {
+	if _, err := s.store.GetAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.store.ListCredentials(ctx, accountID)
+}
+
+// RevokeCredential revokes a credential of an account
+func (s *Service) RevokeCredential(ctx context.Context, accountID, credentialID uuid.UUID, by uuid.UUID) error {
+	return s.store.RevokeCredential(ctx, accountID, credentialID, by, s.now())
+}
+
+// AuthenticateAPIKey resolves an API key to the claims of its account
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string, client auth.ClientInfo) (*auth.Claims, error) {
+	account, err := s.authenticate(ctx, key, KindAPIKey, client)
+	if err != nil {
+		return nil, err
+	}
+	return claims(account), nil
+}
+
+// ExchangeClientCredentials implements the OAuth client credentials grant:
+// the client ID is the account name or ID, and the secret one of its client
+// secrets
+func (s *Service) ExchangeClientCredentials(ctx context.Context, clientID, clientSecret string, client auth.ClientInfo) (*TokenResponse, error) {
+	if s.issuer == nil {
+		return nil, fmt.Errorf("%w: client credentials are not accepted", ErrInvalidCredential)
+	}
+
+	account, err := s.authenticate(ctx, clientSecret, KindClientSecret, client)
+	if err != nil {
+		return nil, err
+	}
+	if clientID != account.Name && clientID != account.ID.String() {
+		return nil, ErrInvalidCredential
+	}
+
+	c := claims(account)
+	token, err := s.issuer.GenerateAccessToken(c.UserID, c.Username, c.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	return &TokenResponse{AccessToken: token, TokenType: "Bearer", ExpiresIn: int64(s.issuer.AccessTTL().Seconds())}, nil
+}
+
+// authenticate checks a secret of a kind and returns its account. Every
+// failure returns ErrInvalidCredential so callers cannot tell a wrong secret
+// from a disabled account; known credentials that are refused are recorded
+// as security events.
+func (s *Service) authenticate(ctx context.Context, secret, kind string, client auth.ClientInfo) (*Account, error) {
+	prefix, raw, ok := parseSecret(secret)
+	if !ok {
+		return nil, ErrInvalidCredential
+	}
+
+	credential, err := s.store.GetCredentialByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrCredentialNotFound) {
+			return nil, ErrInvalidCredential
+		}
+		return nil, err
+	}
+	account, err := s.store.GetAccount(ctx, credential.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var reason string
+	switch {
+	case !secretMatches(credential.SecretHash, raw):
+		reason = "secret_mismatch"
+	case credential.Kind != kind:
+		reason = "wrong_kind"
+	case credential.RevokedAt != nil:
+		reason = "credential_revoked"
+	case credential.Expired(now):
+		reason = "credential_expired"
+	case !account.IsActive:
+		reason = "account_disabled"
+	case account.Expired(now):
+		reason = "account_expired"
+	}
+	if reason != "" {
+		s.recordRejection(ctx, account, credential, reason, client, now)
+		return nil, ErrInvalidCredential
+	}
+
+	if err := s.store.RecordCredentialUse(ctx, credential.ID, account.ID, now); err != nil {
+		log.Printf("Failed to record use of service account credential %s: %v", credential.ID, err)
+	}
+	return account, nil
+}
+
+// recordRejection records a refused credential as a security event
+func (s *Service) recordRejection(ctx context.Context, account *Account, credential *Credential, reason string, client auth.ClientInfo, now time.Time) {
+	event := auth.SecurityEvent{
+		Type:   SecurityEventCredentialRejected,
+		UserID: account.ID.String(),
+		Details: map[string]interface{}{
+			"service_account": account.Name,
+			"credential_id":   credential.ID,
+			"reason":          reason,
+		},
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+		OccurredAt: now,
+	}
+	if s.events == nil {
+		log.Printf("Rejected credential %s of service account %s: %s", credential.ID, account.Name, reason)
+		return
+	}
+	if err := s.events.RecordSecurityEvent(ctx, event); err != nil {
+		log.Printf("Failed to record security event for service account %s: %v", account.Name, err)
+	}
+}
+
+// checkRoles checks that every role exists
+func (s *Service) checkRoles(ctx context.Context, roles []string) error {
+	unknown, err := s.store.UnknownRoles(ctx, roles)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownRole, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// claims returns the claims an account authenticates with
+func claims(account *Account) *auth.Claims {
+	return &auth.Claims{
+		UserID:   account.ID.String(),
+		Username: auth.ServiceAccountPrefix + account.Name,
+		Roles:    account.Roles,
+	}
+}
@@ -0,0 +1,319 @@
+package serviceaccount
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"connect/internal/auth"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// memoryStore holds accounts and credentials in memory and records audit actions
+type memoryStore struct {
+	accounts    map[uuid.UUID]*Account
+	credentials map[uuid.UUID]*Credential
+	audit       []string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{accounts: map[uuid.UUID]*Account{}, credentials: map[uuid.UUID]*Credential{}}
+}
+
+func (m *memoryStore) UnknownRoles(ctx context.Context, roles []string) ([]string, error) {
+	var unknown []string
+	for _, role := range roles {
+		if role != "viewer" && role != "ci_manager" {
+			unknown = append(unknown, role)
+		}
+	}
+	return unknown, nil
+}
+
+func (m *memoryStore) CreateAccount(ctx context.Context, account *Account) error {
+	for _, existing := range m.accounts {
+		if existing.Name == account.Name {
+			return ErrNameTaken
+		}
+	}
+	copied := *account
+	m.accounts[account.ID] = &copied
+	m.audit = append(m.audit, "created")
+	return nil
+}
+
+func (m *memoryStore) GetAccount(ctx context.Context, id uuid.UUID) (*Account, error) {
+	account, ok := m.accounts[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func (m *memoryStore) ListAccounts(ctx context.Context, includeInactive bool) ([]*Account, error) {
+	var accounts []*Account
+	for _, account := range m.accounts {
+		if includeInactive || account.IsActive {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (m *memoryStore) UpdateAccount(ctx context.Context, account *Account, changes map[string]interface{}) error {
+	copied := *account
+	m.accounts[account.ID] = &copied
+	m.audit = append(m.audit, "updated")
+	return nil
+}
+
+func (m *memoryStore) DeleteAccount(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error {
+	delete(m.accounts, id)
+	m.audit = append(m.audit, "deleted")
+	return nil
+}
+
+func (m *memoryStore) CreateCredential(ctx context.Context, credential *Credential) error {
+	copied := *credential
+	m.credentials[credential.ID] = &copied
+	m.audit = append(m.audit, "credential_issued")
+	return nil
+}
+
+func (m *memoryStore) ListCredentials(ctx context.Context, accountID uuid.UUID) ([]*Credential, error) {
+	var credentials []*Credential
+	for _, credential := range m.credentials {
+		if credential.AccountID == accountID {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (m *memoryStore) GetCredentialByPrefix(ctx context.Context, prefix string) (*Credential, error) {
+	for _, credential := range m.credentials {
+		if credential.Prefix == prefix {
+			copied := *credential
+			return &copied, nil
+		}
+	}
+	return nil, ErrCredentialNotFound
+}
+
+func (m *memoryStore) RevokeCredential(ctx context.Context, accountID, credentialID uuid.UUID, by uuid.UUID, at time.Time) error {
+	credential, ok := m.credentials[credentialID]
+	if !ok || credential.AccountID != accountID || credential.RevokedAt != nil {
+		return ErrCredentialNotFound
+	}
+	credential.RevokedAt = &at
+	m.audit = append(m.audit, "credential_revoked")
+	return nil
+}
+
+func (m *memoryStore) RecordCredentialUse(ctx context.Context, credentialID, accountID uuid.UUID, at time.Time) error {
+	m.credentials[credentialID].LastUsedAt = &at
+	m.accounts[accountID].LastUsedAt = &at
+	return nil
+}
+
+// eventRecorder keeps the security events recorded
+type eventRecorder struct {
+	events []auth.SecurityEvent
+}
+
+func (r *eventRecorder) RecordSecurityEvent(ctx context.Context, event auth.SecurityEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// tokenIssuer issues tokens naming the subject
+type tokenIssuer struct{}
+
+func (tokenIssuer) GenerateAccessToken(userID, username string, roles []string) (string, error) {
+	return "token-for-" + username, nil
+}
+
+func (tokenIssuer) AccessTTL() time.Duration {
+	return 15 * time.Minute
+}
+
+func newTestService() (*Service, *memoryStore, *eventRecorder) {
+	store := newMemoryStore()
+	events := &eventRecorder{}
+	service := NewService(store, tokenIssuer{}, events)
+	service.now = func() time.Time { return now }
+	return service, store, events
+}
+
+func createAccount(t *testing.T, service *Service) *Account {
+	account, err := service.Create(context.Background(), CreateRequest{
+		Name:      "ci-pipeline",
+		OwnerID:   uuid.New(),
+		OwnerTeam: "platform",
+		Roles:     []string{"ci_manager"},
+	}, uuid.New())
+	require.NoError(t, err)
+	return account
+}
+
+func TestCreateValidatesRequest(t *testing.T) {
+	service, _, _ := newTestService()
+	past := now.Add(-time.Hour)
+
+	for name, req := range map[string]CreateRequest{
+		"bad name":     {Name: "CI Pipeline", OwnerID: uuid.New(), Roles: []string{"viewer"}},
+		"no owner":     {Name: "ci-pipeline", Roles: []string{"viewer"}},
+		"no roles":     {Name: "ci-pipeline", OwnerID: uuid.New()},
+		"past expiry":  {Name: "ci-pipeline", OwnerID: uuid.New(), Roles: []string{"viewer"}, ExpiresAt: &past},
+		"unknown role": {Name: "ci-pipeline", OwnerID: uuid.New(), Roles: []string{"root"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.Create(context.Background(), req, uuid.New())
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := service.Create(context.Background(), CreateRequest{Name: "ci-pipeline", OwnerID: uuid.New(), Roles: []string{"root"}}, uuid.New())
+	assert.ErrorIs(t, err, ErrUnknownRole)
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	service, store, _ := newTestService()
+	ctx := context.Background()
+	account := createAccount(t, service)
+
+	issued, err := service.IssueCredential(ctx, account.ID, IssueRequest{Kind: KindAPIKey}, uuid.New())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Secret, secretPrefix+issued.Prefix+"."))
+	assert.Equal(t, now.Add(DefaultCredentialLifetime), *issued.ExpiresAt)
+	assert.NotContains(t, issued.SecretHash, issued.Secret)
+
+	claims, err := service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, account.ID.String(), claims.UserID)
+	assert.Equal(t, "svc:ci-pipeline", claims.Username)
+	assert.Equal(t, []string{"ci_manager"}, claims.Roles)
+	assert.Equal(t, now, *store.accounts[account.ID].LastUsedAt)
+	assert.Equal(t, []string{"created", "credential_issued"}, store.audit)
+
+	// An API key cannot be exchanged for a token
+	_, err = service.ExchangeClientCredentials(ctx, account.Name, issued.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	service, _, _ := newTestService()
+	ctx := context.Background()
+	account := createAccount(t, service)
+
+	issued, err := service.IssueCredential(ctx, account.ID, IssueRequest{Kind: KindClientSecret}, uuid.New())
+	require.NoError(t, err)
+
+	token, err := service.ExchangeClientCredentials(ctx, "ci-pipeline", issued.Secret, auth.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, &TokenResponse{AccessToken: "token-for-svc:ci-pipeline", TokenType: "Bearer", ExpiresIn: 900}, token)
+
+	_, err = service.ExchangeClientCredentials(ctx, account.ID.String(), issued.Secret, auth.ClientInfo{})
+	assert.NoError(t, err)
+	_, err = service.ExchangeClientCredentials(ctx, "other-pipeline", issued.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	// A client secret is not an API key
+	_, err = service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+}
+
+func TestRejectedCredentialsAreRecorded(t *testing.T) {
+	service, _, events := newTestService()
+	ctx := context.Background()
+	account := createAccount(t, service)
+	issued, err := service.IssueCredential(ctx, account.ID, IssueRequest{Kind: KindAPIKey}, uuid.New())
+	require.NoError(t, err)
+	client := auth.ClientInfo{IPAddress: "10.0.0.7"}
+
+	// Unknown keys name no account, so nothing is recorded
+	for _, key := range []string{"", "not-a-key", secretPrefix + "000000000000.secret"} {
+		_, err := service.AuthenticateAPIKey(ctx, key, client)
+		assert.ErrorIs(t, err, ErrInvalidCredential)
+	}
+	assert.Empty(t, events.events)
+
+	_, err = service.AuthenticateAPIKey(ctx, secretPrefix+issued.Prefix+".guessed", client)
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	disabled := false
+	_, err = service.Update(ctx, account.ID, UpdateRequest{IsActive: &disabled}, uuid.New())
+	require.NoError(t, err)
+	_, err = service.AuthenticateAPIKey(ctx, issued.Secret, client)
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	enabled := true
+	_, err = service.Update(ctx, account.ID, UpdateRequest{IsActive: &enabled}, uuid.New())
+	require.NoError(t, err)
+	require.NoError(t, service.RevokeCredential(ctx, account.ID, issued.ID, uuid.New()))
+	_, err = service.AuthenticateAPIKey(ctx, issued.Secret, client)
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	require.Len(t, events.events, 3)
+	var reasons []string
+	for _, event := range events.events {
+		assert.Equal(t, SecurityEventCredentialRejected, event.Type)
+		assert.Equal(t, account.ID.String(), event.UserID)
+		assert.Equal(t, "10.0.0.7", event.IPAddress)
+		reasons = append(reasons, event.Details["reason"].(string))
+	}
+	assert.Equal(t, []string{"secret_mismatch", "account_disabled", "credential_revoked"}, reasons)
+}
+
+func TestCredentialsExpireWithAccount(t *testing.T) {
+	service, _, _ := newTestService()
+	ctx := context.Background()
+	expiresAt := now.Add(7 * 24 * time.Hour)
+	account, err := service.Create(ctx, CreateRequest{
+		Name: "nightly-import", OwnerID: uuid.New(), Roles: []string{"viewer"}, ExpiresAt: &expiresAt,
+	}, uuid.New())
+	require.NoError(t, err)
+
+	issued, err := service.IssueCredential(ctx, account.ID, IssueRequest{Kind: KindAPIKey}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, expiresAt, *issued.ExpiresAt)
+
+	tooLong := now.Add(MaxCredentialLifetime + time.Hour)
+	_, err = service.IssueCredential(ctx, account.ID, IssueRequest{Kind: KindAPIKey, ExpiresAt: &tooLong}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidAccount)
+
+	service.now = func() time.Time { return expiresAt }
+	_, err = service.AuthenticateAPIKey(ctx, issued.Secret, auth.ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+	_, err = service.IssueCredential(ctx, account.ID, IssueRequest{Kind: KindAPIKey}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidAccount)
+}
+
+func TestUpdateAccount(t *testing.T) {
+	service, store, _ := newTestService()
+	ctx := context.Background()
+	account := createAccount(t, service)
+
+	team := "sre"
+	updated, err := service.Update(ctx, account.ID, UpdateRequest{OwnerTeam: &team, Roles: []string{"viewer"}}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, "sre", updated.OwnerTeam)
+	assert.Equal(t, []string{"viewer"}, updated.Roles)
+	assert.Equal(t, "ci-pipeline", updated.Name)
+
+	_, err = service.Update(ctx, account.ID, UpdateRequest{Roles: []string{}}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidAccount)
+	_, err = service.Update(ctx, uuid.New(), UpdateRequest{OwnerTeam: &team}, uuid.New())
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+
+	// Nothing to change writes nothing
+	_, err = service.Update(ctx, account.ID, UpdateRequest{}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created", "updated"}, store.audit)
+}
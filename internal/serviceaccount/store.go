@@ -0,0 +1,295 @@
+package serviceaccount
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// auditEntityType is the entity type of service account audit entries
+const auditEntityType = "service_account"
+
+// Store persists service accounts and their credentials. Changes to either
+// are written to the audit log in the same transaction.
+type Store interface {
+	// UnknownRoles returns the roles that do not exist
+	UnknownRoles(ctx context.Context, roles []string) ([]string, error)
+	CreateAccount(ctx context.Context, account *Account) error
+	GetAccount(ctx context.Context, id uuid.UUID) (*Account, error)
+	ListAccounts(ctx context.Context, includeInactive bool) ([]*Account, error)
+	// UpdateAccount saves an account, auditing the changed fields
+	UpdateAccount(ctx context.Context, account *Account, changes map[string]interface{}) error
+	DeleteAccount(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error
+
+	CreateCredential(ctx context.Context, credential *Credential) error
+	ListCredentials(ctx context.Context, accountID uuid.UUID) ([]*Credential, error)
+	GetCredentialByPrefix(ctx context.Context, prefix string) (*Credential, error)
+	RevokeCredential(ctx context.Context, accountID, credentialID uuid.UUID, by uuid.UUID, at time.Time) error
+	// RecordCredentialUse sets when a credential and its account were last used
+	RecordCredentialUse(ctx context.Context, credentialID, accountID uuid.UUID, at time.Time) error
+}
+
+// PostgresStore keeps service accounts in the service_accounts and service_account_credentials tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed service account store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// accountRow is an account as stored, with its roles as a Postgres array
+type accountRow struct {
+	Account
+	Roles pq.StringArray `db:"roles"`
+}
+
+func (r *accountRow) account() *Account {
+	account := r.Account
+	account.Roles = []string(r.Roles)
+	return &account
+}
+
+const accountColumns = `id, name, description, owner_id, owner_team, roles, expires_at, is_active,
+	last_used_at, created_at, updated_at, created_by, updated_by`
+
+const credentialColumns = `id, account_id, kind, prefix, secret_hash, description, expires_at,
+	last_used_at, revoked_at, created_at, created_by`
+
+// UnknownRoles retrieves the roles missing from the roles table
+func (s *PostgresStore) UnknownRoles(ctx context.Context, roles []string) ([]string, error) {
+	var unknown []string
+	err := s.db.SelectContext(ctx, &unknown, `
+		SELECT wanted.name FROM unnest($1::text[]) AS wanted(name)
+		WHERE NOT EXISTS (SELECT 1 FROM roles r WHERE r.name = wanted.name)`, pq.Array(roles))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check roles: %w", err)
+	}
+	return unknown, nil
+}
+
+// CreateAccount inserts an account
+func (s *PostgresStore) CreateAccount(ctx context.Context, account *Account) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO service_accounts (`+accountColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			account.ID, account.Name, account.Description, account.OwnerID, account.OwnerTeam, pq.Array(account.Roles),
+			account.ExpiresAt, account.IsActive, account.LastUsedAt, account.CreatedAt, account.UpdatedAt,
+			account.CreatedBy, account.UpdatedBy)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return fmt.Errorf("%w: %s", ErrNameTaken, account.Name)
+			}
+			return fmt.Errorf("failed to create service account: %w", err)
+		}
+		return insertAudit(ctx, tx, account.ID, "created", account.CreatedBy, account.CreatedAt, map[string]interface{}{
+			"name":       account.Name,
+			"owner_id":   account.OwnerID,
+			"owner_team": account.OwnerTeam,
+			"roles":      account.Roles,
+			"expires_at": account.ExpiresAt,
+		})
+	})
+}
+
+// GetAccount retrieves an account
+func (s *PostgresStore) GetAccount(ctx context.Context, id uuid.UUID) (*Account, error) {
+	var row accountRow
+	err := s.db.GetContext(ctx, &row, `SELECT `+accountColumns+` FROM service_accounts WHERE id = $1`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get service account: %w", err)
+	}
+	return row.account(), nil
+}
+
+// ListAccounts retrieves the accounts ordered by name
+func (s *PostgresStore) ListAccounts(ctx context.Context, includeInactive bool) ([]*Account, error) {
+	var rows []accountRow
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+accountColumns+` FROM service_accounts
+		WHERE $1 OR is_active = true
+		ORDER BY name`, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+
+	accounts := make([]*Account, 0, len(rows))
+	for i := range rows {
+		accounts = append(accounts, rows[i].account())
+	}
+	return accounts, nil
+}
+
+// UpdateAccount saves the mutable fields of an account
+func (s *PostgresStore) UpdateAccount(ctx context.Context, account *Account, changes map[string]interface{}) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE service_accounts SET
+				description = $2, owner_id = $3, owner_team = $4, roles = $5,
+				expires_at = $6, is_active = $7, updated_at = $8, updated_by = $9
+			WHERE id = $1`,
+			account.ID, account.Description, account.OwnerID, account.OwnerTeam, pq.Array(account.Roles),
+			account.ExpiresAt, account.IsActive, account.UpdatedAt, account.UpdatedBy)
+		if err != nil {
+			return fmt.Errorf("failed to update service account: %w", err)
+		}
+		if err := requireRow(result, ErrAccountNotFound); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, account.ID, "updated", account.UpdatedBy, account.UpdatedAt, changes)
+	})
+}
+
+// DeleteAccount deletes an account; its credentials are deleted with it
+func (s *PostgresStore) DeleteAccount(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		var name string
+		err := tx.GetContext(ctx, &name, `DELETE FROM service_accounts WHERE id = $1 RETURNING name`, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrAccountNotFound
+			}
+			return fmt.Errorf("failed to delete service account: %w", err)
+		}
+		return insertAudit(ctx, tx, id, "deleted", by, at, map[string]interface{}{"name": name})
+	})
+}
+
+// CreateCredential inserts a credential
+func (s *PostgresStore) CreateCredential(ctx context.Context, credential *Credential) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO service_account_credentials (`+credentialColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			credential.ID, credential.AccountID, credential.Kind, credential.Prefix, credential.SecretHash,
+			credential.Description, credential.ExpiresAt, credential.LastUsedAt, credential.RevokedAt,
+			credential.CreatedAt, credential.CreatedBy)
+		if err != nil {
+			return fmt.Errorf("failed to create service account credential: %w", err)
+		}
+		return insertAudit(ctx, tx, credential.AccountID, "credential_issued", credential.CreatedBy, credential.CreatedAt, map[string]interface{}{
+			"credential_id": credential.ID,
+			"kind":          credential.Kind,
+			"prefix":        credential.Prefix,
+			"expires_at":    credential.ExpiresAt,
+		})
+	})
+}
+
+// ListCredentials retrieves the credentials of an account, newest first
+func (s *PostgresStore) ListCredentials(ctx context.Context, accountID uuid.UUID) ([]*Credential, error) {
+	var credentials []*Credential
+	err := s.db.SelectContext(ctx, &credentials, `
+		SELECT `+credentialColumns+` FROM service_account_credentials
+		WHERE account_id = $1
+		ORDER BY created_at DESC`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service account credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+// GetCredentialByPrefix retrieves the credential with a prefix
+func (s *PostgresStore) GetCredentialByPrefix(ctx context.Context, prefix string) (*Credential, error) {
+	var credential Credential
+	err := s.db.GetContext(ctx, &credential, `
+		SELECT `+credentialColumns+` FROM service_account_credentials WHERE prefix = $1`, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, fmt.Errorf("failed to get service account credential: %w", err)
+	}
+	return &credential, nil
+}
+
+// RevokeCredential revokes a credential that is not revoked yet
+func (s *PostgresStore) RevokeCredential(ctx context.Context, accountID, credentialID uuid.UUID, by uuid.UUID, at time.Time) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE service_account_credentials SET revoked_at = $3
+			WHERE id = $1 AND account_id = $2 AND revoked_at IS NULL`, credentialID, accountID, at)
+		if err != nil {
+			return fmt.Errorf("failed to revoke service account credential: %w", err)
+		}
+		if err := requireRow(result, ErrCredentialNotFound); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, accountID, "credential_revoked", by, at, map[string]interface{}{"credential_id": credentialID})
+	})
+}
+
+// RecordCredentialUse sets the last use of a credential and its account
+func (s *PostgresStore) RecordCredentialUse(ctx context.Context, credentialID, accountID uuid.UUID, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		WITH credential AS (
+			UPDATE service_account_credentials SET last_used_at = $3 WHERE id = $1
+		)
+		UPDATE service_accounts SET last_used_at = $3 WHERE id = $2`, credentialID, accountID, at)
+	if err != nil {
+		return fmt.Errorf("failed to record service account credential use: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (s *PostgresStore) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// requireRow returns notFound if an update matched no row
+func requireRow(result sql.Result, notFound error) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// insertAudit writes an audit log entry for an account
+func insertAudit(ctx context.Context, tx *sqlx.Tx, accountID uuid.UUID, action string, by uuid.UUID, at time.Time, details map[string]interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	var changedBy *uuid.UUID
+	if by != uuid.Nil {
+		changedBy = &by
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ($1, $2, $3, $4, $5, $6)`, auditEntityType, accountID, action, changedBy, at, data)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+// Package servicetree maintains the service tree, a denormalized projection of
+// the membership of business services: every live CI a service reaches
+// downstream through active relationships, with its depth and status. The sync
+// pipeline applies each processed CI and relationship event to it, rebuilding
+// only the trees the change can affect, so service membership and health are
+// read with an index lookup instead of a graph traversal.
+package servicetree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Health statuses
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// MaxUnhealthyMembers bounds the unhealthy members listed in a health report
+const MaxUnhealthyMembers = 100
+
+var ErrServiceNotFound = errors.New("business service not found")
+
+// degradingStatuses are the member statuses that degrade a service
+var degradingStatuses = map[string]bool{
+	models.CIStatusUnreachable: true,
+	models.CIStatusFixRequired: true,
+	models.CIStatusMaintenance: true,
+}
+
+// downStatuses are the statuses of a service CI that is down itself
+var downStatuses = map[string]bool{
+	models.CIStatusUnreachable: true,
+	models.CIStatusInactive:    true,
+	models.CIStatusRetired:     true,
+}
+
+// Member is a CI in the tree of a service
+type Member struct {
+	ServiceID uuid.UUID `json:"service_id" db:"service_id"`
+	CIID      uuid.UUID `json:"ci_id" db:"ci_id"`
+	Depth     int       `json:"depth" db:"depth"`
+	Name      string    `json:"name" db:"ci_name"`
+	Type      string    `json:"type" db:"ci_type"`
+	Status    string    `json:"status" db:"ci_status"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Health summarizes the statuses of the members of a service
+type Health struct {
+	ServiceID uuid.UUID      `json:"service_id"`
+	Status    string         `json:"status"`
+	Members   int            `json:"members"`
+	ByStatus  map[string]int `json:"by_status"`
+	// Unhealthy lists the members degrading the service, nearest first
+	Unhealthy []Member `json:"unhealthy"`
+}
+
+// RebuildResult is the outcome of rebuilding every tree
+type RebuildResult struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Services    int       `json:"services"`
+	Failed      int       `json:"failed"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Store keeps the projection
+type Store interface {
+	// Rebuild replaces the tree of a service with the CIs it reaches now,
+	// removing it when the CI is no longer a live business service
+	Rebuild(ctx context.Context, serviceID uuid.UUID, maxDepth int, at time.Time) error
+	// ListServiceIDs returns the live business services and the services
+	// still holding a tree
+	ListServiceIDs(ctx context.Context) ([]uuid.UUID, error)
+	// ServicesContaining returns the services whose tree holds the CI above
+	// the maximum depth, so a relationship from it can extend the tree
+	ServicesContaining(ctx context.Context, ciID uuid.UUID, maxDepth int) ([]uuid.UUID, error)
+	// ServicesMissing returns the services whose tree holds a CI with an
+	// active relationship to the CI, above the maximum depth, but not the CI
+	ServicesMissing(ctx context.Context, ciID uuid.UUID, maxDepth int) ([]uuid.UUID, error)
+	// RefreshMember copies the name, type and status of a live CI onto its
+	// memberships, reporting false when the CI is not live
+	RefreshMember(ctx context.Context, ciID uuid.UUID, at time.Time) (bool, error)
+
+	ListMembers(ctx context.Context, serviceID uuid.UUID, filter MemberFilter) ([]Member, error)
+	// ListServicesOf returns the memberships of the CI, carrying the name,
+	// type and status of their service
+	ListServicesOf(ctx context.Context, ciID uuid.UUID) ([]Member, error)
+	StatusCounts(ctx context.Context, serviceID uuid.UUID) (map[string]int, error)
+	GetMember(ctx context.Context, serviceID, ciID uuid.UUID) (*Member, error)
+}
+
+// MemberFilter narrows the members listed
+type MemberFilter struct {
+	Type     string
+	Statuses []string
+	MaxDepth int
+	Limit    int
+}
+
+// Service applies sync events to the service tree and reads it
+type Service struct {
+	store    Store
+	maxDepth int
+	now      func() time.Time
+
+	mu          sync.Mutex
+	rebuilding  bool
+	lastRebuild *RebuildResult
+}
+
+// NewService creates a new service tree service. maxDepth bounds how many
+// relationship hops below a service its tree reaches; 0 uses models.MaxImpactDepth.
+func NewService(store Store, maxDepth int) *Service {
+	if maxDepth <= 0 {
+		maxDepth = models.MaxImpactDepth
+	}
+	return &Service{store: store, maxDepth: maxDepth, now: time.Now}
+}
+
+// Apply updates the trees affected by a processed sync event. It implements
+// the Projection of the sync service.
+func (s *Service) Apply(ctx context.Context, entityType, entityID, action string, data map[string]interface{}) error {
+	switch entityType {
+	case "configuration_item":
+		id, err := uuid.Parse(entityID)
+		if err != nil {
+			return fmt.Errorf("invalid CI ID %q: %w", entityID, err)
+		}
+		return s.applyCI(ctx, id)
+	case "relationship":
+		source, ok := eventUUID(data, "source_id", "source_ci_id")
+		if !ok {
+			return fmt.Errorf("relationship event %s has no source CI", entityID)
+		}
+		return s.applyRelationship(ctx, source)
+	}
+	return nil
+}
+
+// applyCI rebuilds the tree of the CI when it is, or was, a service, then
+// either refreshes its memberships or, once it is gone, rebuilds the trees it
+// was part of. A CI that became live again is added to the trees of the CIs
+// depending on it.
+func (s *Service) applyCI(ctx context.Context, id uuid.UUID) error {
+	at := s.now()
+	if err := s.store.Rebuild(ctx, id, s.maxDepth, at); err != nil {
+		return err
+	}
+
+	live, err := s.store.RefreshMember(ctx, id, at)
+	if err != nil {
+		return err
+	}
+
+	var services []uuid.UUID
+	if live {
+		services, err = s.store.ServicesMissing(ctx, id, s.maxDepth)
+	} else {
+		services, err = s.store.ServicesContaining(ctx, id, s.maxDepth+1)
+	}
+	if err != nil {
+		return err
+	}
+	return s.rebuildAll(ctx, services, id, at)
+}
+
+// applyRelationship rebuilds the trees holding the source of a relationship
+// that was created, changed or removed
+func (s *Service) applyRelationship(ctx context.Context, source uuid.UUID) error {
+	services, err := s.store.ServicesContaining(ctx, source, s.maxDepth)
+	if err != nil {
+		return err
+	}
+	return s.rebuildAll(ctx, services, uuid.Nil, s.now())
+}
+
+// rebuildAll rebuilds the trees of the services, skipping one already rebuilt
+func (s *Service) rebuildAll(ctx context.Context, services []uuid.UUID, skip uuid.UUID, at time.Time) error {
+	for _, service := range services {
+		if service == skip {
+			continue
+		}
+		if err := s.store.Rebuild(ctx, service, s.maxDepth, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebuild rebuilds the tree of every service, e.g. to fill the projection for
+// the first time or after events were lost
+func (s *Service) Rebuild(ctx context.Context) *RebuildResult {
+	result := &RebuildResult{StartedAt: s.now()}
+	services, err := s.store.ListServiceIDs(ctx)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	for _, service := range services {
+		if err := s.store.Rebuild(ctx, service, s.maxDepth, s.now()); err != nil {
+			log.Printf("Failed to rebuild service tree of %s: %v", service, err)
+			result.Failed++
+			continue
+		}
+		result.Services++
+	}
+	if result.Failed > 0 && result.Error == "" {
+		result.Error = fmt.Sprintf("failed to rebuild %d service trees", result.Failed)
+	}
+	result.CompletedAt = s.now()
+
+	s.mu.Lock()
+	s.lastRebuild = result
+	s.mu.Unlock()
+	return result
+}
+
+// StartRebuild rebuilds every tree in the background, reporting false when a
+// rebuild is already running. Poll LastRebuild for the outcome.
+func (s *Service) StartRebuild() bool {
+	s.mu.Lock()
+	if s.rebuilding {
+		s.mu.Unlock()
+		return false
+	}
+	s.rebuilding = true
+	s.mu.Unlock()
+
+	go func() {
+		result := s.Rebuild(context.Background())
+		if result.Error != "" {
+			log.Printf("Service tree rebuild completed with errors: %s", result.Error)
+		}
+		s.mu.Lock()
+		s.rebuilding = false
+		s.mu.Unlock()
+	}()
+	return true
+}
+
+// LastRebuild returns the outcome of the last full rebuild, nil before the first
+func (s *Service) LastRebuild() *RebuildResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRebuild
+}
+
+// Members lists the members of a service, nearest first
+func (s *Service) Members(ctx context.Context, serviceID uuid.UUID, filter MemberFilter) ([]Member, error) {
+	if _, err := s.service(ctx, serviceID); err != nil {
+		return nil, err
+	}
+	return s.store.ListMembers(ctx, serviceID, filter)
+}
+
+// ServicesOf lists the memberships of a CI, one per service holding it
+func (s *Service) ServicesOf(ctx context.Context, ciID uuid.UUID) ([]Member, error) {
+	return s.store.ListServicesOf(ctx, ciID)
+}
+
+// Health reports the health of a service from the statuses of its members: down
+// when the service itself is unreachable, inactive or retired, degraded when a
+// member is unreachable, needs fixing or is in maintenance, healthy otherwise
+func (s *Service) Health(ctx context.Context, serviceID uuid.UUID) (*Health, error) {
+	self, err := s.service(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.store.StatusCounts(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &Health{ServiceID: serviceID, Status: HealthHealthy, ByStatus: counts, Unhealthy: []Member{}}
+	statuses := []string{}
+	for status, count := range counts {
+		health.Members += count
+		if degradingStatuses[status] {
+			statuses = append(statuses, status)
+		}
+	}
+	if len(statuses) > 0 {
+		unhealthy, err := s.store.ListMembers(ctx, serviceID, MemberFilter{Statuses: statuses, Limit: MaxUnhealthyMembers + 1})
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range unhealthy {
+			if member.CIID != serviceID && len(health.Unhealthy) < MaxUnhealthyMembers {
+				health.Unhealthy = append(health.Unhealthy, member)
+			}
+		}
+		if len(health.Unhealthy) > 0 {
+			health.Status = HealthDegraded
+		}
+	}
+	if downStatuses[self.Status] {
+		health.Status = HealthDown
+	}
+	return health, nil
+}
+
+// service returns the membership of a service in its own tree
+func (s *Service) service(ctx context.Context, serviceID uuid.UUID) (*Member, error) {
+	self, err := s.store.GetMember(ctx, serviceID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if self == nil {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, serviceID)
+	}
+	return self, nil
+}
+
+// eventUUID returns the first of the keys of event data holding a UUID
+func eventUUID(data map[string]interface{}, keys ...string) (uuid.UUID, bool) {
+	for _, key := range keys {
+		if value, ok := data[key].(string); ok {
+			if id, err := uuid.Parse(value); err == nil {
+				return id, true
+			}
+		}
+	}
+	return uuid.Nil, false
+}
@@ -0,0 +1,335 @@
+package servicetree
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCI struct {
+	name, ciType, status string
+	deleted              bool
+}
+
+// memoryStore keeps a CI graph and its projection in memory, rebuilding trees
+// the way the Postgres store does
+type memoryStore struct {
+	cis      map[uuid.UUID]*memoryCI
+	edges    map[uuid.UUID][]uuid.UUID
+	members  map[uuid.UUID]map[uuid.UUID]Member
+	rebuilds []uuid.UUID
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{cis: map[uuid.UUID]*memoryCI{}, edges: map[uuid.UUID][]uuid.UUID{}, members: map[uuid.UUID]map[uuid.UUID]Member{}}
+}
+
+func (m *memoryStore) add(name, ciType string) uuid.UUID {
+	id := uuid.New()
+	m.cis[id] = &memoryCI{name: name, ciType: ciType, status: models.CIStatusActive}
+	return id
+}
+
+func (m *memoryStore) link(source, target uuid.UUID) {
+	m.edges[source] = append(m.edges[source], target)
+}
+
+func (m *memoryStore) unlink(source, target uuid.UUID) {
+	var kept []uuid.UUID
+	for _, id := range m.edges[source] {
+		if id != target {
+			kept = append(kept, id)
+		}
+	}
+	m.edges[source] = kept
+}
+
+func (m *memoryStore) Rebuild(ctx context.Context, serviceID uuid.UUID, maxDepth int, at time.Time) error {
+	m.rebuilds = append(m.rebuilds, serviceID)
+	delete(m.members, serviceID)
+	ci, ok := m.cis[serviceID]
+	if !ok || ci.deleted || ci.ciType != models.BusinessServiceType {
+		return nil
+	}
+
+	tree := map[uuid.UUID]Member{}
+	queue := []uuid.UUID{serviceID}
+	depths := map[uuid.UUID]int{serviceID: 0}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		c := m.cis[id]
+		tree[id] = Member{ServiceID: serviceID, CIID: id, Depth: depths[id], Name: c.name, Type: c.ciType, Status: c.status, UpdatedAt: at}
+		if depths[id] >= maxDepth {
+			continue
+		}
+		for _, target := range m.edges[id] {
+			if _, seen := depths[target]; seen || m.cis[target].deleted {
+				continue
+			}
+			depths[target] = depths[id] + 1
+			queue = append(queue, target)
+		}
+	}
+	m.members[serviceID] = tree
+	return nil
+}
+
+func (m *memoryStore) ListServiceIDs(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, ci := range m.cis {
+		if ci.ciType == models.BusinessServiceType && !ci.deleted {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryStore) ServicesContaining(ctx context.Context, ciID uuid.UUID, maxDepth int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for service, tree := range m.members {
+		if member, ok := tree[ciID]; ok && member.Depth < maxDepth {
+			ids = append(ids, service)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryStore) ServicesMissing(ctx context.Context, ciID uuid.UUID, maxDepth int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for service, tree := range m.members {
+		if _, ok := tree[ciID]; ok {
+			continue
+		}
+		for source, targets := range m.edges {
+			member, ok := tree[source]
+			if ok && member.Depth < maxDepth && containsID(targets, ciID) {
+				ids = append(ids, service)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryStore) RefreshMember(ctx context.Context, ciID uuid.UUID, at time.Time) (bool, error) {
+	ci, ok := m.cis[ciID]
+	if !ok || ci.deleted {
+		return false, nil
+	}
+	for _, tree := range m.members {
+		if member, ok := tree[ciID]; ok {
+			member.Name, member.Type, member.Status = ci.name, ci.ciType, ci.status
+			tree[ciID] = member
+		}
+	}
+	return true, nil
+}
+
+func (m *memoryStore) ListMembers(ctx context.Context, serviceID uuid.UUID, filter MemberFilter) ([]Member, error) {
+	members := []Member{}
+	for _, member := range m.members[serviceID] {
+		if filter.Type != "" && member.Type != filter.Type {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !containsString(filter.Statuses, member.Status) {
+			continue
+		}
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Depth != members[j].Depth {
+			return members[i].Depth < members[j].Depth
+		}
+		return members[i].Name < members[j].Name
+	})
+	return members, nil
+}
+
+func (m *memoryStore) ListServicesOf(ctx context.Context, ciID uuid.UUID) ([]Member, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) StatusCounts(ctx context.Context, serviceID uuid.UUID) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, member := range m.members[serviceID] {
+		counts[member.Status]++
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) GetMember(ctx context.Context, serviceID, ciID uuid.UUID) (*Member, error) {
+	if member, ok := m.members[serviceID][ciID]; ok {
+		return &member, nil
+	}
+	return nil, nil
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// memberNames returns the names of the members of a service, sorted
+func memberNames(store *memoryStore, serviceID uuid.UUID) []string {
+	names := []string{}
+	for _, member := range store.members[serviceID] {
+		names = append(names, member.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fixture is a service running an application on a server
+type fixture struct {
+	store                 *memoryStore
+	service               *Service
+	shop, app, server, db uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	store := newMemoryStore()
+	f := &fixture{
+		store:   store,
+		service: NewService(store, 0),
+		shop:    store.add("shop", models.BusinessServiceType),
+		app:     store.add("shop-api", "application"),
+		server:  store.add("srv-1", "server"),
+		db:      store.add("shop-db", "database"),
+	}
+	store.link(f.shop, f.app)
+	store.link(f.app, f.server)
+
+	result := f.service.Rebuild(context.Background())
+	require.Empty(t, result.Error)
+	require.Equal(t, 1, result.Services)
+	require.Equal(t, []string{"shop", "shop-api", "srv-1"}, memberNames(store, f.shop))
+	store.rebuilds = nil
+	return f
+}
+
+func (f *fixture) apply(t *testing.T, entityType string, id uuid.UUID, data map[string]interface{}) {
+	require.NoError(t, f.service.Apply(context.Background(), entityType, id.String(), "UPDATE", data))
+}
+
+func TestApplyRelationshipExtendsTree(t *testing.T) {
+	f := newFixture(t)
+
+	f.store.link(f.app, f.db)
+	f.apply(t, "relationship", uuid.New(), map[string]interface{}{"source_id": f.app.String(), "target_id": f.db.String()})
+	assert.Equal(t, []string{"shop", "shop-api", "shop-db", "srv-1"}, memberNames(f.store, f.shop))
+
+	f.store.unlink(f.app, f.db)
+	f.apply(t, "relationship", uuid.New(), map[string]interface{}{"source_id": f.app.String(), "target_id": f.db.String()})
+	assert.Equal(t, []string{"shop", "shop-api", "srv-1"}, memberNames(f.store, f.shop))
+}
+
+func TestApplyRelationshipOutsideTreesRebuildsNothing(t *testing.T) {
+	f := newFixture(t)
+
+	f.store.link(f.db, f.server)
+	f.apply(t, "relationship", uuid.New(), map[string]interface{}{"source_id": f.db.String(), "target_id": f.server.String()})
+	assert.Empty(t, f.store.rebuilds)
+
+	assert.Error(t, f.service.Apply(context.Background(), "relationship", uuid.New().String(), "CREATE", map[string]interface{}{}))
+}
+
+func TestApplyCIStatusRefreshesMembers(t *testing.T) {
+	f := newFixture(t)
+
+	f.store.cis[f.server].status = models.CIStatusUnreachable
+	f.apply(t, "configuration_item", f.server, nil)
+	assert.Equal(t, models.CIStatusUnreachable, f.store.members[f.shop][f.server].Status)
+	// Only the CI's own tree, which it does not have, is rebuilt
+	assert.Equal(t, []uuid.UUID{f.server}, f.store.rebuilds)
+
+	health, err := f.service.Health(context.Background(), f.shop)
+	require.NoError(t, err)
+	assert.Equal(t, HealthDegraded, health.Status)
+	assert.Equal(t, 3, health.Members)
+	assert.Equal(t, map[string]int{models.CIStatusActive: 2, models.CIStatusUnreachable: 1}, health.ByStatus)
+	require.Len(t, health.Unhealthy, 1)
+	assert.Equal(t, f.server, health.Unhealthy[0].CIID)
+}
+
+func TestApplyCIDeletionAndRestore(t *testing.T) {
+	f := newFixture(t)
+
+	f.store.cis[f.app].deleted = true
+	f.apply(t, "configuration_item", f.app, nil)
+	// The server is only reachable through the deleted application
+	assert.Equal(t, []string{"shop"}, memberNames(f.store, f.shop))
+
+	f.store.cis[f.app].deleted = false
+	f.apply(t, "configuration_item", f.app, nil)
+	assert.Equal(t, []string{"shop", "shop-api", "srv-1"}, memberNames(f.store, f.shop))
+}
+
+func TestApplyServiceCI(t *testing.T) {
+	f := newFixture(t)
+
+	billing := f.store.add("billing", models.BusinessServiceType)
+	f.store.link(billing, f.server)
+	f.apply(t, "configuration_item", billing, nil)
+	assert.Equal(t, []string{"billing", "srv-1"}, memberNames(f.store, billing))
+
+	// A service retyped away loses its tree
+	f.store.cis[billing].ciType = "application"
+	f.apply(t, "configuration_item", billing, nil)
+	assert.NotContains(t, f.store.members, billing)
+
+	f.store.cis[f.shop].status = models.CIStatusRetired
+	f.apply(t, "configuration_item", f.shop, nil)
+	health, err := f.service.Health(context.Background(), f.shop)
+	require.NoError(t, err)
+	assert.Equal(t, HealthDown, health.Status)
+	assert.Empty(t, health.Unhealthy)
+}
+
+func TestHealthOfUnknownService(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.service.Health(context.Background(), f.app)
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	_, err = f.service.Members(context.Background(), uuid.New(), MemberFilter{})
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+
+	members, err := f.service.Members(context.Background(), f.shop, MemberFilter{Type: "server"})
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, 2, members[0].Depth)
+}
+
+func TestTreeDepthIsBounded(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, 2)
+	root := store.add("root", models.BusinessServiceType)
+	previous := root
+	for _, name := range []string{"a", "b", "c"} {
+		id := store.add(name, "application")
+		store.link(previous, id)
+		previous = id
+	}
+
+	service.Rebuild(context.Background())
+	assert.Equal(t, []string{"a", "b", "root"}, memberNames(store, root))
+}
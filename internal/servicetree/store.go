@@ -0,0 +1,238 @@
+package servicetree
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/organization"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PostgresStore keeps the service tree in the service_tree_members table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed service tree store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const memberColumns = `service_id, ci_id, depth, ci_name, ci_type, ci_status, updated_at`
+
+// andVisibleCIs narrows the rows to those whose CI in column the request's
+// organization may see. Trees are built for every organization, so only the
+// reads are scoped.
+func andVisibleCIs(ctx context.Context, column string, args []interface{}) (string, []interface{}) {
+	condition, scopeArgs := organization.ScopeCondition(ctx, "org_id", len(args)+1)
+	if scopeArgs == nil {
+		return "", args
+	}
+	return fmt.Sprintf(" AND %s IN (SELECT id FROM configuration_items WHERE %s)", column, condition), append(args, scopeArgs...)
+}
+
+// Rebuild replaces the tree of a service in a transaction. Rebuilds of the same
+// service are serialized with an advisory lock so they never interleave.
+func (s *PostgresStore) Rebuild(ctx context.Context, serviceID uuid.UUID, maxDepth int, at time.Time) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))`, serviceID); err != nil {
+		return fmt.Errorf("failed to lock service tree: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM service_tree_members WHERE service_id = $1`, serviceID); err != nil {
+		return fmt.Errorf("failed to clear service tree: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		WITH RECURSIVE tree(id, depth) AS (
+			SELECT id, 0
+			FROM configuration_items
+			WHERE id = $1 AND type = $2 AND is_deleted = false
+			UNION
+			SELECT r.target_ci_id, t.depth + 1
+			FROM ci_relationships r
+			JOIN tree t ON r.source_ci_id = t.id
+			JOIN configuration_items c ON c.id = r.target_ci_id AND c.is_deleted = false
+			WHERE r.is_active = true AND r.state = $3 AND t.depth < $4
+		)
+		INSERT INTO service_tree_members (service_id, ci_id, depth, ci_name, ci_type, ci_status, updated_at)
+		SELECT $1, c.id, MIN(t.depth), c.name, c.type, COALESCE(c.status, ''), $5
+		FROM tree t
+		JOIN configuration_items c ON c.id = t.id
+		GROUP BY c.id, c.name, c.type, c.status`,
+		serviceID, models.BusinessServiceType, models.RelationshipStateActive, maxDepth, at)
+	if err != nil {
+		return fmt.Errorf("failed to build service tree: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit service tree: %w", err)
+	}
+	return nil
+}
+
+// ListServiceIDs retrieves the live business services and the services still
+// holding a tree, whose trees a rebuild removes
+func (s *PostgresStore) ListServiceIDs(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT id FROM configuration_items
+		WHERE type = $1 AND is_deleted = false
+		UNION
+		SELECT DISTINCT service_id FROM service_tree_members
+		ORDER BY 1`, models.BusinessServiceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list business services: %w", err)
+	}
+	return ids, nil
+}
+
+// ServicesContaining retrieves the services holding the CI above the depth
+func (s *PostgresStore) ServicesContaining(ctx context.Context, ciID uuid.UUID, maxDepth int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT service_id FROM service_tree_members
+		WHERE ci_id = $1 AND depth < $2`, ciID, maxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services of CI: %w", err)
+	}
+	return ids, nil
+}
+
+// ServicesMissing retrieves the services the CI should belong to through an
+// active relationship from one of their members but does not
+func (s *PostgresStore) ServicesMissing(ctx context.Context, ciID uuid.UUID, maxDepth int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT DISTINCT m.service_id
+		FROM ci_relationships r
+		JOIN service_tree_members m ON m.ci_id = r.source_ci_id AND m.depth < $2
+		WHERE r.target_ci_id = $1 AND r.is_active = true AND r.state = $3
+		  AND NOT EXISTS (
+		      SELECT 1 FROM service_tree_members x
+		      WHERE x.service_id = m.service_id AND x.ci_id = $1)`,
+		ciID, maxDepth, models.RelationshipStateActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services missing CI: %w", err)
+	}
+	return ids, nil
+}
+
+// RefreshMember copies the CI onto its memberships when it is live
+func (s *PostgresStore) RefreshMember(ctx context.Context, ciID uuid.UUID, at time.Time) (bool, error) {
+	var live bool
+	err := s.db.GetContext(ctx, &live, `SELECT EXISTS (SELECT 1 FROM configuration_items WHERE id = $1 AND is_deleted = false)`, ciID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check CI: %w", err)
+	}
+	if !live {
+		return false, nil
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE service_tree_members m
+		SET ci_name = c.name, ci_type = c.type, ci_status = COALESCE(c.status, ''), updated_at = $2
+		FROM configuration_items c
+		WHERE m.ci_id = $1 AND c.id = m.ci_id
+		  AND (m.ci_name, m.ci_type, m.ci_status) IS DISTINCT FROM (c.name, c.type, COALESCE(c.status, ''))`, ciID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to refresh service tree member: %w", err)
+	}
+	return true, nil
+}
+
+// ListMembers retrieves the members of a service, nearest first
+func (s *PostgresStore) ListMembers(ctx context.Context, serviceID uuid.UUID, filter MemberFilter) ([]Member, error) {
+	conditions := []string{"service_id = $1"}
+	args := []interface{}{serviceID}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("ci_type = $%d", len(args)))
+	}
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("ci_status = ANY($%d)", len(args)))
+	}
+	if filter.MaxDepth > 0 {
+		args = append(args, filter.MaxDepth)
+		conditions = append(conditions, fmt.Sprintf("depth <= $%d", len(args)))
+	}
+	orgFilter, args := andVisibleCIs(ctx, "ci_id", args)
+	query := `SELECT ` + memberColumns + ` FROM service_tree_members WHERE ` + strings.Join(conditions, " AND ") + orgFilter + ` ORDER BY depth, ci_name, ci_id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	members := []Member{}
+	if err := s.db.SelectContext(ctx, &members, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list service tree members: %w", err)
+	}
+	return members, nil
+}
+
+// ListServicesOf retrieves the memberships of a CI, with the services named
+func (s *PostgresStore) ListServicesOf(ctx context.Context, ciID uuid.UUID) ([]Member, error) {
+	members := []Member{}
+	orgFilter, args := andVisibleCIs(ctx, "m.service_id", []interface{}{ciID})
+	err := s.db.SelectContext(ctx, &members, `
+		SELECT m.service_id, m.ci_id, m.depth, service.ci_name, service.ci_type, service.ci_status, m.updated_at
+		FROM service_tree_members m
+		JOIN service_tree_members service ON service.service_id = m.service_id AND service.ci_id = m.service_id
+		WHERE m.ci_id = $1`+orgFilter+`
+		ORDER BY m.depth, service.ci_name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services of CI: %w", err)
+	}
+	return members, nil
+}
+
+// StatusCounts counts the members of a service per status
+func (s *PostgresStore) StatusCounts(ctx context.Context, serviceID uuid.UUID) (map[string]int, error) {
+	orgFilter, args := andVisibleCIs(ctx, "ci_id", []interface{}{serviceID})
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ci_status, COUNT(*) FROM service_tree_members
+		WHERE service_id = $1`+orgFilter+`
+		GROUP BY ci_status`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count service tree members: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan service tree count: %w", err)
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count service tree members: %w", err)
+	}
+	return counts, nil
+}
+
+// GetMember retrieves a membership, nil when the service does not hold the CI
+func (s *PostgresStore) GetMember(ctx context.Context, serviceID, ciID uuid.UUID) (*Member, error) {
+	var member Member
+	orgFilter, args := andVisibleCIs(ctx, "ci_id", []interface{}{serviceID, ciID})
+	err := s.db.GetContext(ctx, &member, `SELECT `+memberColumns+` FROM service_tree_members WHERE service_id = $1 AND ci_id = $2`+orgFilter, args...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service tree member: %w", err)
+	}
+	return &member, nil
+}
@@ -0,0 +1,163 @@
+// Package sessionlimits decides how many concurrent sessions a user may hold.
+// Limits are configured per role and per tenant with a default for everyone
+// else, and can be adjusted at runtime through the admin API.
+package sessionlimits
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Limit scopes
+const (
+	ScopeDefault = "default"
+	ScopeRole    = "role"
+	ScopeTenant  = "tenant"
+)
+
+// Unlimited is the limit value that allows any number of sessions
+const Unlimited = 0
+
+// Limit is a concurrent session limit for a scope. The default scope has no name.
+type Limit struct {
+	Scope       string    `json:"scope" db:"scope"`
+	Name        string    `json:"name,omitempty" db:"name"`
+	MaxSessions int       `json:"max_sessions" db:"max_sessions"` // 0 means unlimited
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	UpdatedBy   string    `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// Validate checks the scope, name and value of a limit
+func (l Limit) Validate() error {
+	switch l.Scope {
+	case ScopeDefault:
+		if l.Name != "" {
+			return fmt.Errorf("the default limit has no name")
+		}
+	case ScopeRole, ScopeTenant:
+		if l.Name == "" {
+			return fmt.Errorf("a %s limit needs a name", l.Scope)
+		}
+	default:
+		return fmt.Errorf("unknown scope: %s", l.Scope)
+	}
+	if l.MaxSessions < 0 {
+		return fmt.Errorf("max sessions cannot be negative")
+	}
+	return nil
+}
+
+// Policy holds the effective limits
+type Policy struct {
+	Default int            `json:"default"`
+	Roles   map[string]int `json:"roles"`
+	Tenants map[string]int `json:"tenants"`
+}
+
+// LimitFor returns the session limit of a user. Role limits take precedence:
+// the most restrictive limit among the user's roles applies, and the user is
+// unlimited only if every role with a limit is unlimited. Without role limits
+// the tenant limit applies, then the default.
+func (p Policy) LimitFor(roles []string, tenant string) int {
+	limit, found := Unlimited, false
+	for _, role := range roles {
+		roleLimit, ok := p.Roles[role]
+		if !ok {
+			continue
+		}
+		if !found || (roleLimit != Unlimited && (limit == Unlimited || roleLimit < limit)) {
+			limit = roleLimit
+		}
+		found = true
+	}
+	if found {
+		return limit
+	}
+
+	if tenantLimit, ok := p.Tenants[tenant]; ok && tenant != "" {
+		return tenantLimit
+	}
+	return p.Default
+}
+
+// apply overrides the policy with a stored limit
+func (p *Policy) apply(limit Limit) {
+	switch limit.Scope {
+	case ScopeDefault:
+		p.Default = limit.MaxSessions
+	case ScopeRole:
+		p.Roles[limit.Name] = limit.MaxSessions
+	case ScopeTenant:
+		p.Tenants[limit.Name] = limit.MaxSessions
+	}
+}
+
+// clone returns a deep copy of the policy
+func (p Policy) clone() Policy {
+	c := Policy{Default: p.Default, Roles: make(map[string]int, len(p.Roles)), Tenants: make(map[string]int, len(p.Tenants))}
+	for role, limit := range p.Roles {
+		c.Roles[role] = limit
+	}
+	for tenant, limit := range p.Tenants {
+		c.Tenants[tenant] = limit
+	}
+	return c
+}
+
+// Store persists limits adjusted at runtime
+type Store interface {
+	ListLimits(ctx context.Context) ([]Limit, error)
+	SaveLimit(ctx context.Context, limit *Limit) error
+	DeleteLimit(ctx context.Context, scope, name string) error
+}
+
+// PostgresStore keeps limits in the session_limits table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed limit store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ListLimits retrieves all stored limits
+func (s *PostgresStore) ListLimits(ctx context.Context) ([]Limit, error) {
+	query := `
+		SELECT scope, name, max_sessions, updated_at, COALESCE(updated_by, '') AS updated_by
+		FROM session_limits
+		ORDER BY scope, name`
+
+	var limits []Limit
+	if err := s.db.SelectContext(ctx, &limits, query); err != nil {
+		return nil, fmt.Errorf("failed to list session limits: %w", err)
+	}
+	return limits, nil
+}
+
+// SaveLimit creates or updates a limit
+func (s *PostgresStore) SaveLimit(ctx context.Context, limit *Limit) error {
+	query := `
+		INSERT INTO session_limits (scope, name, max_sessions, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (scope, name) DO UPDATE SET
+			max_sessions = EXCLUDED.max_sessions,
+			updated_at = EXCLUDED.updated_at,
+			updated_by = EXCLUDED.updated_by`
+
+	if _, err := s.db.ExecContext(ctx, query, limit.Scope, limit.Name, limit.MaxSessions, limit.UpdatedAt, limit.UpdatedBy); err != nil {
+		return fmt.Errorf("failed to save session limit: %w", err)
+	}
+	return nil
+}
+
+// DeleteLimit removes a stored limit so the configured value applies again
+func (s *PostgresStore) DeleteLimit(ctx context.Context, scope, name string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM session_limits WHERE scope = $1 AND name = $2`, scope, name); err != nil {
+		return fmt.Errorf("failed to delete session limit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package sessionlimits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	limits map[[2]string]Limit
+	reads  int
+}
+
+func (m *memoryStore) ListLimits(ctx context.Context) ([]Limit, error) {
+	m.reads++
+	limits := make([]Limit, 0, len(m.limits))
+	for _, limit := range m.limits {
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+func (m *memoryStore) SaveLimit(ctx context.Context, limit *Limit) error {
+	m.limits[[2]string{limit.Scope, limit.Name}] = *limit
+	return nil
+}
+
+func (m *memoryStore) DeleteLimit(ctx context.Context, scope, name string) error {
+	delete(m.limits, [2]string{scope, name})
+	return nil
+}
+
+func TestPolicy_LimitFor(t *testing.T) {
+	policy := Policy{
+		Default: 5,
+		Roles:   map[string]int{"admin": 2, "service_account": Unlimited, "auditor": 3},
+		Tenants: map[string]int{"acme": 10},
+	}
+
+	tests := []struct {
+		name   string
+		roles  []string
+		tenant string
+		want   int
+	}{
+		{"default", []string{"viewer"}, "", 5},
+		{"role limit", []string{"viewer", "admin"}, "acme", 2},
+		{"most restrictive role", []string{"auditor", "admin"}, "", 2},
+		{"unlimited role", []string{"service_account"}, "", Unlimited},
+		{"limited role beats unlimited role", []string{"service_account", "auditor"}, "", 3},
+		{"tenant limit", []string{"viewer"}, "acme", 10},
+		{"unknown tenant", nil, "globex", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.LimitFor(tt.roles, tt.tenant))
+		})
+	}
+}
+
+func TestService_Overrides(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{limits: map[[2]string]Limit{}}
+	configured := Policy{Default: 5, Roles: map[string]int{"admin": 2}}
+	service := NewService(store, configured, time.Minute)
+
+	assert.Equal(t, 2, service.LimitFor(ctx, []string{"admin"}, ""))
+
+	_, err := service.Set(ctx, Limit{Scope: ScopeRole, Name: "admin", MaxSessions: 1, UpdatedBy: "root"})
+	require.NoError(t, err)
+	_, err = service.Set(ctx, Limit{Scope: ScopeDefault, MaxSessions: 3})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, service.LimitFor(ctx, []string{"admin"}, ""))
+	assert.Equal(t, 3, service.LimitFor(ctx, []string{"viewer"}, ""))
+	assert.Len(t, service.Overrides(ctx), 2)
+
+	// Configured values are not modified by overrides
+	assert.Equal(t, 2, configured.Roles["admin"])
+
+	require.NoError(t, service.Reset(ctx, ScopeRole, "admin"))
+	assert.Equal(t, 2, service.LimitFor(ctx, []string{"admin"}, ""))
+
+	_, err = service.Set(ctx, Limit{Scope: ScopeRole, MaxSessions: 1})
+	assert.Error(t, err)
+	_, err = service.Set(ctx, Limit{Scope: ScopeTenant, Name: "acme", MaxSessions: -1})
+	assert.Error(t, err)
+}
+
+func TestService_CachesStoredLimits(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{limits: map[[2]string]Limit{}}
+	service := NewService(store, Policy{Default: 5}, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	service.LimitFor(ctx, nil, "")
+	service.LimitFor(ctx, nil, "")
+	assert.Equal(t, 1, store.reads)
+
+	// A limit saved by another instance is picked up after the refresh interval
+	store.limits[[2]string{ScopeDefault, ""}] = Limit{Scope: ScopeDefault, MaxSessions: 7}
+	assert.Equal(t, 5, service.LimitFor(ctx, nil, ""))
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 7, service.LimitFor(ctx, nil, ""))
+}
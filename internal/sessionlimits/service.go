@@ -0,0 +1,122 @@
+package sessionlimits
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval bounds how stale the in-memory limits may be on other instances
+const DefaultRefreshInterval = 30 * time.Second
+
+// Service combines the configured limits with those adjusted at runtime
+type Service struct {
+	store           Store
+	configured      Policy
+	refreshInterval time.Duration
+
+	mu       sync.RWMutex
+	policy   Policy
+	stored   []Limit
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+// NewService creates a new session limit service. configured holds the limits
+// from configuration; stored limits override them.
+func NewService(store Store, configured Policy, refreshInterval time.Duration) *Service {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	configured = configured.clone()
+	return &Service{
+		store:           store,
+		configured:      configured,
+		refreshInterval: refreshInterval,
+		policy:          configured,
+		now:             time.Now,
+	}
+}
+
+// Policy returns the effective limits, reloading stored limits when the cached
+// copy is stale. If the store cannot be read the last known limits are kept.
+func (s *Service) Policy(ctx context.Context) Policy {
+	s.mu.RLock()
+	policy, loadedAt := s.policy, s.loadedAt
+	s.mu.RUnlock()
+
+	if !loadedAt.IsZero() && s.now().Sub(loadedAt) < s.refreshInterval {
+		return policy
+	}
+
+	if err := s.reload(ctx); err != nil {
+		log.Printf("Failed to load session limits, keeping last known limits: %v", err)
+		return policy
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// LimitFor returns the session limit of a user with the given roles and tenant
+func (s *Service) LimitFor(ctx context.Context, roles []string, tenant string) int {
+	return s.Policy(ctx).LimitFor(roles, tenant)
+}
+
+// Overrides returns the limits adjusted at runtime
+func (s *Service) Overrides(ctx context.Context) []Limit {
+	s.Policy(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Limit{}, s.stored...)
+}
+
+// Set stores a limit and applies it immediately on this instance
+func (s *Service) Set(ctx context.Context, limit Limit) (Limit, error) {
+	if err := limit.Validate(); err != nil {
+		return Limit{}, err
+	}
+
+	limit.UpdatedAt = s.now()
+	if err := s.store.SaveLimit(ctx, &limit); err != nil {
+		return Limit{}, err
+	}
+	if err := s.reload(ctx); err != nil {
+		return Limit{}, err
+	}
+
+	log.Printf("Session limit for %s %q set to %d by %s", limit.Scope, limit.Name, limit.MaxSessions, limit.UpdatedBy)
+	return limit, nil
+}
+
+// Reset removes a stored limit so the configured value applies again
+func (s *Service) Reset(ctx context.Context, scope, name string) error {
+	if err := s.store.DeleteLimit(ctx, scope, name); err != nil {
+		return err
+	}
+	return s.reload(ctx)
+}
+
+// reload rebuilds the effective policy from configuration and stored limits
+func (s *Service) reload(ctx context.Context) error {
+	stored, err := s.store.ListLimits(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy := s.configured.clone()
+	for _, limit := range stored {
+		policy.apply(limit)
+	}
+
+	s.mu.Lock()
+	s.policy = policy
+	s.stored = stored
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+
+	return nil
+}
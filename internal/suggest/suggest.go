@@ -0,0 +1,114 @@
+// Package suggest serves search-as-you-type suggestions for the global search
+// box: a few CIs whose name starts with, or contains, what has been typed so
+// far. Unlike the full search it only matches names, served from dedicated
+// prefix and trigram indexes, and caches answers in Redis, since users
+// typing the same first letters ask the same questions.
+package suggest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/visibility"
+)
+
+// Defaults
+const (
+	// DefaultCacheTTL bounds how long suggestions are reused, so new and
+	// renamed CIs are suggested within that delay
+	DefaultCacheTTL = 30 * time.Second
+	// QueryTimeout bounds a suggestion query; a late suggestion is useless as
+	// the user has typed on
+	QueryTimeout = time.Second
+	// MaxQueryLength bounds the characters of a query
+	MaxQueryLength = 100
+)
+
+var ErrInvalidQuery = errors.New("invalid suggest query")
+
+// Store finds the CIs to suggest. The CI repository satisfies it.
+type Store interface {
+	SuggestCIs(ctx context.Context, req *models.SuggestCIsRequest) ([]models.CISuggestion, error)
+}
+
+// Cache is the subset of the Redis cache service used to cache suggestions.
+// database.CacheService satisfies it.
+type Cache interface {
+	GetJSON(ctx context.Context, key string, target interface{}) error
+	SetJSONWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// Service serves suggestions through the cache
+type Service struct {
+	store    Store
+	cache    Cache
+	cacheTTL time.Duration
+}
+
+// NewService creates a new suggest service. cache may be nil to always query the store.
+func NewService(store Store, cache Cache, cacheTTL time.Duration) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{store: store, cache: cache, cacheTTL: cacheTTL}
+}
+
+// Suggest returns the CIs to suggest for a query. The query is trimmed and
+// matched case-insensitively; an empty one suggests nothing.
+func (s *Service) Suggest(ctx context.Context, req models.SuggestCIsRequest) ([]models.CISuggestion, error) {
+	req.Query = strings.ToLower(strings.TrimSpace(req.Query))
+	if req.Query == "" {
+		return []models.CISuggestion{}, nil
+	}
+	if len([]rune(req.Query)) > MaxQueryLength {
+		return nil, fmt.Errorf("%w: q must be at most %d characters", ErrInvalidQuery, MaxQueryLength)
+	}
+	if req.Limit <= 0 {
+		req.Limit = models.DefaultSuggestLimit
+	}
+	if req.Limit > models.MaxSuggestLimit {
+		req.Limit = models.MaxSuggestLimit
+	}
+
+	// The scope and organization are part of the key, so callers only share
+	// the suggestions of the CIs they can all see
+	key := cacheKey(visibility.TenantFromContext(ctx), req)
+	if s.cache != nil {
+		var cached []models.CISuggestion
+		if err := s.cache.GetJSON(ctx, key, &cached); err == nil && cached != nil {
+			return cached, nil
+		}
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+	suggestions, err := s.store.SuggestCIs(queryCtx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		if err := s.cache.SetJSONWithTTL(ctx, key, suggestions, s.cacheTTL); err != nil {
+			log.Printf("Failed to cache suggestions: %v", err)
+		}
+	}
+	return suggestions, nil
+}
+
+// cacheKey identifies a normalized request of a caller acting for the tenant
+func cacheKey(tenant string, req models.SuggestCIsRequest) string {
+	encoded, _ := json.Marshal(struct {
+		Tenant  string                   `json:"tenant"`
+		Request models.SuggestCIsRequest `json:"request"`
+	}{tenant, req})
+	sum := sha256.Sum256(encoded)
+	return "suggest:ci:" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,113 @@
+package suggest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingStore struct {
+	calls    int
+	requests []models.SuggestCIsRequest
+}
+
+func (s *countingStore) SuggestCIs(ctx context.Context, req *models.SuggestCIsRequest) ([]models.CISuggestion, error) {
+	s.calls++
+	s.requests = append(s.requests, *req)
+	return []models.CISuggestion{{ID: uuid.New(), Name: req.Query + "-01", Type: "server"}}, nil
+}
+
+type memoryCache struct {
+	entries map[string][]byte
+}
+
+func (c *memoryCache) GetJSON(ctx context.Context, key string, target interface{}) error {
+	data, ok := c.entries[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+	return json.Unmarshal(data, target)
+}
+
+func (c *memoryCache) SetJSONWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.entries[key] = data
+	return nil
+}
+
+func TestSuggestNormalizesAndCaches(t *testing.T) {
+	store := &countingStore{}
+	service := NewService(store, &memoryCache{entries: map[string][]byte{}}, 0)
+	ctx := context.Background()
+
+	first, err := service.Suggest(ctx, models.SuggestCIsRequest{Query: "  Web "})
+	require.NoError(t, err)
+	second, err := service.Suggest(ctx, models.SuggestCIsRequest{Query: "web", Limit: models.DefaultSuggestLimit})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, store.calls)
+	assert.Equal(t, first, second)
+	assert.Equal(t, "web", store.requests[0].Query)
+	assert.Equal(t, models.DefaultSuggestLimit, store.requests[0].Limit)
+}
+
+func TestSuggestCachesPerScope(t *testing.T) {
+	store := &countingStore{}
+	service := NewService(store, &memoryCache{entries: map[string][]byte{}}, 0)
+	ctx := context.Background()
+
+	_, err := service.Suggest(ctx, models.SuggestCIsRequest{Query: "db", Scope: &models.VisibilityScope{Types: []string{"database"}}})
+	require.NoError(t, err)
+	_, err = service.Suggest(ctx, models.SuggestCIsRequest{Query: "db", Scope: &models.VisibilityScope{Unrestricted: true}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, store.calls)
+}
+
+func TestSuggestCachesPerOrganization(t *testing.T) {
+	store := &countingStore{}
+	service := NewService(store, &memoryCache{entries: map[string][]byte{}}, 0)
+	req := models.SuggestCIsRequest{Query: "db", Scope: &models.VisibilityScope{Unrestricted: true}}
+
+	_, err := service.Suggest(visibility.WithTenant(context.Background(), "acme"), req)
+	require.NoError(t, err)
+	_, err = service.Suggest(visibility.WithTenant(context.Background(), "globex"), req)
+	require.NoError(t, err)
+	_, err = service.Suggest(visibility.WithTenant(context.Background(), "acme"), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, store.calls)
+}
+
+func TestSuggestBoundsQuery(t *testing.T) {
+	store := &countingStore{}
+	service := NewService(store, nil, 0)
+	ctx := context.Background()
+
+	suggestions, err := service.Suggest(ctx, models.SuggestCIsRequest{Query: "   "})
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+	assert.Zero(t, store.calls)
+
+	long := make([]byte, MaxQueryLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	_, err = service.Suggest(ctx, models.SuggestCIsRequest{Query: string(long)})
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+
+	_, err = service.Suggest(ctx, models.SuggestCIsRequest{Query: "app", Limit: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, models.MaxSuggestLimit, store.requests[0].Limit)
+}
@@ -0,0 +1,38 @@
+package suggestion
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// Notification events
+const (
+	EventSubmitted = "suggestion.submitted"
+	EventAccepted  = "suggestion.accepted"
+	EventRejected  = "suggestion.rejected"
+)
+
+// Notification tells the owner of a CI about a new suggestion, or the
+// suggester about its review
+type Notification struct {
+	Event      string      `json:"event"`
+	Recipients []string    `json:"recipients"`
+	Suggestion *Suggestion `json:"suggestion"`
+}
+
+// Notifier delivers suggestion notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the log. It is used until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the notification
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	log.Printf("Suggestion %s for CI %s: %s (%d changes), notifying %s",
+		n.Suggestion.ID, n.Suggestion.CIID, n.Event, len(n.Suggestion.Changes), strings.Join(n.Recipients, ", "))
+	return nil
+}
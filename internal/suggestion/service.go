@@ -0,0 +1,240 @@
+package suggestion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// PermissionSource returns the permissions granted by roles
+type PermissionSource interface {
+	Permissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// Service runs the suggestion workflow
+type Service struct {
+	store       Store
+	permissions PermissionSource
+	notifier    Notifier
+	now         func() time.Time
+}
+
+// NewService creates a new suggestion service. A nil notifier logs notifications.
+func NewService(store Store, permissions PermissionSource, notifier Notifier) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	return &Service{store: store, permissions: permissions, notifier: notifier, now: time.Now}
+}
+
+// caller is an actor with their permissions resolved
+type caller struct {
+	Actor
+	editor bool
+	filter *visibility.Filter
+}
+
+// resolve loads the permissions of the actor
+func (s *Service) resolve(ctx context.Context, actor Actor) (*caller, error) {
+	permissions, err := s.permissions.Permissions(ctx, actor.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve permissions: %w", err)
+	}
+
+	c := &caller{Actor: actor}
+	for _, permission := range permissions {
+		if permission == UpdatePermission || permission == ManagePermission {
+			c.editor = true
+		}
+	}
+	c.filter = visibility.NewFilter(visibility.ScopeFromPermissions(permissions, visibility.TenantFromContext(ctx)), s.store)
+	return c, nil
+}
+
+// reviews reports whether the caller may review suggestions for the CI
+func (c *caller) reviews(ctx context.Context, ciID uuid.UUID) bool {
+	return c.editor && c.filter.CanSee(ctx, ciID)
+}
+
+// Submit suggests a correction of a CI the actor can see. CIs the actor cannot
+// see are reported as not found.
+func (s *Service) Submit(ctx context.Context, actor Actor, ciID uuid.UUID, req SubmitRequest) (*Suggestion, error) {
+	if strings.TrimSpace(actor.ID) == "" {
+		return nil, ErrNotAuthorized
+	}
+	c, err := s.resolve(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !c.filter.CanSee(ctx, ciID) {
+		return nil, fmt.Errorf("%w: %s", ErrCINotFound, ciID)
+	}
+
+	ci, err := s.store.GetCI(ctx, ciID)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := Diff(ci, req)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := &Suggestion{
+		ID:          uuid.New(),
+		CIID:        ciID,
+		CIName:      ci.Name,
+		Changes:     changes,
+		Reason:      strings.TrimSpace(req.Reason),
+		Status:      StatusPending,
+		SuggestedBy: actor.ID,
+		CreatedAt:   s.now(),
+	}
+	if err := s.store.Create(ctx, suggestion); err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, EventSubmitted, suggestion, []string{ci.Owner})
+	return suggestion, nil
+}
+
+// Get retrieves a suggestion for its suggester or a reviewer of its CI
+func (s *Service) Get(ctx context.Context, actor Actor, id uuid.UUID) (*Suggestion, error) {
+	c, err := s.resolve(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	suggestion, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.SuggestedBy != actor.ID && !c.reviews(ctx, suggestion.CIID) {
+		return nil, ErrSuggestionNotFound
+	}
+	return suggestion, nil
+}
+
+// List retrieves suggestions matching the filter. Editors see the suggestions
+// for the CIs they can see, the queue they review; everyone else sees their own.
+func (s *Service) List(ctx context.Context, actor Actor, filter Filter) ([]*Suggestion, error) {
+	c, err := s.resolve(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !c.editor {
+		filter.SuggestedBy = actor.ID
+	}
+
+	suggestions, err := s.store.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if !c.editor {
+		return suggestions, nil
+	}
+
+	visible := make([]*Suggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if suggestion.SuggestedBy == actor.ID || c.filter.CanSee(ctx, suggestion.CIID) {
+			visible = append(visible, suggestion)
+		}
+	}
+	return visible, nil
+}
+
+// Accept applies a suggestion on behalf of an editor. It fails with ErrStale
+// when a corrected field changed since the suggestion was made.
+func (s *Service) Accept(ctx context.Context, actor Actor, id uuid.UUID, note string) (*Suggestion, error) {
+	if _, err := s.authorizeReview(ctx, actor, id); err != nil {
+		return nil, err
+	}
+
+	suggestion, err := s.store.Accept(ctx, id, actor.ID, note, s.now(), func(ci *models.CI, changes []Change) error {
+		stale, err := Stale(ci, changes)
+		if err != nil {
+			return err
+		}
+		if stale {
+			return ErrStale
+		}
+		return Apply(ci, changes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, EventAccepted, suggestion, []string{suggestion.SuggestedBy})
+	return suggestion, nil
+}
+
+// Reject closes a suggestion on behalf of an editor; the CI is unchanged
+func (s *Service) Reject(ctx context.Context, actor Actor, id uuid.UUID, note string) (*Suggestion, error) {
+	if _, err := s.authorizeReview(ctx, actor, id); err != nil {
+		return nil, err
+	}
+
+	suggestion, err := s.store.Close(ctx, id, StatusRejected, actor.ID, note, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, EventRejected, suggestion, []string{suggestion.SuggestedBy})
+	return suggestion, nil
+}
+
+// Withdraw closes a suggestion on behalf of its suggester
+func (s *Service) Withdraw(ctx context.Context, actor Actor, id uuid.UUID) (*Suggestion, error) {
+	suggestion, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.SuggestedBy != actor.ID {
+		return nil, ErrNotAuthorized
+	}
+	return s.store.Close(ctx, id, StatusWithdrawn, actor.ID, "", s.now())
+}
+
+// authorizeReview checks the actor may review the suggestion and that it is pending
+func (s *Service) authorizeReview(ctx context.Context, actor Actor, id uuid.UUID) (*Suggestion, error) {
+	c, err := s.resolve(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	suggestion, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !c.reviews(ctx, suggestion.CIID) {
+		if suggestion.SuggestedBy == actor.ID {
+			return nil, ErrNotAuthorized
+		}
+		return nil, ErrSuggestionNotFound
+	}
+	if suggestion.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	return suggestion, nil
+}
+
+// notify delivers a notification; failures are logged since the workflow step has already been stored
+func (s *Service) notify(ctx context.Context, event string, suggestion *Suggestion, recipients []string) {
+	named := make([]string, 0, len(recipients))
+	for _, recipient := range recipients {
+		if recipient != "" {
+			named = append(named, recipient)
+		}
+	}
+	if len(named) == 0 {
+		return
+	}
+
+	notification := Notification{Event: event, Recipients: named, Suggestion: suggestion}
+	if err := s.notifier.Notify(ctx, notification); err != nil {
+		log.Printf("Failed to send %s notification for suggestion %s: %v", event, suggestion.ID, err)
+	}
+}
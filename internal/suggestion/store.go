@@ -0,0 +1,329 @@
+package suggestion
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// DefaultListLimit bounds a listing without a limit
+const DefaultListLimit = 100
+
+// Store persists suggestions and applies accepted ones
+type Store interface {
+	// GetCI retrieves a live CI, ErrCINotFound when there is none
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+	Create(ctx context.Context, suggestion *Suggestion) error
+	Get(ctx context.Context, id uuid.UUID) (*Suggestion, error)
+	List(ctx context.Context, filter Filter) ([]*Suggestion, error)
+	// Accept closes a pending suggestion as accepted and stores the CI as
+	// corrected by apply, which is given the CI locked for update. Nothing is
+	// stored when apply fails.
+	Accept(ctx context.Context, id uuid.UUID, reviewedBy, note string, at time.Time, apply func(ci *models.CI, changes []Change) error) (*Suggestion, error)
+	// Close moves a pending suggestion to status without changing the CI
+	Close(ctx context.Context, id uuid.UUID, status, reviewedBy, note string, at time.Time) (*Suggestion, error)
+}
+
+// PostgresStore keeps suggestions in the ci_correction_suggestions table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed suggestion store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const ciColumns = `id, name, type, COALESCE(description, ''), COALESCE(status, ''), COALESCE(criticality, ''),
+	COALESCE(owner, ''), COALESCE(location, ''), attributes, tags`
+
+// GetCI retrieves the fields of a live CI needed for visibility and corrections
+func (s *PostgresStore) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	return getCI(ctx, s.db, id, false)
+}
+
+// getCI reads a live CI, optionally locking it for update
+func getCI(ctx context.Context, q sqlx.QueryerContext, id uuid.UUID, lock bool) (*models.CI, error) {
+	query := "SELECT " + ciColumns + " FROM configuration_items WHERE id = $1 AND is_deleted = false"
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	var ci models.CI
+	var attributes []byte
+	err := q.QueryRowxContext(ctx, query, id).Scan(&ci.ID, &ci.Name, &ci.Type, &ci.Description, &ci.Status,
+		&ci.Criticality, &ci.Owner, &ci.Location, &attributes, pq.Array(&ci.Tags))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrCINotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CI: %w", err)
+	}
+	ci.Attributes = attributes
+	return &ci, nil
+}
+
+// Create stores a pending suggestion
+func (s *PostgresStore) Create(ctx context.Context, suggestion *Suggestion) error {
+	changes, err := json.Marshal(suggestion.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode suggestion changes: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO ci_correction_suggestions (id, ci_id, changes, reason, status, suggested_by, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)`,
+		suggestion.ID, suggestion.CIID, changes, suggestion.Reason, suggestion.Status, suggestion.SuggestedBy, suggestion.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create suggestion: %w", err)
+	}
+	return nil
+}
+
+const suggestionColumns = `s.id, s.ci_id, COALESCE(c.name, '') AS ci_name, s.changes, COALESCE(s.reason, '') AS reason,
+	s.status, s.suggested_by, s.created_at, COALESCE(s.reviewed_by, '') AS reviewed_by, s.reviewed_at,
+	COALESCE(s.review_note, '') AS review_note`
+
+const suggestionFrom = ` FROM ci_correction_suggestions s LEFT JOIN configuration_items c ON c.id = s.ci_id`
+
+// suggestionRow is a suggestion as stored
+type suggestionRow struct {
+	ID          uuid.UUID  `db:"id"`
+	CIID        uuid.UUID  `db:"ci_id"`
+	CIName      string     `db:"ci_name"`
+	Changes     []byte     `db:"changes"`
+	Reason      string     `db:"reason"`
+	Status      string     `db:"status"`
+	SuggestedBy string     `db:"suggested_by"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ReviewedBy  string     `db:"reviewed_by"`
+	ReviewedAt  *time.Time `db:"reviewed_at"`
+	ReviewNote  string     `db:"review_note"`
+}
+
+func (r *suggestionRow) suggestion() (*Suggestion, error) {
+	suggestion := &Suggestion{
+		ID:          r.ID,
+		CIID:        r.CIID,
+		CIName:      r.CIName,
+		Reason:      r.Reason,
+		Status:      r.Status,
+		SuggestedBy: r.SuggestedBy,
+		CreatedAt:   r.CreatedAt,
+		ReviewedBy:  r.ReviewedBy,
+		ReviewedAt:  r.ReviewedAt,
+		ReviewNote:  r.ReviewNote,
+	}
+	if err := json.Unmarshal(r.Changes, &suggestion.Changes); err != nil {
+		return nil, fmt.Errorf("failed to decode suggestion changes: %w", err)
+	}
+	return suggestion, nil
+}
+
+// Get retrieves a suggestion
+func (s *PostgresStore) Get(ctx context.Context, id uuid.UUID) (*Suggestion, error) {
+	return getSuggestion(ctx, s.db, id, false)
+}
+
+// getSuggestion reads a suggestion, optionally locking it for update
+func getSuggestion(ctx context.Context, q sqlx.QueryerContext, id uuid.UUID, lock bool) (*Suggestion, error) {
+	query := "SELECT " + suggestionColumns + suggestionFrom + " WHERE s.id = $1"
+	if lock {
+		query += " FOR UPDATE OF s"
+	}
+
+	var row suggestionRow
+	if err := sqlx.GetContext(ctx, q, &row, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSuggestionNotFound
+		}
+		return nil, fmt.Errorf("failed to get suggestion: %w", err)
+	}
+	return row.suggestion()
+}
+
+// List retrieves suggestions matching the filter, oldest first so the queue is
+// worked through in order
+func (s *PostgresStore) List(ctx context.Context, filter Filter) ([]*Suggestion, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.CIID != nil {
+		args = append(args, *filter.CIID)
+		conditions = append(conditions, fmt.Sprintf("s.ci_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("s.status = $%d", len(args)))
+	}
+	if filter.SuggestedBy != "" {
+		args = append(args, filter.SuggestedBy)
+		conditions = append(conditions, fmt.Sprintf("s.suggested_by = $%d", len(args)))
+	}
+
+	query := "SELECT " + suggestionColumns + suggestionFrom
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY s.created_at, s.id LIMIT $%d", len(args))
+
+	var rows []suggestionRow
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list suggestions: %w", err)
+	}
+
+	suggestions := make([]*Suggestion, 0, len(rows))
+	for i := range rows {
+		suggestion, err := rows[i].suggestion()
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// Accept applies a pending suggestion to its CI and closes it in one
+// transaction, writing audit entries for both
+func (s *PostgresStore) Accept(ctx context.Context, id uuid.UUID, reviewedBy, note string, at time.Time, apply func(ci *models.CI, changes []Change) error) (*Suggestion, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	suggestion, err := getSuggestion(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+
+	ci, err := getCI(ctx, tx, suggestion.CIID, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(ci, suggestion.Changes); err != nil {
+		return nil, err
+	}
+
+	var updatedBy *uuid.UUID
+	if reviewer, err := uuid.Parse(reviewedBy); err == nil {
+		updatedBy = &reviewer
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE configuration_items
+		SET name = $1, description = NULLIF($2, ''), owner = NULLIF($3, ''), location = NULLIF($4, ''), criticality = NULLIF($5, ''),
+		    attributes = $6,
+		    updated_at = $7, updated_by = COALESCE($8, updated_by)
+		WHERE id = $9`,
+		ci.Name, ci.Description, ci.Owner, ci.Location, ci.Criticality, []byte(ci.Attributes), at, updatedBy, ci.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply suggestion: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, "ci", ci.ID, "suggestion_applied", reviewedBy, at, map[string]interface{}{
+		"suggestion_id": suggestion.ID,
+		"suggested_by":  suggestion.SuggestedBy,
+		"changes":       suggestion.Changes,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := closeSuggestion(ctx, tx, suggestion, StatusAccepted, reviewedBy, note, at); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit suggestion: %w", err)
+	}
+	return suggestion, nil
+}
+
+// Close moves a pending suggestion to status
+func (s *PostgresStore) Close(ctx context.Context, id uuid.UUID, status, reviewedBy, note string, at time.Time) (*Suggestion, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	suggestion, err := getSuggestion(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+
+	if err := closeSuggestion(ctx, tx, suggestion, status, reviewedBy, note, at); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit suggestion: %w", err)
+	}
+	return suggestion, nil
+}
+
+// closeSuggestion records the review of a locked suggestion and audits it
+func closeSuggestion(ctx context.Context, tx *sqlx.Tx, suggestion *Suggestion, status, reviewedBy, note string, at time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE ci_correction_suggestions
+		SET status = $1, reviewed_by = NULLIF($2, ''), reviewed_at = $3, review_note = NULLIF($4, '')
+		WHERE id = $5`, status, reviewedBy, at, note, suggestion.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update suggestion: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, "ci_correction_suggestion", suggestion.ID, status, reviewedBy, at, map[string]interface{}{
+		"ci_id":        suggestion.CIID,
+		"suggested_by": suggestion.SuggestedBy,
+		"note":         note,
+	}); err != nil {
+		return err
+	}
+
+	suggestion.Status = status
+	suggestion.ReviewedBy = reviewedBy
+	suggestion.ReviewedAt = &at
+	suggestion.ReviewNote = note
+	return nil
+}
+
+// insertAudit writes an audit log entry. Actors that are not user IDs are kept
+// in the details since changed_by references users.
+func insertAudit(ctx context.Context, tx *sqlx.Tx, entityType string, entityID uuid.UUID, action, actor string, at time.Time, details map[string]interface{}) error {
+	var changedBy *uuid.UUID
+	if id, err := uuid.Parse(actor); err == nil {
+		changedBy = &id
+	} else if actor != "" {
+		details["actor"] = actor
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ($1, $2, $3, $4, $5, $6)`, entityType, entityID, action, changedBy, at, data)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
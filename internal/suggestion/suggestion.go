@@ -0,0 +1,279 @@
+// Package suggestion lets users who can see a CI but not edit it suggest
+// corrections, such as a changed owner or a wrong location. Suggestions wait in
+// an approval queue until an editor accepts or rejects them; accepting applies
+// the change unless the CI was changed in the meantime, so crowd knowledge is
+// captured without handing out edit rights.
+package suggestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Suggestion statuses
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusRejected  = "rejected"
+	StatusWithdrawn = "withdrawn"
+)
+
+// Permissions that make a caller an editor, who reviews suggestions
+const (
+	UpdatePermission = "ci:update"
+	ManagePermission = "ci:manage"
+)
+
+// AttributePrefix prefixes fields naming a CI attribute, e.g. attributes.rack
+const AttributePrefix = "attributes."
+
+// Bounds of a suggestion
+const (
+	MaxChanges      = 20
+	MaxReasonLength = 2000
+)
+
+// Fields are the CI fields besides attributes that can be corrected
+var Fields = []string{"name", "description", "owner", "location", "criticality"}
+
+var criticalities = map[string]bool{
+	models.CICriticalityLow:      true,
+	models.CICriticalityMedium:   true,
+	models.CICriticalityHigh:     true,
+	models.CICriticalityCritical: true,
+}
+
+var (
+	ErrInvalidSuggestion  = errors.New("invalid suggestion")
+	ErrNoChange           = errors.New("suggestion does not change the CI")
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+	ErrNotPending         = errors.New("suggestion is no longer pending")
+	ErrCINotFound         = errors.New("CI not found")
+	ErrNotAuthorized      = errors.New("not authorized for this suggestion")
+	ErrStale              = errors.New("CI changed since the suggestion was made")
+)
+
+// Change is the correction of one field, with the value it had when suggested
+type Change struct {
+	Field    string      `json:"field"`
+	Current  interface{} `json:"current"`
+	Proposed interface{} `json:"proposed"`
+}
+
+// Suggestion is a proposed correction of a CI awaiting review
+type Suggestion struct {
+	ID          uuid.UUID  `json:"id"`
+	CIID        uuid.UUID  `json:"ci_id"`
+	CIName      string     `json:"ci_name"`
+	Changes     []Change   `json:"changes"`
+	Reason      string     `json:"reason,omitempty"`
+	Status      string     `json:"status"`
+	SuggestedBy string     `json:"suggested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReviewedBy  string     `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote  string     `json:"review_note,omitempty"`
+}
+
+// SubmitRequest represents a suggested correction, mapping each field to its
+// proposed value. Attribute values may be any JSON value; null removes the attribute.
+type SubmitRequest struct {
+	Changes map[string]interface{} `json:"changes"`
+	Reason  string                 `json:"reason,omitempty"`
+}
+
+// Actor is the caller of a workflow step
+type Actor struct {
+	ID    string
+	Roles []string
+}
+
+// Filter narrows a suggestion listing
+type Filter struct {
+	CIID        *uuid.UUID
+	Status      string
+	SuggestedBy string
+	Limit       int
+}
+
+// Diff validates the proposed values against the CI and returns the changes,
+// sorted by field, leaving out values the CI already has
+func Diff(ci *models.CI, req SubmitRequest) ([]Change, error) {
+	if len(req.Changes) == 0 {
+		return nil, fmt.Errorf("%w: at least one change is required", ErrInvalidSuggestion)
+	}
+	if len(req.Changes) > MaxChanges {
+		return nil, fmt.Errorf("%w: at most %d changes are allowed", ErrInvalidSuggestion, MaxChanges)
+	}
+	if len(req.Reason) > MaxReasonLength {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidSuggestion, MaxReasonLength)
+	}
+
+	var changes []Change
+	for field, proposed := range req.Changes {
+		if err := validateValue(field, proposed); err != nil {
+			return nil, err
+		}
+		current, err := FieldValue(ci, field)
+		if err != nil {
+			return nil, err
+		}
+		if !sameValue(current, proposed) {
+			changes = append(changes, Change{Field: field, Current: current, Proposed: proposed})
+		}
+	}
+	if len(changes) == 0 {
+		return nil, ErrNoChange
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// validateValue checks a proposed value suits its field
+func validateValue(field string, value interface{}) error {
+	if strings.HasPrefix(field, AttributePrefix) {
+		if strings.TrimPrefix(field, AttributePrefix) == "" {
+			return fmt.Errorf("%w: %s needs an attribute name", ErrInvalidSuggestion, field)
+		}
+		return nil
+	}
+	if !isField(field) {
+		return fmt.Errorf("%w: %s cannot be corrected, use one of %s or %s<name>",
+			ErrInvalidSuggestion, field, strings.Join(Fields, ", "), AttributePrefix)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%w: %s must be a string", ErrInvalidSuggestion, field)
+	}
+	switch field {
+	case "name":
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("%w: name cannot be empty", ErrInvalidSuggestion)
+		}
+	case "criticality":
+		if !criticalities[s] {
+			return fmt.Errorf("%w: unknown criticality %q", ErrInvalidSuggestion, s)
+		}
+	}
+	return nil
+}
+
+func isField(field string) bool {
+	for _, candidate := range Fields {
+		if candidate == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldValue returns the value of a correctable field of a CI, nil for an
+// attribute it does not have
+func FieldValue(ci *models.CI, field string) (interface{}, error) {
+	switch field {
+	case "name":
+		return ci.Name, nil
+	case "description":
+		return ci.Description, nil
+	case "owner":
+		return ci.Owner, nil
+	case "location":
+		return ci.Location, nil
+	case "criticality":
+		return ci.Criticality, nil
+	}
+	if name := strings.TrimPrefix(field, AttributePrefix); name != field && name != "" {
+		attrs, err := decodeAttributes(ci.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		return attrs[name], nil
+	}
+	return nil, fmt.Errorf("%w: %s cannot be corrected", ErrInvalidSuggestion, field)
+}
+
+// Stale reports whether a field of the CI no longer has the value it had when
+// the changes were suggested
+func Stale(ci *models.CI, changes []Change) (bool, error) {
+	for _, change := range changes {
+		current, err := FieldValue(ci, change.Field)
+		if err != nil {
+			return false, err
+		}
+		if !sameValue(current, change.Current) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Apply sets the proposed values on the CI
+func Apply(ci *models.CI, changes []Change) error {
+	var attrs map[string]interface{}
+	for _, change := range changes {
+		value, _ := change.Proposed.(string)
+		switch change.Field {
+		case "name":
+			ci.Name = value
+		case "description":
+			ci.Description = value
+		case "owner":
+			ci.Owner = value
+		case "location":
+			ci.Location = value
+		case "criticality":
+			ci.Criticality = value
+		default:
+			if attrs == nil {
+				var err error
+				if attrs, err = decodeAttributes(ci.Attributes); err != nil {
+					return err
+				}
+			}
+			name := strings.TrimPrefix(change.Field, AttributePrefix)
+			if change.Proposed == nil {
+				delete(attrs, name)
+			} else {
+				attrs[name] = change.Proposed
+			}
+		}
+	}
+
+	if attrs != nil {
+		data, err := json.Marshal(attrs)
+		if err != nil {
+			return fmt.Errorf("failed to encode attributes: %w", err)
+		}
+		ci.Attributes = data
+	}
+	return nil
+}
+
+// decodeAttributes decodes CI attributes, treating empty attributes as none
+func decodeAttributes(raw json.RawMessage) (map[string]interface{}, error) {
+	attrs := map[string]interface{}{}
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return attrs, nil
+	}
+	if err := json.Unmarshal(raw, &attrs); err != nil {
+		return nil, fmt.Errorf("failed to decode CI attributes: %w", err)
+	}
+	return attrs, nil
+}
+
+// sameValue compares two JSON values by their encoding, so a number decoded
+// from a request equals the same number decoded from storage
+func sameValue(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,312 @@
+package suggestion
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	cis         map[uuid.UUID]*models.CI
+	suggestions map[uuid.UUID]*Suggestion
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{cis: map[uuid.UUID]*models.CI{}, suggestions: map[uuid.UUID]*Suggestion{}}
+}
+
+func (m *memoryStore) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	ci, ok := m.cis[id]
+	if !ok {
+		return nil, ErrCINotFound
+	}
+	copied := *ci
+	return &copied, nil
+}
+
+func (m *memoryStore) Create(ctx context.Context, suggestion *Suggestion) error {
+	stored := *suggestion
+	m.suggestions[suggestion.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, id uuid.UUID) (*Suggestion, error) {
+	suggestion, ok := m.suggestions[id]
+	if !ok {
+		return nil, ErrSuggestionNotFound
+	}
+	copied := *suggestion
+	return &copied, nil
+}
+
+func (m *memoryStore) List(ctx context.Context, filter Filter) ([]*Suggestion, error) {
+	var suggestions []*Suggestion
+	for _, suggestion := range m.suggestions {
+		if filter.Status != "" && suggestion.Status != filter.Status {
+			continue
+		}
+		if filter.SuggestedBy != "" && suggestion.SuggestedBy != filter.SuggestedBy {
+			continue
+		}
+		copied := *suggestion
+		suggestions = append(suggestions, &copied)
+	}
+	return suggestions, nil
+}
+
+func (m *memoryStore) Accept(ctx context.Context, id uuid.UUID, reviewedBy, note string, at time.Time, apply func(ci *models.CI, changes []Change) error) (*Suggestion, error) {
+	suggestion, ok := m.suggestions[id]
+	if !ok {
+		return nil, ErrSuggestionNotFound
+	}
+	if suggestion.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	ci, err := m.GetCI(ctx, suggestion.CIID)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(ci, suggestion.Changes); err != nil {
+		return nil, err
+	}
+	m.cis[ci.ID] = ci
+	return m.Close(ctx, id, StatusAccepted, reviewedBy, note, at)
+}
+
+func (m *memoryStore) Close(ctx context.Context, id uuid.UUID, status, reviewedBy, note string, at time.Time) (*Suggestion, error) {
+	suggestion, ok := m.suggestions[id]
+	if !ok {
+		return nil, ErrSuggestionNotFound
+	}
+	if suggestion.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	suggestion.Status = status
+	suggestion.ReviewedBy = reviewedBy
+	suggestion.ReviewedAt = &at
+	suggestion.ReviewNote = note
+	copied := *suggestion
+	return &copied, nil
+}
+
+type rolePermissions map[string][]string
+
+func (p rolePermissions) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, p[role]...)
+	}
+	return permissions, nil
+}
+
+type recordingNotifier struct {
+	notifications []Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+var (
+	viewer = Actor{ID: "viewer", Roles: []string{"viewer"}}
+	editor = Actor{ID: "editor", Roles: []string{"editor"}}
+	tagged = Actor{ID: "tagged", Roles: []string{"tagged"}}
+)
+
+func newTestService() (*Service, *memoryStore, *recordingNotifier, *models.CI) {
+	store := newMemoryStore()
+	ci := &models.CI{
+		ID:          uuid.New(),
+		Name:        "db-01",
+		Type:        "server",
+		Owner:       "alice",
+		Location:    "dc-1",
+		Criticality: models.CICriticalityMedium,
+		Attributes:  json.RawMessage(`{"rack":"r1","cores":8}`),
+	}
+	store.cis[ci.ID] = ci
+
+	permissions := rolePermissions{
+		"viewer": {"ci:read"},
+		"editor": {"ci:read", UpdatePermission},
+		"tagged": {"ci:read:tag=edge"},
+	}
+	notifier := &recordingNotifier{}
+	return NewService(store, permissions, notifier), store, notifier, ci
+}
+
+func TestDiffValidatesAndSkipsUnchangedValues(t *testing.T) {
+	ci := &models.CI{Name: "db-01", Owner: "alice", Criticality: models.CICriticalityLow, Attributes: json.RawMessage(`{"cores":8}`)}
+
+	changes, err := Diff(ci, SubmitRequest{Changes: map[string]interface{}{
+		"owner":            "bob",
+		"name":             "db-01",
+		"attributes.cores": float64(8),
+		"attributes.rack":  "r2",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Field: "attributes.rack", Current: nil, Proposed: "r2"},
+		{Field: "owner", Current: "alice", Proposed: "bob"},
+	}, changes)
+
+	_, err = Diff(ci, SubmitRequest{Changes: map[string]interface{}{"owner": "alice"}})
+	assert.ErrorIs(t, err, ErrNoChange)
+
+	for _, changes := range []map[string]interface{}{
+		{},
+		{"type": "vm"},
+		{"name": " "},
+		{"owner": 7},
+		{"criticality": "urgent"},
+		{"attributes.": "x"},
+	} {
+		_, err := Diff(ci, SubmitRequest{Changes: changes})
+		assert.ErrorIs(t, err, ErrInvalidSuggestion, "%v", changes)
+	}
+}
+
+func TestApplyAndStale(t *testing.T) {
+	ci := &models.CI{Owner: "alice", Attributes: json.RawMessage(`{"rack":"r1","cores":8}`)}
+	changes := []Change{
+		{Field: "attributes.rack", Current: "r1", Proposed: nil},
+		{Field: "owner", Current: "alice", Proposed: "bob"},
+	}
+
+	stale, err := Stale(ci, changes)
+	require.NoError(t, err)
+	assert.False(t, stale)
+
+	require.NoError(t, Apply(ci, changes))
+	assert.Equal(t, "bob", ci.Owner)
+	assert.JSONEq(t, `{"cores":8}`, string(ci.Attributes))
+
+	stale, err = Stale(ci, changes)
+	require.NoError(t, err)
+	assert.True(t, stale)
+}
+
+func TestSubmitNotifiesOwner(t *testing.T) {
+	service, _, notifier, ci := newTestService()
+
+	suggestion, err := service.Submit(context.Background(), viewer, ci.ID, SubmitRequest{
+		Changes: map[string]interface{}{"location": "dc-2"},
+		Reason:  " moved last week ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, suggestion.Status)
+	assert.Equal(t, "db-01", suggestion.CIName)
+	assert.Equal(t, "moved last week", suggestion.Reason)
+
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, EventSubmitted, notifier.notifications[0].Event)
+	assert.Equal(t, []string{"alice"}, notifier.notifications[0].Recipients)
+}
+
+func TestSubmitHidesInvisibleCIs(t *testing.T) {
+	service, _, _, ci := newTestService()
+
+	_, err := service.Submit(context.Background(), tagged, ci.ID, SubmitRequest{Changes: map[string]interface{}{"owner": "bob"}})
+	assert.ErrorIs(t, err, ErrCINotFound)
+
+	_, err = service.Submit(context.Background(), Actor{Roles: []string{"viewer"}}, ci.ID, SubmitRequest{Changes: map[string]interface{}{"owner": "bob"}})
+	assert.ErrorIs(t, err, ErrNotAuthorized)
+}
+
+func TestListScopesNonEditorsToTheirOwnSuggestions(t *testing.T) {
+	service, _, _, ci := newTestService()
+	ctx := context.Background()
+
+	_, err := service.Submit(ctx, viewer, ci.ID, SubmitRequest{Changes: map[string]interface{}{"owner": "bob"}})
+	require.NoError(t, err)
+	other := Actor{ID: "other", Roles: []string{"viewer"}}
+	_, err = service.Submit(ctx, other, ci.ID, SubmitRequest{Changes: map[string]interface{}{"owner": "carol"}})
+	require.NoError(t, err)
+
+	own, err := service.List(ctx, viewer, Filter{})
+	require.NoError(t, err)
+	require.Len(t, own, 1)
+	assert.Equal(t, "viewer", own[0].SuggestedBy)
+
+	queue, err := service.List(ctx, editor, Filter{Status: StatusPending})
+	require.NoError(t, err)
+	assert.Len(t, queue, 2)
+
+	_, err = service.Get(ctx, other, own[0].ID)
+	assert.ErrorIs(t, err, ErrSuggestionNotFound)
+}
+
+func TestAcceptAppliesChangesAndNotifiesSuggester(t *testing.T) {
+	service, store, notifier, ci := newTestService()
+	ctx := context.Background()
+
+	submitted, err := service.Submit(ctx, viewer, ci.ID, SubmitRequest{Changes: map[string]interface{}{
+		"owner":           "bob",
+		"attributes.rack": "r7",
+	}})
+	require.NoError(t, err)
+
+	_, err = service.Accept(ctx, viewer, submitted.ID, "")
+	assert.ErrorIs(t, err, ErrNotAuthorized)
+
+	accepted, err := service.Accept(ctx, editor, submitted.ID, "confirmed")
+	require.NoError(t, err)
+	assert.Equal(t, StatusAccepted, accepted.Status)
+	assert.Equal(t, "editor", accepted.ReviewedBy)
+	assert.Equal(t, "confirmed", accepted.ReviewNote)
+
+	assert.Equal(t, "bob", store.cis[ci.ID].Owner)
+	assert.JSONEq(t, `{"rack":"r7","cores":8}`, string(store.cis[ci.ID].Attributes))
+
+	last := notifier.notifications[len(notifier.notifications)-1]
+	assert.Equal(t, EventAccepted, last.Event)
+	assert.Equal(t, []string{"viewer"}, last.Recipients)
+
+	_, err = service.Reject(ctx, editor, submitted.ID, "")
+	assert.ErrorIs(t, err, ErrNotPending)
+}
+
+func TestAcceptRejectsStaleSuggestions(t *testing.T) {
+	service, store, _, ci := newTestService()
+	ctx := context.Background()
+
+	submitted, err := service.Submit(ctx, viewer, ci.ID, SubmitRequest{Changes: map[string]interface{}{"owner": "bob"}})
+	require.NoError(t, err)
+
+	store.cis[ci.ID].Owner = "carol"
+
+	_, err = service.Accept(ctx, editor, submitted.ID, "")
+	assert.ErrorIs(t, err, ErrStale)
+	assert.Equal(t, "carol", store.cis[ci.ID].Owner)
+	assert.Equal(t, StatusPending, store.suggestions[submitted.ID].Status)
+}
+
+func TestRejectAndWithdraw(t *testing.T) {
+	service, store, notifier, ci := newTestService()
+	ctx := context.Background()
+
+	first, err := service.Submit(ctx, viewer, ci.ID, SubmitRequest{Changes: map[string]interface{}{"owner": "bob"}})
+	require.NoError(t, err)
+	second, err := service.Submit(ctx, viewer, ci.ID, SubmitRequest{Changes: map[string]interface{}{"location": "dc-9"}})
+	require.NoError(t, err)
+
+	rejected, err := service.Reject(ctx, editor, first.ID, "owner is correct")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRejected, rejected.Status)
+	assert.Equal(t, "alice", store.cis[ci.ID].Owner)
+	assert.Equal(t, EventRejected, notifier.notifications[len(notifier.notifications)-1].Event)
+
+	_, err = service.Withdraw(ctx, editor, second.ID)
+	assert.ErrorIs(t, err, ErrNotAuthorized)
+
+	withdrawn, err := service.Withdraw(ctx, viewer, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusWithdrawn, withdrawn.Status)
+}
@@ -639,13 +639,18 @@ func (cr *ConflictResolver) mergeRelationshipData(ctx context.Context, conflict
 	return cr.updatePostgresWithNeo4jRelationshipData(ctx, &Conflict{Neo4jData: mergedData})
 }
 
-// getTimestampFromData extracts timestamp from data map
+// getTimestampFromData extracts timestamp from data map. Data read back from
+// stored conflicts holds timestamps as RFC 3339 strings.
 func (cr *ConflictResolver) getTimestampFromData(data map[string]interface{}) time.Time {
-	if updatedAt, ok := data["updated_at"].(time.Time); ok {
-		return updatedAt
-	}
-	if createdAt, ok := data["created_at"].(time.Time); ok {
-		return createdAt
+	for _, key := range []string{"updated_at", "created_at"} {
+		switch value := data[key].(type) {
+		case time.Time:
+			return value
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				return t
+			}
+		}
 	}
 	return time.Time{} // Zero time if no timestamp found
 }
@@ -739,3 +744,44 @@ func (cr *ConflictResolver) MarkConflictResolved(ctx context.Context, conflictID
 
 	return err
 }
+
+// ResolveConflict resolves a stored conflict with a strategy and marks it
+// resolved, as conflict escalation does with the timestamp strategy for
+// conflicts left unresolved too long. Resolved conflicts are left as they are.
+func (cr *ConflictResolver) ResolveConflict(ctx context.Context, conflictID string, strategy ConflictResolution, resolvedBy string) error {
+	conflict := &Conflict{}
+	var postgresJSON, neo4jJSON []byte
+	err := cr.dbManager.Postgres.QueryRow(ctx, `
+		SELECT id, entity_type, entity_id, conflict_type, postgres_data, neo4j_data, resolved
+		FROM sync_conflicts
+		WHERE id = $1
+	`, conflictID).Scan(&conflict.ID, &conflict.EntityType, &conflict.EntityID, &conflict.ConflictType,
+		&postgresJSON, &neo4jJSON, &conflict.Resolved)
+	if err != nil {
+		return fmt.Errorf("failed to get conflict %s: %w", conflictID, err)
+	}
+	if conflict.Resolved {
+		return nil
+	}
+
+	if err := json.Unmarshal(postgresJSON, &conflict.PostgresData); err != nil {
+		return fmt.Errorf("failed to decode PostgreSQL data of conflict %s: %w", conflictID, err)
+	}
+	if err := json.Unmarshal(neo4jJSON, &conflict.Neo4jData); err != nil {
+		return fmt.Errorf("failed to decode Neo4j data of conflict %s: %w", conflictID, err)
+	}
+
+	conflict.Resolution = strategy
+	if err := cr.resolveConflict(ctx, conflict); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = cr.dbManager.Postgres.Exec(ctx, `
+		UPDATE sync_conflicts 
+		SET resolution = $1, resolved = true, resolved_by = $2, resolved_at = $3, updated_at = $3
+		WHERE id = $4
+	`, strategy, resolvedBy, now, conflictID)
+
+	return err
+}
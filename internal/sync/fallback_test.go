@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"connect/internal/config"
+	"connect/internal/testfixtures"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -827,7 +828,7 @@ func setupTestInfrastructure(t *testing.T, ctx context.Context) (*postgres.Postg
 		postgres.WithDatabase("testdb"),
 		postgres.WithUsername("testuser"),
 		postgres.WithPassword("testpass"),
-		postgres.WithInitScripts("../../migrations/001_initial_schema.sql", "../../migrations/002_session_management.sql", "../../migrations/003_sync_triggers.sql"),
+		postgres.WithInitScripts(testfixtures.InitScripts(t, 3)...),
 	)
 	require.NoError(t, err)
 
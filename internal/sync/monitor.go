@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"connect/internal/alertrule"
 	"connect/internal/database"
+	"connect/internal/syncoverview"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,6 +18,7 @@ type Monitor struct {
 	syncService *SyncService
 	resolver    *ConflictResolver
 	logger      *log.Logger
+	alertRules  *alertrule.Service
 }
 
 // SyncHealth represents the health status of the synchronization system
@@ -110,6 +113,11 @@ func NewMonitor(dbManager *database.Manager, syncService *SyncService, resolver
 	}
 }
 
+// SetAlertRules evaluates the operators' alert rules on every health check
+func (m *Monitor) SetAlertRules(service *alertrule.Service) {
+	m.alertRules = service
+}
+
 // CheckHealth performs a comprehensive health check of the synchronization system
 func (m *Monitor) CheckHealth(ctx context.Context) (*SyncHealth, error) {
 	health := &SyncHealth{
@@ -135,6 +143,32 @@ func (m *Monitor) CheckHealth(ctx context.Context) (*SyncHealth, error) {
 	return health, nil
 }
 
+// CheckComponents checks only the database connections, for the sync overview
+func (m *Monitor) CheckComponents(ctx context.Context) ([]syncoverview.Component, error) {
+	health := &SyncHealth{Issues: make([]string, 0)}
+	m.checkDatabaseConnections(ctx, health)
+
+	return []syncoverview.Component{
+		overviewComponent("postgres", health.PostgresStatus.Connected, health.PostgresStatus.ResponseTime, health.PostgresStatus.Error),
+		overviewComponent("neo4j", health.Neo4jStatus.Connected, health.Neo4jStatus.ResponseTime, health.Neo4jStatus.Error),
+		overviewComponent("redis", health.RedisStatus.Connected, health.RedisStatus.ResponseTime, health.RedisStatus.Error),
+	}, nil
+}
+
+// overviewComponent converts a connection status to a sync overview component
+func overviewComponent(name string, connected bool, responseTime int64, errMsg string) syncoverview.Component {
+	status := syncoverview.ComponentUp
+	if !connected {
+		status = syncoverview.ComponentDown
+	}
+	return syncoverview.Component{
+		Name:           name,
+		Status:         status,
+		ResponseTimeMs: responseTime,
+		Error:          errMsg,
+	}
+}
+
 // checkDatabaseConnections checks the status of database connections
 func (m *Monitor) checkDatabaseConnections(ctx context.Context, health *SyncHealth) {
 	// Check PostgreSQL
@@ -568,6 +602,8 @@ func (m *Monitor) StartMonitoring(ctx context.Context) {
 
 // performHealthCheck performs a health check and generates alerts if needed
 func (m *Monitor) performHealthCheck(ctx context.Context) {
+	m.evaluateAlertRules(ctx)
+
 	health, err := m.CheckHealth(ctx)
 	if err != nil {
 		m.logger.Error().Err(err).Msg("Failed to perform health check")
@@ -606,6 +642,24 @@ func (m *Monitor) performHealthCheck(ctx context.Context) {
 	}
 }
 
+// evaluateAlertRules evaluates the alert rules, which notify about the rules
+// that fire or resolve themselves
+func (m *Monitor) evaluateAlertRules(ctx context.Context) {
+	if m.alertRules == nil {
+		return
+	}
+	alerts, err := m.alertRules.Evaluate(ctx)
+	if err != nil {
+		if err != alertrule.ErrEvaluationRunning {
+			m.logger.Error().Err(err).Msg("Failed to evaluate alert rules")
+		}
+		return
+	}
+	if len(alerts) > 0 {
+		m.logger.Info().Int("alerts", len(alerts)).Msg("Alert rules fired or resolved")
+	}
+}
+
 // CleanupExpiredAlerts cleans up expired alerts
 func (m *Monitor) CleanupExpiredAlerts(ctx context.Context) error {
 	_, err := m.dbManager.Postgres.Exec(ctx, `
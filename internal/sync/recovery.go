@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"connect/internal/faultinject"
+)
+
+// DefaultRecoveryTimeout is how long an event or fallback operation may stay in
+// processing before startup recovery considers its worker dead
+const DefaultRecoveryTimeout = 10 * time.Minute
+
+// RecoveryReport describes the work startup recovery returned to the queue
+type RecoveryReport struct {
+	RecoveredEvents     int64         `json:"recovered_events"`
+	RecoveredOperations int64         `json:"recovered_operations"`
+	FullResyncReset     bool          `json:"full_resync_reset"`
+	StaleAfter          time.Duration `json:"stale_after"`
+	RecoveredAt         time.Time     `json:"recovered_at"`
+}
+
+// RecoverInFlight returns events and fallback operations left in processing by
+// a crashed worker to the queue. Only work untouched for longer than staleAfter
+// is reset, so that work other instances are still processing is left alone.
+// Each recovered item is recorded in the sync log as an incident, and a full
+// resync marked as running is marked interrupted.
+func (s *SyncService) RecoverInFlight(ctx context.Context, staleAfter time.Duration) (*RecoveryReport, error) {
+	if staleAfter <= 0 {
+		staleAfter = DefaultRecoveryTimeout
+	}
+	if err := s.fault(ctx, faultinject.Postgres); err != nil {
+		return nil, fmt.Errorf("failed to recover in-flight sync work: %w", err)
+	}
+
+	report := &RecoveryReport{StaleAfter: staleAfter, RecoveredAt: time.Now()}
+	message := fmt.Sprintf("Recovered at startup after %s in processing", staleAfter)
+
+	tx, err := s.dbManager.Postgres.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin recovery transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		WITH recovered AS (
+			UPDATE sync_events
+			SET status = 'PENDING', error_message = $2, updated_at = NOW(), processed_at = NULL
+			WHERE status = 'PROCESSING' AND updated_at < NOW() - $1 * INTERVAL '1 second'
+			RETURNING id, entity_type, entity_id, action
+		)
+		INSERT INTO sync_log (event_id, entity_type, entity_id, action, status, error_message)
+		SELECT id, entity_type, entity_id, action, 'RECOVERED', $2 FROM recovered
+	`, staleAfter.Seconds(), message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sync events: %w", err)
+	}
+	report.RecoveredEvents = tag.RowsAffected()
+
+	tag, err = tx.Exec(ctx, `
+		WITH recovered AS (
+			UPDATE sync_fallback_operations
+			SET status = 'pending', error_message = $2, started_at = NULL, completed_at = NULL, updated_at = NOW()
+			WHERE status = 'processing' AND updated_at < NOW() - $1 * INTERVAL '1 second'
+			RETURNING original_event_id, entity_type, entity_id, action, strategy
+		)
+		INSERT INTO sync_fallback_log (event_id, entity_type, entity_id, action, strategy, status, error_message, created_at)
+		SELECT original_event_id, entity_type, entity_id, action, strategy, 'recovered', $2, NOW() FROM recovered
+	`, staleAfter.Seconds(), message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover fallback operations: %w", err)
+	}
+	report.RecoveredOperations = tag.RowsAffected()
+
+	// A full resync cannot still be running once its operation was recovered,
+	// nor when no processing full resync operation is left
+	tag, err = tx.Exec(ctx, `
+		UPDATE sync_full_resync_status
+		SET in_progress = false, last_resync_status = 'interrupted', last_updated = NOW()
+		WHERE in_progress AND NOT EXISTS (
+			SELECT 1 FROM sync_fallback_operations WHERE strategy = 'full_resync' AND status = 'processing'
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset full resync status: %w", err)
+	}
+	report.FullResyncReset = tag.RowsAffected() > 0
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit recovery: %w", err)
+	}
+
+	s.mu.Lock()
+	s.recovery = report
+	s.mu.Unlock()
+
+	if report.RecoveredEvents > 0 || report.RecoveredOperations > 0 || report.FullResyncReset {
+		s.logger.Warn().
+			Int64("recovered_events", report.RecoveredEvents).
+			Int64("recovered_operations", report.RecoveredOperations).
+			Bool("full_resync_reset", report.FullResyncReset).
+			Dur("stale_after", staleAfter).
+			Msg("Recovered in-flight sync work left by a previous run")
+	}
+	return report, nil
+}
+
+// LastRecovery returns the report of the startup recovery, nil before it ran
+func (s *SyncService) LastRecovery() *RecoveryReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recovery
+}
@@ -0,0 +1,200 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connect/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// recoveryTables are the tables startup recovery reads and writes, as the
+// sync service and the sync trigger migration create them
+const recoveryTables = `
+	CREATE TABLE sync_events (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		entity_type VARCHAR(50) NOT NULL,
+		entity_id UUID NOT NULL,
+		action VARCHAR(20) NOT NULL,
+		data JSONB NOT NULL DEFAULT '{}',
+		status VARCHAR(20) DEFAULT 'PENDING',
+		retry_count INTEGER DEFAULT 0,
+		error_message TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		processed_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE TABLE sync_log (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		event_id UUID NOT NULL,
+		entity_type VARCHAR(50) NOT NULL,
+		entity_id UUID NOT NULL,
+		action VARCHAR(20) NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		duration_ms INTEGER,
+		error_message TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE TABLE sync_fallback_operations (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		original_event_id UUID NOT NULL,
+		strategy VARCHAR(30) NOT NULL,
+		entity_type VARCHAR(50) NOT NULL,
+		entity_id UUID NOT NULL,
+		action VARCHAR(20) NOT NULL,
+		data JSONB NOT NULL DEFAULT '{}'::jsonb,
+		retry_count INTEGER DEFAULT 0,
+		status VARCHAR(30) NOT NULL DEFAULT 'pending',
+		error_message TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		started_at TIMESTAMP WITH TIME ZONE,
+		completed_at TIMESTAMP WITH TIME ZONE,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE TABLE sync_fallback_log (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		event_id UUID NOT NULL,
+		entity_type VARCHAR(50) NOT NULL,
+		entity_id UUID NOT NULL,
+		action VARCHAR(20) NOT NULL,
+		strategy VARCHAR(30) NOT NULL,
+		status VARCHAR(30) NOT NULL,
+		error_message TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE TABLE sync_full_resync_status (
+		id SERIAL PRIMARY KEY,
+		in_progress BOOLEAN NOT NULL DEFAULT false,
+		last_updated TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		last_resync_status VARCHAR(50)
+	);
+`
+
+func TestSyncService_RecoverInFlight(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	pgContainer, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:15"),
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		postgres.BasicWaitStrategies(),
+	)
+	require.NoError(t, err)
+	defer pgContainer.Terminate(ctx)
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, recoveryTables)
+	require.NoError(t, err)
+
+	// An event and an operation whose workers died an hour ago, and ones
+	// another instance picked up just now
+	staleEvent, freshEvent, pendingEvent := uuid.New(), uuid.New(), uuid.New()
+	for _, event := range []struct {
+		id     uuid.UUID
+		status string
+		age    string
+	}{
+		{staleEvent, "PROCESSING", "1 hour"},
+		{freshEvent, "PROCESSING", "0 seconds"},
+		{pendingEvent, "PENDING", "1 hour"},
+	} {
+		_, err := pool.Exec(ctx, `
+			INSERT INTO sync_events (id, entity_type, entity_id, action, status, updated_at)
+			VALUES ($1, 'configuration_item', $2, 'UPDATE', $3, NOW() - $4::interval)`,
+			event.id, uuid.New(), event.status, event.age)
+		require.NoError(t, err)
+	}
+	staleOperation, freshOperation := uuid.New(), uuid.New()
+	for _, operation := range []struct {
+		eventID uuid.UUID
+		age     string
+	}{
+		{staleOperation, "1 hour"},
+		{freshOperation, "0 seconds"},
+	} {
+		_, err := pool.Exec(ctx, `
+			INSERT INTO sync_fallback_operations (original_event_id, strategy, entity_type, entity_id, action, status, started_at, updated_at)
+			VALUES ($1, 'retry', 'configuration_item', $2, 'UPDATE', 'processing', NOW() - $3::interval, NOW() - $3::interval)`,
+			operation.eventID, uuid.New(), operation.age)
+		require.NoError(t, err)
+	}
+	_, err = pool.Exec(ctx, `INSERT INTO sync_full_resync_status (in_progress, last_resync_status) VALUES (true, 'running')`)
+	require.NoError(t, err)
+
+	logger := zerolog.Nop()
+	service := &SyncService{dbManager: &database.Manager{Postgres: pool}, logger: &logger}
+	assert.Nil(t, service.LastRecovery())
+
+	report, err := service.RecoverInFlight(ctx, 10*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.RecoveredEvents)
+	assert.Equal(t, int64(1), report.RecoveredOperations)
+	assert.True(t, report.FullResyncReset)
+	assert.Equal(t, report, service.LastRecovery())
+
+	eventStatus := func(id uuid.UUID) string {
+		var status string
+		require.NoError(t, pool.QueryRow(ctx, `SELECT status FROM sync_events WHERE id = $1`, id).Scan(&status))
+		return status
+	}
+	assert.Equal(t, "PENDING", eventStatus(staleEvent))
+	assert.Equal(t, "PROCESSING", eventStatus(freshEvent), "events another instance is processing are left alone")
+	assert.Equal(t, "PENDING", eventStatus(pendingEvent))
+
+	// Each recovered event is logged as an incident
+	rows, err := pool.Query(ctx, `SELECT event_id, status, error_message FROM sync_log`)
+	require.NoError(t, err)
+	var logged []uuid.UUID
+	for rows.Next() {
+		var eventID uuid.UUID
+		var status, message string
+		require.NoError(t, rows.Scan(&eventID, &status, &message))
+		assert.Equal(t, "RECOVERED", status)
+		assert.Contains(t, message, "Recovered at startup")
+		logged = append(logged, eventID)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []uuid.UUID{staleEvent}, logged)
+
+	operationStatus := func(eventID uuid.UUID) string {
+		var status string
+		require.NoError(t, pool.QueryRow(ctx, `SELECT status FROM sync_fallback_operations WHERE original_event_id = $1`, eventID).Scan(&status))
+		return status
+	}
+	assert.Equal(t, "pending", operationStatus(staleOperation))
+	assert.Equal(t, "processing", operationStatus(freshOperation))
+
+	var recoveredOperations int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM sync_fallback_log WHERE status = 'recovered' AND event_id = $1`, staleOperation).Scan(&recoveredOperations))
+	assert.Equal(t, 1, recoveredOperations)
+
+	// The fresh operation is no full resync, so the resync cannot be running
+	var inProgress bool
+	var resyncStatus string
+	require.NoError(t, pool.QueryRow(ctx, `SELECT in_progress, last_resync_status FROM sync_full_resync_status`).Scan(&inProgress, &resyncStatus))
+	assert.False(t, inProgress)
+	assert.Equal(t, "interrupted", resyncStatus)
+
+	// A second run finds nothing left to recover
+	report, err = service.RecoverInFlight(ctx, 10*time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, report.RecoveredEvents)
+	assert.Zero(t, report.RecoveredOperations)
+	assert.False(t, report.FullResyncReset)
+}
@@ -9,7 +9,9 @@ import (
 
 	"connect/internal/config"
 	"connect/internal/database"
+	"connect/internal/faultinject"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,6 +24,30 @@ type SyncService struct {
 	errorChan    chan SyncError
 	stats        *SyncStats
 	logger       *log.Logger
+	exclusions   EventGate
+	faults       FaultInjector
+	projections  []Projection
+	eventSampler zerolog.Sampler
+
+	mu       sync.Mutex
+	recovery *RecoveryReport
+}
+
+// FaultInjector fails calls to Neo4j, Postgres and Redis in reliability tests;
+// see the faultinject package
+type FaultInjector interface {
+	Inject(ctx context.Context, target string) error
+}
+
+// EventGate decides which events are held back from sync instead of processed
+type EventGate interface {
+	Excluded(ctx context.Context, entityType string, data map[string]interface{}) (bool, string)
+}
+
+// Projection is a read model kept up to date from the events sync processed,
+// e.g. the service tree; see the servicetree package
+type Projection interface {
+	Apply(ctx context.Context, entityType, entityID, action string, data map[string]interface{}) error
 }
 
 // SyncEvent represents a synchronization event
@@ -32,7 +58,7 @@ type SyncEvent struct {
 	Action      string                 `json:"action"` // CREATE, UPDATE, DELETE
 	Data        map[string]interface{} `json:"data"`
 	Timestamp   time.Time              `json:"timestamp"`
-	Status      string                 `json:"status"` // PENDING, PROCESSING, COMPLETED, FAILED
+	Status      string                 `json:"status"` // PENDING, PROCESSING, COMPLETED, FAILED, HELD
 	RetryCount  int                    `json:"retry_count"`
 	Error       string                 `json:"error,omitempty"`
 }
@@ -54,6 +80,7 @@ type SyncStats struct {
 	LastSyncTime     time.Time     `json:"last_sync_time"`
 	AverageSyncTime  time.Duration `json:"average_sync_time"`
 	LastError        *SyncError    `json:"last_error,omitempty"`
+	Recovery         *RecoveryReport `json:"recovery,omitempty"` // in-flight work recovered at startup
 }
 
 // SyncConfig represents synchronization configuration
@@ -68,6 +95,7 @@ type SyncConfig struct {
 	EventTTL          time.Duration `yaml:"event_ttl"`
 	CleanupInterval   time.Duration `yaml:"cleanup_interval"`
 	MaxConcurrentSync int          `yaml:"max_concurrent_sync"`
+	RecoveryTimeout   time.Duration `yaml:"recovery_timeout"`
 }
 
 // NewSyncService creates a new synchronization service
@@ -83,6 +111,7 @@ func NewSyncService(cfg *config.Config, dbManager *database.Manager, redisClient
 		EventTTL:          24 * time.Hour,
 		CleanupInterval:   1 * time.Hour,
 		MaxConcurrentSync: 10,
+		RecoveryTimeout:   DefaultRecoveryTimeout,
 	}
 
 	// Override with config if available
@@ -117,6 +146,13 @@ func NewSyncService(cfg *config.Config, dbManager *database.Manager, redisClient
 		if cfg.Sync.MaxConcurrentSync != nil {
 			syncConfig.MaxConcurrentSync = *cfg.Sync.MaxConcurrentSync
 		}
+		if cfg.Sync.RecoveryTimeout != nil {
+			timeout, err := time.ParseDuration(*cfg.Sync.RecoveryTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sync recovery timeout: %w", err)
+			}
+			syncConfig.RecoveryTimeout = timeout
+		}
 	}
 
 	service := &SyncService{
@@ -129,11 +165,30 @@ func NewSyncService(cfg *config.Config, dbManager *database.Manager, redisClient
 		logger:      logger,
 	}
 
+	if cfg.Faults.Enabled {
+		injector, err := faultinject.New(map[string]float64{
+			faultinject.Neo4j:    cfg.Faults.Neo4jTimeout,
+			faultinject.Postgres: cfg.Faults.PostgresSerialization,
+			faultinject.Redis:    cfg.Faults.RedisOutage,
+		}, cfg.Faults.Seed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure fault injection: %w", err)
+		}
+		service.faults = injector
+		logger.Warn().Msg("Sync fault injection is enabled")
+	}
+
 	// Initialize sync tables and procedures
 	if err := service.initializeSyncInfrastructure(); err != nil {
 		return nil, fmt.Errorf("failed to initialize sync infrastructure: %w", err)
 	}
 
+	// Return work a crashed run left in processing to the queue before the
+	// workers start, otherwise it would never be picked up again
+	if _, err := service.RecoverInFlight(context.Background(), syncConfig.RecoveryTimeout); err != nil {
+		logger.Error().Err(err).Msg("Failed to recover in-flight sync work")
+	}
+
 	// Start background workers
 	go service.startEventProcessor()
 	go service.startErrorProcessor()
@@ -148,7 +203,7 @@ func (s *SyncService) initializeSyncInfrastructure() error {
 	ctx := context.Background()
 
 	// Create sync_events table in PostgreSQL
-	_, err := s.dbManager.Postgres.Exec(ctx, `
+	err := s.ensureTable(ctx, "sync_events", `
 		CREATE TABLE IF NOT EXISTS sync_events (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			entity_type VARCHAR(50) NOT NULL,
@@ -163,7 +218,7 @@ func (s *SyncService) initializeSyncInfrastructure() error {
 			processed_at TIMESTAMP WITH TIME ZONE,
 			
 			CONSTRAINT valid_action CHECK (action IN ('CREATE', 'UPDATE', 'DELETE')),
-			CONSTRAINT valid_status CHECK (status IN ('PENDING', 'PROCESSING', 'COMPLETED', 'FAILED'))
+			CONSTRAINT valid_status CHECK (status IN ('PENDING', 'PROCESSING', 'COMPLETED', 'FAILED', 'HELD'))
 		)
 	`)
 	if err != nil {
@@ -182,7 +237,7 @@ func (s *SyncService) initializeSyncInfrastructure() error {
 	}
 
 	// Create sync_stats table
-	_, err = s.dbManager.Postgres.Exec(ctx, `
+	err = s.ensureTable(ctx, "sync_stats", `
 		CREATE TABLE IF NOT EXISTS sync_stats (
 			id SERIAL PRIMARY KEY,
 			total_events BIGINT DEFAULT 0,
@@ -200,7 +255,7 @@ func (s *SyncService) initializeSyncInfrastructure() error {
 	}
 
 	// Create sync_log table for audit trail
-	_, err = s.dbManager.Postgres.Exec(ctx, `
+	err = s.ensureTable(ctx, "sync_log", `
 		CREATE TABLE IF NOT EXISTS sync_log (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			event_id UUID NOT NULL,
@@ -287,6 +342,67 @@ func (s *SyncService) initializeSyncInfrastructure() error {
 	return nil
 }
 
+// ensureTable creates a sync table that migrations should already have created.
+// A missing table is logged as schema drift; in strict schema check mode it is an error instead.
+func (s *SyncService) ensureTable(ctx context.Context, name, ddl string) error {
+	var exists bool
+	if err := s.dbManager.Postgres.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check %s table: %w", name, err)
+	}
+	if exists {
+		return nil
+	}
+
+	if s.config.SchemaCheck.Strict {
+		return fmt.Errorf("table %s is missing; run the migrations before starting the sync service", name)
+	}
+
+	s.logger.Warn().Str("table", name).Msg("Sync table missing, creating it at runtime; the migrations have not been applied")
+	_, err := s.dbManager.Postgres.Exec(ctx, ddl)
+	return err
+}
+
+// SetExclusions holds back the events the gate excludes from now on
+func (s *SyncService) SetExclusions(gate EventGate) {
+	s.exclusions = gate
+}
+
+// SetFaults injects the failures of the injector into the Neo4j, Postgres and
+// Redis calls of sync from now on
+func (s *SyncService) SetFaults(injector FaultInjector) {
+	s.faults = injector
+}
+
+// SetProjections applies every event processed from now on to the projections.
+// An event a projection fails to apply fails, and is retried like any other.
+func (s *SyncService) SetProjections(projections ...Projection) {
+	s.projections = projections
+}
+
+// SetEventLogSampler samples the debug logs written for every event, which
+// busy instances write too many of to keep in full
+func (s *SyncService) SetEventLogSampler(sampler zerolog.Sampler) {
+	s.eventSampler = sampler
+}
+
+// eventDebug starts a debug log entry about a single event, sampled by the
+// event log sampler if one is set
+func (s *SyncService) eventDebug() *zerolog.Event {
+	if s.eventSampler == nil {
+		return s.logger.Debug()
+	}
+	logger := s.logger.Sample(s.eventSampler)
+	return logger.Debug()
+}
+
+// fault returns the failure injected into a call to target, if any
+func (s *SyncService) fault(ctx context.Context, target string) error {
+	if s.faults == nil {
+		return nil
+	}
+	return s.faults.Inject(ctx, target)
+}
+
 // RecordEvent records a synchronization event
 func (s *SyncService) RecordEvent(ctx context.Context, entityType, entityID, action string, data map[string]interface{}) error {
 	event := SyncEvent{
@@ -301,10 +417,13 @@ func (s *SyncService) RecordEvent(ctx context.Context, entityType, entityID, act
 	}
 
 	// Store in PostgreSQL
-	_, err := s.dbManager.Postgres.Exec(ctx, `
+	err := s.fault(ctx, faultinject.Postgres)
+	if err == nil {
+		_, err = s.dbManager.Postgres.Exec(ctx, `
 		INSERT INTO sync_events (id, entity_type, entity_id, action, data, status, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`, event.ID, event.EntityType, event.EntityID, event.Action, event.Data, event.Status, event.Timestamp)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to record sync event: %w", err)
 	}
@@ -315,7 +434,10 @@ func (s *SyncService) RecordEvent(ctx context.Context, entityType, entityID, act
 		return fmt.Errorf("failed to marshal sync event: %w", err)
 	}
 
-	err = s.redisClient.SetWithTTL(ctx, fmt.Sprintf("sync:event:%s", event.ID), string(eventJSON), 24*time.Hour)
+	err = s.fault(ctx, faultinject.Redis)
+	if err == nil {
+		err = s.redisClient.SetWithTTL(ctx, fmt.Sprintf("sync:event:%s", event.ID), string(eventJSON), 24*time.Hour)
+	}
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Failed to store sync event in Redis")
 	}
@@ -327,12 +449,21 @@ func (s *SyncService) RecordEvent(ctx context.Context, entityType, entityID, act
 		s.logger.Warn().Msg("Event channel full, event will be processed by batch processor")
 	}
 
-	s.logger.Debug().Str("event_id", event.ID).Str("entity_type", entityType).Str("action", action).Msg("Sync event recorded")
+	s.eventDebug().Str("event_id", event.ID).Str("entity_type", entityType).Str("action", action).Msg("Sync event recorded")
 	return nil
 }
 
 // ProcessEvent processes a single synchronization event
 func (s *SyncService) ProcessEvent(ctx context.Context, event SyncEvent) error {
+	// Park events of excluded entity types and tenants rather than failing them;
+	// they return to PENDING when the exclusion is lifted
+	if s.exclusions != nil {
+		if excluded, reason := s.exclusions.Excluded(ctx, event.EntityType, event.Data); excluded {
+			s.eventDebug().Str("event_id", event.ID).Str("reason", reason).Msg("Sync event held")
+			return s.updateEventStatus(ctx, event.ID, "HELD", reason)
+		}
+	}
+
 	startTime := time.Now()
 	
 	// Update status to processing
@@ -348,10 +479,17 @@ func (s *SyncService) ProcessEvent(ctx context.Context, event SyncEvent) error {
 		syncErr = s.syncConfigurationItem(ctx, event)
 	case "relationship":
 		syncErr = s.syncRelationship(ctx, event)
+	case "relationship_batch":
+		syncErr = s.syncRelationshipBatch(ctx, event)
 	default:
 		syncErr = fmt.Errorf("unsupported entity type: %s", event.EntityType)
 	}
 
+	// Projections follow the graph, so they are only updated once it is
+	if syncErr == nil {
+		syncErr = s.applyProjections(ctx, event)
+	}
+
 	duration := time.Since(startTime)
 	status := "COMPLETED"
 	errorMsg := ""
@@ -388,7 +526,7 @@ func (s *SyncService) ProcessEvent(ctx context.Context, event SyncEvent) error {
 		return syncErr
 	}
 
-	s.logger.Debug().Str("event_id", event.ID).Str("status", status).Dur("duration", duration).Msg("Event processed")
+	s.eventDebug().Str("event_id", event.ID).Str("status", status).Dur("duration", duration).Msg("Event processed")
 	return nil
 }
 
@@ -412,6 +550,9 @@ func (s *SyncService) syncConfigurationItem(ctx context.Context, event SyncEvent
 	}
 
 	// Call Neo4j procedure
+	if err := s.fault(ctx, faultinject.Neo4j); err != nil {
+		return fmt.Errorf("failed to sync CI to Neo4j: %w", err)
+	}
 	_, err := neo4jSession.Run(ctx, `
 		CALL syncCI($ciId, $ciName, $ciType, $ciAttributes, $ciTags, $action)
 	`, map[string]interface{}{
@@ -442,6 +583,9 @@ func (s *SyncService) syncRelationship(ctx context.Context, event SyncEvent) err
 	relAttributes, _ := event.Data["attributes"].(map[string]interface{})
 
 	// Call Neo4j procedure
+	if err := s.fault(ctx, faultinject.Neo4j); err != nil {
+		return fmt.Errorf("failed to sync relationship to Neo4j: %w", err)
+	}
 	_, err := neo4jSession.Run(ctx, `
 		CALL syncRelationship($relId, $sourceId, $targetId, $relType, $relAttributes, $action)
 	`, map[string]interface{}{
@@ -460,8 +604,98 @@ func (s *SyncService) syncRelationship(ctx context.Context, event SyncEvent) err
 	return nil
 }
 
+// syncRelationshipBatch synchronizes the relationships created and deleted by
+// a bulk request to Neo4j in one transaction, in the order they were applied
+func (s *SyncService) syncRelationshipBatch(ctx context.Context, event SyncEvent) error {
+	events, err := relationshipBatchEvents(event)
+	if err != nil {
+		return err
+	}
+
+	neo4jSession := s.dbManager.Neo4j.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer neo4jSession.Close(ctx)
+
+	if err := s.fault(ctx, faultinject.Neo4j); err != nil {
+		return fmt.Errorf("failed to sync relationship batch to Neo4j: %w", err)
+	}
+	_, err = neo4jSession.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		for _, rel := range events {
+			relAttributes, _ := rel.Data["attributes"].(map[string]interface{})
+			_, err := tx.Run(ctx, `
+				CALL syncRelationship($relId, $sourceId, $targetId, $relType, $relAttributes, $action)
+			`, map[string]interface{}{
+				"relId":         rel.EntityID,
+				"sourceId":      rel.Data["source_id"],
+				"targetId":      rel.Data["target_id"],
+				"relType":       rel.Data["type"],
+				"relAttributes": relAttributes,
+				"action":        rel.Action,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("relationship %s: %w", rel.EntityID, err)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync relationship batch to Neo4j: %w", err)
+	}
+
+	return nil
+}
+
+// relationshipBatchEvents expands a relationship batch event into the
+// relationship events of its operations
+func relationshipBatchEvents(event SyncEvent) ([]SyncEvent, error) {
+	operations, _ := event.Data["operations"].([]interface{})
+	events := make([]SyncEvent, 0, len(operations))
+	for i, operation := range operations {
+		data, ok := operation.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("relationship batch %s: operation %d is not an object", event.EntityID, i)
+		}
+		id, _ := data["id"].(string)
+		action, _ := data["action"].(string)
+		if id == "" || action == "" {
+			return nil, fmt.Errorf("relationship batch %s: operation %d has no id or action", event.EntityID, i)
+		}
+		events = append(events, SyncEvent{
+			ID:         event.ID,
+			EntityType: "relationship",
+			EntityID:   id,
+			Action:     action,
+			Data:       data,
+			Timestamp:  event.Timestamp,
+		})
+	}
+	return events, nil
+}
+
+// applyProjections applies a synced event to the projections. A relationship
+// batch is applied as the relationship events it holds.
+func (s *SyncService) applyProjections(ctx context.Context, event SyncEvent) error {
+	events := []SyncEvent{event}
+	if event.EntityType == "relationship_batch" {
+		var err error
+		if events, err = relationshipBatchEvents(event); err != nil {
+			return err
+		}
+	}
+	for _, projection := range s.projections {
+		for _, e := range events {
+			if err := projection.Apply(ctx, e.EntityType, e.EntityID, e.Action, e.Data); err != nil {
+				return fmt.Errorf("failed to update projection: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
 // updateEventStatus updates the status of a sync event
 func (s *SyncService) updateEventStatus(ctx context.Context, eventID, status, errorMsg string) error {
+	if err := s.fault(ctx, faultinject.Postgres); err != nil {
+		return fmt.Errorf("failed to update event status: %w", err)
+	}
 	_, err := s.dbManager.Postgres.Exec(ctx, `
 		UPDATE sync_events 
 		SET status = $1, error_message = $2, updated_at = NOW(), processed_at = CASE WHEN $1 IN ('COMPLETED', 'FAILED') THEN NOW() ELSE NULL END
@@ -472,6 +706,9 @@ func (s *SyncService) updateEventStatus(ctx context.Context, eventID, status, er
 	}
 
 	// Update Redis cache
+	if s.fault(ctx, faultinject.Redis) != nil {
+		return nil
+	}
 	eventJSON, err := s.redisClient.Get(ctx, fmt.Sprintf("sync:event:%s", eventID))
 	if err == nil {
 		var event SyncEvent
@@ -771,6 +1008,8 @@ func (s *SyncService) getSyncStats(ctx context.Context) (*SyncStats, error) {
 			Timestamp: time.Now(),
 		}
 	}
+
+	stats.Recovery = s.LastRecovery()
 	
 	return stats, nil
 }
@@ -871,6 +1110,19 @@ func (s *SyncService) ForceSync(ctx context.Context, entityType, entityID string
 	return s.RecordEvent(ctx, entityType, entityID, "UPDATE", data)
 }
 
+// ResyncEntity replays the current state of an entity into Neo4j, as resync jobs do.
+// The replay is an UPDATE, so it is safe to repeat.
+func (s *SyncService) ResyncEntity(ctx context.Context, entityType, entityID string, data map[string]interface{}) error {
+	return s.ProcessEvent(ctx, SyncEvent{
+		ID:         generateEventID(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     "UPDATE",
+		Data:       data,
+		Timestamp:  time.Now(),
+	})
+}
+
 // Close gracefully shuts down the sync service
 func (s *SyncService) Close() error {
 	s.logger.Info("Shutting down sync service")
@@ -0,0 +1,75 @@
+// Package syncexclusion keeps entity types or tenants out of sync, e.g. while
+// a schema migration is in progress. Events of an excluded entity type or
+// tenant are parked in the HELD state instead of failing and being retried,
+// and go back to PENDING when the exclusion is lifted.
+package syncexclusion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Exclusion scopes
+const (
+	ScopeEntityType = "entity_type"
+	ScopeTenant     = "tenant"
+)
+
+// Exclusion sources. Exclusions from config can only be lifted in config.
+const (
+	SourceConfig = "config"
+	SourceAPI    = "api"
+)
+
+// TenantAttribute is the CI attribute holding the tenant a CI belongs to
+const TenantAttribute = "tenant"
+
+var (
+	ErrInvalidExclusion  = errors.New("invalid sync exclusion")
+	ErrExclusionNotFound = errors.New("sync exclusion not found")
+	ErrConfigExclusion   = errors.New("sync exclusion is set in config")
+)
+
+// Exclusion keeps the events of an entity type or a tenant out of sync
+type Exclusion struct {
+	Scope     string    `json:"scope" db:"scope"`
+	Value     string    `json:"value" db:"value"`
+	Reason    string    `json:"reason" db:"reason"`
+	Source    string    `json:"source" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	// HeldEvents is the number of events parked by this exclusion
+	HeldEvents int64 `json:"held_events" db:"-"`
+}
+
+// Validate checks the scope and value of the exclusion
+func (e *Exclusion) Validate() error {
+	e.Value = strings.TrimSpace(e.Value)
+	if e.Scope != ScopeEntityType && e.Scope != ScopeTenant {
+		return fmt.Errorf("%w: scope must be %s or %s", ErrInvalidExclusion, ScopeEntityType, ScopeTenant)
+	}
+	if e.Value == "" {
+		return fmt.Errorf("%w: value is required", ErrInvalidExclusion)
+	}
+	if len(e.Value) > 100 {
+		return fmt.Errorf("%w: value exceeds 100 characters", ErrInvalidExclusion)
+	}
+	return nil
+}
+
+// key identifies an exclusion
+func (e *Exclusion) key() string {
+	return e.Scope + ":" + e.Value
+}
+
+// EventTenant returns the tenant of a sync event payload, or "" when it has none
+func EventTenant(data map[string]interface{}) string {
+	if tenant, ok := data[TenantAttribute].(string); ok {
+		return tenant
+	}
+	attributes, _ := data["attributes"].(map[string]interface{})
+	tenant, _ := attributes[TenantAttribute].(string)
+	return tenant
+}
@@ -0,0 +1,180 @@
+package syncexclusion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL bounds how long exclusions are reused before being reloaded,
+// so changes made through another instance apply within that delay
+const DefaultCacheTTL = 10 * time.Second
+
+// Service decides which sync events are held and manages the exclusions
+type Service struct {
+	store    Store
+	config   []*Exclusion
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	excluded map[string]*Exclusion
+	loadedAt time.Time
+}
+
+// NewService creates a new sync exclusion service. The entity types and
+// tenants given are excluded from config and cannot be lifted through the API.
+func NewService(store Store, entityTypes, tenants []string, cacheTTL time.Duration) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+
+	config := []*Exclusion{}
+	for scope, values := range map[string][]string{ScopeEntityType: entityTypes, ScopeTenant: tenants} {
+		for _, value := range values {
+			config = append(config, &Exclusion{Scope: scope, Value: value, Reason: "excluded in config", Source: SourceConfig})
+		}
+	}
+
+	return &Service{store: store, config: config, cacheTTL: cacheTTL, now: time.Now}
+}
+
+// Excluded reports whether an event of the entity type with the payload must
+// be held, and why. If the exclusions cannot be loaded the last known ones apply.
+func (s *Service) Excluded(ctx context.Context, entityType string, data map[string]interface{}) (bool, string) {
+	excluded := s.exclusions(ctx)
+
+	if exclusion, ok := excluded[ScopeEntityType+":"+entityType]; ok {
+		return true, fmt.Sprintf("entity type %s is excluded from sync: %s", entityType, exclusion.Reason)
+	}
+	if tenant := EventTenant(data); tenant != "" {
+		if exclusion, ok := excluded[ScopeTenant+":"+tenant]; ok {
+			return true, fmt.Sprintf("tenant %s is excluded from sync: %s", tenant, exclusion.Reason)
+		}
+	}
+	return false, ""
+}
+
+// List retrieves every exclusion with the number of events it holds
+func (s *Service) List(ctx context.Context) ([]*Exclusion, error) {
+	stored, err := s.store.ListExclusions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := []*Exclusion{}
+	seen := map[string]bool{}
+	for _, exclusion := range append(s.configExclusions(), stored...) {
+		if seen[exclusion.key()] {
+			continue
+		}
+		seen[exclusion.key()] = true
+
+		held, err := s.store.CountHeldEvents(ctx, exclusion.Scope, exclusion.Value)
+		if err != nil {
+			return nil, err
+		}
+		exclusion.HeldEvents = held
+		exclusions = append(exclusions, exclusion)
+	}
+
+	sort.Slice(exclusions, func(i, j int) bool { return exclusions[i].key() < exclusions[j].key() })
+	return exclusions, nil
+}
+
+// Add excludes an entity type or tenant from sync
+func (s *Service) Add(ctx context.Context, exclusion *Exclusion, by string) (*Exclusion, error) {
+	if err := exclusion.Validate(); err != nil {
+		return nil, err
+	}
+
+	exclusion.Source = SourceAPI
+	exclusion.CreatedAt = s.now()
+	exclusion.CreatedBy = by
+	if err := s.store.SaveExclusion(ctx, exclusion); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Sync exclusion %s %s added by %s: %s", exclusion.Scope, exclusion.Value, by, exclusion.Reason)
+	return exclusion, nil
+}
+
+// Remove lifts an exclusion and returns its held events to the queue. Events
+// still matched by another exclusion are held again when processed.
+func (s *Service) Remove(ctx context.Context, scope, value, by string) (int64, error) {
+	for _, exclusion := range s.config {
+		if exclusion.Scope == scope && exclusion.Value == value {
+			return 0, fmt.Errorf("%w: remove %s %s from the sync configuration instead", ErrConfigExclusion, scope, value)
+		}
+	}
+
+	if err := s.store.DeleteExclusion(ctx, scope, value); err != nil {
+		return 0, err
+	}
+	s.invalidate()
+
+	released, err := s.store.ReleaseEvents(ctx, scope, value)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Sync exclusion %s %s removed by %s, %d held events released", scope, value, by, released)
+	return released, nil
+}
+
+// exclusions returns the exclusions by key, reloading them once the cache expires
+func (s *Service) exclusions(ctx context.Context) map[string]*Exclusion {
+	s.mu.Lock()
+	excluded, loadedAt := s.excluded, s.loadedAt
+	s.mu.Unlock()
+
+	if excluded != nil && s.now().Sub(loadedAt) < s.cacheTTL {
+		return excluded
+	}
+
+	stored, err := s.store.ListExclusions(ctx)
+	if err != nil {
+		log.Printf("Failed to load sync exclusions, keeping the last known ones: %v", err)
+		if excluded == nil {
+			excluded = s.byKey(nil)
+		}
+		return excluded
+	}
+
+	excluded = s.byKey(stored)
+	s.mu.Lock()
+	s.excluded = excluded
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+	return excluded
+}
+
+// byKey indexes the config exclusions and the stored ones
+func (s *Service) byKey(stored []*Exclusion) map[string]*Exclusion {
+	excluded := map[string]*Exclusion{}
+	for _, exclusion := range append(stored, s.config...) {
+		excluded[exclusion.key()] = exclusion
+	}
+	return excluded
+}
+
+// configExclusions returns copies of the exclusions set in config
+func (s *Service) configExclusions() []*Exclusion {
+	exclusions := make([]*Exclusion, len(s.config))
+	for i, exclusion := range s.config {
+		copied := *exclusion
+		exclusions[i] = &copied
+	}
+	return exclusions
+}
+
+// invalidate drops the cached exclusions so the next event reloads them
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.excluded = nil
+	s.mu.Unlock()
+}
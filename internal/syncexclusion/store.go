@@ -0,0 +1,99 @@
+package syncexclusion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Store persists exclusions and moves events in and out of the holding state
+type Store interface {
+	ListExclusions(ctx context.Context) ([]*Exclusion, error)
+	SaveExclusion(ctx context.Context, exclusion *Exclusion) error
+	DeleteExclusion(ctx context.Context, scope, value string) error
+	// CountHeldEvents returns the number of held events an exclusion matches
+	CountHeldEvents(ctx context.Context, scope, value string) (int64, error)
+	// ReleaseEvents moves the held events an exclusion matches back to pending
+	ReleaseEvents(ctx context.Context, scope, value string) (int64, error)
+}
+
+// PostgresStore keeps exclusions in the sync_exclusions table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed exclusion store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ListExclusions retrieves every exclusion stored through the API
+func (s *PostgresStore) ListExclusions(ctx context.Context) ([]*Exclusion, error) {
+	exclusions := []*Exclusion{}
+	err := s.db.SelectContext(ctx, &exclusions, `
+		SELECT scope, value, reason, created_at, created_by
+		FROM sync_exclusions
+		ORDER BY scope, value`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync exclusions: %w", err)
+	}
+	for _, exclusion := range exclusions {
+		exclusion.Source = SourceAPI
+	}
+	return exclusions, nil
+}
+
+// SaveExclusion inserts or replaces an exclusion
+func (s *PostgresStore) SaveExclusion(ctx context.Context, exclusion *Exclusion) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sync_exclusions (scope, value, reason, created_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (scope, value) DO UPDATE SET reason = EXCLUDED.reason`,
+		exclusion.Scope, exclusion.Value, exclusion.Reason, exclusion.CreatedAt, exclusion.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to save sync exclusion: %w", err)
+	}
+	return nil
+}
+
+// DeleteExclusion removes an exclusion
+func (s *PostgresStore) DeleteExclusion(ctx context.Context, scope, value string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM sync_exclusions WHERE scope = $1 AND value = $2`, scope, value)
+	if err != nil {
+		return fmt.Errorf("failed to delete sync exclusion: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrExclusionNotFound
+	}
+	return nil
+}
+
+// heldEventsWhere selects the held events of an exclusion; $1 is the scope and $2 the value
+const heldEventsWhere = `
+	status = 'HELD' AND (
+		($1 = 'entity_type' AND entity_type = $2) OR
+		($1 = 'tenant' AND COALESCE(data->>'tenant', data->'attributes'->>'tenant') = $2)
+	)`
+
+// CountHeldEvents counts the held events of an exclusion
+func (s *PostgresStore) CountHeldEvents(ctx context.Context, scope, value string) (int64, error) {
+	var count int64
+	if err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM sync_events WHERE`+heldEventsWhere, scope, value); err != nil {
+		return 0, fmt.Errorf("failed to count held sync events: %w", err)
+	}
+	return count, nil
+}
+
+// ReleaseEvents returns the held events of an exclusion to the queue
+func (s *PostgresStore) ReleaseEvents(ctx context.Context, scope, value string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE sync_events
+		SET status = 'PENDING', error_message = NULL, updated_at = NOW()
+		WHERE`+heldEventsWhere, scope, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release held sync events: %w", err)
+	}
+	released, _ := result.RowsAffected()
+	return released, nil
+}
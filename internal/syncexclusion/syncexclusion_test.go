@@ -0,0 +1,168 @@
+package syncexclusion
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heldEvent is a parked sync event
+type heldEvent struct {
+	entityType string
+	tenant     string
+}
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	mu         sync.Mutex
+	exclusions map[string]*Exclusion
+	held       []heldEvent
+	reads      int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{exclusions: map[string]*Exclusion{}}
+}
+
+func (m *memoryStore) ListExclusions(ctx context.Context) ([]*Exclusion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	exclusions := []*Exclusion{}
+	for _, exclusion := range m.exclusions {
+		copied := *exclusion
+		exclusions = append(exclusions, &copied)
+	}
+	return exclusions, nil
+}
+
+func (m *memoryStore) SaveExclusion(ctx context.Context, exclusion *Exclusion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *exclusion
+	m.exclusions[exclusion.key()] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteExclusion(ctx context.Context, scope, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := scope + ":" + value
+	if _, ok := m.exclusions[key]; !ok {
+		return ErrExclusionNotFound
+	}
+	delete(m.exclusions, key)
+	return nil
+}
+
+func (m *memoryStore) matches(event heldEvent, scope, value string) bool {
+	return (scope == ScopeEntityType && event.entityType == value) || (scope == ScopeTenant && event.tenant == value)
+}
+
+func (m *memoryStore) CountHeldEvents(ctx context.Context, scope, value string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var count int64
+	for _, event := range m.held {
+		if m.matches(event, scope, value) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryStore) ReleaseEvents(ctx context.Context, scope, value string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	remaining := []heldEvent{}
+	for _, event := range m.held {
+		if !m.matches(event, scope, value) {
+			remaining = append(remaining, event)
+		}
+	}
+	released := int64(len(m.held) - len(remaining))
+	m.held = remaining
+	return released, nil
+}
+
+func ciData(tenant string) map[string]interface{} {
+	return map[string]interface{}{"name": "web-01", "attributes": map[string]interface{}{"tenant": tenant}}
+}
+
+func TestService_Excluded(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, []string{"relationship"}, nil, time.Hour)
+	ctx := context.Background()
+
+	excluded, reason := service.Excluded(ctx, "relationship", nil)
+	assert.True(t, excluded)
+	assert.Contains(t, reason, "entity type relationship")
+
+	excluded, _ = service.Excluded(ctx, "configuration_item", ciData("acme"))
+	assert.False(t, excluded)
+
+	_, err := service.Add(ctx, &Exclusion{Scope: ScopeTenant, Value: "acme", Reason: "schema migration"}, "admin")
+	require.NoError(t, err)
+
+	excluded, reason = service.Excluded(ctx, "configuration_item", ciData("acme"))
+	assert.True(t, excluded)
+	assert.Equal(t, "tenant acme is excluded from sync: schema migration", reason)
+
+	excluded, _ = service.Excluded(ctx, "configuration_item", ciData("globex"))
+	assert.False(t, excluded)
+
+	// Exclusions are cached between events
+	assert.Equal(t, 2, store.reads)
+}
+
+func TestService_Remove(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store, []string{"relationship"}, nil, time.Hour)
+	ctx := context.Background()
+
+	_, err := service.Add(ctx, &Exclusion{Scope: ScopeTenant, Value: "acme"}, "admin")
+	require.NoError(t, err)
+	store.held = []heldEvent{{"configuration_item", "acme"}, {"configuration_item", "acme"}, {"relationship", ""}}
+
+	exclusions, err := service.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, exclusions, 2)
+	assert.Equal(t, SourceConfig, exclusions[0].Source)
+	assert.Equal(t, int64(1), exclusions[0].HeldEvents)
+	assert.Equal(t, SourceAPI, exclusions[1].Source)
+	assert.Equal(t, int64(2), exclusions[1].HeldEvents)
+
+	released, err := service.Remove(ctx, ScopeTenant, "acme", "admin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), released)
+
+	excluded, _ := service.Excluded(ctx, "configuration_item", ciData("acme"))
+	assert.False(t, excluded)
+
+	_, err = service.Remove(ctx, ScopeTenant, "acme", "admin")
+	assert.ErrorIs(t, err, ErrExclusionNotFound)
+
+	_, err = service.Remove(ctx, ScopeEntityType, "relationship", "admin")
+	assert.ErrorIs(t, err, ErrConfigExclusion)
+}
+
+func TestService_AddRejectsInvalid(t *testing.T) {
+	service := NewService(newMemoryStore(), nil, nil, 0)
+
+	_, err := service.Add(context.Background(), &Exclusion{Scope: "ci_type", Value: "server"}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidExclusion)
+
+	_, err = service.Add(context.Background(), &Exclusion{Scope: ScopeTenant, Value: "  "}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidExclusion)
+}
+
+func TestEventTenant(t *testing.T) {
+	assert.Equal(t, "acme", EventTenant(map[string]interface{}{"tenant": "acme"}))
+	assert.Equal(t, "acme", EventTenant(ciData("acme")))
+	assert.Equal(t, "", EventTenant(map[string]interface{}{"attributes": "invalid"}))
+	assert.Equal(t, "", EventTenant(nil))
+}
@@ -0,0 +1,304 @@
+// Package syncoverview aggregates the sync statistics, fallback operations,
+// unresolved conflicts, component health and queue depths into the single
+// payload polled by the operations dashboard. Sections are read concurrently
+// and a section that cannot be read is reported as unavailable rather than
+// failing the whole overview. The result is cached briefly, so any number of
+// dashboards polling at once cost one set of queries.
+package syncoverview
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Overall statuses
+const (
+	StatusHealthy  = "healthy"
+	StatusDegraded = "degraded"
+	StatusCritical = "critical"
+)
+
+// Component statuses
+const (
+	ComponentUp   = "up"
+	ComponentDown = "down"
+)
+
+// Section names, as reported in Overview.Unavailable
+const (
+	SectionStats      = "stats"
+	SectionFallback   = "fallback"
+	SectionConflicts  = "conflicts"
+	SectionComponents = "components"
+	SectionQueues     = "queues"
+)
+
+// Defaults
+const (
+	DefaultCacheTTL = 10 * time.Second
+	DefaultTimeout  = 5 * time.Second
+)
+
+// Thresholds over which an issue is raised, matching the sync monitor
+const (
+	MaxPendingEvents       = 1000
+	MaxFailedEvents        = 100
+	MaxUnresolvedConflicts = 50
+)
+
+// Overview is the aggregate sync payload. Sections that could not be read are
+// left empty and named in Unavailable.
+type Overview struct {
+	Status      string      `json:"status"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Stats       *Stats      `json:"stats,omitempty"`
+	Fallback    *Fallback   `json:"fallback,omitempty"`
+	Conflicts   *Conflicts  `json:"conflicts,omitempty"`
+	Components  []Component `json:"components"`
+	Queues      []Queue     `json:"queues"`
+	Issues      []string    `json:"issues"`
+	// Unavailable maps the sections that could not be read to the reason
+	Unavailable map[string]string `json:"unavailable,omitempty"`
+}
+
+// Stats summarises the sync events, as SyncStats does
+type Stats struct {
+	TotalEvents       int64      `json:"total_events"`
+	SuccessfulEvents  int64      `json:"successful_events"`
+	FailedEvents      int64      `json:"failed_events"`
+	PendingEvents     int64      `json:"pending_events"`
+	LastSyncTime      *time.Time `json:"last_sync_time,omitempty"`
+	AverageSyncTimeMs float64    `json:"average_sync_time_ms"`
+	// ErrorRate is the percentage of the events of the last hour that failed
+	ErrorRate float64 `json:"error_rate_percent"`
+	LastError string  `json:"last_error,omitempty"`
+}
+
+// Fallback summarises the fallback operations, as FallbackReport does
+type Fallback struct {
+	TotalOperations     int64            `json:"total_operations"`
+	PendingOperations   int64            `json:"pending_operations"`
+	CompletedOperations int64            `json:"completed_operations"`
+	FailedOperations    int64            `json:"failed_operations"`
+	SuccessRate         float64          `json:"success_rate_percent"`
+	ByStrategy          map[string]int64 `json:"by_strategy"`
+}
+
+// Conflicts counts the sync conflicts
+type Conflicts struct {
+	Unresolved       int64            `json:"unresolved"`
+	Total            int64            `json:"total"`
+	ResolutionRate   float64          `json:"resolution_rate_percent"`
+	UnresolvedByType map[string]int64 `json:"unresolved_by_type"`
+	OldestUnresolved *time.Time       `json:"oldest_unresolved,omitempty"`
+}
+
+// Component is the health of a store the sync depends on
+type Component struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Queue is the depth of a work queue
+type Queue struct {
+	Name       string `json:"name"`
+	Pending    int64  `json:"pending"`
+	Processing int64  `json:"processing"`
+	Failed     int64  `json:"failed"`
+	// OldestPendingSeconds is the age of the oldest pending item
+	OldestPendingSeconds float64 `json:"oldest_pending_seconds"`
+}
+
+// HealthChecker reports the health of the stores the sync depends on, as the
+// sync monitor does
+type HealthChecker interface {
+	CheckComponents(ctx context.Context) ([]Component, error)
+}
+
+// Service builds and caches the overview
+type Service struct {
+	store    Store
+	health   HealthChecker
+	cacheTTL time.Duration
+	timeout  time.Duration
+	now      func() time.Time
+
+	// loading serialises builds, so concurrent requests on a stale cache
+	// wait for one build instead of each querying
+	loading sync.Mutex
+
+	mu       sync.Mutex
+	cached   *Overview
+	cachedAt time.Time
+}
+
+// NewService creates a new overview service; health may be nil when the sync
+// monitor is not running, in which case components are reported unavailable
+func NewService(store Store, health HealthChecker, cacheTTL time.Duration) *Service {
+	if cacheTTL < 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	return &Service{
+		store:    store,
+		health:   health,
+		cacheTTL: cacheTTL,
+		timeout:  DefaultTimeout,
+		now:      time.Now,
+	}
+}
+
+// Get returns the overview, built at most cacheTTL ago unless refresh is set
+func (s *Service) Get(ctx context.Context, refresh bool) *Overview {
+	if !refresh {
+		if overview := s.fresh(); overview != nil {
+			return overview
+		}
+	}
+
+	s.loading.Lock()
+	defer s.loading.Unlock()
+	// Another request may have built it while this one waited
+	if !refresh {
+		if overview := s.fresh(); overview != nil {
+			return overview
+		}
+	}
+
+	overview := s.build(ctx)
+	s.mu.Lock()
+	s.cached = overview
+	s.cachedAt = s.now()
+	s.mu.Unlock()
+	return overview
+}
+
+// fresh returns the cached overview if it has not expired
+func (s *Service) fresh() *Overview {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached == nil || s.now().Sub(s.cachedAt) >= s.cacheTTL {
+		return nil
+	}
+	return s.cached
+}
+
+// build reads every section concurrently and derives the overall status
+func (s *Service) build(ctx context.Context) *Overview {
+	// The overview is shared through the cache, so a client going away must
+	// not leave an overview of cancelled reads behind
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+	defer cancel()
+
+	overview := &Overview{
+		GeneratedAt: s.now(),
+		Components:  []Component{},
+		Queues:      []Queue{},
+		Issues:      []string{},
+	}
+
+	var mu sync.Mutex
+	unavailable := map[string]string{}
+	var wg sync.WaitGroup
+	read := func(section string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				unavailable[section] = err.Error()
+				mu.Unlock()
+			}
+		}()
+	}
+
+	read(SectionStats, func() (err error) {
+		overview.Stats, err = s.store.Stats(ctx)
+		return err
+	})
+	read(SectionFallback, func() (err error) {
+		overview.Fallback, err = s.store.Fallback(ctx)
+		return err
+	})
+	read(SectionConflicts, func() (err error) {
+		overview.Conflicts, err = s.store.Conflicts(ctx)
+		return err
+	})
+	read(SectionQueues, func() error {
+		queues, err := s.store.Queues(ctx)
+		if err == nil {
+			overview.Queues = queues
+		}
+		return err
+	})
+	read(SectionComponents, func() error {
+		if s.health == nil {
+			return fmt.Errorf("sync monitor is not running")
+		}
+		components, err := s.health.CheckComponents(ctx)
+		if err == nil {
+			overview.Components = components
+		}
+		return err
+	})
+	wg.Wait()
+
+	if len(unavailable) > 0 {
+		overview.Unavailable = unavailable
+	}
+	overview.Issues = issues(overview)
+	overview.Status = status(overview)
+	return overview
+}
+
+// issues lists what an operator should look at, in a stable order
+func issues(o *Overview) []string {
+	issues := []string{}
+	for _, c := range o.Components {
+		if c.Status == ComponentDown {
+			issues = append(issues, fmt.Sprintf("%s is down: %s", c.Name, c.Error))
+		}
+	}
+	if o.Stats != nil {
+		if o.Stats.PendingEvents > MaxPendingEvents {
+			issues = append(issues, fmt.Sprintf("High number of pending events: %d", o.Stats.PendingEvents))
+		}
+		if o.Stats.FailedEvents > MaxFailedEvents {
+			issues = append(issues, fmt.Sprintf("High number of failed events: %d", o.Stats.FailedEvents))
+		}
+	}
+	if o.Conflicts != nil && o.Conflicts.Unresolved > MaxUnresolvedConflicts {
+		issues = append(issues, fmt.Sprintf("High number of unresolved conflicts: %d", o.Conflicts.Unresolved))
+	}
+	if o.Fallback != nil && o.Fallback.FailedOperations > 0 {
+		issues = append(issues, fmt.Sprintf("Failed fallback operations: %d", o.Fallback.FailedOperations))
+	}
+
+	sections := make([]string, 0, len(o.Unavailable))
+	for section := range o.Unavailable {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		issues = append(issues, fmt.Sprintf("Failed to read %s: %s", section, o.Unavailable[section]))
+	}
+	return issues
+}
+
+// status is critical when a component is down, degraded when there is any
+// other issue and healthy otherwise
+func status(o *Overview) string {
+	for _, c := range o.Components {
+		if c.Status == ComponentDown {
+			return StatusCritical
+		}
+	}
+	if len(o.Issues) > 0 {
+		return StatusDegraded
+	}
+	return StatusHealthy
+}
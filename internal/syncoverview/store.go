@@ -0,0 +1,181 @@
+package syncoverview
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Store reads the overview sections
+type Store interface {
+	Stats(ctx context.Context) (*Stats, error)
+	Fallback(ctx context.Context) (*Fallback, error)
+	Conflicts(ctx context.Context) (*Conflicts, error)
+	Queues(ctx context.Context) ([]Queue, error)
+}
+
+// Queue names
+const (
+	QueueSyncEvents         = "sync_events"
+	QueueFallbackOperations = "fallback_operations"
+)
+
+// PostgresStore reads the sections from the sync_events, sync_fallback_operations
+// and sync_conflicts tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed overview store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Stats counts the sync events by status
+func (s *PostgresStore) Stats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	var lastSync sql.NullTime
+	var avgSyncTime sql.NullFloat64
+	var lastHour, lastHourFailed int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'COMPLETED'),
+			COUNT(*) FILTER (WHERE status = 'FAILED'),
+			COUNT(*) FILTER (WHERE status = 'PENDING'),
+			MAX(created_at),
+			AVG(EXTRACT(EPOCH FROM (processed_at - created_at)) * 1000) FILTER (WHERE processed_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '1 hour'),
+			COUNT(*) FILTER (WHERE status = 'FAILED' AND created_at > NOW() - INTERVAL '1 hour'),
+			COALESCE((SELECT error_message FROM sync_events
+				WHERE status = 'FAILED' AND error_message IS NOT NULL
+				ORDER BY created_at DESC LIMIT 1), '')
+		FROM sync_events`).Scan(&stats.TotalEvents, &stats.SuccessfulEvents, &stats.FailedEvents, &stats.PendingEvents,
+		&lastSync, &avgSyncTime, &lastHour, &lastHourFailed, &stats.LastError)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync stats: %w", err)
+	}
+	if lastSync.Valid {
+		stats.LastSyncTime = &lastSync.Time
+	}
+	stats.AverageSyncTimeMs = avgSyncTime.Float64
+	stats.ErrorRate = percent(lastHourFailed, lastHour)
+	return &stats, nil
+}
+
+// Fallback counts the fallback operations by status and strategy
+func (s *PostgresStore) Fallback(ctx context.Context) (*Fallback, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT strategy, status, COUNT(*)
+		FROM sync_fallback_operations
+		GROUP BY strategy, status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fallback operations: %w", err)
+	}
+	defer rows.Close()
+
+	fallback := Fallback{ByStrategy: map[string]int64{}}
+	for rows.Next() {
+		var strategy, status string
+		var count int64
+		if err := rows.Scan(&strategy, &status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan fallback operations: %w", err)
+		}
+		fallback.TotalOperations += count
+		fallback.ByStrategy[strategy] += count
+		switch status {
+		case "pending":
+			fallback.PendingOperations += count
+		case "completed":
+			fallback.CompletedOperations += count
+		case "failed":
+			fallback.FailedOperations += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get fallback operations: %w", err)
+	}
+	fallback.SuccessRate = percent(fallback.CompletedOperations, fallback.TotalOperations)
+	return &fallback, nil
+}
+
+// Conflicts counts the sync conflicts, breaking the unresolved ones down by type
+func (s *PostgresStore) Conflicts(ctx context.Context) (*Conflicts, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT conflict_type, resolved, COUNT(*), MIN(created_at)
+		FROM sync_conflicts
+		GROUP BY conflict_type, resolved`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	conflicts := Conflicts{UnresolvedByType: map[string]int64{}}
+	for rows.Next() {
+		var conflictType string
+		var resolved bool
+		var count int64
+		var oldest sql.NullTime
+		if err := rows.Scan(&conflictType, &resolved, &count, &oldest); err != nil {
+			return nil, fmt.Errorf("failed to scan sync conflicts: %w", err)
+		}
+		conflicts.Total += count
+		if resolved {
+			continue
+		}
+		conflicts.Unresolved += count
+		conflicts.UnresolvedByType[conflictType] += count
+		if oldest.Valid && (conflicts.OldestUnresolved == nil || oldest.Time.Before(*conflicts.OldestUnresolved)) {
+			t := oldest.Time
+			conflicts.OldestUnresolved = &t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sync conflicts: %w", err)
+	}
+	conflicts.ResolutionRate = 100
+	if conflicts.Total > 0 {
+		conflicts.ResolutionRate = percent(conflicts.Total-conflicts.Unresolved, conflicts.Total)
+	}
+	return &conflicts, nil
+}
+
+// Queues reads the depths of the sync event and fallback operation queues
+func (s *PostgresStore) Queues(ctx context.Context) ([]Queue, error) {
+	queues := make([]Queue, 0, 2)
+	for _, q := range []struct {
+		name, table, pending, processing, failed string
+	}{
+		{QueueSyncEvents, "sync_events", "PENDING", "PROCESSING", "FAILED"},
+		{QueueFallbackOperations, "sync_fallback_operations", "pending", "processing", "failed"},
+	} {
+		queue := Queue{Name: q.name}
+		var oldest sql.NullTime
+		err := s.db.QueryRowContext(ctx, `
+			SELECT
+				COUNT(*) FILTER (WHERE status = $1),
+				COUNT(*) FILTER (WHERE status = $2),
+				COUNT(*) FILTER (WHERE status = $3),
+				MIN(created_at) FILTER (WHERE status = $1)
+			FROM `+q.table, q.pending, q.processing, q.failed).Scan(&queue.Pending, &queue.Processing, &queue.Failed, &oldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s queue depth: %w", q.name, err)
+		}
+		if oldest.Valid {
+			queue.OldestPendingSeconds = time.Since(oldest.Time).Seconds()
+		}
+		queues = append(queues, queue)
+	}
+	return queues, nil
+}
+
+// percent returns part as a percentage of total, 0 when total is 0
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
@@ -0,0 +1,164 @@
+package syncoverview
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore serves fixed sections and counts the builds that read them
+type memoryStore struct {
+	mu        sync.Mutex
+	reads     int
+	stats     *Stats
+	fallback  *Fallback
+	conflicts *Conflicts
+	queues    []Queue
+	statsErr  error
+}
+
+func (m *memoryStore) Stats(ctx context.Context) (*Stats, error) {
+	m.mu.Lock()
+	m.reads++
+	m.mu.Unlock()
+	if m.statsErr != nil {
+		return nil, m.statsErr
+	}
+	return m.stats, nil
+}
+
+func (m *memoryStore) Fallback(ctx context.Context) (*Fallback, error) {
+	return m.fallback, nil
+}
+
+func (m *memoryStore) Conflicts(ctx context.Context) (*Conflicts, error) {
+	return m.conflicts, nil
+}
+
+func (m *memoryStore) Queues(ctx context.Context) ([]Queue, error) {
+	return m.queues, nil
+}
+
+// memoryHealth serves fixed components
+type memoryHealth struct {
+	components []Component
+}
+
+func (m *memoryHealth) CheckComponents(ctx context.Context) ([]Component, error) {
+	return m.components, nil
+}
+
+func healthyStore() *memoryStore {
+	return &memoryStore{
+		stats:     &Stats{TotalEvents: 100, SuccessfulEvents: 95, PendingEvents: 5},
+		fallback:  &Fallback{ByStrategy: map[string]int64{}},
+		conflicts: &Conflicts{ResolutionRate: 100, UnresolvedByType: map[string]int64{}},
+		queues:    []Queue{{Name: QueueSyncEvents, Pending: 5}},
+	}
+}
+
+func healthyComponents() *memoryHealth {
+	return &memoryHealth{components: []Component{
+		{Name: "postgres", Status: ComponentUp},
+		{Name: "neo4j", Status: ComponentUp},
+	}}
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(store Store, health HealthChecker) *Service {
+	service := NewService(store, health, time.Minute)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestGetHealthy(t *testing.T) {
+	service := newTestService(healthyStore(), healthyComponents())
+
+	overview := service.Get(context.Background(), false)
+	assert.Equal(t, StatusHealthy, overview.Status)
+	assert.Equal(t, now, overview.GeneratedAt)
+	assert.Equal(t, int64(100), overview.Stats.TotalEvents)
+	assert.Len(t, overview.Components, 2)
+	assert.Len(t, overview.Queues, 1)
+	assert.Empty(t, overview.Issues)
+	assert.Nil(t, overview.Unavailable)
+}
+
+func TestGetRaisesIssues(t *testing.T) {
+	store := healthyStore()
+	store.stats.PendingEvents = MaxPendingEvents + 1
+	store.conflicts.Unresolved = MaxUnresolvedConflicts + 1
+	service := newTestService(store, healthyComponents())
+
+	overview := service.Get(context.Background(), false)
+	assert.Equal(t, StatusDegraded, overview.Status)
+	assert.Equal(t, []string{
+		"High number of pending events: 1001",
+		"High number of unresolved conflicts: 51",
+	}, overview.Issues)
+}
+
+func TestGetComponentDownIsCritical(t *testing.T) {
+	health := healthyComponents()
+	health.components[1] = Component{Name: "neo4j", Status: ComponentDown, Error: "connection refused"}
+	service := newTestService(healthyStore(), health)
+
+	overview := service.Get(context.Background(), false)
+	assert.Equal(t, StatusCritical, overview.Status)
+	assert.Contains(t, overview.Issues, "neo4j is down: connection refused")
+}
+
+func TestGetReportsUnavailableSections(t *testing.T) {
+	store := healthyStore()
+	store.statsErr = errors.New("connection refused")
+	service := newTestService(store, nil)
+
+	overview := service.Get(context.Background(), false)
+	assert.Equal(t, StatusDegraded, overview.Status)
+	assert.Nil(t, overview.Stats)
+	assert.NotNil(t, overview.Conflicts)
+	assert.Empty(t, overview.Components)
+	require.Len(t, overview.Unavailable, 2)
+	assert.Equal(t, "connection refused", overview.Unavailable[SectionStats])
+	assert.Contains(t, overview.Unavailable, SectionComponents)
+}
+
+func TestGetCaches(t *testing.T) {
+	store := healthyStore()
+	service := newTestService(store, healthyComponents())
+
+	first := service.Get(context.Background(), false)
+	assert.Same(t, first, service.Get(context.Background(), false))
+	assert.Equal(t, 1, store.reads)
+
+	// Refresh bypasses the cache
+	service.Get(context.Background(), true)
+	assert.Equal(t, 2, store.reads)
+
+	// An expired overview is built again
+	service.now = func() time.Time { return now.Add(time.Minute) }
+	service.Get(context.Background(), false)
+	assert.Equal(t, 3, store.reads)
+}
+
+func TestGetConcurrentRequestsBuildOnce(t *testing.T) {
+	store := healthyStore()
+	service := newTestService(store, healthyComponents())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.Get(context.Background(), false)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, store.reads)
+}
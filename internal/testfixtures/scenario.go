@@ -0,0 +1,144 @@
+package testfixtures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Scenario is the data a test seeds on top of the schema. Nil IDs are
+// generated when seeding and written back, so tests can refer to what they
+// seeded.
+type Scenario struct {
+	Users         []User
+	CIs           []CI
+	Relationships []Relationship
+}
+
+// User is a user to seed, with the names of the roles to assign it; the
+// initial migration creates the admin, ci_manager, viewer and auditor roles
+type User struct {
+	ID           uuid.UUID
+	Username     string
+	Email        string
+	PasswordHash string
+	Roles        []string
+}
+
+// CI is a configuration item to seed
+type CI struct {
+	ID         uuid.UUID
+	Name       string
+	Type       string
+	Attributes map[string]interface{}
+	Tags       []string
+}
+
+// Relationship is a relationship to seed between two CIs, usually ones
+// seeded by the same scenario. It needs the schema migrated through
+// FlexibleSchemaVersion.
+type Relationship struct {
+	ID         uuid.UUID
+	SourceID   uuid.UUID
+	TargetID   uuid.UUID
+	Type       string
+	Attributes map[string]interface{}
+}
+
+// Seed inserts the scenario in one transaction
+func (s *Scenario) Seed(ctx context.Context, db *sqlx.DB) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range s.Users {
+		user := &s.Users[i]
+		if user.ID == uuid.Nil {
+			user.ID = uuid.New()
+		}
+		if user.Email == "" {
+			user.Email = user.Username + "@example.com"
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO users (id, username, email, password_hash)
+			VALUES ($1, $2, $3, $4)`,
+			user.ID, user.Username, user.Email, user.PasswordHash); err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", user.Username, err)
+		}
+		for _, role := range user.Roles {
+			res, err := tx.ExecContext(ctx, `
+				INSERT INTO user_roles (user_id, role_id)
+				SELECT $1, id FROM roles WHERE name = $2 AND org_id IS NULL`, user.ID, role)
+			if err != nil {
+				return fmt.Errorf("failed to assign role %s to user %s: %w", role, user.Username, err)
+			}
+			if n, err := res.RowsAffected(); err == nil && n == 0 {
+				return fmt.Errorf("failed to assign role %s to user %s: no such role", role, user.Username)
+			}
+		}
+	}
+
+	for i := range s.CIs {
+		ci := &s.CIs[i]
+		if ci.ID == uuid.Nil {
+			ci.ID = uuid.New()
+		}
+		attributes, err := marshalAttributes(ci.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to seed CI %s: %w", ci.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO configuration_items (id, name, type, attributes, tags)
+			VALUES ($1, $2, $3, $4, $5)`,
+			ci.ID, ci.Name, ci.Type, attributes, pq.Array(ci.Tags)); err != nil {
+			return fmt.Errorf("failed to seed CI %s: %w", ci.Name, err)
+		}
+	}
+
+	for i := range s.Relationships {
+		relationship := &s.Relationships[i]
+		if relationship.ID == uuid.Nil {
+			relationship.ID = uuid.New()
+		}
+		attributes, err := marshalAttributes(relationship.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to seed relationship %s: %w", relationship.Type, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO ci_relationships (id, source_ci_id, target_ci_id, type, attributes)
+			VALUES ($1, $2, $3, $4, $5)`,
+			relationship.ID, relationship.SourceID, relationship.TargetID, relationship.Type, attributes); err != nil {
+			return fmt.Errorf("failed to seed relationship %s: %w", relationship.Type, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit scenario: %w", err)
+	}
+	return nil
+}
+
+// CIID returns the ID of the seeded CI with a name, for wiring up
+// relationships and assertions
+func (s *Scenario) CIID(name string) uuid.UUID {
+	for _, ci := range s.CIs {
+		if ci.Name == name {
+			return ci.ID
+		}
+	}
+	return uuid.Nil
+}
+
+// marshalAttributes encodes attributes, nil ones as an empty object
+func marshalAttributes(attributes map[string]interface{}) ([]byte, error) {
+	if attributes == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(attributes)
+}
@@ -0,0 +1,256 @@
+// Package testfixtures provisions the database schema for integration tests
+// from the embedded migrations, so test packages do not depend on the working
+// directory they run in, and seeds scenario specific data on top of it.
+//
+// Tests needing only the first plain SQL migrations can keep using
+// testcontainers init scripts:
+//
+//	postgres.WithInitScripts(testfixtures.InitScripts(t, 3)...)
+//
+// Tests needing the whole schema start a migrated database instead:
+//
+//	connStr := testfixtures.StartPostgres(t, 0)
+package testfixtures
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"connect/migrations"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// FlexibleSchemaVersion is the version of the Go migration moving CIs to
+// flexible attributes, which has no SQL file and cannot be an init script
+const FlexibleSchemaVersion = 4
+
+// flexibleSchema is the schema the flexible schema migration leaves on a new
+// database. Its data conversion is left out as there is no data to convert.
+// The migration converts description, status, criticality, owner and location
+// from the schema before it and no migration creates them, so they are added
+// here as the CI repository reads them.
+const flexibleSchema = `
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS description TEXT DEFAULT '';
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS status VARCHAR(50) DEFAULT '';
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS criticality VARCHAR(50) DEFAULT '';
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS owner VARCHAR(255) DEFAULT '';
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS location VARCHAR(255) DEFAULT '';
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS install_date TIMESTAMP;
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS warranty_expiry TIMESTAMP;
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS last_updated TIMESTAMP;
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS last_scanned TIMESTAMP;
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS is_active BOOLEAN DEFAULT true;
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS ci_type_schemas (
+	id UUID PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	description TEXT,
+	attributes JSONB NOT NULL,
+	is_active BOOLEAN DEFAULT true,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_by UUID,
+	updated_by UUID
+);
+
+CREATE TABLE IF NOT EXISTS relationship_type_schemas (
+	id UUID PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	description TEXT,
+	attributes JSONB NOT NULL,
+	is_active BOOLEAN DEFAULT true,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_by UUID,
+	updated_by UUID
+);
+
+CREATE TABLE IF NOT EXISTS ci_relationships (
+	id UUID PRIMARY KEY,
+	source_ci_id UUID NOT NULL REFERENCES configuration_items(id),
+	target_ci_id UUID NOT NULL REFERENCES configuration_items(id),
+	type VARCHAR(255) NOT NULL,
+	attributes JSONB,
+	description TEXT DEFAULT '',
+	is_active BOOLEAN DEFAULT true,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_by UUID,
+	updated_by UUID,
+	UNIQUE (source_ci_id, target_ci_id, type)
+);
+`
+
+// syncTriggersVersion is the version of the migration adding the sync
+// triggers, which indexes tables the sync service creates at startup
+const syncTriggersVersion = 3
+
+// syncTables are the tables the sync service creates at startup, as it
+// creates them; they are created ahead of the sync trigger migration
+const syncTables = `
+CREATE TABLE IF NOT EXISTS sync_events (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	entity_type VARCHAR(50) NOT NULL,
+	entity_id UUID NOT NULL,
+	action VARCHAR(20) NOT NULL,
+	data JSONB NOT NULL DEFAULT '{}',
+	status VARCHAR(20) DEFAULT 'PENDING',
+	retry_count INTEGER DEFAULT 0,
+	error_message TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	processed_at TIMESTAMP WITH TIME ZONE,
+
+	CONSTRAINT valid_action CHECK (action IN ('CREATE', 'UPDATE', 'DELETE')),
+	CONSTRAINT valid_status CHECK (status IN ('PENDING', 'PROCESSING', 'COMPLETED', 'FAILED'))
+);
+
+CREATE TABLE IF NOT EXISTS sync_log (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	event_id UUID NOT NULL,
+	entity_type VARCHAR(50) NOT NULL,
+	entity_id UUID NOT NULL,
+	action VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	duration_ms INTEGER,
+	error_message TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+`
+
+// step is a migration to apply
+type step struct {
+	version int
+	name    string
+	sql     string
+}
+
+// steps returns the migrations up to through, every one when through is 0,
+// in the order they apply
+func steps(through int) ([]step, error) {
+	list, err := migrations.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []step
+	flexible := step{version: FlexibleSchemaVersion, name: "004_flexible_schema_migration", sql: flexibleSchema}
+	for _, migration := range list {
+		if through > 0 && migration.Version > through {
+			break
+		}
+		if flexible.sql != "" && migration.Version > FlexibleSchemaVersion {
+			result = append(result, flexible)
+			flexible.sql = ""
+		}
+		up, err := migrations.Up(migration.Name)
+		if err != nil {
+			return nil, err
+		}
+		if migration.Version == syncTriggersVersion {
+			up = syncTables + up
+		}
+		result = append(result, step{version: migration.Version, name: migration.Name, sql: up})
+	}
+	if flexible.sql != "" && (through == 0 || through >= FlexibleSchemaVersion) {
+		result = append(result, flexible)
+	}
+	return result, nil
+}
+
+// Migrate applies the migrations up to through, every one when through is 0,
+// each in its own transaction
+func Migrate(ctx context.Context, db *sqlx.DB, through int) error {
+	steps, err := steps(through)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, step.sql); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %s: %w", step.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+// InitScripts writes the migrations up to through to a temporary directory
+// and returns their paths in order, for postgres.WithInitScripts. Init
+// scripts are plain SQL, so through must be below FlexibleSchemaVersion; use
+// StartPostgres beyond it.
+func InitScripts(t testing.TB, through int) []string {
+	t.Helper()
+	if through <= 0 || through >= FlexibleSchemaVersion {
+		t.Fatalf("init scripts only cover migrations before %d, got %d", FlexibleSchemaVersion, through)
+	}
+
+	steps, err := steps(through)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+
+	dir := t.TempDir()
+	paths := make([]string, 0, len(steps))
+	for _, step := range steps {
+		path := filepath.Join(dir, step.name)
+		if err := os.WriteFile(path, []byte(step.sql), 0o644); err != nil {
+			t.Fatalf("failed to write init script %s: %v", step.name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// StartPostgres starts a Postgres container for the test, migrated up to
+// through (every migration when through is 0), and returns its connection
+// string. The container is terminated when the test ends. Integration tests
+// are skipped in short mode.
+func StartPostgres(t testing.TB, through int) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:15"),
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		postgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		t.Fatalf("failed to start postgres: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("failed to get connection string: %v", err)
+	}
+	db, err := sqlx.ConnectContext(ctx, "postgres", connStr)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	defer db.Close()
+
+	if err := Migrate(ctx, db, through); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return connStr
+}
@@ -0,0 +1,54 @@
+package testfixtures
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"connect/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsAreListedInVersionOrder(t *testing.T) {
+	list, err := migrations.List()
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+
+	assert.Equal(t, 1, list[0].Version)
+	for i := 1; i < len(list); i++ {
+		assert.Less(t, list[i-1].Version, list[i].Version, list[i].Name)
+	}
+}
+
+func TestStepsPlaceTheFlexibleSchemaMigrationInOrder(t *testing.T) {
+	all, err := steps(0)
+	require.NoError(t, err)
+	versions := make([]int, len(all))
+	for i, step := range all {
+		versions[i] = step.version
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, versions[:5])
+
+	early, err := steps(3)
+	require.NoError(t, err)
+	assert.Len(t, early, 3)
+	assert.Contains(t, early[2].sql, "CREATE TABLE IF NOT EXISTS sync_events", "the sync triggers index the sync service tables")
+
+	throughFlexible, err := steps(FlexibleSchemaVersion)
+	require.NoError(t, err)
+	require.Len(t, throughFlexible, 4)
+	assert.Contains(t, throughFlexible[3].sql, "CREATE TABLE IF NOT EXISTS ci_relationships")
+}
+
+func TestInitScriptsLeaveOutGooseDownSections(t *testing.T) {
+	paths := InitScripts(t, 3)
+	require.Len(t, paths, 3)
+	assert.Equal(t, "001_initial_schema.sql", filepath.Base(paths[0]))
+
+	initial, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(initial), "CREATE TABLE users")
+	assert.False(t, strings.Contains(string(initial), "DROP TABLE IF EXISTS users"), "the down section would drop the schema again")
+}
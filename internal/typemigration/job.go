@@ -0,0 +1,90 @@
+package typemigration
+
+import (
+	"encoding/json"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// MaxFailures bounds the CI failures listed on a job or preview
+const MaxFailures = 100
+
+// Job is a run of a plan. Counts are updated as the job progresses.
+type Job struct {
+	ID          uuid.UUID  `json:"id"`
+	Plan        Plan       `json:"plan"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Scanned     int        `json:"scanned"`
+	Migrated    int        `json:"migrated"`
+	// Unmatched counts CIs no target matched; they keep their type
+	Unmatched int `json:"unmatched"`
+	// Invalid counts CIs failing validation against their target schema
+	Invalid int `json:"invalid"`
+	// Failed counts CIs that could not be written, e.g. because the target
+	// type already has a CI with the same name
+	Failed int `json:"failed"`
+	// Skipped counts CIs edited while the job ran; run the plan again to migrate them
+	Skipped              int       `json:"skipped"`
+	RelationshipsUpdated int       `json:"relationships_updated"`
+	Failures             []Failure `json:"failures"`
+	Error                string    `json:"error,omitempty"`
+}
+
+// Preview reports what a plan would do without changing anything
+type Preview struct {
+	Scanned int `json:"scanned"`
+	// Targets counts the CIs going to each target type
+	Targets   map[string]int `json:"targets"`
+	Unmatched int            `json:"unmatched"`
+	Invalid   int            `json:"invalid"`
+	Failures  []Failure      `json:"failures"`
+}
+
+// Failure explains why a CI was not migrated
+type Failure struct {
+	CIID       uuid.UUID                `json:"ci_id"`
+	Name       string                   `json:"name"`
+	TargetType string                   `json:"target_type"`
+	Reason     string                   `json:"reason"`
+	Errors     []models.ValidationError `json:"errors,omitempty"`
+}
+
+// Candidate is a CI of the source type
+type Candidate struct {
+	ID         uuid.UUID
+	Name       string
+	Attributes json.RawMessage
+}
+
+// Change is the migration of one CI
+type Change struct {
+	JobID    uuid.UUID
+	CIID     uuid.UUID
+	FromType string
+	ToType   string
+	// Previous guards against concurrent edits; the CI is only changed while
+	// its attributes still equal it
+	Previous      json.RawMessage
+	Attributes    json.RawMessage
+	Relationships map[string]string
+}
+
+// addFailure records a failure, keeping the first MaxFailures
+func addFailure(failures []Failure, failure Failure) []Failure {
+	if len(failures) >= MaxFailures {
+		return failures
+	}
+	return append(failures, failure)
+}
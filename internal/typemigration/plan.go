@@ -0,0 +1,198 @@
+// Package typemigration changes the type of many CIs at once, e.g. splitting
+// "server" into "physical_server" and "virtual_server". A plan routes each CI
+// of the source type to a target type, remaps its attributes, validates the
+// result against the target schema and renames its relationship types. Plans
+// run as background jobs; every migrated CI is written to the audit log with
+// its previous type and attributes.
+package typemigration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxTargets bounds the target types of a single plan
+const MaxTargets = 20
+
+var (
+	ErrInvalidPlan = errors.New("invalid CI type migration plan")
+	ErrJobNotFound = errors.New("CI type migration job not found")
+	ErrJobRunning  = errors.New("a CI type migration job is already running")
+)
+
+// Plan describes a type migration. Each CI of SourceType goes to the first
+// target whose condition matches; CIs no target matches keep their type.
+type Plan struct {
+	SourceType string `json:"source_type"`
+	// CIIDs restricts the migration to these CIs when set
+	CIIDs   []uuid.UUID `json:"ci_ids,omitempty"`
+	Targets []Target    `json:"targets"`
+	// SkipInvalid migrates the CIs that pass validation and reports the others.
+	// Otherwise any invalid CI fails the job before anything is changed.
+	SkipInvalid bool   `json:"skip_invalid"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Target is a type CIs are migrated to
+type Target struct {
+	Type string `json:"type"`
+	// When selects the CIs going to this target; a target without it takes
+	// every CI left and must come last
+	When *Condition `json:"when,omitempty"`
+	// Attributes rename or drop attributes, in order
+	Attributes []AttributeMapping `json:"attributes,omitempty"`
+	// Defaults sets attributes the CI does not have yet
+	Defaults map[string]interface{} `json:"defaults,omitempty"`
+	// Relationships renames the types of the CI's relationships, old to new
+	Relationships map[string]string `json:"relationships,omitempty"`
+}
+
+// Condition matches CIs on a top-level attribute. Without values it matches
+// when the attribute is set; otherwise when it equals any of them, ignoring case.
+type Condition struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values,omitempty"`
+}
+
+// AttributeMapping moves an attribute to a new name, or drops it when To is empty
+type AttributeMapping struct {
+	From string `json:"from"`
+	To   string `json:"to,omitempty"`
+}
+
+// Validate checks the plan and normalizes its names
+func (p *Plan) Validate() error {
+	p.SourceType = strings.TrimSpace(p.SourceType)
+	if p.SourceType == "" {
+		return fmt.Errorf("%w: source_type is required", ErrInvalidPlan)
+	}
+	if len(p.Targets) == 0 {
+		return fmt.Errorf("%w: at least one target is required", ErrInvalidPlan)
+	}
+	if len(p.Targets) > MaxTargets {
+		return fmt.Errorf("%w: at most %d targets are allowed", ErrInvalidPlan, MaxTargets)
+	}
+
+	for i := range p.Targets {
+		target := &p.Targets[i]
+		target.Type = strings.TrimSpace(target.Type)
+		if target.Type == "" {
+			return fmt.Errorf("%w: target %d has no type", ErrInvalidPlan, i+1)
+		}
+		if target.Type == p.SourceType {
+			return fmt.Errorf("%w: target %d keeps the source type", ErrInvalidPlan, i+1)
+		}
+		if target.When == nil && i < len(p.Targets)-1 {
+			return fmt.Errorf("%w: target %d has no condition and must be last", ErrInvalidPlan, i+1)
+		}
+		if target.When != nil && strings.TrimSpace(target.When.Attribute) == "" {
+			return fmt.Errorf("%w: target %d condition has no attribute", ErrInvalidPlan, i+1)
+		}
+
+		seen := map[string]bool{}
+		for _, mapping := range target.Attributes {
+			if mapping.From == "" || mapping.From == mapping.To {
+				return fmt.Errorf("%w: target %d has an invalid attribute mapping %q to %q", ErrInvalidPlan, i+1, mapping.From, mapping.To)
+			}
+			if seen[mapping.From] {
+				return fmt.Errorf("%w: target %d maps attribute %q more than once", ErrInvalidPlan, i+1, mapping.From)
+			}
+			seen[mapping.From] = true
+		}
+
+		for from, to := range target.Relationships {
+			if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" || from == to {
+				return fmt.Errorf("%w: target %d has an invalid relationship mapping %q to %q", ErrInvalidPlan, i+1, from, to)
+			}
+		}
+	}
+	return nil
+}
+
+// Route returns the target a CI goes to, or nil when none matches
+func (p *Plan) Route(attributes map[string]interface{}) *Target {
+	for i := range p.Targets {
+		if p.Targets[i].When == nil || p.Targets[i].When.Matches(attributes) {
+			return &p.Targets[i]
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the condition holds for a CI's attributes
+func (c *Condition) Matches(attributes map[string]interface{}) bool {
+	value, ok := attributes[c.Attribute]
+	if !ok || value == nil {
+		return false
+	}
+	if len(c.Values) == 0 {
+		return true
+	}
+
+	text, ok := scalar(value)
+	if !ok {
+		return false
+	}
+	for _, want := range c.Values {
+		if strings.EqualFold(text, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remap returns the attributes a CI has once migrated to the target. The
+// input is left unchanged.
+func (t *Target) Remap(attributes map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(attributes)+len(t.Defaults))
+	for name, value := range attributes {
+		result[name] = value
+	}
+
+	for _, mapping := range t.Attributes {
+		value, ok := attributes[mapping.From]
+		if !ok {
+			continue
+		}
+		delete(result, mapping.From)
+		if mapping.To != "" {
+			result[mapping.To] = value
+		}
+	}
+
+	for name, value := range t.Defaults {
+		if _, ok := result[name]; !ok {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// parseAttributes decodes CI attributes; anything but an object counts as empty
+func parseAttributes(raw json.RawMessage) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &attributes); err != nil || attributes == nil {
+			return map[string]interface{}{}
+		}
+	}
+	return attributes
+}
+
+// scalar renders a JSON scalar as text
+func scalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
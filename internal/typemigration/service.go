@@ -0,0 +1,262 @@
+package typemigration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// DefaultPageSize is the number of CIs read at a time
+const DefaultPageSize = 200
+
+// Service previews plans and runs them as background jobs, one at a time
+type Service struct {
+	store    Store
+	pageSize int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewService creates a new CI type migration service
+func NewService(store Store) *Service {
+	return &Service{
+		store:    store,
+		pageSize: DefaultPageSize,
+		now:      time.Now,
+	}
+}
+
+// Preview reports what a plan would do without changing anything
+func (s *Service) Preview(ctx context.Context, plan Plan) (*Preview, error) {
+	if err := plan.Validate(); err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{Targets: map[string]int{}, Failures: []Failure{}}
+	schemas := map[string]*models.CITypeSchema{}
+	err := s.scan(ctx, &plan, func(ci Candidate) error {
+		preview.Scanned++
+		change, failure, err := s.prepare(ctx, &plan, ci, schemas)
+		if err != nil {
+			return err
+		}
+		switch {
+		case failure != nil:
+			preview.Invalid++
+			preview.Failures = addFailure(preview.Failures, *failure)
+		case change == nil:
+			preview.Unmatched++
+		default:
+			preview.Targets[change.ToType]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// Start validates a plan and runs it in the background. Poll GetJob for progress.
+func (s *Service) Start(ctx context.Context, plan Plan, requestedBy string) (*Job, error) {
+	if err := plan.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil, ErrJobRunning
+	}
+
+	job := &Job{
+		ID:          uuid.New(),
+		Plan:        plan,
+		Status:      StatusRunning,
+		RequestedBy: requestedBy,
+		CreatedAt:   s.now(),
+		Failures:    []Failure{},
+	}
+	if err := s.store.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+	s.running = true
+
+	log.Printf("CI type migration %s of %s started by %s", job.ID, plan.SourceType, requestedBy)
+	started := *job
+	// The job outlives the request but still acts for its organization
+	go s.run(visibility.WithTenant(context.Background(), visibility.TenantFromContext(ctx)), job)
+	return &started, nil
+}
+
+// GetJob retrieves a job
+func (s *Service) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	return s.store.GetJob(ctx, id)
+}
+
+// ListJobs retrieves the latest jobs, newest first
+func (s *Service) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.store.ListJobs(ctx, limit)
+}
+
+// run migrates the CIs, saving progress after every page
+func (s *Service) run(ctx context.Context, job *Job) {
+	err := s.migrate(ctx, job)
+
+	completedAt := s.now()
+	job.CompletedAt = &completedAt
+	job.Status = StatusCompleted
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+
+	// A new job can start once this one is recorded as finished
+	s.mu.Lock()
+	if err := s.store.UpdateJob(ctx, job); err != nil {
+		log.Printf("Failed to save CI type migration %s: %v", job.ID, err)
+	}
+	s.running = false
+	s.mu.Unlock()
+
+	log.Printf("CI type migration %s %s: %d scanned, %d migrated, %d unmatched, %d invalid, %d failed, %d skipped",
+		job.ID, job.Status, job.Scanned, job.Migrated, job.Unmatched, job.Invalid, job.Failed, job.Skipped)
+}
+
+// migrate applies the plan. Unless invalid CIs are skipped, every CI is
+// validated first so a plan that does not fit changes nothing.
+func (s *Service) migrate(ctx context.Context, job *Job) error {
+	plan := &job.Plan
+	if !plan.SkipInvalid {
+		preview, err := s.Preview(ctx, *plan)
+		if err != nil {
+			return err
+		}
+		if preview.Invalid > 0 {
+			job.Scanned = preview.Scanned
+			job.Invalid = preview.Invalid
+			job.Failures = preview.Failures
+			return fmt.Errorf("%d CIs fail validation against their target schema; nothing was changed", preview.Invalid)
+		}
+	}
+
+	schemas := map[string]*models.CITypeSchema{}
+	processed := 0
+	return s.scan(ctx, plan, func(ci Candidate) error {
+		job.Scanned++
+		change, failure, err := s.prepare(ctx, plan, ci, schemas)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case failure != nil:
+			job.Invalid++
+			job.Failures = addFailure(job.Failures, *failure)
+		case change == nil:
+			job.Unmatched++
+		default:
+			change.JobID = job.ID
+			migrated, relationships, err := s.store.MigrateCI(ctx, change, job.RequestedBy, s.now())
+			switch {
+			case err != nil:
+				// One CI failing to write, e.g. on a name clash, does not stop the others
+				job.Failed++
+				job.Failures = addFailure(job.Failures, Failure{CIID: ci.ID, Name: ci.Name, TargetType: change.ToType, Reason: err.Error()})
+			case !migrated:
+				job.Skipped++
+			default:
+				job.Migrated++
+				job.RelationshipsUpdated += relationships
+			}
+		}
+
+		if processed++; processed%s.pageSize == 0 {
+			if err := s.store.UpdateJob(ctx, job); err != nil {
+				log.Printf("Failed to save CI type migration %s progress: %v", job.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// prepare routes a CI, remaps its attributes and validates them against the
+// target schema. It returns no change and no failure when no target matches.
+func (s *Service) prepare(ctx context.Context, plan *Plan, ci Candidate, schemas map[string]*models.CITypeSchema) (*Change, *Failure, error) {
+	attributes := parseAttributes(ci.Attributes)
+	target := plan.Route(attributes)
+	if target == nil {
+		return nil, nil, nil
+	}
+
+	remapped, err := json.Marshal(target.Remap(attributes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal attributes of CI %s: %w", ci.ID, err)
+	}
+
+	schema, ok := schemas[target.Type]
+	if !ok {
+		if schema, err = s.store.GetSchema(ctx, target.Type); err != nil {
+			return nil, nil, err
+		}
+		schemas[target.Type] = schema
+	}
+	// Types without a schema accept any attributes, as on create
+	if schema != nil {
+		result := models.NewSchemaValidator().ValidateCIAgainstSchema(models.CI{Attributes: remapped}, *schema)
+		if !result.IsValid {
+			return nil, &Failure{
+				CIID:       ci.ID,
+				Name:       ci.Name,
+				TargetType: target.Type,
+				Reason:     "validation against the target schema failed",
+				Errors:     result.Errors,
+			}, nil
+		}
+	}
+
+	previous := ci.Attributes
+	if len(previous) == 0 {
+		previous = json.RawMessage(`{}`)
+	}
+	return &Change{
+		CIID:          ci.ID,
+		FromType:      plan.SourceType,
+		ToType:        target.Type,
+		Previous:      previous,
+		Attributes:    remapped,
+		Relationships: target.Relationships,
+	}, nil, nil
+}
+
+// scan calls fn for every live CI of the plan's source type, page by page
+func (s *Service) scan(ctx context.Context, plan *Plan, fn func(ci Candidate) error) error {
+	after := uuid.Nil
+	for {
+		cis, err := s.store.ListCandidates(ctx, plan.SourceType, plan.CIIDs, after, s.pageSize)
+		if err != nil {
+			return err
+		}
+		for _, ci := range cis {
+			if err := fn(ci); err != nil {
+				return err
+			}
+		}
+		if len(cis) < s.pageSize {
+			return nil
+		}
+		after = cis[len(cis)-1].ID
+	}
+}
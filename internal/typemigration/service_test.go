@@ -0,0 +1,311 @@
+package typemigration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCI struct {
+	name       string
+	ciType     string
+	attributes json.RawMessage
+}
+
+type memoryRelationship struct {
+	source, target uuid.UUID
+	relType        string
+}
+
+type memoryStore struct {
+	mu            sync.Mutex
+	cis           map[uuid.UUID]*memoryCI
+	relationships []*memoryRelationship
+	schemas       map[string]*models.CITypeSchema
+	jobs          map[uuid.UUID]*Job
+	failWrites    map[uuid.UUID]error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		cis:        map[uuid.UUID]*memoryCI{},
+		schemas:    map[string]*models.CITypeSchema{},
+		jobs:       map[uuid.UUID]*Job{},
+		failWrites: map[uuid.UUID]error{},
+	}
+}
+
+func (m *memoryStore) addCI(ciType string, attributes map[string]interface{}) uuid.UUID {
+	raw, _ := json.Marshal(attributes)
+	id := uuid.New()
+	m.cis[id] = &memoryCI{name: id.String()[:8], ciType: ciType, attributes: raw}
+	return id
+}
+
+func (m *memoryStore) ci(id uuid.UUID) memoryCI {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.cis[id]
+}
+
+func (m *memoryStore) ListCandidates(ctx context.Context, ciType string, ids []uuid.UUID, after uuid.UUID, limit int) ([]Candidate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	allowed := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		allowed[id] = true
+	}
+
+	var candidates []Candidate
+	for id, ci := range m.cis {
+		if ci.ciType != ciType || id.String() <= after.String() || (len(ids) > 0 && !allowed[id]) {
+			continue
+		}
+		candidates = append(candidates, Candidate{ID: id, Name: ci.name, Attributes: ci.attributes})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ID.String() < candidates[j].ID.String() })
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+func (m *memoryStore) GetSchema(ctx context.Context, ciType string) (*models.CITypeSchema, error) {
+	return m.schemas[ciType], nil
+}
+
+func (m *memoryStore) MigrateCI(ctx context.Context, change *Change, by string, at time.Time) (bool, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failWrites[change.CIID]; err != nil {
+		return false, 0, err
+	}
+	ci, ok := m.cis[change.CIID]
+	if !ok || ci.ciType != change.FromType || string(ci.attributes) != string(change.Previous) {
+		return false, 0, nil
+	}
+	ci.ciType = change.ToType
+	ci.attributes = change.Attributes
+
+	renamed := 0
+	for _, rel := range m.relationships {
+		if rel.source != change.CIID && rel.target != change.CIID {
+			continue
+		}
+		if to, ok := change.Relationships[rel.relType]; ok {
+			rel.relType = to
+			renamed++
+		}
+	}
+	return true, renamed, nil
+}
+
+func (m *memoryStore) CreateJob(ctx context.Context, job *Job) error {
+	return m.UpdateJob(ctx, job)
+}
+
+func (m *memoryStore) UpdateJob(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *job
+	copied.Failures = append([]Failure(nil), job.Failures...)
+	m.jobs[job.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (m *memoryStore) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	return nil, nil
+}
+
+// splitPlan splits servers into physical and virtual ones
+func splitPlan() Plan {
+	return Plan{
+		SourceType: "server",
+		Targets: []Target{
+			{
+				Type:          "virtual_server",
+				When:          &Condition{Attribute: "virtualization", Values: []string{"vmware", "kvm"}},
+				Attributes:    []AttributeMapping{{From: "virtualization", To: "hypervisor"}, {From: "rack"}},
+				Relationships: map[string]string{"runs_on": "hosted_on"},
+			},
+			{
+				Type:     "physical_server",
+				Defaults: map[string]interface{}{"form_factor": "rack"},
+			},
+		},
+	}
+}
+
+func waitForJob(t *testing.T, service *Service, id uuid.UUID) *Job {
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = service.GetJob(context.Background(), id)
+		return err == nil && job.Status != StatusRunning
+	}, time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestPlanValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		plan Plan
+	}{
+		{"missing source", Plan{Targets: []Target{{Type: "vm"}}}},
+		{"no targets", Plan{SourceType: "server"}},
+		{"same type", Plan{SourceType: "server", Targets: []Target{{Type: "server"}}}},
+		{"catch-all not last", Plan{SourceType: "server", Targets: []Target{{Type: "vm"}, {Type: "host", When: &Condition{Attribute: "x"}}}}},
+		{"empty mapping", Plan{SourceType: "server", Targets: []Target{{Type: "vm", Attributes: []AttributeMapping{{To: "x"}}}}}},
+		{"duplicate mapping", Plan{SourceType: "server", Targets: []Target{{Type: "vm", Attributes: []AttributeMapping{{From: "a", To: "b"}, {From: "a"}}}}}},
+		{"empty relationship", Plan{SourceType: "server", Targets: []Target{{Type: "vm", Relationships: map[string]string{"runs_on": ""}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.plan.Validate(), ErrInvalidPlan)
+		})
+	}
+
+	plan := splitPlan()
+	assert.NoError(t, plan.Validate())
+}
+
+func TestTargetRemap(t *testing.T) {
+	plan := splitPlan()
+	attributes := map[string]interface{}{"virtualization": "VMware", "rack": "R12", "cpu": float64(8)}
+
+	target := plan.Route(attributes)
+	require.NotNil(t, target)
+	assert.Equal(t, "virtual_server", target.Type)
+	assert.Equal(t, map[string]interface{}{"hypervisor": "VMware", "cpu": float64(8)}, target.Remap(attributes))
+	// The input is left unchanged
+	assert.Contains(t, attributes, "rack")
+
+	target = plan.Route(map[string]interface{}{"form_factor": "blade"})
+	require.NotNil(t, target)
+	assert.Equal(t, "physical_server", target.Type)
+	assert.Equal(t, map[string]interface{}{"form_factor": "blade"}, target.Remap(map[string]interface{}{"form_factor": "blade"}))
+}
+
+func TestService_Preview(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	service.pageSize = 2
+	store.addCI("server", map[string]interface{}{"virtualization": "kvm"})
+	store.addCI("server", map[string]interface{}{"virtualization": "kvm"})
+	store.addCI("server", nil)
+	store.addCI("router", nil)
+	store.schemas["physical_server"] = &models.CITypeSchema{
+		Name:       "physical_server",
+		Attributes: []models.CITypeAttribute{{Name: "serial_number", Type: "string", Required: true}},
+	}
+
+	preview, err := service.Preview(context.Background(), splitPlan())
+	require.NoError(t, err)
+	assert.Equal(t, 3, preview.Scanned)
+	assert.Equal(t, map[string]int{"virtual_server": 2}, preview.Targets)
+	assert.Equal(t, 1, preview.Invalid)
+	require.Len(t, preview.Failures, 1)
+	assert.Equal(t, "physical_server", preview.Failures[0].TargetType)
+	assert.NotEmpty(t, preview.Failures[0].Errors)
+
+	// Nothing changed
+	for _, ci := range store.cis {
+		assert.NotEqual(t, "virtual_server", ci.ciType)
+	}
+}
+
+func TestService_RunMigratesAndRenamesRelationships(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	service.pageSize = 2
+	vm := store.addCI("server", map[string]interface{}{"virtualization": "VMware", "rack": "R1"})
+	host := store.addCI("server", map[string]interface{}{"serial_number": "SN1"})
+	clash := store.addCI("server", map[string]interface{}{})
+	store.failWrites[clash] = errors.New("duplicate name")
+	other := store.addCI("router", nil)
+	store.relationships = []*memoryRelationship{
+		{source: vm, target: host, relType: "runs_on"},
+		{source: other, target: vm, relType: "connected_to"},
+	}
+
+	started, err := service.Start(context.Background(), splitPlan(), "admin")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, started.Status)
+
+	job := waitForJob(t, service, started.ID)
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, 3, job.Scanned)
+	assert.Equal(t, 2, job.Migrated)
+	assert.Equal(t, 1, job.Failed)
+	assert.Equal(t, 1, job.RelationshipsUpdated)
+
+	migrated := store.ci(vm)
+	assert.Equal(t, "virtual_server", migrated.ciType)
+	assert.JSONEq(t, `{"hypervisor": "VMware"}`, string(migrated.attributes))
+	migrated = store.ci(host)
+	assert.Equal(t, "physical_server", migrated.ciType)
+	assert.JSONEq(t, `{"serial_number": "SN1", "form_factor": "rack"}`, string(migrated.attributes))
+	assert.Equal(t, "server", store.ci(clash).ciType)
+	assert.Equal(t, "hosted_on", store.relationships[0].relType)
+	assert.Equal(t, "connected_to", store.relationships[1].relType)
+}
+
+func TestService_RunChangesNothingWhenInvalid(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	vm := store.addCI("server", map[string]interface{}{"virtualization": "kvm"})
+	store.addCI("server", nil)
+	store.schemas["physical_server"] = &models.CITypeSchema{
+		Name:       "physical_server",
+		Attributes: []models.CITypeAttribute{{Name: "serial_number", Type: "string", Required: true}},
+	}
+
+	started, err := service.Start(context.Background(), splitPlan(), "admin")
+	require.NoError(t, err)
+	job := waitForJob(t, service, started.ID)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Equal(t, 1, job.Invalid)
+	assert.Equal(t, "server", store.ci(vm).ciType)
+
+	// Skipping invalid CIs migrates the others
+	plan := splitPlan()
+	plan.SkipInvalid = true
+	started, err = service.Start(context.Background(), plan, "admin")
+	require.NoError(t, err)
+	job = waitForJob(t, service, started.ID)
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, 1, job.Migrated)
+	assert.Equal(t, 1, job.Invalid)
+	assert.Equal(t, "virtual_server", store.ci(vm).ciType)
+}
+
+func TestService_StartRejectsConcurrentJobs(t *testing.T) {
+	service := NewService(newMemoryStore())
+	service.running = true
+
+	_, err := service.Start(context.Background(), splitPlan(), "admin")
+	assert.ErrorIs(t, err, ErrJobRunning)
+}
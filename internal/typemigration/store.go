@@ -0,0 +1,334 @@
+package typemigration
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"connect/internal/models"
+	"connect/internal/organization"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store reads the CIs to migrate, applies changes and persists jobs
+type Store interface {
+	// ListCandidates returns up to limit live CIs of the type with IDs greater
+	// than after, ordered by ID. A non-empty ids restricts the listing to them.
+	ListCandidates(ctx context.Context, ciType string, ids []uuid.UUID, after uuid.UUID, limit int) ([]Candidate, error)
+	// GetSchema returns the active schema of a CI type, or nil when it has none
+	GetSchema(ctx context.Context, ciType string) (*models.CITypeSchema, error)
+	// MigrateCI applies a change unless the CI was edited since it was read,
+	// reporting whether it was applied and how many relationships were renamed.
+	// The audit entry is written in the same transaction.
+	MigrateCI(ctx context.Context, change *Change, by string, at time.Time) (bool, int, error)
+	CreateJob(ctx context.Context, job *Job) error
+	// UpdateJob saves the progress of a job, writing an audit entry once it has finished
+	UpdateJob(ctx context.Context, job *Job) error
+	GetJob(ctx context.Context, id uuid.UUID) (*Job, error)
+	ListJobs(ctx context.Context, limit int) ([]*Job, error)
+}
+
+// PostgresStore keeps jobs in the ci_type_migrations table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed type migration store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// ListCandidates retrieves a page of the CIs of the source type the job's
+// organization may see
+func (s *PostgresStore) ListCandidates(ctx context.Context, ciType string, ids []uuid.UUID, after uuid.UUID, limit int) ([]Candidate, error) {
+	query := `
+		SELECT id, name, COALESCE(attributes, '{}'::jsonb)
+		FROM configuration_items
+		WHERE type = $1 AND id > $2 AND is_deleted = false`
+	args := []interface{}{ciType, after}
+	if len(ids) > 0 {
+		strIDs := make([]string, len(ids))
+		for i, id := range ids {
+			strIDs[i] = id.String()
+		}
+		query += ` AND id = ANY($4::uuid[])`
+		args = append(args, limit, pq.Array(strIDs))
+	} else {
+		args = append(args, limit)
+	}
+	var orgFilter string
+	orgFilter, args = organization.AndScopeCondition(ctx, "org_id", args)
+	query += orgFilter + ` ORDER BY id LIMIT $3`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CIs to migrate: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []Candidate
+	for rows.Next() {
+		var candidate Candidate
+		var attributes []byte
+		if err := rows.Scan(&candidate.ID, &candidate.Name, &attributes); err != nil {
+			return nil, fmt.Errorf("failed to scan CI to migrate: %w", err)
+		}
+		candidate.Attributes = attributes
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list CIs to migrate: %w", err)
+	}
+	return candidates, nil
+}
+
+// GetSchema retrieves the active schema of a CI type
+func (s *PostgresStore) GetSchema(ctx context.Context, ciType string) (*models.CITypeSchema, error) {
+	var raw []byte
+	schema := models.CITypeSchema{Name: ciType}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, attributes FROM ci_type_schemas WHERE name = $1 AND is_active = true`, ciType).Scan(&schema.ID, &raw)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get CI type schema: %w", err)
+	}
+	if err := json.Unmarshal(raw, &schema.Attributes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CI type schema attributes: %w", err)
+	}
+	return &schema, nil
+}
+
+// MigrateCI changes the type and attributes of a CI and renames its relationships
+func (s *PostgresStore) MigrateCI(ctx context.Context, change *Change, by string, at time.Time) (bool, int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var updatedBy *uuid.UUID
+	if id, err := uuid.Parse(by); err == nil {
+		updatedBy = &id
+	}
+
+	orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{
+		change.CIID, change.ToType, []byte(change.Attributes), at, updatedBy, change.FromType, []byte(change.Previous)})
+	result, err := tx.ExecContext(ctx, `
+		UPDATE configuration_items
+		SET type = $2, attributes = $3, updated_at = $4, updated_by = COALESCE($5, updated_by)
+		WHERE id = $1 AND type = $6 AND is_deleted = false AND COALESCE(attributes, '{}'::jsonb) = $7::jsonb`+orgFilter,
+		args...)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to migrate CI type: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, 0, nil
+	}
+
+	// Rename in a stable order so the audit entry reads the same every time
+	froms := make([]string, 0, len(change.Relationships))
+	for from := range change.Relationships {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	renamed := map[string][]uuid.UUID{}
+	total := 0
+	for _, from := range froms {
+		var ids []uuid.UUID
+		orgFilter, args := organization.AndScopeCondition(ctx, "org_id", []interface{}{change.CIID, from, change.Relationships[from], at, updatedBy})
+		err := tx.SelectContext(ctx, &ids, `
+			UPDATE ci_relationships
+			SET type = $3, updated_at = $4, updated_by = COALESCE($5, updated_by)
+			WHERE (source_ci_id = $1 OR target_ci_id = $1) AND type = $2`+orgFilter+`
+			RETURNING id`, args...)
+		if err != nil {
+			return false, 0, fmt.Errorf("failed to rename %s relationships: %w", from, err)
+		}
+		if len(ids) > 0 {
+			renamed[from+" -> "+change.Relationships[from]] = ids
+			total += len(ids)
+		}
+	}
+
+	if err := insertAudit(ctx, tx, "ci", change.CIID, "type_migrated", by, at, map[string]interface{}{
+		"job_id":              change.JobID,
+		"from_type":           change.FromType,
+		"to_type":             change.ToType,
+		"previous_attributes": change.Previous,
+		"attributes":          change.Attributes,
+		"relationships":       renamed,
+	}); err != nil {
+		return false, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("failed to commit CI type migration: %w", err)
+	}
+	return true, total, nil
+}
+
+// CreateJob stores a new job and audits its start
+func (s *PostgresStore) CreateJob(ctx context.Context, job *Job) error {
+	plan, err := json.Marshal(job.Plan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CI type migration plan: %w", err)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ci_type_migrations (id, source_type, plan, status, requested_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		job.ID, job.Plan.SourceType, plan, job.Status, job.RequestedBy, job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create CI type migration job: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, "ci_type_migration", job.ID, "started", job.RequestedBy, job.CreatedAt, map[string]interface{}{
+		"plan": job.Plan,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit CI type migration job: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob saves the counts and status of a job
+func (s *PostgresStore) UpdateJob(ctx context.Context, job *Job) error {
+	failures, err := json.Marshal(job.Failures)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CI type migration failures: %w", err)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE ci_type_migrations
+		SET status = $2, completed_at = $3, scanned = $4, migrated = $5, unmatched = $6, invalid = $7,
+		    failed = $8, skipped = $9, relationships_updated = $10, failures = $11, error = NULLIF($12, '')
+		WHERE id = $1`,
+		job.ID, job.Status, job.CompletedAt, job.Scanned, job.Migrated, job.Unmatched, job.Invalid,
+		job.Failed, job.Skipped, job.RelationshipsUpdated, failures, job.Error)
+	if err != nil {
+		return fmt.Errorf("failed to update CI type migration job: %w", err)
+	}
+
+	if job.Status != StatusRunning {
+		if err := insertAudit(ctx, tx, "ci_type_migration", job.ID, job.Status, job.RequestedBy, *job.CompletedAt, map[string]interface{}{
+			"source_type":           job.Plan.SourceType,
+			"scanned":               job.Scanned,
+			"migrated":              job.Migrated,
+			"unmatched":             job.Unmatched,
+			"invalid":               job.Invalid,
+			"failed":                job.Failed,
+			"skipped":               job.Skipped,
+			"relationships_updated": job.RelationshipsUpdated,
+			"error":                 job.Error,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit CI type migration job: %w", err)
+	}
+	return nil
+}
+
+const jobColumns = `id, plan, status, requested_by, created_at, completed_at, scanned, migrated, unmatched,
+	invalid, failed, skipped, relationships_updated, COALESCE(failures, '[]'::jsonb), COALESCE(error, '')`
+
+// GetJob retrieves a job
+func (s *PostgresStore) GetJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := scanJob(s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM ci_type_migrations WHERE id = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get CI type migration job: %w", err)
+	}
+	return job, nil
+}
+
+// ListJobs retrieves the latest jobs, newest first
+func (s *PostgresStore) ListJobs(ctx context.Context, limit int) ([]*Job, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM ci_type_migrations ORDER BY created_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CI type migration jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan CI type migration job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list CI type migration jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// scanJob reads a job row selected with jobColumns
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var job Job
+	var plan, failures []byte
+	if err := row.Scan(&job.ID, &plan, &job.Status, &job.RequestedBy, &job.CreatedAt, &job.CompletedAt,
+		&job.Scanned, &job.Migrated, &job.Unmatched, &job.Invalid, &job.Failed, &job.Skipped,
+		&job.RelationshipsUpdated, &failures, &job.Error); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(plan, &job.Plan); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CI type migration plan: %w", err)
+	}
+	if err := json.Unmarshal(failures, &job.Failures); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CI type migration failures: %w", err)
+	}
+	return &job, nil
+}
+
+// insertAudit writes an audit log entry. changed_by references users, so actors
+// that are not user IDs are recorded in the details.
+func insertAudit(ctx context.Context, tx *sqlx.Tx, entityType string, entityID uuid.UUID, action, actor string, at time.Time, details map[string]interface{}) error {
+	var changedBy *uuid.UUID
+	if id, err := uuid.Parse(actor); err == nil {
+		changedBy = &id
+	} else if actor != "" {
+		details["actor"] = actor
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ($1, $2, $3, $4, $5, $6)`, entityType, entityID, action, changedBy, at, data)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
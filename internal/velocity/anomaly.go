@@ -0,0 +1,116 @@
+// Package velocity flags unusual change patterns in the CMDB: a CI updated
+// hundreds of times in an hour, mass deletions, a type whose CIs were nearly
+// all rewritten at once, or a burst of changes far above the usual rate. Such
+// patterns usually come from misconfigured automation, and raising them early
+// keeps it from corrupting the data.
+package velocity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Anomaly kinds
+const (
+	KindHotEntity    = "hot_entity"
+	KindMassDeletion = "mass_deletion"
+	KindTypeChurn    = "type_churn"
+	KindChangeSpike  = "change_spike"
+)
+
+// Severities
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var (
+	ErrInvalidThresholds = errors.New("invalid anomaly thresholds")
+	ErrAnomalyNotFound   = errors.New("anomaly not found")
+	ErrDetectionRunning  = errors.New("anomaly detection already running")
+)
+
+// Anomaly is an unusual change pattern found by a detection run
+type Anomaly struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	Kind     string    `json:"kind" db:"kind"`
+	Severity string    `json:"severity" db:"severity"`
+	// Subject identifies what the anomaly is about, so it is raised once per
+	// cooldown: an entity ID, a CI type, or "*" for the whole CMDB
+	Subject        string     `json:"subject" db:"subject"`
+	Message        string     `json:"message" db:"message"`
+	Count          int64      `json:"count" db:"count"`
+	Threshold      float64    `json:"threshold" db:"threshold"`
+	WindowStart    time.Time  `json:"window_start" db:"window_start"`
+	WindowEnd      time.Time  `json:"window_end" db:"window_end"`
+	DetectedAt     time.Time  `json:"detected_at" db:"detected_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+}
+
+// Thresholds configure the detectors. A zero threshold disables its detector.
+type Thresholds struct {
+	// Window is the period each run looks back over
+	Window time.Duration `json:"window"`
+	// EntityChanges flags a CI or relationship changed this often in the window
+	EntityChanges int64 `json:"entity_changes"`
+	// Deletions flags this many CIs of a type deleted in the window
+	Deletions int64 `json:"deletions"`
+	// TypeChurnRatio flags a type with this share of its CIs changed in the
+	// window, for types with at least TypeChurnMinCIs CIs
+	TypeChurnRatio  float64 `json:"type_churn_ratio"`
+	TypeChurnMinCIs int64   `json:"type_churn_min_cis"`
+	// SpikeFactor flags a window with this many times the average changes per
+	// window over the baseline before it, once at least SpikeMinEvents changed
+	SpikeFactor    float64       `json:"spike_factor"`
+	SpikeMinEvents int64         `json:"spike_min_events"`
+	Baseline       time.Duration `json:"baseline"`
+	// Cooldown is how long an anomaly is not raised again for the same subject
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+// DefaultThresholds returns the thresholds used when none are configured
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		Window:          time.Hour,
+		EntityChanges:   500,
+		Deletions:       100,
+		TypeChurnRatio:  0.8,
+		TypeChurnMinCIs: 20,
+		SpikeFactor:     5,
+		SpikeMinEvents:  1000,
+		Baseline:        7 * 24 * time.Hour,
+		Cooldown:        6 * time.Hour,
+	}
+}
+
+// Validate checks the thresholds
+func (t *Thresholds) Validate() error {
+	if t.Window <= 0 {
+		return fmt.Errorf("%w: window must be positive", ErrInvalidThresholds)
+	}
+	if t.EntityChanges < 0 || t.Deletions < 0 || t.TypeChurnMinCIs < 0 || t.SpikeMinEvents < 0 || t.Cooldown < 0 {
+		return fmt.Errorf("%w: thresholds cannot be negative", ErrInvalidThresholds)
+	}
+	if t.TypeChurnRatio < 0 || t.TypeChurnRatio > 1 {
+		return fmt.Errorf("%w: type churn ratio must be between 0 and 1", ErrInvalidThresholds)
+	}
+	if t.SpikeFactor < 0 || (t.SpikeFactor > 0 && t.SpikeFactor <= 1) {
+		return fmt.Errorf("%w: spike factor must be greater than 1, or 0 to disable", ErrInvalidThresholds)
+	}
+	if t.SpikeFactor > 0 && t.Baseline < t.Window {
+		return fmt.Errorf("%w: baseline must be at least one window", ErrInvalidThresholds)
+	}
+	return nil
+}
+
+// severity is critical at twice the threshold
+func severity(value, threshold float64) string {
+	if value >= 2*threshold {
+		return SeverityCritical
+	}
+	return SeverityWarning
+}
@@ -0,0 +1,289 @@
+package velocity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Default and maximum number of anomalies listed
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// Notifier delivers raised anomalies
+type Notifier interface {
+	Notify(ctx context.Context, anomaly *Anomaly) error
+}
+
+// LogNotifier writes anomalies to the log. It is used until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the anomaly
+func (LogNotifier) Notify(ctx context.Context, anomaly *Anomaly) error {
+	log.Printf("Change anomaly (%s, %s): %s", anomaly.Kind, anomaly.Severity, anomaly.Message)
+	return nil
+}
+
+// Service runs the detectors, records what they find and notifies about it
+type Service struct {
+	store      Store
+	notifier   Notifier
+	thresholds Thresholds
+	now        func() time.Time
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewService creates a new anomaly detection service. Thresholds must have been validated.
+func NewService(store Store, notifier Notifier, thresholds Thresholds) *Service {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	return &Service{store: store, notifier: notifier, thresholds: thresholds, now: time.Now}
+}
+
+// List retrieves anomalies, newest first
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]Anomaly, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit > MaxListLimit {
+		filter.Limit = MaxListLimit
+	}
+	return s.store.ListAnomalies(ctx, filter)
+}
+
+// Get retrieves an anomaly
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Anomaly, error) {
+	return s.store.GetAnomaly(ctx, id)
+}
+
+// Acknowledge marks an anomaly as seen. Acknowledging it again changes nothing.
+func (s *Service) Acknowledge(ctx context.Context, id uuid.UUID, by string) (*Anomaly, error) {
+	anomaly, err := s.store.GetAnomaly(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if anomaly.AcknowledgedAt != nil {
+		return anomaly, nil
+	}
+	if err := s.store.AcknowledgeAnomaly(ctx, id, s.now(), by); err != nil {
+		return nil, err
+	}
+	return s.store.GetAnomaly(ctx, id)
+}
+
+// Run detects anomalies at each interval
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Detect(ctx); err != nil && err != ErrDetectionRunning {
+				log.Printf("Failed to detect change anomalies: %v", err)
+			}
+		}
+	}
+}
+
+// Detect runs every enabled detector over the last window and returns the
+// anomalies raised. Anomalies already raised for the same subject within the
+// cooldown are not raised again.
+func (s *Service) Detect(ctx context.Context) ([]Anomaly, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrDetectionRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	end := s.now()
+	start := end.Add(-s.thresholds.Window)
+
+	var found []Anomaly
+	for _, detect := range []func(context.Context, time.Time, time.Time) ([]Anomaly, error){
+		s.detectHotEntities, s.detectMassDeletions, s.detectTypeChurn, s.detectChangeSpike,
+	} {
+		anomalies, err := detect(ctx, start, end)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, anomalies...)
+	}
+
+	raised := []Anomaly{}
+	for i := range found {
+		anomaly := &found[i]
+		recent, err := s.store.RecentlyRaised(ctx, anomaly.Kind, anomaly.Subject, end.Add(-s.thresholds.Cooldown))
+		if err != nil {
+			return nil, err
+		}
+		if recent {
+			continue
+		}
+
+		anomaly.ID = uuid.New()
+		anomaly.WindowStart = start
+		anomaly.WindowEnd = end
+		anomaly.DetectedAt = end
+		if err := s.store.CreateAnomaly(ctx, anomaly); err != nil {
+			return nil, err
+		}
+		if err := s.notifier.Notify(ctx, anomaly); err != nil {
+			log.Printf("Failed to notify about change anomaly %s: %v", anomaly.ID, err)
+		}
+		raised = append(raised, *anomaly)
+	}
+	return raised, nil
+}
+
+// detectHotEntities flags entities changed too often
+func (s *Service) detectHotEntities(ctx context.Context, start, end time.Time) ([]Anomaly, error) {
+	threshold := s.thresholds.EntityChanges
+	if threshold == 0 {
+		return nil, nil
+	}
+
+	entities, err := s.store.HotEntities(ctx, start, threshold)
+	if err != nil {
+		return nil, err
+	}
+	anomalies := make([]Anomaly, 0, len(entities))
+	for _, entity := range entities {
+		name := entity.EntityID.String()
+		if entity.Name != "" {
+			name = fmt.Sprintf("%q (%s)", entity.Name, entity.EntityID)
+		}
+		anomalies = append(anomalies, Anomaly{
+			Kind:      KindHotEntity,
+			Severity:  severity(float64(entity.Changes), float64(threshold)),
+			Subject:   entity.EntityID.String(),
+			Message:   fmt.Sprintf("%s %s changed %d times in %s", entity.EntityType, name, entity.Changes, s.thresholds.Window),
+			Count:     entity.Changes,
+			Threshold: float64(threshold),
+		})
+	}
+	return anomalies, nil
+}
+
+// detectMassDeletions flags types losing many CIs at once
+func (s *Service) detectMassDeletions(ctx context.Context, start, end time.Time) ([]Anomaly, error) {
+	threshold := s.thresholds.Deletions
+	if threshold == 0 {
+		return nil, nil
+	}
+
+	counts, err := s.store.Deletions(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	var anomalies []Anomaly
+	for _, count := range counts {
+		if count.Count < threshold {
+			continue
+		}
+		anomalies = append(anomalies, Anomaly{
+			Kind:      KindMassDeletion,
+			Severity:  severity(float64(count.Count), float64(threshold)),
+			Subject:   count.Type,
+			Message:   fmt.Sprintf("%d CIs of type %s deleted in %s", count.Count, count.Type, s.thresholds.Window),
+			Count:     count.Count,
+			Threshold: float64(threshold),
+		})
+	}
+	return anomalies, nil
+}
+
+// detectTypeChurn flags types whose CIs were nearly all rewritten at once
+func (s *Service) detectTypeChurn(ctx context.Context, start, end time.Time) ([]Anomaly, error) {
+	ratio := s.thresholds.TypeChurnRatio
+	if ratio == 0 {
+		return nil, nil
+	}
+
+	counts, err := s.store.TypeChurn(ctx, start, s.thresholds.TypeChurnMinCIs)
+	if err != nil {
+		return nil, err
+	}
+	var anomalies []Anomaly
+	for _, count := range counts {
+		if count.Total == 0 {
+			continue
+		}
+		share := float64(count.Count) / float64(count.Total)
+		if share < ratio {
+			continue
+		}
+		// The share cannot double past 100%, so critical means every CI changed
+		level := SeverityWarning
+		if count.Count == count.Total {
+			level = SeverityCritical
+		}
+		anomalies = append(anomalies, Anomaly{
+			Kind:      KindTypeChurn,
+			Severity:  level,
+			Subject:   count.Type,
+			Message:   fmt.Sprintf("%d of %d CIs of type %s (%.0f%%) changed in %s", count.Count, count.Total, count.Type, share*100, s.thresholds.Window),
+			Count:     count.Count,
+			Threshold: ratio,
+		})
+	}
+	return anomalies, nil
+}
+
+// detectChangeSpike flags a window with far more changes than usual
+func (s *Service) detectChangeSpike(ctx context.Context, start, end time.Time) ([]Anomaly, error) {
+	factor := s.thresholds.SpikeFactor
+	if factor == 0 {
+		return nil, nil
+	}
+
+	current, err := s.store.CountChanges(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if current < s.thresholds.SpikeMinEvents {
+		return nil, nil
+	}
+	before, err := s.store.CountChanges(ctx, start.Add(-s.thresholds.Baseline), start)
+	if err != nil {
+		return nil, err
+	}
+	// Without history there is nothing to compare against, as on a fresh CMDB
+	if before == 0 {
+		return nil, nil
+	}
+
+	windows := float64(s.thresholds.Baseline) / float64(s.thresholds.Window)
+	average := float64(before) / windows
+	threshold := factor * average
+	if float64(current) < threshold {
+		return nil, nil
+	}
+	return []Anomaly{{
+		Kind:      KindChangeSpike,
+		Severity:  severity(float64(current), threshold),
+		Subject:   "*",
+		Message:   fmt.Sprintf("%d changes in %s, %.1f times the average of %.1f over the previous %s", current, s.thresholds.Window, float64(current)/average, average, s.thresholds.Baseline),
+		Count:     current,
+		Threshold: threshold,
+	}}, nil
+}
@@ -0,0 +1,202 @@
+package velocity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxHotEntities bounds the entities reported by one run
+const maxHotEntities = 100
+
+// EntityChanges counts the changes to one CI or relationship
+type EntityChanges struct {
+	EntityType string    `db:"entity_type"`
+	EntityID   uuid.UUID `db:"entity_id"`
+	Name       string    `db:"name"`
+	Changes    int64     `db:"changes"`
+}
+
+// TypeCount counts the CIs of a type
+type TypeCount struct {
+	Type  string `db:"type"`
+	Count int64  `db:"count"`
+	Total int64  `db:"total"`
+}
+
+// ListFilter selects anomalies
+type ListFilter struct {
+	Kind string
+	// Open selects unacknowledged anomalies, Acknowledged the others; neither selects all
+	Open         bool
+	Acknowledged bool
+	Limit        int
+}
+
+// Store reads change activity and persists anomalies
+type Store interface {
+	// HotEntities returns the entities changed at least threshold times since a time, most changed first
+	HotEntities(ctx context.Context, since time.Time, threshold int64) ([]EntityChanges, error)
+	// Deletions returns the number of CIs of each type deleted since a time
+	Deletions(ctx context.Context, since time.Time) ([]TypeCount, error)
+	// TypeChurn returns, per type with at least minCIs CIs existing before a
+	// time, how many of those CIs were changed since
+	TypeChurn(ctx context.Context, since time.Time, minCIs int64) ([]TypeCount, error)
+	// CountChanges returns the number of changes in [from, to)
+	CountChanges(ctx context.Context, from, to time.Time) (int64, error)
+
+	// RecentlyRaised reports whether an anomaly of the kind was raised for the subject since a time
+	RecentlyRaised(ctx context.Context, kind, subject string, since time.Time) (bool, error)
+	CreateAnomaly(ctx context.Context, anomaly *Anomaly) error
+	GetAnomaly(ctx context.Context, id uuid.UUID) (*Anomaly, error)
+	// ListAnomalies returns anomalies, newest first
+	ListAnomalies(ctx context.Context, filter ListFilter) ([]Anomaly, error)
+	AcknowledgeAnomaly(ctx context.Context, id uuid.UUID, at time.Time, by string) error
+}
+
+// PostgresStore reads change activity from the sync_events and
+// configuration_items tables and keeps anomalies in change_anomalies
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed anomaly store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// HotEntities counts the sync events per entity
+func (s *PostgresStore) HotEntities(ctx context.Context, since time.Time, threshold int64) ([]EntityChanges, error) {
+	entities := []EntityChanges{}
+	err := s.db.SelectContext(ctx, &entities, `
+		SELECT entity_type, entity_id, COALESCE(MAX(data->>'name'), '') AS name, COUNT(*) AS changes
+		FROM sync_events
+		WHERE created_at >= $1
+		GROUP BY entity_type, entity_id
+		HAVING COUNT(*) >= $2
+		ORDER BY changes DESC
+		LIMIT $3`, since, threshold, maxHotEntities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count changes per entity: %w", err)
+	}
+	return entities, nil
+}
+
+// Deletions counts soft-deleted CIs along with hard deletes recorded as sync events
+func (s *PostgresStore) Deletions(ctx context.Context, since time.Time) ([]TypeCount, error) {
+	counts := []TypeCount{}
+	err := s.db.SelectContext(ctx, &counts, `
+		SELECT type, SUM(n)::BIGINT AS count, 0::BIGINT AS total
+		FROM (
+			SELECT type, COUNT(*) AS n FROM configuration_items
+			WHERE is_deleted = true AND updated_at >= $1
+			GROUP BY type
+			UNION ALL
+			SELECT COALESCE(data->>'type', ''), COUNT(*) FROM sync_events
+			WHERE entity_type = 'configuration_item' AND action = 'DELETE' AND created_at >= $1
+			GROUP BY data->>'type'
+		) deletions
+		GROUP BY type`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count deletions: %w", err)
+	}
+	return counts, nil
+}
+
+// TypeChurn counts the changed CIs per type, leaving out CIs created in the
+// window so initial loads are not flagged
+func (s *PostgresStore) TypeChurn(ctx context.Context, since time.Time, minCIs int64) ([]TypeCount, error) {
+	counts := []TypeCount{}
+	err := s.db.SelectContext(ctx, &counts, `
+		SELECT type, COUNT(*) FILTER (WHERE updated_at >= $1) AS count, COUNT(*) AS total
+		FROM configuration_items
+		WHERE is_deleted = false AND created_at < $1
+		GROUP BY type
+		HAVING COUNT(*) >= $2`, since, minCIs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count changed CIs per type: %w", err)
+	}
+	return counts, nil
+}
+
+// CountChanges counts the sync events in a time range
+func (s *PostgresStore) CountChanges(ctx context.Context, from, to time.Time) (int64, error) {
+	var count int64
+	if err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM sync_events WHERE created_at >= $1 AND created_at < $2`, from, to); err != nil {
+		return 0, fmt.Errorf("failed to count changes: %w", err)
+	}
+	return count, nil
+}
+
+// RecentlyRaised checks for an anomaly of the kind and subject detected since a time
+func (s *PostgresStore) RecentlyRaised(ctx context.Context, kind, subject string, since time.Time) (bool, error) {
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (SELECT 1 FROM change_anomalies WHERE kind = $1 AND subject = $2 AND detected_at >= $3)`,
+		kind, subject, since)
+	if err != nil {
+		return false, fmt.Errorf("failed to check raised anomalies: %w", err)
+	}
+	return exists, nil
+}
+
+// CreateAnomaly inserts an anomaly
+func (s *PostgresStore) CreateAnomaly(ctx context.Context, anomaly *Anomaly) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO change_anomalies (id, kind, severity, subject, message, count, threshold, window_start, window_end, detected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		anomaly.ID, anomaly.Kind, anomaly.Severity, anomaly.Subject, anomaly.Message, anomaly.Count,
+		anomaly.Threshold, anomaly.WindowStart, anomaly.WindowEnd, anomaly.DetectedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create anomaly: %w", err)
+	}
+	return nil
+}
+
+const anomalyColumns = `id, kind, severity, subject, message, count, threshold, window_start, window_end, detected_at,
+	acknowledged_at, COALESCE(acknowledged_by, '') AS acknowledged_by`
+
+// GetAnomaly retrieves an anomaly
+func (s *PostgresStore) GetAnomaly(ctx context.Context, id uuid.UUID) (*Anomaly, error) {
+	var anomaly Anomaly
+	if err := s.db.GetContext(ctx, &anomaly, `SELECT `+anomalyColumns+` FROM change_anomalies WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAnomalyNotFound
+		}
+		return nil, fmt.Errorf("failed to get anomaly: %w", err)
+	}
+	return &anomaly, nil
+}
+
+// ListAnomalies retrieves anomalies, newest first
+func (s *PostgresStore) ListAnomalies(ctx context.Context, filter ListFilter) ([]Anomaly, error) {
+	anomalies := []Anomaly{}
+	err := s.db.SelectContext(ctx, &anomalies, `SELECT `+anomalyColumns+` FROM change_anomalies
+		WHERE ($1 = '' OR kind = $1)
+		  AND (NOT $2 OR acknowledged_at IS NULL)
+		  AND (NOT $3 OR acknowledged_at IS NOT NULL)
+		ORDER BY detected_at DESC
+		LIMIT $4`, filter.Kind, filter.Open, filter.Acknowledged, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomalies: %w", err)
+	}
+	return anomalies, nil
+}
+
+// AcknowledgeAnomaly marks an open anomaly as seen
+func (s *PostgresStore) AcknowledgeAnomaly(ctx context.Context, id uuid.UUID, at time.Time, by string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE change_anomalies SET acknowledged_at = $2, acknowledged_by = $3
+		WHERE id = $1 AND acknowledged_at IS NULL`, id, at, by)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge anomaly: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrAnomalyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package velocity
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// event is a recorded change
+type event struct {
+	entityType string
+	entityID   uuid.UUID
+	name       string
+	ciType     string
+	deleted    bool
+	at         time.Time
+}
+
+// memoryStore is an in-memory Store. CI counts per type are given directly
+// rather than derived from events.
+type memoryStore struct {
+	events    []event
+	totals    map[string]int64
+	changed   map[string]int64
+	anomalies []Anomaly
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{totals: map[string]int64{}, changed: map[string]int64{}}
+}
+
+func (m *memoryStore) record(n int, e event) {
+	for i := 0; i < n; i++ {
+		m.events = append(m.events, e)
+	}
+}
+
+func (m *memoryStore) HotEntities(ctx context.Context, since time.Time, threshold int64) ([]EntityChanges, error) {
+	counts := map[uuid.UUID]*EntityChanges{}
+	for _, e := range m.events {
+		if e.at.Before(since) {
+			continue
+		}
+		if counts[e.entityID] == nil {
+			counts[e.entityID] = &EntityChanges{EntityType: e.entityType, EntityID: e.entityID, Name: e.name}
+		}
+		counts[e.entityID].Changes++
+	}
+	entities := []EntityChanges{}
+	for _, c := range counts {
+		if c.Changes >= threshold {
+			entities = append(entities, *c)
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Changes > entities[j].Changes })
+	return entities, nil
+}
+
+func (m *memoryStore) Deletions(ctx context.Context, since time.Time) ([]TypeCount, error) {
+	counts := map[string]int64{}
+	for _, e := range m.events {
+		if e.deleted && !e.at.Before(since) {
+			counts[e.ciType]++
+		}
+	}
+	result := []TypeCount{}
+	for t, n := range counts {
+		result = append(result, TypeCount{Type: t, Count: n})
+	}
+	return result, nil
+}
+
+func (m *memoryStore) TypeChurn(ctx context.Context, since time.Time, minCIs int64) ([]TypeCount, error) {
+	result := []TypeCount{}
+	for t, total := range m.totals {
+		if total >= minCIs {
+			result = append(result, TypeCount{Type: t, Count: m.changed[t], Total: total})
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryStore) CountChanges(ctx context.Context, from, to time.Time) (int64, error) {
+	var n int64
+	for _, e := range m.events {
+		if !e.at.Before(from) && e.at.Before(to) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryStore) RecentlyRaised(ctx context.Context, kind, subject string, since time.Time) (bool, error) {
+	for _, a := range m.anomalies {
+		if a.Kind == kind && a.Subject == subject && !a.DetectedAt.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryStore) CreateAnomaly(ctx context.Context, anomaly *Anomaly) error {
+	m.anomalies = append(m.anomalies, *anomaly)
+	return nil
+}
+
+func (m *memoryStore) GetAnomaly(ctx context.Context, id uuid.UUID) (*Anomaly, error) {
+	for i := range m.anomalies {
+		if m.anomalies[i].ID == id {
+			anomaly := m.anomalies[i]
+			return &anomaly, nil
+		}
+	}
+	return nil, ErrAnomalyNotFound
+}
+
+func (m *memoryStore) ListAnomalies(ctx context.Context, filter ListFilter) ([]Anomaly, error) {
+	result := []Anomaly{}
+	for i := len(m.anomalies) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		a := m.anomalies[i]
+		if (filter.Kind != "" && a.Kind != filter.Kind) ||
+			(filter.Open && a.AcknowledgedAt != nil) ||
+			(filter.Acknowledged && a.AcknowledgedAt == nil) {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+func (m *memoryStore) AcknowledgeAnomaly(ctx context.Context, id uuid.UUID, at time.Time, by string) error {
+	for i := range m.anomalies {
+		if m.anomalies[i].ID == id && m.anomalies[i].AcknowledgedAt == nil {
+			m.anomalies[i].AcknowledgedAt = &at
+			m.anomalies[i].AcknowledgedBy = by
+			return nil
+		}
+	}
+	return ErrAnomalyNotFound
+}
+
+// recordingNotifier keeps the anomalies it is notified about
+type recordingNotifier struct {
+	notified []Anomaly
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, anomaly *Anomaly) error {
+	r.notified = append(r.notified, *anomaly)
+	return nil
+}
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestService returns a service with only the given detector thresholds enabled
+func newTestService(store Store, configure func(*Thresholds)) (*Service, *recordingNotifier) {
+	thresholds := Thresholds{Window: time.Hour, Baseline: 24 * time.Hour, Cooldown: 6 * time.Hour}
+	configure(&thresholds)
+	notifier := &recordingNotifier{}
+	service := NewService(store, notifier, thresholds)
+	service.now = func() time.Time { return now }
+	return service, notifier
+}
+
+func TestThresholdsValidate(t *testing.T) {
+	valid := DefaultThresholds()
+	require.NoError(t, valid.Validate())
+
+	for name, change := range map[string]func(*Thresholds){
+		"no window":         func(t *Thresholds) { t.Window = 0 },
+		"negative":          func(t *Thresholds) { t.Deletions = -1 },
+		"ratio above one":   func(t *Thresholds) { t.TypeChurnRatio = 1.5 },
+		"spike factor":      func(t *Thresholds) { t.SpikeFactor = 0.5 },
+		"baseline < window": func(t *Thresholds) { t.Baseline = 30 * time.Minute },
+	} {
+		thresholds := DefaultThresholds()
+		change(&thresholds)
+		assert.ErrorIs(t, thresholds.Validate(), ErrInvalidThresholds, name)
+	}
+
+	disabled := DefaultThresholds()
+	disabled.SpikeFactor = 0
+	disabled.Baseline = 0
+	assert.NoError(t, disabled.Validate())
+}
+
+func TestDetectHotEntity(t *testing.T) {
+	store := newMemoryStore()
+	hot, calm := uuid.New(), uuid.New()
+	store.record(1100, event{entityType: "configuration_item", entityID: hot, name: "web-01", at: now.Add(-10 * time.Minute)})
+	store.record(499, event{entityType: "configuration_item", entityID: calm, at: now.Add(-10 * time.Minute)})
+	// Changes before the window are not counted
+	store.record(600, event{entityType: "configuration_item", entityID: calm, at: now.Add(-2 * time.Hour)})
+
+	service, notifier := newTestService(store, func(t *Thresholds) { t.EntityChanges = 500 })
+	raised, err := service.Detect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, raised, 1)
+
+	anomaly := raised[0]
+	assert.Equal(t, KindHotEntity, anomaly.Kind)
+	assert.Equal(t, SeverityCritical, anomaly.Severity)
+	assert.Equal(t, hot.String(), anomaly.Subject)
+	assert.Equal(t, int64(1100), anomaly.Count)
+	assert.Contains(t, anomaly.Message, `"web-01"`)
+	assert.Equal(t, now.Add(-time.Hour), anomaly.WindowStart)
+	assert.Equal(t, now, anomaly.DetectedAt)
+	assert.Len(t, notifier.notified, 1)
+}
+
+func TestDetectMassDeletion(t *testing.T) {
+	store := newMemoryStore()
+	store.record(150, event{entityID: uuid.New(), ciType: "server", deleted: true, at: now.Add(-time.Minute)})
+	store.record(20, event{entityID: uuid.New(), ciType: "database", deleted: true, at: now.Add(-time.Minute)})
+
+	service, _ := newTestService(store, func(t *Thresholds) { t.Deletions = 100 })
+	raised, err := service.Detect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, raised, 1)
+	assert.Equal(t, KindMassDeletion, raised[0].Kind)
+	assert.Equal(t, "server", raised[0].Subject)
+	assert.Equal(t, SeverityWarning, raised[0].Severity)
+}
+
+func TestDetectTypeChurn(t *testing.T) {
+	store := newMemoryStore()
+	store.totals["server"], store.changed["server"] = 50, 45
+	store.totals["database"], store.changed["database"] = 50, 10
+	store.totals["switch"], store.changed["switch"] = 30, 30
+
+	service, _ := newTestService(store, func(t *Thresholds) {
+		t.TypeChurnRatio = 0.8
+		t.TypeChurnMinCIs = 40
+	})
+	raised, err := service.Detect(context.Background())
+	require.NoError(t, err)
+	// switch has too few CIs to be considered
+	require.Len(t, raised, 1)
+	assert.Equal(t, KindTypeChurn, raised[0].Kind)
+	assert.Equal(t, "server", raised[0].Subject)
+	assert.Contains(t, raised[0].Message, "45 of 50")
+
+	store.totals["switch"] = 40
+	store.changed["switch"] = 40
+	raised, err = service.Detect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, raised, 1)
+	assert.Equal(t, "switch", raised[0].Subject)
+	assert.Equal(t, SeverityCritical, raised[0].Severity)
+}
+
+func TestDetectChangeSpike(t *testing.T) {
+	configure := func(t *Thresholds) {
+		t.SpikeFactor = 5
+		t.SpikeMinEvents = 100
+	}
+
+	// No baseline yet
+	store := newMemoryStore()
+	store.record(500, event{entityID: uuid.New(), at: now.Add(-time.Minute)})
+	service, _ := newTestService(store, configure)
+	raised, err := service.Detect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, raised)
+
+	// 240 changes over the 24 hour baseline averages 10 per window
+	store.record(240, event{entityID: uuid.New(), at: now.Add(-12 * time.Hour)})
+	raised, err = service.Detect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, raised, 1)
+	assert.Equal(t, KindChangeSpike, raised[0].Kind)
+	assert.Equal(t, "*", raised[0].Subject)
+	assert.Equal(t, int64(500), raised[0].Count)
+	assert.InDelta(t, 50, raised[0].Threshold, 0.001)
+	assert.Equal(t, SeverityCritical, raised[0].Severity)
+
+	// Below the minimum number of events nothing is flagged
+	quiet := newMemoryStore()
+	quiet.record(99, event{entityID: uuid.New(), at: now.Add(-time.Minute)})
+	quiet.record(24, event{entityID: uuid.New(), at: now.Add(-12 * time.Hour)})
+	service, _ = newTestService(quiet, configure)
+	raised, err = service.Detect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, raised)
+}
+
+func TestDetectCooldown(t *testing.T) {
+	store := newMemoryStore()
+	store.record(200, event{entityID: uuid.New(), ciType: "server", deleted: true, at: now.Add(-time.Minute)})
+
+	service, notifier := newTestService(store, func(t *Thresholds) { t.Deletions = 100 })
+	raised, err := service.Detect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, raised, 1)
+
+	// Raised again within the cooldown is suppressed
+	service.now = func() time.Time { return now.Add(time.Hour) }
+	store.record(200, event{entityID: uuid.New(), ciType: "server", deleted: true, at: now.Add(30 * time.Minute)})
+	raised, err = service.Detect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, raised)
+
+	// After the cooldown it is raised again
+	service.now = func() time.Time { return now.Add(7 * time.Hour) }
+	store.record(200, event{entityID: uuid.New(), ciType: "server", deleted: true, at: now.Add(6*time.Hour + 30*time.Minute)})
+	raised, err = service.Detect(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, raised, 1)
+	assert.Len(t, notifier.notified, 2)
+}
+
+func TestDetectDisabled(t *testing.T) {
+	store := newMemoryStore()
+	store.record(1000, event{entityID: uuid.New(), ciType: "server", deleted: true, at: now.Add(-time.Minute)})
+	store.totals["server"], store.changed["server"] = 100, 100
+
+	service, _ := newTestService(store, func(t *Thresholds) {})
+	raised, err := service.Detect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, raised)
+}
+
+func TestDetectRunning(t *testing.T) {
+	service, _ := newTestService(newMemoryStore(), func(t *Thresholds) {})
+	service.running = true
+	_, err := service.Detect(context.Background())
+	assert.ErrorIs(t, err, ErrDetectionRunning)
+}
+
+func TestListAndAcknowledge(t *testing.T) {
+	store := newMemoryStore()
+	store.record(200, event{entityID: uuid.New(), ciType: "server", deleted: true, at: now.Add(-time.Minute)})
+	store.record(300, event{entityID: uuid.New(), ciType: "database", deleted: true, at: now.Add(-time.Minute)})
+
+	service, _ := newTestService(store, func(t *Thresholds) { t.Deletions = 100 })
+	raised, err := service.Detect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, raised, 2)
+
+	acknowledged, err := service.Acknowledge(context.Background(), raised[0].ID, "alice")
+	require.NoError(t, err)
+	require.NotNil(t, acknowledged.AcknowledgedAt)
+	assert.Equal(t, "alice", acknowledged.AcknowledgedBy)
+
+	// Acknowledging again keeps the first acknowledgement
+	again, err := service.Acknowledge(context.Background(), raised[0].ID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", again.AcknowledgedBy)
+
+	_, err = service.Acknowledge(context.Background(), uuid.New(), "alice")
+	assert.ErrorIs(t, err, ErrAnomalyNotFound)
+
+	open, err := service.List(context.Background(), ListFilter{Open: true})
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, raised[1].ID, open[0].ID)
+
+	done, err := service.List(context.Background(), ListFilter{Acknowledged: true})
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, raised[0].ID, done[0].ID)
+
+	all, err := service.List(context.Background(), ListFilter{Kind: KindMassDeletion})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -0,0 +1,202 @@
+// Package visibility restricts which CIs a caller can see in list, search, graph
+// and impact responses.
+//
+// Visibility follows the caller's effective permissions. "ci:read" grants every
+// CI, while scoped grants restrict it to matching CIs:
+//
+//	ci:read:type=server
+//	ci:read:tag=payment
+//	ci:read:owner=platform-team
+//	ci:read:location=dc-east
+//
+// Several scoped grants add up. Callers acting for a tenant additionally only see
+// CIs of their own tenant and shared CIs without a tenant.
+package visibility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+)
+
+// ReadPermission grants visibility of every CI
+const ReadPermission = "ci:read"
+
+// PermissionSource returns the permissions granted by a set of roles. It is
+// satisfied by auth.CachedPermissionResolver.
+type PermissionSource interface {
+	Permissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// CILookup loads CIs referenced by relationships
+type CILookup interface {
+	GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error)
+}
+
+// ErrTenantChanged is returned for updates moving a CI to another tenant
+var ErrTenantChanged = errors.New("the tenant of a CI cannot be changed")
+
+type contextKey string
+
+const tenantContextKey contextKey = "visibility_tenant"
+
+// WithTenant returns a context carrying the tenant the caller acts for. It must
+// only be set from authenticated information, never from unverified input.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey, tenant)
+}
+
+// TenantFromContext returns the tenant the caller acts for, if any
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey).(string)
+	return tenant
+}
+
+// TenantScope returns the scope of a caller whose permissions do not restrict
+// visibility: every CI of the tenant they act for and every shared CI
+func TenantScope(ctx context.Context) models.VisibilityScope {
+	return models.VisibilityScope{Unrestricted: true, Tenant: TenantFromContext(ctx)}
+}
+
+// CheckTenantUnchanged returns ErrTenantChanged when an update sets, changes
+// or removes the tenant of a CI, which would move it to another tenant or
+// share it with every tenant
+func CheckTenantUnchanged(previous, updated *models.CI) error {
+	before, err := models.CITenant(previous)
+	if err != nil {
+		return err
+	}
+	after, err := models.CITenant(updated)
+	if err != nil {
+		return err
+	}
+	if before != after {
+		return fmt.Errorf("%w: %s %q would become %q", ErrTenantChanged, models.TenantAttribute, before, after)
+	}
+	return nil
+}
+
+// ScopeFromPermissions builds the visibility scope granted by a set of permissions
+func ScopeFromPermissions(permissions []string, tenant string) models.VisibilityScope {
+	scope := models.VisibilityScope{Tenant: tenant}
+	for _, permission := range permissions {
+		if permission == ReadPermission {
+			scope.Unrestricted = true
+			continue
+		}
+
+		qualifier := strings.TrimPrefix(permission, ReadPermission+":")
+		if qualifier == permission {
+			continue
+		}
+		key, value, ok := strings.Cut(qualifier, "=")
+		if !ok || value == "" {
+			continue
+		}
+		switch key {
+		case "type":
+			scope.Types = append(scope.Types, value)
+		case "tag":
+			scope.Tags = append(scope.Tags, value)
+		case "owner":
+			scope.Owners = append(scope.Owners, value)
+		case "location":
+			scope.Locations = append(scope.Locations, value)
+		}
+	}
+	return scope
+}
+
+// Resolver computes the visibility scope of callers from their roles
+type Resolver struct {
+	permissions PermissionSource
+}
+
+// NewResolver creates a new Resolver resolving role permissions through the
+// source, normally the cached resolver authorizing requests, so that role
+// changes invalidated there apply to visibility too
+func NewResolver(permissions PermissionSource) *Resolver {
+	return &Resolver{permissions: permissions}
+}
+
+// Scope returns the visibility scope of a caller with the given roles
+func (r *Resolver) Scope(ctx context.Context, roles []string) (models.VisibilityScope, error) {
+	permissions, err := r.Permissions(ctx, roles)
+	if err != nil {
+		return models.VisibilityScope{}, err
+	}
+	return ScopeFromPermissions(permissions, TenantFromContext(ctx)), nil
+}
+
+// Permissions returns the permissions granted by the given roles
+func (r *Resolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	return r.permissions.Permissions(ctx, roles)
+}
+
+// FilterCIs returns the CIs visible within the scope
+func FilterCIs(scope models.VisibilityScope, cis []models.CI) []models.CI {
+	visible := make([]models.CI, 0, len(cis))
+	for i := range cis {
+		if scope.Allows(&cis[i]) {
+			visible = append(visible, cis[i])
+		}
+	}
+	return visible
+}
+
+// Filter remembers which CIs are visible while post-filtering relationships,
+// graph nodes and impact results
+type Filter struct {
+	scope   models.VisibilityScope
+	lookup  CILookup
+	visible map[uuid.UUID]bool
+}
+
+// NewFilter creates a filter for one response
+func NewFilter(scope models.VisibilityScope, lookup CILookup) *Filter {
+	return &Filter{scope: scope, lookup: lookup, visible: make(map[uuid.UUID]bool)}
+}
+
+// Scope returns the scope the filter applies
+func (f *Filter) Scope() models.VisibilityScope {
+	return f.scope
+}
+
+// Remember records the visibility of an already loaded CI, saving a lookup
+func (f *Filter) Remember(ci *models.CI) bool {
+	visible := f.scope.Allows(ci)
+	f.visible[ci.ID] = visible
+	return visible
+}
+
+// CanSee reports whether a CI is visible. CIs that cannot be loaded are hidden.
+func (f *Filter) CanSee(ctx context.Context, id uuid.UUID) bool {
+	if f.scope.Unrestricted && f.scope.Tenant == "" {
+		return true
+	}
+	if visible, ok := f.visible[id]; ok {
+		return visible
+	}
+
+	ci, err := f.lookup.GetCI(ctx, id)
+	if err != nil {
+		f.visible[id] = false
+		return false
+	}
+	return f.Remember(ci)
+}
+
+// Relationships returns the relationships whose source and target are both visible
+func (f *Filter) Relationships(ctx context.Context, relationships []*models.CIRelationship) []*models.CIRelationship {
+	visible := make([]*models.CIRelationship, 0, len(relationships))
+	for _, rel := range relationships {
+		if f.CanSee(ctx, rel.SourceCIID) && f.CanSee(ctx, rel.TargetCIID) {
+			visible = append(visible, rel)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,156 @@
+package visibility
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"connect/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePermissions map[string][]string
+
+func (f fakePermissions) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		rolePermissions, ok := f[role]
+		if !ok {
+			return nil, errors.New("role not found")
+		}
+		permissions = append(permissions, rolePermissions...)
+	}
+	return permissions, nil
+}
+
+type fakeCIs map[uuid.UUID]*models.CI
+
+func (f fakeCIs) GetCI(ctx context.Context, id uuid.UUID) (*models.CI, error) {
+	if ci, ok := f[id]; ok {
+		return ci, nil
+	}
+	return nil, errors.New("CI not found")
+}
+
+func newCI(ciType, owner string, tags []string, tenant string) *models.CI {
+	attrs := map[string]interface{}{}
+	if tenant != "" {
+		attrs[models.TenantAttribute] = tenant
+	}
+	raw, _ := json.Marshal(attrs)
+	return &models.CI{ID: uuid.New(), Type: ciType, Owner: owner, Tags: tags, Attributes: raw}
+}
+
+func TestScopeFromPermissions(t *testing.T) {
+	scope := ScopeFromPermissions([]string{
+		"ci:read:type=server", "ci:read:tag=payment", "ci:read:owner=team-a",
+		"ci:read:location=dc-1", "ci:read:bogus", "ci:update",
+	}, "acme")
+
+	assert.False(t, scope.Unrestricted)
+	assert.Equal(t, []string{"server"}, scope.Types)
+	assert.Equal(t, []string{"payment"}, scope.Tags)
+	assert.Equal(t, []string{"team-a"}, scope.Owners)
+	assert.Equal(t, []string{"dc-1"}, scope.Locations)
+	assert.Equal(t, "acme", scope.Tenant)
+
+	assert.True(t, ScopeFromPermissions([]string{"ci:read"}, "").Unrestricted)
+	none := ScopeFromPermissions([]string{"audit_log:read"}, "")
+	assert.True(t, none.SeesNothing())
+}
+
+func TestVisibilityScope_Allows(t *testing.T) {
+	scope := models.VisibilityScope{Types: []string{"server"}, Tags: []string{"payment"}, Tenant: "acme"}
+
+	assert.True(t, scope.Allows(newCI("server", "", nil, "")))
+	assert.True(t, scope.Allows(newCI("database", "", []string{"payment"}, "acme")))
+	assert.False(t, scope.Allows(newCI("database", "", []string{"billing"}, "")))
+	assert.False(t, scope.Allows(newCI("server", "", nil, "globex")))
+
+	unrestricted := models.VisibilityScope{Unrestricted: true, Tenant: "acme"}
+	assert.True(t, unrestricted.Allows(newCI("database", "", nil, "")))
+	assert.False(t, unrestricted.Allows(newCI("database", "", nil, "globex")))
+}
+
+func TestTenantScope(t *testing.T) {
+	// Updates and deletes by ID only reach CIs of the caller's tenant and shared ones
+	scope := TenantScope(WithTenant(context.Background(), "acme"))
+	assert.True(t, scope.Allows(newCI("server", "", nil, "acme")))
+	assert.True(t, scope.Allows(newCI("server", "", nil, "")))
+	assert.False(t, scope.Allows(newCI("server", "", nil, "globex")))
+
+	// Callers acting for no tenant reach every CI
+	scope = TenantScope(context.Background())
+	assert.True(t, scope.Allows(newCI("server", "", nil, "globex")))
+}
+
+func TestCheckTenantUnchanged(t *testing.T) {
+	owned := newCI("server", "", nil, "acme")
+	shared := newCI("server", "", nil, "")
+
+	for name, test := range map[string]struct {
+		previous, updated *models.CI
+	}{
+		"moved":    {owned, newCI("server", "", nil, "globex")},
+		"dropped":  {owned, shared},
+		"claimed":  {shared, owned},
+		"not json": {owned, &models.CI{Attributes: json.RawMessage(`[]`)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, CheckTenantUnchanged(test.previous, test.updated))
+		})
+	}
+	assert.ErrorIs(t, CheckTenantUnchanged(owned, shared), ErrTenantChanged)
+
+	renamed := newCI("database", "team-a", []string{"payment"}, "acme")
+	assert.NoError(t, CheckTenantUnchanged(owned, renamed))
+	assert.NoError(t, CheckTenantUnchanged(shared, newCI("database", "", nil, "")))
+}
+
+func TestResolver_Scope(t *testing.T) {
+	resolver := NewResolver(fakePermissions{
+		"viewer":       {"ci:read:type=server"},
+		"payments-ops": {"ci:read:tag=payment"},
+	})
+
+	ctx := WithTenant(context.Background(), "acme")
+	scope, err := resolver.Scope(ctx, []string{"viewer", "payments-ops"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"server"}, scope.Types)
+	assert.Equal(t, []string{"payment"}, scope.Tags)
+	assert.Equal(t, "acme", scope.Tenant)
+
+	_, err = resolver.Scope(ctx, []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestFilterCIs(t *testing.T) {
+	server := newCI("server", "", nil, "")
+	database := newCI("database", "", nil, "")
+
+	visible := FilterCIs(models.VisibilityScope{Types: []string{"server"}}, []models.CI{*server, *database})
+	require.Len(t, visible, 1)
+	assert.Equal(t, server.ID, visible[0].ID)
+}
+
+func TestFilter_Relationships(t *testing.T) {
+	app := newCI("application", "", []string{"payment"}, "")
+	db := newCI("database", "", []string{"payment"}, "")
+	secret := newCI("database", "", []string{"hr"}, "")
+	cis := fakeCIs{app.ID: app, db.ID: db, secret.ID: secret}
+
+	relationships := []*models.CIRelationship{
+		{ID: uuid.New(), SourceCIID: app.ID, TargetCIID: db.ID},
+		{ID: uuid.New(), SourceCIID: app.ID, TargetCIID: secret.ID},
+		{ID: uuid.New(), SourceCIID: app.ID, TargetCIID: uuid.New()},
+	}
+
+	filter := NewFilter(models.VisibilityScope{Tags: []string{"payment"}}, cis)
+	visible := filter.Relationships(context.Background(), relationships)
+	require.Len(t, visible, 1)
+	assert.Equal(t, relationships[0].ID, visible[0].ID)
+	assert.False(t, filter.CanSee(context.Background(), secret.ID))
+}
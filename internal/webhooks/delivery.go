@@ -0,0 +1,109 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery headers
+const (
+	SignatureHeader = "X-Conx-Signature"
+	EventHeader     = "X-Conx-Event"
+	DeliveryHeader  = "X-Conx-Delivery"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Event is a lifecycle event, the body POSTed to endpoints
+type Event struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Delivery is an event to deliver to an endpoint, and the outcome of the
+// attempts so far
+type Delivery struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	EndpointID     uuid.UUID       `json:"endpoint_id" db:"endpoint_id"`
+	EventID        uuid.UUID       `json:"event_id" db:"event_id"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      string          `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// Sign returns the signature header of a body sent at a time:
+// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">.
+// Receivers recompute it with the endpoint secret and should reject old
+// timestamps to prevent replays.
+func Sign(secret string, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before retrying after a failed attempt: 30
+// seconds doubling per attempt, up to six hours
+func Backoff(attempts int) time.Duration {
+	const base, max = 30 * time.Second, 6 * time.Hour
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// send POSTs a delivery's payload to an endpoint, returning the response
+// status, if any. Only 2xx responses count as delivered.
+func send(ctx context.Context, client *http.Client, endpoint *Endpoint, delivery *Delivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "conx-webhooks/1")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, delivery.Payload, now))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
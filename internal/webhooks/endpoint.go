@@ -0,0 +1,184 @@
+// Package webhooks notifies registered HTTP endpoints of CI, relationship and
+// schema lifecycle events and of alerts. Endpoints subscribe to event types, optionally
+// narrowed by an eventfilter expression. Each matching event is recorded as a
+// delivery, POSTed with an HMAC signature of the body and retried with
+// exponential backoff until the endpoint accepts it or the attempts run out;
+// the delivery log keeps every attempt's outcome.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"connect/internal/eventfilter"
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	EventCICreated           = "ci.created"
+	EventCIUpdated           = "ci.updated"
+	EventCIDeleted           = "ci.deleted"
+	EventRelationshipCreated = "relationship.created"
+	EventRelationshipUpdated = "relationship.updated"
+	EventRelationshipDeleted = "relationship.deleted"
+	EventSchemaCreated       = "schema.created"
+	EventSchemaUpdated       = "schema.updated"
+	EventSchemaDeleted       = "schema.deleted"
+	EventAlertFiring         = "alert.firing"
+	EventAlertResolved       = "alert.resolved"
+	// EventPing is sent by test deliveries only
+	EventPing = "ping"
+)
+
+// EventTypes are the event types endpoints can subscribe to
+var EventTypes = []string{
+	EventCICreated, EventCIUpdated, EventCIDeleted,
+	EventRelationshipCreated, EventRelationshipUpdated, EventRelationshipDeleted,
+	EventSchemaCreated, EventSchemaUpdated, EventSchemaDeleted,
+	EventAlertFiring, EventAlertResolved,
+}
+
+// AllEvents subscribes an endpoint to every event type
+const AllEvents = "*"
+
+var (
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidEndpoint  = errors.New("invalid webhook endpoint")
+)
+
+// Endpoint is a registered webhook endpoint
+type Endpoint struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"-" db:"secret"`
+	Events    []string  `json:"events" db:"-"`
+	Filter    string    `json:"filter,omitempty" db:"filter"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	filter *eventfilter.Filter
+}
+
+// EndpointRequest registers or updates an endpoint. An empty secret on
+// registration generates one; on update it keeps the current one.
+type EndpointRequest struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+	Filter string   `json:"filter,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// validate checks the request, normalizing the events
+func (r *EndpointRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.URL = strings.TrimSpace(r.URL)
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidEndpoint)
+	}
+	target, err := url.Parse(r.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidEndpoint)
+	}
+	if r.Secret != "" && len(r.Secret) < 16 {
+		return fmt.Errorf("%w: secret must be at least 16 characters", ErrInvalidEndpoint)
+	}
+
+	if len(r.Events) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", ErrInvalidEndpoint)
+	}
+	events := make([]string, 0, len(r.Events))
+	seen := make(map[string]bool, len(r.Events))
+	for _, event := range r.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !validEventPattern(event) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidEndpoint, event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	r.Events = events
+
+	if r.Filter = strings.TrimSpace(r.Filter); r.Filter != "" {
+		if _, err := eventfilter.Compile(r.Filter); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidEndpoint, err)
+		}
+	}
+	return nil
+}
+
+// validEventPattern accepts an event type, "*" or a resource wildcard like "ci.*"
+func validEventPattern(pattern string) bool {
+	if pattern == AllEvents {
+		return true
+	}
+	for _, eventType := range EventTypes {
+		if pattern == eventType || pattern == resource(eventType)+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// resource returns the resource part of an event type
+func resource(eventType string) string {
+	if i := strings.Index(eventType, "."); i >= 0 {
+		return eventType[:i]
+	}
+	return eventType
+}
+
+// Subscribed reports whether the endpoint subscribes to an event type. Ping
+// events go to the endpoint they test whatever it subscribes to.
+func (e *Endpoint) Subscribed(eventType string) bool {
+	if eventType == EventPing {
+		return true
+	}
+	for _, pattern := range e.Events {
+		if pattern == AllEvents || pattern == eventType || pattern == resource(eventType)+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the endpoint wants an event: it subscribes to its
+// type and its filter, if any, matches the event payload
+func (e *Endpoint) Matches(event *Event, payload map[string]interface{}) (bool, error) {
+	if !e.Active || !e.Subscribed(event.Type) {
+		return false, nil
+	}
+	if e.Filter == "" || event.Type == EventPing {
+		return true, nil
+	}
+	if e.filter == nil {
+		filter, err := eventfilter.Compile(e.Filter)
+		if err != nil {
+			return false, err
+		}
+		e.filter = filter
+	}
+	return e.filter.Match(payload)
+}
+
+// generateSecret returns a random signing secret
+func generateSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,365 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults used when options are not set
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 8
+	// dispatchBatchSize bounds the deliveries attempted per dispatch
+	dispatchBatchSize = 100
+	// claimLease is how long a claimed delivery is hidden from other
+	// dispatchers while it is attempted
+	claimLease = 5 * time.Minute
+)
+
+// Options configure the service
+type Options struct {
+	// Timeout bounds each delivery attempt
+	Timeout time.Duration
+	// MaxAttempts is the attempts after which a delivery is given up as failed
+	MaxAttempts int
+}
+
+// Service manages endpoints and delivers events to them
+type Service struct {
+	store       Store
+	client      *http.Client
+	maxAttempts int
+	now         func() time.Time
+	wake        chan struct{}
+}
+
+// NewService creates a new webhook service
+func NewService(store Store, opts Options) *Service {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	return &Service{
+		store:       store,
+		client:      &http.Client{Timeout: opts.Timeout},
+		maxAttempts: opts.MaxAttempts,
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// CreateEndpoint registers an endpoint, returning it with its signing
+// secret, which is not returned again
+func (s *Service) CreateEndpoint(ctx context.Context, req EndpointRequest, actor string) (*Endpoint, string, error) {
+	if err := req.validate(); err != nil {
+		return nil, "", err
+	}
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		secret = generated
+	}
+
+	now := s.now()
+	endpoint := &Endpoint{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		Filter:    req.Filter,
+		Active:    req.Active == nil || *req.Active,
+		CreatedBy: actor,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, "", err
+	}
+	return endpoint, secret, nil
+}
+
+// UpdateEndpoint replaces an endpoint's settings, keeping its secret unless a
+// new one is given and its active state unless set
+func (s *Service) UpdateEndpoint(ctx context.Context, id uuid.UUID, req EndpointRequest) (*Endpoint, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	endpoint, err := s.store.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.Name = req.Name
+	endpoint.URL = req.URL
+	endpoint.Events = req.Events
+	endpoint.Filter = req.Filter
+	if req.Secret != "" {
+		endpoint.Secret = req.Secret
+	}
+	if req.Active != nil {
+		endpoint.Active = *req.Active
+	}
+	endpoint.UpdatedAt = s.now()
+	if err := s.store.UpdateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes an endpoint and its delivery log
+func (s *Service) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	return s.store.DeleteEndpoint(ctx, id)
+}
+
+// GetEndpoint returns an endpoint
+func (s *Service) GetEndpoint(ctx context.Context, id uuid.UUID) (*Endpoint, error) {
+	return s.store.GetEndpoint(ctx, id)
+}
+
+// ListEndpoints returns every endpoint
+func (s *Service) ListEndpoints(ctx context.Context) ([]*Endpoint, error) {
+	return s.store.ListEndpoints(ctx, false)
+}
+
+// ListDeliveries returns the latest deliveries to an endpoint, optionally those with a status
+func (s *Service) ListDeliveries(ctx context.Context, endpointID uuid.UUID, status string, limit int) ([]*Delivery, error) {
+	if _, err := s.store.GetEndpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+	return s.store.ListDeliveries(ctx, endpointID, status, limit)
+}
+
+// Publish records a delivery of an event to every active endpoint that wants
+// it and wakes the dispatcher. Failures are logged rather than returned so
+// they never fail the change that caused the event.
+func (s *Service) Publish(ctx context.Context, eventType string, data interface{}) {
+	event := &Event{ID: uuid.New(), Type: eventType, OccurredAt: s.now().UTC(), Data: data}
+	if err := s.publish(ctx, event); err != nil {
+		log.Printf("Failed to publish webhook event %s: %v", eventType, err)
+	}
+}
+
+func (s *Service) publish(ctx context.Context, event *Event) error {
+	endpoints, err := s.store.ListEndpoints(ctx, true)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	body, payload, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []*Delivery
+	for _, endpoint := range endpoints {
+		matched, err := endpoint.Matches(event, payload)
+		if err != nil {
+			log.Printf("Webhook endpoint %s filter failed on event %s: %v", endpoint.ID, event.ID, err)
+			continue
+		}
+		if matched {
+			deliveries = append(deliveries, s.newDelivery(endpoint.ID, event, body))
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := s.store.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Test sends a ping event to an endpoint right away and returns the delivery
+// with the outcome
+func (s *Service) Test(ctx context.Context, endpointID uuid.UUID) (*Delivery, error) {
+	endpoint, err := s.store.GetEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	event := &Event{ID: uuid.New(), Type: EventPing, OccurredAt: s.now().UTC(), Data: map[string]interface{}{
+		"endpoint_id": endpoint.ID,
+		"name":        endpoint.Name,
+	}}
+	body, _, err := encodeEvent(event)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test deliveries are attempted here and never retried, so they are not
+	// left due for the dispatcher
+	delivery := s.newDelivery(endpoint.ID, event, body)
+	delivery.NextAttemptAt = nil
+	if err := s.store.CreateDeliveries(ctx, []*Delivery{delivery}); err != nil {
+		return nil, err
+	}
+	return s.attempt(ctx, endpoint, delivery, 1)
+}
+
+// Redeliver queues a new delivery of a logged delivery's event
+func (s *Service) Redeliver(ctx context.Context, deliveryID uuid.UUID) (*Delivery, error) {
+	previous, err := s.store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	delivery := &Delivery{
+		ID:            uuid.New(),
+		EndpointID:    previous.EndpointID,
+		EventID:       previous.EventID,
+		EventType:     previous.EventType,
+		Payload:       previous.Payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	if err := s.store.CreateDeliveries(ctx, []*Delivery{delivery}); err != nil {
+		return nil, err
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return delivery, nil
+}
+
+// Dispatch attempts the deliveries that are due, returning how many it attempted
+func (s *Service) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := s.store.ClaimDueDeliveries(ctx, s.now(), claimLease, dispatchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	endpoints := make(map[uuid.UUID]*Endpoint)
+	for _, delivery := range deliveries {
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			endpoint, err = s.store.GetEndpoint(ctx, delivery.EndpointID)
+			if err != nil {
+				log.Printf("Failed to load webhook endpoint %s: %v", delivery.EndpointID, err)
+				endpoint = nil
+			}
+			endpoints[delivery.EndpointID] = endpoint
+		}
+
+		if endpoint == nil || !endpoint.Active {
+			delivery.Status = DeliveryFailed
+			delivery.NextAttemptAt = nil
+			delivery.LastError = "endpoint is inactive or was deleted"
+			if err := s.store.UpdateDelivery(ctx, delivery); err != nil {
+				log.Printf("Failed to update webhook delivery %s: %v", delivery.ID, err)
+			}
+			continue
+		}
+		if _, err := s.attempt(ctx, endpoint, delivery, s.maxAttempts); err != nil {
+			log.Printf("Failed to update webhook delivery %s: %v", delivery.ID, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a retry
+// unless it succeeded or reached maxAttempts
+func (s *Service) attempt(ctx context.Context, endpoint *Endpoint, delivery *Delivery, maxAttempts int) (*Delivery, error) {
+	now := s.now()
+	status, err := send(ctx, s.client, endpoint, delivery, now)
+	delivery.Attempts++
+	delivery.LastStatusCode = nil
+	if status != 0 {
+		delivery.LastStatusCode = &status
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = DeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= maxAttempts:
+		delivery.Status = DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+	default:
+		next := now.Add(Backoff(delivery.Attempts))
+		delivery.Status = DeliveryPending
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+	}
+
+	if err := s.store.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Run dispatches due deliveries every interval, and as soon as events are
+// published, until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		// A full batch may leave more due deliveries behind
+		for {
+			attempted, err := s.Dispatch(ctx)
+			if err != nil {
+				log.Printf("Failed to dispatch webhook deliveries: %v", err)
+			}
+			if err != nil || attempted < dispatchBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// newDelivery creates a pending delivery of an event, due now
+func (s *Service) newDelivery(endpointID uuid.UUID, event *Event, body []byte) *Delivery {
+	now := s.now()
+	return &Delivery{
+		ID:            uuid.New(),
+		EndpointID:    endpointID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Payload:       body,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+}
+
+// encodeEvent returns the body of an event and the payload filters are
+// evaluated against
+func encodeEvent(event *Event) ([]byte, map[string]interface{}, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	return body, payload, nil
+}
@@ -0,0 +1,222 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Store persists endpoints and the delivery log, which doubles as the
+// delivery queue
+type Store interface {
+	CreateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	UpdateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	GetEndpoint(ctx context.Context, id uuid.UUID) (*Endpoint, error)
+	ListEndpoints(ctx context.Context, activeOnly bool) ([]*Endpoint, error)
+	CreateDeliveries(ctx context.Context, deliveries []*Delivery) error
+	// ClaimDueDeliveries returns up to limit pending deliveries due at now,
+	// postponing them by lease so that concurrent dispatchers skip them
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery *Delivery) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error)
+	ListDeliveries(ctx context.Context, endpointID uuid.UUID, status string, limit int) ([]*Delivery, error)
+}
+
+// PostgresStore keeps endpoints in webhook_endpoints and deliveries in webhook_deliveries
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a new Postgres backed webhook store
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const endpointColumns = `id, name, url, secret, events, filter, active, created_by, created_at, updated_at`
+
+const deliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+// CreateEndpoint inserts an endpoint
+func (s *PostgresStore) CreateEndpoint(ctx context.Context, endpoint *Endpoint) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_endpoints (`+endpointColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		endpoint.ID, endpoint.Name, endpoint.URL, endpoint.Secret, pq.Array(endpoint.Events),
+		endpoint.Filter, endpoint.Active, endpoint.CreatedBy, endpoint.CreatedAt, endpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// UpdateEndpoint updates an endpoint's settings
+func (s *PostgresStore) UpdateEndpoint(ctx context.Context, endpoint *Endpoint) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_endpoints
+		SET name = $2, url = $3, secret = $4, events = $5, filter = $6, active = $7, updated_at = $8
+		WHERE id = $1`,
+		endpoint.ID, endpoint.Name, endpoint.URL, endpoint.Secret, pq.Array(endpoint.Events),
+		endpoint.Filter, endpoint.Active, endpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return requireRow(res, ErrEndpointNotFound)
+}
+
+// DeleteEndpoint deletes an endpoint; its deliveries cascade
+func (s *PostgresStore) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	return requireRow(res, ErrEndpointNotFound)
+}
+
+// GetEndpoint retrieves an endpoint
+func (s *PostgresStore) GetEndpoint(ctx context.Context, id uuid.UUID) (*Endpoint, error) {
+	endpoints, err := s.queryEndpoints(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, ErrEndpointNotFound
+	}
+	return endpoints[0], nil
+}
+
+// ListEndpoints retrieves endpoints by name
+func (s *PostgresStore) ListEndpoints(ctx context.Context, activeOnly bool) ([]*Endpoint, error) {
+	return s.queryEndpoints(ctx, `
+		SELECT `+endpointColumns+` FROM webhook_endpoints
+		WHERE active OR NOT $1
+		ORDER BY name`, activeOnly)
+}
+
+func (s *PostgresStore) queryEndpoints(ctx context.Context, query string, args ...interface{}) ([]*Endpoint, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []*Endpoint{}
+	for rows.Next() {
+		endpoint := &Endpoint{}
+		var events pq.StringArray
+		if err := rows.Scan(&endpoint.ID, &endpoint.Name, &endpoint.URL, &endpoint.Secret, &events,
+			&endpoint.Filter, &endpoint.Active, &endpoint.CreatedBy, &endpoint.CreatedAt, &endpoint.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		endpoint.Events = events
+		endpoints = append(endpoints, endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// CreateDeliveries inserts deliveries in one transaction
+func (s *PostgresStore) CreateDeliveries(ctx context.Context, deliveries []*Delivery) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, d := range deliveries {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			d.ID, d.EndpointID, d.EventID, d.EventType, []byte(d.Payload), d.Status, d.Attempts,
+			d.NextAttemptAt, d.LastStatusCode, d.LastError, d.CreatedAt, d.DeliveredAt); err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries claims due deliveries, oldest first. Rows locked by
+// another dispatcher are skipped rather than waited for.
+func (s *PostgresStore) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	deliveries := []*Delivery{}
+	err := s.db.SelectContext(ctx, &deliveries, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery records the outcome of an attempt
+func (s *PostgresStore) UpdateDelivery(ctx context.Context, d *Delivery) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5, last_error = $6, delivered_at = $7
+		WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return requireRow(res, ErrDeliveryNotFound)
+}
+
+// GetDelivery retrieves a delivery
+func (s *PostgresStore) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	var delivery Delivery
+	err := s.db.GetContext(ctx, &delivery, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries retrieves the latest deliveries to an endpoint
+func (s *PostgresStore) ListDeliveries(ctx context.Context, endpointID uuid.UUID, status string, limit int) ([]*Delivery, error) {
+	deliveries := []*Delivery{}
+	err := s.db.SelectContext(ctx, &deliveries, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE endpoint_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`, endpointID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// requireRow returns notFound if a statement affected no row
+func requireRow(res sql.Result, notFound error) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rows == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mu         sync.Mutex
+	endpoints  map[uuid.UUID]*Endpoint
+	deliveries map[uuid.UUID]*Delivery
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{endpoints: map[uuid.UUID]*Endpoint{}, deliveries: map[uuid.UUID]*Delivery{}}
+}
+
+func (m *memoryStore) CreateEndpoint(ctx context.Context, endpoint *Endpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *endpoint
+	m.endpoints[endpoint.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) UpdateEndpoint(ctx context.Context, endpoint *Endpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.endpoints[endpoint.ID]; !ok {
+		return ErrEndpointNotFound
+	}
+	copied := *endpoint
+	copied.filter = nil
+	m.endpoints[endpoint.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.endpoints[id]; !ok {
+		return ErrEndpointNotFound
+	}
+	delete(m.endpoints, id)
+	return nil
+}
+
+func (m *memoryStore) GetEndpoint(ctx context.Context, id uuid.UUID) (*Endpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	endpoint, ok := m.endpoints[id]
+	if !ok {
+		return nil, ErrEndpointNotFound
+	}
+	copied := *endpoint
+	return &copied, nil
+}
+
+func (m *memoryStore) ListEndpoints(ctx context.Context, activeOnly bool) ([]*Endpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	endpoints := []*Endpoint{}
+	for _, endpoint := range m.endpoints {
+		if endpoint.Active || !activeOnly {
+			copied := *endpoint
+			endpoints = append(endpoints, &copied)
+		}
+	}
+	return endpoints, nil
+}
+
+func (m *memoryStore) CreateDeliveries(ctx context.Context, deliveries []*Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, delivery := range deliveries {
+		copied := *delivery
+		m.deliveries[delivery.ID] = &copied
+	}
+	return nil
+}
+
+func (m *memoryStore) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claimed := []*Delivery{}
+	for _, delivery := range m.deliveries {
+		if delivery.Status == DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) && len(claimed) < limit {
+			leased := now.Add(lease)
+			delivery.NextAttemptAt = &leased
+			copied := *delivery
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memoryStore) UpdateDelivery(ctx context.Context, delivery *Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.deliveries[delivery.ID]; !ok {
+		return ErrDeliveryNotFound
+	}
+	copied := *delivery
+	m.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delivery, ok := m.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+func (m *memoryStore) ListDeliveries(ctx context.Context, endpointID uuid.UUID, status string, limit int) ([]*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deliveries := []*Delivery{}
+	for _, delivery := range m.deliveries {
+		if delivery.EndpointID == endpointID && (status == "" || delivery.Status == status) {
+			copied := *delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+	return deliveries, nil
+}
+
+// receiver records the requests it gets, failing the first failures of them
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	failures int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestEndpointRequestValidation(t *testing.T) {
+	valid := EndpointRequest{Name: "ops", URL: "https://hooks.example.com/conx", Events: []string{"CI.created", "relationship.*", "ci.created"}}
+	require.NoError(t, valid.validate())
+	assert.Equal(t, []string{"ci.created", "relationship.*"}, valid.Events)
+
+	for _, invalid := range []EndpointRequest{
+		{URL: "https://hooks.example.com", Events: []string{"*"}},
+		{Name: "ops", URL: "ftp://hooks.example.com", Events: []string{"*"}},
+		{Name: "ops", URL: "https://hooks.example.com"},
+		{Name: "ops", URL: "https://hooks.example.com", Events: []string{"ci.exploded"}},
+		{Name: "ops", URL: "https://hooks.example.com", Events: []string{"*"}, Secret: "short"},
+		{Name: "ops", URL: "https://hooks.example.com", Events: []string{"*"}, Filter: "event.data.criticality =="},
+	} {
+		assert.ErrorIs(t, invalid.validate(), ErrInvalidEndpoint)
+	}
+}
+
+func TestPublishDeliversSignedEventsToMatchingEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store := newMemoryStore()
+	service := NewService(store, Options{Timeout: time.Second})
+	critical, secret, err := service.CreateEndpoint(ctx, EndpointRequest{
+		Name:   "critical CIs",
+		URL:    server.URL,
+		Secret: "0123456789abcdef0123",
+		Events: []string{"ci.*"},
+		Filter: `event.data.criticality == "critical"`,
+	}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef0123", secret)
+	_, generated, err := service.CreateEndpoint(ctx, EndpointRequest{Name: "schemas", URL: server.URL, Events: []string{"schema.created"}}, "admin")
+	require.NoError(t, err)
+	assert.Contains(t, generated, "whsec_")
+
+	service.Publish(ctx, EventCIUpdated, map[string]interface{}{"name": "db-1", "criticality": "critical"})
+	service.Publish(ctx, EventCIUpdated, map[string]interface{}{"name": "web-1", "criticality": "low"})
+	service.Publish(ctx, EventRelationshipCreated, map[string]interface{}{"type": "depends_on"})
+
+	attempted, err := service.Dispatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	require.Len(t, rc.requests, 1)
+	request, body := rc.requests[0], rc.bodies[0]
+	assert.Equal(t, EventCIUpdated, request.Header.Get(EventHeader))
+	var event Event
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "db-1", event.Data.(map[string]interface{})["name"])
+
+	// The signature is the HMAC of the timestamp and body under the secret
+	signature := request.Header.Get(SignatureHeader)
+	var timestamp int64
+	_, err = fmt.Sscanf(signature, "t=%d,", &timestamp)
+	require.NoError(t, err)
+	assert.Equal(t, Sign(secret, body, time.Unix(timestamp, 0)), signature)
+
+	deliveries, err := service.ListDeliveries(ctx, critical.ID, DeliverySucceeded, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, *deliveries[0].LastStatusCode)
+}
+
+func TestFailedDeliveriesAreRetriedWithBackoffThenGivenUp(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{failures: 100}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store := newMemoryStore()
+	service := NewService(store, Options{Timeout: time.Second, MaxAttempts: 3})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	endpoint, _, err := service.CreateEndpoint(ctx, EndpointRequest{Name: "flaky", URL: server.URL, Events: []string{"*"}}, "admin")
+	require.NoError(t, err)
+	service.Publish(ctx, EventCIDeleted, map[string]interface{}{"id": uuid.New()})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		attempted, err := service.Dispatch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempted, "attempt %d", attempt)
+
+		// Nothing is due again until the backoff has passed
+		attempted, err = service.Dispatch(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, attempted)
+		now = now.Add(Backoff(attempt))
+	}
+
+	deliveries, err := service.ListDeliveries(ctx, endpoint.ID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, "endpoint responded with status 503", deliveries[0].LastError)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+
+	// Redelivering queues a fresh delivery of the same event
+	rc.failures = 0
+	redelivery, err := service.Redeliver(ctx, deliveries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, deliveries[0].EventID, redelivery.EventID)
+	_, err = service.Dispatch(ctx)
+	require.NoError(t, err)
+	delivered, err := store.GetDelivery(ctx, redelivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, DeliverySucceeded, delivered.Status)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, 6*time.Hour, Backoff(50))
+}
+
+func TestTestSendsPingRegardlessOfSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	service := NewService(newMemoryStore(), Options{})
+	endpoint, _, err := service.CreateEndpoint(ctx, EndpointRequest{Name: "ops", URL: server.URL, Events: []string{"schema.deleted"}, Filter: "false"}, "admin")
+	require.NoError(t, err)
+
+	delivery, err := service.Test(ctx, endpoint.ID)
+	require.NoError(t, err)
+	assert.Equal(t, DeliverySucceeded, delivery.Status)
+	assert.Equal(t, EventPing, rc.requests[0].Header.Get(EventHeader))
+}
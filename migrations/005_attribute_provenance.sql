@@ -0,0 +1,30 @@
+-- Migration: Attribute Provenance
+-- Description: Track which source last set each CI attribute and when
+
+-- Create ci_attribute_provenance table
+CREATE TABLE IF NOT EXISTS ci_attribute_provenance (
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    attribute VARCHAR(255) NOT NULL,
+    source_type VARCHAR(50) NOT NULL,
+    source_name VARCHAR(255),
+    source_id VARCHAR(255),
+    set_by UUID,
+    set_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (ci_id, attribute),
+
+    -- Constraints
+    CONSTRAINT ci_attribute_provenance_source_type_check CHECK (source_type IN ('manual', 'discovery', 'import'))
+);
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_ci_attribute_provenance_source ON ci_attribute_provenance(source_type, source_name);
+CREATE INDEX IF NOT EXISTS idx_ci_attribute_provenance_source_id ON ci_attribute_provenance(source_id) WHERE source_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ci_attribute_provenance_set_at ON ci_attribute_provenance(set_at);
+
+-- Grant permissions (adjust based on your database user)
+-- GRANT ALL PRIVILEGES ON ci_attribute_provenance TO cmdb_user;
+
+-- Migration completion comment
+-- Migration 005: Attribute Provenance completed successfully
+-- Tables created: ci_attribute_provenance
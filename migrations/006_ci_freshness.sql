@@ -0,0 +1,20 @@
+-- Migration: CI Freshness
+-- Description: Index the freshness reference time of CIs for stale data detection
+
+-- Align scan/update timestamps with updated_at so the reference expression is immutable and indexable
+ALTER TABLE configuration_items
+    ALTER COLUMN last_updated TYPE TIMESTAMP WITH TIME ZONE USING last_updated AT TIME ZONE 'UTC',
+    ALTER COLUMN last_scanned TYPE TIMESTAMP WITH TIME ZONE USING last_scanned AT TIME ZONE 'UTC';
+
+-- The indexed expression must match freshnessReferenceExpr in the CI repository
+CREATE INDEX IF NOT EXISTS idx_configuration_items_freshness
+    ON configuration_items (type, (COALESCE(GREATEST(last_scanned, last_updated), updated_at)))
+    WHERE is_deleted = false;
+
+CREATE INDEX IF NOT EXISTS idx_configuration_items_freshness_reference
+    ON configuration_items ((COALESCE(GREATEST(last_scanned, last_updated), updated_at)))
+    WHERE is_deleted = false;
+
+-- Migration completion comment
+-- Migration 006: CI Freshness completed successfully
+-- Indexes created: idx_configuration_items_freshness, idx_configuration_items_freshness_reference
@@ -0,0 +1,21 @@
+-- Migration: Relationship Lifecycle
+-- Description: Add lifecycle states (proposed, active, deprecated) to CI relationships
+
+-- Add lifecycle columns to ci_relationships
+ALTER TABLE ci_relationships ADD COLUMN IF NOT EXISTS state VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE ci_relationships ADD COLUMN IF NOT EXISTS state_changed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE ci_relationships ADD COLUMN IF NOT EXISTS state_changed_by UUID;
+
+-- Constraints
+ALTER TABLE ci_relationships DROP CONSTRAINT IF EXISTS ci_relationships_state_check;
+ALTER TABLE ci_relationships ADD CONSTRAINT ci_relationships_state_check
+    CHECK (state IN ('proposed', 'active', 'deprecated'));
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_ci_relationships_state ON ci_relationships(state);
+CREATE INDEX IF NOT EXISTS idx_ci_relationships_source_state ON ci_relationships(source_ci_id, state);
+CREATE INDEX IF NOT EXISTS idx_ci_relationships_target_state ON ci_relationships(target_ci_id, state);
+
+-- Migration completion comment
+-- Migration 007: Relationship Lifecycle completed successfully
+-- Columns added: ci_relationships.state, state_changed_at, state_changed_by
@@ -0,0 +1,30 @@
+-- Migration: Feature Flags
+-- Description: Add feature flags with per-environment and per-tenant overrides
+
+-- Create feature flags table
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100)
+);
+
+-- Create feature flag overrides table
+CREATE TABLE IF NOT EXISTS feature_flag_overrides (
+    flag_key VARCHAR(100) NOT NULL REFERENCES feature_flags(key) ON DELETE CASCADE,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('environment', 'tenant')),
+    value VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100),
+    PRIMARY KEY (flag_key, scope, value)
+);
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_feature_flag_overrides_scope_value ON feature_flag_overrides(scope, value);
+
+-- Migration completion comment
+-- Migration 008: Feature Flags completed successfully
+-- Tables created: feature_flags, feature_flag_overrides
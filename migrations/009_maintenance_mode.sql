@@ -0,0 +1,20 @@
+-- Migration: Maintenance Mode
+-- Description: Persist the admin-controlled read-only maintenance mode
+
+-- Create maintenance mode table (single row)
+CREATE TABLE IF NOT EXISTS maintenance_mode (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    read_only BOOLEAN NOT NULL DEFAULT false,
+    reason TEXT NOT NULL DEFAULT '',
+    retry_after_seconds INTEGER NOT NULL DEFAULT 0 CHECK (retry_after_seconds >= 0),
+    expected_end TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100)
+);
+
+-- Start out of maintenance
+INSERT INTO maintenance_mode (id, read_only) VALUES (1, false) ON CONFLICT (id) DO NOTHING;
+
+-- Migration completion comment
+-- Migration 009: Maintenance Mode completed successfully
+-- Tables created: maintenance_mode
@@ -0,0 +1,29 @@
+-- Migration: Access Review
+-- Description: Role inheritance and persisted access review reports for auditors
+
+-- Create role inheritance table: a role grants everything the roles it inherits from grant
+CREATE TABLE IF NOT EXISTS role_inheritance (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    inherits_role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (role_id, inherits_role_id),
+    CHECK (role_id <> inherits_role_id)
+);
+
+-- Create access review reports table
+CREATE TABLE IF NOT EXISTS access_review_reports (
+    id UUID PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    requested_by VARCHAR(100) NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    error TEXT,
+    user_count INTEGER NOT NULL DEFAULT 0,
+    matrix JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_review_reports_requested_at ON access_review_reports(requested_at DESC);
+
+-- Migration completion comment
+-- Migration 010: Access Review completed successfully
+-- Tables created: role_inheritance, access_review_reports
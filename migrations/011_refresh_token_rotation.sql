@@ -0,0 +1,39 @@
+-- Migration: Refresh Token Rotation
+-- Description: Track single-use refresh tokens by family and record security events
+
+-- Create refresh tokens table: one row per issued refresh token (its jti)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY,
+    family_id UUID NOT NULL,
+    user_id VARCHAR(100) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    rotated_at TIMESTAMP WITH TIME ZONE,
+    replaced_by UUID,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoke_reason VARCHAR(100),
+    CONSTRAINT refresh_tokens_expires_at_check CHECK (expires_at > issued_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+-- Create security events table
+CREATE TABLE IF NOT EXISTS security_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_type VARCHAR(50) NOT NULL,
+    user_id VARCHAR(100),
+    details JSONB NOT NULL DEFAULT '{}',
+    ip_address INET,
+    user_agent TEXT,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_type ON security_events(event_type);
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_security_events_occurred_at ON security_events(occurred_at);
+
+-- Migration completion comment
+-- Migration 011: Refresh Token Rotation completed successfully
+-- Tables created: refresh_tokens, security_events
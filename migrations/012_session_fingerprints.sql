@@ -0,0 +1,11 @@
+-- Migration: Session Fingerprints
+-- Description: Bind sessions to the client fingerprint they were created from
+
+-- Add fingerprint columns to sessions
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_id TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ua_family VARCHAR(100);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip_prefix VARCHAR(50);
+
+-- Migration completion comment
+-- Migration 012: Session Fingerprints completed successfully
+-- Columns added: sessions.device_id, sessions.ua_family, sessions.ip_prefix
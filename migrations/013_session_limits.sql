@@ -0,0 +1,17 @@
+-- Migration: Session Limits
+-- Description: Concurrent session limits per role and tenant adjusted at runtime
+
+-- Create session limits table; configured limits apply where no row exists
+CREATE TABLE IF NOT EXISTS session_limits (
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('default', 'role', 'tenant')),
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    max_sessions INTEGER NOT NULL CHECK (max_sessions >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100),
+    PRIMARY KEY (scope, name),
+    CHECK ((scope = 'default') = (name = ''))
+);
+
+-- Migration completion comment
+-- Migration 013: Session Limits completed successfully
+-- Tables created: session_limits
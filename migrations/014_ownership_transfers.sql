@@ -0,0 +1,34 @@
+-- Migration: Ownership Transfers
+-- Description: CI ownership transfers that take effect only once the recipient accepts
+
+-- Create ownership transfers table
+CREATE TABLE IF NOT EXISTS ownership_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_owner VARCHAR(255) NOT NULL,
+    to_owner VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled', 'expired')),
+    reason TEXT,
+    initiated_by VARCHAR(100) NOT NULL,
+    deadline TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP WITH TIME ZONE,
+    responded_by VARCHAR(100),
+    note TEXT
+);
+
+-- Create ownership transfer items table
+CREATE TABLE IF NOT EXISTS ownership_transfer_items (
+    transfer_id UUID NOT NULL REFERENCES ownership_transfers(id) ON DELETE CASCADE,
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    PRIMARY KEY (transfer_id, ci_id)
+);
+
+-- Create indexes for recipient inboxes, the expiry job and per-CI lookups
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_to_owner ON ownership_transfers(to_owner, status);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_from_owner ON ownership_transfers(from_owner, status);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_pending_deadline ON ownership_transfers(deadline) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_ownership_transfer_items_ci_id ON ownership_transfer_items(ci_id);
+
+-- Migration completion comment
+-- Migration 014: Ownership Transfers completed successfully
+-- Tables created: ownership_transfers, ownership_transfer_items
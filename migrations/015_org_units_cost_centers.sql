@@ -0,0 +1,37 @@
+-- Migration: Org Units and Cost Centers
+-- Description: Managed org unit and cost center lists and CI chargeback assignment
+
+-- Create org units table
+CREATE TABLE IF NOT EXISTS org_units (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    parent_code VARCHAR(50) REFERENCES org_units(code),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create cost centers table
+CREATE TABLE IF NOT EXISTS cost_centers (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    org_unit_code VARCHAR(50) REFERENCES org_units(code),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Assign CIs to an org unit and cost center; values are validated against the
+-- lists by the API so entries can be deactivated without rewriting CIs
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS org_unit VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS cost_center VARCHAR(50) NOT NULL DEFAULT '';
+
+-- Create indexes for filtering and the chargeback report
+CREATE INDEX IF NOT EXISTS idx_org_units_parent_code ON org_units(parent_code);
+CREATE INDEX IF NOT EXISTS idx_cost_centers_org_unit_code ON cost_centers(org_unit_code);
+CREATE INDEX IF NOT EXISTS idx_configuration_items_org_unit ON configuration_items(org_unit);
+CREATE INDEX IF NOT EXISTS idx_configuration_items_cost_center ON configuration_items(cost_center);
+
+-- Migration completion comment
+-- Migration 015: Org Units and Cost Centers completed successfully
+-- Tables created: org_units, cost_centers
@@ -0,0 +1,11 @@
+-- Migration: Asset Tags
+-- Description: Fast lookup of CIs by the asset tag scanned from a physical label
+
+-- Create index on the asset_tag attribute used by the scan lookup endpoint
+CREATE INDEX IF NOT EXISTS idx_configuration_items_asset_tag
+    ON configuration_items ((attributes->>'asset_tag'))
+    WHERE is_deleted = false;
+
+-- Migration completion comment
+-- Migration 016: Asset Tags completed successfully
+-- Indexes created: idx_configuration_items_asset_tag
@@ -0,0 +1,21 @@
+-- Migration: Auto-Tagging Rules
+-- Description: Rules applying and removing CI tags from attribute values on write
+
+-- Create auto-tagging rules table
+CREATE TABLE IF NOT EXISTS auto_tag_rules (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    attribute VARCHAR(255) NOT NULL,
+    operator VARCHAR(20) NOT NULL CHECK (operator IN ('equals', 'contains', 'matches', 'exists')),
+    value TEXT NOT NULL DEFAULT '',
+    tag VARCHAR(100) NOT NULL,
+    remove_unmatched BOOLEAN NOT NULL DEFAULT false,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100)
+);
+
+-- Migration completion comment
+-- Migration 017: Auto-Tagging Rules completed successfully
+-- Tables created: auto_tag_rules
@@ -0,0 +1,29 @@
+-- Migration: CI Type Migrations
+-- Description: Jobs changing the type of many CIs at once, with attribute remapping and relationship type renames
+
+-- Create CI type migration jobs table
+CREATE TABLE IF NOT EXISTS ci_type_migrations (
+    id UUID PRIMARY KEY,
+    source_type VARCHAR(100) NOT NULL,
+    plan JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    requested_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    scanned INTEGER NOT NULL DEFAULT 0,
+    migrated INTEGER NOT NULL DEFAULT 0,
+    unmatched INTEGER NOT NULL DEFAULT 0,
+    invalid INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    relationships_updated INTEGER NOT NULL DEFAULT 0,
+    failures JSONB NOT NULL DEFAULT '[]',
+    error TEXT
+);
+
+-- Create index for listing the latest jobs
+CREATE INDEX IF NOT EXISTS idx_ci_type_migrations_created_at ON ci_type_migrations(created_at DESC);
+
+-- Migration completion comment
+-- Migration 018: CI Type Migrations completed successfully
+-- Tables created: ci_type_migrations
@@ -0,0 +1,39 @@
+-- Migration: Script Hooks
+-- Description: Admin-defined scripts run before and after CI saves, with their execution log
+
+-- Create script hooks table
+CREATE TABLE IF NOT EXISTS script_hooks (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    phase VARCHAR(20) NOT NULL CHECK (phase IN ('pre_save', 'post_save')),
+    ci_types TEXT[] NOT NULL DEFAULT '{}',
+    script TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    timeout_ms INTEGER NOT NULL DEFAULT 0 CHECK (timeout_ms BETWEEN 0 AND 1000),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100)
+);
+
+-- Create script hook executions table
+CREATE TABLE IF NOT EXISTS script_hook_executions (
+    id UUID PRIMARY KEY,
+    hook_id UUID NOT NULL REFERENCES script_hooks(id) ON DELETE CASCADE,
+    hook_name VARCHAR(255) NOT NULL,
+    ci_id UUID NOT NULL,
+    phase VARCHAR(20) NOT NULL,
+    event VARCHAR(20) NOT NULL,
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('ok', 'rejected', 'error', 'timeout')),
+    message TEXT,
+    logs JSONB NOT NULL DEFAULT '[]',
+    duration_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    executed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for per-hook execution logs
+CREATE INDEX IF NOT EXISTS idx_script_hook_executions_hook_id ON script_hook_executions(hook_id, executed_at DESC);
+
+-- Migration completion comment
+-- Migration 019: Script Hooks completed successfully
+-- Tables created: script_hooks, script_hook_executions
@@ -0,0 +1,25 @@
+-- Migration: Sync Resync Jobs
+-- Description: Orchestrated resyncs of the graph store with checkpoints, so paused or interrupted jobs resume where they stopped
+
+-- Create resync jobs table
+CREATE TABLE IF NOT EXISTS sync_resync_jobs (
+    id UUID PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'paused', 'completed', 'cancelled', 'failed')),
+    phases JSONB NOT NULL DEFAULT '[]',
+    current_phase INTEGER NOT NULL DEFAULT 0,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    requested_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    elapsed_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+-- Create indexes for listing the latest jobs and finding the unfinished one
+CREATE INDEX IF NOT EXISTS idx_sync_resync_jobs_created_at ON sync_resync_jobs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_sync_resync_jobs_unfinished ON sync_resync_jobs(created_at DESC) WHERE status IN ('running', 'paused');
+
+-- Migration completion comment
+-- Migration 020: Sync Resync Jobs completed successfully
+-- Tables created: sync_resync_jobs
@@ -0,0 +1,20 @@
+-- Migration: Sync Exclusions
+-- Description: Entity types and tenants excluded from sync, whose events are held until the exclusion is lifted
+
+-- Allow sync events to be held
+ALTER TABLE sync_events DROP CONSTRAINT IF EXISTS valid_status;
+ALTER TABLE sync_events ADD CONSTRAINT valid_status CHECK (status IN ('PENDING', 'PROCESSING', 'COMPLETED', 'FAILED', 'HELD'));
+
+-- Create sync exclusions table
+CREATE TABLE IF NOT EXISTS sync_exclusions (
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('entity_type', 'tenant')),
+    value VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_by VARCHAR(100) NOT NULL,
+    PRIMARY KEY (scope, value)
+);
+
+-- Migration completion comment
+-- Migration 021: Sync Exclusions completed successfully
+-- Tables created: sync_exclusions
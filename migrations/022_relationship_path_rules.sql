@@ -0,0 +1,22 @@
+-- Migration: Relationship Path Rules
+-- Description: Rules constraining relationships across environment or tenant boundaries
+
+-- Create relationship path rules table
+CREATE TABLE IF NOT EXISTS relationship_path_rules (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    relationship_types TEXT[] NOT NULL DEFAULT '{}',
+    attribute VARCHAR(255) NOT NULL,
+    constraint_kind VARCHAR(20) NOT NULL CHECK (constraint_kind IN ('forbid', 'same_value')),
+    source_values TEXT[] NOT NULL DEFAULT '{}',
+    target_values TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100)
+);
+
+-- Migration completion comment
+-- Migration 022: Relationship Path Rules completed successfully
+-- Tables created: relationship_path_rules
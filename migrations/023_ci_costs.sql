@@ -0,0 +1,21 @@
+-- Migration: CI Costs
+-- Description: Monthly CI costs, set by hand or enriched from cloud billing exports
+
+-- Create CI costs table
+CREATE TABLE IF NOT EXISTS ci_costs (
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    period VARCHAR(7) NOT NULL,
+    amount NUMERIC(14,2) NOT NULL CHECK (amount >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    resource_id TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ci_id, period)
+);
+
+-- Create index for roll-ups and reports over a period
+CREATE INDEX IF NOT EXISTS idx_ci_costs_period ON ci_costs(period);
+
+-- Migration completion comment
+-- Migration 023: CI Costs completed successfully
+-- Tables created: ci_costs
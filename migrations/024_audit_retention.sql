@@ -0,0 +1,49 @@
+-- Migration: Audit Retention
+-- Description: Audit log retention policies, legal holds and the archive catalogue
+
+-- Create retention policies table; an empty entity type covers every type
+CREATE TABLE IF NOT EXISTS audit_retention_policies (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL DEFAULT '' UNIQUE,
+    retain_days INTEGER NOT NULL CHECK (retain_days > 0),
+    archive BOOLEAN NOT NULL DEFAULT false,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100)
+);
+
+-- Create legal holds table; unset scope columns match every entry
+CREATE TABLE IF NOT EXISTS audit_legal_holds (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+    entity_type VARCHAR(50) NOT NULL DEFAULT '',
+    entity_id UUID,
+    from_time TIMESTAMP WITH TIME ZONE,
+    to_time TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMP WITH TIME ZONE,
+    released_by VARCHAR(100)
+);
+
+-- Create archive catalogue table
+CREATE TABLE IF NOT EXISTS audit_archives (
+    id UUID PRIMARY KEY,
+    archive_key TEXT NOT NULL UNIQUE,
+    policy_id UUID NOT NULL,
+    from_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    to_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    entry_count INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes for hold checks and archive lookups by time
+CREATE INDEX IF NOT EXISTS idx_audit_legal_holds_active ON audit_legal_holds(entity_type) WHERE released_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_archives_time ON audit_archives(from_time, to_time);
+
+-- Migration completion comment
+-- Migration 024: Audit Retention completed successfully
+-- Tables created: audit_retention_policies, audit_legal_holds, audit_archives
@@ -0,0 +1,29 @@
+-- Migration: Change Anomalies
+-- Description: Anomalies raised by the change velocity detectors
+
+-- Create change anomalies table; subject is an entity ID, a CI type or '*'
+CREATE TABLE IF NOT EXISTS change_anomalies (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    count BIGINT NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL,
+    window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    window_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_by VARCHAR(100)
+);
+
+-- Create indexes for cooldown checks and listing
+CREATE INDEX IF NOT EXISTS idx_change_anomalies_subject ON change_anomalies(kind, subject, detected_at);
+CREATE INDEX IF NOT EXISTS idx_change_anomalies_detected_at ON change_anomalies(detected_at);
+
+-- Index CIs by update time for the deletion and type churn detectors
+CREATE INDEX IF NOT EXISTS idx_cis_updated_at ON configuration_items(updated_at);
+
+-- Migration completion comment
+-- Migration 025: Change Anomalies completed successfully
+-- Tables created: change_anomalies
@@ -0,0 +1,25 @@
+-- Migration: Sync Force Jobs
+-- Description: Filtered force syncs re-enqueueing sync events for matching CIs, checkpointed so interrupted jobs resume where they stopped
+
+-- Create force sync jobs table
+CREATE TABLE IF NOT EXISTS sync_force_jobs (
+    id UUID PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'cancelled', 'failed')),
+    filter JSONB NOT NULL DEFAULT '{}',
+    phases JSONB NOT NULL DEFAULT '[]',
+    current_phase INTEGER NOT NULL DEFAULT 0,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    requested_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    error TEXT
+);
+
+-- Create indexes for listing the latest jobs and finding running ones
+CREATE INDEX IF NOT EXISTS idx_sync_force_jobs_created_at ON sync_force_jobs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_sync_force_jobs_running ON sync_force_jobs(created_at) WHERE status = 'running';
+
+-- Migration completion comment
+-- Migration 026: Sync Force Jobs completed successfully
+-- Tables created: sync_force_jobs
@@ -0,0 +1,34 @@
+-- Migration: Import Journal
+-- Description: Entities created or modified by each import job, with their previous versions, so an import can be rolled back
+
+-- Create import journal jobs table
+CREATE TABLE IF NOT EXISTS import_journal_jobs (
+    id UUID PRIMARY KEY,
+    source VARCHAR(100) NOT NULL,
+    created_by UUID NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_count INTEGER NOT NULL DEFAULT 0,
+    updated_count INTEGER NOT NULL DEFAULT 0,
+    rolled_back_at TIMESTAMP WITH TIME ZONE,
+    rolled_back_by UUID,
+    rollback JSONB
+);
+
+-- Create import journal entries table
+CREATE TABLE IF NOT EXISTS import_journal_entries (
+    job_id UUID NOT NULL REFERENCES import_journal_jobs(id) ON DELETE CASCADE,
+    seq INTEGER NOT NULL,
+    entity_type VARCHAR(50) NOT NULL CHECK (entity_type IN ('ci', 'relationship', 'ci_type_schema', 'relationship_type_schema')),
+    entity_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('created', 'updated')),
+    before JSONB,
+    imported_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (job_id, seq)
+);
+
+-- Create index for deleting jobs past their rollback window
+CREATE INDEX IF NOT EXISTS idx_import_journal_jobs_completed_at ON import_journal_jobs(completed_at);
+
+-- Migration completion comment
+-- Migration 027: Import Journal completed successfully
+-- Tables created: import_journal_jobs, import_journal_entries
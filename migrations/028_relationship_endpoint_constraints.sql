@@ -0,0 +1,63 @@
+-- Migration: Relationship Endpoint Constraints
+-- Description: Reject relationships to missing, soft-deleted or inactive CIs; the foreign keys only cover missing ones
+
+-- Create function rejecting a live relationship whose source or target CI is not live and active.
+-- Deprecated and inactive relationships are kept for history and may point at retired CIs.
+CREATE OR REPLACE FUNCTION check_relationship_endpoints()
+RETURNS TRIGGER AS $$
+DECLARE
+	endpoint_role TEXT;
+	endpoint_id UUID;
+	endpoint_active BOOLEAN;
+	endpoint_deleted BOOLEAN;
+BEGIN
+	IF NEW.is_active = false OR NEW.state = 'deprecated' THEN
+		RETURN NEW;
+	END IF;
+
+	-- Only check updates that move or revive the relationship
+	IF TG_OP = 'UPDATE'
+		AND NEW.source_ci_id = OLD.source_ci_id AND NEW.target_ci_id = OLD.target_ci_id
+		AND NEW.is_active = OLD.is_active AND NEW.state = OLD.state THEN
+		RETURN NEW;
+	END IF;
+
+	FOREACH endpoint_role IN ARRAY ARRAY['source', 'target'] LOOP
+		IF endpoint_role = 'source' THEN
+			endpoint_id := NEW.source_ci_id;
+		ELSE
+			endpoint_id := NEW.target_ci_id;
+		END IF;
+
+		SELECT is_active, is_deleted INTO endpoint_active, endpoint_deleted
+		FROM configuration_items WHERE id = endpoint_id;
+
+		IF NOT FOUND THEN
+			RAISE EXCEPTION '% CI % not found', endpoint_role, endpoint_id
+				USING ERRCODE = 'foreign_key_violation', HINT = endpoint_role || '_ci_not_found';
+		ELSIF endpoint_deleted THEN
+			RAISE EXCEPTION '% CI % is deleted', endpoint_role, endpoint_id
+				USING ERRCODE = 'foreign_key_violation', HINT = endpoint_role || '_ci_deleted';
+		ELSIF NOT endpoint_active THEN
+			RAISE EXCEPTION '% CI % is inactive', endpoint_role, endpoint_id
+				USING ERRCODE = 'foreign_key_violation', HINT = endpoint_role || '_ci_inactive';
+		END IF;
+	END LOOP;
+
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create trigger for ci_relationships table
+DROP TRIGGER IF EXISTS ci_relationship_endpoints_trigger ON ci_relationships;
+CREATE TRIGGER ci_relationship_endpoints_trigger
+BEFORE INSERT OR UPDATE ON ci_relationships
+FOR EACH ROW
+EXECUTE FUNCTION check_relationship_endpoints();
+
+-- Create index for finding the relationships left pointing at deleted CIs
+CREATE INDEX IF NOT EXISTS idx_cis_deleted ON configuration_items(id) WHERE is_deleted = true;
+
+-- Migration completion comment
+-- Migration 028: Relationship Endpoint Constraints completed successfully
+-- Triggers created: ci_relationship_endpoints_trigger
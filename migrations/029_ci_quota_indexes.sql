@@ -0,0 +1,9 @@
+-- Migration: CI Quota Indexes
+-- Description: Index live CIs by tenant so the per-tenant CI quotas can be counted cheaply
+
+-- Create index for counting the live CIs of a tenant
+CREATE INDEX IF NOT EXISTS idx_cis_tenant ON configuration_items((attributes->>'tenant')) WHERE is_deleted = false;
+
+-- Migration completion comment
+-- Migration 029: CI Quota Indexes completed successfully
+-- Indexes created: idx_cis_tenant
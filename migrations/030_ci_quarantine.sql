@@ -0,0 +1,26 @@
+-- Migration: CI Quarantine
+-- Description: Discovered assets that could not be matched or validated against a CI type schema, held for review
+
+-- Create quarantined CIs table
+CREATE TABLE IF NOT EXISTS quarantined_cis (
+    id UUID PRIMARY KEY,
+    source VARCHAR(255) NOT NULL,
+    reason VARCHAR(50) NOT NULL CHECK (reason IN ('no_schema', 'validation_failed')),
+    errors JSONB NOT NULL DEFAULT '[]',
+    ci JSONB NOT NULL,
+    ci_type VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    reviewed_by UUID,
+    reject_reason TEXT,
+    created_ci_id UUID
+);
+
+-- Create index for listing the review queue
+CREATE INDEX IF NOT EXISTS idx_quarantined_cis_status ON quarantined_cis(status, created_at);
+
+-- Migration completion comment
+-- Migration 030: CI Quarantine completed successfully
+-- Tables created: quarantined_cis
@@ -0,0 +1,14 @@
+-- Migration: User Dashboards
+-- Description: Per-user home dashboard preferences (saved searches, watched CIs, favorite reports and widgets)
+
+-- Create user dashboards table
+CREATE TABLE IF NOT EXISTS user_dashboards (
+    user_id VARCHAR(255) PRIMARY KEY,
+    preferences JSONB NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Migration completion comment
+-- Migration 031: User Dashboards completed successfully
+-- Tables created: user_dashboards
@@ -0,0 +1,15 @@
+-- Migration: CI IP Indexes
+-- Description: Index live CIs by IP address so network flow records can be resolved to CIs, and add the communicates_with relationship type
+
+-- Create indexes for resolving flow addresses to CIs
+CREATE INDEX IF NOT EXISTS idx_cis_ip_address ON configuration_items((attributes->>'ip_address')) WHERE is_deleted = false;
+CREATE INDEX IF NOT EXISTS idx_cis_management_ip ON configuration_items((attributes->>'management_ip')) WHERE is_deleted = false;
+
+-- Create the relationship type observed in network flows
+INSERT INTO relationship_type_schemas (id, name, description, attributes, is_active, created_at, updated_at)
+VALUES (gen_random_uuid(), 'communicates_with', 'Source was observed sending network traffic to the target', '[]', true, NOW(), NOW())
+ON CONFLICT (name) DO NOTHING;
+
+-- Migration completion comment
+-- Migration 032: CI IP Indexes completed successfully
+-- Indexes created: idx_cis_ip_address, idx_cis_management_ip
@@ -0,0 +1,13 @@
+-- Migration: Tenant Shards
+-- Description: Control table assigning tenants with data residency requirements to the database shard holding their CI data
+
+-- Create tenant shards table
+CREATE TABLE IF NOT EXISTS tenant_shards (
+    tenant VARCHAR(255) PRIMARY KEY,
+    shard VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Migration completion comment
+-- Migration 033: Tenant Shards completed successfully
+-- Tables created: tenant_shards
@@ -0,0 +1,73 @@
+-- Migration: Relationship Versions
+-- Description: Keep every version of each CI relationship with the period it was valid, so the dependency graph can be reconstructed as of a past time
+
+-- Create relationship versions table
+CREATE TABLE IF NOT EXISTS ci_relationship_versions (
+    version_id BIGSERIAL PRIMARY KEY,
+    relationship_id UUID NOT NULL,
+    source_ci_id UUID NOT NULL,
+    target_ci_id UUID NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    state VARCHAR(20) NOT NULL,
+    valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_to TIMESTAMP WITH TIME ZONE,
+    changed_by UUID,
+    -- Backfilled versions were recorded from the relationships existing when
+    -- versioning started; their content may postdate valid_from
+    backfilled BOOLEAN NOT NULL DEFAULT false
+);
+
+-- Create function closing the current version of a relationship and recording the new one
+CREATE OR REPLACE FUNCTION record_relationship_version()
+RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'UPDATE'
+		AND NEW.source_ci_id = OLD.source_ci_id AND NEW.target_ci_id = OLD.target_ci_id
+		AND NEW.type = OLD.type AND NEW.attributes IS NOT DISTINCT FROM OLD.attributes
+		AND NEW.description IS NOT DISTINCT FROM OLD.description
+		AND NEW.is_active IS NOT DISTINCT FROM OLD.is_active AND NEW.state = OLD.state THEN
+		RETURN NULL;
+	END IF;
+
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE ci_relationship_versions SET valid_to = NOW()
+		WHERE relationship_id = OLD.id AND valid_to IS NULL;
+	END IF;
+
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		INSERT INTO ci_relationship_versions (relationship_id, source_ci_id, target_ci_id, type, attributes,
+			description, is_active, state, valid_from, changed_by)
+		VALUES (NEW.id, NEW.source_ci_id, NEW.target_ci_id, NEW.type, COALESCE(NEW.attributes, '{}'::jsonb),
+			NEW.description, COALESCE(NEW.is_active, true), NEW.state, NOW(), COALESCE(NEW.updated_by, NEW.created_by));
+	END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create trigger for ci_relationships table
+DROP TRIGGER IF EXISTS ci_relationship_versions_trigger ON ci_relationships;
+CREATE TRIGGER ci_relationship_versions_trigger
+AFTER INSERT OR UPDATE OR DELETE ON ci_relationships
+FOR EACH ROW
+EXECUTE FUNCTION record_relationship_version();
+
+-- Backfill the current version of the existing relationships
+INSERT INTO ci_relationship_versions (relationship_id, source_ci_id, target_ci_id, type, attributes,
+    description, is_active, state, valid_from, changed_by, backfilled)
+SELECT r.id, r.source_ci_id, r.target_ci_id, r.type, COALESCE(r.attributes, '{}'::jsonb),
+    r.description, COALESCE(r.is_active, true), r.state, COALESCE(r.created_at, NOW()), COALESCE(r.updated_by, r.created_by), true
+FROM ci_relationships r
+WHERE NOT EXISTS (SELECT 1 FROM ci_relationship_versions v WHERE v.relationship_id = r.id);
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_ci_relationship_versions_relationship ON ci_relationship_versions(relationship_id) WHERE valid_to IS NULL;
+CREATE INDEX IF NOT EXISTS idx_ci_relationship_versions_source ON ci_relationship_versions(source_ci_id, valid_from);
+CREATE INDEX IF NOT EXISTS idx_ci_relationship_versions_target ON ci_relationship_versions(target_ci_id, valid_from);
+
+-- Migration completion comment
+-- Migration 034: Relationship Versions completed successfully
+-- Tables created: ci_relationship_versions
@@ -0,0 +1,44 @@
+-- Migration: Service Accounts
+-- Description: Automation identities kept apart from users; they cannot log in with a password and authenticate with API keys or OAuth client credentials only
+
+-- Create service accounts table
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(63) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id UUID NOT NULL REFERENCES users(id),
+    owner_team VARCHAR(255) NOT NULL DEFAULT '',
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_by UUID REFERENCES users(id),
+    updated_by UUID REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_accounts_owner_id ON service_accounts(owner_id);
+
+-- Create service account credentials table: API keys and client secrets,
+-- stored as hashes and looked up by their public prefix
+CREATE TABLE IF NOT EXISTS service_account_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    secret_hash VARCHAR(128) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_by UUID REFERENCES users(id),
+    CONSTRAINT service_account_credentials_kind_check CHECK (kind IN ('api_key', 'client_secret'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_account_credentials_account_id ON service_account_credentials(account_id);
+
+-- Migration completion comment
+-- Migration 035: Service Accounts completed successfully
+-- Tables created: service_accounts, service_account_credentials
@@ -0,0 +1,44 @@
+-- Migration: Primary Relationships
+-- Description: Mark one outgoing relationship per type of each CI as primary, e.g. the primary database of an application, and keep the flag in the relationship history
+
+-- Add primary flag to relationships
+ALTER TABLE ci_relationships ADD COLUMN IF NOT EXISTS is_primary BOOLEAN NOT NULL DEFAULT false;
+
+-- At most one primary relationship per type for each source CI
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ci_relationships_primary ON ci_relationships(source_ci_id, type) WHERE is_primary;
+
+-- Add primary flag to relationship versions
+ALTER TABLE ci_relationship_versions ADD COLUMN IF NOT EXISTS is_primary BOOLEAN NOT NULL DEFAULT false;
+
+-- Record the primary flag with each version
+CREATE OR REPLACE FUNCTION record_relationship_version()
+RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'UPDATE'
+		AND NEW.source_ci_id = OLD.source_ci_id AND NEW.target_ci_id = OLD.target_ci_id
+		AND NEW.type = OLD.type AND NEW.attributes IS NOT DISTINCT FROM OLD.attributes
+		AND NEW.description IS NOT DISTINCT FROM OLD.description
+		AND NEW.is_active IS NOT DISTINCT FROM OLD.is_active AND NEW.state = OLD.state
+		AND NEW.is_primary = OLD.is_primary THEN
+		RETURN NULL;
+	END IF;
+
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE ci_relationship_versions SET valid_to = NOW()
+		WHERE relationship_id = OLD.id AND valid_to IS NULL;
+	END IF;
+
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		INSERT INTO ci_relationship_versions (relationship_id, source_ci_id, target_ci_id, type, attributes,
+			description, is_active, state, is_primary, valid_from, changed_by)
+		VALUES (NEW.id, NEW.source_ci_id, NEW.target_ci_id, NEW.type, COALESCE(NEW.attributes, '{}'::jsonb),
+			NEW.description, COALESCE(NEW.is_active, true), NEW.state, NEW.is_primary, NOW(), COALESCE(NEW.updated_by, NEW.created_by));
+	END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Migration completion comment
+-- Migration 036: Primary Relationships completed successfully
+-- Columns added: ci_relationships.is_primary, ci_relationship_versions.is_primary
@@ -0,0 +1,34 @@
+-- Migration: CI Archive
+-- Description: Archive tier for long-inactive CIs; archived CIs, their relationships and their history are moved out of the hot tables, so they are left out of queries and sync until restored
+
+-- Create archived CIs table. Rows are stored whole as JSONB, compressed, and
+-- never updated, so pages are packed full.
+CREATE TABLE IF NOT EXISTS archived_cis (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    data JSONB COMPRESSION lz4 NOT NULL,
+    -- Rows of the tables keyed by the CI, by table name
+    history JSONB COMPRESSION lz4 NOT NULL DEFAULT '{}',
+    last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+) WITH (fillfactor = 100);
+
+CREATE INDEX IF NOT EXISTS idx_archived_cis_type_name ON archived_cis(type, name);
+
+-- Create archived relationships table. A relationship stays archived until
+-- both of its CIs are live again.
+CREATE TABLE IF NOT EXISTS archived_ci_relationships (
+    id UUID PRIMARY KEY,
+    source_ci_id UUID NOT NULL,
+    target_ci_id UUID NOT NULL,
+    data JSONB COMPRESSION lz4 NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+) WITH (fillfactor = 100);
+
+CREATE INDEX IF NOT EXISTS idx_archived_ci_relationships_source ON archived_ci_relationships(source_ci_id);
+CREATE INDEX IF NOT EXISTS idx_archived_ci_relationships_target ON archived_ci_relationships(target_ci_id);
+
+-- Migration completion comment
+-- Migration 037: CI Archive completed successfully
+-- Tables created: archived_cis, archived_ci_relationships
@@ -0,0 +1,20 @@
+-- Migration: CI Heartbeats
+-- Description: Heartbeats sent by agents and monitoring integrations per CI; CIs missing them beyond the threshold of their type are moved to the unreachable status until heartbeats resume
+
+-- Create heartbeats table. A CI is monitored from its first heartbeat on.
+CREATE TABLE IF NOT EXISTS ci_heartbeats (
+    ci_id UUID PRIMARY KEY REFERENCES configuration_items(id) ON DELETE CASCADE,
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    last_heartbeat_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- Set while the CI is unreachable, together with the status it had before
+    unreachable_since TIMESTAMP WITH TIME ZONE,
+    previous_status VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Only reachable CIs are checked for missed heartbeats
+CREATE INDEX IF NOT EXISTS idx_ci_heartbeats_reachable ON ci_heartbeats(last_heartbeat_at) WHERE unreachable_since IS NULL;
+
+-- Migration completion comment
+-- Migration 038: CI Heartbeats completed successfully
+-- Tables created: ci_heartbeats
@@ -0,0 +1,34 @@
+-- Migration: Sync Conflict Escalation
+-- Description: Age unresolved sync conflicts through escalating severities, notify a role at each step, optionally auto-resolve them by timestamp, and record every step
+
+-- Add severity to conflicts; new conflicts start low and escalate as they age
+ALTER TABLE sync_conflicts ADD COLUMN IF NOT EXISTS severity VARCHAR(20) NOT NULL DEFAULT 'low';
+ALTER TABLE sync_conflicts ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sync_conflicts DROP CONSTRAINT IF EXISTS valid_severity;
+ALTER TABLE sync_conflicts ADD CONSTRAINT valid_severity CHECK (severity IN ('low', 'medium', 'high', 'critical'));
+
+-- Only unresolved conflicts are aged
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_unresolved_age ON sync_conflicts(created_at) WHERE resolved = false;
+
+-- Create escalation steps table
+CREATE TABLE IF NOT EXISTS sync_conflict_escalations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conflict_id UUID NOT NULL REFERENCES sync_conflicts(id) ON DELETE CASCADE,
+    action VARCHAR(30) NOT NULL,
+    from_severity VARCHAR(20),
+    to_severity VARCHAR(20),
+    role VARCHAR(50),
+    recipients TEXT[] NOT NULL DEFAULT '{}',
+    -- The error of a failed step
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT valid_escalation_action CHECK (action IN ('escalated', 'notified', 'notify_failed', 'auto_resolved', 'auto_resolve_failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_conflict_escalations_conflict ON sync_conflict_escalations(conflict_id, created_at);
+
+-- Migration completion comment
+-- Migration 039: Sync Conflict Escalation completed successfully
+-- Tables created: sync_conflict_escalations
+-- Columns added: sync_conflicts.severity, sync_conflicts.escalated_at
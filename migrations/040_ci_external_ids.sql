@@ -0,0 +1,24 @@
+-- Migration: CI External IDs
+-- Description: Map the IDs of resources in external systems, such as AWS ARNs and Azure resource IDs, to CIs so relationships imported from those systems can be resolved to CIs
+
+-- Create external IDs table. IDs are stored lower-cased, as cloud providers compare them case-insensitively.
+CREATE TABLE IF NOT EXISTS ci_external_ids (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    system VARCHAR(50) NOT NULL,
+    external_id TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_by UUID,
+
+    CONSTRAINT unique_external_id UNIQUE (system, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ci_external_ids_ci ON ci_external_ids(ci_id);
+
+-- Imported relationships are looked up by the connector that last saw them
+CREATE INDEX IF NOT EXISTS idx_ci_relationships_cloud_connector ON ci_relationships((attributes->>'cloud_connector')) WHERE attributes ? 'cloud_connector';
+
+-- Migration completion comment
+-- Migration 040: CI External IDs completed successfully
+-- Tables created: ci_external_ids
+-- Indexes created: idx_ci_relationships_cloud_connector
@@ -0,0 +1,23 @@
+-- Migration: Service Tree
+-- Description: Denormalized membership of business services, every live CI a service reaches downstream through active relationships, maintained by the sync pipeline so service membership and health are read without traversing the graph
+
+-- Create service tree table. The service is a member of its own tree at depth 0.
+-- ci_id has no foreign key so deleted CIs stay until the sync event of the deletion rebuilds the trees holding them.
+CREATE TABLE IF NOT EXISTS service_tree_members (
+    service_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    ci_id UUID NOT NULL,
+    depth INTEGER NOT NULL,
+    ci_name VARCHAR(255) NOT NULL,
+    ci_type VARCHAR(100) NOT NULL,
+    ci_status VARCHAR(50) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (service_id, ci_id)
+);
+
+-- Services of a CI
+CREATE INDEX IF NOT EXISTS idx_service_tree_members_ci ON service_tree_members(ci_id);
+
+-- Migration completion comment
+-- Migration 041: Service Tree completed successfully
+-- Tables created: service_tree_members
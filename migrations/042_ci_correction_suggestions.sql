@@ -0,0 +1,26 @@
+-- Migration: CI Correction Suggestions
+-- Description: Corrections of CIs suggested by users who cannot edit them, queued until an editor accepts or rejects them
+
+-- Create correction suggestions table. changes holds each corrected field with
+-- its value when suggested and the proposed value.
+CREATE TABLE IF NOT EXISTS ci_correction_suggestions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    changes JSONB NOT NULL,
+    reason TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected', 'withdrawn')),
+    suggested_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reviewed_by VARCHAR(100),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    review_note TEXT
+);
+
+-- Create indexes for the review queue, per-CI lookups and suggesters' own lists
+CREATE INDEX IF NOT EXISTS idx_ci_correction_suggestions_queue ON ci_correction_suggestions(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_ci_correction_suggestions_ci ON ci_correction_suggestions(ci_id);
+CREATE INDEX IF NOT EXISTS idx_ci_correction_suggestions_suggested_by ON ci_correction_suggestions(suggested_by, created_at);
+
+-- Migration completion comment
+-- Migration 042: CI Correction Suggestions completed successfully
+-- Tables created: ci_correction_suggestions
@@ -0,0 +1,22 @@
+-- Migration: User Offboarding
+-- Description: Reports of batch user offboarding runs, with what was revoked and reassigned per user
+
+-- Create offboarding reports table. users holds the result of each offboarded user.
+CREATE TABLE IF NOT EXISTS user_offboarding_reports (
+    id UUID PRIMARY KEY,
+    requested_by VARCHAR(100) NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reason TEXT,
+    reassign_to VARCHAR(255),
+    summary JSONB NOT NULL DEFAULT '{}',
+    users JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_offboarding_reports_requested_at ON user_offboarding_reports(requested_at DESC);
+
+-- Match CI owners case-insensitively when an offboarded user's CIs are reassigned or flagged
+CREATE INDEX IF NOT EXISTS idx_cis_owner_lower ON configuration_items(LOWER(owner)) WHERE is_deleted = false;
+
+-- Migration completion comment
+-- Migration 043: User Offboarding completed successfully
+-- Tables created: user_offboarding_reports
@@ -0,0 +1,59 @@
+-- Migration: CI Aliases
+-- Description: Keep the former names and IDs of renamed and merged CIs, so links to them keep resolving to the current CI
+
+-- Create CI aliases table. Name aliases hold a former type and name, ID aliases the ID of a CI merged away.
+CREATE TABLE IF NOT EXISTS ci_aliases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    kind VARCHAR(10) NOT NULL,
+    ci_type VARCHAR(100),
+    name VARCHAR(255),
+    former_ci_id UUID,
+    reason VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_by UUID,
+
+    CONSTRAINT valid_ci_alias_kind CHECK (
+        (kind = 'name' AND ci_type IS NOT NULL AND name IS NOT NULL AND former_ci_id IS NULL)
+        OR (kind = 'id' AND former_ci_id IS NOT NULL AND ci_type IS NULL AND name IS NULL)
+    ),
+    CONSTRAINT valid_ci_alias_reason CHECK (reason IN ('rename', 'merge'))
+);
+
+-- Create function recording the former name of a renamed CI. A live CI holding a
+-- name takes precedence over an alias of it, so the alias is dropped.
+CREATE OR REPLACE FUNCTION record_ci_name_alias()
+RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'UPDATE' AND NEW.name = OLD.name AND NEW.type = OLD.type THEN
+		RETURN NULL;
+	END IF;
+
+	DELETE FROM ci_aliases WHERE kind = 'name' AND ci_type = NEW.type AND name = NEW.name;
+
+	IF TG_OP = 'UPDATE' THEN
+		INSERT INTO ci_aliases (ci_id, kind, ci_type, name, reason, created_by)
+		VALUES (NEW.id, 'name', OLD.type, OLD.name, 'rename', NEW.updated_by)
+		ON CONFLICT (ci_type, name) WHERE kind = 'name' DO UPDATE
+		SET ci_id = EXCLUDED.ci_id, reason = EXCLUDED.reason, created_at = NOW(), created_by = EXCLUDED.created_by;
+	END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create indexes for resolving aliases and listing the aliases of a CI
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ci_aliases_name ON ci_aliases(ci_type, name) WHERE kind = 'name';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ci_aliases_former_ci ON ci_aliases(former_ci_id) WHERE kind = 'id';
+CREATE INDEX IF NOT EXISTS idx_ci_aliases_ci ON ci_aliases(ci_id);
+
+-- Create trigger for configuration_items table
+DROP TRIGGER IF EXISTS ci_name_alias_trigger ON configuration_items;
+CREATE TRIGGER ci_name_alias_trigger
+AFTER INSERT OR UPDATE OF name, type ON configuration_items
+FOR EACH ROW
+EXECUTE FUNCTION record_ci_name_alias();
+
+-- Migration completion comment
+-- Migration 044: CI Aliases completed successfully
+-- Tables created: ci_aliases
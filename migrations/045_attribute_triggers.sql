@@ -0,0 +1,40 @@
+-- Migration: Attribute Triggers
+-- Description: Admin-defined actions fired when an attribute of a CI changes, with a log of every action run
+
+-- Create attribute triggers table
+CREATE TABLE IF NOT EXISTS attribute_triggers (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    ci_types TEXT[] NOT NULL DEFAULT '{}',
+    attribute VARCHAR(255) NOT NULL,
+    from_value TEXT,
+    to_value TEXT,
+    actions JSONB NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_by VARCHAR(100)
+);
+
+-- Create attribute trigger executions table
+CREATE TABLE IF NOT EXISTS attribute_trigger_executions (
+    id UUID PRIMARY KEY,
+    trigger_id UUID NOT NULL REFERENCES attribute_triggers(id) ON DELETE CASCADE,
+    trigger_name VARCHAR(255) NOT NULL,
+    ci_id UUID NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    attribute VARCHAR(255) NOT NULL,
+    from_value TEXT,
+    to_value TEXT,
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('ok', 'error')),
+    message TEXT,
+    duration_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    executed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for per-trigger execution logs
+CREATE INDEX IF NOT EXISTS idx_attribute_trigger_executions_trigger_id ON attribute_trigger_executions(trigger_id, executed_at DESC);
+
+-- Migration completion comment
+-- Migration 045: Attribute Triggers completed successfully
+-- Tables created: attribute_triggers, attribute_trigger_executions
@@ -0,0 +1,19 @@
+-- Migration: CI Revisions
+-- Description: Keep the state of each CI before every update, delete and restore, so a CI can be restored to any earlier revision
+
+-- Create CI revisions table. Revisions are numbered from 1 per CI and hold the
+-- CI as it was just before the change they were recorded for.
+CREATE TABLE IF NOT EXISTS ci_revisions (
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('update', 'delete', 'restore')),
+    snapshot JSONB NOT NULL,
+    changed_by UUID,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (ci_id, revision)
+);
+
+-- Migration completion comment
+-- Migration 046: CI Revisions completed successfully
+-- Tables created: ci_revisions
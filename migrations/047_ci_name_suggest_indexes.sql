@@ -0,0 +1,15 @@
+-- Migration: CI Name Suggest Indexes
+-- Description: Index live CI names by prefix and by trigram for search-as-you-type suggestions
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Create index for name prefixes; the C collation lets LIKE 'abc%' use it and
+-- returns matches in name order
+CREATE INDEX IF NOT EXISTS idx_cis_name_prefix ON configuration_items((LOWER(name) COLLATE "C")) WHERE is_deleted = false;
+
+-- Create index for names containing a query of three or more characters
+CREATE INDEX IF NOT EXISTS idx_cis_name_trgm ON configuration_items USING GIN (LOWER(name) gin_trgm_ops) WHERE is_deleted = false;
+
+-- Migration completion comment
+-- Migration 047: CI Name Suggest Indexes completed successfully
+-- Indexes created: idx_cis_name_prefix, idx_cis_name_trgm
@@ -0,0 +1,40 @@
+-- Migration: Incident Learning
+-- Description: Incident records referencing CIs, and the relationship confidence learned from how often both ends of a relationship are hit by the same incident
+
+-- Create incidents table. Incidents are identified by the source system and its ID for them.
+CREATE TABLE IF NOT EXISTS incidents (
+    id UUID PRIMARY KEY,
+    source VARCHAR(100) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (source, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_started_at ON incidents(started_at);
+
+-- Create incident CIs table, the CIs an incident affected
+CREATE TABLE IF NOT EXISTS incident_cis (
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    ci_id UUID NOT NULL,
+    PRIMARY KEY (incident_id, ci_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_cis_ci_id ON incident_cis(ci_id);
+
+-- Create relationship incident scores table. confidence is the share of the
+-- incidents hitting the target of a relationship that also hit its source.
+CREATE TABLE IF NOT EXISTS relationship_incident_scores (
+    relationship_id UUID PRIMARY KEY REFERENCES ci_relationships(id) ON DELETE CASCADE,
+    co_occurrences INTEGER NOT NULL,
+    target_incidents INTEGER NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL,
+    critical BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Migration completion comment
+-- Migration 048: Incident Learning completed successfully
+-- Tables created: incidents, incident_cis, relationship_incident_scores
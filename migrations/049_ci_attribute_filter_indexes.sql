@@ -0,0 +1,12 @@
+-- Migration: CI Attribute Filter Indexes
+-- Description: Index live CI attributes for containment so attribute filters on CI listings use an index
+
+-- Create index for attribute containment (attributes @> '{"os": "linux"}'),
+-- which backs eq, neq and array contains filters. jsonb_path_ops indexes are
+-- smaller and faster for containment than the jsonb_ops index
+-- idx_cis_attributes, which remains for key existence checks (attributes ? 'cpu_cores').
+CREATE INDEX IF NOT EXISTS idx_cis_attributes_path_ops ON configuration_items USING GIN (attributes jsonb_path_ops) WHERE is_deleted = false;
+
+-- Migration completion comment
+-- Migration 049: CI Attribute Filter Indexes completed successfully
+-- Indexes created: idx_cis_attributes_path_ops
@@ -0,0 +1,13 @@
+-- Migration: Relationship Attribute Filter Indexes
+-- Description: Index relationship attributes so attribute filters on relationship listings use an index
+
+-- Create index for attribute containment (attributes @> '{"port": 5432}') and
+-- key existence (attributes ? 'port'), which back every attribute filter
+CREATE INDEX IF NOT EXISTS idx_ci_relationships_attributes ON ci_relationships USING GIN (attributes);
+
+-- Create index for listing relationships in a state, newest first
+CREATE INDEX IF NOT EXISTS idx_ci_relationships_state_created ON ci_relationships(state, created_at DESC);
+
+-- Migration completion comment
+-- Migration 051: Relationship Attribute Filter Indexes completed successfully
+-- Indexes created: idx_ci_relationships_attributes, idx_ci_relationships_state_created
@@ -0,0 +1,30 @@
+-- Migration: CI Purges
+-- Description: Requests to permanently remove CIs for legal erasure, with their dry-run reports and approvals
+
+-- Create purge requests table. report holds the dry run the approvers saw and
+-- approvals the admins who approved it; the CI's name is cleared from report
+-- once the purge is executed.
+CREATE TABLE IF NOT EXISTS ci_purge_requests (
+    id UUID PRIMARY KEY,
+    ci_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    audit_policy VARCHAR(20) NOT NULL CHECK (audit_policy IN ('redact', 'delete')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'executed', 'cancelled', 'expired')),
+    requested_by UUID NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    required_approvals INTEGER NOT NULL CHECK (required_approvals >= 1),
+    approvals JSONB NOT NULL DEFAULT '[]',
+    report JSONB NOT NULL,
+    executed_by UUID,
+    executed_at TIMESTAMP WITH TIME ZONE,
+    removed JSONB,
+    cancelled_by UUID
+);
+
+CREATE INDEX IF NOT EXISTS idx_ci_purge_requests_requested_at ON ci_purge_requests(requested_at DESC);
+CREATE INDEX IF NOT EXISTS idx_ci_purge_requests_status ON ci_purge_requests(status, requested_at DESC);
+
+-- Migration completion comment
+-- Migration 052: CI Purges completed successfully
+-- Tables created: ci_purge_requests
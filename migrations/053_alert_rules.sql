@@ -0,0 +1,30 @@
+-- Migration: Alert Rules
+-- Description: Threshold rules over internal metrics and their evaluation state
+
+-- Create alert rules table. state, last_value and the timestamps record the
+-- rule's last evaluation by the sync monitor.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    metric VARCHAR(100) NOT NULL,
+    operator VARCHAR(10) NOT NULL CHECK (operator IN ('gt', 'gte', 'lt', 'lte')),
+    threshold DOUBLE PRECISION NOT NULL,
+    for_seconds INTEGER NOT NULL DEFAULT 0 CHECK (for_seconds >= 0),
+    severity VARCHAR(20) NOT NULL DEFAULT 'warning' CHECK (severity IN ('info', 'warning', 'critical')),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    state VARCHAR(20) NOT NULL DEFAULT 'ok' CHECK (state IN ('ok', 'pending', 'firing')),
+    last_value DOUBLE PRECISION,
+    pending_since TIMESTAMP WITH TIME ZONE,
+    firing_since TIMESTAMP WITH TIME ZONE,
+    last_evaluated_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_enabled ON alert_rules(name) WHERE enabled = true;
+
+-- Migration completion comment
+-- Migration 053: Alert Rules completed successfully
+-- Tables created: alert_rules
@@ -0,0 +1,29 @@
+-- Migration: Organizations
+-- Description: Organizations users act for, keeping the CIs of each customer apart
+
+-- Create organizations table. The slug is the tenant the organization's CIs
+-- carry in their tenant_id attribute.
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create organization members table. Users log in acting for their default
+-- organization, of which they have at most one.
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user ON organization_members(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_members_default ON organization_members(user_id) WHERE is_default;
+
+-- Migration completion comment
+-- Migration 054: Organizations completed successfully
+-- Tables created: organizations, organization_members
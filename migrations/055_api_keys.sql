@@ -0,0 +1,27 @@
+-- Migration: API keys
+-- Description: Permission scoped API keys for machine-to-machine access
+
+-- Create API keys table. Only a hash of each key is stored; the prefix
+-- identifies the key a request was sent with.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    org VARCHAR(63) NOT NULL DEFAULT '',
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    secret_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    rotated_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_owner_id ON api_keys(owner_id);
+
+-- Migration completion comment
+-- Migration 055: API keys completed successfully
+-- Tables created: api_keys
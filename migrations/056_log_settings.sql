@@ -0,0 +1,17 @@
+-- Migration: Log settings
+-- Description: Slow query logging and debug log sampling settings changed at runtime
+
+-- Create log settings table. It holds at most one row, the settings changed
+-- through the admin API; without it the configured settings apply.
+CREATE TABLE IF NOT EXISTS log_settings (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    slow_query_threshold_ms BIGINT NOT NULL DEFAULT 0,
+    debug_sample_rate DOUBLE PRECISION NOT NULL DEFAULT 1,
+    sample_rates JSONB NOT NULL DEFAULT '{}',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Migration completion comment
+-- Migration 056: Log settings completed successfully
+-- Tables created: log_settings
@@ -0,0 +1,44 @@
+-- Migration: Organization scoping
+-- Description: Scope CIs, relationships, type schemas, users and roles to the organization they belong to
+
+-- Rows belong to the organization in org_id, or are shared when it is NULL.
+-- There are no foreign keys: CI data may live on a residency shard without
+-- the organizations table, and rows of a deleted organization must stay
+-- hidden from the others rather than become shared.
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS org_id UUID;
+ALTER TABLE ci_relationships ADD COLUMN IF NOT EXISTS org_id UUID;
+ALTER TABLE ci_type_schemas ADD COLUMN IF NOT EXISTS org_id UUID;
+ALTER TABLE relationship_type_schemas ADD COLUMN IF NOT EXISTS org_id UUID;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id UUID;
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS org_id UUID;
+
+-- CIs created within an organization so far carry its slug in their
+-- tenant_id attribute
+UPDATE configuration_items ci SET org_id = o.id
+FROM organizations o
+WHERE ci.org_id IS NULL
+  AND jsonb_typeof(ci.attributes->'tenant_id') = 'string'
+  AND ci.attributes->>'tenant_id' = o.slug;
+
+-- Relationships between CIs of an organization belong to it
+UPDATE ci_relationships rel SET org_id = COALESCE(s.org_id, t.org_id)
+FROM configuration_items s, configuration_items t
+WHERE rel.org_id IS NULL
+  AND s.id = rel.source_ci_id AND t.id = rel.target_ci_id
+  AND COALESCE(s.org_id, t.org_id) IS NOT NULL;
+
+-- Users belong to their default organization
+UPDATE users u SET org_id = m.organization_id
+FROM organization_members m
+WHERE u.org_id IS NULL AND m.user_id = u.id AND m.is_default;
+
+CREATE INDEX IF NOT EXISTS idx_cis_org_id ON configuration_items(org_id) WHERE is_deleted = false;
+CREATE INDEX IF NOT EXISTS idx_ci_relationships_org_id ON ci_relationships(org_id);
+CREATE INDEX IF NOT EXISTS idx_users_org_id ON users(org_id);
+CREATE INDEX IF NOT EXISTS idx_roles_org_id ON roles(org_id);
+
+-- Migration completion comment
+-- Migration 057: Organization scoping completed successfully
+-- Columns added: configuration_items.org_id, ci_relationships.org_id, ci_type_schemas.org_id,
+--   relationship_type_schemas.org_id, users.org_id, roles.org_id
+-- Indexes created: idx_cis_org_id, idx_ci_relationships_org_id, idx_users_org_id, idx_roles_org_id
@@ -0,0 +1,12 @@
+-- Migration: Organization role names
+-- Description: Make role names unique per organization rather than globally
+
+-- Organizations name their roles independently of each other. Shared roles,
+-- whose org_id is NULL, keep unique names among themselves; an organization's
+-- role shadows the shared role of the same name for the organization.
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_org_id_name ON roles(org_id, name) NULLS NOT DISTINCT;
+
+-- Migration completion comment
+-- Migration 058: Organization role names completed successfully
+-- Indexes created: idx_roles_org_id_name
@@ -0,0 +1,60 @@
+// Package migrations holds the database migrations: the SQL files applied
+// in version order, and the Go migration moving CIs to flexible attributes.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FS holds the SQL migrations, so that tools and tests can apply them
+// without knowing where the repository is checked out
+//
+//go:embed *.sql
+var FS embed.FS
+
+// gooseDown starts the section of a goose migration that rolls it back
+const gooseDown = "-- +goose Down"
+
+// Migration is an embedded SQL migration
+type Migration struct {
+	// Version is the number the file name starts with
+	Version int
+	Name    string
+}
+
+// List returns the embedded SQL migrations in the order they apply
+func List() ([]Migration, error) {
+	names, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		prefix, _, found := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !found || err != nil {
+			return nil, fmt.Errorf("migration %s does not start with a version number", name)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up returns the SQL applying a migration. Migrations written for goose keep
+// only their Up section, so running the whole file does not drop what it
+// just created.
+func Up(name string) (string, error) {
+	content, err := FS.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration %s: %w", name, err)
+	}
+	up, _, _ := strings.Cut(string(content), gooseDown)
+	return up, nil
+}
@@ -1,3 +1,8 @@
+//go:build ignore
+
+// The seeder is a standalone program, run with go run migrations/seed.go;
+// the build constraint keeps it out of the migrations package.
+
 package main
 
 import (